@@ -19,10 +19,12 @@ import (
 	"os"
 	"time"
 
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
 	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
 	podsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod/svc"
 	watchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/watch/svc"
+	"github.com/uber/peloton/.gen/thrift/aurora/api/auroraadminserver"
 	"github.com/uber/peloton/.gen/thrift/aurora/api/auroraschedulermanagerserver"
 	"github.com/uber/peloton/.gen/thrift/aurora/api/readonlyschedulerserver"
 
@@ -175,6 +177,11 @@ func main() {
 				peer.NewPeerChooser(t, 1*time.Second, discovery.GetAppURL, common.ResourceManagerRole),
 			),
 		},
+		common.PelotonHostManager: transport.Outbounds{
+			Unary: t.NewOutbound(
+				peer.NewPeerChooser(t, 1*time.Second, discovery.GetAppURL, common.HostManagerRole),
+			),
+		},
 	}
 
 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
@@ -195,6 +202,9 @@ func main() {
 	respoolClient := respool.NewResourceManagerYARPCClient(
 		dispatcher.ClientConfig(common.PelotonResourceManager))
 
+	hostClient := hostsvc.NewHostServiceYARPCClient(
+		dispatcher.ClientConfig(common.PelotonHostManager))
+
 	watchClient := watchsvc.NewWatchServiceYARPCClient(
 		dispatcher.ClientConfig(common.PelotonJobManager))
 
@@ -240,6 +250,7 @@ func main() {
 		rootScope,
 		jobClient,
 		podClient,
+		hostClient,
 		respoolLoader,
 	)
 	if err != nil {
@@ -248,6 +259,7 @@ func main() {
 
 	dispatcher.Register(auroraschedulermanagerserver.New(handler))
 	dispatcher.Register(readonlyschedulerserver.New(handler))
+	dispatcher.Register(auroraadminserver.New(handler))
 
 	if err := candidate.Start(); err != nil {
 		log.Fatalf("Unable to start leader candidate: %v", err)
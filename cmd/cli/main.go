@@ -29,8 +29,10 @@ import (
 	"github.com/uber/peloton/pkg/common"
 	common_config "github.com/uber/peloton/pkg/common/config"
 	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/common/tlsconfig"
 	"github.com/uber/peloton/pkg/common/util"
 
+	"github.com/uber-go/tally"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -106,6 +108,33 @@ var (
 		Envar("BASIC_AUTH_CONFIG").
 		String()
 
+	hostMgrTLSPort = app.Flag(
+		"hostmgr-tls-port",
+		"host manager port serving the host service over TLS, required when "+
+			"host manager has TLS enabled (set $HOSTMGR_TLS_PORT to override)").
+		Envar("HOSTMGR_TLS_PORT").
+		Int()
+
+	tlsCAFile = app.Flag(
+		"tls-ca-file",
+		"CA bundle verifying the host manager certificate, system roots are "+
+			"used if unset (set $TLS_CA_FILE to override)").
+		Envar("TLS_CA_FILE").
+		String()
+
+	tlsCertFile = app.Flag(
+		"tls-cert-file",
+		"client certificate presented to host manager for mutual TLS "+
+			"(set $TLS_CERT_FILE to override)").
+		Envar("TLS_CERT_FILE").
+		String()
+
+	tlsKeyFile = app.Flag(
+		"tls-key-file",
+		"private key of the client certificate (set $TLS_KEY_FILE to override)").
+		Envar("TLS_KEY_FILE").
+		String()
+
 	timeout = app.Flag(
 		"timeout",
 		"default RPC timeout (set $TIMEOUT to override)").
@@ -193,6 +222,10 @@ var (
 
 	jobGetActiveJobs = job.Command("active-list", "get a list of active jobs")
 
+	jobCheckConstraints       = job.Command("check-constraints", "dry-run the placement constraints of a job against the labels of the hosts which are up")
+	jobCheckConstraintsConfig = jobCheckConstraints.Arg("config", "YAML job configuration").ExistingFile()
+	jobCheckConstraintsSpec   = jobCheckConstraints.Flag("constraint", "YAML or JSON constraint written in the constraint DSL, checked instead of a job configuration").Short('c').ExistingFile()
+
 	// Top level job command for stateless jobs
 	stateless = job.Command("stateless", "manage stateless jobs")
 
@@ -523,14 +556,134 @@ var (
 	host            = app.Command("host", "manage hosts")
 	hostMaintenance = host.Command("maintenance", "host maintenance")
 
-	hostMaintenanceStart          = hostMaintenance.Command("start", "start host maintenance on a list of hosts")
-	hostMaintenanceStartHostnames = hostMaintenanceStart.Arg("hostnames", "comma separated hostnames").Required().String()
-
-	hostMaintenanceComplete          = hostMaintenance.Command("complete", "complete host maintenance on a list of hosts")
-	hostMaintenanceCompleteHostnames = hostMaintenanceComplete.Arg("hostnames", "comma separated hostnames").Required().String()
+	hostMaintenanceStart             = hostMaintenance.Command("start", "start host maintenance on a list of hosts")
+	hostMaintenanceStartHostnames    = hostMaintenanceStart.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostMaintenanceStartFile         = hostMaintenanceStart.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+	hostMaintenanceStartAgentIDs     = hostMaintenanceStart.Flag("agent-ids", "comma separated Mesos agent ids of hosts, in addition to the hostnames").Default("").String()
+	hostMaintenanceStartGracePeriod  = hostMaintenanceStart.Flag("kill-grace-period", "kill grace period in seconds overriding the one of the tasks on the hosts").Default("0").Uint32()
+	hostMaintenanceStartMessage      = hostMaintenanceStart.Flag("message", "message sent to the executor of each task before the task is killed").Default("").String()
+	hostMaintenanceStartLabels       = hostMaintenanceStart.Flag("labels", "labels sent with the message (key=value pairs, comma separated)").Default("").String()
+	hostMaintenanceStartDrainMethod  = hostMaintenanceStart.Flag("drain-method", "how the hosts are drained (maintenance_schedule or agent_drain), the host manager default if not set").Default("").String()
+	hostMaintenanceStartApproval     = hostMaintenanceStart.Flag("require-approval", "keep the hosts DRAINED until their maintenance is approved by another user").Default("false").Bool()
+	hostMaintenanceStartCanaryCount  = hostMaintenanceStart.Flag("canary-count", "drain only this many hosts first, and the remaining hosts once the tasks of the canary hosts are rescheduled").Default("0").Uint32()
+	hostMaintenanceStartCanaryWait   = hostMaintenanceStart.Flag("canary-observation", "time to observe the rescheduling of the tasks of the canary hosts once they are DOWN").Default("10m").Duration()
+	hostMaintenanceStartCanaryRate   = hostMaintenanceStart.Flag("canary-min-reschedule-rate", "minimum fraction of the tasks of the canary hosts rescheduled to drain the remaining hosts").Default("0.9").Float64()
+	hostMaintenanceStartDrainTimeout = hostMaintenanceStart.Flag("drain-timeout", "put the hosts down once they were draining for this long, even if tasks still run on them, never if 0").Default("0s").Duration()
+	hostMaintenanceStartPolicy       = hostMaintenanceStart.Flag("policy", "maintenance policy whose drain options are used, instead of the drain option flags").Default("").String()
+	hostMaintenanceStartExemptTypes  = hostMaintenanceStart.Flag("exempt-job-types", "tasks of jobs of these types (batch, service or daemon, comma separated) are not evicted by the drain").Default("").String()
+	hostMaintenanceStartExemptLabels = hostMaintenanceStart.Flag("exempt-labels", "tasks with these labels (key=value pairs or keys for any value, comma separated) are not evicted by the drain").Default("").String()
+	hostMaintenanceStartExemptCtrls  = hostMaintenanceStart.Flag("exempt-controllers", "tasks of controller jobs are not evicted by the drain").Default("false").Bool()
+	hostMaintenanceStartDryRun       = hostMaintenanceStart.Flag("dry-run", "print the changes of the request without making them").Default("false").Bool()
+	hostMaintenanceStartWatch        = hostMaintenanceStart.Flag("watch", "print host state transitions until all hosts are DOWN").Short('w').Default("false").Bool()
+	hostMaintenanceStartWatchTimeout = hostMaintenanceStart.Flag("watch-timeout", "stop watching after this duration, never if 0").Default("0s").Duration()
+
+	hostMaintenanceComplete             = hostMaintenance.Command("complete", "complete host maintenance on a list of hosts")
+	hostMaintenanceCompleteHostnames    = hostMaintenanceComplete.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostMaintenanceCompleteFile         = hostMaintenanceComplete.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+	hostMaintenanceCompleteAgentIDs     = hostMaintenanceComplete.Flag("agent-ids", "comma separated Mesos agent ids of hosts, in addition to the hostnames").Default("").String()
+	hostMaintenanceCompleteReboot       = hostMaintenanceComplete.Flag("reboot", "reboot the machines of the hosts with the host provider first").Default("false").Bool()
+	hostMaintenanceCompleteDryRun       = hostMaintenanceComplete.Flag("dry-run", "print the changes of the request without making them").Default("false").Bool()
+	hostMaintenanceCompleteWatch        = hostMaintenanceComplete.Flag("watch", "print host state transitions until all hosts are UP").Short('w').Default("false").Bool()
+	hostMaintenanceCompleteWatchTimeout = hostMaintenanceComplete.Flag("watch-timeout", "stop watching after this duration, never if 0").Default("0s").Duration()
+
+	hostMaintenanceStatus             = hostMaintenance.Command("status", "show the maintenance state of a list of hosts")
+	hostMaintenanceStatusHostnames    = hostMaintenanceStatus.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostMaintenanceStatusFile         = hostMaintenanceStatus.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+	hostMaintenanceStatusWatch        = hostMaintenanceStatus.Flag("watch", "print host state transitions until all hosts are UP or DOWN").Short('w').Default("false").Bool()
+	hostMaintenanceStatusWatchTimeout = hostMaintenanceStatus.Flag("watch-timeout", "stop watching after this duration, never if 0").Default("0s").Duration()
+
+	hostMaintenanceDeadLetters = hostMaintenance.Command("dead-letters", "list hosts which failed to drain and are in the maintenance dead-letter queue")
+
+	hostMaintenanceRedrive          = hostMaintenance.Command("redrive", "move hosts from the maintenance dead-letter queue back into the maintenance queue")
+	hostMaintenanceRedriveHostnames = hostMaintenanceRedrive.Arg("hostnames", "comma separated hostnames, all dead-lettered hosts if not specified").Default("").String()
+
+	hostMaintenanceFreeze   = hostMaintenance.Command("freeze", "freeze maintenance, queuing start maintenance requests until released")
+	hostMaintenanceUnfreeze = hostMaintenance.Command("unfreeze", "unfreeze maintenance, without releasing the queued start maintenance requests")
+	hostMaintenancePending  = hostMaintenance.Command("pending", "list the start maintenance requests queued while maintenance is frozen")
+
+	hostMaintenanceRelease          = hostMaintenance.Command("release", "start maintenance on hosts queued while maintenance is frozen")
+	hostMaintenanceReleaseHostnames = hostMaintenanceRelease.Arg("hostnames", "comma separated hostnames, all queued hosts if not specified").Default("").String()
+	hostMaintenanceReleaseDiscard   = hostMaintenanceRelease.Flag("discard", "discard the queued hosts instead of starting maintenance").Default("false").Bool()
+
+	hostMaintenanceUpdate          = hostMaintenance.Command("update", "change the maintenance window of DRAINING hosts")
+	hostMaintenanceUpdateHostnames = hostMaintenanceUpdate.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostMaintenanceUpdateFile      = hostMaintenanceUpdate.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+	hostMaintenanceUpdateStart     = hostMaintenanceUpdate.Flag("start", "new start of the maintenance window in RFC3339 format, the current start if not set").Default("").String()
+	hostMaintenanceUpdateStartIn   = hostMaintenanceUpdate.Flag("start-in", "new start of the maintenance window relative to now").Default("0s").Duration()
+	hostMaintenanceUpdateDuration  = hostMaintenanceUpdate.Flag("duration", "duration of the maintenance window, until maintenance is completed if 0").Default("0s").Duration()
+
+	hostMaintenanceApprove          = hostMaintenance.Command("approve", "approve the maintenance of hosts started with require approval")
+	hostMaintenanceApproveHostnames = hostMaintenanceApprove.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostMaintenanceApproveFile      = hostMaintenanceApprove.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+
+	hostMaintenanceApprovals        = hostMaintenance.Command("approvals", "list the approvals of the hosts in maintenance which require one")
+	hostMaintenanceApprovalsPending = hostMaintenanceApprovals.Flag("pending", "only list the approvals not given yet").Default("false").Bool()
+
+	hostMaintenanceCanaries = hostMaintenance.Command("canaries", "list the canary drains started by start maintenance")
+
+	hostMaintenancePolicies = hostMaintenance.Command("policies", "list the maintenance policies referenced by start maintenance")
+
+	hostMaintenanceHistory         = hostMaintenance.Command("history", "list the archived and recent maintenance state transitions of a host")
+	hostMaintenanceHistoryHostname = hostMaintenanceHistory.Arg("hostname", "hostname").Required().String()
+
+	hostReservation = host.Command("reservation", "manage dynamic reservations and persistent volumes on hosts")
+
+	hostReservationCreate           = hostReservation.Command("create", "dynamically reserve resources on a host for a role")
+	hostReservationCreateHostname   = hostReservationCreate.Arg("hostname", "host to reserve the resources on").Required().String()
+	hostReservationCreateRole       = hostReservationCreate.Arg("role", "role to reserve the resources for").Required().String()
+	hostReservationCreateCPU        = hostReservationCreate.Flag("cpu", "CPUs to reserve").Default("0").Float64()
+	hostReservationCreateMem        = hostReservationCreate.Flag("mem", "memory in MB to reserve").Default("0").Float64()
+	hostReservationCreateDisk       = hostReservationCreate.Flag("disk", "disk in MB to reserve").Default("0").Float64()
+	hostReservationCreateGPU        = hostReservationCreate.Flag("gpu", "GPUs to reserve").Default("0").Float64()
+	hostReservationCreateVolumePath = hostReservationCreate.Flag("volume", "container path of a persistent volume to create on the reserved disk").Default("").String()
+	hostReservationCreateJobID      = hostReservationCreate.Flag("job", "stateful job allowed to launch on the reserved resources").Default("").String()
+	hostReservationCreateInstanceID = hostReservationCreate.Flag("instance", "instance of the stateful job allowed to launch on the reserved resources").Default("0").Uint32()
+
+	hostReservationList          = hostReservation.Command("list", "list dynamic reservations")
+	hostReservationListHostnames = hostReservationList.Arg("hostnames", "comma separated hostnames, all registered hosts if not specified").Default("").String()
+	hostReservationListRole      = hostReservationList.Flag("role", "only list the reservations of the role").Default("").String()
+
+	hostReservationRelease              = hostReservation.Command("release", "release a dynamic reservation, destroying its persistent volume if any")
+	hostReservationReleaseHostname      = hostReservationRelease.Arg("hostname", "host the reservation was made on").Required().String()
+	hostReservationReleaseReservationID = hostReservationRelease.Arg("reservation", "reservation identifier").Required().String()
+
+	hostCordon          = host.Command("cordon", "cordon hosts, so that no new task is placed on them")
+	hostCordonHostnames = hostCordon.Arg("hostnames", "comma separated hostnames").Required().String()
+	hostCordonReason    = hostCordon.Flag("reason", "reason the hosts are cordoned for").Default("").String()
+
+	hostUncordon          = host.Command("uncordon", "uncordon hosts, making them available again for new tasks")
+	hostUncordonHostnames = hostUncordon.Arg("hostnames", "comma separated hostnames").Required().String()
+
+	hostCordoned = host.Command("cordoned", "list the cordoned hosts")
+
+	hostEvents         = host.Command("events", "list the timeline of the events of a host")
+	hostEventsHostname = hostEvents.Arg("hostname", "hostname").Required().String()
+	hostEventsSince    = hostEvents.Flag("since", "only list the events of this last duration, all retained events if 0").Default("0s").Duration()
+
+	hostDecommission          = host.Command("decommission", "terminate the machines of DOWN hosts with the host provider")
+	hostDecommissionHostnames = hostDecommission.Arg("hostnames", "comma separated hostnames").Default("").String()
+	hostDecommissionFile      = hostDecommission.Flag("file", "file with one hostname per line").Short('f').Default("").String()
+
+	hostInventory             = host.Command("inventory", "export and import the host pools and labels of hosts")
+	hostInventoryExport       = hostInventory.Command("export", "export the hostname, IP, state, pool and labels of the hosts")
+	hostInventoryExportFormat = hostInventoryExport.Flag("format", "inventory format: json or csv").Default("json").Enum("json", "csv")
+	hostInventoryExportOutput = hostInventoryExport.Flag("output", "file to write the inventory to, stdout if not set").Short('o').Default("").String()
+	hostInventoryImport       = hostInventory.Command("import", "assign the pools and labels of an inventory file to the hosts")
+	hostInventoryImportFile   = hostInventoryImport.Arg("file", "inventory file").Required().ExistingFile()
+	hostInventoryImportFormat = hostInventoryImport.Flag("format", "inventory format: json or csv").Default("json").Enum("json", "csv")
 
 	hostQuery       = host.Command("query", "query hosts by state(s)")
 	hostQueryStates = hostQuery.Flag("states", "host state(s) to filter").Default("").Short('s').String()
+	hostQueryTasks  = hostQuery.Flag("task-details", "print the tasks still running on draining hosts").Default("false").Bool()
+
+	hostAPI = host.Command("api", "print the API versions and procedures served by host manager")
+
+	hostList        = host.Command("list", "list hosts filtered by state, pool and labels")
+	hostListStates  = hostList.Flag("states", "comma separated host states to filter, e.g. up,draining").Default("").Short('s').String()
+	hostListPools   = hostList.Flag("pools", "comma separated host pools to filter").Default("").Short('p').String()
+	hostListLabels  = hostList.Flag("labels", "labels the hosts must all have (key=value pairs, comma separated)").Default("").Short('l').String()
+	hostListColumns = hostList.Flag("columns", "comma separated table columns out of hostname,ip,state,pool,cpus,mem,disk,gpus,labels").Default("hostname,ip,state,pool,cpus,mem,disk,gpus").Short('c').String()
+	hostListOutput  = hostList.Flag("output", "output format: table, json or yaml").Default("table").Short('o').Enum("table", "json", "yaml")
 
 	// Top level volume command
 	volume = app.Command("volume", "manage persistent volume")
@@ -604,12 +757,31 @@ var (
 	// command for list offers
 	offers = hostmgr.Command("offers", "list all outstanding offers")
 
+	// command for list offers per host
+	hostOffers          = hostmgr.Command("host-offers", "list the offers, status and hold expiry of hosts in offer pool")
+	hostOffersHostnames = hostOffers.Arg("hostnames", "comma separated hostnames, all hosts if not specified").Default("").String()
+
+	// command for scoring the hosts in offer pool for a task config
+	hostScore          = hostmgr.Command("score-hosts", "score the hosts in offer pool for the task config of a job, and show why the other hosts are rejected")
+	hostScoreConfig    = hostScore.Arg("config", "YAML job configuration").Required().ExistingFile()
+	hostScoreInstance  = hostScore.Flag("instance", "instance whose task config is scored, the default config if negative").Default("-1").Int()
+	hostScoreHostPools = hostScore.Flag("host-pools", "comma separated host pools the hosts must belong to, in order of preference").Default("").String()
+	hostScoreLimit     = hostScore.Flag("limit", "maximum number of candidate hosts shown, all if 0").Default("10").Uint32()
+
+	// command for listing the tasks running on hosts
+	hostTasks          = hostmgr.Command("host-tasks", "list the tasks running on hosts from the task-to-host index")
+	hostTasksHostnames = hostTasks.Arg("hostnames", "comma separated hostnames, all hosts if not specified").Default("").String()
+
+	// command for reporting cluster capacity by maintenance state and host pool
+	clusterCapacity = hostmgr.Command("cluster-capacity", "show total, allocated, draining and down capacity by host pool")
+
 	// command for listing hosts
 	getHosts          = hostmgr.Command("hosts", "list all hosts matching the query")
 	getHostsCPU       = getHosts.Flag("cpu", "compare cpu cores available at the host, ignore if not provided").Short('c').Default("0").Float64()
 	getHostsGPU       = getHosts.Flag("gpu", "compare gpu cores available at the host, ignore if not provided").Short('g').Default("0").Float64()
 	getHostsCmpLess   = getHosts.Flag("less", "list hosts with resources less than cpu and/or gpu cores specified (default to greater than and equal to if not specified)").Short('l').Default("false").Bool()
 	getHostsHostnames = getHosts.Flag("hosts", "filter the hosts based on the comma separated hostnames provided").String()
+	getHostsRevocable = getHosts.Flag("revocable", "compare the cpu and/or gpu cores with the revocable resources available at the host").Short('r').Default("false").Bool()
 
 	// command for list status update events present in the event stream
 	eventStream = hostmgr.Command("events", "list all the task status update events present in event stream")
@@ -756,7 +928,30 @@ func main() {
 		basicAuthConfigPtr = &basicAuthConfig
 	}
 
-	client, err := pc.New(discovery, *timeout, basicAuthConfigPtr, *jsonFormat)
+	var hostServiceTLSPtr *pc.HostServiceTLS
+	if *hostMgrTLSPort != 0 {
+		reloader, err := tlsconfig.NewReloader(tlsconfig.Config{
+			Enabled:  true,
+			CertFile: *tlsCertFile,
+			KeyFile:  *tlsKeyFile,
+			CAFile:   *tlsCAFile,
+		}, tally.NoopScope)
+		if err != nil {
+			app.FatalIfError(err, "Fail to load TLS config")
+		}
+		hostServiceTLSPtr = &pc.HostServiceTLS{
+			Port:   *hostMgrTLSPort,
+			Config: reloader.ClientConfig(),
+		}
+	}
+
+	client, err := pc.New(
+		discovery,
+		*timeout,
+		basicAuthConfigPtr,
+		hostServiceTLSPtr,
+		*jsonFormat,
+	)
 	if err != nil {
 		app.FatalIfError(err, "Fail to initialize client")
 	}
@@ -797,6 +992,8 @@ func main() {
 		err = client.JobGetCacheAction(*jobGetCacheName)
 	case jobGetActiveJobs.FullCommand():
 		err = client.JobGetActiveJobsAction()
+	case jobCheckConstraints.FullCommand():
+		err = client.JobCheckConstraintsAction(*jobCheckConstraintsConfig, *jobCheckConstraintsSpec)
 	case taskGet.FullCommand():
 		err = client.TaskGetAction(*taskGetJobName, *taskGetInstanceID)
 	case taskGetCache.FullCommand():
@@ -819,11 +1016,109 @@ func main() {
 	case taskRestart.FullCommand():
 		err = client.TaskRestartAction(*taskRestartJobName, *taskRestartInstanceRanges)
 	case hostMaintenanceStart.FullCommand():
-		err = client.HostMaintenanceStartAction(*hostMaintenanceStartHostnames)
+		err = client.HostMaintenanceStartAction(
+			*hostMaintenanceStartHostnames,
+			*hostMaintenanceStartFile,
+			*hostMaintenanceStartAgentIDs,
+			*hostMaintenanceStartGracePeriod,
+			*hostMaintenanceStartMessage,
+			*hostMaintenanceStartLabels,
+			*hostMaintenanceStartDrainMethod,
+			*hostMaintenanceStartApproval,
+			*hostMaintenanceStartCanaryCount,
+			*hostMaintenanceStartCanaryWait,
+			*hostMaintenanceStartCanaryRate,
+			*hostMaintenanceStartDrainTimeout,
+			*hostMaintenanceStartPolicy,
+			*hostMaintenanceStartExemptTypes,
+			*hostMaintenanceStartExemptLabels,
+			*hostMaintenanceStartExemptCtrls,
+			*hostMaintenanceStartDryRun,
+			*hostMaintenanceStartWatch,
+			*hostMaintenanceStartWatchTimeout)
 	case hostMaintenanceComplete.FullCommand():
-		err = client.HostMaintenanceCompleteAction(*hostMaintenanceCompleteHostnames)
+		err = client.HostMaintenanceCompleteAction(
+			*hostMaintenanceCompleteHostnames,
+			*hostMaintenanceCompleteFile,
+			*hostMaintenanceCompleteAgentIDs,
+			*hostMaintenanceCompleteReboot,
+			*hostMaintenanceCompleteDryRun,
+			*hostMaintenanceCompleteWatch,
+			*hostMaintenanceCompleteWatchTimeout)
+	case hostMaintenanceStatus.FullCommand():
+		err = client.HostMaintenanceStatusAction(
+			*hostMaintenanceStatusHostnames,
+			*hostMaintenanceStatusFile,
+			*hostMaintenanceStatusWatch,
+			*hostMaintenanceStatusWatchTimeout)
+	case hostMaintenanceDeadLetters.FullCommand():
+		err = client.HostMaintenanceDeadLettersAction()
+	case hostMaintenanceRedrive.FullCommand():
+		err = client.HostMaintenanceRedriveAction(*hostMaintenanceRedriveHostnames)
+	case hostMaintenanceFreeze.FullCommand():
+		err = client.HostMaintenanceFreezeAction(true)
+	case hostMaintenanceUnfreeze.FullCommand():
+		err = client.HostMaintenanceFreezeAction(false)
+	case hostMaintenancePending.FullCommand():
+		err = client.HostMaintenancePendingAction()
+	case hostMaintenanceRelease.FullCommand():
+		err = client.HostMaintenanceReleaseAction(
+			*hostMaintenanceReleaseHostnames,
+			*hostMaintenanceReleaseDiscard)
+	case hostMaintenanceUpdate.FullCommand():
+		err = client.HostMaintenanceUpdateAction(
+			*hostMaintenanceUpdateHostnames,
+			*hostMaintenanceUpdateFile,
+			*hostMaintenanceUpdateStart,
+			*hostMaintenanceUpdateStartIn,
+			*hostMaintenanceUpdateDuration)
+	case hostMaintenanceApprove.FullCommand():
+		err = client.HostMaintenanceApproveAction(
+			*hostMaintenanceApproveHostnames,
+			*hostMaintenanceApproveFile)
+	case hostMaintenanceApprovals.FullCommand():
+		err = client.HostMaintenanceApprovalsAction(*hostMaintenanceApprovalsPending)
+	case hostMaintenanceCanaries.FullCommand():
+		err = client.HostMaintenanceCanariesAction()
+	case hostMaintenancePolicies.FullCommand():
+		err = client.HostMaintenancePoliciesAction()
+	case hostMaintenanceHistory.FullCommand():
+		err = client.HostMaintenanceHistoryAction(*hostMaintenanceHistoryHostname)
+	case hostReservationCreate.FullCommand():
+		err = client.HostReservationCreateAction(
+			*hostReservationCreateHostname,
+			*hostReservationCreateRole,
+			*hostReservationCreateCPU,
+			*hostReservationCreateMem,
+			*hostReservationCreateDisk,
+			*hostReservationCreateGPU,
+			*hostReservationCreateVolumePath,
+			*hostReservationCreateJobID,
+			*hostReservationCreateInstanceID)
+	case hostReservationList.FullCommand():
+		err = client.HostReservationListAction(*hostReservationListHostnames, *hostReservationListRole)
+	case hostReservationRelease.FullCommand():
+		err = client.HostReservationReleaseAction(*hostReservationReleaseHostname, *hostReservationReleaseReservationID)
+	case hostCordon.FullCommand():
+		err = client.HostCordonAction(*hostCordonHostnames, *hostCordonReason)
+	case hostUncordon.FullCommand():
+		err = client.HostUncordonAction(*hostUncordonHostnames)
+	case hostCordoned.FullCommand():
+		err = client.HostCordonedAction()
+	case hostEvents.FullCommand():
+		err = client.HostEventsAction(*hostEventsHostname, *hostEventsSince)
+	case hostDecommission.FullCommand():
+		err = client.HostDecommissionAction(*hostDecommissionHostnames, *hostDecommissionFile)
+	case hostInventoryExport.FullCommand():
+		err = client.HostInventoryExportAction(*hostInventoryExportFormat, *hostInventoryExportOutput)
+	case hostInventoryImport.FullCommand():
+		err = client.HostInventoryImportAction(*hostInventoryImportFile, *hostInventoryImportFormat)
 	case hostQuery.FullCommand():
-		err = client.HostQueryAction(*hostQueryStates)
+		err = client.HostQueryAction(*hostQueryStates, *hostQueryTasks)
+	case hostAPI.FullCommand():
+		err = client.HostAPIAction()
+	case hostList.FullCommand():
+		err = client.HostListAction(*hostListStates, *hostListPools, *hostListLabels, *hostListColumns, *hostListOutput)
 	case resMgrActiveTasks.FullCommand():
 		err = client.ResMgrGetActiveTasks(*resMgrActiveTasksGetJobName, *resMgrActiveTasksGetRespoolID, *resMgrActiveTasksGetStates)
 	case resMgrPendingTasks.FullCommand():
@@ -870,8 +1165,20 @@ func main() {
 		err = client.UpdateResumeAction(*updateResumeID, *updateResumeOpaqueData)
 	case offers.FullCommand():
 		err = client.OffersGetAction()
+	case hostOffers.FullCommand():
+		err = client.HostOffersGetAction(*hostOffersHostnames)
+	case hostScore.FullCommand():
+		err = client.HostScoreAction(
+			*hostScoreConfig,
+			*hostScoreInstance,
+			*hostScoreHostPools,
+			*hostScoreLimit)
+	case hostTasks.FullCommand():
+		err = client.HostTasksGetAction(*hostTasksHostnames)
+	case clusterCapacity.FullCommand():
+		err = client.ClusterCapacityGetAction()
 	case getHosts.FullCommand():
-		err = client.HostsGetAction(*getHostsCPU, *getHostsGPU, *getHostsCmpLess, *getHostsHostnames)
+		err = client.HostsGetAction(*getHostsCPU, *getHostsGPU, *getHostsCmpLess, *getHostsHostnames, *getHostsRevocable)
 	case podGetEvents.FullCommand():
 		err = client.PodGetEventsAction(*podGetEventsJobName, *podGetEventsInstanceID, *podGetEventsRunID, *podGetEventsLimit)
 	case podGetCache.FullCommand():
@@ -17,11 +17,16 @@ package main
 import (
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
 
+	"github.com/uber/peloton/pkg/auth"
+	auth_impl "github.com/uber/peloton/pkg/auth/impl"
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/background"
 	"github.com/uber/peloton/pkg/common/backoff"
@@ -32,9 +37,15 @@ import (
 	"github.com/uber/peloton/pkg/common/logging"
 	"github.com/uber/peloton/pkg/common/metrics"
 	"github.com/uber/peloton/pkg/common/rpc"
+	"github.com/uber/peloton/pkg/common/tlsconfig"
 	"github.com/uber/peloton/pkg/hostmgr"
 	bin_packing "github.com/uber/peloton/pkg/hostmgr/binpacking"
+	"github.com/uber/peloton/pkg/hostmgr/consistency"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/faults"
 	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/hostprovider"
 	"github.com/uber/peloton/pkg/hostmgr/hostsvc"
 	"github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
@@ -43,7 +54,12 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/offer"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
+	"github.com/uber/peloton/pkg/hostmgr/reload"
+	"github.com/uber/peloton/pkg/hostmgr/starvation"
 	"github.com/uber/peloton/pkg/hostmgr/task"
+	"github.com/uber/peloton/pkg/middleware/inbound"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+	"github.com/uber/peloton/pkg/storage/orm"
 	"github.com/uber/peloton/pkg/storage/stores"
 
 	log "github.com/sirupsen/logrus"
@@ -53,6 +69,14 @@ import (
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
+// _hostmgrLeaderOutbound is the outbound to the host manager leader,
+// used by followers to proxy host service calls
+const _hostmgrLeaderOutbound = "peloton-hostmgr-leader"
+
+// _hostmgrFederationOutbound prefixes the outbounds to the host managers
+// of the other clusters of the federation, suffixed by their cluster
+const _hostmgrFederationOutbound = "peloton-hostmgr-federation-"
+
 var (
 	version string
 	app     = kingpin.New("peloton-hostmgr", "Peloton Host Manager")
@@ -102,6 +126,12 @@ var (
 		Envar("GRPC_PORT").
 		Int()
 
+	grpcTLSPort = app.Flag(
+		"grpc-tls-port", "Host manager GRPC TLS port (hostmgr.grpc_tls_port override) "+
+			"(set $GRPC_TLS_PORT to override)").
+		Envar("GRPC_TLS_PORT").
+		Int()
+
 	zkPath = app.Flag(
 		"zk-path",
 		"Zookeeper path (mesos.zk_host override) (set $MESOS_ZK_PATH to override)").
@@ -176,6 +206,28 @@ var (
 		"bin_packing", "Bin Packing enable/disable, by default disabled.").
 		Envar("BIN_PACKING").
 		String()
+
+	prometheusListenAddress = app.Flag(
+		"prometheus-listen-address",
+		"Serve prometheus metrics on a dedicated address, e.g. :9100 "+
+			"(metrics.prometheus.listen_address override) "+
+			"(set $PROMETHEUS_LISTEN_ADDRESS to override)").
+		Envar("PROMETHEUS_LISTEN_ADDRESS").
+		String()
+
+	authType = app.Flag(
+		"auth-type",
+		"Define the auth type used for the host service, default to NOOP").
+		Default("NOOP").
+		Envar("AUTH_TYPE").
+		Enum("NOOP", "BASIC", "EXTERNAL")
+
+	authConfigFile = app.Flag(
+		"auth-config-file",
+		"config file for the auth feature, which is specific to the auth type used").
+		Default("").
+		Envar("AUTH_CONFIG_FILE").
+		String()
 )
 
 func main() {
@@ -211,6 +263,10 @@ func main() {
 		cfg.HostManager.GRPCPort = *grpcPort
 	}
 
+	if *grpcTLSPort != 0 {
+		cfg.HostManager.GRPCTLSPort = *grpcTLSPort
+	}
+
 	if *zkPath != "" {
 		cfg.Mesos.ZkPath = *zkPath
 	}
@@ -274,11 +330,31 @@ func main() {
 		cfg.Mesos.Framework.RevocableResourcesSupported = *enableRevocableResources
 	}
 
+	// Revocable resources are only offered by Mesos to frameworks with
+	// the revocable resources capability, so there is no slack capacity
+	// to track when it is disabled.
+	if !cfg.Mesos.Framework.RevocableResourcesSupported &&
+		len(cfg.HostManager.SlackResourceTypes) > 0 {
+		log.WithField("slack_resource_types", cfg.HostManager.SlackResourceTypes).
+			Warn("Ignoring slack resource types as revocable resources are disabled")
+		cfg.HostManager.SlackResourceTypes = nil
+	}
+
 	if *binPacking != "" {
 		log.Info("Bin Packing is enabled")
 		cfg.HostManager.BinPacking = *binPacking
 	}
 
+	if *prometheusListenAddress != "" {
+		if cfg.Metrics.Prometheus == nil {
+			cfg.Metrics.Prometheus = &metrics.PrometheusConfig{}
+		}
+		// Prometheus is only served by the multi reporter
+		cfg.Metrics.MultiReporter = true
+		cfg.Metrics.Prometheus.Enable = true
+		cfg.Metrics.Prometheus.ListenAddress = *prometheusListenAddress
+	}
+
 	log.WithField("config", cfg).Debug("Loaded Host Manager config")
 
 	rootScope, scopeCloser, mux := metrics.InitMetricScope(
@@ -312,6 +388,17 @@ func main() {
 	rootScope.Counter("boot").Inc(1)
 
 	store := stores.MustCreateStore(&cfg.Storage, rootScope)
+	ormStore, ormErr := ormobjects.NewCassandraStore(
+		&cfg.Storage.Cassandra,
+		rootScope)
+	if ormErr != nil {
+		log.WithError(ormErr).Fatal("Failed to create ORM store for Cassandra")
+	}
+	// writes the storage objects buffered for asynchronous writes
+	defer ormStore.Close()
+	mux.HandleFunc(orm.StatsPath, ormStore.StatsHandler())
+	mux.HandleFunc(orm.SlowQueriesPath, ormStore.SlowQueriesHandler())
+	mux.HandleFunc(orm.PoolPath, ormStore.PoolHandler())
 
 	authHeader, err := mesos.GetAuthHeader(&cfg.Mesos, *mesosSecretFile)
 	if err != nil {
@@ -339,15 +426,36 @@ func main() {
 	)
 
 	// MasterOperatorClient API outbound
+	operatorScheme := "http"
+	operatorOptions := []mhttp.OutboundOption{
+		mhttp.MaxConnectionsPerHost(cfg.Mesos.Framework.MaxConnectionsToMesosMaster),
+	}
+	if cfg.Mesos.OperatorTLS.Enabled {
+		operatorTLS, err := tlsconfig.NewReloader(
+			cfg.Mesos.OperatorTLS,
+			rootScope.SubScope("mesos_operator"),
+		)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot load Mesos operator TLS config")
+		}
+		operatorTLS.Start()
+		defer operatorTLS.Stop()
+
+		operatorScheme = "https"
+		operatorOptions = append(
+			operatorOptions,
+			mhttp.TLSConfig(operatorTLS.ClientConfig),
+		)
+	}
 	mOperatorOutbound := mhttp.NewOutbound(
 		rootScope,
 		mesosMasterDetector,
 		url.URL{
-			Scheme: "http",
+			Scheme: operatorScheme,
 			Path:   common.MesosMasterOperatorEndPoint,
 		},
 		authHeader,
-		mhttp.MaxConnectionsPerHost(cfg.Mesos.Framework.MaxConnectionsToMesosMaster),
+		operatorOptions...,
 	)
 
 	// All leader discovery metrics share a scope (and will be tagged
@@ -382,6 +490,74 @@ func main() {
 		},
 	}
 
+	// In follower mode, the host service of a follower proxies read-only
+	// calls to the leader. The leader serves the host service on the TLS
+	// port when TLS is enabled, which the outbound does not support.
+	followerProxy := cfg.HostManager.FollowerProxy
+	if followerProxy && cfg.HostManager.TLS.Enabled {
+		log.Warn("Follower mode is not supported with TLS, disabling it")
+		followerProxy = false
+	}
+	if followerProxy {
+		hostmgrPeerChooser, err := peer.NewSmartChooser(
+			cfg.Election,
+			discoveryScope,
+			common.HostManagerRole,
+			t,
+		)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err, "role": common.HostManagerRole}).
+				Fatal("Could not create smart peer chooser")
+		}
+		defer hostmgrPeerChooser.Stop()
+
+		outbounds[_hostmgrLeaderOutbound] = transport.Outbounds{
+			ServiceName: common.PelotonHostManager,
+			Unary:       t.NewOutbound(hostmgrPeerChooser),
+		}
+	}
+
+	// Federated host queries call the host managers of the other
+	// clusters of the federation
+	for _, federationPeer := range cfg.HostManager.Federation.Peers {
+		if federationPeer.Cluster == "" || federationPeer.Address == "" {
+			log.WithField("peer", federationPeer).
+				Fatal("Federation peer needs a cluster and an address")
+		}
+		outbounds[_hostmgrFederationOutbound+federationPeer.Cluster] = transport.Outbounds{
+			ServiceName: common.PelotonHostManager,
+			Unary:       t.NewSingleOutbound(federationPeer.Address),
+		}
+	}
+
+	securityManager, err := auth_impl.CreateNewSecurityManager(
+		auth.Type(*authType),
+		*authConfigFile,
+	)
+	if err != nil {
+		log.WithError(err).
+			Fatal("Could not enable security feature")
+	}
+	log.WithFields(log.Fields{
+		"auth_type":        *authType,
+		"auth_config_file": *authConfigFile,
+	}).Info("Loaded auth config")
+
+	// Only the host service is authorized. The internal host service
+	// is called by the other Peloton components, and the Mesos
+	// inbound by Mesos master.
+	authInboundManager := inbound.NewAuthInboundMiddleware(
+		securityManager,
+		hostsvc.ServiceName,
+	)
+
+	// The metrics middleware comes first so that denied calls are measured
+	// as well, followed by the audit middleware so that they are audited
+	inboundMiddleware := inbound.Chain(
+		hostsvc.NewInboundMiddleware(rootScope),
+		inbound.NewAuditInboundMiddleware(hostmgr.IsMutatingProcedure),
+		authInboundManager,
+	)
 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
 		Name:      common.PelotonHostManager,
 		Inbounds:  inbounds,
@@ -389,8 +565,48 @@ func main() {
 		Metrics: yarpc.MetricsConfig{
 			Tally: rootScope,
 		},
+		InboundMiddleware: yarpc.InboundMiddleware{
+			Unary:  inboundMiddleware,
+			Oneway: inboundMiddleware,
+			Stream: inboundMiddleware,
+		},
 	})
 
+	// When TLS is enabled, the host service which exposes the maintenance
+	// APIs is served by a separate dispatcher only accepting TLS
+	// connections, while the other Peloton components keep calling the
+	// internal host service on the plaintext ports.
+	hostsvcDispatcher := dispatcher
+	if cfg.HostManager.TLS.Enabled {
+		inboundTLS, err := tlsconfig.NewReloader(
+			cfg.HostManager.TLS,
+			rootScope.SubScope("hostsvc"),
+		)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot load host service TLS config")
+		}
+		inboundTLS.Start()
+		defer inboundTLS.Stop()
+
+		hostsvcDispatcher = yarpc.NewDispatcher(yarpc.Config{
+			Name: common.PelotonHostManager,
+			Inbounds: []transport.Inbound{
+				rpc.NewTLSInbound(
+					cfg.HostManager.GRPCTLSPort,
+					inboundTLS.ServerConfig(),
+				),
+			},
+			Metrics: yarpc.MetricsConfig{
+				Tally: rootScope,
+			},
+			InboundMiddleware: yarpc.InboundMiddleware{
+				Unary:  inboundMiddleware,
+				Oneway: inboundMiddleware,
+				Stream: inboundMiddleware,
+			},
+		})
+	}
+
 	// Init the managers driven by the mesos callbacks.
 	// They are driven by the leader who will subscribe to
 	// Mesos callbacks
@@ -405,10 +621,41 @@ func main() {
 		cfg.Mesos.Encoding,
 	)
 
+	// Faults are injected on demand into the calls of Mesos Master, the
+	// agent map and the maintenance queue, to test the resilience of the
+	// maintenance flows
+	var faultInjector *faults.Injector
+	if cfg.HostManager.FaultInjection {
+		log.Warn("Fault injection is enabled")
+		faultInjector = faults.NewInjector(rootScope)
+		masterOperatorClient = faults.NewOperatorClient(
+			masterOperatorClient, faultInjector)
+		mux.HandleFunc(faults.Path, faultInjector.Handler())
+	}
+
+	maintenanceHostInfoMap := host.NewMaintenanceHostInfoMap(rootScope)
+	// Host state changes, offers and agent churn are published on the
+	// event bus, so that consumers subscribe without touching producers.
+	eventBus := eventbus.NewBus(rootScope)
+	attributeWatcher := host.NewAttributeWatcher(rootScope, eventBus)
+
+	loader := host.Loader{
+		OperatorClient:         masterOperatorClient,
+		Scope:                  rootScope.SubScope("hostmap"),
+		SlackResourceTypes:     cfg.HostManager.SlackResourceTypes,
+		MaintenanceHostInfoMap: maintenanceHostInfoMap,
+		AttributeWatcher:       attributeWatcher,
+		EventBus:               eventBus,
+	}
+	if faultInjector != nil {
+		loader.DropUpdate = faultInjector.DropAgentMapUpdate
+	}
+
 	mesos.InitManager(
 		dispatcher,
 		&cfg.Mesos,
 		store, // store implements FrameworkInfoStore
+		&loader,
 	)
 
 	log.WithFields(log.Fields{
@@ -426,22 +673,13 @@ func main() {
 		cfg.HostManager.TaskReconcilerConfig,
 	)
 
-	maintenanceHostInfoMap := host.NewMaintenanceHostInfoMap(rootScope)
-
-	loader := host.Loader{
-		OperatorClient:         masterOperatorClient,
-		Scope:                  rootScope.SubScope("hostmap"),
-		SlackResourceTypes:     cfg.HostManager.SlackResourceTypes,
-		MaintenanceHostInfoMap: maintenanceHostInfoMap,
-	}
-
 	backgroundManager := background.NewManager()
 	// Retry on hostmap loader with Background Manager.
 	err = backoff.Retry(
 		func() error {
 			return backgroundManager.RegisterWorks(
 				background.Work{
-					Name:   "hostmap",
+					Name:   reload.HostmapWork,
 					Func:   loader.Load,
 					Period: cfg.HostManager.HostmapRefreshInterval,
 				},
@@ -478,6 +716,42 @@ func main() {
 
 	bin_packing.Init()
 	log.Infof(" %s Bin Packing is enabled", cfg.HostManager.BinPacking)
+
+	// Placement resolves the host pool of the offers with the
+	// same attribute as the cluster capacity reports.
+	host.SetHostPoolAttribute(cfg.HostManager.HostPoolAttribute)
+
+	declinepolicy.Init()
+	declinePolicy := declinepolicy.CreatePolicy(
+		cfg.HostManager.DeclinePolicy,
+		rootScope)
+	if declinePolicy == nil {
+		log.WithField("decline_policy", cfg.HostManager.DeclinePolicy).
+			Fatal("Cannot create offer decline policy.")
+	}
+
+	// The offer starvation detector flags the hosts whose offers are
+	// consistently declined, from the match results recorded through
+	// the decline policy, if enabled.
+	if cfg.HostManager.OfferStarvation.Period > 0 {
+		starvationDetector := starvation.NewDetector(
+			cfg.HostManager.OfferStarvation,
+			rootScope.SubScope("offer_starvation"))
+		if _, err := starvationDetector.Subscribe(eventBus); err != nil {
+			log.WithError(err).
+				Fatal("Cannot subscribe offer starvation detector.")
+		}
+		declinePolicy = starvation.NewDeclinePolicy(
+			declinePolicy, starvationDetector)
+		mux.HandleFunc(starvation.Path, starvationDetector.Handler())
+		backgroundManager.RegisterWorks(
+			background.Work{
+				Name:   "offerstarvationdetector",
+				Func:   starvationDetector.Refresh,
+				Period: cfg.HostManager.OfferStarvation.Period,
+			},
+		)
+	}
 	offer.InitEventHandler(
 		dispatcher,
 		rootScope,
@@ -493,15 +767,66 @@ func main() {
 		bin_packing.CreateRanker(cfg.HostManager.BinPacking),
 		cfg.HostManager.BinPackingRefreshIntervalSec,
 		cfg.HostManager.HostPlacingOfferStatusTimeout,
+		declinePolicy,
+		attributeWatcher,
+		eventBus,
 	)
 
-	maintenanceQueue := queue.NewMaintenanceQueue()
+	maintenanceQueue := queue.NewMaintenanceQueue(
+		cfg.HostManager.MaintenanceQueueMaxAttempts)
+	if faultInjector != nil {
+		maintenanceQueue = faults.NewMaintenanceQueue(
+			maintenanceQueue, faultInjector)
+	}
 
 	// Initializing TaskStateManager will start to record task status
 	// update back to storage.  TODO(zhitao): This is
 	// temporary. Eventually we should create proper API protocol for
 	// `WaitTaskStatusUpdate` and allow RM/JM to retrieve this
 	// separately.
+	hostTaskIndex := task.NewHostTaskIndex(
+		ormobjects.NewHostTasksOps(ormStore),
+		rootScope,
+	)
+	cordonMap := host.NewCordonMap(
+		ormobjects.NewHostCordonOps(ormStore),
+		rootScope,
+	)
+	assignmentMap := host.NewAssignmentMap(
+		ormobjects.NewHostAssignmentOps(ormStore),
+		rootScope,
+	)
+	approvalMap := host.NewApprovalMap(
+		ormobjects.NewMaintenanceApprovalOps(ormStore),
+		rootScope,
+	)
+	maintenanceHistory := host.NewMaintenanceHistory(
+		ormobjects.NewHostMaintenanceEventOps(ormStore),
+		ormobjects.NewHostMaintenanceHistoryOps(ormStore),
+		rootScope,
+	)
+	if _, err := host.SubscribeMaintenanceHistory(
+		eventBus,
+		maintenanceHistory,
+	); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe maintenance history to event bus")
+	}
+	hostEventLog := host.NewHostEventLog(
+		ormobjects.NewHostEventOps(ormStore),
+		rootScope,
+	)
+	if _, err := host.SubscribeHostEvents(eventBus, hostEventLog); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe host event log to event bus")
+	}
+	if _, err := host.SubscribeUnknownAgents(eventBus, &loader); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe agent map loader to event bus")
+	}
+	if _, err := host.SubscribeJobHostMap(
+		eventBus,
+		host.NewJobHostMap(rootScope),
+	); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe job host map to event bus")
+	}
 	taskStateManager := task.NewStateManager(
 		dispatcher,
 		schedulerClient,
@@ -509,8 +834,17 @@ func main() {
 		cfg.HostManager.TaskUpdateAckConcurrency,
 		resmgrsvc.NewResourceManagerServiceYARPCClient(
 			dispatcher.ClientConfig(common.PelotonResourceManager)),
+		hostTaskIndex,
+		eventBus,
 		rootScope,
 	)
+	// Publish agent attribute changes on the host event stream.
+	if _, err := task.SubscribeHostEvents(
+		eventBus,
+		taskStateManager,
+	); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe host event stream to event bus")
+	}
 
 	// Create new hostmgr internal service handler.
 	hostmgr.NewServiceHandler(
@@ -527,14 +861,10 @@ func main() {
 		cfg.HostManager.SlackResourceTypes,
 		maintenanceHostInfoMap,
 		taskStateManager,
-	)
-
-	hostsvc.InitServiceHandler(
-		dispatcher,
-		rootScope,
-		masterOperatorClient,
-		maintenanceQueue,
-		maintenanceHostInfoMap,
+		hostTaskIndex,
+		cordonMap,
+		approvalMap,
+		eventBus,
 	)
 
 	// Register background worker to start mesos task status update counter.
@@ -545,20 +875,52 @@ func main() {
 			Period:       time.Duration(1) * time.Second,
 			InitialDelay: time.Duration(1) * time.Second,
 		},
+		background.Work{
+			Name:   "hosttaskindexpersister",
+			Func:   hostTaskIndex.Persist,
+			Period: cfg.HostManager.HostTaskIndexPersistInterval,
+		},
 	)
 
+	// Register background worker checking the consistency of the hosts
+	// with Mesos master, if enabled.
+	if cfg.HostManager.ConsistencyChecker.Period > 0 {
+		checker := consistency.NewChecker(
+			cfg.HostManager.ConsistencyChecker,
+			masterOperatorClient,
+			maintenanceHostInfoMap,
+			offer.GetEventHandler().GetOfferPool(),
+			func() { loader.Load(nil) },
+			eventBus,
+			rootScope.SubScope("consistency"),
+		)
+		backgroundManager.RegisterWorks(
+			background.Work{
+				Name:   "consistencychecker",
+				Func:   checker.Check,
+				Period: cfg.HostManager.ConsistencyChecker.Period,
+			},
+		)
+	}
+
 	recoveryHandler := hostmgr.NewRecoveryHandler(
 		rootScope,
 		maintenanceQueue,
 		masterOperatorClient,
 		maintenanceHostInfoMap,
+		hostTaskIndex,
+		cordonMap,
+		assignmentMap,
+		approvalMap,
 	)
 
 	drainer := host.NewDrainer(
+		rootScope,
 		cfg.HostManager.HostDrainerPeriod,
 		masterOperatorClient,
 		maintenanceQueue,
 		maintenanceHostInfoMap,
+		eventBus,
 	)
 
 	server := hostmgr.NewServer(
@@ -573,12 +935,6 @@ func main() {
 		recoveryHandler,
 		drainer,
 	)
-	server.Start()
-
-	// Start dispatch loop
-	if err := dispatcher.Start(); err != nil {
-		log.Fatalf("Could not start rpc server: %v", err)
-	}
 
 	candidate, err := leader.NewCandidate(
 		cfg.Election,
@@ -589,6 +945,115 @@ func main() {
 	if err != nil {
 		log.Fatalf("Unable to create leader candidate: %v", err)
 	}
+
+	hostmgrDiscovery, err := leader.NewZkServiceDiscovery(
+		cfg.Election.ZKServers,
+		cfg.Election.Root,
+	)
+	if err != nil {
+		log.Fatalf("Unable to create host manager leader discovery: %v", err)
+	}
+
+	var leaderClient host_svc.HostServiceYARPCClient
+	if followerProxy {
+		leaderClient = host_svc.NewHostServiceYARPCClient(
+			dispatcher.ClientConfig(_hostmgrLeaderOutbound))
+	}
+
+	var federation *hostsvc.Federation
+	if len(cfg.HostManager.Federation.Peers) > 0 {
+		federation = &hostsvc.Federation{
+			Cluster: cfg.HostManager.Federation.Cluster,
+			Peers:   make(map[string]host_svc.HostServiceYARPCClient),
+			Timeout: cfg.HostManager.Federation.Timeout,
+		}
+		for _, federationPeer := range cfg.HostManager.Federation.Peers {
+			federation.Peers[federationPeer.Cluster] = host_svc.NewHostServiceYARPCClient(
+				dispatcher.ClientConfig(
+					_hostmgrFederationOutbound + federationPeer.Cluster))
+		}
+	}
+
+	drainMethod, err := hostsvc.ParseDrainMethod(cfg.HostManager.DrainMethod)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot parse drain method")
+	}
+
+	hostprovider.Init()
+	hostProvider, err := hostprovider.CreateProvider(cfg.HostManager.HostProvider)
+	if err != nil {
+		log.WithError(err).
+			WithField("host_provider", cfg.HostManager.HostProvider.Name).
+			Fatal("Cannot create host provider")
+	}
+
+	hostsvc.InitServiceHandler(
+		hostsvcDispatcher,
+		rootScope,
+		masterOperatorClient,
+		maintenanceQueue,
+		maintenanceHostInfoMap,
+		maintenanceHistory,
+		hostEventLog,
+		assignmentMap,
+		approvalMap,
+		hostTaskIndex,
+		eventBus,
+		ormStore,
+		candidate,
+		hostmgrDiscovery,
+		leaderClient,
+		cfg.HostManager.MaintenanceFreeze,
+		drainMethod,
+		auth.Type(*authType),
+		hostProvider,
+		federation,
+		cfg.HostManager.DefaultMaintenancePolicies,
+		resmgrsvc.NewResourceManagerServiceYARPCClient(
+			dispatcher.ClientConfig(common.PelotonResourceManager)),
+	)
+
+	// Liveness only requires the process to serve HTTP, while readiness
+	// verifies the components host manager depends on
+	prober := health.NewProber(cfg.HostManager.Readiness.CheckTimeout)
+	hostmgr.AddReadinessChecks(
+		prober,
+		cfg.HostManager.Readiness,
+		cfg.HostManager.HostmapRefreshInterval,
+		candidate,
+		mesosMasterDetector,
+		mInbound,
+		maintenanceQueue,
+		ormobjects.NewHostCordonOps(ormStore),
+	)
+	mux.HandleFunc(health.LivenessPath, prober.LivenessHandler())
+	mux.HandleFunc(health.ReadinessPath, prober.ReadinessHandler())
+
+	if cfg.HostManager.Reload.Enabled {
+		source := reload.NewFileSource(*configFiles...)
+		if cfg.HostManager.Reload.URL != "" {
+			source = reload.NewURLSource(cfg.HostManager.Reload.URL)
+		}
+		watcher := reload.NewWatcher(
+			cfg.HostManager.Reload,
+			source,
+			cfg.HostManager,
+			rootScope,
+			backgroundManager,
+			drainer,
+			maintenanceQueue,
+		)
+		watcher.Start()
+		defer watcher.Stop()
+	}
+
+	server.Start()
+
+	// Start dispatch loop
+	if err := dispatcher.Start(); err != nil {
+		log.Fatalf("Could not start rpc server: %v", err)
+	}
+
 	err = candidate.Start()
 	if err != nil {
 		log.Fatalf("Unable to start leader candidate: %v", err)
@@ -601,9 +1066,18 @@ func main() {
 	}
 	defer dispatcher.Stop()
 
+	if hostsvcDispatcher != dispatcher {
+		if err := hostsvcDispatcher.Start(); err != nil {
+			log.Fatalf("Could not start host service TLS rpc server: %v", err)
+		}
+		defer hostsvcDispatcher.Stop()
+	}
+
 	log.WithFields(log.Fields{
-		"httpPort": cfg.HostManager.HTTPPort,
-		"grpcPort": cfg.HostManager.GRPCPort,
+		"httpPort":    cfg.HostManager.HTTPPort,
+		"grpcPort":    cfg.HostManager.GRPCPort,
+		"grpcTLSPort": cfg.HostManager.GRPCTLSPort,
+		"tls":         cfg.HostManager.TLS.Enabled,
 	}).Info("Started host manager")
 
 	// we can *honestly* say the server is booted up now
@@ -615,5 +1089,9 @@ func main() {
 		cfg.Metrics.RuntimeMetrics.Enabled,
 		cfg.Metrics.RuntimeMetrics.CollectInterval)()
 
-	select {}
+	// wait for termination, so that the deferred calls stop the
+	// components and close the stores
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	log.WithField("signal", <-sig).Info("Stopping host manager")
 }
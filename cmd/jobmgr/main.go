@@ -17,6 +17,8 @@ package main
 import (
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
@@ -52,7 +54,9 @@ import (
 	"github.com/uber/peloton/pkg/jobmgr/volumesvc"
 	"github.com/uber/peloton/pkg/jobmgr/watchsvc"
 	"github.com/uber/peloton/pkg/middleware/inbound"
+	"github.com/uber/peloton/pkg/storage/cassandra"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+	"github.com/uber/peloton/pkg/storage/orm"
 	"github.com/uber/peloton/pkg/storage/stores"
 
 	log "github.com/sirupsen/logrus"
@@ -199,7 +203,7 @@ var (
 		"Define the auth type used, default to NOOP").
 		Default("NOOP").
 		Envar("AUTH_TYPE").
-		Enum("NOOP", "BASIC")
+		Enum("NOOP", "BASIC", "EXTERNAL")
 
 	authConfigFile = app.Flag(
 		"auth-config-file",
@@ -313,15 +317,24 @@ func main() {
 
 	mux.HandleFunc(buildversion.Get, buildversion.Handler(version))
 
-	// store implements JobStore, TaskStore, VolumeStore, UpdateStore
-	// and FrameworkInfoStore
-	store := stores.MustCreateStore(&cfg.Storage, rootScope)
 	ormStore, ormErr := ormobjects.NewCassandraStore(
 		&cfg.Storage.Cassandra,
 		rootScope)
 	if ormErr != nil {
 		log.WithError(ormErr).Fatal("Failed to create ORM store for Cassandra")
 	}
+	// store implements JobStore, TaskStore, VolumeStore, UpdateStore
+	// and FrameworkInfoStore, it deletes the job configs and pod events
+	// of deleted jobs in bulk through the ORM
+	store := stores.MustCreateStore(
+		&cfg.Storage,
+		rootScope,
+		cassandra.WithPartitionDeleter(ormobjects.NewPartitionDeleter(ormStore)))
+	// writes the storage objects buffered for asynchronous writes
+	defer ormStore.Close()
+	mux.HandleFunc(orm.StatsPath, ormStore.StatsHandler())
+	mux.HandleFunc(orm.SlowQueriesPath, ormStore.SlowQueriesHandler())
+	mux.HandleFunc(orm.PoolPath, ormStore.PoolHandler())
 
 	// Create both HTTP and GRPC inbounds
 	inbounds := rpc.NewInbounds(
@@ -622,7 +635,11 @@ func main() {
 		cfg.Metrics.RuntimeMetrics.Enabled,
 		cfg.Metrics.RuntimeMetrics.CollectInterval)()
 
-	select {}
+	// wait for termination, so that the deferred calls stop the
+	// components and close the stores
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	log.WithField("signal", <-sig).Info("Stopping job manager")
 }
 
 func getInboundMiddleware(middleware inbound.DispatcherInboundMiddleWare) yarpc.InboundMiddleware {
@@ -0,0 +1,212 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ormbackup exports every row of the ORM storage objects to files, and
+// restores them from these files. The exports are logical backups of the
+// storage objects, independent of the snapshots of the storage backend.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/uber/peloton/pkg/common/config"
+	storage "github.com/uber/peloton/pkg/storage/config"
+	escassandra "github.com/uber/peloton/pkg/storage/connectors/cassandra"
+	"github.com/uber/peloton/pkg/storage/objects"
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	app = kingpin.New("ormbackup", "Peloton ORM storage object export and restore")
+
+	cfgFiles = app.Flag(
+		"config",
+		"YAML config files of a Peloton component, whose storage is "+
+			"exported or restored (can be provided multiple times to merge configs)").
+		Short('c').
+		Required().
+		ExistingFiles()
+
+	objectNames = app.Flag(
+		"object",
+		"Table name of a storage object to export or restore, all the "+
+			"storage objects if not set (can be provided multiple times)").
+		Short('o').
+		Strings()
+
+	export = app.Command(
+		"export", "Export every row of the storage objects to files")
+
+	exportDir = export.Arg(
+		"dir", "Directory the export files are written to").
+		Required().
+		String()
+
+	restore = app.Command(
+		"restore", "Restore the rows of the storage objects from export files")
+
+	restoreDir = restore.Arg(
+		"dir", "Directory of the export files").
+		Required().
+		ExistingDir()
+)
+
+// Config is the storage section of the config of a Peloton component.
+type Config struct {
+	Storage storage.Config `yaml:"storage"`
+}
+
+// namedObject is a storage object along with its table name.
+type namedObject struct {
+	name   string
+	object base.Object
+}
+
+func main() {
+	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	log.SetFormatter(&log.JSONFormatter{})
+
+	var cfg Config
+	if err := config.Parse(&cfg, *cfgFiles...); err != nil {
+		log.WithError(err).Fatal("Cannot parse yaml config")
+	}
+
+	objs, err := selectObjects(*objectNames)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot select storage objects")
+	}
+
+	connector, err := escassandra.NewCassandraConnector(
+		&cfg.Storage.Cassandra, tally.NoopScope)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot connect to storage")
+	}
+
+	ctx := context.Background()
+	switch cmd {
+	case export.FullCommand():
+		scanner, ok := connector.(orm.Scanner)
+		if !ok {
+			log.Fatal("Storage connector cannot scan tables")
+		}
+		if err := os.MkdirAll(*exportDir, 0755); err != nil {
+			log.WithError(err).Fatal("Cannot create export directory")
+		}
+		for _, obj := range objs {
+			exportObject(ctx, scanner, obj, *exportDir)
+		}
+	case restore.FullCommand():
+		for _, obj := range objs {
+			restoreObject(ctx, connector, obj, *restoreDir)
+		}
+	}
+}
+
+// selectObjects returns the storage objects with the given table names,
+// or all the storage objects if no name is given.
+func selectObjects(names []string) ([]*namedObject, error) {
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	var objs []*namedObject
+	for _, obj := range objects.Objs {
+		table, err := orm.TableFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 && !selected[table.Name] {
+			continue
+		}
+		delete(selected, table.Name)
+		objs = append(objs, &namedObject{name: table.Name, object: obj})
+	}
+	for name := range selected {
+		return nil, fmt.Errorf("unknown storage object %s", name)
+	}
+	return objs, nil
+}
+
+// exportObject exports every row of the storage object to its export file
+// in the directory. The file is only replaced once the export completes.
+func exportObject(
+	ctx context.Context,
+	scanner orm.Scanner,
+	obj *namedObject,
+	dir string,
+) {
+	path := filepath.Join(dir, obj.name+orm.ExportFileSuffix)
+	logger := log.WithField("object", obj.name).WithField("path", path)
+
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		logger.WithError(err).Fatal("Cannot create export file")
+	}
+	count, err := orm.Export(ctx, scanner, obj.object, f)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		logger.WithError(err).Fatal("Cannot export storage object")
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		logger.WithError(err).Fatal("Cannot write export file")
+	}
+	logger.WithField("rows", count).Info("Storage object exported")
+}
+
+// restoreObject restores the rows of the storage object from its export
+// file in the directory. The objects which were not exported are skipped
+// unless they were selected explicitly.
+func restoreObject(
+	ctx context.Context,
+	connector orm.Connector,
+	obj *namedObject,
+	dir string,
+) {
+	path := filepath.Join(dir, obj.name+orm.ExportFileSuffix)
+	logger := log.WithField("object", obj.name).WithField("path", path)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) && len(*objectNames) == 0 {
+		logger.Info("Storage object not exported, skipped")
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Fatal("Cannot open export file")
+	}
+	defer f.Close()
+
+	count, err := orm.Restore(ctx, connector, obj.object, f)
+	if err != nil {
+		logger.WithError(err).
+			WithField("rows", count).
+			Fatal("Cannot restore storage object")
+	}
+	logger.WithField("rows", count).Info("Storage object restored")
+}
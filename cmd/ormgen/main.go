@@ -0,0 +1,57 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ormgen generates the typed stores of the storage objects of a package.
+// It is run with go generate from the package of the storage objects.
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/uber/peloton/pkg/storage/orm/ormgen"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	app = kingpin.New("ormgen", "Peloton ORM typed store generator")
+
+	dir = app.Flag(
+		"dir", "directory of the package declaring the storage objects").
+		Default(".").
+		ExistingDir()
+
+	output = app.Flag(
+		"output", "name of the generated file in the package directory").
+		Default("stores_generated.go").
+		String()
+)
+
+func main() {
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	src, err := ormgen.GenerateDir(*dir, *output)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to generate typed stores")
+	}
+	path := filepath.Join(*dir, *output)
+	if err := ioutil.WriteFile(path, src, 0644); err != nil {
+		log.WithError(err).
+			WithField("path", path).
+			Fatal("Failed to write typed stores")
+	}
+}
@@ -349,7 +349,9 @@ func main() {
 		hostmgrClient,
 		cfg.ResManager.HostDrainerPeriod,
 		task.GetTracker(),
-		preemptor)
+		preemptor,
+		maintenance.DrainOrder(cfg.ResManager.HostDrainOrder),
+		cfg.ResManager.HostDrainHintTTL)
 
 	// Initialize resource manager service handlers
 	serviceHandler := resmgr.NewServiceHandler(
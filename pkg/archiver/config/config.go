@@ -38,6 +38,10 @@ const (
 	_defaultMaxRetryAttemptsJobQuery = 3
 	// default backoff for job query
 	_defaultRetryIntervalJobQuery = 10 * time.Second
+	// archive host maintenance events over 7 days
+	_defaultHostMaintenanceArchiveAge = 168 * time.Hour
+	// keep the archived host maintenance history for 365 days
+	_defaultHostMaintenanceRetention = 8760 * time.Hour
 	// default delay when bootstrapping the archiver
 	// to account for not overloading jobmgr during recovery
 	_defaultBootstrapDelay = 180 * time.Second
@@ -103,6 +107,17 @@ type ArchiverConfig struct {
 
 	// Kafka topic used by archiver to stream jobs via filebeat
 	KafkaTopic string `yaml:"kafka_topic"`
+
+	// Flag to archive the host maintenance history
+	HostMaintenanceArchive bool `yaml:"host_maintenance_archive"`
+
+	// Minimum age of the host maintenance events to be moved to the
+	// host maintenance history, example: (7 * 24)h
+	HostMaintenanceArchiveAge time.Duration `yaml:"host_maintenance_archive_age"`
+
+	// Duration the host maintenance history is kept for,
+	// example: (365 * 24)h
+	HostMaintenanceRetention time.Duration `yaml:"host_maintenance_retention"`
 }
 
 // Normalize configuration by setting unassigned fields to default values.
@@ -131,4 +146,10 @@ func (c *ArchiverConfig) Normalize() {
 	if c.BootstrapDelay == 0 {
 		c.BootstrapDelay = _defaultBootstrapDelay
 	}
+	if c.HostMaintenanceArchiveAge == 0 {
+		c.HostMaintenanceArchiveAge = _defaultHostMaintenanceArchiveAge
+	}
+	if c.HostMaintenanceRetention == 0 {
+		c.HostMaintenanceRetention = _defaultHostMaintenanceRetention
+	}
 }
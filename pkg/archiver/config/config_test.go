@@ -33,4 +33,6 @@ func TestConfigNormalize(t *testing.T) {
 	assert.Equal(t, _defaultMaxRetryAttemptsJobQuery, c.MaxRetryAttemptsJobQuery)
 	assert.Equal(t, _defaultRetryIntervalJobQuery, c.RetryIntervalJobQuery)
 	assert.Equal(t, _defaultBootstrapDelay, c.BootstrapDelay)
+	assert.Equal(t, _defaultHostMaintenanceArchiveAge, c.HostMaintenanceArchiveAge)
+	assert.Equal(t, _defaultHostMaintenanceRetention, c.HostMaintenanceRetention)
 }
@@ -33,5 +33,10 @@ By default, the archiver engine will function like this:
 	   call the JobDelete API for this job_id
 	Outside the scope of this code, the data streamed to kafka will be ingested by
 	secondary storage like ELK or query builder.
+
+If host_maintenance_archive is set, the archiver also periodically asks hostmgr
+to move the host maintenance events older than host_maintenance_archive_age to
+the host maintenance history, and to delete the history older than
+host_maintenance_retention.
 */
 package archiver
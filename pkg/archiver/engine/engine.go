@@ -21,6 +21,7 @@ import (
 	nethttp "net/http"
 	"time"
 
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/query"
@@ -64,6 +65,10 @@ const (
 
 	// Number of pod events run to persist in DB.
 	_defaultPodEventsToConstraint = uint64(100)
+
+	// Timeout for archiving the host maintenance history, which goes
+	// through the maintenance events of every host of the cluster.
+	_hostMaintenanceArchiveTimeout = 10 * time.Minute
 )
 
 // Engine defines the interface used to query a peloton component
@@ -82,6 +87,8 @@ type engine struct {
 	jobClient job.JobManagerYARPCClient
 	// Task Manager Client to query task events.
 	taskClient task.TaskManagerYARPCClient
+	// Host Service Client to archive the host maintenance history.
+	hostClient host_svc.HostServiceYARPCClient
 	// Yarpc dispatcher
 	dispatcher *yarpc.Dispatcher
 	// Archiver config
@@ -106,6 +113,11 @@ func New(
 		return nil, err
 	}
 
+	hostmgrURL, err := discovery.GetAppURL(common.HostManagerRole)
+	if err != nil {
+		return nil, err
+	}
+
 	t := grpc.NewTransport()
 
 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
@@ -115,6 +127,9 @@ func New(
 			common.PelotonJobManager: transport.Outbounds{
 				Unary: t.NewSingleOutbound(jobmgrURL.Host),
 			},
+			common.PelotonHostManager: transport.Outbounds{
+				Unary: t.NewSingleOutbound(hostmgrURL.Host),
+			},
 		},
 		Metrics: yarpc.MetricsConfig{
 			Tally: scope,
@@ -132,6 +147,9 @@ func New(
 		taskClient: task.NewTaskManagerYARPCClient(
 			dispatcher.ClientConfig(common.PelotonJobManager),
 		),
+		hostClient: host_svc.NewHostServiceYARPCClient(
+			dispatcher.ClientConfig(common.PelotonHostManager),
+		),
 		dispatcher: dispatcher,
 		config:     cfg,
 		metrics:    NewMetrics(scope),
@@ -144,6 +162,7 @@ func New(
 // Start starts archiver with actions such as
 // 1) archive terminal batch jobs
 // 2) constraint pod events for RUNNING stateless jobs.
+// 3) archive the host maintenance history.
 // Actions are iterated sequentially to minimize the load on
 // Cassandra cluster to not impact real-time workload.
 func (e *engine) Start() error {
@@ -221,6 +240,10 @@ func (e *engine) Start() error {
 			}
 		}
 
+		if e.config.Archiver.HostMaintenanceArchive {
+			e.archiveHostMaintenance(context.Background())
+		}
+
 		jitter := time.Duration(rand.Intn(jitterMax)) * time.Millisecond
 		time.Sleep(e.config.Archiver.ArchiveInterval + jitter)
 	}
@@ -396,6 +419,36 @@ func (e *engine) deletePodEvents(
 	}
 }
 
+// archiveHostMaintenance asks hostmgr to move the old host maintenance
+// events to the host maintenance history, and to delete the history
+// past its retention. Failures are retried on the next archiver run.
+func (e *engine) archiveHostMaintenance(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, _hostMaintenanceArchiveTimeout)
+	defer cancel()
+
+	resp, err := e.hostClient.ArchiveMaintenanceHistory(
+		ctx,
+		&host_svc.ArchiveMaintenanceHistoryRequest{
+			ArchiveAgeSeconds: uint32(
+				e.config.Archiver.HostMaintenanceArchiveAge.Seconds()),
+			RetentionSeconds: uint32(
+				e.config.Archiver.HostMaintenanceRetention.Seconds()),
+		})
+	if err != nil {
+		log.WithError(err).Error("host maintenance history archive failed")
+		e.metrics.HostMaintenanceArchiveFail.Inc(1)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"archived": resp.GetArchived(),
+		"pruned":   resp.GetPruned(),
+	}).Info("Host maintenance history archive summary")
+	e.metrics.HostMaintenanceArchiveSuccess.Inc(1)
+	e.metrics.HostMaintenanceArchived.Inc(int64(resp.GetArchived()))
+	e.metrics.HostMaintenancePruned.Inc(int64(resp.GetPruned()))
+}
+
 func (e *engine) queryJobs(
 	ctx context.Context,
 	req *job.QueryRequest,
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	host_svc_mocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	job_mocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
@@ -55,6 +57,7 @@ type archiverEngineTestSuite struct {
 	mockCtrl       *gomock.Controller
 	mockJobClient  *job_mocks.MockJobManagerYARPCClient
 	mockTaskClient *task_mocks.MockTaskManagerYARPCClient
+	mockHostClient *host_svc_mocks.MockHostServiceYARPCClient
 	retryPolicy    backoff.RetryPolicy
 	e              *engine
 }
@@ -63,6 +66,7 @@ func (suite *archiverEngineTestSuite) SetupSuite() {
 	suite.mockCtrl = gomock.NewController(suite.T())
 	suite.mockJobClient = job_mocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
 	suite.mockTaskClient = task_mocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.mockHostClient = host_svc_mocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
 	suite.retryPolicy = backoff.NewRetryPolicy(3, 100*time.Millisecond)
 	suite.e = &engine{
 		jobClient: suite.mockJobClient,
@@ -349,3 +353,33 @@ func (suite *archiverEngineTestSuite) TestArchiveJobsStreamOnly() {
 		context.Background(),
 		summaryList)
 }
+
+// TestArchiveHostMaintenance tests archiving the host maintenance history
+func (suite *archiverEngineTestSuite) TestArchiveHostMaintenance() {
+	cfg := config.ArchiverConfig{HostMaintenanceArchive: true}
+	cfg.Normalize()
+	e := &engine{
+		hostClient: suite.mockHostClient,
+		config:     config.Config{Archiver: cfg},
+		metrics:    NewMetrics(tally.NoopScope),
+	}
+
+	suite.mockHostClient.EXPECT().
+		ArchiveMaintenanceHistory(
+			gomock.Any(),
+			&host_svc.ArchiveMaintenanceHistoryRequest{
+				ArchiveAgeSeconds: 7 * 24 * 60 * 60,
+				RetentionSeconds:  365 * 24 * 60 * 60,
+			}).
+		Return(&host_svc.ArchiveMaintenanceHistoryResponse{
+			Archived: 10,
+			Pruned:   2,
+		}, nil)
+	e.archiveHostMaintenance(context.Background())
+
+	// Failures are left to the next archiver run
+	suite.mockHostClient.EXPECT().
+		ArchiveMaintenanceHistory(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("archive failed"))
+	e.archiveHostMaintenance(context.Background())
+}
@@ -31,6 +31,11 @@ type Metrics struct {
 	PodDeleteEventsFail    tally.Counter
 	PodDeleteEventsSuccess tally.Counter
 
+	HostMaintenanceArchiveSuccess tally.Counter
+	HostMaintenanceArchiveFail    tally.Counter
+	HostMaintenanceArchived       tally.Counter
+	HostMaintenancePruned         tally.Counter
+
 	ArchiverRunDuration tally.Timer
 }
 
@@ -47,6 +52,11 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		PodDeleteEventsSuccess:    scope.Counter("pod_delete_events_success"),
 		PodDeleteEventsFail:       scope.Counter("pod_delete_events_fail"),
 
+		HostMaintenanceArchiveSuccess: scope.Counter("host_maintenance_archive_success"),
+		HostMaintenanceArchiveFail:    scope.Counter("host_maintenance_archive_fail"),
+		HostMaintenanceArchived:       scope.Counter("host_maintenance_archived"),
+		HostMaintenancePruned:         scope.Counter("host_maintenance_pruned"),
+
 		ArchiverRunDuration: scope.Timer("archiver_run_duration"),
 	}
 }
@@ -20,6 +20,7 @@ import (
 	"sort"
 
 	"github.com/pborman/uuid"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
 	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
@@ -52,6 +53,7 @@ type ServiceHandler struct {
 	metrics       *Metrics
 	jobClient     statelesssvc.JobServiceYARPCClient
 	podClient     podsvc.PodServiceYARPCClient
+	hostClient    hostsvc.HostServiceYARPCClient
 	respoolLoader RespoolLoader
 }
 
@@ -61,6 +63,7 @@ func NewServiceHandler(
 	parent tally.Scope,
 	jobClient statelesssvc.JobServiceYARPCClient,
 	podClient podsvc.PodServiceYARPCClient,
+	hostClient hostsvc.HostServiceYARPCClient,
 	respoolLoader RespoolLoader,
 ) (*ServiceHandler, error) {
 
@@ -74,6 +77,7 @@ func NewServiceHandler(
 		metrics:       NewMetrics(parent.SubScope("aurorabridge").SubScope("api")),
 		jobClient:     jobClient,
 		podClient:     podClient,
+		hostClient:    hostClient,
 		respoolLoader: respoolLoader,
 	}, nil
 }
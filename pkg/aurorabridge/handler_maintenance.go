@@ -0,0 +1,350 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aurorabridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	hostpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/thrift/aurora/api"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/thriftrw/ptr"
+)
+
+// This file contains the Aurora maintenance RPCs, which are mapped onto the
+// Peloton host service. Aurora maintenance modes map to Peloton host states as:
+//
+//	NONE      <- HOST_STATE_UP, or host unknown to Peloton
+//	DRAINING  <- HOST_STATE_DRAINING
+//	DRAINED   <- HOST_STATE_DRAINED, HOST_STATE_DOWN
+//
+// Peloton has no equivalent of the SCHEDULED mode, as putting a host into
+// maintenance both stops placement on it and drains its tasks.
+
+// StartMaintenance returns the maintenance modes of the hosts. Since Peloton
+// has no SCHEDULED mode, hosts are only put into maintenance by DrainHosts.
+func (h *ServiceHandler) StartMaintenance(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Response, error) {
+
+	result, err := h.startMaintenance(ctx, hosts)
+	defer func() {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"params": log.Fields{
+					"hosts": hosts,
+				},
+				"code":  err.responseCode,
+				"error": err.msg,
+			}).Error("StartMaintenance error")
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"params": log.Fields{
+				"hosts": hosts,
+			},
+			"result": result,
+		}).Debug("StartMaintenance success")
+	}()
+	return newResponse(result, err), nil
+}
+
+func (h *ServiceHandler) startMaintenance(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Result, *auroraError) {
+
+	hostnames := getHostnames(hosts)
+	states, err := h.getHostStates(ctx)
+	if err != nil {
+		return nil, auroraErrorf("get host states: %s", err)
+	}
+	return &api.Result{
+		StartMaintenanceResult: &api.StartMaintenanceResult{
+			Statuses: newHostStatuses(hostnames, states),
+		},
+	}, nil
+}
+
+// DrainHosts starts maintenance on the hosts which are up, which drains the
+// tasks running on them before putting them down.
+func (h *ServiceHandler) DrainHosts(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Response, error) {
+
+	result, details, err := h.drainHosts(ctx, hosts)
+	defer func() {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"params": log.Fields{
+					"hosts": hosts,
+				},
+				"code":  err.responseCode,
+				"error": err.msg,
+			}).Error("DrainHosts error")
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"params": log.Fields{
+				"hosts": hosts,
+			},
+			"result": result,
+		}).Info("DrainHosts success")
+	}()
+	return newResponse(result, err, details...), nil
+}
+
+func (h *ServiceHandler) drainHosts(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Result, []string, *auroraError) {
+
+	hostnames := getHostnames(hosts)
+	states, err := h.getHostStates(ctx)
+	if err != nil {
+		return nil, nil, auroraErrorf("get host states: %s", err)
+	}
+
+	var up, unknown []string
+	for _, hostname := range hostnames {
+		state, ok := states[hostname]
+		if !ok {
+			unknown = append(unknown, hostname)
+		} else if state == hostpb.HostState_HOST_STATE_UP {
+			up = append(up, hostname)
+		}
+	}
+
+	if len(up) > 0 {
+		if _, err := h.hostClient.StartMaintenance(
+			ctx,
+			&hostsvc.StartMaintenanceRequest{Hostnames: up},
+		); err != nil {
+			return nil, nil, auroraErrorf("start maintenance: %s", err)
+		}
+		for _, hostname := range up {
+			states[hostname] = hostpb.HostState_HOST_STATE_DRAINING
+		}
+	}
+
+	var details []string
+	if len(unknown) > 0 {
+		details = append(details, fmt.Sprintf(
+			"hosts not registered with Peloton are not drained: %s",
+			strings.Join(unknown, ", ")))
+	}
+	return &api.Result{
+		DrainHostsResult: &api.DrainHostsResult{
+			Statuses: newHostStatuses(hostnames, states),
+		},
+	}, details, nil
+}
+
+// MaintenanceStatus returns the maintenance modes of the hosts.
+func (h *ServiceHandler) MaintenanceStatus(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Response, error) {
+
+	result, err := h.maintenanceStatus(ctx, hosts)
+	defer func() {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"params": log.Fields{
+					"hosts": hosts,
+				},
+				"code":  err.responseCode,
+				"error": err.msg,
+			}).Error("MaintenanceStatus error")
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"params": log.Fields{
+				"hosts": hosts,
+			},
+			"result": result,
+		}).Debug("MaintenanceStatus success")
+	}()
+	return newResponse(result, err), nil
+}
+
+func (h *ServiceHandler) maintenanceStatus(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Result, *auroraError) {
+
+	hostnames := getHostnames(hosts)
+	states, err := h.getHostStates(ctx)
+	if err != nil {
+		return nil, auroraErrorf("get host states: %s", err)
+	}
+	return &api.Result{
+		MaintenanceStatusResult: &api.MaintenanceStatusResult{
+			Statuses: newHostStatuses(hostnames, states),
+		},
+	}, nil
+}
+
+// EndMaintenance completes maintenance on the hosts which are down. Peloton
+// cannot stop draining a host, so draining hosts are left in maintenance.
+func (h *ServiceHandler) EndMaintenance(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Response, error) {
+
+	result, details, err := h.endMaintenance(ctx, hosts)
+	defer func() {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"params": log.Fields{
+					"hosts": hosts,
+				},
+				"code":  err.responseCode,
+				"error": err.msg,
+			}).Error("EndMaintenance error")
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"params": log.Fields{
+				"hosts": hosts,
+			},
+			"result": result,
+		}).Info("EndMaintenance success")
+	}()
+	return newResponse(result, err, details...), nil
+}
+
+func (h *ServiceHandler) endMaintenance(
+	ctx context.Context,
+	hosts *api.Hosts,
+) (*api.Result, []string, *auroraError) {
+
+	hostnames := getHostnames(hosts)
+	states, err := h.getHostStates(ctx)
+	if err != nil {
+		return nil, nil, auroraErrorf("get host states: %s", err)
+	}
+
+	var down, draining []string
+	for _, hostname := range hostnames {
+		switch states[hostname] {
+		case hostpb.HostState_HOST_STATE_DOWN:
+			down = append(down, hostname)
+		case hostpb.HostState_HOST_STATE_DRAINING,
+			hostpb.HostState_HOST_STATE_DRAINED:
+			draining = append(draining, hostname)
+		}
+	}
+
+	var details []string
+	if len(down) > 0 {
+		resp, err := h.hostClient.CompleteMaintenance(
+			ctx,
+			&hostsvc.CompleteMaintenanceRequest{Hostnames: down},
+		)
+		if err != nil {
+			return nil, nil, auroraErrorf("complete maintenance: %s", err)
+		}
+		for _, hostname := range resp.GetCompletedHostnames() {
+			states[hostname] = hostpb.HostState_HOST_STATE_UP
+		}
+		for _, failure := range resp.GetFailures() {
+			details = append(details, fmt.Sprintf(
+				"complete maintenance of %s: %s",
+				failure.GetHostname(),
+				failure.GetMessage()))
+		}
+	}
+
+	if len(draining) > 0 {
+		details = append(details, fmt.Sprintf(
+			"draining hosts stay in maintenance until they are down: %s",
+			strings.Join(draining, ", ")))
+	}
+	return &api.Result{
+		EndMaintenanceResult: &api.EndMaintenanceResult{
+			Statuses: newHostStatuses(hostnames, states),
+		},
+	}, details, nil
+}
+
+// getHostStates returns the states of all the hosts known to Peloton.
+func (h *ServiceHandler) getHostStates(
+	ctx context.Context,
+) (map[string]hostpb.HostState, error) {
+
+	resp, err := h.hostClient.QueryHosts(ctx, &hostsvc.QueryHostsRequest{
+		Fields: []string{"hostname", "state"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]hostpb.HostState)
+	for _, info := range resp.GetHostInfos() {
+		states[info.GetHostname()] = info.GetState()
+	}
+	return states, nil
+}
+
+// getHostnames returns the sorted hostnames of hosts.
+func getHostnames(hosts *api.Hosts) []string {
+	var hostnames []string
+	for hostname := range hosts.GetHostNames() {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// newHostStatuses returns the maintenance modes of the given hosts.
+func newHostStatuses(
+	hostnames []string,
+	states map[string]hostpb.HostState,
+) []*api.HostStatus {
+
+	var statuses []*api.HostStatus
+	for _, hostname := range hostnames {
+		statuses = append(statuses, &api.HostStatus{
+			Host: ptr.String(hostname),
+			Mode: newMaintenanceMode(states[hostname]).Ptr(),
+		})
+	}
+	return statuses
+}
+
+// newMaintenanceMode converts a Peloton host state into an Aurora
+// maintenance mode.
+func newMaintenanceMode(state hostpb.HostState) api.MaintenanceMode {
+	switch state {
+	case hostpb.HostState_HOST_STATE_DRAINING:
+		return api.MaintenanceModeDraining
+	case hostpb.HostState_HOST_STATE_DRAINED,
+		hostpb.HostState_HOST_STATE_DOWN:
+		return api.MaintenanceModeDrained
+	default:
+		return api.MaintenanceModeNone
+	}
+}
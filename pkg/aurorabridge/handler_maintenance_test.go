@@ -0,0 +1,219 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aurorabridge
+
+import (
+	"errors"
+
+	hostpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/thrift/aurora/api"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/thriftrw/ptr"
+)
+
+func newHosts(hostnames ...string) *api.Hosts {
+	hosts := &api.Hosts{HostNames: make(map[string]struct{})}
+	for _, hostname := range hostnames {
+		hosts.HostNames[hostname] = struct{}{}
+	}
+	return hosts
+}
+
+func newHostStatus(
+	hostname string,
+	mode api.MaintenanceMode,
+) *api.HostStatus {
+	return &api.HostStatus{
+		Host: ptr.String(hostname),
+		Mode: mode.Ptr(),
+	}
+}
+
+func (suite *ServiceHandlerTestSuite) expectQueryHosts(
+	states map[string]hostpb.HostState,
+) {
+	resp := &hostsvc.QueryHostsResponse{}
+	for hostname, state := range states {
+		resp.HostInfos = append(resp.HostInfos, &hostpb.HostInfo{
+			Hostname: hostname,
+			State:    state,
+		})
+	}
+	suite.hostClient.EXPECT().
+		QueryHosts(suite.ctx, &hostsvc.QueryHostsRequest{
+			Fields: []string{"hostname", "state"},
+		}).
+		Return(resp, nil)
+}
+
+// Tests that StartMaintenance returns the current maintenance modes
+func (suite *ServiceHandlerTestSuite) TestStartMaintenance() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_UP,
+		"host2": hostpb.HostState_HOST_STATE_DOWN,
+	})
+
+	resp, err := suite.handler.StartMaintenance(
+		suite.ctx, newHosts("host2", "host1", "host3"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeOk, resp.GetResponseCode())
+	suite.Equal([]*api.HostStatus{
+		newHostStatus("host1", api.MaintenanceModeNone),
+		newHostStatus("host2", api.MaintenanceModeDrained),
+		newHostStatus("host3", api.MaintenanceModeNone),
+	}, resp.GetResult().GetStartMaintenanceResult().GetStatuses())
+}
+
+// Tests that DrainHosts starts maintenance on the hosts which are up
+func (suite *ServiceHandlerTestSuite) TestDrainHosts() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_UP,
+		"host2": hostpb.HostState_HOST_STATE_DRAINING,
+		"host3": hostpb.HostState_HOST_STATE_UP,
+	})
+	suite.hostClient.EXPECT().
+		StartMaintenance(suite.ctx, &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"host1", "host3"},
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{}, nil)
+
+	resp, err := suite.handler.DrainHosts(
+		suite.ctx, newHosts("host1", "host2", "host3", "host4"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeOk, resp.GetResponseCode())
+	suite.Equal([]*api.HostStatus{
+		newHostStatus("host1", api.MaintenanceModeDraining),
+		newHostStatus("host2", api.MaintenanceModeDraining),
+		newHostStatus("host3", api.MaintenanceModeDraining),
+		newHostStatus("host4", api.MaintenanceModeNone),
+	}, resp.GetResult().GetDrainHostsResult().GetStatuses())
+	suite.Len(resp.GetDetails(), 1)
+	suite.Contains(resp.GetDetails()[0].GetMessage(), "host4")
+}
+
+// Tests failures of DrainHosts
+func (suite *ServiceHandlerTestSuite) TestDrainHostsError() {
+	suite.hostClient.EXPECT().
+		QueryHosts(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("query hosts error"))
+
+	resp, err := suite.handler.DrainHosts(suite.ctx, newHosts("host1"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeError, resp.GetResponseCode())
+
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_UP,
+	})
+	suite.hostClient.EXPECT().
+		StartMaintenance(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("start maintenance error"))
+
+	resp, err = suite.handler.DrainHosts(suite.ctx, newHosts("host1"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeError, resp.GetResponseCode())
+}
+
+// Tests that MaintenanceStatus maps Peloton host states to maintenance modes
+func (suite *ServiceHandlerTestSuite) TestMaintenanceStatus() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_UP,
+		"host2": hostpb.HostState_HOST_STATE_DRAINING,
+		"host3": hostpb.HostState_HOST_STATE_DRAINED,
+		"host4": hostpb.HostState_HOST_STATE_DOWN,
+	})
+
+	resp, err := suite.handler.MaintenanceStatus(
+		suite.ctx, newHosts("host1", "host2", "host3", "host4"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeOk, resp.GetResponseCode())
+	suite.Equal([]*api.HostStatus{
+		newHostStatus("host1", api.MaintenanceModeNone),
+		newHostStatus("host2", api.MaintenanceModeDraining),
+		newHostStatus("host3", api.MaintenanceModeDrained),
+		newHostStatus("host4", api.MaintenanceModeDrained),
+	}, resp.GetResult().GetMaintenanceStatusResult().GetStatuses())
+}
+
+// Tests that EndMaintenance completes maintenance on the hosts which are down
+func (suite *ServiceHandlerTestSuite) TestEndMaintenance() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_DOWN,
+		"host2": hostpb.HostState_HOST_STATE_DRAINING,
+		"host3": hostpb.HostState_HOST_STATE_UP,
+	})
+	suite.hostClient.EXPECT().
+		CompleteMaintenance(suite.ctx, &hostsvc.CompleteMaintenanceRequest{
+			Hostnames: []string{"host1"},
+		}).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			CompletedHostnames: []string{"host1"},
+		}, nil)
+
+	resp, err := suite.handler.EndMaintenance(
+		suite.ctx, newHosts("host1", "host2", "host3"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeOk, resp.GetResponseCode())
+	suite.Equal([]*api.HostStatus{
+		newHostStatus("host1", api.MaintenanceModeNone),
+		newHostStatus("host2", api.MaintenanceModeDraining),
+		newHostStatus("host3", api.MaintenanceModeNone),
+	}, resp.GetResult().GetEndMaintenanceResult().GetStatuses())
+	suite.Len(resp.GetDetails(), 1)
+	suite.Contains(resp.GetDetails()[0].GetMessage(), "host2")
+}
+
+// Tests that hosts which maintenance could not be completed on stay
+// drained and are reported in the details of EndMaintenance
+func (suite *ServiceHandlerTestSuite) TestEndMaintenancePartialFailure() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_DOWN,
+		"host2": hostpb.HostState_HOST_STATE_DOWN,
+	})
+	suite.hostClient.EXPECT().
+		CompleteMaintenance(suite.ctx, gomock.Any()).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			CompletedHostnames: []string{"host1"},
+			Failures: []*hostsvc.CompleteMaintenanceFailure{
+				{Hostname: "host2", Message: "stop maintenance error"},
+			},
+		}, nil)
+
+	resp, err := suite.handler.EndMaintenance(
+		suite.ctx, newHosts("host1", "host2"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeOk, resp.GetResponseCode())
+	suite.Equal([]*api.HostStatus{
+		newHostStatus("host1", api.MaintenanceModeNone),
+		newHostStatus("host2", api.MaintenanceModeDrained),
+	}, resp.GetResult().GetEndMaintenanceResult().GetStatuses())
+	suite.Len(resp.GetDetails(), 1)
+	suite.Contains(resp.GetDetails()[0].GetMessage(), "host2")
+}
+
+// Tests failure of CompleteMaintenance in EndMaintenance
+func (suite *ServiceHandlerTestSuite) TestEndMaintenanceError() {
+	suite.expectQueryHosts(map[string]hostpb.HostState{
+		"host1": hostpb.HostState_HOST_STATE_DOWN,
+	})
+	suite.hostClient.EXPECT().
+		CompleteMaintenance(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("complete maintenance error"))
+
+	resp, err := suite.handler.EndMaintenance(suite.ctx, newHosts("host1"))
+	suite.NoError(err)
+	suite.Equal(api.ResponseCodeError, resp.GetResponseCode())
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/pborman/uuid"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
 	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
@@ -57,6 +58,7 @@ type ServiceHandlerTestSuite struct {
 	ctrl          *gomock.Controller
 	jobClient     *jobmocks.MockJobServiceYARPCClient
 	podClient     *podmocks.MockPodServiceYARPCClient
+	hostClient    *hostmocks.MockHostServiceYARPCClient
 	respoolLoader *aurorabridgemocks.MockRespoolLoader
 
 	config        ServiceHandlerConfig
@@ -71,6 +73,7 @@ func (suite *ServiceHandlerTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.jobClient = jobmocks.NewMockJobServiceYARPCClient(suite.ctrl)
 	suite.podClient = podmocks.NewMockPodServiceYARPCClient(suite.ctrl)
+	suite.hostClient = hostmocks.NewMockHostServiceYARPCClient(suite.ctrl)
 	suite.respoolLoader = aurorabridgemocks.NewMockRespoolLoader(suite.ctrl)
 
 	suite.config = ServiceHandlerConfig{
@@ -87,6 +90,7 @@ func (suite *ServiceHandlerTestSuite) SetupTest() {
 		tally.NoopScope,
 		suite.jobClient,
 		suite.podClient,
+		suite.hostClient,
 		suite.respoolLoader,
 	)
 	suite.NoError(err)
@@ -110,3 +110,90 @@ func (h *ServiceHandler) ReplaceCronTemplate(
 	config *api.JobConfiguration) (*api.Response, error) {
 	return nil, errUnimplemented
 }
+
+// SetQuota will remain unimplemented.
+func (h *ServiceHandler) SetQuota(
+	ctx context.Context,
+	ownerRole *string,
+	quota *api.ResourceAggregate) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// ForceTaskState will remain unimplemented.
+func (h *ServiceHandler) ForceTaskState(
+	ctx context.Context,
+	taskId *string,
+	status *api.ScheduleStatus) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// PerformBackup will remain unimplemented.
+func (h *ServiceHandler) PerformBackup(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// ListBackups will remain unimplemented.
+func (h *ServiceHandler) ListBackups(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// StageRecovery will remain unimplemented.
+func (h *ServiceHandler) StageRecovery(
+	ctx context.Context,
+	backupId *string) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// QueryRecovery will remain unimplemented.
+func (h *ServiceHandler) QueryRecovery(
+	ctx context.Context,
+	query *api.TaskQuery) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// DeleteRecoveryTasks will remain unimplemented.
+func (h *ServiceHandler) DeleteRecoveryTasks(
+	ctx context.Context,
+	query *api.TaskQuery) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// CommitRecovery will remain unimplemented.
+func (h *ServiceHandler) CommitRecovery(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// UnloadRecovery will remain unimplemented.
+func (h *ServiceHandler) UnloadRecovery(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// Snapshot will remain unimplemented.
+func (h *ServiceHandler) Snapshot(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// TriggerExplicitTaskReconciliation will remain unimplemented.
+func (h *ServiceHandler) TriggerExplicitTaskReconciliation(
+	ctx context.Context,
+	settings *api.ExplicitReconciliationSettings) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// TriggerImplicitTaskReconciliation will remain unimplemented.
+func (h *ServiceHandler) TriggerImplicitTaskReconciliation(
+	ctx context.Context) (*api.Response, error) {
+	return nil, errUnimplemented
+}
+
+// PruneTasks will remain unimplemented.
+func (h *ServiceHandler) PruneTasks(
+	ctx context.Context,
+	query *api.TaskQuery) (*api.Response, error) {
+	return nil, errUnimplemented
+}
@@ -3,6 +3,7 @@ package impl
 import (
 	"github.com/uber/peloton/pkg/auth"
 	"github.com/uber/peloton/pkg/auth/impl/basic"
+	"github.com/uber/peloton/pkg/auth/impl/external"
 	"github.com/uber/peloton/pkg/auth/impl/noop"
 
 	"go.uber.org/yarpc/yarpcerrors"
@@ -15,6 +16,8 @@ func CreateNewSecurityManager(t auth.Type, configPath string) (auth.SecurityMana
 		return noop.NewNoopSecurityManager(), nil
 	case auth.BASIC:
 		return basic.NewBasicSecurityManager(configPath)
+	case auth.EXTERNAL:
+		return external.NewExternalSecurityManager(configPath)
 	default:
 		return nil,
 			yarpcerrors.InvalidArgumentErrorf("unknown security type provided: %s", t)
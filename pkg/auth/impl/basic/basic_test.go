@@ -376,6 +376,35 @@ func (suite *SecurityManagerTestSuite) TestValidateRule() {
 	}
 }
 
+func (suite *SecurityManagerTestSuite) TestHostServicePermission() {
+	m, err := NewBasicSecurityManager("testdata/test_hostmgr_auth_config.yaml")
+	suite.NoError(err)
+
+	tests := []struct {
+		procedureName string
+		operator      bool
+		readOnly      bool
+	}{
+		{procedureName: "peloton.api.v0.host.svc.HostService::QueryHosts", operator: true, readOnly: true},
+		{procedureName: "peloton.api.v0.host.svc.HostService::ListReservations", operator: true, readOnly: true},
+		{procedureName: "peloton.api.v0.host.svc.HostService::StartMaintenance", operator: true, readOnly: false},
+		{procedureName: "peloton.api.v0.host.svc.HostService::CompleteMaintenance", operator: true, readOnly: false},
+		{procedureName: "peloton.api.v1alpha.job.stateless.svc.JobService::GetJob", operator: false, readOnly: false},
+	}
+
+	operator, err := m.Authenticate(
+		&testToken{username: "operator", password: "operator-password"},
+	)
+	suite.NoError(err)
+	readOnly, err := m.Authenticate(&testToken{})
+	suite.NoError(err)
+
+	for _, test := range tests {
+		suite.Equal(test.operator, operator.IsPermitted(test.procedureName), test.procedureName)
+		suite.Equal(test.readOnly, readOnly.IsPermitted(test.procedureName), test.procedureName)
+	}
+}
+
 type testToken struct {
 	username string
 	password string
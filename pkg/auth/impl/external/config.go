@@ -0,0 +1,14 @@
+package external
+
+import "time"
+
+type managerConfig struct {
+	// URL of the authorizer, which is POSTed a JSON encoded
+	// decisionRequest for each decision
+	URL string `yaml:"url"`
+	// Timeout of the requests to the authorizer
+	Timeout time.Duration `yaml:"timeout"`
+	// Duration the decisions of the authorizer are cached for,
+	// decisions are not cached if zero
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
@@ -0,0 +1,235 @@
+package external
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/auth"
+	"github.com/uber/peloton/pkg/common/config"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// expected fields passed by token
+	_usernameHeaderKey = "username"
+	_passwordHeaderKey = "password"
+
+	_defaultTimeout = 5 * time.Second
+
+	// number of cached decisions above which expired decisions are pruned
+	_cachePruneSize = 10000
+)
+
+// Authorizer decides whether a caller can access a procedure
+type Authorizer interface {
+	// Authorize returns whether the caller identified by username and
+	// credential can access the procedure
+	Authorize(username, credential, procedure string) (bool, error)
+}
+
+// decisionRequest is sent to the external authorizer
+type decisionRequest struct {
+	Username   string `json:"username"`
+	Credential string `json:"credential"`
+	Procedure  string `json:"procedure"`
+}
+
+// decisionResponse is returned by the external authorizer
+type decisionResponse struct {
+	Permitted bool `json:"permitted"`
+}
+
+// httpAuthorizer asks an external authorizer over HTTP
+type httpAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+// Authorize POSTs the decision request to the authorizer
+func (a *httpAuthorizer) Authorize(
+	username, credential, procedure string) (bool, error) {
+	body, err := json.Marshal(&decisionRequest{
+		Username:   username,
+		Credential: credential,
+		Procedure:  procedure,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, yarpcerrors.UnavailableErrorf(
+			"authorizer returned status %d", resp.StatusCode)
+	}
+
+	decision := &decisionResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(decision); err != nil {
+		return false, err
+	}
+	return decision.Permitted, nil
+}
+
+// SecurityManager takes the identity of the caller from the
+// Username/Password headers and delegates authorization
+// decisions to an external authorizer
+type SecurityManager struct {
+	sync.Mutex
+
+	authorizer Authorizer
+	cacheTTL   time.Duration
+	// cache key -> decision
+	decisions map[string]*decision
+}
+
+// decision is a cached decision of the authorizer
+type decision struct {
+	permitted bool
+	expiry    time.Time
+}
+
+// user is the identity of a caller, the authorizer
+// authenticates it along with each decision
+type user struct {
+	m          *SecurityManager
+	username   string
+	credential string
+}
+
+var _ auth.SecurityManager = &SecurityManager{}
+
+// Authenticate returns the identity of the caller, which is
+// checked by the authorizer when a procedure is called
+func (m *SecurityManager) Authenticate(token auth.Token) (auth.User, error) {
+	username, _ := token.Get(_usernameHeaderKey)
+	password, _ := token.Get(_passwordHeaderKey)
+
+	// invalid token format, expect Password only along with Username
+	if len(username) == 0 && len(password) != 0 {
+		return nil, yarpcerrors.UnauthenticatedErrorf("no Username provided")
+	}
+
+	return &user{
+		m:          m,
+		username:   username,
+		credential: password,
+	}, nil
+}
+
+// IsPermitted returns if a procedure is permitted for user. Procedures
+// are not permitted if the authorizer cannot be reached.
+func (u *user) IsPermitted(procedure string) bool {
+	return u.m.isPermitted(u.username, u.credential, procedure)
+}
+
+func (m *SecurityManager) isPermitted(
+	username, credential, procedure string) bool {
+	key := cacheKey(username, credential, procedure)
+	now := time.Now()
+
+	m.Lock()
+	d, ok := m.decisions[key]
+	m.Unlock()
+	if ok && now.Before(d.expiry) {
+		return d.permitted
+	}
+
+	permitted, err := m.authorizer.Authorize(username, credential, procedure)
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"username":  username,
+				"procedure": procedure,
+			}).Warn("Failed to get authorization decision")
+		return false
+	}
+
+	if m.cacheTTL > 0 {
+		m.Lock()
+		if len(m.decisions) >= _cachePruneSize {
+			m.pruneLocked(now)
+		}
+		m.decisions[key] = &decision{
+			permitted: permitted,
+			expiry:    now.Add(m.cacheTTL),
+		}
+		m.Unlock()
+	}
+	return permitted
+}
+
+// pruneLocked removes the expired decisions from the cache,
+// or all of them if none is expired
+func (m *SecurityManager) pruneLocked(now time.Time) {
+	for key, d := range m.decisions {
+		if !now.Before(d.expiry) {
+			delete(m.decisions, key)
+		}
+	}
+	if len(m.decisions) >= _cachePruneSize {
+		m.decisions = make(map[string]*decision)
+	}
+}
+
+// cacheKey returns the key of a decision, the credential is
+// hashed so that it is not kept in memory in clear
+func cacheKey(username, credential, procedure string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return username + "\x00" + hex.EncodeToString(sum[:]) + "\x00" + procedure
+}
+
+// NewExternalSecurityManager returns SecurityManager
+func NewExternalSecurityManager(configPath string) (*SecurityManager, error) {
+	mConfig, err := parseConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(mConfig.URL) == 0 {
+		return nil, yarpcerrors.InvalidArgumentErrorf("no authorizer url specified")
+	}
+
+	timeout := mConfig.Timeout
+	if timeout == 0 {
+		timeout = _defaultTimeout
+	}
+
+	return newExternalSecurityManager(
+		&httpAuthorizer{
+			url:    mConfig.URL,
+			client: &http.Client{Timeout: timeout},
+		},
+		mConfig.CacheTTL,
+	), nil
+}
+
+// helper method to create SecurityManager which makes test easier
+func newExternalSecurityManager(
+	authorizer Authorizer,
+	cacheTTL time.Duration) *SecurityManager {
+	return &SecurityManager{
+		authorizer: authorizer,
+		cacheTTL:   cacheTTL,
+		decisions:  make(map[string]*decision),
+	}
+}
+
+func parseConfig(configPath string) (*managerConfig, error) {
+	mConfig := &managerConfig{}
+	if err := config.Parse(mConfig, configPath); err != nil {
+		return nil, err
+	}
+	return mConfig, nil
+}
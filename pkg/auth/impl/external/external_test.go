@@ -0,0 +1,157 @@
+package external
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_testConfigPath = "testdata/test_external_auth_config.yaml"
+
+	_queryHosts       = "peloton.api.v0.host.svc.HostService::QueryHosts"
+	_startMaintenance = "peloton.api.v0.host.svc.HostService::StartMaintenance"
+)
+
+type SecurityManagerTestSuite struct {
+	suite.Suite
+
+	server   *httptest.Server
+	requests []*decisionRequest
+}
+
+func (suite *SecurityManagerTestSuite) SetupTest() {
+	suite.requests = nil
+	// operator can call any procedure, other callers
+	// can only query hosts
+	suite.server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			request := &decisionRequest{}
+			suite.NoError(json.NewDecoder(r.Body).Decode(request))
+			suite.requests = append(suite.requests, request)
+
+			permitted := request.Procedure == _queryHosts ||
+				(request.Username == "operator" && request.Credential == "secret")
+			suite.NoError(json.NewEncoder(w).Encode(
+				&decisionResponse{Permitted: permitted}))
+		}))
+}
+
+func (suite *SecurityManagerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *SecurityManagerTestSuite) newSecurityManager(
+	cacheTTL time.Duration) *SecurityManager {
+	return newExternalSecurityManager(
+		&httpAuthorizer{url: suite.server.URL, client: &http.Client{}},
+		cacheTTL,
+	)
+}
+
+func (suite *SecurityManagerTestSuite) TestCreateExternalSecurityManager() {
+	m, err := NewExternalSecurityManager(_testConfigPath)
+	suite.NoError(err)
+	suite.Equal(30*time.Second, m.cacheTTL)
+
+	m, err = NewExternalSecurityManager("testdata/non_exist.yaml")
+	suite.Nil(m)
+	suite.Error(err)
+}
+
+func (suite *SecurityManagerTestSuite) TestAuthenticate() {
+	m := suite.newSecurityManager(0)
+
+	u, err := m.Authenticate(&testToken{username: "operator", password: "secret"})
+	suite.NoError(err)
+	suite.NotNil(u)
+
+	u, err = m.Authenticate(&testToken{})
+	suite.NoError(err)
+	suite.NotNil(u)
+
+	u, err = m.Authenticate(&testToken{password: "secret"})
+	suite.Error(err)
+	suite.Nil(u)
+}
+
+func (suite *SecurityManagerTestSuite) TestPermission() {
+	m := suite.newSecurityManager(0)
+
+	operator, err := m.Authenticate(
+		&testToken{username: "operator", password: "secret"})
+	suite.NoError(err)
+	suite.True(operator.IsPermitted(_queryHosts))
+	suite.True(operator.IsPermitted(_startMaintenance))
+
+	reader, err := m.Authenticate(&testToken{})
+	suite.NoError(err)
+	suite.True(reader.IsPermitted(_queryHosts))
+	suite.False(reader.IsPermitted(_startMaintenance))
+
+	impostor, err := m.Authenticate(
+		&testToken{username: "operator", password: "wrong"})
+	suite.NoError(err)
+	suite.False(impostor.IsPermitted(_startMaintenance))
+
+	suite.Len(suite.requests, 5)
+	suite.Equal(&decisionRequest{
+		Username:   "operator",
+		Credential: "secret",
+		Procedure:  _queryHosts,
+	}, suite.requests[0])
+}
+
+func (suite *SecurityManagerTestSuite) TestDecisionCache() {
+	m := suite.newSecurityManager(time.Hour)
+
+	operator, err := m.Authenticate(
+		&testToken{username: "operator", password: "secret"})
+	suite.NoError(err)
+	suite.True(operator.IsPermitted(_startMaintenance))
+	suite.True(operator.IsPermitted(_startMaintenance))
+	suite.Len(suite.requests, 1)
+
+	// a different credential is not served from the cache
+	impostor, err := m.Authenticate(
+		&testToken{username: "operator", password: "wrong"})
+	suite.NoError(err)
+	suite.False(impostor.IsPermitted(_startMaintenance))
+	suite.Len(suite.requests, 2)
+}
+
+func (suite *SecurityManagerTestSuite) TestAuthorizerUnavailable() {
+	m := suite.newSecurityManager(time.Hour)
+	suite.server.Close()
+
+	operator, err := m.Authenticate(
+		&testToken{username: "operator", password: "secret"})
+	suite.NoError(err)
+	suite.False(operator.IsPermitted(_queryHosts))
+	suite.Empty(m.decisions)
+}
+
+func TestSecurityManagerTestSuite(t *testing.T) {
+	suite.Run(t, &SecurityManagerTestSuite{})
+}
+
+type testToken struct {
+	username string
+	password string
+}
+
+func (t *testToken) Get(k string) (string, bool) {
+	if k == _usernameHeaderKey {
+		return t.username, len(t.username) != 0
+	}
+
+	if k == _passwordHeaderKey {
+		return t.password, len(t.password) != 0
+	}
+
+	return "", false
+}
@@ -10,6 +10,9 @@ const (
 	NOOP = Type("NOOP")
 	// BASIC would use username and password for auth
 	BASIC = Type("BASIC")
+	// EXTERNAL would delegate authorization decisions
+	// to an external authorizer
+	EXTERNAL = Type("EXTERNAL")
 )
 
 // Token is used by SecurityManager to authenticate a user
@@ -16,12 +16,17 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/transport/grpc"
+	"google.golang.org/grpc/credentials"
 
 	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
@@ -40,6 +45,19 @@ import (
 	"github.com/uber/peloton/pkg/common/leader"
 )
 
+// _hostServiceOutbound is the outbound calling the host service, which
+// may be served on a separate TLS port of host manager
+const _hostServiceOutbound = "peloton-hostmgr-hostsvc"
+
+// HostServiceTLS is the TLS config for calling the host service, which
+// host manager only serves over TLS when TLS is enabled
+type HostServiceTLS struct {
+	// Port of host manager serving the host service over TLS
+	Port int
+	// Config of the TLS connection
+	Config *tls.Config
+}
+
 // Client is a JSON Client with associated dispatcher and context
 type Client struct {
 	jobClient       job.JobManagerYARPCClient
@@ -65,6 +83,7 @@ func New(
 	discovery leader.Discovery,
 	timeout time.Duration,
 	authConfig *middleware.BasicAuthConfig,
+	hostServiceTLS *HostServiceTLS,
 	debug bool) (*Client, error) {
 
 	jobmgrURL, err := discovery.GetAppURL(common.JobManagerRole)
@@ -84,6 +103,21 @@ func New(
 
 	t := grpc.NewTransport()
 
+	hostServiceOutbound := t.NewSingleOutbound(hostmgrURL.Host)
+	if hostServiceTLS != nil {
+		host, _, err := net.SplitHostPort(hostmgrURL.Host)
+		if err != nil {
+			return nil, err
+		}
+		dialer := t.NewDialer(grpc.DialerCredentials(
+			credentials.NewTLS(hostServiceTLS.Config)))
+		hostServiceOutbound = t.NewOutbound(hostport.NewSingle(
+			hostport.PeerIdentifier(
+				net.JoinHostPort(host, strconv.Itoa(hostServiceTLS.Port))),
+			dialer,
+		))
+	}
+
 	authMiddleware := middleware.NewBasicAuthOutboundMiddleware(authConfig)
 
 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
@@ -99,6 +133,10 @@ func New(
 			common.PelotonHostManager: transport.Outbounds{
 				Unary: t.NewSingleOutbound(hostmgrURL.Host),
 			},
+			_hostServiceOutbound: transport.Outbounds{
+				ServiceName: common.PelotonHostManager,
+				Unary:       hostServiceOutbound,
+			},
 		},
 		OutboundMiddleware: yarpc.OutboundMiddleware{
 			Unary:  authMiddleware,
@@ -135,7 +173,7 @@ func New(
 			dispatcher.ClientConfig(common.PelotonHostManager),
 		),
 		hostClient: hostsvc.NewHostServiceYARPCClient(
-			dispatcher.ClientConfig(common.PelotonHostManager),
+			dispatcher.ClientConfig(_hostServiceOutbound),
 		),
 		podClient: podsvc.NewPodServiceYARPCClient(
 			dispatcher.ClientConfig(common.PelotonJobManager),
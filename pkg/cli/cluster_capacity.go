@@ -0,0 +1,43 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+// ClusterCapacityGetAction prints the total, allocated, draining and down
+// capacity of the cluster and of each host pool.
+func (c *Client) ClusterCapacityGetAction() error {
+	resp, err := c.hostMgrClient.GetClusterCapacity(
+		c.ctx,
+		&hostsvc.GetClusterCapacityRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetError() != nil {
+		return errors.New(resp.GetError().GetClusterUnavailable().GetMessage())
+	}
+
+	out, err := marshallResponse(jsonResponseFormat, resp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v\n", string(out))
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	hostMocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type clusterCapacityActionsTestSuite struct {
+	suite.Suite
+	ctx         context.Context
+	ctrl        *gomock.Controller
+	mockHostMgr *hostMocks.MockInternalHostServiceYARPCClient
+}
+
+func (suite *clusterCapacityActionsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostMgr = hostMocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
+	suite.ctx = context.Background()
+}
+
+func (suite *clusterCapacityActionsTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *clusterCapacityActionsTestSuite) TestClusterCapacityGetAction() {
+	c := Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+
+	suite.mockHostMgr.EXPECT().GetClusterCapacity(
+		gomock.Any(),
+		&hostsvc.GetClusterCapacityRequest{}).
+		Return(&hostsvc.GetClusterCapacityResponse{
+			Cluster: &hostsvc.HostPoolCapacity{Hosts: 1},
+			Pools: []*hostsvc.HostPoolCapacity{
+				{Pool: "default", Hosts: 1},
+			},
+		}, nil)
+	suite.NoError(c.ClusterCapacityGetAction())
+
+	// Test cluster unavailable error
+	suite.mockHostMgr.EXPECT().GetClusterCapacity(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetClusterCapacityResponse{
+			Error: &hostsvc.GetClusterCapacityResponse_Error{
+				ClusterUnavailable: &hostsvc.ClusterUnavailable{
+					Message: "master unavailable",
+				},
+			},
+		}, nil)
+	suite.Error(c.ClusterCapacityGetAction())
+
+	// Test GetClusterCapacity error
+	suite.mockHostMgr.EXPECT().GetClusterCapacity(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake GetClusterCapacity error"))
+	suite.Error(c.ClusterCapacityGetAction())
+}
+
+func TestClusterCapacityAction(t *testing.T) {
+	suite.Run(t, new(clusterCapacityActionsTestSuite))
+}
@@ -18,9 +18,13 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	pb_task "github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
@@ -31,57 +35,900 @@ const (
 	hostQueryFormatHeader = "Hostname\tIP\tState\n"
 	hostQueryFormatBody   = "%s\t%s\t%s\n"
 	hostSeparator         = ","
-	getHostsFormatHeader  = "Hostname\tCPU\tGPU\tMEM\tDisk\tState\t\n"
-	getHostsFormatBody    = "%s\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%s\t\n"
+	getHostsFormatHeader  = "Hostname\tCPU\tGPU\tMEM\tDisk\tRevocable CPU\tCustom\tState\t\n"
+	getHostsFormatBody    = "%s\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%.2f\t%s\t%s\t\n"
+
+	reservationFormatHeader = "Hostname\tReservation ID\tRole\tCPU\tMEM\tDisk\tGPU\tVolume ID\tJob ID\tInstance\tCreated\t\n"
+	reservationFormatBody   = "%s\t%s\t%s\t%.2f\t%.2f MB\t%.2f MB\t%.2f\t%s\t%s\t%d\t%s\t\n"
+
+	maintenanceHistoryFormatHeader = "Time\tFrom\tTo\tArchived\t\n"
+	maintenanceHistoryFormatBody   = "%s\t%s\t%s\t%t\t\n"
+
+	pendingMaintenanceFormatHeader = "Requested\tHostnames\tKill Grace Period\tMessage\t\n"
+	pendingMaintenanceFormatBody   = "%s\t%s\t%d\t%s\t\n"
+
+	maintenanceApprovalFormatHeader = "Hostname\tRequester\tRequested\tDrained\tApprover\tApproved\t\n"
+	maintenanceApprovalFormatBody   = "%s\t%s\t%s\t%s\t%s\t%s\t\n"
+
+	canaryDrainFormatHeader = "ID\tState\tCanary Hosts\tRemaining Hosts\tStarted\tObservation End\tReschedule Rate\tMessage\t\n"
+	canaryDrainFormatBody   = "%s\t%s\t%s\t%s\t%s\t%s\t%.2f\t%s\t\n"
+
+	hostEventsFormatHeader = "Time\tEvent\tMessage\t\n"
+	hostEventsFormatBody   = "%s\t%s\t%s\t\n"
+
+	drainingTaskFormatHeader = "Hostname\tTask ID\tJob ID\tInstance\tEvicting Since\tResisting\t\n"
+	drainingTaskFormatBody   = "%s\t%s\t%s\t%d\t%s\t%s\t\n"
+
+	exemptTaskFormatHeader = "Hostname\tTask ID\tJob ID\tInstance\tReason\t\n"
+	exemptTaskFormatBody   = "%s\t%s\t%s\t%d\t%s\t\n"
+
+	maintenancePolicyFormatHeader = "Name\tMax Draining Hosts\tKill Grace Period\tDrain Timeout\tDrain Method\tRequire Approval\tDescription\t\n"
+	maintenancePolicyFormatBody   = "%s\t%d\t%d\t%s\t%s\t%t\t%s\t\n"
 )
 
+// drainMethods are the drain methods of hosts by name. An empty name
+// leaves the drain method to host manager.
+var drainMethods = map[string]host.DrainMethod{
+	"":                     host.DrainMethod_DRAIN_METHOD_DEFAULT,
+	"maintenance_schedule": host.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE,
+	"agent_drain":          host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+}
+
 // HostMaintenanceStartAction is the action for starting host maintenance. StartMaintenance puts the host(s)
 // into DRAINING state by posting a maintenance schedule to Mesos Master. Inverse offers are sent out and
 // all future offers from the(se) host(s) are tagged with unavailability (Please check Mesos Maintenance
 // Primitives for more info). The hosts are first drained of tasks before they are put into maintenance
 // by posting to /machine/down endpoint of Mesos Master.
 // The hosts transition from UP to DRAINING and finally to DOWN.
-func (c *Client) HostMaintenanceStartAction(hosts string) error {
-	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
+// The kill grace period, message and labels are the optional drain options
+// the tasks on the hosts are terminated with. The drain method selects
+// between the maintenance schedule and draining the agents with Mesos Master.
+// With requireApproval, the drained hosts stay DRAINED until their maintenance is approved by another user.
+// With canaryCount, only the first canaryCount hosts are drained first, and the remaining hosts are drained once
+// at least canaryMinRescheduleRate of their tasks were rescheduled within canaryObservation after they are DOWN.
+// With drainTimeout, the hosts are put down once they were draining for that long, even if tasks still run on them.
+// With policy, the hosts are drained with the drain options of the named maintenance policy instead.
+// The tasks of the exemptJobTypes, with one of the exemptLabels, or of controller jobs with exemptControllers,
+// are exempt from eviction and are not rescheduled by the drain. The exempt tasks are printed.
+// With dryRun, the changes the request would make are printed without being made.
+// The hosts are read from both hosts and file, if set, and can also be given by the agentIDs of their Mesos agents.
+// With watch, the host state transitions are printed until all hosts are DOWN, or watchTimeout expires if set.
+func (c *Client) HostMaintenanceStartAction(
+	hosts string,
+	file string,
+	agentIDs string,
+	killGracePeriodSeconds uint32,
+	message string,
+	labels string,
+	drainMethod string,
+	requireApproval bool,
+	canaryCount uint32,
+	canaryObservation time.Duration,
+	canaryMinRescheduleRate float64,
+	drainTimeout time.Duration,
+	policy string,
+	exemptJobTypes string,
+	exemptLabels string,
+	exemptControllers bool,
+	dryRun bool,
+	watch bool,
+	watchTimeout time.Duration) error {
+	hostnames, ids, err := c.readHostsAndAgentIDs(hosts, file, agentIDs)
+	if err != nil {
+		return err
+	}
+	method, ok := drainMethods[drainMethod]
+	if !ok {
+		return fmt.Errorf("unknown drain method %q", drainMethod)
+	}
+	exemption, err := parseEvictionExemption(
+		exemptJobTypes,
+		exemptLabels,
+		exemptControllers)
 	if err != nil {
 		return err
 	}
+	hasDrainOptions := killGracePeriodSeconds > 0 || message != "" ||
+		labels != "" || method != host.DrainMethod_DRAIN_METHOD_DEFAULT ||
+		requireApproval || canaryCount > 0 || drainTimeout > 0 ||
+		exemption != nil
+	if policy != "" {
+		if hasDrainOptions {
+			return fmt.Errorf("drain options cannot be set with a maintenance policy")
+		}
+		if err := c.requireHostServiceField("StartMaintenance", "policy"); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		if err := c.requireHostServiceField("StartMaintenance", "dry_run"); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		if err := c.requireHostServiceField("StartMaintenance", "agent_ids"); err != nil {
+			return err
+		}
+	}
 
 	request := &host_svc.StartMaintenanceRequest{
 		Hostnames: hostnames,
+		AgentIds:  ids,
+		DryRun:    dryRun,
+		Policy:    policy,
 	}
-	_, err = c.hostClient.StartMaintenance(c.ctx, request)
+	if hasDrainOptions {
+		request.DrainOptions = &host.DrainOptions{
+			KillGracePeriodSeconds: killGracePeriodSeconds,
+			Message:                message,
+			Method:                 method,
+			RequireApproval:        requireApproval,
+			DrainTimeoutSeconds:    uint32(drainTimeout.Seconds()),
+			Exemption:              exemption,
+		}
+		if labels != "" {
+			request.DrainOptions.Labels, err = parsePelotonLabels(labels)
+			if err != nil {
+				return err
+			}
+		}
+		if canaryCount > 0 {
+			request.DrainOptions.Canary = &host.CanaryOptions{
+				Count:              canaryCount,
+				ObservationSeconds: uint32(canaryObservation.Seconds()),
+				MinRescheduleRate:  canaryMinRescheduleRate,
+			}
+		}
+	}
+	response, err := c.hostClient.StartMaintenance(c.ctx, request)
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(tabWriter, "Started draining hosts\n")
+	hostnames = applyHostnameMappings(
+		append(hostnames, ids...), response.GetHostnameMappings())
+	printExemptTasks(response.GetExemptTasks())
+	if dryRun {
+		if response.GetQueued() {
+			fmt.Fprintf(tabWriter,
+				"Maintenance is frozen, hosts would be queued until released\n")
+		}
+		printMaintenanceDryRun(response.GetDryRun())
+		return nil
+	}
+	if response.GetQueued() {
+		fmt.Fprintf(tabWriter,
+			"Maintenance is frozen, queued hosts until released\n")
+		tabWriter.Flush()
+		return nil
+	}
+	if id := response.GetCanaryDrainId(); id != "" {
+		fmt.Fprintf(tabWriter,
+			"Started draining canary hosts, canary drain %s\n", id)
+	} else {
+		fmt.Fprintf(tabWriter, "Started draining hosts\n")
+	}
+	if skipped := response.GetSkippedHostnames(); len(skipped) > 0 {
+		fmt.Fprintf(tabWriter,
+			"Hosts already queued or dead-lettered, not enqueued again: %s\n",
+			strings.Join(skipped, ", "))
+	}
 	tabWriter.Flush()
+
+	if watch {
+		return c.watchHostStates(hostnames, isHostDown, watchTimeout)
+	}
 	return nil
 }
 
+// parseEvictionExemption returns the eviction exemption of the comma
+// separated job types and labels, or nil if no task is exempt. The labels
+// are key=value pairs, or keys exempting the tasks with any value of the
+// label.
+func parseEvictionExemption(
+	jobTypes string,
+	labels string,
+	controllers bool) (*host.EvictionExemption, error) {
+	if jobTypes == "" && labels == "" && !controllers {
+		return nil, nil
+	}
+
+	exemption := &host.EvictionExemption{ControllerTasks: controllers}
+	if jobTypes != "" {
+		for _, name := range strings.Split(jobTypes, labelSeparator) {
+			jobType, ok := job.JobType_value[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown job type %q", name)
+			}
+			exemption.JobTypes = append(exemption.JobTypes, job.JobType(jobType))
+		}
+	}
+	if labels != "" {
+		for _, l := range strings.Split(labels, labelSeparator) {
+			keyVal := strings.SplitN(l, keyValSeparator, 2)
+			if keyVal[0] == "" {
+				return nil, fmt.Errorf("invalid label %q", l)
+			}
+			label := &peloton.Label{Key: keyVal[0]}
+			if len(keyVal) == 2 {
+				label.Value = keyVal[1]
+			}
+			exemption.Labels = append(exemption.Labels, label)
+		}
+	}
+	return exemption, nil
+}
+
+// printExemptTasks prints the tasks exempt from eviction by a maintenance
+// request.
+func printExemptTasks(tasks []*host.ExemptTask) {
+	if len(tasks) == 0 {
+		return
+	}
+	fmt.Fprintf(tabWriter, "Tasks exempt from eviction:\n")
+	fmt.Fprint(tabWriter, exemptTaskFormatHeader)
+	for _, t := range tasks {
+		fmt.Fprintf(tabWriter, exemptTaskFormatBody,
+			t.GetHostname(),
+			t.GetTaskId(),
+			t.GetJobId().GetValue(),
+			t.GetInstanceId(),
+			t.GetReason())
+	}
+	tabWriter.Flush()
+}
+
+// printMaintenanceDryRun prints the changes a maintenance request run with
+// dry run would make.
+func printMaintenanceDryRun(dryRun *host_svc.MaintenanceDryRun) {
+	fmt.Fprintf(tabWriter, "Dry run, nothing was changed\n")
+	for _, line := range dryRun.GetScheduleDiff() {
+		fmt.Fprintf(tabWriter, "Maintenance schedule: %s\n", line)
+	}
+	for _, hosts := range []struct {
+		description string
+		hostnames   []string
+	}{
+		{"Hosts enqueued to be drained", dryRun.GetEnqueuedHostnames()},
+		{"Agents drained by Mesos Master", dryRun.GetDrainedAgentHostnames()},
+		{"Agents reactivated", dryRun.GetReactivatedAgentHostnames()},
+		{"Hosts rebooted", dryRun.GetRebootedHostnames()},
+	} {
+		if len(hosts.hostnames) > 0 {
+			fmt.Fprintf(tabWriter, "%s: %s\n",
+				hosts.description, strings.Join(hosts.hostnames, ", "))
+		}
+	}
+	tabWriter.Flush()
+}
+
 // HostMaintenanceCompleteAction is the action for completing host maintenance. Complete maintenance brings UP a host
 // which is in maintenance by posting to /machine/up endpoint of Mesos Master i.e. the machine transitions from DOWN to
 // UP state (Please check Mesos Maintenance Primitives for more info)
-func (c *Client) HostMaintenanceCompleteAction(hosts string) error {
-	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
+// With reboot, the machines of the hosts are first rebooted by the host provider of host manager.
+// With dryRun, the changes the request would make are printed without being made.
+// The hosts are read from both hosts and file, if set, and can also be given by the agentIDs of their Mesos agents.
+// With watch, the host state transitions are printed until all hosts are UP, or watchTimeout expires if set.
+func (c *Client) HostMaintenanceCompleteAction(
+	hosts string,
+	file string,
+	agentIDs string,
+	reboot bool,
+	dryRun bool,
+	watch bool,
+	watchTimeout time.Duration) error {
+	hostnames, ids, err := c.readHostsAndAgentIDs(hosts, file, agentIDs)
 	if err != nil {
 		return err
 	}
 
+	if dryRun {
+		if err := c.requireHostServiceField("CompleteMaintenance", "dry_run"); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		if err := c.requireHostServiceField("CompleteMaintenance", "agent_ids"); err != nil {
+			return err
+		}
+	}
+
 	request := &host_svc.CompleteMaintenanceRequest{
 		Hostnames: hostnames,
+		AgentIds:  ids,
+		Reboot:    reboot,
+		DryRun:    dryRun,
+	}
+	response, err := c.hostClient.CompleteMaintenance(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	applyHostnameMappings(
+		append(hostnames, ids...), response.GetHostnameMappings())
+	completed, failed := "Maintenance completed", "Failed to complete maintenance of"
+	if dryRun {
+		completed, failed = "Maintenance would be completed",
+			"Would fail to complete maintenance of"
+	}
+	if len(response.GetCompletedHostnames()) > 0 {
+		fmt.Fprintf(tabWriter, "%s: %s\n",
+			completed, strings.Join(response.GetCompletedHostnames(), ", "))
+	}
+	for _, failure := range response.GetFailures() {
+		fmt.Fprintf(tabWriter, "%s %s: %s\n",
+			failed, failure.GetHostname(), failure.GetMessage())
+	}
+	if dryRun {
+		printMaintenanceDryRun(response.GetDryRun())
+		return nil
+	}
+	tabWriter.Flush()
+
+	if watch && len(response.GetCompletedHostnames()) > 0 {
+		if err := c.watchHostStates(
+			response.GetCompletedHostnames(),
+			isHostUp,
+			watchTimeout); err != nil {
+			return err
+		}
+	}
+	if len(response.GetFailures()) > 0 {
+		return fmt.Errorf(
+			"failed to complete maintenance of %d host(s)",
+			len(response.GetFailures()))
+	}
+	return nil
+}
+
+// HostDecommissionAction is the action for decommissioning hosts in maintenance. The machines of the DOWN hosts
+// are terminated by the host provider of host manager, and the hosts are no longer tracked by host manager.
+// The hosts are read from both hosts and file, if set.
+func (c *Client) HostDecommissionAction(hosts string, file string) error {
+	hostnames, err := c.readHostnames(hosts, file)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.hostClient.DecommissionHosts(
+		c.ctx,
+		&host_svc.DecommissionHostsRequest{Hostnames: hostnames})
+	if err != nil {
+		return err
+	}
+
+	applyHostnameMappings(hostnames, response.GetHostnameMappings())
+	if len(response.GetDecommissionedHostnames()) > 0 {
+		fmt.Fprintf(tabWriter, "Decommissioned hosts: %s\n",
+			strings.Join(response.GetDecommissionedHostnames(), ", "))
+	}
+	for _, failure := range response.GetFailures() {
+		fmt.Fprintf(tabWriter, "Failed to decommission %s: %s\n",
+			failure.GetHostname(), failure.GetMessage())
+	}
+	tabWriter.Flush()
+
+	if len(response.GetFailures()) > 0 {
+		return fmt.Errorf(
+			"failed to decommission %d host(s)",
+			len(response.GetFailures()))
+	}
+	return nil
+}
+
+// applyHostnameMappings prints the hostnames which host manager resolved
+// to another hostname, and returns the hostnames with the resolved
+// hostnames in place of the requested ones.
+func applyHostnameMappings(
+	hostnames []string,
+	mappings []*host.HostnameMapping) []string {
+	resolved := make(map[string]string)
+	for _, mapping := range mappings {
+		fmt.Fprintf(tabWriter, "Resolved %s to %s\n",
+			mapping.GetRequested(), mapping.GetHostname())
+		resolved[mapping.GetRequested()] = mapping.GetHostname()
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, hostname := range hostnames {
+		if r, ok := resolved[hostname]; ok {
+			hostname = r
+		}
+		if !seen[hostname] {
+			seen[hostname] = true
+			result = append(result, hostname)
+		}
+	}
+	return result
+}
+
+// HostMaintenanceDeadLettersAction is the action for listing the hosts in the maintenance dead-letter queue.
+// Hosts which fail to drain too many times are moved to the dead-letter queue, and stay DRAINING until
+// they are re-driven.
+func (c *Client) HostMaintenanceDeadLettersAction() error {
+	response, err := c.hostClient.GetMaintenanceDeadLetters(
+		c.ctx,
+		&host_svc.GetMaintenanceDeadLettersRequest{})
+	if err != nil {
+		return err
+	}
+
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetHostnames()) == 0 {
+		fmt.Fprintf(tabWriter, "No hosts found\n")
+	}
+	for _, hostname := range response.GetHostnames() {
+		fmt.Fprintf(tabWriter, "%s\n", hostname)
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenanceRedriveAction is the action for moving hosts from the maintenance dead-letter queue back into
+// the maintenance queue, so that draining them is retried. All dead-lettered hosts are re-driven if no hosts
+// are specified.
+func (c *Client) HostMaintenanceRedriveAction(hosts string) error {
+	var hostnames []string
+	if hosts != "" {
+		var err error
+		hostnames, err = c.ExtractHostnames(hosts, hostSeparator)
+		if err != nil {
+			return err
+		}
+	}
+
+	request := &host_svc.RedriveMaintenanceDeadLettersRequest{
+		Hostnames: hostnames,
+	}
+	_, err := c.hostClient.RedriveMaintenanceDeadLetters(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(tabWriter, "Re-drove hosts\n")
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenanceFreezeAction is the action for freezing or unfreezing maintenance. While maintenance is
+// frozen, start maintenance requests are queued instead of draining the hosts.
+func (c *Client) HostMaintenanceFreezeAction(frozen bool) error {
+	_, err := c.hostClient.SetMaintenanceFreeze(
+		c.ctx,
+		&host_svc.SetMaintenanceFreezeRequest{Frozen: frozen})
+	if err != nil {
+		return err
+	}
+
+	if frozen {
+		fmt.Fprintf(tabWriter, "Maintenance frozen\n")
+	} else {
+		fmt.Fprintf(tabWriter, "Maintenance unfrozen, release pending maintenance to drain queued hosts\n")
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenancePendingAction is the action for listing whether maintenance is frozen, and the start
+// maintenance requests queued while it was.
+func (c *Client) HostMaintenancePendingAction() error {
+	response, err := c.hostClient.GetMaintenanceFreeze(
+		c.ctx,
+		&host_svc.GetMaintenanceFreezeRequest{})
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	fmt.Fprintf(tabWriter, "Maintenance frozen: %t\n", response.GetFrozen())
+	if len(response.GetPending()) == 0 {
+		fmt.Fprintf(tabWriter, "No pending maintenance found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, pendingMaintenanceFormatHeader)
+	for _, p := range response.GetPending() {
+		fmt.Fprintf(
+			tabWriter,
+			pendingMaintenanceFormatBody,
+			p.GetRequestTime(),
+			strings.Join(p.GetHostnames(), ", "),
+			p.GetDrainOptions().GetKillGracePeriodSeconds(),
+			p.GetDrainOptions().GetMessage(),
+		)
+	}
+	return nil
+}
+
+// HostMaintenanceReleaseAction is the action for starting maintenance on the hosts queued while maintenance was
+// frozen, or discarding them. All queued hosts are released if no hosts are specified.
+func (c *Client) HostMaintenanceReleaseAction(hosts string, discard bool) error {
+	var hostnames []string
+	if hosts != "" {
+		var err error
+		hostnames, err = c.ExtractHostnames(hosts, hostSeparator)
+		if err != nil {
+			return err
+		}
+	}
+
+	response, err := c.hostClient.ReleasePendingMaintenance(
+		c.ctx,
+		&host_svc.ReleasePendingMaintenanceRequest{
+			Hostnames: hostnames,
+			Discard:   discard,
+		})
+	if err != nil {
+		return err
+	}
+
+	if len(response.GetHostnames()) == 0 {
+		fmt.Fprintf(tabWriter, "No pending maintenance found\n")
+	} else if discard {
+		fmt.Fprintf(tabWriter, "Discarded pending maintenance: %s\n",
+			strings.Join(response.GetHostnames(), ", "))
+	} else {
+		fmt.Fprintf(tabWriter, "Started draining hosts: %s\n",
+			strings.Join(response.GetHostnames(), ", "))
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenanceUpdateAction is the action for changing the maintenance window of DRAINING hosts. The new start
+// is either start, in RFC3339 format, or startIn from now; the current start of each host is kept if neither is set.
+// The hosts are read from both hosts and file, if set.
+func (c *Client) HostMaintenanceUpdateAction(
+	hosts string,
+	file string,
+	start string,
+	startIn time.Duration,
+	duration time.Duration) error {
+	if start != "" && startIn != 0 {
+		return fmt.Errorf("only one of start and start-in can be set")
+	}
+	if startIn < 0 || duration < 0 {
+		return fmt.Errorf("start-in and duration must not be negative")
+	}
+
+	hostnames, err := c.readHostnames(hosts, file)
+	if err != nil {
+		return err
+	}
+
+	if startIn != 0 {
+		start = time.Now().Add(startIn).UTC().Format(time.RFC3339)
+	}
+	response, err := c.hostClient.UpdateMaintenance(
+		c.ctx,
+		&host_svc.UpdateMaintenanceRequest{
+			Hostnames:       hostnames,
+			StartTime:       start,
+			DurationSeconds: uint32(duration.Seconds()),
+		})
+	if err != nil {
+		return err
+	}
+
+	hostnames = applyHostnameMappings(hostnames, response.GetHostnameMappings())
+	fmt.Fprintf(tabWriter, "Updated maintenance window of hosts: %s\n",
+		strings.Join(hostnames, ", "))
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenanceApproveAction is the action for approving the maintenance of DRAINING hosts started with
+// require approval, so that they go DOWN once drained. The hosts are read from both hosts and file, if set.
+func (c *Client) HostMaintenanceApproveAction(hosts string, file string) error {
+	hostnames, err := c.readHostnames(hosts, file)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.hostClient.ApproveMaintenance(
+		c.ctx,
+		&host_svc.ApproveMaintenanceRequest{Hostnames: hostnames})
+	if err != nil {
+		return err
+	}
+
+	hostnames = applyHostnameMappings(hostnames, response.GetHostnameMappings())
+	fmt.Fprintf(tabWriter, "Approved maintenance of hosts: %s\n",
+		strings.Join(hostnames, ", "))
+	tabWriter.Flush()
+	return nil
+}
+
+// HostMaintenanceApprovalsAction is the action for listing the approvals of the hosts in maintenance which
+// require one, or only the pending ones with pendingOnly.
+func (c *Client) HostMaintenanceApprovalsAction(pendingOnly bool) error {
+	response, err := c.hostClient.GetMaintenanceApprovals(
+		c.ctx,
+		&host_svc.GetMaintenanceApprovalsRequest{PendingOnly: pendingOnly})
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetApprovals()) == 0 {
+		fmt.Fprintf(tabWriter, "No maintenance approvals found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, maintenanceApprovalFormatHeader)
+	for _, approval := range response.GetApprovals() {
+		fmt.Fprintf(
+			tabWriter,
+			maintenanceApprovalFormatBody,
+			approval.GetHostname(),
+			approval.GetRequester(),
+			approval.GetRequestTime(),
+			approval.GetDrainedTime(),
+			approval.GetApprover(),
+			approval.GetApproveTime(),
+		)
+	}
+	return nil
+}
+
+// HostMaintenanceCanariesAction is the action for listing the canary drains started by host maintenance start,
+// with the reschedule rate of the tasks of their canary hosts once observed.
+func (c *Client) HostMaintenanceCanariesAction() error {
+	response, err := c.hostClient.GetCanaryDrains(
+		c.ctx,
+		&host_svc.GetCanaryDrainsRequest{})
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetCanaryDrains()) == 0 {
+		fmt.Fprintf(tabWriter, "No canary drains found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, canaryDrainFormatHeader)
+	for _, drain := range response.GetCanaryDrains() {
+		fmt.Fprintf(
+			tabWriter,
+			canaryDrainFormatBody,
+			drain.GetId(),
+			strings.TrimPrefix(drain.GetState().String(), "CANARY_DRAIN_STATE_"),
+			strings.Join(drain.GetCanaryHostnames(), hostSeparator),
+			strings.Join(drain.GetRemainingHostnames(), hostSeparator),
+			drain.GetStartTime(),
+			drain.GetObservationEndTime(),
+			drain.GetRescheduleRate(),
+			drain.GetMessage(),
+		)
+	}
+	return nil
+}
+
+// HostMaintenancePoliciesAction is the action for listing the maintenance policies referenced by start maintenance.
+func (c *Client) HostMaintenancePoliciesAction() error {
+	response, err := c.hostClient.GetMaintenancePolicies(
+		c.ctx,
+		&host_svc.GetMaintenancePoliciesRequest{})
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetPolicies()) == 0 {
+		fmt.Fprintf(tabWriter, "No maintenance policies found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, maintenancePolicyFormatHeader)
+	for _, policy := range response.GetPolicies() {
+		options := policy.GetDrainOptions()
+		fmt.Fprintf(
+			tabWriter,
+			maintenancePolicyFormatBody,
+			policy.GetName(),
+			policy.GetMaxDrainingHosts(),
+			options.GetKillGracePeriodSeconds(),
+			time.Duration(options.GetDrainTimeoutSeconds())*time.Second,
+			strings.TrimPrefix(options.GetMethod().String(), "DRAIN_METHOD_"),
+			options.GetRequireApproval(),
+			policy.GetDescription(),
+		)
+	}
+	return nil
+}
+
+// HostMaintenanceHistoryAction is the action for listing the maintenance state transitions of a host, oldest
+// first. Transitions older than the archive age of the archiver are read from the maintenance history.
+func (c *Client) HostMaintenanceHistoryAction(hostname string) error {
+	response, err := c.hostClient.GetMaintenanceHistory(
+		c.ctx,
+		&host_svc.GetMaintenanceHistoryRequest{Hostname: hostname})
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetEvents()) == 0 {
+		fmt.Fprintf(tabWriter, "No maintenance history found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, maintenanceHistoryFormatHeader)
+	for _, event := range response.GetEvents() {
+		fmt.Fprintf(
+			tabWriter,
+			maintenanceHistoryFormatBody,
+			event.GetEventTime(),
+			event.GetFromState().String(),
+			event.GetToState().String(),
+			event.GetArchived(),
+		)
+	}
+	return nil
+}
+
+// HostEventsAction is the action for listing the timeline of the events of a host, oldest first. Only the events
+// of the last since duration are listed if set.
+func (c *Client) HostEventsAction(hostname string, since time.Duration) error {
+	request := &host_svc.GetHostEventsRequest{Hostname: hostname}
+	if since > 0 {
+		request.Since = time.Now().Add(-since).UTC().Format(time.RFC3339)
+	}
+	response, err := c.hostClient.GetHostEvents(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	if len(response.GetEvents()) == 0 {
+		fmt.Fprintf(tabWriter, "No host events found\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, hostEventsFormatHeader)
+	for _, event := range response.GetEvents() {
+		fmt.Fprintf(
+			tabWriter,
+			hostEventsFormatBody,
+			event.GetEventTime(),
+			strings.TrimPrefix(event.GetType().String(), "HOST_EVENT_TYPE_"),
+			event.GetMessage(),
+		)
+	}
+	return nil
+}
+
+// HostReservationCreateAction is the action for dynamically reserving resources on a host for a role, and
+// optionally creating a persistent volume on the reserved disk. The reservation can be bound to an instance of
+// a stateful job, in which case only that instance is launched on the reserved resources.
+func (c *Client) HostReservationCreateAction(
+	hostname string,
+	role string,
+	cpus float64,
+	memMb float64,
+	diskMb float64,
+	gpus float64,
+	volumeContainerPath string,
+	jobID string,
+	instanceID uint32) error {
+	request := &host_svc.CreateReservationRequest{
+		Hostname: hostname,
+		Role:     role,
+		Spec: &host.ReservationSpec{
+			Cpus:                cpus,
+			MemMb:               memMb,
+			DiskMb:              diskMb,
+			Gpus:                gpus,
+			VolumeContainerPath: volumeContainerPath,
+		},
+		JobId:      jobID,
+		InstanceId: instanceID,
+	}
+	response, err := c.hostClient.CreateReservation(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	printReservations([]*host.Reservation{response.GetReservation()}, c.Debug)
+	return nil
+}
+
+// HostReservationListAction is the action for listing the dynamic reservations made on the hosts, or on all
+// registered hosts if no hosts are specified.
+func (c *Client) HostReservationListAction(hosts string, role string) error {
+	var hostnames []string
+	if hosts != "" {
+		var err error
+		hostnames, err = c.ExtractHostnames(hosts, hostSeparator)
+		if err != nil {
+			return err
+		}
+	}
+
+	request := &host_svc.ListReservationsRequest{
+		Hostnames: hostnames,
+		Role:      role,
+	}
+	response, err := c.hostClient.ListReservations(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	printReservations(response.GetReservations(), c.Debug)
+	return nil
+}
+
+// HostReservationReleaseAction is the action for releasing a dynamic reservation. The persistent volume created
+// on the reservation, if any, is destroyed as well.
+func (c *Client) HostReservationReleaseAction(hostname string, reservationID string) error {
+	request := &host_svc.ReleaseReservationRequest{
+		Hostname:      hostname,
+		ReservationId: reservationID,
 	}
-	_, err = c.hostClient.CompleteMaintenance(c.ctx, request)
+	_, err := c.hostClient.ReleaseReservation(c.ctx, request)
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(tabWriter, "Maintenance completed\n")
+	fmt.Fprintf(tabWriter, "Released reservation %s\n", reservationID)
 	tabWriter.Flush()
 	return nil
 }
 
+func printReservations(reservations []*host.Reservation, debug bool) {
+	defer tabWriter.Flush()
+
+	if debug {
+		printResponseJSON(reservations)
+		return
+	}
+	if len(reservations) == 0 {
+		fmt.Fprintf(tabWriter, "No reservations found\n")
+		return
+	}
+	fmt.Fprintf(tabWriter, reservationFormatHeader)
+	for _, r := range reservations {
+		fmt.Fprintf(
+			tabWriter,
+			reservationFormatBody,
+			r.GetHostname(),
+			r.GetReservationId(),
+			r.GetRole(),
+			r.GetSpec().GetCpus(),
+			r.GetSpec().GetMemMb(),
+			r.GetSpec().GetDiskMb(),
+			r.GetSpec().GetGpus(),
+			r.GetVolumeId(),
+			r.GetJobId(),
+			r.GetInstanceId(),
+			r.GetCreationTime(),
+		)
+	}
+}
+
 // HostQueryAction is the action for querying hosts by states. This can be to used to monitor the state of the host(s)
 // Eg. When a list of hosts are put into maintenance (`host maintenance start`).
 // A host, at any given time, will be in one of the following states
@@ -90,7 +937,9 @@ func (c *Client) HostMaintenanceCompleteAction(hosts string) error {
 // 										  there will be no further placement of tasks on the host
 //		3.HostState_HOST_STATE_DRAINED - There are no tasks running on this host and it is ready to be 'DOWN'ed
 // 		4.HostState_HOST_STATE_DOWN - The host is in maintenance.
-func (c *Client) HostQueryAction(states string) error {
+// With taskDetails, the tasks still running on draining hosts are printed,
+// to find the tasks blocking their drain.
+func (c *Client) HostQueryAction(states string, taskDetails bool) error {
 	var hostStates []host.HostState
 	for _, state := range strings.Split(states, hostSeparator) {
 		if state != "" {
@@ -98,8 +947,15 @@ func (c *Client) HostQueryAction(states string) error {
 		}
 	}
 
+	if taskDetails {
+		if err := c.requireHostServiceField("QueryHosts", "include_task_details"); err != nil {
+			return err
+		}
+	}
+
 	request := &host_svc.QueryHostsRequest{
-		HostStates: hostStates,
+		HostStates:         hostStates,
+		IncludeTaskDetails: taskDetails,
 	}
 	response, err := c.hostClient.QueryHosts(c.ctx, request)
 	if err != nil {
@@ -128,17 +984,57 @@ func printHostQueryResponse(r *host_svc.QueryHostsResponse, debug bool) {
 				h.GetState(),
 			)
 		}
+		printDrainingTasks(r.GetHostInfos())
 	}
 	tabWriter.Flush()
 }
 
+// printDrainingTasks prints the tasks still running on draining hosts,
+// the ones resisting draining the longest first.
+func printDrainingTasks(hostInfos []*host.HostInfo) {
+	type drainingTask struct {
+		hostname string
+		task     *host.DrainingTask
+	}
+	var tasks []drainingTask
+	for _, h := range hostInfos {
+		for _, t := range h.GetDrainingTasks() {
+			tasks = append(tasks, drainingTask{hostname: h.GetHostname(), task: t})
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].task.GetResistingSeconds() >
+			tasks[j].task.GetResistingSeconds()
+	})
+
+	fmt.Fprintf(tabWriter, "\n")
+	fmt.Fprintf(tabWriter, drainingTaskFormatHeader)
+	for _, t := range tasks {
+		fmt.Fprintf(
+			tabWriter,
+			drainingTaskFormatBody,
+			t.hostname,
+			t.task.GetTaskId(),
+			t.task.GetJobId().GetValue(),
+			t.task.GetInstanceId(),
+			t.task.GetEvictingSince(),
+			time.Duration(t.task.GetResistingSeconds())*time.Second,
+		)
+	}
+}
+
 // HostsGetAction prints all the hosts based on resource requirement
-// passed in.
+// passed in. The requirement is compared with the revocable resources
+// of the hosts if revocable is set.
 func (c *Client) HostsGetAction(
 	cpu float64,
 	gpu float64,
 	cmpLess bool,
-	hosts string) error {
+	hosts string,
+	revocable bool) error {
 	var hostnames []string
 	var err error
 
@@ -160,12 +1056,29 @@ func (c *Client) HostsGetAction(
 			Resource:  resourceConfig,
 			CmpLess:   cmpLess,
 			Hostnames: hostnames,
+			Revocable: revocable,
 		})
 
 	printGetHostsResponse(resp)
 	return nil
 }
 
+// formatCustomScalars returns the custom scalar resources as a sorted,
+// comma separated list of name=value pairs.
+func formatCustomScalars(resources []*mesos.Resource) string {
+	custom := scalar.CustomScalarsFromMesosResources(resources)
+	if len(custom) == 0 {
+		return "-"
+	}
+
+	var pairs []string
+	for name, value := range custom {
+		pairs = append(pairs, fmt.Sprintf("%s=%.2f", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func printGetHostsResponse(resp *hostsvc.GetHostsByQueryResponse) {
 	defer tabWriter.Flush()
 
@@ -180,6 +1093,7 @@ func printGetHostsResponse(resp *hostsvc.GetHostsByQueryResponse) {
 		fmt.Fprint(tabWriter, getHostsFormatHeader)
 		for _, host := range hosts {
 			resource := scalar.FromMesosResources(host.GetResources())
+			revocable := scalar.FromMesosResources(host.GetRevocableResources())
 			fmt.Fprintf(tabWriter,
 				getHostsFormatBody,
 				host.GetHostname(),
@@ -187,6 +1101,8 @@ func printGetHostsResponse(resp *hostsvc.GetHostsByQueryResponse) {
 				resource.GetGPU(),
 				resource.GetMem(),
 				resource.GetDisk(),
+				revocable.GetCPU(),
+				formatCustomScalars(host.GetResources()),
 				host.GetStatus())
 		}
 	}
@@ -18,11 +18,14 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	pb_task "github.com/uber/peloton/.gen/peloton/api/v0/task"
 	hostmgrsvc "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	hostmgrMocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
@@ -69,26 +72,167 @@ func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceStartAction() {
 	suite.mockHostmgr.EXPECT().
 		StartMaintenance(gomock.Any(), gomock.Any()).
 		Return(resp, nil)
-	err := c.HostMaintenanceStartAction("hostname")
+	err := c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test request queued while maintenance is frozen, which is not
+	// watched
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.StartMaintenanceResponse{Queued: true}, nil)
+	err = c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, true, 0)
 	suite.NoError(err)
 
 	// Test StartMaintenance error
 	suite.mockHostmgr.EXPECT().
 		StartMaintenance(gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("fake StartMaintenance error"))
-	err = c.HostMaintenanceStartAction("hostname")
+	err = c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	// Test empty hostname error
-	err = c.HostMaintenanceStartAction("")
+	err = c.HostMaintenanceStartAction("", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	//Test duplicate hostname error
-	err = c.HostMaintenanceStartAction("hostname, hostname")
+	err = c.HostMaintenanceStartAction("hostname, hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	// Test invalid input error
-	err = c.HostMaintenanceStartAction("hostname,,")
+	err = c.HostMaintenanceStartAction("hostname,,", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.Error(err)
+
+	// Test drain options
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DrainOptions: &host.DrainOptions{
+				KillGracePeriodSeconds: 60,
+				Message:                "deregister",
+				Labels: []*peloton.Label{
+					{Key: "reason", Value: "upgrade"},
+				},
+			},
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 60, "deregister", "reason=upgrade", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test invalid drain labels
+	err = c.HostMaintenanceStartAction("hostname", "", "", 0, "", "reason", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.Error(err)
+
+	// Test drain method
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DrainOptions: &host.DrainOptions{
+				Method: host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			},
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "agent_drain", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test invalid drain method
+	err = c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "unknown", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.Error(err)
+
+	// Test maintenance policy and drain timeout
+	suite.expectHostAPIInfo(1)
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			Policy:    "kernel-upgrade",
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "kernel-upgrade", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DrainOptions: &host.DrainOptions{
+				DrainTimeoutSeconds: 3600,
+			},
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, time.Hour, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test maintenance policy with drain options error
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 60, "", "", "", false, 0, 0, 0, 0, "kernel-upgrade", "", "", false, false, false, 0)
+	suite.Error(err)
+
+	// Test requiring approval
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DrainOptions: &host.DrainOptions{
+				RequireApproval: true,
+			},
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "", true, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test canary drain
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname1", "hostname2"},
+			DrainOptions: &host.DrainOptions{
+				Canary: &host.CanaryOptions{
+					Count:              1,
+					ObservationSeconds: 600,
+					MinRescheduleRate:  0.9,
+				},
+			},
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{CanaryDrainId: "canary1"}, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname1,hostname2", "", "", 0, "", "", "", false, 1, 10*time.Minute, 0.9, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test eviction exemptions, the exempt tasks are printed
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DrainOptions: &host.DrainOptions{
+				Exemption: &host.EvictionExemption{
+					JobTypes: []job.JobType{job.JobType_DAEMON},
+					Labels: []*peloton.Label{
+						{Key: "role", Value: "ingress"},
+						{Key: "infra"},
+					},
+					ControllerTasks: true,
+				},
+			},
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{
+			ExemptTasks: []*host.ExemptTask{
+				{
+					Hostname:   "hostname",
+					TaskId:     "job1-0-1",
+					JobId:      &peloton.JobID{Value: "job1"},
+					InstanceId: 0,
+					Reason:     "job type DAEMON",
+				},
+			},
+		}, nil)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "daemon", "role=ingress,infra", true, false, false, 0)
+	suite.NoError(err)
+
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "unknown", "", false, false, false, 0)
+	suite.Error(err)
+	err = c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "=ingress", false, false, false, 0)
 	suite.Error(err)
 }
 
@@ -105,26 +249,730 @@ func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceCompleteAction()
 	suite.mockHostmgr.EXPECT().
 		CompleteMaintenance(gomock.Any(), gomock.Any()).
 		Return(resp, nil)
-	err := c.HostMaintenanceCompleteAction("hostname")
+	err := c.HostMaintenanceCompleteAction("hostname", "", "", false, false, false, 0)
 	suite.NoError(err)
 
+	// Test rebooting the hosts
+	suite.mockHostmgr.EXPECT().
+		CompleteMaintenance(gomock.Any(), &hostsvc.CompleteMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			Reboot:    true,
+		}).
+		Return(resp, nil)
+	err = c.HostMaintenanceCompleteAction("hostname", "", "", true, false, false, 0)
+	suite.NoError(err)
+
+	// Test hosts which maintenance could not be completed on
+	suite.mockHostmgr.EXPECT().
+		CompleteMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			Failures: []*hostsvc.CompleteMaintenanceFailure{
+				{Hostname: "hostname", Message: "host is not DOWN"},
+			},
+		}, nil)
+	err = c.HostMaintenanceCompleteAction("hostname", "", "", false, false, false, 0)
+	suite.Error(err)
+
 	//Test CompleteMaintenance error
 	suite.mockHostmgr.EXPECT().
 		CompleteMaintenance(gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("fake CompleteMaintenance error"))
-	err = c.HostMaintenanceCompleteAction("hostname")
+	err = c.HostMaintenanceCompleteAction("hostname", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	// Test empty hostname error
-	err = c.HostMaintenanceCompleteAction("")
+	err = c.HostMaintenanceCompleteAction("", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	//Test duplicate hostname error
-	err = c.HostMaintenanceCompleteAction("hostname, hostname")
+	err = c.HostMaintenanceCompleteAction("hostname, hostname", "", "", false, false, false, 0)
 	suite.Error(err)
 
 	// Test invalid input error
-	err = c.HostMaintenanceStartAction("hostname,,")
+	err = c.HostMaintenanceStartAction("hostname,,", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.Error(err)
+}
+
+// TestClientHostMaintenanceDryRun tests that dry runs of maintenance
+// requests are not watched.
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceDryRun() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.expectHostAPIInfo(2)
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DryRun:    true,
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{
+			DryRun: &hostsvc.MaintenanceDryRun{
+				ScheduleDiff:      []string{"+ hostname (10.0.0.1) from now"},
+				EnqueuedHostnames: []string{"hostname"},
+			},
+		}, nil)
+	err := c.HostMaintenanceStartAction("hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, true, true, 0)
+	suite.NoError(err)
+
+	suite.mockHostmgr.EXPECT().
+		CompleteMaintenance(gomock.Any(), &hostsvc.CompleteMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+			DryRun:    true,
+		}).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			CompletedHostnames: []string{"hostname"},
+			DryRun:             &hostsvc.MaintenanceDryRun{},
+		}, nil)
+	err = c.HostMaintenanceCompleteAction("hostname", "", "", false, true, true, 0)
+	suite.NoError(err)
+}
+
+// TestClientHostMaintenanceAgentIDs tests giving hosts by the agent ids of
+// their Mesos agents.
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceAgentIDs() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.expectHostAPIInfo(2)
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			AgentIds: []string{"agent1", "agent2"},
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{
+			HostnameMappings: []*host.HostnameMapping{
+				{Requested: "agent1", Hostname: "hostname1"},
+				{Requested: "agent2", Hostname: "hostname2"},
+			},
+		}, nil)
+	err := c.HostMaintenanceStartAction("", "", "agent2,agent1", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.NoError(err)
+
+	suite.mockHostmgr.EXPECT().
+		CompleteMaintenance(gomock.Any(), &hostsvc.CompleteMaintenanceRequest{
+			Hostnames: []string{"hostname1"},
+			AgentIds:  []string{"agent2"},
+		}).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			HostnameMappings: []*host.HostnameMapping{
+				{Requested: "agent2", Hostname: "hostname2"},
+			},
+			CompletedHostnames: []string{"hostname1", "hostname2"},
+		}, nil)
+	err = c.HostMaintenanceCompleteAction("hostname1", "", "agent2", false, false, false, 0)
+	suite.NoError(err)
+
+	// Test duplicate agent ids
+	err = c.HostMaintenanceStartAction("", "", "agent1,agent1", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, false, 0)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceApproveAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		ApproveMaintenance(gomock.Any(), &hostsvc.ApproveMaintenanceRequest{
+			Hostnames: []string{"hostname"},
+		}).
+		Return(&hostsvc.ApproveMaintenanceResponse{}, nil)
+	err := c.HostMaintenanceApproveAction("hostname", "")
+	suite.NoError(err)
+
+	// Test ApproveMaintenance error
+	suite.mockHostmgr.EXPECT().
+		ApproveMaintenance(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake ApproveMaintenance error"))
+	err = c.HostMaintenanceApproveAction("hostname", "")
+	suite.Error(err)
+
+	// Test empty hostname error
+	err = c.HostMaintenanceApproveAction("", "")
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceApprovalsAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceApprovals(gomock.Any(), &hostsvc.GetMaintenanceApprovalsRequest{
+			PendingOnly: true,
+		}).
+		Return(&hostsvc.GetMaintenanceApprovalsResponse{
+			Approvals: []*host.MaintenanceApproval{
+				{
+					Hostname:    "hostname",
+					Requester:   "alice",
+					RequestTime: "2019-01-01T00:00:00Z",
+				},
+			},
+		}, nil)
+	suite.NoError(c.HostMaintenanceApprovalsAction(true))
+
+	// Test no approvals
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceApprovals(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenanceApprovalsResponse{}, nil)
+	suite.NoError(c.HostMaintenanceApprovalsAction(false))
+
+	// Test GetMaintenanceApprovals error
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceApprovals(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMaintenanceApprovals error"))
+	suite.Error(c.HostMaintenanceApprovalsAction(false))
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceCanariesAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetCanaryDrains(gomock.Any(), &hostsvc.GetCanaryDrainsRequest{}).
+		Return(&hostsvc.GetCanaryDrainsResponse{
+			CanaryDrains: []*host.CanaryDrain{
+				{
+					Id:                 "canary1",
+					CanaryHostnames:    []string{"hostname1"},
+					RemainingHostnames: []string{"hostname2"},
+					State:              host.CanaryDrainState_CANARY_DRAIN_STATE_PROCEEDED,
+					StartTime:          "2019-01-01T00:00:00Z",
+					ObservationEndTime: "2019-01-01T01:00:00Z",
+					RescheduleRate:     1,
+					Message:            "reschedule rate 1.00",
+				},
+			},
+		}, nil)
+	suite.NoError(c.HostMaintenanceCanariesAction())
+
+	// Test no canary drains
+	suite.mockHostmgr.EXPECT().
+		GetCanaryDrains(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetCanaryDrainsResponse{}, nil)
+	suite.NoError(c.HostMaintenanceCanariesAction())
+
+	// Test GetCanaryDrains error
+	suite.mockHostmgr.EXPECT().
+		GetCanaryDrains(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetCanaryDrains error"))
+	suite.Error(c.HostMaintenanceCanariesAction())
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenancePoliciesAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetMaintenancePolicies(gomock.Any(), &hostsvc.GetMaintenancePoliciesRequest{}).
+		Return(&hostsvc.GetMaintenancePoliciesResponse{
+			Policies: []*host.MaintenancePolicy{
+				{
+					Name: "kernel-upgrade",
+					DrainOptions: &host.DrainOptions{
+						KillGracePeriodSeconds: 30,
+						DrainTimeoutSeconds:    3600,
+					},
+					MaxDrainingHosts: 10,
+				},
+				{Name: "no-options"},
+			},
+		}, nil)
+	suite.NoError(c.HostMaintenancePoliciesAction())
+
+	// Test no maintenance policies
+	suite.mockHostmgr.EXPECT().
+		GetMaintenancePolicies(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenancePoliciesResponse{}, nil)
+	suite.NoError(c.HostMaintenancePoliciesAction())
+
+	// Test GetMaintenancePolicies error
+	suite.mockHostmgr.EXPECT().
+		GetMaintenancePolicies(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMaintenancePolicies error"))
+	suite.Error(c.HostMaintenancePoliciesAction())
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostDecommissionAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		DecommissionHosts(gomock.Any(), &hostsvc.DecommissionHostsRequest{
+			Hostnames: []string{"hostname"},
+		}).
+		Return(&hostsvc.DecommissionHostsResponse{
+			DecommissionedHostnames: []string{"hostname"},
+		}, nil)
+	suite.NoError(c.HostDecommissionAction("hostname", ""))
+
+	// Test hosts which could not be decommissioned
+	suite.mockHostmgr.EXPECT().
+		DecommissionHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.DecommissionHostsResponse{
+			Failures: []*hostsvc.DecommissionHostsFailure{
+				{Hostname: "hostname", Message: "host is not DOWN"},
+			},
+		}, nil)
+	suite.Error(c.HostDecommissionAction("hostname", ""))
+
+	// Test DecommissionHosts error
+	suite.mockHostmgr.EXPECT().
+		DecommissionHosts(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake DecommissionHosts error"))
+	suite.Error(c.HostDecommissionAction("hostname", ""))
+
+	// Test empty hostname error
+	suite.Error(c.HostDecommissionAction("", ""))
+}
+
+// TestApplyHostnameMappings tests replacing the requested hostnames by
+// the hostnames resolved by host manager
+func (suite *hostmgrActionsTestSuite) TestApplyHostnameMappings() {
+	hostnames := applyHostnameMappings(
+		[]string{"Host1", "10.0.0.1", "host2"},
+		[]*host.HostnameMapping{
+			{Requested: "Host1", Hostname: "host1"},
+			{Requested: "10.0.0.1", Hostname: "host1"},
+		})
+	suite.Equal([]string{"host1", "host2"}, hostnames)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceDeadLettersAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceDeadLetters(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenanceDeadLettersResponse{
+			Hostnames: []string{"hostname"},
+		}, nil)
+	err := c.HostMaintenanceDeadLettersAction()
+	suite.NoError(err)
+
+	// Test GetMaintenanceDeadLetters error
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceDeadLetters(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMaintenanceDeadLetters error"))
+	err = c.HostMaintenanceDeadLettersAction()
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceHistoryAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceHistory(
+			gomock.Any(),
+			&hostsvc.GetMaintenanceHistoryRequest{Hostname: "hostname"}).
+		Return(&hostsvc.GetMaintenanceHistoryResponse{
+			Events: []*host.MaintenanceEvent{
+				{
+					Hostname:  "hostname",
+					FromState: host.HostState_HOST_STATE_UP,
+					ToState:   host.HostState_HOST_STATE_DRAINING,
+					EventTime: "2019-05-01T10:00:00Z",
+					Archived:  true,
+				},
+			},
+		}, nil)
+	err := c.HostMaintenanceHistoryAction("hostname")
+	suite.NoError(err)
+
+	// Test no history
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceHistory(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenanceHistoryResponse{}, nil)
+	err = c.HostMaintenanceHistoryAction("hostname")
+	suite.NoError(err)
+
+	// Test GetMaintenanceHistory error
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceHistory(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMaintenanceHistory error"))
+	err = c.HostMaintenanceHistoryAction("hostname")
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceRedriveAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		RedriveMaintenanceDeadLetters(
+			gomock.Any(),
+			&hostsvc.RedriveMaintenanceDeadLettersRequest{
+				Hostnames: []string{"hostname1", "hostname2"},
+			}).
+		Return(&hostsvc.RedriveMaintenanceDeadLettersResponse{}, nil)
+	err := c.HostMaintenanceRedriveAction("hostname2,hostname1")
+	suite.NoError(err)
+
+	// Test re-driving all hosts
+	suite.mockHostmgr.EXPECT().
+		RedriveMaintenanceDeadLetters(
+			gomock.Any(),
+			&hostsvc.RedriveMaintenanceDeadLettersRequest{}).
+		Return(&hostsvc.RedriveMaintenanceDeadLettersResponse{}, nil)
+	err = c.HostMaintenanceRedriveAction("")
+	suite.NoError(err)
+
+	// Test RedriveMaintenanceDeadLetters error
+	suite.mockHostmgr.EXPECT().
+		RedriveMaintenanceDeadLetters(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake RedriveMaintenanceDeadLetters error"))
+	err = c.HostMaintenanceRedriveAction("hostname")
+	suite.Error(err)
+
+	// Test duplicate hostname error
+	err = c.HostMaintenanceRedriveAction("hostname, hostname")
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceFreezeAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	for _, frozen := range []bool{true, false} {
+		suite.mockHostmgr.EXPECT().
+			SetMaintenanceFreeze(
+				gomock.Any(),
+				&hostsvc.SetMaintenanceFreezeRequest{Frozen: frozen}).
+			Return(&hostsvc.SetMaintenanceFreezeResponse{}, nil)
+		err := c.HostMaintenanceFreezeAction(frozen)
+		suite.NoError(err)
+	}
+
+	// Test SetMaintenanceFreeze error
+	suite.mockHostmgr.EXPECT().
+		SetMaintenanceFreeze(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake SetMaintenanceFreeze error"))
+	err := c.HostMaintenanceFreezeAction(true)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenancePendingAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceFreeze(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenanceFreezeResponse{
+			Frozen: true,
+			Pending: []*host.PendingMaintenance{
+				{
+					Hostnames: []string{"hostname1", "hostname2"},
+					DrainOptions: &host.DrainOptions{
+						KillGracePeriodSeconds: 60,
+					},
+					RequestTime: "2019-05-01T10:00:00Z",
+				},
+			},
+		}, nil)
+	err := c.HostMaintenancePendingAction()
+	suite.NoError(err)
+
+	// Test no pending maintenance
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceFreeze(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetMaintenanceFreezeResponse{}, nil)
+	err = c.HostMaintenancePendingAction()
+	suite.NoError(err)
+
+	// Test GetMaintenanceFreeze error
+	suite.mockHostmgr.EXPECT().
+		GetMaintenanceFreeze(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMaintenanceFreeze error"))
+	err = c.HostMaintenancePendingAction()
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceReleaseAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		ReleasePendingMaintenance(
+			gomock.Any(),
+			&hostsvc.ReleasePendingMaintenanceRequest{
+				Hostnames: []string{"hostname1", "hostname2"},
+			}).
+		Return(&hostsvc.ReleasePendingMaintenanceResponse{
+			Hostnames: []string{"hostname1", "hostname2"},
+		}, nil)
+	err := c.HostMaintenanceReleaseAction("hostname2,hostname1", false)
+	suite.NoError(err)
+
+	// Test discarding all hosts
+	suite.mockHostmgr.EXPECT().
+		ReleasePendingMaintenance(
+			gomock.Any(),
+			&hostsvc.ReleasePendingMaintenanceRequest{Discard: true}).
+		Return(&hostsvc.ReleasePendingMaintenanceResponse{}, nil)
+	err = c.HostMaintenanceReleaseAction("", true)
+	suite.NoError(err)
+
+	// Test ReleasePendingMaintenance error
+	suite.mockHostmgr.EXPECT().
+		ReleasePendingMaintenance(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake ReleasePendingMaintenance error"))
+	err = c.HostMaintenanceReleaseAction("hostname", false)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceUpdateAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		UpdateMaintenance(
+			gomock.Any(),
+			&hostsvc.UpdateMaintenanceRequest{
+				Hostnames:       []string{"hostname1", "hostname2"},
+				StartTime:       "2019-01-01T00:00:00Z",
+				DurationSeconds: 3600,
+			}).
+		Return(&hostsvc.UpdateMaintenanceResponse{}, nil)
+	err := c.HostMaintenanceUpdateAction(
+		"hostname2,hostname1", "", "2019-01-01T00:00:00Z", 0, time.Hour)
+	suite.NoError(err)
+
+	// Test start relative to now
+	suite.mockHostmgr.EXPECT().
+		UpdateMaintenance(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *hostsvc.UpdateMaintenanceRequest) {
+			start, err := time.Parse(time.RFC3339, request.GetStartTime())
+			suite.NoError(err)
+			suite.True(start.After(time.Now().Add(30 * time.Minute)))
+			suite.Zero(request.GetDurationSeconds())
+		}).
+		Return(&hostsvc.UpdateMaintenanceResponse{}, nil)
+	err = c.HostMaintenanceUpdateAction("hostname", "", "", time.Hour, 0)
+	suite.NoError(err)
+
+	// Test both start and start-in set
+	err = c.HostMaintenanceUpdateAction(
+		"hostname", "", "2019-01-01T00:00:00Z", time.Hour, 0)
+	suite.Error(err)
+
+	// Test UpdateMaintenance error
+	suite.mockHostmgr.EXPECT().
+		UpdateMaintenance(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake UpdateMaintenance error"))
+	err = c.HostMaintenanceUpdateAction("hostname", "", "", 0, time.Hour)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostEventsAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetHostEvents(
+			gomock.Any(),
+			&hostsvc.GetHostEventsRequest{Hostname: "hostname"}).
+		Return(&hostsvc.GetHostEventsResponse{
+			Events: []*host.HostEvent{
+				{
+					Hostname:  "hostname",
+					Type:      host.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+					EventTime: "2019-05-01T10:00:00Z",
+					Message:   "2 tasks",
+				},
+			},
+		}, nil)
+	err := c.HostEventsAction("hostname", 0)
+	suite.NoError(err)
+
+	// Test since and no events
+	suite.mockHostmgr.EXPECT().
+		GetHostEvents(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetHostEventsResponse{}, nil)
+	err = c.HostEventsAction("hostname", time.Hour)
+	suite.NoError(err)
+
+	// Test GetHostEvents error
+	suite.mockHostmgr.EXPECT().
+		GetHostEvents(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetHostEvents error"))
+	err = c.HostEventsAction("hostname", 0)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostReservationCreateAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	request := &hostsvc.CreateReservationRequest{
+		Hostname: "hostname",
+		Role:     "peloton",
+		Spec: &host.ReservationSpec{
+			Cpus:                1.0,
+			MemMb:               1024.0,
+			DiskMb:              2048.0,
+			VolumeContainerPath: "/data",
+		},
+		JobId:      "job",
+		InstanceId: 1,
+	}
+	suite.mockHostmgr.EXPECT().
+		CreateReservation(gomock.Any(), request).
+		Return(&hostsvc.CreateReservationResponse{
+			Reservation: &host.Reservation{
+				ReservationId: "reservation",
+				Hostname:      "hostname",
+				Role:          "peloton",
+				Spec:          request.GetSpec(),
+			},
+		}, nil)
+	err := c.HostReservationCreateAction(
+		"hostname", "peloton", 1.0, 1024.0, 2048.0, 0, "/data", "job", 1)
+	suite.NoError(err)
+
+	// Test CreateReservation error
+	suite.mockHostmgr.EXPECT().
+		CreateReservation(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake CreateReservation error"))
+	err = c.HostReservationCreateAction(
+		"hostname", "peloton", 1.0, 0, 0, 0, "", "", 0)
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostReservationListAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		ListReservations(
+			gomock.Any(),
+			&hostsvc.ListReservationsRequest{
+				Hostnames: []string{"hostname1", "hostname2"},
+				Role:      "peloton",
+			}).
+		Return(&hostsvc.ListReservationsResponse{
+			Reservations: []*host.Reservation{
+				{
+					ReservationId: "reservation",
+					Hostname:      "hostname1",
+					Role:          "peloton",
+				},
+			},
+		}, nil)
+	err := c.HostReservationListAction("hostname2,hostname1", "peloton")
+	suite.NoError(err)
+
+	// Test listing the reservations of all hosts
+	suite.mockHostmgr.EXPECT().
+		ListReservations(
+			gomock.Any(),
+			&hostsvc.ListReservationsRequest{}).
+		Return(&hostsvc.ListReservationsResponse{}, nil)
+	err = c.HostReservationListAction("", "")
+	suite.NoError(err)
+
+	// Test ListReservations error
+	suite.mockHostmgr.EXPECT().
+		ListReservations(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake ListReservations error"))
+	err = c.HostReservationListAction("hostname", "")
+	suite.Error(err)
+
+	// Test duplicate hostname error
+	err = c.HostReservationListAction("hostname, hostname", "")
+	suite.Error(err)
+}
+
+func (suite *hostmgrActionsTestSuite) TestClientHostReservationReleaseAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		ReleaseReservation(
+			gomock.Any(),
+			&hostsvc.ReleaseReservationRequest{
+				Hostname:      "hostname",
+				ReservationId: "reservation",
+			}).
+		Return(&hostsvc.ReleaseReservationResponse{}, nil)
+	err := c.HostReservationReleaseAction("hostname", "reservation")
+	suite.NoError(err)
+
+	// Test ReleaseReservation error
+	suite.mockHostmgr.EXPECT().
+		ReleaseReservation(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake ReleaseReservation error"))
+	err = c.HostReservationReleaseAction("hostname", "reservation")
 	suite.Error(err)
 }
 
@@ -137,9 +985,10 @@ func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 	}
 
 	tt := []struct {
-		debug bool
-		resp  *hostsvc.QueryHostsResponse
-		err   error
+		debug       bool
+		taskDetails bool
+		resp        *hostsvc.QueryHostsResponse
+		err         error
 	}{
 		{
 			resp: &hostsvc.QueryHostsResponse{
@@ -160,6 +1009,27 @@ func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 			},
 			err: nil,
 		},
+		{
+			resp: &hostsvc.QueryHostsResponse{
+				HostInfos: []*host.HostInfo{
+					{
+						Hostname: "hostname",
+						State:    host.HostState_HOST_STATE_DRAINING,
+						DrainingTasks: []*host.DrainingTask{
+							{
+								TaskId:           "task-id",
+								JobId:            &peloton.JobID{Value: "job-id"},
+								InstanceId:       1,
+								EvictingSince:    "2019-01-01T00:00:00Z",
+								ResistingSeconds: 60,
+							},
+						},
+					},
+				},
+			},
+			taskDetails: true,
+			err:         nil,
+		},
 		{
 			resp: nil,
 			err:  fmt.Errorf("fake QueryHosts error"),
@@ -168,13 +1038,20 @@ func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 
 	for _, t := range tt {
 		c.Debug = t.debug
+		if t.taskDetails {
+			suite.expectHostAPIInfo(1)
+		}
 		suite.mockHostmgr.EXPECT().
-			QueryHosts(gomock.Any(), gomock.Any()).
+			QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{
+				HostStates:         []host.HostState{host.HostState_HOST_STATE_DRAINING},
+				IncludeTaskDetails: t.taskDetails,
+			}).
 			Return(t.resp, t.err)
+		err := c.HostQueryAction("HOST_STATE_DRAINING", t.taskDetails)
 		if t.err != nil {
-			suite.Error(c.HostQueryAction(""))
+			suite.Error(err)
 		} else {
-			suite.NoError(c.HostQueryAction("HOST_STATE_DRAINING"))
+			suite.NoError(err)
 		}
 	}
 }
@@ -265,7 +1142,7 @@ func (suite *hostmgrActionsInternalTestSuite) TestGetHostsByQuery() {
 	}
 
 	suite.mockHostMgr.EXPECT().GetHostsByQuery(gomock.Any(), req).Return(resp, nil)
-	err := c.HostsGetAction(1.0, 2.0, false, "host1,host3")
+	err := c.HostsGetAction(1.0, 2.0, false, "host1,host3", false)
 	suite.NoError(err)
 }
 
@@ -293,7 +1170,7 @@ func (suite *hostmgrActionsInternalTestSuite) TestGetHostsByQueryLessThan() {
 	}
 
 	suite.mockHostMgr.EXPECT().GetHostsByQuery(gomock.Any(), req).Return(resp, nil)
-	err := c.HostsGetAction(4.0, 3.0, true, "")
+	err := c.HostsGetAction(4.0, 3.0, true, "", false)
 	suite.NoError(err)
 }
 
@@ -316,6 +1193,57 @@ func (suite *hostmgrActionsInternalTestSuite) TestGetHostsByQueryNoHost() {
 	resp := &hostmgrsvc.GetHostsByQueryResponse{}
 
 	suite.mockHostMgr.EXPECT().GetHostsByQuery(gomock.Any(), req).Return(resp, nil)
-	err := c.HostsGetAction(1.0, 2.0, false, "")
+	err := c.HostsGetAction(1.0, 2.0, false, "", false)
+	suite.NoError(err)
+}
+
+func (suite *hostmgrActionsInternalTestSuite) TestGetHostsByQueryRevocable() {
+	c := Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+
+	req := &hostmgrsvc.GetHostsByQueryRequest{
+		Resource: &pb_task.ResourceConfig{
+			CpuLimit: 2.0,
+		},
+		CmpLess:   false,
+		Revocable: true,
+	}
+
+	host := newHost("host1", 2.0, 1.0, 1024.0, 20000.0)
+	host.RevocableResources = []*mesos.Resource{
+		util.NewMesosResourceBuilder().
+			WithName("cpus").
+			WithValue(4.0).
+			WithRevocable(&mesos.Resource_RevocableInfo{}).
+			Build(),
+	}
+	resp := &hostmgrsvc.GetHostsByQueryResponse{
+		Hosts: []*hostmgrsvc.GetHostsByQueryResponse_Host{host},
+	}
+
+	suite.mockHostMgr.EXPECT().GetHostsByQuery(gomock.Any(), req).Return(resp, nil)
+	err := c.HostsGetAction(2.0, 0, false, "", true)
 	suite.NoError(err)
 }
+
+func (suite *hostmgrActionsInternalTestSuite) TestFormatCustomScalars() {
+	suite.Equal("-", formatCustomScalars(nil))
+	suite.Equal("-", formatCustomScalars(newHost("host1", 1, 0, 1, 1).
+		GetResources()[:3]))
+
+	resources := []*mesos.Resource{
+		util.NewMesosResourceBuilder().
+			WithName("fpgas").
+			WithValue(2.0).
+			Build(),
+		util.NewMesosResourceBuilder().
+			WithName("asics").
+			WithValue(1.0).
+			Build(),
+	}
+	suite.Equal("asics=1.00,fpgas=2.00", formatCustomScalars(resources))
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// _hostServiceName prefixes the procedure names of the host service
+	_hostServiceName = "peloton.api.v0.host.svc.HostService"
+
+	hostAPIFormatHeader = "Procedure\tEncodings\tRequest Fields\t\n"
+	hostAPIFormatBody   = "%s\t%s\t%s\t\n"
+)
+
+// _hostAPIVersions are the API versions of the host service supported by
+// the CLI, in the order of preference
+var _hostAPIVersions = []string{"v0"}
+
+// HostAPIAction is the action for printing the API versions and the
+// procedures served by host manager, with the fields of their requests.
+func (c *Client) HostAPIAction() error {
+	response, err := c.getHostAPIInfo()
+	if err != nil {
+		return err
+	}
+
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+	fmt.Fprintf(tabWriter, "API versions: %s\n",
+		strings.Join(response.GetApiVersions(), ", "))
+	fmt.Fprintf(tabWriter, "Negotiated API version: %s\n",
+		response.GetNegotiatedApiVersion())
+	fmt.Fprintf(tabWriter, hostAPIFormatHeader)
+	for _, p := range response.GetProcedures() {
+		fmt.Fprintf(
+			tabWriter,
+			hostAPIFormatBody,
+			p.GetName(),
+			strings.Join(p.GetEncodings(), ","),
+			strings.Join(p.GetRequestFields(), ","),
+		)
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// getHostAPIInfo negotiates the API version of the host service with
+// host manager.
+func (c *Client) getHostAPIInfo() (*host_svc.GetAPIInfoResponse, error) {
+	response, err := c.hostClient.GetAPIInfo(
+		c.ctx,
+		&host_svc.GetAPIInfoRequest{ApiVersions: _hostAPIVersions},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if response.GetNegotiatedApiVersion() == "" {
+		return nil, fmt.Errorf(
+			"host manager serves none of the API versions %s of the CLI",
+			strings.Join(_hostAPIVersions, ", "))
+	}
+	return response, nil
+}
+
+// requireHostServiceField returns an error if host manager does not
+// support a field of the request of a method of the host service. Host
+// manager ignores the fields it does not know, so that e.g. a dry run
+// sent to a host manager predating dry runs would make the changes.
+func (c *Client) requireHostServiceField(method string, field string) error {
+	unsupported := fmt.Errorf(
+		"host manager does not support %s of %s, upgrade host manager",
+		field, method)
+
+	response, err := c.getHostAPIInfo()
+	if err != nil {
+		if yarpcerrors.IsUnimplemented(err) {
+			// host manager predates GetAPIInfo
+			return unsupported
+		}
+		return err
+	}
+
+	procedure := _hostServiceName + "::" + method
+	for _, p := range response.GetProcedures() {
+		if p.GetName() != procedure {
+			continue
+		}
+		for _, f := range p.GetRequestFields() {
+			if f == field {
+				return nil
+			}
+		}
+	}
+	return unsupported
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// expectHostAPIInfo expects the negotiation of the API of the host
+// service, describing the requests of the maintenance methods.
+func (suite *hostmgrActionsTestSuite) expectHostAPIInfo(times int) {
+	suite.mockHostmgr.EXPECT().
+		GetAPIInfo(gomock.Any(), &hostsvc.GetAPIInfoRequest{
+			ApiVersions: []string{"v0"},
+		}).
+		Return(&hostsvc.GetAPIInfoResponse{
+			ApiVersions:          []string{"v0"},
+			NegotiatedApiVersion: "v0",
+			Procedures: []*hostsvc.Procedure{
+				{
+					Name:          _hostServiceName + "::CompleteMaintenance",
+					Encodings:     []string{"json", "proto"},
+					RequestFields: []string{"hostnames", "reboot", "dry_run", "agent_ids"},
+				},
+				{
+					Name:          _hostServiceName + "::QueryHosts",
+					Encodings:     []string{"json", "proto"},
+					RequestFields: []string{"host_states", "include_task_details"},
+				},
+				{
+					Name:          _hostServiceName + "::StartMaintenance",
+					Encodings:     []string{"json", "proto"},
+					RequestFields: []string{"hostnames", "drain_options", "dry_run", "agent_ids", "policy"},
+				},
+			},
+		}, nil).
+		Times(times)
+}
+
+// TestClientHostAPIAction tests printing the API of host manager.
+func (suite *hostmgrActionsTestSuite) TestClientHostAPIAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.expectHostAPIInfo(2)
+	suite.NoError(c.HostAPIAction())
+	c.Debug = true
+	suite.NoError(c.HostAPIAction())
+	c.Debug = false
+
+	// Host manager serves none of the API versions of the CLI
+	suite.mockHostmgr.EXPECT().
+		GetAPIInfo(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetAPIInfoResponse{ApiVersions: []string{"v1alpha"}}, nil)
+	suite.Error(c.HostAPIAction())
+}
+
+// TestClientRequireHostServiceField tests that requests are not sent with
+// fields host manager does not support.
+func (suite *hostmgrActionsTestSuite) TestClientRequireHostServiceField() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	suite.expectHostAPIInfo(3)
+	suite.NoError(c.requireHostServiceField("StartMaintenance", "dry_run"))
+	suite.Error(c.requireHostServiceField("StartMaintenance", "unknown"))
+	suite.Error(c.requireHostServiceField("UnknownMethod", "dry_run"))
+
+	// Host manager predating GetAPIInfo
+	suite.mockHostmgr.EXPECT().
+		GetAPIInfo(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.UnimplementedErrorf("unrecognized procedure"))
+	suite.Error(c.HostMaintenanceStartAction(
+		"hostname", "", "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, true, false, 0))
+
+	suite.mockHostmgr.EXPECT().
+		GetAPIInfo(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.UnavailableErrorf("unavailable"))
+	suite.Error(c.requireHostServiceField("StartMaintenance", "dry_run"))
+}
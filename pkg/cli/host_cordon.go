@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+const (
+	hostCordonFormatHeader = "Hostname\tReason\t\n"
+	hostCordonFormatBody   = "%s\t%s\t\n"
+)
+
+// HostCordonAction cordons the given hosts, so that no new task is
+// placed on them.
+func (c *Client) HostCordonAction(hosts string, reason string) error {
+	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.hostMgrClient.CordonHosts(
+		c.ctx,
+		&hostsvc.CordonHostsRequest{
+			Hostnames: hostnames,
+			Reason:    reason,
+		})
+	if err != nil {
+		return err
+	}
+	if resp.GetError() != nil {
+		return errors.New(resp.GetError().GetInvalidArgument().GetMessage())
+	}
+
+	fmt.Fprintf(tabWriter, "Cordoned hosts %v\n", hostnames)
+	tabWriter.Flush()
+	return nil
+}
+
+// HostUncordonAction uncordons the given hosts.
+func (c *Client) HostUncordonAction(hosts string) error {
+	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.hostMgrClient.UncordonHosts(
+		c.ctx,
+		&hostsvc.UncordonHostsRequest{
+			Hostnames: hostnames,
+		})
+	if err != nil {
+		return err
+	}
+	if resp.GetError() != nil {
+		return errors.New(resp.GetError().GetInvalidArgument().GetMessage())
+	}
+
+	fmt.Fprintf(tabWriter, "Uncordoned hosts %v\n", hostnames)
+	tabWriter.Flush()
+	return nil
+}
+
+// HostCordonedAction prints the cordoned hosts.
+func (c *Client) HostCordonedAction() error {
+	resp, err := c.hostMgrClient.GetCordonedHosts(
+		c.ctx,
+		&hostsvc.GetCordonedHostsRequest{})
+	if err != nil {
+		return err
+	}
+
+	printGetCordonedHostsResponse(resp)
+	return nil
+}
+
+func printGetCordonedHostsResponse(resp *hostsvc.GetCordonedHostsResponse) {
+	if len(resp.GetHosts()) == 0 {
+		fmt.Fprint(tabWriter, "No host is cordoned\n")
+		tabWriter.Flush()
+		return
+	}
+
+	var hostnames []string
+	for hostname := range resp.GetHosts() {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	fmt.Fprint(tabWriter, hostCordonFormatHeader)
+	for _, hostname := range hostnames {
+		fmt.Fprintf(tabWriter, hostCordonFormatBody,
+			hostname, resp.GetHosts()[hostname])
+	}
+	tabWriter.Flush()
+}
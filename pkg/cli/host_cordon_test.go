@@ -0,0 +1,119 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	hostMocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostCordonActionsTestSuite struct {
+	suite.Suite
+	ctx         context.Context
+	ctrl        *gomock.Controller
+	mockHostMgr *hostMocks.MockInternalHostServiceYARPCClient
+	client      Client
+}
+
+func (suite *hostCordonActionsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostMgr = hostMocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
+	suite.ctx = context.Background()
+	suite.client = Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+}
+
+func (suite *hostCordonActionsTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *hostCordonActionsTestSuite) TestHostCordonAction() {
+	suite.mockHostMgr.EXPECT().CordonHosts(
+		gomock.Any(),
+		&hostsvc.CordonHostsRequest{
+			Hostnames: []string{_testAgent},
+			Reason:    "bad disk",
+		}).Return(&hostsvc.CordonHostsResponse{}, nil)
+	suite.NoError(suite.client.HostCordonAction(_testAgent, "bad disk"))
+
+	// Test CordonHosts error
+	suite.mockHostMgr.EXPECT().CordonHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake CordonHosts error"))
+	suite.Error(suite.client.HostCordonAction(_testAgent, ""))
+
+	// Test invalid argument
+	suite.mockHostMgr.EXPECT().CordonHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.CordonHostsResponse{
+			Error: &hostsvc.CordonHostsResponse_Error{
+				InvalidArgument: &hostsvc.InvalidArgument{
+					Message: "no hostnames provided",
+				},
+			},
+		}, nil)
+	suite.Error(suite.client.HostCordonAction(_testAgent, ""))
+
+	// Test duplicate hostname error
+	suite.Error(suite.client.HostCordonAction("host,host", ""))
+}
+
+func (suite *hostCordonActionsTestSuite) TestHostUncordonAction() {
+	suite.mockHostMgr.EXPECT().UncordonHosts(
+		gomock.Any(),
+		&hostsvc.UncordonHostsRequest{
+			Hostnames: []string{_testAgent},
+		}).Return(&hostsvc.UncordonHostsResponse{}, nil)
+	suite.NoError(suite.client.HostUncordonAction(_testAgent))
+
+	// Test UncordonHosts error
+	suite.mockHostMgr.EXPECT().UncordonHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake UncordonHosts error"))
+	suite.Error(suite.client.HostUncordonAction(_testAgent))
+
+	// Test duplicate hostname error
+	suite.Error(suite.client.HostUncordonAction("host,host"))
+}
+
+func (suite *hostCordonActionsTestSuite) TestHostCordonedAction() {
+	suite.mockHostMgr.EXPECT().GetCordonedHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetCordonedHostsResponse{
+			Hosts: map[string]string{_testAgent: "bad disk"},
+		}, nil)
+	suite.NoError(suite.client.HostCordonedAction())
+
+	// Test no cordoned host
+	suite.mockHostMgr.EXPECT().GetCordonedHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetCordonedHostsResponse{}, nil)
+	suite.NoError(suite.client.HostCordonedAction())
+
+	// Test GetCordonedHosts error
+	suite.mockHostMgr.EXPECT().GetCordonedHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake GetCordonedHosts error"))
+	suite.Error(suite.client.HostCordonedAction())
+}
+
+func TestHostCordonAction(t *testing.T) {
+	suite.Run(t, new(hostCordonActionsTestSuite))
+}
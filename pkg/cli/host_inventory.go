@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+)
+
+// inventoryFormats are the formats of host inventories by name
+var inventoryFormats = map[string]host.InventoryFormat{
+	"json": host.InventoryFormat_INVENTORY_FORMAT_JSON,
+	"csv":  host.InventoryFormat_INVENTORY_FORMAT_CSV,
+}
+
+// getInventoryFormat returns the inventory format of the given name
+func getInventoryFormat(name string) (host.InventoryFormat, error) {
+	format, ok := inventoryFormats[strings.ToLower(name)]
+	if !ok {
+		return format, fmt.Errorf("unknown inventory format %q", name)
+	}
+	return format, nil
+}
+
+// HostInventoryExportAction exports the hostname, IP, state, host pool
+// and labels of the hosts as JSON or CSV, into the output file if set,
+// otherwise to stdout.
+func (c *Client) HostInventoryExportAction(format string, output string) error {
+	inventoryFormat, err := getInventoryFormat(format)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.hostClient.ExportHostInventory(
+		c.ctx,
+		&host_svc.ExportHostInventoryRequest{Format: inventoryFormat})
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(response.GetContent())
+		return err
+	}
+	if err := ioutil.WriteFile(output, response.GetContent(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(tabWriter, "Exported host inventory to %s\n", output)
+	tabWriter.Flush()
+	return nil
+}
+
+// HostInventoryImportAction assigns the host pools and labels of an
+// inventory file, in the format of HostInventoryExportAction, to the
+// hosts. Hosts without pool or labels get back the pool and labels of
+// their agents.
+func (c *Client) HostInventoryImportAction(file string, format string) error {
+	inventoryFormat, err := getInventoryFormat(format)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("unable to read inventory file %s: %v", file, err)
+	}
+
+	response, err := c.hostClient.ImportHostInventory(
+		c.ctx,
+		&host_svc.ImportHostInventoryRequest{
+			Format:  inventoryFormat,
+			Content: content,
+		})
+	if err != nil {
+		return err
+	}
+
+	for _, mapping := range response.GetHostnameMappings() {
+		fmt.Fprintf(tabWriter, "Resolved %s to %s\n",
+			mapping.GetRequested(), mapping.GetHostname())
+	}
+	fmt.Fprintf(tabWriter, "Imported inventory of hosts: %s\n",
+		strings.Join(response.GetHostnames(), ", "))
+	tabWriter.Flush()
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostInventoryTestSuite struct {
+	suite.Suite
+	ctrl        *gomock.Controller
+	mockHostmgr *hostmocks.MockHostServiceYARPCClient
+	client      Client
+	dir         string
+}
+
+func TestHostInventory(t *testing.T) {
+	suite.Run(t, new(hostInventoryTestSuite))
+}
+
+func (suite *hostInventoryTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostmgr = hostmocks.NewMockHostServiceYARPCClient(suite.ctrl)
+	suite.client = Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        context.Background(),
+	}
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "host_inventory")
+	suite.NoError(err)
+}
+
+func (suite *hostInventoryTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+	suite.ctrl.Finish()
+}
+
+// TestHostInventoryExportAction tests exporting the inventory to a file
+func (suite *hostInventoryTestSuite) TestHostInventoryExportAction() {
+	content := []byte("hostname,ip,state,pool,labels\n")
+	output := filepath.Join(suite.dir, "inventory.csv")
+	suite.mockHostmgr.EXPECT().
+		ExportHostInventory(gomock.Any(), &hostsvc.ExportHostInventoryRequest{
+			Format: host.InventoryFormat_INVENTORY_FORMAT_CSV,
+		}).
+		Return(&hostsvc.ExportHostInventoryResponse{Content: content}, nil)
+	suite.NoError(suite.client.HostInventoryExportAction("csv", output))
+
+	written, err := ioutil.ReadFile(output)
+	suite.NoError(err)
+	suite.Equal(content, written)
+
+	suite.mockHostmgr.EXPECT().
+		ExportHostInventory(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake ExportHostInventory error"))
+	suite.Error(suite.client.HostInventoryExportAction("json", output))
+
+	// Unknown format
+	suite.Error(suite.client.HostInventoryExportAction("yaml", output))
+}
+
+// TestHostInventoryImportAction tests importing an inventory file
+func (suite *hostInventoryTestSuite) TestHostInventoryImportAction() {
+	content := []byte(`[{"hostname": "host1", "pool": "batch"}]`)
+	file := filepath.Join(suite.dir, "inventory.json")
+	suite.NoError(ioutil.WriteFile(file, content, 0644))
+
+	suite.mockHostmgr.EXPECT().
+		ImportHostInventory(gomock.Any(), &hostsvc.ImportHostInventoryRequest{
+			Format:  host.InventoryFormat_INVENTORY_FORMAT_JSON,
+			Content: content,
+		}).
+		Return(&hostsvc.ImportHostInventoryResponse{
+			Hostnames: []string{"host1"},
+		}, nil)
+	suite.NoError(suite.client.HostInventoryImportAction(file, "json"))
+
+	suite.mockHostmgr.EXPECT().
+		ImportHostInventory(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake ImportHostInventory error"))
+	suite.Error(suite.client.HostInventoryImportAction(file, "json"))
+
+	// Missing file
+	suite.Error(suite.client.HostInventoryImportAction(
+		filepath.Join(suite.dir, "missing"), "json"))
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+)
+
+const (
+	hostListTableOutput = "table"
+	hostListStatePrefix = "HOST_STATE_"
+)
+
+// hostListColumn is a column of the `host list` table.
+type hostListColumn struct {
+	header string
+	// field is the HostInfo field the column is read from
+	field string
+	value func(*host.HostInfo) string
+}
+
+// hostListColumnNames is the ordered list of columns supported by `host list`.
+var hostListColumnNames = []string{
+	"hostname", "ip", "state", "pool", "cpus", "mem", "disk", "gpus", "labels",
+}
+
+var hostListColumns = map[string]hostListColumn{
+	"hostname": {"Hostname", "hostname", func(h *host.HostInfo) string { return h.GetHostname() }},
+	"ip":       {"IP", "ip", func(h *host.HostInfo) string { return h.GetIp() }},
+	"state":    {"State", "state", func(h *host.HostInfo) string { return h.GetState().String() }},
+	"pool":     {"Pool", "pool", func(h *host.HostInfo) string { return h.GetPool() }},
+	"cpus": {"CPU", "resources", func(h *host.HostInfo) string {
+		return fmt.Sprintf("%.2f", h.GetResources().GetCpus())
+	}},
+	"mem": {"Mem(MB)", "resources", func(h *host.HostInfo) string {
+		return fmt.Sprintf("%.0f", h.GetResources().GetMemMb())
+	}},
+	"disk": {"Disk(MB)", "resources", func(h *host.HostInfo) string {
+		return fmt.Sprintf("%.0f", h.GetResources().GetDiskMb())
+	}},
+	"gpus": {"GPU", "resources", func(h *host.HostInfo) string {
+		return fmt.Sprintf("%.0f", h.GetResources().GetGpus())
+	}},
+	"labels": {"Labels", "labels", func(h *host.HostInfo) string {
+		var labels []string
+		for _, l := range h.GetLabels() {
+			labels = append(labels, l.GetKey()+keyValSeparator+l.GetValue())
+		}
+		return strings.Join(labels, labelSeparator)
+	}},
+}
+
+// HostListAction is the action for listing the hosts in the given states and pools which have all the given labels.
+// The table output prints the given columns, while the json and yaml outputs print the full host information.
+// Only the fields of the columns are requested for the table output.
+func (c *Client) HostListAction(
+	states string,
+	pools string,
+	labels string,
+	columns string,
+	output string) error {
+	hostStates, err := parseHostStates(states)
+	if err != nil {
+		return err
+	}
+
+	var pelotonLabels []*peloton.Label
+	if labels != "" {
+		pelotonLabels, err = parsePelotonLabels(labels)
+		if err != nil {
+			return err
+		}
+	}
+
+	output = strings.ToLower(output)
+	var cols []hostListColumn
+	switch output {
+	case hostListTableOutput:
+		cols, err = parseHostListColumns(columns)
+		if err != nil {
+			return err
+		}
+	case defaultResponseFormat, jsonResponseFormat:
+	default:
+		return fmt.Errorf(
+			"invalid output %s, must be one of %s, %s or %s",
+			output, hostListTableOutput, jsonResponseFormat, defaultResponseFormat)
+	}
+
+	request := &host_svc.QueryHostsRequest{
+		HostStates: hostStates,
+		Pools:      splitNonEmpty(pools),
+		Labels:     pelotonLabels,
+	}
+	if output == hostListTableOutput && !c.Debug {
+		request.Fields = hostListFields(cols)
+	}
+	response, err := c.hostClient.QueryHosts(c.ctx, request)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(response.HostInfos, func(i, j int) bool {
+		return response.HostInfos[i].GetHostname() <
+			response.HostInfos[j].GetHostname()
+	})
+
+	if c.Debug {
+		printResponseJSON(response)
+		return nil
+	}
+
+	if output != hostListTableOutput {
+		out, err := marshallResponse(output, response)
+		if err != nil {
+			return err
+		}
+		cliOutPutter.output(fmt.Sprintf("%s\n", string(out)))
+		return nil
+	}
+
+	printHostListTable(response.GetHostInfos(), cols)
+	return nil
+}
+
+// parseHostStates parses comma separated host states, which can be
+// given either as the full enum name (HOST_STATE_UP) or without the
+// prefix (up).
+func parseHostStates(states string) ([]host.HostState, error) {
+	var hostStates []host.HostState
+	for _, state := range splitNonEmpty(states) {
+		name := strings.ToUpper(state)
+		if !strings.HasPrefix(name, hostListStatePrefix) {
+			name = hostListStatePrefix + name
+		}
+		value, ok := host.HostState_value[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid host state %s", state)
+		}
+		hostStates = append(hostStates, host.HostState(value))
+	}
+	return hostStates, nil
+}
+
+// parseHostListColumns parses comma separated column names.
+func parseHostListColumns(columns string) ([]hostListColumn, error) {
+	names := splitNonEmpty(columns)
+	if len(names) == 0 {
+		return nil, fmt.Errorf(
+			"no columns selected, supported columns are %s",
+			strings.Join(hostListColumnNames, labelSeparator))
+	}
+	var cols []hostListColumn
+	for _, name := range names {
+		col, ok := hostListColumns[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf(
+				"invalid column %s, supported columns are %s",
+				name, strings.Join(hostListColumnNames, labelSeparator))
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// hostListFields returns the HostInfo fields read by the columns, and the
+// hostname the hosts are sorted by.
+func hostListFields(cols []hostListColumn) []string {
+	fields := []string{"hostname"}
+	seen := map[string]bool{"hostname": true}
+	for _, col := range cols {
+		if !seen[col.field] {
+			seen[col.field] = true
+			fields = append(fields, col.field)
+		}
+	}
+	return fields
+}
+
+// splitNonEmpty splits a comma separated list, dropping empty items.
+func splitNonEmpty(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, labelSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func printHostListTable(hostInfos []*host.HostInfo, cols []hostListColumn) {
+	defer tabWriter.Flush()
+
+	if len(hostInfos) == 0 {
+		fmt.Fprintf(tabWriter, "No hosts found\n")
+		return
+	}
+
+	headers := make([]string, 0, len(cols))
+	for _, col := range cols {
+		headers = append(headers, col.header)
+	}
+	fmt.Fprintf(tabWriter, "%s\t\n", strings.Join(headers, "\t"))
+
+	for _, h := range hostInfos {
+		values := make([]string, 0, len(cols))
+		for _, col := range cols {
+			values = append(values, col.value(h))
+		}
+		fmt.Fprintf(tabWriter, "%s\t\n", strings.Join(values, "\t"))
+	}
+}
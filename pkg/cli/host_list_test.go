@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostListTestSuite struct {
+	suite.Suite
+	ctrl        *gomock.Controller
+	mockHostmgr *hostmocks.MockHostServiceYARPCClient
+	client      Client
+	outputter   outputter
+	encoder     encoderDecoder
+	fake        *fakeOutputter
+}
+
+func TestHostList(t *testing.T) {
+	suite.Run(t, new(hostListTestSuite))
+}
+
+func (suite *hostListTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostmgr = hostmocks.NewMockHostServiceYARPCClient(suite.ctrl)
+	suite.client = Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        context.Background(),
+	}
+
+	suite.outputter = cliOutPutter
+	suite.encoder = cliEncoder
+	suite.fake = &fakeOutputter{}
+	cliOutPutter = suite.fake
+	cliEncoder = newJSONEncoderDecoder()
+}
+
+func (suite *hostListTestSuite) TearDownTest() {
+	cliOutPutter = suite.outputter
+	cliEncoder = suite.encoder
+	suite.ctrl.Finish()
+}
+
+func (suite *hostListTestSuite) response() *hostsvc.QueryHostsResponse {
+	return &hostsvc.QueryHostsResponse{
+		HostInfos: []*host.HostInfo{
+			{
+				Hostname: "host2",
+				Ip:       "10.0.0.2",
+				State:    host.HostState_HOST_STATE_DRAINING,
+			},
+			{
+				Hostname: "host1",
+				Ip:       "10.0.0.1",
+				State:    host.HostState_HOST_STATE_UP,
+				Pool:     "shared",
+				Resources: &host.HostResources{
+					Cpus:   4,
+					MemMb:  1024,
+					DiskMb: 2048,
+				},
+				Labels: []*peloton.Label{
+					{Key: "zone", Value: "dca1"},
+				},
+			},
+		},
+	}
+}
+
+// TestHostListAction tests that the filters are passed to QueryHosts
+func (suite *hostListTestSuite) TestHostListAction() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{
+			HostStates: []host.HostState{
+				host.HostState_HOST_STATE_UP,
+				host.HostState_HOST_STATE_DRAINING,
+			},
+			Pools: []string{"shared", "batch"},
+			Labels: []*peloton.Label{
+				{Key: "zone", Value: "dca1"},
+				{Key: "rack", Value: "a1"},
+			},
+			Fields: []string{"hostname", "state", "pool", "resources", "labels"},
+		}).
+		Return(suite.response(), nil)
+
+	suite.NoError(suite.client.HostListAction(
+		"up,HOST_STATE_DRAINING",
+		"shared, batch",
+		"zone=dca1,rack=a1",
+		"hostname,state,pool,cpus,labels",
+		hostListTableOutput,
+	))
+}
+
+// TestHostListActionJSON tests the json output, sorted by hostname
+func (suite *hostListTestSuite) TestHostListActionJSON() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{}).
+		Return(suite.response(), nil)
+
+	suite.NoError(suite.client.HostListAction(
+		"", "", "", "", jsonResponseFormat))
+	suite.True(strings.Index(suite.fake.Out, "host1") <
+		strings.Index(suite.fake.Out, "host2"))
+	suite.Contains(suite.fake.Out, "\"pool\": \"shared\"")
+}
+
+// TestHostListActionYAML tests the yaml output
+func (suite *hostListTestSuite) TestHostListActionYAML() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{}).
+		Return(suite.response(), nil)
+
+	suite.NoError(suite.client.HostListAction(
+		"", "", "", "", "YAML"))
+	suite.Contains(suite.fake.Out, "pool: shared")
+}
+
+// TestHostListActionInvalidArgs tests that invalid arguments are
+// rejected without calling QueryHosts
+func (suite *hostListTestSuite) TestHostListActionInvalidArgs() {
+	suite.Error(suite.client.HostListAction(
+		"running", "", "", "hostname", hostListTableOutput))
+	suite.Error(suite.client.HostListAction(
+		"", "", "zone", "hostname", hostListTableOutput))
+	suite.Error(suite.client.HostListAction(
+		"", "", "", "hostname,rack", hostListTableOutput))
+	suite.Error(suite.client.HostListAction(
+		"", "", "", "", hostListTableOutput))
+	suite.Error(suite.client.HostListAction(
+		"", "", "", "hostname", "xml"))
+}
+
+// TestHostListActionError tests QueryHosts failure
+func (suite *hostListTestSuite) TestHostListActionError() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake QueryHosts error"))
+
+	suite.Error(suite.client.HostListAction(
+		"", "", "", "hostname", hostListTableOutput))
+}
+
+// TestHostListColumns tests the values of the table columns
+func (suite *hostListTestSuite) TestHostListColumns() {
+	h := suite.response().GetHostInfos()[1]
+	cols, err := parseHostListColumns(strings.Join(hostListColumnNames, ","))
+	suite.NoError(err)
+
+	var values []string
+	for _, col := range cols {
+		values = append(values, col.value(h))
+	}
+	suite.Equal([]string{
+		"host1", "10.0.0.1", "HOST_STATE_UP", "shared",
+		"4.00", "1024", "2048", "0", "zone=dca1",
+	}, values)
+}
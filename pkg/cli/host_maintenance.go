@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+)
+
+const (
+	hostMaintenanceQueryTimeout = 5 * time.Second
+	hostStatusFormatHeader      = "Hostname\tIP\tState\t\n"
+	hostStatusFormatBody        = "%s\t%s\t%s\t\n"
+	hostTransitionFormat        = "%s\t%s\t%s -> %s\t\n"
+)
+
+// hostMaintenanceWatchRefresh is the period of polling the host states
+// while watching host maintenance
+var hostMaintenanceWatchRefresh = 5 * time.Second
+
+// HostMaintenanceStatusAction is the action for printing the maintenance state of the hosts read from both hosts
+// and file, if set. With watch, the host state transitions are printed until all hosts are either UP or DOWN,
+// or watchTimeout expires if set.
+func (c *Client) HostMaintenanceStatusAction(
+	hosts string,
+	file string,
+	watch bool,
+	watchTimeout time.Duration) error {
+	hostnames, err := c.readHostnames(hosts, file)
+	if err != nil {
+		return err
+	}
+
+	if watch {
+		return c.watchHostStates(hostnames, isHostSettled, watchTimeout)
+	}
+
+	infos, err := c.queryHostInfos(hostnames)
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	if c.Debug {
+		printResponseJSON(infos)
+		return nil
+	}
+	fmt.Fprint(tabWriter, hostStatusFormatHeader)
+	for _, hostname := range hostnames {
+		fmt.Fprintf(
+			tabWriter,
+			hostStatusFormatBody,
+			hostname,
+			infos[hostname].GetIp(),
+			hostState(infos[hostname]),
+		)
+	}
+	return nil
+}
+
+// readHostsAndAgentIDs returns the hostnames read with readHostnames, and
+// the comma separated Mesos agent ids. Only agent ids may be given.
+func (c *Client) readHostsAndAgentIDs(
+	hosts string,
+	file string,
+	agentIDs string) ([]string, []string, error) {
+	var (
+		hostnames []string
+		ids       []string
+		err       error
+	)
+	if hosts != "" || file != "" || agentIDs == "" {
+		hostnames, err = c.readHostnames(hosts, file)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if agentIDs != "" {
+		ids, err = c.ExtractHostnames(agentIDs, hostSeparator)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return hostnames, ids, nil
+}
+
+// readHostnames returns the hostnames of the comma separated hosts and of
+// the file with one hostname per line, if set. Empty lines and lines
+// starting with # are skipped.
+func (c *Client) readHostnames(hosts string, file string) ([]string, error) {
+	var all []string
+	if hosts != "" || file == "" {
+		all = append(all, hosts)
+	}
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			all = append(all, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("No hosts found in %s", file)
+		}
+	}
+	return c.ExtractHostnames(strings.Join(all, hostSeparator), hostSeparator)
+}
+
+// watchHostStates polls the states of the hosts, printing their
+// transitions, until all hosts are done, or timeout expires if set.
+func (c *Client) watchHostStates(
+	hostnames []string,
+	done func(host.HostState) bool,
+	timeout time.Duration) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	refresh := time.NewTicker(hostMaintenanceWatchRefresh)
+	defer refresh.Stop()
+
+	states := make(map[string]host.HostState)
+	for {
+		infos, err := c.queryHostInfos(hostnames)
+		if err != nil {
+			return err
+		}
+
+		finished := true
+		now := time.Now().Format(time.RFC3339)
+		for _, hostname := range hostnames {
+			state := hostState(infos[hostname])
+			if previous, ok := states[hostname]; !ok || previous != state {
+				fmt.Fprintf(
+					tabWriter,
+					hostTransitionFormat,
+					now,
+					hostname,
+					hostStateName(previous),
+					hostStateName(state),
+				)
+				states[hostname] = state
+			}
+			if !done(state) {
+				finished = false
+			}
+		}
+		tabWriter.Flush()
+		if finished {
+			fmt.Fprintf(tabWriter, "All hosts reached the target state\n")
+			tabWriter.Flush()
+			return nil
+		}
+
+		select {
+		case <-expired:
+			return fmt.Errorf("Timed out watching hosts after %s", timeout)
+		case <-refresh.C:
+		}
+	}
+}
+
+// queryHostInfos returns the host infos of the given hosts by hostname.
+// Hosts unknown to host manager are missing from the result.
+func (c *Client) queryHostInfos(
+	hostnames []string) (map[string]*host.HostInfo, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		hostMaintenanceQueryTimeout)
+	defer cancel()
+
+	// Only the fields which are printed are requested, as the hosts are
+	// polled while they are watched. Host managers predating field masks
+	// return all fields.
+	response, err := c.hostClient.QueryHosts(
+		ctx,
+		&host_svc.QueryHostsRequest{
+			Fields: []string{"hostname", "ip", "state"},
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, hostname := range hostnames {
+		wanted[hostname] = true
+	}
+	infos := make(map[string]*host.HostInfo)
+	for _, info := range response.GetHostInfos() {
+		if wanted[info.GetHostname()] {
+			infos[info.GetHostname()] = info
+		}
+	}
+	return infos, nil
+}
+
+// hostState returns the state of a host, which is unknown if the host is
+// unknown to host manager.
+func hostState(info *host.HostInfo) host.HostState {
+	if info == nil {
+		return host.HostState_HOST_STATE_UNKNOWN
+	}
+	return info.GetState()
+}
+
+// hostStateName returns the state without its HOST_STATE_ prefix, or - for
+// the initial invalid state.
+func hostStateName(state host.HostState) string {
+	if state == host.HostState_HOST_STATE_INVALID {
+		return "-"
+	}
+	return strings.TrimPrefix(state.String(), "HOST_STATE_")
+}
+
+func isHostDown(state host.HostState) bool {
+	return state == host.HostState_HOST_STATE_DOWN
+}
+
+func isHostUp(state host.HostState) bool {
+	return state == host.HostState_HOST_STATE_UP
+}
+
+func isHostSettled(state host.HostState) bool {
+	return isHostUp(state) || isHostDown(state)
+}
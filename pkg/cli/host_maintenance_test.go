@@ -0,0 +1,219 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostMaintenanceTestSuite struct {
+	suite.Suite
+	ctrl        *gomock.Controller
+	mockHostmgr *hostmocks.MockHostServiceYARPCClient
+	client      Client
+	dir         string
+	refresh     time.Duration
+}
+
+func TestHostMaintenance(t *testing.T) {
+	suite.Run(t, new(hostMaintenanceTestSuite))
+}
+
+func (suite *hostMaintenanceTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostmgr = hostmocks.NewMockHostServiceYARPCClient(suite.ctrl)
+	suite.client = Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        context.Background(),
+	}
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "host_maintenance")
+	suite.NoError(err)
+
+	suite.refresh = hostMaintenanceWatchRefresh
+	hostMaintenanceWatchRefresh = time.Millisecond
+}
+
+func (suite *hostMaintenanceTestSuite) TearDownTest() {
+	hostMaintenanceWatchRefresh = suite.refresh
+	os.RemoveAll(suite.dir)
+	suite.ctrl.Finish()
+}
+
+func (suite *hostMaintenanceTestSuite) writeFile(content string) string {
+	file := filepath.Join(suite.dir, "hosts")
+	suite.NoError(ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func queryHostsResponse(states map[string]host.HostState) *hostsvc.QueryHostsResponse {
+	response := &hostsvc.QueryHostsResponse{}
+	for hostname, state := range states {
+		response.HostInfos = append(response.HostInfos, &host.HostInfo{
+			Hostname: hostname,
+			Ip:       "10.0.0.1",
+			State:    state,
+		})
+	}
+	return response
+}
+
+// TestReadHostnames tests reading hostnames from arguments and files
+func (suite *hostMaintenanceTestSuite) TestReadHostnames() {
+	hostnames, err := suite.client.readHostnames("host2,host1", "")
+	suite.NoError(err)
+	suite.Equal([]string{"host1", "host2"}, hostnames)
+
+	file := suite.writeFile("# rack 1\nhost3\n\n  host4  \n")
+	hostnames, err = suite.client.readHostnames("", file)
+	suite.NoError(err)
+	suite.Equal([]string{"host3", "host4"}, hostnames)
+
+	hostnames, err = suite.client.readHostnames("host1", file)
+	suite.NoError(err)
+	suite.Equal([]string{"host1", "host3", "host4"}, hostnames)
+
+	// Duplicates across arguments and file
+	_, err = suite.client.readHostnames("host3", file)
+	suite.Error(err)
+
+	// No hosts
+	_, err = suite.client.readHostnames("", "")
+	suite.Error(err)
+	_, err = suite.client.readHostnames("", suite.writeFile("# empty\n"))
+	suite.Error(err)
+
+	// Missing file
+	_, err = suite.client.readHostnames("", filepath.Join(suite.dir, "missing"))
+	suite.Error(err)
+}
+
+// TestHostMaintenanceStatusAction tests printing the state of hosts,
+// including hosts unknown to host manager
+func (suite *hostMaintenanceTestSuite) TestHostMaintenanceStatusAction() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{
+			Fields: []string{"hostname", "ip", "state"},
+		}).
+		Return(queryHostsResponse(map[string]host.HostState{
+			"host1": host.HostState_HOST_STATE_DRAINING,
+			"host3": host.HostState_HOST_STATE_UP,
+		}), nil)
+	suite.NoError(suite.client.HostMaintenanceStatusAction(
+		"host1,host2", "", false, 0))
+
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("unavailable"))
+	suite.Error(suite.client.HostMaintenanceStatusAction(
+		"host1", "", false, 0))
+}
+
+// TestHostMaintenanceStartWatch tests watching hosts until they are DOWN
+func (suite *hostMaintenanceTestSuite) TestHostMaintenanceStartWatch() {
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: []string{"host1", "host2"},
+		}).
+		Return(&hostsvc.StartMaintenanceResponse{}, nil)
+	gomock.InOrder(
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), gomock.Any()).
+			Return(queryHostsResponse(map[string]host.HostState{
+				"host1": host.HostState_HOST_STATE_DRAINING,
+				"host2": host.HostState_HOST_STATE_DRAINING,
+			}), nil),
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), gomock.Any()).
+			Return(queryHostsResponse(map[string]host.HostState{
+				"host1": host.HostState_HOST_STATE_DOWN,
+				"host2": host.HostState_HOST_STATE_DRAINED,
+			}), nil),
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), gomock.Any()).
+			Return(queryHostsResponse(map[string]host.HostState{
+				"host1": host.HostState_HOST_STATE_DOWN,
+				"host2": host.HostState_HOST_STATE_DOWN,
+			}), nil),
+	)
+
+	file := suite.writeFile("host2\n")
+	suite.NoError(suite.client.HostMaintenanceStartAction(
+		"host1", file, "", 0, "", "", "", false, 0, 0, 0, 0, "", "", "", false, false, true, 0))
+}
+
+// TestHostMaintenanceCompleteWatch tests watching hosts until they are UP
+func (suite *hostMaintenanceTestSuite) TestHostMaintenanceCompleteWatch() {
+	suite.mockHostmgr.EXPECT().
+		CompleteMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.CompleteMaintenanceResponse{
+			CompletedHostnames: []string{"host1"},
+		}, nil)
+	gomock.InOrder(
+		// The host is not reported while it is registering again
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), gomock.Any()).
+			Return(queryHostsResponse(nil), nil),
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), gomock.Any()).
+			Return(queryHostsResponse(map[string]host.HostState{
+				"host1": host.HostState_HOST_STATE_UP,
+			}), nil),
+	)
+
+	suite.NoError(suite.client.HostMaintenanceCompleteAction(
+		"host1", "", "", false, false, true, 0))
+}
+
+// TestHostMaintenanceWatchTimeout tests that watching stops with an
+// error once the timeout expires
+func (suite *hostMaintenanceTestSuite) TestHostMaintenanceWatchTimeout() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(queryHostsResponse(map[string]host.HostState{
+			"host1": host.HostState_HOST_STATE_DRAINING,
+		}), nil).
+		MinTimes(1)
+
+	suite.Error(suite.client.HostMaintenanceStatusAction(
+		"host1", "", true, 20*time.Millisecond))
+}
+
+// TestHostMaintenanceWatchQueryError tests that watching stops on a
+// query error
+func (suite *hostMaintenanceTestSuite) TestHostMaintenanceWatchQueryError() {
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("unavailable"))
+
+	suite.Error(suite.client.HostMaintenanceStatusAction(
+		"host1", "", true, 0))
+}
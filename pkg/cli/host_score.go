@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/common/taskconfig"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	hostScoreCandidatesFormatHeader = "Hostname\tScore\t\n"
+	hostScoreCandidatesFormatBody   = "%s\t%.3f\t\n"
+	hostScoreRejectedFormatHeader   = "Hostname\tRejected By\tReason\t\n"
+	hostScoreRejectedFormatBody     = "%s\t%s\t%s\t\n"
+)
+
+// HostScoreAction is the action for scoring the hosts in the offer pool of host manager for the task config of a
+// job config, the default config or the config of the given instance if not negative. It prints the candidate hosts
+// in the order they are matched for placement, up to limit if set, and the filter rejecting each of the other hosts.
+// Only the hosts of the comma separated hostPools are matched, if set.
+func (c *Client) HostScoreAction(
+	cfg string,
+	instance int,
+	hostPools string,
+	limit uint32) error {
+	var jobConfig job.JobConfig
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	if err := yaml.Unmarshal(buffer, &jobConfig); err != nil {
+		return fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+
+	config := jobConfig.GetDefaultConfig()
+	if instance >= 0 {
+		if uint32(instance) >= jobConfig.GetInstanceCount() {
+			return fmt.Errorf("instance %d is not below the instance count %d",
+				instance, jobConfig.GetInstanceCount())
+		}
+		config = taskconfig.Merge(
+			config, jobConfig.GetInstanceConfig()[uint32(instance)])
+	}
+	var pools []string
+	if hostPools != "" {
+		pools = strings.Split(hostPools, ",")
+	}
+
+	resp, err := c.hostMgrClient.ScoreHosts(
+		c.ctx,
+		&hostsvc.ScoreHostsRequest{
+			Config:    config,
+			HostPools: pools,
+			Limit:     limit,
+		})
+	if err != nil {
+		return err
+	}
+
+	printScoreHostsResponse(resp, c.Debug)
+	return nil
+}
+
+func printScoreHostsResponse(resp *hostsvc.ScoreHostsResponse, debug bool) {
+	defer tabWriter.Flush()
+
+	if debug {
+		printResponseJSON(resp)
+		return
+	}
+
+	fmt.Fprintf(tabWriter, "%d of %d candidate hosts:\n",
+		len(resp.GetCandidates()), resp.GetTotalCandidates())
+	if len(resp.GetCandidates()) > 0 {
+		fmt.Fprintf(tabWriter, hostScoreCandidatesFormatHeader)
+		for _, score := range resp.GetCandidates() {
+			fmt.Fprintf(tabWriter, hostScoreCandidatesFormatBody,
+				score.GetHostname(), score.GetScore())
+		}
+	}
+	if len(resp.GetRejected()) == 0 {
+		return
+	}
+	fmt.Fprintf(tabWriter, "\n%d rejected hosts:\n", len(resp.GetRejected()))
+	fmt.Fprintf(tabWriter, hostScoreRejectedFormatHeader)
+	for _, score := range resp.GetRejected() {
+		fmt.Fprintf(tabWriter, hostScoreRejectedFormatBody,
+			score.GetHostname(),
+			strings.ToLower(score.GetResult().String()),
+			score.GetReason())
+	}
+}
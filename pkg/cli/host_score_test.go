@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	hostMocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostScoreTestSuite struct {
+	suite.Suite
+	ctrl        *gomock.Controller
+	mockHostMgr *hostMocks.MockInternalHostServiceYARPCClient
+	client      Client
+	dir         string
+}
+
+func TestHostScore(t *testing.T) {
+	suite.Run(t, new(hostScoreTestSuite))
+}
+
+func (suite *hostScoreTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostMgr = hostMocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
+	suite.client = Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           context.Background(),
+	}
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "host_score")
+	suite.NoError(err)
+}
+
+func (suite *hostScoreTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+	suite.ctrl.Finish()
+}
+
+func (suite *hostScoreTestSuite) writeFile(content string) string {
+	file := filepath.Join(suite.dir, "job.yaml")
+	suite.NoError(ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func (suite *hostScoreTestSuite) TestHostScoreAction() {
+	file := suite.writeFile(testCheckConstraintsJobConfig)
+	resp := &hostsvc.ScoreHostsResponse{
+		Candidates: []*hostsvc.HostScore{
+			{
+				Hostname: "host1",
+				Score:    1,
+				Result:   hostsvc.HostFilterResult_MATCH,
+			},
+		},
+		TotalCandidates: 2,
+		Rejected: []*hostsvc.HostScore{
+			{
+				Hostname: "host3",
+				Result:   hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+				Reason:   "constraint not satisfied",
+			},
+		},
+	}
+
+	// Test scoring the hosts for the default config
+	suite.mockHostMgr.EXPECT().
+		ScoreHosts(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *hostsvc.ScoreHostsRequest) {
+			suite.Equal("dca1",
+				req.GetConfig().GetConstraint().GetLabelConstraint().GetLabel().GetValue())
+			suite.Equal([]string{"pool1", "pool2"}, req.GetHostPools())
+			suite.Equal(uint32(1), req.GetLimit())
+		}).
+		Return(resp, nil)
+	suite.NoError(suite.client.HostScoreAction(file, -1, "pool1,pool2", 1))
+
+	// Test scoring the hosts for the config of an instance
+	suite.mockHostMgr.EXPECT().
+		ScoreHosts(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *hostsvc.ScoreHostsRequest) {
+			suite.Equal(task.Constraint_LABEL_CONSTRAINT,
+				req.GetConfig().GetConstraint().GetType())
+			suite.Equal("sjc1",
+				req.GetConfig().GetConstraint().GetLabelConstraint().GetLabel().GetValue())
+			suite.Empty(req.GetHostPools())
+		}).
+		Return(&hostsvc.ScoreHostsResponse{}, nil)
+	suite.NoError(suite.client.HostScoreAction(file, 1, "", 0))
+
+	// Test instance beyond the instance count
+	suite.Error(suite.client.HostScoreAction(file, 2, "", 0))
+
+	// Test ScoreHosts error
+	suite.mockHostMgr.EXPECT().
+		ScoreHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake ScoreHosts error"))
+	suite.Error(suite.client.HostScoreAction(file, -1, "", 0))
+
+	// Test missing file
+	suite.Error(suite.client.HostScoreAction(
+		filepath.Join(suite.dir, "missing.yaml"), -1, "", 0))
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+// HostTasksGetAction prints the tasks running on the given hosts as known
+// to the Host Manager task-to-host index. All indexed hosts are printed if
+// no hostname is specified.
+func (c *Client) HostTasksGetAction(hosts string) error {
+	var hostnames []string
+	if hosts != "" {
+		var err error
+		hostnames, err = c.ExtractHostnames(hosts, hostSeparator)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.hostMgrClient.GetTasksByHosts(
+		c.ctx,
+		&hostsvc.GetTasksByHostsRequest{
+			Hostnames: hostnames,
+		})
+	if err != nil {
+		return err
+	}
+
+	printGetTasksByHostsResponse(resp)
+	return nil
+}
+
+func printGetTasksByHostsResponse(resp *hostsvc.GetTasksByHostsResponse) {
+	if len(resp.GetHostTasks()) == 0 {
+		fmt.Fprint(tabWriter, "No tasks are indexed for the hosts \n")
+	} else {
+		out, err := marshallResponse(jsonResponseFormat, resp)
+		if err != nil {
+			fmt.Fprint(tabWriter, "Unable to marshall response \n")
+		}
+		fmt.Printf("%v\n", string(out))
+	}
+	tabWriter.Flush()
+}
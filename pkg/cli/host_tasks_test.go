@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	hostMocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostTasksActionsTestSuite struct {
+	suite.Suite
+	ctx         context.Context
+	ctrl        *gomock.Controller
+	mockHostMgr *hostMocks.MockInternalHostServiceYARPCClient
+}
+
+func (suite *hostTasksActionsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostMgr = hostMocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
+	suite.ctx = context.Background()
+}
+
+func (suite *hostTasksActionsTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *hostTasksActionsTestSuite) TestHostTasksGetAction() {
+	c := Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+
+	suite.mockHostMgr.EXPECT().GetTasksByHosts(
+		gomock.Any(),
+		&hostsvc.GetTasksByHostsRequest{
+			Hostnames: []string{_testAgent},
+		}).Return(&hostsvc.GetTasksByHostsResponse{
+		HostTasks: map[string]*hostsvc.TaskIDList{
+			_testAgent: {TaskIds: []string{"task-1", "task-2"}},
+		},
+	}, nil)
+	suite.NoError(c.HostTasksGetAction(_testAgent))
+
+	// Test no tasks indexed
+	suite.mockHostMgr.EXPECT().GetTasksByHosts(
+		gomock.Any(),
+		&hostsvc.GetTasksByHostsRequest{}).
+		Return(&hostsvc.GetTasksByHostsResponse{}, nil)
+	suite.NoError(c.HostTasksGetAction(""))
+
+	// Test GetTasksByHosts error
+	suite.mockHostMgr.EXPECT().GetTasksByHosts(
+		gomock.Any(),
+		gomock.Any()).
+		Return(nil, errors.New("fake GetTasksByHosts error"))
+	suite.Error(c.HostTasksGetAction(_testAgent))
+
+	// Test duplicate hostname error
+	suite.Error(c.HostTasksGetAction("host,host"))
+}
+
+func TestHostTasksAction(t *testing.T) {
+	suite.Run(t, new(hostTasksActionsTestSuite))
+}
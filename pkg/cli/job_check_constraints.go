@@ -0,0 +1,321 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/constraints"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	constraintCheckDefaultConfig   = "default config"
+	constraintCheckConstraint      = "constraint"
+	constraintCheckExclusiveHosts  = "exclusive hosts"
+	constraintCheckPrunerHeader    = "Constraint\tHosts pruned\t\n"
+	constraintCheckPrunerBody      = "%s\t%d\t\n"
+	constraintCheckTaskLabelNotice = "Task label constraints are not evaluated, as they depend on the tasks " +
+		"running on the hosts at placement time.\n"
+)
+
+// constraintPruner is the number of hosts a part of a constraint
+// rejects on its own.
+type constraintPruner struct {
+	description string
+	pruned      int
+}
+
+// constraintCheck is a constraint to dry-run, and the name it is reported
+// with.
+type constraintCheck struct {
+	name       string
+	constraint *task.Constraint
+}
+
+// constraintCheckResult is the result of evaluating a constraint
+// against the current hosts.
+type constraintCheckResult struct {
+	name      string
+	total     int
+	satisfied int
+	// pruners is sorted by the number of pruned hosts, descending.
+	pruners []constraintPruner
+	// hasTaskConstraints is true if the constraint contains task label
+	// constraints, which cannot be evaluated locally.
+	hasTaskConstraints bool
+}
+
+// JobCheckConstraintsAction is the action for a dry-run of the placement constraints of a job config, or of a
+// constraint written in the constraint DSL, against the labels of the hosts which are currently up. It reports how
+// many hosts satisfy the constraint of the default config and of each instance config overriding it, and which part
+// of the constraint prunes the most hosts.
+func (c *Client) JobCheckConstraintsAction(cfg string, constraintFile string) error {
+	var checks []constraintCheck
+	var err error
+	switch {
+	case cfg != "" && constraintFile != "":
+		return fmt.Errorf("a job config and a constraint cannot be checked together")
+	case constraintFile != "":
+		checks, err = readConstraintCheck(constraintFile)
+	case cfg != "":
+		checks, err = readJobConfigConstraintChecks(cfg)
+	default:
+		return fmt.Errorf("a job config or a constraint is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	response, err := c.hostClient.QueryHosts(c.ctx, &host_svc.QueryHostsRequest{
+		HostStates: []host.HostState{host.HostState_HOST_STATE_UP},
+	})
+	if err != nil {
+		return err
+	}
+
+	hosts := make(map[string]constraints.LabelValues)
+	for _, info := range response.GetHostInfos() {
+		hosts[info.GetHostname()] = hostLabelValues(info)
+	}
+
+	now := time.Now()
+	var results []*constraintCheckResult
+	for _, check := range checks {
+		result, err := checkConstraint(check.name, check.constraint, hosts, now)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	printConstraintCheckResults(results)
+	return nil
+}
+
+// readConstraintCheck reads a constraint written in the constraint DSL,
+// in YAML or JSON.
+func readConstraintCheck(constraintFile string) ([]constraintCheck, error) {
+	buffer, err := ioutil.ReadFile(constraintFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", constraintFile, err)
+	}
+	constraint, err := constraints.Unmarshal(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse constraint %s: %v", constraintFile, err)
+	}
+	return []constraintCheck{{
+		name:       constraintCheckConstraint,
+		constraint: constraint,
+	}}, nil
+}
+
+// readJobConfigConstraintChecks reads the constraints of the default config
+// of a job config, and of each instance config overriding it.
+func readJobConfigConstraintChecks(cfg string) ([]constraintCheck, error) {
+	var jobConfig job.JobConfig
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	if err := yaml.Unmarshal(buffer, &jobConfig); err != nil {
+		return nil, fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+
+	checks := []constraintCheck{{
+		name:       constraintCheckDefaultConfig,
+		constraint: jobConfig.GetDefaultConfig().GetConstraint(),
+	}}
+	var instances []uint32
+	for instance, config := range jobConfig.GetInstanceConfig() {
+		if config.GetConstraint() != nil {
+			instances = append(instances, instance)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i] < instances[j]
+	})
+	for _, instance := range instances {
+		checks = append(checks, constraintCheck{
+			name:       fmt.Sprintf("instance %d", instance),
+			constraint: jobConfig.GetInstanceConfig()[instance].GetConstraint(),
+		})
+	}
+	return checks, nil
+}
+
+// hostLabelValues returns the label values of a host used for
+// evaluating host label constraints.
+func hostLabelValues(info *host.HostInfo) constraints.LabelValues {
+	lv := constraints.LabelValues{
+		constraints.HostNameKey: {info.GetHostname(): 1},
+	}
+	for _, label := range info.GetLabels() {
+		lv.AddLabel(label.GetKey(), label.GetValue())
+	}
+	return lv
+}
+
+// hasAnyValue returns true if one of the given values is a label value.
+func hasAnyValue(labelValues map[string]uint32, values []string) bool {
+	for _, value := range values {
+		if labelValues[value] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConstraint evaluates a constraint against the label values of
+// each host at the given time, the same way host manager filters hosts
+// for placement. Each label and time window constraint in the tree is
+// also evaluated on its own, to find the ones pruning the most hosts.
+func checkConstraint(
+	name string,
+	constraint *task.Constraint,
+	hosts map[string]constraints.LabelValues,
+	at time.Time) (*constraintCheckResult, error) {
+	evaluator := constraints.NewEvaluator(task.LabelConstraint_HOST)
+	exclusiveValues := constraints.GetRequiredExclusiveValues(constraint)
+	leaves := getLeafConstraints(constraint)
+
+	result := &constraintCheckResult{
+		name:  name,
+		total: len(hosts),
+	}
+	pruned := make([]int, len(leaves))
+	exclusivePruned := 0
+	for _, lv := range hosts {
+		// Hosts with the exclusive attribute are only used by tasks
+		// which require one of its values
+		if values, ok := lv[common.PelotonExclusiveAttributeName]; ok &&
+			!hasAnyValue(values, exclusiveValues) {
+			exclusivePruned++
+			continue
+		}
+
+		if constraint == nil {
+			result.satisfied++
+			continue
+		}
+
+		r, err := evaluator.EvaluateAt(constraint, lv, at)
+		if err != nil {
+			return nil, err
+		}
+		if r != constraints.EvaluateResultMismatch {
+			result.satisfied++
+		}
+
+		for i, leaf := range leaves {
+			r, err := evaluator.EvaluateAt(leaf, lv, at)
+			if err != nil {
+				return nil, err
+			}
+			if r == constraints.EvaluateResultMismatch {
+				pruned[i]++
+			}
+		}
+	}
+
+	for i, leaf := range leaves {
+		if leaf.GetType() == task.Constraint_LABEL_CONSTRAINT &&
+			leaf.GetLabelConstraint().GetKind() == task.LabelConstraint_TASK {
+			result.hasTaskConstraints = true
+			continue
+		}
+		description, err := constraints.MarshalJSON(leaf)
+		if err != nil {
+			return nil, err
+		}
+		result.pruners = append(result.pruners, constraintPruner{
+			description: string(description),
+			pruned:      pruned[i],
+		})
+	}
+	if exclusivePruned > 0 {
+		result.pruners = append(result.pruners, constraintPruner{
+			description: constraintCheckExclusiveHosts,
+			pruned:      exclusivePruned,
+		})
+	}
+	sort.SliceStable(result.pruners, func(i, j int) bool {
+		return result.pruners[i].pruned > result.pruners[j].pruned
+	})
+	return result, nil
+}
+
+// getLeafConstraints returns the label and time window constraints in
+// a constraint tree, in depth-first order. Time window constraints are
+// not descended into, as they only apply within their windows.
+func getLeafConstraints(constraint *task.Constraint) []*task.Constraint {
+	switch constraint.GetType() {
+	case task.Constraint_AND_CONSTRAINT:
+		var leaves []*task.Constraint
+		for _, c := range constraint.GetAndConstraint().GetConstraints() {
+			leaves = append(leaves, getLeafConstraints(c)...)
+		}
+		return leaves
+	case task.Constraint_OR_CONSTRAINT:
+		var leaves []*task.Constraint
+		for _, c := range constraint.GetOrConstraint().GetConstraints() {
+			leaves = append(leaves, getLeafConstraints(c)...)
+		}
+		return leaves
+	case task.Constraint_LABEL_CONSTRAINT,
+		task.Constraint_TIME_WINDOW_CONSTRAINT:
+		return []*task.Constraint{constraint}
+	}
+	return nil
+}
+
+func printConstraintCheckResults(results []*constraintCheckResult) {
+	defer tabWriter.Flush()
+
+	for _, r := range results {
+		fmt.Fprintf(tabWriter, "Constraint of %s: %d of %d hosts satisfy\n",
+			r.name, r.satisfied, r.total)
+		if len(r.pruners) == 0 {
+			continue
+		}
+		if r.pruners[0].pruned > 0 {
+			fmt.Fprintf(tabWriter, "Most pruning: %s (%d hosts)\n",
+				r.pruners[0].description, r.pruners[0].pruned)
+		}
+		fmt.Fprintf(tabWriter, constraintCheckPrunerHeader)
+		for _, p := range r.pruners {
+			fmt.Fprintf(tabWriter, constraintCheckPrunerBody,
+				p.description, p.pruned)
+		}
+		fmt.Fprintf(tabWriter, "\n")
+	}
+	hasTaskConstraints := false
+	for _, r := range results {
+		hasTaskConstraints = hasTaskConstraints || r.hasTaskConstraints
+	}
+	if hasTaskConstraints {
+		fmt.Fprintf(tabWriter, constraintCheckTaskLabelNotice)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/constraints"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testCheckConstraintsJobConfig = `
+name: TestJob
+instancecount: 2
+defaultconfig:
+  constraint:
+    type: 1
+    labelconstraint:
+      kind: 2
+      condition: 2
+      requirement: 1
+      label:
+        key: zone
+        value: dca1
+instanceconfig:
+  1:
+    constraint:
+      type: 1
+      labelconstraint:
+        kind: 2
+        condition: 2
+        requirement: 1
+        label:
+          key: zone
+          value: sjc1
+`
+
+type jobCheckConstraintsTestSuite struct {
+	suite.Suite
+	ctrl        *gomock.Controller
+	mockHostmgr *hostmocks.MockHostServiceYARPCClient
+	client      Client
+	dir         string
+}
+
+func TestJobCheckConstraints(t *testing.T) {
+	suite.Run(t, new(jobCheckConstraintsTestSuite))
+}
+
+func (suite *jobCheckConstraintsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockHostmgr = hostmocks.NewMockHostServiceYARPCClient(suite.ctrl)
+	suite.client = Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        context.Background(),
+	}
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "job_check_constraints")
+	suite.NoError(err)
+}
+
+func (suite *jobCheckConstraintsTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+	suite.ctrl.Finish()
+}
+
+func (suite *jobCheckConstraintsTestSuite) writeFile(content string) string {
+	file := filepath.Join(suite.dir, "job.yaml")
+	suite.NoError(ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func hostLabelConstraint(
+	key, value string,
+	kind task.LabelConstraint_Kind,
+	condition task.LabelConstraint_Condition,
+	requirement uint32) *task.Constraint {
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:        kind,
+			Condition:   condition,
+			Requirement: requirement,
+			Label:       &peloton.Label{Key: key, Value: value},
+		},
+	}
+}
+
+func (suite *jobCheckConstraintsTestSuite) hosts() map[string]constraints.LabelValues {
+	return map[string]constraints.LabelValues{
+		"host1": hostLabelValues(&host.HostInfo{
+			Hostname: "host1",
+			Labels:   []*peloton.Label{{Key: "zone", Value: "dca1"}},
+		}),
+		"host2": hostLabelValues(&host.HostInfo{
+			Hostname: "host2",
+			Labels:   []*peloton.Label{{Key: "zone", Value: "sjc1"}},
+		}),
+		"host3": hostLabelValues(&host.HostInfo{
+			Hostname: "host3",
+			Labels: []*peloton.Label{
+				{Key: "zone", Value: "dca1"},
+				{Key: common.PelotonExclusiveAttributeName, Value: "storage"},
+			},
+		}),
+	}
+}
+
+// TestCheckConstraint tests counting the hosts satisfying a constraint
+// and the hosts pruned by each part of it
+func (suite *jobCheckConstraintsTestSuite) TestCheckConstraint() {
+	zone := hostLabelConstraint("zone", "dca1",
+		task.LabelConstraint_HOST, task.LabelConstraint_CONDITION_EQUAL, 1)
+	rack := hostLabelConstraint("rack", "r1",
+		task.LabelConstraint_HOST, task.LabelConstraint_CONDITION_LESS_THAN, 1)
+	app := hostLabelConstraint("app", "x",
+		task.LabelConstraint_TASK, task.LabelConstraint_CONDITION_LESS_THAN, 1)
+
+	result, err := checkConstraint("test", &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{zone, rack, app},
+		},
+	}, suite.hosts(), time.Now())
+	suite.NoError(err)
+	suite.Equal(3, result.total)
+	suite.Equal(1, result.satisfied)
+	suite.True(result.hasTaskConstraints)
+
+	zoneJSON, err := constraints.MarshalJSON(zone)
+	suite.NoError(err)
+	rackJSON, err := constraints.MarshalJSON(rack)
+	suite.NoError(err)
+	suite.Equal([]constraintPruner{
+		{description: string(zoneJSON), pruned: 1},
+		{description: constraintCheckExclusiveHosts, pruned: 1},
+		{description: string(rackJSON), pruned: 0},
+	}, result.pruners)
+}
+
+// TestCheckConstraintExclusive tests that exclusive hosts are not
+// pruned for a constraint on the exclusive attribute
+func (suite *jobCheckConstraintsTestSuite) TestCheckConstraintExclusive() {
+	result, err := checkConstraint("test", hostLabelConstraint(
+		common.PelotonExclusiveAttributeName, "storage",
+		task.LabelConstraint_HOST, task.LabelConstraint_CONDITION_EQUAL, 1,
+	), suite.hosts(), time.Now())
+	suite.NoError(err)
+	suite.Equal(1, result.satisfied)
+	suite.Len(result.pruners, 1)
+	suite.Equal(2, result.pruners[0].pruned)
+	suite.False(result.hasTaskConstraints)
+}
+
+// TestCheckConstraintOtherExclusive tests that exclusive hosts are
+// pruned for a constraint requiring another exclusive value
+func (suite *jobCheckConstraintsTestSuite) TestCheckConstraintOtherExclusive() {
+	result, err := checkConstraint("test", hostLabelConstraint(
+		common.PelotonExclusiveAttributeName, "gpu",
+		task.LabelConstraint_HOST, task.LabelConstraint_CONDITION_EQUAL, 1,
+	), suite.hosts(), time.Now())
+	suite.NoError(err)
+	suite.Zero(result.satisfied)
+	suite.Contains(result.pruners, constraintPruner{
+		description: constraintCheckExclusiveHosts,
+		pruned:      1,
+	})
+}
+
+// TestCheckConstraintNil tests that all non-exclusive hosts satisfy a
+// job without constraint
+func (suite *jobCheckConstraintsTestSuite) TestCheckConstraintNil() {
+	result, err := checkConstraint("test", nil, suite.hosts(), time.Now())
+	suite.NoError(err)
+	suite.Equal(2, result.satisfied)
+	suite.Equal([]constraintPruner{
+		{description: constraintCheckExclusiveHosts, pruned: 1},
+	}, result.pruners)
+}
+
+// TestJobCheckConstraintsAction tests the dry-run of a job config
+func (suite *jobCheckConstraintsTestSuite) TestJobCheckConstraintsAction() {
+	file := suite.writeFile(testCheckConstraintsJobConfig)
+
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{
+			HostStates: []host.HostState{host.HostState_HOST_STATE_UP},
+		}).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{
+					Hostname: "host1",
+					Labels:   []*peloton.Label{{Key: "zone", Value: "dca1"}},
+				},
+			},
+		}, nil)
+	suite.NoError(suite.client.JobCheckConstraintsAction(file, ""))
+}
+
+// TestJobCheckConstraintsActionDSL tests the dry-run of a constraint
+// written in the constraint DSL
+func (suite *jobCheckConstraintsTestSuite) TestJobCheckConstraintsActionDSL() {
+	file := suite.writeFile(`
+or:
+- label: {kind: host, key: zone, value: dca1, condition: greater_than}
+- label: {kind: host, key: zone, value: phx2, condition: greater_than}
+`)
+
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{
+					Hostname: "host1",
+					Labels:   []*peloton.Label{{Key: "zone", Value: "dca1"}},
+				},
+			},
+		}, nil)
+	suite.NoError(suite.client.JobCheckConstraintsAction("", file))
+
+	checks, err := readConstraintCheck(file)
+	suite.NoError(err)
+	suite.Len(checks, 1)
+	suite.Equal(constraintCheckConstraint, checks[0].name)
+	suite.Equal(task.Constraint_OR_CONSTRAINT, checks[0].constraint.GetType())
+	suite.Len(checks[0].constraint.GetOrConstraint().GetConstraints(), 2)
+}
+
+// TestJobCheckConstraintsActionErrors tests failures to read the job
+// config or to query the hosts
+func (suite *jobCheckConstraintsTestSuite) TestJobCheckConstraintsActionErrors() {
+	suite.Error(suite.client.JobCheckConstraintsAction(
+		filepath.Join(suite.dir, "missing.yaml"), ""))
+	suite.Error(suite.client.JobCheckConstraintsAction(
+		suite.writeFile("defaultconfig: ["), ""))
+	suite.Error(suite.client.JobCheckConstraintsAction(
+		"", filepath.Join(suite.dir, "missing.yaml")))
+	suite.Error(suite.client.JobCheckConstraintsAction(
+		"", suite.writeFile("label: {kind: pod}")))
+	suite.Error(suite.client.JobCheckConstraintsAction("", ""))
+	file := suite.writeFile(testCheckConstraintsJobConfig)
+	suite.Error(suite.client.JobCheckConstraintsAction(file, file))
+
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("fake QueryHosts error"))
+	suite.Error(suite.client.JobCheckConstraintsAction(
+		suite.writeFile(testCheckConstraintsJobConfig), ""))
+}
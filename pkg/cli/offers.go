@@ -44,3 +44,42 @@ func printGetOutstandingOffersResponse(resp *hostsvc.GetOutstandingOffersRespons
 	}
 	tabWriter.Flush()
 }
+
+// HostOffersGetAction prints the offers held in Host Manager offer pool for
+// each of the given hosts, along with the host status and hold expiry.
+// Offers of all hosts are printed if no hosts are given.
+func (c *Client) HostOffersGetAction(hosts string) error {
+	var hostnames []string
+	if hosts != "" {
+		var err error
+		hostnames, err = c.ExtractHostnames(hosts, hostSeparator)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.hostMgrClient.GetHostOffers(
+		c.ctx,
+		&hostsvc.GetHostOffersRequest{
+			Hostnames: hostnames,
+		})
+	if err != nil {
+		return err
+	}
+
+	printGetHostOffersResponse(resp)
+	return nil
+}
+
+func printGetHostOffersResponse(resp *hostsvc.GetHostOffersResponse) {
+	if len(resp.GetHosts()) == 0 {
+		fmt.Fprint(tabWriter, "No hosts are present in offer pool \n")
+	} else {
+		out, err := marshallResponse(jsonResponseFormat, resp)
+		if err != nil {
+			fmt.Fprint(tabWriter, "Unable to marshall response \n")
+		}
+		fmt.Printf("%v\n", string(out))
+	}
+	tabWriter.Flush()
+}
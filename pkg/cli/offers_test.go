@@ -16,6 +16,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"testing"
 
@@ -131,6 +132,49 @@ func (suite *offersActionsTestSuite) TestGetOutstandingOffers() {
 	suite.NoError(c.OffersGetAction())
 }
 
+func (suite *offersActionsTestSuite) TestGetHostOffers() {
+	c := Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+
+	resp := &hostsvc.GetHostOffersResponse{
+		Hosts: []*hostsvc.GetHostOffersResponse_Host{
+			{
+				Hostname:         _testAgent,
+				Status:           "ready",
+				UnreservedOffers: suite.createUnreservedMesosOffers(2),
+			},
+		},
+	}
+
+	suite.mockHostMgr.EXPECT().GetHostOffers(
+		gomock.Any(),
+		&hostsvc.GetHostOffersRequest{
+			Hostnames: []string{_testAgent},
+		}).Return(resp, nil)
+	suite.NoError(c.HostOffersGetAction(_testAgent))
+
+	// Test no hosts in offer pool
+	suite.mockHostMgr.EXPECT().GetHostOffers(
+		gomock.Any(),
+		&hostsvc.GetHostOffersRequest{}).
+		Return(&hostsvc.GetHostOffersResponse{}, nil)
+	suite.NoError(c.HostOffersGetAction(""))
+
+	// Test GetHostOffers error
+	suite.mockHostMgr.EXPECT().GetHostOffers(
+		gomock.Any(),
+		gomock.Any()).
+		Return(nil, errors.New("fake GetHostOffers error"))
+	suite.Error(c.HostOffersGetAction(_testAgent))
+
+	// Test duplicate hostname error
+	suite.Error(c.HostOffersGetAction("host,host"))
+}
+
 func TestOffersAction(t *testing.T) {
 	suite.Run(t, new(offersActionsTestSuite))
 }
@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/api/transport"
+)
+
+const (
+	// RequestIDHeader is the header carrying the correlation ID of a
+	// request. It is generated by the inbound if the caller did not set it,
+	// and propagated to the calls made while handling the request.
+	RequestIDHeader = "x-request-id"
+
+	// UserAgentHeader is the header carrying the user agent of the caller
+	UserAgentHeader = "user-agent"
+
+	// UserHeader is the header carrying the user of the caller, which is
+	// also used for authentication
+	UserHeader = "username"
+)
+
+type infoKey struct{}
+
+// Info identifies a request and its caller
+type Info struct {
+	// Caller is the service name of the caller
+	Caller string
+	// User is the user the caller authenticates as
+	User string
+	// RequestID is the correlation ID of the request
+	RequestID string
+	// UserAgent is the user agent of the caller
+	UserAgent string
+}
+
+// NewInfo extracts the request info from the caller and the headers of
+// a request, and generates a request ID if the headers do not have one.
+func NewInfo(caller string, headers transport.Headers) Info {
+	info := Info{Caller: caller}
+	info.User, _ = headers.Get(UserHeader)
+	info.RequestID, _ = headers.Get(RequestIDHeader)
+	info.UserAgent, _ = headers.Get(UserAgentHeader)
+	if info.RequestID == "" {
+		info.RequestID = uuid.New()
+	}
+	return info
+}
+
+// Fields returns the request info as log fields
+func (i Info) Fields() log.Fields {
+	return log.Fields{
+		"caller":     i.Caller,
+		"user":       i.User,
+		"request_id": i.RequestID,
+		"user_agent": i.UserAgent,
+	}
+}
+
+// WithInfo returns a copy of the context carrying the request info
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// NewOperation returns a copy of the context carrying the request info
+// of an operation the caller starts on its own, e.g. a cycle of a
+// background job, with a new request ID.
+func NewOperation(ctx context.Context, caller string) context.Context {
+	return WithInfo(ctx, Info{Caller: caller, RequestID: uuid.New()})
+}
+
+// FromContext returns the request info carried by the context
+func FromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(infoKey{}).(Info)
+	return info, ok
+}
+
+// RequestID returns the request ID carried by the context, or an empty
+// string if the context does not carry request info
+func RequestID(ctx context.Context) string {
+	info, _ := FromContext(ctx)
+	return info.RequestID
+}
+
+// Logger returns a log entry with the fields of the request info carried
+// by the context, so that the logs of a request can be correlated.
+func Logger(ctx context.Context) *log.Entry {
+	info, ok := FromContext(ctx)
+	if !ok {
+		return log.NewEntry(log.StandardLogger())
+	}
+	return log.WithFields(info.Fields())
+}
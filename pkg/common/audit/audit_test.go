@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
+
+// TestNewInfo tests extracting the request info from headers.
+func (suite *AuditTestSuite) TestNewInfo() {
+	headers := transport.NewHeaders().
+		With(UserHeader, "operator").
+		With(RequestIDHeader, "request-1").
+		With(UserAgentHeader, "peloton-cli")
+
+	info := NewInfo("peloton-cli", headers)
+	suite.Equal(Info{
+		Caller:    "peloton-cli",
+		User:      "operator",
+		RequestID: "request-1",
+		UserAgent: "peloton-cli",
+	}, info)
+	suite.Equal("request-1", info.Fields()["request_id"])
+}
+
+// TestNewInfoGeneratesRequestID tests that a request ID is generated
+// for requests without one.
+func (suite *AuditTestSuite) TestNewInfoGeneratesRequestID() {
+	info1 := NewInfo("caller", transport.NewHeaders())
+	info2 := NewInfo("caller", transport.NewHeaders())
+	suite.NotEmpty(info1.RequestID)
+	suite.NotEqual(info1.RequestID, info2.RequestID)
+}
+
+// TestContext tests carrying the request info in a context.
+func (suite *AuditTestSuite) TestContext() {
+	ctx := context.Background()
+	_, ok := FromContext(ctx)
+	suite.False(ok)
+	suite.Empty(RequestID(ctx))
+	suite.Empty(Logger(ctx).Data)
+
+	info := Info{Caller: "caller", RequestID: "request-1"}
+	ctx = WithInfo(ctx, info)
+	actual, ok := FromContext(ctx)
+	suite.True(ok)
+	suite.Equal(info, actual)
+	suite.Equal("request-1", RequestID(ctx))
+	suite.Equal("request-1", Logger(ctx).Data["request_id"])
+}
+
+// TestNewOperation tests that operations get distinct request IDs.
+func (suite *AuditTestSuite) TestNewOperation() {
+	ctx1 := NewOperation(context.Background(), "caller")
+	ctx2 := NewOperation(context.Background(), "caller")
+	info, ok := FromContext(ctx1)
+	suite.True(ok)
+	suite.Equal("caller", info.Caller)
+	suite.NotEmpty(info.RequestID)
+	suite.NotEqual(info.RequestID, RequestID(ctx2))
+}
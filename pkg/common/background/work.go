@@ -31,6 +31,8 @@ const (
 var (
 	errEmptyName     = errors.New("background work name cannot be empty")
 	errDuplicateName = errors.New("duplicate background work name")
+	errUnknownName   = errors.New("unknown background work name")
+	errBadPeriod     = errors.New("background work period must be positive")
 )
 
 // Work refers to a piece of background work which needs to happen
@@ -51,6 +53,9 @@ type Manager interface {
 	Stop()
 	// RegisterWork registers a background work against the Manager
 	RegisterWorks(works ...Work) error
+	// UpdatePeriod changes the period of a registered background work,
+	// taking effect immediately if the work is running.
+	UpdatePeriod(name string, period time.Duration) error
 }
 
 // manager implements Manager interface.
@@ -77,8 +82,9 @@ func (r *manager) RegisterWorks(works ...Work) error {
 		}
 
 		r.runners[work.Name] = &runner{
-			work:     work,
-			stopChan: make(chan struct{}, 1),
+			work:       work,
+			stopChan:   make(chan struct{}, 1),
+			periodChan: make(chan time.Duration, 1),
 		}
 	}
 	return nil
@@ -98,13 +104,27 @@ func (r *manager) Stop() {
 	}
 }
 
+// UpdatePeriod changes the period of a registered background work.
+func (r *manager) UpdatePeriod(name string, period time.Duration) error {
+	if period <= 0 {
+		return errBadPeriod
+	}
+	runner, ok := r.runners[name]
+	if !ok {
+		return errUnknownName
+	}
+	runner.updatePeriod(period)
+	return nil
+}
+
 type runner struct {
 	sync.Mutex
 
 	work Work
 
-	running  atomic.Bool
-	stopChan chan struct{}
+	running    atomic.Bool
+	stopChan   chan struct{}
+	periodChan chan time.Duration
 }
 
 func (r *runner) start() {
@@ -118,6 +138,7 @@ func (r *runner) start() {
 		return
 	}
 
+	period := r.work.Period
 	go func() {
 		defer r.running.Store(false)
 
@@ -139,14 +160,20 @@ func (r *runner) start() {
 			r.work.Func(&r.running)
 		}
 
-		ticker := time.NewTicker(r.work.Period)
-		defer ticker.Stop()
+		ticker := time.NewTicker(period)
+		defer func() { ticker.Stop() }()
 		for {
 			select {
 			case <-r.stopChan:
 				log.WithField("name", r.work.Name).
 					Info("Background work stopped.")
 				return
+			case period := <-r.periodChan:
+				log.WithField("name", r.work.Name).
+					WithField("interval_secs", period.Seconds()).
+					Info("Background work period updated.")
+				ticker.Stop()
+				ticker = time.NewTicker(period)
 			case t := <-ticker.C:
 				log.WithField("tick", t).
 					WithField("name", r.work.Name).
@@ -157,6 +184,24 @@ func (r *runner) start() {
 	}()
 }
 
+// updatePeriod changes the period of the work, resetting the ticker of
+// the running work.
+func (r *runner) updatePeriod(period time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.work.Period = period
+
+	// Replace any period not yet picked up by the running work
+	select {
+	case <-r.periodChan:
+	default:
+	}
+	if r.running.Load() {
+		r.periodChan <- period
+	}
+}
+
 func (r *runner) stop() {
 	log.WithField("name", r.work.Name).Info("Stopping Background work.")
 
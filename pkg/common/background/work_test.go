@@ -153,3 +153,43 @@ func (suite *WorkManagerTestSuite) TestRepeatedStartStop() {
 	suite.False(runner.running.Load())
 	suite.Zero(len(runner.stopChan))
 }
+
+// TestUpdatePeriod tests that the period of a running work is updated
+// immediately, and that of a stopped work on its next start
+func (suite *WorkManagerTestSuite) TestUpdatePeriod() {
+	v1 := atomic.Int64{}
+	testMgr := NewManager()
+	err := testMgr.RegisterWorks(
+		Work{
+			Name:   "TestUpdatePeriod",
+			Period: time.Hour,
+			Func: func(_ *atomic.Bool) {
+				v1.Inc()
+			},
+		},
+	)
+	suite.NoError(err)
+
+	suite.Error(testMgr.UpdatePeriod("unknown", time.Millisecond))
+	suite.Error(testMgr.UpdatePeriod("TestUpdatePeriod", 0))
+
+	testMgr.Start()
+	time.Sleep(time.Millisecond * 15)
+	suite.Zero(v1.Load())
+
+	suite.NoError(testMgr.UpdatePeriod("TestUpdatePeriod", time.Millisecond))
+	time.Sleep(time.Millisecond * 15)
+	suite.NotZero(v1.Load())
+	testMgr.Stop()
+
+	suite.NoError(testMgr.UpdatePeriod("TestUpdatePeriod", time.Hour))
+	runner := testMgr.(*manager).runners["TestUpdatePeriod"]
+	suite.Zero(len(runner.periodChan))
+	suite.Equal(time.Hour, runner.work.Period)
+
+	testMgr.Start()
+	stopped := v1.Load()
+	time.Sleep(time.Millisecond * 15)
+	suite.Equal(stopped, v1.Load())
+	testMgr.Stop()
+}
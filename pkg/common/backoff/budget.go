@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"math"
+	"sync"
+)
+
+// Budget limits the retries of a caller to a ratio of its calls, so that
+// retries do not multiply the load of a backend which is already failing.
+// Every call deposits ratio tokens into the budget, up to maxTokens, and
+// every retry withdraws one token. A nil Budget allows every retry.
+type Budget struct {
+	sync.Mutex
+
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+}
+
+// NewBudget returns a Budget allowing ratio retries per call, with at most
+// maxTokens retries in a burst. The budget starts full.
+func NewBudget(ratio float64, maxTokens int) *Budget {
+	return &Budget{
+		ratio:     ratio,
+		maxTokens: float64(maxTokens),
+		tokens:    float64(maxTokens),
+	}
+}
+
+// Deposit records a call.
+func (b *Budget) Deposit() {
+	if b == nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.tokens = math.Min(b.maxTokens, b.tokens+b.ratio)
+}
+
+// Withdraw records a retry, and returns false if the budget is exhausted,
+// in which case the call must not be retried.
+func (b *Budget) Withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.Lock()
+	defer b.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns the number of retries left in the budget.
+func (b *Budget) Tokens() float64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.tokens
+}
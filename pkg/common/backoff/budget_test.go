@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	budget := NewBudget(0.5, 1)
+	assert.True(t, budget.Withdraw())
+	assert.False(t, budget.Withdraw())
+
+	budget.Deposit()
+	assert.False(t, budget.Withdraw())
+	budget.Deposit()
+	assert.True(t, budget.Withdraw())
+
+	// deposits are capped to the max tokens
+	for i := 0; i < 10; i++ {
+		budget.Deposit()
+	}
+	assert.Equal(t, 1.0, budget.Tokens())
+}
+
+func TestNilBudget(t *testing.T) {
+	var budget *Budget
+	budget.Deposit()
+	assert.True(t, budget.Withdraw())
+}
@@ -15,6 +15,8 @@
 package backoff
 
 import (
+	"math"
+	"math/rand"
 	"time"
 )
 
@@ -74,3 +76,43 @@ func (p *retryPolicy) CalculateNextDelay(attempts int) time.Duration {
 	}
 	return p.retryInterval
 }
+
+// NewExponentialRetryPolicy returns a RetryPolicy which doubles the delay
+// between attempts from initialInterval up to maxInterval, for at most
+// maxAttempts attempts. Each delay is reduced by a random fraction of up
+// to jitter (between 0 and 1) of it, so that callers failing at the same
+// time do not retry in lockstep.
+func NewExponentialRetryPolicy(
+	maxAttempts int,
+	initialInterval time.Duration,
+	maxInterval time.Duration,
+	jitter float64) RetryPolicy {
+	return &exponentialRetryPolicy{
+		maxAttempts:     maxAttempts,
+		initialInterval: initialInterval,
+		maxInterval:     maxInterval,
+		jitter:          math.Max(0, math.Min(1, jitter)),
+	}
+}
+
+type exponentialRetryPolicy struct {
+	maxAttempts     int
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	jitter          float64
+}
+
+// CalculateNextDelay returns next delay.
+func (p *exponentialRetryPolicy) CalculateNextDelay(
+	attempts int) time.Duration {
+	if attempts >= p.maxAttempts {
+		return done
+	}
+
+	delay := float64(p.initialInterval) * math.Pow(2, float64(attempts-1))
+	if delay > float64(p.maxInterval) {
+		delay = float64(p.maxInterval)
+	}
+	delay -= delay * p.jitter * rand.Float64()
+	return time.Duration(delay)
+}
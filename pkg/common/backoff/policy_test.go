@@ -48,3 +48,24 @@ func (s *RetryTestSuite) TestRetryMaxAttempts() {
 	}
 	s.Equal(next, done)
 }
+
+func (s *RetryPolicyTestSuite) TestExponentialRetryPolicy() {
+	policy := NewExponentialRetryPolicy(
+		5, 10*time.Millisecond, 50*time.Millisecond, 0)
+	r := NewRetrier(policy)
+	s.Equal(10*time.Millisecond, r.NextBackOff())
+	s.Equal(20*time.Millisecond, r.NextBackOff())
+	s.Equal(40*time.Millisecond, r.NextBackOff())
+	s.Equal(50*time.Millisecond, r.NextBackOff())
+	s.Equal(done, r.NextBackOff())
+}
+
+func (s *RetryPolicyTestSuite) TestExponentialRetryPolicyJitter() {
+	policy := NewExponentialRetryPolicy(
+		100, 10*time.Millisecond, time.Second, 0.5)
+	for i := 0; i < 50; i++ {
+		next := policy.CalculateNextDelay(2)
+		s.True(next > 10*time.Millisecond)
+		s.True(next <= 20*time.Millisecond)
+	}
+}
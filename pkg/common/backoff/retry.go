@@ -15,6 +15,7 @@
 package backoff
 
 import (
+	"context"
 	"time"
 )
 
@@ -24,6 +25,91 @@ type Retryable func() error
 // IsErrorRetryable could be used to exclude certain errors during retry
 type IsErrorRetryable func(error) bool
 
+// RetriableError is implemented by errors which know whether the call which
+// failed with them can be retried. The classification of a RetriableError
+// overrides the IsErrorRetryable of the retry.
+type RetriableError interface {
+	error
+	Retriable() bool
+}
+
+// classifiedError wraps an error with its retry classification.
+type classifiedError struct {
+	error
+	retriable bool
+}
+
+// Retriable returns whether the error can be retried.
+func (e *classifiedError) Retriable() bool {
+	return e.retriable
+}
+
+// NewRetriableError wraps err into an error which is always retried.
+func NewRetriableError(err error) error {
+	return &classifiedError{error: err, retriable: true}
+}
+
+// NewPermanentError wraps err into an error which is never retried.
+func NewPermanentError(err error) error {
+	return &classifiedError{error: err, retriable: false}
+}
+
+// Unwrap returns the error wrapped by NewRetriableError or
+// NewPermanentError, and err itself otherwise.
+func Unwrap(err error) error {
+	if e, ok := err.(*classifiedError); ok {
+		return e.error
+	}
+	return err
+}
+
+// IsRetriable returns whether a call failed with err can be retried. The
+// classification of err is used if it is a RetriableError, otherwise err
+// is retriable if isRetryable is nil or returns true.
+func IsRetriable(err error, isRetryable IsErrorRetryable) bool {
+	if e, ok := err.(RetriableError); ok {
+		return e.Retriable()
+	}
+	return isRetryable == nil || isRetryable(err)
+}
+
+// RetryContext retries the given function like Retry, until the context is
+// done or the budget is exhausted. Every call of RetryContext deposits into
+// the budget, which may be nil. The last error is returned unwrapped from
+// its retry classification.
+func RetryContext(
+	ctx context.Context,
+	f Retryable,
+	p RetryPolicy,
+	isRetryable IsErrorRetryable,
+	budget *Budget) error {
+	budget.Deposit()
+
+	r := NewRetrier(p)
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		if !IsRetriable(err, isRetryable) {
+			return Unwrap(err)
+		}
+		backoff := r.NextBackOff()
+		if backoff == done || !budget.Withdraw() {
+			return Unwrap(err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Unwrap(err)
+		case <-timer.C:
+		}
+	}
+}
+
 // Retry will retry the given function until it succeeded or hit maximum number
 // of retries then return last error.
 func Retry(f Retryable, p RetryPolicy, isRetryable IsErrorRetryable) error {
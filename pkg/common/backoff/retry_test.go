@@ -15,6 +15,7 @@
 package backoff
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -110,3 +111,54 @@ func (s *RetryTestSuite) TestCheckRetry() {
 		}
 	}
 }
+
+func (s *RetryTestSuite) TestRetryContextClassifiedErrors() {
+	i := 0
+	op := func() error {
+		i++
+		if i < 3 {
+			return NewRetriableError(errTest)
+		}
+		return NewPermanentError(errTest)
+	}
+	isRetryable := func(err error) bool {
+		return false
+	}
+	policy := NewRetryPolicy(10, time.Millisecond)
+	err := RetryContext(context.Background(), op, policy, isRetryable, nil)
+	s.Equal(errTest, err)
+	s.Equal(3, i)
+}
+
+func (s *RetryTestSuite) TestRetryContextBudget() {
+	budget := NewBudget(0.5, 2)
+	i := 0
+	op := func() error {
+		i++
+		return errTest
+	}
+	policy := NewRetryPolicy(10, time.Millisecond)
+
+	// the full budget allows two retries
+	s.Equal(errTest, RetryContext(context.Background(), op, policy, nil, budget))
+	s.Equal(3, i)
+
+	// the deposit of a single call does not allow a retry
+	i = 0
+	s.Equal(errTest, RetryContext(context.Background(), op, policy, nil, budget))
+	s.Equal(1, i)
+	s.Equal(0.5, budget.Tokens())
+}
+
+func (s *RetryTestSuite) TestRetryContextCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	i := 0
+	op := func() error {
+		i++
+		cancel()
+		return errTest
+	}
+	policy := NewRetryPolicy(10, time.Minute)
+	s.Equal(errTest, RetryContext(ctx, op, policy, nil, nil))
+	s.Equal(1, i)
+}
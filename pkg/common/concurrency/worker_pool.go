@@ -0,0 +1,261 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
+)
+
+// DefaultMaxWorkers is the max number of tasks run at the same time by a
+// WorkerPool whose options do not set it.
+const DefaultMaxWorkers = 16
+
+// Task is a unit of work run by a WorkerPool. It should return early once
+// its context is canceled.
+type Task func(ctx context.Context) error
+
+// PanicError is the error of a task which panicked.
+type PanicError struct {
+	// Value is the value the task panicked with.
+	Value interface{}
+	// Stack is the stack trace of the goroutine of the task.
+	Stack string
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// PoolMetrics are the metrics of the worker pools of a component, which
+// are usually created once and shared by its worker pools.
+type PoolMetrics struct {
+	// Submitted counts the tasks submitted to the pools.
+	Submitted tally.Counter
+	// Succeeded counts the tasks which returned no error.
+	Succeeded tally.Counter
+	// Failed counts the tasks which returned an error or panicked.
+	Failed tally.Counter
+	// Panicked counts the tasks which panicked.
+	Panicked tally.Counter
+	// Canceled counts the tasks which were not run because the context
+	// of their pool was canceled.
+	Canceled tally.Counter
+	// Latency is the duration of the tasks.
+	Latency tally.Timer
+}
+
+// NewPoolMetrics returns the worker pool metrics of the given scope.
+func NewPoolMetrics(scope tally.Scope) *PoolMetrics {
+	return &PoolMetrics{
+		Submitted: scope.Counter("tasks_submitted"),
+		Succeeded: scope.Counter("tasks_succeeded"),
+		Failed:    scope.Counter("tasks_failed"),
+		Panicked:  scope.Counter("tasks_panicked"),
+		Canceled:  scope.Counter("tasks_canceled"),
+		Latency:   scope.Timer("task_latency"),
+	}
+}
+
+// WorkerPoolOptions are the options of a WorkerPool.
+type WorkerPoolOptions struct {
+	// MaxWorkers is the max number of tasks run at the same time,
+	// DefaultMaxWorkers if zero.
+	MaxWorkers int
+	// StopOnError cancels the context of the pool once a task fails, so
+	// that the remaining tasks are not run.
+	StopOnError bool
+}
+
+// WorkerPool runs a batch of tasks with bounded parallelism, and collects
+// their errors. A task which panics is recovered, and fails with a
+// PanicError. Tasks are submitted with Submit, then Wait waits for them to
+// complete; a WorkerPool is not reused after Wait.
+type WorkerPool struct {
+	sync.Mutex
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	options WorkerPoolOptions
+	metrics *PoolMetrics
+
+	// semaphore bounding the number of tasks running
+	workers chan struct{}
+	// tasks running
+	running sync.WaitGroup
+
+	// first error of the tasks or of the context
+	firstErr error
+	// errors of all the tasks, and of the context if it was canceled
+	errs error
+	// whether the error of the context was recorded
+	canceled bool
+}
+
+// NewWorkerPool returns a new WorkerPool running its tasks with a context
+// derived from ctx. The metrics are not reported if nil.
+func NewWorkerPool(
+	ctx context.Context,
+	options WorkerPoolOptions,
+	metrics *PoolMetrics,
+) *WorkerPool {
+	if options.MaxWorkers <= 0 {
+		options.MaxWorkers = DefaultMaxWorkers
+	}
+	if metrics == nil {
+		metrics = NewPoolMetrics(tally.NoopScope)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &WorkerPool{
+		ctx:     ctx,
+		cancel:  cancel,
+		options: options,
+		metrics: metrics,
+		workers: make(chan struct{}, options.MaxWorkers),
+	}
+}
+
+// Submit runs the task once a worker is free, and blocks while all the
+// workers are busy. It returns the error of the context of the pool, and
+// does not run the task, if the context is canceled before a worker is
+// free.
+func (p *WorkerPool) Submit(task Task) error {
+	p.metrics.Submitted.Inc(1)
+
+	acquired := false
+	select {
+	case p.workers <- struct{}{}:
+		acquired = true
+	case <-p.ctx.Done():
+	}
+	if err := p.ctx.Err(); err != nil {
+		if acquired {
+			<-p.workers
+		}
+		p.metrics.Canceled.Inc(1)
+		p.recordCanceled(err)
+		return err
+	}
+
+	p.running.Add(1)
+	go p.run(task)
+	return nil
+}
+
+// Wait waits for the submitted tasks to complete. It returns the first
+// error of the tasks if the pool stops on error, or the errors of all the
+// tasks combined otherwise, along with the error of the context if tasks
+// were not run because it was canceled.
+func (p *WorkerPool) Wait() error {
+	p.running.Wait()
+	p.cancel()
+
+	p.Lock()
+	defer p.Unlock()
+	if p.options.StopOnError {
+		return p.firstErr
+	}
+	return p.errs
+}
+
+// run runs a task and records its outcome.
+func (p *WorkerPool) run(task Task) {
+	defer p.running.Done()
+	defer func() { <-p.workers }()
+
+	start := time.Now()
+	err := p.call(task)
+	p.metrics.Latency.Record(time.Since(start))
+	if err == nil {
+		p.metrics.Succeeded.Inc(1)
+		return
+	}
+
+	p.metrics.Failed.Inc(1)
+	p.recordError(err)
+	if p.options.StopOnError {
+		p.cancel()
+	}
+}
+
+// call calls the task, turning a panic into a PanicError.
+func (p *WorkerPool) call(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.metrics.Panicked.Inc(1)
+			panicErr := &PanicError{Value: r, Stack: string(debug.Stack())}
+			log.WithField("panic", r).
+				WithField("stack", panicErr.Stack).
+				Error("Worker pool task panicked")
+			err = panicErr
+		}
+	}()
+	return task(p.ctx)
+}
+
+// recordError records the error of a task.
+func (p *WorkerPool) recordError(err error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.firstErr == nil {
+		p.firstErr = err
+	}
+	p.errs = multierr.Append(p.errs, err)
+}
+
+// recordCanceled records the error of the context of the pool, once.
+func (p *WorkerPool) recordCanceled(err error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.canceled {
+		return
+	}
+	p.canceled = true
+	if p.firstErr == nil {
+		p.firstErr = err
+	}
+	p.errs = multierr.Append(p.errs, err)
+}
+
+// ForEach calls fn with every index from 0 to n-1 in a worker pool, and
+// returns the errors of the pool. It is meant for fan-out operations
+// which write the result of index i at index i of a slice allocated by the
+// caller.
+func ForEach(
+	ctx context.Context,
+	n int,
+	options WorkerPoolOptions,
+	metrics *PoolMetrics,
+	fn func(ctx context.Context, i int) error,
+) error {
+	pool := NewWorkerPool(ctx, options, metrics)
+	for i := 0; i < n; i++ {
+		i := i
+		if err := pool.Submit(func(ctx context.Context) error {
+			return fn(ctx, i)
+		}); err != nil {
+			break
+		}
+	}
+	return pool.Wait()
+}
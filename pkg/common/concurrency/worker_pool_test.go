@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
+)
+
+func TestWorkerPool_BoundedParallelism(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	pool := NewWorkerPool(
+		context.Background(),
+		WorkerPoolOptions{MaxWorkers: 3},
+		NewPoolMetrics(scope))
+
+	var running, maxRunning int32
+	for i := 0; i < 20; i++ {
+		require.NoError(t, pool.Submit(func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		}))
+	}
+	require.NoError(t, pool.Wait())
+	require.True(t, maxRunning <= 3)
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(20), counters["tasks_submitted+"].Value())
+	require.Equal(t, int64(20), counters["tasks_succeeded+"].Value())
+}
+
+func TestWorkerPool_CollectsErrors(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), WorkerPoolOptions{}, nil)
+
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	for _, err := range []error{err1, nil, err2} {
+		err := err
+		require.NoError(t, pool.Submit(func(ctx context.Context) error {
+			return err
+		}))
+	}
+	require.ElementsMatch(t, []error{err1, err2}, multierr.Errors(pool.Wait()))
+}
+
+func TestWorkerPool_StopOnError(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	pool := NewWorkerPool(
+		context.Background(),
+		WorkerPoolOptions{MaxWorkers: 1, StopOnError: true},
+		NewPoolMetrics(scope))
+
+	expectedErr := errors.New("some error")
+	require.NoError(t, pool.Submit(func(ctx context.Context) error {
+		return expectedErr
+	}))
+
+	// the next tasks are not run once the first one failed
+	var run int32
+	var submitErr error
+	for i := 0; i < 10 && submitErr == nil; i++ {
+		submitErr = pool.Submit(func(ctx context.Context) error {
+			if ctx.Err() == nil {
+				atomic.AddInt32(&run, 1)
+			}
+			return ctx.Err()
+		})
+	}
+	require.Equal(t, context.Canceled, submitErr)
+	require.Equal(t, expectedErr, pool.Wait())
+	require.Zero(t, atomic.LoadInt32(&run))
+	require.Equal(t, int64(1),
+		scope.Snapshot().Counters()["tasks_canceled+"].Value())
+}
+
+func TestWorkerPool_RecoversPanics(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	pool := NewWorkerPool(
+		context.Background(), WorkerPoolOptions{}, NewPoolMetrics(scope))
+
+	require.NoError(t, pool.Submit(func(ctx context.Context) error {
+		panic("boom")
+	}))
+	err := pool.Wait()
+
+	panicErr, ok := err.(*PanicError)
+	require.True(t, ok)
+	require.Equal(t, "boom", panicErr.Value)
+	require.NotEmpty(t, panicErr.Stack)
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(1), counters["tasks_panicked+"].Value())
+	require.Equal(t, int64(1), counters["tasks_failed+"].Value())
+}
+
+func TestWorkerPool_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pool := NewWorkerPool(ctx, WorkerPoolOptions{}, nil)
+	require.Equal(t, context.Canceled, pool.Submit(func(ctx context.Context) error {
+		require.Fail(t, "task of a canceled pool run")
+		return nil
+	}))
+	require.Equal(t, context.Canceled, pool.Wait())
+}
+
+func TestForEach(t *testing.T) {
+	results := make([]int, 100)
+	err := ForEach(
+		context.Background(),
+		len(results),
+		WorkerPoolOptions{MaxWorkers: 8},
+		nil,
+		func(ctx context.Context, i int) error {
+			results[i] = i * i
+			return nil
+		})
+	require.NoError(t, err)
+	for i, result := range results {
+		require.Equal(t, i*i, result)
+	}
+
+	expectedErr := errors.New("some error")
+	err = ForEach(
+		context.Background(),
+		100,
+		WorkerPoolOptions{MaxWorkers: 1, StopOnError: true},
+		nil,
+		func(ctx context.Context, i int) error {
+			return expectedErr
+		})
+	require.Equal(t, expectedErr, err)
+}
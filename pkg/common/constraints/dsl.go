@@ -0,0 +1,344 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	_conditionPrefix = "CONDITION_"
+	_timeOfDayLayout = "15:04:05"
+)
+
+// ConstraintSpec is a compact human readable representation of a
+// task.Constraint tree. Exactly one of the fields must be set, and an
+// and/or constraint without children is an empty list. For example:
+//
+//	and:
+//	- label: {kind: host, key: rack, value: r1, condition: greater_than}
+//	- time_window:
+//	    windows: [{start: "00:00:00", end: "04:00:00"}]
+//	    constraint:
+//	      label: {kind: host, key: backup, value: nightly,
+//	              condition: less_than, requirement: 1}
+type ConstraintSpec struct {
+	And        []*ConstraintSpec         `yaml:"and,omitempty" json:"and,omitempty"`
+	Or         []*ConstraintSpec         `yaml:"or,omitempty" json:"or,omitempty"`
+	Label      *LabelConstraintSpec      `yaml:"label,omitempty" json:"label,omitempty"`
+	TimeWindow *TimeWindowConstraintSpec `yaml:"time_window,omitempty" json:"time_window,omitempty"`
+}
+
+// constraintSpecFields are the fields of a ConstraintSpec as marshaled. The
+// and/or lists are only omitted if nil, so that and/or constraints without
+// children are kept.
+type constraintSpecFields struct {
+	And        *[]*ConstraintSpec        `yaml:"and,omitempty" json:"and,omitempty"`
+	Or         *[]*ConstraintSpec        `yaml:"or,omitempty" json:"or,omitempty"`
+	Label      *LabelConstraintSpec      `yaml:"label,omitempty" json:"label,omitempty"`
+	TimeWindow *TimeWindowConstraintSpec `yaml:"time_window,omitempty" json:"time_window,omitempty"`
+}
+
+func (s ConstraintSpec) fields() *constraintSpecFields {
+	fields := &constraintSpecFields{
+		Label:      s.Label,
+		TimeWindow: s.TimeWindow,
+	}
+	if s.And != nil {
+		fields.And = &s.And
+	}
+	if s.Or != nil {
+		fields.Or = &s.Or
+	}
+	return fields
+}
+
+// MarshalYAML marshals the constraint, keeping empty and/or lists.
+func (s ConstraintSpec) MarshalYAML() (interface{}, error) {
+	return s.fields(), nil
+}
+
+// MarshalJSON marshals the constraint, keeping empty and/or lists.
+func (s ConstraintSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.fields())
+}
+
+// LabelConstraintSpec is the representation of a task.LabelConstraint.
+// Kind is one of `host` or `task`, and condition is one of `less_than`,
+// `equal` or `greater_than`. The requirement is either a number of
+// occurrences, or a percentage of the denominator supplied at evaluation
+// time (see WithDenominator).
+type LabelConstraintSpec struct {
+	Kind               string  `yaml:"kind" json:"kind"`
+	Key                string  `yaml:"key" json:"key"`
+	Value              string  `yaml:"value" json:"value"`
+	Condition          string  `yaml:"condition" json:"condition"`
+	Requirement        uint32  `yaml:"requirement,omitempty" json:"requirement,omitempty"`
+	RequirementPercent float64 `yaml:"requirement_percent,omitempty" json:"requirement_percent,omitempty"`
+}
+
+// TimeWindowConstraintSpec is the representation of a
+// task.TimeWindowConstraint.
+type TimeWindowConstraintSpec struct {
+	Windows    []*TimeWindowSpec `yaml:"windows" json:"windows"`
+	Constraint *ConstraintSpec   `yaml:"constraint" json:"constraint"`
+}
+
+// TimeWindowSpec is the representation of a task.TimeWindow, with start
+// and end given as HH:MM:SS in UTC.
+type TimeWindowSpec struct {
+	Start string `yaml:"start" json:"start"`
+	End   string `yaml:"end" json:"end"`
+}
+
+// MarshalYAML converts the constraint into its YAML representation.
+func MarshalYAML(constraint *task.Constraint) ([]byte, error) {
+	spec, err := ToSpec(constraint)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(spec)
+}
+
+// MarshalJSON converts the constraint into its JSON representation.
+func MarshalJSON(constraint *task.Constraint) ([]byte, error) {
+	spec, err := ToSpec(constraint)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(spec)
+}
+
+// Unmarshal parses a constraint from its YAML or JSON representation.
+func Unmarshal(data []byte) (*task.Constraint, error) {
+	var spec ConstraintSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return FromSpec(&spec)
+}
+
+// ToSpec converts the constraint into a ConstraintSpec.
+func ToSpec(constraint *task.Constraint) (*ConstraintSpec, error) {
+	switch constraint.GetType() {
+	case task.Constraint_AND_CONSTRAINT:
+		specs, err := toSpecs(constraint.GetAndConstraint().GetConstraints())
+		if err != nil {
+			return nil, err
+		}
+		return &ConstraintSpec{And: specs}, nil
+	case task.Constraint_OR_CONSTRAINT:
+		specs, err := toSpecs(constraint.GetOrConstraint().GetConstraints())
+		if err != nil {
+			return nil, err
+		}
+		return &ConstraintSpec{Or: specs}, nil
+	case task.Constraint_LABEL_CONSTRAINT:
+		lc := constraint.GetLabelConstraint()
+		return &ConstraintSpec{
+			Label: &LabelConstraintSpec{
+				Kind:  strings.ToLower(lc.GetKind().String()),
+				Key:   lc.GetLabel().GetKey(),
+				Value: lc.GetLabel().GetValue(),
+				Condition: strings.ToLower(strings.TrimPrefix(
+					lc.GetCondition().String(), _conditionPrefix)),
+				Requirement:        lc.GetRequirement(),
+				RequirementPercent: lc.GetRequirementPercent(),
+			},
+		}, nil
+	case task.Constraint_TIME_WINDOW_CONSTRAINT:
+		twc := constraint.GetTimeWindowConstraint()
+		inner, err := ToSpec(twc.GetConstraint())
+		if err != nil {
+			return nil, err
+		}
+		spec := &TimeWindowConstraintSpec{Constraint: inner}
+		for _, w := range twc.GetWindows() {
+			spec.Windows = append(spec.Windows, &TimeWindowSpec{
+				Start: formatSecondOfDay(w.GetStartSecond()),
+				End:   formatSecondOfDay(w.GetEndSecond()),
+			})
+		}
+		return &ConstraintSpec{TimeWindow: spec}, nil
+	}
+	return nil, ErrUnknownConstraintType
+}
+
+// toSpecs converts the children of an and/or constraint. The specs are not
+// nil even without children, so that the and/or constraint is kept.
+func toSpecs(constraints []*task.Constraint) ([]*ConstraintSpec, error) {
+	specs := make([]*ConstraintSpec, 0, len(constraints))
+	for _, c := range constraints {
+		spec, err := ToSpec(c)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// FromSpec converts the ConstraintSpec into a task.Constraint.
+func FromSpec(spec *ConstraintSpec) (*task.Constraint, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("empty constraint")
+	}
+
+	set := 0
+	for _, ok := range []bool{
+		spec.And != nil,
+		spec.Or != nil,
+		spec.Label != nil,
+		spec.TimeWindow != nil,
+	} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf(
+			"constraint must have exactly one of and, or, label, time_window")
+	}
+
+	switch {
+	case spec.And != nil:
+		constraints, err := fromSpecs(spec.And)
+		if err != nil {
+			return nil, err
+		}
+		return &task.Constraint{
+			Type:          task.Constraint_AND_CONSTRAINT,
+			AndConstraint: &task.AndConstraint{Constraints: constraints},
+		}, nil
+	case spec.Or != nil:
+		constraints, err := fromSpecs(spec.Or)
+		if err != nil {
+			return nil, err
+		}
+		return &task.Constraint{
+			Type:         task.Constraint_OR_CONSTRAINT,
+			OrConstraint: &task.OrConstraint{Constraints: constraints},
+		}, nil
+	case spec.Label != nil:
+		lc, err := fromLabelSpec(spec.Label)
+		if err != nil {
+			return nil, err
+		}
+		return &task.Constraint{
+			Type:            task.Constraint_LABEL_CONSTRAINT,
+			LabelConstraint: lc,
+		}, nil
+	default:
+		twc, err := fromTimeWindowSpec(spec.TimeWindow)
+		if err != nil {
+			return nil, err
+		}
+		return &task.Constraint{
+			Type:                 task.Constraint_TIME_WINDOW_CONSTRAINT,
+			TimeWindowConstraint: twc,
+		}, nil
+	}
+}
+
+// fromSpecs converts the children of an and/or constraint, which are nil
+// if there are none.
+func fromSpecs(specs []*ConstraintSpec) ([]*task.Constraint, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	constraints := make([]*task.Constraint, 0, len(specs))
+	for _, s := range specs {
+		c, err := FromSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+func fromLabelSpec(spec *LabelConstraintSpec) (*task.LabelConstraint, error) {
+	kind, ok := task.LabelConstraint_Kind_value[strings.ToUpper(spec.Kind)]
+	if !ok {
+		return nil, fmt.Errorf("unknown label constraint kind %q", spec.Kind)
+	}
+	condition, ok := task.LabelConstraint_Condition_value[_conditionPrefix+
+		strings.ToUpper(spec.Condition)]
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown label constraint condition %q", spec.Condition)
+	}
+	if spec.RequirementPercent < 0 {
+		return nil, fmt.Errorf(
+			"negative label constraint requirement percent %v",
+			spec.RequirementPercent)
+	}
+	return &task.LabelConstraint{
+		Kind:      task.LabelConstraint_Kind(kind),
+		Condition: task.LabelConstraint_Condition(condition),
+		Label: &peloton.Label{
+			Key:   spec.Key,
+			Value: spec.Value,
+		},
+		Requirement:        spec.Requirement,
+		RequirementPercent: spec.RequirementPercent,
+	}, nil
+}
+
+func fromTimeWindowSpec(
+	spec *TimeWindowConstraintSpec) (*task.TimeWindowConstraint, error) {
+	constraint, err := FromSpec(spec.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	twc := &task.TimeWindowConstraint{Constraint: constraint}
+	for _, w := range spec.Windows {
+		start, err := parseSecondOfDay(w.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseSecondOfDay(w.End)
+		if err != nil {
+			return nil, err
+		}
+		twc.Windows = append(twc.Windows, &task.TimeWindow{
+			StartSecond: start,
+			EndSecond:   end,
+		})
+	}
+	return twc, nil
+}
+
+func formatSecondOfDay(second uint32) string {
+	return time.Unix(int64(second%_secondsPerDay), 0).
+		UTC().
+		Format(_timeOfDayLayout)
+}
+
+func parseSecondOfDay(s string) (uint32, error) {
+	t, err := time.Parse(_timeOfDayLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %v", s, err)
+	}
+	return secondOfDay(t), nil
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DSLTestSuite struct {
+	suite.Suite
+
+	constraint *task.Constraint
+}
+
+func (suite *DSLTestSuite) SetupTest() {
+	suite.constraint = &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{
+				{
+					Type: task.Constraint_LABEL_CONSTRAINT,
+					LabelConstraint: &task.LabelConstraint{
+						Kind:      task.LabelConstraint_HOST,
+						Label:     &peloton.Label{Key: "rack", Value: "r1"},
+						Condition: task.LabelConstraint_CONDITION_GREATER_THAN,
+					},
+				},
+				{
+					Type: task.Constraint_OR_CONSTRAINT,
+					OrConstraint: &task.OrConstraint{
+						Constraints: []*task.Constraint{
+							{
+								Type: task.Constraint_LABEL_CONSTRAINT,
+								LabelConstraint: &task.LabelConstraint{
+									Kind: task.LabelConstraint_TASK,
+									Label: &peloton.Label{
+										Key:   "job",
+										Value: "cache",
+									},
+									Condition:   task.LabelConstraint_CONDITION_EQUAL,
+									Requirement: 2,
+								},
+							},
+						},
+					},
+				},
+				{
+					Type: task.Constraint_TIME_WINDOW_CONSTRAINT,
+					TimeWindowConstraint: &task.TimeWindowConstraint{
+						Windows: []*task.TimeWindow{
+							{StartSecond: 0, EndSecond: 4 * 3600},
+						},
+						Constraint: &task.Constraint{
+							Type: task.Constraint_LABEL_CONSTRAINT,
+							LabelConstraint: &task.LabelConstraint{
+								Kind: task.LabelConstraint_HOST,
+								Label: &peloton.Label{
+									Key:   "backup",
+									Value: "nightly",
+								},
+								Condition:   task.LabelConstraint_CONDITION_LESS_THAN,
+								Requirement: 1,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// emptyConstraints returns and/or constraints without children, alone
+// and nested into other constraints.
+func emptyConstraints() []*task.Constraint {
+	emptyAnd := &task.Constraint{
+		Type:          task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{},
+	}
+	emptyOr := &task.Constraint{
+		Type:         task.Constraint_OR_CONSTRAINT,
+		OrConstraint: &task.OrConstraint{},
+	}
+	return []*task.Constraint{
+		emptyAnd,
+		emptyOr,
+		{
+			Type: task.Constraint_OR_CONSTRAINT,
+			OrConstraint: &task.OrConstraint{
+				Constraints: []*task.Constraint{emptyAnd, emptyOr},
+			},
+		},
+		{
+			Type: task.Constraint_TIME_WINDOW_CONSTRAINT,
+			TimeWindowConstraint: &task.TimeWindowConstraint{
+				Windows: []*task.TimeWindow{
+					{StartSecond: 0, EndSecond: 3600},
+				},
+				Constraint: emptyAnd,
+			},
+		},
+	}
+}
+
+func (suite *DSLTestSuite) TestYAMLRoundTrip() {
+	constraints := append(
+		[]*task.Constraint{suite.constraint}, emptyConstraints()...)
+	for _, constraint := range constraints {
+		data, err := MarshalYAML(constraint)
+		suite.NoError(err)
+
+		c, err := Unmarshal(data)
+		suite.NoError(err, string(data))
+		suite.Equal(constraint, c)
+	}
+}
+
+func (suite *DSLTestSuite) TestJSONRoundTrip() {
+	constraints := append(
+		[]*task.Constraint{suite.constraint}, emptyConstraints()...)
+	for _, constraint := range constraints {
+		data, err := MarshalJSON(constraint)
+		suite.NoError(err)
+
+		c, err := Unmarshal(data)
+		suite.NoError(err, string(data))
+		suite.Equal(constraint, c)
+	}
+}
+
+func (suite *DSLTestSuite) TestMarshalEmptyConstraint() {
+	data, err := MarshalJSON(emptyConstraints()[2])
+	suite.NoError(err)
+	suite.Equal(`{"or":[{"and":[]},{"or":[]}]}`, string(data))
+
+	data, err = MarshalYAML(emptyConstraints()[0])
+	suite.NoError(err)
+	suite.Equal("and: []\n", string(data))
+}
+
+func (suite *DSLTestSuite) TestUnmarshalHandWritten() {
+	c, err := Unmarshal([]byte(`
+label:
+  kind: host
+  key: hostname
+  value: host1
+  condition: equal
+  requirement: 1
+`))
+	suite.NoError(err)
+	suite.Equal(task.Constraint_LABEL_CONSTRAINT, c.GetType())
+	suite.Equal(
+		task.LabelConstraint_CONDITION_EQUAL,
+		c.GetLabelConstraint().GetCondition())
+	suite.Equal("host1", c.GetLabelConstraint().GetLabel().GetValue())
+}
+
+func (suite *DSLTestSuite) TestRequirementPercentRoundTrip() {
+	c, err := Unmarshal([]byte(`
+label:
+  kind: host
+  key: rack
+  value: r1
+  condition: less_than
+  requirement_percent: 2.5
+`))
+	suite.NoError(err)
+	suite.Equal(2.5, c.GetLabelConstraint().GetRequirementPercent())
+
+	data, err := MarshalJSON(c)
+	suite.NoError(err)
+	suite.Contains(string(data), `"requirement_percent":2.5`)
+	parsed, err := Unmarshal(data)
+	suite.NoError(err)
+	suite.Equal(c, parsed)
+}
+
+func (suite *DSLTestSuite) TestUnmarshalErrors() {
+	for _, data := range []string{
+		`{}`,
+		`{label: {kind: rack, key: a, value: b, condition: equal}}`,
+		`{label: {kind: host, key: a, value: b, condition: bogus}}`,
+		`{label: {kind: host, key: a, value: b, condition: equal,
+		  requirement_percent: -1}}`,
+		`{and: [], or: []}`,
+		`{time_window: {windows: [{start: "25:00:00", end: "01:00:00"}],
+		  constraint: {label: {kind: host, key: a, value: b, condition: equal}}}}`,
+		`not: [valid`,
+	} {
+		_, err := Unmarshal([]byte(data))
+		suite.Error(err, data)
+	}
+}
+
+func (suite *DSLTestSuite) TestToSpecUnknownType() {
+	_, err := ToSpec(&task.Constraint{Type: task.Constraint_Type(-1)})
+	suite.Equal(ErrUnknownConstraintType, err)
+}
+
+func TestDSLTestSuite(t *testing.T) {
+	suite.Run(t, new(DSLTestSuite))
+}
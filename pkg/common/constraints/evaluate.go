@@ -16,12 +16,13 @@ package constraints
 
 import (
 	"errors"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 
-	"github.com/uber/peloton/pkg/common"
-
 	log "github.com/sirupsen/logrus"
 )
 
@@ -34,6 +35,14 @@ type Evaluator interface {
 		constraint *task.Constraint,
 		labelValues LabelValues,
 	) (EvaluateResult, error)
+
+	// EvaluateAt is the same as Evaluate, but evaluates time windowed
+	// constraints against the given timestamp instead of current time.
+	EvaluateAt(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error)
 }
 
 // EvaluateResult is an enum indicating various possible result.
@@ -60,34 +69,92 @@ var (
 	// LabelConstraint.Condition enum is processed.
 	ErrUnknownLabelCondition = errors.New(
 		"unknown enum value for LabelConstraint.Condition")
+	// ErrEvaluatorConflict is the error when an evaluator is registered
+	// for a Constraint.Type which already has one.
+	ErrEvaluatorConflict = errors.New(
+		"constraint type already has an evaluator")
+	// ErrNoDenominator is the error when a label constraint whose
+	// requirement is a percentage is evaluated without a denominator.
+	ErrNoDenominator = errors.New(
+		"no denominator to evaluate a percentage requirement against")
 )
 
 // evaluator implements Evaluator by filtering out any constraint which has a
-// different kind.
-type evaluator task.LabelConstraint_Kind
+// different kind, and dispatching the constraints to the evaluators of their
+// type.
+type evaluator struct {
+	kind task.LabelConstraint_Kind
+	// factories of the evaluators of the constraints by type
+	factories map[task.Constraint_Type]EvaluatorFactory
+	// evaluators of the constraints by type
+	types map[task.Constraint_Type]Evaluator
+	// denominator of the percentage requirements, if hasDenominator
+	denominator    uint32
+	hasDenominator bool
+}
 
 // NewEvaluator return a new instance of evaluator which filters out constraints
-// of different kind.
+// of different kind. Constraints are evaluated by the evaluators of their type
+// registered in DefaultRegistry.
 func NewEvaluator(kind task.LabelConstraint_Kind) Evaluator {
-	return evaluator(kind)
+	return DefaultRegistry.NewEvaluator(kind)
+}
+
+// WithDenominator returns an evaluator which is the same as e, but
+// evaluates the label constraints whose requirement is a percentage
+// against the given denominator, e.g. the instance count of the job whose
+// tasks are being placed. The denominator is forwarded through the
+// decorators of this package, like WithMetrics, to the evaluator they
+// decorate. The other evaluators, which are not returned by NewEvaluator,
+// a Registry or an Optimizer, are returned as is.
+func WithDenominator(e Evaluator, denominator uint32) Evaluator {
+	if d, ok := e.(denominatorEvaluator); ok {
+		return d.withDenominator(denominator)
+	}
+	return e
+}
+
+// denominatorEvaluator is implemented by the evaluators which can return a
+// copy of themselves evaluating the percentage requirements against a
+// denominator.
+type denominatorEvaluator interface {
+	withDenominator(denominator uint32) Evaluator
+}
+
+// withDenominator implements denominatorEvaluator by rebuilding the
+// evaluators of the constraint types with the new evaluator as parent.
+func (e *evaluator) withDenominator(denominator uint32) Evaluator {
+	d := &evaluator{
+		kind:      e.kind,
+		factories: e.factories,
+		types: make(
+			map[task.Constraint_Type]Evaluator, len(e.factories)),
+		denominator:    denominator,
+		hasDenominator: true,
+	}
+	for t, factory := range e.factories {
+		d.types[t] = factory(d.kind, d)
+	}
+	return d
 }
 
 // Evaluate takes given constraints and labels, and evaluate whether all parts
 // in the given kind matches the input.
-func (e evaluator) Evaluate(
+func (e *evaluator) Evaluate(
 	constraint *task.Constraint,
 	labelValues LabelValues) (EvaluateResult, error) {
+	return e.EvaluateAt(constraint, labelValues, time.Now())
+}
 
-	switch constraint.GetType() {
-	case task.Constraint_AND_CONSTRAINT:
-		return e.evaluateAndConstraint(
-			constraint.GetAndConstraint(), labelValues)
-	case task.Constraint_OR_CONSTRAINT:
-		return e.evaluateOrConstraint(
-			constraint.GetOrConstraint(), labelValues)
-	case task.Constraint_LABEL_CONSTRAINT:
-		return e.evaluateLabelConstraint(
-			constraint.GetLabelConstraint(), labelValues)
+// EvaluateAt takes given constraints and labels, and evaluate whether all
+// parts in the given kind matches the input at the given time.
+func (e *evaluator) EvaluateAt(
+	constraint *task.Constraint,
+	labelValues LabelValues,
+	at time.Time) (EvaluateResult, error) {
+
+	if typeEvaluator, ok := e.types[constraint.GetType()]; ok {
+		return typeEvaluator.EvaluateAt(constraint, labelValues, at)
 	}
 
 	log.WithField("type", constraint.GetType()).
@@ -95,14 +162,82 @@ func (e evaluator) Evaluate(
 	return EvaluateResultNotApplicable, ErrUnknownConstraintType
 }
 
-func (e evaluator) evaluateAndConstraint(
+// newAndEvaluator returns the evaluator of the AND constraints.
+func newAndEvaluator(
+	_ task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error) {
+		return evaluateAndConstraint(
+			parent, constraint.GetAndConstraint(), labelValues, at)
+	})
+}
+
+// newOrEvaluator returns the evaluator of the OR constraints.
+func newOrEvaluator(
+	_ task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error) {
+		return evaluateOrConstraint(
+			parent, constraint.GetOrConstraint(), labelValues, at)
+	})
+}
+
+// newLabelEvaluator returns the evaluator of the label constraints, whose
+// percentage requirements are evaluated against the denominator of parent.
+func newLabelEvaluator(
+	kind task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	var denominator *uint32
+	if e, ok := parent.(*evaluator); ok && e.hasDenominator {
+		denominator = &e.denominator
+	}
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		_ time.Time,
+	) (EvaluateResult, error) {
+		return evaluateLabelConstraint(
+			kind, constraint.GetLabelConstraint(), labelValues, denominator)
+	})
+}
+
+// newTimeWindowEvaluator returns the evaluator of the time window
+// constraints.
+func newTimeWindowEvaluator(
+	_ task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error) {
+		return evaluateTimeWindowConstraint(
+			parent, constraint.GetTimeWindowConstraint(), labelValues, at)
+	})
+}
+
+func evaluateAndConstraint(
+	e Evaluator,
 	andConstraint *task.AndConstraint,
 	labelValues LabelValues,
+	at time.Time,
 ) (EvaluateResult, error) {
 
 	result := EvaluateResultNotApplicable
 	for _, c := range andConstraint.GetConstraints() {
-		subResult, err := e.Evaluate(c, labelValues)
+		subResult, err := e.EvaluateAt(c, labelValues, at)
 		if err != nil {
 			return EvaluateResultNotApplicable, err
 		}
@@ -117,14 +252,16 @@ func (e evaluator) evaluateAndConstraint(
 	return result, nil
 }
 
-func (e evaluator) evaluateOrConstraint(
+func evaluateOrConstraint(
+	e Evaluator,
 	orConstraint *task.OrConstraint,
 	labelValues LabelValues,
+	at time.Time,
 ) (EvaluateResult, error) {
 
 	result := EvaluateResultNotApplicable
 	for _, c := range orConstraint.GetConstraints() {
-		subResult, err := e.Evaluate(c, labelValues)
+		subResult, err := e.EvaluateAt(c, labelValues, at)
 		if err != nil {
 			return EvaluateResultNotApplicable, err
 		}
@@ -139,19 +276,29 @@ func (e evaluator) evaluateOrConstraint(
 	return result, nil
 }
 
-func (e evaluator) evaluateLabelConstraint(
+func evaluateLabelConstraint(
+	kind task.LabelConstraint_Kind,
 	labelConstraint *task.LabelConstraint,
 	labelValues LabelValues,
+	denominator *uint32,
 ) (EvaluateResult, error) {
 
 	// If kind of LabelConstraint does not match, returns not applicable
 	// which will not short-circuit any And/Or constraint evaluation.
-	if labelConstraint.GetKind() != task.LabelConstraint_Kind(e) {
+	if labelConstraint.GetKind() != kind {
 		return EvaluateResultNotApplicable, nil
 	}
 
-	count := valueCount(labelConstraint.GetLabel(), labelValues)
-	requirement := labelConstraint.GetRequirement()
+	count := float64(valueCount(labelConstraint.GetLabel(), labelValues))
+	requirement := float64(labelConstraint.GetRequirement())
+	if percent := labelConstraint.GetRequirementPercent(); percent > 0 {
+		if denominator == nil {
+			log.WithField("label", labelConstraint.GetLabel()).
+				Error(ErrNoDenominator.Error())
+			return EvaluateResultNotApplicable, ErrNoDenominator
+		}
+		requirement = percent * float64(*denominator) / 100
+	}
 
 	match := false
 
@@ -174,35 +321,52 @@ func (e evaluator) evaluateLabelConstraint(
 	return EvaluateResultMismatch, nil
 }
 
-func valueCount(label *peloton.Label, labelValues LabelValues) uint32 {
-	return labelValues[label.GetKey()][label.GetValue()]
+func evaluateTimeWindowConstraint(
+	e Evaluator,
+	timeWindowConstraint *task.TimeWindowConstraint,
+	labelValues LabelValues,
+	at time.Time,
+) (EvaluateResult, error) {
+
+	// Outside of the time windows the constraint does not apply, which
+	// will not short-circuit any And/Or constraint evaluation.
+	if !InTimeWindows(timeWindowConstraint.GetWindows(), at) {
+		return EvaluateResultNotApplicable, nil
+	}
+	return e.EvaluateAt(
+		timeWindowConstraint.GetConstraint(), labelValues, at)
 }
 
-// IsNonExclusiveConstraint returns true if all components of the constraint
-// specification do not use a host label constraint for exclusive attribute.
-func IsNonExclusiveConstraint(constraint *task.Constraint) bool {
-	if constraint == nil {
-		return true
+// valueCount returns the number of occurrences of the label value. If the
+// label key is a glob pattern (e.g. `gpu/*`), counts are aggregated across
+// all keys matching the pattern.
+func valueCount(label *peloton.Label, labelValues LabelValues) uint32 {
+	key := label.GetKey()
+	if !IsKeyPattern(key) {
+		return labelValues[key][label.GetValue()]
 	}
 
-	var toEval []*task.Constraint
-	switch constraint.GetType() {
-	case task.Constraint_AND_CONSTRAINT:
-		toEval = constraint.GetAndConstraint().GetConstraints()
-	case task.Constraint_OR_CONSTRAINT:
-		toEval = constraint.GetOrConstraint().GetConstraints()
-	case task.Constraint_LABEL_CONSTRAINT:
-		lc := constraint.GetLabelConstraint()
-		if lc.GetKind() == task.LabelConstraint_HOST &&
-			lc.GetLabel().GetKey() == common.PelotonExclusiveAttributeName {
-			return false
+	var count uint32
+	for k, values := range labelValues {
+		if matched, err := path.Match(key, k); err == nil && matched {
+			count += values[label.GetValue()]
 		}
-		return true
 	}
-	for _, c := range toEval {
-		if !IsNonExclusiveConstraint(c) {
-			return false
-		}
+	return count
+}
+
+// IsKeyPattern returns true if the label key is a glob pattern as
+// accepted by path.Match.
+func IsKeyPattern(key string) bool {
+	if !strings.ContainsAny(key, "*?[") {
+		return false
 	}
-	return true
+	_, err := path.Match(key, "")
+	return err == nil
+}
+
+// IsNonExclusiveConstraint returns true if all components of the constraint
+// specification do not use a host label constraint for exclusive attribute.
+func IsNonExclusiveConstraint(constraint *task.Constraint) bool {
+	return len(GetExclusiveRequirements(constraint)) == 0
 }
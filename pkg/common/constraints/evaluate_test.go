@@ -425,6 +425,120 @@ func (suite *EvaluatorTestSuite) TestIsNonExclusiveConstraint() {
 	}
 }
 
+// TestGlobLabelKey tests that label keys with glob patterns aggregate counts
+// across all matching keys.
+func (suite *EvaluatorTestSuite) TestGlobLabelKey() {
+	labelValues := LabelValues(map[string]map[string]uint32{
+		"gpu/nvidia": {"true": 2},
+		"gpu/amd":    {"true": 1},
+		"cpu/intel":  {"true": 4},
+		"gpu/a/b":    {"true": 8},
+	})
+	newConstraint := func(key string, requirement uint32) *task.Constraint {
+		return &task.Constraint{
+			Type: task.Constraint_LABEL_CONSTRAINT,
+			LabelConstraint: &task.LabelConstraint{
+				Kind:        task.LabelConstraint_HOST,
+				Label:       &peloton.Label{Key: key, Value: "true"},
+				Requirement: requirement,
+				Condition:   task.LabelConstraint_CONDITION_EQUAL,
+			},
+		}
+	}
+
+	e := NewEvaluator(task.LabelConstraint_HOST)
+	for _, tc := range []struct {
+		key         string
+		requirement uint32
+	}{
+		{"gpu/*", 3},
+		{"gpu/nvidia", 2},
+		{"*/intel", 4},
+		{"gpu/[", 0},
+	} {
+		result, err := e.Evaluate(
+			newConstraint(tc.key, tc.requirement), labelValues)
+		suite.NoError(err)
+		suite.Equal(EvaluateResultMatch, result, tc.key)
+	}
+
+	suite.True(IsKeyPattern("gpu/*"))
+	suite.False(IsKeyPattern("gpu/nvidia"))
+	suite.False(IsKeyPattern("gpu/["))
+}
+
+// TestRequirementPercent tests that percentage requirements are evaluated
+// against the denominator supplied to the evaluator.
+func (suite *EvaluatorTestSuite) TestRequirementPercent() {
+	labelValues := LabelValues(map[string]map[string]uint32{
+		_rackLabel: {_testRack: 4},
+	})
+	// fewer than 5% of the hosts of the cluster on the rack
+	lessThan := &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:               task.LabelConstraint_HOST,
+			Label:              &peloton.Label{Key: _rackLabel, Value: _testRack},
+			Condition:          task.LabelConstraint_CONDITION_LESS_THAN,
+			Requirement:        100,
+			RequirementPercent: 5,
+		},
+	}
+
+	e := NewEvaluator(task.LabelConstraint_HOST)
+	_, err := e.Evaluate(lessThan, labelValues)
+	suite.Equal(ErrNoDenominator, err)
+
+	for _, tc := range []struct {
+		denominator uint32
+		expected    EvaluateResult
+	}{
+		{200, EvaluateResultMatch},
+		{80, EvaluateResultMismatch},
+		{60, EvaluateResultMismatch},
+		{0, EvaluateResultMismatch},
+	} {
+		result, err := WithDenominator(e, tc.denominator).
+			Evaluate(lessThan, labelValues)
+		suite.NoError(err)
+		suite.Equal(tc.expected, result, tc.denominator)
+	}
+
+	// the denominator applies to the constraints nested in AND, OR and
+	// time window constraints, also with an optimizer
+	nested := &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{
+				{
+					Type: task.Constraint_OR_CONSTRAINT,
+					OrConstraint: &task.OrConstraint{
+						Constraints: []*task.Constraint{lessThan},
+					},
+				},
+			},
+		},
+	}
+	for _, e := range []Evaluator{
+		NewEvaluator(task.LabelConstraint_HOST),
+		NewOptimizer(0).NewEvaluator(task.LabelConstraint_HOST),
+	} {
+		result, err := WithDenominator(e, 200).Evaluate(nested, labelValues)
+		suite.NoError(err)
+		suite.Equal(EvaluateResultMatch, result)
+		result, err = WithDenominator(e, 40).Evaluate(nested, labelValues)
+		suite.NoError(err)
+		suite.Equal(EvaluateResultMismatch, result)
+	}
+
+	// constraints with a count requirement ignore the denominator
+	count := proto.Clone(lessThan).(*task.Constraint)
+	count.LabelConstraint.RequirementPercent = 0
+	result, err := WithDenominator(e, 40).Evaluate(count, labelValues)
+	suite.NoError(err)
+	suite.Equal(EvaluateResultMatch, result)
+}
+
 func TestEvaluatorTestSuite(t *testing.T) {
 	suite.Run(t, new(EvaluatorTestSuite))
 }
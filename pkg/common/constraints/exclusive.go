@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+)
+
+// ExclusiveRequirement describes a host label constraint on the exclusive
+// attribute found in a constraint tree.
+type ExclusiveRequirement struct {
+	// Value of the exclusive attribute, e.g. "storage".
+	Value string
+	// Condition of the label constraint.
+	Condition task.LabelConstraint_Condition
+	// Requirement of the label constraint.
+	Requirement uint32
+}
+
+// IsRequired returns true if the requirement can only be satisfied on a
+// host which carries the exclusive attribute with the given value.
+func (r ExclusiveRequirement) IsRequired() bool {
+	switch r.Condition {
+	case task.LabelConstraint_CONDITION_GREATER_THAN:
+		return true
+	case task.LabelConstraint_CONDITION_EQUAL:
+		return r.Requirement > 0
+	}
+	return false
+}
+
+// GetExclusiveRequirements returns all host label constraints on the
+// exclusive attribute in the given constraint tree, in depth-first order.
+func GetExclusiveRequirements(
+	constraint *task.Constraint) []ExclusiveRequirement {
+	if constraint == nil {
+		return nil
+	}
+
+	var toEval []*task.Constraint
+	switch constraint.GetType() {
+	case task.Constraint_AND_CONSTRAINT:
+		toEval = constraint.GetAndConstraint().GetConstraints()
+	case task.Constraint_OR_CONSTRAINT:
+		toEval = constraint.GetOrConstraint().GetConstraints()
+	case task.Constraint_TIME_WINDOW_CONSTRAINT:
+		toEval = []*task.Constraint{
+			constraint.GetTimeWindowConstraint().GetConstraint(),
+		}
+	case task.Constraint_LABEL_CONSTRAINT:
+		lc := constraint.GetLabelConstraint()
+		if lc.GetKind() == task.LabelConstraint_HOST &&
+			lc.GetLabel().GetKey() == common.PelotonExclusiveAttributeName {
+			return []ExclusiveRequirement{{
+				Value:       lc.GetLabel().GetValue(),
+				Condition:   lc.GetCondition(),
+				Requirement: lc.GetRequirement(),
+			}}
+		}
+		return nil
+	}
+
+	var result []ExclusiveRequirement
+	for _, c := range toEval {
+		result = append(result, GetExclusiveRequirements(c)...)
+	}
+	return result
+}
+
+// GetRequiredExclusiveValues returns the distinct exclusive attribute values
+// which the constraint positively requires on a host, i.e. the set of
+// exclusive hosts the task may be mapped to.
+func GetRequiredExclusiveValues(constraint *task.Constraint) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, r := range GetExclusiveRequirements(constraint) {
+		if !r.IsRequired() || seen[r.Value] {
+			continue
+		}
+		seen[r.Value] = true
+		values = append(values, r.Value)
+	}
+	return values
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newExclusiveConstraint(
+	kind task.LabelConstraint_Kind,
+	value string,
+	condition task.LabelConstraint_Condition,
+	requirement uint32) *task.Constraint {
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind: kind,
+			Label: &peloton.Label{
+				Key:   common.PelotonExclusiveAttributeName,
+				Value: value,
+			},
+			Condition:   condition,
+			Requirement: requirement,
+		},
+	}
+}
+
+// TestGetExclusiveRequirements tests extracting exclusive requirements
+// from a constraint tree.
+func TestGetExclusiveRequirements(t *testing.T) {
+	storage := newExclusiveConstraint(
+		task.LabelConstraint_HOST,
+		"storage",
+		task.LabelConstraint_CONDITION_GREATER_THAN,
+		0)
+	notGPU := newExclusiveConstraint(
+		task.LabelConstraint_HOST,
+		"gpu",
+		task.LabelConstraint_CONDITION_LESS_THAN,
+		1)
+	taskLabel := newExclusiveConstraint(
+		task.LabelConstraint_TASK,
+		"ignored",
+		task.LabelConstraint_CONDITION_GREATER_THAN,
+		0)
+
+	assert.Empty(t, GetExclusiveRequirements(nil))
+	assert.Empty(t, GetExclusiveRequirements(taskLabel))
+
+	c := &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{
+				storage,
+				taskLabel,
+				{
+					Type: task.Constraint_OR_CONSTRAINT,
+					OrConstraint: &task.OrConstraint{
+						Constraints: []*task.Constraint{notGPU, storage},
+					},
+				},
+			},
+		},
+	}
+
+	reqs := GetExclusiveRequirements(c)
+	assert.Len(t, reqs, 3)
+	assert.Equal(t, "storage", reqs[0].Value)
+	assert.True(t, reqs[0].IsRequired())
+	assert.Equal(t, "gpu", reqs[1].Value)
+	assert.False(t, reqs[1].IsRequired())
+	assert.Equal(t, []string{"storage"}, GetRequiredExclusiveValues(c))
+	assert.False(t, IsNonExclusiveConstraint(c))
+}
+
+// TestExclusiveRequirementIsRequired tests ExclusiveRequirement.IsRequired
+func TestExclusiveRequirementIsRequired(t *testing.T) {
+	testTable := []struct {
+		msg      string
+		req      ExclusiveRequirement
+		expected bool
+	}{
+		{
+			msg: "greater than",
+			req: ExclusiveRequirement{
+				Condition: task.LabelConstraint_CONDITION_GREATER_THAN,
+			},
+			expected: true,
+		},
+		{
+			msg: "equal to one",
+			req: ExclusiveRequirement{
+				Condition:   task.LabelConstraint_CONDITION_EQUAL,
+				Requirement: 1,
+			},
+			expected: true,
+		},
+		{
+			msg: "equal to zero",
+			req: ExclusiveRequirement{
+				Condition: task.LabelConstraint_CONDITION_EQUAL,
+			},
+			expected: false,
+		},
+		{
+			msg: "less than",
+			req: ExclusiveRequirement{
+				Condition:   task.LabelConstraint_CONDITION_LESS_THAN,
+				Requirement: 1,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testTable {
+		assert.Equal(t, tc.expected, tc.req.IsRequired(), tc.msg)
+	}
+}
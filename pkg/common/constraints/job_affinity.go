@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"fmt"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+)
+
+// JobNameLabelKey is the key of the system label carrying the job name,
+// which is added to every task of a job.
+var JobNameLabelKey = fmt.Sprintf(
+	common.SystemLabelKeyTemplate,
+	common.SystemLabelPrefix,
+	common.SystemLabelJobName)
+
+// NewJobAffinityConstraint returns a task constraint which only matches
+// hosts running at least one task of the given job.
+func NewJobAffinityConstraint(jobName string) *task.Constraint {
+	return newJobConstraint(
+		jobName, task.LabelConstraint_CONDITION_GREATER_THAN, 0)
+}
+
+// NewJobAntiAffinityConstraint returns a task constraint which only matches
+// hosts running no task of the given job.
+func NewJobAntiAffinityConstraint(jobName string) *task.Constraint {
+	return newJobConstraint(
+		jobName, task.LabelConstraint_CONDITION_LESS_THAN, 1)
+}
+
+func newJobConstraint(
+	jobName string,
+	condition task.LabelConstraint_Condition,
+	requirement uint32) *task.Constraint {
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind: task.LabelConstraint_TASK,
+			Label: &peloton.Label{
+				Key:   JobNameLabelKey,
+				Value: jobName,
+			},
+			Condition:   condition,
+			Requirement: requirement,
+		},
+	}
+}
+
+// GetTaskLabelValues returns label counts for the tasks running on a host,
+// given the labels of each task, which can be used to evaluate a constraint
+// of kind TASK.
+func GetTaskLabelValues(taskLabels [][]*peloton.Label) LabelValues {
+	result := make(LabelValues)
+	for _, labels := range taskLabels {
+		for _, l := range labels {
+			result.AddLabel(l.GetKey(), l.GetValue())
+		}
+	}
+	return result
+}
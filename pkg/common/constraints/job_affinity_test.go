@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJobAffinityConstraints tests inter-job affinity and anti-affinity.
+func TestJobAffinityConstraints(t *testing.T) {
+	assert.Equal(t, "peloton.job_name", JobNameLabelKey)
+
+	serviceTask := []*peloton.Label{
+		{Key: JobNameLabelKey, Value: "service"},
+		{Key: "team", Value: "infra"},
+	}
+	otherTask := []*peloton.Label{
+		{Key: JobNameLabelKey, Value: "other"},
+	}
+
+	withService := GetTaskLabelValues(
+		[][]*peloton.Label{serviceTask, serviceTask, otherTask})
+	assert.Equal(t, uint32(2), withService[JobNameLabelKey]["service"])
+	assert.Equal(t, uint32(2), withService["team"]["infra"])
+
+	withoutService := GetTaskLabelValues([][]*peloton.Label{otherTask})
+
+	e := NewEvaluator(task.LabelConstraint_TASK)
+	affinity := NewJobAffinityConstraint("service")
+	antiAffinity := NewJobAntiAffinityConstraint("service")
+
+	for _, tc := range []struct {
+		msg         string
+		constraint  *task.Constraint
+		labelValues LabelValues
+		expected    EvaluateResult
+	}{
+		{"affinity with job", affinity, withService, EvaluateResultMatch},
+		{"affinity without job", affinity, withoutService, EvaluateResultMismatch},
+		{"anti-affinity with job", antiAffinity, withService, EvaluateResultMismatch},
+		{"anti-affinity without job", antiAffinity, withoutService, EvaluateResultMatch},
+	} {
+		result, err := e.Evaluate(tc.constraint, tc.labelValues)
+		assert.NoError(t, err, tc.msg)
+		assert.Equal(t, tc.expected, result, tc.msg)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LabelCounter is a concurrency-safe counter of label key and value
+// occurrences, which can be exported as LabelValues for constraint
+// evaluation. Counts of already seen labels are updated atomically under a
+// shared lock, so concurrent updates don't contend on a global lock.
+type LabelCounter struct {
+	lock sync.RWMutex
+	// first level key is label key, second level key is label value
+	counts map[string]map[string]*uint32
+}
+
+// NewLabelCounter returns a new empty LabelCounter.
+func NewLabelCounter() *LabelCounter {
+	return &LabelCounter{
+		counts: make(map[string]map[string]*uint32),
+	}
+}
+
+// Increment increments the count of given label value by one.
+func (c *LabelCounter) Increment(key, value string) {
+	c.lock.RLock()
+	if counter := c.counts[key][value]; counter != nil {
+		// The count is updated under the shared lock, so that Compact
+		// cannot remove the counter before it is incremented.
+		atomic.AddUint32(counter, 1)
+		c.lock.RUnlock()
+		return
+	}
+	c.lock.RUnlock()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	values, ok := c.counts[key]
+	if !ok {
+		values = make(map[string]*uint32)
+		c.counts[key] = values
+	}
+	counter, ok := values[value]
+	if !ok {
+		counter = new(uint32)
+		values[value] = counter
+	}
+	atomic.AddUint32(counter, 1)
+}
+
+// Decrement decrements the count of given label value by one. The count
+// never drops below zero.
+func (c *LabelCounter) Decrement(key, value string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	counter := c.counts[key][value]
+	if counter == nil {
+		return
+	}
+	for {
+		old := atomic.LoadUint32(counter)
+		if old == 0 || atomic.CompareAndSwapUint32(counter, old, old-1) {
+			return
+		}
+	}
+}
+
+// Get returns the current count of given label value.
+func (c *LabelCounter) Get(key, value string) uint32 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	counter := c.counts[key][value]
+	if counter == nil {
+		return 0
+	}
+	return atomic.LoadUint32(counter)
+}
+
+// Snapshot returns a copy of all non-zero counts as LabelValues.
+func (c *LabelCounter) Snapshot() LabelValues {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	result := make(LabelValues, len(c.counts))
+	for key, values := range c.counts {
+		for value, counter := range values {
+			count := atomic.LoadUint32(counter)
+			if count == 0 {
+				continue
+			}
+			if _, ok := result[key]; !ok {
+				result[key] = make(map[string]uint32)
+			}
+			result[key][value] = count
+		}
+	}
+	return result
+}
+
+// Compact removes label values whose count dropped to zero.
+func (c *LabelCounter) Compact() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, values := range c.counts {
+		for value, counter := range values {
+			if atomic.LoadUint32(counter) == 0 {
+				delete(values, value)
+			}
+		}
+		if len(values) == 0 {
+			delete(c.counts, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelCounter(t *testing.T) {
+	c := NewLabelCounter()
+	c.Increment("job", "cache")
+	c.Increment("job", "cache")
+	c.Increment("rack", "r1")
+	assert.Equal(t, uint32(2), c.Get("job", "cache"))
+	assert.Equal(t, uint32(0), c.Get("job", "unknown"))
+
+	c.Decrement("rack", "r1")
+	c.Decrement("rack", "r1")
+	c.Decrement("unknown", "unknown")
+	assert.Equal(t, uint32(0), c.Get("rack", "r1"))
+
+	assert.Equal(t, LabelValues{"job": {"cache": 2}}, c.Snapshot())
+
+	c.Compact()
+	assert.Len(t, c.counts, 1)
+}
+
+// TestLabelCounterConcurrent is meant to be run with the race detector.
+func TestLabelCounterConcurrent(t *testing.T) {
+	c := NewLabelCounter()
+	numWorkers := 10
+	numUpdates := 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numUpdates; j++ {
+				c.Increment("job", "cache")
+				c.Increment("job", "service")
+				c.Decrement("job", "service")
+				c.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint32(numWorkers*numUpdates), c.Get("job", "cache"))
+	assert.Equal(t, uint32(0), c.Get("job", "service"))
+}
+
+// TestLabelCounterConcurrentCompact tests that no update is lost to a
+// concurrent Compact removing the counter being updated.
+func TestLabelCounterConcurrentCompact(t *testing.T) {
+	c := NewLabelCounter()
+	numWorkers := 10
+	numUpdates := 100
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				c.Compact()
+			}
+		}
+	}()
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numUpdates; j++ {
+				c.Increment("job", "service")
+				c.Decrement("job", "service")
+				c.Increment("job", "cache")
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+
+	assert.Equal(t, uint32(numWorkers*numUpdates), c.Get("job", "cache"))
+	assert.Equal(t, uint32(0), c.Get("job", "service"))
+}
@@ -45,32 +45,12 @@ func GetHostLabelValues(
 
 	result := make(map[string]map[string]uint32)
 	result[HostNameKey] = map[string]uint32{hostname: 1}
-OUTER:
 	for _, attr := range attributes {
-		key := attr.GetName()
-		values := []string{}
-		switch attr.GetType() {
-		case mesos.Value_TEXT:
-			values = append(values, attr.GetText().GetValue())
-		case mesos.Value_SCALAR:
-			value := strconv.FormatFloat(
-				attr.GetScalar().GetValue(),
-				'f',
-				_precision,
-				_bitsize)
-			values = append(values, value)
-		case mesos.Value_SET:
-			for _, value := range attr.GetSet().GetItem() {
-				values = append(values, value)
-			}
-		default:
-			// TODO: Add support for range attributes.
-			log.WithFields(log.Fields{
-				"key":  key,
-				"type": attr.GetType(),
-			}).Warn("Attribute type is not supported yet")
-			continue OUTER
+		values, ok := GetAttributeValues(attr)
+		if !ok {
+			continue
 		}
+		key := attr.GetName()
 		if _, ok := result[key]; !ok {
 			result[key] = make(map[string]uint32)
 		}
@@ -80,3 +60,138 @@ OUTER:
 	}
 	return result
 }
+
+// GetAttributeValues returns the label values of an attribute, as used
+// for constraint evaluation: a set attribute has one value per item.
+// Returns false if the attribute type is not supported.
+func GetAttributeValues(attr *mesos.Attribute) ([]string, bool) {
+	var values []string
+	switch attr.GetType() {
+	case mesos.Value_TEXT:
+		values = append(values, attr.GetText().GetValue())
+	case mesos.Value_SCALAR:
+		value := strconv.FormatFloat(
+			attr.GetScalar().GetValue(),
+			'f',
+			_precision,
+			_bitsize)
+		values = append(values, value)
+	case mesos.Value_SET:
+		for _, value := range attr.GetSet().GetItem() {
+			values = append(values, value)
+		}
+	default:
+		// TODO: Add support for range attributes.
+		log.WithFields(log.Fields{
+			"key":  attr.GetName(),
+			"type": attr.GetType(),
+		}).Warn("Attribute type is not supported yet")
+		return nil, false
+	}
+	return values, true
+}
+
+// LabelValueChange is the change of count for a single label key and value
+// between two LabelValues snapshots.
+type LabelValueChange struct {
+	Key   string
+	Value string
+	// Delta is the change of count from the old to the new snapshot.
+	Delta int64
+}
+
+// AddLabel increments the count of given label value by one.
+func (lv LabelValues) AddLabel(key, value string) {
+	if _, ok := lv[key]; !ok {
+		lv[key] = make(map[string]uint32)
+	}
+	lv[key][value]++
+}
+
+// RemoveLabel decrements the count of given label value by one. Values
+// and keys whose count drops to zero are removed.
+func (lv LabelValues) RemoveLabel(key, value string) {
+	values, ok := lv[key]
+	if !ok {
+		return
+	}
+	if values[value] <= 1 {
+		delete(values, value)
+	} else {
+		values[value]--
+	}
+	if len(values) == 0 {
+		delete(lv, key)
+	}
+}
+
+// Merge adds all counts of other LabelValues into this one.
+func (lv LabelValues) Merge(other LabelValues) {
+	for key, values := range other {
+		if _, ok := lv[key]; !ok {
+			lv[key] = make(map[string]uint32)
+		}
+		for value, count := range values {
+			lv[key][value] += count
+		}
+	}
+}
+
+// Copy returns a deep copy of the LabelValues.
+func (lv LabelValues) Copy() LabelValues {
+	result := make(LabelValues, len(lv))
+	result.Merge(lv)
+	return result
+}
+
+// Diff returns the changes needed to turn this LabelValues into other.
+// Unchanged label values are not included in the result.
+func (lv LabelValues) Diff(other LabelValues) []LabelValueChange {
+	var changes []LabelValueChange
+	for key, values := range lv {
+		for value, count := range values {
+			newCount := other[key][value]
+			if newCount != count {
+				changes = append(changes, LabelValueChange{
+					Key:   key,
+					Value: value,
+					Delta: int64(newCount) - int64(count),
+				})
+			}
+		}
+	}
+	for key, values := range other {
+		for value, count := range values {
+			if _, ok := lv[key][value]; ok || count == 0 {
+				continue
+			}
+			changes = append(changes, LabelValueChange{
+				Key:   key,
+				Value: value,
+				Delta: int64(count),
+			})
+		}
+	}
+	return changes
+}
+
+// Apply applies the given changes to the LabelValues. Counts never drop
+// below zero, and values with zero count are removed.
+func (lv LabelValues) Apply(changes []LabelValueChange) {
+	for _, c := range changes {
+		count := int64(lv[c.Key][c.Value]) + c.Delta
+		if count > 0 {
+			if _, ok := lv[c.Key]; !ok {
+				lv[c.Key] = make(map[string]uint32)
+			}
+			lv[c.Key][c.Value] = uint32(count)
+			continue
+		}
+		if values, ok := lv[c.Key]; ok {
+			delete(values, c.Value)
+			if len(values) == 0 {
+				delete(lv, c.Key)
+			}
+		}
+	}
+}
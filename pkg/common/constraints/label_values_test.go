@@ -107,6 +107,58 @@ func (suite *LabelValuesTestSuite) TestGetHostLabelValues() {
 	suite.Equal(map[string]uint32{"1.000000": 1}, res[scalarName])
 }
 
+func (suite *LabelValuesTestSuite) TestAddRemoveLabel() {
+	lv := LabelValues{}
+	lv.AddLabel("job", "cache")
+	lv.AddLabel("job", "cache")
+	lv.AddLabel("job", "service")
+	suite.Equal(uint32(2), lv["job"]["cache"])
+	suite.Equal(uint32(1), lv["job"]["service"])
+
+	lv.RemoveLabel("job", "cache")
+	suite.Equal(uint32(1), lv["job"]["cache"])
+	lv.RemoveLabel("job", "cache")
+	lv.RemoveLabel("job", "service")
+	suite.Empty(lv)
+
+	// Removing unknown labels is a no-op.
+	lv.RemoveLabel("job", "unknown")
+	suite.Empty(lv)
+}
+
+func (suite *LabelValuesTestSuite) TestMergeAndCopy() {
+	lv := LabelValues{"rack": {"r1": 1}}
+	cp := lv.Copy()
+	cp.Merge(LabelValues{"rack": {"r1": 2, "r2": 1}, "zone": {"z1": 1}})
+
+	suite.Equal(LabelValues{"rack": {"r1": 1}}, lv)
+	suite.Equal(
+		LabelValues{"rack": {"r1": 3, "r2": 1}, "zone": {"z1": 1}},
+		cp)
+}
+
+func (suite *LabelValuesTestSuite) TestDiffAndApply() {
+	old := LabelValues{
+		"job":  {"cache": 2, "service": 1},
+		"rack": {"r1": 1},
+	}
+	updated := LabelValues{
+		"job":  {"cache": 1, "batch": 1},
+		"rack": {"r1": 1},
+	}
+
+	changes := old.Diff(updated)
+	suite.ElementsMatch([]LabelValueChange{
+		{Key: "job", Value: "cache", Delta: -1},
+		{Key: "job", Value: "service", Delta: -1},
+		{Key: "job", Value: "batch", Delta: 1},
+	}, changes)
+	suite.Empty(old.Diff(old.Copy()))
+
+	old.Apply(changes)
+	suite.Equal(updated, old)
+}
+
 func TestLabelValuesTestSuite(t *testing.T) {
 	suite.Run(t, new(LabelValuesTestSuite))
 }
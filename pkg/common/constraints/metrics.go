@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber-go/tally"
+)
+
+// DefaultMetricsScope is the default sub-scope name under which
+// constraint evaluation metrics are exported.
+const DefaultMetricsScope = "constraints"
+
+var _latencyBuckets = tally.DurationBuckets{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// Metrics tracks the outcomes of constraint evaluation for a constraint kind.
+type Metrics struct {
+	Match         tally.Counter
+	Mismatch      tally.Counter
+	NotApplicable tally.Counter
+	Error         tally.Counter
+
+	Latency tally.Histogram
+}
+
+// NewMetrics returns a new Metrics struct for the given constraint kind.
+func NewMetrics(scope tally.Scope, kind task.LabelConstraint_Kind) *Metrics {
+	kindScope := scope.Tagged(map[string]string{"kind": kind.String()})
+	resultScope := kindScope.SubScope("evaluate")
+	return &Metrics{
+		Match:         resultScope.Counter("match"),
+		Mismatch:      resultScope.Counter("mismatch"),
+		NotApplicable: resultScope.Counter("not_applicable"),
+		Error:         resultScope.Counter("error"),
+
+		Latency: kindScope.Histogram("evaluate_latency", _latencyBuckets),
+	}
+}
+
+// metricsEvaluator decorates an Evaluator with evaluation metrics.
+type metricsEvaluator struct {
+	Evaluator
+	metrics *Metrics
+}
+
+// NewEvaluatorWithMetrics returns a new evaluator for the given kind which
+// reports evaluation outcomes and latency into the given scope.
+func NewEvaluatorWithMetrics(
+	kind task.LabelConstraint_Kind,
+	scope tally.Scope) Evaluator {
+	return WithMetrics(NewEvaluator(kind), kind, scope)
+}
+
+// WithMetrics decorates the evaluator of the given kind with evaluation
+// outcomes and latency reported into the given scope.
+func WithMetrics(
+	evaluator Evaluator,
+	kind task.LabelConstraint_Kind,
+	scope tally.Scope) Evaluator {
+	return &metricsEvaluator{
+		Evaluator: evaluator,
+		metrics:   NewMetrics(scope, kind),
+	}
+}
+
+// withDenominator implements denominatorEvaluator by decorating the
+// decorated evaluator with the denominator, sharing the metrics.
+func (e *metricsEvaluator) withDenominator(denominator uint32) Evaluator {
+	return &metricsEvaluator{
+		Evaluator: WithDenominator(e.Evaluator, denominator),
+		metrics:   e.metrics,
+	}
+}
+
+// Evaluate evaluates the constraint and records its outcome.
+func (e *metricsEvaluator) Evaluate(
+	constraint *task.Constraint,
+	labelValues LabelValues) (EvaluateResult, error) {
+	return e.EvaluateAt(constraint, labelValues, time.Now())
+}
+
+// EvaluateAt evaluates the constraint at given time and records its outcome.
+func (e *metricsEvaluator) EvaluateAt(
+	constraint *task.Constraint,
+	labelValues LabelValues,
+	at time.Time) (EvaluateResult, error) {
+	start := time.Now()
+	result, err := e.Evaluator.EvaluateAt(constraint, labelValues, at)
+	e.metrics.Latency.RecordDuration(time.Since(start))
+
+	if err != nil {
+		e.metrics.Error.Inc(1)
+		return result, err
+	}
+
+	switch result {
+	case EvaluateResultMatch:
+		e.metrics.Match.Inc(1)
+	case EvaluateResultMismatch:
+		e.metrics.Mismatch.Inc(1)
+	case EvaluateResultNotApplicable:
+		e.metrics.NotApplicable.Inc(1)
+	}
+	return result, nil
+}
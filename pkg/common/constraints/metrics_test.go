@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// TestEvaluatorWithMetrics tests that evaluation outcomes are counted.
+func TestEvaluatorWithMetrics(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	e := NewEvaluatorWithMetrics(task.LabelConstraint_HOST, scope)
+
+	c := &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:        task.LabelConstraint_HOST,
+			Label:       &peloton.Label{Key: HostNameKey, Value: "h1"},
+			Condition:   task.LabelConstraint_CONDITION_EQUAL,
+			Requirement: 1,
+		},
+	}
+
+	result, err := e.Evaluate(c, LabelValues{HostNameKey: {"h1": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMatch, result)
+
+	result, err = e.Evaluate(c, LabelValues{HostNameKey: {"h2": 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMismatch, result)
+
+	_, err = e.Evaluate(
+		&task.Constraint{Type: task.Constraint_Type(-1)}, LabelValues{})
+	assert.Equal(t, ErrUnknownConstraintType, err)
+
+	counters := scope.Snapshot().Counters()
+	assert.Equal(t, int64(1),
+		counters["evaluate.match+kind=HOST"].Value())
+	assert.Equal(t, int64(1),
+		counters["evaluate.mismatch+kind=HOST"].Value())
+	assert.Equal(t, int64(1),
+		counters["evaluate.error+kind=HOST"].Value())
+}
+
+// TestEvaluatorWithMetricsAndDenominator tests that the denominator is
+// forwarded through the metrics decorator, of an optimized evaluator too,
+// and that the evaluations with the denominator are counted.
+func TestEvaluatorWithMetricsAndDenominator(t *testing.T) {
+	c := &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:               task.LabelConstraint_HOST,
+			Label:              &peloton.Label{Key: HostNameKey, Value: "h1"},
+			Condition:          task.LabelConstraint_CONDITION_LESS_THAN,
+			RequirementPercent: 10,
+		},
+	}
+	lv := LabelValues{HostNameKey: {"h1": 2}}
+
+	for _, e := range []Evaluator{
+		NewEvaluator(task.LabelConstraint_HOST),
+		NewOptimizer(0).NewEvaluator(task.LabelConstraint_HOST),
+	} {
+		scope := tally.NewTestScope("", map[string]string{})
+		e = WithMetrics(e, task.LabelConstraint_HOST, scope)
+
+		_, err := e.Evaluate(c, lv)
+		assert.Equal(t, ErrNoDenominator, err)
+
+		result, err := WithDenominator(e, 40).Evaluate(c, lv)
+		assert.NoError(t, err)
+		assert.Equal(t, EvaluateResultMatch, result)
+
+		result, err = WithDenominator(e, 10).Evaluate(c, lv)
+		assert.NoError(t, err)
+		assert.Equal(t, EvaluateResultMismatch, result)
+
+		counters := scope.Snapshot().Counters()
+		assert.Equal(t, int64(1),
+			counters["evaluate.match+kind=HOST"].Value())
+		assert.Equal(t, int64(1),
+			counters["evaluate.mismatch+kind=HOST"].Value())
+		assert.Equal(t, int64(1),
+			counters["evaluate.error+kind=HOST"].Value())
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+// GetMismatchedConstraint returns the part of a constraint which the label
+// values do not satisfy, to explain why a host is rejected. The first
+// mismatched constraint of an AND constraint is descended into; any other
+// mismatched constraint is returned as a whole, as no single part of it
+// rejects the host. Returns nil if the label values satisfy the constraint.
+func GetMismatchedConstraint(
+	evaluator Evaluator,
+	constraint *task.Constraint,
+	labelValues LabelValues) (*task.Constraint, error) {
+	result, err := evaluator.Evaluate(constraint, labelValues)
+	if err != nil {
+		return nil, err
+	}
+	if result != EvaluateResultMismatch {
+		return nil, nil
+	}
+
+	if constraint.GetType() == task.Constraint_AND_CONSTRAINT {
+		for _, c := range constraint.GetAndConstraint().GetConstraints() {
+			mismatched, err := GetMismatchedConstraint(evaluator, c, labelValues)
+			if err != nil {
+				return nil, err
+			}
+			if mismatched != nil {
+				return mismatched, nil
+			}
+		}
+	}
+	return constraint, nil
+}
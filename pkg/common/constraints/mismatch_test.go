@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHostLabelConstraint(key, value string) *task.Constraint {
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind: task.LabelConstraint_HOST,
+			Label: &peloton.Label{
+				Key:   key,
+				Value: value,
+			},
+			Condition:   task.LabelConstraint_CONDITION_EQUAL,
+			Requirement: 1,
+		},
+	}
+}
+
+// TestGetMismatchedConstraint tests finding the part of a constraint
+// rejecting a host.
+func TestGetMismatchedConstraint(t *testing.T) {
+	evaluator := NewEvaluator(task.LabelConstraint_HOST)
+	labelValues := LabelValues{
+		HostNameKey: {"host1": 1},
+		"rack":      {"rack1": 1},
+	}
+	onHost := newHostLabelConstraint(HostNameKey, "host1")
+	onRack := newHostLabelConstraint("rack", "rack2")
+	onZone := newHostLabelConstraint("zone", "zone1")
+
+	and := func(constraints ...*task.Constraint) *task.Constraint {
+		return &task.Constraint{
+			Type:          task.Constraint_AND_CONSTRAINT,
+			AndConstraint: &task.AndConstraint{Constraints: constraints},
+		}
+	}
+	or := func(constraints ...*task.Constraint) *task.Constraint {
+		return &task.Constraint{
+			Type:         task.Constraint_OR_CONSTRAINT,
+			OrConstraint: &task.OrConstraint{Constraints: constraints},
+		}
+	}
+
+	testCases := map[string]struct {
+		constraint *task.Constraint
+		expected   *task.Constraint
+	}{
+		"satisfied": {
+			constraint: and(onHost, or(onRack, onHost)),
+		},
+		"label constraint": {
+			constraint: onRack,
+			expected:   onRack,
+		},
+		"first mismatched part of and constraint": {
+			constraint: and(onHost, onRack, onZone),
+			expected:   onRack,
+		},
+		"nested and constraint": {
+			constraint: and(onHost, and(onHost, onZone)),
+			expected:   onZone,
+		},
+		"or constraint as a whole": {
+			constraint: and(onHost, or(onRack, onZone)),
+			expected:   or(onRack, onZone),
+		},
+	}
+
+	for name, tc := range testCases {
+		mismatched, err := GetMismatchedConstraint(
+			evaluator, tc.constraint, labelValues)
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expected, mismatched, name)
+	}
+
+	_, err := GetMismatchedConstraint(
+		evaluator, &task.Constraint{}, labelValues)
+	assert.Equal(t, ErrUnknownConstraintType, err)
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+const (
+	// DefaultOptimizerMaxConstraints is the default number of AND and OR
+	// constraints whose statistics are kept by an Optimizer.
+	DefaultOptimizerMaxConstraints = 10000
+	// _reorderPeriod is the number of evaluations of a constraint after
+	// which the order of its children is computed again.
+	_reorderPeriod = 100
+)
+
+// childStats are the evaluation statistics of a child of an AND or OR
+// constraint.
+type childStats struct {
+	// number of times the child was evaluated
+	evaluations uint64
+	// number of times the child decided the result of its parent, i.e.
+	// mismatched in an AND constraint or matched in an OR constraint
+	shortCircuits uint64
+}
+
+// constraintStats are the evaluation statistics of the children of an AND
+// or OR constraint, and the order in which they are evaluated.
+type constraintStats struct {
+	// number of evaluations since the order was computed
+	evaluations int
+	// indices of the children in evaluation order
+	order    []int
+	children []childStats
+	// estimated cost of the evaluation of each child
+	costs []float64
+}
+
+// Optimizer reorders the children of the AND and OR constraints so that
+// the children most likely to decide the result of their parent, for the
+// lowest cost, are evaluated first, which short-circuits the evaluation of
+// the others. The likelihood is learnt from the previous evaluations of
+// the constraint, and the cost is the number of label constraints of the
+// child. Reordering does not change the result of an evaluation, unless
+// one of the children fails.
+//
+// The statistics are kept by constraint, so an Optimizer is only useful
+// with constraints evaluated many times, such as the constraints of the
+// tasks of large jobs matched against every host.
+type Optimizer struct {
+	sync.Mutex
+
+	// max number of constraints whose statistics are kept
+	maxConstraints int
+	stats          map[*task.Constraint]*constraintStats
+}
+
+// NewOptimizer returns an optimizer keeping the statistics of at most
+// maxConstraints AND and OR constraints, DefaultOptimizerMaxConstraints
+// if zero. The statistics are dropped once more constraints are evaluated.
+func NewOptimizer(maxConstraints int) *Optimizer {
+	if maxConstraints <= 0 {
+		maxConstraints = DefaultOptimizerMaxConstraints
+	}
+	return &Optimizer{
+		maxConstraints: maxConstraints,
+		stats:          make(map[*task.Constraint]*constraintStats),
+	}
+}
+
+// NewEvaluator returns an evaluator of the constraints of the types of
+// DefaultRegistry, whose AND and OR constraints are reordered by the
+// optimizer.
+func (o *Optimizer) NewEvaluator(kind task.LabelConstraint_Kind) Evaluator {
+	return DefaultRegistry.newEvaluator(kind,
+		map[task.Constraint_Type]EvaluatorFactory{
+			task.Constraint_AND_CONSTRAINT: o.newAndEvaluator,
+			task.Constraint_OR_CONSTRAINT:  o.newOrEvaluator,
+		})
+}
+
+// newAndEvaluator returns the evaluator of the AND constraints, which are
+// decided by the first child which mismatches.
+func (o *Optimizer) newAndEvaluator(
+	_ task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error) {
+		return o.evaluate(parent, constraint,
+			constraint.GetAndConstraint().GetConstraints(),
+			EvaluateResultMismatch, labelValues, at)
+	})
+}
+
+// newOrEvaluator returns the evaluator of the OR constraints, which are
+// decided by the first child which matches.
+func (o *Optimizer) newOrEvaluator(
+	_ task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator {
+	return EvaluatorFunc(func(
+		constraint *task.Constraint,
+		labelValues LabelValues,
+		at time.Time,
+	) (EvaluateResult, error) {
+		return o.evaluate(parent, constraint,
+			constraint.GetOrConstraint().GetConstraints(),
+			EvaluateResultMatch, labelValues, at)
+	})
+}
+
+// evaluate evaluates the children of the constraint in the order of the
+// optimizer until one of them returns the deciding result. Otherwise the
+// result is the result of the applicable children, if any, like for
+// evaluateAndConstraint and evaluateOrConstraint.
+func (o *Optimizer) evaluate(
+	parent Evaluator,
+	constraint *task.Constraint,
+	children []*task.Constraint,
+	decidingResult EvaluateResult,
+	labelValues LabelValues,
+	at time.Time,
+) (EvaluateResult, error) {
+	order := o.order(constraint, children)
+
+	result := EvaluateResultNotApplicable
+	for n, i := range order {
+		subResult, err := parent.EvaluateAt(children[i], labelValues, at)
+		if err != nil {
+			return EvaluateResultNotApplicable, err
+		}
+		if subResult == decidingResult {
+			o.record(constraint, order[:n+1], true)
+			return decidingResult, nil
+		} else if subResult != EvaluateResultNotApplicable {
+			result = subResult
+		}
+	}
+	o.record(constraint, order, false)
+	return result, nil
+}
+
+// order returns the indices of the children of the constraint in the order
+// they should be evaluated.
+func (o *Optimizer) order(
+	constraint *task.Constraint,
+	children []*task.Constraint,
+) []int {
+	o.Lock()
+	defer o.Unlock()
+
+	stats, ok := o.stats[constraint]
+	if !ok || len(stats.children) != len(children) {
+		if len(o.stats) >= o.maxConstraints {
+			o.stats = make(map[*task.Constraint]*constraintStats)
+		}
+		stats = &constraintStats{
+			order:    make([]int, len(children)),
+			children: make([]childStats, len(children)),
+			costs:    make([]float64, len(children)),
+		}
+		for i, c := range children {
+			stats.order[i] = i
+			stats.costs[i] = float64(leafCount(c))
+		}
+		o.stats[constraint] = stats
+	}
+
+	stats.evaluations++
+	if stats.evaluations >= _reorderPeriod {
+		stats.evaluations = 0
+		stats.reorder()
+	}
+	return stats.order
+}
+
+// record records the evaluation of the children of the constraint, the
+// last of which decided the result if decided is true.
+func (o *Optimizer) record(
+	constraint *task.Constraint,
+	evaluated []int,
+	decided bool,
+) {
+	o.Lock()
+	defer o.Unlock()
+
+	stats, ok := o.stats[constraint]
+	if !ok {
+		return
+	}
+	for _, i := range evaluated {
+		stats.children[i].evaluations++
+	}
+	if decided && len(evaluated) > 0 {
+		stats.children[evaluated[len(evaluated)-1]].shortCircuits++
+	}
+}
+
+// reorder sorts the children by decreasing rate of short circuits per
+// unit of cost. Children never evaluated come first, so that their rate
+// gets known. The order slice is replaced rather than sorted in place as
+// it may be in use by concurrent evaluations.
+func (s *constraintStats) reorder() {
+	score := func(i int) float64 {
+		c := s.children[i]
+		if c.evaluations == 0 {
+			return math.Inf(1)
+		}
+		rate := float64(c.shortCircuits) / float64(c.evaluations)
+		return rate / s.costs[i]
+	}
+	order := append([]int(nil), s.order...)
+	sort.SliceStable(order, func(a, b int) bool {
+		return score(order[a]) > score(order[b])
+	})
+	s.order = order
+}
+
+// leafCount returns the number of label constraints of the constraint,
+// and at least one.
+func leafCount(constraint *task.Constraint) int {
+	count := 0
+	switch constraint.GetType() {
+	case task.Constraint_AND_CONSTRAINT:
+		for _, c := range constraint.GetAndConstraint().GetConstraints() {
+			count += leafCount(c)
+		}
+	case task.Constraint_OR_CONSTRAINT:
+		for _, c := range constraint.GetOrConstraint().GetConstraints() {
+			count += leafCount(c)
+		}
+	case task.Constraint_TIME_WINDOW_CONSTRAINT:
+		count = leafCount(
+			constraint.GetTimeWindowConstraint().GetConstraint())
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hostLabelConstraint returns a host constraint requiring the label
+func hostLabelConstraint(key, value string) *task.Constraint {
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:        task.LabelConstraint_HOST,
+			Label:       &peloton.Label{Key: key, Value: value},
+			Condition:   task.LabelConstraint_CONDITION_GREATER_THAN,
+			Requirement: 0,
+		},
+	}
+}
+
+// TestOptimizerReordersAnd tests that the child of an AND constraint which
+// mismatches most often gets evaluated first, without changing results.
+func TestOptimizerReordersAnd(t *testing.T) {
+	c := &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{
+				hostLabelConstraint("zone", "a"),
+				hostLabelConstraint("rack", "r1"),
+				hostLabelConstraint("gpu", "yes"),
+			},
+		},
+	}
+	// every host is in zone a, half of them in rack r1 and none has a gpu
+	hosts := []LabelValues{
+		{"zone": {"a": 1}, "rack": {"r1": 1}},
+		{"zone": {"a": 1}, "rack": {"r2": 1}},
+	}
+
+	o := NewOptimizer(0)
+	optimized := o.NewEvaluator(task.LabelConstraint_HOST)
+	plain := NewEvaluator(task.LabelConstraint_HOST)
+	for i := 0; i < 2*_reorderPeriod; i++ {
+		lv := hosts[i%len(hosts)]
+		expected, err := plain.Evaluate(c, lv)
+		assert.NoError(t, err)
+		result, err := optimized.Evaluate(c, lv)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	}
+
+	// the gpu child, which always mismatches, comes first, then the rack
+	// child which mismatches for half of the hosts
+	assert.Equal(t, []int{2, 1, 0}, o.stats[c].order)
+}
+
+// TestOptimizerReordersOr tests that the child of an OR constraint which
+// matches most often per label constraint gets evaluated first.
+func TestOptimizerReordersOr(t *testing.T) {
+	nested := &task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{
+				hostLabelConstraint("zone", "a"),
+				hostLabelConstraint("rack", "r1"),
+			},
+		},
+	}
+	c := &task.Constraint{
+		Type: task.Constraint_OR_CONSTRAINT,
+		OrConstraint: &task.OrConstraint{
+			Constraints: []*task.Constraint{
+				hostLabelConstraint("gpu", "yes"),
+				nested,
+				hostLabelConstraint("zone", "a"),
+			},
+		},
+	}
+	lv := LabelValues{"zone": {"a": 1}, "rack": {"r1": 1}}
+
+	o := NewOptimizer(0)
+	e := o.NewEvaluator(task.LabelConstraint_HOST)
+	for i := 0; i < 3*_reorderPeriod; i++ {
+		result, err := e.Evaluate(c, lv)
+		assert.NoError(t, err)
+		assert.Equal(t, EvaluateResultMatch, result)
+	}
+
+	// the zone child matches as often as the nested child, for half the
+	// cost, and the gpu child never matches
+	assert.Equal(t, []int{2, 1, 0}, o.stats[c].order)
+}
+
+// TestOptimizerMaxConstraints tests that the statistics are dropped once
+// too many constraints are evaluated.
+func TestOptimizerMaxConstraints(t *testing.T) {
+	o := NewOptimizer(2)
+	e := o.NewEvaluator(task.LabelConstraint_HOST)
+	for i := 0; i < 3; i++ {
+		_, err := e.Evaluate(&task.Constraint{
+			Type: task.Constraint_AND_CONSTRAINT,
+			AndConstraint: &task.AndConstraint{
+				Constraints: []*task.Constraint{
+					hostLabelConstraint("zone", "a"),
+				},
+			},
+		}, LabelValues{})
+		assert.NoError(t, err)
+	}
+	assert.Len(t, o.stats, 1)
+}
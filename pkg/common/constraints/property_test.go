@@ -0,0 +1,248 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_propertyIterations = 2000
+	_maxDepth           = 4
+	_maxChildren        = 4
+	_numKeys            = 3
+	_numValues          = 3
+	_maxCount           = 3
+)
+
+// generator produces random constraint trees and label values.
+type generator struct {
+	r *rand.Rand
+	// wellFormed restricts generation to constraint trees using only known
+	// enums, host label constraints and non-empty and/or constraints.
+	wellFormed bool
+}
+
+func (g *generator) key() string {
+	return fmt.Sprintf("key%d", g.r.Intn(_numKeys))
+}
+
+func (g *generator) value() string {
+	return fmt.Sprintf("value%d", g.r.Intn(_numValues))
+}
+
+func (g *generator) labelValues() LabelValues {
+	lv := make(LabelValues)
+	for i := g.r.Intn(_numKeys * _numValues); i > 0; i-- {
+		lv.AddLabel(g.key(), g.value())
+	}
+	return lv
+}
+
+func (g *generator) labelConstraint() *task.Constraint {
+	condition := task.LabelConstraint_Condition(g.r.Intn(3) + 1)
+	kind := task.LabelConstraint_HOST
+	// requirement is at least one so that LESS_THAN can be negated
+	requirement := uint32(g.r.Intn(_maxCount) + 1)
+	if !g.wellFormed {
+		condition = task.LabelConstraint_Condition(g.r.Intn(5) - 1)
+		kind = task.LabelConstraint_Kind(g.r.Intn(3))
+		requirement = uint32(g.r.Intn(_maxCount + 1))
+	}
+	return &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind:        kind,
+			Label:       &peloton.Label{Key: g.key(), Value: g.value()},
+			Condition:   condition,
+			Requirement: requirement,
+		},
+	}
+}
+
+func (g *generator) constraint(depth int) *task.Constraint {
+	if depth >= _maxDepth || g.r.Intn(3) == 0 {
+		if !g.wellFormed && g.r.Intn(10) == 0 {
+			return &task.Constraint{Type: task.Constraint_Type(g.r.Intn(6) - 1)}
+		}
+		return g.labelConstraint()
+	}
+
+	minChildren := 0
+	if g.wellFormed {
+		minChildren = 1
+	}
+	var children []*task.Constraint
+	for i := g.r.Intn(_maxChildren) + minChildren; i > 0; i-- {
+		children = append(children, g.constraint(depth+1))
+	}
+	if g.r.Intn(2) == 0 {
+		return &task.Constraint{
+			Type:          task.Constraint_AND_CONSTRAINT,
+			AndConstraint: &task.AndConstraint{Constraints: children},
+		}
+	}
+	return &task.Constraint{
+		Type:         task.Constraint_OR_CONSTRAINT,
+		OrConstraint: &task.OrConstraint{Constraints: children},
+	}
+}
+
+// negate returns the logical negation of a well formed constraint tree,
+// pushing the negation down to label constraints using De Morgan's laws.
+func negate(c *task.Constraint) *task.Constraint {
+	switch c.GetType() {
+	case task.Constraint_AND_CONSTRAINT:
+		return &task.Constraint{
+			Type: task.Constraint_OR_CONSTRAINT,
+			OrConstraint: &task.OrConstraint{
+				Constraints: negateAll(c.GetAndConstraint().GetConstraints()),
+			},
+		}
+	case task.Constraint_OR_CONSTRAINT:
+		return &task.Constraint{
+			Type: task.Constraint_AND_CONSTRAINT,
+			AndConstraint: &task.AndConstraint{
+				Constraints: negateAll(c.GetOrConstraint().GetConstraints()),
+			},
+		}
+	}
+
+	lc := c.GetLabelConstraint()
+	newLabel := func(
+		condition task.LabelConstraint_Condition,
+		requirement uint32) *task.Constraint {
+		return &task.Constraint{
+			Type: task.Constraint_LABEL_CONSTRAINT,
+			LabelConstraint: &task.LabelConstraint{
+				Kind:        lc.GetKind(),
+				Label:       lc.GetLabel(),
+				Condition:   condition,
+				Requirement: requirement,
+			},
+		}
+	}
+	switch lc.GetCondition() {
+	case task.LabelConstraint_CONDITION_LESS_THAN:
+		return newLabel(
+			task.LabelConstraint_CONDITION_GREATER_THAN,
+			lc.GetRequirement()-1)
+	case task.LabelConstraint_CONDITION_GREATER_THAN:
+		return newLabel(
+			task.LabelConstraint_CONDITION_LESS_THAN,
+			lc.GetRequirement()+1)
+	default:
+		return &task.Constraint{
+			Type: task.Constraint_OR_CONSTRAINT,
+			OrConstraint: &task.OrConstraint{
+				Constraints: []*task.Constraint{
+					newLabel(
+						task.LabelConstraint_CONDITION_LESS_THAN,
+						lc.GetRequirement()),
+					newLabel(
+						task.LabelConstraint_CONDITION_GREATER_THAN,
+						lc.GetRequirement()),
+				},
+			},
+		}
+	}
+}
+
+func negateAll(cs []*task.Constraint) []*task.Constraint {
+	result := make([]*task.Constraint, 0, len(cs))
+	for _, c := range cs {
+		result = append(result, negate(c))
+	}
+	return result
+}
+
+type PropertyTestSuite struct {
+	suite.Suite
+
+	seed int64
+}
+
+func (suite *PropertyTestSuite) SetupTest() {
+	suite.seed = time.Now().UnixNano()
+}
+
+func (suite *PropertyTestSuite) generator(wellFormed bool) *generator {
+	return &generator{
+		r:          rand.New(rand.NewSource(suite.seed)),
+		wellFormed: wellFormed,
+	}
+}
+
+// TestDeMorgan checks that evaluating the negation of a constraint always
+// yields the opposite result of evaluating the constraint itself.
+func (suite *PropertyTestSuite) TestDeMorgan() {
+	g := suite.generator(true)
+	e := NewEvaluator(task.LabelConstraint_HOST)
+
+	for i := 0; i < _propertyIterations; i++ {
+		c := g.constraint(0)
+		lv := g.labelValues()
+
+		result, err := e.Evaluate(c, lv)
+		suite.NoError(err)
+		negated, err := e.Evaluate(negate(c), lv)
+		suite.NoError(err)
+
+		suite.NotEqual(EvaluateResultNotApplicable, result)
+		suite.NotEqual(result, negated,
+			"seed %d: constraint %v on %v", suite.seed, c, lv)
+	}
+}
+
+// TestDeterminismAndNoPanics checks that evaluation of arbitrary, possibly
+// malformed, constraint trees never panics and is deterministic.
+func (suite *PropertyTestSuite) TestDeterminismAndNoPanics() {
+	g := suite.generator(false)
+	evaluators := []Evaluator{
+		NewEvaluator(task.LabelConstraint_HOST),
+		NewEvaluator(task.LabelConstraint_TASK),
+	}
+
+	for i := 0; i < _propertyIterations; i++ {
+		c := g.constraint(0)
+		lv := g.labelValues()
+
+		for _, e := range evaluators {
+			var result1, result2 EvaluateResult
+			var err1, err2 error
+			suite.NotPanics(func() {
+				result1, err1 = e.Evaluate(c, lv)
+				result2, err2 = e.Evaluate(c, lv)
+			}, "seed %d: constraint %v", suite.seed, c)
+			suite.Equal(result1, result2, "seed %d", suite.seed)
+			suite.Equal(err1, err2, "seed %d", suite.seed)
+			if err1 != nil {
+				suite.Equal(EvaluateResultNotApplicable, result1)
+			}
+		}
+	}
+}
+
+func TestPropertyTestSuite(t *testing.T) {
+	suite.Run(t, new(PropertyTestSuite))
+}
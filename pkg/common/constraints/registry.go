@@ -0,0 +1,145 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/pkg/errors"
+)
+
+// EvaluatorFunc is an Evaluator of a single function.
+type EvaluatorFunc func(
+	constraint *task.Constraint,
+	labelValues LabelValues,
+	at time.Time,
+) (EvaluateResult, error)
+
+// Evaluate evaluates the constraint at the current time.
+func (f EvaluatorFunc) Evaluate(
+	constraint *task.Constraint,
+	labelValues LabelValues) (EvaluateResult, error) {
+	return f(constraint, labelValues, time.Now())
+}
+
+// EvaluateAt evaluates the constraint at the given time.
+func (f EvaluatorFunc) EvaluateAt(
+	constraint *task.Constraint,
+	labelValues LabelValues,
+	at time.Time) (EvaluateResult, error) {
+	return f(constraint, labelValues, at)
+}
+
+// EvaluatorFactory returns the Evaluator of the constraints of a type, for
+// an evaluator of the label constraints of the given kind. The constraints
+// nested in the evaluated ones should be evaluated with parent, which
+// dispatches them to the evaluators of their type.
+type EvaluatorFactory func(
+	kind task.LabelConstraint_Kind,
+	parent Evaluator,
+) Evaluator
+
+// Registry maps the types of constraints to their evaluators, so that new
+// types of constraints can be evaluated without changing the evaluation of
+// the other types.
+type Registry struct {
+	sync.RWMutex
+
+	factories map[task.Constraint_Type]EvaluatorFactory
+}
+
+// DefaultRegistry is the registry of NewEvaluator, which has the built-in
+// types of constraints. Custom types of constraints should be registered
+// in it from the init function of their package.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a registry of the built-in types of constraints.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[task.Constraint_Type]EvaluatorFactory{
+			task.Constraint_AND_CONSTRAINT:         newAndEvaluator,
+			task.Constraint_OR_CONSTRAINT:          newOrEvaluator,
+			task.Constraint_LABEL_CONSTRAINT:       newLabelEvaluator,
+			task.Constraint_TIME_WINDOW_CONSTRAINT: newTimeWindowEvaluator,
+		},
+	}
+}
+
+// Register registers the evaluator factory of a type of constraints. It
+// fails if the type already has one, so that two packages cannot both
+// claim a type: errors.Cause of the error is ErrEvaluatorConflict.
+func (r *Registry) Register(
+	constraintType task.Constraint_Type,
+	factory EvaluatorFactory,
+) error {
+	if constraintType == task.Constraint_UNKNOWN_CONSTRAINT {
+		return errors.Errorf("cannot register an evaluator of %s",
+			constraintType)
+	}
+	if factory == nil {
+		return errors.Errorf("nil evaluator of %s", constraintType)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.factories[constraintType]; ok {
+		return errors.Wrapf(ErrEvaluatorConflict, "%s", constraintType)
+	}
+	r.factories[constraintType] = factory
+	return nil
+}
+
+// NewEvaluator returns an evaluator of the constraints of the registered
+// types, which filters out the label constraints of a different kind. The
+// types registered later are not evaluated by it.
+func (r *Registry) NewEvaluator(kind task.LabelConstraint_Kind) Evaluator {
+	return r.newEvaluator(kind, nil)
+}
+
+// newEvaluator returns an evaluator of the constraints of the registered
+// types, whose evaluators are replaced by the given ones, if any.
+func (r *Registry) newEvaluator(
+	kind task.LabelConstraint_Kind,
+	overrides map[task.Constraint_Type]EvaluatorFactory,
+) Evaluator {
+	r.RLock()
+	defer r.RUnlock()
+	e := &evaluator{
+		kind: kind,
+		factories: make(
+			map[task.Constraint_Type]EvaluatorFactory, len(r.factories)),
+		types: make(map[task.Constraint_Type]Evaluator, len(r.factories)),
+	}
+	for t, factory := range r.factories {
+		if override, ok := overrides[t]; ok {
+			factory = override
+		}
+		e.factories[t] = factory
+		e.types[t] = factory(kind, e)
+	}
+	return e
+}
+
+// Register registers the evaluator factory of a type of constraints in
+// DefaultRegistry.
+func Register(
+	constraintType task.Constraint_Type,
+	factory EvaluatorFactory,
+) error {
+	return DefaultRegistry.Register(constraintType, factory)
+}
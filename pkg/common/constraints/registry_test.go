@@ -0,0 +1,100 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// _gpuConstraint is a custom type of constraint, matching the hosts which
+// have a gpu label
+const _gpuConstraint = task.Constraint_Type(100)
+
+// newGPUEvaluator returns the evaluator of _gpuConstraint, which only
+// applies to host constraints
+func newGPUEvaluator(kind task.LabelConstraint_Kind, _ Evaluator) Evaluator {
+	return EvaluatorFunc(func(
+		_ *task.Constraint,
+		labelValues LabelValues,
+		_ time.Time,
+	) (EvaluateResult, error) {
+		if kind != task.LabelConstraint_HOST {
+			return EvaluateResultNotApplicable, nil
+		}
+		if len(labelValues["gpu"]) > 0 {
+			return EvaluateResultMatch, nil
+		}
+		return EvaluateResultMismatch, nil
+	})
+}
+
+// TestRegistryCustomEvaluator tests evaluating a custom type of constraint,
+// including nested in built-in types.
+func TestRegistryCustomEvaluator(t *testing.T) {
+	r := NewRegistry()
+	gpu := &task.Constraint{Type: _gpuConstraint}
+	lv := LabelValues{"gpu": {"nvidia": 1}}
+
+	// the custom type is unknown until registered
+	_, err := r.NewEvaluator(task.LabelConstraint_HOST).Evaluate(gpu, lv)
+	assert.Equal(t, ErrUnknownConstraintType, err)
+
+	assert.NoError(t, r.Register(_gpuConstraint, newGPUEvaluator))
+	e := r.NewEvaluator(task.LabelConstraint_HOST)
+
+	result, err := e.Evaluate(gpu, lv)
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMatch, result)
+
+	result, err = e.Evaluate(&task.Constraint{
+		Type: task.Constraint_AND_CONSTRAINT,
+		AndConstraint: &task.AndConstraint{
+			Constraints: []*task.Constraint{gpu},
+		},
+	}, LabelValues{})
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMismatch, result)
+
+	result, err = r.NewEvaluator(task.LabelConstraint_TASK).Evaluate(gpu, lv)
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultNotApplicable, result)
+
+	// the default registry is not changed
+	_, err = NewEvaluator(task.LabelConstraint_HOST).Evaluate(gpu, lv)
+	assert.Equal(t, ErrUnknownConstraintType, err)
+}
+
+// TestRegistryConflict tests that a type of constraint cannot have two
+// evaluators, and that invalid registrations are rejected.
+func TestRegistryConflict(t *testing.T) {
+	r := NewRegistry()
+
+	err := r.Register(task.Constraint_LABEL_CONSTRAINT, newGPUEvaluator)
+	assert.Equal(t, ErrEvaluatorConflict, errors.Cause(err))
+
+	assert.NoError(t, r.Register(_gpuConstraint, newGPUEvaluator))
+	err = r.Register(_gpuConstraint, newGPUEvaluator)
+	assert.Equal(t, ErrEvaluatorConflict, errors.Cause(err))
+
+	assert.Error(t, r.Register(
+		task.Constraint_UNKNOWN_CONSTRAINT, newGPUEvaluator))
+	assert.Error(t, r.Register(task.Constraint_Type(101), nil))
+}
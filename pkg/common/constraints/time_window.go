@@ -0,0 +1,58 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+const _secondsPerDay = 24 * 60 * 60
+
+// secondOfDay returns the number of seconds since midnight UTC for t.
+func secondOfDay(t time.Time) uint32 {
+	t = t.UTC()
+	return uint32(t.Hour()*3600 + t.Minute()*60 + t.Second())
+}
+
+// InTimeWindow returns true if the given time falls into the daily window.
+// A window whose end is before its start wraps around midnight, and a
+// window whose start equals its end covers the whole day.
+func InTimeWindow(window *task.TimeWindow, at time.Time) bool {
+	start := window.GetStartSecond() % _secondsPerDay
+	end := window.GetEndSecond() % _secondsPerDay
+	now := secondOfDay(at)
+
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return now >= start && now < end
+	default:
+		return now >= start || now < end
+	}
+}
+
+// InTimeWindows returns true if the given time falls into any of the
+// daily windows. An empty list of windows never matches.
+func InTimeWindows(windows []*task.TimeWindow, at time.Time) bool {
+	for _, w := range windows {
+		if InTimeWindow(w, at) {
+			return true
+		}
+	}
+	return false
+}
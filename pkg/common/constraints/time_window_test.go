@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package constraints
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func atUTC(hour, minute int) time.Time {
+	return time.Date(2019, 1, 1, hour, minute, 0, 0, time.UTC)
+}
+
+// TestInTimeWindow tests daily window matching including wrap-around.
+func TestInTimeWindow(t *testing.T) {
+	night := &task.TimeWindow{StartSecond: 0, EndSecond: 4 * 3600}
+	wrap := &task.TimeWindow{StartSecond: 22 * 3600, EndSecond: 2 * 3600}
+	allDay := &task.TimeWindow{StartSecond: 3600, EndSecond: 3600}
+
+	assert.True(t, InTimeWindow(night, atUTC(0, 0)))
+	assert.True(t, InTimeWindow(night, atUTC(3, 59)))
+	assert.False(t, InTimeWindow(night, atUTC(4, 0)))
+	assert.True(t, InTimeWindow(wrap, atUTC(23, 0)))
+	assert.True(t, InTimeWindow(wrap, atUTC(1, 0)))
+	assert.False(t, InTimeWindow(wrap, atUTC(12, 0)))
+	assert.True(t, InTimeWindow(allDay, atUTC(12, 0)))
+
+	assert.False(t, InTimeWindows(nil, atUTC(1, 0)))
+	assert.True(t, InTimeWindows(
+		[]*task.TimeWindow{wrap, night}, atUTC(3, 0)))
+}
+
+// TestEvaluateTimeWindowConstraint tests that time windowed constraints
+// only apply during their windows.
+func TestEvaluateTimeWindowConstraint(t *testing.T) {
+	// avoid hosts labeled backup=nightly between 00:00-04:00
+	c := &task.Constraint{
+		Type: task.Constraint_TIME_WINDOW_CONSTRAINT,
+		TimeWindowConstraint: &task.TimeWindowConstraint{
+			Windows: []*task.TimeWindow{
+				{StartSecond: 0, EndSecond: 4 * 3600},
+			},
+			Constraint: &task.Constraint{
+				Type: task.Constraint_LABEL_CONSTRAINT,
+				LabelConstraint: &task.LabelConstraint{
+					Kind: task.LabelConstraint_HOST,
+					Label: &peloton.Label{
+						Key:   "backup",
+						Value: "nightly",
+					},
+					Condition:   task.LabelConstraint_CONDITION_LESS_THAN,
+					Requirement: 1,
+				},
+			},
+		},
+	}
+	lv := LabelValues{"backup": {"nightly": 1}}
+	e := NewEvaluator(task.LabelConstraint_HOST)
+
+	result, err := e.EvaluateAt(c, lv, atUTC(1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMismatch, result)
+
+	result, err = e.EvaluateAt(c, lv, atUTC(5, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultNotApplicable, result)
+
+	result, err = e.EvaluateAt(c, LabelValues{}, atUTC(1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, EvaluateResultMatch, result)
+}
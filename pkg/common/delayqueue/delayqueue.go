@@ -0,0 +1,247 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delayqueue
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDequeueTimeout is returned by Dequeue when no item became ready
+// within the max wait time.
+var ErrDequeueTimeout = errors.New("dequeue max wait time expired")
+
+// Item is an item in a DelayQueue.
+type Item struct {
+	// Key identifies the item, enqueueing an item with a key which is
+	// already in the queue updates the pending item
+	Key string
+	// Value is the payload of the item
+	Value interface{}
+	// Priority of the item, ready items with a higher priority are
+	// dequeued first
+	Priority int
+	// NotBefore is the time before which the item is not dequeued
+	NotBefore time.Time
+}
+
+// DelayQueue is a priority queue of items which become ready to be
+// dequeued at their not-before time. It is safe for concurrent use by
+// multiple goroutines.
+type DelayQueue interface {
+	// Enqueue adds an item to the queue. If an item with the same key is
+	// already queued, its value is replaced, it keeps the earlier of the
+	// two not-before times and the higher of the two priorities, and
+	// false is returned.
+	Enqueue(item Item) bool
+	// Dequeue returns the highest priority ready item, waiting for up to
+	// maxWaitTime for an item to become ready. ErrDequeueTimeout is
+	// returned if no item is ready by then.
+	Dequeue(maxWaitTime time.Duration) (*Item, error)
+	// Remove removes the item with the given key, returning false if
+	// it is not in the queue
+	Remove(key string) bool
+	// Contains checks if an item with the given key is in the queue
+	Contains(key string) bool
+	// Len returns the number of items in the queue, ready or not
+	Len() int
+}
+
+// entry is the book-keeping of an item in the queue.
+type entry struct {
+	item Item
+	// seq is the order in which the key was first enqueued
+	seq uint64
+	// index of the entry in the heap it belongs to
+	index int
+	// ready is true if the entry is in the ready heap
+	ready bool
+}
+
+// delayQueue implements DelayQueue using two heaps: the delayed heap
+// ordered by not-before time, and the ready heap ordered by priority.
+// Entries are moved from the delayed heap to the ready heap once their
+// not-before time passes.
+type delayQueue struct {
+	sync.Mutex
+
+	entries map[string]*entry
+	delayed *itemHeap
+	ready   *itemHeap
+	seq     uint64
+
+	// changed is closed and replaced whenever an item is enqueued, to
+	// wake up waiting dequeuers
+	changed chan struct{}
+
+	metrics *Metrics
+	// now returns the current time, and is replaced in tests
+	now func() time.Time
+}
+
+// New creates a new DelayQueue.
+func New(metrics *Metrics) DelayQueue {
+	return newDelayQueue(metrics)
+}
+
+func newDelayQueue(metrics *Metrics) *delayQueue {
+	return &delayQueue{
+		entries: make(map[string]*entry),
+		delayed: &itemHeap{less: byNotBefore},
+		ready:   &itemHeap{less: byPriority},
+		changed: make(chan struct{}),
+		metrics: metrics,
+		now:     time.Now,
+	}
+}
+
+// Enqueue adds an item to the queue, or updates the queued item with
+// the same key.
+func (q *delayQueue) Enqueue(item Item) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	defer q.notify()
+
+	q.metrics.Enqueue.Inc(1)
+	if e, ok := q.entries[item.Key]; ok {
+		q.metrics.Dedupe.Inc(1)
+		e.item.Value = item.Value
+		if item.NotBefore.Before(e.item.NotBefore) {
+			e.item.NotBefore = item.NotBefore
+		}
+		if item.Priority > e.item.Priority {
+			e.item.Priority = item.Priority
+		}
+		heap.Fix(q.heapOf(e), e.index)
+		return false
+	}
+
+	q.seq++
+	e := &entry{item: item, seq: q.seq}
+	q.entries[item.Key] = e
+	heap.Push(q.delayed, e)
+	q.updateLength()
+	return true
+}
+
+// Dequeue returns the highest priority ready item, waiting for up to
+// maxWaitTime for an item to become ready.
+func (q *delayQueue) Dequeue(maxWaitTime time.Duration) (*Item, error) {
+	deadline := q.now().Add(maxWaitTime)
+	for {
+		q.Lock()
+		now := q.now()
+		q.promote(now)
+		if q.ready.Len() > 0 {
+			e := heap.Pop(q.ready).(*entry)
+			delete(q.entries, e.item.Key)
+			q.updateLength()
+			q.metrics.Dequeue.Inc(1)
+			q.metrics.DequeueDelay.Record(now.Sub(e.item.NotBefore))
+			q.Unlock()
+			return &e.item, nil
+		}
+
+		if !now.Before(deadline) {
+			q.Unlock()
+			return nil, ErrDequeueTimeout
+		}
+
+		// Wait until the next item becomes ready, an item is
+		// enqueued, or the max wait time expires.
+		wait := deadline.Sub(now)
+		if next := q.delayed.peek(); next != nil {
+			if d := next.item.NotBefore.Sub(now); d < wait {
+				wait = d
+			}
+		}
+		changed := q.changed
+		q.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		}
+	}
+}
+
+// Remove removes the item with the given key.
+func (q *delayQueue) Remove(key string) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	e, ok := q.entries[key]
+	if !ok {
+		return false
+	}
+	heap.Remove(q.heapOf(e), e.index)
+	delete(q.entries, key)
+	q.updateLength()
+	q.metrics.Remove.Inc(1)
+	return true
+}
+
+// Contains checks if an item with the given key is in the queue.
+func (q *delayQueue) Contains(key string) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	_, ok := q.entries[key]
+	return ok
+}
+
+// Len returns the number of items in the queue.
+func (q *delayQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+
+	return len(q.entries)
+}
+
+// promote moves all entries whose not-before time has passed from the
+// delayed heap to the ready heap. Must be called with the lock held.
+func (q *delayQueue) promote(now time.Time) {
+	for next := q.delayed.peek(); next != nil && !next.item.NotBefore.After(now); next = q.delayed.peek() {
+		e := heap.Pop(q.delayed).(*entry)
+		e.ready = true
+		heap.Push(q.ready, e)
+	}
+	q.metrics.ReadyLength.Update(float64(q.ready.Len()))
+}
+
+// heapOf returns the heap the entry belongs to.
+func (q *delayQueue) heapOf(e *entry) *itemHeap {
+	if e.ready {
+		return q.ready
+	}
+	return q.delayed
+}
+
+// notify wakes up all waiting dequeuers. Must be called with the lock held.
+func (q *delayQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// updateLength updates the length gauges. Must be called with the lock held.
+func (q *delayQueue) updateLength() {
+	q.metrics.Length.Update(float64(len(q.entries)))
+	q.metrics.ReadyLength.Update(float64(q.ready.Len()))
+}
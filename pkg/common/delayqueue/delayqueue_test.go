@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delayqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type DelayQueueTestSuite struct {
+	suite.Suite
+
+	now   time.Time
+	queue *delayQueue
+}
+
+func (suite *DelayQueueTestSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.queue = newDelayQueue(NewMetrics(tally.NoopScope))
+	suite.queue.now = func() time.Time { return suite.now }
+}
+
+func TestDelayQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(DelayQueueTestSuite))
+}
+
+// dequeueKeys dequeues all ready items without waiting and returns their keys
+func (suite *DelayQueueTestSuite) dequeueKeys() []string {
+	var keys []string
+	for {
+		item, err := suite.queue.Dequeue(0)
+		if err != nil {
+			suite.Equal(ErrDequeueTimeout, err)
+			return keys
+		}
+		keys = append(keys, item.Key)
+	}
+}
+
+// TestDequeueOrder tests that ready items are dequeued by priority, then
+// by not-before time, then by enqueue order
+func (suite *DelayQueueTestSuite) TestDequeueOrder() {
+	suite.True(suite.queue.Enqueue(Item{Key: "a", NotBefore: suite.now}))
+	suite.True(suite.queue.Enqueue(Item{Key: "b", NotBefore: suite.now.Add(-time.Second)}))
+	suite.True(suite.queue.Enqueue(Item{Key: "c", Priority: 1, NotBefore: suite.now}))
+	suite.True(suite.queue.Enqueue(Item{Key: "d", NotBefore: suite.now}))
+	suite.True(suite.queue.Enqueue(Item{Key: "e", Priority: 2, NotBefore: suite.now.Add(time.Minute)}))
+
+	suite.Equal(5, suite.queue.Len())
+	suite.Equal([]string{"c", "b", "a", "d"}, suite.dequeueKeys())
+	suite.Equal(1, suite.queue.Len())
+
+	suite.now = suite.now.Add(time.Minute)
+	suite.Equal([]string{"e"}, suite.dequeueKeys())
+	suite.Equal(0, suite.queue.Len())
+}
+
+// TestDedupe tests that enqueueing an existing key updates the queued item
+func (suite *DelayQueueTestSuite) TestDedupe() {
+	suite.True(suite.queue.Enqueue(Item{Key: "a", Value: 1, NotBefore: suite.now.Add(time.Minute)}))
+	suite.True(suite.queue.Enqueue(Item{Key: "b", Priority: 1, NotBefore: suite.now}))
+
+	// A later not-before time and lower priority do not override
+	suite.False(suite.queue.Enqueue(Item{Key: "a", Value: 2, Priority: -1, NotBefore: suite.now.Add(time.Hour)}))
+	suite.Equal(2, suite.queue.Len())
+	suite.Equal([]string{"b"}, suite.dequeueKeys())
+
+	// An earlier not-before time and higher priority do
+	suite.False(suite.queue.Enqueue(Item{Key: "a", Value: 3, Priority: 2, NotBefore: suite.now}))
+	suite.Equal(1, suite.queue.Len())
+
+	item, err := suite.queue.Dequeue(0)
+	suite.NoError(err)
+	suite.Equal(&Item{Key: "a", Value: 3, Priority: 2, NotBefore: suite.now}, item)
+}
+
+// TestDedupeReadyItem tests that updating a ready item re-orders it
+func (suite *DelayQueueTestSuite) TestDedupeReadyItem() {
+	suite.queue.Enqueue(Item{Key: "a", NotBefore: suite.now})
+	suite.queue.Enqueue(Item{Key: "b", Priority: 1, NotBefore: suite.now})
+	// promote both items to the ready heap
+	suite.queue.promote(suite.now)
+
+	suite.queue.Enqueue(Item{Key: "a", Priority: 2, NotBefore: suite.now})
+	suite.Equal([]string{"a", "b"}, suite.dequeueKeys())
+}
+
+// TestRemove tests removing delayed and ready items
+func (suite *DelayQueueTestSuite) TestRemove() {
+	suite.queue.Enqueue(Item{Key: "a", NotBefore: suite.now})
+	suite.queue.Enqueue(Item{Key: "b", NotBefore: suite.now.Add(time.Minute)})
+	suite.queue.Enqueue(Item{Key: "c", NotBefore: suite.now})
+	suite.queue.promote(suite.now)
+
+	suite.True(suite.queue.Contains("a"))
+	suite.True(suite.queue.Remove("a"))
+	suite.False(suite.queue.Contains("a"))
+	suite.False(suite.queue.Remove("a"))
+
+	suite.True(suite.queue.Remove("b"))
+	suite.False(suite.queue.Contains("b"))
+	suite.Equal(1, suite.queue.Len())
+
+	suite.Equal([]string{"c"}, suite.dequeueKeys())
+}
+
+// TestDequeueWaitsForNotBefore tests that Dequeue blocks until the
+// not-before time of the next item
+func (suite *DelayQueueTestSuite) TestDequeueWaitsForNotBefore() {
+	suite.queue.now = time.Now
+	suite.queue.Enqueue(Item{Key: "a", NotBefore: time.Now().Add(20 * time.Millisecond)})
+
+	start := time.Now()
+	item, err := suite.queue.Dequeue(time.Second)
+	suite.NoError(err)
+	suite.Equal("a", item.Key)
+	suite.True(time.Since(start) >= 20*time.Millisecond)
+}
+
+// TestDequeueWakesOnEnqueue tests that a blocked Dequeue returns an item
+// enqueued while it is waiting
+func (suite *DelayQueueTestSuite) TestDequeueWakesOnEnqueue() {
+	suite.queue.now = time.Now
+
+	result := make(chan *Item)
+	go func() {
+		item, _ := suite.queue.Dequeue(10 * time.Second)
+		result <- item
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	suite.queue.Enqueue(Item{Key: "a", NotBefore: time.Now()})
+
+	select {
+	case item := <-result:
+		suite.NotNil(item)
+		suite.Equal("a", item.Key)
+	case <-time.After(5 * time.Second):
+		suite.Fail("dequeue not woken up by enqueue")
+	}
+}
+
+// TestDequeueTimeout tests that Dequeue returns an error when no item
+// becomes ready within the max wait time
+func (suite *DelayQueueTestSuite) TestDequeueTimeout() {
+	suite.queue.now = time.Now
+	suite.queue.Enqueue(Item{Key: "a", NotBefore: time.Now().Add(time.Hour)})
+
+	item, err := suite.queue.Dequeue(10 * time.Millisecond)
+	suite.Nil(item)
+	suite.Equal(ErrDequeueTimeout, err)
+	suite.True(suite.queue.Contains("a"))
+}
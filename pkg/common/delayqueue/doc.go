@@ -0,0 +1,26 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package delayqueue implements a keyed priority queue with delayed items.
+
+Each item is enqueued with a dedupe key, a priority and a not-before time.
+An item becomes ready once its not-before time has passed, and Dequeue
+returns the ready item with the highest priority, breaking ties by the
+earliest not-before time and then by the order of enqueue. Enqueueing a
+key which is already in the queue updates the pending item instead of
+adding a duplicate, which lets retry and reconciliation loops schedule
+work for an entity without tracking timers of their own.
+*/
+package delayqueue
@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delayqueue
+
+// itemHeap is a heap of queue items implementing container/heap.Interface.
+// It must only be accessed through the container/heap functions.
+type itemHeap struct {
+	items []*entry
+	less  func(a, b *entry) bool
+}
+
+func (h *itemHeap) Len() int { return len(h.items) }
+
+func (h *itemHeap) Less(i, j int) bool {
+	return h.less(h.items[i], h.items[j])
+}
+
+func (h *itemHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(h.items)
+	h.items = append(h.items, e)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	n := len(h.items)
+	e := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	e.index = -1
+	return e
+}
+
+// peek returns the top of the heap, or nil if the heap is empty.
+func (h *itemHeap) peek() *entry {
+	if len(h.items) == 0 {
+		return nil
+	}
+	return h.items[0]
+}
+
+// byNotBefore orders entries by not-before time, then by enqueue order.
+func byNotBefore(a, b *entry) bool {
+	if !a.item.NotBefore.Equal(b.item.NotBefore) {
+		return a.item.NotBefore.Before(b.item.NotBefore)
+	}
+	return a.seq < b.seq
+}
+
+// byPriority orders entries by descending priority, then by not-before
+// time and enqueue order.
+func byPriority(a, b *entry) bool {
+	if a.item.Priority != b.item.Priority {
+		return a.item.Priority > b.item.Priority
+	}
+	return byNotBefore(a, b)
+}
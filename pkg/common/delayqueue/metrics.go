@@ -0,0 +1,47 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delayqueue
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics contains the counters and gauges of a DelayQueue
+type Metrics struct {
+	Length      tally.Gauge
+	ReadyLength tally.Gauge
+
+	Enqueue tally.Counter
+	Dedupe  tally.Counter
+	Dequeue tally.Counter
+	Remove  tally.Counter
+
+	DequeueDelay tally.Timer
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics
+// initialized and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	queueScope := scope.SubScope("delay_queue")
+	return &Metrics{
+		Length:       queueScope.Gauge("length"),
+		ReadyLength:  queueScope.Gauge("ready_length"),
+		Enqueue:      queueScope.Counter("enqueue"),
+		Dedupe:       queueScope.Counter("dedupe"),
+		Dequeue:      queueScope.Counter("dequeue"),
+		Remove:       queueScope.Counter("remove"),
+		DequeueDelay: queueScope.Timer("dequeue_delay"),
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expiringset
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/lifecycle"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// _defaultJanitorPeriod is the janitor period of a Config which does not
+// set a positive one.
+const _defaultJanitorPeriod = time.Minute
+
+// ExpireFunc is called with the key of each item removed by expiry.
+type ExpireFunc func(key string)
+
+// ExpiringSet is a set of strings in which each item expires after its own
+// time-to-live. Expired items are never returned, and are removed from
+// memory by a janitor goroutine running between Start and Stop.
+// It is safe for concurrent use by multiple goroutines.
+type ExpiringSet interface {
+	// Add adds 'key' to the set with the default TTL, refreshing the
+	// expiry of the key if it is already present
+	Add(key string)
+	// AddWithTTL adds 'key' to the set expiring after 'ttl', refreshing
+	// the expiry of the key if it is already present
+	AddWithTTL(key string, ttl time.Duration)
+	// Contains checks if the set contains an unexpired 'key'
+	Contains(key string) bool
+	// Remove removes 'key' from the set without calling the expire func
+	Remove(key string)
+	// Len returns the number of unexpired items in the set
+	Len() int
+	// ToSlice returns a slice containing all unexpired items in the set
+	ToSlice() []string
+	// Start starts the janitor which removes expired items
+	Start()
+	// Stop stops the janitor
+	Stop()
+}
+
+// Config is the configuration of an ExpiringSet.
+type Config struct {
+	// DefaultTTL is the TTL of items added with Add
+	DefaultTTL time.Duration
+	// JanitorPeriod is the period at which expired items are removed,
+	// one minute if not positive
+	JanitorPeriod time.Duration
+	// OnExpire is called for each item removed by the janitor. Optional.
+	OnExpire ExpireFunc
+}
+
+// expiringSet implements ExpiringSet
+type expiringSet struct {
+	sync.RWMutex
+
+	// map from key to its expiry time
+	items map[string]time.Time
+
+	config    Config
+	lifecycle lifecycle.LifeCycle
+	// now returns the current time, and is replaced in tests
+	now func() time.Time
+}
+
+// New creates a new ExpiringSet with the given config.
+func New(config Config) ExpiringSet {
+	if config.JanitorPeriod <= 0 {
+		config.JanitorPeriod = _defaultJanitorPeriod
+	}
+	return &expiringSet{
+		items:     make(map[string]time.Time),
+		config:    config,
+		lifecycle: lifecycle.NewLifeCycle(),
+		now:       time.Now,
+	}
+}
+
+// Add adds 'key' to the set with the default TTL
+func (s *expiringSet) Add(key string) {
+	s.AddWithTTL(key, s.config.DefaultTTL)
+}
+
+// AddWithTTL adds 'key' to the set expiring after 'ttl'
+func (s *expiringSet) AddWithTTL(key string, ttl time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.items[key] = s.now().Add(ttl)
+}
+
+// Contains checks if the set contains an unexpired 'key'
+func (s *expiringSet) Contains(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	expiry, ok := s.items[key]
+	return ok && s.now().Before(expiry)
+}
+
+// Remove removes 'key' from the set
+func (s *expiringSet) Remove(key string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.items, key)
+}
+
+// Len returns the number of unexpired items in the set
+func (s *expiringSet) Len() int {
+	return len(s.ToSlice())
+}
+
+// ToSlice returns a slice containing all unexpired items in the set
+func (s *expiringSet) ToSlice() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	now := s.now()
+	keys := make([]string, 0, len(s.items))
+	for k, expiry := range s.items {
+		if now.Before(expiry) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Start starts the janitor which removes expired items
+func (s *expiringSet) Start() {
+	if !s.lifecycle.Start() {
+		log.Warn("expiring set janitor is already started, no" +
+			" action will be performed")
+		return
+	}
+
+	go func() {
+		defer s.lifecycle.StopComplete()
+
+		ticker := time.NewTicker(s.config.JanitorPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.lifecycle.StopCh():
+				return
+			case <-ticker.C:
+				s.removeExpired()
+			}
+		}
+	}()
+}
+
+// Stop stops the janitor
+func (s *expiringSet) Stop() {
+	if !s.lifecycle.Stop() {
+		log.Warn("expiring set janitor is already stopped, no" +
+			" action will be performed")
+		return
+	}
+	s.lifecycle.Wait()
+}
+
+// removeExpired removes all expired items, and calls the expire func for
+// each of them after releasing the lock.
+func (s *expiringSet) removeExpired() {
+	var expired []string
+
+	s.Lock()
+	now := s.now()
+	for k, expiry := range s.items {
+		if !now.Before(expiry) {
+			delete(s.items, k)
+			expired = append(expired, k)
+		}
+	}
+	s.Unlock()
+
+	if s.config.OnExpire == nil {
+		return
+	}
+	for _, k := range expired {
+		s.config.OnExpire(k)
+	}
+}
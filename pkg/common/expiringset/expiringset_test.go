@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expiringset
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExpiringSetTestSuite struct {
+	suite.Suite
+
+	now time.Time
+	set *expiringSet
+
+	lock    sync.Mutex
+	expired []string
+}
+
+func (suite *ExpiringSetTestSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.expired = nil
+	suite.set = New(Config{
+		DefaultTTL:    time.Minute,
+		JanitorPeriod: time.Millisecond,
+		OnExpire: func(key string) {
+			suite.lock.Lock()
+			defer suite.lock.Unlock()
+			suite.expired = append(suite.expired, key)
+		},
+	}).(*expiringSet)
+	suite.set.now = func() time.Time { return suite.now }
+}
+
+func (suite *ExpiringSetTestSuite) TestAddContainsRemove() {
+	suite.set.Add("a")
+	suite.set.AddWithTTL("b", 2*time.Minute)
+	suite.True(suite.set.Contains("a"))
+	suite.True(suite.set.Contains("b"))
+	suite.Equal(2, suite.set.Len())
+
+	suite.set.Remove("a")
+	suite.False(suite.set.Contains("a"))
+	suite.Equal([]string{"b"}, suite.set.ToSlice())
+}
+
+func (suite *ExpiringSetTestSuite) TestPerItemTTL() {
+	suite.set.Add("a")
+	suite.set.AddWithTTL("b", 2*time.Minute)
+
+	suite.now = suite.now.Add(time.Minute)
+	suite.False(suite.set.Contains("a"))
+	suite.True(suite.set.Contains("b"))
+	suite.Equal([]string{"b"}, suite.set.ToSlice())
+
+	// Re-adding refreshes the expiry.
+	suite.set.Add("b")
+	suite.now = suite.now.Add(30 * time.Second)
+	suite.True(suite.set.Contains("b"))
+}
+
+func (suite *ExpiringSetTestSuite) TestRemoveExpired() {
+	suite.set.Add("a")
+	suite.set.Add("b")
+	suite.set.AddWithTTL("c", time.Hour)
+	suite.now = suite.now.Add(time.Minute)
+
+	suite.set.removeExpired()
+
+	sort.Strings(suite.expired)
+	suite.Equal([]string{"a", "b"}, suite.expired)
+	suite.Len(suite.set.items, 1)
+}
+
+func (suite *ExpiringSetTestSuite) TestJanitor() {
+	suite.set.AddWithTTL("a", -time.Second)
+
+	suite.set.Start()
+	// Starting twice is a no-op.
+	suite.set.Start()
+
+	for i := 0; i < 1000; i++ {
+		suite.lock.Lock()
+		done := len(suite.expired) == 1
+		suite.lock.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	suite.set.Stop()
+	suite.set.Stop()
+
+	suite.Equal([]string{"a"}, suite.expired)
+}
+
+// TestDefaultJanitorPeriod tests that a set without a janitor period
+// gets the default one, so that its janitor can start.
+func (suite *ExpiringSetTestSuite) TestDefaultJanitorPeriod() {
+	set := New(Config{DefaultTTL: time.Minute}).(*expiringSet)
+	suite.Equal(_defaultJanitorPeriod, set.config.JanitorPeriod)
+
+	set = New(Config{JanitorPeriod: -time.Second}).(*expiringSet)
+	suite.Equal(_defaultJanitorPeriod, set.config.JanitorPeriod)
+
+	set.Start()
+	set.Stop()
+}
+
+func TestExpiringSetTestSuite(t *testing.T) {
+	suite.Run(t, new(ExpiringSetTestSuite))
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LivenessPath is the endpoint of the liveness probe
+	LivenessPath = "/health/live"
+	// ReadinessPath is the endpoint of the readiness probe
+	ReadinessPath = "/health/ready"
+
+	// StatusHealthy is the status of a healthy component or probe
+	StatusHealthy = "healthy"
+	// StatusUnhealthy is the status of an unhealthy component or probe
+	StatusUnhealthy = "unhealthy"
+
+	_defaultCheckTimeout = 5 * time.Second
+)
+
+// Check verifies the health of a component. It returns a human readable
+// detail of the component state, and an error if the component is
+// unhealthy.
+type Check func(ctx context.Context) (string, error)
+
+// ComponentStatus is the result of the check of a component.
+type ComponentStatus struct {
+	Status  string `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+// Report is the result of a probe, healthy only if all of its components
+// are healthy.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Healthy returns true if all components of the report are healthy.
+func (r *Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+// Prober runs the named liveness and readiness checks of a daemon and
+// serves their reports over HTTP.
+type Prober struct {
+	sync.RWMutex
+
+	timeout   time.Duration
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+// NewProber returns a Prober whose checks are abandoned and reported
+// unhealthy after timeout.
+func NewProber(timeout time.Duration) *Prober {
+	if timeout <= 0 {
+		timeout = _defaultCheckTimeout
+	}
+	return &Prober{
+		timeout:   timeout,
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+	}
+}
+
+// AddLivenessCheck adds a check to the liveness probe.
+func (p *Prober) AddLivenessCheck(name string, check Check) {
+	p.Lock()
+	defer p.Unlock()
+	p.liveness[name] = check
+}
+
+// AddReadinessCheck adds a check to the readiness probe.
+func (p *Prober) AddReadinessCheck(name string, check Check) {
+	p.Lock()
+	defer p.Unlock()
+	p.readiness[name] = check
+}
+
+// Liveness runs the liveness checks.
+func (p *Prober) Liveness(ctx context.Context) *Report {
+	p.RLock()
+	defer p.RUnlock()
+	return p.run(ctx, p.liveness)
+}
+
+// Readiness runs the readiness checks.
+func (p *Prober) Readiness(ctx context.Context) *Report {
+	p.RLock()
+	defer p.RUnlock()
+	return p.run(ctx, p.readiness)
+}
+
+// LivenessHandler returns the HTTP handler of the liveness probe.
+func (p *Prober) LivenessHandler() func(http.ResponseWriter, *http.Request) {
+	return handler(p.Liveness)
+}
+
+// ReadinessHandler returns the HTTP handler of the readiness probe.
+func (p *Prober) ReadinessHandler() func(http.ResponseWriter, *http.Request) {
+	return handler(p.Readiness)
+}
+
+// run runs the given checks concurrently, each with the prober timeout.
+func (p *Prober) run(ctx context.Context, checks map[string]Check) *Report {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]ComponentStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			statuses[i] = p.runCheck(ctx, check)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:     StatusHealthy,
+		Components: make(map[string]ComponentStatus, len(names)),
+	}
+	for i, name := range names {
+		report.Components[name] = statuses[i]
+		if statuses[i].Status != StatusHealthy {
+			report.Status = StatusUnhealthy
+		}
+	}
+	return report
+}
+
+// runCheck runs a check, failing it if it does not return within the
+// prober timeout.
+func (p *Prober) runCheck(ctx context.Context, check Check) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	type result struct {
+		detail string
+		err    error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		detail, err := check(ctx)
+		done <- result{detail: detail, err: err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		r.err = ctx.Err()
+	}
+
+	status := ComponentStatus{
+		Status:  StatusHealthy,
+		Detail:  r.detail,
+		Latency: time.Since(start).String(),
+	}
+	if r.err != nil {
+		status.Status = StatusUnhealthy
+		status.Error = r.err.Error()
+	}
+	return status
+}
+
+func handler(
+	probe func(context.Context) *Report,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+
+		code := http.StatusOK
+		if !report.Healthy() {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.WithError(err).Warn("Failed to write health report")
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProberTestSuite struct {
+	suite.Suite
+
+	prober *Prober
+}
+
+func (suite *ProberTestSuite) SetupTest() {
+	suite.prober = NewProber(50 * time.Millisecond)
+}
+
+func TestProber(t *testing.T) {
+	suite.Run(t, new(ProberTestSuite))
+}
+
+func healthyCheck(detail string) Check {
+	return func(context.Context) (string, error) {
+		return detail, nil
+	}
+}
+
+func (suite *ProberTestSuite) serve(
+	h func(http.ResponseWriter, *http.Request),
+	path string,
+) (int, *Report) {
+	req := httptest.NewRequest("GET", "http://example.com"+path, nil)
+	w := httptest.NewRecorder()
+	h(w, req)
+
+	resp := w.Result()
+	suite.Equal("application/json", resp.Header.Get("Content-Type"))
+	report := &Report{}
+	suite.NoError(json.NewDecoder(resp.Body).Decode(report))
+	return resp.StatusCode, report
+}
+
+// TestReadinessHealthy tests that readiness is healthy when all checks pass
+func (suite *ProberTestSuite) TestReadinessHealthy() {
+	suite.prober.AddReadinessCheck("mesos", healthyCheck("connected"))
+	suite.prober.AddReadinessCheck("storage", healthyCheck(""))
+
+	code, report := suite.serve(
+		suite.prober.ReadinessHandler(), ReadinessPath)
+	suite.Equal(http.StatusOK, code)
+	suite.Equal(StatusHealthy, report.Status)
+	suite.Len(report.Components, 2)
+	suite.Equal(StatusHealthy, report.Components["mesos"].Status)
+	suite.Equal("connected", report.Components["mesos"].Detail)
+	suite.NotEmpty(report.Components["storage"].Latency)
+}
+
+// TestReadinessUnhealthy tests that a failed check fails readiness and
+// is reported with its error
+func (suite *ProberTestSuite) TestReadinessUnhealthy() {
+	suite.prober.AddReadinessCheck("mesos", healthyCheck(""))
+	suite.prober.AddReadinessCheck(
+		"storage",
+		func(context.Context) (string, error) {
+			return "", errors.New("no hosts available")
+		})
+
+	code, report := suite.serve(
+		suite.prober.ReadinessHandler(), ReadinessPath)
+	suite.Equal(http.StatusServiceUnavailable, code)
+	suite.Equal(StatusUnhealthy, report.Status)
+	suite.Equal(StatusHealthy, report.Components["mesos"].Status)
+	suite.Equal(StatusUnhealthy, report.Components["storage"].Status)
+	suite.Equal("no hosts available", report.Components["storage"].Error)
+}
+
+// TestCheckTimeout tests that a check not returning within the timeout
+// is reported unhealthy
+func (suite *ProberTestSuite) TestCheckTimeout() {
+	block := make(chan struct{})
+	defer close(block)
+	suite.prober.AddReadinessCheck(
+		"storage",
+		func(context.Context) (string, error) {
+			<-block
+			return "", nil
+		})
+
+	report := suite.prober.Readiness(context.Background())
+	suite.False(report.Healthy())
+	suite.Equal(
+		context.DeadlineExceeded.Error(),
+		report.Components["storage"].Error)
+}
+
+// TestLivenessSeparateFromReadiness tests that readiness checks do not
+// affect liveness
+func (suite *ProberTestSuite) TestLivenessSeparateFromReadiness() {
+	suite.prober.AddReadinessCheck(
+		"storage",
+		func(context.Context) (string, error) {
+			return "", errors.New("unreachable")
+		})
+
+	code, report := suite.serve(
+		suite.prober.LivenessHandler(), LivenessPath)
+	suite.Equal(http.StatusOK, code)
+	suite.True(report.Healthy())
+	suite.Empty(report.Components)
+
+	suite.prober.AddLivenessCheck("process", healthyCheck("running"))
+	report = suite.prober.Liveness(context.Background())
+	suite.True(report.Healthy())
+	suite.Equal("running", report.Components["process"].Detail)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"context"
+
+	"github.com/uber/peloton/pkg/common/audit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// HostsField is the log field of the hostnames involved in an
+	// operation.
+	HostsField = "hosts"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of the context carrying the log fields in
+// addition to the log fields already carried by the context. A field
+// already carried is overwritten.
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	carried, _ := ctx.Value(fieldsKey{}).(log.Fields)
+	merged := make(log.Fields, len(carried)+len(fields))
+	for k, v := range carried {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithHosts returns a copy of the context carrying the hostnames
+// involved in an operation as log field.
+func WithHosts(ctx context.Context, hostnames []string) context.Context {
+	return WithFields(ctx, log.Fields{HostsField: hostnames})
+}
+
+// FromContext returns a log entry with the fields of the request info
+// and the log fields carried by the context, so that all the logs of an
+// operation can be correlated.
+func FromContext(ctx context.Context) *log.Entry {
+	entry := audit.Logger(ctx)
+	if fields, ok := ctx.Value(fieldsKey{}).(log.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	return entry
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uber/peloton/pkg/common/audit"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx).Data)
+
+	ctx = audit.WithInfo(ctx, audit.Info{
+		Caller:    "peloton-cli",
+		User:      "user1",
+		RequestID: "request1",
+	})
+	ctx = WithHosts(ctx, []string{"host1"})
+	ctx = WithFields(ctx, log.Fields{"policy": "policy1"})
+
+	fields := FromContext(ctx).Data
+	assert.Equal(t, "peloton-cli", fields["caller"])
+	assert.Equal(t, "user1", fields["user"])
+	assert.Equal(t, "request1", fields["request_id"])
+	assert.Equal(t, []string{"host1"}, fields[HostsField])
+	assert.Equal(t, "policy1", fields["policy"])
+}
+
+func TestWithFieldsOverwrite(t *testing.T) {
+	parent := WithHosts(context.Background(), []string{"host1"})
+	child := WithHosts(parent, []string{"host2"})
+
+	assert.Equal(t, []string{"host2"}, FromContext(child).Data[HostsField])
+	// The fields carried by the parent context are not changed
+	assert.Equal(t, []string{"host1"}, FromContext(parent).Data[HostsField])
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lru
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// errLoadPanicked is returned to the callers waiting for a load which
+// panicked.
+var errLoadPanicked = errors.New("load of the key panicked")
+
+// LoadFunc loads the value of a key which is missing from the cache.
+type LoadFunc func(key string) (interface{}, error)
+
+// Cache is a size bounded cache of values by string key, which evicts its
+// least recently used entries when it is full, and in which each entry
+// expires after its own time-to-live. Expired entries are never returned,
+// and are removed when they are looked up or evicted.
+// It is safe for concurrent use by multiple goroutines.
+type Cache interface {
+	// Get returns the value of 'key' if it is cached and not expired,
+	// and marks it as the most recently used
+	Get(key string) (interface{}, bool)
+	// GetOrLoad returns the value of 'key', calling 'load' and caching
+	// its result with the default TTL if the key is missing or expired.
+	// Concurrent loads of the same key are deduplicated: 'load' is called
+	// once and its result is returned to every caller. Errors are not
+	// cached. If 'load' panics, the panic propagates to the caller which
+	// called it and the other callers get an error.
+	GetOrLoad(key string, load LoadFunc) (interface{}, error)
+	// Add caches the value of 'key' with the default TTL, replacing the
+	// current value of the key
+	Add(key string, value interface{})
+	// AddWithTTL caches the value of 'key' expiring after 'ttl', replacing
+	// the current value of the key. The value never expires if 'ttl' is
+	// zero.
+	AddWithTTL(key string, value interface{}, ttl time.Duration)
+	// Remove removes 'key' from the cache
+	Remove(key string)
+	// Retain removes the entries whose key is not accepted by 'keep'
+	Retain(keep func(key string) bool)
+	// Len returns the number of entries in the cache, including the
+	// expired entries which were not removed yet
+	Len() int
+}
+
+// Config is the configuration of a Cache.
+type Config struct {
+	// MaxSize is the max number of entries in the cache, beyond which the
+	// least recently used entries are evicted. Unbounded if zero.
+	MaxSize int
+	// TTL is the time-to-live of the entries added with Add and
+	// GetOrLoad. The entries never expire if zero.
+	TTL time.Duration
+}
+
+// metrics are the metrics of a Cache.
+type metrics struct {
+	hits        tally.Counter
+	misses      tally.Counter
+	evictions   tally.Counter
+	expirations tally.Counter
+	loads       tally.Counter
+	loadErrors  tally.Counter
+	size        tally.Gauge
+}
+
+func newMetrics(scope tally.Scope) *metrics {
+	return &metrics{
+		hits:        scope.Counter("hits"),
+		misses:      scope.Counter("misses"),
+		evictions:   scope.Counter("evictions"),
+		expirations: scope.Counter("expirations"),
+		loads:       scope.Counter("loads"),
+		loadErrors:  scope.Counter("load_errors"),
+		size:        scope.Gauge("size"),
+	}
+}
+
+// entry is a cached value, stored in the recency list of the cache.
+type entry struct {
+	key   string
+	value interface{}
+	// time after which the entry is expired, never if zero
+	expiry time.Time
+}
+
+// load is a load of a key in flight, which concurrent loads of the same
+// key wait for.
+type load struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cache implements Cache
+type cache struct {
+	sync.Mutex
+
+	config  Config
+	metrics *metrics
+
+	// list of entries from the most to the least recently used
+	recency *list.List
+	// map from key to its element in the recency list
+	items map[string]*list.Element
+	// map from key to its load in flight
+	loads map[string]*load
+
+	// now returns the current time, and is replaced in tests
+	now func() time.Time
+}
+
+// New creates a new Cache with the given config, reporting its metrics
+// into the given scope.
+func New(config Config, scope tally.Scope) Cache {
+	return &cache{
+		config:  config,
+		metrics: newMetrics(scope),
+		recency: list.New(),
+		items:   make(map[string]*list.Element),
+		loads:   make(map[string]*load),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value of 'key' if it is cached and not expired
+func (c *cache) Get(key string) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.get(key)
+}
+
+// get looks up 'key', removing it if it expired. It must be called with
+// the lock held.
+func (c *cache) get(key string) (interface{}, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		c.metrics.misses.Inc(1)
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !e.expiry.IsZero() && !c.now().Before(e.expiry) {
+		c.removeElement(elem)
+		c.metrics.expirations.Inc(1)
+		c.metrics.misses.Inc(1)
+		return nil, false
+	}
+	c.recency.MoveToFront(elem)
+	c.metrics.hits.Inc(1)
+	return e.value, true
+}
+
+// GetOrLoad returns the value of 'key', loading it if it is not cached
+func (c *cache) GetOrLoad(key string, loadFunc LoadFunc) (interface{}, error) {
+	c.Lock()
+	if value, ok := c.get(key); ok {
+		c.Unlock()
+		return value, nil
+	}
+	if l, ok := c.loads[key]; ok {
+		// another caller is loading the key, wait for its result
+		c.Unlock()
+		<-l.done
+		return l.value, l.err
+	}
+	// the error stays set only if loadFunc panics
+	l := &load{done: make(chan struct{}), err: errLoadPanicked}
+	c.loads[key] = l
+	c.Unlock()
+
+	// finish the load even if loadFunc panics, so that the callers
+	// waiting for it do not block forever
+	defer c.finishLoad(key, l)
+
+	value, err := loadFunc(key)
+	l.value, l.err = value, err
+	return value, err
+}
+
+// finishLoad caches the result of a load of 'key' if it succeeded, and
+// releases the callers waiting for it.
+func (c *cache) finishLoad(key string, l *load) {
+	c.metrics.loads.Inc(1)
+
+	c.Lock()
+	delete(c.loads, key)
+	if l.err == nil {
+		c.add(key, l.value, c.config.TTL)
+	} else {
+		c.metrics.loadErrors.Inc(1)
+	}
+	c.Unlock()
+	close(l.done)
+}
+
+// Add caches the value of 'key' with the default TTL
+func (c *cache) Add(key string, value interface{}) {
+	c.AddWithTTL(key, value, c.config.TTL)
+}
+
+// AddWithTTL caches the value of 'key' expiring after 'ttl'
+func (c *cache) AddWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.add(key, value, ttl)
+}
+
+// add caches the value of 'key', and evicts the least recently used
+// entries beyond the max size. It must be called with the lock held.
+func (c *cache) add(key string, value interface{}, ttl time.Duration) {
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expiry = expiry
+		c.recency.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.recency.PushFront(
+		&entry{key: key, value: value, expiry: expiry})
+	for c.config.MaxSize > 0 && c.recency.Len() > c.config.MaxSize {
+		c.removeElement(c.recency.Back())
+		c.metrics.evictions.Inc(1)
+	}
+	c.metrics.size.Update(float64(c.recency.Len()))
+}
+
+// Remove removes 'key' from the cache
+func (c *cache) Remove(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Retain removes the entries whose key is not accepted by 'keep'
+func (c *cache) Retain(keep func(key string) bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, elem := range c.items {
+		if !keep(key) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// Len returns the number of entries in the cache
+func (c *cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.recency.Len()
+}
+
+// removeElement removes an entry from the cache. It must be called with
+// the lock held.
+func (c *cache) removeElement(elem *list.Element) {
+	c.recency.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+	c.metrics.size.Update(float64(c.recency.Len()))
+}
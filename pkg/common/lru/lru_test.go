@@ -0,0 +1,224 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lru
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type LRUTestSuite struct {
+	suite.Suite
+
+	now   time.Time
+	scope tally.TestScope
+	cache *cache
+}
+
+func (suite *LRUTestSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.scope = tally.NewTestScope("", map[string]string{})
+	suite.cache = New(Config{
+		MaxSize: 3,
+		TTL:     time.Minute,
+	}, suite.scope).(*cache)
+	suite.cache.now = func() time.Time { return suite.now }
+}
+
+func TestLRUTestSuite(t *testing.T) {
+	suite.Run(t, new(LRUTestSuite))
+}
+
+// counter returns the value of a counter of the cache
+func (suite *LRUTestSuite) counter(name string) int64 {
+	c, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return c.Value()
+}
+
+func (suite *LRUTestSuite) TestAddGetRemove() {
+	suite.cache.Add("a", 1)
+	suite.cache.Add("b", 2)
+
+	value, ok := suite.cache.Get("a")
+	suite.True(ok)
+	suite.Equal(1, value)
+	_, ok = suite.cache.Get("c")
+	suite.False(ok)
+
+	suite.cache.Add("a", 3)
+	value, _ = suite.cache.Get("a")
+	suite.Equal(3, value)
+	suite.Equal(2, suite.cache.Len())
+
+	suite.cache.Remove("a")
+	_, ok = suite.cache.Get("a")
+	suite.False(ok)
+	suite.Equal(1, suite.cache.Len())
+
+	suite.Equal(int64(2), suite.counter("hits"))
+	suite.Equal(int64(2), suite.counter("misses"))
+}
+
+func (suite *LRUTestSuite) TestEvictLeastRecentlyUsed() {
+	suite.cache.Add("a", 1)
+	suite.cache.Add("b", 2)
+	suite.cache.Add("c", 3)
+
+	// "a" becomes the most recently used, so "b" is evicted
+	suite.cache.Get("a")
+	suite.cache.Add("d", 4)
+
+	_, ok := suite.cache.Get("b")
+	suite.False(ok)
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok := suite.cache.Get(key)
+		suite.True(ok, key)
+	}
+	suite.Equal(3, suite.cache.Len())
+	suite.Equal(int64(1), suite.counter("evictions"))
+	suite.Equal(
+		float64(3), suite.scope.Snapshot().Gauges()["size+"].Value())
+}
+
+func (suite *LRUTestSuite) TestExpiry() {
+	suite.cache.Add("a", 1)
+	suite.cache.AddWithTTL("b", 2, 2*time.Minute)
+	suite.cache.AddWithTTL("c", 3, 0)
+
+	suite.now = suite.now.Add(time.Minute)
+	_, ok := suite.cache.Get("a")
+	suite.False(ok)
+	_, ok = suite.cache.Get("b")
+	suite.True(ok)
+
+	suite.now = suite.now.Add(time.Hour)
+	_, ok = suite.cache.Get("b")
+	suite.False(ok)
+	_, ok = suite.cache.Get("c")
+	suite.True(ok)
+
+	suite.Equal(1, suite.cache.Len())
+	suite.Equal(int64(2), suite.counter("expirations"))
+}
+
+func (suite *LRUTestSuite) TestRetain() {
+	suite.cache.Add("a", 1)
+	suite.cache.Add("b", 2)
+	suite.cache.Add("c", 3)
+
+	suite.cache.Retain(func(key string) bool { return key != "b" })
+	suite.Equal(2, suite.cache.Len())
+	_, ok := suite.cache.Get("b")
+	suite.False(ok)
+}
+
+func (suite *LRUTestSuite) TestGetOrLoad() {
+	value, err := suite.cache.GetOrLoad("a", func(key string) (interface{}, error) {
+		return key + "-value", nil
+	})
+	suite.NoError(err)
+	suite.Equal("a-value", value)
+
+	// the loaded value is cached
+	value, err = suite.cache.GetOrLoad("a", func(key string) (interface{}, error) {
+		suite.Fail("cached value loaded again")
+		return nil, nil
+	})
+	suite.NoError(err)
+	suite.Equal("a-value", value)
+
+	// errors are not cached
+	_, err = suite.cache.GetOrLoad("b", func(key string) (interface{}, error) {
+		return nil, errors.New("load failed")
+	})
+	suite.Error(err)
+	suite.Equal(1, suite.cache.Len())
+
+	suite.Equal(int64(2), suite.counter("loads"))
+	suite.Equal(int64(1), suite.counter("load_errors"))
+}
+
+func (suite *LRUTestSuite) TestGetOrLoadDeduplicated() {
+	var calls int32
+	release := make(chan struct{})
+	load := func(key string) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return key + "-value", nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]interface{}, 5)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := suite.cache.GetOrLoad("a", load)
+			suite.NoError(err)
+			values[i] = value
+		}(i)
+	}
+
+	// wait for the first load to start before releasing it
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	suite.Equal(int32(1), atomic.LoadInt32(&calls))
+	for _, value := range values {
+		suite.Equal("a-value", value)
+	}
+}
+
+// TestGetOrLoadPanic tests that a panicking load releases the callers
+// waiting for it with an error, and does not block the next loads.
+func (suite *LRUTestSuite) TestGetOrLoadPanic() {
+	var inFlight *load
+	suite.Panics(func() {
+		suite.cache.GetOrLoad("a", func(key string) (interface{}, error) {
+			suite.cache.Lock()
+			inFlight = suite.cache.loads[key]
+			suite.cache.Unlock()
+			panic("load panicked")
+		})
+	})
+
+	// the waiters of the load are released with an error
+	suite.NotNil(inFlight)
+	<-inFlight.done
+	suite.Equal(errLoadPanicked, inFlight.err)
+	suite.Empty(suite.cache.loads)
+	suite.Equal(0, suite.cache.Len())
+	suite.Equal(int64(1), suite.counter("load_errors"))
+
+	// the key can be loaded again
+	value, err := suite.cache.GetOrLoad("a", func(key string) (interface{}, error) {
+		return key + "-value", nil
+	})
+	suite.NoError(err)
+	suite.Equal("a-value", value)
+}
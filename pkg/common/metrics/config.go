@@ -49,7 +49,7 @@ const (
 // Config will be containing the metrics configuration
 type Config struct {
 	MultiReporter  bool                   `yaml:"multi_reporter"`
-	Prometheus     *prometheusConfig      `yaml:"prometheus"`
+	Prometheus     *PrometheusConfig      `yaml:"prometheus"`
 	Statsd         *statsdConfig          `yaml:"statsd"`
 	M3             *tallym3.Configuration `yaml:"m3"`
 	RuntimeMetrics *runtimeConfig         `yaml:"runtime_metrics"`
@@ -61,10 +61,6 @@ type runtimeConfig struct {
 	CollectInterval time.Duration `yaml:"interval"`
 }
 
-type prometheusConfig struct {
-	Enable bool `yaml:"enable"`
-}
-
 type statsdConfig struct {
 	Enable   bool   `yaml:"enable"`
 	Endpoint string `yaml:"endpoint"`
@@ -92,12 +88,11 @@ func InitMetricScope(
 		metricSeparator = "_"
 
 		if cfg.Prometheus != nil && cfg.Prometheus.Enable {
-			promReporter = tallyprom.NewReporter(tallyprom.Options{})
-			promHandler := promReporter.HTTPHandler()
-
-			// if prometheus support is enabled, handle /metrics to serve prom metrics
-			log.Infof("Setting up prometheus metrics handler at /metrics")
-			mux.Handle("/metrics", promHandler)
+			r, err := newPrometheusReporter(cfg.Prometheus, mux)
+			if err != nil {
+				log.Fatalf("Failed to create prometheus reporter: %v", err)
+			}
+			promReporter = r
 		}
 
 		loadM3Configs(cfg.M3)
@@ -129,6 +124,10 @@ func InitMetricScope(
 			},
 			metricFlushInterval)
 	} else {
+		if cfg.Prometheus != nil && cfg.Prometheus.Enable {
+			log.Warn("Prometheus requires multi_reporter, not serving prometheus metrics")
+		}
+
 		// To use statsd, MultiReporter should be turned off
 		if cfg.Statsd != nil && cfg.Statsd.Enable {
 			log.Infof("Metrics configured with statsd endpoint %s", cfg.Statsd.Endpoint)
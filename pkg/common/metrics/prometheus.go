@@ -0,0 +1,110 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	nethttp "net/http"
+
+	log "github.com/sirupsen/logrus"
+	tallyprom "github.com/uber-go/tally/prometheus"
+)
+
+const (
+	// _defaultPrometheusHandlerPath is the path the prometheus metrics
+	// are served at if not configured
+	_defaultPrometheusHandlerPath = "/metrics"
+
+	_prometheusSummaryTimer   = "summary"
+	_prometheusHistogramTimer = "histogram"
+)
+
+// PrometheusConfig contains configuration for the prometheus reporter.
+// Prometheus requires multi_reporter to be enabled.
+type PrometheusConfig struct {
+	Enable bool `yaml:"enable"`
+
+	// Path of the scrape endpoint, /metrics by default
+	HandlerPath string `yaml:"handler_path"`
+
+	// Address, e.g. ":9100", of a dedicated HTTP server serving the scrape
+	// endpoint. The endpoint is served on the HTTP port of the component
+	// if not set.
+	ListenAddress string `yaml:"listen_address"`
+
+	// Prometheus type of the tally timers, such as API latencies.
+	// Either summary (default) or histogram.
+	TimerType string `yaml:"timer_type"`
+
+	// Upper bounds, in seconds, of the buckets of histogram timers.
+	// The prometheus default buckets are used if not set.
+	HistogramBuckets []float64 `yaml:"histogram_buckets"`
+}
+
+// options returns the options of the prometheus reporter.
+func (c *PrometheusConfig) options() (tallyprom.Options, error) {
+	opts := tallyprom.Options{
+		DefaultHistogramBuckets: c.HistogramBuckets,
+	}
+
+	switch c.TimerType {
+	case "", _prometheusSummaryTimer:
+		opts.DefaultTimerType = tallyprom.SummaryTimerType
+	case _prometheusHistogramTimer:
+		opts.DefaultTimerType = tallyprom.HistogramTimerType
+	default:
+		return opts, fmt.Errorf("unknown prometheus timer type %q", c.TimerType)
+	}
+	return opts, nil
+}
+
+// handlerPath returns the path of the scrape endpoint.
+func (c *PrometheusConfig) handlerPath() string {
+	if c.HandlerPath == "" {
+		return _defaultPrometheusHandlerPath
+	}
+	return c.HandlerPath
+}
+
+// newPrometheusReporter creates a prometheus reporter, and serves its scrape
+// endpoint either on the given mux or on a dedicated HTTP server.
+func newPrometheusReporter(
+	cfg *PrometheusConfig,
+	mux *nethttp.ServeMux) (tallyprom.Reporter, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	reporter := tallyprom.NewReporter(opts)
+
+	path := cfg.handlerPath()
+	if cfg.ListenAddress == "" {
+		log.Infof("Setting up prometheus metrics handler at %s", path)
+		mux.Handle(path, reporter.HTTPHandler())
+		return reporter, nil
+	}
+
+	promMux := nethttp.NewServeMux()
+	promMux.Handle(path, reporter.HTTPHandler())
+	log.Infof("Setting up prometheus metrics handler at %s%s",
+		cfg.ListenAddress, path)
+	go func() {
+		err := nethttp.ListenAndServe(cfg.ListenAddress, promMux)
+		log.WithError(err).
+			WithField("listen_address", cfg.ListenAddress).
+			Fatal("Prometheus metrics server failed")
+	}()
+	return reporter, nil
+}
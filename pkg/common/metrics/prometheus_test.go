@@ -0,0 +1,45 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tallyprom "github.com/uber-go/tally/prometheus"
+)
+
+func TestPrometheusConfigOptions(t *testing.T) {
+	c := &PrometheusConfig{}
+	opts, err := c.options()
+	assert.NoError(t, err)
+	assert.Equal(t, tallyprom.SummaryTimerType, opts.DefaultTimerType)
+	assert.Equal(t, "/metrics", c.handlerPath())
+
+	c = &PrometheusConfig{
+		HandlerPath:      "/prometheus",
+		TimerType:        "histogram",
+		HistogramBuckets: []float64{0.01, 0.1, 1},
+	}
+	opts, err = c.options()
+	assert.NoError(t, err)
+	assert.Equal(t, tallyprom.HistogramTimerType, opts.DefaultTimerType)
+	assert.Equal(t, []float64{0.01, 0.1, 1}, opts.DefaultHistogramBuckets)
+	assert.Equal(t, "/prometheus", c.handlerPath())
+
+	c = &PrometheusConfig{TimerType: "gauge"}
+	_, err = c.options()
+	assert.Error(t, err)
+}
@@ -27,6 +27,8 @@ var (
 	_jobKey      = "job"
 	_instanceKey = "instance"
 	_hostnameKey = "hostname"
+
+	_reservationIDKey = "reservation_id"
 )
 
 // CreateReservationLabels creates reservation labels for stateful task.
@@ -50,6 +52,19 @@ func CreateReservationLabels(
 	}
 }
 
+// CreateHostReservationLabels creates reservation labels for a dynamic
+// reservation which is not bound to a stateful task.
+func CreateHostReservationLabels(reservationID string) *mesos.Labels {
+	return &mesos.Labels{
+		Labels: []*mesos.Label{
+			{
+				Key:   &_reservationIDKey,
+				Value: util.PtrPrintf(reservationID),
+			},
+		},
+	}
+}
+
 // ParseReservationLabels parses jobid and instanceid from given reservation labels.
 func ParseReservationLabels(labels *mesos.Labels) (string, uint32, error) {
 	var jobID string
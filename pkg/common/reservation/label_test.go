@@ -53,6 +53,17 @@ func (suite *LabelTestSuite) TestCreateParseReservationLabel() {
 	suite.Equal(instanceID, uint32(_testInstance))
 }
 
+func (suite *LabelTestSuite) TestCreateHostReservationLabels() {
+	reservationLabels := CreateHostReservationLabels("reservation-0")
+	suite.Len(reservationLabels.GetLabels(), 1)
+	suite.Equal(_reservationIDKey, reservationLabels.GetLabels()[0].GetKey())
+	suite.Equal("reservation-0", reservationLabels.GetLabels()[0].GetValue())
+
+	// labels are not valid stateful task reservation labels
+	_, _, err := ParseReservationLabels(reservationLabels)
+	suite.Error(err)
+}
+
 func (suite *LabelTestSuite) TestParseReservationLabelWithInstanceIDMissingError() {
 	reservationLabels := &mesos.Labels{
 		Labels: []*mesos.Label{
@@ -15,6 +15,7 @@
 package rpc
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	nethttp "net/http"
@@ -90,3 +91,13 @@ func NewInbounds(
 	}
 	return inbounds
 }
+
+// NewTLSInbound creates a gRPC inbound for the given port which only
+// accepts TLS connections
+func NewTLSInbound(grpcPort int, tlsConfig *tls.Config) transport.Inbound {
+	gl, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+	if err != nil {
+		log.WithError(err).Fatal("failed to listen to gRPC TLS port")
+	}
+	return NewTransport().NewInbound(tls.NewListener(gl, tlsConfig))
+}
@@ -15,30 +15,58 @@
 package stringset
 
 import (
+	"sort"
 	"sync"
 )
 
-// StringSet defines the interface for a set of strings
+// StringSet defines the interface for a set of strings.
+// Sets created with New are safe for concurrent use by multiple goroutines.
+// Sets created with NewUnsafe do no locking and must only be used by a
+// single goroutine, or be externally synchronized.
 type StringSet interface {
 	// Add adds 'key' to the set
 	Add(key string)
+	// AddAll adds all 'keys' to the set
+	AddAll(keys []string)
 	// Remove removes 'key' from the set
 	Remove(key string)
+	// RemoveAll removes all 'keys' from the set
+	RemoveAll(keys []string)
 	// Contains checks if the set contains 'key'
 	Contains(key string) bool
 	// Clear clears the contents of set
 	Clear()
 	// ToSlice returns a slice containing all elements in the set
 	ToSlice() []string
+	// ToSortedSlice returns a sorted slice containing all elements in the set
+	ToSortedSlice() []string
+	// Range calls 'f' for each element in the set until 'f' returns false.
+	// 'f' must not modify the set.
+	Range(f func(key string) bool)
+	// Len returns the number of elements in the set
+	Len() int
+	// Equals checks if the set contains exactly the same elements as 'other'
+	Equals(other StringSet) bool
+	// IsSubsetOf checks if all elements of the set are in 'other'
+	IsSubsetOf(other StringSet) bool
+	// Union returns a new set with the elements in either set
+	Union(other StringSet) StringSet
+	// Difference returns a new set with the elements in this set
+	// which are not in 'other'
+	Difference(other StringSet) StringSet
+	// SymmetricDifference returns a new set with the elements which
+	// are in exactly one of the two sets
+	SymmetricDifference(other StringSet) StringSet
 }
 
-// stringSet implements StringSet interface. It is thread safe
+// stringSet implements StringSet interface. All methods are guarded by
+// a read-write mutex, so it is safe for concurrent use.
 type stringSet struct {
 	sync.RWMutex
 	m map[string]bool
 }
 
-// New creates and initializes a new StringSet
+// New creates and initializes a new goroutine-safe StringSet
 func New() StringSet {
 	s := &stringSet{
 		m: make(map[string]bool),
@@ -46,6 +74,17 @@ func New() StringSet {
 	return s
 }
 
+// FromSlice creates a new goroutine-safe StringSet containing 'keys'
+func FromSlice(keys []string) StringSet {
+	s := &stringSet{
+		m: make(map[string]bool, len(keys)),
+	}
+	for _, k := range keys {
+		s.m[k] = true
+	}
+	return s
+}
+
 // Add adds 'key' to the set
 func (s *stringSet) Add(key string) {
 	defer s.Unlock()
@@ -54,6 +93,16 @@ func (s *stringSet) Add(key string) {
 	s.m[key] = true
 }
 
+// AddAll adds all 'keys' to the set under a single lock acquisition
+func (s *stringSet) AddAll(keys []string) {
+	defer s.Unlock()
+	s.Lock()
+
+	for _, k := range keys {
+		s.m[k] = true
+	}
+}
+
 // Contains checks if the set contains 'key'
 func (s *stringSet) Contains(key string) bool {
 	defer s.RUnlock()
@@ -70,6 +119,16 @@ func (s *stringSet) Remove(key string) {
 	delete(s.m, key)
 }
 
+// RemoveAll removes all 'keys' from the set under a single lock acquisition
+func (s *stringSet) RemoveAll(keys []string) {
+	defer s.Unlock()
+	s.Lock()
+
+	for _, k := range keys {
+		delete(s.m, k)
+	}
+}
+
 // Clear clears the contents of the set
 func (s *stringSet) Clear() {
 	defer s.Unlock()
@@ -94,6 +153,76 @@ func (s *stringSet) ToSlice() []string {
 	return keys
 }
 
+// ToSortedSlice returns a sorted slice containing all elements in the set
+func (s *stringSet) ToSortedSlice() []string {
+	keys := s.ToSlice()
+	sort.Strings(keys)
+	return keys
+}
+
+// Range calls 'f' for each element in the set until 'f' returns false.
+// The read lock is held while iterating, so 'f' must not modify the set.
+func (s *stringSet) Range(f func(key string) bool) {
+	defer s.RUnlock()
+	s.RLock()
+
+	for k := range s.m {
+		if !f(k) {
+			return
+		}
+	}
+}
+
+// Len returns the number of elements in the set
+func (s *stringSet) Len() int {
+	defer s.RUnlock()
+	s.RLock()
+
+	return len(s.m)
+}
+
+// Equals checks if the set contains exactly the same elements as 'other'
+func (s *stringSet) Equals(other StringSet) bool {
+	return equals(s.ToSlice(), other)
+}
+
+// IsSubsetOf checks if all elements of the set are in 'other'
+func (s *stringSet) IsSubsetOf(other StringSet) bool {
+	return isSubsetOf(s.ToSlice(), other)
+}
+
+// Union returns a new set with the elements in either set
+func (s *stringSet) Union(other StringSet) StringSet {
+	otherKeys := other.ToSlice()
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return &stringSet{m: union(s.m, otherKeys)}
+}
+
+// Difference returns a new set with the elements in this set which are
+// not in 'other'
+func (s *stringSet) Difference(other StringSet) StringSet {
+	otherKeys := other.ToSlice()
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return &stringSet{m: difference(s.m, otherKeys)}
+}
+
+// SymmetricDifference returns a new set with the elements which are in
+// exactly one of the two sets
+func (s *stringSet) SymmetricDifference(other StringSet) StringSet {
+	otherKeys := other.ToSlice()
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return &stringSet{m: symmetricDifference(s.m, otherKeys)}
+}
+
 // Intersect returns the intersection between two StringSet
 func (s *stringSet) Intersect(other *stringSet) (intersection *stringSet) {
 	var ret *stringSet
@@ -150,3 +279,178 @@ func (s *stringSet) Intersect(other *stringSet) (intersection *stringSet) {
 	}
 	return ret
 }
+
+// unsafeStringSet implements StringSet interface without any locking.
+type unsafeStringSet struct {
+	m map[string]bool
+}
+
+// NewUnsafe creates and initializes a new StringSet which is not safe for
+// concurrent use. It avoids the locking overhead for sets which are owned
+// by a single goroutine.
+func NewUnsafe() StringSet {
+	return &unsafeStringSet{
+		m: make(map[string]bool),
+	}
+}
+
+// Add adds 'key' to the set
+func (s *unsafeStringSet) Add(key string) {
+	s.m[key] = true
+}
+
+// AddAll adds all 'keys' to the set
+func (s *unsafeStringSet) AddAll(keys []string) {
+	for _, k := range keys {
+		s.m[k] = true
+	}
+}
+
+// Contains checks if the set contains 'key'
+func (s *unsafeStringSet) Contains(key string) bool {
+	return s.m[key]
+}
+
+// Remove removes 'key' from the set
+func (s *unsafeStringSet) Remove(key string) {
+	delete(s.m, key)
+}
+
+// RemoveAll removes all 'keys' from the set
+func (s *unsafeStringSet) RemoveAll(keys []string) {
+	for _, k := range keys {
+		delete(s.m, k)
+	}
+}
+
+// Clear clears the contents of the set
+func (s *unsafeStringSet) Clear() {
+	for k := range s.m {
+		delete(s.m, k)
+	}
+}
+
+// ToSlice returns a slice containing all elements in the set
+func (s *unsafeStringSet) ToSlice() []string {
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ToSortedSlice returns a sorted slice containing all elements in the set
+func (s *unsafeStringSet) ToSortedSlice() []string {
+	keys := s.ToSlice()
+	sort.Strings(keys)
+	return keys
+}
+
+// Range calls 'f' for each element in the set until 'f' returns false
+func (s *unsafeStringSet) Range(f func(key string) bool) {
+	for k := range s.m {
+		if !f(k) {
+			return
+		}
+	}
+}
+
+// Len returns the number of elements in the set
+func (s *unsafeStringSet) Len() int {
+	return len(s.m)
+}
+
+// Equals checks if the set contains exactly the same elements as 'other'
+func (s *unsafeStringSet) Equals(other StringSet) bool {
+	return equals(s.ToSlice(), other)
+}
+
+// IsSubsetOf checks if all elements of the set are in 'other'
+func (s *unsafeStringSet) IsSubsetOf(other StringSet) bool {
+	return isSubsetOf(s.ToSlice(), other)
+}
+
+// Union returns a new set with the elements in either set
+func (s *unsafeStringSet) Union(other StringSet) StringSet {
+	return &unsafeStringSet{m: union(s.m, other.ToSlice())}
+}
+
+// Difference returns a new set with the elements in this set which are
+// not in 'other'
+func (s *unsafeStringSet) Difference(other StringSet) StringSet {
+	return &unsafeStringSet{m: difference(s.m, other.ToSlice())}
+}
+
+// SymmetricDifference returns a new set with the elements which are in
+// exactly one of the two sets
+func (s *unsafeStringSet) SymmetricDifference(other StringSet) StringSet {
+	return &unsafeStringSet{m: symmetricDifference(s.m, other.ToSlice())}
+}
+
+// union returns a new map with the keys of m and the given keys.
+// Snapshotting the other set into a slice first means that at most one
+// set lock is held at any time, so operations between two locked sets
+// can never deadlock.
+func union(m map[string]bool, keys []string) map[string]bool {
+	ret := make(map[string]bool, len(m)+len(keys))
+	for k := range m {
+		ret[k] = true
+	}
+	for _, k := range keys {
+		ret[k] = true
+	}
+	return ret
+}
+
+// difference returns a new map with the keys of m which are not in keys
+func difference(m map[string]bool, keys []string) map[string]bool {
+	ret := make(map[string]bool, len(m))
+	for k := range m {
+		ret[k] = true
+	}
+	for _, k := range keys {
+		delete(ret, k)
+	}
+	return ret
+}
+
+// symmetricDifference returns a new map with the keys which are either in
+// m or in keys, but not in both
+func symmetricDifference(m map[string]bool, keys []string) map[string]bool {
+	ret := difference(m, keys)
+	for _, k := range keys {
+		if !m[k] {
+			ret[k] = true
+		}
+	}
+	return ret
+}
+
+// isSubsetOf checks if all keys are in 'other'. The keys are a snapshot
+// of the receiving set, so no two set locks are ever held together.
+func isSubsetOf(keys []string, other StringSet) bool {
+	for _, k := range keys {
+		if !other.Contains(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// equals checks if 'other' contains exactly the given keys
+func equals(keys []string, other StringSet) bool {
+	otherKeys := other.ToSlice()
+	if len(keys) != len(otherKeys) {
+		return false
+	}
+	otherSet := make(map[string]bool, len(otherKeys))
+	for _, k := range otherKeys {
+		otherSet[k] = true
+	}
+	for _, k := range keys {
+		if !otherSet[k] {
+			return false
+		}
+	}
+	return true
+}
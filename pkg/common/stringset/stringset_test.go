@@ -15,7 +15,9 @@
 package stringset
 
 import (
+	"fmt"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,6 +32,51 @@ func TestStringSet_New(t *testing.T) {
 	assert.NotNil(t, testSet)
 }
 
+func TestStringSet_NewUnsafe(t *testing.T) {
+	testSet := NewUnsafe()
+	assert.NotNil(t, testSet)
+
+	testSet.Add(testItem)
+	assert.True(t, testSet.Contains(testItem))
+	assert.Equal(t, []string{testItem}, testSet.ToSlice())
+
+	testSet.Remove(testItem)
+	assert.False(t, testSet.Contains(testItem))
+
+	testSet.Add(testItem)
+	testSet.Clear()
+	assert.Empty(t, testSet.ToSlice())
+}
+
+// TestStringSet_Concurrent exercises the goroutine-safe set from multiple
+// goroutines, and is meant to be run with the race detector.
+func TestStringSet_Concurrent(t *testing.T) {
+	testSet := New()
+	numWriters := 10
+	numItems := 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWriters; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < numItems; j++ {
+				testSet.Add(fmt.Sprintf("item-%d-%d", i, j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numItems; j++ {
+				testSet.Contains(fmt.Sprintf("item-0-%d", j))
+				testSet.ToSlice()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, testSet.ToSlice(), numWriters*numItems)
+}
+
 func TestStringSet_Add(t *testing.T) {
 	// Create a new StringSet
 	testSet := &stringSet{
@@ -40,6 +87,29 @@ func TestStringSet_Add(t *testing.T) {
 	assert.Equal(t, true, testSet.m[testItem])
 }
 
+func TestStringSet_FromSlice(t *testing.T) {
+	testSet := FromSlice([]string{"a", "b", "a"})
+	slice := testSet.ToSlice()
+	sort.Strings(slice)
+	assert.Equal(t, []string{"a", "b"}, slice)
+
+	assert.Empty(t, FromSlice(nil).ToSlice())
+}
+
+func TestStringSet_AddAllRemoveAll(t *testing.T) {
+	for _, testSet := range []StringSet{New(), NewUnsafe()} {
+		testSet.AddAll([]string{"a", "b", "c"})
+		assert.Len(t, testSet.ToSlice(), 3)
+
+		testSet.RemoveAll([]string{"a", "c", "unknown"})
+		assert.Equal(t, []string{"b"}, testSet.ToSlice())
+
+		testSet.AddAll(nil)
+		testSet.RemoveAll(nil)
+		assert.Equal(t, []string{"b"}, testSet.ToSlice())
+	}
+}
+
 func TestStringSet_Contains(t *testing.T) {
 	// Create a new StringSet
 	testSet := &stringSet{
@@ -151,3 +221,134 @@ func TestStringSet_Intersect(t *testing.T) {
 		})
 	}
 }
+
+func TestStringSet_SetOperations(t *testing.T) {
+	tt := []struct {
+		name                string
+		a                   []string
+		b                   []string
+		union               []string
+		difference          []string
+		symmetricDifference []string
+	}{
+		{
+			name:                "both sets void",
+			union:               []string{},
+			difference:          []string{},
+			symmetricDifference: []string{},
+		},
+		{
+			name:                "disjoint sets",
+			a:                   []string{"a"},
+			b:                   []string{"b"},
+			union:               []string{"a", "b"},
+			difference:          []string{"a"},
+			symmetricDifference: []string{"a", "b"},
+		},
+		{
+			name:                "overlapping sets",
+			a:                   []string{"a", "b", "c"},
+			b:                   []string{"b", "c", "d"},
+			union:               []string{"a", "b", "c", "d"},
+			difference:          []string{"a"},
+			symmetricDifference: []string{"a", "d"},
+		},
+		{
+			name:                "equal sets",
+			a:                   []string{"a", "b"},
+			b:                   []string{"b", "a"},
+			union:               []string{"a", "b"},
+			difference:          []string{},
+			symmetricDifference: []string{},
+		},
+	}
+
+	sorted := func(s StringSet) []string {
+		slice := s.ToSlice()
+		sort.Strings(slice)
+		return slice
+	}
+
+	for _, newSet := range []func() StringSet{New, NewUnsafe} {
+		for _, tc := range tt {
+			a, b := newSet(), newSet()
+			for _, item := range tc.a {
+				a.Add(item)
+			}
+			for _, item := range tc.b {
+				b.Add(item)
+			}
+
+			assert.Equal(t, tc.union, sorted(a.Union(b)), tc.name)
+			assert.Equal(t, tc.difference, sorted(a.Difference(b)), tc.name)
+			assert.Equal(t, tc.symmetricDifference,
+				sorted(a.SymmetricDifference(b)), tc.name)
+
+			// operations must not modify the operands
+			assert.Len(t, a.ToSlice(), len(tc.a), tc.name)
+		}
+	}
+
+	// operations between the two implementations are allowed, and an
+	// operation of a set with itself must not deadlock
+	a := New()
+	a.Add("a")
+	b := NewUnsafe()
+	b.Add("b")
+	assert.Equal(t, []string{"a", "b"}, sorted(a.Union(b)))
+	assert.Equal(t, []string{"a"}, sorted(a.Union(a)))
+}
+
+func TestStringSet_Predicates(t *testing.T) {
+	for _, newSet := range []func() StringSet{New, NewUnsafe} {
+		empty := newSet()
+		ab := newSet()
+		ab.AddAll([]string{"a", "b"})
+		ba := newSet()
+		ba.AddAll([]string{"b", "a"})
+		abc := newSet()
+		abc.AddAll([]string{"a", "b", "c"})
+		cd := newSet()
+		cd.AddAll([]string{"c", "d"})
+
+		assert.Equal(t, 0, empty.Len())
+		assert.Equal(t, 3, abc.Len())
+
+		assert.True(t, ab.Equals(ba))
+		assert.True(t, ab.Equals(ab))
+		assert.True(t, empty.Equals(newSet()))
+		assert.False(t, ab.Equals(abc))
+		assert.False(t, ab.Equals(cd))
+
+		assert.True(t, empty.IsSubsetOf(ab))
+		assert.True(t, ab.IsSubsetOf(abc))
+		assert.True(t, ab.IsSubsetOf(ba))
+		assert.False(t, abc.IsSubsetOf(ab))
+		assert.False(t, ab.IsSubsetOf(cd))
+	}
+}
+
+func TestStringSet_RangeAndToSortedSlice(t *testing.T) {
+	for _, newSet := range []func() StringSet{New, NewUnsafe} {
+		testSet := newSet()
+		testSet.AddAll([]string{"c", "a", "b"})
+
+		assert.Equal(t, []string{"a", "b", "c"}, testSet.ToSortedSlice())
+		assert.Equal(t, []string{}, newSet().ToSortedSlice())
+
+		var visited []string
+		testSet.Range(func(key string) bool {
+			visited = append(visited, key)
+			return true
+		})
+		sort.Strings(visited)
+		assert.Equal(t, []string{"a", "b", "c"}, visited)
+
+		count := 0
+		testSet.Range(func(key string) bool {
+			count++
+			return false
+		})
+		assert.Equal(t, 1, count)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"time"
+)
+
+// Config is the TLS configuration of an inbound or an outbound
+type Config struct {
+	// Enabled turns TLS on
+	Enabled bool `yaml:"enabled"`
+
+	// CertFile and KeyFile are the PEM encoded certificate and private
+	// key presented to the peer. Required by inbounds, and by outbounds
+	// connecting to a peer which requires client certificates.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// CAFile is the PEM encoded CA bundle used to verify the peer
+	// certificate. Outbounds fall back to the system roots if empty.
+	CAFile string `yaml:"ca_file"`
+
+	// RequireClientCert makes an inbound require and verify client
+	// certificates against CAFile (mutual TLS)
+	RequireClientCert bool `yaml:"require_client_cert"`
+
+	// ServerName overrides the host name verified by an outbound
+	ServerName string `yaml:"server_name"`
+
+	// ReloadInterval is the period at which the certificate, key and CA
+	// files are checked for rotation
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
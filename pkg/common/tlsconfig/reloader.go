@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/lifecycle"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const _defaultReloadInterval = time.Minute
+
+// Reloader loads the certificates of a TLS config, and reloads them
+// whenever the files change so that certificates can be rotated without
+// restarting the process.
+type Reloader struct {
+	sync.RWMutex
+
+	config Config
+
+	cert     *tls.Certificate
+	caPool   *x509.CertPool
+	modTimes map[string]time.Time
+
+	lifeCycle lifecycle.LifeCycle
+	metrics   *Metrics
+}
+
+// NewReloader loads the files of the given config and returns a Reloader.
+func NewReloader(config Config, parent tally.Scope) (*Reloader, error) {
+	if config.ReloadInterval <= 0 {
+		config.ReloadInterval = _defaultReloadInterval
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("cert_file and key_file must be set together")
+	}
+	if config.RequireClientCert && config.CAFile == "" {
+		return nil, errors.New("ca_file is required to verify client certificates")
+	}
+
+	r := &Reloader{
+		config:    config,
+		lifeCycle: lifecycle.NewLifeCycle(),
+		metrics:   NewMetrics(parent.SubScope("tls")),
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start starts watching the files for changes.
+func (r *Reloader) Start() {
+	if !r.lifeCycle.Start() {
+		return
+	}
+	go r.run()
+}
+
+// Stop stops watching the files, and blocks until the watcher exits.
+func (r *Reloader) Stop() {
+	if !r.lifeCycle.Stop() {
+		return
+	}
+	r.lifeCycle.Wait()
+}
+
+// ServerConfig returns a TLS config for an inbound. The current
+// certificate and CA bundle are resolved on every handshake.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.RLock()
+			defer r.RUnlock()
+
+			if r.cert == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.config.RequireClientCert {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = r.caPool
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns a TLS config for an outbound, with the certificate
+// and CA bundle currently loaded. Long lived clients should fetch a new
+// config for every connection to pick up rotated certificates.
+func (r *Reloader) ClientConfig() *tls.Config {
+	r.RLock()
+	defer r.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.config.ServerName,
+		RootCAs:    r.caPool,
+	}
+	if r.cert != nil {
+		cfg.Certificates = []tls.Certificate{*r.cert}
+	}
+	return cfg
+}
+
+func (r *Reloader) run() {
+	defer r.lifeCycle.StopComplete()
+
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.lifeCycle.StopCh():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				// Keep serving with the previously loaded certificates,
+				// the files may be in the middle of being rotated.
+				r.metrics.ReloadFail.Inc(1)
+				log.WithError(err).Warn("Failed to reload TLS certificates")
+				continue
+			}
+			if changed {
+				r.metrics.Reload.Inc(1)
+				log.WithField("cert_file", r.config.CertFile).
+					Info("Reloaded TLS certificates")
+			}
+		}
+	}
+}
+
+// reload loads the files if any of them changed since the last load,
+// and returns whether they were loaded.
+func (r *Reloader) reload() (bool, error) {
+	modTimes := make(map[string]time.Time)
+	changed := false
+	for _, f := range []string{
+		r.config.CertFile,
+		r.config.KeyFile,
+		r.config.CAFile,
+	} {
+		if f == "" {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to stat %s", f)
+		}
+		modTimes[f] = info.ModTime()
+		if !info.ModTime().Equal(r.modTimes[f]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if r.config.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to load key pair")
+		}
+		cert = &c
+	}
+
+	var caPool *x509.CertPool
+	if r.config.CAFile != "" {
+		pem, err := ioutil.ReadFile(r.config.CAFile)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to read %s", r.config.CAFile)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return false, errors.Errorf(
+				"no certificates found in %s", r.config.CAFile)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.cert = cert
+	r.caPool = caPool
+	r.modTimes = modTimes
+	return true, nil
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type ReloaderTestSuite struct {
+	suite.Suite
+
+	dir    string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+}
+
+func TestReloader(t *testing.T) {
+	suite.Run(t, new(ReloaderTestSuite))
+}
+
+func (s *ReloaderTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	s.NoError(err)
+	s.dir = dir
+
+	s.caCert, s.caKey = s.newCert("test-ca", nil, nil)
+	s.writeCert("ca.pem", s.caCert)
+}
+
+func (s *ReloaderTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// newCert creates a certificate signed by the given parent, or a self
+// signed CA certificate if parent is nil.
+func (s *ReloaderTestSuite) newCert(
+	name string,
+	parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(
+		rand.Reader, template, parent, &key.PublicKey, parentKey)
+	s.NoError(err)
+	cert, err := x509.ParseCertificate(der)
+	s.NoError(err)
+	return cert, key
+}
+
+func (s *ReloaderTestSuite) writeCert(name string, cert *x509.Certificate) {
+	s.NoError(ioutil.WriteFile(
+		filepath.Join(s.dir, name),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		0600,
+	))
+}
+
+func (s *ReloaderTestSuite) writeKeyPair(name string, modTime time.Time) {
+	cert, key := s.newCert(name, s.caCert, s.caKey)
+	s.writeCert(name+".pem", cert)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	s.NoError(err)
+	s.NoError(ioutil.WriteFile(
+		filepath.Join(s.dir, name+"-key.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		0600,
+	))
+
+	for _, f := range []string{name + ".pem", name + "-key.pem"} {
+		s.NoError(os.Chtimes(filepath.Join(s.dir, f), modTime, modTime))
+	}
+}
+
+func (s *ReloaderTestSuite) config(name string) Config {
+	return Config{
+		Enabled:           true,
+		CertFile:          filepath.Join(s.dir, name+".pem"),
+		KeyFile:           filepath.Join(s.dir, name+"-key.pem"),
+		CAFile:            filepath.Join(s.dir, "ca.pem"),
+		RequireClientCert: true,
+		ServerName:        "server",
+	}
+}
+
+// handshake serves one connection with the server reloader, and returns
+// the certificate presented by the server.
+func (s *ReloaderTestSuite) handshake(
+	server *Reloader,
+	client *tls.Config,
+) (*x509.Certificate, error) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server.ServerConfig())
+	s.NoError(err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 a rejected client certificate only fails the first
+	// read, the server closes the connection after a successful handshake.
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+// TestMutualTLS tests a handshake between an inbound and an outbound
+// both configured from reloaders.
+func (s *ReloaderTestSuite) TestMutualTLS() {
+	s.writeKeyPair("server", time.Now())
+	s.writeKeyPair("client", time.Now())
+
+	server, err := NewReloader(s.config("server"), tally.NoopScope)
+	s.NoError(err)
+	client, err := NewReloader(s.config("client"), tally.NoopScope)
+	s.NoError(err)
+
+	cert, err := s.handshake(server, client.ClientConfig())
+	s.NoError(err)
+	s.Equal("server", cert.Subject.CommonName)
+
+	// The server rejects clients without a certificate
+	noCert := client.ClientConfig()
+	noCert.Certificates = nil
+	_, err = s.handshake(server, noCert)
+	s.Error(err)
+}
+
+// TestReloadRotatedCertificate tests that a rotated certificate is
+// served without recreating the reloader.
+func (s *ReloaderTestSuite) TestReloadRotatedCertificate() {
+	s.writeKeyPair("server", time.Now().Add(-time.Minute))
+	s.writeKeyPair("client", time.Now())
+
+	server, err := NewReloader(s.config("server"), tally.NoopScope)
+	s.NoError(err)
+	client, err := NewReloader(s.config("client"), tally.NoopScope)
+	s.NoError(err)
+
+	before, err := s.handshake(server, client.ClientConfig())
+	s.NoError(err)
+
+	// Unchanged files are not reloaded
+	changed, err := server.reload()
+	s.NoError(err)
+	s.False(changed)
+
+	s.writeKeyPair("server", time.Now())
+	changed, err = server.reload()
+	s.NoError(err)
+	s.True(changed)
+
+	after, err := s.handshake(server, client.ClientConfig())
+	s.NoError(err)
+	s.NotEqual(before.SerialNumber, after.SerialNumber)
+}
+
+// TestReloadFailureKeepsCertificate tests that a failed reload keeps the
+// previously loaded certificate.
+func (s *ReloaderTestSuite) TestReloadFailureKeepsCertificate() {
+	s.writeKeyPair("server", time.Now().Add(-time.Minute))
+	server, err := NewReloader(s.config("server"), tally.NoopScope)
+	s.NoError(err)
+
+	keyFile := filepath.Join(s.dir, "server-key.pem")
+	s.NoError(ioutil.WriteFile(keyFile, []byte("garbage"), 0600))
+	_, err = server.reload()
+	s.Error(err)
+	s.NotNil(server.ClientConfig().Certificates)
+}
+
+// TestStartStop tests starting and stopping the file watcher.
+func (s *ReloaderTestSuite) TestStartStop() {
+	s.writeKeyPair("server", time.Now())
+	cfg := s.config("server")
+	cfg.ReloadInterval = time.Millisecond
+
+	server, err := NewReloader(cfg, tally.NoopScope)
+	s.NoError(err)
+	server.Start()
+	time.Sleep(10 * time.Millisecond)
+	server.Stop()
+}
+
+// TestNewReloaderInvalidConfig tests the validation of the config.
+func (s *ReloaderTestSuite) TestNewReloaderInvalidConfig() {
+	cfg := s.config("server")
+	cfg.KeyFile = ""
+	_, err := NewReloader(cfg, tally.NoopScope)
+	s.Error(err)
+
+	cfg = s.config("server")
+	cfg.CAFile = ""
+	_, err = NewReloader(cfg, tally.NoopScope)
+	s.Error(err)
+
+	// Missing files
+	_, err = NewReloader(s.config("missing"), tally.NoopScope)
+	s.Error(err)
+
+	// CA file without certificates
+	s.writeKeyPair("server", time.Now())
+	s.NoError(ioutil.WriteFile(filepath.Join(s.dir, "ca.pem"), nil, 0600))
+	_, err = NewReloader(s.config("server"), tally.NoopScope)
+	s.Error(err)
+}
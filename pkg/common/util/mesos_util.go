@@ -17,6 +17,11 @@ package util
 import (
 	"fmt"
 	"strings"
+
+	"github.com/uber/peloton/pkg/common/lru"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
 )
 
 const (
@@ -24,6 +29,9 @@ const (
 	ipPortSeparator = ":"
 	// slaveIPSeparator is the separator for slave id and IP address
 	slaveIPSeparator = "@"
+	// maxCachedAgentPIDs bounds the number of parsed agent PIDs cached,
+	// which is well above the number of agents of a cluster
+	maxCachedAgentPIDs = 100000
 )
 
 // ExtractIPAndPortFromMesosAgentPID parses Mesos PID to extract IP-address
@@ -53,3 +61,84 @@ func ExtractIPAndPortFromMesosAgentPID(pid string) (string, string, error) {
 	}
 	return ip, port, nil
 }
+
+// AgentPID is the IP-address and port number parsed from a Mesos agent PID.
+type AgentPID struct {
+	IP   string
+	Port string
+}
+
+// AgentPIDCache parses Mesos agent PIDs and memoizes the result, so that
+// the PIDs of registered agents are only parsed once per agent instead of
+// on every request.
+type AgentPIDCache struct {
+	// cache of the parsed pids by raw pid
+	parsed lru.Cache
+
+	parseSuccess tally.Counter
+	parseFail    tally.Counter
+	cacheHit     tally.Counter
+}
+
+// NewAgentPIDCache returns a new AgentPIDCache reporting into given scope.
+func NewAgentPIDCache(scope tally.Scope) *AgentPIDCache {
+	pidScope := scope.SubScope("agent_pid")
+	return &AgentPIDCache{
+		parsed: lru.New(
+			lru.Config{MaxSize: maxCachedAgentPIDs},
+			pidScope.SubScope("cache")),
+		parseSuccess: pidScope.Counter("parse_success"),
+		parseFail:    pidScope.Counter("parse_fail"),
+		cacheHit:     pidScope.Counter("cache_hit"),
+	}
+}
+
+// Parse returns the IP-address and port number of the agent PID, parsing
+// and memoizing it if it was not seen before. Invalid PIDs are not cached.
+func (c *AgentPIDCache) Parse(pid string) (string, string, error) {
+	if cached, ok := c.parsed.Get(pid); ok {
+		c.cacheHit.Inc(1)
+		p := cached.(*AgentPID)
+		return p.IP, p.Port, nil
+	}
+
+	ip, port, err := ExtractIPAndPortFromMesosAgentPID(pid)
+	if err != nil {
+		c.parseFail.Inc(1)
+		return "", "", err
+	}
+	c.parseSuccess.Inc(1)
+
+	c.parsed.Add(pid, &AgentPID{IP: ip, Port: port})
+	return ip, port, nil
+}
+
+// ParseAll parses all the given agent PIDs, and returns the parsed PIDs
+// keyed by raw PID along with the combined errors of all invalid PIDs.
+func (c *AgentPIDCache) ParseAll(pids []string) (map[string]*AgentPID, error) {
+	var errs error
+	result := make(map[string]*AgentPID, len(pids))
+	for _, pid := range pids {
+		ip, port, err := c.Parse(pid)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		result[pid] = &AgentPID{IP: ip, Port: port}
+	}
+	return result, errs
+}
+
+// Retain removes all cached PIDs which are not in the given list, and
+// should be called with the PIDs of the current set of registered agents
+// so that the cache does not grow unbounded as agents come and go.
+func (c *AgentPIDCache) Retain(pids []string) {
+	keep := make(map[string]struct{}, len(pids))
+	for _, pid := range pids {
+		keep[pid] = struct{}{}
+	}
+	c.parsed.Retain(func(pid string) bool {
+		_, ok := keep[pid]
+		return ok
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
 )
 
 // Test extraction of IP and port from Agent PID
@@ -50,3 +51,31 @@ func TestExtractIPAndPortFromMesosPid(t *testing.T) {
 		}
 	}
 }
+
+// Test bulk parsing and memoization of Agent PIDs
+func TestAgentPIDCache(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	cache := NewAgentPIDCache(scope)
+
+	ip, port, err := cache.Parse("slave(1)@1.2.3.4:9090")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", ip)
+	assert.Equal(t, "9090", port)
+
+	parsed, err := cache.ParseAll([]string{
+		"slave(1)@1.2.3.4:9090",
+		"slave(2)@2.3.4.5",
+		"badpid",
+	})
+	assert.Error(t, err)
+	assert.Len(t, parsed, 2)
+	assert.Equal(t, &AgentPID{IP: "2.3.4.5"}, parsed["slave(2)@2.3.4.5"])
+
+	counters := scope.Snapshot().Counters()
+	assert.Equal(t, int64(2), counters["agent_pid.parse_success+"].Value())
+	assert.Equal(t, int64(1), counters["agent_pid.parse_fail+"].Value())
+	assert.Equal(t, int64(1), counters["agent_pid.cache_hit+"].Value())
+
+	cache.Retain([]string{"slave(2)@2.3.4.5"})
+	assert.Equal(t, 1, cache.parsed.Len())
+}
@@ -125,11 +125,15 @@ func TestConvertTaskToResMgrTask(t *testing.T) {
 	}
 
 	jobConfig := &job.JobConfig{
-		SLA: &job.SlaConfig{},
+		Type:          job.JobType_DAEMON,
+		SLA:           &job.SlaConfig{},
+		InstanceCount: uint32(len(taskInfos)),
 	}
 	for _, taskInfo := range taskInfos {
 		rmTask := ConvertTaskToResMgrTask(taskInfo, jobConfig)
 		assert.Equal(t, taskInfo.JobId.Value, rmTask.JobId.Value)
+		assert.Equal(t, job.JobType_DAEMON, rmTask.GetJobType())
+		assert.Equal(t, uint32(len(taskInfos)), rmTask.GetInstanceCount())
 		assert.Equal(t, uint32(len(taskInfo.Config.Ports)), rmTask.NumPorts)
 		taskState := taskInfo.Runtime.GetState()
 		if taskState == task.TaskState_LAUNCHED ||
@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+)
+
+// EvictionExemptReason returns why a task is exempt from eviction when
+// its host is drained with the given exemption, or an empty string if
+// the task is not exempt.
+func EvictionExemptReason(
+	exemption *hpb.EvictionExemption,
+	t *resmgr.Task) string {
+	if exemption == nil {
+		return ""
+	}
+
+	if exemption.GetControllerTasks() && t.GetController() {
+		return "controller task"
+	}
+
+	jobType := getJobType(t)
+	for _, exemptType := range exemption.GetJobTypes() {
+		if exemptType == jobType {
+			return fmt.Sprintf("job type %s", jobType)
+		}
+	}
+
+	for _, exemptLabel := range exemption.GetLabels() {
+		for _, label := range t.GetLabels().GetLabels() {
+			if label.GetKey() != exemptLabel.GetKey() {
+				continue
+			}
+			// A label without value matches the label with any value
+			if exemptLabel.GetValue() == "" ||
+				exemptLabel.GetValue() == label.GetValue() {
+				return fmt.Sprintf("label %s=%s", label.GetKey(), label.GetValue())
+			}
+		}
+	}
+	return ""
+}
+
+// returns the type of the job of a resource manager task. The tasks of
+// DAEMON jobs are BATCH tasks, so the job type of the task is checked
+// first.
+func getJobType(t *resmgr.Task) job.JobType {
+	if t.GetJobType() == job.JobType_DAEMON {
+		return job.JobType_DAEMON
+	}
+
+	switch t.GetType() {
+	case resmgr.TaskType_STATELESS, resmgr.TaskType_STATEFUL:
+		return job.JobType_SERVICE
+	case resmgr.TaskType_DAEMON:
+		return job.JobType_DAEMON
+	}
+	return job.JobType_BATCH
+}
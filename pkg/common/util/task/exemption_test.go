@@ -0,0 +1,120 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+)
+
+func TestEvictionExemptReason(t *testing.T) {
+	key, value := "role", "ingress"
+	labels := &mesos.Labels{
+		Labels: []*mesos.Label{{Key: &key, Value: &value}},
+	}
+
+	tt := []struct {
+		name      string
+		exemption *hpb.EvictionExemption
+		task      *resmgr.Task
+		reason    string
+	}{
+		{
+			name: "no exemption",
+			task: &resmgr.Task{Type: resmgr.TaskType_BATCH},
+		},
+		{
+			name: "controller task",
+			exemption: &hpb.EvictionExemption{
+				ControllerTasks: true,
+			},
+			task:   &resmgr.Task{Type: resmgr.TaskType_BATCH, Controller: true},
+			reason: "controller task",
+		},
+		{
+			name: "not a controller task",
+			exemption: &hpb.EvictionExemption{
+				ControllerTasks: true,
+			},
+			task: &resmgr.Task{Type: resmgr.TaskType_BATCH},
+		},
+		{
+			name: "daemon task",
+			exemption: &hpb.EvictionExemption{
+				JobTypes: []job.JobType{job.JobType_DAEMON},
+			},
+			task: &resmgr.Task{
+				Type:    resmgr.TaskType_BATCH,
+				JobType: job.JobType_DAEMON,
+			},
+			reason: "job type DAEMON",
+		},
+		{
+			name: "batch task of a daemon job",
+			exemption: &hpb.EvictionExemption{
+				JobTypes: []job.JobType{job.JobType_BATCH},
+			},
+			task: &resmgr.Task{
+				Type:    resmgr.TaskType_BATCH,
+				JobType: job.JobType_DAEMON,
+			},
+		},
+		{
+			name: "stateful task",
+			exemption: &hpb.EvictionExemption{
+				JobTypes: []job.JobType{job.JobType_SERVICE},
+			},
+			task:   &resmgr.Task{Type: resmgr.TaskType_STATEFUL},
+			reason: "job type SERVICE",
+		},
+		{
+			name: "label with value",
+			exemption: &hpb.EvictionExemption{
+				Labels: []*peloton.Label{{Key: "role", Value: "ingress"}},
+			},
+			task:   &resmgr.Task{Labels: labels},
+			reason: "label role=ingress",
+		},
+		{
+			name: "label with any value",
+			exemption: &hpb.EvictionExemption{
+				Labels: []*peloton.Label{{Key: "role"}},
+			},
+			task:   &resmgr.Task{Labels: labels},
+			reason: "label role=ingress",
+		},
+		{
+			name: "label with another value",
+			exemption: &hpb.EvictionExemption{
+				Labels: []*peloton.Label{{Key: "role", Value: "egress"}},
+			},
+			task: &resmgr.Task{Labels: labels},
+		},
+	}
+
+	for _, test := range tt {
+		assert.Equal(t,
+			test.reason,
+			EvictionExemptReason(test.exemption, test.task),
+			test.name)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"strings"
+)
+
+// _procedureSeparator separates the service and the method of the
+// procedures of the host manager services
+const _procedureSeparator = "::"
+
+// _readOnlyMethodPrefixes are the prefixes of the methods of the host
+// manager services which do not change any state
+var _readOnlyMethodPrefixes = []string{"Get", "Query", "List", "Export", "ClusterCapacity"}
+
+// IsMutatingProcedure returns whether the procedure is a method of a host
+// manager service which changes state, such as the maintenance, cordon,
+// reservation and offer methods. The procedures of the Mesos inbound are
+// not host manager methods.
+func IsMutatingProcedure(procedure string) bool {
+	parts := strings.Split(procedure, _procedureSeparator)
+	if len(parts) != 2 {
+		return false
+	}
+	for _, prefix := range _readOnlyMethodPrefixes {
+		if strings.HasPrefix(parts[1], prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMutatingProcedure(t *testing.T) {
+	testCases := map[string]bool{
+		"peloton.api.v0.host.svc.HostService::StartMaintenance":                  true,
+		"peloton.api.v0.host.svc.HostService::ReleaseReservation":                true,
+		"peloton.api.v0.host.svc.HostService::ImportHostInventory":               true,
+		"peloton.private.hostmgr.hostsvc.InternalHostService::CordonHosts":       true,
+		"peloton.private.hostmgr.hostsvc.InternalHostService::AcquireHostOffers": true,
+		"peloton.api.v0.host.svc.HostService::QueryHosts":                        false,
+		"peloton.api.v0.host.svc.HostService::ListReservations":                  false,
+		"peloton.api.v0.host.svc.HostService::ExportHostInventory":               false,
+		"peloton.private.hostmgr.hostsvc.InternalHostService::GetCordonedHosts":  false,
+		"peloton.private.hostmgr.hostsvc.InternalHostService::ClusterCapacity":   false,
+		"peloton.private.hostmgr.hostsvc.InternalHostService::GetHostsByQuery":   false,
+		"Call_MasterOperator": false,
+	}
+	for procedure, mutating := range testCases {
+		assert.Equal(t, mutating, IsMutatingProcedure(procedure), procedure)
+	}
+}
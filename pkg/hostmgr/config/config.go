@@ -17,7 +17,12 @@ package config
 import (
 	"time"
 
+	"github.com/uber/peloton/pkg/common/tlsconfig"
+	"github.com/uber/peloton/pkg/hostmgr/consistency"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/hostprovider"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
+	"github.com/uber/peloton/pkg/hostmgr/starvation"
 )
 
 // Config is Host Manager specific configuration
@@ -28,6 +33,19 @@ type Config struct {
 	// GRPC port which hostmgr is listening on
 	GRPCPort int `yaml:"grpc_port"`
 
+	// GRPC port serving the host service over TLS, used when TLS is
+	// enabled
+	GRPCTLSPort int `yaml:"grpc_tls_port"`
+
+	// TLS of the inbound serving the host service. When enabled, the host
+	// service is only served on GRPCTLSPort instead of the plaintext ports.
+	TLS tlsconfig.Config `yaml:"tls"`
+
+	// FollowerProxy makes a host manager which is not the leader proxy
+	// read-only host service calls to the leader, instead of returning
+	// a not leader error
+	FollowerProxy bool `yaml:"follower_proxy"`
+
 	// Time to hold offer for in seconds
 	OfferHoldTimeSec int `yaml:"offer_hold_time_sec"`
 
@@ -48,8 +66,18 @@ type Config struct {
 
 	TaskReconcilerConfig *reconcile.TaskReconcilerConfig `yaml:"task_reconciler"`
 
+	// Period for fully reloading the agent map from Mesos master. Agents
+	// removed in between are applied to the agent map as deltas.
 	HostmapRefreshInterval time.Duration `yaml:"hostmap_refresh_interval"`
 
+	// Name of the Mesos agent attribute whose value is the host pool of
+	// the agent in capacity reports. Agents without the attribute are
+	// reported in the default pool.
+	HostPoolAttribute string `yaml:"host_pool_attribute"`
+
+	// Period for persisting the task-to-host index to storage
+	HostTaskIndexPersistInterval time.Duration `yaml:"host_task_index_persist_interval"`
+
 	// Period in sec for running host pruning
 	HostPruningPeriodSec time.Duration `yaml:"host_pruning_period_sec"`
 
@@ -68,6 +96,28 @@ type Config struct {
 	// Host Drainer Period
 	HostDrainerPeriod time.Duration `yaml:"host_drainer_period"`
 
+	// Max number of times a host is handed out for draining without
+	// being marked drained, before it is moved to the maintenance
+	// dead-letter queue. 0 disables the dead-letter queue.
+	MaintenanceQueueMaxAttempts int `yaml:"maintenance_queue_max_attempts"`
+
+	// Starts host manager with maintenance frozen, so that start
+	// maintenance requests are queued until released by an operator.
+	MaintenanceFreeze bool `yaml:"maintenance_freeze"`
+
+	// Default method of draining hosts for maintenance, either
+	// "maintenance_schedule" or "agent_drain". Defaults to
+	// "maintenance_schedule".
+	DrainMethod string `yaml:"drain_method"`
+
+	// Names of the maintenance policies used by the start maintenance
+	// requests without drain options nor policy, keyed by host pool
+	DefaultMaintenancePolicies map[string]string `yaml:"default_maintenance_policies"`
+
+	// Plugin rebooting and terminating the machines of hosts in
+	// maintenance, none if not configured
+	HostProvider hostprovider.Config `yaml:"host_provider"`
+
 	// Represents scarce resource types such as GPU.
 	ScarceResourceTypes []string `yaml:"scarce_resource_types"`
 
@@ -78,4 +128,92 @@ type Config struct {
 	BinPacking string `yaml:"bin_packing"`
 	// Bin Packing Refresh Interval
 	BinPackingRefreshIntervalSec time.Duration `yaml:"bin_packing_refresh_interval"`
+
+	// Name of the metrics sub-scope for constraint evaluation metrics
+	ConstraintMetricsScope string `yaml:"constraint_metrics_scope"`
+
+	// Enables reordering the children of the AND and OR host constraints
+	// by their past evaluations, so that the children most likely to
+	// decide the result are evaluated first
+	ConstraintOptimizer bool `yaml:"constraint_optimizer"`
+
+	// Policy deciding the refuse seconds of declined offers
+	DeclinePolicy declinepolicy.Config `yaml:"decline_policy"`
+
+	// Detection of the hosts whose offers are consistently declined
+	OfferStarvation starvation.Config `yaml:"offer_starvation"`
+
+	// Thresholds of the readiness probe
+	Readiness ReadinessConfig `yaml:"readiness"`
+
+	// Reloading of settings without restart
+	Reload ReloadConfig `yaml:"reload"`
+
+	// Periodic check of the consistency of the hosts with Mesos master
+	ConsistencyChecker consistency.Config `yaml:"consistency_checker"`
+
+	// Federation with the host managers of other Peloton clusters, whose
+	// hosts are aggregated by federated host queries
+	Federation FederationConfig `yaml:"federation"`
+
+	// Enables the injection of faults on demand with the
+	// /debug/hostmgr/faults endpoint, to test the resilience of the
+	// maintenance flows. Never enable it in production.
+	FaultInjection bool `yaml:"fault_injection"`
+}
+
+// ReloadConfig is the config of reloading host manager settings without
+// restart. Only the agent map refresh interval, the host drainer period and
+// the maintenance queue max attempts are reloaded.
+type ReloadConfig struct {
+	// Enables reloading of settings
+	Enabled bool `yaml:"enabled"`
+
+	// Period for reloading the config
+	Interval time.Duration `yaml:"interval"`
+
+	// URL of a config service serving the config as a YAML document. The
+	// config files are reloaded if empty.
+	URL string `yaml:"url"`
+}
+
+// ReadinessConfig is the config of the host manager readiness probe.
+type ReadinessConfig struct {
+	// Time after which a component check is reported unhealthy
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+
+	// Max time since the last full reload of the agent map, after which
+	// the leader is not ready. Defaults to 3 times the agent map refresh
+	// interval.
+	AgentMapMaxStaleness time.Duration `yaml:"agent_map_max_staleness"`
+
+	// Max number of hosts in the maintenance dead-letter queue, above
+	// which the maintenance queue is reported unhealthy. 0 only reports
+	// the queue.
+	MaxDeadLetters int `yaml:"max_dead_letters"`
+}
+
+// FederationConfig is the config of the federation of the host managers
+// of several Peloton clusters.
+type FederationConfig struct {
+	// Name of the cluster of this host manager
+	Cluster string `yaml:"cluster"`
+
+	// Host managers of the other clusters. The federation is disabled
+	// if empty.
+	Peers []FederationPeerConfig `yaml:"peers"`
+
+	// Timeout of the calls to the peers
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// FederationPeerConfig is the config of a host manager of another cluster
+// of the federation.
+type FederationPeerConfig struct {
+	// Name of the cluster of the host manager
+	Cluster string `yaml:"cluster"`
+
+	// gRPC address of the host service of the host manager, host:port.
+	// The host manager must be the leader, or a follower in follower mode.
+	Address string `yaml:"address"`
 }
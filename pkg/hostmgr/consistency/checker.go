@@ -0,0 +1,477 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consistency cross-validates the state of the hosts held by host
+// manager, i.e. the agent map, the offer pool and the maintenance host
+// info map, with Mesos master, and fixes the drift between them.
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+	"github.com/uber/peloton/pkg/hostmgr/summary"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Timeout of declining the offers of the hosts with drift
+	_declineTimeout = 10 * time.Second
+)
+
+// Drift is a class of inconsistency between the state of the hosts in
+// host manager and in Mesos master.
+type Drift string
+
+const (
+	// DriftAgentMissing is an agent registered with Mesos master which
+	// is missing from the agent map. Fixed by reloading the agent map.
+	DriftAgentMissing Drift = "agent_missing"
+	// DriftAgentStale is an agent of the agent map which is no longer
+	// registered with Mesos master. Fixed by reloading the agent map.
+	DriftAgentStale Drift = "agent_stale"
+	// DriftMaintenanceMissing is a host in maintenance in Mesos master
+	// which is missing from the maintenance host info map. Fixed by
+	// adding the host to the map.
+	DriftMaintenanceMissing Drift = "maintenance_missing"
+	// DriftMaintenanceStale is a host of the maintenance host info map,
+	// e.g. DRAINING, which is not in maintenance in Mesos master. Fixed
+	// by removing the host from the map, i.e. the host is UP.
+	DriftMaintenanceStale Drift = "maintenance_stale"
+	// DriftOffersUnregistered is a host with offers in the offer pool
+	// whose agent is not in the agent map. Fixed by declining the offers.
+	DriftOffersUnregistered Drift = "offers_unregistered"
+	// DriftOffersDown is a DOWN host with offers in the offer pool.
+	// Fixed by declining the offers.
+	DriftOffersDown Drift = "offers_down"
+	// DriftStateMismatch is a host in different maintenance states in
+	// host manager and in Mesos master. Not fixed, since the transitions
+	// of the states are driven by the drains.
+	DriftStateMismatch Drift = "state_mismatch"
+	// DriftAgentOnDownHost is an agent registered with Mesos master on a
+	// host which is DOWN in the maintenance status. Not fixed, since the
+	// agent should have been shut down by Mesos master.
+	DriftAgentOnDownHost Drift = "agent_on_down_host"
+)
+
+// _drifts are all the classes of drift, for their metrics to be reset
+// once fixed
+var _drifts = []Drift{
+	DriftAgentMissing,
+	DriftAgentStale,
+	DriftMaintenanceMissing,
+	DriftMaintenanceStale,
+	DriftOffersUnregistered,
+	DriftOffersDown,
+	DriftStateMismatch,
+	DriftAgentOnDownHost,
+}
+
+// fixable returns whether the drift is fixed by the checker.
+func (d Drift) fixable() bool {
+	return d != DriftStateMismatch && d != DriftAgentOnDownHost
+}
+
+// finding is the drift of a host.
+type finding struct {
+	drift    Drift
+	hostname string
+}
+
+// masterState is the state of the hosts in Mesos master.
+type masterState struct {
+	// registered agents by hostname
+	agents map[string]*mesos_master.Response_GetAgents_Agent
+	// hosts in maintenance by hostname
+	maintenance map[string]*hpb.HostInfo
+	// DOWN hosts of the maintenance status
+	downMachines map[string]bool
+}
+
+// Checker periodically cross-validates the agent map, the offer pool and
+// the maintenance host info map with Mesos master. Drift is only acted
+// upon once found by two checks in a row, so that the state changing
+// while it is read, e.g. a maintenance being started, is not mistaken for
+// drift. Fixable drift is fixed, while the other drift is published on the
+// event bus, to be recorded in the host event log, once per host until it
+// is resolved.
+type Checker struct {
+	masterOperatorClient   mpb.MasterOperatorClient
+	maintenanceHostInfoMap host.MaintenanceHostInfoMap
+	offerPool              offerpool.Pool
+	// reloadAgentMap reloads the agent map from Mesos master
+	reloadAgentMap func()
+	// eventBus is where drift which is not fixed and the host state
+	// changes of the fixes are published. It is optional.
+	eventBus eventbus.Bus
+	config   Config
+	metrics  *Metrics
+
+	// drift found by the previous check, with its description
+	suspected map[finding]string
+	// drift already published on the event bus
+	reported map[finding]bool
+}
+
+// NewChecker returns a new consistency checker.
+func NewChecker(
+	config Config,
+	masterOperatorClient mpb.MasterOperatorClient,
+	maintenanceHostInfoMap host.MaintenanceHostInfoMap,
+	offerPool offerpool.Pool,
+	reloadAgentMap func(),
+	eventBus eventbus.Bus,
+	scope tally.Scope,
+) *Checker {
+	return &Checker{
+		masterOperatorClient:   masterOperatorClient,
+		maintenanceHostInfoMap: maintenanceHostInfoMap,
+		offerPool:              offerPool,
+		reloadAgentMap:         reloadAgentMap,
+		eventBus:               eventBus,
+		config:                 config,
+		metrics:                NewMetrics(scope),
+		suspected:              make(map[finding]string),
+		reported:               make(map[finding]bool),
+	}
+}
+
+// Check runs one check, and fixes or reports the drift found by this
+// check and by the previous one.
+func (c *Checker) Check(_ *atomic.Bool) {
+	c.metrics.Checks.Inc(1)
+	master, err := c.getMasterState()
+	if err != nil {
+		c.metrics.CheckFail.Inc(1)
+		log.WithError(err).Warn("Consistency check failed to get Mesos master state")
+		return
+	}
+
+	findings := c.findDrift(master)
+	confirmed := make(map[finding]string)
+	for f, message := range findings {
+		if _, ok := c.suspected[f]; ok {
+			confirmed[f] = message
+		}
+	}
+	c.suspected = findings
+	for f := range c.reported {
+		if _, ok := findings[f]; !ok {
+			delete(c.reported, f)
+		}
+	}
+
+	hosts := make(map[Drift]int)
+	for f := range confirmed {
+		hosts[f.drift]++
+	}
+	for _, drift := range _drifts {
+		c.metrics.Hosts(drift).Update(float64(hosts[drift]))
+	}
+	if len(confirmed) == 0 {
+		return
+	}
+
+	unfixed := confirmed
+	if !c.config.ReportOnly {
+		unfixed = c.fix(confirmed, master)
+	}
+	c.report(unfixed)
+}
+
+// getMasterState returns the registered agents and the hosts in
+// maintenance of Mesos master.
+func (c *Checker) getMasterState() (*masterState, error) {
+	agents, err := c.masterOperatorClient.Agents()
+	if err != nil {
+		return nil, err
+	}
+	status, err := c.masterOperatorClient.GetMaintenanceStatus()
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := c.masterOperatorClient.GetMaintenanceSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	master := &masterState{
+		agents:       make(map[string]*mesos_master.Response_GetAgents_Agent),
+		maintenance:  make(map[string]*hpb.HostInfo),
+		downMachines: make(map[string]bool),
+	}
+	for _, agent := range agents.GetAgents() {
+		if hostname := agent.GetAgentInfo().GetHostname(); hostname != "" {
+			master.agents[hostname] = agent
+		}
+	}
+	for _, hostInfo := range host.MasterMaintenanceHostInfos(
+		status, schedule, agents.GetAgents()) {
+		master.maintenance[hostInfo.GetHostname()] = hostInfo
+	}
+	for _, machine := range status.GetStatus().GetDownMachines() {
+		master.downMachines[machine.GetHostname()] = true
+	}
+	return master, nil
+}
+
+// findDrift returns the drift between host manager and Mesos master, with
+// the description of each finding.
+func (c *Checker) findDrift(master *masterState) map[finding]string {
+	findings := make(map[finding]string)
+	add := func(drift Drift, hostname string, format string, args ...interface{}) {
+		findings[finding{drift: drift, hostname: hostname}] =
+			fmt.Sprintf(format, args...)
+	}
+
+	current := make(map[string]*hpb.HostInfo)
+	for _, hostInfo := range append(
+		c.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}),
+		c.maintenanceHostInfoMap.GetDownHostInfos([]string{})...) {
+		current[hostInfo.GetHostname()] = hostInfo
+	}
+
+	// The agent checks are skipped until the agent map is loaded
+	var registered map[string]*mesos_master.Response_GetAgents_Agent
+	if agentMap := host.GetAgentMap(); agentMap != nil {
+		registered = agentMap.RegisteredAgents
+		for hostname := range master.agents {
+			// The agents of DRAINING hosts are left out of the agent map
+			if current[hostname].GetState() == hpb.HostState_HOST_STATE_DRAINING {
+				continue
+			}
+			if _, ok := registered[hostname]; !ok {
+				add(DriftAgentMissing, hostname,
+					"agent registered with Mesos master is missing from the agent map")
+			}
+		}
+		for hostname := range registered {
+			if _, ok := master.agents[hostname]; !ok {
+				add(DriftAgentStale, hostname,
+					"agent of the agent map is not registered with Mesos master")
+			}
+		}
+	}
+
+	for hostname, hostInfo := range current {
+		masterInfo, ok := master.maintenance[hostname]
+		switch {
+		case !ok:
+			add(DriftMaintenanceStale, hostname,
+				"host is %s in host manager but not in maintenance in Mesos master",
+				hostInfo.GetState())
+		case masterInfo.GetState() != hostInfo.GetState():
+			add(DriftStateMismatch, hostname,
+				"host is %s in host manager but %s in Mesos master",
+				hostInfo.GetState(), masterInfo.GetState())
+		}
+	}
+	for hostname, masterInfo := range master.maintenance {
+		if _, ok := current[hostname]; !ok {
+			add(DriftMaintenanceMissing, hostname,
+				"host is %s in Mesos master but not in maintenance in host manager",
+				masterInfo.GetState())
+		}
+	}
+
+	offers, _ := c.offerPool.GetOffers(summary.All)
+	for hostname, hostOffers := range offers {
+		if len(hostOffers) == 0 {
+			continue
+		}
+		state := current[hostname].GetState()
+		if _, ok := registered[hostname]; registered != nil && !ok &&
+			state != hpb.HostState_HOST_STATE_DRAINING {
+			add(DriftOffersUnregistered, hostname,
+				"offer pool holds %d offers of an agent missing from the agent map",
+				len(hostOffers))
+		} else if state == hpb.HostState_HOST_STATE_DOWN {
+			add(DriftOffersDown, hostname,
+				"offer pool holds %d offers of a DOWN host", len(hostOffers))
+		}
+	}
+
+	for hostname := range master.downMachines {
+		if _, ok := master.agents[hostname]; ok {
+			add(DriftAgentOnDownHost, hostname,
+				"agent is registered with Mesos master on a DOWN host")
+		}
+	}
+	return findings
+}
+
+// fix fixes the fixable drift. Returns the drift which is not fixed.
+func (c *Checker) fix(
+	confirmed map[finding]string,
+	master *masterState) map[finding]string {
+	unfixed := make(map[finding]string)
+	byDrift := make(map[Drift][]string)
+	for f, message := range confirmed {
+		if !f.drift.fixable() {
+			unfixed[f] = message
+			continue
+		}
+		byDrift[f.drift] = append(byDrift[f.drift], f.hostname)
+	}
+	for _, hostnames := range byDrift {
+		sort.Strings(hostnames)
+	}
+
+	agentHosts := append(byDrift[DriftAgentMissing], byDrift[DriftAgentStale]...)
+	if len(agentHosts) > 0 {
+		log.WithField("hosts", agentHosts).
+			Warn("Agent map diverged from Mesos master, reloading it")
+		c.reloadAgentMap()
+		c.metrics.Fixed(DriftAgentMissing).Inc(int64(len(byDrift[DriftAgentMissing])))
+		c.metrics.Fixed(DriftAgentStale).Inc(int64(len(byDrift[DriftAgentStale])))
+	}
+
+	if hostnames := byDrift[DriftMaintenanceStale]; len(hostnames) > 0 {
+		c.removeStaleMaintenance(hostnames)
+	}
+	if hostnames := byDrift[DriftMaintenanceMissing]; len(hostnames) > 0 {
+		c.addMissingMaintenance(hostnames, master)
+	}
+
+	for _, drift := range []Drift{DriftOffersUnregistered, DriftOffersDown} {
+		if hostnames := byDrift[drift]; len(hostnames) > 0 {
+			c.declineOffers(drift, hostnames)
+		}
+	}
+	return unfixed
+}
+
+// removeStaleMaintenance removes the hosts which are not in maintenance
+// in Mesos master from the maintenance host info map.
+func (c *Checker) removeStaleMaintenance(hostnames []string) {
+	byState := make(map[hpb.HostState][]string)
+	for _, hostInfo := range append(
+		c.maintenanceHostInfoMap.GetDrainingHostInfos(hostnames),
+		c.maintenanceHostInfoMap.GetDownHostInfos(hostnames)...) {
+		byState[hostInfo.GetState()] = append(
+			byState[hostInfo.GetState()], hostInfo.GetHostname())
+	}
+
+	log.WithField("hosts", hostnames).
+		Warn("Hosts not in maintenance in Mesos master, removing them from maintenance")
+	c.maintenanceHostInfoMap.RemoveHostInfos(hostnames)
+	c.metrics.Fixed(DriftMaintenanceStale).Inc(int64(len(hostnames)))
+	for state, stateHostnames := range byState {
+		c.publish(&eventbus.HostStateChangedEvent{
+			Hostnames: stateHostnames,
+			From:      state,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
+	}
+}
+
+// addMissingMaintenance adds the hosts in maintenance in Mesos master to
+// the maintenance host info map.
+func (c *Checker) addMissingMaintenance(
+	hostnames []string,
+	master *masterState) {
+	var hostInfos []*hpb.HostInfo
+	byState := make(map[hpb.HostState][]string)
+	for _, hostname := range hostnames {
+		hostInfo := master.maintenance[hostname]
+		hostInfos = append(hostInfos, hostInfo)
+		byState[hostInfo.GetState()] = append(
+			byState[hostInfo.GetState()], hostname)
+	}
+
+	log.WithField("hosts", hostnames).
+		Warn("Hosts in maintenance in Mesos master, adding them to maintenance")
+	c.maintenanceHostInfoMap.AddHostInfos(hostInfos)
+	c.metrics.Fixed(DriftMaintenanceMissing).Inc(int64(len(hostnames)))
+	for state, stateHostnames := range byState {
+		c.publish(&eventbus.HostStateChangedEvent{
+			Hostnames: stateHostnames,
+			From:      hpb.HostState_HOST_STATE_UP,
+			To:        state,
+		})
+	}
+}
+
+// declineOffers declines the offers of the hosts from the offer pool.
+func (c *Checker) declineOffers(drift Drift, hostnames []string) {
+	offers, _ := c.offerPool.GetOffers(summary.All)
+	var offerIDs []*mesos.OfferID
+	for _, hostname := range hostnames {
+		for _, offer := range offers[hostname] {
+			offerIDs = append(offerIDs, offer.GetId())
+		}
+	}
+	if len(offerIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), _declineTimeout)
+	defer cancel()
+	if err := c.offerPool.DeclineOffers(ctx, offerIDs); err != nil {
+		c.metrics.FixFail(drift).Inc(int64(len(hostnames)))
+		log.WithError(err).
+			WithFields(log.Fields{
+				"hosts": hostnames,
+				"drift": drift,
+			}).Warn("Failed to decline offers of hosts")
+		return
+	}
+	log.WithFields(log.Fields{
+		"hosts":  hostnames,
+		"drift":  drift,
+		"offers": len(offerIDs),
+	}).Warn("Declined offers of hosts")
+	c.metrics.Fixed(drift).Inc(int64(len(hostnames)))
+}
+
+// report publishes the drift which is not fixed on the event bus, once
+// per host until the drift is resolved.
+func (c *Checker) report(unfixed map[finding]string) {
+	for f, message := range unfixed {
+		if c.reported[f] {
+			continue
+		}
+		c.reported[f] = true
+		c.metrics.Unfixed(f.drift).Inc(1)
+		log.WithFields(log.Fields{
+			"hostname": f.hostname,
+			"drift":    f.drift,
+		}).Warn(message)
+		c.publish(&eventbus.HostDriftDetectedEvent{
+			Hostname: f.hostname,
+			Drift:    string(f.drift),
+			Message:  message,
+		})
+	}
+}
+
+// publish publishes an event on the event bus, if any.
+func (c *Checker) publish(event eventbus.Event) {
+	if c.eventBus != nil {
+		c.eventBus.Publish(event)
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consistency
+
+import (
+	"fmt"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ebmocks "github.com/uber/peloton/pkg/hostmgr/eventbus/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+	offerpool_mocks "github.com/uber/peloton/pkg/hostmgr/offer/offerpool/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/summary"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type CheckerTestSuite struct {
+	suite.Suite
+
+	ctrl                     *gomock.Controller
+	testScope                tally.TestScope
+	mockMasterOperatorClient *mpb_mocks.MockMasterOperatorClient
+	mockOfferPool            *offerpool_mocks.MockPool
+	mockEventBus             *ebmocks.MockBus
+	maintenanceHostInfoMap   host.MaintenanceHostInfoMap
+	reloads                  int
+	checker                  *Checker
+}
+
+func (suite *CheckerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.testScope = tally.NewTestScope("", map[string]string{})
+	suite.mockMasterOperatorClient = mpb_mocks.NewMockMasterOperatorClient(suite.ctrl)
+	suite.mockOfferPool = offerpool_mocks.NewMockPool(suite.ctrl)
+	suite.mockEventBus = ebmocks.NewMockBus(suite.ctrl)
+	suite.maintenanceHostInfoMap = host.NewMaintenanceHostInfoMap(tally.NoopScope)
+	suite.reloads = 0
+	suite.checker = suite.newChecker(Config{})
+}
+
+func (suite *CheckerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestCheckerTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckerTestSuite))
+}
+
+func (suite *CheckerTestSuite) newChecker(config Config) *Checker {
+	return NewChecker(
+		config,
+		suite.mockMasterOperatorClient,
+		suite.maintenanceHostInfoMap,
+		suite.mockOfferPool,
+		func() { suite.reloads++ },
+		suite.mockEventBus,
+		suite.testScope)
+}
+
+func newAgents(hostnames ...string) *mesos_master.Response_GetAgents {
+	agents := &mesos_master.Response_GetAgents{}
+	for _, hostname := range hostnames {
+		hostname := hostname
+		agents.Agents = append(agents.Agents, &mesos_master.Response_GetAgents_Agent{
+			AgentInfo: &mesos.AgentInfo{Hostname: &hostname},
+		})
+	}
+	return agents
+}
+
+func newMachineID(hostname string) *mesos.MachineID {
+	return &mesos.MachineID{Hostname: &hostname}
+}
+
+func newOffers(hostnames ...string) map[string]map[string]*mesos.Offer {
+	offers := make(map[string]map[string]*mesos.Offer)
+	for _, hostname := range hostnames {
+		offerID := fmt.Sprintf("%s-offer", hostname)
+		offers[hostname] = map[string]*mesos.Offer{
+			offerID: {Id: &mesos.OfferID{Value: &offerID}},
+		}
+	}
+	return offers
+}
+
+// loadAgentMap loads the agent map with the agents of the given hosts.
+func (suite *CheckerTestSuite) loadAgentMap(hostnames ...string) {
+	loader := &host.Loader{
+		OperatorClient:         suite.mockMasterOperatorClient,
+		MaintenanceHostInfoMap: suite.maintenanceHostInfoMap,
+		Scope:                  tally.NoopScope,
+	}
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(newAgents(hostnames...), nil)
+	loader.Load(nil)
+}
+
+// expectMaster sets up the given number of checks to find the agents of
+// the given hosts registered with Mesos master, with the given DRAINING
+// and DOWN hosts.
+func (suite *CheckerTestSuite) expectMaster(
+	checks int,
+	agents []string,
+	draining []string,
+	down []string) {
+	status := &mesos_maintenance.ClusterStatus{}
+	for _, hostname := range draining {
+		status.DrainingMachines = append(
+			status.DrainingMachines,
+			&mesos_maintenance.ClusterStatus_DrainingMachine{
+				Id: newMachineID(hostname),
+			})
+	}
+	for _, hostname := range down {
+		status.DownMachines = append(status.DownMachines, newMachineID(hostname))
+	}
+
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(newAgents(agents...), nil).
+		Times(checks)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{Status: status}, nil).
+		Times(checks)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{}, nil).
+		Times(checks)
+}
+
+func (suite *CheckerTestSuite) counter(name string, drift Drift) int64 {
+	key := name + "+"
+	if drift != "" {
+		key += "drift=" + string(drift)
+	}
+	counter, ok := suite.testScope.Snapshot().Counters()[key]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func (suite *CheckerTestSuite) driftHosts(drift Drift) float64 {
+	gauge, ok := suite.testScope.Snapshot().
+		Gauges()["drift_hosts+drift="+string(drift)]
+	if !ok {
+		return 0
+	}
+	return gauge.Value()
+}
+
+// TestCheckNoDrift tests that nothing is fixed or reported when host
+// manager agrees with Mesos master
+func (suite *CheckerTestSuite) TestCheckNoDrift() {
+	suite.loadAgentMap("host1", "host2")
+	suite.maintenanceHostInfoMap.AddHostInfos([]*hpb.HostInfo{
+		{Hostname: "host3", State: hpb.HostState_HOST_STATE_DRAINING},
+	})
+	suite.expectMaster(2, []string{"host1", "host2", "host3"}, []string{"host3"}, nil)
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers("host1"), 1).
+		Times(2)
+
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.Equal(0, suite.reloads)
+	suite.Equal(int64(2), suite.counter("checks", ""))
+	for _, drift := range _drifts {
+		suite.Zero(suite.driftHosts(drift))
+	}
+}
+
+// TestCheckMasterError tests that a check fails when the state of Mesos
+// master cannot be read
+func (suite *CheckerTestSuite) TestCheckMasterError() {
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(nil, fmt.Errorf("fake Agents error"))
+
+	suite.checker.Check(nil)
+	suite.Equal(int64(1), suite.counter("check_fail", ""))
+}
+
+// TestCheckFixesStaleMaintenance tests that a DRAINING host which is not
+// in maintenance in Mesos master is brought back UP, once found by two
+// checks in a row
+func (suite *CheckerTestSuite) TestCheckFixesStaleMaintenance() {
+	suite.loadAgentMap("host1")
+	suite.maintenanceHostInfoMap.AddHostInfos([]*hpb.HostInfo{
+		{Hostname: "host1", State: hpb.HostState_HOST_STATE_DRAINING},
+	})
+	suite.expectMaster(2, []string{"host1"}, nil, nil)
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers(), 0).
+		Times(2)
+
+	suite.checker.Check(nil)
+	suite.Len(suite.maintenanceHostInfoMap.GetDrainingHostInfos(nil), 1)
+	suite.Zero(suite.driftHosts(DriftMaintenanceStale))
+
+	suite.mockEventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host1"},
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_UP,
+	})
+	suite.checker.Check(nil)
+	suite.Empty(suite.maintenanceHostInfoMap.GetDrainingHostInfos(nil))
+	suite.Equal(float64(1), suite.driftHosts(DriftMaintenanceStale))
+	suite.Equal(int64(1), suite.counter("fixed", DriftMaintenanceStale))
+}
+
+// TestCheckFixesMissingMaintenance tests that a host DOWN in Mesos master
+// is added to the maintenance host info map
+func (suite *CheckerTestSuite) TestCheckFixesMissingMaintenance() {
+	suite.loadAgentMap("host1")
+	suite.expectMaster(2, []string{"host1"}, nil, []string{"host2"})
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers(), 0).
+		Times(2)
+	suite.mockEventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host2"},
+		From:      hpb.HostState_HOST_STATE_UP,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	downHostInfos := suite.maintenanceHostInfoMap.GetDownHostInfos(nil)
+	suite.Len(downHostInfos, 1)
+	suite.Equal("host2", downHostInfos[0].GetHostname())
+	suite.Equal(int64(1), suite.counter("fixed", DriftMaintenanceMissing))
+}
+
+// TestCheckReloadsAgentMap tests that the agent map is reloaded when it
+// diverges from the agents registered with Mesos master
+func (suite *CheckerTestSuite) TestCheckReloadsAgentMap() {
+	suite.loadAgentMap("host1", "host2")
+	suite.expectMaster(2, []string{"host1", "host3"}, nil, nil)
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers(), 0).
+		Times(2)
+
+	suite.checker.Check(nil)
+	suite.Equal(0, suite.reloads)
+	suite.checker.Check(nil)
+	suite.Equal(1, suite.reloads)
+	suite.Equal(int64(1), suite.counter("fixed", DriftAgentMissing))
+	suite.Equal(int64(1), suite.counter("fixed", DriftAgentStale))
+}
+
+// TestCheckDeclinesOffers tests that the offers of unregistered and DOWN
+// hosts are declined
+func (suite *CheckerTestSuite) TestCheckDeclinesOffers() {
+	suite.loadAgentMap("host1", "host2")
+	suite.maintenanceHostInfoMap.AddHostInfos([]*hpb.HostInfo{
+		{Hostname: "host2", State: hpb.HostState_HOST_STATE_DOWN},
+	})
+	suite.expectMaster(2, []string{"host1", "host2"}, nil, []string{"host2"})
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers("host1", "host2", "host3"), 3).
+		Times(4)
+
+	host2OfferID := "host2-offer"
+	host3OfferID := "host3-offer"
+	suite.mockOfferPool.EXPECT().
+		DeclineOffers(gomock.Any(), []*mesos.OfferID{{Value: &host3OfferID}}).
+		Return(nil)
+	suite.mockOfferPool.EXPECT().
+		DeclineOffers(gomock.Any(), []*mesos.OfferID{{Value: &host2OfferID}}).
+		Return(fmt.Errorf("fake DeclineOffers error"))
+	// The agent of host2 is registered on a DOWN host
+	suite.mockEventBus.EXPECT().Publish(gomock.Any())
+
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.Equal(int64(1), suite.counter("fixed", DriftOffersUnregistered))
+	suite.Equal(int64(1), suite.counter("fix_fail", DriftOffersDown))
+}
+
+// TestCheckReportsUnfixedDriftOnce tests that drift which cannot be fixed
+// is reported once until it is resolved
+func (suite *CheckerTestSuite) TestCheckReportsUnfixedDriftOnce() {
+	suite.loadAgentMap("host1")
+	suite.maintenanceHostInfoMap.AddHostInfos([]*hpb.HostInfo{
+		{Hostname: "host1", State: hpb.HostState_HOST_STATE_DOWN},
+	})
+	suite.expectMaster(3, []string{"host1"}, nil, []string{"host1"})
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers(), 0).
+		Times(6)
+	suite.mockEventBus.EXPECT().Publish(&eventbus.HostDriftDetectedEvent{
+		Hostname: "host1",
+		Drift:    string(DriftAgentOnDownHost),
+		Message:  "agent is registered with Mesos master on a DOWN host",
+	})
+
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.Equal(int64(1), suite.counter("unfixed", DriftAgentOnDownHost))
+
+	// The drift is reported again once resolved and found anew
+	suite.expectMaster(1, []string{"host1"}, nil, nil)
+	suite.checker.Check(nil)
+	suite.expectMaster(2, []string{"host1"}, nil, []string{"host1"})
+	suite.mockEventBus.EXPECT().Publish(gomock.Any())
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.Equal(int64(2), suite.counter("unfixed", DriftAgentOnDownHost))
+}
+
+// TestCheckReportOnly tests that drift is reported without being fixed
+// in report only mode
+func (suite *CheckerTestSuite) TestCheckReportOnly() {
+	suite.checker = suite.newChecker(Config{ReportOnly: true})
+	suite.loadAgentMap("host1")
+	suite.maintenanceHostInfoMap.AddHostInfos([]*hpb.HostInfo{
+		{Hostname: "host1", State: hpb.HostState_HOST_STATE_DRAINING},
+	})
+	suite.expectMaster(2, []string{"host1"}, nil, nil)
+	suite.mockOfferPool.EXPECT().
+		GetOffers(summary.All).
+		Return(newOffers(), 0).
+		Times(2)
+	suite.mockEventBus.EXPECT().Publish(&eventbus.HostDriftDetectedEvent{
+		Hostname: "host1",
+		Drift:    string(DriftMaintenanceStale),
+		Message: "host is HOST_STATE_DRAINING in host manager " +
+			"but not in maintenance in Mesos master",
+	})
+
+	suite.checker.Check(nil)
+	suite.checker.Check(nil)
+	suite.Len(suite.maintenanceHostInfoMap.GetDrainingHostInfos(nil), 1)
+	suite.Zero(suite.counter("fixed", DriftMaintenanceStale))
+	suite.Equal(int64(1), suite.counter("unfixed", DriftMaintenanceStale))
+}
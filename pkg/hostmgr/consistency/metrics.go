@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consistency
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics of the consistency checker.
+type Metrics struct {
+	scope tally.Scope
+
+	Checks    tally.Counter
+	CheckFail tally.Counter
+}
+
+// NewMetrics returns a new instance of Metrics.
+func NewMetrics(scope tally.Scope) *Metrics {
+	return &Metrics{
+		scope:     scope,
+		Checks:    scope.Counter("checks"),
+		CheckFail: scope.Counter("check_fail"),
+	}
+}
+
+// driftScope returns the scope of the metrics of a class of drift.
+func (m *Metrics) driftScope(drift Drift) tally.Scope {
+	return m.scope.Tagged(map[string]string{"drift": string(drift)})
+}
+
+// Hosts is the number of hosts with the given drift found by the last
+// check.
+func (m *Metrics) Hosts(drift Drift) tally.Gauge {
+	return m.driftScope(drift).Gauge("drift_hosts")
+}
+
+// Fixed counts the hosts whose drift was fixed.
+func (m *Metrics) Fixed(drift Drift) tally.Counter {
+	return m.driftScope(drift).Counter("fixed")
+}
+
+// FixFail counts the failures to fix the drift of hosts.
+func (m *Metrics) FixFail(drift Drift) tally.Counter {
+	return m.driftScope(drift).Counter("fix_fail")
+}
+
+// Unfixed counts the hosts whose drift was reported without being fixed.
+func (m *Metrics) Unfixed(drift Drift) tally.Counter {
+	return m.driftScope(drift).Counter("unfixed")
+}
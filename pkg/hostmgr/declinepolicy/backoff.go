@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"math"
+	"sync"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	_defaultMinRefuseSeconds  = 5.0
+	_defaultMaxRefuseSeconds  = 300.0
+	_defaultMismatchThreshold = 3
+	_defaultBackoffMultiplier = 2.0
+)
+
+// backoffPolicy declines the offers of a host with refuse_seconds growing
+// exponentially with the number of consecutive constraint mismatches of the
+// host, once they reach the mismatch threshold. A match resets the backoff.
+type backoffPolicy struct {
+	sync.Mutex
+
+	name   string
+	config Config
+
+	// map of hostname to the number of consecutive constraint mismatches
+	mismatches map[string]int
+	// number of hosts which reached the mismatch threshold
+	backedOffHosts int
+
+	metrics *Metrics
+}
+
+// NewBackoffPolicy returns the backoff decline policy object
+func NewBackoffPolicy(config Config, scope tally.Scope) Policy {
+	if config.MinRefuseSeconds <= 0 {
+		config.MinRefuseSeconds = _defaultMinRefuseSeconds
+	}
+	if config.MaxRefuseSeconds < config.MinRefuseSeconds {
+		config.MaxRefuseSeconds = math.Max(
+			_defaultMaxRefuseSeconds, config.MinRefuseSeconds)
+	}
+	if config.MismatchThreshold <= 0 {
+		config.MismatchThreshold = _defaultMismatchThreshold
+	}
+	if config.BackoffMultiplier < 1 {
+		config.BackoffMultiplier = _defaultBackoffMultiplier
+	}
+
+	return &backoffPolicy{
+		name:       Backoff,
+		config:     config,
+		mismatches: make(map[string]int),
+		metrics:    NewMetrics(scope),
+	}
+}
+
+// Name is implementation of Policy.Name
+func (p *backoffPolicy) Name() string {
+	return p.name
+}
+
+// RecordMatchResult is implementation of Policy.RecordMatchResult
+// Constraint mismatches count towards the backoff of the host, and a match
+// resets it. Other results are ignored as they do not tell whether the host
+// is useful to the framework.
+func (p *backoffPolicy) RecordMatchResult(
+	hostname string,
+	result hostsvc.HostFilterResult) {
+	p.Lock()
+	defer p.Unlock()
+
+	switch result {
+	case hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS:
+		p.mismatches[hostname]++
+		p.metrics.Mismatch.Inc(1)
+		if p.mismatches[hostname] == p.config.MismatchThreshold {
+			p.backedOffHosts++
+		}
+	case hostsvc.HostFilterResult_MATCH:
+		if p.mismatches[hostname] >= p.config.MismatchThreshold {
+			p.backedOffHosts--
+			p.metrics.Reset.Inc(1)
+		}
+		delete(p.mismatches, hostname)
+	default:
+		return
+	}
+	p.metrics.BackedOffHosts.Update(float64(p.backedOffHosts))
+}
+
+// RefuseSeconds is implementation of Policy.RefuseSeconds
+func (p *backoffPolicy) RefuseSeconds(hostname string) float64 {
+	p.Lock()
+	defer p.Unlock()
+
+	mismatches := p.mismatches[hostname]
+	if mismatches < p.config.MismatchThreshold {
+		return 0
+	}
+
+	refuseSeconds := math.Min(
+		p.config.MinRefuseSeconds*math.Pow(
+			p.config.BackoffMultiplier,
+			float64(mismatches-p.config.MismatchThreshold)),
+		p.config.MaxRefuseSeconds)
+	p.metrics.RefuseSeconds.Update(refuseSeconds)
+	return refuseSeconds
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type BackoffPolicyTestSuite struct {
+	suite.Suite
+
+	scope  tally.TestScope
+	policy *backoffPolicy
+}
+
+func TestBackoffPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(BackoffPolicyTestSuite))
+}
+
+func (suite *BackoffPolicyTestSuite) SetupTest() {
+	suite.scope = tally.NewTestScope("", map[string]string{})
+	suite.policy = NewBackoffPolicy(Config{
+		MinRefuseSeconds:  10,
+		MaxRefuseSeconds:  50,
+		MismatchThreshold: 2,
+		BackoffMultiplier: 2,
+	}, suite.scope).(*backoffPolicy)
+}
+
+func (suite *BackoffPolicyTestSuite) mismatch(hostname string, times int) {
+	for i := 0; i < times; i++ {
+		suite.policy.RecordMatchResult(
+			hostname,
+			hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS)
+	}
+}
+
+// TestBackoff tests that refuse seconds grow exponentially once the
+// mismatch threshold is reached, up to the max refuse seconds
+func (suite *BackoffPolicyTestSuite) TestBackoff() {
+	suite.Zero(suite.policy.RefuseSeconds("host"))
+
+	suite.mismatch("host", 1)
+	suite.Zero(suite.policy.RefuseSeconds("host"))
+
+	expected := []float64{10, 20, 40, 50, 50}
+	for _, refuseSeconds := range expected {
+		suite.mismatch("host", 1)
+		suite.Equal(refuseSeconds, suite.policy.RefuseSeconds("host"))
+	}
+
+	// Other hosts are not affected
+	suite.Zero(suite.policy.RefuseSeconds("other"))
+	suite.Equal(1.0, suite.scope.Snapshot().Gauges()["backed_off_hosts+"].Value())
+}
+
+// TestReset tests that a match resets the backoff of the host
+func (suite *BackoffPolicyTestSuite) TestReset() {
+	suite.mismatch("host", 3)
+	suite.Equal(20.0, suite.policy.RefuseSeconds("host"))
+
+	// Results other than match and constraint mismatch are ignored
+	suite.policy.RecordMatchResult("host", hostsvc.HostFilterResult_NO_OFFER)
+	suite.policy.RecordMatchResult("host", hostsvc.HostFilterResult_MISMATCH_STATUS)
+	suite.Equal(20.0, suite.policy.RefuseSeconds("host"))
+
+	suite.policy.RecordMatchResult("host", hostsvc.HostFilterResult_MATCH)
+	suite.Zero(suite.policy.RefuseSeconds("host"))
+	suite.Empty(suite.policy.mismatches)
+	suite.Zero(suite.policy.backedOffHosts)
+	suite.EqualValues(1, suite.scope.Snapshot().Counters()["reset+"].Value())
+	suite.EqualValues(3, suite.scope.Snapshot().Counters()["mismatch+"].Value())
+}
+
+// TestDefaultConfig tests that invalid config values are defaulted
+func (suite *BackoffPolicyTestSuite) TestDefaultConfig() {
+	policy := NewBackoffPolicy(Config{}, tally.NoopScope).(*backoffPolicy)
+	suite.Equal(Config{
+		MinRefuseSeconds:  _defaultMinRefuseSeconds,
+		MaxRefuseSeconds:  _defaultMaxRefuseSeconds,
+		MismatchThreshold: _defaultMismatchThreshold,
+		BackoffMultiplier: _defaultBackoffMultiplier,
+	}, policy.config)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Default is the name of the policy which declines offers with the
+	// Mesos default refuse_seconds
+	Default = "DEFAULT"
+
+	// Backoff is the name of the policy which backs off refuse_seconds for
+	// hosts which repeatedly fail to match constraints
+	Backoff = "BACKOFF"
+)
+
+// Config is the configuration of the offer decline policy
+type Config struct {
+	// Name of the decline policy, DEFAULT if not specified
+	Name string `yaml:"name"`
+
+	// Refuse seconds used once a host reaches the mismatch threshold
+	MinRefuseSeconds float64 `yaml:"min_refuse_seconds"`
+
+	// Upper bound of the refuse seconds of a host
+	MaxRefuseSeconds float64 `yaml:"max_refuse_seconds"`
+
+	// Number of consecutive constraint mismatches of a host after which
+	// the refuse seconds of the host are backed off
+	MismatchThreshold int `yaml:"mismatch_threshold"`
+
+	// Factor by which the refuse seconds grow with each further mismatch
+	BackoffMultiplier float64 `yaml:"backoff_multiplier"`
+}
+
+// PolicyFunc type of func which returns Policy interface
+type PolicyFunc func(config Config, scope tally.Scope) Policy
+
+// map of policy name to Init Policy Func
+var policies = make(map[string]PolicyFunc)
+
+// Register registers the policy and keep it in the
+// policy map.
+func Register(name string, policy PolicyFunc) {
+	log.Infof("Registering %s decline policy", name)
+	if policy == nil {
+		log.Errorf("decline policy does not exist")
+		return
+	}
+	if _, registered := policies[name]; registered {
+		log.Errorf("decline policy already registered")
+		return
+	}
+	policies[name] = policy
+}
+
+// Init registers all the policies
+func Init() {
+	Register(Default, NewDefaultPolicy)
+	Register(Backoff, NewBackoffPolicy)
+}
+
+// CreatePolicy creates and returns the policy specified in the config
+func CreatePolicy(config Config, scope tally.Scope) Policy {
+	name := config.Name
+	if name == "" {
+		name = Default
+	}
+	policy, ok := policies[name]
+	if !ok {
+		log.WithField("name", name).Errorf("Decline policy is not registered")
+		return nil
+	}
+	return policy(config, scope.SubScope("decline_policy"))
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type DeclinePolicyTestSuite struct {
+	suite.Suite
+}
+
+func TestDeclinePolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(DeclinePolicyTestSuite))
+}
+
+func (suite *DeclinePolicyTestSuite) SetupTest() {
+	Init()
+}
+
+func (suite *DeclinePolicyTestSuite) TestInit() {
+	suite.EqualValues(Default, policies[Default](Config{}, tally.NoopScope).Name())
+	suite.EqualValues(Backoff, policies[Backoff](Config{}, tally.NoopScope).Name())
+}
+
+func (suite *DeclinePolicyTestSuite) TestRegister() {
+	policies[Default] = nil
+	Register(Default, nil)
+	suite.Nil(policies[Default])
+	Register(Default, NewDefaultPolicy)
+	suite.Nil(policies[Default])
+	delete(policies, Default)
+	Register(Default, NewDefaultPolicy)
+	suite.NotNil(policies[Default])
+}
+
+func (suite *DeclinePolicyTestSuite) TestCreatePolicy() {
+	policy := CreatePolicy(Config{}, tally.NoopScope)
+	suite.EqualValues(Default, policy.Name())
+	policy = CreatePolicy(Config{Name: Backoff}, tally.NoopScope)
+	suite.EqualValues(Backoff, policy.Name())
+	policy = CreatePolicy(Config{Name: "Not_existing"}, tally.NoopScope)
+	suite.Nil(policy)
+}
+
+func (suite *DeclinePolicyTestSuite) TestDefaultPolicy() {
+	policy := NewDefaultPolicy(Config{}, tally.NoopScope)
+	for i := 0; i < 10; i++ {
+		policy.RecordMatchResult("host", hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS)
+	}
+	suite.Zero(policy.RefuseSeconds("host"))
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber-go/tally"
+)
+
+// defaultPolicy declines all offers with the Mesos default refuse_seconds
+type defaultPolicy struct {
+	name string
+}
+
+// NewDefaultPolicy returns the default decline policy object
+func NewDefaultPolicy(config Config, scope tally.Scope) Policy {
+	return &defaultPolicy{name: Default}
+}
+
+// Name is implementation of Policy.Name
+func (p *defaultPolicy) Name() string {
+	return p.name
+}
+
+// RecordMatchResult is implementation of Policy.RecordMatchResult
+// This is no op for the default policy
+func (p *defaultPolicy) RecordMatchResult(
+	hostname string,
+	result hostsvc.HostFilterResult) {
+}
+
+// RefuseSeconds is implementation of Policy.RefuseSeconds
+func (p *defaultPolicy) RefuseSeconds(hostname string) float64 {
+	return 0
+}
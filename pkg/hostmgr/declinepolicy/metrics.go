@@ -0,0 +1,43 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics is the struct containing all the counters that track internal
+// state of the backoff decline policy
+type Metrics struct {
+	// Number of constraint mismatches recorded
+	Mismatch tally.Counter
+	// Number of hosts whose backoff got reset by a match
+	Reset tally.Counter
+	// Number of hosts whose refuse seconds are backed off
+	BackedOffHosts tally.Gauge
+	// Refuse seconds returned for declining offers
+	RefuseSeconds tally.Gauge
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics
+// initialized and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	return &Metrics{
+		Mismatch:       scope.Counter("mismatch"),
+		Reset:          scope.Counter("reset"),
+		BackedOffHosts: scope.Gauge("backed_off_hosts"),
+		RefuseSeconds:  scope.Gauge("refuse_seconds"),
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package declinepolicy
+
+import (
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+// Policy is the interface for deciding the Mesos filter refuse_seconds used
+// when declining the offers of a host. Mesos does not send offers of a host
+// to the framework again until refuse_seconds expire, so a longer value
+// reduces the offer churn of hosts which are not useful to the framework.
+type Policy interface {
+	// Returns the name of the policy implementation
+	Name() string
+	// RecordMatchResult records the result of matching the offers of
+	// a host against a host filter
+	RecordMatchResult(hostname string, result hostsvc.HostFilterResult)
+	// RefuseSeconds returns the refuse_seconds to decline the offers of the
+	// host with. A value of 0 means that the Mesos default is used.
+	RefuseSeconds(hostname string) float64
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// _drainNotificationType is the type of the framework message sent to the
+// executor of a task before the task is killed on a DRAINING host.
+const _drainNotificationType = "peloton.drain"
+
+// drainNotification is the payload of the framework message sent to the
+// executor of a task before the task is killed on a DRAINING host, so that
+// the service can deregister from load balancers.
+type drainNotification struct {
+	Type                   string            `json:"type"`
+	Hostname               string            `json:"hostname"`
+	TaskID                 string            `json:"task_id"`
+	Message                string            `json:"message,omitempty"`
+	Labels                 map[string]string `json:"labels,omitempty"`
+	KillGracePeriodSeconds uint32            `json:"kill_grace_period_seconds,omitempty"`
+}
+
+// getDrainingHosts returns the host info of the DRAINING host each of
+// the given tasks is running on. Tasks which are not on a DRAINING host,
+// or whose host was drained without options, are not returned.
+func (h *ServiceHandler) getDrainingHosts(
+	taskIDs []*mesos.TaskID) map[string]*hpb.HostInfo {
+	hostInfos := make(map[string]*hpb.HostInfo)
+	for _, hostInfo := range h.maintenanceHostInfoMap.GetDrainingHostInfos(
+		[]string{}) {
+		if hostInfo.GetDrainOptions() != nil {
+			hostInfos[hostInfo.GetHostname()] = hostInfo
+		}
+	}
+	if len(hostInfos) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for _, taskID := range taskIDs {
+		ids = append(ids, taskID.GetValue())
+	}
+
+	drainingHosts := make(map[string]*hpb.HostInfo)
+	for taskID, hostname := range h.hostTaskIndex.GetHostsByTasks(ids) {
+		if hostInfo, ok := hostInfos[hostname]; ok {
+			drainingHosts[taskID] = hostInfo
+		}
+	}
+	return drainingHosts
+}
+
+// notifyDrain sends the drain message and labels of the DRAINING host to
+// the executor of the task before the task is killed. The notification is best effort, a
+// failure to send it does not prevent the task from being killed.
+func (h *ServiceHandler) notifyDrain(
+	ctx context.Context,
+	taskID *mesos.TaskID,
+	hostInfo *hpb.HostInfo) {
+	options := hostInfo.GetDrainOptions()
+	if options.GetMessage() == "" && len(options.GetLabels()) == 0 {
+		return
+	}
+
+	agentID, executorID := h.hostTaskIndex.GetExecutor(taskID.GetValue())
+	if executorID == nil {
+		h.metrics.DrainNotificationsSkipped.Inc(1)
+		log.WithField("task_id", taskID.GetValue()).
+			Info("Executor of the task is unknown, skipping drain notification")
+		return
+	}
+
+	notification := &drainNotification{
+		Type:                   _drainNotificationType,
+		Hostname:               hostInfo.GetHostname(),
+		TaskID:                 taskID.GetValue(),
+		Message:                options.GetMessage(),
+		KillGracePeriodSeconds: options.GetKillGracePeriodSeconds(),
+	}
+	if len(options.GetLabels()) > 0 {
+		notification.Labels = make(map[string]string)
+		for _, label := range options.GetLabels() {
+			notification.Labels[label.GetKey()] = label.GetValue()
+		}
+	}
+	data, err := json.Marshal(notification)
+	if err != nil {
+		h.metrics.DrainNotificationsFail.Inc(1)
+		log.WithField("task_id", taskID.GetValue()).
+			WithError(err).
+			Error("Failed to marshal drain notification")
+		return
+	}
+
+	callType := sched.Call_MESSAGE
+	msg := &sched.Call{
+		FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
+		Type:        &callType,
+		Message: &sched.Call_Message{
+			AgentId:    agentID,
+			ExecutorId: executorID,
+			Data:       data,
+		},
+	}
+	msid := h.frameworkInfoProvider.GetMesosStreamID(ctx)
+	if err := h.schedulerClient.Call(msid, msg); err != nil {
+		h.metrics.DrainNotificationsFail.Inc(1)
+		log.WithField("task_id", taskID.GetValue()).
+			WithError(err).
+			Warn("Failed to send drain notification")
+		return
+	}
+	h.metrics.DrainNotifications.Inc(1)
+}
+
+// newKillPolicy returns the kill policy overriding the kill grace period
+// the task was launched with, or nil to keep the task's own grace period.
+func newKillPolicy(gracePeriodSecs uint32) *mesos.KillPolicy {
+	if gracePeriodSecs == 0 {
+		return nil
+	}
+	gracePeriodNsec := (time.Duration(gracePeriodSecs) * time.Second).Nanoseconds()
+	return &mesos.KillPolicy{
+		GracePeriod: &mesos.DurationInfo{
+			Nanoseconds: &gracePeriodNsec,
+		},
+	}
+}
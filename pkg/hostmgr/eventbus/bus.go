@@ -0,0 +1,292 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// DefaultQueueSize is the number of events buffered for a subscriber
+// which does not set its queue size.
+const DefaultQueueSize = 1024
+
+var (
+	errBusClosed        = errors.New("event bus is closed")
+	errNoSubscriberName = errors.New("subscriber name is required")
+	errNoHandler        = errors.New("subscriber handler is required")
+	errNoTopics         = errors.New("subscriber must subscribe to at least one topic")
+	errDuplicateName    = errors.New("subscriber name is already in use")
+	errInvalidQueueSize = errors.New("subscriber queue size cannot be negative")
+	errUnknownOverflow  = errors.New("unknown overflow policy")
+)
+
+// OverflowPolicy tells how Publish behaves when the queue
+// of a subscriber is full.
+type OverflowPolicy int
+
+const (
+	// Block blocks the publisher until the subscriber makes room in its
+	// queue, pushing back on the producer. It fits the subscribers which
+	// cannot miss events.
+	Block OverflowPolicy = iota
+	// Drop drops the event for the subscriber, so that a slow subscriber
+	// never slows down the producer.
+	Drop
+)
+
+// Handler processes the events delivered to a subscriber.
+type Handler func(event Event)
+
+// Subscriber describes a consumer of the bus.
+type Subscriber struct {
+	// Name identifies the subscriber in logs and metrics.
+	Name string
+	// Topics are the topics whose events are delivered to the subscriber.
+	Topics []Topic
+	// Handler is invoked for each event in publish order, from a
+	// goroutine dedicated to the subscriber.
+	Handler Handler
+	// QueueSize is the number of events buffered for the subscriber,
+	// DefaultQueueSize if zero.
+	QueueSize int
+	// Policy is applied when the queue of the subscriber is full.
+	Policy OverflowPolicy
+}
+
+// Subscription is the registration of a subscriber on the bus.
+type Subscription interface {
+	// Unsubscribe stops the delivery of events to the subscriber.
+	// The events still queued for the subscriber are discarded.
+	Unsubscribe()
+}
+
+// Bus is an in-process publish/subscribe bus for the events of the host
+// manager. Producers publish typed events without knowing about their
+// consumers, and every subscriber receives the events of its topics
+// through a bounded queue of its own.
+type Bus interface {
+	// Publish delivers the event to the subscribers of its topic.
+	Publish(event Event)
+
+	// Subscribe registers a subscriber and starts the delivery of
+	// the events of its topics.
+	Subscribe(subscriber Subscriber) (Subscription, error)
+
+	// Close unsubscribes all subscribers. Events published
+	// afterwards are discarded.
+	Close()
+}
+
+// bus implements Bus interface
+type bus struct {
+	lock        sync.RWMutex
+	closed      bool
+	subscribers map[string]*subscription
+	metrics     *Metrics
+}
+
+// NewBus returns a new Bus
+func NewBus(scope tally.Scope) Bus {
+	return &bus{
+		subscribers: make(map[string]*subscription),
+		metrics:     NewMetrics(scope.SubScope("event_bus")),
+	}
+}
+
+// Publish delivers the event to the subscribers of its topic.
+func (b *bus) Publish(event Event) {
+	topic := event.Topic()
+
+	b.lock.RLock()
+	if b.closed {
+		b.lock.RUnlock()
+		b.metrics.PublishedOnClosedBus.Inc(1)
+		return
+	}
+	var subs []*subscription
+	for _, sub := range b.subscribers {
+		if _, ok := sub.topics[topic]; ok {
+			subs = append(subs, sub)
+		}
+	}
+	b.lock.RUnlock()
+
+	b.metrics.published(topic).Inc(1)
+	for _, sub := range subs {
+		sub.enqueue(event)
+	}
+}
+
+// Subscribe registers a subscriber and starts the delivery of
+// the events of its topics.
+func (b *bus) Subscribe(subscriber Subscriber) (Subscription, error) {
+	if subscriber.Name == "" {
+		return nil, errNoSubscriberName
+	}
+	if subscriber.Handler == nil {
+		return nil, errNoHandler
+	}
+	if len(subscriber.Topics) == 0 {
+		return nil, errNoTopics
+	}
+	if subscriber.QueueSize < 0 {
+		return nil, errInvalidQueueSize
+	}
+	if subscriber.Policy != Block && subscriber.Policy != Drop {
+		return nil, errUnknownOverflow
+	}
+
+	queueSize := subscriber.QueueSize
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+	sub := &subscription{
+		bus:     b,
+		name:    subscriber.Name,
+		topics:  make(map[Topic]struct{}),
+		handler: subscriber.Handler,
+		policy:  subscriber.Policy,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+		metrics: b.metrics.newSubscriberMetrics(subscriber.Name),
+	}
+	for _, topic := range subscriber.Topics {
+		sub.topics[topic] = struct{}{}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil, errBusClosed
+	}
+	if _, ok := b.subscribers[sub.name]; ok {
+		return nil, errors.Wrap(errDuplicateName, sub.name)
+	}
+	b.subscribers[sub.name] = sub
+	b.metrics.Subscribers.Update(float64(len(b.subscribers)))
+
+	go sub.run()
+
+	log.WithFields(log.Fields{
+		"subscriber": sub.name,
+		"topics":     subscriber.Topics,
+	}).Info("Event bus subscriber registered")
+	return sub, nil
+}
+
+// Close unsubscribes all subscribers.
+func (b *bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for name, sub := range b.subscribers {
+		sub.stop()
+		delete(b.subscribers, name)
+	}
+	b.metrics.Subscribers.Update(0)
+}
+
+// remove unregisters the subscription from the bus.
+func (b *bus) remove(sub *subscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.subscribers[sub.name] != sub {
+		return
+	}
+	delete(b.subscribers, sub.name)
+	b.metrics.Subscribers.Update(float64(len(b.subscribers)))
+}
+
+// subscription implements Subscription interface
+type subscription struct {
+	bus     *bus
+	name    string
+	topics  map[Topic]struct{}
+	handler Handler
+	policy  OverflowPolicy
+	queue   chan Event
+	metrics *SubscriberMetrics
+
+	// done is closed when the subscription stops.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Unsubscribe stops the delivery of events to the subscriber.
+func (s *subscription) Unsubscribe() {
+	s.bus.remove(s)
+	s.stop()
+}
+
+// stop closes the done channel, which releases the publishers blocked
+// on the queue and terminates the delivery goroutine.
+func (s *subscription) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// enqueue adds the event to the queue of the subscriber, applying
+// the overflow policy of the subscriber if the queue is full.
+func (s *subscription) enqueue(event Event) {
+	select {
+	case <-s.done:
+		return
+	case s.queue <- event:
+		s.metrics.QueueLength.Update(float64(len(s.queue)))
+		return
+	default:
+	}
+
+	if s.policy == Drop {
+		s.metrics.Dropped.Inc(1)
+		log.WithFields(log.Fields{
+			"subscriber": s.name,
+			"topic":      event.Topic(),
+		}).Debug("Event bus subscriber queue is full, dropping event")
+		return
+	}
+
+	s.metrics.Blocked.Inc(1)
+	select {
+	case <-s.done:
+	case s.queue <- event:
+		s.metrics.QueueLength.Update(float64(len(s.queue)))
+	}
+}
+
+// run delivers the queued events to the handler
+// until the subscription stops.
+func (s *subscription) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.queue:
+			s.handler(event)
+			s.metrics.Delivered.Inc(1)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _deliveryTimeout = 5 * time.Second
+
+type BusTestSuite struct {
+	suite.Suite
+
+	testScope tally.TestScope
+	bus       Bus
+}
+
+func (suite *BusTestSuite) SetupTest() {
+	suite.testScope = tally.NewTestScope("", map[string]string{})
+	suite.bus = NewBus(suite.testScope)
+}
+
+func (suite *BusTestSuite) TearDownTest() {
+	suite.bus.Close()
+}
+
+func TestBusTestSuite(t *testing.T) {
+	suite.Run(t, new(BusTestSuite))
+}
+
+// collect subscribes to the topics and returns the channel
+// the delivered events are sent to.
+func (suite *BusTestSuite) collect(name string, topics ...Topic) <-chan Event {
+	events := make(chan Event, 16)
+	_, err := suite.bus.Subscribe(Subscriber{
+		Name:   name,
+		Topics: topics,
+		Handler: func(event Event) {
+			events <- event
+		},
+	})
+	suite.NoError(err)
+	return events
+}
+
+// receive returns the next event delivered on the channel.
+func (suite *BusTestSuite) receive(events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(_deliveryTimeout):
+		suite.FailNow("event not delivered")
+	}
+	return nil
+}
+
+// counter returns the value of the counter with the given key,
+// zero if the counter was never incremented.
+func (suite *BusTestSuite) counter(key string) int64 {
+	counter, ok := suite.testScope.Snapshot().Counters()[key]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func hostStateChanged(hostname string) *HostStateChangedEvent {
+	return &HostStateChangedEvent{
+		Hostnames: []string{hostname},
+		From:      hpb.HostState_HOST_STATE_UP,
+		To:        hpb.HostState_HOST_STATE_DRAINING,
+	}
+}
+
+// TestPublishDeliversToTopicSubscribers tests that an event is delivered
+// only to the subscribers of its topic
+func (suite *BusTestSuite) TestPublishDeliversToTopicSubscribers() {
+	hostEvents := suite.collect("host", HostStateChanged)
+	allEvents := suite.collect("all", HostStateChanged, AgentAdded)
+	agentEvents := suite.collect("agent", AgentAdded)
+
+	event := hostStateChanged("host1")
+	suite.bus.Publish(event)
+	suite.Equal(event, suite.receive(hostEvents))
+	suite.Equal(event, suite.receive(allEvents))
+
+	agentEvent := &AgentAddedEvent{Hostname: "host1"}
+	suite.bus.Publish(agentEvent)
+	suite.Equal(agentEvent, suite.receive(allEvents))
+	suite.Equal(agentEvent, suite.receive(agentEvents))
+	suite.Empty(hostEvents)
+
+	suite.Equal(int64(1), suite.counter("event_bus.published+topic=host_state_changed"))
+}
+
+// TestPublishKeepsOrder tests that the events are delivered
+// to a subscriber in publish order
+func (suite *BusTestSuite) TestPublishKeepsOrder() {
+	events := suite.collect("host", HostStateChanged)
+
+	hostnames := []string{"host1", "host2", "host3", "host4"}
+	for _, hostname := range hostnames {
+		suite.bus.Publish(hostStateChanged(hostname))
+	}
+	for _, hostname := range hostnames {
+		event := suite.receive(events).(*HostStateChangedEvent)
+		suite.Equal([]string{hostname}, event.Hostnames)
+	}
+}
+
+// TestDropPolicy tests that the events are dropped for a subscriber
+// with a full queue and the drop policy, without blocking the publisher
+func (suite *BusTestSuite) TestDropPolicy() {
+	started := make(chan struct{}, 4)
+	release := make(chan struct{})
+	delivered := make(chan Event, 4)
+	_, err := suite.bus.Subscribe(Subscriber{
+		Name:      "slow",
+		Topics:    []Topic{HostStateChanged},
+		QueueSize: 1,
+		Policy:    Drop,
+		Handler: func(event Event) {
+			started <- struct{}{}
+			<-release
+			delivered <- event
+		},
+	})
+	suite.NoError(err)
+
+	// The first event is held by the handler, the second one fills
+	// the queue and the following ones are dropped.
+	suite.bus.Publish(hostStateChanged("host1"))
+	<-started
+	for _, hostname := range []string{"host2", "host3", "host4"} {
+		suite.bus.Publish(hostStateChanged(hostname))
+	}
+	close(release)
+
+	suite.Equal([]string{"host1"},
+		suite.receive(delivered).(*HostStateChangedEvent).Hostnames)
+	suite.Equal([]string{"host2"},
+		suite.receive(delivered).(*HostStateChangedEvent).Hostnames)
+	suite.Equal(int64(2), suite.counter("event_bus.dropped+subscriber=slow"))
+}
+
+// TestBlockPolicy tests that the publisher is blocked while the queue
+// of a subscriber with the block policy is full
+func (suite *BusTestSuite) TestBlockPolicy() {
+	started := make(chan struct{}, 4)
+	release := make(chan struct{})
+	delivered := make(chan Event, 4)
+	_, err := suite.bus.Subscribe(Subscriber{
+		Name:      "slow",
+		Topics:    []Topic{HostStateChanged},
+		QueueSize: 1,
+		Policy:    Block,
+		Handler: func(event Event) {
+			started <- struct{}{}
+			<-release
+			delivered <- event
+		},
+	})
+	suite.NoError(err)
+
+	var published sync.WaitGroup
+	published.Add(1)
+	go func() {
+		defer published.Done()
+		for _, hostname := range []string{"host1", "host2", "host3"} {
+			suite.bus.Publish(hostStateChanged(hostname))
+		}
+	}()
+
+	// The first event is held by the handler, the second one fills
+	// the queue and the publisher blocks on the third one.
+	<-started
+	suite.Eventually(func() bool {
+		return suite.counter("event_bus.blocked+subscriber=slow") >= 1
+	}, _deliveryTimeout, 10*time.Millisecond)
+
+	close(release)
+	published.Wait()
+	for _, hostname := range []string{"host1", "host2", "host3"} {
+		suite.Equal([]string{hostname},
+			suite.receive(delivered).(*HostStateChangedEvent).Hostnames)
+	}
+}
+
+// TestUnsubscribe tests that the events are no longer delivered
+// to a subscriber after it unsubscribes, and that its name can be reused
+func (suite *BusTestSuite) TestUnsubscribe() {
+	events := make(chan Event, 4)
+	sub, err := suite.bus.Subscribe(Subscriber{
+		Name:    "host",
+		Topics:  []Topic{HostStateChanged},
+		Handler: func(event Event) { events <- event },
+	})
+	suite.NoError(err)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	suite.bus.Publish(hostStateChanged("host1"))
+	suite.Empty(events)
+
+	reused := suite.collect("host", HostStateChanged)
+	suite.bus.Publish(hostStateChanged("host2"))
+	suite.Equal([]string{"host2"},
+		suite.receive(reused).(*HostStateChangedEvent).Hostnames)
+}
+
+// TestUnsubscribeReleasesBlockedPublisher tests that a publisher blocked
+// on the queue of a subscriber is released when the subscriber unsubscribes
+func (suite *BusTestSuite) TestUnsubscribeReleasesBlockedPublisher() {
+	block := make(chan struct{})
+	defer close(block)
+	sub, err := suite.bus.Subscribe(Subscriber{
+		Name:      "stuck",
+		Topics:    []Topic{HostStateChanged},
+		QueueSize: 1,
+		Handler:   func(event Event) { <-block },
+	})
+	suite.NoError(err)
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for _, hostname := range []string{"host1", "host2", "host3"} {
+			suite.bus.Publish(hostStateChanged(hostname))
+		}
+	}()
+
+	suite.Eventually(func() bool {
+		return suite.counter("event_bus.blocked+subscriber=stuck") >= 1
+	}, _deliveryTimeout, 10*time.Millisecond)
+
+	sub.Unsubscribe()
+	select {
+	case <-published:
+	case <-time.After(_deliveryTimeout):
+		suite.Fail("publisher not released")
+	}
+}
+
+// TestSubscribeValidation tests that invalid subscribers are rejected
+func (suite *BusTestSuite) TestSubscribeValidation() {
+	handler := func(event Event) {}
+	topics := []Topic{HostStateChanged}
+
+	tests := []struct {
+		msg        string
+		subscriber Subscriber
+	}{
+		{
+			msg:        "missing name",
+			subscriber: Subscriber{Topics: topics, Handler: handler},
+		},
+		{
+			msg:        "missing handler",
+			subscriber: Subscriber{Name: "sub", Topics: topics},
+		},
+		{
+			msg:        "missing topics",
+			subscriber: Subscriber{Name: "sub", Handler: handler},
+		},
+		{
+			msg: "negative queue size",
+			subscriber: Subscriber{
+				Name: "sub", Topics: topics, Handler: handler, QueueSize: -1,
+			},
+		},
+		{
+			msg: "unknown policy",
+			subscriber: Subscriber{
+				Name: "sub", Topics: topics, Handler: handler, Policy: 5,
+			},
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.bus.Subscribe(test.subscriber)
+		suite.Error(err, test.msg)
+	}
+
+	suite.collect("sub", HostStateChanged)
+	_, err := suite.bus.Subscribe(
+		Subscriber{Name: "sub", Topics: topics, Handler: handler})
+	suite.Error(err)
+}
+
+// TestClose tests that the events published after the bus is closed
+// are discarded and that no subscriber can be added
+func (suite *BusTestSuite) TestClose() {
+	events := suite.collect("host", HostStateChanged)
+
+	suite.bus.Close()
+	suite.bus.Close()
+	suite.bus.Publish(hostStateChanged("host1"))
+	suite.Empty(events)
+	suite.Equal(int64(1), suite.counter("event_bus.published_on_closed_bus+"))
+
+	_, err := suite.bus.Subscribe(Subscriber{
+		Name:    "late",
+		Topics:  []Topic{HostStateChanged},
+		Handler: func(event Event) {},
+	})
+	suite.Equal(errBusClosed, err)
+}
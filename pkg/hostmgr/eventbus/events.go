@@ -0,0 +1,179 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+)
+
+// Topic identifies the kind of the events published on the bus.
+type Topic string
+
+const (
+	// HostStateChanged is the topic of the maintenance state
+	// transitions of hosts.
+	HostStateChanged Topic = "host_state_changed"
+	// OffersReceived is the topic of the offers received from Mesos master.
+	OffersReceived Topic = "offers_received"
+	// OfferRescinded is the topic of the offers rescinded by Mesos master.
+	OfferRescinded Topic = "offer_rescinded"
+	// AgentAdded is the topic of the agents added to the agent map.
+	AgentAdded Topic = "agent_added"
+	// AgentRemoved is the topic of the agents removed from the agent map.
+	AgentRemoved Topic = "agent_removed"
+	// AttributesChanged is the topic of the agents which re-registered
+	// with a different set of attributes.
+	AttributesChanged Topic = "attributes_changed"
+	// TasksEvicted is the topic of the tasks of draining hosts handed
+	// out to be evicted.
+	TasksEvicted Topic = "tasks_evicted"
+	// TasksLaunched is the topic of the tasks launched on hosts.
+	TasksLaunched Topic = "tasks_launched"
+	// TaskStatusUpdated is the topic of the task status updates
+	// received from Mesos master.
+	TaskStatusUpdated Topic = "task_status_updated"
+	// HostDriftDetected is the topic of the inconsistencies of the state
+	// of hosts which host manager cannot fix by itself.
+	HostDriftDetected Topic = "host_drift_detected"
+)
+
+// Event is an event published on the bus.
+type Event interface {
+	// Topic returns the topic the event is published on.
+	Topic() Topic
+}
+
+// HostStateChangedEvent is published when hosts transition
+// from one maintenance state to another.
+type HostStateChangedEvent struct {
+	Hostnames []string
+	From      hpb.HostState
+	To        hpb.HostState
+}
+
+// Topic returns HostStateChanged.
+func (e *HostStateChangedEvent) Topic() Topic {
+	return HostStateChanged
+}
+
+// OffersReceivedEvent is published when offers are received
+// from Mesos master, before they are added to the offer pool.
+type OffersReceivedEvent struct {
+	Offers []*mesos.Offer
+}
+
+// Topic returns OffersReceived.
+func (e *OffersReceivedEvent) Topic() Topic {
+	return OffersReceived
+}
+
+// OfferRescindedEvent is published when Mesos master rescinds an offer.
+type OfferRescindedEvent struct {
+	OfferID *mesos.OfferID
+}
+
+// Topic returns OfferRescinded.
+func (e *OfferRescindedEvent) Topic() Topic {
+	return OfferRescinded
+}
+
+// AgentAddedEvent is published when an agent is added to the agent map.
+type AgentAddedEvent struct {
+	Hostname string
+	Agent    *mesos_master.Response_GetAgents_Agent
+}
+
+// Topic returns AgentAdded.
+func (e *AgentAddedEvent) Topic() Topic {
+	return AgentAdded
+}
+
+// AgentRemovedEvent is published when an agent is removed
+// from the agent map.
+type AgentRemovedEvent struct {
+	Hostname string
+	AgentID  *mesos.AgentID
+}
+
+// Topic returns AgentRemoved.
+func (e *AgentRemovedEvent) Topic() Topic {
+	return AgentRemoved
+}
+
+// AttributesChangedEvent is published when an agent re-registered with
+// Mesos master with a different set of attributes.
+type AttributesChangedEvent struct {
+	Previous *mesos.AgentInfo
+	Current  *mesos.AgentInfo
+}
+
+// Topic returns AttributesChanged.
+func (e *AttributesChangedEvent) Topic() Topic {
+	return AttributesChanged
+}
+
+// TasksEvictedEvent is published when a draining host is handed out to
+// be drained, with the tasks running on it to be evicted.
+type TasksEvictedEvent struct {
+	Hostname string
+	TaskIDs  []string
+}
+
+// Topic returns TasksEvicted.
+func (e *TasksEvictedEvent) Topic() Topic {
+	return TasksEvicted
+}
+
+// TasksLaunchedEvent is published when tasks are launched on a host.
+type TasksLaunchedEvent struct {
+	Hostname string
+	// Labels of the launched tasks by Mesos task id.
+	TaskLabels map[string][]*peloton.Label
+}
+
+// Topic returns TasksLaunched.
+func (e *TasksLaunchedEvent) Topic() Topic {
+	return TasksLaunched
+}
+
+// TaskStatusUpdatedEvent is published when a task status update is
+// received from Mesos master.
+type TaskStatusUpdatedEvent struct {
+	Status *mesos.TaskStatus
+}
+
+// Topic returns TaskStatusUpdated.
+func (e *TaskStatusUpdatedEvent) Topic() Topic {
+	return TaskStatusUpdated
+}
+
+// HostDriftDetectedEvent is published when the state of a host in host
+// manager diverged from Mesos master in a way which is not fixed
+// automatically.
+type HostDriftDetectedEvent struct {
+	Hostname string
+	// Drift is the class of the inconsistency
+	Drift string
+	// Message describes the inconsistency
+	Message string
+}
+
+// Topic returns HostDriftDetected.
+func (e *HostDriftDetectedEvent) Topic() Topic {
+	return HostDriftDetected
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics is the metrics of the event bus.
+type Metrics struct {
+	scope tally.Scope
+
+	Subscribers tally.Gauge
+	// PublishedOnClosedBus counts the events published after the
+	// bus was closed, which are discarded.
+	PublishedOnClosedBus tally.Counter
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics
+// initialized and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	return &Metrics{
+		scope: scope,
+
+		Subscribers:          scope.Gauge("subscribers"),
+		PublishedOnClosedBus: scope.Counter("published_on_closed_bus"),
+	}
+}
+
+// published returns the counter of the events published on a topic.
+func (m *Metrics) published(topic Topic) tally.Counter {
+	return m.scope.Tagged(map[string]string{"topic": string(topic)}).
+		Counter("published")
+}
+
+// SubscriberMetrics is the metrics of a single subscriber of the bus.
+type SubscriberMetrics struct {
+	Delivered   tally.Counter
+	Dropped     tally.Counter
+	Blocked     tally.Counter
+	QueueLength tally.Gauge
+}
+
+// newSubscriberMetrics returns the metrics of the named subscriber.
+func (m *Metrics) newSubscriberMetrics(name string) *SubscriberMetrics {
+	scope := m.scope.Tagged(map[string]string{"subscriber": name})
+	return &SubscriberMetrics{
+		Delivered:   scope.Counter("delivered"),
+		Dropped:     scope.Counter("dropped"),
+		Blocked:     scope.Counter("blocked"),
+		QueueLength: scope.Gauge("queue_length"),
+	}
+}
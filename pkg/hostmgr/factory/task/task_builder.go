@@ -41,6 +41,9 @@ const (
 	PelotonInstanceIDLabelKey = "peloton.instance_id"
 	// PelotonTaskIDLabelKey is the task label key for task ID
 	PelotonTaskIDLabelKey = "peloton.task_id"
+	// PelotonRevocableLabelKey is the task label key set on tasks launched
+	// using revocable resources
+	PelotonRevocableLabelKey = "peloton.revocable"
 
 	// Set default task kill grace period to 30 seconds
 	_defaultTaskKillGracePeriod = 30 * time.Second
@@ -275,7 +278,12 @@ func (tb *Builder) Build(
 		instanceID,
 	)
 	tb.populateContainerInfo(mesosTask, taskConfig.GetContainer())
-	tb.populateLabels(mesosTask, taskConfig.GetLabels(), jobID, instanceID)
+	tb.populateLabels(
+		mesosTask,
+		taskConfig.GetLabels(),
+		jobID,
+		instanceID,
+		taskConfig.GetRevocable())
 
 	tb.populateHealthCheck(mesosTask, taskConfig.GetHealthCheck())
 
@@ -458,6 +466,7 @@ func (tb *Builder) populateLabels(
 	labels []*peloton.Label,
 	jobID string,
 	instanceID uint32,
+	revocable bool,
 ) {
 	var mesosLabels *mesos.Labels
 
@@ -483,6 +492,14 @@ func (tb *Builder) populateLabels(
 		Key:   util.PtrPrintf(PelotonTaskIDLabelKey),
 		Value: util.PtrPrintf("%s-%d", jobID, instanceID),
 	})
+	// tag tasks running on revocable resources, as they are subject to
+	// preemption by Mesos when the slack capacity shrinks
+	if revocable {
+		mesosLabels.Labels = append(mesosLabels.Labels, &mesos.Label{
+			Key:   util.PtrPrintf(PelotonRevocableLabelKey),
+			Value: util.PtrPrintf("true"),
+		})
+	}
 
 	mesosTask.Labels = &mesos.Labels{
 		Labels: mesosLabels.Labels,
@@ -719,6 +719,7 @@ func (suite *BuilderTestSuite) TestPopulateLabels() {
 
 	tt := []struct {
 		pelotonTaskLabels []*peloton.Label // input labels
+		revocable         bool             // whether the task is revocable
 		mesosTaskLabels   *mesos.Labels    // expected output labels
 	}{
 		{
@@ -768,13 +769,41 @@ func (suite *BuilderTestSuite) TestPopulateLabels() {
 					},
 				},
 			},
+		}, {
+			// Revocable task
+			pelotonTaskLabels: []*peloton.Label{},
+			revocable:         true,
+			mesosTaskLabels: &mesos.Labels{
+				Labels: []*mesos.Label{
+					{
+						Key:   util.PtrPrintf(PelotonJobIDLabelKey),
+						Value: util.PtrPrintf("test-job"),
+					},
+					{
+						Key:   util.PtrPrintf(PelotonInstanceIDLabelKey),
+						Value: util.PtrPrintf("0"),
+					},
+					{
+						Key:   util.PtrPrintf(PelotonTaskIDLabelKey),
+						Value: util.PtrPrintf("test-job-0"),
+					},
+					{
+						Key:   util.PtrPrintf(PelotonRevocableLabelKey),
+						Value: util.PtrPrintf("true"),
+					},
+				},
+			},
 		},
 	}
 
 	for _, test := range tt {
 		mesosTask := &mesos.TaskInfo{}
 		builder.populateLabels(
-			mesosTask, test.pelotonTaskLabels, jobID, uint32(instanceID))
+			mesosTask,
+			test.pelotonTaskLabels,
+			jobID,
+			uint32(instanceID),
+			test.revocable)
 		suite.Equal(test.mesosTaskLabels, mesosTask.Labels)
 	}
 }
@@ -0,0 +1,200 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults injects faults into host manager on demand, to test the
+// resilience of the maintenance flows end to end. Faults delay or fail the
+// calls of the Mesos Master operator API, drop the updates of the agent
+// map, or fail and drop the operations of the maintenance queue. It is
+// only enabled by config, and must never be enabled in production.
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// OperatorPointPrefix prefixes the points of the calls of the Mesos
+	// Master operator API, followed by the method, e.g.
+	// operator.StartMaintenance
+	OperatorPointPrefix = "operator."
+
+	// QueuePointPrefix prefixes the points of the operations of the
+	// maintenance queue, followed by the method, e.g. queue.Enqueue
+	QueuePointPrefix = "queue."
+
+	// AgentMapUpdatePoint is the point of the updates of the agent map,
+	// from both full reloads and Mesos Master events
+	AgentMapUpdatePoint = "agent_map.update"
+)
+
+// Fault is a fault injected at a point of host manager.
+type Fault struct {
+	// Point where the fault is injected, e.g. operator.StartMaintenance
+	Point string `json:"point"`
+	// Delay before the operation is performed or failed.
+	Delay time.Duration `json:"delay_ns,omitempty"`
+	// Error returned instead of performing the operation, if not empty.
+	Error string `json:"error,omitempty"`
+	// Drop skips the operation while reporting it succeeded.
+	Drop bool `json:"drop,omitempty"`
+	// Probability of injecting the fault in every operation, the fault
+	// is always injected if 0.
+	Probability float64 `json:"probability,omitempty"`
+	// Count is the number of injections left, after which the fault is
+	// removed. The fault is never removed if 0.
+	Count int `json:"count,omitempty"`
+}
+
+// Validate returns an error if the fault is invalid.
+func (f *Fault) Validate() error {
+	if f.Point == "" {
+		return errors.New("point is required")
+	}
+	if f.Delay < 0 {
+		return errors.New("delay must not be negative")
+	}
+	if f.Probability < 0 || f.Probability > 1 {
+		return errors.New("probability must be between 0 and 1")
+	}
+	if f.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	if f.Error != "" && f.Drop {
+		return errors.New("a fault cannot both fail and drop")
+	}
+	return nil
+}
+
+// Injector holds the faults injected into host manager, by point. A nil
+// injector injects no fault.
+type Injector struct {
+	sync.Mutex
+	faults map[string]*Fault
+	scope  tally.Scope
+
+	// random returns a number in [0, 1) to apply the probability of the
+	// faults, and sleep delays the operations
+	random func() float64
+	sleep  func(time.Duration)
+}
+
+// NewInjector returns an injector without any fault.
+func NewInjector(parent tally.Scope) *Injector {
+	return &Injector{
+		faults: make(map[string]*Fault),
+		scope:  parent.SubScope("faults"),
+		random: rand.Float64,
+		sleep:  time.Sleep,
+	}
+}
+
+// Set injects a fault at its point, replacing the fault of the point if
+// any.
+func (i *Injector) Set(f Fault) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	i.Lock()
+	defer i.Unlock()
+	i.faults[f.Point] = &f
+	log.WithField("fault", f).Warn("Fault injected")
+	return nil
+}
+
+// Remove removes the fault of a point, or all faults if point is empty.
+func (i *Injector) Remove(point string) {
+	i.Lock()
+	defer i.Unlock()
+	if point == "" {
+		i.faults = make(map[string]*Fault)
+	} else {
+		delete(i.faults, point)
+	}
+	log.WithField("point", point).Info("Faults removed")
+}
+
+// Faults returns the faults injected, sorted by point.
+func (i *Injector) Faults() []Fault {
+	i.Lock()
+	defer i.Unlock()
+	faults := make([]Fault, 0, len(i.faults))
+	for _, f := range i.faults {
+		faults = append(faults, *f)
+	}
+	sort.Slice(faults, func(a, b int) bool {
+		return faults[a].Point < faults[b].Point
+	})
+	return faults
+}
+
+// Inject injects the fault of a point, if any. It sleeps for the delay
+// of the fault, and returns whether the operation must be dropped and
+// the error it must fail with.
+func (i *Injector) Inject(point string) (drop bool, err error) {
+	if i == nil {
+		return false, nil
+	}
+	f := i.take(point)
+	if f == nil {
+		return false, nil
+	}
+
+	i.scope.Tagged(map[string]string{"point": point}).
+		Counter("injected").Inc(1)
+	if f.Delay > 0 {
+		i.sleep(f.Delay)
+	}
+	if f.Error != "" {
+		return false, fmt.Errorf("injected fault: %s", f.Error)
+	}
+	return f.Drop, nil
+}
+
+// DropAgentMapUpdate returns whether to drop an update of the agent map,
+// which is dropped by the faults failing it as well.
+func (i *Injector) DropAgentMapUpdate() bool {
+	drop, err := i.Inject(AgentMapUpdatePoint)
+	return drop || err != nil
+}
+
+// take returns the fault to inject at a point, nil if none or if the
+// probability of the fault rules it out, and counts the injection.
+func (i *Injector) take(point string) *Fault {
+	i.Lock()
+	defer i.Unlock()
+	f, ok := i.faults[point]
+	if !ok {
+		return nil
+	}
+	if f.Probability > 0 && i.random() >= f.Probability {
+		return nil
+	}
+	if f.Count > 0 {
+		f.Count--
+		if f.Count == 0 {
+			delete(i.faults, point)
+		}
+	}
+	injected := *f
+	return &injected
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type InjectorTestSuite struct {
+	suite.Suite
+
+	injector *Injector
+	slept    []time.Duration
+	random   float64
+}
+
+func TestInjector(t *testing.T) {
+	suite.Run(t, new(InjectorTestSuite))
+}
+
+func (suite *InjectorTestSuite) SetupTest() {
+	suite.slept = nil
+	suite.random = 0
+	suite.injector = NewInjector(tally.NoopScope)
+	suite.injector.sleep = func(d time.Duration) {
+		suite.slept = append(suite.slept, d)
+	}
+	suite.injector.random = func() float64 { return suite.random }
+}
+
+// TestInject tests injecting delays, errors and drops.
+func (suite *InjectorTestSuite) TestInject() {
+	drop, err := suite.injector.Inject("operator.Agents")
+	suite.False(drop)
+	suite.NoError(err)
+
+	suite.NoError(suite.injector.Set(Fault{
+		Point: "operator.Agents",
+		Delay: time.Second,
+		Error: "unavailable",
+	}))
+	suite.NoError(suite.injector.Set(Fault{
+		Point: "queue.Enqueue",
+		Drop:  true,
+	}))
+
+	drop, err = suite.injector.Inject("operator.Agents")
+	suite.False(drop)
+	suite.EqualError(err, "injected fault: unavailable")
+	suite.Equal([]time.Duration{time.Second}, suite.slept)
+
+	drop, err = suite.injector.Inject("queue.Enqueue")
+	suite.True(drop)
+	suite.NoError(err)
+
+	suite.Len(suite.injector.Faults(), 2)
+	suite.Equal("operator.Agents", suite.injector.Faults()[0].Point)
+
+	suite.injector.Remove("operator.Agents")
+	_, err = suite.injector.Inject("operator.Agents")
+	suite.NoError(err)
+	suite.injector.Remove("")
+	suite.Empty(suite.injector.Faults())
+}
+
+// TestInjectCount tests that faults are removed after count injections.
+func (suite *InjectorTestSuite) TestInjectCount() {
+	suite.NoError(suite.injector.Set(Fault{
+		Point: AgentMapUpdatePoint,
+		Drop:  true,
+		Count: 2,
+	}))
+	suite.True(suite.injector.DropAgentMapUpdate())
+	suite.True(suite.injector.DropAgentMapUpdate())
+	suite.False(suite.injector.DropAgentMapUpdate())
+	suite.Empty(suite.injector.Faults())
+}
+
+// TestInjectProbability tests injecting faults with a probability.
+func (suite *InjectorTestSuite) TestInjectProbability() {
+	suite.NoError(suite.injector.Set(Fault{
+		Point:       "queue.Dequeue",
+		Error:       "corrupted",
+		Probability: 0.5,
+	}))
+
+	suite.random = 0.7
+	_, err := suite.injector.Inject("queue.Dequeue")
+	suite.NoError(err)
+
+	suite.random = 0.2
+	_, err = suite.injector.Inject("queue.Dequeue")
+	suite.Error(err)
+}
+
+// TestSetInvalid tests that invalid faults are rejected.
+func (suite *InjectorTestSuite) TestSetInvalid() {
+	for _, f := range []Fault{
+		{},
+		{Point: "queue.Enqueue", Delay: -time.Second},
+		{Point: "queue.Enqueue", Probability: 2},
+		{Point: "queue.Enqueue", Count: -1},
+		{Point: "queue.Enqueue", Error: "failed", Drop: true},
+	} {
+		suite.Error(suite.injector.Set(f))
+	}
+	suite.Empty(suite.injector.Faults())
+}
+
+// TestNilInjector tests that a nil injector injects no fault.
+func (suite *InjectorTestSuite) TestNilInjector() {
+	var injector *Injector
+	drop, err := injector.Inject("operator.Agents")
+	suite.False(drop)
+	suite.NoError(err)
+	suite.False(injector.DropAgentMapUpdate())
+}
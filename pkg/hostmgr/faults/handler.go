@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// Path is the debug endpoint of the faults injected into host
+	// manager. A POST injects the fault of the point, delay, error, drop,
+	// probability and count parameters, and a DELETE removes the fault of
+	// the point parameter, or all faults without it.
+	Path = "/debug/hostmgr/faults"
+
+	_pointParam       = "point"
+	_delayParam       = "delay"
+	_errorParam       = "error"
+	_dropParam        = "drop"
+	_probabilityParam = "probability"
+	_countParam       = "count"
+)
+
+// Handler returns a handler dumping the faults injected as JSON, after
+// injecting or removing a fault on POST and DELETE.
+func (i *Injector) Handler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.Method {
+		case http.MethodPost:
+			err = i.setFromRequest(r)
+		case http.MethodDelete:
+			if err = r.ParseForm(); err == nil {
+				i.Remove(r.Form.Get(_pointParam))
+			}
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+
+		body, err := json.MarshalIndent(i.Faults(), "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// setFromRequest injects the fault of the parameters of the request.
+func (i *Injector) setFromRequest(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	f := Fault{
+		Point: r.Form.Get(_pointParam),
+		Error: r.Form.Get(_errorParam),
+	}
+	var err error
+	if v := r.Form.Get(_delayParam); v != "" {
+		if f.Delay, err = time.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid %s: %v", _delayParam, err)
+		}
+	}
+	if v := r.Form.Get(_dropParam); v != "" {
+		if f.Drop, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid %s: %v", _dropParam, err)
+		}
+	}
+	if v := r.Form.Get(_probabilityParam); v != "" {
+		if f.Probability, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("invalid %s: %v", _probabilityParam, err)
+		}
+	}
+	if v := r.Form.Get(_countParam); v != "" {
+		if f.Count, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("invalid %s: %v", _countParam, err)
+		}
+	}
+	return i.Set(f)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+func doFaultsRequest(
+	injector *Injector,
+	method string,
+	params url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, Path+"?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	injector.Handler()(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	injector := NewInjector(tally.NoopScope)
+
+	w := doFaultsRequest(injector, http.MethodPost, url.Values{
+		"point":       {"operator.StartMaintenance"},
+		"delay":       {"2s"},
+		"error":       {"unavailable"},
+		"probability": {"0.5"},
+		"count":       {"3"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var faults []Fault
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &faults))
+	assert.Equal(t, []Fault{{
+		Point:       "operator.StartMaintenance",
+		Delay:       2 * time.Second,
+		Error:       "unavailable",
+		Probability: 0.5,
+		Count:       3,
+	}}, faults)
+
+	w = doFaultsRequest(injector, http.MethodPost, url.Values{
+		"point": {"queue.Enqueue"},
+		"drop":  {"true"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, injector.Faults(), 2)
+
+	for _, params := range []url.Values{
+		{"point": {"queue.Enqueue"}, "delay": {"soon"}},
+		{"point": {"queue.Enqueue"}, "drop": {"maybe"}},
+		{"point": {"queue.Enqueue"}, "probability": {"high"}},
+		{"point": {"queue.Enqueue"}, "count": {"many"}},
+		{"error": {"no point"}},
+	} {
+		w = doFaultsRequest(injector, http.MethodPost, params)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w = doFaultsRequest(injector, http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &faults))
+	assert.Len(t, faults, 2)
+
+	w = doFaultsRequest(injector, http.MethodDelete, url.Values{
+		"point": {"queue.Enqueue"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, injector.Faults(), 1)
+
+	w = doFaultsRequest(injector, http.MethodDelete, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, injector.Faults())
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"context"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+)
+
+// operatorClient injects faults into the calls of the Mesos Master
+// operator API. Dropped calls are not sent, and return empty results.
+type operatorClient struct {
+	client   mpb.MasterOperatorClient
+	injector *Injector
+}
+
+// NewOperatorClient returns a client of the Mesos Master operator API
+// injecting the faults of the operator points into the calls of client.
+func NewOperatorClient(
+	client mpb.MasterOperatorClient,
+	injector *Injector) mpb.MasterOperatorClient {
+	return &operatorClient{client: client, injector: injector}
+}
+
+func (o *operatorClient) inject(method string) (bool, error) {
+	return o.injector.Inject(OperatorPointPrefix + method)
+}
+
+func (o *operatorClient) Agents() (*mesos_master.Response_GetAgents, error) {
+	if drop, err := o.inject("Agents"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.Agents()
+}
+
+func (o *operatorClient) GetTasksAllocation(
+	ID string) ([]*mesos.Resource, []*mesos.Resource, error) {
+	if drop, err := o.inject("GetTasksAllocation"); drop || err != nil {
+		return nil, nil, err
+	}
+	return o.client.GetTasksAllocation(ID)
+}
+
+func (o *operatorClient) AllocatedResources(ID string) ([]*mesos.Resource, error) {
+	if drop, err := o.inject("AllocatedResources"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.AllocatedResources(ID)
+}
+
+func (o *operatorClient) GetMaintenanceSchedule() (
+	*mesos_master.Response_GetMaintenanceSchedule, error) {
+	if drop, err := o.inject("GetMaintenanceSchedule"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.GetMaintenanceSchedule()
+}
+
+func (o *operatorClient) GetMaintenanceStatus() (
+	*mesos_master.Response_GetMaintenanceStatus, error) {
+	if drop, err := o.inject("GetMaintenanceStatus"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.GetMaintenanceStatus()
+}
+
+func (o *operatorClient) StartMaintenance(
+	ctx context.Context, machineIDs []*mesos.MachineID) error {
+	if drop, err := o.inject("StartMaintenance"); drop || err != nil {
+		return err
+	}
+	return o.client.StartMaintenance(ctx, machineIDs)
+}
+
+func (o *operatorClient) StopMaintenance(
+	ctx context.Context, machineIDs []*mesos.MachineID) error {
+	if drop, err := o.inject("StopMaintenance"); drop || err != nil {
+		return err
+	}
+	return o.client.StopMaintenance(ctx, machineIDs)
+}
+
+func (o *operatorClient) GetQuota(role string) ([]*mesos.Resource, error) {
+	if drop, err := o.inject("GetQuota"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.GetQuota(role)
+}
+
+func (o *operatorClient) UpdateMaintenanceSchedule(
+	ctx context.Context, schedule *mesos_maintenance.Schedule) error {
+	if drop, err := o.inject("UpdateMaintenanceSchedule"); drop || err != nil {
+		return err
+	}
+	return o.client.UpdateMaintenanceSchedule(ctx, schedule)
+}
+
+func (o *operatorClient) ReserveResources(
+	agentID *mesos.AgentID, resources []*mesos.Resource) error {
+	if drop, err := o.inject("ReserveResources"); drop || err != nil {
+		return err
+	}
+	return o.client.ReserveResources(agentID, resources)
+}
+
+func (o *operatorClient) UnreserveResources(
+	agentID *mesos.AgentID, resources []*mesos.Resource) error {
+	if drop, err := o.inject("UnreserveResources"); drop || err != nil {
+		return err
+	}
+	return o.client.UnreserveResources(agentID, resources)
+}
+
+func (o *operatorClient) CreateVolumes(
+	agentID *mesos.AgentID, volumes []*mesos.Resource) error {
+	if drop, err := o.inject("CreateVolumes"); drop || err != nil {
+		return err
+	}
+	return o.client.CreateVolumes(agentID, volumes)
+}
+
+func (o *operatorClient) DestroyVolumes(
+	agentID *mesos.AgentID, volumes []*mesos.Resource) error {
+	if drop, err := o.inject("DestroyVolumes"); drop || err != nil {
+		return err
+	}
+	return o.client.DestroyVolumes(agentID, volumes)
+}
+
+func (o *operatorClient) GetMaster() (*mesos_master.Response_GetMaster, error) {
+	if drop, err := o.inject("GetMaster"); drop || err != nil {
+		return nil, err
+	}
+	return o.client.GetMaster()
+}
+
+func (o *operatorClient) DrainAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID,
+	maxGracePeriod time.Duration) error {
+	if drop, err := o.inject("DrainAgent"); drop || err != nil {
+		return err
+	}
+	return o.client.DrainAgent(ctx, agentID, maxGracePeriod)
+}
+
+func (o *operatorClient) DeactivateAgent(
+	ctx context.Context, agentID *mesos.AgentID) error {
+	if drop, err := o.inject("DeactivateAgent"); drop || err != nil {
+		return err
+	}
+	return o.client.DeactivateAgent(ctx, agentID)
+}
+
+func (o *operatorClient) ReactivateAgent(
+	ctx context.Context, agentID *mesos.AgentID) error {
+	if drop, err := o.inject("ReactivateAgent"); drop || err != nil {
+		return err
+	}
+	return o.client.ReactivateAgent(ctx, agentID)
+}
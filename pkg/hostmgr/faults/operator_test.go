@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// TestOperatorClient tests injecting faults into the calls of the Mesos
+// Master operator API.
+func TestOperatorClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mpb_mocks.NewMockMasterOperatorClient(ctrl)
+	injector := NewInjector(tally.NoopScope)
+	client := NewOperatorClient(mockClient, injector)
+	ctx := context.Background()
+	machineIDs := []*mesos.MachineID{{Hostname: &[]string{"host1"}[0]}}
+
+	// Calls without faults are sent
+	agents := &mesos_master.Response_GetAgents{}
+	mockClient.EXPECT().Agents().Return(agents, nil)
+	result, err := client.Agents()
+	assert.NoError(t, err)
+	assert.Equal(t, agents, result)
+
+	mockClient.EXPECT().StartMaintenance(ctx, machineIDs).Return(nil)
+	assert.NoError(t, client.StartMaintenance(ctx, machineIDs))
+
+	// Failed calls are not sent
+	assert.NoError(t, injector.Set(Fault{
+		Point: OperatorPointPrefix + "Agents",
+		Error: "unavailable",
+	}))
+	_, err = client.Agents()
+	assert.Error(t, err)
+
+	// Dropped calls are not sent, but succeed
+	assert.NoError(t, injector.Set(Fault{
+		Point: OperatorPointPrefix + "StartMaintenance",
+		Drop:  true,
+	}))
+	assert.NoError(t, client.StartMaintenance(ctx, machineIDs))
+
+	// Faults only apply to their point
+	mockClient.EXPECT().
+		StopMaintenance(ctx, machineIDs).
+		Return(errors.New("stop maintenance error"))
+	assert.EqualError(t,
+		client.StopMaintenance(ctx, machineIDs), "stop maintenance error")
+}
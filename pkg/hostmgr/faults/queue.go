@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"time"
+
+	common_queue "github.com/uber/peloton/pkg/common/queue"
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceQueue injects faults into the operations of the maintenance
+// queue which change it. Dropped hosts are lost, as if the queue was
+// corrupted: dropped enqueues do not enqueue the hosts, dropped dequeues
+// discard the dequeued host, and dropped or failed MarkProcessed keep the
+// attempts of the hosts.
+type maintenanceQueue struct {
+	queue.MaintenanceQueue
+	injector *Injector
+}
+
+// NewMaintenanceQueue returns a maintenance queue injecting the faults of
+// the queue points into the operations of q.
+func NewMaintenanceQueue(
+	q queue.MaintenanceQueue,
+	injector *Injector) queue.MaintenanceQueue {
+	return &maintenanceQueue{MaintenanceQueue: q, injector: injector}
+}
+
+func (q *maintenanceQueue) inject(method string) (bool, error) {
+	return q.injector.Inject(QueuePointPrefix + method)
+}
+
+func (q *maintenanceQueue) Enqueue(hostnames []string) ([]string, error) {
+	if drop, err := q.inject("Enqueue"); drop || err != nil {
+		return nil, err
+	}
+	return q.MaintenanceQueue.Enqueue(hostnames)
+}
+
+func (q *maintenanceQueue) Dequeue(maxWaitTime time.Duration) (string, error) {
+	drop, err := q.inject("Dequeue")
+	if err != nil {
+		return "", err
+	}
+
+	host, err := q.MaintenanceQueue.Dequeue(maxWaitTime)
+	if err != nil || !drop {
+		return host, err
+	}
+	log.WithField("host", host).Warn("Dropped host dequeued from maintenance queue")
+	return "", common_queue.DequeueTimeOutError{}
+}
+
+func (q *maintenanceQueue) MarkProcessed(hostnames []string) {
+	if drop, err := q.inject("MarkProcessed"); drop || err != nil {
+		return
+	}
+	q.MaintenanceQueue.MarkProcessed(hostnames)
+}
+
+func (q *maintenanceQueue) Redrive(hostnames []string) error {
+	if drop, err := q.inject("Redrive"); drop || err != nil {
+		return err
+	}
+	return q.MaintenanceQueue.Redrive(hostnames)
+}
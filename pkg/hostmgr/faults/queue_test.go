@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// TestMaintenanceQueue tests injecting faults into the operations of the
+// maintenance queue.
+func TestMaintenanceQueue(t *testing.T) {
+	injector := NewInjector(tally.NoopScope)
+	q := NewMaintenanceQueue(queue.NewMaintenanceQueue(0), injector)
+
+	// Failed enqueues do not enqueue the hosts
+	assert.NoError(t, injector.Set(Fault{
+		Point: QueuePointPrefix + "Enqueue",
+		Error: "queue full",
+		Count: 1,
+	}))
+	_, err := q.Enqueue([]string{"host1"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, q.Length())
+
+	// Dropped enqueues succeed without enqueuing the hosts
+	assert.NoError(t, injector.Set(Fault{
+		Point: QueuePointPrefix + "Enqueue",
+		Drop:  true,
+		Count: 1,
+	}))
+	_, err = q.Enqueue([]string{"host1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Length())
+
+	_, err = q.Enqueue([]string{"host1", "host2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"host1", "host2"}, q.Hosts())
+
+	// Dropped dequeues lose the dequeued host
+	assert.NoError(t, injector.Set(Fault{
+		Point: QueuePointPrefix + "Dequeue",
+		Drop:  true,
+		Count: 1,
+	}))
+	host, err := q.Dequeue(10 * time.Millisecond)
+	assert.Error(t, err)
+	assert.Empty(t, host)
+	assert.Equal(t, 1, q.Length())
+
+	host, err = q.Dequeue(10 * time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "host2", host)
+}
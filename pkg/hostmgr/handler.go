@@ -18,11 +18,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
@@ -37,6 +39,7 @@ import (
 	"github.com/uber/peloton/pkg/common/stringset"
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	"github.com/uber/peloton/pkg/hostmgr/factory/operation"
 	"github.com/uber/peloton/pkg/hostmgr/factory/task"
 	"github.com/uber/peloton/pkg/hostmgr/host"
@@ -63,6 +66,9 @@ const (
 	// This is the number of completed reservations which
 	// will be fetched in one call from the reserver.
 	_completedReservationLimit = 10
+
+	// Host pool of the agents without the host pool attribute.
+	_defaultHostPool = host.DefaultHostPool
 )
 
 // validation errors
@@ -97,6 +103,12 @@ type ServiceHandler struct {
 	slackResourceTypes     []string
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap
 	taskStateManager       taskStateManager.StateManager
+	hostTaskIndex          taskStateManager.HostTaskIndex
+	cordonMap              host.CordonMap
+	approvalMap            host.ApprovalMap
+	eventBus               eventbus.Bus
+	hostEvaluator          constraints.Evaluator
+	hostPoolAttribute      string
 }
 
 // NewServiceHandler creates a new ServiceHandler.
@@ -113,7 +125,21 @@ func NewServiceHandler(
 	maintenanceQueue mqueue.MaintenanceQueue,
 	slackResourceTypes []string,
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap,
-	taskStateManager taskStateManager.StateManager) *ServiceHandler {
+	taskStateManager taskStateManager.StateManager,
+	hostTaskIndex taskStateManager.HostTaskIndex,
+	cordonMap host.CordonMap,
+	approvalMap host.ApprovalMap,
+	eventBus eventbus.Bus) *ServiceHandler {
+
+	constraintScope := hmConfig.ConstraintMetricsScope
+	if constraintScope == "" {
+		constraintScope = constraints.DefaultMetricsScope
+	}
+	hostEvaluator := constraints.NewEvaluator(pb_task.LabelConstraint_HOST)
+	if hmConfig.ConstraintOptimizer {
+		hostEvaluator = constraints.NewOptimizer(0).NewEvaluator(
+			pb_task.LabelConstraint_HOST)
+	}
 
 	handler := &ServiceHandler{
 		schedulerClient:        schedulerClient,
@@ -128,6 +154,15 @@ func NewServiceHandler(
 		slackResourceTypes:     slackResourceTypes,
 		maintenanceHostInfoMap: maintenanceHostInfoMap,
 		taskStateManager:       taskStateManager,
+		hostTaskIndex:          hostTaskIndex,
+		cordonMap:              cordonMap,
+		approvalMap:            approvalMap,
+		eventBus:               eventBus,
+		hostPoolAttribute:      hmConfig.HostPoolAttribute,
+		hostEvaluator: constraints.WithMetrics(
+			hostEvaluator,
+			pb_task.LabelConstraint_HOST,
+			parent.SubScope(constraintScope)),
 	}
 	// Creating Reserver object for handler
 	handler.reserver = reserver.NewReserver(
@@ -181,6 +216,138 @@ func (h *ServiceHandler) GetOutstandingOffers(
 	}, nil
 }
 
+// GetHostOffers implements InternalHostService.GetHostOffers.
+// This function returns the offers held in offer pool for each of the
+// requested hosts along with the host status and hold expiry, to debug
+// why hosts are not offered for placement.
+func (h *ServiceHandler) GetHostOffers(
+	ctx context.Context,
+	body *hostsvc.GetHostOffersRequest,
+) (*hostsvc.GetHostOffersResponse, error) {
+	hostSummaries, err := h.offerPool.GetHostSummaries(body.GetHostnames())
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]*hostsvc.GetHostOffersResponse_Host, 0, len(hostSummaries))
+	for hostname, hostSummary := range hostSummaries {
+		host := &hostsvc.GetHostOffersResponse_Host{
+			Hostname: hostname,
+			Status:   toHostStatus(hostSummary.GetHostStatus()),
+			UnreservedOffers: toSortedOffers(
+				hostSummary.GetOffers(summary.Unreserved)),
+			ReservedOffers: toSortedOffers(
+				hostSummary.GetOffers(summary.Reserved)),
+		}
+		if expiration := hostSummary.GetPlacingOfferExpiration(); !expiration.IsZero() {
+			host.PlacingExpiration = expiration.Format(time.RFC3339)
+		}
+		for taskID, expiration := range hostSummary.GetHeldTasks() {
+			host.HeldTasks = append(host.HeldTasks,
+				&hostsvc.GetHostOffersResponse_HeldTask{
+					TaskId:     &peloton.TaskID{Value: taskID},
+					Expiration: expiration.Format(time.RFC3339),
+				})
+		}
+		sort.Slice(host.HeldTasks, func(i, j int) bool {
+			return host.HeldTasks[i].GetTaskId().GetValue() <
+				host.HeldTasks[j].GetTaskId().GetValue()
+		})
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].GetHostname() < hosts[j].GetHostname()
+	})
+
+	return &hostsvc.GetHostOffersResponse{
+		Hosts: hosts,
+	}, nil
+}
+
+// ScoreHosts implements InternalHostService.ScoreHosts.
+// This function matches the hosts in offer pool with the resources and
+// constraints of a task config, the same way AcquireHostOffers does but
+// without claiming their offers, to debug where a task would be placed.
+func (h *ServiceHandler) ScoreHosts(
+	ctx context.Context,
+	body *hostsvc.ScoreHostsRequest,
+) (*hostsvc.ScoreHostsResponse, error) {
+	config := body.GetConfig()
+	numPorts := 0
+	for _, portConfig := range config.GetPorts() {
+		if portConfig.GetValue() == 0 {
+			// Dynamic port.
+			numPorts++
+		}
+	}
+	hostFilter := &hostsvc.HostFilter{
+		ResourceConstraint: &hostsvc.ResourceConstraint{
+			Minimum:   config.GetResource(),
+			NumPorts:  uint32(numPorts),
+			Revocable: config.GetRevocable(),
+		},
+		SchedulingConstraint: config.GetConstraint(),
+		HostPools:            body.GetHostPools(),
+	}
+
+	candidates, rejected := h.offerPool.ScoreHosts(hostFilter)
+	total := len(candidates)
+	if limit := int(body.GetLimit()); limit > 0 && limit < total {
+		candidates = candidates[:limit]
+	}
+
+	return &hostsvc.ScoreHostsResponse{
+		Candidates:      candidates,
+		TotalCandidates: uint32(total),
+		Rejected:        rejected,
+	}, nil
+}
+
+// GetTasksByHosts implements InternalHostService.GetTasksByHosts.
+// This function returns the Mesos tasks running on each of the requested
+// hosts from the task-to-host index.
+func (h *ServiceHandler) GetTasksByHosts(
+	ctx context.Context,
+	body *hostsvc.GetTasksByHostsRequest,
+) (*hostsvc.GetTasksByHostsResponse, error) {
+	hostTasks := make(map[string]*hostsvc.TaskIDList)
+	for hostname, taskIDs := range h.hostTaskIndex.GetTasksByHosts(
+		body.GetHostnames()) {
+		hostTasks[hostname] = &hostsvc.TaskIDList{
+			TaskIds: taskIDs,
+		}
+	}
+
+	return &hostsvc.GetTasksByHostsResponse{
+		HostTasks: hostTasks,
+	}, nil
+}
+
+// GetHostsByTasks implements InternalHostService.GetHostsByTasks.
+// This function returns the host each of the requested Mesos tasks
+// is running on from the task-to-host index.
+func (h *ServiceHandler) GetHostsByTasks(
+	ctx context.Context,
+	body *hostsvc.GetHostsByTasksRequest,
+) (*hostsvc.GetHostsByTasksResponse, error) {
+	return &hostsvc.GetHostsByTasksResponse{
+		TaskHosts: h.hostTaskIndex.GetHostsByTasks(body.GetTaskIds()),
+	}, nil
+}
+
+// toSortedOffers converts a map of offer id to offer into a slice of
+// offers sorted by offer id.
+func toSortedOffers(offers map[string]*mesos.Offer) []*mesos.Offer {
+	result := make([]*mesos.Offer, 0, len(offers))
+	for _, offer := range offers {
+		result = append(result, offer)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetId().GetValue() < result[j].GetId().GetValue()
+	})
+	return result
+}
+
 // GetHostsByQuery implements InternalHostService.GetHostsByQuery.
 // This function gets host resources from offer pool and filters
 // host list based on the requirements passed in the request
@@ -203,17 +370,23 @@ func (h *ServiceHandler) GetHostsByQuery(
 	hosts := make([]*hostsvc.GetHostsByQueryResponse_Host, 0, hostSummariesCount)
 	for hostname, hostSummary := range hostSummaries {
 		resources := scalar.FromOffersMapToMesosResources(hostSummary.GetOffers(summary.All))
-		_, nonRevocable := scalar.FilterRevocableMesosResources(resources)
-		nonRevocableResources := scalar.FromMesosResources(nonRevocable)
+		// Host summaries only hold revocable resources of the supported
+		// slack resource types.
+		revocable, nonRevocable := scalar.FilterRevocableMesosResources(resources)
 
-		if !nonRevocableResources.Compare(resourcesLimit, cmpLess) {
+		queriedResources := scalar.FromMesosResources(nonRevocable)
+		if body.GetRevocable() {
+			queriedResources = scalar.FromMesosResources(revocable)
+		}
+		if !queriedResources.Compare(resourcesLimit, cmpLess) {
 			continue
 		}
 
 		hosts = append(hosts, &hostsvc.GetHostsByQueryResponse_Host{
-			Hostname:  hostname,
-			Resources: nonRevocable,
-			Status:    toHostStatus(hostSummary.GetHostStatus()),
+			Hostname:           hostname,
+			Resources:          nonRevocable,
+			Status:             toHostStatus(hostSummary.GetHostStatus()),
+			RevocableResources: revocable,
 		})
 	}
 
@@ -292,11 +465,12 @@ func (h *ServiceHandler) AcquireHostOffers(
 
 		// create the peloton host offer
 		pHostOffer := hostsvc.HostOffer{
-			Hostname:   hostname,
-			AgentId:    offers[0].GetAgentId(),
-			Attributes: attributes,
-			Resources:  resources,
-			Id:         &peloton.HostOfferID{Value: hostOffer.ID},
+			Hostname:       hostname,
+			AgentId:        offers[0].GetAgentId(),
+			Attributes:     attributes,
+			Resources:      resources,
+			Id:             &peloton.HostOfferID{Value: hostOffer.ID},
+			Unavailability: hmutil.GetUnavailability(offers),
 		}
 
 		response.HostOffers = append(response.HostOffers, &pHostOffer)
@@ -325,7 +499,7 @@ func (h *ServiceHandler) GetHosts(
 
 	matcher := host.NewMatcher(
 		body.GetFilter(),
-		constraints.NewEvaluator(pb_task.LabelConstraint_HOST),
+		h.hostEvaluator,
 		func(resourceType string) bool {
 			return hmutil.IsSlackResourceType(resourceType, h.slackResourceTypes)
 		})
@@ -727,6 +901,7 @@ func (h *ServiceHandler) LaunchTasks(
 
 	var mesosTasks []*mesos.TaskInfo
 	var mesosTaskIds []string
+	taskLabels := make(map[string][]*peloton.Label, len(req.GetTasks()))
 
 	builder := task.NewBuilder(mesosResources)
 	for _, t := range req.GetTasks() {
@@ -775,6 +950,7 @@ func (h *ServiceHandler) LaunchTasks(
 		mesosTask.AgentId = req.GetAgentId()
 		mesosTasks = append(mesosTasks, mesosTask)
 		mesosTaskIds = append(mesosTaskIds, mesosTask.GetTaskId().GetValue())
+		taskLabels[mesosTask.GetTaskId().GetValue()] = t.GetConfig().GetLabels()
 	}
 
 	callType := sched.Call_ACCEPT
@@ -820,6 +996,12 @@ func (h *ServiceHandler) LaunchTasks(
 		}, nil
 	}
 
+	h.hostTaskIndex.AddTasks(req.GetHostname(), req.GetAgentId(), mesosTaskIds)
+	h.eventBus.Publish(&eventbus.TasksLaunchedEvent{
+		Hostname:   req.GetHostname(),
+		TaskLabels: taskLabels,
+	})
+
 	h.metrics.LaunchTasks.Inc(int64(len(mesosTasks)))
 	log.WithFields(log.Fields{
 		"tasks":         len(mesosTasks),
@@ -1031,6 +1213,9 @@ func (h *ServiceHandler) killTasks(
 		return &hostsvc.InvalidTaskIDs{Message: "Empty task ids"}, nil
 	}
 
+	// Tasks on DRAINING hosts are killed with the drain options of the host
+	drainingHosts := h.getDrainingHosts(taskIds)
+
 	var wg sync.WaitGroup
 	failedMutex := &sync.Mutex{}
 	var failedTaskIds []*mesos.TaskID
@@ -1039,12 +1224,19 @@ func (h *ServiceHandler) killTasks(
 		wg.Add(1)
 		go func(taskID *mesos.TaskID) {
 			defer wg.Done()
+			hostInfo := drainingHosts[taskID.GetValue()]
+			if hostInfo != nil {
+				h.notifyDrain(ctx, taskID, hostInfo)
+			}
+
 			callType := sched.Call_KILL
 			msg := &sched.Call{
 				FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
 				Type:        &callType,
 				Kill: &sched.Call_Kill{
 					TaskId: taskID,
+					KillPolicy: newKillPolicy(
+						hostInfo.GetDrainOptions().GetKillGracePeriodSeconds()),
 				},
 			}
 
@@ -1217,6 +1409,126 @@ func (h *ServiceHandler) ClusterCapacity(
 	return clusterCapacityResponse, nil
 }
 
+// GetClusterCapacity implements InternalHostService.GetClusterCapacity.
+// This function returns the total, allocated, draining and down capacity
+// of the cluster and of each host pool, computed from the agents
+// registered with Mesos master and the maintenance state of the hosts.
+func (h *ServiceHandler) GetClusterCapacity(
+	ctx context.Context,
+	body *hostsvc.GetClusterCapacityRequest,
+) (*hostsvc.GetClusterCapacityResponse, error) {
+	agents, err := h.operatorMasterClient.Agents()
+	if err != nil {
+		h.metrics.GetClusterCapacityFail.Inc(1)
+		log.WithError(err).Error("error getting agents for cluster capacity")
+		return &hostsvc.GetClusterCapacityResponse{
+			Error: &hostsvc.GetClusterCapacityResponse_Error{
+				ClusterUnavailable: &hostsvc.ClusterUnavailable{
+					Message: err.Error(),
+				},
+			},
+		}, nil
+	}
+
+	drainingHosts := stringset.New()
+	for _, hostInfo := range h.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}) {
+		drainingHosts.Add(hostInfo.GetHostname())
+	}
+	downHosts := stringset.New()
+	for _, hostInfo := range h.maintenanceHostInfoMap.GetDownHostInfos([]string{}) {
+		downHosts.Add(hostInfo.GetHostname())
+	}
+
+	cluster := &poolCapacity{}
+	pools := make(map[string]*poolCapacity)
+	for _, agent := range agents.GetAgents() {
+		pool := h.getHostPool(agent.GetAgentInfo())
+		if _, ok := pools[pool]; !ok {
+			pools[pool] = &poolCapacity{}
+		}
+
+		hostname := agent.GetAgentInfo().GetHostname()
+		draining := drainingHosts.Contains(hostname)
+		down := downHosts.Contains(hostname)
+		cluster.add(agent, draining, down)
+		pools[pool].add(agent, draining, down)
+	}
+	// DOWN hosts are usually no longer registered with Mesos master,
+	// so they are only counted for the whole cluster.
+	cluster.downHosts = uint32(downHosts.Len())
+
+	poolNames := make([]string, 0, len(pools))
+	for pool := range pools {
+		poolNames = append(poolNames, pool)
+	}
+	sort.Strings(poolNames)
+
+	resp := &hostsvc.GetClusterCapacityResponse{
+		Cluster: cluster.toHostPoolCapacity(""),
+	}
+	for _, pool := range poolNames {
+		resp.Pools = append(resp.Pools, pools[pool].toHostPoolCapacity(pool))
+	}
+
+	h.metrics.GetClusterCapacity.Inc(1)
+	return resp, nil
+}
+
+// getHostPool returns the host pool of an agent from the configured
+// host pool attribute.
+func (h *ServiceHandler) getHostPool(agentInfo *mesos.AgentInfo) string {
+	return host.GetHostPoolByAttribute(
+		agentInfo.GetAttributes(),
+		h.hostPoolAttribute)
+}
+
+// poolCapacity accumulates the capacity of a set of hosts.
+type poolCapacity struct {
+	hosts         uint32
+	drainingHosts uint32
+	downHosts     uint32
+	total         scalar.Resources
+	allocated     scalar.Resources
+	draining      scalar.Resources
+	down          scalar.Resources
+}
+
+// add adds the non-revocable resources of an agent to the capacity.
+func (c *poolCapacity) add(
+	agent *mesos_master.Response_GetAgents_Agent,
+	draining bool,
+	down bool) {
+	_, total := scalar.FilterRevocableMesosResources(agent.GetTotalResources())
+	_, allocated := scalar.FilterRevocableMesosResources(agent.GetAllocatedResources())
+	totalResources := scalar.FromMesosResources(total)
+
+	c.hosts++
+	c.total = c.total.Add(totalResources)
+	c.allocated = c.allocated.Add(scalar.FromMesosResources(allocated))
+	if draining {
+		c.drainingHosts++
+		c.draining = c.draining.Add(totalResources)
+	}
+	if down {
+		c.downHosts++
+		c.down = c.down.Add(totalResources)
+	}
+}
+
+func (c *poolCapacity) toHostPoolCapacity(
+	pool string) *hostsvc.HostPoolCapacity {
+	return &hostsvc.HostPoolCapacity{
+		Pool:          pool,
+		Hosts:         c.hosts,
+		DrainingHosts: c.drainingHosts,
+		DownHosts:     c.downHosts,
+		Total:         toHostSvcResources(&c.total),
+		Allocated:     toHostSvcResources(&c.allocated),
+		Draining:      toHostSvcResources(&c.draining),
+		Down:          toHostSvcResources(&c.down),
+	}
+}
+
 // GetMesosMasterHostPort returns the Leader Mesos Master hostname and port.
 func (h *ServiceHandler) GetMesosMasterHostPort(
 	ctx context.Context,
@@ -1334,14 +1646,118 @@ func (h *ServiceHandler) GetDrainingHosts(
 	}
 	log.WithField("hosts", hostnames).
 		Debug("Maintenance Queue - Dequeued hosts")
-	return &hostsvc.GetDrainingHostsResponse{
+
+	response := &hostsvc.GetDrainingHostsResponse{
 		Hostnames: hostnames,
+	}
+	// Hint resource manager with the tasks displaced by the drain, so
+	// that it can pre-admit their replacement capacity
+	if len(hostnames) > 0 {
+		for hostname, taskIDs := range h.hostTaskIndex.GetTasksByHosts(
+			hostnames) {
+			if response.HostTasks == nil {
+				response.HostTasks = make(map[string]*hostsvc.TaskIDList)
+			}
+			response.HostTasks[hostname] = &hostsvc.TaskIDList{
+				TaskIds: taskIDs,
+			}
+			h.eventBus.Publish(&eventbus.TasksEvictedEvent{
+				Hostname: hostname,
+				TaskIDs:  taskIDs,
+			})
+		}
+		// Tasks exempt from eviction by the drain options of the hosts
+		// are not rescheduled by resource manager
+		for _, hostInfo := range h.maintenanceHostInfoMap.GetDrainingHostInfos(
+			hostnames) {
+			exemption := hostInfo.GetDrainOptions().GetExemption()
+			if exemption == nil {
+				continue
+			}
+			if response.HostExemptions == nil {
+				response.HostExemptions = make(
+					map[string]*hpb.EvictionExemption)
+			}
+			response.HostExemptions[hostInfo.GetHostname()] = exemption
+		}
+	}
+	return response, nil
+}
+
+// CordonHosts implements InternalHostService.CordonHosts
+func (h *ServiceHandler) CordonHosts(
+	ctx context.Context,
+	request *hostsvc.CordonHostsRequest,
+) (*hostsvc.CordonHostsResponse, error) {
+	if len(request.GetHostnames()) == 0 {
+		h.metrics.CordonHostsInvalid.Inc(1)
+		return &hostsvc.CordonHostsResponse{
+			Error: &hostsvc.CordonHostsResponse_Error{
+				InvalidArgument: &hostsvc.InvalidArgument{
+					Message: "no hostnames provided",
+				},
+			},
+		}, nil
+	}
+
+	if err := h.cordonMap.Cordon(
+		ctx,
+		request.GetHostnames(),
+		request.GetReason()); err != nil {
+		log.WithError(err).
+			WithField("hosts", request.GetHostnames()).
+			Error("failed to cordon hosts")
+		h.metrics.CordonHostsFail.Inc(1)
+		return nil, err
+	}
+
+	h.metrics.CordonHosts.Inc(1)
+	return &hostsvc.CordonHostsResponse{}, nil
+}
+
+// UncordonHosts implements InternalHostService.UncordonHosts
+func (h *ServiceHandler) UncordonHosts(
+	ctx context.Context,
+	request *hostsvc.UncordonHostsRequest,
+) (*hostsvc.UncordonHostsResponse, error) {
+	if len(request.GetHostnames()) == 0 {
+		h.metrics.UncordonHostsInvalid.Inc(1)
+		return &hostsvc.UncordonHostsResponse{
+			Error: &hostsvc.UncordonHostsResponse_Error{
+				InvalidArgument: &hostsvc.InvalidArgument{
+					Message: "no hostnames provided",
+				},
+			},
+		}, nil
+	}
+
+	if err := h.cordonMap.Uncordon(ctx, request.GetHostnames()); err != nil {
+		log.WithError(err).
+			WithField("hosts", request.GetHostnames()).
+			Error("failed to uncordon hosts")
+		h.metrics.UncordonHostsFail.Inc(1)
+		return nil, err
+	}
+
+	h.metrics.UncordonHosts.Inc(1)
+	return &hostsvc.UncordonHostsResponse{}, nil
+}
+
+// GetCordonedHosts implements InternalHostService.GetCordonedHosts
+func (h *ServiceHandler) GetCordonedHosts(
+	ctx context.Context,
+	request *hostsvc.GetCordonedHostsRequest,
+) (*hostsvc.GetCordonedHostsResponse, error) {
+	h.metrics.GetCordonedHosts.Inc(1)
+	return &hostsvc.GetCordonedHostsResponse{
+		Hosts: h.cordonMap.GetCordonedHosts(),
 	}, nil
 }
 
 // MarkHostsDrained implements InternalHostService.MarkHostsDrained
 // Mark the host as drained. This method is called by Resource Manager Drainer
-// when there are no tasks on the DRAINING hosts
+// when there are no tasks on the DRAINING hosts. Hosts whose maintenance
+// waits for approval are marked DRAINED instead of being put down.
 func (h *ServiceHandler) MarkHostsDrained(
 	ctx context.Context,
 	request *hostsvc.MarkHostsDrainedRequest,
@@ -1370,12 +1786,37 @@ func (h *ServiceHandler) MarkHostsDrained(
 	if len(machineIDs) == 0 {
 		return &hostsvc.MarkHostsDrainedResponse{}, nil
 	}
-	var downedHosts []string
+	var downedHosts, approvedHosts, pendingHosts, drainedHosts []string
+	var approvedDrainedHosts []string
 	var errs error
 	for _, machineID := range machineIDs {
+		approval := h.approvalMap.Get(machineID.GetHostname())
+		if host.IsApprovalPending(approval) {
+			// Keep the host drained until its maintenance is approved.
+			// The host is re-enqueued by the drainer until then.
+			pendingHosts = append(pendingHosts, machineID.GetHostname())
+			drained, err := h.approvalMap.MarkDrained(
+				ctx,
+				machineID.GetHostname())
+			if err != nil {
+				errs = multierr.Append(errs, err)
+				log.WithError(err).
+					WithField("hostname", machineID.GetHostname()).
+					Error("failed to mark host drained")
+				h.metrics.MarkHostsDrainedFail.Inc(1)
+				continue
+			}
+			if drained {
+				drainedHosts = append(drainedHosts, machineID.GetHostname())
+			}
+			continue
+		}
+
 		// Start maintenance on the host by posting to
 		// /machine/down endpoint of the Mesos Master
-		err := h.operatorMasterClient.StartMaintenance([]*mesos.MachineID{machineID})
+		err := h.operatorMasterClient.StartMaintenance(
+			ctx,
+			[]*mesos.MachineID{machineID})
 		if err != nil {
 			errs = multierr.Append(errs, err)
 			log.WithError(err).
@@ -1400,9 +1841,55 @@ func (h *ServiceHandler) MarkHostsDrained(
 				}).WithError(err).
 				Error("failed to update host state in host map")
 		}
-		downedHosts = append(downedHosts, machineID.GetHostname())
+		if approval == nil {
+			downedHosts = append(downedHosts, machineID.GetHostname())
+			continue
+		}
+		approvedHosts = append(approvedHosts, machineID.GetHostname())
+		if approval.GetDrainedTime() == "" {
+			// Approved before the host was drained
+			downedHosts = append(downedHosts, machineID.GetHostname())
+		} else {
+			approvedDrainedHosts = append(
+				approvedDrainedHosts,
+				machineID.GetHostname())
+		}
 	}
 
+	if len(pendingHosts) > 0 {
+		h.maintenanceQueue.MarkProcessed(pendingHosts)
+	}
+	if len(drainedHosts) > 0 {
+		h.eventBus.Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: drainedHosts,
+			From:      hpb.HostState_HOST_STATE_DRAINING,
+			To:        hpb.HostState_HOST_STATE_DRAINED,
+		})
+	}
+	if len(approvedDrainedHosts) > 0 {
+		h.maintenanceQueue.MarkProcessed(approvedDrainedHosts)
+		h.eventBus.Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: approvedDrainedHosts,
+			From:      hpb.HostState_HOST_STATE_DRAINED,
+			To:        hpb.HostState_HOST_STATE_DOWN,
+		})
+	}
+	if len(downedHosts) > 0 {
+		h.maintenanceQueue.MarkProcessed(downedHosts)
+		h.eventBus.Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: downedHosts,
+			From:      hpb.HostState_HOST_STATE_DRAINING,
+			To:        hpb.HostState_HOST_STATE_DOWN,
+		})
+	}
+	if len(approvedHosts) > 0 {
+		if err := h.approvalMap.Remove(ctx, approvedHosts); err != nil {
+			log.WithError(err).
+				WithField("hostnames", approvedHosts).
+				Error("failed to remove maintenance approvals of downed hosts")
+		}
+	}
+	downedHosts = append(downedHosts, approvedDrainedHosts...)
 	h.metrics.MarkHostsDrained.Inc(int64(len(downedHosts)))
 	return &hostsvc.MarkHostsDrainedResponse{
 		MarkedHosts: downedHosts,
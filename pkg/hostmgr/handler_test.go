@@ -15,6 +15,7 @@
 package hostmgr
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -28,17 +29,23 @@ import (
 	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/api/v0/volume"
 	pb_eventstream "github.com/uber/peloton/.gen/peloton/private/eventstream"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/constraints"
 	"github.com/uber/peloton/pkg/common/queue"
 	"github.com/uber/peloton/pkg/common/reservation"
 	"github.com/uber/peloton/pkg/common/util"
 	bin_packing "github.com/uber/peloton/pkg/hostmgr/binpacking"
 	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ebmocks "github.com/uber/peloton/pkg/hostmgr/eventbus/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
 	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
@@ -48,7 +55,9 @@ import (
 	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/reserver"
 	reserver_mocks "github.com/uber/peloton/pkg/hostmgr/reserver/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
+	taskStateManager "github.com/uber/peloton/pkg/hostmgr/task"
 	task_state_mocks "github.com/uber/peloton/pkg/hostmgr/task/mocks"
 	storage_mocks "github.com/uber/peloton/pkg/storage/mocks"
 
@@ -203,6 +212,9 @@ type HostMgrHandlerTestSuite struct {
 	downMachines           []*mesos.MachineID
 	maintenanceHostInfoMap *hm.MockMaintenanceHostInfoMap
 	taskStateManager       *task_state_mocks.MockStateManager
+	cordonMap              *hm.MockCordonMap
+	approvalMap            *hm.MockApprovalMap
+	eventBus               *ebmocks.MockBus
 }
 
 func (suite *HostMgrHandlerTestSuite) SetupSuite() {
@@ -252,10 +264,14 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 		[]string{},        /*slack_resource_types*/
 		bin_packing.CreateRanker("FIRST_FIT"),
 		time.Duration(30*time.Second),
+		declinepolicy.NewDefaultPolicy(declinepolicy.Config{}, tally.NoopScope),
 	)
 
 	suite.maintenanceQueue = qm.NewMockMaintenanceQueue(suite.ctrl)
 	suite.maintenanceHostInfoMap = hm.NewMockMaintenanceHostInfoMap(suite.ctrl)
+	suite.cordonMap = hm.NewMockCordonMap(suite.ctrl)
+	suite.approvalMap = hm.NewMockApprovalMap(suite.ctrl)
+	suite.eventBus = ebmocks.NewMockBus(suite.ctrl)
 
 	suite.handler = &ServiceHandler{
 		schedulerClient:        suite.schedulerClient,
@@ -268,6 +284,15 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 		maintenanceQueue:       suite.maintenanceQueue,
 		maintenanceHostInfoMap: suite.maintenanceHostInfoMap,
 		taskStateManager:       suite.taskStateManager,
+		hostTaskIndex: taskStateManager.NewHostTaskIndex(
+			nil,
+			suite.testScope),
+		cordonMap:   suite.cordonMap,
+		approvalMap: suite.approvalMap,
+		eventBus:    suite.eventBus,
+		hostEvaluator: constraints.NewEvaluatorWithMetrics(
+			task.LabelConstraint_HOST,
+			suite.testScope),
 	}
 	suite.handler.reserver = reserver.NewReserver(
 		metrics.NewMetrics(suite.testScope),
@@ -356,6 +381,117 @@ func (suite *HostMgrHandlerTestSuite) TestGetOutstandingOffers() {
 	suite.Equal(len(resp.Offers), numHosts)
 }
 
+func (suite *HostMgrHandlerTestSuite) TestGetHostOffers() {
+	defer suite.ctrl.Finish()
+
+	resp, err := suite.handler.GetHostOffers(rootCtx, &hostsvc.GetHostOffersRequest{})
+	suite.NoError(err)
+	suite.Empty(resp.GetHosts())
+
+	numHosts := 3
+	suite.pool.AddOffers(context.Background(), generateOffers(numHosts))
+	taskID := &peloton.TaskID{Value: "t1"}
+	suite.NoError(suite.pool.HoldForTasks("hostname-1", []*peloton.TaskID{taskID}))
+
+	// Get offers of all hosts
+	resp, err = suite.handler.GetHostOffers(rootCtx, &hostsvc.GetHostOffersRequest{})
+	suite.NoError(err)
+	suite.Len(resp.GetHosts(), numHosts)
+	for i, host := range resp.GetHosts() {
+		suite.Equal(fmt.Sprintf("hostname-%d", i), host.GetHostname())
+		suite.Len(host.GetUnreservedOffers(), 1)
+		suite.Empty(host.GetReservedOffers())
+		suite.Empty(host.GetPlacingExpiration())
+	}
+
+	// Get offers of the held host
+	resp, err = suite.handler.GetHostOffers(rootCtx, &hostsvc.GetHostOffersRequest{
+		Hostnames: []string{"hostname-1", "unknown-host"},
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetHosts(), 1)
+	host := resp.GetHosts()[0]
+	suite.Equal("hostname-1", host.GetHostname())
+	suite.Equal("held", host.GetStatus())
+	suite.Len(host.GetHeldTasks(), 1)
+	suite.Equal(taskID.GetValue(), host.GetHeldTasks()[0].GetTaskId().GetValue())
+	suite.NotEmpty(host.GetHeldTasks()[0].GetExpiration())
+}
+
+// TestScoreHosts tests scoring the hosts in offer pool for a task config,
+// without claiming their offers.
+func (suite *HostMgrHandlerTestSuite) TestScoreHosts() {
+	defer suite.ctrl.Finish()
+
+	numHosts := 3
+	suite.pool.AddOffers(context.Background(), generateOffers(numHosts))
+	suite.NoError(suite.pool.HoldForTasks(
+		"hostname-1", []*peloton.TaskID{{Value: "t1"}}))
+
+	// Test rejecting hosts by constraint and status
+	resp, err := suite.handler.ScoreHosts(rootCtx, &hostsvc.ScoreHostsRequest{
+		Config: &task.TaskConfig{
+			Resource: &task.ResourceConfig{CpuLimit: 1.0},
+			Constraint: &task.Constraint{
+				Type: task.Constraint_LABEL_CONSTRAINT,
+				LabelConstraint: &task.LabelConstraint{
+					Kind: task.LabelConstraint_HOST,
+					Label: &peloton.Label{
+						Key:   constraints.HostNameKey,
+						Value: "hostname-2",
+					},
+					Condition:   task.LabelConstraint_CONDITION_EQUAL,
+					Requirement: 1,
+				},
+			},
+		},
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetCandidates(), 1)
+	suite.Equal(uint32(1), resp.GetTotalCandidates())
+	candidate := resp.GetCandidates()[0]
+	suite.Equal("hostname-2", candidate.GetHostname())
+	suite.Equal(hostsvc.HostFilterResult_MATCH, candidate.GetResult())
+	suite.True(candidate.GetScore() > 0 && candidate.GetScore() <= 1)
+
+	suite.Len(resp.GetRejected(), 2)
+	suite.Equal("hostname-0", resp.GetRejected()[0].GetHostname())
+	suite.Equal(hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+		resp.GetRejected()[0].GetResult())
+	suite.Contains(resp.GetRejected()[0].GetReason(), "hostname-2")
+	suite.Equal("hostname-1", resp.GetRejected()[1].GetHostname())
+	suite.Equal(hostsvc.HostFilterResult_MISMATCH_STATUS,
+		resp.GetRejected()[1].GetResult())
+
+	// The offers of the candidate hosts are not claimed
+	hostSummary, err := suite.pool.GetHostSummary("hostname-2")
+	suite.NoError(err)
+	suite.Equal(summary.ReadyHost, hostSummary.GetHostStatus())
+
+	// Test candidates beyond the limit, highest score first
+	resp, err = suite.handler.ScoreHosts(rootCtx, &hostsvc.ScoreHostsRequest{
+		Config: &task.TaskConfig{},
+		Limit:  1,
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetCandidates(), 1)
+	suite.Equal(uint32(2), resp.GetTotalCandidates())
+	suite.Len(resp.GetRejected(), 1)
+
+	// Test rejecting hosts by resources
+	resp, err = suite.handler.ScoreHosts(rootCtx, &hostsvc.ScoreHostsRequest{
+		Config: &task.TaskConfig{
+			Resource: &task.ResourceConfig{CpuLimit: _perHostCPU + 1},
+		},
+	})
+	suite.NoError(err)
+	suite.Empty(resp.GetCandidates())
+	suite.Len(resp.GetRejected(), numHosts)
+	suite.Equal(hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES,
+		resp.GetRejected()[0].GetResult())
+	suite.NotEmpty(resp.GetRejected()[0].GetReason())
+}
+
 func (suite *HostMgrHandlerTestSuite) TestGetHostsByQueryNoOffers() {
 	defer suite.ctrl.Finish()
 
@@ -416,6 +552,90 @@ func (suite *HostMgrHandlerTestSuite) TestGetHostsByQueryFilterHostnames() {
 	suite.Equal("hostname-2", resp.Hosts[1].Hostname)
 }
 
+// TestGetHostsByQueryRevocable tests querying the revocable resources
+// of the hosts.
+func (suite *HostMgrHandlerTestSuite) TestGetHostsByQueryRevocable() {
+	defer suite.ctrl.Finish()
+
+	pool := offerpool.NewOfferPool(
+		_offerHoldTime,
+		suite.schedulerClient,
+		offerpool.NewMetrics(suite.testScope.SubScope("revocable_offer")),
+		nil,               /* frameworkInfoProvider */
+		suite.volumeStore, /* volumeStore */
+		[]string{},        /*scarce_resource_types*/
+		[]string{"cpus"},  /*slack_resource_types*/
+		bin_packing.CreateRanker("FIRST_FIT"),
+		time.Duration(30*time.Second),
+		declinepolicy.NewDefaultPolicy(declinepolicy.Config{}, tally.NoopScope),
+	)
+	suite.handler.offerPool = pool
+	defer func() {
+		suite.handler.offerPool = suite.pool
+	}()
+
+	var offers []*mesos.Offer
+	for i, revocableCPU := range []float64{0.0, 2.0, 4.0} {
+		offer := generateOfferWithResource(
+			fmt.Sprintf("offer-%d", i),
+			fmt.Sprintf("agent-%d", i),
+			fmt.Sprintf("hostname-%d", i),
+			1.0, _perHostMem, _perHostDisk, 0.0)
+		if revocableCPU > 0 {
+			offer.Resources = append(offer.Resources,
+				util.NewMesosResourceBuilder().
+					WithName("cpus").
+					WithValue(revocableCPU).
+					WithRevocable(&mesos.Resource_RevocableInfo{}).
+					Build())
+		}
+		offers = append(offers, offer)
+	}
+	pool.AddOffers(context.Background(), offers)
+
+	// Revocable resources are returned separately from the
+	// non-revocable ones
+	resp, err := suite.handler.GetHostsByQuery(
+		rootCtx,
+		&hostsvc.GetHostsByQueryRequest{
+			Hostnames: []string{"hostname-1"},
+		})
+	suite.NoError(err)
+	suite.Equal(1, len(resp.Hosts))
+	suite.Equal(1.0,
+		scalar.FromMesosResources(resp.Hosts[0].GetResources()).GetCPU())
+	suite.Equal(2.0,
+		scalar.FromMesosResources(resp.Hosts[0].GetRevocableResources()).GetCPU())
+
+	// Query hosts by their revocable resources
+	resp, err = suite.handler.GetHostsByQuery(
+		rootCtx,
+		&hostsvc.GetHostsByQueryRequest{
+			Resource: &task.ResourceConfig{
+				CpuLimit: 2.0,
+			},
+			Revocable: true,
+		})
+	suite.NoError(err)
+	suite.Equal(2, len(resp.Hosts))
+	sort.Slice(resp.Hosts, func(i, j int) bool {
+		return strings.Compare(resp.Hosts[i].Hostname, resp.Hosts[j].Hostname) < 0
+	})
+	suite.Equal("hostname-1", resp.Hosts[0].Hostname)
+	suite.Equal("hostname-2", resp.Hosts[1].Hostname)
+
+	// None of the hosts has enough non-revocable resources
+	resp, err = suite.handler.GetHostsByQuery(
+		rootCtx,
+		&hostsvc.GetHostsByQueryRequest{
+			Resource: &task.ResourceConfig{
+				CpuLimit: 2.0,
+			},
+		})
+	suite.NoError(err)
+	suite.Empty(resp.Hosts)
+}
+
 func (suite *HostMgrHandlerTestSuite) TestGetHostsByQueryGreaterThanEqualTo() {
 	defer suite.ctrl.Finish()
 
@@ -776,6 +996,13 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunch() {
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
 			Return(nil),
+		suite.eventBus.EXPECT().
+			Publish(&eventbus.TasksLaunchedEvent{
+				Hostname: launchReq.GetHostname(),
+				TaskLabels: map[string][]*peloton.Label{
+					fmt.Sprintf(_taskIDFmt, 0): launchReq.GetTasks()[0].GetConfig().GetLabels(),
+				},
+			}),
 	)
 
 	launchResp, err = suite.handler.LaunchTasks(
@@ -789,6 +1016,28 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunch() {
 		int64(1),
 		suite.testScope.Snapshot().Counters()["launch_tasks+"].Value())
 
+	// Launched tasks are added to the task-to-host index.
+	taskID := fmt.Sprintf(_taskIDFmt, 0)
+	tasksResp, err := suite.handler.GetTasksByHosts(
+		rootCtx,
+		&hostsvc.GetTasksByHostsRequest{
+			Hostnames: []string{launchReq.GetHostname()},
+		})
+	suite.NoError(err)
+	suite.Equal(
+		[]string{taskID},
+		tasksResp.GetHostTasks()[launchReq.GetHostname()].GetTaskIds())
+
+	hostsResp, err := suite.handler.GetHostsByTasks(
+		rootCtx,
+		&hostsvc.GetHostsByTasksRequest{
+			TaskIds: []string{taskID, "unknown-task"},
+		})
+	suite.NoError(err)
+	suite.Equal(
+		map[string]string{taskID: launchReq.GetHostname()},
+		hostsResp.GetTaskHosts())
+
 	// TODO: Add check for number of HostOffers in placing state.
 	suite.checkResourcesGauges(0, "ready")
 	suite.checkResourcesGauges(0, "placing")
@@ -864,6 +1113,8 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunchOnNonHeldTask() {
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
 			Return(nil),
+		suite.eventBus.EXPECT().
+			Publish(gomock.AssignableToTypeOf(&eventbus.TasksLaunchedEvent{})),
 	)
 
 	launchResp, err := suite.handler.LaunchTasks(
@@ -1187,6 +1438,10 @@ func (suite *HostMgrHandlerTestSuite) TestKillAndReserveTaskWithoutHostSummary()
 	killedTaskIds := make(map[string]bool)
 	mockMutex := &sync.Mutex{}
 
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+
 	// Set expectations on provider
 	suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
 		suite.frameworkID,
@@ -1248,6 +1503,10 @@ func (suite *HostMgrHandlerTestSuite) TestKillAndReserveTaskKillFailure() {
 	killedTaskIds := make(map[string]bool)
 	mockMutex := &sync.Mutex{}
 
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+
 	// Set expectations on provider
 	suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
 		suite.frameworkID,
@@ -1316,6 +1575,10 @@ func (suite *HostMgrHandlerTestSuite) TestKillAndReserveTask() {
 	killedTaskIds := make(map[string]bool)
 	mockMutex := &sync.Mutex{}
 
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+
 	// Set expectations on provider
 	suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
 		suite.frameworkID,
@@ -1381,6 +1644,10 @@ func (suite *HostMgrHandlerTestSuite) TestKillTask() {
 	killedTaskIds := make(map[string]bool)
 	mockMutex := &sync.Mutex{}
 
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+
 	// Set expectations on provider
 	suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
 		suite.frameworkID,
@@ -1473,6 +1740,9 @@ func (suite *HostMgrHandlerTestSuite) TestKillTaskFailure() {
 				err = errors.New(tt.errMsg)
 			}
 
+			suite.maintenanceHostInfoMap.EXPECT().
+				GetDrainingHostInfos([]string{}).
+				Return(nil)
 			suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(suite.frameworkID).Times(2)
 			suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID).Times(2)
 
@@ -1511,6 +1781,106 @@ func (suite *HostMgrHandlerTestSuite) TestKillTaskFailure() {
 	}
 }
 
+// TestKillTaskOnDrainingHost tests that the tasks on a DRAINING host are
+// notified and killed with the drain options of the host
+func (suite *HostMgrHandlerTestSuite) TestKillTaskOnDrainingHost() {
+	defer suite.ctrl.Finish()
+
+	t1 := "t1"
+	t2 := "t2"
+	agentID := "agent-0"
+	executorID := "thermos-t1"
+	suite.handler.hostTaskIndex.AddTasks(
+		"hostname-0",
+		&mesos.AgentID{Value: &agentID},
+		[]string{t1, t2})
+	runningState := mesos.TaskState_TASK_RUNNING
+	suite.handler.hostTaskIndex.UpdateTaskStatus(&mesos.TaskStatus{
+		TaskId:     &mesos.TaskID{Value: &t1},
+		AgentId:    &mesos.AgentID{Value: &agentID},
+		ExecutorId: &mesos.ExecutorID{Value: &executorID},
+		State:      &runningState,
+	})
+
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: "hostname-0",
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: &hpb.DrainOptions{
+					KillGracePeriodSeconds: 60,
+					Message:                "deregister",
+					Labels: []*peloton.Label{
+						{Key: "reason", Value: "maintenance"},
+					},
+				},
+			},
+		})
+
+	suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
+		suite.frameworkID,
+	).Times(3)
+	suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(
+		_streamID,
+	).Times(3)
+
+	killedTaskIds := make(map[string]bool)
+	var notification drainNotification
+	mockMutex := &sync.Mutex{}
+	suite.schedulerClient.EXPECT().
+		Call(
+			gomock.Eq(_streamID),
+			gomock.Any(),
+		).
+		Do(func(_ string, msg proto.Message) {
+			call := msg.(*sched.Call)
+			mockMutex.Lock()
+			defer mockMutex.Unlock()
+			switch call.GetType() {
+			case sched.Call_MESSAGE:
+				suite.Equal(agentID, call.GetMessage().GetAgentId().GetValue())
+				suite.Equal(
+					executorID,
+					call.GetMessage().GetExecutorId().GetValue())
+				suite.NoError(
+					json.Unmarshal(call.GetMessage().GetData(), &notification))
+			case sched.Call_KILL:
+				suite.Equal(
+					int64(60*time.Second),
+					call.GetKill().GetKillPolicy().GetGracePeriod().GetNanoseconds())
+				killedTaskIds[call.GetKill().GetTaskId().GetValue()] = true
+			default:
+				suite.Fail("unexpected call type")
+			}
+		}).
+		Return(nil).
+		Times(3)
+
+	resp, err := suite.handler.KillTasks(rootCtx, &hostsvc.KillTasksRequest{
+		TaskIds: []*mesos.TaskID{{Value: &t1}, {Value: &t2}},
+	})
+	suite.NoError(err)
+	suite.Nil(resp.GetError())
+	suite.Equal(map[string]bool{t1: true, t2: true}, killedTaskIds)
+	suite.Equal(drainNotification{
+		Type:                   _drainNotificationType,
+		Hostname:               "hostname-0",
+		TaskID:                 t1,
+		Message:                "deregister",
+		Labels:                 map[string]string{"reason": "maintenance"},
+		KillGracePeriodSeconds: 60,
+	}, notification)
+
+	// The executor of t2 is not known yet, so it is killed without notification
+	suite.Equal(
+		int64(1),
+		suite.testScope.Snapshot().Counters()["drain_notifications+"].Value())
+	suite.Equal(
+		int64(1),
+		suite.testScope.Snapshot().Counters()["drain_notifications_skipped+"].Value())
+}
+
 func (suite *HostMgrHandlerTestSuite) TestServiceHandlerClusterCapacity() {
 	scalerType := mesos.Value_SCALAR
 	scalerVal := 200.0
@@ -1650,6 +2020,107 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerClusterCapacityWithoutAg
 	suite.Equal(resp.Error.ClusterUnavailable.Message, "error getting host agentmap")
 }
 
+// TestGetClusterCapacity tests reporting the capacity of the cluster
+// and of each host pool by maintenance state.
+func (suite *HostMgrHandlerTestSuite) TestGetClusterCapacity() {
+	defer suite.ctrl.Finish()
+
+	poolAttribute := "pool"
+	suite.handler.hostPoolAttribute = poolAttribute
+	defer func() { suite.handler.hostPoolAttribute = "" }()
+
+	textType := mesos.Value_TEXT
+	makeAgent := func(hostname string, pool string) *mesos_master.Response_GetAgents_Agent {
+		agentInfo := &mesos.AgentInfo{Hostname: &hostname}
+		if pool != "" {
+			agentInfo.Attributes = []*mesos.Attribute{{
+				Name: &poolAttribute,
+				Type: &textType,
+				Text: &mesos.Value_Text{Value: &pool},
+			}}
+		}
+		return &mesos_master.Response_GetAgents_Agent{
+			AgentInfo: agentInfo,
+			TotalResources: []*mesos.Resource{
+				util.NewMesosResourceBuilder().
+					WithName(common.MesosCPU).
+					WithValue(4.0).
+					Build(),
+				util.NewMesosResourceBuilder().
+					WithName(common.MesosGPU).
+					WithValue(1.0).
+					Build(),
+			},
+			AllocatedResources: []*mesos.Resource{
+				util.NewMesosResourceBuilder().
+					WithName(common.MesosCPU).
+					WithValue(1.0).
+					Build(),
+			},
+		}
+	}
+
+	suite.masterOperatorClient.EXPECT().Agents().
+		Return(&mesos_master.Response_GetAgents{
+			Agents: []*mesos_master.Response_GetAgents_Agent{
+				makeAgent("host1", "batch"),
+				makeAgent("host2", "batch"),
+				makeAgent("host3", ""),
+			},
+		}, nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{{Hostname: "host2"}})
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{{Hostname: "host4"}})
+
+	resp, err := suite.handler.GetClusterCapacity(
+		rootCtx,
+		&hostsvc.GetClusterCapacityRequest{})
+	suite.NoError(err)
+	suite.Nil(resp.GetError())
+
+	getCapacity := func(resources []*hostsvc.Resource, kind string) float64 {
+		for _, r := range resources {
+			if r.GetKind() == kind {
+				return r.GetCapacity()
+			}
+		}
+		return 0
+	}
+
+	cluster := resp.GetCluster()
+	suite.Equal(uint32(3), cluster.GetHosts())
+	suite.Equal(uint32(1), cluster.GetDrainingHosts())
+	suite.Equal(uint32(1), cluster.GetDownHosts())
+	suite.Equal(12.0, getCapacity(cluster.GetTotal(), common.CPU))
+	suite.Equal(3.0, getCapacity(cluster.GetTotal(), common.GPU))
+	suite.Equal(3.0, getCapacity(cluster.GetAllocated(), common.CPU))
+	suite.Equal(4.0, getCapacity(cluster.GetDraining(), common.CPU))
+	suite.Equal(0.0, getCapacity(cluster.GetDown(), common.CPU))
+
+	suite.Len(resp.GetPools(), 2)
+	suite.Equal("batch", resp.GetPools()[0].GetPool())
+	suite.Equal(uint32(2), resp.GetPools()[0].GetHosts())
+	suite.Equal(uint32(1), resp.GetPools()[0].GetDrainingHosts())
+	suite.Equal(8.0, getCapacity(resp.GetPools()[0].GetTotal(), common.CPU))
+	suite.Equal(_defaultHostPool, resp.GetPools()[1].GetPool())
+	suite.Equal(uint32(1), resp.GetPools()[1].GetHosts())
+	suite.Equal(0.0, getCapacity(resp.GetPools()[1].GetDraining(), common.CPU))
+
+	// Test Agents error
+	suite.masterOperatorClient.EXPECT().Agents().
+		Return(nil, errors.New("master unavailable"))
+	resp, err = suite.handler.GetClusterCapacity(
+		rootCtx,
+		&hostsvc.GetClusterCapacityRequest{})
+	suite.NoError(err)
+	suite.Equal(
+		"master unavailable",
+		resp.GetError().GetClusterUnavailable().GetMessage())
+}
+
 func (suite *HostMgrHandlerTestSuite) TestServiceHandlerClusterCapacityWithQuota() {
 	scalerType := mesos.Value_SCALAR
 	scalerVal := 200.0
@@ -2085,10 +2556,61 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerGetDrainingHosts() {
 	testHost := "testhost"
 
 	suite.maintenanceQueue.EXPECT().Dequeue(gomock.Any()).Return(testHost, nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{testHost}).
+		Return(nil)
 	resp, err := suite.handler.GetDrainingHosts(context.Background(), req)
 	suite.Equal(1, len(resp.GetHostnames()))
 	suite.Equal(testHost, resp.GetHostnames()[0])
 	suite.NoError(err)
+	suite.Empty(resp.GetHostTasks())
+	suite.Empty(resp.GetHostExemptions())
+
+	// Tasks running on the draining host are returned as hints
+	agentID := "agent-0"
+	suite.handler.hostTaskIndex.AddTasks(
+		testHost,
+		&mesos.AgentID{Value: &agentID},
+		[]string{"t1", "t2"})
+	suite.eventBus.EXPECT().
+		Publish(&eventbus.TasksEvictedEvent{
+			Hostname: testHost,
+			TaskIDs:  []string{"t1", "t2"},
+		}).
+		Times(3)
+	suite.maintenanceQueue.EXPECT().Dequeue(gomock.Any()).Return(testHost, nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{testHost}).
+		Return(nil)
+	resp, err = suite.handler.GetDrainingHosts(context.Background(), req)
+	suite.NoError(err)
+	suite.Equal(
+		map[string]*hostsvc.TaskIDList{
+			testHost: {TaskIds: []string{"t1", "t2"}},
+		},
+		resp.GetHostTasks())
+
+	// The exemption of the drain options of the host is returned
+	exemption := &hpb.EvictionExemption{
+		JobTypes: []job.JobType{job.JobType_DAEMON},
+	}
+	suite.maintenanceQueue.EXPECT().Dequeue(gomock.Any()).Return(testHost, nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{testHost}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: testHost,
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: &hpb.DrainOptions{
+					Exemption: exemption,
+				},
+			},
+		})
+	resp, err = suite.handler.GetDrainingHosts(context.Background(), req)
+	suite.NoError(err)
+	suite.Equal(
+		map[string]*hpb.EvictionExemption{testHost: exemption},
+		resp.GetHostExemptions())
 
 	suite.maintenanceQueue.EXPECT().
 		Dequeue(gomock.Any()).
@@ -2109,6 +2631,9 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerGetDrainingHosts() {
 	suite.maintenanceQueue.EXPECT().
 		Dequeue(gomock.Any()).
 		Return("", queue.DequeueTimeOutError{})
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{testHost}).
+		Return(nil)
 	resp, err = suite.handler.GetDrainingHosts(context.Background(), req)
 	suite.Equal(1, len(resp.GetHostnames()))
 	suite.Equal(testHost, resp.GetHostnames()[0])
@@ -2144,7 +2669,7 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrained() {
 			Return(hostInfos),
 
 		suite.masterOperatorClient.EXPECT().
-			StartMaintenance(gomock.Any()).
+			StartMaintenance(gomock.Any(), gomock.Any()).
 			Return(nil).
 			Do(func(machineIds []*mesos.MachineID) {
 				for i := range drainingMachines {
@@ -2154,6 +2679,7 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrained() {
 	)
 
 	for _, hostInfo := range hostInfos {
+		suite.approvalMap.EXPECT().Get(hostInfo.GetHostname()).Return(nil)
 		suite.maintenanceHostInfoMap.EXPECT().UpdateHostState(
 			hostInfo.GetHostname(),
 			hpb.HostState_HOST_STATE_DRAINING,
@@ -2164,6 +2690,12 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrained() {
 	for _, hostInfo := range hostInfos {
 		hostnames = append(hostnames, hostInfo.GetHostname())
 	}
+	suite.maintenanceQueue.EXPECT().MarkProcessed(hostnames)
+	suite.eventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: hostnames,
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
 	resp, err := suite.handler.MarkHostsDrained(
 		context.Background(),
 		&hostsvc.MarkHostsDrainedRequest{
@@ -2195,8 +2727,9 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrained() {
 			},
 		})
 
+	suite.approvalMap.EXPECT().Get("host1").Return(nil)
 	suite.masterOperatorClient.EXPECT().
-		StartMaintenance(gomock.Any()).
+		StartMaintenance(gomock.Any(), gomock.Any()).
 		Return(fmt.Errorf("fake StartMaintenance error"))
 	resp, err = suite.handler.MarkHostsDrained(
 		context.Background(),
@@ -2207,6 +2740,197 @@ func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrained() {
 	suite.Nil(resp.GetMarkedHosts())
 }
 
+// TestServiceHandlerMarkHostsDrainedApproval tests that hosts whose
+// maintenance waits for approval are kept drained, and that approved
+// hosts are put down
+func (suite *HostMgrHandlerTestSuite) TestServiceHandlerMarkHostsDrainedApproval() {
+	defer suite.ctrl.Finish()
+
+	hostInfos := []*hpb.HostInfo{
+		{
+			Hostname: "host1",
+			Ip:       "0.0.0.1",
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		},
+		{
+			Hostname: "host2",
+			Ip:       "0.0.0.2",
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		},
+	}
+	hostnames := []string{"host1", "host2"}
+
+	// Test hosts waiting for approval
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(hostInfos)
+	suite.approvalMap.EXPECT().Get("host1").
+		Return(&hpb.MaintenanceApproval{Hostname: "host1"})
+	suite.approvalMap.EXPECT().MarkDrained(gomock.Any(), "host1").
+		Return(true, nil)
+	suite.approvalMap.EXPECT().Get("host2").
+		Return(&hpb.MaintenanceApproval{
+			Hostname:    "host2",
+			DrainedTime: "2019-01-01T00:00:00Z",
+		})
+	suite.approvalMap.EXPECT().MarkDrained(gomock.Any(), "host2").
+		Return(false, nil)
+	suite.maintenanceQueue.EXPECT().MarkProcessed(hostnames)
+	suite.eventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host1"},
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DRAINED,
+	})
+	resp, err := suite.handler.MarkHostsDrained(
+		context.Background(),
+		&hostsvc.MarkHostsDrainedRequest{
+			Hostnames: hostnames,
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetMarkedHosts())
+
+	// Test approved hosts
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(hostInfos)
+	suite.approvalMap.EXPECT().Get("host1").
+		Return(&hpb.MaintenanceApproval{
+			Hostname:    "host1",
+			DrainedTime: "2019-01-01T00:00:00Z",
+			Approver:    "bob",
+		})
+	suite.approvalMap.EXPECT().Get("host2").
+		Return(&hpb.MaintenanceApproval{
+			Hostname: "host2",
+			Approver: "bob",
+		})
+	suite.masterOperatorClient.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+	for _, hostname := range hostnames {
+		suite.maintenanceHostInfoMap.EXPECT().UpdateHostState(
+			hostname,
+			hpb.HostState_HOST_STATE_DRAINING,
+			hpb.HostState_HOST_STATE_DOWN)
+	}
+	suite.maintenanceQueue.EXPECT().MarkProcessed([]string{"host1"})
+	suite.eventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host1"},
+		From:      hpb.HostState_HOST_STATE_DRAINED,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+	suite.maintenanceQueue.EXPECT().MarkProcessed([]string{"host2"})
+	suite.eventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host2"},
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+	suite.approvalMap.EXPECT().Remove(gomock.Any(), hostnames).Return(nil)
+	resp, err = suite.handler.MarkHostsDrained(
+		context.Background(),
+		&hostsvc.MarkHostsDrainedRequest{
+			Hostnames: hostnames,
+		})
+	suite.NoError(err)
+	suite.ElementsMatch(hostnames, resp.GetMarkedHosts())
+
+	// Test MarkDrained error
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(hostInfos[:1])
+	suite.approvalMap.EXPECT().Get("host1").
+		Return(&hpb.MaintenanceApproval{Hostname: "host1"})
+	suite.approvalMap.EXPECT().MarkDrained(gomock.Any(), "host1").
+		Return(false, fmt.Errorf("fake MarkDrained error"))
+	suite.maintenanceQueue.EXPECT().MarkProcessed([]string{"host1"})
+	resp, err = suite.handler.MarkHostsDrained(
+		context.Background(),
+		&hostsvc.MarkHostsDrainedRequest{
+			Hostnames: []string{"host1"},
+		})
+	suite.Error(err)
+	suite.Empty(resp.GetMarkedHosts())
+}
+
+// TestServiceHandlerCordonHosts tests cordoning and uncordoning hosts
+func (suite *HostMgrHandlerTestSuite) TestServiceHandlerCordonHosts() {
+	defer suite.ctrl.Finish()
+
+	hostnames := []string{"host1", "host2"}
+
+	// Test no hostnames
+	cordonResp, err := suite.handler.CordonHosts(
+		context.Background(),
+		&hostsvc.CordonHostsRequest{})
+	suite.NoError(err)
+	suite.NotNil(cordonResp.GetError().GetInvalidArgument())
+
+	uncordonResp, err := suite.handler.UncordonHosts(
+		context.Background(),
+		&hostsvc.UncordonHostsRequest{})
+	suite.NoError(err)
+	suite.NotNil(uncordonResp.GetError().GetInvalidArgument())
+
+	// Test cordon and uncordon
+	suite.cordonMap.EXPECT().
+		Cordon(gomock.Any(), hostnames, "bad disk").
+		Return(nil)
+	cordonResp, err = suite.handler.CordonHosts(
+		context.Background(),
+		&hostsvc.CordonHostsRequest{
+			Hostnames: hostnames,
+			Reason:    "bad disk",
+		})
+	suite.NoError(err)
+	suite.Nil(cordonResp.GetError())
+
+	suite.cordonMap.EXPECT().
+		Uncordon(gomock.Any(), hostnames).
+		Return(nil)
+	uncordonResp, err = suite.handler.UncordonHosts(
+		context.Background(),
+		&hostsvc.UncordonHostsRequest{
+			Hostnames: hostnames,
+		})
+	suite.NoError(err)
+	suite.Nil(uncordonResp.GetError())
+
+	// Test storage errors
+	suite.cordonMap.EXPECT().
+		Cordon(gomock.Any(), hostnames, "").
+		Return(fmt.Errorf("fake Cordon error"))
+	_, err = suite.handler.CordonHosts(
+		context.Background(),
+		&hostsvc.CordonHostsRequest{
+			Hostnames: hostnames,
+		})
+	suite.Error(err)
+
+	suite.cordonMap.EXPECT().
+		Uncordon(gomock.Any(), hostnames).
+		Return(fmt.Errorf("fake Uncordon error"))
+	_, err = suite.handler.UncordonHosts(
+		context.Background(),
+		&hostsvc.UncordonHostsRequest{
+			Hostnames: hostnames,
+		})
+	suite.Error(err)
+}
+
+// TestServiceHandlerGetCordonedHosts tests getting the cordoned hosts
+func (suite *HostMgrHandlerTestSuite) TestServiceHandlerGetCordonedHosts() {
+	defer suite.ctrl.Finish()
+
+	hosts := map[string]string{"host1": "bad disk"}
+	suite.cordonMap.EXPECT().GetCordonedHosts().Return(hosts)
+	resp, err := suite.handler.GetCordonedHosts(
+		context.Background(),
+		&hostsvc.GetCordonedHostsRequest{})
+	suite.NoError(err)
+	suite.Equal(hosts, resp.GetHosts())
+}
+
 func getAcquireHostOffersRequest() *hostsvc.AcquireHostOffersRequest {
 	return &hostsvc.AcquireHostOffersRequest{
 		Filter: &hostsvc.HostFilter{
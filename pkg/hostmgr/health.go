@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/health"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/mesos"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/transport/mhttp"
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+)
+
+const (
+	// _probeHostname is the host looked up to verify storage reachability.
+	_probeHostname = "peloton-readiness-probe"
+
+	// _notLeaderDetail is the detail of checks skipped by followers,
+	// which are neither connected to Mesos master nor load the agent map.
+	_notLeaderDetail = "not leader"
+
+	// _agentMapStalenessFactor is the number of agent map refresh
+	// intervals after which the agent map is stale by default.
+	_agentMapStalenessFactor = 3
+)
+
+// readinessChecker verifies the components host manager depends on.
+type readinessChecker struct {
+	config config.ReadinessConfig
+
+	candidate        leader.Candidate
+	detector         mesos.MasterDetector
+	mesosInbound     mhttp.Inbound
+	maintenanceQueue queue.MaintenanceQueue
+	hostCordonOps    ormobjects.HostCordonOps
+
+	agentMap func() *host.AgentMap
+	now      func() time.Time
+}
+
+// AddReadinessChecks adds the checks of Mesos master connectivity, agent
+// map freshness, maintenance queue health and storage reachability to the
+// readiness probe of prober.
+func AddReadinessChecks(
+	prober *health.Prober,
+	cfg config.ReadinessConfig,
+	hostmapRefreshInterval time.Duration,
+	candidate leader.Candidate,
+	detector mesos.MasterDetector,
+	mesosInbound mhttp.Inbound,
+	maintenanceQueue queue.MaintenanceQueue,
+	hostCordonOps ormobjects.HostCordonOps) {
+	if cfg.AgentMapMaxStaleness <= 0 {
+		cfg.AgentMapMaxStaleness =
+			_agentMapStalenessFactor * hostmapRefreshInterval
+	}
+
+	c := &readinessChecker{
+		config:           cfg,
+		candidate:        candidate,
+		detector:         detector,
+		mesosInbound:     mesosInbound,
+		maintenanceQueue: maintenanceQueue,
+		hostCordonOps:    hostCordonOps,
+		agentMap:         host.GetAgentMap,
+		now:              time.Now,
+	}
+	prober.AddReadinessCheck("mesos_master", c.checkMesosMaster)
+	prober.AddReadinessCheck("agent_map", c.checkAgentMap)
+	prober.AddReadinessCheck("maintenance_queue", c.checkMaintenanceQueue)
+	prober.AddReadinessCheck("storage", c.checkStorage)
+}
+
+// checkMesosMaster verifies that a Mesos master is detected, and that the
+// leader is connected to it.
+func (c *readinessChecker) checkMesosMaster(
+	ctx context.Context) (string, error) {
+	hostPort := c.detector.HostPort()
+	if hostPort == "" {
+		return "", fmt.Errorf("no Mesos master detected")
+	}
+	if !c.candidate.IsLeader() {
+		return fmt.Sprintf("master %s, %s", hostPort, _notLeaderDetail), nil
+	}
+	if !c.mesosInbound.IsRunning() {
+		return "", fmt.Errorf("not connected to Mesos master %s", hostPort)
+	}
+	return fmt.Sprintf("connected to master %s", hostPort), nil
+}
+
+// checkAgentMap verifies that the leader has recently reloaded the agent
+// map from Mesos master.
+func (c *readinessChecker) checkAgentMap(
+	ctx context.Context) (string, error) {
+	if !c.candidate.IsLeader() {
+		return _notLeaderDetail, nil
+	}
+	m := c.agentMap()
+	if m == nil {
+		return "", fmt.Errorf("agent map not loaded")
+	}
+	age := c.now().Sub(m.RefreshTime)
+	if age > c.config.AgentMapMaxStaleness {
+		return "", fmt.Errorf(
+			"agent map last refreshed %s ago, max staleness %s",
+			age, c.config.AgentMapMaxStaleness)
+	}
+	return fmt.Sprintf(
+		"%d agents, refreshed %s ago", len(m.RegisteredAgents), age), nil
+}
+
+// checkMaintenanceQueue reports the maintenance queue, and fails if too
+// many hosts are dead-lettered.
+func (c *readinessChecker) checkMaintenanceQueue(
+	ctx context.Context) (string, error) {
+	deadLetters := len(c.maintenanceQueue.DeadLetters())
+	detail := fmt.Sprintf(
+		"%d queued, %d dead-lettered",
+		c.maintenanceQueue.Length(), deadLetters)
+	if c.config.MaxDeadLetters > 0 && deadLetters > c.config.MaxDeadLetters {
+		return detail, fmt.Errorf(
+			"%d dead-lettered hosts exceed max %d",
+			deadLetters, c.config.MaxDeadLetters)
+	}
+	return detail, nil
+}
+
+// checkStorage verifies that storage is reachable by looking up the cordon
+// of a host.
+func (c *readinessChecker) checkStorage(ctx context.Context) (string, error) {
+	if _, _, err := c.hostCordonOps.Get(ctx, _probeHostname); err != nil {
+		return "", err
+	}
+	return "", nil
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/common/health"
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	hm_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+	mhttp_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/transport/mhttp/mocks"
+	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReadinessTestSuite struct {
+	suite.Suite
+
+	ctrl             *gomock.Controller
+	candidate        *leadermocks.MockCandidate
+	detector         *hm_mocks.MockMasterDetector
+	mesosInbound     *mhttp_mocks.MockInbound
+	maintenanceQueue *qm.MockMaintenanceQueue
+	hostCordonOps    *objectmocks.MockHostCordonOps
+
+	now      time.Time
+	agentMap *host.AgentMap
+	checker  *readinessChecker
+}
+
+func (suite *ReadinessTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.candidate = leadermocks.NewMockCandidate(suite.ctrl)
+	suite.detector = hm_mocks.NewMockMasterDetector(suite.ctrl)
+	suite.mesosInbound = mhttp_mocks.NewMockInbound(suite.ctrl)
+	suite.maintenanceQueue = qm.NewMockMaintenanceQueue(suite.ctrl)
+	suite.hostCordonOps = objectmocks.NewMockHostCordonOps(suite.ctrl)
+
+	suite.now = time.Now()
+	suite.agentMap = &host.AgentMap{
+		RegisteredAgents: map[string]*mesos_master.Response_GetAgents_Agent{
+			"host1": {},
+		},
+		RefreshTime: suite.now.Add(-10 * time.Second),
+	}
+	suite.checker = &readinessChecker{
+		config: config.ReadinessConfig{
+			AgentMapMaxStaleness: 30 * time.Second,
+			MaxDeadLetters:       1,
+		},
+		candidate:        suite.candidate,
+		detector:         suite.detector,
+		mesosInbound:     suite.mesosInbound,
+		maintenanceQueue: suite.maintenanceQueue,
+		hostCordonOps:    suite.hostCordonOps,
+		agentMap:         func() *host.AgentMap { return suite.agentMap },
+		now:              func() time.Time { return suite.now },
+	}
+}
+
+func (suite *ReadinessTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestReadiness(t *testing.T) {
+	suite.Run(t, new(ReadinessTestSuite))
+}
+
+// TestCheckMesosMaster tests the Mesos master check of leader and followers
+func (suite *ReadinessTestSuite) TestCheckMesosMaster() {
+	suite.detector.EXPECT().HostPort().Return("").Times(1)
+	_, err := suite.checker.checkMesosMaster(context.Background())
+	suite.Error(err)
+
+	suite.detector.EXPECT().HostPort().Return("master:5050").AnyTimes()
+	suite.candidate.EXPECT().IsLeader().Return(false).Times(1)
+	detail, err := suite.checker.checkMesosMaster(context.Background())
+	suite.NoError(err)
+	suite.Contains(detail, _notLeaderDetail)
+
+	suite.candidate.EXPECT().IsLeader().Return(true).Times(2)
+	suite.mesosInbound.EXPECT().IsRunning().Return(false).Times(1)
+	_, err = suite.checker.checkMesosMaster(context.Background())
+	suite.Error(err)
+
+	suite.mesosInbound.EXPECT().IsRunning().Return(true).Times(1)
+	detail, err = suite.checker.checkMesosMaster(context.Background())
+	suite.NoError(err)
+	suite.Contains(detail, "master:5050")
+}
+
+// TestCheckAgentMap tests the agent map freshness check
+func (suite *ReadinessTestSuite) TestCheckAgentMap() {
+	suite.candidate.EXPECT().IsLeader().Return(false).Times(1)
+	detail, err := suite.checker.checkAgentMap(context.Background())
+	suite.NoError(err)
+	suite.Equal(_notLeaderDetail, detail)
+
+	suite.candidate.EXPECT().IsLeader().Return(true).AnyTimes()
+	detail, err = suite.checker.checkAgentMap(context.Background())
+	suite.NoError(err)
+	suite.Contains(detail, "1 agents")
+
+	suite.agentMap.RefreshTime = suite.now.Add(-time.Minute)
+	_, err = suite.checker.checkAgentMap(context.Background())
+	suite.Error(err)
+
+	suite.agentMap = nil
+	_, err = suite.checker.checkAgentMap(context.Background())
+	suite.Error(err)
+}
+
+// TestCheckMaintenanceQueue tests that the maintenance queue check fails
+// once the dead-letter queue exceeds its max
+func (suite *ReadinessTestSuite) TestCheckMaintenanceQueue() {
+	suite.maintenanceQueue.EXPECT().Length().Return(2).AnyTimes()
+	suite.maintenanceQueue.EXPECT().
+		DeadLetters().Return([]string{"host1"}).Times(1)
+	detail, err := suite.checker.checkMaintenanceQueue(context.Background())
+	suite.NoError(err)
+	suite.Equal("2 queued, 1 dead-lettered", detail)
+
+	suite.maintenanceQueue.EXPECT().
+		DeadLetters().Return([]string{"host1", "host2"}).Times(2)
+	_, err = suite.checker.checkMaintenanceQueue(context.Background())
+	suite.Error(err)
+
+	suite.checker.config.MaxDeadLetters = 0
+	_, err = suite.checker.checkMaintenanceQueue(context.Background())
+	suite.NoError(err)
+}
+
+// TestCheckStorage tests the storage reachability check
+func (suite *ReadinessTestSuite) TestCheckStorage() {
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), _probeHostname).
+		Return("", false, nil)
+	_, err := suite.checker.checkStorage(context.Background())
+	suite.NoError(err)
+
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), _probeHostname).
+		Return("", false, errors.New("no hosts available"))
+	_, err = suite.checker.checkStorage(context.Background())
+	suite.Error(err)
+}
+
+// TestAddReadinessChecks tests that all components are added to the
+// readiness probe
+func (suite *ReadinessTestSuite) TestAddReadinessChecks() {
+	prober := health.NewProber(time.Second)
+	AddReadinessChecks(
+		prober,
+		config.ReadinessConfig{},
+		10*time.Second,
+		suite.candidate,
+		suite.detector,
+		suite.mesosInbound,
+		suite.maintenanceQueue,
+		suite.hostCordonOps,
+	)
+
+	suite.detector.EXPECT().HostPort().Return("master:5050")
+	suite.candidate.EXPECT().IsLeader().Return(false).AnyTimes()
+	suite.maintenanceQueue.EXPECT().Length().Return(0)
+	suite.maintenanceQueue.EXPECT().DeadLetters().Return(nil)
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), _probeHostname).
+		Return("", false, nil)
+
+	report := prober.Readiness(context.Background())
+	suite.True(report.Healthy())
+	suite.Len(report.Components, 4)
+	for _, name := range []string{
+		"mesos_master", "agent_map", "maintenance_queue", "storage"} {
+		suite.Equal(health.StatusHealthy, report.Components[name].Status)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/concurrency"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Timeout of the storage calls made to persist approvals.
+	_approvalStorageTimeout = 10 * time.Second
+
+	// Number of hosts whose approvals are recovered concurrently.
+	_approvalRecoverWorkers = 16
+)
+
+// IsApprovalPending returns true if the approval is required and not
+// given yet.
+func IsApprovalPending(approval *hpb.MaintenanceApproval) bool {
+	return approval != nil && approval.GetApprover() == ""
+}
+
+// ApprovalMap keeps the approvals required to put hosts drained with
+// require_approval into maintenance. Such hosts stay DRAINED once their
+// tasks are drained, until their maintenance is approved.
+type ApprovalMap interface {
+	// Require persists that maintenance of the given hosts, started by
+	// requester, requires approval.
+	Require(ctx context.Context, hostnames []string, requester string) error
+	// Approve persists the approval of the maintenance of a host. No-op
+	// if the maintenance of the host does not require approval.
+	Approve(ctx context.Context, hostname string, approver string) error
+	// MarkDrained records that a host requiring approval was drained,
+	// and returns whether it was not drained before.
+	MarkDrained(ctx context.Context, hostname string) (bool, error)
+	// Remove removes the approvals of the given hosts.
+	Remove(ctx context.Context, hostnames []string) error
+	// Get returns the approval of a host, nil if its maintenance does
+	// not require one.
+	Get(hostname string) *hpb.MaintenanceApproval
+	// GetApprovals returns the approvals, sorted by hostname.
+	GetApprovals() []*hpb.MaintenanceApproval
+	// Recover loads the persisted approvals of the given hosts.
+	Recover(ctx context.Context, hostnames []string) error
+}
+
+// approvalMap implements ApprovalMap
+type approvalMap struct {
+	sync.RWMutex
+	approvals              map[string]*hpb.MaintenanceApproval
+	maintenanceApprovalOps ormobjects.MaintenanceApprovalOps
+	pendingApprovals       tally.Gauge
+	recoverMetrics         *concurrency.PoolMetrics
+}
+
+// NewApprovalMap returns a new ApprovalMap persisting approvals with the
+// given MaintenanceApprovalOps.
+func NewApprovalMap(
+	maintenanceApprovalOps ormobjects.MaintenanceApprovalOps,
+	scope tally.Scope) ApprovalMap {
+	return &approvalMap{
+		approvals:              make(map[string]*hpb.MaintenanceApproval),
+		maintenanceApprovalOps: maintenanceApprovalOps,
+		pendingApprovals:       scope.Gauge("pending_approvals"),
+		recoverMetrics: concurrency.NewPoolMetrics(
+			scope.SubScope("approval_recover")),
+	}
+}
+
+// Require persists that maintenance of the given hosts requires approval.
+func (a *approvalMap) Require(
+	ctx context.Context,
+	hostnames []string,
+	requester string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, hostname := range hostnames {
+		if err := a.store(ctx, &hpb.MaintenanceApproval{
+			Hostname:    hostname,
+			Requester:   requester,
+			RequestTime: now,
+		}); err != nil {
+			return err
+		}
+	}
+	log.WithFields(log.Fields{
+		"hostnames": hostnames,
+		"requester": requester,
+	}).Info("Maintenance approval required")
+	return nil
+}
+
+// Approve persists the approval of the maintenance of a host.
+func (a *approvalMap) Approve(
+	ctx context.Context,
+	hostname string,
+	approver string) error {
+	approval := a.Get(hostname)
+	if approval == nil {
+		return nil
+	}
+	approval.Approver = approver
+	approval.ApproveTime = time.Now().UTC().Format(time.RFC3339)
+	if err := a.store(ctx, approval); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"hostname": hostname,
+		"approver": approver,
+	}).Info("Maintenance approved")
+	return nil
+}
+
+// MarkDrained records that a host requiring approval was drained.
+func (a *approvalMap) MarkDrained(
+	ctx context.Context,
+	hostname string) (bool, error) {
+	approval := a.Get(hostname)
+	if approval == nil || approval.GetDrainedTime() != "" {
+		return false, nil
+	}
+	approval.DrainedTime = time.Now().UTC().Format(time.RFC3339)
+	if err := a.store(ctx, approval); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove removes the approvals of the given hosts.
+func (a *approvalMap) Remove(ctx context.Context, hostnames []string) error {
+	for _, hostname := range hostnames {
+		if a.Get(hostname) == nil {
+			continue
+		}
+		storageCtx, cancel := context.WithTimeout(ctx, _approvalStorageTimeout)
+		err := a.maintenanceApprovalOps.Delete(storageCtx, hostname)
+		cancel()
+		if err != nil {
+			return err
+		}
+		a.Lock()
+		delete(a.approvals, hostname)
+		a.reportLocked()
+		a.Unlock()
+	}
+	return nil
+}
+
+// Get returns a copy of the approval of a host.
+func (a *approvalMap) Get(hostname string) *hpb.MaintenanceApproval {
+	a.RLock()
+	defer a.RUnlock()
+
+	approval, ok := a.approvals[hostname]
+	if !ok {
+		return nil
+	}
+	return proto.Clone(approval).(*hpb.MaintenanceApproval)
+}
+
+// GetApprovals returns the approvals, sorted by hostname.
+func (a *approvalMap) GetApprovals() []*hpb.MaintenanceApproval {
+	a.RLock()
+	defer a.RUnlock()
+
+	result := make([]*hpb.MaintenanceApproval, 0, len(a.approvals))
+	for _, approval := range a.approvals {
+		result = append(result,
+			proto.Clone(approval).(*hpb.MaintenanceApproval))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetHostname() < result[j].GetHostname()
+	})
+	return result
+}
+
+// Recover loads the persisted approvals of the given hosts.
+func (a *approvalMap) Recover(
+	ctx context.Context,
+	hostnames []string) error {
+	return concurrency.ForEach(
+		ctx,
+		len(hostnames),
+		concurrency.WorkerPoolOptions{
+			MaxWorkers:  _approvalRecoverWorkers,
+			StopOnError: true,
+		},
+		a.recoverMetrics,
+		func(ctx context.Context, i int) error {
+			hostname := hostnames[i]
+			storageCtx, cancel := context.WithTimeout(ctx, _approvalStorageTimeout)
+			approval, err := a.maintenanceApprovalOps.Get(storageCtx, hostname)
+			cancel()
+			if err != nil || approval == nil {
+				return err
+			}
+
+			a.Lock()
+			defer a.Unlock()
+			a.approvals[hostname] = approval
+			a.reportLocked()
+			return nil
+		})
+}
+
+// store persists and applies the approval of a host.
+func (a *approvalMap) store(
+	ctx context.Context,
+	approval *hpb.MaintenanceApproval) error {
+	storageCtx, cancel := context.WithTimeout(ctx, _approvalStorageTimeout)
+	defer cancel()
+	if err := a.maintenanceApprovalOps.Create(storageCtx, approval); err != nil {
+		return err
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	a.approvals[approval.GetHostname()] = approval
+	a.reportLocked()
+	return nil
+}
+
+// reportLocked reports the number of pending approvals. Must be called
+// with the lock held.
+func (a *approvalMap) reportLocked() {
+	pending := 0
+	for _, approval := range a.approvals {
+		if IsApprovalPending(approval) {
+			pending++
+		}
+	}
+	a.pendingApprovals.Update(float64(pending))
+}
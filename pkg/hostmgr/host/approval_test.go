@@ -0,0 +1,160 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type ApprovalMapTestSuite struct {
+	suite.Suite
+
+	ctx                    context.Context
+	ctrl                   *gomock.Controller
+	maintenanceApprovalOps *objectmocks.MockMaintenanceApprovalOps
+	approvalMap            ApprovalMap
+}
+
+func (suite *ApprovalMapTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.maintenanceApprovalOps = objectmocks.NewMockMaintenanceApprovalOps(suite.ctrl)
+	suite.approvalMap = NewApprovalMap(
+		suite.maintenanceApprovalOps,
+		tally.NoopScope)
+}
+
+func (suite *ApprovalMapTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestApprovalMapTestSuite(t *testing.T) {
+	suite.Run(t, new(ApprovalMapTestSuite))
+}
+
+// TestApprovalLifecycle tests requiring, draining, approving and removing
+// the approval of a host
+func (suite *ApprovalMapTestSuite) TestApprovalLifecycle() {
+	suite.maintenanceApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(4)
+
+	suite.NoError(suite.approvalMap.Require(
+		suite.ctx, []string{"host2", "host1"}, "alice"))
+	approvals := suite.approvalMap.GetApprovals()
+	suite.Len(approvals, 2)
+	suite.Equal("host1", approvals[0].GetHostname())
+	suite.Equal("alice", approvals[0].GetRequester())
+	suite.NotEmpty(approvals[0].GetRequestTime())
+	suite.True(IsApprovalPending(approvals[0]))
+
+	drained, err := suite.approvalMap.MarkDrained(suite.ctx, "host1")
+	suite.NoError(err)
+	suite.True(drained)
+	drained, err = suite.approvalMap.MarkDrained(suite.ctx, "host1")
+	suite.NoError(err)
+	suite.False(drained)
+	suite.NotEmpty(suite.approvalMap.Get("host1").GetDrainedTime())
+
+	suite.NoError(suite.approvalMap.Approve(suite.ctx, "host1", "bob"))
+	approval := suite.approvalMap.Get("host1")
+	suite.Equal("bob", approval.GetApprover())
+	suite.NotEmpty(approval.GetApproveTime())
+	suite.False(IsApprovalPending(approval))
+
+	// Copies are returned
+	approval.Approver = ""
+	suite.False(IsApprovalPending(suite.approvalMap.Get("host1")))
+
+	// Hosts without approval are ignored
+	suite.NoError(suite.approvalMap.Approve(suite.ctx, "host3", "bob"))
+	drained, err = suite.approvalMap.MarkDrained(suite.ctx, "host3")
+	suite.NoError(err)
+	suite.False(drained)
+	suite.Nil(suite.approvalMap.Get("host3"))
+	suite.False(IsApprovalPending(nil))
+
+	suite.maintenanceApprovalOps.EXPECT().
+		Delete(gomock.Any(), "host1").
+		Return(nil)
+	suite.NoError(suite.approvalMap.Remove(
+		suite.ctx, []string{"host1", "host3"}))
+	suite.Nil(suite.approvalMap.Get("host1"))
+	suite.Len(suite.approvalMap.GetApprovals(), 1)
+}
+
+// TestApprovalStorageErrors tests that approvals are not applied if
+// they fail to be persisted
+func (suite *ApprovalMapTestSuite) TestApprovalStorageErrors() {
+	suite.maintenanceApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	suite.Error(suite.approvalMap.Require(
+		suite.ctx, []string{"host1"}, "alice"))
+	suite.Nil(suite.approvalMap.Get("host1"))
+
+	suite.maintenanceApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(suite.approvalMap.Require(
+		suite.ctx, []string{"host1"}, "alice"))
+
+	suite.maintenanceApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	suite.Error(suite.approvalMap.Approve(suite.ctx, "host1", "bob"))
+	suite.True(IsApprovalPending(suite.approvalMap.Get("host1")))
+
+	suite.maintenanceApprovalOps.EXPECT().
+		Delete(gomock.Any(), "host1").
+		Return(errors.New("delete failed"))
+	suite.Error(suite.approvalMap.Remove(suite.ctx, []string{"host1"}))
+	suite.NotNil(suite.approvalMap.Get("host1"))
+}
+
+// TestRecover tests loading the persisted approvals of hosts
+func (suite *ApprovalMapTestSuite) TestRecover() {
+	approval := &hpb.MaintenanceApproval{
+		Hostname:  "host1",
+		Requester: "alice",
+	}
+	suite.maintenanceApprovalOps.EXPECT().
+		Get(gomock.Any(), "host1").
+		Return(approval, nil)
+	suite.maintenanceApprovalOps.EXPECT().
+		Get(gomock.Any(), "host2").
+		Return(nil, nil)
+	suite.NoError(suite.approvalMap.Recover(
+		suite.ctx, []string{"host1", "host2"}))
+	suite.Equal(
+		[]*hpb.MaintenanceApproval{approval},
+		suite.approvalMap.GetApprovals())
+
+	suite.maintenanceApprovalOps.EXPECT().
+		Get(gomock.Any(), "host3").
+		Return(nil, errors.New("get failed"))
+	suite.Error(suite.approvalMap.Recover(suite.ctx, []string{"host3"}))
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/common/concurrency"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Timeout of the storage calls made to persist assignments.
+	_assignmentStorageTimeout = 10 * time.Second
+
+	// Number of hosts whose assignments are recovered concurrently.
+	_assignmentRecoverWorkers = 16
+)
+
+// Atomic pointer to the map from hostname to the assignment of the host,
+// read lock free by the placement path.
+var hostAssignments atomic.Value
+
+// GetHostAssignment returns the host pool and labels assigned to a host,
+// nil if the host has none.
+func GetHostAssignment(hostname string) *hpb.HostAssignment {
+	m, _ := hostAssignments.Load().(map[string]*hpb.HostAssignment)
+	return m[hostname]
+}
+
+// GetAssignedHostPool returns the host pool assigned to a host, or the
+// one of the attributes of its agent if none is assigned.
+func GetAssignedHostPool(
+	hostname string,
+	attributes []*mesos.Attribute) string {
+	if pool := GetHostAssignment(hostname).GetPool(); pool != "" {
+		return pool
+	}
+	return GetHostPool(attributes)
+}
+
+// GetAssignedLabels returns the labels assigned to a host, with the
+// given labels whose keys are not assigned.
+func GetAssignedLabels(
+	hostname string,
+	labels []*peloton.Label) []*peloton.Label {
+	assigned := GetHostAssignment(hostname).GetLabels()
+	if len(assigned) == 0 {
+		return labels
+	}
+
+	keys := make(map[string]bool)
+	for _, label := range assigned {
+		keys[label.GetKey()] = true
+	}
+	result := append([]*peloton.Label{}, assigned...)
+	for _, label := range labels {
+		if !keys[label.GetKey()] {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+// AssignmentMap keeps the host pool and labels assigned to hosts by
+// operators, e.g. imported from an external inventory.
+type AssignmentMap interface {
+	// Assign persists and applies the given assignments. An assignment
+	// with neither pool nor labels removes the one of its host.
+	Assign(ctx context.Context, assignments []*hpb.HostAssignment) error
+	// GetAssignments returns the assignments, sorted by hostname.
+	GetAssignments() []*hpb.HostAssignment
+	// Recover loads the persisted assignments of the given hosts.
+	Recover(ctx context.Context, hostnames []string) error
+}
+
+// assignmentMap implements AssignmentMap
+type assignmentMap struct {
+	sync.Mutex
+	hostAssignmentOps ormobjects.HostAssignmentOps
+	assignedHosts     tally.Gauge
+	recoverMetrics    *concurrency.PoolMetrics
+}
+
+// NewAssignmentMap returns a new AssignmentMap persisting assignments
+// with the given HostAssignmentOps.
+func NewAssignmentMap(
+	hostAssignmentOps ormobjects.HostAssignmentOps,
+	scope tally.Scope) AssignmentMap {
+	hostAssignments.Store(map[string]*hpb.HostAssignment{})
+	return &assignmentMap{
+		hostAssignmentOps: hostAssignmentOps,
+		assignedHosts:     scope.Gauge("assigned_hosts"),
+		recoverMetrics: concurrency.NewPoolMetrics(
+			scope.SubScope("assignment_recover")),
+	}
+}
+
+// Assign persists and applies the given assignments.
+func (a *assignmentMap) Assign(
+	ctx context.Context,
+	assignments []*hpb.HostAssignment) error {
+	a.Lock()
+	defer a.Unlock()
+
+	for _, assignment := range assignments {
+		hostname := assignment.GetHostname()
+		remove := assignment.GetPool() == "" &&
+			len(assignment.GetLabels()) == 0
+
+		storageCtx, cancel := context.WithTimeout(
+			ctx,
+			_assignmentStorageTimeout)
+		var err error
+		if remove {
+			err = a.hostAssignmentOps.Delete(storageCtx, hostname)
+		} else {
+			err = a.hostAssignmentOps.Create(storageCtx, assignment)
+		}
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if remove {
+			a.update(func(m map[string]*hpb.HostAssignment) {
+				delete(m, hostname)
+			})
+			log.WithField("hostname", hostname).
+				Info("Host assignment removed")
+			continue
+		}
+		a.update(func(m map[string]*hpb.HostAssignment) {
+			m[hostname] = assignment
+		})
+		log.WithField("assignment", assignment).Info("Host assigned")
+	}
+	return nil
+}
+
+// GetAssignments returns the assignments, sorted by hostname.
+func (a *assignmentMap) GetAssignments() []*hpb.HostAssignment {
+	m, _ := hostAssignments.Load().(map[string]*hpb.HostAssignment)
+	result := make([]*hpb.HostAssignment, 0, len(m))
+	for _, assignment := range m {
+		result = append(result, assignment)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetHostname() < result[j].GetHostname()
+	})
+	return result
+}
+
+// Recover loads the persisted assignments of the given hosts.
+func (a *assignmentMap) Recover(
+	ctx context.Context,
+	hostnames []string) error {
+	return concurrency.ForEach(
+		ctx,
+		len(hostnames),
+		concurrency.WorkerPoolOptions{
+			MaxWorkers:  _assignmentRecoverWorkers,
+			StopOnError: true,
+		},
+		a.recoverMetrics,
+		func(ctx context.Context, i int) error {
+			hostname := hostnames[i]
+			storageCtx, cancel := context.WithTimeout(
+				ctx,
+				_assignmentStorageTimeout)
+			assignment, err := a.hostAssignmentOps.Get(storageCtx, hostname)
+			cancel()
+			if err != nil || assignment == nil {
+				return err
+			}
+
+			a.Lock()
+			defer a.Unlock()
+			a.update(func(m map[string]*hpb.HostAssignment) {
+				m[hostname] = assignment
+			})
+			return nil
+		})
+}
+
+// update applies fn to a copy of the assignments and stores the copy.
+// Must be called with the lock held.
+func (a *assignmentMap) update(fn func(m map[string]*hpb.HostAssignment)) {
+	old, _ := hostAssignments.Load().(map[string]*hpb.HostAssignment)
+	m := make(map[string]*hpb.HostAssignment, len(old)+1)
+	for hostname, assignment := range old {
+		m[hostname] = assignment
+	}
+	fn(m)
+	hostAssignments.Store(m)
+	a.assignedHosts.Update(float64(len(m)))
+}
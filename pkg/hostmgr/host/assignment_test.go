@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type AssignmentMapTestSuite struct {
+	suite.Suite
+
+	ctrl              *gomock.Controller
+	hostAssignmentOps *objectmocks.MockHostAssignmentOps
+	assignmentMap     AssignmentMap
+}
+
+func (suite *AssignmentMapTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.hostAssignmentOps = objectmocks.NewMockHostAssignmentOps(suite.ctrl)
+	suite.assignmentMap = NewAssignmentMap(
+		suite.hostAssignmentOps,
+		tally.NoopScope)
+}
+
+func (suite *AssignmentMapTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestAssignmentMapTestSuite(t *testing.T) {
+	suite.Run(t, new(AssignmentMapTestSuite))
+}
+
+// TestAssign tests assigning pools and labels to hosts, and removing
+// the assignments
+func (suite *AssignmentMapTestSuite) TestAssign() {
+	ctx := context.Background()
+	host1 := &hpb.HostAssignment{
+		Hostname: "host1",
+		Pool:     "batch",
+		Labels:   []*peloton.Label{{Key: "rack", Value: "r1"}},
+	}
+	host2 := &hpb.HostAssignment{Hostname: "host2", Pool: "stateless"}
+
+	suite.hostAssignmentOps.EXPECT().Create(gomock.Any(), host1)
+	suite.hostAssignmentOps.EXPECT().Create(gomock.Any(), host2)
+	suite.NoError(suite.assignmentMap.Assign(
+		ctx, []*hpb.HostAssignment{host2, host1}))
+	suite.Equal(host1, GetHostAssignment("host1"))
+	suite.Nil(GetHostAssignment("host3"))
+	suite.Equal([]*hpb.HostAssignment{host1, host2},
+		suite.assignmentMap.GetAssignments())
+
+	suite.hostAssignmentOps.EXPECT().Delete(gomock.Any(), "host1")
+	suite.NoError(suite.assignmentMap.Assign(
+		ctx, []*hpb.HostAssignment{{Hostname: "host1"}}))
+	suite.Nil(GetHostAssignment("host1"))
+	suite.Equal(host2, GetHostAssignment("host2"))
+}
+
+// TestAssignStorageError tests that a host is not assigned if its
+// assignment cannot be persisted
+func (suite *AssignmentMapTestSuite) TestAssignStorageError() {
+	assignment := &hpb.HostAssignment{Hostname: "host1", Pool: "batch"}
+	suite.hostAssignmentOps.EXPECT().
+		Create(gomock.Any(), assignment).
+		Return(errors.New("create failed"))
+	suite.Error(suite.assignmentMap.Assign(
+		context.Background(), []*hpb.HostAssignment{assignment}))
+	suite.Nil(GetHostAssignment("host1"))
+}
+
+// TestRecover tests recovering the persisted assignments
+func (suite *AssignmentMapTestSuite) TestRecover() {
+	ctx := context.Background()
+	assignment := &hpb.HostAssignment{Hostname: "host1", Pool: "batch"}
+
+	suite.hostAssignmentOps.EXPECT().
+		Get(gomock.Any(), "host1").
+		Return(assignment, nil)
+	suite.hostAssignmentOps.EXPECT().
+		Get(gomock.Any(), "host2").
+		Return(nil, nil)
+	suite.NoError(suite.assignmentMap.Recover(
+		ctx, []string{"host1", "host2"}))
+	suite.Equal([]*hpb.HostAssignment{assignment},
+		suite.assignmentMap.GetAssignments())
+
+	suite.hostAssignmentOps.EXPECT().
+		Get(gomock.Any(), "host3").
+		Return(nil, errors.New("get failed"))
+	suite.Error(suite.assignmentMap.Recover(ctx, []string{"host3"}))
+}
+
+// TestAssignedPoolAndLabels tests that the assigned pool and labels
+// take precedence over the agent attributes
+func (suite *AssignmentMapTestSuite) TestAssignedPoolAndLabels() {
+	SetHostPoolAttribute("pool")
+	defer SetHostPoolAttribute("")
+
+	text := mesos.Value_TEXT
+	poolName, pool := "pool", "batch"
+	rackName, rack := "rack", "r1"
+	attributes := []*mesos.Attribute{
+		{Name: &poolName, Type: &text, Text: &mesos.Value_Text{Value: &pool}},
+		{Name: &rackName, Type: &text, Text: &mesos.Value_Text{Value: &rack}},
+	}
+	labels := []*peloton.Label{
+		{Key: "pool", Value: "batch"},
+		{Key: "rack", Value: "r1"},
+	}
+
+	suite.Equal("batch", GetAssignedHostPool("host1", attributes))
+	suite.Equal(labels, GetAssignedLabels("host1", labels))
+
+	assignment := &hpb.HostAssignment{
+		Hostname: "host1",
+		Pool:     "stateless",
+		Labels:   []*peloton.Label{{Key: "rack", Value: "r2"}},
+	}
+	suite.hostAssignmentOps.EXPECT().Create(gomock.Any(), assignment)
+	suite.NoError(suite.assignmentMap.Assign(
+		context.Background(), []*hpb.HostAssignment{assignment}))
+
+	suite.Equal("stateless", GetAssignedHostPool("host1", attributes))
+	suite.Equal([]*peloton.Label{
+		{Key: "rack", Value: "r2"},
+		{Key: "pool", Value: "batch"},
+	}, GetAssignedLabels("host1", labels))
+
+	lv := GetHostLabelValues("host1", attributes)
+	suite.Equal(map[string]uint32{"r2": 1}, lv["rack"])
+	suite.Equal(map[string]uint32{"stateless": 1}, lv[PoolLabel])
+}
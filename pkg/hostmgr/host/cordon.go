@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/concurrency"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Timeout of the storage calls made to persist cordons.
+	_cordonStorageTimeout = 10 * time.Second
+
+	// Number of hosts whose cordons are recovered concurrently.
+	_cordonRecoverWorkers = 16
+)
+
+// Atomic pointer to the map from cordoned hostname to cordon reason,
+// read lock free by the placement path.
+var cordonedHosts atomic.Value
+
+// IsCordoned returns true if the host is cordoned.
+func IsCordoned(hostname string) bool {
+	m, _ := cordonedHosts.Load().(map[string]string)
+	_, ok := m[hostname]
+	return ok
+}
+
+// CordonMap keeps the hosts cordoned by operators. Cordoned hosts keep
+// their running tasks, but placement avoids them for new tasks.
+type CordonMap interface {
+	// Cordon persists and cordons the given hosts.
+	Cordon(ctx context.Context, hostnames []string, reason string) error
+	// Uncordon removes the cordon of the given hosts.
+	Uncordon(ctx context.Context, hostnames []string) error
+	// GetCordonedHosts returns the cordoned hosts with their reason.
+	GetCordonedHosts() map[string]string
+	// Recover loads the persisted cordons of the given hosts.
+	Recover(ctx context.Context, hostnames []string) error
+}
+
+// cordonMap implements CordonMap
+type cordonMap struct {
+	sync.Mutex
+	hostCordonOps  ormobjects.HostCordonOps
+	cordonedHosts  tally.Gauge
+	recoverMetrics *concurrency.PoolMetrics
+}
+
+// NewCordonMap returns a new CordonMap persisting cordons
+// with the given HostCordonOps.
+func NewCordonMap(
+	hostCordonOps ormobjects.HostCordonOps,
+	scope tally.Scope) CordonMap {
+	cordonedHosts.Store(map[string]string{})
+	return &cordonMap{
+		hostCordonOps:  hostCordonOps,
+		cordonedHosts:  scope.Gauge("cordoned_hosts"),
+		recoverMetrics: concurrency.NewPoolMetrics(scope.SubScope("cordon_recover")),
+	}
+}
+
+// Cordon persists and cordons the given hosts.
+func (c *cordonMap) Cordon(
+	ctx context.Context,
+	hostnames []string,
+	reason string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, hostname := range hostnames {
+		storageCtx, cancel := context.WithTimeout(ctx, _cordonStorageTimeout)
+		err := c.hostCordonOps.Create(storageCtx, hostname, reason)
+		cancel()
+		if err != nil {
+			return err
+		}
+		c.update(func(m map[string]string) { m[hostname] = reason })
+		log.WithFields(log.Fields{
+			"hostname": hostname,
+			"reason":   reason,
+		}).Info("Host cordoned")
+	}
+	return nil
+}
+
+// Uncordon removes the cordon of the given hosts.
+func (c *cordonMap) Uncordon(ctx context.Context, hostnames []string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, hostname := range hostnames {
+		storageCtx, cancel := context.WithTimeout(ctx, _cordonStorageTimeout)
+		err := c.hostCordonOps.Delete(storageCtx, hostname)
+		cancel()
+		if err != nil {
+			return err
+		}
+		c.update(func(m map[string]string) { delete(m, hostname) })
+		log.WithField("hostname", hostname).Info("Host uncordoned")
+	}
+	return nil
+}
+
+// GetCordonedHosts returns the cordoned hosts with their reason.
+func (c *cordonMap) GetCordonedHosts() map[string]string {
+	m, _ := cordonedHosts.Load().(map[string]string)
+	result := make(map[string]string, len(m))
+	for hostname, reason := range m {
+		result[hostname] = reason
+	}
+	return result
+}
+
+// Recover loads the persisted cordons of the given hosts.
+func (c *cordonMap) Recover(ctx context.Context, hostnames []string) error {
+	return concurrency.ForEach(
+		ctx,
+		len(hostnames),
+		concurrency.WorkerPoolOptions{
+			MaxWorkers:  _cordonRecoverWorkers,
+			StopOnError: true,
+		},
+		c.recoverMetrics,
+		func(ctx context.Context, i int) error {
+			hostname := hostnames[i]
+			storageCtx, cancel := context.WithTimeout(ctx, _cordonStorageTimeout)
+			reason, found, err := c.hostCordonOps.Get(storageCtx, hostname)
+			cancel()
+			if err != nil || !found {
+				return err
+			}
+
+			c.Lock()
+			defer c.Unlock()
+			c.update(func(m map[string]string) { m[hostname] = reason })
+			return nil
+		})
+}
+
+// update applies fn to a copy of the cordoned hosts and stores the copy.
+// Must be called with the lock held.
+func (c *cordonMap) update(fn func(m map[string]string)) {
+	m := c.GetCordonedHosts()
+	fn(m)
+	cordonedHosts.Store(m)
+	c.cordonedHosts.Update(float64(len(m)))
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type CordonMapTestSuite struct {
+	suite.Suite
+
+	ctrl          *gomock.Controller
+	hostCordonOps *objectmocks.MockHostCordonOps
+	cordonMap     CordonMap
+}
+
+func (suite *CordonMapTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.hostCordonOps = objectmocks.NewMockHostCordonOps(suite.ctrl)
+	suite.cordonMap = NewCordonMap(suite.hostCordonOps, tally.NoopScope)
+}
+
+func (suite *CordonMapTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestCordonMapTestSuite(t *testing.T) {
+	suite.Run(t, new(CordonMapTestSuite))
+}
+
+// TestCordonUncordon tests cordoning and uncordoning hosts
+func (suite *CordonMapTestSuite) TestCordonUncordon() {
+	ctx := context.Background()
+
+	suite.hostCordonOps.EXPECT().Create(gomock.Any(), "host1", "bad disk")
+	suite.hostCordonOps.EXPECT().Create(gomock.Any(), "host2", "bad disk")
+	suite.NoError(suite.cordonMap.Cordon(
+		ctx, []string{"host1", "host2"}, "bad disk"))
+	suite.True(IsCordoned("host1"))
+	suite.True(IsCordoned("host2"))
+	suite.False(IsCordoned("host3"))
+	suite.Equal(map[string]string{
+		"host1": "bad disk",
+		"host2": "bad disk",
+	}, suite.cordonMap.GetCordonedHosts())
+
+	suite.hostCordonOps.EXPECT().Delete(gomock.Any(), "host1")
+	suite.NoError(suite.cordonMap.Uncordon(ctx, []string{"host1"}))
+	suite.False(IsCordoned("host1"))
+	suite.True(IsCordoned("host2"))
+}
+
+// TestCordonStorageError tests that a host is not cordoned
+// if its cordon cannot be persisted
+func (suite *CordonMapTestSuite) TestCordonStorageError() {
+	ctx := context.Background()
+
+	suite.hostCordonOps.EXPECT().
+		Create(gomock.Any(), "host1", "").
+		Return(errors.New("create failed"))
+	suite.Error(suite.cordonMap.Cordon(ctx, []string{"host1"}, ""))
+	suite.False(IsCordoned("host1"))
+
+	suite.hostCordonOps.EXPECT().Create(gomock.Any(), "host1", "")
+	suite.NoError(suite.cordonMap.Cordon(ctx, []string{"host1"}, ""))
+	suite.hostCordonOps.EXPECT().
+		Delete(gomock.Any(), "host1").
+		Return(errors.New("delete failed"))
+	suite.Error(suite.cordonMap.Uncordon(ctx, []string{"host1"}))
+	suite.True(IsCordoned("host1"))
+}
+
+// TestRecover tests recovering the persisted cordons
+func (suite *CordonMapTestSuite) TestRecover() {
+	ctx := context.Background()
+
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), "host1").
+		Return("kernel upgrade", true, nil)
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), "host2").
+		Return("", false, nil)
+	suite.NoError(suite.cordonMap.Recover(ctx, []string{"host1", "host2"}))
+	suite.Equal(map[string]string{"host1": "kernel upgrade"},
+		suite.cordonMap.GetCordonedHosts())
+
+	suite.hostCordonOps.EXPECT().
+		Get(gomock.Any(), "host3").
+		Return("", false, errors.New("get failed"))
+	suite.Error(suite.cordonMap.Recover(ctx, []string{"host3"}))
+}
+
+// TestGetHostPoolByAttribute tests resolving the host pool of an agent
+func (suite *CordonMapTestSuite) TestGetHostPoolByAttribute() {
+	poolName := "pool"
+	poolValue := "batch"
+	attributes := []*mesos.Attribute{{
+		Name: &poolName,
+		Text: &mesos.Value_Text{Value: &poolValue},
+	}}
+
+	suite.Equal(DefaultHostPool, GetHostPoolByAttribute(attributes, ""))
+	suite.Equal(DefaultHostPool, GetHostPoolByAttribute(attributes, "zone"))
+	suite.Equal("batch", GetHostPoolByAttribute(attributes, "pool"))
+
+	SetHostPoolAttribute("pool")
+	defer SetHostPoolAttribute("")
+	suite.Equal("batch", GetHostPool(attributes))
+	suite.Equal(DefaultHostPool, GetHostPool(nil))
+}
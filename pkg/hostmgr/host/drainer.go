@@ -15,46 +15,60 @@
 package host
 
 import (
+	"sync"
 	"time"
 
 	"github.com/uber/peloton/pkg/common/lifecycle"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
 
-	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
-
 	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
 )
 
 // drainer defines the host drainer which drains
 // the hosts which are to be put into maintenance
 type drainer struct {
-	drainerPeriod          time.Duration
-	masterOperatorClient   mpb.MasterOperatorClient
-	maintenanceQueue       queue.MaintenanceQueue
-	lifecycle              lifecycle.LifeCycle // lifecycle manager
-	maintenanceHostInfoMap MaintenanceHostInfoMap
+	sync.Mutex
+
+	drainerPeriod    time.Duration
+	periodChan       chan time.Duration
+	reconciler       *MaintenanceReconciler
+	maintenanceQueue queue.MaintenanceQueue
+	lifecycle        lifecycle.LifeCycle // lifecycle manager
 }
 
 // Drainer defines the interface for host drainer
 type Drainer interface {
 	Start()
 	Stop()
+	// SetPeriod changes the period of the host drainer, taking effect
+	// immediately if the drainer is running
+	SetPeriod(period time.Duration)
 }
 
-// NewDrainer creates a new host drainer
+// NewDrainer creates a new host drainer. The hosts whose agents are
+// drained by Mesos Master are published to eventBus once they are DOWN.
 func NewDrainer(
+	parent tally.Scope,
 	drainerPeriod time.Duration,
 	masterOperatorClient mpb.MasterOperatorClient,
 	maintenanceQueue queue.MaintenanceQueue,
 	hostInfoMap MaintenanceHostInfoMap,
+	eventBus eventbus.Bus,
 ) Drainer {
+	reconciler := NewMaintenanceReconciler(
+		parent,
+		masterOperatorClient,
+		hostInfoMap)
+	reconciler.eventBus = eventBus
 	return &drainer{
-		drainerPeriod:          drainerPeriod,
-		masterOperatorClient:   masterOperatorClient,
-		maintenanceQueue:       maintenanceQueue,
-		lifecycle:              lifecycle.NewLifeCycle(),
-		maintenanceHostInfoMap: hostInfoMap,
+		drainerPeriod:    drainerPeriod,
+		periodChan:       make(chan time.Duration, 1),
+		reconciler:       reconciler,
+		maintenanceQueue: maintenanceQueue,
+		lifecycle:        lifecycle.NewLifeCycle(),
 	}
 }
 
@@ -66,11 +80,15 @@ func (d *drainer) Start() {
 		return
 	}
 
+	d.Lock()
+	period := d.drainerPeriod
+	d.Unlock()
+
 	go func() {
 		defer d.lifecycle.StopComplete()
 
-		ticker := time.NewTicker(d.drainerPeriod)
-		defer ticker.Stop()
+		ticker := time.NewTicker(period)
+		defer func() { ticker.Stop() }()
 
 		log.Info("Starting Host drainer")
 
@@ -79,6 +97,11 @@ func (d *drainer) Start() {
 			case <-d.lifecycle.StopCh():
 				log.Info("Exiting Host drainer")
 				return
+			case period := <-d.periodChan:
+				log.WithField("period", period).
+					Info("Host drainer period updated")
+				ticker.Stop()
+				ticker = time.NewTicker(period)
 			case <-ticker.C:
 				err := d.reconcileMaintenanceState()
 				if err != nil {
@@ -102,32 +125,31 @@ func (d *drainer) Stop() {
 	log.Info("drainer stopped")
 }
 
-func (d *drainer) reconcileMaintenanceState() error {
-	response, err := d.masterOperatorClient.GetMaintenanceStatus()
-	if err != nil {
-		return err
+// SetPeriod changes the period of the host drainer
+func (d *drainer) SetPeriod(period time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.drainerPeriod = period
+
+	// Replace any period not yet picked up by the running drainer
+	select {
+	case <-d.periodChan:
+	default:
 	}
-	var drainingHosts []string
-	var hostInfos []*host.HostInfo
-	for _, drainingMachine := range response.GetStatus().GetDrainingMachines() {
-		machineID := drainingMachine.GetId()
-		hostInfos = append(hostInfos,
-			&host.HostInfo{
-				Hostname: machineID.GetHostname(),
-				Ip:       machineID.GetIp(),
-				State:    host.HostState_HOST_STATE_DRAINING,
-			})
-		drainingHosts = append(drainingHosts, machineID.GetHostname())
+	select {
+	case d.periodChan <- period:
+	default:
 	}
+}
 
-	for _, downMachine := range response.GetStatus().GetDownMachines() {
-		hostInfos = append(hostInfos,
-			&host.HostInfo{
-				Hostname: downMachine.GetHostname(),
-				Ip:       downMachine.GetIp(),
-				State:    host.HostState_HOST_STATE_DOWN,
-			})
+// reconcileMaintenanceState repairs the maintenance host info map from
+// Mesos Master, and enqueues the DRAINING hosts to be drained.
+func (d *drainer) reconcileMaintenanceState() error {
+	drainingHosts, err := d.reconciler.Reconcile()
+	if err != nil {
+		return err
 	}
-	d.maintenanceHostInfoMap.ClearAndFillMap(hostInfos)
-	return d.maintenanceQueue.Enqueue(drainingHosts)
+	_, err = d.maintenanceQueue.Enqueue(drainingHosts)
+	return err
 }
@@ -31,6 +31,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
 )
 
 const (
@@ -110,11 +111,14 @@ func (suite *drainerTestSuite) SetupTest() {
 	suite.mockMaintenanceMap = host_mocks.NewMockMaintenanceHostInfoMap(suite.mockCtrl)
 
 	suite.drainer = &drainer{
-		drainerPeriod:          drainerPeriod,
-		masterOperatorClient:   suite.mockMasterOperatorClient,
-		maintenanceQueue:       suite.mockMaintenanceQueue,
-		lifecycle:              lifecycle.NewLifeCycle(),
-		maintenanceHostInfoMap: suite.mockMaintenanceMap,
+		drainerPeriod: drainerPeriod,
+		periodChan:    make(chan time.Duration, 1),
+		reconciler: NewMaintenanceReconciler(
+			tally.NoopScope,
+			suite.mockMasterOperatorClient,
+			suite.mockMaintenanceMap),
+		maintenanceQueue: suite.mockMaintenanceQueue,
+		lifecycle:        lifecycle.NewLifeCycle(),
 	}
 }
 
@@ -126,12 +130,36 @@ func TestDrainer(t *testing.T) {
 	suite.Run(t, new(drainerTestSuite))
 }
 
+// expectMaintenanceReconcile sets the expectations to reconcile the
+// maintenance host info map with an empty maintenance schedule, up to
+// twice
+func (suite *drainerTestSuite) expectMaintenanceReconcile() {
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{}, nil).
+		MinTimes(1).
+		MaxTimes(2)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil).
+		MinTimes(1).
+		MaxTimes(2)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil).
+		MinTimes(1).
+		MaxTimes(2)
+}
+
 //TestNewDrainer test creation of new host drainer
 func (suite *drainerTestSuite) TestDrainerNewDrainer() {
-	drainer := NewDrainer(drainerPeriod,
+	drainer := NewDrainer(
+		tally.NoopScope,
+		drainerPeriod,
 		suite.mockMasterOperatorClient,
 		suite.mockMaintenanceQueue,
-		host_mocks.NewMockMaintenanceHostInfoMap(suite.mockCtrl))
+		host_mocks.NewMockMaintenanceHostInfoMap(suite.mockCtrl),
+		nil)
 	suite.NotNil(drainer)
 }
 
@@ -164,6 +192,7 @@ func (suite *drainerTestSuite) TestDrainerStartSuccess() {
 		MinTimes(1).
 		MaxTimes(2)
 
+	suite.expectMaintenanceReconcile()
 	suite.mockMaintenanceMap.EXPECT().
 		ClearAndFillMap(suite.hostInfos).
 		MinTimes(1).
@@ -171,7 +200,7 @@ func (suite *drainerTestSuite) TestDrainerStartSuccess() {
 
 	suite.mockMaintenanceQueue.EXPECT().
 		Enqueue(drainingHostnames).
-		Return(nil).
+		Return(nil, nil).
 		MinTimes(1).
 		MaxTimes(2)
 
@@ -182,6 +211,23 @@ func (suite *drainerTestSuite) TestDrainerStartSuccess() {
 	suite.drainer.Stop()
 }
 
+// TestDrainerSetPeriod tests that the period of a running drainer is
+// updated immediately
+func (suite *drainerTestSuite) TestDrainerSetPeriod() {
+	suite.drainer.drainerPeriod = time.Hour
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(nil, fmt.Errorf("Fake GetMaintenanceStatus error")).
+		MinTimes(1).
+		MaxTimes(2)
+
+	suite.drainer.Start()
+	suite.drainer.SetPeriod(drainerPeriod)
+	time.Sleep(2 * drainerPeriod)
+	suite.drainer.Stop()
+	suite.Equal(drainerPeriod, suite.drainer.drainerPeriod)
+}
+
 // TestDrainerStartGetMaintenanceStatusFailure tests the failure case of
 // starting the host drainer due to error while getting maintenance status
 func (suite *drainerTestSuite) TestDrainerStartGetMaintenanceStatusFailure() {
@@ -227,6 +273,7 @@ func (suite *drainerTestSuite) TestDrainerStartEnqueueFailure() {
 		MinTimes(1).
 		MaxTimes(2)
 
+	suite.expectMaintenanceReconcile()
 	suite.mockMaintenanceMap.EXPECT().
 		ClearAndFillMap(suite.hostInfos).
 		MinTimes(1).
@@ -234,7 +281,7 @@ func (suite *drainerTestSuite) TestDrainerStartEnqueueFailure() {
 
 	suite.mockMaintenanceQueue.EXPECT().
 		Enqueue(drainingHostnames).
-		Return(fmt.Errorf("Fake Enqueue error")).
+		Return(nil, fmt.Errorf("Fake Enqueue error")).
 		MinTimes(1).
 		MaxTimes(2)
 
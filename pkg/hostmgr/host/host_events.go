@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// Timeout of the storage calls made to persist host events.
+	_hostEventStorageTimeout = 10 * time.Second
+)
+
+// HostEventLog keeps the timeline of the events of the hosts, e.g.
+// registered, drain started, downed. Events expire with the TTL of
+// the host_events table.
+type HostEventLog interface {
+	// Record persists an event of a host, in a batch with other events
+	// which is written shortly after. Failures are logged, since the event
+	// itself already happened.
+	Record(
+		ctx context.Context,
+		hostname string,
+		eventType hpb.HostEventType,
+		message string)
+	// Get returns the events of a host at or after since, oldest first.
+	Get(
+		ctx context.Context,
+		hostname string,
+		since time.Time) ([]*hpb.HostEvent, error)
+}
+
+// hostEventLog implements HostEventLog
+type hostEventLog struct {
+	eventOps   ormobjects.HostEventOps
+	recordFail tally.Counter
+}
+
+// NewHostEventLog returns a new HostEventLog persisting events with
+// the given ops.
+func NewHostEventLog(
+	eventOps ormobjects.HostEventOps,
+	scope tally.Scope) HostEventLog {
+	return &hostEventLog{
+		eventOps:   eventOps,
+		recordFail: scope.Counter("host_event_record_fail"),
+	}
+}
+
+// SubscribeHostEvents records the events published on the event bus
+// in the host event log. Events are dropped rather than the publishers,
+// e.g. the offer path, being slowed down if recording falls behind.
+// Withheld offers are only recorded for the first offer of a host
+// scheduled for maintenance, until the host is up again.
+func SubscribeHostEvents(
+	eventBus eventbus.Bus,
+	eventLog HostEventLog) (eventbus.Subscription, error) {
+	// Only accessed from the goroutine of the subscriber
+	withheld := make(map[string]bool)
+
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name: "host_events",
+		Topics: []eventbus.Topic{
+			eventbus.AgentAdded,
+			eventbus.OffersReceived,
+			eventbus.HostStateChanged,
+			eventbus.TasksEvicted,
+			eventbus.HostDriftDetected,
+		},
+		Policy: eventbus.Drop,
+		Handler: func(event eventbus.Event) {
+			ctx := context.Background()
+			switch e := event.(type) {
+			case *eventbus.AgentAddedEvent:
+				delete(withheld, e.Hostname)
+				eventLog.Record(
+					ctx, e.Hostname, hpb.HostEventType_HOST_EVENT_TYPE_REGISTERED, "")
+			case *eventbus.OffersReceivedEvent:
+				for _, offer := range e.Offers {
+					hostname := offer.GetHostname()
+					if offer.GetUnavailability() == nil || withheld[hostname] {
+						continue
+					}
+					withheld[hostname] = true
+					eventLog.Record(
+						ctx,
+						hostname,
+						hpb.HostEventType_HOST_EVENT_TYPE_OFFERS_WITHHELD,
+						"host is scheduled for maintenance")
+				}
+			case *eventbus.HostStateChangedEvent:
+				eventType := hostStateEventType(e.To)
+				if eventType == hpb.HostEventType_HOST_EVENT_TYPE_INVALID {
+					return
+				}
+				for _, hostname := range e.Hostnames {
+					if eventType == hpb.HostEventType_HOST_EVENT_TYPE_UPPED {
+						delete(withheld, hostname)
+					}
+					eventLog.Record(ctx, hostname, eventType, "")
+				}
+			case *eventbus.TasksEvictedEvent:
+				eventLog.Record(
+					ctx,
+					e.Hostname,
+					hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+					fmt.Sprintf("%d tasks", len(e.TaskIDs)))
+			case *eventbus.HostDriftDetectedEvent:
+				eventLog.Record(
+					ctx,
+					e.Hostname,
+					hpb.HostEventType_HOST_EVENT_TYPE_DRIFT_DETECTED,
+					fmt.Sprintf("%s: %s", e.Drift, e.Message))
+			}
+		},
+	})
+}
+
+// hostStateEventType returns the event type of a transition to the
+// given host state, or HOST_EVENT_TYPE_INVALID if it has none.
+func hostStateEventType(to hpb.HostState) hpb.HostEventType {
+	switch to {
+	case hpb.HostState_HOST_STATE_DRAINING:
+		return hpb.HostEventType_HOST_EVENT_TYPE_DRAIN_STARTED
+	case hpb.HostState_HOST_STATE_DRAINED:
+		return hpb.HostEventType_HOST_EVENT_TYPE_DRAINED
+	case hpb.HostState_HOST_STATE_DOWN:
+		return hpb.HostEventType_HOST_EVENT_TYPE_DOWNED
+	case hpb.HostState_HOST_STATE_UP:
+		return hpb.HostEventType_HOST_EVENT_TYPE_UPPED
+	}
+	return hpb.HostEventType_HOST_EVENT_TYPE_INVALID
+}
+
+// Record persists an event of a host.
+func (l *hostEventLog) Record(
+	ctx context.Context,
+	hostname string,
+	eventType hpb.HostEventType,
+	message string) {
+	// Cassandra timestamps have a millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	storageCtx, cancel := context.WithTimeout(ctx, _hostEventStorageTimeout)
+	defer cancel()
+
+	if err := l.eventOps.Create(
+		storageCtx, hostname, now, eventType.String(), message); err != nil {
+		l.recordFail.Inc(1)
+		log.WithFields(log.Fields{
+			"hostname":   hostname,
+			"event_type": eventType.String(),
+		}).WithError(err).Error("failed to record host event")
+	}
+}
+
+// Get returns the events of a host at or after since.
+func (l *hostEventLog) Get(
+	ctx context.Context,
+	hostname string,
+	since time.Time) ([]*hpb.HostEvent, error) {
+	objs, err := l.eventOps.GetAll(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*hpb.HostEvent, 0, len(objs))
+	for _, obj := range objs {
+		if obj.EventTime.Before(since) {
+			continue
+		}
+		result = append(result, &hpb.HostEvent{
+			Hostname:  obj.Hostname,
+			Type:      hpb.HostEventType(hpb.HostEventType_value[obj.EventType]),
+			EventTime: obj.EventTime.UTC().Format(time.RFC3339Nano),
+			Message:   obj.Message,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type HostEventLogTestSuite struct {
+	suite.Suite
+
+	ctrl     *gomock.Controller
+	eventOps *objectmocks.MockHostEventOps
+	eventLog HostEventLog
+}
+
+func (suite *HostEventLogTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.eventOps = objectmocks.NewMockHostEventOps(suite.ctrl)
+	suite.eventLog = NewHostEventLog(suite.eventOps, tally.NoopScope)
+}
+
+func (suite *HostEventLogTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestHostEventLogTestSuite(t *testing.T) {
+	suite.Run(t, new(HostEventLogTestSuite))
+}
+
+// TestRecord tests recording an event of a host, including a storage
+// failure which must not be returned
+func (suite *HostEventLogTestSuite) TestRecord() {
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), "host1", gomock.Any(),
+			"HOST_EVENT_TYPE_TASKS_EVICTED", "2 tasks").
+		Return(nil)
+	suite.eventLog.Record(
+		context.Background(),
+		"host1",
+		hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+		"2 tasks")
+
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), "host1", gomock.Any(),
+			"HOST_EVENT_TYPE_DOWNED", "").
+		Return(errors.New("fake Create error"))
+	suite.eventLog.Record(
+		context.Background(),
+		"host1",
+		hpb.HostEventType_HOST_EVENT_TYPE_DOWNED,
+		"")
+}
+
+// TestGet tests getting the events of a host since a given time
+func (suite *HostEventLogTestSuite) TestGet() {
+	now := time.Now().UTC()
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return([]*ormobjects.HostEventObject{
+			{
+				Hostname:  "host1",
+				EventTime: now.Add(-2 * time.Hour),
+				EventType: "HOST_EVENT_TYPE_REGISTERED",
+			},
+			{
+				Hostname:  "host1",
+				EventTime: now.Add(-time.Hour),
+				EventType: "HOST_EVENT_TYPE_DRAIN_STARTED",
+			},
+			{
+				Hostname:  "host1",
+				EventTime: now,
+				EventType: "HOST_EVENT_TYPE_TASKS_EVICTED",
+				Message:   "2 tasks",
+			},
+		}, nil)
+
+	events, err := suite.eventLog.Get(
+		context.Background(), "host1", now.Add(-time.Hour))
+	suite.NoError(err)
+	suite.Equal([]*hpb.HostEvent{
+		{
+			Hostname:  "host1",
+			Type:      hpb.HostEventType_HOST_EVENT_TYPE_DRAIN_STARTED,
+			EventTime: now.Add(-time.Hour).Format(time.RFC3339Nano),
+		},
+		{
+			Hostname:  "host1",
+			Type:      hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+			EventTime: now.Format(time.RFC3339Nano),
+			Message:   "2 tasks",
+		},
+	}, events)
+
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return(nil, errors.New("fake GetAll error"))
+	_, err = suite.eventLog.Get(context.Background(), "host1", time.Time{})
+	suite.Error(err)
+}
+
+// TestSubscribeHostEvents tests that the events published on the event
+// bus are recorded, and that withheld offers are recorded once per drain
+func (suite *HostEventLogTestSuite) TestSubscribeHostEvents() {
+	eventBus := eventbus.NewBus(tally.NoopScope)
+	defer eventBus.Close()
+
+	_, err := SubscribeHostEvents(eventBus, suite.eventLog)
+	suite.NoError(err)
+
+	recorded := make(chan string, 10)
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), "host1", gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ string,
+			_ time.Time,
+			eventType string,
+			_ string) error {
+			recorded <- eventType
+			return nil
+		}).
+		AnyTimes()
+
+	hostname := "host1"
+	unavailableOffer := &mesos.Offer{
+		Hostname:       &hostname,
+		Unavailability: &mesos.Unavailability{},
+	}
+	eventBus.Publish(&eventbus.AgentAddedEvent{Hostname: hostname})
+	eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{hostname},
+		From:      hpb.HostState_HOST_STATE_UP,
+		To:        hpb.HostState_HOST_STATE_DRAINING,
+	})
+	eventBus.Publish(&eventbus.OffersReceivedEvent{
+		Offers: []*mesos.Offer{{Hostname: &hostname}, unavailableOffer},
+	})
+	eventBus.Publish(&eventbus.OffersReceivedEvent{
+		Offers: []*mesos.Offer{unavailableOffer},
+	})
+	eventBus.Publish(&eventbus.TasksEvictedEvent{
+		Hostname: hostname,
+		TaskIDs:  []string{"t1"},
+	})
+	eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{hostname},
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DRAINED,
+	})
+	eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{hostname},
+		From:      hpb.HostState_HOST_STATE_DRAINED,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+	eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{hostname},
+		From:      hpb.HostState_HOST_STATE_DOWN,
+		To:        hpb.HostState_HOST_STATE_UP,
+	})
+	eventBus.Publish(&eventbus.OffersReceivedEvent{
+		Offers: []*mesos.Offer{unavailableOffer},
+	})
+	eventBus.Publish(&eventbus.HostDriftDetectedEvent{
+		Hostname: hostname,
+		Drift:    "state_mismatch",
+		Message:  "DRAINING in host manager, DOWN in Mesos master",
+	})
+
+	for _, eventType := range []string{
+		"HOST_EVENT_TYPE_REGISTERED",
+		"HOST_EVENT_TYPE_DRAIN_STARTED",
+		"HOST_EVENT_TYPE_OFFERS_WITHHELD",
+		"HOST_EVENT_TYPE_TASKS_EVICTED",
+		"HOST_EVENT_TYPE_DRAINED",
+		"HOST_EVENT_TYPE_DOWNED",
+		"HOST_EVENT_TYPE_UPPED",
+		"HOST_EVENT_TYPE_OFFERS_WITHHELD",
+		"HOST_EVENT_TYPE_DRIFT_DETECTED",
+	} {
+		select {
+		case r := <-recorded:
+			suite.Equal(eventType, r)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("host event not recorded", eventType)
+		}
+	}
+}
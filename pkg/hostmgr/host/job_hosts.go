@@ -0,0 +1,246 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/uber-go/tally"
+)
+
+// Atomic pointer to the map from hostname to the counts of the labels of
+// the tasks running on the host, read lock free by the placement path.
+// The map is replaced when a host is added, and the counters are updated
+// in place.
+var taskLabelCounters atomic.Value
+
+// GetTaskLabelValues returns a snapshot of the label counts of the tasks
+// running on a host, to evaluate constraints of kind TASK. It returns
+// false if the labels of the tasks are not tracked by a JobHostMap.
+func GetTaskLabelValues(hostname string) (constraints.LabelValues, bool) {
+	m, ok := taskLabelCounters.Load().(map[string]*constraints.LabelCounter)
+	if !ok {
+		return nil, false
+	}
+	counter, ok := m[hostname]
+	if !ok {
+		return constraints.LabelValues{}, true
+	}
+	return counter.Snapshot(), true
+}
+
+// JobHostMap keeps track of the hosts running the tasks of each job, and
+// of the labels of the tasks running on each host, so that constraints of
+// kind TASK, e.g. job anti-affinity, are evaluated against a snapshot of
+// the labels of a host instead of being recomputed for every offer. It is
+// fed by the tasks launched by host manager and by their status updates,
+// so the tasks launched before host manager started are not tracked.
+type JobHostMap interface {
+	// AddTasks records that the tasks were launched on the host, given
+	// the labels of each task by Mesos task id.
+	AddTasks(hostname string, taskLabels map[string][]*peloton.Label)
+
+	// UpdateTaskStatus removes the tasks in terminal states.
+	UpdateTaskStatus(status *mesos.TaskStatus)
+
+	// GetHostsByJob returns the sorted hosts running tasks of the job.
+	GetHostsByJob(jobID string) []string
+}
+
+// jobHostTask is a task tracked by the job host map.
+type jobHostTask struct {
+	hostname string
+	jobID    string
+	labels   []*peloton.Label
+}
+
+// jobHostMap implements JobHostMap
+type jobHostMap struct {
+	sync.Mutex
+
+	// Mesos task id to the task
+	tasks map[string]*jobHostTask
+	// job id to the number of tasks of the job running on each host
+	jobHosts map[string]map[string]int
+	// hostname to the counts of the labels of its tasks, published to
+	// taskLabelCounters
+	counters map[string]*constraints.LabelCounter
+
+	trackedTasks tally.Gauge
+	trackedJobs  tally.Gauge
+}
+
+// NewJobHostMap returns a new JobHostMap, and enables the evaluation of
+// the constraints of kind TASK by the placement path.
+func NewJobHostMap(scope tally.Scope) JobHostMap {
+	taskLabelCounters.Store(map[string]*constraints.LabelCounter{})
+	jobHostScope := scope.SubScope("job_host_map")
+	return &jobHostMap{
+		tasks:        make(map[string]*jobHostTask),
+		jobHosts:     make(map[string]map[string]int),
+		counters:     make(map[string]*constraints.LabelCounter),
+		trackedTasks: jobHostScope.Gauge("tasks"),
+		trackedJobs:  jobHostScope.Gauge("jobs"),
+	}
+}
+
+// AddTasks records that the tasks were launched on the host.
+func (m *jobHostMap) AddTasks(
+	hostname string,
+	taskLabels map[string][]*peloton.Label) {
+	m.Lock()
+	defer m.Unlock()
+
+	for taskID, labels := range taskLabels {
+		if t, ok := m.tasks[taskID]; ok {
+			if t.hostname == hostname {
+				continue
+			}
+			m.removeTask(taskID)
+		}
+		m.addTask(hostname, taskID, labels)
+	}
+	m.updateGauges()
+}
+
+// UpdateTaskStatus removes the tasks in terminal states.
+func (m *jobHostMap) UpdateTaskStatus(status *mesos.TaskStatus) {
+	state := util.MesosStateToPelotonState(status.GetState())
+	if !util.IsPelotonStateTerminal(state) {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.removeTask(status.GetTaskId().GetValue())
+	m.updateGauges()
+}
+
+// GetHostsByJob returns the sorted hosts running tasks of the job.
+func (m *jobHostMap) GetHostsByJob(jobID string) []string {
+	m.Lock()
+	defer m.Unlock()
+
+	hostnames := make([]string, 0, len(m.jobHosts[jobID]))
+	for hostname := range m.jobHosts[jobID] {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// addTask adds a task to the map. It must be called with the lock held.
+func (m *jobHostMap) addTask(
+	hostname string,
+	taskID string,
+	labels []*peloton.Label) {
+	t := &jobHostTask{hostname: hostname, labels: labels}
+	if jobID, _, err := util.ParseJobAndInstanceID(taskID); err == nil {
+		t.jobID = jobID
+		hosts, ok := m.jobHosts[jobID]
+		if !ok {
+			hosts = make(map[string]int)
+			m.jobHosts[jobID] = hosts
+		}
+		hosts[hostname]++
+	}
+	m.tasks[taskID] = t
+
+	counter, ok := m.counters[hostname]
+	if !ok {
+		counter = constraints.NewLabelCounter()
+		m.counters[hostname] = counter
+		m.publishCounters()
+	}
+	for _, label := range labels {
+		counter.Increment(label.GetKey(), label.GetValue())
+	}
+}
+
+// removeTask removes a task from the map. It must be called with the
+// lock held.
+func (m *jobHostMap) removeTask(taskID string) {
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return
+	}
+	delete(m.tasks, taskID)
+
+	if hosts, ok := m.jobHosts[t.jobID]; ok {
+		hosts[t.hostname]--
+		if hosts[t.hostname] <= 0 {
+			delete(hosts, t.hostname)
+		}
+		if len(hosts) == 0 {
+			delete(m.jobHosts, t.jobID)
+		}
+	}
+
+	if counter, ok := m.counters[t.hostname]; ok {
+		for _, label := range t.labels {
+			counter.Decrement(label.GetKey(), label.GetValue())
+		}
+		counter.Compact()
+	}
+}
+
+// publishCounters stores a copy of the label counters of the hosts in
+// the map for GetTaskLabelValues. It must be called with the lock held.
+func (m *jobHostMap) publishCounters() {
+	counters := make(map[string]*constraints.LabelCounter, len(m.counters))
+	for hostname, counter := range m.counters {
+		counters[hostname] = counter
+	}
+	taskLabelCounters.Store(counters)
+}
+
+// updateGauges reports the size of the map. It must be called with the
+// lock held.
+func (m *jobHostMap) updateGauges() {
+	m.trackedTasks.Update(float64(len(m.tasks)))
+	m.trackedJobs.Update(float64(len(m.jobHosts)))
+}
+
+// SubscribeJobHostMap feeds the job host map with the tasks launched and
+// the task status updates published on the event bus.
+func SubscribeJobHostMap(
+	eventBus eventbus.Bus,
+	jobHostMap JobHostMap) (eventbus.Subscription, error) {
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name: "job_host_map",
+		Topics: []eventbus.Topic{
+			eventbus.TasksLaunched,
+			eventbus.TaskStatusUpdated,
+		},
+		Policy: eventbus.Block,
+		Handler: func(event eventbus.Event) {
+			switch e := event.(type) {
+			case *eventbus.TasksLaunchedEvent:
+				jobHostMap.AddTasks(e.Hostname, e.TaskLabels)
+			case *eventbus.TaskStatusUpdatedEvent:
+				jobHostMap.UpdateTaskStatus(e.Status)
+			}
+		},
+	})
+}
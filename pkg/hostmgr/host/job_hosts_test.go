@@ -0,0 +1,193 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_jobHostJob1 = "8d7e5a2c-5ee6-4a3b-9e1f-1a3c4d5e6f70"
+	_jobHostJob2 = "0b6a2f51-1c0d-4e2f-8a9b-7c6d5e4f3a21"
+)
+
+type JobHostMapTestSuite struct {
+	suite.Suite
+
+	jobHostMap JobHostMap
+}
+
+func (suite *JobHostMapTestSuite) SetupTest() {
+	suite.jobHostMap = NewJobHostMap(tally.NoopScope)
+}
+
+func TestJobHostMapTestSuite(t *testing.T) {
+	suite.Run(t, new(JobHostMapTestSuite))
+}
+
+// jobLabels returns the labels of the tasks of a job.
+func jobLabels(jobName string) []*peloton.Label {
+	return []*peloton.Label{
+		{Key: constraints.JobNameLabelKey, Value: jobName},
+	}
+}
+
+// mesosTaskID returns the id of the first run of an instance of a job.
+func mesosTaskID(jobID string, instanceID string) string {
+	return jobID + "-" + instanceID + "-1"
+}
+
+// terminalStatus returns the TASK_FINISHED status of a task.
+func terminalStatus(taskID string) *mesos.TaskStatus {
+	state := mesos.TaskState_TASK_FINISHED
+	return &mesos.TaskStatus{
+		TaskId: &mesos.TaskID{Value: &taskID},
+		State:  &state,
+	}
+}
+
+// TestAddTasks tests tracking the hosts and the task labels of the
+// launched tasks, and removing the tasks once terminal
+func (suite *JobHostMapTestSuite) TestAddTasks() {
+	suite.jobHostMap.AddTasks("host1", map[string][]*peloton.Label{
+		mesosTaskID(_jobHostJob1, "0"): jobLabels("job1"),
+		mesosTaskID(_jobHostJob1, "1"): jobLabels("job1"),
+		mesosTaskID(_jobHostJob2, "0"): jobLabels("job2"),
+	})
+	suite.jobHostMap.AddTasks("host2", map[string][]*peloton.Label{
+		mesosTaskID(_jobHostJob1, "2"): jobLabels("job1"),
+	})
+
+	suite.Equal([]string{"host1", "host2"},
+		suite.jobHostMap.GetHostsByJob(_jobHostJob1))
+	suite.Equal([]string{"host1"},
+		suite.jobHostMap.GetHostsByJob(_jobHostJob2))
+	suite.Empty(suite.jobHostMap.GetHostsByJob("unknown"))
+
+	lv, ok := GetTaskLabelValues("host1")
+	suite.True(ok)
+	suite.Equal(constraints.LabelValues{
+		constraints.JobNameLabelKey: {"job1": 2, "job2": 1},
+	}, lv)
+
+	// Non-terminal status updates are ignored
+	running := mesos.TaskState_TASK_RUNNING
+	taskID := mesosTaskID(_jobHostJob2, "0")
+	suite.jobHostMap.UpdateTaskStatus(&mesos.TaskStatus{
+		TaskId: &mesos.TaskID{Value: &taskID},
+		State:  &running,
+	})
+	suite.Equal([]string{"host1"},
+		suite.jobHostMap.GetHostsByJob(_jobHostJob2))
+
+	suite.jobHostMap.UpdateTaskStatus(terminalStatus(taskID))
+	suite.Empty(suite.jobHostMap.GetHostsByJob(_jobHostJob2))
+	lv, ok = GetTaskLabelValues("host1")
+	suite.True(ok)
+	suite.Equal(constraints.LabelValues{
+		constraints.JobNameLabelKey: {"job1": 2},
+	}, lv)
+
+	suite.jobHostMap.UpdateTaskStatus(
+		terminalStatus(mesosTaskID(_jobHostJob1, "2")))
+	suite.Equal([]string{"host1"},
+		suite.jobHostMap.GetHostsByJob(_jobHostJob1))
+	lv, ok = GetTaskLabelValues("host2")
+	suite.True(ok)
+	suite.Empty(lv)
+
+	// Hosts without tracked tasks have no task labels
+	lv, ok = GetTaskLabelValues("host3")
+	suite.True(ok)
+	suite.Empty(lv)
+}
+
+// TestAddTasksMoved tests moving a task launched again on another host
+func (suite *JobHostMapTestSuite) TestAddTasksMoved() {
+	taskID := mesosTaskID(_jobHostJob1, "0")
+	suite.jobHostMap.AddTasks("host1", map[string][]*peloton.Label{
+		taskID: jobLabels("job1"),
+	})
+	suite.jobHostMap.AddTasks("host2", map[string][]*peloton.Label{
+		taskID: jobLabels("job1"),
+	})
+
+	suite.Equal([]string{"host2"},
+		suite.jobHostMap.GetHostsByJob(_jobHostJob1))
+	lv, _ := GetTaskLabelValues("host1")
+	suite.Empty(lv)
+	lv, _ = GetTaskLabelValues("host2")
+	suite.Equal(constraints.LabelValues{
+		constraints.JobNameLabelKey: {"job1": 1},
+	}, lv)
+}
+
+// TestJobAntiAffinity tests evaluating job anti-affinity constraints
+// against the task labels of the hosts
+func (suite *JobHostMapTestSuite) TestJobAntiAffinity() {
+	suite.jobHostMap.AddTasks("host1", map[string][]*peloton.Label{
+		mesosTaskID(_jobHostJob1, "0"): jobLabels("job1"),
+	})
+
+	evaluator := constraints.NewEvaluator(task.LabelConstraint_TASK)
+	constraint := constraints.NewJobAntiAffinityConstraint("job1")
+	for hostname, want := range map[string]constraints.EvaluateResult{
+		"host1": constraints.EvaluateResultMismatch,
+		"host2": constraints.EvaluateResultMatch,
+	} {
+		lv, ok := GetTaskLabelValues(hostname)
+		suite.True(ok)
+		result, err := evaluator.Evaluate(constraint, lv)
+		suite.NoError(err)
+		suite.Equal(want, result, hostname)
+	}
+}
+
+// TestSubscribeJobHostMap tests feeding the job host map with the
+// events published on the event bus
+func (suite *JobHostMapTestSuite) TestSubscribeJobHostMap() {
+	eventBus := eventbus.NewBus(tally.NoopScope)
+	defer eventBus.Close()
+
+	_, err := SubscribeJobHostMap(eventBus, suite.jobHostMap)
+	suite.NoError(err)
+
+	taskID := mesosTaskID(_jobHostJob1, "0")
+	eventBus.Publish(&eventbus.TasksLaunchedEvent{
+		Hostname:   "host1",
+		TaskLabels: map[string][]*peloton.Label{taskID: jobLabels("job1")},
+	})
+	suite.Eventually(func() bool {
+		return len(suite.jobHostMap.GetHostsByJob(_jobHostJob1)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	eventBus.Publish(&eventbus.TaskStatusUpdatedEvent{
+		Status: terminalStatus(taskID),
+	})
+	suite.Eventually(func() bool {
+		return len(suite.jobHostMap.GetHostsByJob(_jobHostJob1)) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+)
+
+// Synthetic labels added by host manager to the label values of every
+// host, so that scheduling constraints can refer to the maintenance
+// state, host pool and cordon of a host like to any agent attribute.
+const (
+	// StateLabel is the maintenance state of the host,
+	// one of up, draining or down.
+	StateLabel = "peloton/state"
+	// PoolLabel is the host pool of the host.
+	PoolLabel = "peloton/pool"
+	// CordonedLabel is true if the host is cordoned, false otherwise.
+	CordonedLabel = "peloton/cordoned"
+
+	_hostStatePrefix = "HOST_STATE_"
+)
+
+// Atomic pointer to the map from hostname to maintenance state of the
+// hosts in maintenance, read lock free by the placement path.
+var maintenanceStates atomic.Value
+
+// GetMaintenanceState returns the maintenance state of a host,
+// HOST_STATE_UP if the host is not in maintenance.
+func GetMaintenanceState(hostname string) host.HostState {
+	m, _ := maintenanceStates.Load().(map[string]host.HostState)
+	if state, ok := m[hostname]; ok {
+		return state
+	}
+	return host.HostState_HOST_STATE_UP
+}
+
+// GetHostLabelValues returns the label values of a host and its
+// attributes with the labels assigned to the host and the synthetic
+// peloton labels added. Assigned labels override agent attributes with
+// the same name, and the synthetic labels override both.
+func GetHostLabelValues(
+	hostname string,
+	attributes []*mesos.Attribute) constraints.LabelValues {
+	lv := constraints.GetHostLabelValues(hostname, attributes)
+	assigned := make(map[string]map[string]uint32)
+	for _, label := range GetHostAssignment(hostname).GetLabels() {
+		if _, ok := assigned[label.GetKey()]; !ok {
+			assigned[label.GetKey()] = make(map[string]uint32)
+		}
+		assigned[label.GetKey()][label.GetValue()]++
+	}
+	for key, values := range assigned {
+		lv[key] = values
+	}
+	lv[StateLabel] = map[string]uint32{
+		stateLabelValue(GetMaintenanceState(hostname)): 1,
+	}
+	lv[PoolLabel] = map[string]uint32{
+		GetAssignedHostPool(hostname, attributes): 1,
+	}
+	lv[CordonedLabel] = map[string]uint32{
+		strconv.FormatBool(IsCordoned(hostname)): 1,
+	}
+	return lv
+}
+
+// stateLabelValue returns the value of StateLabel for a host state,
+// e.g. draining for HOST_STATE_DRAINING.
+func stateLabelValue(state host.HostState) string {
+	return strings.ToLower(strings.TrimPrefix(state.String(), _hostStatePrefix))
+}
+
+// publishStates stores a copy of the maintenance states of the hosts
+// in the map for GetMaintenanceState. Must be called with the lock held.
+func (m *maintenanceHostInfoMap) publishStates() {
+	states := make(
+		map[string]host.HostState,
+		len(m.drainingHosts)+len(m.downHosts))
+	for hostname := range m.drainingHosts {
+		states[hostname] = host.HostState_HOST_STATE_DRAINING
+	}
+	for hostname := range m.downHosts {
+		states[hostname] = host.HostState_HOST_STATE_DOWN
+	}
+	maintenanceStates.Store(states)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type LabelsTestSuite struct {
+	suite.Suite
+
+	maintenanceMap MaintenanceHostInfoMap
+}
+
+func (suite *LabelsTestSuite) SetupTest() {
+	suite.maintenanceMap = NewMaintenanceHostInfoMap(tally.NoopScope)
+	cordonedHosts.Store(map[string]string{})
+	SetHostPoolAttribute("pool")
+}
+
+func (suite *LabelsTestSuite) TearDownTest() {
+	maintenanceStates.Store(map[string]host.HostState{})
+	cordonedHosts.Store(map[string]string{})
+	SetHostPoolAttribute("")
+}
+
+func TestLabelsTestSuite(t *testing.T) {
+	suite.Run(t, new(LabelsTestSuite))
+}
+
+// TestGetHostLabelValuesDefaults tests the synthetic labels of an
+// up host without host pool attribute which is not cordoned
+func (suite *LabelsTestSuite) TestGetHostLabelValuesDefaults() {
+	lv := GetHostLabelValues("host1", []*mesos.Attribute{
+		makeTextAttribute("zone", "us1"),
+	})
+	suite.Equal(constraints.LabelValues{
+		constraints.HostNameKey: {"host1": 1},
+		"zone":                  {"us1": 1},
+		StateLabel:              {"up": 1},
+		PoolLabel:               {DefaultHostPool: 1},
+		CordonedLabel:           {"false": 1},
+	}, lv)
+}
+
+// TestGetHostLabelValues tests the synthetic labels follow the
+// maintenance state, host pool and cordon of a host
+func (suite *LabelsTestSuite) TestGetHostLabelValues() {
+	attributes := []*mesos.Attribute{makeTextAttribute("pool", "batch")}
+	suite.maintenanceMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+	})
+	cordonedHosts.Store(map[string]string{"host1": "bad disk"})
+
+	lv := GetHostLabelValues("host1", attributes)
+	suite.Equal(map[string]uint32{"draining": 1}, lv[StateLabel])
+	suite.Equal(map[string]uint32{"batch": 1}, lv[PoolLabel])
+	suite.Equal(map[string]uint32{"true": 1}, lv[CordonedLabel])
+
+	suite.NoError(suite.maintenanceMap.UpdateHostState(
+		"host1",
+		host.HostState_HOST_STATE_DRAINING,
+		host.HostState_HOST_STATE_DOWN))
+	lv = GetHostLabelValues("host1", attributes)
+	suite.Equal(map[string]uint32{"down": 1}, lv[StateLabel])
+
+	suite.maintenanceMap.RemoveHostInfos([]string{"host1"})
+	lv = GetHostLabelValues("host1", attributes)
+	suite.Equal(map[string]uint32{"up": 1}, lv[StateLabel])
+}
+
+// TestGetHostLabelValuesOverridesAttributes tests that agent attributes
+// cannot set the synthetic labels
+func (suite *LabelsTestSuite) TestGetHostLabelValuesOverridesAttributes() {
+	lv := GetHostLabelValues("host1", []*mesos.Attribute{
+		makeTextAttribute(CordonedLabel, "true"),
+		makeTextAttribute(StateLabel, "draining"),
+	})
+	suite.Equal(map[string]uint32{"false": 1}, lv[CordonedLabel])
+	suite.Equal(map[string]uint32{"up": 1}, lv[StateLabel])
+}
+
+// TestClearAndFillMapPublishesStates tests that reloading the
+// maintenance map from Mesos Master replaces the published states
+func (suite *LabelsTestSuite) TestClearAndFillMapPublishesStates() {
+	suite.maintenanceMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+	})
+	suite.maintenanceMap.ClearAndFillMap([]*host.HostInfo{
+		{
+			Hostname: "host2",
+			State:    host.HostState_HOST_STATE_DOWN,
+		},
+	})
+	suite.Equal(host.HostState_HOST_STATE_UP, GetMaintenanceState("host1"))
+	suite.Equal(host.HostState_HOST_STATE_DOWN, GetMaintenanceState("host2"))
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
+)
+
+const (
+	// Timeout of the storage calls made to persist maintenance events.
+	_maintenanceHistoryStorageTimeout = 10 * time.Second
+)
+
+// MaintenanceHistory keeps the maintenance state transitions of the hosts.
+// Transitions are first recorded as recent events, and periodically moved
+// to the long-term maintenance history by the archiver.
+type MaintenanceHistory interface {
+	// Record persists a maintenance state transition of the given hosts.
+	// Failures are logged, since the transition itself already happened.
+	Record(
+		ctx context.Context,
+		hostnames []string,
+		from hpb.HostState,
+		to hpb.HostState)
+	// Get returns the archived and recent transitions of a host,
+	// oldest first.
+	Get(ctx context.Context, hostname string) ([]*hpb.MaintenanceEvent, error)
+	// Archive moves the transitions of the given hosts older than
+	// archiveAge to the history, and deletes the archived transitions
+	// older than retention. The history is kept forever if retention
+	// is zero. Returns the number of archived and pruned transitions.
+	Archive(
+		ctx context.Context,
+		hostnames []string,
+		archiveAge time.Duration,
+		retention time.Duration) (int, int, error)
+}
+
+// maintenanceHistory implements MaintenanceHistory
+type maintenanceHistory struct {
+	eventOps   ormobjects.HostMaintenanceEventOps
+	historyOps ormobjects.HostMaintenanceHistoryOps
+
+	recordFail tally.Counter
+	archived   tally.Counter
+	pruned     tally.Counter
+}
+
+// NewMaintenanceHistory returns a new MaintenanceHistory persisting
+// transitions with the given ops.
+func NewMaintenanceHistory(
+	eventOps ormobjects.HostMaintenanceEventOps,
+	historyOps ormobjects.HostMaintenanceHistoryOps,
+	scope tally.Scope) MaintenanceHistory {
+	return &maintenanceHistory{
+		eventOps:   eventOps,
+		historyOps: historyOps,
+		recordFail: scope.Counter("maintenance_event_record_fail"),
+		archived:   scope.Counter("maintenance_events_archived"),
+		pruned:     scope.Counter("maintenance_history_pruned"),
+	}
+}
+
+// SubscribeMaintenanceHistory records the host state changes published
+// on the event bus in the maintenance history. The publishers are blocked
+// rather than transitions being lost if recording falls behind.
+func SubscribeMaintenanceHistory(
+	eventBus eventbus.Bus,
+	history MaintenanceHistory) (eventbus.Subscription, error) {
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name:   "maintenance_history",
+		Topics: []eventbus.Topic{eventbus.HostStateChanged},
+		Policy: eventbus.Block,
+		Handler: func(event eventbus.Event) {
+			change := event.(*eventbus.HostStateChangedEvent)
+			history.Record(
+				context.Background(),
+				change.Hostnames,
+				change.From,
+				change.To)
+		},
+	})
+}
+
+// Record persists a maintenance state transition of the given hosts.
+func (h *maintenanceHistory) Record(
+	ctx context.Context,
+	hostnames []string,
+	from hpb.HostState,
+	to hpb.HostState) {
+	// Cassandra timestamps have a millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, hostname := range hostnames {
+		storageCtx, cancel := context.WithTimeout(
+			ctx, _maintenanceHistoryStorageTimeout)
+		err := h.eventOps.Create(
+			storageCtx, hostname, now, from.String(), to.String())
+		cancel()
+		if err != nil {
+			h.recordFail.Inc(1)
+			log.WithFields(log.Fields{
+				"hostname": hostname,
+				"from":     from.String(),
+				"to":       to.String(),
+			}).WithError(err).Error("failed to record maintenance event")
+		}
+	}
+}
+
+// Get returns the archived and recent transitions of a host.
+func (h *maintenanceHistory) Get(
+	ctx context.Context,
+	hostname string) ([]*hpb.MaintenanceEvent, error) {
+	history, err := h.historyOps.GetAll(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.eventOps.GetAll(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	type timedEvent struct {
+		eventTime time.Time
+		event     *hpb.MaintenanceEvent
+	}
+	var timedEvents []timedEvent
+	for _, obj := range history {
+		timedEvents = append(timedEvents, timedEvent{
+			eventTime: obj.EventTime,
+			event: newMaintenanceEvent(
+				obj.Hostname, obj.EventTime, obj.FromState, obj.ToState, true),
+		})
+	}
+	for _, obj := range events {
+		timedEvents = append(timedEvents, timedEvent{
+			eventTime: obj.EventTime,
+			event: newMaintenanceEvent(
+				obj.Hostname, obj.EventTime, obj.FromState, obj.ToState, false),
+		})
+	}
+	sort.SliceStable(timedEvents, func(i, j int) bool {
+		return timedEvents[i].eventTime.Before(timedEvents[j].eventTime)
+	})
+
+	result := make([]*hpb.MaintenanceEvent, 0, len(timedEvents))
+	for _, e := range timedEvents {
+		result = append(result, e.event)
+	}
+	return result, nil
+}
+
+// Archive moves old transitions to the history and prunes the history.
+func (h *maintenanceHistory) Archive(
+	ctx context.Context,
+	hostnames []string,
+	archiveAge time.Duration,
+	retention time.Duration) (int, int, error) {
+	now := time.Now().UTC()
+	var archived, pruned int
+	var errs error
+
+	for _, hostname := range hostnames {
+		n, err := h.archiveHost(ctx, hostname, now.Add(-archiveAge))
+		archived += n
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+
+		if retention == 0 {
+			continue
+		}
+		n, err = h.pruneHost(ctx, hostname, now.Add(-retention))
+		pruned += n
+		if err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+
+	h.archived.Inc(int64(archived))
+	h.pruned.Inc(int64(pruned))
+	return archived, pruned, errs
+}
+
+// archiveHost moves the transitions of a host older than the given time
+// to the history. The transition is only deleted from the recent events
+// once it is in the history, so a failure never loses a transition.
+func (h *maintenanceHistory) archiveHost(
+	ctx context.Context,
+	hostname string,
+	before time.Time) (int, error) {
+	events, err := h.eventOps.GetAll(ctx, hostname)
+	if err != nil {
+		return 0, err
+	}
+
+	var archived int
+	for _, event := range events {
+		if !event.EventTime.Before(before) {
+			continue
+		}
+		if err := h.historyOps.Create(ctx, event); err != nil {
+			return archived, err
+		}
+		if err := h.eventOps.Delete(ctx, hostname, event.EventTime); err != nil {
+			return archived, err
+		}
+		archived++
+	}
+	return archived, nil
+}
+
+// pruneHost deletes the archived transitions of a host older than
+// the given time.
+func (h *maintenanceHistory) pruneHost(
+	ctx context.Context,
+	hostname string,
+	before time.Time) (int, error) {
+	history, err := h.historyOps.GetAll(ctx, hostname)
+	if err != nil {
+		return 0, err
+	}
+
+	var pruned int
+	for _, obj := range history {
+		if !obj.EventTime.Before(before) {
+			continue
+		}
+		if err := h.historyOps.Delete(ctx, hostname, obj.EventTime); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// newMaintenanceEvent builds the API message of a persisted transition.
+func newMaintenanceEvent(
+	hostname string,
+	eventTime time.Time,
+	from string,
+	to string,
+	archived bool) *hpb.MaintenanceEvent {
+	return &hpb.MaintenanceEvent{
+		Hostname:  hostname,
+		FromState: hpb.HostState(hpb.HostState_value[from]),
+		ToState:   hpb.HostState(hpb.HostState_value[to]),
+		EventTime: eventTime.UTC().Format(time.RFC3339Nano),
+		Archived:  archived,
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type MaintenanceHistoryTestSuite struct {
+	suite.Suite
+
+	ctrl       *gomock.Controller
+	eventOps   *objectmocks.MockHostMaintenanceEventOps
+	historyOps *objectmocks.MockHostMaintenanceHistoryOps
+	history    MaintenanceHistory
+}
+
+func (suite *MaintenanceHistoryTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.eventOps = objectmocks.NewMockHostMaintenanceEventOps(suite.ctrl)
+	suite.historyOps = objectmocks.NewMockHostMaintenanceHistoryOps(suite.ctrl)
+	suite.history = NewMaintenanceHistory(
+		suite.eventOps, suite.historyOps, tally.NoopScope)
+}
+
+func (suite *MaintenanceHistoryTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestMaintenanceHistoryTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceHistoryTestSuite))
+}
+
+// TestRecord tests recording a transition of hosts, including a
+// storage failure which must not be returned
+func (suite *MaintenanceHistoryTestSuite) TestRecord() {
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), "host1", gomock.Any(),
+			"HOST_STATE_UP", "HOST_STATE_DRAINING").
+		Return(nil)
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), "host2", gomock.Any(),
+			"HOST_STATE_UP", "HOST_STATE_DRAINING").
+		Return(errors.New("create failed"))
+
+	suite.history.Record(
+		context.Background(),
+		[]string{"host1", "host2"},
+		hpb.HostState_HOST_STATE_UP,
+		hpb.HostState_HOST_STATE_DRAINING)
+}
+
+// TestSubscribeMaintenanceHistory tests that the host state changes
+// published on the event bus are recorded
+func (suite *MaintenanceHistoryTestSuite) TestSubscribeMaintenanceHistory() {
+	eventBus := eventbus.NewBus(tally.NoopScope)
+	defer eventBus.Close()
+
+	_, err := SubscribeMaintenanceHistory(eventBus, suite.history)
+	suite.NoError(err)
+
+	recorded := make(chan string, 2)
+	suite.eventOps.EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any(),
+			"HOST_STATE_DRAINING", "HOST_STATE_DOWN").
+		DoAndReturn(func(
+			_ context.Context,
+			hostname string,
+			_ time.Time,
+			_ string,
+			_ string) error {
+			recorded <- hostname
+			return nil
+		}).
+		Times(2)
+
+	eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host1", "host2"},
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+	for _, hostname := range []string{"host1", "host2"} {
+		select {
+		case r := <-recorded:
+			suite.Equal(hostname, r)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("host state change not recorded")
+		}
+	}
+}
+
+// TestGet tests merging the archived and recent transitions of a host
+func (suite *MaintenanceHistoryTestSuite) TestGet() {
+	now := time.Now().UTC()
+	suite.historyOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return([]*ormobjects.HostMaintenanceHistoryObject{
+			{
+				Hostname:  "host1",
+				EventTime: now.Add(-2 * time.Hour),
+				FromState: "HOST_STATE_UP",
+				ToState:   "HOST_STATE_DRAINING",
+			},
+		}, nil)
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return([]*ormobjects.HostMaintenanceEventObject{
+			{
+				Hostname:  "host1",
+				EventTime: now,
+				FromState: "HOST_STATE_DOWN",
+				ToState:   "HOST_STATE_UP",
+			},
+			{
+				Hostname:  "host1",
+				EventTime: now.Add(-time.Hour),
+				FromState: "HOST_STATE_DRAINING",
+				ToState:   "HOST_STATE_DOWN",
+			},
+		}, nil)
+
+	events, err := suite.history.Get(context.Background(), "host1")
+	suite.NoError(err)
+	suite.Len(events, 3)
+	suite.Equal(hpb.HostState_HOST_STATE_DRAINING, events[0].GetToState())
+	suite.True(events[0].GetArchived())
+	suite.Equal(hpb.HostState_HOST_STATE_DOWN, events[1].GetToState())
+	suite.False(events[1].GetArchived())
+	suite.Equal(hpb.HostState_HOST_STATE_UP, events[2].GetToState())
+	suite.Equal(now.Format(time.RFC3339Nano), events[2].GetEventTime())
+}
+
+// TestGetStorageError tests that storage errors are returned
+func (suite *MaintenanceHistoryTestSuite) TestGetStorageError() {
+	suite.historyOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return(nil, errors.New("getall failed"))
+
+	_, err := suite.history.Get(context.Background(), "host1")
+	suite.EqualError(err, "getall failed")
+}
+
+// TestArchive tests moving old transitions to the history and pruning
+// the history past its retention
+func (suite *MaintenanceHistoryTestSuite) TestArchive() {
+	now := time.Now().UTC()
+	old := &ormobjects.HostMaintenanceEventObject{
+		Hostname:  "host1",
+		EventTime: now.Add(-48 * time.Hour),
+		FromState: "HOST_STATE_UP",
+		ToState:   "HOST_STATE_DRAINING",
+	}
+	recent := &ormobjects.HostMaintenanceEventObject{
+		Hostname:  "host1",
+		EventTime: now.Add(-time.Hour),
+		FromState: "HOST_STATE_DRAINING",
+		ToState:   "HOST_STATE_DOWN",
+	}
+	expired := now.Add(-30 * 24 * time.Hour)
+
+	gomock.InOrder(
+		suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").
+			Return([]*ormobjects.HostMaintenanceEventObject{old, recent}, nil),
+		suite.historyOps.EXPECT().Create(gomock.Any(), old).Return(nil),
+		suite.eventOps.EXPECT().Delete(gomock.Any(), "host1", old.EventTime).
+			Return(nil),
+		suite.historyOps.EXPECT().GetAll(gomock.Any(), "host1").
+			Return([]*ormobjects.HostMaintenanceHistoryObject{
+				{Hostname: "host1", EventTime: expired},
+				{Hostname: "host1", EventTime: old.EventTime},
+			}, nil),
+		suite.historyOps.EXPECT().Delete(gomock.Any(), "host1", expired).
+			Return(nil),
+	)
+
+	archived, pruned, err := suite.history.Archive(
+		context.Background(),
+		[]string{"host1"},
+		24*time.Hour,
+		7*24*time.Hour)
+	suite.NoError(err)
+	suite.Equal(1, archived)
+	suite.Equal(1, pruned)
+}
+
+// TestArchiveNoRetention tests that the history is not pruned
+// without a retention
+func (suite *MaintenanceHistoryTestSuite) TestArchiveNoRetention() {
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").Return(nil, nil)
+
+	archived, pruned, err := suite.history.Archive(
+		context.Background(), []string{"host1"}, 24*time.Hour, 0)
+	suite.NoError(err)
+	suite.Zero(archived)
+	suite.Zero(pruned)
+}
+
+// TestArchiveStorageError tests that a transition is kept in the recent
+// events if it cannot be archived, and the other hosts are still archived
+func (suite *MaintenanceHistoryTestSuite) TestArchiveStorageError() {
+	old := &ormobjects.HostMaintenanceEventObject{
+		Hostname:  "host1",
+		EventTime: time.Now().UTC().Add(-48 * time.Hour),
+	}
+
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host1").
+		Return([]*ormobjects.HostMaintenanceEventObject{old}, nil)
+	suite.historyOps.EXPECT().Create(gomock.Any(), old).
+		Return(errors.New("create failed"))
+	suite.eventOps.EXPECT().GetAll(gomock.Any(), "host2").
+		Return(nil, nil)
+
+	archived, _, err := suite.history.Archive(
+		context.Background(), []string{"host1", "host2"}, 24*time.Hour, 0)
+	suite.EqualError(err, "create failed")
+	suite.Zero(archived)
+}
@@ -17,12 +17,14 @@ package host
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 
 	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 	"github.com/uber/peloton/pkg/hostmgr/util"
@@ -41,6 +43,15 @@ type AgentMap struct {
 
 	Capacity      scalar.Resources
 	SlackCapacity scalar.Resources
+	// CustomCapacity is the non-revocable capacity of scalar resources
+	// other than cpus, mem, disk and gpus, keyed by resource name.
+	CustomCapacity map[string]float64
+	// GPUHosts is the number of registered agents with GPUs.
+	GPUHosts int
+
+	// RefreshTime is the time of the last full reload from Mesos master.
+	// Agents added or removed since then are applied as deltas.
+	RefreshTime time.Time
 }
 
 // ReportCapacityMetrics into given metric scope.
@@ -51,6 +62,26 @@ func (a *AgentMap) ReportCapacityMetrics(scope tally.Scope) {
 	scope.Gauge(common.MesosGPU).Update(a.Capacity.GetGPU())
 	scope.Gauge("cpus_revocable").Update(a.SlackCapacity.GetCPU())
 	scope.Gauge("registered_hosts").Update(float64(len(a.RegisteredAgents)))
+	scope.Gauge("gpu_hosts").Update(float64(a.GPUHosts))
+	for name, value := range a.CustomCapacity {
+		scope.Tagged(map[string]string{"resource": name}).
+			Gauge("custom_scalar_capacity").Update(value)
+	}
+}
+
+// addAgentCapacity adds (or, if sign is negative, removes) the custom
+// scalar capacity and GPU host count of agent to the agent map.
+func (a *AgentMap) addAgentCapacity(
+	agent *mesos_master.Response_GetAgents_Agent,
+	sign float64) {
+	_, nonRevocable := scalar.FilterRevocableMesosResources(
+		agent.GetTotalResources())
+	for name, value := range scalar.CustomScalarsFromMesosResources(nonRevocable) {
+		a.CustomCapacity[name] += sign * value
+	}
+	if scalar.FromMesosResources(nonRevocable).HasGPU() {
+		a.GPUHosts += int(sign)
+	}
 }
 
 // Atomic pointer to singleton instance.
@@ -81,6 +112,14 @@ func GetAgentMap() *AgentMap {
 	return v
 }
 
+// AgentEventHandler applies agent added and removed events from Mesos
+// master to the agent map between two full reloads.
+type AgentEventHandler interface {
+	// HandleMasterEvent applies an AGENT_ADDED or AGENT_REMOVED event to
+	// the agent map. Other event types are ignored.
+	HandleMasterEvent(event *mesos_master.Event)
+}
+
 // Loader loads hostmap from Mesos and stores in global singleton.
 type Loader struct {
 	sync.Mutex
@@ -88,20 +127,44 @@ type Loader struct {
 	MaintenanceHostInfoMap MaintenanceHostInfoMap
 	SlackResourceTypes     []string
 	Scope                  tally.Scope
+	// AttributeWatcher is notified of the agents whose attributes changed
+	// between two loads. It is optional.
+	AttributeWatcher AttributeWatcher
+	// EventBus is where the agents added to and removed from the agent
+	// map are published. It is optional.
+	EventBus eventbus.Bus
+	// DropUpdate tells whether to drop an update of the agent map, to
+	// inject faults in tests. It is optional.
+	DropUpdate func() bool
+
+	// lastRefresh is the time of the last successful full reload.
+	lastRefresh time.Time
+	// lookups are the times the hosts of offers from agents missing in
+	// the agent map were last looked up, since the last full reload.
+	lookups map[string]time.Time
 }
 
+// _unknownAgentLookupInterval is the min interval between two lookups
+// of the agent of a host sending offers while missing in the agent map.
+const _unknownAgentLookupInterval = time.Minute
+
 // Load hostmap into singleton.
 func (loader *Loader) Load(_ *uatomic.Bool) {
 	agents, err := loader.OperatorClient.Agents()
 	if err != nil {
 		log.WithError(err).Warn("Cannot refresh agent map from master")
+		loader.Scope.Counter("refresh_fail").Inc(1)
+		loader.reportStaleness(time.Now())
 		return
 	}
 
+	now := time.Now()
 	m := &AgentMap{
 		RegisteredAgents: make(map[string]*mesos_master.Response_GetAgents_Agent),
 		Capacity:         scalar.Resources{},
 		SlackCapacity:    scalar.Resources{},
+		CustomCapacity:   make(map[string]float64),
+		RefreshTime:      now,
 	}
 
 	outchan := make(chan func() (scalar.Resources, scalar.Resources))
@@ -113,6 +176,7 @@ func (loader *Loader) Load(_ *uatomic.Bool) {
 			continue
 		}
 		m.RegisteredAgents[hostname] = agent
+		m.addAgentCapacity(agent, 1)
 		count++
 		go getResourcesByType(
 			agent.GetTotalResources(),
@@ -126,8 +190,276 @@ func (loader *Loader) Load(_ *uatomic.Bool) {
 		m.Capacity = m.Capacity.Add(nonRevocable)
 	}
 
+	if loader.dropUpdate() {
+		return
+	}
+
+	agentMapUpdateLock.Lock()
+	previous := GetAgentMap()
+	agentInfoMap.Store(m)
+	agentMapUpdateLock.Unlock()
+
+	loader.Lock()
+	loader.lastRefresh = now
+	loader.lookups = nil
+	loader.Unlock()
+
+	loader.Scope.Counter("refresh").Inc(1)
+	loader.reportStaleness(now)
+	m.ReportCapacityMetrics(loader.Scope)
+	if loader.AttributeWatcher != nil {
+		loader.AttributeWatcher.ObserveAgentMap(previous, m)
+	}
+	loader.publishAgentChurn(previous, m)
+}
+
+// publishAgentChurn publishes the agents added and removed between two
+// loads of the agent map. Nothing is published for the first load.
+func (loader *Loader) publishAgentChurn(previous *AgentMap, current *AgentMap) {
+	if loader.EventBus == nil || previous == nil {
+		return
+	}
+
+	for hostname, agent := range current.RegisteredAgents {
+		if _, ok := previous.RegisteredAgents[hostname]; ok {
+			continue
+		}
+		loader.EventBus.Publish(&eventbus.AgentAddedEvent{
+			Hostname: hostname,
+			Agent:    agent,
+		})
+	}
+	for hostname, agent := range previous.RegisteredAgents {
+		if _, ok := current.RegisteredAgents[hostname]; ok {
+			continue
+		}
+		loader.EventBus.Publish(&eventbus.AgentRemovedEvent{
+			Hostname: hostname,
+			AgentID:  agent.GetAgentInfo().GetId(),
+		})
+	}
+}
+
+// HandleMasterEvent applies an AGENT_ADDED or AGENT_REMOVED event from
+// Mesos master to the agent map without waiting for the next full reload.
+func (loader *Loader) HandleMasterEvent(event *mesos_master.Event) {
+	switch event.GetType() {
+	case mesos_master.Event_AGENT_ADDED:
+		loader.AgentAdded(event.GetAgentAdded().GetAgent())
+	case mesos_master.Event_AGENT_REMOVED:
+		loader.AgentRemoved(event.GetAgentRemoved().GetAgentId())
+	}
+}
+
+// AddUnknownAgents adds the agents of offers which are missing in the
+// agent map, i.e. registered since the last full reload, looking them up
+// in Mesos master. A host is looked up at most once per
+// _unknownAgentLookupInterval, as the hosts kept out of the agent map,
+// e.g. DRAINING hosts, keep sending offers.
+func (loader *Loader) AddUnknownAgents(offers []*mesos.Offer) {
+	m := GetAgentMap()
+	if m == nil {
+		// The first full reload adds all the agents
+		return
+	}
+
+	now := time.Now()
+	unknown := make(map[string]bool)
+	loader.Lock()
+	for _, offer := range offers {
+		hostname := offer.GetHostname()
+		if _, ok := m.RegisteredAgents[hostname]; ok || unknown[hostname] {
+			continue
+		}
+		if last, ok := loader.lookups[hostname]; ok &&
+			now.Sub(last) < _unknownAgentLookupInterval {
+			continue
+		}
+		if loader.lookups == nil {
+			loader.lookups = make(map[string]time.Time)
+		}
+		loader.lookups[hostname] = now
+		unknown[hostname] = true
+	}
+	loader.Unlock()
+	if len(unknown) == 0 {
+		return
+	}
+
+	agents, err := loader.OperatorClient.Agents()
+	if err != nil {
+		log.WithError(err).
+			Warn("Cannot look up the agents of offers missing in agent map")
+		loader.Scope.Counter("unknown_agents_lookup_fail").Inc(1)
+		return
+	}
+	for _, agent := range agents.GetAgents() {
+		if unknown[agent.GetAgentInfo().GetHostname()] {
+			loader.AgentAdded(agent)
+		}
+	}
+}
+
+// SubscribeUnknownAgents adds the agents of the offers received from
+// agents missing in the agent map, without waiting for the next full
+// reload. Offers are dropped rather than the offer path being slowed
+// down if the lookups fall behind.
+func SubscribeUnknownAgents(
+	eventBus eventbus.Bus,
+	loader *Loader) (eventbus.Subscription, error) {
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name:   "unknown_agents",
+		Topics: []eventbus.Topic{eventbus.OffersReceived},
+		Policy: eventbus.Drop,
+		Handler: func(event eventbus.Event) {
+			loader.AddUnknownAgents(event.(*eventbus.OffersReceivedEvent).Offers)
+		},
+	})
+}
+
+// AgentAdded adds or replaces a single agent in the agent map.
+// Agents on DRAINING hosts are skipped, as in a full reload.
+func (loader *Loader) AgentAdded(agent *mesos_master.Response_GetAgents_Agent) {
+	hostname := agent.GetAgentInfo().GetHostname()
+	if hostname == "" {
+		return
+	}
+	if len(loader.MaintenanceHostInfoMap.GetDrainingHostInfos([]string{hostname})) != 0 {
+		return
+	}
+
+	updated := loader.updateAgentMap(func(m *AgentMap) bool {
+		if existing, ok := m.RegisteredAgents[hostname]; ok {
+			loader.subtractCapacity(m, existing)
+		}
+		m.RegisteredAgents[hostname] = agent
+		m.addAgentCapacity(agent, 1)
+		revocable, nonRevocable := splitResourcesByType(
+			agent.GetTotalResources(),
+			loader.SlackResourceTypes)
+		m.SlackCapacity = m.SlackCapacity.Add(revocable)
+		m.Capacity = m.Capacity.Add(nonRevocable)
+		return true
+	})
+	if !updated {
+		return
+	}
+
+	log.WithField("hostname", hostname).Info("Agent added to agent map")
+	loader.Scope.Counter("agents_added").Inc(1)
+	if loader.EventBus != nil {
+		loader.EventBus.Publish(&eventbus.AgentAddedEvent{
+			Hostname: hostname,
+			Agent:    agent,
+		})
+	}
+}
+
+// AgentRemoved removes the agent with the given id from the agent map.
+func (loader *Loader) AgentRemoved(agentID *mesos.AgentID) {
+	if agentID.GetValue() == "" {
+		return
+	}
+
+	var hostname string
+	removed := loader.updateAgentMap(func(m *AgentMap) bool {
+		for name, agent := range m.RegisteredAgents {
+			if agent.GetAgentInfo().GetId().GetValue() != agentID.GetValue() {
+				continue
+			}
+			hostname = name
+			loader.subtractCapacity(m, agent)
+			delete(m.RegisteredAgents, name)
+			return true
+		}
+		return false
+	})
+	if !removed {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"hostname": hostname,
+		"agent_id": agentID.GetValue(),
+	}).Info("Agent removed from agent map")
+	loader.Scope.Counter("agents_removed").Inc(1)
+	if loader.EventBus != nil {
+		loader.EventBus.Publish(&eventbus.AgentRemovedEvent{
+			Hostname: hostname,
+			AgentID:  agentID,
+		})
+	}
+}
+
+// updateAgentMap applies fn to a copy of the current agent map and stores
+// the copy if fn reports a change.
+func (loader *Loader) updateAgentMap(fn func(m *AgentMap) bool) bool {
+	agentMapUpdateLock.Lock()
+	previous := GetAgentMap()
+	m := &AgentMap{
+		RegisteredAgents: make(map[string]*mesos_master.Response_GetAgents_Agent),
+		CustomCapacity:   make(map[string]float64),
+	}
+	if previous != nil {
+		for hostname, agent := range previous.RegisteredAgents {
+			m.RegisteredAgents[hostname] = agent
+		}
+		for name, value := range previous.CustomCapacity {
+			m.CustomCapacity[name] = value
+		}
+		m.Capacity = previous.Capacity
+		m.SlackCapacity = previous.SlackCapacity
+		m.GPUHosts = previous.GPUHosts
+		m.RefreshTime = previous.RefreshTime
+	}
+	if !fn(m) || loader.dropUpdate() {
+		agentMapUpdateLock.Unlock()
+		return false
+	}
 	agentInfoMap.Store(m)
+	agentMapUpdateLock.Unlock()
+
 	m.ReportCapacityMetrics(loader.Scope)
+	if loader.AttributeWatcher != nil {
+		loader.AttributeWatcher.ObserveAgentMap(previous, m)
+	}
+	return true
+}
+
+// dropUpdate returns whether to drop an update of the agent map.
+func (loader *Loader) dropUpdate() bool {
+	if loader.DropUpdate == nil || !loader.DropUpdate() {
+		return false
+	}
+	log.Warn("Dropped update of agent map")
+	loader.Scope.Counter("update_dropped").Inc(1)
+	return true
+}
+
+// subtractCapacity removes the capacity of agent from the agent map.
+func (loader *Loader) subtractCapacity(
+	m *AgentMap,
+	agent *mesos_master.Response_GetAgents_Agent) {
+	revocable, nonRevocable := splitResourcesByType(
+		agent.GetTotalResources(),
+		loader.SlackResourceTypes)
+	m.SlackCapacity = m.SlackCapacity.Subtract(revocable)
+	m.Capacity = m.Capacity.Subtract(nonRevocable)
+	m.addAgentCapacity(agent, -1)
+}
+
+// reportStaleness reports the time elapsed since the last successful full
+// reload of the agent map.
+func (loader *Loader) reportStaleness(now time.Time) {
+	loader.Lock()
+	lastRefresh := loader.lastRefresh
+	loader.Unlock()
+
+	if lastRefresh.IsZero() {
+		return
+	}
+	loader.Scope.Gauge("staleness_seconds").
+		Update(now.Sub(lastRefresh).Seconds())
 }
 
 // getResourcesByType returns supported revocable
@@ -136,6 +468,21 @@ func getResourcesByType(
 	agentResources []*mesos.Resource,
 	outchan chan func() (scalar.Resources, scalar.Resources),
 	slackResourceTypes []string) {
+	revocable, nonRevocable := splitResourcesByType(
+		agentResources,
+		slackResourceTypes)
+	outchan <- (func() (
+		scalar.Resources,
+		scalar.Resources) {
+		return revocable, nonRevocable
+	})
+}
+
+// splitResourcesByType splits the supported physical resources of an
+// agent into revocable and non-revocable resources.
+func splitResourcesByType(
+	agentResources []*mesos.Resource,
+	slackResourceTypes []string) (scalar.Resources, scalar.Resources) {
 	agentRes, _ := scalar.FilterMesosResources(
 		agentResources,
 		func(r *mesos.Resource) bool {
@@ -146,13 +493,7 @@ func getResourcesByType(
 		})
 
 	revRes, nonrevRes := scalar.FilterRevocableMesosResources(agentRes)
-	revocable := scalar.FromMesosResources(revRes)
-	nonRevocable := scalar.FromMesosResources(nonrevRes)
-	outchan <- (func() (
-		scalar.Resources,
-		scalar.Resources) {
-		return revocable, nonRevocable
-	})
+	return scalar.FromMesosResources(revRes), scalar.FromMesosResources(nonrevRes)
 }
 
 // MaintenanceHostInfoMap defines an interface of a map of
@@ -256,6 +597,7 @@ func (m *maintenanceHostInfoMap) AddHostInfos(
 		}
 	}
 
+	m.publishStates()
 	m.metrics.DrainingHosts.Update(float64(len(m.drainingHosts)))
 	m.metrics.DownHosts.Update(float64(len(m.downHosts)))
 }
@@ -271,6 +613,7 @@ func (m *maintenanceHostInfoMap) RemoveHostInfos(hosts []string) {
 		delete(m.downHosts, host)
 	}
 
+	m.publishStates()
 	m.metrics.DrainingHosts.Update(float64(len(m.drainingHosts)))
 	m.metrics.DownHosts.Update(float64(len(m.downHosts)))
 }
@@ -323,32 +666,55 @@ func (m *maintenanceHostInfoMap) UpdateHostState(
 		m.downHosts[hostname] = hostInfo
 	}
 
+	m.publishStates()
 	m.metrics.DrainingHosts.Update(float64(len(m.drainingHosts)))
 	m.metrics.DownHosts.Update(float64(len(m.downHosts)))
 	return nil
 }
 
+// ClearAndFillMap clears the content of the map and fills the map with the
+// given host infos. The drain options of the hosts which are still DRAINING
+// are kept, since they are not known to Mesos Master, as well as the agent
+// ids of the hosts which are still in maintenance.
 func (m *maintenanceHostInfoMap) ClearAndFillMap(hostInfos []*host.HostInfo) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	for hostname := range m.drainingHosts {
+	drainOptions := make(map[string]*host.DrainOptions)
+	agentIDs := make(map[string]string)
+	for hostname, hostInfo := range m.drainingHosts {
+		if hostInfo.GetDrainOptions() != nil {
+			drainOptions[hostname] = hostInfo.GetDrainOptions()
+		}
+		if hostInfo.GetAgentId() != "" {
+			agentIDs[hostname] = hostInfo.GetAgentId()
+		}
 		delete(m.drainingHosts, hostname)
 	}
 
-	for hostname := range m.downHosts {
+	for hostname, hostInfo := range m.downHosts {
+		if hostInfo.GetAgentId() != "" {
+			agentIDs[hostname] = hostInfo.GetAgentId()
+		}
 		delete(m.downHosts, hostname)
 	}
 
 	for _, hostInfo := range hostInfos {
+		if hostInfo.GetAgentId() == "" {
+			hostInfo.AgentId = agentIDs[hostInfo.GetHostname()]
+		}
 		switch hostInfo.State {
 		case host.HostState_HOST_STATE_DRAINING:
+			if hostInfo.GetDrainOptions() == nil {
+				hostInfo.DrainOptions = drainOptions[hostInfo.GetHostname()]
+			}
 			m.drainingHosts[hostInfo.GetHostname()] = hostInfo
 		case host.HostState_HOST_STATE_DOWN:
 			m.downHosts[hostInfo.GetHostname()] = hostInfo
 		}
 	}
 
+	m.publishStates()
 	m.metrics.DrainingHosts.Update(float64(len(m.drainingHosts)))
 	m.metrics.DownHosts.Update(float64(len(m.downHosts)))
 }
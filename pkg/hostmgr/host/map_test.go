@@ -25,6 +25,8 @@ import (
 
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ebmocks "github.com/uber/peloton/pkg/hostmgr/eventbus/mocks"
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
 	mock_mpb "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 
@@ -161,6 +163,248 @@ func (suite *HostMapTestSuite) TestRefresh() {
 	suite.Equal(float64(numRegisteredAgents*_defaultResourceValue), gauges["disk+"].Value())
 	suite.Contains(gauges, "gpus+")
 	suite.Equal(float64(numRegisteredAgents*_defaultResourceValue), gauges["gpus+"].Value())
+	suite.Contains(gauges, "gpu_hosts+")
+	suite.Equal(float64(numRegisteredAgents), gauges["gpu_hosts+"].Value())
+}
+
+// TestAgentDeltaUpdates tests applying agent added and removed events
+// to the agent map between two full reloads.
+func (suite *HostMapTestSuite) TestAgentDeltaUpdates() {
+	defer suite.ctrl.Finish()
+
+	mockMaintenanceMap := hm.NewMockMaintenanceHostInfoMap(suite.ctrl)
+	loader := &Loader{
+		OperatorClient:         suite.operatorClient,
+		Scope:                  suite.testScope,
+		SlackResourceTypes:     []string{common.MesosCPU},
+		MaintenanceHostInfoMap: mockMaintenanceMap,
+	}
+
+	response := makeAgentsResponse(3)
+	for i, agent := range response.GetAgents() {
+		agentID := fmt.Sprintf("agent-%d", i)
+		agent.AgentInfo.Id = &mesos.AgentID{Value: &agentID}
+	}
+	added := response.Agents[2]
+	added.TotalResources = append(added.TotalResources,
+		util.NewMesosResourceBuilder().
+			WithName("fpgas").
+			WithValue(2.0).
+			Build())
+	response.Agents = response.Agents[:2]
+
+	mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*host.HostInfo{}).AnyTimes()
+	suite.operatorClient.EXPECT().Agents().Return(response, nil)
+	loader.Load(nil)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+	refreshTime := GetAgentMap().RefreshTime
+	suite.False(refreshTime.IsZero())
+
+	addedType := mesos_master.Event_AGENT_ADDED
+	loader.HandleMasterEvent(&mesos_master.Event{
+		Type:       &addedType,
+		AgentAdded: &mesos_master.Event_AgentAdded{Agent: added},
+	})
+	m := GetAgentMap()
+	suite.Len(m.RegisteredAgents, 3)
+	suite.Equal(refreshTime, m.RefreshTime)
+	suite.Equal(float64(3*_defaultResourceValue), m.Capacity.GetCPU())
+	suite.Equal(float64(3*_defaultResourceValue), m.SlackCapacity.GetCPU())
+	suite.Equal(3, m.GPUHosts)
+	suite.Equal(2.0, m.CustomCapacity["fpgas"])
+
+	// Adding the same agent again does not double count its capacity.
+	loader.AgentAdded(added)
+	suite.Equal(float64(3*_defaultResourceValue), GetAgentMap().Capacity.GetCPU())
+	suite.Equal(2.0, GetAgentMap().CustomCapacity["fpgas"])
+
+	removedType := mesos_master.Event_AGENT_REMOVED
+	loader.HandleMasterEvent(&mesos_master.Event{
+		Type: &removedType,
+		AgentRemoved: &mesos_master.Event_AgentRemoved{
+			AgentId: response.Agents[0].GetAgentInfo().GetId(),
+		},
+	})
+	m = GetAgentMap()
+	suite.Len(m.RegisteredAgents, 2)
+	suite.Nil(GetAgentInfo(response.Agents[0].GetAgentInfo().GetHostname()))
+	suite.Equal(float64(2*_defaultResourceValue), m.Capacity.GetCPU())
+	suite.Equal(float64(2*_defaultResourceValue), m.SlackCapacity.GetCPU())
+
+	// Removing an unknown agent is a no-op.
+	unknownID := "unknown"
+	loader.AgentRemoved(&mesos.AgentID{Value: &unknownID})
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+
+	counters := suite.testScope.Snapshot().Counters()
+	suite.Equal(int64(2), counters["agents_added+"].Value())
+	suite.Equal(int64(1), counters["agents_removed+"].Value())
+
+	// A failed reload reports the staleness of the agent map.
+	suite.operatorClient.EXPECT().Agents().
+		Return(nil, errors.New("unable to get agents"))
+	loader.Load(nil)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+	suite.Equal(int64(1), suite.testScope.Snapshot().Counters()["refresh_fail+"].Value())
+	suite.Contains(suite.testScope.Snapshot().Gauges(), "staleness_seconds+")
+}
+
+// TestAddUnknownAgents tests adding the agents of offers missing in the
+// agent map between two full reloads.
+func (suite *HostMapTestSuite) TestAddUnknownAgents() {
+	defer suite.ctrl.Finish()
+
+	mockMaintenanceMap := hm.NewMockMaintenanceHostInfoMap(suite.ctrl)
+	loader := &Loader{
+		OperatorClient:         suite.operatorClient,
+		Scope:                  suite.testScope,
+		MaintenanceHostInfoMap: mockMaintenanceMap,
+	}
+
+	response := makeAgentsResponse(3)
+	mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*host.HostInfo{}).AnyTimes()
+	suite.operatorClient.EXPECT().
+		Agents().
+		Return(&mesos_master.Response_GetAgents{
+			Agents: response.Agents[:2],
+		}, nil)
+	loader.Load(nil)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+
+	offer := func(agent *mesos_master.Response_GetAgents_Agent) *mesos.Offer {
+		hostname := agent.GetAgentInfo().GetHostname()
+		return &mesos.Offer{Hostname: &hostname}
+	}
+	unregistered := "unregistered"
+	offers := []*mesos.Offer{
+		offer(response.Agents[0]),
+		offer(response.Agents[2]),
+		offer(response.Agents[2]),
+		{Hostname: &unregistered},
+	}
+
+	// Only the hosts missing in the agent map are looked up, once
+	suite.operatorClient.EXPECT().Agents().Return(response, nil)
+	loader.AddUnknownAgents(offers)
+	suite.Len(GetAgentMap().RegisteredAgents, 3)
+	suite.NotNil(GetAgentInfo(response.Agents[2].GetAgentInfo().GetHostname()))
+
+	// A host still missing in the agent map is not looked up again
+	// right away
+	loader.AddUnknownAgents(offers)
+	suite.Len(GetAgentMap().RegisteredAgents, 3)
+	suite.Equal(
+		int64(1),
+		suite.testScope.Snapshot().Counters()["agents_added+"].Value())
+}
+
+// TestDropUpdate tests that the dropped updates of the agent map are not
+// applied.
+func (suite *HostMapTestSuite) TestDropUpdate() {
+	defer suite.ctrl.Finish()
+
+	drop := false
+	mockMaintenanceMap := hm.NewMockMaintenanceHostInfoMap(suite.ctrl)
+	loader := &Loader{
+		OperatorClient:         suite.operatorClient,
+		Scope:                  suite.testScope,
+		MaintenanceHostInfoMap: mockMaintenanceMap,
+		DropUpdate:             func() bool { return drop },
+	}
+
+	response := makeAgentsResponse(3)
+	added := response.Agents[2]
+	response.Agents = response.Agents[:2]
+	mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*host.HostInfo{}).AnyTimes()
+	suite.operatorClient.EXPECT().Agents().Return(response, nil).Times(2)
+	loader.Load(nil)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+
+	drop = true
+	loader.AgentAdded(added)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+	suite.Nil(GetAgentInfo(added.GetAgentInfo().GetHostname()))
+
+	response.Agents = response.Agents[:1]
+	loader.Load(nil)
+	suite.Len(GetAgentMap().RegisteredAgents, 2)
+
+	counters := suite.testScope.Snapshot().Counters()
+	suite.Equal(int64(2), counters["update_dropped+"].Value())
+	suite.Nil(counters["agents_added+"])
+}
+
+// TestAgentChurnEvents tests that the agents added to and removed from
+// the agent map are published on the event bus.
+func (suite *HostMapTestSuite) TestAgentChurnEvents() {
+	defer suite.ctrl.Finish()
+
+	mockMaintenanceMap := hm.NewMockMaintenanceHostInfoMap(suite.ctrl)
+	mockEventBus := ebmocks.NewMockBus(suite.ctrl)
+	loader := &Loader{
+		OperatorClient:         suite.operatorClient,
+		Scope:                  suite.testScope,
+		MaintenanceHostInfoMap: mockMaintenanceMap,
+		EventBus:               mockEventBus,
+	}
+
+	response := makeAgentsResponse(2)
+	for i, agent := range response.GetAgents() {
+		agentID := fmt.Sprintf("agent-%d", i)
+		agent.AgentInfo.Id = &mesos.AgentID{Value: &agentID}
+	}
+	goneHostname := "gone"
+	goneID := "agent-gone"
+	gone := &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{
+			Hostname: &goneHostname,
+			Id:       &mesos.AgentID{Value: &goneID},
+		},
+	}
+	agentInfoMap.Store(&AgentMap{
+		RegisteredAgents: map[string]*mesos_master.Response_GetAgents_Agent{
+			goneHostname: gone,
+		},
+	})
+
+	mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*host.HostInfo{}).AnyTimes()
+	suite.operatorClient.EXPECT().Agents().Return(response, nil)
+	for _, agent := range response.GetAgents() {
+		mockEventBus.EXPECT().Publish(&eventbus.AgentAddedEvent{
+			Hostname: agent.GetAgentInfo().GetHostname(),
+			Agent:    agent,
+		})
+	}
+	mockEventBus.EXPECT().Publish(&eventbus.AgentRemovedEvent{
+		Hostname: goneHostname,
+		AgentID:  gone.GetAgentInfo().GetId(),
+	})
+	loader.Load(nil)
+
+	removed := response.Agents[0]
+	mockEventBus.EXPECT().Publish(&eventbus.AgentRemovedEvent{
+		Hostname: removed.GetAgentInfo().GetHostname(),
+		AgentID:  removed.GetAgentInfo().GetId(),
+	})
+	loader.AgentRemoved(removed.GetAgentInfo().GetId())
+
+	mockEventBus.EXPECT().Publish(&eventbus.AgentAddedEvent{
+		Hostname: removed.GetAgentInfo().GetHostname(),
+		Agent:    removed,
+	})
+	loader.AgentAdded(removed)
+
+	// Removing an unknown agent publishes nothing.
+	unknownID := "unknown"
+	loader.AgentRemoved(&mesos.AgentID{Value: &unknownID})
 }
 
 func (suite *HostMapTestSuite) TestMaintenanceHostInfoMap() {
@@ -301,6 +545,86 @@ func (suite *HostMapTestSuite) TestMaintenanceHostInfoMap() {
 	suite.Empty(maintenanceHostInfoMap.GetDrainingHostInfos([]string{}))
 }
 
+// TestClearAndFillMapKeepsDrainOptions tests that the drain options of
+// DRAINING hosts survive the map being refilled from Mesos Master
+func (suite *HostMapTestSuite) TestClearAndFillMapKeepsDrainOptions() {
+	maintenanceHostInfoMap := NewMaintenanceHostInfoMap(tally.NoopScope)
+	drainOptions := &host.DrainOptions{
+		KillGracePeriodSeconds: 60,
+		Message:                "deregister",
+	}
+	maintenanceHostInfoMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname:     "host1",
+			State:        host.HostState_HOST_STATE_DRAINING,
+			DrainOptions: drainOptions,
+		},
+	})
+
+	maintenanceHostInfoMap.ClearAndFillMap([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+		{
+			Hostname: "host2",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+	})
+
+	hostInfos := maintenanceHostInfoMap.GetDrainingHostInfos([]string{"host1"})
+	suite.Len(hostInfos, 1)
+	suite.Equal(drainOptions, hostInfos[0].GetDrainOptions())
+	hostInfos = maintenanceHostInfoMap.GetDrainingHostInfos([]string{"host2"})
+	suite.Len(hostInfos, 1)
+	suite.Nil(hostInfos[0].GetDrainOptions())
+
+	// Drain options are dropped once the host is no longer DRAINING
+	maintenanceHostInfoMap.ClearAndFillMap([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DOWN,
+		},
+	})
+	hostInfos = maintenanceHostInfoMap.GetDownHostInfos([]string{"host1"})
+	suite.Len(hostInfos, 1)
+	suite.Nil(hostInfos[0].GetDrainOptions())
+}
+
+// TestClearAndFillMapKeepsAgentIDs tests that the agent ids of the hosts
+// in maintenance survive the map being refilled from Mesos Master
+func (suite *HostMapTestSuite) TestClearAndFillMapKeepsAgentIDs() {
+	maintenanceHostInfoMap := NewMaintenanceHostInfoMap(tally.NoopScope)
+	maintenanceHostInfoMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+			AgentId:  "agent1",
+		},
+	})
+
+	// The agent id is kept once the host is DOWN
+	maintenanceHostInfoMap.ClearAndFillMap([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DOWN,
+		},
+	})
+	hostInfos := maintenanceHostInfoMap.GetDownHostInfos([]string{"host1"})
+	suite.Len(hostInfos, 1)
+	suite.Equal("agent1", hostInfos[0].GetAgentId())
+
+	maintenanceHostInfoMap.ClearAndFillMap([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DOWN,
+		},
+	})
+	hostInfos = maintenanceHostInfoMap.GetDownHostInfos([]string{"host1"})
+	suite.Len(hostInfos, 1)
+	suite.Equal("agent1", hostInfos[0].GetAgentId())
+}
+
 func TestHostMapTestSuite(t *testing.T) {
 	suite.Run(t, new(HostMapTestSuite))
 }
@@ -50,6 +50,11 @@ func NewMatcher(
 	hostFilter *hostsvc.HostFilter,
 	evaluator constraints.Evaluator,
 	filter filterSlackResources) *Matcher {
+	// The percentage requirements of the constraint are evaluated against
+	// the denominator of the filter, the instance count of the job.
+	if d := hostFilter.GetConstraintDenominator(); d > 0 {
+		evaluator = constraints.WithDenominator(evaluator, d)
+	}
 	return &Matcher{
 		hostFilter: hostFilter,
 		evaluator:  evaluator,
@@ -101,17 +106,16 @@ func (m *Matcher) matchHostFilter(
 	hc := c.GetSchedulingConstraint()
 	agent := agentMap.RegisteredAgents[hostname].GetAgentInfo()
 
-	// If constraints don't specify an exclusive host, then reject
-	// hosts that are designated as exclusive
-	if constraints.IsNonExclusiveConstraint(hc) &&
-		util.HasExclusiveAttribute(agent.GetAttributes()) {
+	// Reject hosts designated as exclusive, unless the constraints
+	// require one of their exclusive values
+	if !util.MatchExclusiveAttribute(hc, agent.GetAttributes()) {
 		log.WithField("hostname", hostname).Debug("Skipped exclusive host")
 		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS
 	}
 
 	// tries to get the constraints from the host filter
 	if hc != nil {
-		lv := constraints.GetHostLabelValues(
+		lv := GetHostLabelValues(
 			hostname,
 			agent.GetAttributes(),
 		)
@@ -324,7 +324,7 @@ func (suite *MatcherTestSuite) TestHostConstraints() {
 			},
 		}
 		agentInfo := suite.response.GetAgents()[0].AgentInfo
-		lv := constraints.GetHostLabelValues(agentInfo.GetHostname(), agentInfo.GetAttributes())
+		lv := GetHostLabelValues(agentInfo.GetHostname(), agentInfo.GetAttributes())
 		mockEvaluator.
 			EXPECT().
 			Evaluate(
@@ -498,34 +498,40 @@ func (suite *MatcherTestSuite) TestMatchHostsFilterExclusiveHosts() {
 	loader.Load(nil)
 
 	testTable := []struct {
-		msg            string
-		agentIndex     int
-		exclConstraint bool
-		expected       hostsvc.HostFilterResult
+		msg        string
+		agentIndex int
+		// exclValue is the exclusive value required by the constraint,
+		// none if empty
+		exclValue string
+		expected  hostsvc.HostFilterResult
 	}{
 		{
-			msg:            "excl host, excl constraint -> match",
-			agentIndex:     0,
-			exclConstraint: true,
-			expected:       hostsvc.HostFilterResult_MATCH,
+			msg:        "excl host, excl constraint -> match",
+			agentIndex: 0,
+			exclValue:  exclAttrValue,
+			expected:   hostsvc.HostFilterResult_MATCH,
+		},
+		{
+			msg:        "excl host, other excl constraint -> mismatch",
+			agentIndex: 0,
+			exclValue:  "storage",
+			expected:   hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
 		},
 		{
-			msg:            "excl host, non-excl constraint -> mismatch",
-			agentIndex:     0,
-			exclConstraint: false,
-			expected:       hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			msg:        "excl host, non-excl constraint -> mismatch",
+			agentIndex: 0,
+			expected:   hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
 		},
 		{
-			msg:            "non-excl host, excl constraint -> mismatch",
-			agentIndex:     1,
-			exclConstraint: true,
-			expected:       hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			msg:        "non-excl host, excl constraint -> mismatch",
+			agentIndex: 1,
+			exclValue:  exclAttrValue,
+			expected:   hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
 		},
 		{
-			msg:            "non-excl host, non-excl constraint -> match",
-			agentIndex:     1,
-			exclConstraint: false,
-			expected:       hostsvc.HostFilterResult_MATCH,
+			msg:        "non-excl host, non-excl constraint -> match",
+			agentIndex: 1,
+			expected:   hostsvc.HostFilterResult_MATCH,
 		},
 	}
 
@@ -539,14 +545,14 @@ func (suite *MatcherTestSuite) TestMatchHostsFilterExclusiveHosts() {
 				Minimum: &task.ResourceConfig{},
 			},
 		}
-		if tt.exclConstraint {
+		if tt.exclValue != "" {
 			filter.SchedulingConstraint = &task.Constraint{
 				Type: task.Constraint_LABEL_CONSTRAINT,
 				LabelConstraint: &task.LabelConstraint{
 					Kind: task.LabelConstraint_HOST,
 					Label: &peloton.Label{
 						Key:   "peloton/exclusive",
-						Value: "web-tier",
+						Value: tt.exclValue,
 					},
 					Condition:   task.LabelConstraint_CONDITION_EQUAL,
 					Requirement: 1,
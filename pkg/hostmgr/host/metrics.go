@@ -24,6 +24,15 @@ type Metrics struct {
 
 	DrainingHosts tally.Gauge
 	DownHosts     tally.Gauge
+
+	AttributeChanges          tally.Counter
+	ExclusiveAttributeChanges tally.Counter
+
+	ReconcileSuccess         tally.Counter
+	ReconcileFail            tally.Counter
+	ReconcileMissingHosts    tally.Counter
+	ReconcileStaleHosts      tally.Counter
+	ReconcileStateMismatches tally.Counter
 }
 
 // NewMetrics returns a new Metrics struct, with all metrics
@@ -34,5 +43,14 @@ func NewMetrics(scope tally.Scope) *Metrics {
 
 		DrainingHosts: scope.Gauge("draining_hosts"),
 		DownHosts:     scope.Gauge("down_hosts"),
+
+		AttributeChanges:          scope.Counter("attribute_changes"),
+		ExclusiveAttributeChanges: scope.Counter("exclusive_attribute_changes"),
+
+		ReconcileSuccess:         scope.Counter("reconcile_success"),
+		ReconcileFail:            scope.Counter("reconcile_fail"),
+		ReconcileMissingHosts:    scope.Counter("reconcile_missing_hosts"),
+		ReconcileStaleHosts:      scope.Counter("reconcile_stale_hosts"),
+		ReconcileStateMismatches: scope.Counter("reconcile_state_mismatches"),
 	}
 }
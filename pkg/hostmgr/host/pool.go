@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"sync/atomic"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+)
+
+// DefaultHostPool is the pool of the hosts which do not have
+// the host pool attribute.
+const DefaultHostPool = "default"
+
+// Name of the agent attribute holding the host pool, shared by the
+// placement path which has no access to the host manager config.
+var hostPoolAttribute atomic.Value
+
+// SetHostPoolAttribute sets the name of the agent attribute
+// holding the host pool.
+func SetHostPoolAttribute(name string) {
+	hostPoolAttribute.Store(name)
+}
+
+// GetHostPool returns the host pool of an agent from the attribute
+// set by SetHostPoolAttribute.
+func GetHostPool(attributes []*mesos.Attribute) string {
+	name, _ := hostPoolAttribute.Load().(string)
+	return GetHostPoolByAttribute(attributes, name)
+}
+
+// GetHostPoolByHostname returns the host pool of a registered agent,
+// the assigned one if any.
+func GetHostPoolByHostname(hostname string) string {
+	return GetAssignedHostPool(
+		hostname,
+		GetAgentInfo(hostname).GetAttributes())
+}
+
+// GetHostPoolByAttribute returns the value of the text attribute with
+// the given name, or DefaultHostPool if the agent does not have it.
+func GetHostPoolByAttribute(
+	attributes []*mesos.Attribute,
+	name string) string {
+	if name == "" {
+		return DefaultHostPool
+	}
+	for _, attribute := range attributes {
+		if attribute.GetName() == name &&
+			attribute.GetText().GetValue() != "" {
+			return attribute.GetText().GetValue()
+		}
+	}
+	return DefaultHostPool
+}
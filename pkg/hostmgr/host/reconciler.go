@@ -0,0 +1,363 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// MaintenanceReconciler repairs the MaintenanceHostInfoMap from the
+// maintenance schedule and machine statuses of Mesos Master, which
+// commonly drift apart after host manager failovers.
+type MaintenanceReconciler struct {
+	masterOperatorClient   mpb.MasterOperatorClient
+	maintenanceHostInfoMap MaintenanceHostInfoMap
+	metrics                *Metrics
+
+	// eventBus is where hosts whose agents finished draining are
+	// published as DOWN. It is optional.
+	eventBus eventbus.Bus
+}
+
+// NewMaintenanceReconciler returns a new MaintenanceReconciler.
+func NewMaintenanceReconciler(
+	scope tally.Scope,
+	masterOperatorClient mpb.MasterOperatorClient,
+	hostInfoMap MaintenanceHostInfoMap) *MaintenanceReconciler {
+	return &MaintenanceReconciler{
+		masterOperatorClient:   masterOperatorClient,
+		maintenanceHostInfoMap: hostInfoMap,
+		metrics:                NewMetrics(scope.SubScope("maintenance")),
+	}
+}
+
+// Reconcile fetches the maintenance status and schedule from Mesos
+// Master, and refills the MaintenanceHostInfoMap with them: DRAINING
+// and DOWN hosts missing from the map are added, and stale hosts are
+// removed. Hosts in the schedule which the status does not report yet
+// are DRAINING. Hosts whose agents are drained by Mesos Master are
+// DRAINING or DOWN according to the drain state of their agents.
+// Returns the DRAINING hosts which are to be drained by Peloton, without
+// the hosts whose maintenance window has not started yet.
+func (r *MaintenanceReconciler) Reconcile() ([]string, error) {
+	statusResponse, err := r.masterOperatorClient.GetMaintenanceStatus()
+	if err != nil {
+		r.metrics.ReconcileFail.Inc(1)
+		return nil, err
+	}
+	scheduleResponse, err := r.masterOperatorClient.GetMaintenanceSchedule()
+	if err != nil {
+		r.metrics.ReconcileFail.Inc(1)
+		return nil, err
+	}
+
+	currentHostInfos := append(
+		r.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}),
+		r.maintenanceHostInfoMap.GetDownHostInfos([]string{})...)
+
+	agents, err := r.getDrainedAgents(currentHostInfos)
+	if err != nil {
+		r.metrics.ReconcileFail.Inc(1)
+		return nil, err
+	}
+
+	hostInfos := buildMaintenanceHostInfos(statusResponse, scheduleResponse)
+	agentDrainHostInfos := buildAgentDrainHostInfos(
+		agents,
+		hostInfos,
+		currentHostInfos)
+	hostInfos = append(hostInfos, agentDrainHostInfos...)
+
+	r.reportDivergence(currentHostInfos, hostInfos)
+	r.maintenanceHostInfoMap.ClearAndFillMap(hostInfos)
+	r.metrics.ReconcileSuccess.Inc(1)
+	r.publishDrainedAgents(currentHostInfos, agentDrainHostInfos)
+
+	notStarted := notStartedMaintenanceHosts(scheduleResponse, time.Now())
+	var drainingHosts []string
+	for _, hostInfo := range hostInfos {
+		if hostInfo.GetState() == host.HostState_HOST_STATE_DRAINING &&
+			!isAgentDrain(hostInfo) &&
+			!notStarted[hostInfo.GetHostname()] {
+			drainingHosts = append(drainingHosts, hostInfo.GetHostname())
+		}
+	}
+	return drainingHosts, nil
+}
+
+// getDrainedAgents returns the agents registered with Mesos Master if
+// any host is drained by Mesos Master, either according to the
+// MaintenanceHostInfoMap or the agent map. The agents are fetched from
+// Mesos Master since the agent map is only reloaded periodically, and
+// would report the drain state of reactivated agents otherwise.
+func (r *MaintenanceReconciler) getDrainedAgents(
+	currentHostInfos []*host.HostInfo,
+) ([]*mesos_master.Response_GetAgents_Agent, error) {
+	agentDrain := false
+	for _, hostInfo := range currentHostInfos {
+		if isAgentDrain(hostInfo) {
+			agentDrain = true
+			break
+		}
+	}
+	if agentMap := GetAgentMap(); !agentDrain && agentMap != nil {
+		for _, agent := range agentMap.RegisteredAgents {
+			if agent.GetDrainInfo() != nil {
+				agentDrain = true
+				break
+			}
+		}
+	}
+	if !agentDrain {
+		return nil, nil
+	}
+
+	response, err := r.masterOperatorClient.Agents()
+	if err != nil {
+		return nil, err
+	}
+	return response.GetAgents(), nil
+}
+
+// publishDrainedAgents publishes the hosts which were DRAINING and are
+// DOWN now that Mesos Master finished draining their agents.
+func (r *MaintenanceReconciler) publishDrainedAgents(
+	currentHostInfos []*host.HostInfo,
+	agentDrainHostInfos []*host.HostInfo) {
+	if r.eventBus == nil {
+		return
+	}
+
+	draining := make(map[string]bool)
+	for _, hostInfo := range currentHostInfos {
+		if hostInfo.GetState() == host.HostState_HOST_STATE_DRAINING {
+			draining[hostInfo.GetHostname()] = true
+		}
+	}
+	var drained []string
+	for _, hostInfo := range agentDrainHostInfos {
+		if hostInfo.GetState() == host.HostState_HOST_STATE_DOWN &&
+			draining[hostInfo.GetHostname()] {
+			drained = append(drained, hostInfo.GetHostname())
+		}
+	}
+	if len(drained) == 0 {
+		return
+	}
+	log.WithField("hostnames", drained).
+		Info("Agents drained by Mesos Master, hosts are down")
+	r.eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: drained,
+		From:      host.HostState_HOST_STATE_DRAINING,
+		To:        host.HostState_HOST_STATE_DOWN,
+	})
+}
+
+// reportDivergence compares the hosts in maintenance according to Mesos
+// Master with the MaintenanceHostInfoMap, and reports the differences.
+func (r *MaintenanceReconciler) reportDivergence(
+	currentHostInfos []*host.HostInfo,
+	hostInfos []*host.HostInfo) {
+	current := make(map[string]host.HostState)
+	for _, hostInfo := range currentHostInfos {
+		current[hostInfo.GetHostname()] = hostInfo.GetState()
+	}
+
+	var missing, stale, mismatched []string
+	for _, hostInfo := range hostInfos {
+		state, ok := current[hostInfo.GetHostname()]
+		switch {
+		case !ok:
+			missing = append(missing, hostInfo.GetHostname())
+		case state != hostInfo.GetState():
+			mismatched = append(mismatched, hostInfo.GetHostname())
+		}
+		delete(current, hostInfo.GetHostname())
+	}
+	for hostname := range current {
+		stale = append(stale, hostname)
+	}
+
+	r.metrics.ReconcileMissingHosts.Inc(int64(len(missing)))
+	r.metrics.ReconcileStaleHosts.Inc(int64(len(stale)))
+	r.metrics.ReconcileStateMismatches.Inc(int64(len(mismatched)))
+	if len(missing)+len(stale)+len(mismatched) > 0 {
+		log.WithFields(log.Fields{
+			"missing_hosts":    missing,
+			"stale_hosts":      stale,
+			"mismatched_hosts": mismatched,
+		}).Warn("Maintenance state diverged from Mesos Master")
+	}
+}
+
+// MasterMaintenanceHostInfos returns the hosts in maintenance according
+// to Mesos Master: the DRAINING and DOWN hosts of the maintenance status,
+// the hosts of the maintenance schedule, and the hosts whose agents are
+// drained by Mesos Master.
+func MasterMaintenanceHostInfos(
+	statusResponse *mesos_master.Response_GetMaintenanceStatus,
+	scheduleResponse *mesos_master.Response_GetMaintenanceSchedule,
+	agents []*mesos_master.Response_GetAgents_Agent,
+) []*host.HostInfo {
+	hostInfos := buildMaintenanceHostInfos(statusResponse, scheduleResponse)
+	return append(
+		hostInfos,
+		buildAgentDrainHostInfos(agents, hostInfos, nil)...)
+}
+
+// buildMaintenanceHostInfos returns the DRAINING and DOWN hosts of the
+// maintenance status, and the hosts of the maintenance schedule which
+// are not in the status as DRAINING.
+func buildMaintenanceHostInfos(
+	statusResponse *mesos_master.Response_GetMaintenanceStatus,
+	scheduleResponse *mesos_master.Response_GetMaintenanceSchedule,
+) []*host.HostInfo {
+	var hostInfos []*host.HostInfo
+	seen := make(map[string]bool)
+	addHostInfo := func(hostname, ip string, state host.HostState) {
+		if hostname == "" || seen[hostname] {
+			return
+		}
+		seen[hostname] = true
+		hostInfos = append(hostInfos, &host.HostInfo{
+			Hostname: hostname,
+			Ip:       ip,
+			State:    state,
+		})
+	}
+
+	status := statusResponse.GetStatus()
+	for _, drainingMachine := range status.GetDrainingMachines() {
+		machineID := drainingMachine.GetId()
+		addHostInfo(
+			machineID.GetHostname(),
+			machineID.GetIp(),
+			host.HostState_HOST_STATE_DRAINING)
+	}
+	for _, downMachine := range status.GetDownMachines() {
+		addHostInfo(
+			downMachine.GetHostname(),
+			downMachine.GetIp(),
+			host.HostState_HOST_STATE_DOWN)
+	}
+	for _, window := range scheduleResponse.GetSchedule().GetWindows() {
+		for _, machineID := range window.GetMachineIds() {
+			addHostInfo(
+				machineID.GetHostname(),
+				machineID.GetIp(),
+				host.HostState_HOST_STATE_DRAINING)
+		}
+	}
+	return hostInfos
+}
+
+// buildAgentDrainHostInfos returns the hosts whose agents are drained by
+// Mesos Master and are not in maintenance otherwise: DRAINING agents are
+// DRAINING and DRAINED agents are DOWN. The drain options of the hosts
+// are kept from the MaintenanceHostInfoMap if present.
+func buildAgentDrainHostInfos(
+	agents []*mesos_master.Response_GetAgents_Agent,
+	hostInfos []*host.HostInfo,
+	currentHostInfos []*host.HostInfo,
+) []*host.HostInfo {
+	seen := make(map[string]bool)
+	for _, hostInfo := range hostInfos {
+		seen[hostInfo.GetHostname()] = true
+	}
+	drainOptions := make(map[string]*host.DrainOptions)
+	for _, hostInfo := range currentHostInfos {
+		if isAgentDrain(hostInfo) {
+			drainOptions[hostInfo.GetHostname()] = hostInfo.GetDrainOptions()
+		}
+	}
+
+	var agentDrainHostInfos []*host.HostInfo
+	for _, agent := range agents {
+		hostname := agent.GetAgentInfo().GetHostname()
+		if hostname == "" || seen[hostname] || agent.GetDrainInfo() == nil {
+			continue
+		}
+		var state host.HostState
+		switch agent.GetDrainInfo().GetState() {
+		case mesos.DrainState_DRAINING:
+			state = host.HostState_HOST_STATE_DRAINING
+		case mesos.DrainState_DRAINED:
+			state = host.HostState_HOST_STATE_DOWN
+		default:
+			continue
+		}
+		seen[hostname] = true
+
+		options, ok := drainOptions[hostname]
+		if !ok {
+			gracePeriod := agent.GetDrainInfo().GetConfig().
+				GetMaxGracePeriod().GetNanoseconds()
+			options = &host.DrainOptions{
+				KillGracePeriodSeconds: uint32(gracePeriod / 1e9),
+				Method:                 host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			}
+		}
+		ip, _, err := util.ExtractIPAndPortFromMesosAgentPID(agent.GetPid())
+		if err != nil {
+			log.WithError(err).
+				WithField("hostname", hostname).
+				Warn("Failed to parse pid of drained agent")
+		}
+		agentDrainHostInfos = append(agentDrainHostInfos, &host.HostInfo{
+			Hostname:     hostname,
+			Ip:           ip,
+			State:        state,
+			DrainOptions: options,
+		})
+	}
+	return agentDrainHostInfos
+}
+
+// notStartedMaintenanceHosts returns the hosts of the maintenance
+// schedule whose unavailability starts after now.
+func notStartedMaintenanceHosts(
+	scheduleResponse *mesos_master.Response_GetMaintenanceSchedule,
+	now time.Time,
+) map[string]bool {
+	notStarted := make(map[string]bool)
+	for _, window := range scheduleResponse.GetSchedule().GetWindows() {
+		start := window.GetUnavailability().GetStart().GetNanoseconds()
+		if start <= now.UnixNano() {
+			continue
+		}
+		for _, machineID := range window.GetMachineIds() {
+			notStarted[machineID.GetHostname()] = true
+		}
+	}
+	return notStarted
+}
+
+// isAgentDrain returns whether the host is drained by Mesos Master
+// instead of Peloton.
+func isAgentDrain(hostInfo *host.HostInfo) bool {
+	return hostInfo.GetDrainOptions().GetMethod() ==
+		host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ebmocks "github.com/uber/peloton/pkg/hostmgr/eventbus/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type MaintenanceReconcilerTestSuite struct {
+	suite.Suite
+
+	ctrl                     *gomock.Controller
+	testScope                tally.TestScope
+	mockMasterOperatorClient *mpb_mocks.MockMasterOperatorClient
+	maintenanceHostInfoMap   MaintenanceHostInfoMap
+	reconciler               *MaintenanceReconciler
+}
+
+func (suite *MaintenanceReconcilerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.testScope = tally.NewTestScope("", map[string]string{})
+	suite.mockMasterOperatorClient = mpb_mocks.NewMockMasterOperatorClient(suite.ctrl)
+	suite.maintenanceHostInfoMap = NewMaintenanceHostInfoMap(tally.NoopScope)
+	suite.reconciler = NewMaintenanceReconciler(
+		suite.testScope,
+		suite.mockMasterOperatorClient,
+		suite.maintenanceHostInfoMap)
+}
+
+func (suite *MaintenanceReconcilerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+	maintenanceStates.Store(map[string]host.HostState{})
+}
+
+func TestMaintenanceReconcilerTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceReconcilerTestSuite))
+}
+
+func newMachineID(hostname string, ip string) *mesos.MachineID {
+	return &mesos.MachineID{Hostname: &hostname, Ip: &ip}
+}
+
+func (suite *MaintenanceReconcilerTestSuite) counter(name string) int64 {
+	counter, ok := suite.testScope.Snapshot().Counters()["maintenance."+name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestReconcile tests that the maintenance host info map is repaired
+// from the maintenance status and schedule of Mesos Master
+func (suite *MaintenanceReconcilerTestSuite) TestReconcile() {
+	suite.maintenanceHostInfoMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			Ip:       "10.0.0.1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+		{
+			Hostname: "host2",
+			Ip:       "10.0.0.2",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+	})
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{
+			Status: &mesos_maintenance.ClusterStatus{
+				DrainingMachines: []*mesos_maintenance.ClusterStatus_DrainingMachine{
+					{Id: newMachineID("host3", "10.0.0.3")},
+				},
+				DownMachines: []*mesos.MachineID{
+					newMachineID("host2", "10.0.0.2"),
+				},
+			},
+		}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{
+			Schedule: &mesos_maintenance.Schedule{
+				Windows: []*mesos_maintenance.Window{
+					{
+						MachineIds: []*mesos.MachineID{
+							newMachineID("host2", "10.0.0.2"),
+							newMachineID("host3", "10.0.0.3"),
+							newMachineID("host4", "10.0.0.4"),
+						},
+					},
+				},
+			},
+		}, nil)
+
+	drainingHosts, err := suite.reconciler.Reconcile()
+	suite.NoError(err)
+	suite.Equal([]string{"host3", "host4"}, drainingHosts)
+
+	suite.Len(suite.maintenanceHostInfoMap.GetDrainingHostInfos(
+		[]string{"host1", "host3", "host4"}), 2)
+	suite.Len(suite.maintenanceHostInfoMap.GetDownHostInfos(
+		[]string{"host2"}), 1)
+
+	suite.Equal(int64(1), suite.counter("reconcile_success"))
+	suite.Equal(int64(2), suite.counter("reconcile_missing_hosts"))
+	suite.Equal(int64(1), suite.counter("reconcile_stale_hosts"))
+	suite.Equal(int64(1), suite.counter("reconcile_state_mismatches"))
+}
+
+// TestReconcileError tests that the maintenance host info map is kept
+// if the maintenance schedule cannot be fetched
+func (suite *MaintenanceReconcilerTestSuite) TestReconcileError() {
+	suite.maintenanceHostInfoMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname: "host1",
+			State:    host.HostState_HOST_STATE_DRAINING,
+		},
+	})
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+
+	_, err := suite.reconciler.Reconcile()
+	suite.Error(err)
+	suite.Len(suite.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}), 1)
+	suite.Equal(int64(1), suite.counter("reconcile_fail"))
+}
+
+// TestReconcileAgentDrain tests that hosts whose agents are drained by
+// Mesos Master are reconciled from the drain state of their agents
+func (suite *MaintenanceReconcilerTestSuite) TestReconcileAgentDrain() {
+	mockEventBus := ebmocks.NewMockBus(suite.ctrl)
+	suite.reconciler.eventBus = mockEventBus
+
+	agentDrain := &host.DrainOptions{
+		Message: "draining",
+		Method:  host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+	}
+	suite.maintenanceHostInfoMap.AddHostInfos([]*host.HostInfo{
+		{
+			Hostname:     "host1",
+			Ip:           "10.0.0.1",
+			State:        host.HostState_HOST_STATE_DRAINING,
+			DrainOptions: agentDrain,
+		},
+		{
+			Hostname:     "host3",
+			Ip:           "10.0.0.3",
+			State:        host.HostState_HOST_STATE_DRAINING,
+			DrainOptions: agentDrain,
+		},
+	})
+
+	newAgent := func(hostname string, ip string, state *mesos.DrainState) *mesos_master.Response_GetAgents_Agent {
+		pid := fmt.Sprintf("slave(1)@%s:5051", ip)
+		agent := &mesos_master.Response_GetAgents_Agent{
+			AgentInfo: &mesos.AgentInfo{Hostname: &hostname},
+			Pid:       &pid,
+		}
+		if state != nil {
+			agent.DrainInfo = &mesos.DrainInfo{State: state}
+		}
+		return agent
+	}
+	drained := mesos.DrainState_DRAINED
+	draining := mesos.DrainState_DRAINING
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(&mesos_master.Response_GetAgents{
+			Agents: []*mesos_master.Response_GetAgents_Agent{
+				newAgent("host1", "10.0.0.1", &drained),
+				newAgent("host2", "10.0.0.2", &draining),
+				newAgent("host3", "10.0.0.3", nil),
+				newAgent("host4", "10.0.0.4", nil),
+			},
+		}, nil)
+	mockEventBus.EXPECT().Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: []string{"host1"},
+		From:      host.HostState_HOST_STATE_DRAINING,
+		To:        host.HostState_HOST_STATE_DOWN,
+	})
+
+	// Hosts drained by Mesos Master are not drained by Peloton
+	drainingHosts, err := suite.reconciler.Reconcile()
+	suite.NoError(err)
+	suite.Empty(drainingHosts)
+
+	downHostInfos := suite.maintenanceHostInfoMap.GetDownHostInfos([]string{})
+	suite.Len(downHostInfos, 1)
+	suite.Equal("host1", downHostInfos[0].GetHostname())
+	suite.Equal(agentDrain, downHostInfos[0].GetDrainOptions())
+
+	// The agent of host3 was reactivated outside of Peloton
+	drainingHostInfos := suite.maintenanceHostInfoMap.GetDrainingHostInfos(
+		[]string{})
+	suite.Len(drainingHostInfos, 1)
+	suite.Equal("host2", drainingHostInfos[0].GetHostname())
+	suite.Equal(
+		host.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+		drainingHostInfos[0].GetDrainOptions().GetMethod())
+
+	suite.Equal(int64(1), suite.counter("reconcile_missing_hosts"))
+	suite.Equal(int64(1), suite.counter("reconcile_stale_hosts"))
+	suite.Equal(int64(1), suite.counter("reconcile_state_mismatches"))
+
+	// Failing to fetch the agents fails the reconciliation
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(nil, fmt.Errorf("fake Agents error"))
+	_, err = suite.reconciler.Reconcile()
+	suite.Error(err)
+	suite.Equal(int64(1), suite.counter("reconcile_fail"))
+}
+
+// TestReconcileNotStarted tests that hosts whose maintenance window has
+// not started yet are DRAINING but not returned to be drained
+func (suite *MaintenanceReconcilerTestSuite) TestReconcileNotStarted() {
+	started := time.Now().Add(-time.Hour).UnixNano()
+	notStarted := time.Now().Add(time.Hour).UnixNano()
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{
+			Schedule: &mesos_maintenance.Schedule{
+				Windows: []*mesos_maintenance.Window{
+					{
+						MachineIds: []*mesos.MachineID{
+							newMachineID("host1", "10.0.0.1"),
+						},
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &started},
+						},
+					},
+					{
+						MachineIds: []*mesos.MachineID{
+							newMachineID("host2", "10.0.0.2"),
+						},
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &notStarted},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	drainingHosts, err := suite.reconciler.Reconcile()
+	suite.NoError(err)
+	suite.Equal([]string{"host1"}, drainingHosts)
+	suite.Len(suite.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}), 2)
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"sort"
+	"sync"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/util"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// agentMapUpdateLock serializes the writers of the agent map singleton, so
+// that an in-place update of a host by the AttributeWatcher does not
+// overwrite a more recent agent map stored by the Loader.
+var agentMapUpdateLock sync.Mutex
+
+// AttributeWatcher detects agents which re-registered with Mesos master
+// with a different set of attributes. The agent map entry of such a host
+// is updated right away, so that exclusive and constraint matching of the
+// host is re-evaluated against the new attributes instead of waiting for
+// the next agent map refresh, and the change is published on the event bus.
+type AttributeWatcher interface {
+	// ObserveOffers compares the attributes carried by the offers with the
+	// attributes of the agents in the agent map, and updates the agent map
+	// for the hosts whose attributes changed.
+	ObserveOffers(offers []*mesos.Offer)
+
+	// ObserveAgentMap compares the agents of a freshly loaded agent map
+	// with the agents of the previous agent map.
+	ObserveAgentMap(previous *AgentMap, current *AgentMap)
+}
+
+// attributeChange is the agent info of a host before and after
+// its attributes changed.
+type attributeChange struct {
+	previous *mesos.AgentInfo
+	current  *mesos.AgentInfo
+}
+
+// attributeWatcher implements AttributeWatcher interface
+type attributeWatcher struct {
+	eventBus eventbus.Bus
+	metrics  *Metrics
+}
+
+// NewAttributeWatcher returns a new AttributeWatcher
+func NewAttributeWatcher(
+	scope tally.Scope,
+	eventBus eventbus.Bus) AttributeWatcher {
+	return &attributeWatcher{
+		eventBus: eventBus,
+		metrics:  NewMetrics(scope.SubScope("attribute_watcher")),
+	}
+}
+
+// ObserveOffers compares the attributes carried by the offers with the
+// attributes of the agents in the agent map.
+func (w *attributeWatcher) ObserveOffers(offers []*mesos.Offer) {
+	agentMap := GetAgentMap()
+	if agentMap == nil {
+		return
+	}
+
+	var changes []attributeChange
+	observed := make(map[string]struct{})
+	for _, offer := range offers {
+		hostname := offer.GetHostname()
+		if _, ok := observed[hostname]; ok {
+			continue
+		}
+		observed[hostname] = struct{}{}
+
+		// Agents unknown to the agent map are picked up
+		// by the next agent map refresh.
+		agent, ok := agentMap.RegisteredAgents[hostname]
+		if !ok {
+			continue
+		}
+
+		previous := agent.GetAgentInfo()
+		if attributesEqual(previous.GetAttributes(), offer.GetAttributes()) {
+			continue
+		}
+
+		current := *previous
+		current.Attributes = offer.GetAttributes()
+		if offer.GetAgentId() != nil {
+			current.Id = offer.GetAgentId()
+		}
+		changes = append(changes, attributeChange{
+			previous: previous,
+			current:  &current,
+		})
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	w.updateAgentMap(changes)
+	w.notify(changes)
+}
+
+// ObserveAgentMap compares the agents of a freshly loaded agent map
+// with the agents of the previous agent map.
+func (w *attributeWatcher) ObserveAgentMap(previous *AgentMap, current *AgentMap) {
+	if previous == nil || current == nil {
+		return
+	}
+
+	var changes []attributeChange
+	for hostname, agent := range current.RegisteredAgents {
+		prevAgent, ok := previous.RegisteredAgents[hostname]
+		if !ok {
+			continue
+		}
+		if attributesEqual(
+			prevAgent.GetAgentInfo().GetAttributes(),
+			agent.GetAgentInfo().GetAttributes()) {
+			continue
+		}
+		changes = append(changes, attributeChange{
+			previous: prevAgent.GetAgentInfo(),
+			current:  agent.GetAgentInfo(),
+		})
+	}
+
+	w.notify(changes)
+}
+
+// updateAgentMap stores a copy of the agent map singleton with the agent
+// info of the changed hosts replaced.
+func (w *attributeWatcher) updateAgentMap(changes []attributeChange) {
+	agentMapUpdateLock.Lock()
+	defer agentMapUpdateLock.Unlock()
+
+	m := GetAgentMap()
+	if m == nil {
+		return
+	}
+
+	updated := &AgentMap{
+		RegisteredAgents: make(
+			map[string]*mesos_master.Response_GetAgents_Agent,
+			len(m.RegisteredAgents)),
+		Capacity:       m.Capacity,
+		SlackCapacity:  m.SlackCapacity,
+		CustomCapacity: m.CustomCapacity,
+		GPUHosts:       m.GPUHosts,
+		RefreshTime:    m.RefreshTime,
+	}
+	for hostname, agent := range m.RegisteredAgents {
+		updated.RegisteredAgents[hostname] = agent
+	}
+
+	for _, change := range changes {
+		hostname := change.current.GetHostname()
+		agent, ok := updated.RegisteredAgents[hostname]
+		if !ok {
+			continue
+		}
+		updatedAgent := *agent
+		updatedAgent.AgentInfo = change.current
+		updated.RegisteredAgents[hostname] = &updatedAgent
+	}
+
+	agentInfoMap.Store(updated)
+}
+
+// notify records the attribute changes and publishes them
+// on the event bus.
+func (w *attributeWatcher) notify(changes []attributeChange) {
+	for _, change := range changes {
+		previousExclusive := util.GetExclusiveAttributeValues(
+			change.previous.GetAttributes())
+		currentExclusive := util.GetExclusiveAttributeValues(
+			change.current.GetAttributes())
+		exclusiveChanged := !stringsEqual(previousExclusive, currentExclusive)
+
+		w.metrics.AttributeChanges.Inc(1)
+		if exclusiveChanged {
+			w.metrics.ExclusiveAttributeChanges.Inc(1)
+		}
+
+		log.WithFields(log.Fields{
+			"hostname":            change.current.GetHostname(),
+			"previous_attributes": change.previous.GetAttributes(),
+			"attributes":          change.current.GetAttributes(),
+			"exclusive_changed":   exclusiveChanged,
+		}).Info("Agent attributes changed")
+
+		w.eventBus.Publish(&eventbus.AttributesChangedEvent{
+			Previous: change.previous,
+			Current:  change.current,
+		})
+	}
+}
+
+// attributesEqual returns true if both lists contain the same
+// attributes, regardless of their order.
+func attributesEqual(a, b []*mesos.Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := make(map[string]int)
+	for _, attr := range a {
+		counts[attr.String()]++
+	}
+	for _, attr := range b {
+		key := attr.String()
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+	return true
+}
+
+// stringsEqual returns true if both lists contain the same
+// strings, regardless of their order.
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
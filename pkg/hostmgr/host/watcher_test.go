@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	mock_mpb "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_watcherHostname = "hostname"
+	_watcherAgentID  = "agent-id"
+)
+
+// fakeEventBus records the attribute changes published on it.
+type fakeEventBus struct {
+	previous []*mesos.AgentInfo
+	current  []*mesos.AgentInfo
+}
+
+func (b *fakeEventBus) Publish(event eventbus.Event) {
+	change, ok := event.(*eventbus.AttributesChangedEvent)
+	if !ok {
+		return
+	}
+	b.previous = append(b.previous, change.Previous)
+	b.current = append(b.current, change.Current)
+}
+
+func (b *fakeEventBus) Subscribe(
+	eventbus.Subscriber) (eventbus.Subscription, error) {
+	return nil, nil
+}
+
+func (b *fakeEventBus) Close() {}
+
+type AttributeWatcherTestSuite struct {
+	suite.Suite
+
+	testScope tally.TestScope
+	eventBus  *fakeEventBus
+	watcher   AttributeWatcher
+}
+
+func (suite *AttributeWatcherTestSuite) SetupTest() {
+	suite.testScope = tally.NewTestScope("", map[string]string{})
+	suite.eventBus = &fakeEventBus{}
+	suite.watcher = NewAttributeWatcher(suite.testScope, suite.eventBus)
+}
+
+func makeTextAttribute(name string, value string) *mesos.Attribute {
+	textType := mesos.Value_TEXT
+	return &mesos.Attribute{
+		Name: &name,
+		Type: &textType,
+		Text: &mesos.Value_Text{Value: &value},
+	}
+}
+
+func makeAgent(
+	hostname string,
+	attributes ...*mesos.Attribute) *mesos_master.Response_GetAgents_Agent {
+	agentID := _watcherAgentID
+	return &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{
+			Hostname:   &hostname,
+			Id:         &mesos.AgentID{Value: &agentID},
+			Attributes: attributes,
+		},
+	}
+}
+
+func makeOffer(
+	hostname string,
+	attributes ...*mesos.Attribute) *mesos.Offer {
+	agentID := _watcherAgentID
+	return &mesos.Offer{
+		Hostname:   &hostname,
+		AgentId:    &mesos.AgentID{Value: &agentID},
+		Attributes: attributes,
+	}
+}
+
+func storeAgents(agents ...*mesos_master.Response_GetAgents_Agent) *AgentMap {
+	m := &AgentMap{
+		RegisteredAgents: make(map[string]*mesos_master.Response_GetAgents_Agent),
+	}
+	for _, agent := range agents {
+		m.RegisteredAgents[agent.GetAgentInfo().GetHostname()] = agent
+	}
+	agentInfoMap.Store(m)
+	return m
+}
+
+func (suite *AttributeWatcherTestSuite) attributeChanges() int64 {
+	counter, ok := suite.testScope.Snapshot().
+		Counters()["attribute_watcher.attribute_changes+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func (suite *AttributeWatcherTestSuite) exclusiveAttributeChanges() int64 {
+	counter, ok := suite.testScope.Snapshot().
+		Counters()["attribute_watcher.exclusive_attribute_changes+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestObserveOffersUpdatesAgentMap tests that the agent map entry of a host
+// is updated when its offers carry different attributes.
+func (suite *AttributeWatcherTestSuite) TestObserveOffersUpdatesAgentMap() {
+	previous := storeAgents(
+		makeAgent(_watcherHostname, makeTextAttribute("rack", "rack1")),
+		makeAgent("other-host", makeTextAttribute("rack", "rack1")),
+	)
+
+	suite.watcher.ObserveOffers([]*mesos.Offer{
+		makeOffer(_watcherHostname, makeTextAttribute("rack", "rack2")),
+		makeOffer(_watcherHostname, makeTextAttribute("rack", "rack2")),
+		makeOffer("other-host", makeTextAttribute("rack", "rack1")),
+	})
+
+	suite.Len(suite.eventBus.current, 1)
+	suite.Equal(
+		"rack1",
+		suite.eventBus.previous[0].GetAttributes()[0].GetText().GetValue())
+	suite.Equal(
+		"rack2",
+		suite.eventBus.current[0].GetAttributes()[0].GetText().GetValue())
+
+	agentInfo := GetAgentInfo(_watcherHostname)
+	suite.Equal("rack2", agentInfo.GetAttributes()[0].GetText().GetValue())
+	suite.Equal(
+		previous.RegisteredAgents["other-host"],
+		GetAgentMap().RegisteredAgents["other-host"])
+
+	// The previous agent map is left untouched.
+	suite.Equal(
+		"rack1",
+		previous.RegisteredAgents[_watcherHostname].GetAgentInfo().
+			GetAttributes()[0].GetText().GetValue())
+	suite.Equal(int64(1), suite.attributeChanges())
+	suite.Equal(int64(0), suite.exclusiveAttributeChanges())
+}
+
+// TestObserveOffersNoChange tests that attributes advertised in a different
+// order are not reported as changed.
+func (suite *AttributeWatcherTestSuite) TestObserveOffersNoChange() {
+	previous := storeAgents(makeAgent(
+		_watcherHostname,
+		makeTextAttribute("rack", "rack1"),
+		makeTextAttribute("zone", "zone1"),
+	))
+
+	suite.watcher.ObserveOffers([]*mesos.Offer{
+		makeOffer(
+			_watcherHostname,
+			makeTextAttribute("zone", "zone1"),
+			makeTextAttribute("rack", "rack1"),
+		),
+		makeOffer("unknown-host", makeTextAttribute("rack", "rack1")),
+	})
+
+	suite.Empty(suite.eventBus.current)
+	suite.Equal(previous, GetAgentMap())
+	suite.Equal(int64(0), suite.attributeChanges())
+}
+
+// TestObserveAgentMapExclusiveChange tests that a change of the exclusive
+// attribute between two agent maps is reported.
+func (suite *AttributeWatcherTestSuite) TestObserveAgentMapExclusiveChange() {
+	previous := &AgentMap{
+		RegisteredAgents: map[string]*mesos_master.Response_GetAgents_Agent{
+			_watcherHostname: makeAgent(_watcherHostname),
+			"other-host":     makeAgent("other-host"),
+		},
+	}
+	current := &AgentMap{
+		RegisteredAgents: map[string]*mesos_master.Response_GetAgents_Agent{
+			_watcherHostname: makeAgent(
+				_watcherHostname,
+				makeTextAttribute(common.PelotonExclusiveAttributeName, "web")),
+			"other-host": makeAgent("other-host"),
+			"new-host": makeAgent(
+				"new-host",
+				makeTextAttribute(common.PelotonExclusiveAttributeName, "web")),
+		},
+	}
+
+	suite.watcher.ObserveAgentMap(nil, current)
+	suite.Empty(suite.eventBus.current)
+
+	suite.watcher.ObserveAgentMap(previous, current)
+	suite.Len(suite.eventBus.current, 1)
+	suite.Equal(_watcherHostname, suite.eventBus.current[0].GetHostname())
+	suite.Equal(int64(1), suite.attributeChanges())
+	suite.Equal(int64(1), suite.exclusiveAttributeChanges())
+}
+
+// TestLoadNotifiesAttributeWatcher tests that the loader reports the
+// attribute changes between two loads of the agent map.
+func (suite *AttributeWatcherTestSuite) TestLoadNotifiesAttributeWatcher() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	operatorClient := mock_mpb.NewMockMasterOperatorClient(ctrl)
+	maintenanceMap := hm.NewMockMaintenanceHostInfoMap(ctrl)
+	loader := &Loader{
+		OperatorClient:         operatorClient,
+		Scope:                  suite.testScope,
+		MaintenanceHostInfoMap: maintenanceMap,
+		AttributeWatcher:       suite.watcher,
+	}
+
+	resources := []*mesos.Resource{
+		util.NewMesosResourceBuilder().
+			WithName(common.MesosCPU).
+			WithValue(1).
+			Build(),
+	}
+	agent := makeAgent(_watcherHostname, makeTextAttribute("rack", "rack1"))
+	agent.TotalResources = resources
+	changedAgent := makeAgent(_watcherHostname, makeTextAttribute("rack", "rack2"))
+	changedAgent.TotalResources = resources
+
+	maintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*host.HostInfo{}).
+		Times(2)
+	gomock.InOrder(
+		operatorClient.EXPECT().Agents().Return(
+			&mesos_master.Response_GetAgents{
+				Agents: []*mesos_master.Response_GetAgents_Agent{agent},
+			}, nil),
+		operatorClient.EXPECT().Agents().Return(
+			&mesos_master.Response_GetAgents{
+				Agents: []*mesos_master.Response_GetAgents_Agent{changedAgent},
+			}, nil),
+	)
+
+	storeAgents()
+	loader.Load(nil)
+	suite.Empty(suite.eventBus.current)
+
+	loader.Load(nil)
+	suite.Len(suite.eventBus.current, 1)
+	suite.Equal(
+		"rack2",
+		GetAgentInfo(_watcherHostname).GetAttributes()[0].GetText().GetValue())
+}
+
+// TestAttributesEqual tests the order independent comparison of attributes.
+func (suite *AttributeWatcherTestSuite) TestAttributesEqual() {
+	rack := makeTextAttribute("rack", "rack1")
+	zone := makeTextAttribute("zone", "zone1")
+
+	suite.True(attributesEqual(nil, nil))
+	suite.True(attributesEqual(
+		[]*mesos.Attribute{rack, zone},
+		[]*mesos.Attribute{zone, rack}))
+	suite.False(attributesEqual(
+		[]*mesos.Attribute{rack},
+		[]*mesos.Attribute{rack, zone}))
+	suite.False(attributesEqual(
+		[]*mesos.Attribute{rack, rack},
+		[]*mesos.Attribute{rack, zone}))
+	suite.False(attributesEqual(
+		[]*mesos.Attribute{rack},
+		[]*mesos.Attribute{makeTextAttribute("rack", "rack2")}))
+}
+
+func TestAttributeWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(AttributeWatcherTestSuite))
+}
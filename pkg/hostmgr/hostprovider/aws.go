@@ -0,0 +1,119 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+)
+
+const _defaultAWSCLI = "aws"
+
+// AWSConfig is the configuration of the AWS provider
+type AWSConfig struct {
+	// Region of the instances, the default region of the CLI if not
+	// specified
+	Region string `yaml:"region"`
+
+	// Path of the AWS CLI, "aws" if not specified
+	CLI string `yaml:"cli"`
+}
+
+// awsProvider reboots and terminates the EC2 instances of the hosts with
+// the AWS CLI. Instances are found by the private IP of the hosts, or by
+// their private DNS name if the IP is not known.
+type awsProvider struct {
+	config AWSConfig
+}
+
+// NewAWSProvider returns the AWS host provider
+func NewAWSProvider(config Config) (HostProvider, error) {
+	awsConfig := config.AWS
+	if awsConfig.CLI == "" {
+		awsConfig.CLI = _defaultAWSCLI
+	}
+	return &awsProvider{config: awsConfig}, nil
+}
+
+// Name is implementation of HostProvider.Name
+func (p *awsProvider) Name() string {
+	return AWS
+}
+
+// Reboot is implementation of HostProvider.Reboot
+func (p *awsProvider) Reboot(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	return p.instanceCommand(ctx, hostInfo, "reboot-instances")
+}
+
+// Terminate is implementation of HostProvider.Terminate
+func (p *awsProvider) Terminate(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	return p.instanceCommand(ctx, hostInfo, "terminate-instances")
+}
+
+// instanceCommand runs an ec2 command on the instance of the host
+func (p *awsProvider) instanceCommand(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo,
+	command string) error {
+	instanceID, err := p.getInstanceID(ctx, hostInfo)
+	if err != nil {
+		return err
+	}
+	_, err = runCommand(ctx, p.config.CLI,
+		p.args(command, "--instance-ids", instanceID)...)
+	return err
+}
+
+// getInstanceID returns the ID of the live instance of the host
+func (p *awsProvider) getInstanceID(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) (string, error) {
+	filter := "Name=private-ip-address,Values=" + hostInfo.GetIp()
+	if hostInfo.GetIp() == "" {
+		filter = "Name=private-dns-name,Values=" + hostInfo.GetHostname()
+	}
+	output, err := runCommand(ctx, p.config.CLI, p.args(
+		"describe-instances",
+		"--filters", filter,
+		"Name=instance-state-name,Values=pending,running,stopping,stopped",
+		"--query", "Reservations[].Instances[].InstanceId",
+		"--output", "text")...)
+	if err != nil {
+		return "", err
+	}
+
+	instanceIDs := strings.Fields(string(output))
+	if len(instanceIDs) != 1 {
+		return "", fmt.Errorf("found %d instances of host %s",
+			len(instanceIDs), hostInfo.GetHostname())
+	}
+	return instanceIDs[0], nil
+}
+
+// args returns the arguments of an ec2 command
+func (p *awsProvider) args(command string, args ...string) []string {
+	result := []string{"ec2", command}
+	if p.config.Region != "" {
+		result = append(result, "--region", p.config.Region)
+	}
+	return append(result, args...)
+}
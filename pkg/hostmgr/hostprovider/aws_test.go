@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"errors"
+)
+
+func (suite *HostProviderTestSuite) TestAWSProvider() {
+	provider, err := NewAWSProvider(Config{AWS: AWSConfig{Region: "us-west-2"}})
+	suite.NoError(err)
+
+	suite.commands.outputs = []string{"i-0123\n"}
+	suite.NoError(provider.Reboot(suite.ctx, suite.hostInfo))
+	suite.Equal([]string{
+		"aws ec2 describe-instances --region us-west-2 " +
+			"--filters Name=private-ip-address,Values=10.0.0.1 " +
+			"Name=instance-state-name,Values=pending,running,stopping,stopped " +
+			"--query Reservations[].Instances[].InstanceId --output text",
+		"aws ec2 reboot-instances --region us-west-2 --instance-ids i-0123",
+	}, suite.commands.commands)
+
+	// Instance looked up by DNS name without IP
+	suite.commands.commands = nil
+	suite.commands.outputs = []string{"i-0123"}
+	suite.hostInfo.Ip = ""
+	suite.NoError(provider.Terminate(suite.ctx, suite.hostInfo))
+	suite.Contains(suite.commands.commands[0],
+		"Name=private-dns-name,Values=host1.example.com")
+	suite.Equal("aws ec2 terminate-instances --region us-west-2 --instance-ids i-0123",
+		suite.commands.commands[1])
+}
+
+func (suite *HostProviderTestSuite) TestAWSProviderErrors() {
+	provider, err := NewAWSProvider(Config{})
+	suite.NoError(err)
+
+	// No instance found
+	suite.Error(provider.Terminate(suite.ctx, suite.hostInfo))
+	suite.Len(suite.commands.commands, 1)
+
+	// More than one instance found
+	suite.commands.outputs = []string{"i-0123\ti-4567\n"}
+	suite.Error(provider.Terminate(suite.ctx, suite.hostInfo))
+	suite.Len(suite.commands.commands, 2)
+
+	suite.commands.err = errors.New("exit status 255")
+	suite.Error(provider.Reboot(suite.ctx, suite.hostInfo))
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runCommand runs a command and returns its standard output. Replaced
+// in tests.
+var runCommand = func(
+	ctx context.Context,
+	name string,
+	args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %v: %s",
+			name,
+			strings.Join(args, " "),
+			err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+)
+
+const _defaultGCPCLI = "gcloud"
+
+// GCPConfig is the configuration of the GCP provider
+type GCPConfig struct {
+	// Project of the instances, the default project of the CLI if not
+	// specified
+	Project string `yaml:"project"`
+
+	// Zone of the instances, the default zone of the CLI if not
+	// specified
+	Zone string `yaml:"zone"`
+
+	// Path of the Cloud SDK CLI, "gcloud" if not specified
+	CLI string `yaml:"cli"`
+}
+
+// gcpProvider resets and deletes the Compute Engine instances of the
+// hosts with the Cloud SDK CLI. Instances are named after the short
+// hostname of the hosts.
+type gcpProvider struct {
+	config GCPConfig
+}
+
+// NewGCPProvider returns the GCP host provider
+func NewGCPProvider(config Config) (HostProvider, error) {
+	gcpConfig := config.GCP
+	if gcpConfig.CLI == "" {
+		gcpConfig.CLI = _defaultGCPCLI
+	}
+	return &gcpProvider{config: gcpConfig}, nil
+}
+
+// Name is implementation of HostProvider.Name
+func (p *gcpProvider) Name() string {
+	return GCP
+}
+
+// Reboot is implementation of HostProvider.Reboot
+func (p *gcpProvider) Reboot(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	_, err := runCommand(ctx, p.config.CLI, p.args("reset", hostInfo)...)
+	return err
+}
+
+// Terminate is implementation of HostProvider.Terminate
+func (p *gcpProvider) Terminate(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	_, err := runCommand(ctx, p.config.CLI, p.args("delete", hostInfo)...)
+	return err
+}
+
+// args returns the arguments of an instances command on the instance of
+// the host
+func (p *gcpProvider) args(command string, hostInfo *hpb.HostInfo) []string {
+	instance := strings.SplitN(hostInfo.GetHostname(), ".", 2)[0]
+	args := []string{"compute", "instances", command, instance, "--quiet"}
+	if p.config.Project != "" {
+		args = append(args, "--project", p.config.Project)
+	}
+	if p.config.Zone != "" {
+		args = append(args, "--zone", p.config.Zone)
+	}
+	return args
+}
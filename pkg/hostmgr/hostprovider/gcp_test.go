@@ -0,0 +1,38 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+func (suite *HostProviderTestSuite) TestGCPProvider() {
+	provider, err := NewGCPProvider(Config{
+		GCP: GCPConfig{Project: "peloton", Zone: "us-central1-a"},
+	})
+	suite.NoError(err)
+
+	suite.NoError(provider.Reboot(suite.ctx, suite.hostInfo))
+	suite.NoError(provider.Terminate(suite.ctx, suite.hostInfo))
+	suite.Equal([]string{
+		"gcloud compute instances reset host1 --quiet " +
+			"--project peloton --zone us-central1-a",
+		"gcloud compute instances delete host1 --quiet " +
+			"--project peloton --zone us-central1-a",
+	}, suite.commands.commands)
+
+	// CLI defaults to its project and zone
+	provider, err = NewGCPProvider(Config{GCP: GCPConfig{CLI: "/opt/gcloud"}})
+	suite.NoError(err)
+	suite.NoError(provider.Reboot(suite.ctx, suite.hostInfo))
+	suite.Equal("/opt/gcloud compute instances reset host1 --quiet",
+		suite.commands.commands[2])
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+)
+
+// OnPremConfig is the configuration of the on-premise provider. The
+// commands are a program and its arguments, in which {hostname} and {ip}
+// are replaced by the hostname and the IP of the host.
+type OnPremConfig struct {
+	// Command rebooting a host, e.g. with IPMI
+	RebootCommand []string `yaml:"reboot_command"`
+
+	// Command terminating a host, e.g. powering it off and handing it
+	// back to the inventory system
+	TerminateCommand []string `yaml:"terminate_command"`
+}
+
+// onPremProvider runs the configured commands for on-premise hosts
+type onPremProvider struct {
+	config OnPremConfig
+}
+
+// NewOnPremProvider returns the on-premise host provider
+func NewOnPremProvider(config Config) (HostProvider, error) {
+	if len(config.OnPrem.RebootCommand) == 0 &&
+		len(config.OnPrem.TerminateCommand) == 0 {
+		return nil, errors.New("on-premise host provider without commands")
+	}
+	return &onPremProvider{config: config.OnPrem}, nil
+}
+
+// Name is implementation of HostProvider.Name
+func (p *onPremProvider) Name() string {
+	return OnPrem
+}
+
+// Reboot is implementation of HostProvider.Reboot
+func (p *onPremProvider) Reboot(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	return p.run(ctx, p.config.RebootCommand, hostInfo)
+}
+
+// Terminate is implementation of HostProvider.Terminate
+func (p *onPremProvider) Terminate(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	return p.run(ctx, p.config.TerminateCommand, hostInfo)
+}
+
+// run runs the command for the host
+func (p *onPremProvider) run(
+	ctx context.Context,
+	command []string,
+	hostInfo *hpb.HostInfo) error {
+	if len(command) == 0 {
+		return errors.New("operation not configured for on-premise hosts")
+	}
+	replacer := strings.NewReplacer(
+		"{hostname}", hostInfo.GetHostname(),
+		"{ip}", hostInfo.GetIp())
+	args := make([]string, 0, len(command)-1)
+	for _, arg := range command[1:] {
+		args = append(args, replacer.Replace(arg))
+	}
+	_, err := runCommand(ctx, command[0], args...)
+	return err
+}
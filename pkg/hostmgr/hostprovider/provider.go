@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"fmt"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AWS is the name of the provider of EC2 instances
+	AWS = "AWS"
+
+	// GCP is the name of the provider of Compute Engine instances
+	GCP = "GCP"
+
+	// OnPrem is the name of the provider which runs configured commands
+	// for on-premise hosts
+	OnPrem = "ONPREM"
+)
+
+// HostProvider is the interface of the plugins managing the lifecycle of
+// the machines of the hosts, e.g. the instances of a cloud provider.
+// Operations return once the provider accepted them, the hosts may take
+// longer to actually reboot or go away.
+type HostProvider interface {
+	// Returns the name of the provider implementation
+	Name() string
+	// Reboot reboots the machine of the host
+	Reboot(ctx context.Context, hostInfo *hpb.HostInfo) error
+	// Terminate terminates the machine of the host, which does not
+	// come back
+	Terminate(ctx context.Context, hostInfo *hpb.HostInfo) error
+}
+
+// Config is the configuration of the host provider
+type Config struct {
+	// Name of the host provider, no provider if not specified
+	Name string `yaml:"name"`
+
+	// Configuration of the AWS provider
+	AWS AWSConfig `yaml:"aws"`
+
+	// Configuration of the GCP provider
+	GCP GCPConfig `yaml:"gcp"`
+
+	// Configuration of the on-premise provider
+	OnPrem OnPremConfig `yaml:"onprem"`
+}
+
+// ProviderFunc type of func which returns HostProvider interface
+type ProviderFunc func(config Config) (HostProvider, error)
+
+// map of provider name to Init Provider Func
+var providers = make(map[string]ProviderFunc)
+
+// Register registers the provider and keeps it in the
+// provider map.
+func Register(name string, provider ProviderFunc) {
+	log.Infof("Registering %s host provider", name)
+	if provider == nil {
+		log.Errorf("host provider does not exist")
+		return
+	}
+	if _, registered := providers[name]; registered {
+		log.Errorf("host provider already registered")
+		return
+	}
+	providers[name] = provider
+}
+
+// Init registers all the providers
+func Init() {
+	Register(AWS, NewAWSProvider)
+	Register(GCP, NewGCPProvider)
+	Register(OnPrem, NewOnPremProvider)
+}
+
+// CreateProvider creates and returns the provider specified in the
+// config, or nil if no provider is configured
+func CreateProvider(config Config) (HostProvider, error) {
+	if config.Name == "" {
+		return nil, nil
+	}
+	provider, ok := providers[config.Name]
+	if !ok {
+		return nil, fmt.Errorf("host provider %s is not registered", config.Name)
+	}
+	return provider(config)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostprovider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeCommands replaces runCommand with a fake which records the
+// commands and returns the given outputs in order
+type fakeCommands struct {
+	commands []string
+	outputs  []string
+	err      error
+}
+
+func (f *fakeCommands) run(
+	ctx context.Context,
+	name string,
+	args ...string) ([]byte, error) {
+	f.commands = append(f.commands,
+		strings.Join(append([]string{name}, args...), " "))
+	if f.err != nil {
+		return nil, f.err
+	}
+	var output string
+	if len(f.outputs) > 0 {
+		output, f.outputs = f.outputs[0], f.outputs[1:]
+	}
+	return []byte(output), nil
+}
+
+type HostProviderTestSuite struct {
+	suite.Suite
+	ctx      context.Context
+	hostInfo *hpb.HostInfo
+	commands *fakeCommands
+	run      func(context.Context, string, ...string) ([]byte, error)
+}
+
+func TestHostProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(HostProviderTestSuite))
+}
+
+func (suite *HostProviderTestSuite) SetupTest() {
+	Init()
+	suite.ctx = context.Background()
+	suite.hostInfo = &hpb.HostInfo{
+		Hostname: "host1.example.com",
+		Ip:       "10.0.0.1",
+	}
+	suite.commands = &fakeCommands{}
+	suite.run = runCommand
+	runCommand = suite.commands.run
+}
+
+func (suite *HostProviderTestSuite) TearDownTest() {
+	runCommand = suite.run
+}
+
+func (suite *HostProviderTestSuite) TestRegister() {
+	providers[AWS] = nil
+	Register(AWS, nil)
+	suite.Nil(providers[AWS])
+	Register(AWS, NewAWSProvider)
+	suite.Nil(providers[AWS])
+	delete(providers, AWS)
+	Register(AWS, NewAWSProvider)
+	suite.NotNil(providers[AWS])
+}
+
+func (suite *HostProviderTestSuite) TestCreateProvider() {
+	provider, err := CreateProvider(Config{})
+	suite.NoError(err)
+	suite.Nil(provider)
+
+	for _, name := range []string{AWS, GCP} {
+		provider, err = CreateProvider(Config{Name: name})
+		suite.NoError(err)
+		suite.Equal(name, provider.Name())
+	}
+
+	provider, err = CreateProvider(Config{
+		Name:   OnPrem,
+		OnPrem: OnPremConfig{RebootCommand: []string{"ipmitool"}},
+	})
+	suite.NoError(err)
+	suite.Equal(OnPrem, provider.Name())
+
+	// On-premise provider without commands
+	_, err = CreateProvider(Config{Name: OnPrem})
+	suite.Error(err)
+
+	_, err = CreateProvider(Config{Name: "Not_existing"})
+	suite.Error(err)
+}
+
+func (suite *HostProviderTestSuite) TestOnPremProvider() {
+	provider, err := NewOnPremProvider(Config{
+		OnPrem: OnPremConfig{
+			RebootCommand: []string{"ipmitool", "-H", "{hostname}-ipmi", "power", "reset"},
+		},
+	})
+	suite.NoError(err)
+
+	suite.NoError(provider.Reboot(suite.ctx, suite.hostInfo))
+	suite.Equal([]string{
+		"ipmitool -H host1.example.com-ipmi power reset",
+	}, suite.commands.commands)
+
+	// Terminate is not configured
+	suite.Error(provider.Terminate(suite.ctx, suite.hostInfo))
+	suite.Len(suite.commands.commands, 1)
+
+	suite.commands.err = errors.New("exit status 1")
+	suite.Error(provider.Reboot(suite.ctx, suite.hostInfo))
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/logging"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Names of the drain methods in the host manager config.
+const (
+	_drainMethodMaintenanceSchedule = "maintenance_schedule"
+	_drainMethodAgentDrain          = "agent_drain"
+)
+
+// ParseDrainMethod returns the drain method configured by name. An
+// empty name is the maintenance schedule drain method.
+func ParseDrainMethod(name string) (hpb.DrainMethod, error) {
+	switch name {
+	case "", _drainMethodMaintenanceSchedule:
+		return hpb.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE, nil
+	case _drainMethodAgentDrain:
+		return hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN, nil
+	default:
+		return hpb.DrainMethod_DRAIN_METHOD_DEFAULT,
+			fmt.Errorf("unknown drain method %q", name)
+	}
+}
+
+// getDrainMethod returns the drain method requested by drainOptions,
+// or the configured one if none is requested.
+func (m *serviceHandler) getDrainMethod(
+	drainOptions *hpb.DrainOptions) hpb.DrainMethod {
+	if method := drainOptions.GetMethod(); method !=
+		hpb.DrainMethod_DRAIN_METHOD_DEFAULT {
+		return method
+	}
+	return m.drainMethod
+}
+
+// agentDrainingSupported returns whether Mesos Master has the
+// AGENT_DRAINING capability.
+func (m *serviceHandler) agentDrainingSupported(
+	ctx context.Context) (bool, error) {
+	var response *mesos_master.Response_GetMaster
+	err := m.retryMasterRead(ctx, func() (err error) {
+		response, err = m.operatorMasterClient.GetMaster()
+		return err
+	})
+	if err != nil {
+		return false, newMasterError(err, "failed to get master info")
+	}
+	for _, capability := range response.GetMasterInfo().GetCapabilities() {
+		if capability.GetType() ==
+			mesos.MasterInfo_Capability_AGENT_DRAINING {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// drainAgents drains the agents of the hosts with the DRAIN_AGENT call
+// of Mesos Master, which deactivates the agents and kills the tasks
+// running on them. The hosts are not enqueued into the maintenance
+// queue, and the drainer puts them DOWN once Mesos Master reports their
+// agents as drained. Hosts are drained one by one until a call fails.
+func (m *serviceHandler) drainAgents(
+	ctx context.Context,
+	hostnames []string,
+	drainOptions *hpb.DrainOptions) error {
+	machineIds, err := m.buildMachineIDsForHosts(ctx, hostnames)
+	if err != nil {
+		return err
+	}
+
+	options := &hpb.DrainOptions{}
+	if drainOptions != nil {
+		options = proto.Clone(drainOptions).(*hpb.DrainOptions)
+	}
+	options.Method = hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+	maxGracePeriod := time.Duration(
+		options.GetKillGracePeriodSeconds()) * time.Second
+
+	var hostInfos []*hpb.HostInfo
+	var drained []string
+	for _, machine := range machineIds {
+		agentID, err := getAgentID(machine.GetHostname())
+		if err == nil {
+			if err = m.operatorMasterClient.DrainAgent(
+				ctx,
+				agentID,
+				maxGracePeriod); err != nil {
+				err = newMasterError(err,
+					"failed to drain agent of host %s", machine.GetHostname())
+			}
+		}
+		if err != nil {
+			m.addAgentDrainHosts(ctx, hostInfos, drained)
+			return err
+		}
+		hostInfos = append(hostInfos,
+			&hpb.HostInfo{
+				Hostname:     machine.GetHostname(),
+				Ip:           machine.GetIp(),
+				State:        hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: options,
+				AgentId:      agentID.GetValue(),
+			})
+		drained = append(drained, machine.GetHostname())
+	}
+	m.addAgentDrainHosts(ctx, hostInfos, drained)
+	return nil
+}
+
+// addAgentDrainHosts adds the hosts whose agents are being drained by
+// Mesos Master to the maintenance host info map as DRAINING.
+func (m *serviceHandler) addAgentDrainHosts(
+	ctx context.Context,
+	hostInfos []*hpb.HostInfo,
+	hostnames []string) {
+	if len(hostnames) == 0 {
+		return
+	}
+	m.maintenanceHostInfoMap.AddHostInfos(hostInfos)
+	m.eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: hostnames,
+		From:      hpb.HostState_HOST_STATE_UP,
+		To:        hpb.HostState_HOST_STATE_DRAINING,
+	})
+	logging.FromContext(ctx).WithField("drained_hosts", hostnames).
+		Info("Agents drained by Mesos Master")
+	m.metrics.AgentDrainHosts.Inc(int64(len(hostnames)))
+}
+
+// reactivateAgent reactivates the drained agent of the host, so that
+// its resources are offered again.
+func (m *serviceHandler) reactivateAgent(
+	ctx context.Context,
+	hostname string) error {
+	agentID, err := getAgentID(hostname)
+	if err != nil {
+		return err
+	}
+	return m.operatorMasterClient.ReactivateAgent(ctx, agentID)
+}
+
+// isAgentDrain returns whether the agent of the host is drained by Mesos
+// Master instead of Peloton.
+func isAgentDrain(hostInfo *hpb.HostInfo) bool {
+	return hostInfo.GetDrainOptions().GetMethod() ==
+		hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/mock/gomock"
+)
+
+// getMasterResponse returns the GET_MASTER response of a master with
+// the given capabilities.
+func getMasterResponse(
+	capabilities ...mesos.MasterInfo_Capability_Type,
+) *mesosmaster.Response_GetMaster {
+	masterInfo := &mesos.MasterInfo{}
+	for _, capability := range capabilities {
+		capability := capability
+		masterInfo.Capabilities = append(masterInfo.Capabilities,
+			&mesos.MasterInfo_Capability{Type: &capability})
+	}
+	return &mesosmaster.Response_GetMaster{MasterInfo: masterInfo}
+}
+
+// TestParseDrainMethod tests parsing the configured drain method
+func (suite *HostSvcHandlerTestSuite) TestParseDrainMethod() {
+	tests := map[string]hpb.DrainMethod{
+		"":                     hpb.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE,
+		"maintenance_schedule": hpb.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE,
+		"agent_drain":          hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+	}
+	for name, expected := range tests {
+		method, err := ParseDrainMethod(name)
+		suite.NoError(err, name)
+		suite.Equal(expected, method, name)
+	}
+
+	_, err := ParseDrainMethod("unknown")
+	suite.Error(err)
+}
+
+// TestStartMaintenanceAgentDrain tests that the agents of the hosts are
+// drained by Mesos Master when configured, without enqueuing the hosts
+// into the maintenance queue
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceAgentDrain() {
+	suite.handler.drainMethod = hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+
+	drainOptions := &hpb.DrainOptions{KillGracePeriodSeconds: 60}
+	var (
+		hosts     []string
+		hostInfos []*hpb.HostInfo
+	)
+	for _, machine := range suite.upMachines {
+		hosts = append(hosts, machine.GetHostname())
+		hostInfos = append(hostInfos, &hpb.HostInfo{
+			Hostname: machine.GetHostname(),
+			Ip:       machine.GetIp(),
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+			DrainOptions: &hpb.DrainOptions{
+				KillGracePeriodSeconds: 60,
+				Method:                 hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			},
+		})
+	}
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaster().
+			Return(getMasterResponse(
+				mesos.MasterInfo_Capability_AGENT_UPDATE,
+				mesos.MasterInfo_Capability_AGENT_DRAINING), nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			DrainAgent(gomock.Any(), gomock.Any(), time.Minute).
+			Return(nil).
+			Times(len(hosts)),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+	)
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    hosts,
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
+	// The drain options of the request are left as is
+	suite.Equal(
+		hpb.DrainMethod_DRAIN_METHOD_DEFAULT,
+		drainOptions.GetMethod())
+}
+
+// TestStartMaintenanceAgentDrainError tests that failing to drain an
+// agent fails the request
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceAgentDrainError() {
+	hosts := []string{suite.upMachines[0].GetHostname()}
+
+	// Fail to get the capabilities of Mesos Master
+	suite.mockMasterOperatorClient.EXPECT().GetMaster().
+		Return(nil, fmt.Errorf("fake GetMaster error"))
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: hosts,
+			DrainOptions: &hpb.DrainOptions{
+				Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			},
+		})
+	suite.Error(err)
+
+	// Fail to drain the agent
+	suite.mockMasterOperatorClient.EXPECT().GetMaster().
+		Return(getMasterResponse(
+			mesos.MasterInfo_Capability_AGENT_DRAINING), nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		DrainAgent(gomock.Any(), gomock.Any(), time.Duration(0)).
+		Return(fmt.Errorf("fake DrainAgent error"))
+	_, err = suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: hosts,
+			DrainOptions: &hpb.DrainOptions{
+				Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			},
+		})
+	suite.Error(err)
+}
+
+// TestStartMaintenanceAgentDrainFallback tests that hosts are drained
+// with the maintenance schedule if Mesos Master cannot drain agents
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceAgentDrainFallback() {
+	drainOptions := &hpb.DrainOptions{
+		Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+	}
+	var (
+		hosts     []string
+		hostInfos []*hpb.HostInfo
+	)
+	for _, machine := range suite.upMachines {
+		hosts = append(hosts, machine.GetHostname())
+		hostInfos = append(hostInfos, &hpb.HostInfo{
+			Hostname:     machine.GetHostname(),
+			Ip:           machine.GetIp(),
+			State:        hpb.HostState_HOST_STATE_DRAINING,
+			DrainOptions: drainOptions,
+		})
+	}
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaster().
+			Return(getMasterResponse(
+				mesos.MasterInfo_Capability_AGENT_UPDATE), nil),
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue(hosts).Return(nil, nil),
+	)
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    hosts,
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
+}
+
+// TestCompleteMaintenanceAgentDrain tests that the agents of hosts
+// drained by Mesos Master are reactivated to complete maintenance
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceAgentDrain() {
+	// Drained agents stay registered with Mesos Master
+	machine := suite.upMachines[0]
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				Ip:       machine.GetIp(),
+				State:    hpb.HostState_HOST_STATE_DOWN,
+				DrainOptions: &hpb.DrainOptions{
+					Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+				},
+			},
+		})
+	suite.mockMasterOperatorClient.EXPECT().
+		ReactivateAgent(gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.mockMaintenanceMap.EXPECT().
+		RemoveHostInfos([]string{machine.GetHostname()})
+	suite.mockEventBus.EXPECT().
+		Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: []string{machine.GetHostname()},
+			From:      hpb.HostState_HOST_STATE_DOWN,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
+
+	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
+		&svcpb.CompleteMaintenanceRequest{
+			Hostnames: []string{machine.GetHostname()},
+		})
+	suite.NoError(err)
+	suite.Equal(
+		[]string{machine.GetHostname()},
+		resp.GetCompletedHostnames())
+	suite.Empty(resp.GetFailures())
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/stringset"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// _apiVersionPattern matches the API version in the service names of the
+// Peloton APIs, e.g. v0 in peloton.api.v0.host.svc.HostService
+var _apiVersionPattern = regexp.MustCompile(`^peloton\.api\.(v[0-9]+[a-z0-9]*)\.`)
+
+// _serviceServers are the YARPC server interfaces of the services whose
+// request fields are described by GetAPIInfo, keyed by service name
+var _serviceServers = map[string]reflect.Type{
+	ServiceName: reflect.TypeOf((*host_svc.HostServiceYARPCServer)(nil)).Elem(),
+}
+
+// GetAPIInfo returns the API versions and the procedures served by host
+// manager, and the first API version of the request it serves. It does
+// not require the leader, so that clients can negotiate with any host
+// manager.
+func (m *serviceHandler) GetAPIInfo(
+	ctx context.Context,
+	request *host_svc.GetAPIInfoRequest,
+) (*host_svc.GetAPIInfoResponse, error) {
+	m.metrics.GetAPIInfoAPI.Inc(1)
+
+	procedures := describeProcedures(m.procedures)
+	apiVersions := stringset.New()
+	for _, p := range procedures {
+		if v := apiVersion(p.GetName()); v != "" {
+			apiVersions.Add(v)
+		}
+	}
+
+	response := &host_svc.GetAPIInfoResponse{
+		ApiVersions: apiVersions.ToSortedSlice(),
+		Procedures:  procedures,
+	}
+	for _, v := range request.GetApiVersions() {
+		if apiVersions.Contains(v) {
+			response.NegotiatedApiVersion = v
+			break
+		}
+	}
+
+	m.metrics.GetAPIInfoSuccess.Inc(1)
+	return response, nil
+}
+
+// describeProcedures returns the procedures sorted by name, with the
+// encodings each one is registered with and the fields of its request.
+func describeProcedures(procedures []transport.Procedure) []*host_svc.Procedure {
+	byName := make(map[string]*host_svc.Procedure)
+	for _, p := range procedures {
+		described, ok := byName[p.Name]
+		if !ok {
+			described = &host_svc.Procedure{
+				Name:          p.Name,
+				RequestFields: requestFields(p.Name),
+			}
+			byName[p.Name] = described
+		}
+		described.Encodings = append(described.Encodings, string(p.Encoding))
+	}
+
+	result := make([]*host_svc.Procedure, 0, len(byName))
+	for _, p := range byName {
+		sort.Strings(p.Encodings)
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result
+}
+
+// apiVersion returns the API version of the service of a procedure, or
+// an empty string if the service is not part of the Peloton APIs.
+func apiVersion(procedure string) string {
+	match := _apiVersionPattern.FindStringSubmatch(procedure)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// requestFields returns the proto names of the fields of the request of
+// a procedure, read from the YARPC server interface of its service, or
+// nil if the service is unknown.
+func requestFields(procedure string) []string {
+	parts := strings.Split(procedure, "::")
+	if len(parts) != 2 {
+		return nil
+	}
+	server, ok := _serviceServers[parts[0]]
+	if !ok {
+		return nil
+	}
+	method, ok := server.MethodByName(parts[1])
+	if !ok || method.Type.NumIn() != 2 {
+		return nil
+	}
+
+	request := method.Type.In(1)
+	if request.Kind() == reflect.Ptr {
+		request = request.Elem()
+	}
+	if request.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []string
+	for i := 0; i < request.NumField(); i++ {
+		if name := protoFieldName(request.Field(i)); name != "" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// protoFieldName returns the proto name of a field of a generated proto
+// message struct, or an empty string for the fields which are not part
+// of the message, e.g. XXX_unrecognized.
+func protoFieldName(field reflect.StructField) string {
+	if name, ok := field.Tag.Lookup("protobuf_oneof"); ok {
+		return name
+	}
+	for _, option := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(option, "name=") {
+			return strings.TrimPrefix(option, "name=")
+		}
+	}
+	return ""
+}
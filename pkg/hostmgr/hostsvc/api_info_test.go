@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+)
+
+// TestGetAPIInfo tests describing the procedures of the HostService and
+// negotiating the API version.
+func (suite *HostSvcHandlerTestSuite) TestGetAPIInfo() {
+	suite.handler.procedures = svcpb.BuildHostServiceYARPCProcedures(suite.handler)
+	defer func() { suite.handler.procedures = nil }()
+
+	response, err := suite.handler.GetAPIInfo(suite.ctx, &svcpb.GetAPIInfoRequest{
+		ApiVersions: []string{"v1alpha", "v0"},
+	})
+	suite.NoError(err)
+	suite.Equal([]string{"v0"}, response.GetApiVersions())
+	suite.Equal("v0", response.GetNegotiatedApiVersion())
+
+	var queryHosts *svcpb.Procedure
+	for i, p := range response.GetProcedures() {
+		if i > 0 {
+			suite.True(response.GetProcedures()[i-1].GetName() < p.GetName())
+		}
+		if p.GetName() == ServiceName+"::QueryHosts" {
+			queryHosts = p
+		}
+	}
+	suite.NotNil(queryHosts)
+	suite.Equal([]string{"json", "proto"}, queryHosts.GetEncodings())
+	suite.Contains(queryHosts.GetRequestFields(), "host_states")
+	suite.Contains(queryHosts.GetRequestFields(), "include_task_details")
+
+	// No API version of the client is served
+	response, err = suite.handler.GetAPIInfo(suite.ctx, &svcpb.GetAPIInfoRequest{
+		ApiVersions: []string{"v1alpha"},
+	})
+	suite.NoError(err)
+	suite.Empty(response.GetNegotiatedApiVersion())
+}
+
+// TestRequestFields tests reading the request fields of procedures.
+func (suite *HostSvcHandlerTestSuite) TestRequestFields() {
+	suite.Contains(
+		requestFields(ServiceName+"::StartMaintenance"), "dry_run")
+	suite.Nil(requestFields(ServiceName + "::Unknown"))
+	suite.Nil(requestFields("peloton.private.hostmgr.InternalHostService::LaunchTasks"))
+	suite.Nil(requestFields("QueryHosts"))
+
+	suite.Equal("v0", apiVersion(ServiceName+"::QueryHosts"))
+	suite.Equal("v1alpha", apiVersion("peloton.api.v1alpha.host.svc.HostService::QueryHosts"))
+	suite.Empty(apiVersion("peloton.private.hostmgr.InternalHostService::LaunchTasks"))
+}
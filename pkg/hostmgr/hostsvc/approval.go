@@ -0,0 +1,169 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/logging"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// ApproveMaintenance approves the maintenance of the specified DRAINING
+// hosts started with require_approval, so that they go DOWN once
+// drained. The maintenance of a host cannot be approved by the user who
+// requested it, nor when either user is not identified.
+func (m *serviceHandler) ApproveMaintenance(
+	ctx context.Context,
+	request *host_svc.ApproveMaintenanceRequest,
+) (*host_svc.ApproveMaintenanceResponse, error) {
+	m.metrics.ApproveMaintenanceAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ApproveMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	if len(request.GetHostnames()) == 0 {
+		m.metrics.ApproveMaintenanceFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf("no hostnames specified")
+	}
+	info, _ := audit.FromContext(ctx)
+	approver := info.User
+	if approver == "" {
+		m.metrics.ApproveMaintenanceFail.Inc(1)
+		return nil, yarpcerrors.PermissionDeniedErrorf(
+			"maintenance cannot be approved by an unidentified user")
+	}
+
+	hostnames, mappings, err := m.resolveHostnames(
+		request.GetHostnames(),
+		m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}))
+	if err != nil {
+		m.metrics.ApproveMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	ctx = logging.WithHosts(ctx, hostnames)
+
+	for _, hostname := range hostnames {
+		approval := m.approvalMap.Get(hostname)
+		if approval == nil {
+			m.metrics.ApproveMaintenanceFail.Inc(1)
+			return nil, newHostStateError(
+				hostname, "does not require maintenance approval")
+		}
+		if !host.IsApprovalPending(approval) {
+			m.metrics.ApproveMaintenanceFail.Inc(1)
+			return nil, newHostStateError(
+				hostname, "maintenance already approved by %s",
+				approval.GetApprover())
+		}
+		// the approver can only be told apart from an identified requester
+		if approval.GetRequester() == "" {
+			m.metrics.ApproveMaintenanceFail.Inc(1)
+			return nil, newHostStateError(
+				hostname, "maintenance requested by an unidentified user "+
+					"cannot be approved")
+		}
+		if approval.GetRequester() == approver {
+			m.metrics.ApproveMaintenanceFail.Inc(1)
+			return nil, newHostStateError(
+				hostname, "maintenance cannot be approved by its requester %s",
+				approver)
+		}
+	}
+
+	for _, hostname := range hostnames {
+		if err := m.approvalMap.Approve(ctx, hostname, approver); err != nil {
+			m.metrics.ApproveMaintenanceFail.Inc(1)
+			return nil, newInternalError(
+				err, "failed to approve maintenance of host %s", hostname)
+		}
+	}
+
+	logging.FromContext(ctx).Info("Maintenance approved")
+	m.metrics.ApproveMaintenanceSuccess.Inc(1)
+	return &host_svc.ApproveMaintenanceResponse{
+		HostnameMappings: mappings,
+	}, nil
+}
+
+// GetMaintenanceApprovals returns the approvals of the hosts in
+// maintenance which require one.
+func (m *serviceHandler) GetMaintenanceApprovals(
+	ctx context.Context,
+	request *host_svc.GetMaintenanceApprovalsRequest,
+) (*host_svc.GetMaintenanceApprovalsResponse, error) {
+	m.metrics.GetMaintenanceApprovalsAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.GetMaintenanceApprovalsFail.Inc(1)
+		return nil, err
+	}
+
+	var approvals []*hpb.MaintenanceApproval
+	for _, approval := range m.approvalMap.GetApprovals() {
+		if request.GetPendingOnly() && !host.IsApprovalPending(approval) {
+			continue
+		}
+		approvals = append(approvals, approval)
+	}
+
+	m.metrics.GetMaintenanceApprovalsSuccess.Inc(1)
+	return &host_svc.GetMaintenanceApprovalsResponse{
+		Approvals: approvals,
+	}, nil
+}
+
+// drainedHostInfo returns a copy of the info of a DRAINING host in
+// HOST_STATE_DRAINED if the host was drained and waits for its
+// maintenance to be approved, nil otherwise.
+func (m *serviceHandler) drainedHostInfo(hostInfo *hpb.HostInfo) *hpb.HostInfo {
+	approval := m.approvalMap.Get(hostInfo.GetHostname())
+	if !host.IsApprovalPending(approval) || approval.GetDrainedTime() == "" {
+		return nil
+	}
+	drained := proto.Clone(hostInfo).(*hpb.HostInfo)
+	drained.State = hpb.HostState_HOST_STATE_DRAINED
+	return drained
+}
+
+// validateApprovalRequester returns an error if the drain options
+// require approval, but the requester cannot be told apart from its
+// approver: the requester is unidentified, or its identity is not
+// authenticated.
+func (m *serviceHandler) validateApprovalRequester(
+	drainOptions *hpb.DrainOptions,
+	requester string) error {
+	if !drainOptions.GetRequireApproval() {
+		return nil
+	}
+	if !m.authenticated {
+		return yarpcerrors.FailedPreconditionErrorf(
+			"approval cannot be required without an authenticating auth type")
+	}
+	if requester == "" {
+		return yarpcerrors.PermissionDeniedErrorf(
+			"maintenance requiring approval cannot be requested " +
+				"by an unidentified user")
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// requireApproval requires the approval of the maintenance of the
+// draining host, requested by alice
+func (suite *HostSvcHandlerTestSuite) requireApproval(drained bool) string {
+	hostname := suite.drainingMachines[0].GetHostname()
+	suite.mockApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	suite.NoError(suite.handler.approvalMap.Require(
+		suite.ctx, []string{hostname}, "alice"))
+	if drained {
+		_, err := suite.handler.approvalMap.MarkDrained(suite.ctx, hostname)
+		suite.NoError(err)
+	}
+	return hostname
+}
+
+// drainingHostInfos returns the infos of the draining hosts
+func (suite *HostSvcHandlerTestSuite) drainingHostInfos() []*hpb.HostInfo {
+	var hostInfos []*hpb.HostInfo
+	for _, machine := range suite.drainingMachines {
+		hostInfos = append(hostInfos, &hpb.HostInfo{
+			Hostname: machine.GetHostname(),
+			Ip:       machine.GetIp(),
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		})
+	}
+	return hostInfos
+}
+
+// TestStartMaintenanceRequireApproval tests that the approval of the
+// maintenance is required by the requester
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceRequireApproval() {
+	ctx := audit.WithInfo(suite.ctx, audit.Info{User: "alice"})
+	drainOptions := &hpb.DrainOptions{RequireApproval: true}
+	hostname := suite.upMachines[0].GetHostname()
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockApprovalOps.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(gomock.Any()),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: []string{hostname},
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue([]string{hostname}).Return(nil, nil),
+	)
+
+	_, err := suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
+	approval := suite.handler.approvalMap.Get(hostname)
+	suite.Equal("alice", approval.GetRequester())
+	suite.Empty(approval.GetApprover())
+
+	// Test that the requester is kept with a queued request
+	suite.handler.maintenanceFreeze.setFrozen(true)
+	resp, err := suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
+	suite.True(resp.GetQueued())
+	_, pending := suite.handler.maintenanceFreeze.get()
+	suite.Equal("alice", pending[0].GetRequester())
+}
+
+// TestStartMaintenanceRequireApprovalError tests the failures to start
+// maintenance requiring approval
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceRequireApprovalError() {
+	ctx := audit.WithInfo(suite.ctx, audit.Info{User: "alice"})
+	hostname := suite.upMachines[0].GetHostname()
+
+	// Test agent drain method
+	suite.handler.drainMethod = hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+	_, err := suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{RequireApproval: true},
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+	suite.handler.drainMethod = hpb.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE
+
+	// Test unidentified requester
+	_, err = suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{RequireApproval: true},
+		})
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	// Test requester identified by a client header, without authentication
+	suite.handler.authenticated = false
+	_, err = suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{RequireApproval: true},
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+	suite.handler.authenticated = true
+
+	// Test error while persisting the approval
+	suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+		Return(&mesosmaster.Response_GetMaintenanceSchedule{
+			Schedule: &mesosmaintenance.Schedule{},
+		}, nil)
+	suite.mockApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("fake Create error"))
+	_, err = suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{RequireApproval: true},
+		})
+	suite.True(yarpcerrors.IsInternal(err))
+
+	// Test that the approval is removed if the schedule is not posted
+	suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+		Return(&mesosmaster.Response_GetMaintenanceSchedule{
+			Schedule: &mesosmaintenance.Schedule{},
+		}, nil)
+	suite.mockApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("fake UpdateMaintenanceSchedule error"))
+	suite.mockApprovalOps.EXPECT().
+		Delete(gomock.Any(), hostname).
+		Return(nil)
+	_, err = suite.handler.StartMaintenance(ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{RequireApproval: true},
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+	suite.Nil(suite.handler.approvalMap.Get(hostname))
+}
+
+// TestApproveMaintenance tests approving the maintenance of a host by
+// another user than its requester
+func (suite *HostSvcHandlerTestSuite) TestApproveMaintenance() {
+	hostname := suite.requireApproval(true)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(suite.drainingHostInfos()).
+		AnyTimes()
+
+	// Test no hostnames
+	_, err := suite.handler.ApproveMaintenance(
+		suite.ctx,
+		&svcpb.ApproveMaintenanceRequest{})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Test approval by an unidentified user
+	_, err = suite.handler.ApproveMaintenance(
+		suite.ctx,
+		&svcpb.ApproveMaintenanceRequest{Hostnames: []string{hostname}})
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	// Test approval by the requester
+	_, err = suite.handler.ApproveMaintenance(
+		audit.WithInfo(suite.ctx, audit.Info{User: "alice"}),
+		&svcpb.ApproveMaintenanceRequest{Hostnames: []string{hostname}})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+
+	// Test host which does not require approval
+	_, err = suite.handler.ApproveMaintenance(
+		audit.WithInfo(suite.ctx, audit.Info{User: "bob"}),
+		&svcpb.ApproveMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+
+	_, err = suite.handler.ApproveMaintenance(
+		audit.WithInfo(suite.ctx, audit.Info{User: "bob"}),
+		&svcpb.ApproveMaintenanceRequest{Hostnames: []string{hostname}})
+	suite.NoError(err)
+	approval := suite.handler.approvalMap.Get(hostname)
+	suite.Equal("bob", approval.GetApprover())
+	suite.NotEmpty(approval.GetApproveTime())
+
+	// Test host already approved
+	_, err = suite.handler.ApproveMaintenance(
+		audit.WithInfo(suite.ctx, audit.Info{User: "carol"}),
+		&svcpb.ApproveMaintenanceRequest{Hostnames: []string{hostname}})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestApproveMaintenanceUnidentifiedRequester tests that the maintenance
+// requested by an unidentified user cannot be approved
+func (suite *HostSvcHandlerTestSuite) TestApproveMaintenanceUnidentifiedRequester() {
+	hostname := suite.drainingMachines[0].GetHostname()
+	suite.mockApprovalOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(suite.handler.approvalMap.Require(
+		suite.ctx, []string{hostname}, ""))
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(suite.drainingHostInfos())
+
+	_, err := suite.handler.ApproveMaintenance(
+		audit.WithInfo(suite.ctx, audit.Info{User: "bob"}),
+		&svcpb.ApproveMaintenanceRequest{Hostnames: []string{hostname}})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+	suite.True(host.IsApprovalPending(
+		suite.handler.approvalMap.Get(hostname)))
+}
+
+// TestGetMaintenanceApprovals tests getting all or only the pending
+// approvals
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceApprovals() {
+	resp, err := suite.handler.GetMaintenanceApprovals(
+		suite.ctx,
+		&svcpb.GetMaintenanceApprovalsRequest{})
+	suite.NoError(err)
+	suite.Empty(resp.GetApprovals())
+
+	hostname := suite.requireApproval(false)
+	suite.NoError(suite.handler.approvalMap.Require(
+		suite.ctx, []string{"host4"}, "alice"))
+	suite.NoError(suite.handler.approvalMap.Approve(suite.ctx, "host4", "bob"))
+
+	resp, err = suite.handler.GetMaintenanceApprovals(
+		suite.ctx,
+		&svcpb.GetMaintenanceApprovalsRequest{})
+	suite.NoError(err)
+	suite.Len(resp.GetApprovals(), 2)
+
+	resp, err = suite.handler.GetMaintenanceApprovals(
+		suite.ctx,
+		&svcpb.GetMaintenanceApprovalsRequest{PendingOnly: true})
+	suite.NoError(err)
+	suite.Len(resp.GetApprovals(), 1)
+	suite.Equal(hostname, resp.GetApprovals()[0].GetHostname())
+}
+
+// TestQueryHostsDrained tests that drained hosts waiting for approval
+// are returned as DRAINED
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsDrained() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(suite.drainingHostInfos()).
+		AnyTimes()
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil).
+		AnyTimes()
+	request := &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_DRAINING,
+			hpb.HostState_HOST_STATE_DRAINED,
+		},
+	}
+
+	// Not drained yet
+	hostname := suite.requireApproval(false)
+	resp, err := suite.handler.QueryHosts(suite.ctx, request)
+	suite.NoError(err)
+	suite.Len(resp.GetHostInfos(), 1)
+	suite.Equal(
+		hpb.HostState_HOST_STATE_DRAINING,
+		resp.GetHostInfos()[0].GetState())
+
+	_, err = suite.handler.approvalMap.MarkDrained(suite.ctx, hostname)
+	suite.NoError(err)
+	resp, err = suite.handler.QueryHosts(suite.ctx, request)
+	suite.NoError(err)
+	suite.Len(resp.GetHostInfos(), 1)
+	suite.Equal(hostname, resp.GetHostInfos()[0].GetHostname())
+	suite.Equal(
+		hpb.HostState_HOST_STATE_DRAINED,
+		resp.GetHostInfos()[0].GetState())
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// _maxFinishedCanaryDrains is the number of canary drains kept once
+	// they proceeded or were aborted.
+	_maxFinishedCanaryDrains = 100
+)
+
+// canaryDrain is a canary drain started by StartMaintenance.
+type canaryDrain struct {
+	drain *hpb.CanaryDrain
+
+	// drainOptions and requester of the request, which the remaining
+	// hosts are drained with
+	drainOptions *hpb.DrainOptions
+	requester    string
+
+	// downHosts are the canary hosts which are down
+	downHosts stringset.StringSet
+	// canaryTasks are the Mesos task ids running on the canary hosts
+	// when the canary drain was started
+	canaryTasks []string
+}
+
+// canaryDrains keeps the canary drains started by StartMaintenance, in
+// memory of the leader.
+type canaryDrains struct {
+	sync.Mutex
+
+	drains []*canaryDrain
+}
+
+// add adds a canary drain.
+func (c *canaryDrains) add(d *canaryDrain) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.drains = append(c.drains, d)
+}
+
+// get returns a copy of the canary drains, oldest first.
+func (c *canaryDrains) get() []*hpb.CanaryDrain {
+	c.Lock()
+	defer c.Unlock()
+
+	var drains []*hpb.CanaryDrain
+	for _, d := range c.drains {
+		drains = append(drains, proto.Clone(d.drain).(*hpb.CanaryDrain))
+	}
+	return drains
+}
+
+// hostsDown records that the given hosts are down, and returns the
+// canary drains whose canary hosts are now all down. They are moved to
+// CANARY_DRAIN_STATE_OBSERVING.
+func (c *canaryDrains) hostsDown(
+	hostnames []string,
+	now time.Time) []*canaryDrain {
+	c.Lock()
+	defer c.Unlock()
+
+	var observing []*canaryDrain
+	for _, d := range c.drains {
+		if d.drain.GetState() != hpb.CanaryDrainState_CANARY_DRAIN_STATE_DRAINING {
+			continue
+		}
+		canaryHosts := stringset.FromSlice(d.drain.GetCanaryHostnames())
+		for _, hostname := range hostnames {
+			if canaryHosts.Contains(hostname) {
+				d.downHosts.Add(hostname)
+			}
+		}
+		if d.downHosts.Len() < canaryHosts.Len() {
+			continue
+		}
+		observation := time.Duration(
+			d.drain.GetOptions().GetObservationSeconds()) * time.Second
+		d.drain.State = hpb.CanaryDrainState_CANARY_DRAIN_STATE_OBSERVING
+		d.drain.ObservationEndTime = now.Add(observation).
+			UTC().Format(time.RFC3339)
+		observing = append(observing, d)
+	}
+	return observing
+}
+
+// observed returns the canary drain with the given id if it is in
+// CANARY_DRAIN_STATE_OBSERVING, nil otherwise.
+func (c *canaryDrains) observed(id string) *canaryDrain {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, d := range c.drains {
+		if d.drain.GetId() == id &&
+			d.drain.GetState() == hpb.CanaryDrainState_CANARY_DRAIN_STATE_OBSERVING {
+			return d
+		}
+	}
+	return nil
+}
+
+// finish records the outcome of a canary drain, and drops the oldest
+// finished canary drains beyond _maxFinishedCanaryDrains.
+func (c *canaryDrains) finish(
+	id string,
+	state hpb.CanaryDrainState,
+	rescheduleRate float64,
+	message string) {
+	c.Lock()
+	defer c.Unlock()
+
+	finished := 0
+	for i := len(c.drains) - 1; i >= 0; i-- {
+		d := c.drains[i]
+		if d.drain.GetId() == id {
+			d.drain.State = state
+			d.drain.RescheduleRate = rescheduleRate
+			d.drain.Message = message
+		}
+		if !isCanaryDrainFinished(d.drain) {
+			continue
+		}
+		finished++
+		if finished > _maxFinishedCanaryDrains {
+			c.drains = append(c.drains[:i], c.drains[i+1:]...)
+		}
+	}
+}
+
+// isCanaryDrainFinished returns true if the canary drain proceeded or
+// was aborted.
+func isCanaryDrainFinished(drain *hpb.CanaryDrain) bool {
+	return drain.GetState() == hpb.CanaryDrainState_CANARY_DRAIN_STATE_PROCEEDED ||
+		drain.GetState() == hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED
+}
+
+// isCanaryDrain returns true if a request to drain the given number of
+// hosts with the drain options is a canary drain, i.e. it has more hosts
+// than canary hosts.
+func isCanaryDrain(drainOptions *hpb.DrainOptions, hostCount int) bool {
+	count := drainOptions.GetCanary().GetCount()
+	return count > 0 && int(count) < hostCount
+}
+
+// hostDrainOptions returns the drain options the hosts of a request are
+// drained with, i.e. without its canary options.
+func hostDrainOptions(drainOptions *hpb.DrainOptions) *hpb.DrainOptions {
+	if drainOptions.GetCanary() == nil {
+		return drainOptions
+	}
+	options := proto.Clone(drainOptions).(*hpb.DrainOptions)
+	options.Canary = nil
+	return options
+}
+
+// startMaintenanceRequest starts maintenance on the hosts of a request,
+// as a canary drain if its drain options have canary options. Returns
+// the id of the canary drain, if any, and the hosts skipped by the
+// maintenance queue.
+func (m *serviceHandler) startMaintenanceRequest(
+	ctx context.Context,
+	hostnames []string,
+	drainOptions *hpb.DrainOptions,
+	requester string) (string, []string, error) {
+	if !isCanaryDrain(drainOptions, len(hostnames)) {
+		skipped, err := m.startMaintenance(
+			ctx,
+			hostnames,
+			hostDrainOptions(drainOptions),
+			requester)
+		return "", skipped, err
+	}
+
+	count := drainOptions.GetCanary().GetCount()
+	canaryHosts := hostnames[:count]
+	var canaryTasks []string
+	for _, taskIDs := range m.hostTaskIndex.GetTasksByHosts(canaryHosts) {
+		canaryTasks = append(canaryTasks, taskIDs...)
+	}
+
+	options := hostDrainOptions(drainOptions)
+	skipped, err := m.startMaintenance(
+		ctx,
+		canaryHosts,
+		options,
+		requester)
+	if err != nil {
+		return "", nil, err
+	}
+
+	d := &canaryDrain{
+		drain: &hpb.CanaryDrain{
+			Id:                 uuid.New(),
+			CanaryHostnames:    canaryHosts,
+			RemainingHostnames: hostnames[count:],
+			State:              hpb.CanaryDrainState_CANARY_DRAIN_STATE_DRAINING,
+			Options:            drainOptions.GetCanary(),
+			StartTime:          time.Now().UTC().Format(time.RFC3339),
+		},
+		drainOptions: options,
+		requester:    requester,
+		downHosts:    stringset.New(),
+		canaryTasks:  canaryTasks,
+	}
+	m.canaryDrains.add(d)
+	m.metrics.CanaryDrainsStarted.Inc(1)
+	log.WithFields(log.Fields{
+		"canary_drain_id": d.drain.GetId(),
+		"canary_hosts":    canaryHosts,
+		"remaining_hosts": d.drain.GetRemainingHostnames(),
+	}).Info("Canary drain started")
+	return d.drain.GetId(), skipped, nil
+}
+
+// subscribeCanaryDrains subscribes the canary drains to the host state
+// transitions, to start observing them once their canary hosts are down.
+func (m *serviceHandler) subscribeCanaryDrains() (eventbus.Subscription, error) {
+	return m.eventBus.Subscribe(eventbus.Subscriber{
+		Name:   "canary_drains",
+		Topics: []eventbus.Topic{eventbus.HostStateChanged},
+		Policy: eventbus.Block,
+		Handler: func(event eventbus.Event) {
+			change := event.(*eventbus.HostStateChangedEvent)
+			if change.To != hpb.HostState_HOST_STATE_DOWN {
+				return
+			}
+			m.canaryHostsDown(change.Hostnames)
+		},
+	})
+}
+
+// canaryHostsDown starts observing the canary drains whose canary hosts
+// are all down.
+func (m *serviceHandler) canaryHostsDown(hostnames []string) {
+	for _, d := range m.canaryDrains.hostsDown(hostnames, time.Now()) {
+		id := d.drain.GetId()
+		observation := time.Duration(
+			d.drain.GetOptions().GetObservationSeconds()) * time.Second
+		log.WithFields(log.Fields{
+			"canary_drain_id": id,
+			"observation":     observation,
+		}).Info("Canary hosts down, observing rescheduling of their tasks")
+		m.afterFunc(observation, func() {
+			m.evaluateCanaryDrain(id)
+		})
+	}
+}
+
+// evaluateCanaryDrain drains the remaining hosts of a canary drain at
+// the end of its observation, if enough tasks of its canary hosts were
+// rescheduled, and aborts it otherwise. The remaining hosts are queued
+// if maintenance is frozen.
+func (m *serviceHandler) evaluateCanaryDrain(id string) {
+	d := m.canaryDrains.observed(id)
+	if d == nil {
+		return
+	}
+
+	rate := m.rescheduleRate(d.drain.GetCanaryHostnames(), d.canaryTasks)
+	remaining := d.drain.GetRemainingHostnames()
+	logger := log.WithFields(log.Fields{
+		"canary_drain_id": id,
+		"reschedule_rate": rate,
+		"remaining_hosts": remaining,
+	})
+	abort := func(message string) {
+		m.canaryDrains.finish(
+			id, hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED, rate, message)
+		m.metrics.CanaryDrainsAborted.Inc(1)
+		logger.WithField("message", message).Warn("Canary drain aborted")
+	}
+
+	if !m.candidate.IsLeader() {
+		abort("host manager is no longer the leader")
+		return
+	}
+	if minRate := d.drain.GetOptions().GetMinRescheduleRate(); rate < minRate {
+		abort(fmt.Sprintf(
+			"reschedule rate %.2f is below %.2f", rate, minRate))
+		return
+	}
+
+	message := fmt.Sprintf("reschedule rate %.2f", rate)
+	if m.maintenanceFreeze.queue(
+		remaining,
+		d.drainOptions,
+		d.requester,
+		time.Now()) {
+		m.reportMaintenanceFreeze()
+		message += ", remaining hosts queued as maintenance is frozen"
+	} else if _, err := m.startMaintenance(
+		context.Background(),
+		remaining,
+		d.drainOptions,
+		d.requester); err != nil {
+		abort(fmt.Sprintf("failed to drain remaining hosts: %v", err))
+		return
+	}
+	m.canaryDrains.finish(
+		id, hpb.CanaryDrainState_CANARY_DRAIN_STATE_PROCEEDED, rate, message)
+	m.metrics.CanaryDrainsProceeded.Inc(1)
+	logger.Info("Canary drain proceeded")
+}
+
+// rescheduleRate returns the fraction of the given tasks of the canary
+// hosts whose Peloton task runs on another host, 1 if there are none.
+func (m *serviceHandler) rescheduleRate(
+	canaryHosts []string,
+	canaryTasks []string) float64 {
+	if len(canaryTasks) == 0 {
+		return 1
+	}
+
+	canaryHostSet := stringset.FromSlice(canaryHosts)
+	running := stringset.NewUnsafe()
+	for hostname, taskIDs := range m.hostTaskIndex.GetTasksByHosts(nil) {
+		if canaryHostSet.Contains(hostname) {
+			continue
+		}
+		for _, taskID := range taskIDs {
+			if pelotonTaskID, err := util.ParseTaskIDFromMesosTaskID(
+				taskID); err == nil {
+				running.Add(pelotonTaskID)
+			}
+		}
+	}
+
+	rescheduled := 0
+	for _, taskID := range canaryTasks {
+		pelotonTaskID, err := util.ParseTaskIDFromMesosTaskID(taskID)
+		if err == nil && running.Contains(pelotonTaskID) {
+			rescheduled++
+		}
+	}
+	return float64(rescheduled) / float64(len(canaryTasks))
+}
+
+// GetCanaryDrains returns the canary drains started by StartMaintenance.
+func (m *serviceHandler) GetCanaryDrains(
+	ctx context.Context,
+	request *host_svc.GetCanaryDrainsRequest,
+) (*host_svc.GetCanaryDrainsResponse, error) {
+	m.metrics.GetCanaryDrainsAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.GetCanaryDrainsFail.Inc(1)
+		return nil, err
+	}
+
+	m.metrics.GetCanaryDrainsSuccess.Inc(1)
+	return &host_svc.GetCanaryDrainsResponse{
+		CanaryDrains: m.canaryDrains.get(),
+	}, nil
+}
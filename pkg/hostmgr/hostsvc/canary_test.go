@@ -0,0 +1,294 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+	"time"
+
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_canaryJobID = "7ac74273-4ef0-4ca4-8fd2-34bc52aeac06"
+)
+
+// expectStartMaintenance sets the expectations to start maintenance on
+// the UP host
+func (suite *HostSvcHandlerTestSuite) expectStartMaintenance() {
+	hostname := suite.upMachines[0].GetHostname()
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos([]*hpb.HostInfo{{
+				Hostname: hostname,
+				Ip:       suite.upMachines[0].GetIp(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+			}}),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: []string{hostname},
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue([]string{hostname}).Return(nil, nil),
+	)
+}
+
+// observeCanaryDrain adds a canary drain of the UP host, observing the
+// rescheduling of a task of host4, and returns its id
+func (suite *HostSvcHandlerTestSuite) observeCanaryDrain(minRate float64) string {
+	d := &canaryDrain{
+		drain: &hpb.CanaryDrain{
+			Id:                 "canary1",
+			CanaryHostnames:    []string{"host4"},
+			RemainingHostnames: []string{suite.upMachines[0].GetHostname()},
+			State:              hpb.CanaryDrainState_CANARY_DRAIN_STATE_DRAINING,
+			Options: &hpb.CanaryOptions{
+				Count:             1,
+				MinRescheduleRate: minRate,
+			},
+		},
+		downHosts:   stringset.New(),
+		canaryTasks: []string{_canaryJobID + "-0-1", _canaryJobID + "-1-1"},
+	}
+	suite.handler.canaryDrains.add(d)
+	suite.Len(suite.handler.canaryDrains.hostsDown(
+		[]string{"host4"}, time.Now()), 1)
+	return d.drain.GetId()
+}
+
+// TestStartMaintenanceCanary tests that only the canary hosts are
+// drained first, and that their observation starts once they are down
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceCanary() {
+	hostname := suite.upMachines[0].GetHostname()
+	drainOptions := &hpb.DrainOptions{
+		KillGracePeriodSeconds: 60,
+		Canary: &hpb.CanaryOptions{
+			Count:              1,
+			ObservationSeconds: 600,
+			MinRescheduleRate:  0.9,
+		},
+	}
+
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts([]string{hostname}).
+		Return(map[string][]string{hostname: {_canaryJobID + "-0-1"}})
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		// The canary options are not recorded for the hosts
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos([]*hpb.HostInfo{{
+				Hostname: hostname,
+				Ip:       suite.upMachines[0].GetIp(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: &hpb.DrainOptions{
+					KillGracePeriodSeconds: 60,
+				},
+			}}),
+		suite.mockEventBus.EXPECT().
+			Publish(gomock.Any()),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue([]string{hostname}).Return(nil, nil),
+	)
+
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname, "host4"},
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
+	suite.NotEmpty(resp.GetCanaryDrainId())
+
+	drainsResp, err := suite.handler.GetCanaryDrains(
+		suite.ctx,
+		&svcpb.GetCanaryDrainsRequest{})
+	suite.NoError(err)
+	suite.Len(drainsResp.GetCanaryDrains(), 1)
+	drain := drainsResp.GetCanaryDrains()[0]
+	suite.Equal(resp.GetCanaryDrainId(), drain.GetId())
+	suite.Equal([]string{hostname}, drain.GetCanaryHostnames())
+	suite.Equal([]string{"host4"}, drain.GetRemainingHostnames())
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_DRAINING, drain.GetState())
+
+	// Other hosts going down do not start the observation
+	suite.handler.canaryHostsDown([]string{"host5"})
+
+	var observation time.Duration
+	suite.handler.afterFunc = func(d time.Duration, f func()) {
+		observation = d
+	}
+	suite.handler.canaryHostsDown([]string{hostname})
+	suite.Equal(600*time.Second, observation)
+	drain = suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_OBSERVING, drain.GetState())
+	suite.NotEmpty(drain.GetObservationEndTime())
+}
+
+// TestStartMaintenanceCanaryAllHosts tests that requests with no more
+// hosts than canary hosts are not canary drains
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceCanaryAllHosts() {
+	suite.expectStartMaintenance()
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+			DrainOptions: &hpb.DrainOptions{
+				Canary: &hpb.CanaryOptions{Count: 1},
+			},
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetCanaryDrainId())
+	suite.Empty(suite.handler.canaryDrains.get())
+}
+
+// TestStartMaintenanceCanaryInvalidRate tests that the minimum
+// reschedule rate must be between 0 and 1
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceCanaryInvalidRate() {
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname(), "host4"},
+			DrainOptions: &hpb.DrainOptions{
+				Canary: &hpb.CanaryOptions{Count: 1, MinRescheduleRate: 1.5},
+			},
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestEvaluateCanaryDrainProceed tests that the remaining hosts are
+// drained if enough tasks of the canary hosts were rescheduled
+func (suite *HostSvcHandlerTestSuite) TestEvaluateCanaryDrainProceed() {
+	id := suite.observeCanaryDrain(0.5)
+
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts(nil).
+		Return(map[string][]string{
+			"host4": {_canaryJobID + "-1-1"},
+			"host5": {_canaryJobID + "-0-2", "invalid"},
+		})
+	suite.expectStartMaintenance()
+	suite.handler.evaluateCanaryDrain(id)
+
+	drain := suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_PROCEEDED, drain.GetState())
+	suite.Equal(0.5, drain.GetRescheduleRate())
+
+	// Canary drains are only evaluated once
+	suite.handler.evaluateCanaryDrain(id)
+}
+
+// TestEvaluateCanaryDrainAbort tests that the remaining hosts are not
+// drained if too few tasks of the canary hosts were rescheduled, or
+// cannot be drained
+func (suite *HostSvcHandlerTestSuite) TestEvaluateCanaryDrainAbort() {
+	id := suite.observeCanaryDrain(0.9)
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts(nil).
+		Return(map[string][]string{
+			"host5": {_canaryJobID + "-0-2"},
+		})
+	suite.handler.evaluateCanaryDrain(id)
+	drain := suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED, drain.GetState())
+	suite.Equal(0.5, drain.GetRescheduleRate())
+	suite.NotEmpty(drain.GetMessage())
+
+	// Test failing to drain the remaining hosts
+	suite.handler.canaryDrains = &canaryDrains{}
+	id = suite.observeCanaryDrain(0.5)
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts(nil).
+		Return(map[string][]string{
+			"host5": {_canaryJobID + "-0-2"},
+		})
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+	suite.handler.evaluateCanaryDrain(id)
+	drain = suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED, drain.GetState())
+}
+
+// TestEvaluateCanaryDrainNotLeader tests that canary drains are
+// aborted if host manager is no longer the leader
+func (suite *HostSvcHandlerTestSuite) TestEvaluateCanaryDrainNotLeader() {
+	id := suite.observeCanaryDrain(0)
+	candidate := leadermocks.NewMockCandidate(suite.mockCtrl)
+	suite.handler.candidate = candidate
+	candidate.EXPECT().IsLeader().Return(false)
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts(nil).
+		Return(nil)
+	suite.handler.evaluateCanaryDrain(id)
+
+	drain := suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED, drain.GetState())
+}
+
+// TestEvaluateCanaryDrainFrozen tests that the remaining hosts are
+// queued if maintenance is frozen
+func (suite *HostSvcHandlerTestSuite) TestEvaluateCanaryDrainFrozen() {
+	id := suite.observeCanaryDrain(0)
+	suite.handler.maintenanceFreeze.setFrozen(true)
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts(nil).
+		Return(nil)
+	suite.handler.evaluateCanaryDrain(id)
+
+	drain := suite.handler.canaryDrains.get()[0]
+	suite.Equal(hpb.CanaryDrainState_CANARY_DRAIN_STATE_PROCEEDED, drain.GetState())
+	suite.Equal(1, suite.handler.maintenanceFreeze.hostCount())
+}
+
+// TestCanaryDrainsFinishedLimit tests that the oldest finished canary
+// drains are dropped
+func (suite *HostSvcHandlerTestSuite) TestCanaryDrainsFinishedLimit() {
+	drains := &canaryDrains{}
+	drains.add(&canaryDrain{drain: &hpb.CanaryDrain{
+		Id:    "draining",
+		State: hpb.CanaryDrainState_CANARY_DRAIN_STATE_DRAINING,
+	}})
+	for i := 0; i <= _maxFinishedCanaryDrains; i++ {
+		id := fmt.Sprintf("drain%d", i)
+		drains.add(&canaryDrain{drain: &hpb.CanaryDrain{Id: id}})
+		drains.finish(
+			id, hpb.CanaryDrainState_CANARY_DRAIN_STATE_ABORTED, 0, "")
+	}
+
+	result := drains.get()
+	suite.Len(result, _maxFinishedCanaryDrains+1)
+	suite.Equal("draining", result[0].GetId())
+	suite.Equal("drain1", result[1].GetId())
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/logging"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// DecommissionHosts terminates the machines of the specified DOWN hosts
+// with the host provider, and takes the hosts out of maintenance so that
+// host manager no longer tracks them. Hosts whose agents were drained by
+// Mesos Master are left for Mesos Master to remove once their agents are
+// gone. Each host is decommissioned on its own, and hosts which are not
+// DOWN or fail to be decommissioned are returned as failures of the
+// response instead of failing the whole request.
+func (m *serviceHandler) DecommissionHosts(
+	ctx context.Context,
+	request *host_svc.DecommissionHostsRequest,
+) (*host_svc.DecommissionHostsResponse, error) {
+	m.metrics.DecommissionHostsAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.DecommissionHostsFail.Inc(1)
+		return nil, err
+	}
+	if m.hostProvider == nil {
+		m.metrics.DecommissionHostsFail.Inc(1)
+		return nil, yarpcerrors.FailedPreconditionErrorf(
+			"no host provider is configured to decommission hosts")
+	}
+
+	downHostInfos := m.maintenanceHostInfoMap.GetDownHostInfos([]string{})
+	downHostInfoMap := make(map[string]*hpb.HostInfo)
+	for _, hostInfo := range downHostInfos {
+		downHostInfoMap[hostInfo.GetHostname()] = hostInfo
+	}
+
+	hostnames, mappings, err := m.resolveHostnames(
+		request.GetHostnames(),
+		downHostInfos)
+	if err != nil {
+		m.metrics.DecommissionHostsFail.Inc(1)
+		return nil, err
+	}
+	ctx = logging.WithHosts(ctx, hostnames)
+
+	response := &host_svc.DecommissionHostsResponse{
+		HostnameMappings: mappings,
+	}
+	for _, hostname := range hostnames {
+		hostInfo, ok := downHostInfoMap[hostname]
+		if !ok {
+			response.Failures = append(response.Failures,
+				&host_svc.DecommissionHostsFailure{
+					Hostname: hostname,
+					Message:  "host is not DOWN",
+				})
+			continue
+		}
+		err := m.runProviderOperation(
+			ctx,
+			hostInfo,
+			m.hostProvider.Terminate,
+			hpb.HostEventType_HOST_EVENT_TYPE_TERMINATED)
+		if err == nil && !isAgentDrain(hostInfo) {
+			// The machine is gone, remove it from the maintenance
+			// schedule of Mesos Master
+			err = m.stopHostMaintenance(ctx, hostInfo)
+		}
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).
+				WithField("hostname", hostname).
+				Warn("Failed to decommission host")
+			response.Failures = append(response.Failures,
+				&host_svc.DecommissionHostsFailure{
+					Hostname: hostname,
+					Message:  err.Error(),
+				})
+			continue
+		}
+		response.DecommissionedHostnames = append(
+			response.DecommissionedHostnames, hostname)
+	}
+
+	if len(response.GetDecommissionedHostnames()) > 0 {
+		m.maintenanceHostInfoMap.RemoveHostInfos(
+			response.GetDecommissionedHostnames())
+		logging.FromContext(ctx).
+			WithField("decommissioned_hosts", response.GetDecommissionedHostnames()).
+			Info("Hosts decommissioned")
+	}
+
+	if len(response.GetFailures()) > 0 {
+		m.metrics.DecommissionHostsFail.Inc(1)
+	} else {
+		m.metrics.DecommissionHostsSuccess.Inc(1)
+	}
+	return response, nil
+}
+
+// runProviderOperation runs an operation of the host provider on the
+// machine of the host, and records its outcome in the events of the host.
+func (m *serviceHandler) runProviderOperation(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo,
+	operation func(context.Context, *hpb.HostInfo) error,
+	eventType hpb.HostEventType) error {
+	m.metrics.HostProviderOperations.Inc(1)
+	if err := operation(ctx, hostInfo); err != nil {
+		m.metrics.HostProviderOperationFail.Inc(1)
+		m.hostEventLog.Record(
+			ctx,
+			hostInfo.GetHostname(),
+			hpb.HostEventType_HOST_EVENT_TYPE_PROVIDER_FAILED,
+			eventType.String()+": "+err.Error())
+		return newInternalError(err, "%s provider failed on host %s",
+			m.hostProvider.Name(), hostInfo.GetHostname())
+	}
+	m.hostEventLog.Record(
+		ctx,
+		hostInfo.GetHostname(),
+		eventType,
+		m.hostProvider.Name())
+	return nil
+}
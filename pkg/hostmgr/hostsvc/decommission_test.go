@@ -0,0 +1,202 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// downHostInfo returns the host info of the DOWN host
+func (suite *HostSvcHandlerTestSuite) downHostInfo() *hpb.HostInfo {
+	machine := suite.downMachines[0]
+	return &hpb.HostInfo{
+		Hostname: machine.GetHostname(),
+		Ip:       machine.GetIp(),
+		State:    hpb.HostState_HOST_STATE_DOWN,
+	}
+}
+
+// TestDecommissionHosts tests terminating the machine of a DOWN host
+// and taking it out of maintenance
+func (suite *HostSvcHandlerTestSuite) TestDecommissionHosts() {
+	suite.handler.hostProvider = suite.mockHostProvider
+	hostInfo := suite.downHostInfo()
+
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{hostInfo})
+	gomock.InOrder(
+		suite.mockHostProvider.EXPECT().
+			Terminate(gomock.Any(), hostInfo).
+			Return(nil),
+		suite.mockHostEventLog.EXPECT().
+			Record(
+				gomock.Any(),
+				hostInfo.GetHostname(),
+				hpb.HostEventType_HOST_EVENT_TYPE_TERMINATED,
+				"AWS"),
+		suite.mockMasterOperatorClient.EXPECT().
+			StopMaintenance(gomock.Any(), suite.downMachines).
+			Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			RemoveHostInfos([]string{hostInfo.GetHostname()}),
+	)
+
+	resp, err := suite.handler.DecommissionHosts(suite.ctx,
+		&svcpb.DecommissionHostsRequest{
+			Hostnames: []string{"typo-host", hostInfo.GetHostname()},
+		})
+	suite.NoError(err)
+	suite.Equal(
+		[]string{hostInfo.GetHostname()},
+		resp.GetDecommissionedHostnames())
+	suite.Len(resp.GetFailures(), 1)
+	suite.Equal("typo-host", resp.GetFailures()[0].GetHostname())
+}
+
+// TestDecommissionHostsAgentDrain tests that the maintenance schedule is
+// not updated for hosts whose agents were drained by Mesos Master
+func (suite *HostSvcHandlerTestSuite) TestDecommissionHostsAgentDrain() {
+	suite.handler.hostProvider = suite.mockHostProvider
+	hostInfo := suite.downHostInfo()
+	hostInfo.DrainOptions = &hpb.DrainOptions{
+		Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+	}
+
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{hostInfo})
+	suite.mockHostProvider.EXPECT().
+		Terminate(gomock.Any(), hostInfo).
+		Return(nil)
+	suite.mockHostEventLog.EXPECT().
+		Record(gomock.Any(), hostInfo.GetHostname(),
+			hpb.HostEventType_HOST_EVENT_TYPE_TERMINATED, "AWS")
+	suite.mockMaintenanceMap.EXPECT().
+		RemoveHostInfos([]string{hostInfo.GetHostname()})
+
+	resp, err := suite.handler.DecommissionHosts(suite.ctx,
+		&svcpb.DecommissionHostsRequest{
+			Hostnames: []string{hostInfo.GetHostname()},
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetFailures())
+}
+
+// TestDecommissionHostsErrors tests the failures of decommissioning hosts
+func (suite *HostSvcHandlerTestSuite) TestDecommissionHostsErrors() {
+	hostInfo := suite.downHostInfo()
+
+	// No host provider
+	_, err := suite.handler.DecommissionHosts(suite.ctx,
+		&svcpb.DecommissionHostsRequest{
+			Hostnames: []string{hostInfo.GetHostname()},
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+
+	// Provider failure, the host stays in maintenance
+	suite.handler.hostProvider = suite.mockHostProvider
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{hostInfo})
+	suite.mockHostProvider.EXPECT().
+		Terminate(gomock.Any(), hostInfo).
+		Return(errors.New("fake Terminate error"))
+	suite.mockHostEventLog.EXPECT().
+		Record(
+			gomock.Any(),
+			hostInfo.GetHostname(),
+			hpb.HostEventType_HOST_EVENT_TYPE_PROVIDER_FAILED,
+			"HOST_EVENT_TYPE_TERMINATED: fake Terminate error")
+
+	resp, err := suite.handler.DecommissionHosts(suite.ctx,
+		&svcpb.DecommissionHostsRequest{
+			Hostnames: []string{hostInfo.GetHostname()},
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetDecommissionedHostnames())
+	suite.Len(resp.GetFailures(), 1)
+	suite.Contains(resp.GetFailures()[0].GetMessage(), "fake Terminate error")
+}
+
+// TestCompleteMaintenanceReboot tests rebooting the machines of hosts
+// before completing their maintenance
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceReboot() {
+	hostInfo := suite.downHostInfo()
+	request := &svcpb.CompleteMaintenanceRequest{
+		Hostnames: []string{hostInfo.GetHostname()},
+		Reboot:    true,
+	}
+
+	// No host provider
+	_, err := suite.handler.CompleteMaintenance(suite.ctx, request)
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+
+	suite.handler.hostProvider = suite.mockHostProvider
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{hostInfo})
+	gomock.InOrder(
+		suite.mockHostProvider.EXPECT().
+			Reboot(gomock.Any(), hostInfo).
+			Return(nil),
+		suite.mockHostEventLog.EXPECT().
+			Record(
+				gomock.Any(),
+				hostInfo.GetHostname(),
+				hpb.HostEventType_HOST_EVENT_TYPE_REBOOTED,
+				"AWS"),
+		suite.mockMasterOperatorClient.EXPECT().
+			StopMaintenance(gomock.Any(), []*mesos.MachineID{suite.downMachines[0]}).
+			Return(nil),
+	)
+	suite.mockMaintenanceMap.EXPECT().
+		RemoveHostInfos([]string{hostInfo.GetHostname()})
+	suite.mockEventBus.EXPECT().
+		Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: []string{hostInfo.GetHostname()},
+			From:      hpb.HostState_HOST_STATE_DOWN,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
+
+	resp, err := suite.handler.CompleteMaintenance(suite.ctx, request)
+	suite.NoError(err)
+	suite.Equal(request.GetHostnames(), resp.GetCompletedHostnames())
+
+	// Reboot failure, maintenance is not completed
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{hostInfo})
+	suite.mockHostProvider.EXPECT().
+		Reboot(gomock.Any(), hostInfo).
+		Return(errors.New("fake Reboot error"))
+	suite.mockHostEventLog.EXPECT().
+		Record(gomock.Any(), hostInfo.GetHostname(),
+			hpb.HostEventType_HOST_EVENT_TYPE_PROVIDER_FAILED, gomock.Any())
+
+	resp, err = suite.handler.CompleteMaintenance(suite.ctx, request)
+	suite.NoError(err)
+	suite.Empty(resp.GetCompletedHostnames())
+	suite.Len(resp.GetFailures(), 1)
+}
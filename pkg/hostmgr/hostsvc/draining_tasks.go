@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/common/util"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// withDrainingTasks returns a copy of the HostInfo of a DRAINING host with
+// the tasks still running on the host, from the task-to-host index, and
+// how long they have resisted draining, from the events of the host.
+func (m *serviceHandler) withDrainingTasks(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) *hpb.HostInfo {
+	hostname := hostInfo.GetHostname()
+	result := proto.Clone(hostInfo).(*hpb.HostInfo)
+
+	taskIDs := m.hostTaskIndex.GetTasksByHosts([]string{hostname})[hostname]
+	if len(taskIDs) == 0 {
+		return result
+	}
+
+	since, ok := m.getEvictingSince(ctx, hostname)
+	for _, taskID := range taskIDs {
+		task := &hpb.DrainingTask{TaskId: taskID}
+		if jobID, instanceID, err := util.ParseJobAndInstanceID(
+			taskID); err == nil {
+			task.JobId = &peloton.JobID{Value: jobID}
+			task.InstanceId = instanceID
+		}
+		if ok {
+			task.EvictingSince = since.UTC().Format(time.RFC3339)
+			task.ResistingSeconds = uint32(time.Since(since).Seconds())
+		}
+		result.DrainingTasks = append(result.DrainingTasks, task)
+	}
+	return result
+}
+
+// getEvictingSince returns when the tasks of a host started to be
+// evicted, i.e. when they were first handed out to be evicted since the
+// last drain of the host started, or when the drain started if they were
+// not yet. It returns false if neither is known.
+func (m *serviceHandler) getEvictingSince(
+	ctx context.Context,
+	hostname string) (time.Time, bool) {
+	events, err := m.hostEventLog.Get(ctx, hostname, time.Time{})
+	if err != nil {
+		log.WithError(err).
+			WithField("hostname", hostname).
+			Warn("Cannot get the events of a draining host")
+		return time.Time{}, false
+	}
+
+	var since time.Time
+	evicted := false
+	for _, event := range events {
+		eventTime, err := time.Parse(time.RFC3339, event.GetEventTime())
+		if err != nil {
+			continue
+		}
+		switch event.GetType() {
+		case hpb.HostEventType_HOST_EVENT_TYPE_DRAIN_STARTED:
+			since, evicted = eventTime, false
+		case hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED:
+			if !evicted {
+				since, evicted = eventTime, true
+			}
+		}
+	}
+	return since, !since.IsZero()
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/golang/mock/gomock"
+)
+
+// TestQueryHostsTaskDetails tests querying the tasks still running on
+// draining hosts, and how long they have resisted draining.
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsTaskDetails() {
+	jobID := "b64fd26b-0e39-41b7-b22a-205b69f247bd"
+	taskID := jobID + "-3-1"
+	drainingHostInfos := []*hpb.HostInfo{
+		{Hostname: "host1", State: hpb.HostState_HOST_STATE_DRAINING},
+		{Hostname: "host2", State: hpb.HostState_HOST_STATE_DRAINING},
+		{Hostname: "host3", State: hpb.HostState_HOST_STATE_DRAINING},
+	}
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(drainingHostInfos).
+		AnyTimes()
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil).
+		AnyTimes()
+
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts([]string{"host1"}).
+		Return(map[string][]string{"host1": {taskID, "unparsable"}})
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts([]string{"host2"}).
+		Return(map[string][]string{"host2": {taskID}})
+	suite.mockHostTaskIndex.EXPECT().
+		GetTasksByHosts([]string{"host3"}).
+		Return(map[string][]string{})
+
+	evicted := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	event := func(eventType hpb.HostEventType, t time.Time) *hpb.HostEvent {
+		return &hpb.HostEvent{
+			Hostname:  "host1",
+			Type:      eventType,
+			EventTime: t.Format(time.RFC3339),
+		}
+	}
+	suite.mockHostEventLog.EXPECT().
+		Get(gomock.Any(), "host1", time.Time{}).
+		Return([]*hpb.HostEvent{
+			event(hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+				evicted.Add(-48*time.Hour)),
+			event(hpb.HostEventType_HOST_EVENT_TYPE_DRAIN_STARTED,
+				evicted.Add(-time.Minute)),
+			event(hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED, evicted),
+			event(hpb.HostEventType_HOST_EVENT_TYPE_TASKS_EVICTED,
+				evicted.Add(time.Minute)),
+		}, nil)
+	suite.mockHostEventLog.EXPECT().
+		Get(gomock.Any(), "host2", time.Time{}).
+		Return(nil, errors.New("storage error"))
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_DRAINING,
+		},
+		IncludeTaskDetails: true,
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetHostInfos(), 3)
+
+	tasks := resp.GetHostInfos()[0].GetDrainingTasks()
+	suite.Len(tasks, 2)
+	suite.Equal(taskID, tasks[0].GetTaskId())
+	suite.Equal(jobID, tasks[0].GetJobId().GetValue())
+	suite.Equal(uint32(3), tasks[0].GetInstanceId())
+	suite.Equal(evicted.Format(time.RFC3339), tasks[0].GetEvictingSince())
+	suite.True(tasks[0].GetResistingSeconds() >= 3600)
+	suite.Equal("unparsable", tasks[1].GetTaskId())
+	suite.Nil(tasks[1].GetJobId())
+	suite.Equal(tasks[0].GetEvictingSince(), tasks[1].GetEvictingSince())
+
+	// The tasks are returned without the eviction time if it is unknown
+	tasks = resp.GetHostInfos()[1].GetDrainingTasks()
+	suite.Len(tasks, 1)
+	suite.Equal(jobID, tasks[0].GetJobId().GetValue())
+	suite.Empty(tasks[0].GetEvictingSince())
+	suite.Zero(tasks[0].GetResistingSeconds())
+
+	suite.Empty(resp.GetHostInfos()[2].GetDrainingTasks())
+
+	// The host infos of the maintenance map are left unchanged
+	for _, hostInfo := range drainingHostInfos {
+		suite.Empty(hostInfo.GetDrainingTasks())
+	}
+
+	// The tasks are not returned unless requested
+	resp, err = suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_DRAINING,
+		},
+	})
+	suite.NoError(err)
+	for _, hostInfo := range resp.GetHostInfos() {
+		suite.Empty(hostInfo.GetDrainingTasks())
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/stringset"
+)
+
+// startMaintenanceDryRun returns the changes a StartMaintenance request
+// of the hosts would make, without making them. Only the canary hosts of
+// a canary drain would start maintenance right away.
+func (m *serviceHandler) startMaintenanceDryRun(
+	ctx context.Context,
+	hostnames []string,
+	drainOptions *hpb.DrainOptions,
+) (*host_svc.MaintenanceDryRun, error) {
+	if isCanaryDrain(drainOptions, len(hostnames)) {
+		hostnames = hostnames[:drainOptions.GetCanary().GetCount()]
+	}
+
+	machineIds, err := m.buildMachineIDsForHosts(ctx, hostnames)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.getDrainMethod(drainOptions) == hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN {
+		supported, err := m.agentDrainingSupported(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if supported {
+			for _, hostname := range hostnames {
+				if _, err := getAgentID(hostname); err != nil {
+					return nil, err
+				}
+			}
+			return &host_svc.MaintenanceDryRun{
+				DrainedAgentHostnames: hostnames,
+			}, nil
+		}
+	}
+
+	response, err := m.getMaintenanceSchedule(ctx)
+	if err != nil {
+		return nil, newMasterError(err, "failed to get maintenance schedule")
+	}
+	current := response.GetSchedule()
+	window := newMaintenanceWindow(time.Now().UnixNano(), 0)
+	window.MachineIds = machineIds
+	schedule := &mesos_maintenance.Schedule{
+		Windows: append(
+			append([]*mesos_maintenance.Window{}, current.GetWindows()...),
+			window),
+	}
+
+	// Hosts already in the maintenance or dead-letter queue are skipped
+	// by the maintenance queue
+	skipped := stringset.FromSlice(m.maintenanceQueue.Hosts())
+	skipped.AddAll(m.maintenanceQueue.DeadLetters())
+	var enqueued []string
+	for _, hostname := range hostnames {
+		if !skipped.Contains(hostname) {
+			enqueued = append(enqueued, hostname)
+		}
+	}
+
+	return &host_svc.MaintenanceDryRun{
+		ScheduleDiff:      diffMaintenanceSchedules(current, schedule),
+		EnqueuedHostnames: enqueued,
+	}, nil
+}
+
+// completeMaintenanceDryRun fills the response of a CompleteMaintenance
+// request of the hosts with the changes it would make, without making
+// them. DOWN hosts are taken out of the maintenance schedule, or have
+// their agent reactivated if it was drained by Mesos Master.
+func (m *serviceHandler) completeMaintenanceDryRun(
+	ctx context.Context,
+	hostnames []string,
+	downHostInfoMap map[string]*hpb.HostInfo,
+	reboot bool,
+	response *host_svc.CompleteMaintenanceResponse) error {
+	dryRun := &host_svc.MaintenanceDryRun{}
+	stopped := make(map[string]bool)
+	for _, hostname := range hostnames {
+		hostInfo, ok := downHostInfoMap[hostname]
+		if !ok {
+			response.Failures = append(response.Failures,
+				&host_svc.CompleteMaintenanceFailure{
+					Hostname: hostname,
+					Message:  "host is not DOWN",
+				})
+			continue
+		}
+		if reboot {
+			dryRun.RebootedHostnames = append(
+				dryRun.RebootedHostnames, hostname)
+		}
+		if isAgentDrain(hostInfo) {
+			dryRun.ReactivatedAgentHostnames = append(
+				dryRun.ReactivatedAgentHostnames, hostname)
+		} else {
+			stopped[hostname] = true
+		}
+		response.CompletedHostnames = append(
+			response.CompletedHostnames, hostname)
+	}
+
+	if len(stopped) > 0 {
+		scheduleResponse, err := m.getMaintenanceSchedule(ctx)
+		if err != nil {
+			return newMasterError(err, "failed to get maintenance schedule")
+		}
+		current := scheduleResponse.GetSchedule()
+		schedule := &mesos_maintenance.Schedule{}
+		for _, window := range current.GetWindows() {
+			var kept []*mesos.MachineID
+			for _, machineID := range window.GetMachineIds() {
+				if !stopped[machineID.GetHostname()] {
+					kept = append(kept, machineID)
+				}
+			}
+			if len(kept) > 0 {
+				schedule.Windows = append(schedule.Windows,
+					&mesos_maintenance.Window{
+						MachineIds:     kept,
+						Unavailability: window.GetUnavailability(),
+					})
+			}
+		}
+		dryRun.ScheduleDiff = diffMaintenanceSchedules(current, schedule)
+	}
+
+	response.DryRun = dryRun
+	return nil
+}
+
+// diffMaintenanceSchedules returns a line for every host added to ("+")
+// or removed from ("-") the maintenance schedule, sorted by hostname. A
+// host whose window changed is both removed and added.
+func diffMaintenanceSchedules(
+	before *mesos_maintenance.Schedule,
+	after *mesos_maintenance.Schedule) []string {
+	beforeHosts := scheduledHosts(before)
+	afterHosts := scheduledHosts(after)
+
+	hostnames := stringset.New()
+	for hostname := range beforeHosts {
+		hostnames.Add(hostname)
+	}
+	for hostname := range afterHosts {
+		hostnames.Add(hostname)
+	}
+
+	var diff []string
+	for _, hostname := range hostnames.ToSortedSlice() {
+		b, inBefore := beforeHosts[hostname]
+		a, inAfter := afterHosts[hostname]
+		if inBefore && inAfter && a == b {
+			continue
+		}
+		if inBefore {
+			diff = append(diff, "- "+b)
+		}
+		if inAfter {
+			diff = append(diff, "+ "+a)
+		}
+	}
+	return diff
+}
+
+// scheduledHosts returns the description of the window of every host of
+// the maintenance schedule, by hostname.
+func scheduledHosts(schedule *mesos_maintenance.Schedule) map[string]string {
+	hosts := make(map[string]string)
+	for _, window := range schedule.GetWindows() {
+		unavailability := window.GetUnavailability()
+		start := time.Unix(0, unavailability.GetStart().GetNanoseconds())
+		description := "from " + start.UTC().Format(time.RFC3339)
+		if nanos := unavailability.GetDuration().GetNanoseconds(); nanos > 0 {
+			description += " for " + time.Duration(nanos).String()
+		}
+		for _, machineID := range window.GetMachineIds() {
+			hosts[machineID.GetHostname()] = fmt.Sprintf("%s (%s) %s",
+				machineID.GetHostname(), machineID.GetIp(), description)
+		}
+	}
+	return hosts
+}
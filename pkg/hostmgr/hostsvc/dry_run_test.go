@@ -0,0 +1,197 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"strings"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// scheduleResponse returns the GET_MAINTENANCE_SCHEDULE response of a
+// schedule with a window of the machines starting at start.
+func scheduleResponse(
+	start time.Time,
+	machines ...*mesos.MachineID,
+) *mesosmaster.Response_GetMaintenanceSchedule {
+	schedule := &mesosmaintenance.Schedule{}
+	if len(machines) > 0 {
+		window := newMaintenanceWindow(start.UnixNano(), 0)
+		window.MachineIds = machines
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return &mesosmaster.Response_GetMaintenanceSchedule{Schedule: schedule}
+}
+
+// TestStartMaintenanceDryRun tests that a dry run of StartMaintenance
+// returns the changes of the schedule and of the maintenance queue,
+// without making them.
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDryRun() {
+	start := time.Unix(1000, 0)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(scheduleResponse(start, suite.drainingMachines...), nil)
+	suite.mockMaintenanceQueue.EXPECT().Hosts().Return(nil)
+	suite.mockMaintenanceQueue.EXPECT().DeadLetters().Return(nil)
+
+	hosts := []string{suite.upMachines[0].GetHostname()}
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: hosts,
+			DryRun:    true,
+		})
+	suite.NoError(err)
+	suite.False(resp.GetQueued())
+	suite.Equal(hosts, resp.GetDryRun().GetEnqueuedHostnames())
+
+	diff := resp.GetDryRun().GetScheduleDiff()
+	suite.Len(diff, 1)
+	suite.True(strings.HasPrefix(diff[0], "+ host1 (172.17.0.5) from "))
+}
+
+// TestStartMaintenanceDryRunSkippedHosts tests that hosts already in the
+// maintenance queue are not reported as enqueued by a dry run.
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDryRunSkippedHosts() {
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(scheduleResponse(time.Now()), nil)
+	suite.mockMaintenanceQueue.EXPECT().
+		Hosts().
+		Return([]string{suite.upMachines[0].GetHostname()})
+	suite.mockMaintenanceQueue.EXPECT().DeadLetters().Return(nil)
+
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+			DryRun:    true,
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetDryRun().GetEnqueuedHostnames())
+}
+
+// TestStartMaintenanceDryRunFrozen tests that a dry run of StartMaintenance
+// reports that the request would be queued while maintenance is frozen.
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDryRunFrozen() {
+	suite.handler.maintenanceFreeze = &maintenanceFreeze{frozen: true}
+
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+			DryRun:    true,
+		})
+	suite.NoError(err)
+	suite.True(resp.GetQueued())
+	suite.Nil(resp.GetDryRun())
+	suite.Empty(suite.handler.maintenanceFreeze.pending)
+}
+
+// TestStartMaintenanceDryRunUnknownHost tests that a dry run of
+// StartMaintenance validates the hosts of the request.
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDryRunUnknownHost() {
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{"unknown-host"},
+			DryRun:    true,
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestCompleteMaintenanceDryRun tests that a dry run of CompleteMaintenance
+// returns the hosts which would be taken out of the schedule, without
+// bringing them up.
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceDryRun() {
+	start := time.Unix(1000, 0)
+	machine := suite.downMachines[0]
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				Ip:       machine.GetIp(),
+				State:    hpb.HostState_HOST_STATE_DOWN,
+			},
+		})
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(scheduleResponse(start, machine), nil)
+
+	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
+		&svcpb.CompleteMaintenanceRequest{
+			Hostnames: []string{"typo-host", machine.GetHostname()},
+			DryRun:    true,
+		})
+	suite.NoError(err)
+	suite.Equal([]string{machine.GetHostname()}, resp.GetCompletedHostnames())
+	suite.Len(resp.GetFailures(), 1)
+	suite.Equal(
+		[]string{"- host2 (172.17.0.6) from 1970-01-01T00:16:40Z"},
+		resp.GetDryRun().GetScheduleDiff())
+	suite.Empty(resp.GetDryRun().GetReactivatedAgentHostnames())
+}
+
+// TestCompleteMaintenanceDryRunAgentDrain tests that a dry run of
+// CompleteMaintenance reports the agents which would be reactivated.
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceDryRunAgentDrain() {
+	machine := suite.downMachines[0]
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				Ip:       machine.GetIp(),
+				State:    hpb.HostState_HOST_STATE_DOWN,
+				DrainOptions: &hpb.DrainOptions{
+					Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+				},
+			},
+		})
+
+	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
+		&svcpb.CompleteMaintenanceRequest{
+			Hostnames: []string{machine.GetHostname()},
+			DryRun:    true,
+		})
+	suite.NoError(err)
+	suite.Equal(
+		[]string{machine.GetHostname()},
+		resp.GetDryRun().GetReactivatedAgentHostnames())
+	suite.Empty(resp.GetDryRun().GetScheduleDiff())
+}
+
+// TestDiffMaintenanceSchedules tests the diff of maintenance schedules.
+func (suite *HostSvcHandlerTestSuite) TestDiffMaintenanceSchedules() {
+	start := time.Unix(1000, 0)
+	before := scheduleResponse(start, suite.upMachines[0], suite.downMachines[0])
+	after := scheduleResponse(start.Add(time.Hour), suite.downMachines[0])
+	hour := time.Hour.Nanoseconds()
+	after.Schedule.Windows[0].Unavailability.Duration = &mesos.DurationInfo{
+		Nanoseconds: &hour,
+	}
+	suite.Equal(
+		[]string{
+			"- host1 (172.17.0.5) from 1970-01-01T00:16:40Z",
+			"- host2 (172.17.0.6) from 1970-01-01T00:16:40Z",
+			"+ host2 (172.17.0.6) from 1970-01-01T01:16:40Z for 1h0m0s",
+		},
+		diffMaintenanceSchedules(before.GetSchedule(), after.GetSchedule()))
+	suite.Empty(diffMaintenanceSchedules(after.GetSchedule(), after.GetSchedule()))
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// The errors of the host service carry the YARPC code which tells clients
+// whether and when to retry a call:
+//  - NotFound for hosts unknown to host manager, which are not retried.
+//  - FailedPrecondition for hosts not in the state required by the call,
+//    which can be retried once the host changed state.
+//  - Unavailable for failed calls to Mesos Master, and while the agents
+//    are not loaded yet, which can be retried right away.
+//  - Internal for failures of host manager itself.
+
+// newNoRegisteredAgentsError returns the error for a call received
+// before the agents registered with Mesos Master are loaded
+func newNoRegisteredAgentsError() error {
+	return yarpcerrors.UnavailableErrorf("no registered agents")
+}
+
+// newUnknownHostError returns the error for a host which has no agent
+// registered with Mesos Master
+func newUnknownHostError(hostname string) error {
+	return yarpcerrors.NotFoundErrorf("unknown host %s", hostname)
+}
+
+// newHostStateError returns the error for a host which is not in the
+// state required by the call
+func newHostStateError(hostname string, format string, args ...interface{}) error {
+	return yarpcerrors.FailedPreconditionErrorf(
+		"host %s %s", hostname, fmt.Sprintf(format, args...))
+}
+
+// newMasterError returns the error for a failed call to Mesos Master
+func newMasterError(err error, format string, args ...interface{}) error {
+	return yarpcerrors.UnavailableErrorf(
+		"%s: %v", fmt.Sprintf(format, args...), err)
+}
+
+// newInternalError returns the error for a failure of host manager
+func newInternalError(err error, format string, args ...interface{}) error {
+	return yarpcerrors.InternalErrorf(
+		"%s: %v", fmt.Sprintf(format, args...), err)
+}
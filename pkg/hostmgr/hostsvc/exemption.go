@@ -0,0 +1,78 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/uber/peloton/pkg/common/logging"
+	"github.com/uber/peloton/pkg/common/util"
+	taskutil "github.com/uber/peloton/pkg/common/util/task"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// getExemptTasks returns the tasks running on the hosts which are exempt
+// from eviction by the exemption, as a preview of the tasks left running
+// by the drain. It is best effort, no task is returned if resource
+// manager cannot list the tasks of the hosts.
+func (m *serviceHandler) getExemptTasks(
+	ctx context.Context,
+	hostnames []string,
+	exemption *hpb.EvictionExemption) []*hpb.ExemptTask {
+	if exemption == nil || m.resmgrClient == nil || len(hostnames) == 0 {
+		return nil
+	}
+
+	response, err := m.resmgrClient.GetTasksByHosts(
+		ctx,
+		&resmgrsvc.GetTasksByHostsRequest{Hostnames: hostnames})
+	if err == nil && response.GetError() != nil {
+		err = yarpcerrors.InternalErrorf("%s", response.GetError().GetMessage())
+	}
+	if err != nil {
+		m.metrics.ExemptTasksFail.Inc(1)
+		logging.FromContext(ctx).WithError(err).
+			WithField("hosts", hostnames).
+			Warn("Cannot get the tasks of the hosts exempt from eviction")
+		return nil
+	}
+
+	var exemptTasks []*hpb.ExemptTask
+	for _, hostname := range hostnames {
+		for _, t := range response.GetHostTasksMap()[hostname].GetTasks() {
+			reason := taskutil.EvictionExemptReason(exemption, t)
+			if reason == "" {
+				continue
+			}
+			exemptTask := &hpb.ExemptTask{
+				Hostname: hostname,
+				TaskId:   t.GetTaskId().GetValue(),
+				JobId:    t.GetJobId(),
+				Reason:   reason,
+			}
+			if _, instanceID, err := util.ParseJobAndInstanceID(
+				exemptTask.GetTaskId()); err == nil {
+				exemptTask.InstanceId = instanceID
+			}
+			exemptTasks = append(exemptTasks, exemptTask)
+		}
+	}
+	m.metrics.ExemptTasks.Inc(int64(len(exemptTasks)))
+	return exemptTasks
+}
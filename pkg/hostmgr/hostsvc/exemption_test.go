@@ -0,0 +1,131 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// daemonExemption returns drain options exempting the tasks of DAEMON
+// jobs from eviction
+func daemonExemption() *hpb.DrainOptions {
+	return &hpb.DrainOptions{
+		Exemption: &hpb.EvictionExemption{
+			JobTypes: []job.JobType{job.JobType_DAEMON},
+		},
+	}
+}
+
+// TestStartMaintenanceExemptTasks tests that the tasks of the hosts
+// exempt from eviction are reported
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceExemptTasks() {
+	hostname := suite.upMachines[0].GetHostname()
+	jobID := "bca875f5-322a-4439-b0c9-63e3cf9f982e"
+	daemonTaskID := jobID + "-3-1"
+	serviceTaskID := "ad4ba3a8-5d3d-4d9c-bd6e-3ba5e0a0a7e1-0-1"
+
+	suite.expectStartMaintenanceWithOptions(daemonExemption())
+	suite.mockResmgrClient.EXPECT().
+		GetTasksByHosts(gomock.Any(), &resmgrsvc.GetTasksByHostsRequest{
+			Hostnames: []string{hostname},
+		}).
+		Return(&resmgrsvc.GetTasksByHostsResponse{
+			HostTasksMap: map[string]*resmgrsvc.TaskList{
+				hostname: {
+					Tasks: []*resmgr.Task{
+						{
+							JobId:   &peloton.JobID{Value: jobID},
+							TaskId:  &mesos.TaskID{Value: &daemonTaskID},
+							Type:    resmgr.TaskType_BATCH,
+							JobType: job.JobType_DAEMON,
+						},
+						{
+							TaskId: &mesos.TaskID{Value: &serviceTaskID},
+							Type:   resmgr.TaskType_STATELESS,
+						},
+					},
+				},
+			},
+		}, nil)
+
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: daemonExemption(),
+		})
+	suite.NoError(err)
+	suite.Equal([]*hpb.ExemptTask{
+		{
+			Hostname:   hostname,
+			TaskId:     daemonTaskID,
+			JobId:      &peloton.JobID{Value: jobID},
+			InstanceId: 3,
+			Reason:     "job type DAEMON",
+		},
+	}, resp.GetExemptTasks())
+}
+
+// TestStartMaintenanceExemptTasksError tests that maintenance is started
+// even if the exempt tasks cannot be listed
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceExemptTasksError() {
+	suite.expectStartMaintenanceWithOptions(daemonExemption())
+	suite.mockResmgrClient.EXPECT().
+		GetTasksByHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+
+	resp, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{suite.upMachines[0].GetHostname()},
+			DrainOptions: daemonExemption(),
+		})
+	suite.NoError(err)
+	suite.Empty(resp.GetExemptTasks())
+}
+
+// TestStartMaintenanceExemptionInvalid tests that invalid exemptions are
+// rejected
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceExemptionInvalid() {
+	for _, options := range []*hpb.DrainOptions{
+		{
+			Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+			Exemption: &hpb.EvictionExemption{
+				ControllerTasks: true,
+			},
+		},
+		{
+			Exemption: &hpb.EvictionExemption{
+				Labels: []*peloton.Label{{Value: "ingress"}},
+			},
+		},
+	} {
+		_, err := suite.handler.StartMaintenance(suite.ctx,
+			&svcpb.StartMaintenanceRequest{
+				Hostnames:    []string{suite.upMachines[0].GetHostname()},
+				DrainOptions: options,
+			})
+		suite.True(yarpcerrors.IsInvalidArgument(err), options.String())
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/concurrency"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Federation is the federation of the host managers of several Peloton
+// clusters. Federated QueryHosts calls aggregate the hosts of the peers,
+// the host managers of the other clusters, with the hosts of this host
+// manager, so that a single endpoint serves the hosts of all clusters.
+type Federation struct {
+	// Cluster is the name of the cluster of this host manager
+	Cluster string
+
+	// Peers are the host service clients of the host managers of the
+	// other clusters, keyed by the name of their cluster
+	Peers map[string]host_svc.HostServiceYARPCClient
+
+	// Timeout of the calls to the peers, none if zero
+	Timeout time.Duration
+}
+
+// newNotFederatedError returns the error of a federated call to a host
+// manager without federation
+func newNotFederatedError() error {
+	return yarpcerrors.FailedPreconditionErrorf(
+		"host manager has no federation configured")
+}
+
+// federateHostInfos returns the given host infos of this cluster followed
+// by the host infos of the peers matching the request, all with their
+// cluster set, and the clusters whose hosts could not be queried.
+func (m *serviceHandler) federateHostInfos(
+	ctx context.Context,
+	request *host_svc.QueryHostsRequest,
+	hostInfos []*hpb.HostInfo,
+) ([]*hpb.HostInfo, []*host_svc.ClusterQueryFailure) {
+	m.metrics.QueryHostsFederated.Inc(1)
+
+	clusters := make([]string, 0, len(m.federation.Peers))
+	for cluster := range m.federation.Peers {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	// The peers answer with their own hosts only
+	peerRequest := &host_svc.QueryHostsRequest{
+		HostStates:         request.GetHostStates(),
+		Pools:              request.GetPools(),
+		Labels:             request.GetLabels(),
+		IncludeTaskDetails: request.GetIncludeTaskDetails(),
+		Fields:             request.GetFields(),
+	}
+
+	// Query the peers in a worker pool, each worker writes the hosts or
+	// the error of its cluster at the index of the cluster
+	peerHostInfos := make([][]*hpb.HostInfo, len(clusters))
+	peerErrs := make([]error, len(clusters))
+	queried := make([]bool, len(clusters))
+	err := concurrency.ForEach(
+		ctx,
+		len(clusters),
+		concurrency.WorkerPoolOptions{},
+		nil,
+		func(ctx context.Context, i int) error {
+			if m.federation.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, m.federation.Timeout)
+				defer cancel()
+			}
+			response, err := m.federation.Peers[clusters[i]].QueryHosts(
+				ctx, peerRequest)
+			queried[i] = true
+			if err != nil {
+				peerErrs[i] = err
+				return nil
+			}
+			peerHostInfos[i] = response.GetHostInfos()
+			return nil
+		})
+	if err != nil {
+		// The call was canceled before the remaining peers were queried
+		for i := range clusters {
+			if !queried[i] {
+				peerErrs[i] = err
+			}
+		}
+	}
+
+	result := withCluster(hostInfos, m.federation.Cluster)
+	var failures []*host_svc.ClusterQueryFailure
+	for i, cluster := range clusters {
+		if err := peerErrs[i]; err != nil {
+			m.metrics.QueryHostsPeerFail.Inc(1)
+			log.WithError(err).
+				WithField("cluster", cluster).
+				Warn("Failed to query hosts of federation peer")
+			failures = append(failures, &host_svc.ClusterQueryFailure{
+				Cluster: cluster,
+				Message: err.Error(),
+			})
+			continue
+		}
+		result = append(result, withCluster(peerHostInfos[i], cluster)...)
+	}
+	return result, failures
+}
+
+// withCluster returns copies of the host infos with their cluster set.
+// The host infos are copied since they may be shared with the maintenance
+// host info map.
+func withCluster(hostInfos []*hpb.HostInfo, cluster string) []*hpb.HostInfo {
+	result := make([]*hpb.HostInfo, 0, len(hostInfos))
+	for _, hostInfo := range hostInfos {
+		copied := *hostInfo
+		copied.Cluster = cluster
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	svcmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// TestQueryHostsFederated tests aggregating the hosts of the federation
+// peers with the local hosts, namespaced by their cluster
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsFederated() {
+	peer1 := svcmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	peer2 := svcmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.handler.federation = &Federation{
+		Cluster: "local",
+		Peers: map[string]svcpb.HostServiceYARPCClient{
+			"peer1": peer1,
+			"peer2": peer2,
+		},
+	}
+
+	downHostInfos := []*hpb.HostInfo{
+		{Hostname: "host1", State: hpb.HostState_HOST_STATE_DOWN},
+	}
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(downHostInfos)
+
+	request := &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{hpb.HostState_HOST_STATE_DOWN},
+		Fields:     []string{"hostname", "state"},
+		Federated:  true,
+	}
+	// The peers are queried for their own hosts only
+	peerRequest := &svcpb.QueryHostsRequest{
+		HostStates: request.GetHostStates(),
+		Fields:     request.GetFields(),
+	}
+	peer1.EXPECT().
+		QueryHosts(gomock.Any(), peerRequest).
+		Return(&svcpb.QueryHostsResponse{
+			HostInfos: []*hpb.HostInfo{
+				{Hostname: "host2", State: hpb.HostState_HOST_STATE_DOWN},
+			},
+		}, nil)
+	peer2.EXPECT().
+		QueryHosts(gomock.Any(), peerRequest).
+		Return(nil, yarpcerrors.UnavailableErrorf("fake QueryHosts error"))
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, request)
+	suite.NoError(err)
+	suite.Equal([]*hpb.HostInfo{
+		{
+			Hostname: "host1",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+			Cluster:  "local",
+		},
+		{
+			Hostname: "host2",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+			Cluster:  "peer1",
+		},
+	}, resp.GetHostInfos())
+	suite.Len(resp.GetFailedClusters(), 1)
+	suite.Equal("peer2", resp.GetFailedClusters()[0].GetCluster())
+	suite.Contains(resp.GetFailedClusters()[0].GetMessage(), "fake QueryHosts error")
+
+	// The host infos of the maintenance map are left unchanged
+	suite.Empty(downHostInfos[0].GetCluster())
+}
+
+// TestQueryHostsFederatedWithoutFederation tests that federated queries
+// fail on a host manager without federation
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsFederatedWithoutFederation() {
+	_, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		Federated: true,
+	})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestWithCluster tests setting the cluster of copies of host infos
+func (suite *HostSvcHandlerTestSuite) TestWithCluster() {
+	hostInfos := []*hpb.HostInfo{{Hostname: "host1"}, {Hostname: "host2"}}
+	result := withCluster(hostInfos, "cluster1")
+	suite.Len(result, 2)
+	for i, hostInfo := range result {
+		suite.Equal(fmt.Sprintf("host%d", i+1), hostInfo.GetHostname())
+		suite.Equal("cluster1", hostInfo.GetCluster())
+		suite.Empty(hostInfos[i].GetCluster())
+	}
+	suite.Empty(withCluster(nil, "cluster1"))
+}
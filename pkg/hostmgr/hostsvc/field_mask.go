@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"reflect"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// _hostInfoFields are the indexes of the fields of the HostInfo struct,
+// keyed by the proto names of the fields
+var _hostInfoFields = func() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeOf(hpb.HostInfo{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := protoFieldName(t.Field(i)); name != "" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// hostInfoFieldMask is the set of the fields of the host infos returned
+// to a request. A nil mask keeps all the fields.
+type hostInfoFieldMask map[string]bool
+
+// newHostInfoFieldMask returns the mask of the given proto field names of
+// HostInfo, or nil if no field is given. Returns an invalid argument error
+// for a field name which is not a field of HostInfo.
+func newHostInfoFieldMask(fields []string) (hostInfoFieldMask, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	mask := make(hostInfoFieldMask)
+	for _, field := range fields {
+		name := strings.TrimSpace(field)
+		if _, ok := _hostInfoFields[name]; !ok {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"unknown host info field %q", field)
+		}
+		mask[name] = true
+	}
+	return mask, nil
+}
+
+// includes returns true if the field with the given proto name is
+// returned.
+func (m hostInfoFieldMask) includes(field string) bool {
+	return m == nil || m[field]
+}
+
+// apply returns copies of the host infos with only the fields of the
+// mask set, or the host infos themselves if the mask is nil. The host
+// infos are copied since they may be shared with the maintenance host
+// info map.
+func (m hostInfoFieldMask) apply(hostInfos []*hpb.HostInfo) []*hpb.HostInfo {
+	if m == nil {
+		return hostInfos
+	}
+	result := make([]*hpb.HostInfo, 0, len(hostInfos))
+	for _, hostInfo := range hostInfos {
+		src := reflect.ValueOf(hostInfo).Elem()
+		masked := &hpb.HostInfo{}
+		dst := reflect.ValueOf(masked).Elem()
+		for name := range m {
+			i := _hostInfoFields[name]
+			dst.Field(i).Set(src.Field(i))
+		}
+		result = append(result, masked)
+	}
+	return result
+}
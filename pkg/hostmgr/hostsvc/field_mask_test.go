@@ -0,0 +1,100 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// TestHostInfoFieldMask tests parsing and applying field masks of host
+// infos
+func (suite *HostSvcHandlerTestSuite) TestHostInfoFieldMask() {
+	hostInfos := []*hpb.HostInfo{
+		{
+			Hostname: "host1",
+			Ip:       "172.17.0.5",
+			State:    hpb.HostState_HOST_STATE_UP,
+			Pool:     "shared",
+		},
+	}
+
+	mask, err := newHostInfoFieldMask(nil)
+	suite.NoError(err)
+	suite.True(mask.includes("draining_tasks"))
+	suite.Equal(hostInfos, mask.apply(hostInfos))
+
+	mask, err = newHostInfoFieldMask([]string{"hostname", " state "})
+	suite.NoError(err)
+	suite.True(mask.includes("state"))
+	suite.False(mask.includes("draining_tasks"))
+	suite.Equal([]*hpb.HostInfo{
+		{
+			Hostname: "host1",
+			State:    hpb.HostState_HOST_STATE_UP,
+		},
+	}, mask.apply(hostInfos))
+	suite.Equal("shared", hostInfos[0].GetPool())
+
+	_, err = newHostInfoFieldMask([]string{"hostname", "XXX_unrecognized"})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+	_, err = newHostInfoFieldMask([]string{"Hostname"})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestQueryHostsFields tests returning only the requested fields of the
+// host infos, without looking up the draining tasks unless requested
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsFields() {
+	drainingHostInfos := []*hpb.HostInfo{
+		{
+			Hostname:     "host1",
+			Ip:           "172.17.0.5",
+			State:        hpb.HostState_HOST_STATE_DRAINING,
+			DrainOptions: &hpb.DrainOptions{KillGracePeriodSeconds: 60},
+		},
+	}
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(drainingHostInfos)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil)
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_DRAINING,
+		},
+		IncludeTaskDetails: true,
+		Fields:             []string{"hostname", "state"},
+	})
+	suite.NoError(err)
+	suite.Equal([]*hpb.HostInfo{
+		{
+			Hostname: "host1",
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		},
+	}, resp.GetHostInfos())
+
+	// The host infos of the maintenance map are left unchanged
+	suite.Equal("172.17.0.5", drainingHostInfos[0].GetIp())
+	suite.NotNil(drainingHostInfos[0].GetDrainOptions())
+
+	_, err = suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		Fields: []string{"unknown"},
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
@@ -16,23 +16,58 @@ package hostsvc
 
 import (
 	"context"
-	"fmt"
+	"sync"
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
 
+	"github.com/uber/peloton/pkg/auth"
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/backoff"
+	"github.com/uber/peloton/pkg/common/concurrency"
+	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/common/logging"
 	"github.com/uber/peloton/pkg/common/stringset"
 	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/hostprovider"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
+	"github.com/uber/peloton/pkg/hostmgr/task"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
+	"github.com/golang/protobuf/proto"
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// ServiceName is the name of the HostService, which prefixes its
+// procedure names
+const ServiceName = "peloton.api.v0.host.svc.HostService"
+
+const (
+	// Retries of the reads of Mesos Master, with an exponential backoff
+	// from _masterRetryInterval up to _masterMaxRetryInterval
+	_masterRetryAttempts    = 3
+	_masterRetryInterval    = 100 * time.Millisecond
+	_masterMaxRetryInterval = time.Second
+	_masterRetryJitter      = 0.2
+
+	// Retries allowed per read of Mesos Master, and in a burst
+	_masterRetryBudgetRatio = 0.2
+	_masterRetryBudgetBurst = 10
 )
 
 // serviceHandler implements peloton.api.host.svc.HostService
@@ -41,6 +76,66 @@ type serviceHandler struct {
 	metrics                *Metrics
 	operatorMasterClient   mpb.MasterOperatorClient
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap
+	maintenanceHistory     host.MaintenanceHistory
+	hostEventLog           host.HostEventLog
+	assignmentMap          host.AssignmentMap
+	approvalMap            host.ApprovalMap
+	hostTaskIndex          task.HostTaskIndex
+	eventBus               eventbus.Bus
+	pidCache               *util.AgentPIDCache
+	machineIDMetrics       *concurrency.PoolMetrics
+	masterRetryPolicy      backoff.RetryPolicy
+	masterRetryBudget      *backoff.Budget
+	reservationOps         ormobjects.HostReservationOps
+	idempotencyKeys        *idempotencyKeys
+	maintenancePolicyOps   ormobjects.MaintenancePolicyOps
+	maintenanceFreeze      *maintenanceFreeze
+	canaryDrains           *canaryDrains
+	drainMethod            hpb.DrainMethod
+
+	// authenticated tells whether the callers are authenticated by a
+	// security manager. Otherwise the user a call is made by is taken
+	// from a client header, and cannot be trusted to request maintenance
+	// requiring the approval of another user.
+	authenticated bool
+
+	// defaultMaintenancePolicies are the names of the maintenance
+	// policies of the requests without drain options nor policy, keyed
+	// by host pool
+	defaultMaintenancePolicies map[string]string
+
+	// afterFunc runs a function after a duration, to end the
+	// observation of canary drains and to time out drains
+	afterFunc func(time.Duration, func())
+
+	// hostProvider reboots and terminates the machines of the hosts,
+	// nil if no host provider is configured
+	hostProvider hostprovider.HostProvider
+
+	// resmgrClient lists the tasks of the hosts put into maintenance,
+	// to report the tasks exempt from eviction
+	resmgrClient resmgrsvc.ResourceManagerServiceYARPCClient
+
+	// scheduleLock serializes the updates of the maintenance schedule
+	// of Mesos Master, which are read-modify-write
+	scheduleLock sync.Mutex
+
+	// candidate tells whether this host manager is the leader, and
+	// discovery finds the leader otherwise
+	candidate leader.Candidate
+	discovery leader.Discovery
+
+	// leaderClient proxies read-only calls to the leader when this host
+	// manager is a follower, nil if follower mode is disabled
+	leaderClient host_svc.HostServiceYARPCClient
+
+	// federation aggregates the hosts of the host managers of the other
+	// clusters in federated queries, nil if federation is disabled
+	federation *Federation
+
+	// procedures are the procedures of the HostService registered with
+	// the dispatcher, described by GetAPIInfo
+	procedures []transport.Procedure
 }
 
 // InitServiceHandler initializes the HostService
@@ -49,14 +144,69 @@ func InitServiceHandler(
 	parent tally.Scope,
 	operatorMasterClient mpb.MasterOperatorClient,
 	maintenanceQueue queue.MaintenanceQueue,
-	hostInfoMap host.MaintenanceHostInfoMap) {
+	hostInfoMap host.MaintenanceHostInfoMap,
+	maintenanceHistory host.MaintenanceHistory,
+	hostEventLog host.HostEventLog,
+	assignmentMap host.AssignmentMap,
+	approvalMap host.ApprovalMap,
+	hostTaskIndex task.HostTaskIndex,
+	eventBus eventbus.Bus,
+	ormStore *ormobjects.Store,
+	candidate leader.Candidate,
+	discovery leader.Discovery,
+	leaderClient host_svc.HostServiceYARPCClient,
+	maintenanceFrozen bool,
+	drainMethod hpb.DrainMethod,
+	authType auth.Type,
+	hostProvider hostprovider.HostProvider,
+	federation *Federation,
+	defaultMaintenancePolicies map[string]string,
+	resmgrClient resmgrsvc.ResourceManagerServiceYARPCClient) {
+	scope := parent.SubScope("hostsvc")
 	handler := &serviceHandler{
 		maintenanceQueue:       maintenanceQueue,
-		metrics:                NewMetrics(parent.SubScope("hostsvc")),
+		metrics:                NewMetrics(scope),
 		operatorMasterClient:   operatorMasterClient,
 		maintenanceHostInfoMap: hostInfoMap,
+		maintenanceHistory:     maintenanceHistory,
+		hostEventLog:           hostEventLog,
+		assignmentMap:          assignmentMap,
+		approvalMap:            approvalMap,
+		hostTaskIndex:          hostTaskIndex,
+		eventBus:               eventBus,
+		pidCache:               util.NewAgentPIDCache(scope),
+		machineIDMetrics:       concurrency.NewPoolMetrics(scope.SubScope("machine_ids")),
+		reservationOps:         ormobjects.NewHostReservationOps(ormStore),
+		idempotencyKeys:        newIdempotencyKeys(ormobjects.NewIdempotencyKeyOps(ormStore)),
+		maintenancePolicyOps:   ormobjects.NewMaintenancePolicyOps(ormStore),
+		maintenanceFreeze:      &maintenanceFreeze{frozen: maintenanceFrozen},
+		canaryDrains:           &canaryDrains{},
+		drainMethod:            drainMethod,
+		authenticated:          authType != auth.NOOP && authType != auth.UNDEFINED,
+		masterRetryPolicy: backoff.NewExponentialRetryPolicy(
+			_masterRetryAttempts,
+			_masterRetryInterval,
+			_masterMaxRetryInterval,
+			_masterRetryJitter),
+		masterRetryBudget: backoff.NewBudget(
+			_masterRetryBudgetRatio, _masterRetryBudgetBurst),
+		defaultMaintenancePolicies: defaultMaintenancePolicies,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		hostProvider: hostProvider,
+		resmgrClient: resmgrClient,
+		candidate:    candidate,
+		discovery:    discovery,
+		leaderClient: leaderClient,
+		federation:   federation,
+	}
+	handler.reportMaintenanceFreeze()
+	if _, err := handler.subscribeCanaryDrains(); err != nil {
+		log.WithError(err).Fatal("Cannot subscribe canary drains to event bus")
 	}
-	d.Register(host_svc.BuildHostServiceYARPCProcedures(handler))
+	handler.procedures = host_svc.BuildHostServiceYARPCProcedures(handler)
+	d.Register(handler.procedures)
 	log.Info("Hostsvc handler initialized")
 }
 
@@ -67,13 +217,46 @@ func InitServiceHandler(
 // 										  there will be no further placement of tasks on the host
 //		3.HostState_HOST_STATE_DRAINED - There are no tasks running on this host and it is ready to be 'DOWN'ed
 // 		4.HostState_HOST_STATE_DOWN - The host is in maintenance.
+// The hosts can be further filtered by host pools and labels, which
+// are only known for hosts in HOST_STATE_UP.
+// With fields, only the given fields of the host infos are returned, and
+// the draining tasks are only looked up if requested.
+// A federated query aggregates the hosts of the federation peers with the
+// hosts of this host manager, namespaced by their cluster.
 func (m *serviceHandler) QueryHosts(
 	ctx context.Context,
 	request *host_svc.QueryHostsRequest) (*host_svc.QueryHostsResponse, error) {
 	m.metrics.QueryHostsAPI.Inc(1)
 
+	// A follower proxies the call to the leader in follower mode, as
+	// its own host states may be stale
+	if !m.candidate.IsLeader() && m.canProxy(ctx) {
+		m.metrics.QueryHostsProxied.Inc(1)
+		return m.leaderClient.QueryHosts(
+			ctx,
+			request,
+			yarpc.WithHeader(_proxiedHeader, "true"),
+		)
+	}
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.QueryHostsFail.Inc(1)
+		return nil, err
+	}
+
+	mask, err := newHostInfoFieldMask(request.GetFields())
+	if err != nil {
+		m.metrics.QueryHostsFail.Inc(1)
+		return nil, err
+	}
+
+	if request.GetFederated() && m.federation == nil {
+		m.metrics.QueryHostsFail.Inc(1)
+		return nil, newNotFederatedError()
+	}
+
 	// Add request.HostStates to a set to remove duplicates
-	hostStateSet := stringset.New()
+	hostStateSet := stringset.NewUnsafe()
 	for _, state := range request.GetHostStates() {
 		hostStateSet.Add(state.String())
 	}
@@ -87,17 +270,18 @@ func (m *serviceHandler) QueryHosts(
 	var hostInfos []*hpb.HostInfo
 	drainingHostsInfo := m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{})
 	downHostsInfo := m.maintenanceHostInfoMap.GetDownHostInfos([]string{})
-	for _, hostState := range hostStateSet.ToSlice() {
+	for _, hostState := range hostStateSet.ToSortedSlice() {
 		switch hostState {
 		case hpb.HostState_HOST_STATE_UP.String():
-			upHosts, err := buildHostInfoForRegisteredAgents()
+			upHosts, err := m.buildHostInfoForRegisteredAgents()
 			if err != nil {
 				m.metrics.QueryHostsFail.Inc(1)
 				return nil, err
 			}
 			// Remove draining and down hosts from the result.
-			// This is needed because AgentMap is updated every 15s
-			// and might not have the up to date information.
+			// This is needed because AgentMap is only fully reloaded
+			// every hostmap_refresh_interval and hosts put into
+			// maintenance since then are not removed by deltas.
 			for _, hostInfo := range drainingHostsInfo {
 				delete(upHosts, hostInfo.GetHostname())
 			}
@@ -110,8 +294,22 @@ func (m *serviceHandler) QueryHosts(
 			}
 		case hpb.HostState_HOST_STATE_DRAINING.String():
 			for _, hostInfo := range drainingHostsInfo {
+				if m.drainedHostInfo(hostInfo) != nil {
+					continue
+				}
+				if request.GetIncludeTaskDetails() &&
+					mask.includes("draining_tasks") {
+					hostInfo = m.withDrainingTasks(ctx, hostInfo)
+				}
 				hostInfos = append(hostInfos, hostInfo)
 			}
+		case hpb.HostState_HOST_STATE_DRAINED.String():
+			// Drained hosts waiting for their maintenance to be approved
+			for _, hostInfo := range drainingHostsInfo {
+				if drained := m.drainedHostInfo(hostInfo); drained != nil {
+					hostInfos = append(hostInfos, drained)
+				}
+			}
 		case hpb.HostState_HOST_STATE_DOWN.String():
 			for _, hostInfo := range downHostsInfo {
 				hostInfos = append(hostInfos, hostInfo)
@@ -119,10 +317,68 @@ func (m *serviceHandler) QueryHosts(
 		}
 	}
 
+	hostInfos = filterHostInfos(
+		hostInfos,
+		request.GetPools(),
+		request.GetLabels())
+
+	response := &host_svc.QueryHostsResponse{
+		HostInfos: mask.apply(hostInfos),
+	}
+	if request.GetFederated() {
+		response.HostInfos, response.FailedClusters = m.federateHostInfos(
+			ctx, request, response.GetHostInfos())
+	}
+
 	m.metrics.QueryHostsSuccess.Inc(1)
-	return &host_svc.QueryHostsResponse{
-		HostInfos: hostInfos,
-	}, nil
+	return response, nil
+}
+
+// filterHostInfos returns the hosts which are in one of the given
+// pools and have all of the given labels. Empty pools or labels
+// match all hosts.
+func filterHostInfos(
+	hostInfos []*hpb.HostInfo,
+	pools []string,
+	labels []*peloton.Label) []*hpb.HostInfo {
+	if len(pools) == 0 && len(labels) == 0 {
+		return hostInfos
+	}
+
+	poolSet := stringset.NewUnsafe()
+	for _, pool := range pools {
+		poolSet.Add(pool)
+	}
+
+	var result []*hpb.HostInfo
+	for _, hostInfo := range hostInfos {
+		if len(pools) > 0 && !poolSet.Contains(hostInfo.GetPool()) {
+			continue
+		}
+		if !hasAllLabels(hostInfo.GetLabels(), labels) {
+			continue
+		}
+		result = append(result, hostInfo)
+	}
+	return result
+}
+
+// hasAllLabels returns true if hostLabels contains every label in labels.
+func hasAllLabels(hostLabels []*peloton.Label, labels []*peloton.Label) bool {
+	for _, label := range labels {
+		found := false
+		for _, hostLabel := range hostLabels {
+			if hostLabel.GetKey() == label.GetKey() &&
+				hostLabel.GetValue() == label.GetValue() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // StartMaintenance puts the host(s) into DRAINING state by posting a maintenance
@@ -131,25 +387,202 @@ func (m *serviceHandler) QueryHosts(
 // Maintenance Primitives for more info). The hosts are first drained of tasks
 // before they are put into maintenance by posting to /machine/down endpoint of
 // Mesos Master. The hosts transition from UP to DRAINING and finally to DOWN.
+// The hostnames are resolved to the hostnames of the registered agents first.
+// Hosts can also be given by the Mesos agent ids of their agents.
+// While maintenance is frozen, the request is queued instead.
+// With require_approval, the drained hosts are kept DRAINED until their
+// maintenance is approved by another user than the requester.
+// With canary options, only the first hosts of the request are drained
+// first, and the remaining hosts are drained once enough tasks of the
+// canary hosts were rescheduled after they went down.
+// With dry_run, the request is validated and the changes it would make
+// are returned without being made.
+// Retries of a call with an idempotency key get the original response.
 func (m *serviceHandler) StartMaintenance(
 	ctx context.Context,
 	request *host_svc.StartMaintenanceRequest,
+) (*host_svc.StartMaintenanceResponse, error) {
+	response, replayed, err := m.idempotencyKeys.call(
+		ctx,
+		"StartMaintenance",
+		request,
+		&host_svc.StartMaintenanceResponse{},
+		func() (proto.Message, error) {
+			return m.handleStartMaintenance(ctx, request)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		m.metrics.StartMaintenanceReplayed.Inc(1)
+	}
+	return response.(*host_svc.StartMaintenanceResponse), nil
+}
+
+// handleStartMaintenance handles a StartMaintenance call which is not a
+// replay.
+func (m *serviceHandler) handleStartMaintenance(
+	ctx context.Context,
+	request *host_svc.StartMaintenanceRequest,
 ) (*host_svc.StartMaintenanceResponse, error) {
 	m.metrics.StartMaintenanceAPI.Inc(1)
 
-	machineIds, err := buildMachineIDsForHosts(request.GetHostnames())
+	if err := m.checkLeader(); err != nil {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	if request.GetDrainOptions() != nil && request.GetPolicy() != "" {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"drain options and maintenance policy cannot both be set")
+	}
+	if err := m.validateDrainOptions(request.GetDrainOptions()); err != nil {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	hostnames, mappings, err := m.resolveHosts(
+		request.GetHostnames(),
+		request.GetAgentIds(),
+		nil)
+	if err != nil {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	ctx = logging.WithHosts(ctx, hostnames)
+	drainOptions, policy, err := m.resolveDrainOptions(ctx, request, hostnames)
 	if err != nil {
 		m.metrics.StartMaintenanceFail.Inc(1)
 		return nil, err
 	}
+	if err := m.checkMaintenancePolicyLimit(policy, hostnames); err != nil {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	info, _ := audit.FromContext(ctx)
+	requester := info.User
+	if err := m.validateApprovalRequester(drainOptions, requester); err != nil {
+		m.metrics.StartMaintenanceFail.Inc(1)
+		return nil, err
+	}
 
-	// Get current maintenance schedule
-	response, err := m.operatorMasterClient.GetMaintenanceSchedule()
+	if request.GetDryRun() {
+		// A request received while maintenance is frozen would only be
+		// queued
+		queued := m.maintenanceFreeze.isFrozen()
+		var dryRun *host_svc.MaintenanceDryRun
+		if queued {
+			_, err = m.buildMachineIDsForHosts(ctx, hostnames)
+		} else {
+			dryRun, err = m.startMaintenanceDryRun(ctx, hostnames, drainOptions)
+		}
+		if err != nil {
+			m.metrics.StartMaintenanceFail.Inc(1)
+			return nil, err
+		}
+		m.metrics.StartMaintenanceDryRun.Inc(1)
+		return &host_svc.StartMaintenanceResponse{
+			HostnameMappings: mappings,
+			Queued:           queued,
+			DryRun:           dryRun,
+			ExemptTasks: m.getExemptTasks(
+				ctx,
+				hostnames,
+				drainOptions.GetExemption()),
+		}, nil
+	}
+
+	if m.maintenanceFreeze.isFrozen() {
+		// Validate the hosts before queuing the request, so that
+		// unknown hosts are not found only once it is released.
+		if _, err := m.buildMachineIDsForHosts(ctx, hostnames); err != nil {
+			m.metrics.StartMaintenanceFail.Inc(1)
+			return nil, err
+		}
+		if m.maintenanceFreeze.queue(
+			hostnames,
+			drainOptions,
+			requester,
+			time.Now()) {
+			m.reportMaintenanceFreeze()
+			logging.FromContext(ctx).
+				Info("Maintenance frozen, start maintenance request queued")
+			m.metrics.StartMaintenanceQueued.Inc(1)
+			return &host_svc.StartMaintenanceResponse{
+				HostnameMappings: mappings,
+				Queued:           true,
+				ExemptTasks: m.getExemptTasks(
+					ctx,
+					hostnames,
+					drainOptions.GetExemption()),
+			}, nil
+		}
+	}
+
+	canaryDrainID, skipped, err := m.startMaintenanceRequest(
+		ctx,
+		hostnames,
+		drainOptions,
+		requester)
 	if err != nil {
 		m.metrics.StartMaintenanceFail.Inc(1)
 		return nil, err
 	}
+
+	m.metrics.StartMaintenanceSuccess.Inc(1)
+	return &host_svc.StartMaintenanceResponse{
+		HostnameMappings: mappings,
+		CanaryDrainId:    canaryDrainID,
+		SkippedHostnames: skipped,
+		ExemptTasks: m.getExemptTasks(
+			ctx,
+			hostnames,
+			drainOptions.GetExemption()),
+	}, nil
+}
+
+// startMaintenance posts a maintenance window of the hosts to Mesos
+// Master, and enqueues the hosts to be drained. Hosts drained with the
+// agent drain method are drained by Mesos Master instead, if the master
+// supports it. The approval of the maintenance requested by requester is
+// required first if the drain options require it. Returns the hosts
+// skipped by the maintenance queue. The window is removed again if the
+// hosts fail to be enqueued.
+func (m *serviceHandler) startMaintenance(
+	ctx context.Context,
+	hostnames []string,
+	drainOptions *hpb.DrainOptions,
+	requester string) ([]string, error) {
+	if m.getDrainMethod(drainOptions) == hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN {
+		supported, err := m.agentDrainingSupported(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if supported {
+			return nil, m.drainAgents(ctx, hostnames, drainOptions)
+		}
+		logging.FromContext(ctx).
+			Warn("Mesos Master does not support agent draining, " +
+				"falling back to maintenance schedule")
+		m.metrics.AgentDrainFallback.Inc(1)
+	}
+
+	machineIds, err := m.buildMachineIDsForHosts(ctx, hostnames)
+	if err != nil {
+		return nil, err
+	}
+
+	m.scheduleLock.Lock()
+	defer m.scheduleLock.Unlock()
+
+	// Get current maintenance schedule
+	response, err := m.getMaintenanceSchedule(ctx)
+	if err != nil {
+		return nil, newMasterError(err, "failed to get maintenance schedule")
+	}
 	schedule := response.GetSchedule()
+	windows := schedule.GetWindows()
 	// Set current time as the `start` of maintenance window
 	nanos := time.Now().UnixNano()
 
@@ -168,126 +601,443 @@ func (m *serviceHandler) StartMaintenance(
 		},
 	}
 	schedule.Windows = append(schedule.Windows, maintenanceWindow)
+	drainOptions = withDrainDeadline(drainOptions, time.Unix(0, nanos))
+
+	if drainOptions.GetRequireApproval() {
+		if err := m.approvalMap.Require(ctx, hostnames, requester); err != nil {
+			return nil, newInternalError(err, "failed to require maintenance approval")
+		}
+	}
 
-	err = m.operatorMasterClient.UpdateMaintenanceSchedule(schedule)
+	err = m.operatorMasterClient.UpdateMaintenanceSchedule(ctx, schedule)
 	if err != nil {
-		m.metrics.StartMaintenanceFail.Inc(1)
-		return nil, err
+		if drainOptions.GetRequireApproval() {
+			if err := m.approvalMap.Remove(ctx, hostnames); err != nil {
+				logging.FromContext(ctx).WithError(err).
+					Warn("failed to remove maintenance approvals")
+			}
+		}
+		return nil, newMasterError(err, "failed to update maintenance schedule")
 	}
-	log.WithField("maintenance_schedule", schedule).
+	logging.FromContext(ctx).WithField("maintenance_schedule", schedule).
 		Info("Maintenance Schedule posted to Mesos Master")
 
 	var hostInfos []*hpb.HostInfo
 	for _, machine := range machineIds {
+		// The agent id is kept to resolve the host once it is DOWN
+		// and its agent is no longer registered
+		agentID, _ := getAgentID(machine.GetHostname())
 		hostInfos = append(hostInfos,
 			&hpb.HostInfo{
-				Hostname: machine.GetHostname(),
-				Ip:       machine.GetIp(),
-				State:    hpb.HostState_HOST_STATE_DRAINING,
+				Hostname:     machine.GetHostname(),
+				Ip:           machine.GetIp(),
+				State:        hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: drainOptions,
+				AgentId:      agentID.GetValue(),
 			})
 	}
 	m.maintenanceHostInfoMap.AddHostInfos(hostInfos)
+	m.eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: hostnames,
+		From:      hpb.HostState_HOST_STATE_UP,
+		To:        hpb.HostState_HOST_STATE_DRAINING,
+	})
 	// Enqueue hostnames into maintenance queue to initiate
 	// the rescheduling of tasks running on these hosts
-	err = m.maintenanceQueue.Enqueue(request.GetHostnames())
+	skipped, err := m.maintenanceQueue.Enqueue(hostnames)
 	if err != nil {
-		return nil, err
+		schedule.Windows = windows
+		m.undoStartMaintenance(ctx, hostnames, schedule, drainOptions)
+		return nil, newInternalError(err, "failed to enqueue hosts")
+	}
+	if len(skipped) > 0 {
+		logging.FromContext(ctx).WithField("skipped_hosts", skipped).
+			Warn("Hosts already queued or dead-lettered skipped by the maintenance queue")
+	}
+	if timeout := drainOptions.GetDrainTimeoutSeconds(); timeout > 0 {
+		m.afterFunc(time.Duration(timeout)*time.Second, func() {
+			m.downTimedOutHosts(hostnames)
+		})
 	}
+	return skipped, nil
+}
 
-	m.metrics.StartMaintenanceSuccess.Inc(1)
-	return &host_svc.StartMaintenanceResponse{}, nil
+// undoStartMaintenance brings the hosts whose maintenance was started
+// back UP, by posting the maintenance schedule without their window and
+// forgetting their DRAINING state and approvals. It is best effort, and
+// must be called with the schedule lock held.
+func (m *serviceHandler) undoStartMaintenance(
+	ctx context.Context,
+	hostnames []string,
+	schedule *mesos_maintenance.Schedule,
+	drainOptions *hpb.DrainOptions) {
+	logger := logging.FromContext(ctx)
+	if err := m.operatorMasterClient.UpdateMaintenanceSchedule(
+		ctx,
+		schedule); err != nil {
+		logger.WithError(err).
+			Error("failed to remove maintenance window of hosts")
+	}
+	m.maintenanceHostInfoMap.RemoveHostInfos(hostnames)
+	m.eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: hostnames,
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_UP,
+	})
+	if drainOptions.GetRequireApproval() {
+		if err := m.approvalMap.Remove(ctx, hostnames); err != nil {
+			logger.WithError(err).
+				Warn("failed to remove maintenance approvals")
+		}
+	}
 }
 
 // CompleteMaintenance completes maintenance on the specified hosts. It brings
 // UP a host which is in maintenance by posting to /machine/up endpoint of
 // Mesos Master i.e. the machine transitions from DOWN to UP state
-// (Please check Mesos Maintenance Primitives for more info)
+// (Please check Mesos Maintenance Primitives for more info). Hosts whose
+// agents were drained by Mesos Master are reactivated instead.
+// Hosts can also be given by agent id, which is resolved with the agent
+// id the DOWN hosts had when their maintenance was started.
+// With reboot, the machines of the hosts are first rebooted with the host
+// provider.
+// Each host is brought up on its own, and hosts which are not DOWN or
+// fail to be brought up are returned as failures of the response
+// instead of failing the whole request.
+// With dry_run, the changes the request would make are returned without
+// being made.
+// Retries of a call with an idempotency key get the original response.
 func (m *serviceHandler) CompleteMaintenance(
 	ctx context.Context,
 	request *host_svc.CompleteMaintenanceRequest,
+) (*host_svc.CompleteMaintenanceResponse, error) {
+	response, replayed, err := m.idempotencyKeys.call(
+		ctx,
+		"CompleteMaintenance",
+		request,
+		&host_svc.CompleteMaintenanceResponse{},
+		func() (proto.Message, error) {
+			return m.handleCompleteMaintenance(ctx, request)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		m.metrics.CompleteMaintenanceReplayed.Inc(1)
+	}
+	return response.(*host_svc.CompleteMaintenanceResponse), nil
+}
+
+// handleCompleteMaintenance handles a CompleteMaintenance call which is
+// not a replay.
+func (m *serviceHandler) handleCompleteMaintenance(
+	ctx context.Context,
+	request *host_svc.CompleteMaintenanceRequest,
 ) (*host_svc.CompleteMaintenanceResponse, error) {
 	m.metrics.CompleteMaintenanceAPI.Inc(1)
 
+	if err := m.checkLeader(); err != nil {
+		m.metrics.CompleteMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	if request.GetReboot() && m.hostProvider == nil {
+		m.metrics.CompleteMaintenanceFail.Inc(1)
+		return nil, yarpcerrors.FailedPreconditionErrorf(
+			"no host provider is configured to reboot hosts")
+	}
+
+	downHostInfos := m.maintenanceHostInfoMap.GetDownHostInfos([]string{})
 	downHostInfoMap := make(map[string]*hpb.HostInfo)
-	for _, hostInfo := range m.maintenanceHostInfoMap.GetDownHostInfos([]string{}) {
+	for _, hostInfo := range downHostInfos {
 		downHostInfoMap[hostInfo.GetHostname()] = hostInfo
 	}
 
-	var machineIds []*mesos.MachineID
-	hostnames := request.GetHostnames()
+	hostnames, mappings, err := m.resolveHosts(
+		request.GetHostnames(),
+		request.GetAgentIds(),
+		downHostInfos)
+	if err != nil {
+		m.metrics.CompleteMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	ctx = logging.WithHosts(ctx, hostnames)
+
+	response := &host_svc.CompleteMaintenanceResponse{
+		HostnameMappings: mappings,
+	}
+	if request.GetDryRun() {
+		if err := m.completeMaintenanceDryRun(
+			ctx,
+			hostnames,
+			downHostInfoMap,
+			request.GetReboot(),
+			response); err != nil {
+			m.metrics.CompleteMaintenanceFail.Inc(1)
+			return nil, err
+		}
+		m.metrics.CompleteMaintenanceDryRun.Inc(1)
+		return response, nil
+	}
+
 	for _, hostname := range hostnames {
 		hostInfo, ok := downHostInfoMap[hostname]
 		if !ok {
-			m.metrics.CompleteMaintenanceFail.Inc(1)
-			return nil, fmt.Errorf("invalid request. Host %s is not DOWN", hostname)
+			response.Failures = append(response.Failures,
+				&host_svc.CompleteMaintenanceFailure{
+					Hostname: hostname,
+					Message:  "host is not DOWN",
+				})
+			continue
 		}
-		machineID := &mesos.MachineID{
-			Hostname: &hostInfo.Hostname,
-			Ip:       &hostInfo.Ip,
+		var err error
+		if request.GetReboot() {
+			err = m.runProviderOperation(
+				ctx,
+				hostInfo,
+				m.hostProvider.Reboot,
+				hpb.HostEventType_HOST_EVENT_TYPE_REBOOTED)
+		}
+		if err == nil {
+			err = m.stopHostMaintenance(ctx, hostInfo)
+		}
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).
+				WithField("hostname", hostname).
+				Warn("Failed to complete maintenance")
+			response.Failures = append(response.Failures,
+				&host_svc.CompleteMaintenanceFailure{
+					Hostname: hostname,
+					Message:  err.Error(),
+				})
+			continue
 		}
-		machineIds = append(machineIds, machineID)
+		response.CompletedHostnames = append(
+			response.CompletedHostnames, hostname)
 	}
 
-	err := m.operatorMasterClient.StopMaintenance(machineIds)
-	if err != nil {
+	if len(response.GetCompletedHostnames()) > 0 {
+		m.maintenanceHostInfoMap.RemoveHostInfos(
+			response.GetCompletedHostnames())
+		m.eventBus.Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: response.GetCompletedHostnames(),
+			From:      hpb.HostState_HOST_STATE_DOWN,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
+		logging.FromContext(ctx).
+			WithField("completed_hosts", response.GetCompletedHostnames()).
+			Info("Maintenance completed")
+	}
+
+	if len(response.GetFailures()) > 0 {
 		m.metrics.CompleteMaintenanceFail.Inc(1)
+	} else {
+		m.metrics.CompleteMaintenanceSuccess.Inc(1)
+	}
+	return response, nil
+}
+
+// stopHostMaintenance takes a DOWN host out of maintenance in Mesos
+// Master, by stopping the maintenance of its machine or reactivating its
+// agent if it was drained by Mesos Master.
+func (m *serviceHandler) stopHostMaintenance(
+	ctx context.Context,
+	hostInfo *hpb.HostInfo) error {
+	if isAgentDrain(hostInfo) {
+		return m.reactivateAgent(ctx, hostInfo.GetHostname())
+	}
+	machineID := &mesos.MachineID{
+		Hostname: &hostInfo.Hostname,
+		Ip:       &hostInfo.Ip,
+	}
+	return m.operatorMasterClient.StopMaintenance(
+		ctx,
+		[]*mesos.MachineID{machineID})
+}
+
+// GetMaintenanceDeadLetters returns the hosts which failed to drain too many
+// times and have been moved to the maintenance dead-letter queue.
+func (m *serviceHandler) GetMaintenanceDeadLetters(
+	ctx context.Context,
+	request *host_svc.GetMaintenanceDeadLettersRequest,
+) (*host_svc.GetMaintenanceDeadLettersResponse, error) {
+	m.metrics.GetMaintenanceDeadLettersAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
 		return nil, err
 	}
+	return &host_svc.GetMaintenanceDeadLettersResponse{
+		Hostnames: m.maintenanceQueue.DeadLetters(),
+	}, nil
+}
 
-	m.maintenanceHostInfoMap.RemoveHostInfos(hostnames)
+// RedriveMaintenanceDeadLetters moves hosts from the maintenance dead-letter
+// queue back into the maintenance queue, so that the draining of the tasks
+// running on them is retried.
+func (m *serviceHandler) RedriveMaintenanceDeadLetters(
+	ctx context.Context,
+	request *host_svc.RedriveMaintenanceDeadLettersRequest,
+) (*host_svc.RedriveMaintenanceDeadLettersResponse, error) {
+	m.metrics.RedriveMaintenanceDeadLettersAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.RedriveMaintenanceDeadLettersFail.Inc(1)
+		return nil, err
+	}
 
-	m.metrics.CompleteMaintenanceSuccess.Inc(1)
-	return &host_svc.CompleteMaintenanceResponse{}, nil
+	hostnames, mappings, err := m.resolveHostnames(request.GetHostnames(), nil)
+	if err != nil {
+		m.metrics.RedriveMaintenanceDeadLettersFail.Inc(1)
+		return nil, err
+	}
+
+	// Redrive only fails for hosts which are not dead-lettered
+	if err := m.maintenanceQueue.Redrive(hostnames); err != nil {
+		m.metrics.RedriveMaintenanceDeadLettersFail.Inc(1)
+		return nil, yarpcerrors.FailedPreconditionErrorf("%v", err)
+	}
+
+	logging.FromContext(logging.WithHosts(ctx, hostnames)).
+		Info("Re-drove hosts from maintenance dead-letter queue")
+	m.metrics.RedriveMaintenanceDeadLettersSuccess.Inc(1)
+	return &host_svc.RedriveMaintenanceDeadLettersResponse{
+		HostnameMappings: mappings,
+	}, nil
 }
 
 // Build host info for registered agents
-func buildHostInfoForRegisteredAgents() (map[string]*hpb.HostInfo, error) {
+func (m *serviceHandler) buildHostInfoForRegisteredAgents() (map[string]*hpb.HostInfo, error) {
 	agentMap := host.GetAgentMap()
 	if agentMap == nil || len(agentMap.RegisteredAgents) == 0 {
 		return nil, nil
 	}
+	pids := make([]string, 0, len(agentMap.RegisteredAgents))
+	for _, agent := range agentMap.RegisteredAgents {
+		pids = append(pids, agent.GetPid())
+	}
+	// Drop cached PIDs of agents which are no longer registered
+	m.pidCache.Retain(pids)
+
 	upHosts := make(map[string]*hpb.HostInfo)
 	for _, agent := range agentMap.RegisteredAgents {
 		hostname := agent.GetAgentInfo().GetHostname()
-		agentIP, _, err := util.ExtractIPAndPortFromMesosAgentPID(
-			agent.GetPid())
+		agentIP, _, err := m.pidCache.Parse(agent.GetPid())
 		if err != nil {
-			return nil, err
+			return nil, newInternalError(err,
+				"failed to parse pid of host %s", hostname)
 		}
+		attributes := agent.GetAgentInfo().GetAttributes()
 		hostInfo := &hpb.HostInfo{
-			Hostname: hostname,
-			Ip:       agentIP,
-			State:    hpb.HostState_HOST_STATE_UP,
+			Hostname:  hostname,
+			Ip:        agentIP,
+			State:     hpb.HostState_HOST_STATE_UP,
+			Resources: buildHostResources(agent.GetTotalResources()),
+			Pool:      host.GetAssignedHostPool(hostname, attributes),
+			Labels: host.GetAssignedLabels(
+				hostname,
+				buildHostLabels(attributes)),
 		}
 		upHosts[hostname] = hostInfo
 	}
 	return upHosts, nil
 }
 
-// Build machine ID for specified hosts
-func buildMachineIDsForHosts(
+// buildHostResources returns the non-revocable resources of a host
+// from the total resources of its agent.
+func buildHostResources(resources []*mesos.Resource) *hpb.HostResources {
+	_, nonRevocable := scalar.FilterRevocableMesosResources(resources)
+	r := scalar.FromMesosResources(nonRevocable)
+	return &hpb.HostResources{
+		Cpus:          r.GetCPU(),
+		MemMb:         r.GetMem(),
+		DiskMb:        r.GetDisk(),
+		Gpus:          r.GetGPU(),
+		CustomScalars: scalar.CustomScalarsFromMesosResources(nonRevocable),
+	}
+}
+
+// buildHostLabels returns the attributes of an agent as labels, with
+// the same values used for evaluating constraints on the host.
+func buildHostLabels(attributes []*mesos.Attribute) []*peloton.Label {
+	var labels []*peloton.Label
+	for _, attr := range attributes {
+		values, ok := constraints.GetAttributeValues(attr)
+		if !ok {
+			continue
+		}
+		for _, value := range values {
+			labels = append(labels, &peloton.Label{
+				Key:   attr.GetName(),
+				Value: value,
+			})
+		}
+	}
+	return labels
+}
+
+// retryMasterRead retries a read of Mesos Master. Writes are not retried
+// since a failed write may have been applied.
+func (m *serviceHandler) retryMasterRead(
+	ctx context.Context,
+	read func() error,
+) error {
+	if m.masterRetryPolicy == nil {
+		return read()
+	}
+	return backoff.RetryContext(
+		ctx, read, m.masterRetryPolicy, nil, m.masterRetryBudget)
+}
+
+// getMaintenanceSchedule reads the maintenance schedule of Mesos Master.
+func (m *serviceHandler) getMaintenanceSchedule(
+	ctx context.Context,
+) (response *mesos_master.Response_GetMaintenanceSchedule, err error) {
+	err = m.retryMasterRead(ctx, func() error {
+		response, err = m.operatorMasterClient.GetMaintenanceSchedule()
+		return err
+	})
+	return response, err
+}
+
+// Build machine ID for specified hosts, the parsing of the pids of their
+// agents stops once 'ctx' is done.
+func (m *serviceHandler) buildMachineIDsForHosts(
+	ctx context.Context,
 	hostnames []string,
 ) ([]*mesos.MachineID, error) {
-	var machineIds []*mesos.MachineID
 	agentMap := host.GetAgentMap()
 	if agentMap == nil || len(agentMap.RegisteredAgents) == 0 {
-		return nil, fmt.Errorf("no registered agents")
+		return nil, newNoRegisteredAgentsError()
 	}
-	for i := 0; i < len(hostnames); i++ {
-		hostname := hostnames[i]
+	for _, hostname := range hostnames {
 		if _, ok := agentMap.RegisteredAgents[hostname]; !ok {
-			return nil, fmt.Errorf("unknown host %s", hostname)
+			return nil, newUnknownHostError(hostname)
 		}
-		pid := agentMap.RegisteredAgents[hostname].GetPid()
-		ip, _, err := util.ExtractIPAndPortFromMesosAgentPID(pid)
-		if err != nil {
-			return nil, err
-		}
-		machineID := &mesos.MachineID{
-			Hostname: &hostname,
-			Ip:       &ip,
-		}
-		machineIds = append(machineIds, machineID)
+	}
+
+	// Parse the pids of the agents in a worker pool, each worker writes
+	// the machine ID of its host at the index of the host.
+	machineIds := make([]*mesos.MachineID, len(hostnames))
+	err := concurrency.ForEach(
+		ctx,
+		len(hostnames),
+		concurrency.WorkerPoolOptions{StopOnError: true},
+		m.machineIDMetrics,
+		func(_ context.Context, i int) error {
+			hostname := hostnames[i]
+			pid := agentMap.RegisteredAgents[hostname].GetPid()
+			ip, _, err := m.pidCache.Parse(pid)
+			if err != nil {
+				return newInternalError(err,
+					"failed to parse pid of host %s", hostname)
+			}
+			machineIds[i] = &mesos.MachineID{
+				Hostname: &hostname,
+				Ip:       &ip,
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
 	}
 	return machineIds, nil
 }
@@ -17,26 +17,45 @@ package hostsvc
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"testing"
+	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
 	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
 	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	"github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	svcmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	resmocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
 
+	"github.com/uber/peloton/pkg/common/backoff"
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
 	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	ebmocks "github.com/uber/peloton/pkg/hostmgr/eventbus/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	hpmocks "github.com/uber/peloton/pkg/hostmgr/hostprovider/mocks"
 	ym "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
+	task_state_mocks "github.com/uber/peloton/pkg/hostmgr/task/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/encoding"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
+var _zoneAttribute = "zone"
+
 type HostSvcHandlerTestSuite struct {
 	suite.Suite
 
@@ -50,11 +69,25 @@ type HostSvcHandlerTestSuite struct {
 	mockMasterOperatorClient *ym.MockMasterOperatorClient
 	mockMaintenanceQueue     *qm.MockMaintenanceQueue
 	mockMaintenanceMap       *hm.MockMaintenanceHostInfoMap
+	mockMaintenanceHistory   *hm.MockMaintenanceHistory
+	mockHostEventLog         *hm.MockHostEventLog
+	mockAssignmentMap        *hm.MockAssignmentMap
+	mockApprovalOps          *objectmocks.MockMaintenanceApprovalOps
+	mockHostTaskIndex        *task_state_mocks.MockHostTaskIndex
+	mockHostProvider         *hpmocks.MockHostProvider
+	mockEventBus             *ebmocks.MockBus
+	mockReservationOps       *objectmocks.MockHostReservationOps
+	mockIdempotencyKeyOps    *objectmocks.MockIdempotencyKeyOps
+	mockMaintenancePolicyOps *objectmocks.MockMaintenancePolicyOps
+	mockResmgrClient         *resmocks.MockResourceManagerServiceYARPCClient
+	mockCandidate            *leadermocks.MockCandidate
+	mockDiscovery            *leadermocks.MockDiscovery
 }
 
 func (suite *HostSvcHandlerTestSuite) SetupSuite() {
 	suite.handler = &serviceHandler{
-		metrics: NewMetrics(tally.NoopScope),
+		metrics:  NewMetrics(tally.NoopScope),
+		pidCache: util.NewAgentPIDCache(tally.NoopScope),
 	}
 	testUpMachines := []struct {
 		host string
@@ -118,7 +151,45 @@ func (suite *HostSvcHandlerTestSuite) SetupTest() {
 	suite.mockMaintenanceMap = hm.NewMockMaintenanceHostInfoMap(suite.mockCtrl)
 	suite.handler.operatorMasterClient = suite.mockMasterOperatorClient
 	suite.handler.maintenanceQueue = suite.mockMaintenanceQueue
+	suite.mockReservationOps = objectmocks.NewMockHostReservationOps(suite.mockCtrl)
 	suite.handler.maintenanceHostInfoMap = suite.mockMaintenanceMap
+	suite.mockMaintenanceHistory = hm.NewMockMaintenanceHistory(suite.mockCtrl)
+	suite.handler.maintenanceHistory = suite.mockMaintenanceHistory
+	suite.mockHostEventLog = hm.NewMockHostEventLog(suite.mockCtrl)
+	suite.handler.hostEventLog = suite.mockHostEventLog
+	suite.mockAssignmentMap = hm.NewMockAssignmentMap(suite.mockCtrl)
+	suite.handler.assignmentMap = suite.mockAssignmentMap
+	suite.mockApprovalOps = objectmocks.NewMockMaintenanceApprovalOps(suite.mockCtrl)
+	suite.handler.approvalMap = host.NewApprovalMap(
+		suite.mockApprovalOps,
+		tally.NoopScope)
+	suite.handler.authenticated = true
+	suite.mockHostTaskIndex = task_state_mocks.NewMockHostTaskIndex(suite.mockCtrl)
+	suite.handler.hostTaskIndex = suite.mockHostTaskIndex
+	suite.mockHostProvider = hpmocks.NewMockHostProvider(suite.mockCtrl)
+	suite.mockHostProvider.EXPECT().Name().Return("AWS").AnyTimes()
+	suite.handler.hostProvider = nil
+	suite.mockEventBus = ebmocks.NewMockBus(suite.mockCtrl)
+	suite.handler.eventBus = suite.mockEventBus
+	suite.handler.reservationOps = suite.mockReservationOps
+	suite.mockIdempotencyKeyOps = objectmocks.NewMockIdempotencyKeyOps(suite.mockCtrl)
+	suite.handler.idempotencyKeys = newIdempotencyKeys(suite.mockIdempotencyKeyOps)
+	suite.mockMaintenancePolicyOps = objectmocks.NewMockMaintenancePolicyOps(suite.mockCtrl)
+	suite.handler.maintenancePolicyOps = suite.mockMaintenancePolicyOps
+	suite.handler.defaultMaintenancePolicies = nil
+	suite.mockResmgrClient = resmocks.NewMockResourceManagerServiceYARPCClient(suite.mockCtrl)
+	suite.handler.resmgrClient = suite.mockResmgrClient
+	suite.mockCandidate = leadermocks.NewMockCandidate(suite.mockCtrl)
+	suite.mockDiscovery = leadermocks.NewMockDiscovery(suite.mockCtrl)
+	suite.handler.candidate = suite.mockCandidate
+	suite.handler.discovery = suite.mockDiscovery
+	suite.handler.leaderClient = nil
+	suite.handler.federation = nil
+	suite.handler.maintenanceFreeze = &maintenanceFreeze{}
+	suite.handler.canaryDrains = &canaryDrains{}
+	suite.handler.afterFunc = nil
+	suite.handler.drainMethod = hpb.DrainMethod_DRAIN_METHOD_MAINTENANCE_SCHEDULE
+	suite.mockCandidate.EXPECT().IsLeader().Return(true).AnyTimes()
 
 	response := suite.makeAgentsResponse()
 	loader := &host.Loader{
@@ -140,11 +211,33 @@ func (suite *HostSvcHandlerTestSuite) makeAgentsResponse() *mesosmaster.Response
 	}
 	for i, upMachine := range suite.upMachines {
 		pid := fmt.Sprintf("slave(%d)@%s:0.0.0.0", i, upMachine.GetIp())
+		zone := "dca1"
 		agent := &mesosmaster.Response_GetAgents_Agent{
 			AgentInfo: &mesos.AgentInfo{
 				Hostname: upMachine.Hostname,
+				Attributes: []*mesos.Attribute{
+					{
+						Name: &_zoneAttribute,
+						Type: mesos.Value_TEXT.Enum(),
+						Text: &mesos.Value_Text{Value: &zone},
+					},
+				},
 			},
 			Pid: &pid,
+			TotalResources: []*mesos.Resource{
+				util.NewMesosResourceBuilder().
+					WithName("cpus").
+					WithValue(4.0).
+					Build(),
+				util.NewMesosResourceBuilder().
+					WithName("gpus").
+					WithValue(2.0).
+					Build(),
+				util.NewMesosResourceBuilder().
+					WithName("fpgas").
+					WithValue(1.0).
+					Build(),
+			},
 		}
 		response.Agents = append(response.Agents, agent)
 	}
@@ -191,18 +284,77 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenance() {
 				Schedule: &mesosmaintenance.Schedule{},
 			}, nil),
 		suite.mockMasterOperatorClient.EXPECT().
-			UpdateMaintenanceSchedule(gomock.Any()).Return(nil),
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
 		suite.mockMaintenanceMap.EXPECT().
 			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
 		suite.mockMaintenanceQueue.EXPECT().
-			Enqueue(hosts).Return(nil),
+			Enqueue(hosts).Return(hosts[:1], nil),
 	)
 
-	_, err := suite.handler.StartMaintenance(suite.ctx,
+	response, err := suite.handler.StartMaintenance(suite.ctx,
 		&svcpb.StartMaintenanceRequest{
 			Hostnames: hosts,
 		})
 	suite.NoError(err)
+	suite.Equal(hosts[:1], response.GetSkippedHostnames())
+}
+
+// TestStartMaintenanceDrainOptions tests that the drain options of the
+// request are recorded for the DRAINING hosts
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDrainOptions() {
+	drainOptions := &hpb.DrainOptions{
+		KillGracePeriodSeconds: 120,
+		Message:                "host going down for maintenance",
+		Labels: []*peloton.Label{
+			{Key: "reason", Value: "kernel-upgrade"},
+		},
+	}
+
+	var (
+		hosts     []string
+		hostInfos []*hpb.HostInfo
+	)
+	for _, machine := range suite.upMachines {
+		hosts = append(hosts, machine.GetHostname())
+		hostInfos = append(hostInfos, &hpb.HostInfo{
+			Hostname:     machine.GetHostname(),
+			Ip:           machine.GetIp(),
+			State:        hpb.HostState_HOST_STATE_DRAINING,
+			DrainOptions: drainOptions,
+		})
+	}
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue(hosts).Return(nil, nil),
+	)
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    hosts,
+			DrainOptions: drainOptions,
+		})
+	suite.NoError(err)
 }
 
 func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
@@ -227,7 +379,7 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
 		&svcpb.StartMaintenanceRequest{
 			Hostnames: hosts,
 		})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsUnavailable(err), err.Error())
 	suite.Nil(response)
 
 	// Test error while posting maintenance schedule
@@ -237,13 +389,13 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
 			Schedule: &mesosmaintenance.Schedule{},
 		}, nil)
 	suite.mockMasterOperatorClient.EXPECT().
-		UpdateMaintenanceSchedule(gomock.Any()).
+		UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).
 		Return(fmt.Errorf("fake UpdateMaintenanceSchedule error"))
 	response, err = suite.handler.StartMaintenance(suite.ctx,
 		&svcpb.StartMaintenanceRequest{
 			Hostnames: hosts,
 		})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsUnavailable(err), err.Error())
 	suite.Nil(response)
 
 	// Test error while enqueuing in maintenance queue
@@ -254,24 +406,41 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
 				Schedule: &mesosmaintenance.Schedule{},
 			}, nil),
 		suite.mockMasterOperatorClient.EXPECT().
-			UpdateMaintenanceSchedule(gomock.Any()).Return(nil),
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
 		suite.mockMaintenanceMap.EXPECT().
 			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
 		suite.mockMaintenanceQueue.EXPECT().
-			Enqueue(hosts).Return(fmt.Errorf("fake Enqueue error")),
+			Enqueue(hosts).Return(nil, fmt.Errorf("fake Enqueue error")),
+		// The maintenance of the hosts is undone
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), &mesosmaintenance.Schedule{}).
+			Return(nil),
+		suite.mockMaintenanceMap.EXPECT().RemoveHostInfos(hosts),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_DRAINING,
+				To:        hpb.HostState_HOST_STATE_UP,
+			}),
 	)
 	response, err = suite.handler.StartMaintenance(suite.ctx,
 		&svcpb.StartMaintenanceRequest{
 			Hostnames: hosts,
 		})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsInternal(err), err.Error())
 	suite.Nil(response)
 
 	// Test Unknown host error
 	response, err = suite.handler.StartMaintenance(suite.ctx, &svcpb.StartMaintenanceRequest{
 		Hostnames: []string{"invalid"},
 	})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsNotFound(err), err.Error())
 	suite.Nil(response)
 
 	// TestExtractIPFromMesosAgentPID error
@@ -280,7 +449,7 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
 	response, err = suite.handler.StartMaintenance(suite.ctx, &svcpb.StartMaintenanceRequest{
 		Hostnames: hosts,
 	})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsInternal(err), err.Error())
 	suite.Nil(response)
 
 	// Test 'No registered agents' error
@@ -294,10 +463,28 @@ func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceError() {
 	response, err = suite.handler.StartMaintenance(suite.ctx, &svcpb.StartMaintenanceRequest{
 		Hostnames: hosts,
 	})
-	suite.Error(err)
+	suite.True(yarpcerrors.IsUnavailable(err), err.Error())
 	suite.Nil(response)
 }
 
+// TestBuildMachineIDsForHostsCanceled tests that the machine IDs of the
+// hosts are not built once the context of the request is canceled.
+func (suite *HostSvcHandlerTestSuite) TestBuildMachineIDsForHostsCanceled() {
+	hostnames := []string{suite.upMachines[0].GetHostname()}
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+	machineIDs, err := suite.handler.buildMachineIDsForHosts(ctx, hostnames)
+	suite.Equal(context.Canceled, err)
+	suite.Nil(machineIDs)
+
+	machineIDs, err = suite.handler.buildMachineIDsForHosts(
+		suite.ctx,
+		hostnames)
+	suite.NoError(err)
+	suite.Equal(suite.upMachines, machineIDs)
+}
+
 func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenance() {
 	var (
 		hosts     []string
@@ -318,16 +505,62 @@ func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenance() {
 		GetDownHostInfos([]string{}).
 		Return(hostInfos)
 	suite.mockMasterOperatorClient.EXPECT().
-		StopMaintenance(suite.downMachines).Return(nil)
+		StopMaintenance(gomock.Any(), suite.downMachines).Return(nil)
 	suite.mockMaintenanceMap.EXPECT().
 		RemoveHostInfos(hosts)
+	suite.mockEventBus.EXPECT().
+		Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: suite.hostsToDown,
+			From:      hpb.HostState_HOST_STATE_DOWN,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
 
 	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
 		&svcpb.CompleteMaintenanceRequest{
 			Hostnames: suite.hostsToDown,
 		})
 	suite.NoError(err)
-	suite.NotNil(resp)
+	suite.Equal(suite.hostsToDown, resp.GetCompletedHostnames())
+	suite.Empty(resp.GetFailures())
+}
+
+// TestCompleteMaintenancePartialFailure tests that hosts which are not
+// DOWN do not block completing maintenance on the other hosts
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenancePartialFailure() {
+	machine := suite.downMachines[0]
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				Ip:       machine.GetIp(),
+				State:    hpb.HostState_HOST_STATE_DOWN,
+			},
+		})
+	suite.mockMasterOperatorClient.EXPECT().
+		StopMaintenance(gomock.Any(), []*mesos.MachineID{machine}).
+		Return(nil)
+	suite.mockMaintenanceMap.EXPECT().
+		RemoveHostInfos([]string{machine.GetHostname()})
+	suite.mockEventBus.EXPECT().
+		Publish(&eventbus.HostStateChangedEvent{
+			Hostnames: []string{machine.GetHostname()},
+			From:      hpb.HostState_HOST_STATE_DOWN,
+			To:        hpb.HostState_HOST_STATE_UP,
+		})
+
+	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
+		&svcpb.CompleteMaintenanceRequest{
+			Hostnames: []string{
+				"typo-host",
+				machine.GetHostname(),
+				machine.GetHostname(),
+			},
+		})
+	suite.NoError(err)
+	suite.Equal([]string{machine.GetHostname()}, resp.GetCompletedHostnames())
+	suite.Len(resp.GetFailures(), 1)
+	suite.Equal("typo-host", resp.GetFailures()[0].GetHostname())
 }
 
 func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceError() {
@@ -351,21 +584,21 @@ func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceError() {
 		GetDownHostInfos([]string{}).
 		Return(hostInfos)
 	suite.mockMasterOperatorClient.EXPECT().
-		StopMaintenance(suite.downMachines).
+		StopMaintenance(gomock.Any(), suite.downMachines).
 		Return(fmt.Errorf("fake StopMaintenance error"))
 
 	resp, err := suite.handler.CompleteMaintenance(suite.ctx,
 		&svcpb.CompleteMaintenanceRequest{
 			Hostnames: suite.hostsToDown,
 		})
-	suite.Error(err)
-	suite.Nil(resp)
+	suite.NoError(err)
+	suite.Empty(resp.GetCompletedHostnames())
+	suite.Len(resp.GetFailures(), len(suite.hostsToDown))
+	suite.Equal(
+		"fake StopMaintenance error",
+		resp.GetFailures()[0].GetMessage())
 
 	// Test 'Host not down' error
-	var hostnames []string
-	for _, hostInfo := range hostInfos {
-		hostnames = append(hostnames, hostInfo.GetHostname())
-	}
 	suite.mockMaintenanceMap.EXPECT().
 		GetDownHostInfos([]string{}).
 		Return([]*hpb.HostInfo{})
@@ -373,7 +606,44 @@ func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceError() {
 		&svcpb.CompleteMaintenanceRequest{
 			Hostnames: suite.hostsToDown,
 		})
-	suite.Error(err)
+	suite.NoError(err)
+	suite.Empty(resp.GetCompletedHostnames())
+	suite.Len(resp.GetFailures(), len(suite.hostsToDown))
+}
+
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceDeadLetters() {
+	suite.mockMaintenanceQueue.EXPECT().
+		DeadLetters().
+		Return(suite.hostsToDown)
+
+	resp, err := suite.handler.GetMaintenanceDeadLetters(suite.ctx,
+		&svcpb.GetMaintenanceDeadLettersRequest{})
+	suite.NoError(err)
+	suite.Equal(suite.hostsToDown, resp.GetHostnames())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestRedriveMaintenanceDeadLetters() {
+	suite.mockMaintenanceQueue.EXPECT().
+		Redrive(suite.hostsToDown).
+		Return(nil)
+
+	resp, err := suite.handler.RedriveMaintenanceDeadLetters(suite.ctx,
+		&svcpb.RedriveMaintenanceDeadLettersRequest{
+			Hostnames: suite.hostsToDown,
+		})
+	suite.NoError(err)
+	suite.NotNil(resp)
+
+	// Test Redrive error
+	suite.mockMaintenanceQueue.EXPECT().
+		Redrive(suite.hostsToDown).
+		Return(fmt.Errorf("fake Redrive error"))
+
+	resp, err = suite.handler.RedriveMaintenanceDeadLetters(suite.ctx,
+		&svcpb.RedriveMaintenanceDeadLettersRequest{
+			Hostnames: suite.hostsToDown,
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err), err.Error())
 	suite.Nil(resp)
 }
 
@@ -487,6 +757,167 @@ func (suite *HostSvcHandlerTestSuite) TestQueryHosts() {
 	}
 }
 
+// TestQueryHostsResources tests that the resources of UP hosts,
+// including GPUs and custom scalar resources, are returned.
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsResources() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{})
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{})
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_UP,
+		},
+	})
+	suite.NoError(err)
+
+	upHosts := stringset.New()
+	for _, machine := range suite.upMachines {
+		upHosts.Add(machine.GetHostname())
+	}
+	for _, hostInfo := range resp.GetHostInfos() {
+		if !upHosts.Contains(hostInfo.GetHostname()) {
+			continue
+		}
+		suite.Equal(4.0, hostInfo.GetResources().GetCpus())
+		suite.Equal(2.0, hostInfo.GetResources().GetGpus())
+		suite.Equal(
+			map[string]float64{"fpgas": 1.0},
+			hostInfo.GetResources().GetCustomScalars())
+	}
+}
+
+// TestQueryHostsFilters tests filtering the hosts by pools and labels.
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsFilters() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: suite.drainingMachines[0].GetHostname(),
+				Ip:       suite.drainingMachines[0].GetIp(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+			},
+		}).
+		AnyTimes()
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{}).
+		AnyTimes()
+
+	testCases := map[string]struct {
+		pools     []string
+		labels    []*peloton.Label
+		hostnames []string
+	}{
+		"no filter": {
+			hostnames: []string{"host1", "host3"},
+		},
+		"matching pool": {
+			pools:     []string{"other", host.DefaultHostPool},
+			hostnames: []string{"host1"},
+		},
+		"unknown pool": {
+			pools: []string{"other"},
+		},
+		"matching label": {
+			labels: []*peloton.Label{
+				{Key: _zoneAttribute, Value: "dca1"},
+			},
+			hostnames: []string{"host1"},
+		},
+		"missing label": {
+			labels: []*peloton.Label{
+				{Key: _zoneAttribute, Value: "dca1"},
+				{Key: "rack", Value: "a1"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+			Pools:  tc.pools,
+			Labels: tc.labels,
+		})
+		suite.NoError(err, name)
+
+		var hostnames []string
+		for _, hostInfo := range resp.GetHostInfos() {
+			hostnames = append(hostnames, hostInfo.GetHostname())
+		}
+		suite.ElementsMatch(tc.hostnames, hostnames, name)
+	}
+}
+
+// TestQueryHostsPoolAndLabels tests that the pool and labels of UP
+// hosts are returned.
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsPoolAndLabels() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{})
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{})
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_UP,
+		},
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetHostInfos(), len(suite.upMachines))
+	for _, hostInfo := range resp.GetHostInfos() {
+		suite.Equal(host.DefaultHostPool, hostInfo.GetPool())
+		suite.Equal([]*peloton.Label{
+			{Key: _zoneAttribute, Value: "dca1"},
+		}, hostInfo.GetLabels())
+	}
+}
+
+// TestBuildHostLabels tests converting agent attributes into labels.
+func (suite *HostSvcHandlerTestSuite) TestBuildHostLabels() {
+	rack := "rack"
+	rackValue := "a1"
+	cores := "cores"
+	coresValue := 1.5
+	disks := "disks"
+	ports := "ports"
+	begin, end := uint64(31000), uint64(32000)
+
+	labels := buildHostLabels([]*mesos.Attribute{
+		{
+			Name: &rack,
+			Type: mesos.Value_TEXT.Enum(),
+			Text: &mesos.Value_Text{Value: &rackValue},
+		},
+		{
+			Name:   &cores,
+			Type:   mesos.Value_SCALAR.Enum(),
+			Scalar: &mesos.Value_Scalar{Value: &coresValue},
+		},
+		{
+			Name: &disks,
+			Type: mesos.Value_SET.Enum(),
+			Set:  &mesos.Value_Set{Item: []string{"ssd", "hdd"}},
+		},
+		{
+			Name: &ports,
+			Type: mesos.Value_RANGES.Enum(),
+			Ranges: &mesos.Value_Ranges{
+				Range: []*mesos.Value_Range{{Begin: &begin, End: &end}},
+			},
+		},
+	})
+	suite.Equal([]*peloton.Label{
+		{Key: rack, Value: rackValue},
+		{Key: cores, Value: "1.500000"},
+		{Key: disks, Value: "ssd"},
+		{Key: disks, Value: "hdd"},
+	}, labels)
+}
+
 func (suite *HostSvcHandlerTestSuite) TestQueryHostsError() {
 	// Test ExtractIPFromMesosAgentPID error
 	hostname := "testhost"
@@ -540,3 +971,139 @@ func (suite *HostSvcHandlerTestSuite) TestQueryHostsError() {
 	suite.NoError(err)
 	suite.NotNil(resp)
 }
+
+// TestNotLeader tests that the handlers of a host manager which is not
+// the leader return a not leader error carrying the leader address.
+func (suite *HostSvcHandlerTestSuite) TestNotLeader() {
+	candidate := leadermocks.NewMockCandidate(suite.mockCtrl)
+	suite.handler.candidate = candidate
+	candidate.EXPECT().IsLeader().Return(false).AnyTimes()
+	suite.mockDiscovery.EXPECT().
+		GetAppURL(gomock.Any()).
+		Return(&url.URL{Host: "10.0.0.1:5391"}, nil).
+		AnyTimes()
+
+	calls := []func() error{
+		func() error {
+			_, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.StartMaintenance(suite.ctx, &svcpb.StartMaintenanceRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.CompleteMaintenance(suite.ctx, &svcpb.CompleteMaintenanceRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.GetMaintenanceDeadLetters(suite.ctx, &svcpb.GetMaintenanceDeadLettersRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.RedriveMaintenanceDeadLetters(suite.ctx, &svcpb.RedriveMaintenanceDeadLettersRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.CreateReservation(suite.ctx, &svcpb.CreateReservationRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.ListReservations(suite.ctx, &svcpb.ListReservationsRequest{})
+			return err
+		},
+		func() error {
+			_, err := suite.handler.ReleaseReservation(suite.ctx, &svcpb.ReleaseReservationRequest{})
+			return err
+		},
+	}
+	for _, call := range calls {
+		leaderAddress, ok := LeaderFromError(call())
+		suite.True(ok)
+		suite.Equal("10.0.0.1:5391", leaderAddress)
+	}
+}
+
+// TestNotLeaderUnknownLeader tests the not leader error when the leader
+// cannot be discovered.
+func (suite *HostSvcHandlerTestSuite) TestNotLeaderUnknownLeader() {
+	candidate := leadermocks.NewMockCandidate(suite.mockCtrl)
+	suite.handler.candidate = candidate
+	candidate.EXPECT().IsLeader().Return(false)
+	suite.mockDiscovery.EXPECT().
+		GetAppURL(gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	_, err := suite.handler.StartMaintenance(suite.ctx, &svcpb.StartMaintenanceRequest{})
+	leaderAddress, ok := LeaderFromError(err)
+	suite.True(ok)
+	suite.Empty(leaderAddress)
+
+	_, ok = LeaderFromError(errors.New("test error"))
+	suite.False(ok)
+}
+
+// TestQueryHostsFollowerProxy tests that a follower proxies QueryHosts
+// to the leader, unless the call was already proxied.
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsFollowerProxy() {
+	candidate := leadermocks.NewMockCandidate(suite.mockCtrl)
+	leaderClient := svcmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.handler.candidate = candidate
+	suite.handler.leaderClient = leaderClient
+	candidate.EXPECT().IsLeader().Return(false).AnyTimes()
+
+	request := &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{hpb.HostState_HOST_STATE_UP},
+	}
+	response := &svcpb.QueryHostsResponse{
+		HostInfos: []*hpb.HostInfo{{Hostname: "host1"}},
+	}
+	leaderClient.EXPECT().
+		QueryHosts(gomock.Any(), request, gomock.Any()).
+		Return(response, nil)
+	resp, err := suite.handler.QueryHosts(suite.ctx, request)
+	suite.NoError(err)
+	suite.Equal(response, resp)
+
+	// A proxied call is not proxied again
+	ctx, call := encoding.NewInboundCall(suite.ctx)
+	suite.NoError(call.ReadFromRequest(&transport.Request{
+		Headers: transport.NewHeaders().With(_proxiedHeader, "true"),
+	}))
+	suite.mockDiscovery.EXPECT().
+		GetAppURL(gomock.Any()).
+		Return(&url.URL{Host: "10.0.0.1:5391"}, nil)
+	_, err = suite.handler.QueryHosts(ctx, request)
+	_, ok := LeaderFromError(err)
+	suite.True(ok)
+}
+
+// TestGetMaintenanceScheduleRetry tests that failed reads of the
+// maintenance schedule are retried within the retry policy.
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceScheduleRetry() {
+	suite.handler.masterRetryPolicy = backoff.NewRetryPolicy(2, time.Millisecond)
+	defer func() {
+		suite.handler.masterRetryPolicy = nil
+	}()
+
+	schedule := &mesosmaster.Response_GetMaintenanceSchedule{}
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			GetMaintenanceSchedule().
+			Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error")),
+		suite.mockMasterOperatorClient.EXPECT().
+			GetMaintenanceSchedule().
+			Return(schedule, nil),
+	)
+	response, err := suite.handler.getMaintenanceSchedule(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(schedule, response)
+
+	// the error of the last attempt is returned
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error")).
+		Times(2)
+	_, err = suite.handler.getMaintenanceSchedule(suite.ctx)
+	suite.Error(err)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"time"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// GetHostEvents returns the timeline of the events of a host, oldest
+// first. The events are read from storage, so any host manager can serve
+// the call.
+func (m *serviceHandler) GetHostEvents(
+	ctx context.Context,
+	request *host_svc.GetHostEventsRequest,
+) (*host_svc.GetHostEventsResponse, error) {
+	m.metrics.GetHostEventsAPI.Inc(1)
+
+	if request.GetHostname() == "" {
+		m.metrics.GetHostEventsFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf("hostname is required")
+	}
+
+	var since time.Time
+	if request.GetSince() != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, request.GetSince())
+		if err != nil {
+			m.metrics.GetHostEventsFail.Inc(1)
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid since %q: %v", request.GetSince(), err)
+		}
+	}
+
+	events, err := m.hostEventLog.Get(ctx, request.GetHostname(), since)
+	if err != nil {
+		m.metrics.GetHostEventsFail.Inc(1)
+		return nil, err
+	}
+
+	m.metrics.GetHostEventsSuccess.Inc(1)
+	return &host_svc.GetHostEventsResponse{Events: events}, nil
+}
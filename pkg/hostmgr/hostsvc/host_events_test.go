@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func (suite *HostSvcHandlerTestSuite) TestGetHostEvents() {
+	events := []*hpb.HostEvent{
+		{
+			Hostname:  "host1",
+			Type:      hpb.HostEventType_HOST_EVENT_TYPE_DRAIN_STARTED,
+			EventTime: "2019-05-01T10:00:00Z",
+		},
+	}
+	since, err := time.Parse(time.RFC3339, "2019-05-01T09:00:00Z")
+	suite.NoError(err)
+	suite.mockHostEventLog.EXPECT().
+		Get(gomock.Any(), "host1", since).
+		Return(events, nil)
+
+	resp, err := suite.handler.GetHostEvents(
+		suite.ctx,
+		&svc.GetHostEventsRequest{
+			Hostname: "host1",
+			Since:    "2019-05-01T09:00:00Z",
+		})
+	suite.NoError(err)
+	suite.Equal(events, resp.GetEvents())
+
+	// Test all events are returned without since
+	suite.mockHostEventLog.EXPECT().
+		Get(gomock.Any(), "host1", time.Time{}).
+		Return(events, nil)
+	resp, err = suite.handler.GetHostEvents(
+		suite.ctx,
+		&svc.GetHostEventsRequest{Hostname: "host1"})
+	suite.NoError(err)
+	suite.Equal(events, resp.GetEvents())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestGetHostEventsError() {
+	// Test missing hostname
+	_, err := suite.handler.GetHostEvents(
+		suite.ctx,
+		&svc.GetHostEventsRequest{})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Test invalid since
+	_, err = suite.handler.GetHostEvents(
+		suite.ctx,
+		&svc.GetHostEventsRequest{Hostname: "host1", Since: "yesterday"})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Test storage error
+	suite.mockHostEventLog.EXPECT().
+		Get(gomock.Any(), "host1", gomock.Any()).
+		Return(nil, errors.New("fake Get error"))
+	_, err = suite.handler.GetHostEvents(
+		suite.ctx,
+		&svc.GetHostEventsRequest{Hostname: "host1"})
+	suite.Error(err)
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Maximum length of a hostname, see RFC 1035
+	_maxHostnameLength = 253
+)
+
+// Pattern of a hostname after normalization. Underscores are accepted
+// since some agents are registered with them.
+var _hostnamePattern = regexp.MustCompile(
+	`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?(\.[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?)*$`)
+
+// hostnameResolver resolves the hostnames of requests to the hostnames
+// of the agents known to host manager.
+type hostnameResolver struct {
+	// canonical hostnames by lower case hostname or IP
+	hostnames map[string]string
+	// canonical hostnames by short name, i.e. the first label of the
+	// hostname, empty for short names shared by several hosts
+	shortNames map[string]string
+}
+
+// newHostnameResolver returns a resolver of the registered agents and
+// of the given hosts, e.g. DOWN hosts which are no longer registered.
+func (m *serviceHandler) newHostnameResolver(
+	hostInfos []*hpb.HostInfo) *hostnameResolver {
+	r := &hostnameResolver{
+		hostnames:  make(map[string]string),
+		shortNames: make(map[string]string),
+	}
+	if agentMap := host.GetAgentMap(); agentMap != nil {
+		for hostname, agent := range agentMap.RegisteredAgents {
+			ip, _, err := m.pidCache.Parse(agent.GetPid())
+			if err != nil {
+				ip = ""
+			}
+			r.add(hostname, ip)
+		}
+	}
+	for _, hostInfo := range hostInfos {
+		r.add(hostInfo.GetHostname(), hostInfo.GetIp())
+	}
+	return r
+}
+
+// add adds a known host with its IP, which may be empty.
+func (r *hostnameResolver) add(hostname string, ip string) {
+	r.hostnames[strings.ToLower(hostname)] = hostname
+	if ip != "" {
+		r.hostnames[ip] = hostname
+	}
+	short := strings.ToLower(strings.SplitN(hostname, ".", 2)[0])
+	if other, ok := r.shortNames[short]; ok && other != hostname {
+		r.shortNames[short] = ""
+		return
+	}
+	r.shortNames[short] = hostname
+}
+
+// resolve returns the canonical hostname of a normalized hostname,
+// or the hostname itself if it is not known or is an ambiguous short
+// name.
+func (r *hostnameResolver) resolve(hostname string) string {
+	if canonical, ok := r.hostnames[hostname]; ok {
+		return canonical
+	}
+	if canonical := r.shortNames[hostname]; canonical != "" {
+		return canonical
+	}
+	return hostname
+}
+
+// normalizeHostname lower cases a hostname and removes surrounding
+// spaces and trailing dots. Returns an error if the result is neither
+// a valid hostname nor an IP address.
+func normalizeHostname(hostname string) (string, error) {
+	normalized := strings.ToLower(
+		strings.TrimRight(strings.TrimSpace(hostname), "."))
+	if normalized == "" {
+		return "", yarpcerrors.InvalidArgumentErrorf("empty hostname")
+	}
+	if net.ParseIP(normalized) != nil {
+		return normalized, nil
+	}
+	if len(normalized) > _maxHostnameLength ||
+		!_hostnamePattern.MatchString(normalized) {
+		return "", yarpcerrors.InvalidArgumentErrorf(
+			"invalid hostname %q", hostname)
+	}
+	return normalized, nil
+}
+
+// resolveHostnames validates the hostnames of a request, and resolves
+// them to the hostnames of the registered agents or of the given hosts:
+// the hostnames are normalized, and IP addresses and unique short names
+// are replaced by the hostnames of their hosts. Duplicate hosts are
+// removed. Returns the resolved hostnames in request order, with the
+// mappings of the hostnames which were changed.
+func (m *serviceHandler) resolveHostnames(
+	hostnames []string,
+	hostInfos []*hpb.HostInfo,
+) ([]string, []*hpb.HostnameMapping, error) {
+	if len(hostnames) == 0 {
+		return nil, nil, nil
+	}
+
+	resolver := m.newHostnameResolver(hostInfos)
+	seen := stringset.NewUnsafe()
+	var (
+		resolved []string
+		mappings []*hpb.HostnameMapping
+	)
+	for _, requested := range hostnames {
+		normalized, err := normalizeHostname(requested)
+		if err != nil {
+			return nil, nil, err
+		}
+		hostname := resolver.resolve(normalized)
+		if hostname != requested {
+			mappings = append(mappings, &hpb.HostnameMapping{
+				Requested: requested,
+				Hostname:  hostname,
+			})
+		}
+		if seen.Contains(hostname) {
+			continue
+		}
+		seen.Add(hostname)
+		resolved = append(resolved, hostname)
+	}
+	return resolved, mappings, nil
+}
+
+// resolveAgentIDs resolves the Mesos agent ids of a request to the
+// hostnames of the registered agents, or of the given hosts whose agent
+// id is known, e.g. DOWN hosts which are no longer registered. Returns
+// the hostnames in request order, with the mappings of all agent ids.
+// Returns a not found error for an agent id which is not known.
+func resolveAgentIDs(
+	agentIDs []string,
+	hostInfos []*hpb.HostInfo,
+) ([]string, []*hpb.HostnameMapping, error) {
+	if len(agentIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	hostnames := make(map[string]string)
+	for _, hostInfo := range hostInfos {
+		if hostInfo.GetAgentId() != "" {
+			hostnames[hostInfo.GetAgentId()] = hostInfo.GetHostname()
+		}
+	}
+	// Registered agents take precedence, as they are current
+	if agentMap := host.GetAgentMap(); agentMap != nil {
+		for hostname, agent := range agentMap.RegisteredAgents {
+			if id := agent.GetAgentInfo().GetId().GetValue(); id != "" {
+				hostnames[id] = hostname
+			}
+		}
+	}
+
+	var (
+		resolved []string
+		mappings []*hpb.HostnameMapping
+	)
+	for _, requested := range agentIDs {
+		agentID := strings.TrimSpace(requested)
+		if agentID == "" {
+			return nil, nil, yarpcerrors.InvalidArgumentErrorf("empty agent id")
+		}
+		hostname, ok := hostnames[agentID]
+		if !ok {
+			return nil, nil, yarpcerrors.NotFoundErrorf(
+				"unknown agent id %q", requested)
+		}
+		mappings = append(mappings, &hpb.HostnameMapping{
+			Requested: requested,
+			Hostname:  hostname,
+		})
+		resolved = append(resolved, hostname)
+	}
+	return resolved, mappings, nil
+}
+
+// resolveHosts resolves the hostnames and the agent ids of a request
+// with resolveHostnames and resolveAgentIDs. Returns the hostnames
+// followed by the hostnames of the agent ids, without duplicates, with
+// the mappings of both.
+func (m *serviceHandler) resolveHosts(
+	hostnames []string,
+	agentIDs []string,
+	hostInfos []*hpb.HostInfo,
+) ([]string, []*hpb.HostnameMapping, error) {
+	resolved, mappings, err := m.resolveHostnames(hostnames, hostInfos)
+	if err != nil {
+		return nil, nil, err
+	}
+	agentHostnames, agentMappings, err := resolveAgentIDs(agentIDs, hostInfos)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := stringset.NewUnsafe()
+	for _, hostname := range resolved {
+		seen.Add(hostname)
+	}
+	for _, hostname := range agentHostnames {
+		if seen.Contains(hostname) {
+			continue
+		}
+		seen.Add(hostname)
+		resolved = append(resolved, hostname)
+	}
+	return resolved, append(mappings, agentMappings...), nil
+}
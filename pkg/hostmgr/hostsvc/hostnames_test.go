@@ -0,0 +1,196 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"strings"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/mock/gomock"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// TestNormalizeHostname tests normalizing and validating hostnames
+func (suite *HostSvcHandlerTestSuite) TestNormalizeHostname() {
+	tt := []struct {
+		hostname   string
+		normalized string
+		valid      bool
+	}{
+		{hostname: "host1", normalized: "host1", valid: true},
+		{hostname: " Host1.Example.COM. ", normalized: "host1.example.com", valid: true},
+		{hostname: "host_1-a", normalized: "host_1-a", valid: true},
+		{hostname: "172.17.0.5", normalized: "172.17.0.5", valid: true},
+		{hostname: "::1", normalized: "::1", valid: true},
+		{hostname: ""},
+		{hostname: " . "},
+		{hostname: "host 1"},
+		{hostname: "host/1"},
+		{hostname: "-host1"},
+		{hostname: "host1..example.com"},
+		{hostname: strings.Repeat("a", 64)},
+	}
+
+	for _, t := range tt {
+		normalized, err := normalizeHostname(t.hostname)
+		if !t.valid {
+			suite.True(yarpcerrors.IsInvalidArgument(err), t.hostname)
+			continue
+		}
+		suite.NoError(err, t.hostname)
+		suite.Equal(t.normalized, normalized)
+	}
+}
+
+// TestResolveHostnames tests resolving hostnames, IP addresses and
+// short names to the hostnames of known hosts
+func (suite *HostSvcHandlerTestSuite) TestResolveHostnames() {
+	downHostInfos := []*hpb.HostInfo{
+		{
+			Hostname: "host2",
+			Ip:       "172.17.0.6",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+		},
+		{
+			Hostname: "web1.dc1.example.com",
+			Ip:       "10.0.0.1",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+		},
+		{
+			Hostname: "db1.dc1.example.com",
+			Ip:       "10.0.0.2",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+		},
+		{
+			Hostname: "db1.dc2.example.com",
+			Ip:       "10.0.0.3",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+		},
+	}
+
+	hostnames, mappings, err := suite.handler.resolveHostnames(
+		[]string{
+			"HOST1.",
+			"172.17.0.5",
+			"172.17.0.6",
+			"web1",
+			"db1",
+			"unknown",
+		},
+		downHostInfos)
+	suite.NoError(err)
+	suite.Equal([]string{
+		"host1",
+		"host2",
+		"web1.dc1.example.com",
+		"db1",
+		"unknown",
+	}, hostnames)
+	suite.Equal([]*hpb.HostnameMapping{
+		{Requested: "HOST1.", Hostname: "host1"},
+		{Requested: "172.17.0.5", Hostname: "host1"},
+		{Requested: "172.17.0.6", Hostname: "host2"},
+		{Requested: "web1", Hostname: "web1.dc1.example.com"},
+	}, mappings)
+
+	_, _, err = suite.handler.resolveHostnames(
+		[]string{"host1", "host 2"}, nil)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// loadAgentsWithIDs reloads the registered agents of the suite with the
+// agent id "agent-<hostname>" for each of them.
+func (suite *HostSvcHandlerTestSuite) loadAgentsWithIDs() {
+	response := suite.makeAgentsResponse()
+	for _, agent := range response.GetAgents() {
+		id := "agent-" + agent.GetAgentInfo().GetHostname()
+		agent.AgentInfo.Id = &mesos.AgentID{Value: &id}
+	}
+	loader := &host.Loader{
+		OperatorClient:         suite.mockMasterOperatorClient,
+		Scope:                  tally.NoopScope,
+		MaintenanceHostInfoMap: suite.mockMaintenanceMap,
+	}
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos(gomock.Any()).
+		Return([]*hpb.HostInfo{}).
+		AnyTimes()
+	suite.mockMasterOperatorClient.EXPECT().Agents().Return(response, nil)
+	loader.Load(nil)
+}
+
+// TestResolveAgentIDs tests resolving agent ids to the hostnames of the
+// registered agents and of the DOWN hosts
+func (suite *HostSvcHandlerTestSuite) TestResolveAgentIDs() {
+	suite.loadAgentsWithIDs()
+	downHostInfos := []*hpb.HostInfo{
+		{
+			Hostname: "host3",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+			AgentId:  "agent-old",
+		},
+		{
+			// The hostname was reused by the registered agent, whose
+			// agent id takes precedence
+			Hostname: "host4",
+			State:    hpb.HostState_HOST_STATE_DOWN,
+			AgentId:  "agent-host1",
+		},
+	}
+
+	hostnames, mappings, err := resolveAgentIDs(
+		[]string{"agent-host1", " agent-old "},
+		downHostInfos)
+	suite.NoError(err)
+	suite.Equal([]string{"host1", "host3"}, hostnames)
+	suite.Equal([]*hpb.HostnameMapping{
+		{Requested: "agent-host1", Hostname: "host1"},
+		{Requested: " agent-old ", Hostname: "host3"},
+	}, mappings)
+
+	_, _, err = resolveAgentIDs([]string{"agent-unknown"}, downHostInfos)
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	_, _, err = resolveAgentIDs([]string{" "}, downHostInfos)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestResolveHosts tests resolving the hostnames and the agent ids of a
+// request together, without duplicate hosts
+func (suite *HostSvcHandlerTestSuite) TestResolveHosts() {
+	suite.loadAgentsWithIDs()
+
+	hostnames, mappings, err := suite.handler.resolveHosts(
+		[]string{"HOST1"},
+		[]string{"agent-host1"},
+		nil)
+	suite.NoError(err)
+	suite.Equal([]string{"host1"}, hostnames)
+	suite.Equal([]*hpb.HostnameMapping{
+		{Requested: "HOST1", Hostname: "host1"},
+		{Requested: "agent-host1", Hostname: "host1"},
+	}, mappings)
+
+	_, _, err = suite.handler.resolveHosts(
+		[]string{"host1"},
+		[]string{"agent-unknown"},
+		nil)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FakesTestSuite struct {
+	suite.Suite
+}
+
+func TestFakes(t *testing.T) {
+	suite.Run(t, new(FakesTestSuite))
+}
+
+// TestMaintenanceQueue tests that the fake queue records its calls,
+// fails as scripted and otherwise behaves as the maintenance queue
+func (suite *FakesTestSuite) TestMaintenanceQueue() {
+	q := NewMaintenanceQueue(0)
+	q.FailNext("Enqueue", errors.New("queue is full"))
+
+	_, err := q.Enqueue([]string{"host1"})
+	suite.Error(err)
+	suite.Equal(0, q.Length())
+
+	_, err = q.Enqueue([]string{"host1", "host2"})
+	suite.NoError(err)
+	suite.Equal([]string{"host1", "host2"}, q.Hosts())
+
+	hostname, err := q.Dequeue(1)
+	suite.NoError(err)
+	suite.Equal("host1", hostname)
+
+	q.AssertNumberOfCalls(suite.T(), "Enqueue", 2)
+	q.AssertCalled(suite.T(), "Enqueue", []string{"host1", "host2"})
+	q.AssertNumberOfCalls(suite.T(), "Dequeue", 1)
+}
+
+// TestMaintenanceHostInfoMap tests that the fake map records its calls,
+// fails as scripted and otherwise behaves as the map of the hosts in
+// maintenance
+func (suite *FakesTestSuite) TestMaintenanceHostInfoMap() {
+	m := NewMaintenanceHostInfoMap(&hpb.HostInfo{
+		Hostname: "host1",
+		Ip:       "0.0.0.0",
+		State:    hpb.HostState_HOST_STATE_DRAINING,
+	})
+	m.AssertHostState(suite.T(), "host1", hpb.HostState_HOST_STATE_DRAINING)
+	m.AssertHostState(suite.T(), "host2", hpb.HostState_HOST_STATE_UP)
+
+	m.FailNext("UpdateHostState", errors.New("update failed"))
+	suite.Error(m.UpdateHostState(
+		"host1",
+		hpb.HostState_HOST_STATE_DRAINING,
+		hpb.HostState_HOST_STATE_DOWN))
+	m.AssertHostState(suite.T(), "host1", hpb.HostState_HOST_STATE_DRAINING)
+
+	suite.NoError(m.UpdateHostState(
+		"host1",
+		hpb.HostState_HOST_STATE_DRAINING,
+		hpb.HostState_HOST_STATE_DOWN))
+	m.AssertHostState(suite.T(), "host1", hpb.HostState_HOST_STATE_DOWN)
+	suite.Len(m.GetDownHostInfos([]string{}), 1)
+
+	m.AssertNumberOfCalls(suite.T(), "UpdateHostState", 2)
+	m.AssertCalled(suite.T(), "GetDownHostInfos", []string{})
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// MaintenanceHostInfoMap is a fake host.MaintenanceHostInfoMap. It
+// behaves as the map of the hosts in maintenance of host manager, and
+// records the calls of its methods by method name. UpdateHostState can
+// be scripted to fail with FailNext.
+type MaintenanceHostInfoMap struct {
+	Recorder
+
+	hostInfoMap host.MaintenanceHostInfoMap
+}
+
+var _ host.MaintenanceHostInfoMap = (*MaintenanceHostInfoMap)(nil)
+
+// NewMaintenanceHostInfoMap returns a fake map of the hosts in
+// maintenance with the given hosts.
+func NewMaintenanceHostInfoMap(
+	hostInfos ...*hpb.HostInfo) *MaintenanceHostInfoMap {
+	m := &MaintenanceHostInfoMap{
+		hostInfoMap: host.NewMaintenanceHostInfoMap(tally.NoopScope),
+	}
+	m.hostInfoMap.AddHostInfos(hostInfos)
+	return m
+}
+
+// GetDrainingHostInfos implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) GetDrainingHostInfos(
+	hostFilter []string) []*hpb.HostInfo {
+	m.record("GetDrainingHostInfos", hostFilter)
+	return m.hostInfoMap.GetDrainingHostInfos(hostFilter)
+}
+
+// GetDownHostInfos implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) GetDownHostInfos(
+	hostFilter []string) []*hpb.HostInfo {
+	m.record("GetDownHostInfos", hostFilter)
+	return m.hostInfoMap.GetDownHostInfos(hostFilter)
+}
+
+// AddHostInfos implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) AddHostInfos(hostInfos []*hpb.HostInfo) {
+	m.record("AddHostInfos", hostInfos)
+	m.hostInfoMap.AddHostInfos(hostInfos)
+}
+
+// RemoveHostInfos implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) RemoveHostInfos(hosts []string) {
+	m.record("RemoveHostInfos", hosts)
+	m.hostInfoMap.RemoveHostInfos(hosts)
+}
+
+// UpdateHostState implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) UpdateHostState(
+	hostname string,
+	from hpb.HostState,
+	to hpb.HostState) error {
+	if err := m.record("UpdateHostState", hostname, from, to); err != nil {
+		return err
+	}
+	return m.hostInfoMap.UpdateHostState(hostname, from, to)
+}
+
+// ClearAndFillMap implements host.MaintenanceHostInfoMap.
+func (m *MaintenanceHostInfoMap) ClearAndFillMap(hostInfos []*hpb.HostInfo) {
+	m.record("ClearAndFillMap", hostInfos)
+	m.hostInfoMap.ClearAndFillMap(hostInfos)
+}
+
+// GetHostState returns the maintenance state of the host in the map, or
+// HOST_STATE_UP if the host is not in maintenance. The call is not
+// recorded.
+func (m *MaintenanceHostInfoMap) GetHostState(hostname string) hpb.HostState {
+	for _, hostInfos := range [][]*hpb.HostInfo{
+		m.hostInfoMap.GetDrainingHostInfos([]string{hostname}),
+		m.hostInfoMap.GetDownHostInfos([]string{hostname}),
+	} {
+		if len(hostInfos) > 0 {
+			return hostInfos[0].GetState()
+		}
+	}
+	return hpb.HostState_HOST_STATE_UP
+}
+
+// AssertHostState asserts the maintenance state of the host in the map.
+func (m *MaintenanceHostInfoMap) AssertHostState(
+	t assert.TestingT,
+	hostname string,
+	state hpb.HostState) bool {
+	return assert.Equal(t, state.String(), m.GetHostState(hostname).String(),
+		"state of host %s", hostname)
+}
@@ -0,0 +1,429 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MasterOperatorClient is a fake mpb.MasterOperatorClient simulating the
+// agents and the maintenance primitives of Mesos Master, and recording
+// the calls of its methods by method name. Machines are identified by
+// their hostname. Every method returning an error can be scripted to
+// fail with FailNext. The resources of the agents are not simulated,
+// their methods only record their calls.
+type MasterOperatorClient struct {
+	Recorder
+
+	lock sync.Mutex
+	// registered agents, by hostname
+	agents map[string]*mesos_master.Response_GetAgents_Agent
+	// hostnames of the agents, in registration order
+	hostnames []string
+	// maintenance schedule, whose machines are DRAINING unless DOWN
+	schedule *mesos_maintenance.Schedule
+	// DOWN machines, by hostname
+	down         map[string]*mesos.MachineID
+	capabilities []mesos.MasterInfo_Capability_Type
+}
+
+var _ mpb.MasterOperatorClient = (*MasterOperatorClient)(nil)
+
+// NewMasterOperatorClient returns a fake Mesos Master without agents nor
+// maintenance schedule.
+func NewMasterOperatorClient() *MasterOperatorClient {
+	return &MasterOperatorClient{
+		agents:   make(map[string]*mesos_master.Response_GetAgents_Agent),
+		schedule: &mesos_maintenance.Schedule{},
+		down:     make(map[string]*mesos.MachineID),
+	}
+}
+
+// AddAgent registers an active agent on the host with the given IP, and
+// returns the id of the agent.
+func (c *MasterOperatorClient) AddAgent(hostname, ip string) *mesos.AgentID {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	id := fmt.Sprintf("agent-%s", hostname)
+	pid := fmt.Sprintf("slave(1)@%s:5051", ip)
+	version := "1.9.0"
+	active := true
+	if _, ok := c.agents[hostname]; !ok {
+		c.hostnames = append(c.hostnames, hostname)
+	}
+	c.agents[hostname] = &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{
+			Hostname: &hostname,
+			Id:       &mesos.AgentID{Value: &id},
+		},
+		Active:  &active,
+		Version: &version,
+		Pid:     &pid,
+	}
+	return &mesos.AgentID{Value: &id}
+}
+
+// SetCapabilities sets the capabilities of Mesos Master reported by
+// GetMaster, e.g. AGENT_DRAINING.
+func (c *MasterOperatorClient) SetCapabilities(
+	capabilities ...mesos.MasterInfo_Capability_Type) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.capabilities = capabilities
+}
+
+// MarkAgentDrained marks the agent of the host, drained by DrainAgent,
+// as DRAINED, as Mesos Master does once its tasks are terminated.
+func (c *MasterOperatorClient) MarkAgentDrained(hostname string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	agent, ok := c.agents[hostname]
+	if !ok || agent.GetDrainInfo() == nil {
+		return fmt.Errorf("agent of host %s is not draining", hostname)
+	}
+	state := mesos.DrainState_DRAINED
+	agent.DrainInfo.State = &state
+	return nil
+}
+
+// Agents implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) Agents() (
+	*mesos_master.Response_GetAgents, error) {
+	if err := c.record("Agents"); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	response := &mesos_master.Response_GetAgents{}
+	for _, hostname := range c.hostnames {
+		response.Agents = append(response.Agents,
+			proto.Clone(c.agents[hostname]).(*mesos_master.Response_GetAgents_Agent))
+	}
+	return response, nil
+}
+
+// GetTasksAllocation implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) GetTasksAllocation(
+	ID string) ([]*mesos.Resource, []*mesos.Resource, error) {
+	return nil, nil, c.record("GetTasksAllocation", ID)
+}
+
+// AllocatedResources implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) AllocatedResources(
+	ID string) ([]*mesos.Resource, error) {
+	return nil, c.record("AllocatedResources", ID)
+}
+
+// GetMaintenanceSchedule implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) GetMaintenanceSchedule() (
+	*mesos_master.Response_GetMaintenanceSchedule, error) {
+	if err := c.record("GetMaintenanceSchedule"); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return &mesos_master.Response_GetMaintenanceSchedule{
+		Schedule: proto.Clone(c.schedule).(*mesos_maintenance.Schedule),
+	}, nil
+}
+
+// GetMaintenanceStatus implements mpb.MasterOperatorClient. The machines
+// of the maintenance schedule are DRAINING unless they are DOWN.
+func (c *MasterOperatorClient) GetMaintenanceStatus() (
+	*mesos_master.Response_GetMaintenanceStatus, error) {
+	if err := c.record("GetMaintenanceStatus"); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	status := &mesos_maintenance.ClusterStatus{}
+	for _, window := range c.schedule.GetWindows() {
+		for _, machine := range window.GetMachineIds() {
+			if down, ok := c.down[machine.GetHostname()]; ok {
+				status.DownMachines = append(status.DownMachines,
+					proto.Clone(down).(*mesos.MachineID))
+				continue
+			}
+			status.DrainingMachines = append(status.DrainingMachines,
+				&mesos_maintenance.ClusterStatus_DrainingMachine{
+					Id: proto.Clone(machine).(*mesos.MachineID),
+				})
+		}
+	}
+	return &mesos_master.Response_GetMaintenanceStatus{Status: status}, nil
+}
+
+// StartMaintenance implements mpb.MasterOperatorClient. The machines
+// must be in the maintenance schedule.
+func (c *MasterOperatorClient) StartMaintenance(
+	ctx context.Context,
+	machines []*mesos.MachineID) error {
+	if err := c.record("StartMaintenance", machines); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, machine := range machines {
+		if !c.isScheduled(machine.GetHostname()) {
+			return fmt.Errorf(
+				"machine %s is not in the maintenance schedule",
+				machine.GetHostname())
+		}
+	}
+	for _, machine := range machines {
+		c.down[machine.GetHostname()] = proto.Clone(machine).(*mesos.MachineID)
+	}
+	return nil
+}
+
+// StopMaintenance implements mpb.MasterOperatorClient. The machines must
+// be DOWN, and are removed from the maintenance schedule.
+func (c *MasterOperatorClient) StopMaintenance(
+	ctx context.Context,
+	machines []*mesos.MachineID) error {
+	if err := c.record("StopMaintenance", machines); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, machine := range machines {
+		if _, ok := c.down[machine.GetHostname()]; !ok {
+			return fmt.Errorf("machine %s is not DOWN", machine.GetHostname())
+		}
+	}
+
+	up := make(map[string]bool)
+	for _, machine := range machines {
+		delete(c.down, machine.GetHostname())
+		up[machine.GetHostname()] = true
+	}
+	var windows []*mesos_maintenance.Window
+	for _, window := range c.schedule.GetWindows() {
+		var machineIDs []*mesos.MachineID
+		for _, machine := range window.GetMachineIds() {
+			if !up[machine.GetHostname()] {
+				machineIDs = append(machineIDs, machine)
+			}
+		}
+		if len(machineIDs) > 0 {
+			window.MachineIds = machineIDs
+			windows = append(windows, window)
+		}
+	}
+	c.schedule.Windows = windows
+	return nil
+}
+
+// GetQuota implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) GetQuota(role string) ([]*mesos.Resource, error) {
+	return nil, c.record("GetQuota", role)
+}
+
+// UpdateMaintenanceSchedule implements mpb.MasterOperatorClient. DOWN
+// machines cannot be removed from the schedule.
+func (c *MasterOperatorClient) UpdateMaintenanceSchedule(
+	ctx context.Context,
+	schedule *mesos_maintenance.Schedule) error {
+	if err := c.record("UpdateMaintenanceSchedule", schedule); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	scheduled := make(map[string]bool)
+	for _, window := range schedule.GetWindows() {
+		for _, machine := range window.GetMachineIds() {
+			scheduled[machine.GetHostname()] = true
+		}
+	}
+	for hostname := range c.down {
+		if !scheduled[hostname] {
+			return fmt.Errorf(
+				"DOWN machine %s cannot be removed from the schedule",
+				hostname)
+		}
+	}
+	c.schedule = proto.Clone(schedule).(*mesos_maintenance.Schedule)
+	return nil
+}
+
+// ReserveResources implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) ReserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	return c.record("ReserveResources", agentID, resources)
+}
+
+// UnreserveResources implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) UnreserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	return c.record("UnreserveResources", agentID, resources)
+}
+
+// CreateVolumes implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) CreateVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	return c.record("CreateVolumes", agentID, volumes)
+}
+
+// DestroyVolumes implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) DestroyVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	return c.record("DestroyVolumes", agentID, volumes)
+}
+
+// GetMaster implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) GetMaster() (
+	*mesos_master.Response_GetMaster, error) {
+	if err := c.record("GetMaster"); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	masterInfo := &mesos.MasterInfo{}
+	for _, capability := range c.capabilities {
+		capability := capability
+		masterInfo.Capabilities = append(masterInfo.Capabilities,
+			&mesos.MasterInfo_Capability{Type: &capability})
+	}
+	return &mesos_master.Response_GetMaster{MasterInfo: masterInfo}, nil
+}
+
+// DrainAgent implements mpb.MasterOperatorClient. The agent is
+// deactivated and DRAINING until it is marked drained with
+// MarkAgentDrained.
+func (c *MasterOperatorClient) DrainAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID,
+	maxGracePeriod time.Duration) error {
+	if err := c.record("DrainAgent", agentID, maxGracePeriod); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	agent, err := c.getAgent(agentID)
+	if err != nil {
+		return err
+	}
+	state := mesos.DrainState_DRAINING
+	config := &mesos.DrainConfig{}
+	if maxGracePeriod > 0 {
+		nanos := maxGracePeriod.Nanoseconds()
+		config.MaxGracePeriod = &mesos.DurationInfo{Nanoseconds: &nanos}
+	}
+	deactivated := true
+	agent.Deactivated = &deactivated
+	agent.DrainInfo = &mesos.DrainInfo{State: &state, Config: config}
+	return nil
+}
+
+// DeactivateAgent implements mpb.MasterOperatorClient.
+func (c *MasterOperatorClient) DeactivateAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID) error {
+	if err := c.record("DeactivateAgent", agentID); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	agent, err := c.getAgent(agentID)
+	if err != nil {
+		return err
+	}
+	deactivated := true
+	agent.Deactivated = &deactivated
+	return nil
+}
+
+// ReactivateAgent implements mpb.MasterOperatorClient. The drain of the
+// agent, if any, is cleared.
+func (c *MasterOperatorClient) ReactivateAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID) error {
+	if err := c.record("ReactivateAgent", agentID); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	agent, err := c.getAgent(agentID)
+	if err != nil {
+		return err
+	}
+	deactivated := false
+	agent.Deactivated = &deactivated
+	agent.DrainInfo = nil
+	return nil
+}
+
+// isScheduled returns whether the machine of the host is in the
+// maintenance schedule. It must be called with the lock held.
+func (c *MasterOperatorClient) isScheduled(hostname string) bool {
+	for _, window := range c.schedule.GetWindows() {
+		for _, machine := range window.GetMachineIds() {
+			if machine.GetHostname() == hostname {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getAgent returns the registered agent with the id. It must be called
+// with the lock held.
+func (c *MasterOperatorClient) getAgent(
+	agentID *mesos.AgentID) (*mesos_master.Response_GetAgents_Agent, error) {
+	for _, agent := range c.agents {
+		if agent.GetAgentInfo().GetId().GetValue() == agentID.GetValue() {
+			return agent, nil
+		}
+	}
+	return nil, fmt.Errorf("agent %s is not registered", agentID.GetValue())
+}
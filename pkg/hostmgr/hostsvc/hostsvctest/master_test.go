@@ -0,0 +1,181 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MasterOperatorClientTestSuite struct {
+	suite.Suite
+	client   *MasterOperatorClient
+	machines []*mesos.MachineID
+}
+
+func (suite *MasterOperatorClientTestSuite) SetupTest() {
+	suite.client = NewMasterOperatorClient()
+	suite.machines = nil
+	for _, h := range []struct{ hostname, ip string }{
+		{"host1", "172.17.0.5"},
+		{"host2", "172.17.0.6"},
+	} {
+		hostname, ip := h.hostname, h.ip
+		suite.client.AddAgent(hostname, ip)
+		suite.machines = append(suite.machines, &mesos.MachineID{
+			Hostname: &hostname,
+			Ip:       &ip,
+		})
+	}
+}
+
+func TestMasterOperatorClient(t *testing.T) {
+	suite.Run(t, new(MasterOperatorClientTestSuite))
+}
+
+// schedule schedules the maintenance of the machines
+func (suite *MasterOperatorClientTestSuite) schedule(
+	machines ...*mesos.MachineID) {
+	suite.NoError(suite.client.UpdateMaintenanceSchedule(
+		context.Background(),
+		&mesos_maintenance.Schedule{
+			Windows: []*mesos_maintenance.Window{
+				{MachineIds: machines},
+			},
+		}))
+}
+
+// TestAgents tests that the registered agents are returned in order
+func (suite *MasterOperatorClientTestSuite) TestAgents() {
+	response, err := suite.client.Agents()
+	suite.NoError(err)
+	suite.Len(response.GetAgents(), 2)
+	suite.Equal("host1", response.GetAgents()[0].GetAgentInfo().GetHostname())
+	suite.Equal("slave(1)@172.17.0.5:5051", response.GetAgents()[0].GetPid())
+	suite.True(response.GetAgents()[0].GetActive())
+	suite.Equal("host2", response.GetAgents()[1].GetAgentInfo().GetHostname())
+	suite.client.AssertNumberOfCalls(suite.T(), "Agents", 1)
+}
+
+// TestMaintenance tests the maintenance of a machine from its schedule
+// to its end
+func (suite *MasterOperatorClientTestSuite) TestMaintenance() {
+	suite.schedule(suite.machines...)
+
+	status, err := suite.client.GetMaintenanceStatus()
+	suite.NoError(err)
+	suite.Len(status.GetStatus().GetDrainingMachines(), 2)
+	suite.Empty(status.GetStatus().GetDownMachines())
+
+	suite.NoError(suite.client.StartMaintenance(
+		context.Background(), suite.machines[:1]))
+	status, err = suite.client.GetMaintenanceStatus()
+	suite.NoError(err)
+	suite.Len(status.GetStatus().GetDrainingMachines(), 1)
+	suite.Equal(
+		"host1",
+		status.GetStatus().GetDownMachines()[0].GetHostname())
+
+	suite.NoError(suite.client.StopMaintenance(
+		context.Background(), suite.machines[:1]))
+	schedule, err := suite.client.GetMaintenanceSchedule()
+	suite.NoError(err)
+	suite.Len(schedule.GetSchedule().GetWindows(), 1)
+	suite.Equal(
+		suite.machines[1:],
+		schedule.GetSchedule().GetWindows()[0].GetMachineIds())
+}
+
+// TestMaintenanceErrors tests that the maintenance primitives are
+// rejected as by Mesos Master
+func (suite *MasterOperatorClientTestSuite) TestMaintenanceErrors() {
+	// Machine not scheduled
+	suite.Error(suite.client.StartMaintenance(
+		context.Background(), suite.machines[:1]))
+
+	// Machine not DOWN
+	suite.schedule(suite.machines...)
+	suite.Error(suite.client.StopMaintenance(
+		context.Background(), suite.machines[:1]))
+
+	// DOWN machine removed from the schedule
+	suite.NoError(suite.client.StartMaintenance(
+		context.Background(), suite.machines[:1]))
+	suite.Error(suite.client.UpdateMaintenanceSchedule(
+		context.Background(),
+		&mesos_maintenance.Schedule{}))
+}
+
+// TestFailNext tests that scripted errors are returned without applying
+// the call
+func (suite *MasterOperatorClientTestSuite) TestFailNext() {
+	suite.schedule(suite.machines...)
+	suite.client.FailNext("StartMaintenance", errors.New("master unavailable"))
+
+	suite.Error(suite.client.StartMaintenance(
+		context.Background(), suite.machines[:1]))
+	status, err := suite.client.GetMaintenanceStatus()
+	suite.NoError(err)
+	suite.Empty(status.GetStatus().GetDownMachines())
+	suite.client.AssertCalled(
+		suite.T(), "StartMaintenance", suite.machines[:1])
+}
+
+// TestDrainAgent tests the drain and the reactivation of an agent
+func (suite *MasterOperatorClientTestSuite) TestDrainAgent() {
+	suite.client.SetCapabilities(mesos.MasterInfo_Capability_AGENT_DRAINING)
+	master, err := suite.client.GetMaster()
+	suite.NoError(err)
+	suite.Equal(
+		mesos.MasterInfo_Capability_AGENT_DRAINING,
+		master.GetMasterInfo().GetCapabilities()[0].GetType())
+
+	agentID := suite.client.AddAgent("host3", "172.17.0.7")
+	suite.NoError(suite.client.DrainAgent(
+		context.Background(), agentID, time.Minute))
+	response, err := suite.client.Agents()
+	suite.NoError(err)
+	agent := response.GetAgents()[2]
+	suite.True(agent.GetDeactivated())
+	suite.Equal(mesos.DrainState_DRAINING, agent.GetDrainInfo().GetState())
+	suite.Equal(
+		time.Minute.Nanoseconds(),
+		agent.GetDrainInfo().GetConfig().GetMaxGracePeriod().GetNanoseconds())
+
+	suite.NoError(suite.client.MarkAgentDrained("host3"))
+	response, err = suite.client.Agents()
+	suite.NoError(err)
+	suite.Equal(
+		mesos.DrainState_DRAINED,
+		response.GetAgents()[2].GetDrainInfo().GetState())
+
+	suite.NoError(suite.client.ReactivateAgent(context.Background(), agentID))
+	response, err = suite.client.Agents()
+	suite.NoError(err)
+	suite.False(response.GetAgents()[2].GetDeactivated())
+	suite.Nil(response.GetAgents()[2].GetDrainInfo())
+
+	unknown := "unknown"
+	suite.Error(suite.client.DrainAgent(
+		context.Background(), &mesos.AgentID{Value: &unknown}, 0))
+	suite.Error(suite.client.MarkAgentDrained("host1"))
+}
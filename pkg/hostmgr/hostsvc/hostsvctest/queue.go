@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	"time"
+
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+)
+
+// MaintenanceQueue is a fake queue.MaintenanceQueue. It behaves as the
+// maintenance queue of host manager, and records the calls of its
+// methods by method name. Enqueue, Dequeue and Redrive can be scripted
+// to fail with FailNext.
+type MaintenanceQueue struct {
+	Recorder
+
+	queue queue.MaintenanceQueue
+}
+
+var _ queue.MaintenanceQueue = (*MaintenanceQueue)(nil)
+
+// NewMaintenanceQueue returns a fake maintenance queue whose hosts are
+// dead-lettered once dequeued maxAttempts times without being marked
+// processed. A maxAttempts of 0 disables the dead-letter queue.
+func NewMaintenanceQueue(maxAttempts int) *MaintenanceQueue {
+	return &MaintenanceQueue{
+		queue: queue.NewMaintenanceQueue(maxAttempts),
+	}
+}
+
+// Enqueue implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Enqueue(hostnames []string) ([]string, error) {
+	if err := q.record("Enqueue", hostnames); err != nil {
+		return nil, err
+	}
+	return q.queue.Enqueue(hostnames)
+}
+
+// Hosts implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Hosts() []string {
+	q.record("Hosts")
+	return q.queue.Hosts()
+}
+
+// Dequeue implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Dequeue(maxWaitTime time.Duration) (string, error) {
+	if err := q.record("Dequeue", maxWaitTime); err != nil {
+		return "", err
+	}
+	return q.queue.Dequeue(maxWaitTime)
+}
+
+// Length implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Length() int {
+	q.record("Length")
+	return q.queue.Length()
+}
+
+// Clear implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Clear() {
+	q.record("Clear")
+	q.queue.Clear()
+}
+
+// MarkProcessed implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) MarkProcessed(hostnames []string) {
+	q.record("MarkProcessed", hostnames)
+	q.queue.MarkProcessed(hostnames)
+}
+
+// DeadLetters implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) DeadLetters() []string {
+	q.record("DeadLetters")
+	return q.queue.DeadLetters()
+}
+
+// Redrive implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Redrive(hostnames []string) error {
+	if err := q.record("Redrive", hostnames); err != nil {
+		return err
+	}
+	return q.queue.Redrive(hostnames)
+}
+
+// SetMaxAttempts implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) SetMaxAttempts(maxAttempts int) {
+	q.record("SetMaxAttempts", maxAttempts)
+	q.queue.SetMaxAttempts(maxAttempts)
+}
+
+// Defer implements queue.MaintenanceQueue.
+func (q *MaintenanceQueue) Defer(hostnames []string, until time.Time) error {
+	q.record("Defer", hostnames, until)
+	return q.queue.Defer(hostnames, until)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hostsvctest provides fakes of the dependencies of the host
+// service handler, to test maintenance flows end to end without
+// generating mocks. The fakes behave as the components of host manager
+// or as Mesos Master, record their calls, and can be scripted to fail.
+package hostsvctest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Recorder records the calls of the methods of a fake, and scripts the
+// errors and side effects of their next calls. It is safe for concurrent
+// use.
+type Recorder struct {
+	lock  sync.Mutex
+	calls map[string][][]interface{}
+	errs  map[string][]error
+	hooks map[string]func(args ...interface{})
+}
+
+// FailNext makes the next calls of the method return the errors, one
+// error per call, instead of being applied to the fake. Errors scripted
+// for methods which do not return errors are ignored.
+func (r *Recorder) FailNext(method string, errs ...error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.errs == nil {
+		r.errs = make(map[string][]error)
+	}
+	r.errs[method] = append(r.errs[method], errs...)
+}
+
+// OnCall runs f with the arguments of every following call of the
+// method, before the call is applied to the fake. A nil f removes the
+// hook of the method.
+func (r *Recorder) OnCall(method string, f func(args ...interface{})) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.hooks == nil {
+		r.hooks = make(map[string]func(args ...interface{}))
+	}
+	if f == nil {
+		delete(r.hooks, method)
+		return
+	}
+	r.hooks[method] = f
+}
+
+// Calls returns the arguments of the calls of the method, in order.
+func (r *Recorder) Calls(method string) [][]interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([][]interface{}{}, r.calls[method]...)
+}
+
+// Reset forgets the calls of all methods, and their scripted errors and
+// hooks.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.calls = nil
+	r.errs = nil
+	r.hooks = nil
+}
+
+// AssertCalled asserts that the method was called at least once with
+// the given arguments.
+func (r *Recorder) AssertCalled(
+	t assert.TestingT,
+	method string,
+	args ...interface{}) bool {
+	calls := r.Calls(method)
+	for _, call := range calls {
+		if assert.ObjectsAreEqual(args, call) {
+			return true
+		}
+	}
+	return assert.Fail(t,
+		fmt.Sprintf("%s was not called with %v", method, args),
+		"calls: %v", calls)
+}
+
+// AssertNotCalled asserts that the method was not called.
+func (r *Recorder) AssertNotCalled(t assert.TestingT, method string) bool {
+	return assert.Empty(t, r.Calls(method),
+		fmt.Sprintf("%s was called", method))
+}
+
+// AssertNumberOfCalls asserts that the method was called n times.
+func (r *Recorder) AssertNumberOfCalls(
+	t assert.TestingT,
+	method string,
+	n int) bool {
+	return assert.Len(t, r.Calls(method), n,
+		fmt.Sprintf("number of calls of %s", method))
+}
+
+// record records a call of the method, runs its hook, and returns the
+// error scripted for the call, if any.
+func (r *Recorder) record(method string, args ...interface{}) error {
+	r.lock.Lock()
+	if r.calls == nil {
+		r.calls = make(map[string][][]interface{})
+	}
+	r.calls[method] = append(r.calls[method], args)
+	var err error
+	if errs := r.errs[method]; len(errs) > 0 {
+		err, r.errs[method] = errs[0], errs[1:]
+	}
+	hook := r.hooks[method]
+	r.lock.Unlock()
+
+	// The hook runs without the lock, so that it can script the fake
+	if hook != nil {
+		hook(args...)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvctest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RecorderTestSuite struct {
+	suite.Suite
+	recorder *Recorder
+}
+
+func (suite *RecorderTestSuite) SetupTest() {
+	suite.recorder = &Recorder{}
+}
+
+func TestRecorder(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
+
+// TestRecord tests that the calls are recorded in order by method
+func (suite *RecorderTestSuite) TestRecord() {
+	suite.NoError(suite.recorder.record("Enqueue", []string{"host1"}))
+	suite.NoError(suite.recorder.record("Enqueue", []string{"host2"}))
+	suite.NoError(suite.recorder.record("Length"))
+
+	suite.Equal([][]interface{}{
+		{[]string{"host1"}},
+		{[]string{"host2"}},
+	}, suite.recorder.Calls("Enqueue"))
+	suite.recorder.AssertCalled(suite.T(), "Enqueue", []string{"host2"})
+	suite.recorder.AssertNumberOfCalls(suite.T(), "Enqueue", 2)
+	suite.recorder.AssertNumberOfCalls(suite.T(), "Length", 1)
+	suite.recorder.AssertNotCalled(suite.T(), "Dequeue")
+}
+
+// TestFailNext tests that the scripted errors are returned one per call
+func (suite *RecorderTestSuite) TestFailNext() {
+	err1 := errors.New("error 1")
+	err2 := errors.New("error 2")
+	suite.recorder.FailNext("Enqueue", err1, err2)
+
+	suite.Equal(err1, suite.recorder.record("Enqueue"))
+	suite.NoError(suite.recorder.record("Dequeue"))
+	suite.Equal(err2, suite.recorder.record("Enqueue"))
+	suite.NoError(suite.recorder.record("Enqueue"))
+	suite.recorder.AssertNumberOfCalls(suite.T(), "Enqueue", 3)
+}
+
+// TestOnCall tests that the hook of a method runs with the arguments of
+// its calls until it is removed
+func (suite *RecorderTestSuite) TestOnCall() {
+	var hostnames []string
+	suite.recorder.OnCall("Enqueue", func(args ...interface{}) {
+		hostnames = append(hostnames, args[0].([]string)...)
+	})
+
+	suite.recorder.record("Enqueue", []string{"host1"})
+	suite.recorder.record("Enqueue", []string{"host2"})
+	suite.recorder.OnCall("Enqueue", nil)
+	suite.recorder.record("Enqueue", []string{"host3"})
+
+	suite.Equal([]string{"host1", "host2"}, hostnames)
+}
+
+// TestReset tests that Reset forgets the calls, errors and hooks
+func (suite *RecorderTestSuite) TestReset() {
+	called := false
+	suite.recorder.FailNext("Enqueue", errors.New("error"))
+	suite.recorder.OnCall("Enqueue", func(args ...interface{}) {
+		called = true
+	})
+	suite.recorder.record("Length")
+
+	suite.recorder.Reset()
+
+	suite.recorder.AssertNotCalled(suite.T(), "Length")
+	suite.NoError(suite.recorder.record("Enqueue"))
+	suite.False(called)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// IdempotencyKeyHeader is the header carrying the idempotency key of
+	// a StartMaintenance or CompleteMaintenance call. The retries of a
+	// call with the same key get the response of the first successful
+	// call, instead of applying the request again.
+	IdempotencyKeyHeader = "idempotency-key"
+
+	// _maxIdempotencyKeyLength is the max length of an idempotency key
+	_maxIdempotencyKeyLength = 256
+)
+
+// idempotencyKeys records the responses of the calls made with an
+// idempotency key, to replay them to the retries of the calls. The
+// responses are stored with a TTL, so keys are forgotten after a while.
+type idempotencyKeys struct {
+	sync.Mutex
+
+	ops ormobjects.IdempotencyKeyOps
+	// keys of the calls in progress, by procedure and key
+	inFlight map[string]bool
+}
+
+// newIdempotencyKeys returns the idempotency keys stored with the ops.
+func newIdempotencyKeys(ops ormobjects.IdempotencyKeyOps) *idempotencyKeys {
+	return &idempotencyKeys{
+		ops:      ops,
+		inFlight: make(map[string]bool),
+	}
+}
+
+// call returns the response of fn for a request of the procedure. If the
+// call has an idempotency key which a previous call of the procedure
+// succeeded with, the response of the previous call is unmarshaled into
+// replay and returned instead, without calling fn, and replayed is true.
+// A key reused with a different request fails with an invalid argument
+// error, and a key of a call in progress with an aborted error. The keys
+// are ignored by a nil idempotencyKeys.
+func (k *idempotencyKeys) call(
+	ctx context.Context,
+	procedure string,
+	request proto.Message,
+	replay proto.Message,
+	fn func() (proto.Message, error),
+) (response proto.Message, replayed bool, err error) {
+	var key string
+	if k != nil {
+		key = yarpc.CallFromContext(ctx).Header(IdempotencyKeyHeader)
+	}
+	if key == "" {
+		response, err = fn()
+		return response, false, err
+	}
+	if len(key) > _maxIdempotencyKeyLength {
+		return nil, false, yarpcerrors.InvalidArgumentErrorf(
+			"idempotency key is longer than %d characters",
+			_maxIdempotencyKeyLength)
+	}
+
+	requestHash, err := hashRequest(request)
+	if err != nil {
+		return nil, false, newInternalError(err, "failed to hash request")
+	}
+
+	if !k.acquire(procedure, key) {
+		return nil, false, yarpcerrors.AbortedErrorf(
+			"a call with idempotency key %q is in progress", key)
+	}
+	defer k.release(procedure, key)
+
+	obj, err := k.ops.Get(ctx, key, procedure)
+	if err != nil {
+		return nil, false, yarpcerrors.UnavailableErrorf(
+			"failed to read idempotency key %q: %v", key, err)
+	}
+	if obj != nil {
+		if obj.RequestHash != requestHash {
+			return nil, false, yarpcerrors.InvalidArgumentErrorf(
+				"idempotency key %q was used by a different request", key)
+		}
+		if err := proto.Unmarshal(obj.Response, replay); err != nil {
+			return nil, false, newInternalError(err,
+				"failed to unmarshal response of idempotency key %q", key)
+		}
+		log.WithFields(log.Fields{
+			"procedure":       procedure,
+			"idempotency_key": key,
+		}).Info("Replayed response of idempotency key")
+		return replay, true, nil
+	}
+
+	response, err = fn()
+	if err != nil {
+		// Failed calls are not recorded, so that they can be retried
+		return nil, false, err
+	}
+	k.record(ctx, procedure, key, requestHash, response)
+	return response, false, nil
+}
+
+// record stores the response of a call made with an idempotency key. A
+// failure is only logged, since the call itself succeeded.
+func (k *idempotencyKeys) record(
+	ctx context.Context,
+	procedure string,
+	key string,
+	requestHash string,
+	response proto.Message) {
+	buffer, err := proto.Marshal(response)
+	if err == nil {
+		err = k.ops.Create(ctx, key, procedure, requestHash, buffer)
+	}
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"procedure":       procedure,
+				"idempotency_key": key,
+			}).Warn("Failed to record response of idempotency key")
+	}
+}
+
+// acquire marks the call of the procedure with the key in progress.
+// Returns false if it already is.
+func (k *idempotencyKeys) acquire(procedure string, key string) bool {
+	k.Lock()
+	defer k.Unlock()
+	if k.inFlight[procedure+"/"+key] {
+		return false
+	}
+	k.inFlight[procedure+"/"+key] = true
+	return true
+}
+
+// release marks the call of the procedure with the key completed.
+func (k *idempotencyKeys) release(procedure string, key string) {
+	k.Lock()
+	defer k.Unlock()
+	delete(k.inFlight, procedure+"/"+key)
+}
+
+// hashRequest returns the fingerprint of a request, to tell whether an
+// idempotency key is reused by a different request.
+func hashRequest(request proto.Message) (string, error) {
+	buffer, err := proto.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buffer)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/yarpc/api/encoding"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// idempotencyContext returns a context of an inbound call with the given
+// idempotency key.
+func (suite *HostSvcHandlerTestSuite) idempotencyContext(key string) context.Context {
+	ctx, call := encoding.NewInboundCall(suite.ctx)
+	suite.NoError(call.ReadFromRequest(&transport.Request{
+		Headers: transport.NewHeaders().With(IdempotencyKeyHeader, key),
+	}))
+	return ctx
+}
+
+// TestIdempotencyKeysCall tests recording the responses of the calls with
+// an idempotency key, and replaying them to the retries of the calls
+func (suite *HostSvcHandlerTestSuite) TestIdempotencyKeysCall() {
+	keys := suite.handler.idempotencyKeys
+	request := &svcpb.StartMaintenanceRequest{Hostnames: []string{"host1"}}
+	response := &svcpb.StartMaintenanceResponse{Queued: true}
+	requestHash, err := hashRequest(request)
+	suite.NoError(err)
+	calls := 0
+	fn := func() (proto.Message, error) {
+		calls++
+		return response, nil
+	}
+
+	// Calls without key are neither looked up nor recorded
+	resp, replayed, err := keys.call(
+		suite.ctx, "StartMaintenance", request, &svcpb.StartMaintenanceResponse{}, fn)
+	suite.NoError(err)
+	suite.False(replayed)
+	suite.Equal(response, resp)
+	suite.Equal(1, calls)
+
+	// The response of the first call with a key is recorded
+	ctx := suite.idempotencyContext("key1")
+	buffer, err := proto.Marshal(response)
+	suite.NoError(err)
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "StartMaintenance").
+		Return(nil, nil)
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Create(gomock.Any(), "key1", "StartMaintenance", requestHash, buffer).
+		Return(nil)
+	resp, replayed, err = keys.call(
+		ctx, "StartMaintenance", request, &svcpb.StartMaintenanceResponse{}, fn)
+	suite.NoError(err)
+	suite.False(replayed)
+	suite.Equal(response, resp)
+	suite.Equal(2, calls)
+
+	// A retry gets the recorded response
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "StartMaintenance").
+		Return(&ormobjects.IdempotencyKeyObject{
+			RequestHash: requestHash,
+			Response:    buffer,
+		}, nil)
+	resp, replayed, err = keys.call(
+		ctx, "StartMaintenance", request, &svcpb.StartMaintenanceResponse{}, fn)
+	suite.NoError(err)
+	suite.True(replayed)
+	suite.True(proto.Equal(response, resp))
+	suite.Equal(2, calls)
+
+	// A different request fails with the key
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "StartMaintenance").
+		Return(&ormobjects.IdempotencyKeyObject{
+			RequestHash: "other",
+			Response:    buffer,
+		}, nil)
+	_, _, err = keys.call(
+		ctx, "StartMaintenance", request, &svcpb.StartMaintenanceResponse{}, fn)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+	suite.Equal(2, calls)
+}
+
+// TestIdempotencyKeysCallFailures tests that failed calls are not
+// recorded, and the failures of the idempotency keys
+func (suite *HostSvcHandlerTestSuite) TestIdempotencyKeysCallFailures() {
+	keys := suite.handler.idempotencyKeys
+	request := &svcpb.CompleteMaintenanceRequest{Hostnames: []string{"host1"}}
+	ctx := suite.idempotencyContext("key1")
+
+	// A failed call is not recorded, so that it can be retried
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "CompleteMaintenance").
+		Return(nil, nil)
+	_, replayed, err := keys.call(
+		ctx, "CompleteMaintenance", request, &svcpb.CompleteMaintenanceResponse{},
+		func() (proto.Message, error) {
+			return nil, yarpcerrors.FailedPreconditionErrorf("fake error")
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+	suite.False(replayed)
+
+	// A failure to read the key fails the call without calling it
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "CompleteMaintenance").
+		Return(nil, errors.New("fake Get error"))
+	_, _, err = keys.call(
+		ctx, "CompleteMaintenance", request, &svcpb.CompleteMaintenanceResponse{},
+		func() (proto.Message, error) {
+			suite.Fail("call with unreadable idempotency key")
+			return nil, nil
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+
+	// A call with a key in progress fails
+	suite.True(keys.acquire("CompleteMaintenance", "key1"))
+	_, _, err = keys.call(
+		ctx, "CompleteMaintenance", request, &svcpb.CompleteMaintenanceResponse{},
+		func() (proto.Message, error) {
+			suite.Fail("call with idempotency key in progress")
+			return nil, nil
+		})
+	suite.True(yarpcerrors.IsAborted(err))
+	keys.release("CompleteMaintenance", "key1")
+
+	// A too long key fails
+	_, _, err = keys.call(
+		suite.idempotencyContext(strings.Repeat("k", _maxIdempotencyKeyLength+1)),
+		"CompleteMaintenance", request, &svcpb.CompleteMaintenanceResponse{},
+		func() (proto.Message, error) {
+			suite.Fail("call with too long idempotency key")
+			return nil, nil
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestStartMaintenanceReplayed tests that a retry of StartMaintenance
+// with an idempotency key gets the original response without starting
+// maintenance again
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceReplayed() {
+	request := &svcpb.StartMaintenanceRequest{Hostnames: []string{"host1"}}
+	requestHash, err := hashRequest(request)
+	suite.NoError(err)
+	response := &svcpb.StartMaintenanceResponse{
+		HostnameMappings: []*hpb.HostnameMapping{
+			{Requested: "host1", Hostname: "host1"},
+		},
+	}
+	buffer, err := proto.Marshal(response)
+	suite.NoError(err)
+
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "StartMaintenance").
+		Return(&ormobjects.IdempotencyKeyObject{
+			RequestHash: requestHash,
+			Response:    buffer,
+		}, nil)
+	resp, err := suite.handler.StartMaintenance(
+		suite.idempotencyContext("key1"), request)
+	suite.NoError(err)
+	suite.True(proto.Equal(response, resp))
+}
+
+// TestCompleteMaintenanceReplayed tests that a retry of
+// CompleteMaintenance with an idempotency key gets the original response
+// without completing maintenance again
+func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceReplayed() {
+	request := &svcpb.CompleteMaintenanceRequest{Hostnames: []string{"host1"}}
+	requestHash, err := hashRequest(request)
+	suite.NoError(err)
+	response := &svcpb.CompleteMaintenanceResponse{
+		CompletedHostnames: []string{"host1"},
+	}
+	buffer, err := proto.Marshal(response)
+	suite.NoError(err)
+
+	suite.mockIdempotencyKeyOps.EXPECT().
+		Get(gomock.Any(), "key1", "CompleteMaintenance").
+		Return(&ormobjects.IdempotencyKeyObject{
+			RequestHash: requestHash,
+			Response:    buffer,
+		}, nil)
+	resp, err := suite.handler.CompleteMaintenance(
+		suite.idempotencyContext("key1"), request)
+	suite.NoError(err)
+	suite.True(proto.Equal(response, resp))
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Separator of the labels of a host in a CSV inventory
+	_inventoryLabelSeparator = ";"
+	// Separator of the key and the value of a label in an inventory
+	_inventoryLabelKeySeparator = "="
+)
+
+// Columns of a CSV inventory
+var _inventoryColumns = []string{"hostname", "ip", "state", "pool", "labels"}
+
+// inventoryEntry is a host of a JSON inventory
+type inventoryEntry struct {
+	Hostname string   `json:"hostname"`
+	IP       string   `json:"ip,omitempty"`
+	State    string   `json:"state,omitempty"`
+	Pool     string   `json:"pool,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// ExportHostInventory returns the hostname, IP, state, host pool and
+// labels of the registered agents and of the hosts in maintenance, as
+// JSON or CSV. The pool and labels of hosts in maintenance are only the
+// assigned ones, as their agents may no longer be registered.
+func (m *serviceHandler) ExportHostInventory(
+	ctx context.Context,
+	request *host_svc.ExportHostInventoryRequest,
+) (*host_svc.ExportHostInventoryResponse, error) {
+	m.metrics.ExportHostInventoryAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ExportHostInventoryFail.Inc(1)
+		return nil, err
+	}
+
+	hosts, err := m.buildHostInfoForRegisteredAgents()
+	if err != nil {
+		m.metrics.ExportHostInventoryFail.Inc(1)
+		return nil, err
+	}
+	if hosts == nil {
+		hosts = make(map[string]*hpb.HostInfo)
+	}
+	// Hosts in maintenance take the place of their registered agents,
+	// which are not removed from the agent map until it is reloaded
+	for _, hostInfos := range [][]*hpb.HostInfo{
+		m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}),
+		m.maintenanceHostInfoMap.GetDownHostInfos([]string{}),
+	} {
+		for _, hostInfo := range hostInfos {
+			assignment := host.GetHostAssignment(hostInfo.GetHostname())
+			hosts[hostInfo.GetHostname()] = &hpb.HostInfo{
+				Hostname: hostInfo.GetHostname(),
+				Ip:       hostInfo.GetIp(),
+				State:    hostInfo.GetState(),
+				Pool:     assignment.GetPool(),
+				Labels:   assignment.GetLabels(),
+			}
+		}
+	}
+
+	hostInfos := make([]*hpb.HostInfo, 0, len(hosts))
+	for _, hostInfo := range hosts {
+		hostInfos = append(hostInfos, hostInfo)
+	}
+	sort.Slice(hostInfos, func(i, j int) bool {
+		return hostInfos[i].GetHostname() < hostInfos[j].GetHostname()
+	})
+
+	content, err := encodeInventory(hostInfos, request.GetFormat())
+	if err != nil {
+		m.metrics.ExportHostInventoryFail.Inc(1)
+		return nil, newInternalError(err, "failed to encode inventory")
+	}
+
+	m.metrics.ExportHostInventorySuccess.Inc(1)
+	return &host_svc.ExportHostInventoryResponse{
+		Content: content,
+	}, nil
+}
+
+// ImportHostInventory assigns the host pools and labels of an inventory
+// in the format of ExportHostInventory to the hosts. Hostnames are
+// resolved as for StartMaintenance, and the request fails without
+// assigning any host if one of them is unknown.
+func (m *serviceHandler) ImportHostInventory(
+	ctx context.Context,
+	request *host_svc.ImportHostInventoryRequest,
+) (*host_svc.ImportHostInventoryResponse, error) {
+	m.metrics.ImportHostInventoryAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, err
+	}
+
+	assignments, err := decodeInventory(
+		request.GetContent(),
+		request.GetFormat())
+	if err != nil {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf("no hosts in inventory")
+	}
+
+	var maintenanceHostInfos []*hpb.HostInfo
+	maintenanceHostInfos = append(maintenanceHostInfos,
+		m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{})...)
+	maintenanceHostInfos = append(maintenanceHostInfos,
+		m.maintenanceHostInfoMap.GetDownHostInfos([]string{})...)
+	known := make(map[string]bool)
+	for _, hostInfo := range maintenanceHostInfos {
+		known[hostInfo.GetHostname()] = true
+	}
+	if agentMap := host.GetAgentMap(); agentMap != nil {
+		for hostname := range agentMap.RegisteredAgents {
+			known[hostname] = true
+		}
+	}
+
+	var hostnames []string
+	for _, assignment := range assignments {
+		hostnames = append(hostnames, assignment.GetHostname())
+	}
+	resolved, mappings, err := m.resolveHostnames(
+		hostnames,
+		maintenanceHostInfos)
+	if err != nil {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, err
+	}
+	if len(resolved) != len(hostnames) {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"hosts are listed more than once")
+	}
+	for i, hostname := range resolved {
+		if !known[hostname] {
+			m.metrics.ImportHostInventoryFail.Inc(1)
+			return nil, newUnknownHostError(hostname)
+		}
+		assignments[i].Hostname = hostname
+	}
+
+	if err := m.assignmentMap.Assign(ctx, assignments); err != nil {
+		m.metrics.ImportHostInventoryFail.Inc(1)
+		return nil, newInternalError(err, "failed to assign hosts")
+	}
+
+	audit.Logger(ctx).WithField("hosts", resolved).
+		Info("Host inventory imported")
+	m.metrics.ImportHostInventorySuccess.Inc(1)
+	return &host_svc.ImportHostInventoryResponse{
+		Hostnames:        resolved,
+		HostnameMappings: mappings,
+	}, nil
+}
+
+// encodeInventory encodes the hosts as an inventory of the given format
+func encodeInventory(
+	hostInfos []*hpb.HostInfo,
+	format hpb.InventoryFormat) ([]byte, error) {
+	entries := make([]*inventoryEntry, 0, len(hostInfos))
+	for _, hostInfo := range hostInfos {
+		entry := &inventoryEntry{
+			Hostname: hostInfo.GetHostname(),
+			IP:       hostInfo.GetIp(),
+			State:    hostInfo.GetState().String(),
+			Pool:     hostInfo.GetPool(),
+		}
+		for _, label := range hostInfo.GetLabels() {
+			entry.Labels = append(entry.Labels,
+				label.GetKey()+_inventoryLabelKeySeparator+label.GetValue())
+		}
+		entries = append(entries, entry)
+	}
+
+	if format != hpb.InventoryFormat_INVENTORY_FORMAT_CSV {
+		return json.MarshalIndent(entries, "", "  ")
+	}
+
+	var buffer bytes.Buffer
+	w := csv.NewWriter(&buffer)
+	if err := w.Write(_inventoryColumns); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := w.Write([]string{
+			entry.Hostname,
+			entry.IP,
+			entry.State,
+			entry.Pool,
+			strings.Join(entry.Labels, _inventoryLabelSeparator),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeInventory decodes the host pools and labels of an inventory of
+// the given format. The other columns are ignored.
+func decodeInventory(
+	content []byte,
+	format hpb.InventoryFormat) ([]*hpb.HostAssignment, error) {
+	var entries []*inventoryEntry
+	if format != hpb.InventoryFormat_INVENTORY_FORMAT_CSV {
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid JSON inventory: %v", err)
+		}
+	} else {
+		var err error
+		if entries, err = decodeCSVInventory(content); err != nil {
+			return nil, err
+		}
+	}
+
+	assignments := make([]*hpb.HostAssignment, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Hostname == "" {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"inventory host without hostname")
+		}
+		assignment := &hpb.HostAssignment{
+			Hostname: entry.Hostname,
+			Pool:     entry.Pool,
+		}
+		for _, label := range entry.Labels {
+			parts := strings.SplitN(label, _inventoryLabelKeySeparator, 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return nil, yarpcerrors.InvalidArgumentErrorf(
+					"invalid label %q of host %s, expected key=value",
+					label, entry.Hostname)
+			}
+			assignment.Labels = append(assignment.Labels, &peloton.Label{
+				Key:   parts[0],
+				Value: parts[1],
+			})
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+// decodeCSVInventory decodes the hosts of a CSV inventory, whose header
+// row names the columns. Only the hostname column is required.
+func decodeCSVInventory(content []byte) ([]*inventoryEntry, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid CSV inventory: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	if _, ok := columns["hostname"]; !ok {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"CSV inventory without hostname column")
+	}
+	value := func(record []string, column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []*inventoryEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid CSV inventory: %v", err)
+		}
+		entry := &inventoryEntry{
+			Hostname: value(record, "hostname"),
+			Pool:     value(record, "pool"),
+		}
+		if labels := value(record, "labels"); labels != "" {
+			entry.Labels = strings.Split(labels, _inventoryLabelSeparator)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// expectMaintenanceHosts sets the expectations of the hosts in
+// maintenance, host3 DRAINING and host2 DOWN
+func (suite *HostSvcHandlerTestSuite) expectMaintenanceHosts() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: suite.drainingMachines[0].GetHostname(),
+				Ip:       suite.drainingMachines[0].GetIp(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+			},
+		})
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: suite.downMachines[0].GetHostname(),
+				Ip:       suite.downMachines[0].GetIp(),
+				State:    hpb.HostState_HOST_STATE_DOWN,
+			},
+		})
+}
+
+// TestExportHostInventory tests exporting the inventory as JSON and CSV
+func (suite *HostSvcHandlerTestSuite) TestExportHostInventory() {
+	suite.expectMaintenanceHosts()
+	resp, err := suite.handler.ExportHostInventory(suite.ctx,
+		&svcpb.ExportHostInventoryRequest{})
+	suite.NoError(err)
+
+	var entries []*inventoryEntry
+	suite.NoError(json.Unmarshal(resp.GetContent(), &entries))
+	suite.Equal([]*inventoryEntry{
+		{
+			Hostname: "host1",
+			IP:       "172.17.0.5",
+			State:    "HOST_STATE_UP",
+			Pool:     "default",
+			Labels:   []string{"zone=dca1"},
+		},
+		{
+			Hostname: "host2",
+			IP:       "172.17.0.6",
+			State:    "HOST_STATE_DOWN",
+		},
+		{
+			Hostname: "host3",
+			IP:       "172.17.0.7",
+			State:    "HOST_STATE_DRAINING",
+		},
+	}, entries)
+
+	suite.expectMaintenanceHosts()
+	resp, err = suite.handler.ExportHostInventory(suite.ctx,
+		&svcpb.ExportHostInventoryRequest{
+			Format: hpb.InventoryFormat_INVENTORY_FORMAT_CSV,
+		})
+	suite.NoError(err)
+	suite.Equal([]string{
+		"hostname,ip,state,pool,labels",
+		"host1,172.17.0.5,HOST_STATE_UP,default,zone=dca1",
+		"host2,172.17.0.6,HOST_STATE_DOWN,,",
+		"host3,172.17.0.7,HOST_STATE_DRAINING,,",
+	}, strings.Split(strings.TrimSpace(string(resp.GetContent())), "\n"))
+}
+
+// TestImportHostInventory tests importing the pools and labels of hosts
+// from JSON and CSV inventories
+func (suite *HostSvcHandlerTestSuite) TestImportHostInventory() {
+	suite.expectMaintenanceHosts()
+	suite.mockAssignmentMap.EXPECT().
+		Assign(gomock.Any(), []*hpb.HostAssignment{
+			{
+				Hostname: "host1",
+				Pool:     "batch",
+				Labels:   []*peloton.Label{{Key: "rack", Value: "r1"}},
+			},
+			{Hostname: "host2"},
+		}).
+		Return(nil)
+	resp, err := suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Content: []byte(`[
+				{"hostname": "172.17.0.5", "pool": "batch", "labels": ["rack=r1"]},
+				{"hostname": "HOST2", "state": "HOST_STATE_DOWN"}
+			]`),
+		})
+	suite.NoError(err)
+	suite.Equal([]string{"host1", "host2"}, resp.GetHostnames())
+	suite.Len(resp.GetHostnameMappings(), 2)
+
+	suite.expectMaintenanceHosts()
+	suite.mockAssignmentMap.EXPECT().
+		Assign(gomock.Any(), []*hpb.HostAssignment{
+			{
+				Hostname: "host3",
+				Labels: []*peloton.Label{
+					{Key: "rack", Value: "r2"},
+					{Key: "zone", Value: "dca1"},
+				},
+			},
+		}).
+		Return(nil)
+	resp, err = suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Format:  hpb.InventoryFormat_INVENTORY_FORMAT_CSV,
+			Content: []byte("labels,hostname\nrack=r2;zone=dca1,host3\n"),
+		})
+	suite.NoError(err)
+	suite.Equal([]string{"host3"}, resp.GetHostnames())
+}
+
+// TestImportHostInventoryErrors tests the failures of importing an
+// inventory
+func (suite *HostSvcHandlerTestSuite) TestImportHostInventoryErrors() {
+	for _, content := range []string{
+		`{"hostname": "host1"}`,
+		`[]`,
+		`[{"pool": "batch"}]`,
+		`[{"hostname": "host1", "labels": ["rack"]}]`,
+	} {
+		_, err := suite.handler.ImportHostInventory(suite.ctx,
+			&svcpb.ImportHostInventoryRequest{Content: []byte(content)})
+		suite.True(yarpcerrors.IsInvalidArgument(err), content)
+	}
+
+	_, err := suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Format:  hpb.InventoryFormat_INVENTORY_FORMAT_CSV,
+			Content: []byte("pool\nbatch\n"),
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Duplicate host
+	suite.expectMaintenanceHosts()
+	_, err = suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Content: []byte(`[{"hostname": "host1"}, {"hostname": "HOST1"}]`),
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Unknown host
+	suite.expectMaintenanceHosts()
+	_, err = suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Content: []byte(`[{"hostname": "host1"}, {"hostname": "host4"}]`),
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	// Storage failure
+	suite.expectMaintenanceHosts()
+	suite.mockAssignmentMap.EXPECT().
+		Assign(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	_, err = suite.handler.ImportHostInventory(suite.ctx,
+		&svcpb.ImportHostInventoryRequest{
+			Content: []byte(`[{"hostname": "host1", "pool": "batch"}]`),
+		})
+	suite.True(yarpcerrors.IsInternal(err))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/uber/peloton/pkg/common"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// NotLeaderErrorName is the name of the errors returned by the host
+	// service of a host manager which is not the leader
+	NotLeaderErrorName = "not-leader"
+
+	// _notLeaderMessage prefixes the leader address in the message of
+	// not leader errors
+	_notLeaderMessage = "host manager is not the leader, leader is at "
+
+	// _proxiedHeader marks the calls proxied from a follower to the
+	// leader, so that they are never proxied again
+	_proxiedHeader = "x-peloton-proxied"
+)
+
+// NewNotLeaderError returns the error of a host manager which is not the
+// leader, carrying the gRPC address of the current leader. The address
+// is empty if the leader is unknown.
+func NewNotLeaderError(leaderAddress string) error {
+	return yarpcerrors.Newf(
+		yarpcerrors.CodeUnavailable,
+		"%s%s", _notLeaderMessage, leaderAddress,
+	).WithName(NotLeaderErrorName)
+}
+
+// LeaderFromError returns the leader address carried by an error returned
+// by a host manager which is not the leader, and whether the error is
+// such an error.
+func LeaderFromError(err error) (string, bool) {
+	status := yarpcerrors.FromError(err)
+	if status == nil ||
+		status.Code() != yarpcerrors.CodeUnavailable ||
+		status.Name() != NotLeaderErrorName {
+		return "", false
+	}
+	return strings.TrimPrefix(status.Message(), _notLeaderMessage), true
+}
+
+// checkLeader returns a not leader error if this host manager is not
+// the leader, so that handlers do not operate on stale local state.
+func (m *serviceHandler) checkLeader() error {
+	if m.candidate.IsLeader() {
+		return nil
+	}
+	m.metrics.NotLeader.Inc(1)
+	return NewNotLeaderError(m.leaderAddress())
+}
+
+// leaderAddress returns the gRPC address of the leader, or an empty
+// string if it cannot be discovered.
+func (m *serviceHandler) leaderAddress() string {
+	leaderURL, err := m.discovery.GetAppURL(common.HostManagerRole)
+	if err != nil {
+		log.WithError(err).Warn("Failed to discover host manager leader")
+		return ""
+	}
+	return leaderURL.Host
+}
+
+// canProxy returns whether a call can be proxied to the leader, which
+// requires follower mode and the call not being already proxied.
+func (m *serviceHandler) canProxy(ctx context.Context) bool {
+	return m.leaderClient != nil &&
+		yarpc.CallFromContext(ctx).Header(_proxiedHeader) == ""
+}
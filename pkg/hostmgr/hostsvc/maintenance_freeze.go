@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/stringset"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// maintenanceFreeze is the cluster level switch which queues
+// StartMaintenance requests instead of acting on them, e.g. during
+// incident response. The queued requests are kept in memory of the
+// leader until operators release them.
+type maintenanceFreeze struct {
+	sync.Mutex
+
+	frozen  bool
+	pending []*hpb.PendingMaintenance
+}
+
+// setFrozen freezes or unfreezes maintenance. Unfreezing does not
+// release the queued requests.
+func (f *maintenanceFreeze) setFrozen(frozen bool) {
+	f.Lock()
+	defer f.Unlock()
+	f.frozen = frozen
+}
+
+// isFrozen returns whether maintenance is frozen.
+func (f *maintenanceFreeze) isFrozen() bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.frozen
+}
+
+// get returns whether maintenance is frozen, and a copy of the queued
+// requests.
+func (f *maintenanceFreeze) get() (bool, []*hpb.PendingMaintenance) {
+	f.Lock()
+	defer f.Unlock()
+
+	var pending []*hpb.PendingMaintenance
+	for _, p := range f.pending {
+		pending = append(pending, proto.Clone(p).(*hpb.PendingMaintenance))
+	}
+	return f.frozen, pending
+}
+
+// queue queues a request if maintenance is frozen, and returns whether
+// it was queued. Hosts already queued by an older request are moved to
+// the new one.
+func (f *maintenanceFreeze) queue(
+	hostnames []string,
+	drainOptions *hpb.DrainOptions,
+	requester string,
+	now time.Time) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	if !f.frozen {
+		return false
+	}
+	f.removeLocked(stringset.FromSlice(hostnames))
+	f.pending = append(f.pending, &hpb.PendingMaintenance{
+		Hostnames:    hostnames,
+		DrainOptions: drainOptions,
+		RequestTime:  now.UTC().Format(time.RFC3339),
+		Requester:    requester,
+	})
+	return true
+}
+
+// take removes the given hosts from the queued requests, or all queued
+// requests if hostnames is empty, and returns the removed requests.
+func (f *maintenanceFreeze) take(hostnames []string) []*hpb.PendingMaintenance {
+	f.Lock()
+	defer f.Unlock()
+
+	if len(hostnames) == 0 {
+		taken := f.pending
+		f.pending = nil
+		return taken
+	}
+	return f.removeLocked(stringset.FromSlice(hostnames))
+}
+
+// restore puts back requests taken from the queue, ahead of the
+// requests queued since.
+func (f *maintenanceFreeze) restore(pending []*hpb.PendingMaintenance) {
+	f.Lock()
+	defer f.Unlock()
+	f.pending = append(pending, f.pending...)
+}
+
+// hostCount returns the number of hosts in the queued requests.
+func (f *maintenanceFreeze) hostCount() int {
+	f.Lock()
+	defer f.Unlock()
+
+	count := 0
+	for _, p := range f.pending {
+		count += len(p.GetHostnames())
+	}
+	return count
+}
+
+// removeLocked removes the given hosts from the queued requests, and
+// returns requests with the removed hosts. Requests left without hosts
+// are dropped. Must be called with the lock held.
+func (f *maintenanceFreeze) removeLocked(
+	hostnames stringset.StringSet) []*hpb.PendingMaintenance {
+	var kept, removed []*hpb.PendingMaintenance
+	for _, p := range f.pending {
+		var keep, remove []string
+		for _, hostname := range p.GetHostnames() {
+			if hostnames.Contains(hostname) {
+				remove = append(remove, hostname)
+			} else {
+				keep = append(keep, hostname)
+			}
+		}
+		if len(remove) > 0 {
+			removed = append(removed, &hpb.PendingMaintenance{
+				Hostnames:    remove,
+				DrainOptions: p.GetDrainOptions(),
+				RequestTime:  p.GetRequestTime(),
+			})
+		}
+		if len(keep) > 0 {
+			p.Hostnames = keep
+			kept = append(kept, p)
+		}
+	}
+	f.pending = kept
+	return removed
+}
+
+// SetMaintenanceFreeze freezes or unfreezes maintenance. While maintenance
+// is frozen, StartMaintenance requests are validated and queued until
+// they are released by ReleasePendingMaintenance.
+func (m *serviceHandler) SetMaintenanceFreeze(
+	ctx context.Context,
+	request *host_svc.SetMaintenanceFreezeRequest,
+) (*host_svc.SetMaintenanceFreezeResponse, error) {
+	m.metrics.SetMaintenanceFreezeAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		return nil, err
+	}
+
+	m.maintenanceFreeze.setFrozen(request.GetFrozen())
+	m.reportMaintenanceFreeze()
+	audit.Logger(ctx).WithField("frozen", request.GetFrozen()).
+		Info("Maintenance freeze set")
+	return &host_svc.SetMaintenanceFreezeResponse{}, nil
+}
+
+// GetMaintenanceFreeze returns whether maintenance is frozen, and the
+// StartMaintenance requests queued while it was.
+func (m *serviceHandler) GetMaintenanceFreeze(
+	ctx context.Context,
+	request *host_svc.GetMaintenanceFreezeRequest,
+) (*host_svc.GetMaintenanceFreezeResponse, error) {
+	m.metrics.GetMaintenanceFreezeAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		return nil, err
+	}
+
+	frozen, pending := m.maintenanceFreeze.get()
+	return &host_svc.GetMaintenanceFreezeResponse{
+		Frozen:  frozen,
+		Pending: pending,
+	}, nil
+}
+
+// ReleasePendingMaintenance starts maintenance on the hosts of the queued
+// StartMaintenance requests, or discards them. Requests can be released
+// while maintenance is still frozen. If maintenance cannot be started,
+// the requests not started yet stay queued.
+func (m *serviceHandler) ReleasePendingMaintenance(
+	ctx context.Context,
+	request *host_svc.ReleasePendingMaintenanceRequest,
+) (*host_svc.ReleasePendingMaintenanceResponse, error) {
+	m.metrics.ReleasePendingMaintenanceAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ReleasePendingMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	hostnames, _, err := m.resolveHostnames(request.GetHostnames(), nil)
+	if err != nil {
+		m.metrics.ReleasePendingMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	pending := m.maintenanceFreeze.take(hostnames)
+	defer m.reportMaintenanceFreeze()
+
+	var released []string
+	for i, p := range pending {
+		if !request.GetDiscard() {
+			if _, _, err := m.startMaintenanceRequest(
+				ctx,
+				p.GetHostnames(),
+				p.GetDrainOptions(),
+				p.GetRequester()); err != nil {
+				m.maintenanceFreeze.restore(pending[i:])
+				m.metrics.ReleasePendingMaintenanceFail.Inc(1)
+				return nil, err
+			}
+		}
+		released = append(released, p.GetHostnames()...)
+	}
+
+	audit.Logger(ctx).WithField("hosts", released).
+		WithField("discard", request.GetDiscard()).
+		Info("Released pending maintenance")
+	m.metrics.ReleasePendingMaintenanceSuccess.Inc(1)
+	return &host_svc.ReleasePendingMaintenanceResponse{
+		Hostnames: released,
+	}, nil
+}
+
+// reportMaintenanceFreeze updates the maintenance freeze gauges.
+func (m *serviceHandler) reportMaintenanceFreeze() {
+	if m.maintenanceFreeze.isFrozen() {
+		m.metrics.MaintenanceFrozen.Update(1)
+	} else {
+		m.metrics.MaintenanceFrozen.Update(0)
+	}
+	m.metrics.PendingMaintenanceHosts.Update(
+		float64(m.maintenanceFreeze.hostCount()))
+}
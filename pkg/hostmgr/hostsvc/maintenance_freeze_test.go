@@ -0,0 +1,172 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+	"time"
+
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/golang/mock/gomock"
+)
+
+// TestMaintenanceFreezeQueue tests that requests queued for the same
+// hosts replace each other, and that hosts can be taken back
+func (suite *HostSvcHandlerTestSuite) TestMaintenanceFreezeQueue() {
+	f := &maintenanceFreeze{}
+	suite.False(f.queue([]string{"host1"}, nil, "", time.Now()))
+
+	f.setFrozen(true)
+	drainOptions := &hpb.DrainOptions{KillGracePeriodSeconds: 60}
+	suite.True(f.queue([]string{"host1", "host2"}, nil, "", time.Now()))
+	suite.True(f.queue([]string{"host2", "host3"}, drainOptions, "alice", time.Now()))
+	suite.Equal(3, f.hostCount())
+
+	frozen, pending := f.get()
+	suite.True(frozen)
+	suite.Len(pending, 2)
+	suite.Equal([]string{"host1"}, pending[0].GetHostnames())
+	suite.Equal([]string{"host2", "host3"}, pending[1].GetHostnames())
+	suite.Equal(drainOptions, pending[1].GetDrainOptions())
+	suite.Equal("alice", pending[1].GetRequester())
+
+	taken := f.take([]string{"host3"})
+	suite.Len(taken, 1)
+	suite.Equal([]string{"host3"}, taken[0].GetHostnames())
+	suite.Equal(drainOptions, taken[0].GetDrainOptions())
+	suite.Equal(2, f.hostCount())
+
+	f.restore(taken)
+	suite.Equal(3, f.hostCount())
+	suite.Len(f.take(nil), 3)
+	suite.Equal(0, f.hostCount())
+}
+
+// TestStartMaintenanceFrozen tests that StartMaintenance requests are
+// queued while maintenance is frozen, and started once released
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceFrozen() {
+	_, err := suite.handler.SetMaintenanceFreeze(
+		suite.ctx,
+		&svcpb.SetMaintenanceFreezeRequest{Frozen: true})
+	suite.NoError(err)
+
+	var (
+		hosts     []string
+		hostInfos []*hpb.HostInfo
+	)
+	for _, machine := range suite.upMachines {
+		hosts = append(hosts, machine.GetHostname())
+		hostInfos = append(hostInfos, &hpb.HostInfo{
+			Hostname: machine.GetHostname(),
+			Ip:       machine.GetIp(),
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		})
+	}
+
+	resp, err := suite.handler.StartMaintenance(
+		suite.ctx,
+		&svcpb.StartMaintenanceRequest{Hostnames: hosts})
+	suite.NoError(err)
+	suite.True(resp.GetQueued())
+
+	freezeResp, err := suite.handler.GetMaintenanceFreeze(
+		suite.ctx,
+		&svcpb.GetMaintenanceFreezeRequest{})
+	suite.NoError(err)
+	suite.True(freezeResp.GetFrozen())
+	suite.Len(freezeResp.GetPending(), 1)
+	suite.Equal(hosts, freezeResp.GetPending()[0].GetHostnames())
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(hostInfos),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: hosts,
+				From:      hpb.HostState_HOST_STATE_UP,
+				To:        hpb.HostState_HOST_STATE_DRAINING,
+			}),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue(hosts).Return(nil, nil),
+	)
+
+	releaseResp, err := suite.handler.ReleasePendingMaintenance(
+		suite.ctx,
+		&svcpb.ReleasePendingMaintenanceRequest{})
+	suite.NoError(err)
+	suite.Equal(hosts, releaseResp.GetHostnames())
+	suite.Equal(0, suite.handler.maintenanceFreeze.hostCount())
+}
+
+// TestStartMaintenanceFrozenUnknownHost tests that requests for unknown
+// hosts are rejected instead of queued while maintenance is frozen
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceFrozenUnknownHost() {
+	suite.handler.maintenanceFreeze.setFrozen(true)
+
+	resp, err := suite.handler.StartMaintenance(
+		suite.ctx,
+		&svcpb.StartMaintenanceRequest{Hostnames: []string{"unknown"}})
+	suite.Error(err)
+	suite.Nil(resp)
+	suite.Equal(0, suite.handler.maintenanceFreeze.hostCount())
+}
+
+// TestReleasePendingMaintenanceDiscard tests discarding queued requests
+func (suite *HostSvcHandlerTestSuite) TestReleasePendingMaintenanceDiscard() {
+	suite.handler.maintenanceFreeze.setFrozen(true)
+	suite.handler.maintenanceFreeze.queue(
+		[]string{"host1", "host2"}, nil, "", time.Now())
+
+	resp, err := suite.handler.ReleasePendingMaintenance(
+		suite.ctx,
+		&svcpb.ReleasePendingMaintenanceRequest{
+			Hostnames: []string{"host2"},
+			Discard:   true,
+		})
+	suite.NoError(err)
+	suite.Equal([]string{"host2"}, resp.GetHostnames())
+	suite.Equal(1, suite.handler.maintenanceFreeze.hostCount())
+}
+
+// TestReleasePendingMaintenanceError tests that requests which cannot
+// be started stay queued
+func (suite *HostSvcHandlerTestSuite) TestReleasePendingMaintenanceError() {
+	suite.handler.maintenanceFreeze.setFrozen(true)
+	suite.handler.maintenanceFreeze.queue(
+		[]string{suite.upMachines[0].GetHostname()}, nil, "", time.Now())
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+
+	resp, err := suite.handler.ReleasePendingMaintenance(
+		suite.ctx,
+		&svcpb.ReleasePendingMaintenanceRequest{})
+	suite.Error(err)
+	suite.Nil(resp)
+	suite.Equal(1, suite.handler.maintenanceFreeze.hostCount())
+}
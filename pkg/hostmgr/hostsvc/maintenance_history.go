@@ -0,0 +1,120 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"time"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/stringset"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// GetMaintenanceHistory returns the archived and recent maintenance state
+// transitions of a host. The transitions are read from storage, so any
+// host manager can serve the call.
+func (m *serviceHandler) GetMaintenanceHistory(
+	ctx context.Context,
+	request *host_svc.GetMaintenanceHistoryRequest,
+) (*host_svc.GetMaintenanceHistoryResponse, error) {
+	m.metrics.GetMaintenanceHistoryAPI.Inc(1)
+
+	if request.GetHostname() == "" {
+		m.metrics.GetMaintenanceHistoryFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf("hostname is required")
+	}
+
+	events, err := m.maintenanceHistory.Get(ctx, request.GetHostname())
+	if err != nil {
+		m.metrics.GetMaintenanceHistoryFail.Inc(1)
+		return nil, err
+	}
+
+	m.metrics.GetMaintenanceHistorySuccess.Inc(1)
+	return &host_svc.GetMaintenanceHistoryResponse{Events: events}, nil
+}
+
+// ArchiveMaintenanceHistory moves the old maintenance state transitions of
+// the hosts known to the host manager to the maintenance history, and
+// prunes the history past its retention. It is called periodically by the
+// archiver. Transitions of hosts which left the cluster expire with the
+// TTL of the recent events instead.
+func (m *serviceHandler) ArchiveMaintenanceHistory(
+	ctx context.Context,
+	request *host_svc.ArchiveMaintenanceHistoryRequest,
+) (*host_svc.ArchiveMaintenanceHistoryResponse, error) {
+	m.metrics.ArchiveMaintenanceHistoryAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ArchiveMaintenanceHistoryFail.Inc(1)
+		return nil, err
+	}
+
+	hostnames, err := m.getKnownHostnames()
+	if err != nil {
+		m.metrics.ArchiveMaintenanceHistoryFail.Inc(1)
+		return nil, err
+	}
+
+	archived, pruned, err := m.maintenanceHistory.Archive(
+		ctx,
+		hostnames,
+		time.Duration(request.GetArchiveAgeSeconds())*time.Second,
+		time.Duration(request.GetRetentionSeconds())*time.Second,
+	)
+	log.WithFields(log.Fields{
+		"hosts":    len(hostnames),
+		"archived": archived,
+		"pruned":   pruned,
+	}).Info("Archived host maintenance history")
+	if err != nil {
+		m.metrics.ArchiveMaintenanceHistoryFail.Inc(1)
+		return nil, err
+	}
+
+	m.metrics.ArchiveMaintenanceHistorySuccess.Inc(1)
+	return &host_svc.ArchiveMaintenanceHistoryResponse{
+		Archived: uint32(archived),
+		Pruned:   uint32(pruned),
+	}, nil
+}
+
+// getKnownHostnames returns the sorted hostnames of the registered agents
+// and of the hosts in maintenance.
+func (m *serviceHandler) getKnownHostnames() ([]string, error) {
+	upHosts, err := m.buildHostInfoForRegisteredAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := stringset.NewUnsafe()
+	for hostname := range upHosts {
+		hostnames.Add(hostname)
+	}
+	for _, hostInfo := range m.maintenanceHostInfoMap.GetDrainingHostInfos(
+		[]string{}) {
+		hostnames.Add(hostInfo.GetHostname())
+	}
+	for _, hostInfo := range m.maintenanceHostInfoMap.GetDownHostInfos(
+		[]string{}) {
+		hostnames.Add(hostInfo.GetHostname())
+	}
+
+	return hostnames.ToSortedSlice(), nil
+}
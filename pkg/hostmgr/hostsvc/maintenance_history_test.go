@@ -0,0 +1,110 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceHistory() {
+	events := []*hpb.MaintenanceEvent{
+		{
+			Hostname:  "host1",
+			FromState: hpb.HostState_HOST_STATE_UP,
+			ToState:   hpb.HostState_HOST_STATE_DRAINING,
+			EventTime: "2019-05-01T10:00:00Z",
+			Archived:  true,
+		},
+	}
+	suite.mockMaintenanceHistory.EXPECT().
+		Get(gomock.Any(), "host1").
+		Return(events, nil)
+
+	resp, err := suite.handler.GetMaintenanceHistory(
+		suite.ctx,
+		&svc.GetMaintenanceHistoryRequest{Hostname: "host1"})
+	suite.NoError(err)
+	suite.Equal(events, resp.GetEvents())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceHistoryError() {
+	// Test missing hostname
+	_, err := suite.handler.GetMaintenanceHistory(
+		suite.ctx,
+		&svc.GetMaintenanceHistoryRequest{})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Test storage error
+	suite.mockMaintenanceHistory.EXPECT().
+		Get(gomock.Any(), "host1").
+		Return(nil, errors.New("getall failed"))
+
+	_, err = suite.handler.GetMaintenanceHistory(
+		suite.ctx,
+		&svc.GetMaintenanceHistoryRequest{Hostname: "host1"})
+	suite.EqualError(err, "getall failed")
+}
+
+// TestArchiveMaintenanceHistory tests that the history of the registered
+// hosts and of the hosts in maintenance is archived
+func (suite *HostSvcHandlerTestSuite) TestArchiveMaintenanceHistory() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{{Hostname: "host3"}})
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return([]*hpb.HostInfo{{Hostname: "host2"}})
+	suite.mockMaintenanceHistory.EXPECT().
+		Archive(
+			gomock.Any(),
+			[]string{"host1", "host2", "host3"},
+			24*time.Hour,
+			365*24*time.Hour).
+		Return(4, 2, nil)
+
+	resp, err := suite.handler.ArchiveMaintenanceHistory(
+		suite.ctx,
+		&svc.ArchiveMaintenanceHistoryRequest{
+			ArchiveAgeSeconds: 24 * 60 * 60,
+			RetentionSeconds:  365 * 24 * 60 * 60,
+		})
+	suite.NoError(err)
+	suite.Equal(uint32(4), resp.GetArchived())
+	suite.Equal(uint32(2), resp.GetPruned())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestArchiveMaintenanceHistoryError() {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil)
+	suite.mockMaintenanceHistory.EXPECT().
+		Archive(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(1, 0, errors.New("create failed"))
+
+	_, err := suite.handler.ArchiveMaintenanceHistory(
+		suite.ctx,
+		&svc.ArchiveMaintenanceHistoryRequest{ArchiveAgeSeconds: 60})
+	suite.EqualError(err, "create failed")
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/logging"
+	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Timeout of the calls of Mesos Master putting down the hosts whose
+	// drain timed out.
+	_drainTimeoutCallTimeout = 10 * time.Second
+)
+
+// _maintenancePolicyNamePattern matches the valid names of maintenance
+// policies, e.g. kernel-upgrade
+var _maintenancePolicyNamePattern = regexp.MustCompile(
+	`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SetMaintenancePolicy creates a maintenance policy, or replaces the
+// policy with the same name. Hosts already draining with the policy keep
+// the drain options they were started with.
+func (m *serviceHandler) SetMaintenancePolicy(
+	ctx context.Context,
+	request *host_svc.SetMaintenancePolicyRequest,
+) (*host_svc.SetMaintenancePolicyResponse, error) {
+	m.metrics.SetMaintenancePolicyAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.SetMaintenancePolicyFail.Inc(1)
+		return nil, err
+	}
+
+	policy := request.GetPolicy()
+	if !_maintenancePolicyNamePattern.MatchString(policy.GetName()) {
+		m.metrics.SetMaintenancePolicyFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid maintenance policy name %q", policy.GetName())
+	}
+	if err := m.validateDrainOptions(policy.GetDrainOptions()); err != nil {
+		m.metrics.SetMaintenancePolicyFail.Inc(1)
+		return nil, err
+	}
+
+	// The policy and the deadline of the drain options are set by host
+	// manager when hosts are drained with the policy
+	policy = proto.Clone(policy).(*hpb.MaintenancePolicy)
+	if options := policy.GetDrainOptions(); options != nil {
+		options.Policy = ""
+		options.DrainDeadline = ""
+	}
+	if err := m.maintenancePolicyOps.Create(ctx, policy); err != nil {
+		m.metrics.SetMaintenancePolicyFail.Inc(1)
+		return nil, newInternalError(
+			err, "failed to store maintenance policy %s", policy.GetName())
+	}
+
+	audit.Logger(ctx).WithField("policy", policy).
+		Info("Maintenance policy set")
+	m.metrics.SetMaintenancePolicySuccess.Inc(1)
+	return &host_svc.SetMaintenancePolicyResponse{}, nil
+}
+
+// GetMaintenancePolicies returns the maintenance policies.
+func (m *serviceHandler) GetMaintenancePolicies(
+	ctx context.Context,
+	request *host_svc.GetMaintenancePoliciesRequest,
+) (*host_svc.GetMaintenancePoliciesResponse, error) {
+	m.metrics.GetMaintenancePoliciesAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.GetMaintenancePoliciesFail.Inc(1)
+		return nil, err
+	}
+
+	policies, err := m.maintenancePolicyOps.GetAll(ctx)
+	if err != nil {
+		m.metrics.GetMaintenancePoliciesFail.Inc(1)
+		return nil, newInternalError(err, "failed to get maintenance policies")
+	}
+
+	m.metrics.GetMaintenancePoliciesSuccess.Inc(1)
+	return &host_svc.GetMaintenancePoliciesResponse{
+		Policies: policies,
+	}, nil
+}
+
+// DeleteMaintenancePolicy deletes a maintenance policy. Hosts draining
+// with the policy keep their drain options.
+func (m *serviceHandler) DeleteMaintenancePolicy(
+	ctx context.Context,
+	request *host_svc.DeleteMaintenancePolicyRequest,
+) (*host_svc.DeleteMaintenancePolicyResponse, error) {
+	m.metrics.DeleteMaintenancePolicyAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.DeleteMaintenancePolicyFail.Inc(1)
+		return nil, err
+	}
+
+	name := request.GetName()
+	policy, err := m.getMaintenancePolicy(ctx, name)
+	if err != nil {
+		m.metrics.DeleteMaintenancePolicyFail.Inc(1)
+		return nil, err
+	}
+	if err := m.maintenancePolicyOps.Delete(ctx, policy.GetName()); err != nil {
+		m.metrics.DeleteMaintenancePolicyFail.Inc(1)
+		return nil, newInternalError(
+			err, "failed to delete maintenance policy %s", name)
+	}
+
+	audit.Logger(ctx).WithField("policy", name).
+		Info("Maintenance policy deleted")
+	m.metrics.DeleteMaintenancePolicySuccess.Inc(1)
+	return &host_svc.DeleteMaintenancePolicyResponse{}, nil
+}
+
+// getMaintenancePolicy returns a maintenance policy, or a not found
+// error if there is no policy with the name.
+func (m *serviceHandler) getMaintenancePolicy(
+	ctx context.Context,
+	name string) (*hpb.MaintenancePolicy, error) {
+	policy, err := m.maintenancePolicyOps.Get(ctx, name)
+	if err != nil {
+		return nil, newInternalError(
+			err, "failed to get maintenance policy %s", name)
+	}
+	if policy == nil {
+		return nil, yarpcerrors.NotFoundErrorf(
+			"maintenance policy %s not found", name)
+	}
+	return policy, nil
+}
+
+// validateDrainOptions returns an invalid argument error if the drain
+// options of a request or a policy cannot be applied.
+func (m *serviceHandler) validateDrainOptions(options *hpb.DrainOptions) error {
+	agentDrain := m.getDrainMethod(options) == hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN
+	if options.GetRequireApproval() && agentDrain {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"approval cannot be required with the agent drain method")
+	}
+	if options.GetDrainTimeoutSeconds() > 0 && agentDrain {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"drain timeout is not supported with the agent drain method")
+	}
+	if options.GetExemption() != nil && agentDrain {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"eviction exemptions are not supported with the agent drain method")
+	}
+	for _, label := range options.GetExemption().GetLabels() {
+		if label.GetKey() == "" {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"eviction exemption label without key")
+		}
+	}
+	if rate := options.GetCanary().GetMinRescheduleRate(); rate < 0 || rate > 1 {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"min reschedule rate %v is not between 0 and 1", rate)
+	}
+	return nil
+}
+
+// resolveDrainOptions returns the drain options of a StartMaintenance
+// request: the drain options of the request, else the ones of its
+// maintenance policy, else the ones of the default maintenance policy of
+// the host pool of its hosts. Also returns the policy the options come
+// from, if any.
+func (m *serviceHandler) resolveDrainOptions(
+	ctx context.Context,
+	request *host_svc.StartMaintenanceRequest,
+	hostnames []string) (*hpb.DrainOptions, *hpb.MaintenancePolicy, error) {
+	if request.GetDrainOptions() != nil {
+		return request.GetDrainOptions(), nil, nil
+	}
+
+	name := request.GetPolicy()
+	if name == "" {
+		var err error
+		name, err = m.defaultMaintenancePolicy(hostnames)
+		if err != nil || name == "" {
+			return nil, nil, err
+		}
+	}
+	policy, err := m.getMaintenancePolicy(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := &hpb.DrainOptions{}
+	if policy.GetDrainOptions() != nil {
+		options = proto.Clone(policy.GetDrainOptions()).(*hpb.DrainOptions)
+	}
+	options.Policy = policy.GetName()
+	// The drain method defaults to the one of host manager, which may
+	// not support the options of the policy
+	if err := m.validateDrainOptions(options); err != nil {
+		return nil, nil, err
+	}
+	return options, policy, nil
+}
+
+// defaultMaintenancePolicy returns the name of the default maintenance
+// policy of the host pool of the given hosts, empty if there is none.
+func (m *serviceHandler) defaultMaintenancePolicy(
+	hostnames []string) (string, error) {
+	if len(m.defaultMaintenancePolicies) == 0 {
+		return "", nil
+	}
+
+	names := stringset.NewUnsafe()
+	for _, hostname := range hostnames {
+		pool := host.GetHostPoolByHostname(hostname)
+		names.Add(m.defaultMaintenancePolicies[pool])
+	}
+	if names.Len() > 1 {
+		return "", yarpcerrors.InvalidArgumentErrorf(
+			"hosts of the request have different default maintenance "+
+				"policies %q, set the policy or the drain options of the request",
+			names.ToSortedSlice())
+	}
+	for _, name := range names.ToSlice() {
+		return name, nil
+	}
+	return "", nil
+}
+
+// checkMaintenancePolicyLimit returns a resource exhausted error if
+// draining the given hosts would exceed the maximum number of hosts
+// draining with the policy. Hosts waiting for their maintenance to be
+// approved count as draining.
+func (m *serviceHandler) checkMaintenancePolicyLimit(
+	policy *hpb.MaintenancePolicy,
+	hostnames []string) error {
+	limit := int(policy.GetMaxDrainingHosts())
+	if limit == 0 {
+		return nil
+	}
+
+	requested := stringset.NewUnsafe()
+	requested.AddAll(hostnames)
+	draining := 0
+	for _, hostInfo := range m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}) {
+		if hostInfo.GetDrainOptions().GetPolicy() == policy.GetName() &&
+			!requested.Contains(hostInfo.GetHostname()) {
+			draining++
+		}
+	}
+	if draining+requested.Len() > limit {
+		return yarpcerrors.ResourceExhaustedErrorf(
+			"maintenance policy %s allows %d draining hosts, %d are "+
+				"draining and %d were requested",
+			policy.GetName(), limit, draining, requested.Len())
+	}
+	return nil
+}
+
+// withDrainDeadline returns the drain options of hosts starting to drain
+// now, with the deadline of their drain timeout if they have one.
+func withDrainDeadline(
+	drainOptions *hpb.DrainOptions,
+	now time.Time) *hpb.DrainOptions {
+	timeout := drainOptions.GetDrainTimeoutSeconds()
+	if timeout == 0 {
+		return drainOptions
+	}
+	options := proto.Clone(drainOptions).(*hpb.DrainOptions)
+	options.DrainDeadline = now.Add(time.Duration(timeout) * time.Second).
+		UTC().Format(time.RFC3339)
+	return options
+}
+
+// downTimedOutHosts puts down the given hosts still draining past the
+// deadline of their drain options, unless they wait for their maintenance
+// to be approved. The tasks still running on the hosts are killed by
+// Mesos Master once the hosts are down.
+func (m *serviceHandler) downTimedOutHosts(hostnames []string) {
+	if len(hostnames) == 0 || !m.candidate.IsLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		logging.WithHosts(
+			audit.NewOperation(context.Background(), common.PelotonHostManager),
+			hostnames),
+		_drainTimeoutCallTimeout)
+	defer cancel()
+
+	now := time.Now()
+	var downedHosts, approvedHosts []string
+	for _, hostInfo := range m.maintenanceHostInfoMap.GetDrainingHostInfos(hostnames) {
+		deadline, err := time.Parse(
+			time.RFC3339, hostInfo.GetDrainOptions().GetDrainDeadline())
+		if err != nil || now.Before(deadline) {
+			// Not drained with a timeout, or drained again since
+			continue
+		}
+		hostname := hostInfo.GetHostname()
+		approval := m.approvalMap.Get(hostname)
+		if host.IsApprovalPending(approval) {
+			continue
+		}
+
+		if err := m.operatorMasterClient.StartMaintenance(
+			ctx,
+			[]*mesos.MachineID{{
+				Hostname: &hostInfo.Hostname,
+				Ip:       &hostInfo.Ip,
+			}}); err != nil {
+			logging.FromContext(ctx).WithError(err).
+				WithField("hostname", hostname).
+				Error("failed to down host whose drain timed out")
+			m.metrics.DrainTimeoutFail.Inc(1)
+			continue
+		}
+		if err := m.maintenanceHostInfoMap.UpdateHostState(
+			hostname,
+			hpb.HostState_HOST_STATE_DRAINING,
+			hpb.HostState_HOST_STATE_DOWN); err != nil {
+			// The host map converges on reconciliation with Mesos Master
+			logging.FromContext(ctx).WithError(err).
+				WithField("hostname", hostname).
+				Error("failed to update host state in host map")
+		}
+		downedHosts = append(downedHosts, hostname)
+		if approval != nil {
+			approvedHosts = append(approvedHosts, hostname)
+		}
+	}
+	if len(downedHosts) == 0 {
+		return
+	}
+
+	m.maintenanceQueue.MarkProcessed(downedHosts)
+	m.eventBus.Publish(&eventbus.HostStateChangedEvent{
+		Hostnames: downedHosts,
+		From:      hpb.HostState_HOST_STATE_DRAINING,
+		To:        hpb.HostState_HOST_STATE_DOWN,
+	})
+	if len(approvedHosts) > 0 {
+		if err := m.approvalMap.Remove(ctx, approvedHosts); err != nil {
+			logging.FromContext(ctx).WithError(err).
+				WithField("approved_hosts", approvedHosts).
+				Error("failed to remove maintenance approvals of downed hosts")
+		}
+	}
+	logging.FromContext(ctx).WithField("downed_hosts", downedHosts).
+		Warn("Drain timed out, hosts put down with tasks still running")
+	m.metrics.DrainTimeoutHosts.Inc(int64(len(downedHosts)))
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const _kernelUpgradePolicy = "kernel-upgrade"
+
+// kernelUpgradePolicy returns a maintenance policy of the tests
+func kernelUpgradePolicy() *hpb.MaintenancePolicy {
+	return &hpb.MaintenancePolicy{
+		Name: _kernelUpgradePolicy,
+		DrainOptions: &hpb.DrainOptions{
+			KillGracePeriodSeconds: 30,
+			Message:                "kernel upgrade",
+		},
+		MaxDrainingHosts: 2,
+	}
+}
+
+// expectStartMaintenanceWithOptions sets the expectations to start
+// maintenance on the UP host with the given drain options
+func (suite *HostSvcHandlerTestSuite) expectStartMaintenanceWithOptions(
+	drainOptions *hpb.DrainOptions) {
+	hostname := suite.upMachines[0].GetHostname()
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos([]*hpb.HostInfo{{
+				Hostname:     hostname,
+				Ip:           suite.upMachines[0].GetIp(),
+				State:        hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: drainOptions,
+			}}),
+		suite.mockEventBus.EXPECT().Publish(gomock.Any()),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue([]string{hostname}).Return(nil, nil),
+	)
+}
+
+// TestSetMaintenancePolicy tests that policies are stored without the
+// fields of the drain options set by host manager
+func (suite *HostSvcHandlerTestSuite) TestSetMaintenancePolicy() {
+	policy := kernelUpgradePolicy()
+	policy.DrainOptions.Policy = "other"
+	policy.DrainOptions.DrainDeadline = "2019-05-01T10:00:00Z"
+
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Create(gomock.Any(), kernelUpgradePolicy()).Return(nil)
+	_, err := suite.handler.SetMaintenancePolicy(
+		suite.ctx,
+		&svcpb.SetMaintenancePolicyRequest{Policy: policy})
+	suite.NoError(err)
+
+	// The policy of the request is not modified
+	suite.Equal("other", policy.GetDrainOptions().GetPolicy())
+}
+
+// TestSetMaintenancePolicyInvalid tests that invalid policies are
+// rejected
+func (suite *HostSvcHandlerTestSuite) TestSetMaintenancePolicyInvalid() {
+	for _, policy := range []*hpb.MaintenancePolicy{
+		nil,
+		{Name: "kernel upgrade"},
+		{
+			Name: _kernelUpgradePolicy,
+			DrainOptions: &hpb.DrainOptions{
+				Method:          hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+				RequireApproval: true,
+			},
+		},
+		{
+			Name: _kernelUpgradePolicy,
+			DrainOptions: &hpb.DrainOptions{
+				Method:              hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+				DrainTimeoutSeconds: 600,
+			},
+		},
+	} {
+		_, err := suite.handler.SetMaintenancePolicy(
+			suite.ctx,
+			&svcpb.SetMaintenancePolicyRequest{Policy: policy})
+		suite.True(yarpcerrors.IsInvalidArgument(err), policy.String())
+	}
+}
+
+// TestSetMaintenancePolicyStorageError tests that storage errors are
+// returned as internal errors
+func (suite *HostSvcHandlerTestSuite) TestSetMaintenancePolicyStorageError() {
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Create(gomock.Any(), gomock.Any()).Return(errors.New("create failed"))
+	_, err := suite.handler.SetMaintenancePolicy(
+		suite.ctx,
+		&svcpb.SetMaintenancePolicyRequest{Policy: kernelUpgradePolicy()})
+	suite.True(yarpcerrors.IsInternal(err))
+}
+
+// TestGetMaintenancePolicies tests listing the policies
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenancePolicies() {
+	policies := []*hpb.MaintenancePolicy{kernelUpgradePolicy()}
+	suite.mockMaintenancePolicyOps.EXPECT().
+		GetAll(gomock.Any()).Return(policies, nil)
+	resp, err := suite.handler.GetMaintenancePolicies(
+		suite.ctx,
+		&svcpb.GetMaintenancePoliciesRequest{})
+	suite.NoError(err)
+	suite.Equal(policies, resp.GetPolicies())
+
+	suite.mockMaintenancePolicyOps.EXPECT().
+		GetAll(gomock.Any()).Return(nil, errors.New("getall failed"))
+	_, err = suite.handler.GetMaintenancePolicies(
+		suite.ctx,
+		&svcpb.GetMaintenancePoliciesRequest{})
+	suite.True(yarpcerrors.IsInternal(err))
+}
+
+// TestDeleteMaintenancePolicy tests deleting policies, and that unknown
+// policies are not found
+func (suite *HostSvcHandlerTestSuite) TestDeleteMaintenancePolicy() {
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Get(gomock.Any(), "unknown").Return(nil, nil)
+	_, err := suite.handler.DeleteMaintenancePolicy(
+		suite.ctx,
+		&svcpb.DeleteMaintenancePolicyRequest{Name: "unknown"})
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	gomock.InOrder(
+		suite.mockMaintenancePolicyOps.EXPECT().
+			Get(gomock.Any(), _kernelUpgradePolicy).
+			Return(kernelUpgradePolicy(), nil),
+		suite.mockMaintenancePolicyOps.EXPECT().
+			Delete(gomock.Any(), _kernelUpgradePolicy).Return(nil),
+	)
+	_, err = suite.handler.DeleteMaintenancePolicy(
+		suite.ctx,
+		&svcpb.DeleteMaintenancePolicyRequest{Name: _kernelUpgradePolicy})
+	suite.NoError(err)
+}
+
+// TestStartMaintenanceWithPolicy tests that the hosts are drained with
+// the drain options of the policy of the request
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceWithPolicy() {
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Get(gomock.Any(), _kernelUpgradePolicy).
+		Return(kernelUpgradePolicy(), nil)
+	// One other host is draining with the policy, within its limit
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{{
+			Hostname: "host5",
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+			DrainOptions: &hpb.DrainOptions{
+				Policy: _kernelUpgradePolicy,
+			},
+		}})
+	suite.expectStartMaintenanceWithOptions(&hpb.DrainOptions{
+		KillGracePeriodSeconds: 30,
+		Message:                "kernel upgrade",
+		Policy:                 _kernelUpgradePolicy,
+	})
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+			Policy:    _kernelUpgradePolicy,
+		})
+	suite.NoError(err)
+}
+
+// TestStartMaintenanceDefaultPolicy tests that the hosts of requests
+// without drain options nor policy are drained with the default policy
+// of their host pool
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDefaultPolicy() {
+	suite.handler.defaultMaintenancePolicies = map[string]string{
+		host.DefaultHostPool: _kernelUpgradePolicy,
+	}
+	policy := kernelUpgradePolicy()
+	policy.MaxDrainingHosts = 0
+
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Get(gomock.Any(), _kernelUpgradePolicy).Return(policy, nil)
+	suite.expectStartMaintenanceWithOptions(&hpb.DrainOptions{
+		KillGracePeriodSeconds: 30,
+		Message:                "kernel upgrade",
+		Policy:                 _kernelUpgradePolicy,
+	})
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname()},
+		})
+	suite.NoError(err)
+}
+
+// TestStartMaintenancePolicyErrors tests the requests whose policy
+// cannot be applied
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenancePolicyErrors() {
+	hostname := suite.upMachines[0].GetHostname()
+
+	// Both drain options and policy
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames:    []string{hostname},
+			DrainOptions: &hpb.DrainOptions{KillGracePeriodSeconds: 30},
+			Policy:       _kernelUpgradePolicy,
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// Unknown policy
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Get(gomock.Any(), "unknown").Return(nil, nil)
+	_, err = suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{hostname},
+			Policy:    "unknown",
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	// Too many hosts draining with the policy
+	suite.mockMaintenancePolicyOps.EXPECT().
+		Get(gomock.Any(), _kernelUpgradePolicy).
+		Return(kernelUpgradePolicy(), nil)
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname:     "host5",
+				DrainOptions: &hpb.DrainOptions{Policy: _kernelUpgradePolicy},
+			},
+			{
+				Hostname:     "host6",
+				DrainOptions: &hpb.DrainOptions{Policy: _kernelUpgradePolicy},
+			},
+		})
+	_, err = suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{hostname},
+			Policy:    _kernelUpgradePolicy,
+		})
+	suite.True(yarpcerrors.IsResourceExhausted(err))
+}
+
+// TestStartMaintenanceDifferentDefaultPolicies tests that requests whose
+// hosts have different default policies are rejected
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDifferentDefaultPolicies() {
+	// The UP host is in the dca1 pool, and host4 whose agent is not
+	// registered in the default pool
+	host.SetHostPoolAttribute(_zoneAttribute)
+	defer host.SetHostPoolAttribute("")
+	suite.handler.defaultMaintenancePolicies = map[string]string{
+		"dca1":               _kernelUpgradePolicy,
+		host.DefaultHostPool: "stateful-upgrade",
+	}
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{suite.upMachines[0].GetHostname(), "host4"},
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestStartMaintenanceDrainTimeout tests that the hosts still draining
+// at the end of the drain timeout are put down
+func (suite *HostSvcHandlerTestSuite) TestStartMaintenanceDrainTimeout() {
+	hostname := suite.upMachines[0].GetHostname()
+	var timeout time.Duration
+	var timedOut func()
+	suite.handler.afterFunc = func(d time.Duration, f func()) {
+		timeout = d
+		timedOut = f
+	}
+
+	var hostInfo *hpb.HostInfo
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: &mesosmaintenance.Schedule{},
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			AddHostInfos(gomock.Any()).
+			Do(func(hostInfos []*hpb.HostInfo) {
+				hostInfo = hostInfos[0]
+			}),
+		suite.mockEventBus.EXPECT().Publish(gomock.Any()),
+		suite.mockMaintenanceQueue.EXPECT().
+			Enqueue([]string{hostname}).Return(nil, nil),
+	)
+
+	_, err := suite.handler.StartMaintenance(suite.ctx,
+		&svcpb.StartMaintenanceRequest{
+			Hostnames: []string{hostname},
+			DrainOptions: &hpb.DrainOptions{
+				DrainTimeoutSeconds: 600,
+			},
+		})
+	suite.NoError(err)
+	suite.Equal(600*time.Second, timeout)
+	deadline, err := time.Parse(
+		time.RFC3339, hostInfo.GetDrainOptions().GetDrainDeadline())
+	suite.NoError(err)
+	suite.True(deadline.After(time.Now().Add(590 * time.Second)))
+
+	// The host is not put down before its deadline
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{hostname}).
+		Return([]*hpb.HostInfo{hostInfo})
+	timedOut()
+
+	hostInfo.DrainOptions.DrainDeadline = time.Now().Add(-time.Second).
+		UTC().Format(time.RFC3339)
+	gomock.InOrder(
+		suite.mockMaintenanceMap.EXPECT().
+			GetDrainingHostInfos([]string{hostname}).
+			Return([]*hpb.HostInfo{hostInfo}),
+		suite.mockMasterOperatorClient.EXPECT().
+			StartMaintenance(gomock.Any(), []*mesos.MachineID{{
+				Hostname: &hostInfo.Hostname,
+				Ip:       &hostInfo.Ip,
+			}}).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			UpdateHostState(
+				hostname,
+				hpb.HostState_HOST_STATE_DRAINING,
+				hpb.HostState_HOST_STATE_DOWN).Return(nil),
+		suite.mockMaintenanceQueue.EXPECT().
+			MarkProcessed([]string{hostname}),
+		suite.mockEventBus.EXPECT().
+			Publish(&eventbus.HostStateChangedEvent{
+				Hostnames: []string{hostname},
+				From:      hpb.HostState_HOST_STATE_DRAINING,
+				To:        hpb.HostState_HOST_STATE_DOWN,
+			}),
+	)
+	timedOut()
+}
+
+// TestDownTimedOutHostsMasterError tests that hosts failing to be put
+// down stay draining
+func (suite *HostSvcHandlerTestSuite) TestDownTimedOutHostsMasterError() {
+	hostname := suite.upMachines[0].GetHostname()
+	hostInfo := &hpb.HostInfo{
+		Hostname: hostname,
+		Ip:       suite.upMachines[0].GetIp(),
+		State:    hpb.HostState_HOST_STATE_DRAINING,
+		DrainOptions: &hpb.DrainOptions{
+			DrainTimeoutSeconds: 600,
+			DrainDeadline:       "2019-05-01T10:00:00Z",
+		},
+	}
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{hostname}).
+		Return([]*hpb.HostInfo{hostInfo})
+	suite.mockMasterOperatorClient.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(errors.New("master unavailable"))
+	suite.handler.downTimedOutHosts([]string{hostname})
+}
@@ -14,21 +14,167 @@
 
 package hostsvc
 
-import "github.com/uber-go/tally"
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uber-go/tally"
+)
+
+// _sizeBuckets are the buckets of the request and response size
+// histograms, from 64B to 16MB
+var _sizeBuckets = tally.MustMakeExponentialValueBuckets(64, 4, 10)
+
+// ProcedureMetrics are the metrics recorded by the inbound middleware for
+// every call of a procedure
+type ProcedureMetrics struct {
+	Latency      tally.Timer
+	InFlight     tally.Gauge
+	RequestSize  tally.Histogram
+	ResponseSize tally.Histogram
+
+	// number of calls in flight, reported by InFlight
+	inFlight int64
+}
+
+// start records the start of a call
+func (p *ProcedureMetrics) start() {
+	p.InFlight.Update(float64(atomic.AddInt64(&p.inFlight, 1)))
+}
+
+// done records the end of a call
+func (p *ProcedureMetrics) done() {
+	p.InFlight.Update(float64(atomic.AddInt64(&p.inFlight, -1)))
+}
 
 // Metrics is a placeholder for all metrics in host.svc
 type Metrics struct {
-	StartMaintenanceAPI     tally.Counter
-	StartMaintenanceSuccess tally.Counter
-	StartMaintenanceFail    tally.Counter
+	StartMaintenanceAPI      tally.Counter
+	StartMaintenanceSuccess  tally.Counter
+	StartMaintenanceFail     tally.Counter
+	StartMaintenanceQueued   tally.Counter
+	StartMaintenanceDryRun   tally.Counter
+	StartMaintenanceReplayed tally.Counter
+
+	AgentDrainHosts    tally.Counter
+	AgentDrainFallback tally.Counter
+
+	CompleteMaintenanceAPI      tally.Counter
+	CompleteMaintenanceSuccess  tally.Counter
+	CompleteMaintenanceFail     tally.Counter
+	CompleteMaintenanceDryRun   tally.Counter
+	CompleteMaintenanceReplayed tally.Counter
+
+	QueryHostsAPI       tally.Counter
+	QueryHostsSuccess   tally.Counter
+	QueryHostsFail      tally.Counter
+	QueryHostsProxied   tally.Counter
+	QueryHostsFederated tally.Counter
+	QueryHostsPeerFail  tally.Counter
+
+	GetMaintenanceDeadLettersAPI tally.Counter
+
+	RedriveMaintenanceDeadLettersAPI     tally.Counter
+	RedriveMaintenanceDeadLettersSuccess tally.Counter
+	RedriveMaintenanceDeadLettersFail    tally.Counter
+
+	CreateReservationAPI     tally.Counter
+	CreateReservationSuccess tally.Counter
+	CreateReservationFail    tally.Counter
+
+	ListReservationsAPI     tally.Counter
+	ListReservationsSuccess tally.Counter
+	ListReservationsFail    tally.Counter
+
+	ReleaseReservationAPI     tally.Counter
+	ReleaseReservationSuccess tally.Counter
+	ReleaseReservationFail    tally.Counter
+
+	GetMaintenanceHistoryAPI     tally.Counter
+	GetMaintenanceHistorySuccess tally.Counter
+	GetMaintenanceHistoryFail    tally.Counter
+
+	ArchiveMaintenanceHistoryAPI     tally.Counter
+	ArchiveMaintenanceHistorySuccess tally.Counter
+	ArchiveMaintenanceHistoryFail    tally.Counter
+
+	SetMaintenanceFreezeAPI tally.Counter
+	GetMaintenanceFreezeAPI tally.Counter
+
+	ReleasePendingMaintenanceAPI     tally.Counter
+	ReleasePendingMaintenanceSuccess tally.Counter
+	ReleasePendingMaintenanceFail    tally.Counter
+
+	GetHostEventsAPI     tally.Counter
+	GetHostEventsSuccess tally.Counter
+	GetHostEventsFail    tally.Counter
+
+	UpdateMaintenanceAPI     tally.Counter
+	UpdateMaintenanceSuccess tally.Counter
+	UpdateMaintenanceFail    tally.Counter
+
+	DecommissionHostsAPI     tally.Counter
+	DecommissionHostsSuccess tally.Counter
+	DecommissionHostsFail    tally.Counter
+
+	HostProviderOperations    tally.Counter
+	HostProviderOperationFail tally.Counter
+
+	ExportHostInventoryAPI     tally.Counter
+	ExportHostInventorySuccess tally.Counter
+	ExportHostInventoryFail    tally.Counter
+
+	ImportHostInventoryAPI     tally.Counter
+	ImportHostInventorySuccess tally.Counter
+	ImportHostInventoryFail    tally.Counter
+
+	ApproveMaintenanceAPI     tally.Counter
+	ApproveMaintenanceSuccess tally.Counter
+	ApproveMaintenanceFail    tally.Counter
+
+	GetMaintenanceApprovalsAPI     tally.Counter
+	GetMaintenanceApprovalsSuccess tally.Counter
+	GetMaintenanceApprovalsFail    tally.Counter
+
+	GetCanaryDrainsAPI     tally.Counter
+	GetCanaryDrainsSuccess tally.Counter
+	GetCanaryDrainsFail    tally.Counter
+
+	SetMaintenancePolicyAPI     tally.Counter
+	SetMaintenancePolicySuccess tally.Counter
+	SetMaintenancePolicyFail    tally.Counter
+
+	GetMaintenancePoliciesAPI     tally.Counter
+	GetMaintenancePoliciesSuccess tally.Counter
+	GetMaintenancePoliciesFail    tally.Counter
+
+	DeleteMaintenancePolicyAPI     tally.Counter
+	DeleteMaintenancePolicySuccess tally.Counter
+	DeleteMaintenancePolicyFail    tally.Counter
+
+	GetAPIInfoAPI     tally.Counter
+	GetAPIInfoSuccess tally.Counter
+
+	CanaryDrainsStarted   tally.Counter
+	CanaryDrainsProceeded tally.Counter
+	CanaryDrainsAborted   tally.Counter
+
+	DrainTimeoutHosts tally.Counter
+	DrainTimeoutFail  tally.Counter
+
+	ExemptTasks     tally.Counter
+	ExemptTasksFail tally.Counter
 
-	CompleteMaintenanceAPI     tally.Counter
-	CompleteMaintenanceSuccess tally.Counter
-	CompleteMaintenanceFail    tally.Counter
+	MaintenanceFrozen       tally.Gauge
+	PendingMaintenanceHosts tally.Gauge
 
-	QueryHostsAPI     tally.Counter
-	QueryHostsSuccess tally.Counter
-	QueryHostsFail    tally.Counter
+	NotLeader tally.Counter
+
+	// scope of the per procedure metrics, tagged with the procedure
+	procedureScope tally.Scope
+	procedureLock  sync.Mutex
+	procedures     map[string]*ProcedureMetrics
 }
 
 // NewMetrics returns a new instance of host.svc.Metrics
@@ -37,16 +183,153 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	successScope := scope.Tagged(map[string]string{"result": "success"})
 	failScope := scope.Tagged(map[string]string{"result": "fail"})
 	return &Metrics{
-		StartMaintenanceAPI:     apiScope.Counter("start_maintenance"),
-		StartMaintenanceSuccess: successScope.Counter("start_maintenance"),
-		StartMaintenanceFail:    failScope.Counter("start_maintenance"),
+		StartMaintenanceAPI:      apiScope.Counter("start_maintenance"),
+		StartMaintenanceSuccess:  successScope.Counter("start_maintenance"),
+		StartMaintenanceFail:     failScope.Counter("start_maintenance"),
+		StartMaintenanceQueued:   scope.Counter("start_maintenance_queued"),
+		StartMaintenanceDryRun:   scope.Counter("start_maintenance_dry_run"),
+		StartMaintenanceReplayed: scope.Counter("start_maintenance_replayed"),
+
+		AgentDrainHosts:    scope.Counter("agent_drain_hosts"),
+		AgentDrainFallback: scope.Counter("agent_drain_fallback"),
+
+		CompleteMaintenanceAPI:      apiScope.Counter("complete_maintenance"),
+		CompleteMaintenanceSuccess:  successScope.Counter("complete_maintenance"),
+		CompleteMaintenanceFail:     failScope.Counter("complete_maintenance"),
+		CompleteMaintenanceDryRun:   scope.Counter("complete_maintenance_dry_run"),
+		CompleteMaintenanceReplayed: scope.Counter("complete_maintenance_replayed"),
+
+		QueryHostsAPI:       apiScope.Counter("query_hosts"),
+		QueryHostsSuccess:   successScope.Counter("query_hosts"),
+		QueryHostsFail:      failScope.Counter("query_hosts"),
+		QueryHostsProxied:   scope.Counter("query_hosts_proxied"),
+		QueryHostsFederated: scope.Counter("query_hosts_federated"),
+		QueryHostsPeerFail:  scope.Counter("query_hosts_peer_fail"),
+
+		GetMaintenanceDeadLettersAPI: apiScope.Counter("get_maintenance_dead_letters"),
+
+		RedriveMaintenanceDeadLettersAPI:     apiScope.Counter("redrive_maintenance_dead_letters"),
+		RedriveMaintenanceDeadLettersSuccess: successScope.Counter("redrive_maintenance_dead_letters"),
+		RedriveMaintenanceDeadLettersFail:    failScope.Counter("redrive_maintenance_dead_letters"),
+
+		CreateReservationAPI:     apiScope.Counter("create_reservation"),
+		CreateReservationSuccess: successScope.Counter("create_reservation"),
+		CreateReservationFail:    failScope.Counter("create_reservation"),
+
+		ListReservationsAPI:     apiScope.Counter("list_reservations"),
+		ListReservationsSuccess: successScope.Counter("list_reservations"),
+		ListReservationsFail:    failScope.Counter("list_reservations"),
+
+		ReleaseReservationAPI:     apiScope.Counter("release_reservation"),
+		ReleaseReservationSuccess: successScope.Counter("release_reservation"),
+		ReleaseReservationFail:    failScope.Counter("release_reservation"),
+
+		GetMaintenanceHistoryAPI:     apiScope.Counter("get_maintenance_history"),
+		GetMaintenanceHistorySuccess: successScope.Counter("get_maintenance_history"),
+		GetMaintenanceHistoryFail:    failScope.Counter("get_maintenance_history"),
+
+		ArchiveMaintenanceHistoryAPI:     apiScope.Counter("archive_maintenance_history"),
+		ArchiveMaintenanceHistorySuccess: successScope.Counter("archive_maintenance_history"),
+		ArchiveMaintenanceHistoryFail:    failScope.Counter("archive_maintenance_history"),
+
+		SetMaintenanceFreezeAPI: apiScope.Counter("set_maintenance_freeze"),
+		GetMaintenanceFreezeAPI: apiScope.Counter("get_maintenance_freeze"),
+
+		ReleasePendingMaintenanceAPI:     apiScope.Counter("release_pending_maintenance"),
+		ReleasePendingMaintenanceSuccess: successScope.Counter("release_pending_maintenance"),
+		ReleasePendingMaintenanceFail:    failScope.Counter("release_pending_maintenance"),
+
+		GetHostEventsAPI:     apiScope.Counter("get_host_events"),
+		GetHostEventsSuccess: successScope.Counter("get_host_events"),
+		GetHostEventsFail:    failScope.Counter("get_host_events"),
+
+		UpdateMaintenanceAPI:     apiScope.Counter("update_maintenance"),
+		UpdateMaintenanceSuccess: successScope.Counter("update_maintenance"),
+		UpdateMaintenanceFail:    failScope.Counter("update_maintenance"),
+
+		DecommissionHostsAPI:     apiScope.Counter("decommission_hosts"),
+		DecommissionHostsSuccess: successScope.Counter("decommission_hosts"),
+		DecommissionHostsFail:    failScope.Counter("decommission_hosts"),
+
+		HostProviderOperations:    scope.Counter("host_provider_operations"),
+		HostProviderOperationFail: scope.Counter("host_provider_operation_fail"),
+
+		ExportHostInventoryAPI:     apiScope.Counter("export_host_inventory"),
+		ExportHostInventorySuccess: successScope.Counter("export_host_inventory"),
+		ExportHostInventoryFail:    failScope.Counter("export_host_inventory"),
+
+		ImportHostInventoryAPI:     apiScope.Counter("import_host_inventory"),
+		ImportHostInventorySuccess: successScope.Counter("import_host_inventory"),
+		ImportHostInventoryFail:    failScope.Counter("import_host_inventory"),
 
-		CompleteMaintenanceAPI:     apiScope.Counter("complete_maintenance"),
-		CompleteMaintenanceSuccess: successScope.Counter("complete_maintenance"),
-		CompleteMaintenanceFail:    failScope.Counter("complete_maintenance"),
+		ApproveMaintenanceAPI:     apiScope.Counter("approve_maintenance"),
+		ApproveMaintenanceSuccess: successScope.Counter("approve_maintenance"),
+		ApproveMaintenanceFail:    failScope.Counter("approve_maintenance"),
 
-		QueryHostsAPI:     apiScope.Counter("query_hosts"),
-		QueryHostsSuccess: successScope.Counter("query_hosts"),
-		QueryHostsFail:    failScope.Counter("query_hosts"),
+		GetMaintenanceApprovalsAPI:     apiScope.Counter("get_maintenance_approvals"),
+		GetMaintenanceApprovalsSuccess: successScope.Counter("get_maintenance_approvals"),
+		GetMaintenanceApprovalsFail:    failScope.Counter("get_maintenance_approvals"),
+
+		GetCanaryDrainsAPI:     apiScope.Counter("get_canary_drains"),
+		GetCanaryDrainsSuccess: successScope.Counter("get_canary_drains"),
+		GetCanaryDrainsFail:    failScope.Counter("get_canary_drains"),
+
+		SetMaintenancePolicyAPI:     apiScope.Counter("set_maintenance_policy"),
+		SetMaintenancePolicySuccess: successScope.Counter("set_maintenance_policy"),
+		SetMaintenancePolicyFail:    failScope.Counter("set_maintenance_policy"),
+
+		GetMaintenancePoliciesAPI:     apiScope.Counter("get_maintenance_policies"),
+		GetMaintenancePoliciesSuccess: successScope.Counter("get_maintenance_policies"),
+		GetMaintenancePoliciesFail:    failScope.Counter("get_maintenance_policies"),
+
+		DeleteMaintenancePolicyAPI:     apiScope.Counter("delete_maintenance_policy"),
+		DeleteMaintenancePolicySuccess: successScope.Counter("delete_maintenance_policy"),
+		DeleteMaintenancePolicyFail:    failScope.Counter("delete_maintenance_policy"),
+
+		GetAPIInfoAPI:     apiScope.Counter("get_api_info"),
+		GetAPIInfoSuccess: successScope.Counter("get_api_info"),
+
+		CanaryDrainsStarted:   scope.Counter("canary_drains_started"),
+		CanaryDrainsProceeded: scope.Counter("canary_drains_proceeded"),
+		CanaryDrainsAborted:   scope.Counter("canary_drains_aborted"),
+
+		DrainTimeoutHosts: scope.Counter("drain_timeout_hosts"),
+		DrainTimeoutFail:  scope.Counter("drain_timeout_fail"),
+
+		ExemptTasks:     scope.Counter("exempt_tasks"),
+		ExemptTasksFail: scope.Counter("exempt_tasks_fail"),
+
+		MaintenanceFrozen:       scope.Gauge("maintenance_frozen"),
+		PendingMaintenanceHosts: scope.Gauge("pending_maintenance_hosts"),
+
+		NotLeader: scope.Counter("not_leader"),
+
+		procedureScope: scope.SubScope("procedure"),
+		procedures:     make(map[string]*ProcedureMetrics),
+	}
+}
+
+// Procedure returns the metrics of a procedure of the HostService, which
+// are created on its first call
+func (m *Metrics) Procedure(procedure string) *ProcedureMetrics {
+	m.procedureLock.Lock()
+	defer m.procedureLock.Unlock()
+
+	if p, ok := m.procedures[procedure]; ok {
+		return p
+	}
+
+	method := procedure
+	if i := strings.LastIndex(procedure, "::"); i >= 0 {
+		method = procedure[i+2:]
+	}
+	scope := m.procedureScope.Tagged(map[string]string{"procedure": method})
+	p := &ProcedureMetrics{
+		Latency:      scope.Timer("latency"),
+		InFlight:     scope.Gauge("in_flight"),
+		RequestSize:  scope.Histogram("request_bytes", _sizeBuckets),
+		ResponseSize: scope.Histogram("response_bytes", _sizeBuckets),
 	}
+	m.procedures[procedure] = p
+	return p
 }
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/middleware/inbound"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+)
+
+// metricsInboundMiddleware records the latency, in-flight calls and
+// request and response sizes of the procedures of the HostService, so
+// that new procedures get them without changes to the handler
+type metricsInboundMiddleware struct {
+	metrics *Metrics
+}
+
+// NewInboundMiddleware returns the inbound middleware recording the per
+// procedure metrics of the HostService. The calls of other services are
+// let through.
+func NewInboundMiddleware(parent tally.Scope) inbound.DispatcherInboundMiddleWare {
+	return &metricsInboundMiddleware{
+		metrics: NewMetrics(parent.SubScope("hostsvc")),
+	}
+}
+
+func (m *metricsInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if !isHostServiceProcedure(req.Procedure) {
+		return h.Handle(ctx, req, resw)
+	}
+
+	p := m.metrics.Procedure(req.Procedure)
+	p.start()
+	defer p.done()
+
+	body := &countingReader{reader: req.Body}
+	req.Body = body
+	w := &countingResponseWriter{ResponseWriter: resw}
+
+	start := time.Now()
+	err := h.Handle(ctx, req, w)
+	p.Latency.Record(time.Since(start))
+	p.RequestSize.RecordValue(float64(body.count))
+	p.ResponseSize.RecordValue(float64(w.count))
+	return err
+}
+
+func (m *metricsInboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	if !isHostServiceProcedure(req.Procedure) {
+		return h.HandleOneway(ctx, req)
+	}
+
+	p := m.metrics.Procedure(req.Procedure)
+	p.start()
+	defer p.done()
+
+	body := &countingReader{reader: req.Body}
+	req.Body = body
+
+	start := time.Now()
+	err := h.HandleOneway(ctx, req)
+	p.Latency.Record(time.Since(start))
+	p.RequestSize.RecordValue(float64(body.count))
+	return err
+}
+
+func (m *metricsInboundMiddleware) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	procedure := s.Request().Meta.Procedure
+	if !isHostServiceProcedure(procedure) {
+		return h.HandleStream(s)
+	}
+
+	p := m.metrics.Procedure(procedure)
+	p.start()
+	defer p.done()
+
+	start := time.Now()
+	err := h.HandleStream(s)
+	p.Latency.Record(time.Since(start))
+	return err
+}
+
+// isHostServiceProcedure returns whether the procedure belongs to the
+// HostService
+func isHostServiceProcedure(procedure string) bool {
+	return strings.HasPrefix(procedure, ServiceName+"::")
+}
+
+// countingReader counts the bytes read from the request body
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	if r.reader == nil {
+		return 0, io.EOF
+	}
+	n, err := r.reader.Read(b)
+	r.count += n
+	return n, err
+}
+
+// countingResponseWriter counts the bytes written to the response body
+type countingResponseWriter struct {
+	transport.ResponseWriter
+	count int
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.count += n
+	return n, err
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type MetricsInboundMiddlewareTestSuite struct {
+	suite.Suite
+
+	ctrl  *gomock.Controller
+	scope tally.TestScope
+	m     *metricsInboundMiddleware
+}
+
+func (suite *MetricsInboundMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.scope = tally.NewTestScope("", map[string]string{})
+	suite.m = NewInboundMiddleware(suite.scope).(*metricsInboundMiddleware)
+}
+
+func (suite *MetricsInboundMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestMetricsInboundMiddleware(t *testing.T) {
+	suite.Run(t, new(MetricsInboundMiddlewareTestSuite))
+}
+
+// procedureKey returns the key of a metric of a procedure in the snapshot
+func procedureKey(name string, procedure string) string {
+	return "hostsvc.procedure." + name + "+procedure=" + procedure
+}
+
+// TestHandle tests that the latency, in-flight calls and sizes of a
+// HostService call are recorded.
+func (suite *MetricsInboundMiddlewareTestSuite) TestHandle() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request, resw transport.ResponseWriter) {
+			body, err := ioutil.ReadAll(req.Body)
+			suite.NoError(err)
+			suite.Equal("request", string(body))
+			suite.Equal(1.0, suite.scope.Snapshot().Gauges()[procedureKey("in_flight", "StartMaintenance")].Value())
+			resw.Write([]byte("response body"))
+		}).
+		Return(nil)
+
+	req := &transport.Request{
+		Procedure: ServiceName + "::StartMaintenance",
+		Body:      bytes.NewBufferString("request"),
+	}
+	suite.NoError(suite.m.Handle(
+		context.Background(), req, &transporttest.FakeResponseWriter{}, h))
+
+	snapshot := suite.scope.Snapshot()
+	suite.Equal(0.0, snapshot.Gauges()[procedureKey("in_flight", "StartMaintenance")].Value())
+	suite.Len(snapshot.Timers()[procedureKey("latency", "StartMaintenance")].Values(), 1)
+	suite.Equal(int64(1), snapshot.Histograms()[procedureKey("request_bytes", "StartMaintenance")].Values()[64])
+	suite.Equal(int64(1), snapshot.Histograms()[procedureKey("response_bytes", "StartMaintenance")].Values()[64])
+}
+
+// TestHandleError tests that the metrics of failed calls are recorded,
+// and the error returned.
+func (suite *MetricsInboundMiddlewareTestSuite) TestHandleError() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(yarpcerrors.InternalErrorf("fake error"))
+
+	req := &transport.Request{Procedure: ServiceName + "::QueryHosts"}
+	suite.Error(suite.m.Handle(
+		context.Background(), req, &transporttest.FakeResponseWriter{}, h))
+
+	snapshot := suite.scope.Snapshot()
+	suite.Equal(0.0, snapshot.Gauges()[procedureKey("in_flight", "QueryHosts")].Value())
+	suite.Len(snapshot.Timers()[procedureKey("latency", "QueryHosts")].Values(), 1)
+}
+
+// TestHandleOtherService tests that the calls of other services are not
+// measured.
+func (suite *MetricsInboundMiddlewareTestSuite) TestHandleOtherService() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	req := &transport.Request{
+		Procedure: "peloton.private.hostmgr.InternalHostService::AcquireHostOffers",
+	}
+	suite.NoError(suite.m.Handle(
+		context.Background(), req, &transporttest.FakeResponseWriter{}, h))
+	suite.Empty(suite.scope.Snapshot().Timers())
+}
+
+// TestProcedure tests that the metrics of a procedure are created once.
+func (suite *MetricsInboundMiddlewareTestSuite) TestProcedure() {
+	p := suite.m.metrics.Procedure(ServiceName + "::GetHostEvents")
+	suite.True(p == suite.m.metrics.Procedure(ServiceName+"::GetHostEvents"))
+	suite.False(p == suite.m.metrics.Procedure(ServiceName+"::QueryHosts"))
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/reservation"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// _pelotonPrincipal is the principal dynamic reservations are made with
+var _pelotonPrincipal = "peloton"
+
+// CreateReservation dynamically reserves resources on a host for a role
+// through the Mesos Master operator API, and optionally creates a persistent
+// volume on the reserved disk. The reservation is persisted so that it can
+// be listed and released later on.
+func (m *serviceHandler) CreateReservation(
+	ctx context.Context,
+	request *host_svc.CreateReservationRequest,
+) (*host_svc.CreateReservationResponse, error) {
+	m.metrics.CreateReservationAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.CreateReservationFail.Inc(1)
+		return nil, err
+	}
+
+	if err := validateReservationRequest(request); err != nil {
+		m.metrics.CreateReservationFail.Inc(1)
+		return nil, err
+	}
+
+	agentID, err := getAgentID(request.GetHostname())
+	if err != nil {
+		m.metrics.CreateReservationFail.Inc(1)
+		return nil, err
+	}
+
+	r := &hpb.Reservation{
+		ReservationId: uuid.New(),
+		Hostname:      request.GetHostname(),
+		Role:          request.GetRole(),
+		Spec:          request.GetSpec(),
+		JobId:         request.GetJobId(),
+		InstanceId:    request.GetInstanceId(),
+		CreationTime:  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if request.GetSpec().GetVolumeContainerPath() != "" {
+		r.VolumeId = uuid.New()
+	}
+
+	resources, volume := buildReservedResources(r)
+	if err := m.operatorMasterClient.ReserveResources(
+		agentID, resources); err != nil {
+		m.metrics.CreateReservationFail.Inc(1)
+		return nil, err
+	}
+
+	if volume != nil {
+		if err := m.operatorMasterClient.CreateVolumes(
+			agentID, []*mesos.Resource{volume}); err != nil {
+			m.rollbackReservation(agentID, r, resources, nil)
+			m.metrics.CreateReservationFail.Inc(1)
+			return nil, err
+		}
+	}
+
+	if err := m.reservationOps.Create(ctx, r); err != nil {
+		m.rollbackReservation(agentID, r, resources, volume)
+		m.metrics.CreateReservationFail.Inc(1)
+		return nil, err
+	}
+
+	log.WithField("reservation", r).Info("Dynamic reservation created")
+	m.metrics.CreateReservationSuccess.Inc(1)
+	return &host_svc.CreateReservationResponse{
+		Reservation: r,
+	}, nil
+}
+
+// ListReservations returns the dynamic reservations made on the requested
+// hosts, or on all registered hosts if none are specified.
+func (m *serviceHandler) ListReservations(
+	ctx context.Context,
+	request *host_svc.ListReservationsRequest,
+) (*host_svc.ListReservationsResponse, error) {
+	m.metrics.ListReservationsAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ListReservationsFail.Inc(1)
+		return nil, err
+	}
+
+	hostnames := request.GetHostnames()
+	if len(hostnames) == 0 {
+		if agentMap := host.GetAgentMap(); agentMap != nil {
+			for hostname := range agentMap.RegisteredAgents {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+	}
+	sort.Strings(hostnames)
+
+	var reservations []*hpb.Reservation
+	for _, hostname := range hostnames {
+		hostReservations, err := m.reservationOps.GetAll(ctx, hostname)
+		if err != nil {
+			m.metrics.ListReservationsFail.Inc(1)
+			return nil, err
+		}
+		sort.Slice(hostReservations, func(i, j int) bool {
+			return hostReservations[i].GetReservationId() <
+				hostReservations[j].GetReservationId()
+		})
+
+		for _, r := range hostReservations {
+			if request.GetRole() != "" && r.GetRole() != request.GetRole() {
+				continue
+			}
+			reservations = append(reservations, r)
+		}
+	}
+
+	m.metrics.ListReservationsSuccess.Inc(1)
+	return &host_svc.ListReservationsResponse{
+		Reservations: reservations,
+	}, nil
+}
+
+// ReleaseReservation destroys the persistent volume, if any, and unreserves
+// the resources of a dynamic reservation before removing it.
+func (m *serviceHandler) ReleaseReservation(
+	ctx context.Context,
+	request *host_svc.ReleaseReservationRequest,
+) (*host_svc.ReleaseReservationResponse, error) {
+	m.metrics.ReleaseReservationAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.ReleaseReservationFail.Inc(1)
+		return nil, err
+	}
+
+	r, err := m.reservationOps.Get(
+		ctx, request.GetHostname(), request.GetReservationId())
+	if err != nil {
+		m.metrics.ReleaseReservationFail.Inc(1)
+		return nil, err
+	}
+
+	agentID, err := getAgentID(r.GetHostname())
+	if err != nil {
+		m.metrics.ReleaseReservationFail.Inc(1)
+		return nil, err
+	}
+
+	resources, volume := buildReservedResources(r)
+	if volume != nil {
+		if err := m.operatorMasterClient.DestroyVolumes(
+			agentID, []*mesos.Resource{volume}); err != nil {
+			m.metrics.ReleaseReservationFail.Inc(1)
+			return nil, err
+		}
+	}
+
+	if err := m.operatorMasterClient.UnreserveResources(
+		agentID, resources); err != nil {
+		m.metrics.ReleaseReservationFail.Inc(1)
+		return nil, err
+	}
+
+	if err := m.reservationOps.Delete(
+		ctx, r.GetHostname(), r.GetReservationId()); err != nil {
+		m.metrics.ReleaseReservationFail.Inc(1)
+		return nil, err
+	}
+
+	log.WithField("reservation", r).Info("Dynamic reservation released")
+	m.metrics.ReleaseReservationSuccess.Inc(1)
+	return &host_svc.ReleaseReservationResponse{}, nil
+}
+
+// rollbackReservation makes a best effort to release the Mesos reservation
+// of a reservation which could not be created completely.
+func (m *serviceHandler) rollbackReservation(
+	agentID *mesos.AgentID,
+	r *hpb.Reservation,
+	resources []*mesos.Resource,
+	volume *mesos.Resource) {
+	if volume != nil {
+		if err := m.operatorMasterClient.DestroyVolumes(
+			agentID, []*mesos.Resource{volume}); err != nil {
+			log.WithError(err).
+				WithField("reservation", r).
+				Error("Failed to destroy volume of reservation")
+			return
+		}
+	}
+	if err := m.operatorMasterClient.UnreserveResources(
+		agentID, resources); err != nil {
+		log.WithError(err).
+			WithField("reservation", r).
+			Error("Failed to unreserve resources of reservation")
+	}
+}
+
+// validateReservationRequest validates a CreateReservation request.
+func validateReservationRequest(
+	request *host_svc.CreateReservationRequest) error {
+	spec := request.GetSpec()
+	switch {
+	case request.GetHostname() == "":
+		return yarpcerrors.InvalidArgumentErrorf("hostname is required")
+	case request.GetRole() == "" || request.GetRole() == "*":
+		return yarpcerrors.InvalidArgumentErrorf("a reservation role is required")
+	case spec.GetCpus() < 0 || spec.GetMemMb() < 0 ||
+		spec.GetDiskMb() < 0 || spec.GetGpus() < 0:
+		return yarpcerrors.InvalidArgumentErrorf(
+			"reserved resources cannot be negative")
+	case spec.GetCpus() == 0 && spec.GetMemMb() == 0 &&
+		spec.GetDiskMb() == 0 && spec.GetGpus() == 0:
+		return yarpcerrors.InvalidArgumentErrorf("no resources to reserve")
+	case spec.GetVolumeContainerPath() != "" && spec.GetDiskMb() == 0:
+		return yarpcerrors.InvalidArgumentErrorf(
+			"a persistent volume requires reserved disk")
+	}
+	return nil
+}
+
+// getAgentID returns the ID of the agent registered on a host.
+func getAgentID(hostname string) (*mesos.AgentID, error) {
+	agentMap := host.GetAgentMap()
+	if agentMap == nil {
+		return nil, newNoRegisteredAgentsError()
+	}
+	agent, ok := agentMap.RegisteredAgents[hostname]
+	if !ok || agent.GetAgentInfo().GetId() == nil {
+		return nil, newUnknownHostError(hostname)
+	}
+	return agent.GetAgentInfo().GetId(), nil
+}
+
+// buildReservedResources returns the Mesos resources of a reservation, as
+// well as the persistent volume resource to create on the reserved disk,
+// if any. The resources are rebuilt the same way for every operation, as
+// Mesos requires the resources to match exactly with their reservation.
+func buildReservedResources(
+	r *hpb.Reservation) ([]*mesos.Resource, *mesos.Resource) {
+	labels := reservation.CreateHostReservationLabels(r.GetReservationId())
+	if r.GetJobId() != "" {
+		// Reservations bound to a stateful task use the same labels as the
+		// ones made when launching the task, so that the task can claim it.
+		labels = reservation.CreateReservationLabels(
+			r.GetJobId(), r.GetInstanceId(), r.GetHostname())
+	}
+	reservationInfo := &mesos.Resource_ReservationInfo{
+		Principal: &_pelotonPrincipal,
+		Labels:    labels,
+	}
+
+	var resources []*mesos.Resource
+	for _, res := range []struct {
+		name  string
+		value float64
+	}{
+		{common.MesosCPU, r.GetSpec().GetCpus()},
+		{common.MesosMem, r.GetSpec().GetMemMb()},
+		{common.MesosDisk, r.GetSpec().GetDiskMb()},
+		{common.MesosGPU, r.GetSpec().GetGpus()},
+	} {
+		if res.value <= 0 {
+			continue
+		}
+		resources = append(resources, util.NewMesosResourceBuilder().
+			WithName(res.name).
+			WithValue(res.value).
+			WithRole(r.GetRole()).
+			WithReservation(reservationInfo).
+			Build())
+	}
+
+	if r.GetVolumeId() == "" {
+		return resources, nil
+	}
+
+	volumeID := r.GetVolumeId()
+	containerPath := r.GetSpec().GetVolumeContainerPath()
+	volumeRWMode := mesos.Volume_RW
+	volume := util.NewMesosResourceBuilder().
+		WithName(common.MesosDisk).
+		WithValue(r.GetSpec().GetDiskMb()).
+		WithRole(r.GetRole()).
+		WithReservation(reservationInfo).
+		WithDisk(&mesos.Resource_DiskInfo{
+			Persistence: &mesos.Resource_DiskInfo_Persistence{
+				Id:        &volumeID,
+				Principal: &_pelotonPrincipal,
+			},
+			Volume: &mesos.Volume{
+				ContainerPath: &containerPath,
+				Mode:          &volumeRWMode,
+			},
+		}).
+		Build()
+	return resources, volume
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"errors"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/reservation"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/golang/mock/gomock"
+)
+
+// setAgentID sets the ID of the agent registered on the host.
+func (suite *HostSvcHandlerTestSuite) setAgentID(
+	hostname string, agentID string) *mesos.AgentID {
+	id := &mesos.AgentID{Value: &agentID}
+	host.GetAgentMap().RegisteredAgents[hostname].AgentInfo.Id = id
+	return id
+}
+
+func (suite *HostSvcHandlerTestSuite) TestCreateReservation() {
+	hostname := suite.upMachines[0].GetHostname()
+	agentID := suite.setAgentID(hostname, "agent-1")
+
+	request := &svc.CreateReservationRequest{
+		Hostname: hostname,
+		Role:     "peloton",
+		Spec: &hpb.ReservationSpec{
+			Cpus:                2.0,
+			MemMb:               1024.0,
+			DiskMb:              4096.0,
+			VolumeContainerPath: "/data",
+		},
+		JobId:      "job-1",
+		InstanceId: 1,
+	}
+
+	var created *hpb.Reservation
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			ReserveResources(agentID, gomock.Any()).
+			Do(func(_ *mesos.AgentID, resources []*mesos.Resource) {
+				suite.Len(resources, 3)
+				for _, res := range resources {
+					suite.Equal("peloton", res.GetRole())
+					suite.Nil(res.GetDisk())
+					suite.Equal(
+						reservation.CreateReservationLabels("job-1", 1, hostname),
+						res.GetReservation().GetLabels())
+				}
+			}).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			CreateVolumes(agentID, gomock.Any()).
+			Do(func(_ *mesos.AgentID, volumes []*mesos.Resource) {
+				suite.Len(volumes, 1)
+				suite.Equal(common.MesosDisk, volumes[0].GetName())
+				suite.Equal(4096.0, volumes[0].GetScalar().GetValue())
+				suite.Equal("/data",
+					volumes[0].GetDisk().GetVolume().GetContainerPath())
+				suite.NotEmpty(volumes[0].GetDisk().GetPersistence().GetId())
+			}).
+			Return(nil),
+		suite.mockReservationOps.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Do(func(_ interface{}, r *hpb.Reservation) {
+				created = r
+			}).
+			Return(nil),
+	)
+
+	response, err := suite.handler.CreateReservation(suite.ctx, request)
+	suite.NoError(err)
+	suite.Equal(created, response.GetReservation())
+	suite.NotEmpty(response.GetReservation().GetReservationId())
+	suite.NotEmpty(response.GetReservation().GetVolumeId())
+	suite.Equal(hostname, response.GetReservation().GetHostname())
+	suite.Equal(request.GetSpec(), response.GetReservation().GetSpec())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestCreateReservationWithoutVolume() {
+	hostname := suite.upMachines[0].GetHostname()
+	agentID := suite.setAgentID(hostname, "agent-1")
+
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			ReserveResources(agentID, gomock.Any()).
+			Do(func(_ *mesos.AgentID, resources []*mesos.Resource) {
+				suite.Len(resources, 1)
+				suite.Equal(common.MesosCPU, resources[0].GetName())
+				_, _, err := reservation.ParseReservationLabels(
+					resources[0].GetReservation().GetLabels())
+				suite.Error(err)
+			}).
+			Return(nil),
+		suite.mockReservationOps.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(nil),
+	)
+
+	response, err := suite.handler.CreateReservation(
+		suite.ctx,
+		&svc.CreateReservationRequest{
+			Hostname: hostname,
+			Role:     "peloton",
+			Spec:     &hpb.ReservationSpec{Cpus: 1.0},
+		})
+	suite.NoError(err)
+	suite.Empty(response.GetReservation().GetVolumeId())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestCreateReservationInvalidRequest() {
+	hostname := suite.upMachines[0].GetHostname()
+	suite.setAgentID(hostname, "agent-1")
+
+	requests := []*svc.CreateReservationRequest{
+		{
+			Role: "peloton",
+			Spec: &hpb.ReservationSpec{Cpus: 1.0},
+		},
+		{
+			Hostname: hostname,
+			Spec:     &hpb.ReservationSpec{Cpus: 1.0},
+		},
+		{
+			Hostname: hostname,
+			Role:     "*",
+			Spec:     &hpb.ReservationSpec{Cpus: 1.0},
+		},
+		{
+			Hostname: hostname,
+			Role:     "peloton",
+		},
+		{
+			Hostname: hostname,
+			Role:     "peloton",
+			Spec:     &hpb.ReservationSpec{Cpus: 1.0, MemMb: -1.0},
+		},
+		{
+			Hostname: hostname,
+			Role:     "peloton",
+			Spec: &hpb.ReservationSpec{
+				Cpus:                1.0,
+				VolumeContainerPath: "/data",
+			},
+		},
+		{
+			Hostname: "unknown",
+			Role:     "peloton",
+			Spec:     &hpb.ReservationSpec{Cpus: 1.0},
+		},
+	}
+
+	for _, request := range requests {
+		_, err := suite.handler.CreateReservation(suite.ctx, request)
+		suite.Error(err, request.String())
+	}
+}
+
+func (suite *HostSvcHandlerTestSuite) TestCreateReservationRollback() {
+	hostname := suite.upMachines[0].GetHostname()
+	agentID := suite.setAgentID(hostname, "agent-1")
+
+	request := &svc.CreateReservationRequest{
+		Hostname: hostname,
+		Role:     "peloton",
+		Spec: &hpb.ReservationSpec{
+			DiskMb:              1024.0,
+			VolumeContainerPath: "/data",
+		},
+	}
+
+	// Failure to reserve the resources
+	suite.mockMasterOperatorClient.EXPECT().
+		ReserveResources(agentID, gomock.Any()).
+		Return(errors.New("reserve failed"))
+	_, err := suite.handler.CreateReservation(suite.ctx, request)
+	suite.EqualError(err, "reserve failed")
+
+	// Failure to create the volume unreserves the resources
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			ReserveResources(agentID, gomock.Any()).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			CreateVolumes(agentID, gomock.Any()).
+			Return(errors.New("create volumes failed")),
+		suite.mockMasterOperatorClient.EXPECT().
+			UnreserveResources(agentID, gomock.Any()).
+			Return(nil),
+	)
+	_, err = suite.handler.CreateReservation(suite.ctx, request)
+	suite.EqualError(err, "create volumes failed")
+
+	// Failure to persist the reservation destroys the volume and
+	// unreserves the resources
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			ReserveResources(agentID, gomock.Any()).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			CreateVolumes(agentID, gomock.Any()).
+			Return(nil),
+		suite.mockReservationOps.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(errors.New("create failed")),
+		suite.mockMasterOperatorClient.EXPECT().
+			DestroyVolumes(agentID, gomock.Any()).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UnreserveResources(agentID, gomock.Any()).
+			Return(nil),
+	)
+	_, err = suite.handler.CreateReservation(suite.ctx, request)
+	suite.EqualError(err, "create failed")
+}
+
+func (suite *HostSvcHandlerTestSuite) TestListReservations() {
+	host1 := suite.upMachines[0].GetHostname()
+	host3 := suite.drainingMachines[0].GetHostname()
+
+	host1Reservations := []*hpb.Reservation{
+		{ReservationId: "b", Hostname: host1, Role: "peloton"},
+		{ReservationId: "a", Hostname: host1, Role: "other"},
+	}
+	host3Reservations := []*hpb.Reservation{
+		{ReservationId: "c", Hostname: host3, Role: "peloton"},
+	}
+
+	// All registered hosts are listed if no host is specified
+	suite.mockReservationOps.EXPECT().
+		GetAll(gomock.Any(), host1).
+		Return(host1Reservations, nil)
+	suite.mockReservationOps.EXPECT().
+		GetAll(gomock.Any(), host3).
+		Return(host3Reservations, nil)
+
+	response, err := suite.handler.ListReservations(
+		suite.ctx, &svc.ListReservationsRequest{})
+	suite.NoError(err)
+	suite.Equal([]*hpb.Reservation{
+		host1Reservations[1],
+		host1Reservations[0],
+		host3Reservations[0],
+	}, response.GetReservations())
+
+	// Filter on role
+	suite.mockReservationOps.EXPECT().
+		GetAll(gomock.Any(), host1).
+		Return(host1Reservations, nil)
+
+	response, err = suite.handler.ListReservations(
+		suite.ctx,
+		&svc.ListReservationsRequest{
+			Hostnames: []string{host1},
+			Role:      "peloton",
+		})
+	suite.NoError(err)
+	suite.Equal(
+		[]*hpb.Reservation{host1Reservations[0]},
+		response.GetReservations())
+
+	// Storage error
+	suite.mockReservationOps.EXPECT().
+		GetAll(gomock.Any(), host1).
+		Return(nil, errors.New("get all failed"))
+
+	_, err = suite.handler.ListReservations(
+		suite.ctx,
+		&svc.ListReservationsRequest{Hostnames: []string{host1}})
+	suite.EqualError(err, "get all failed")
+}
+
+func (suite *HostSvcHandlerTestSuite) TestReleaseReservation() {
+	hostname := suite.upMachines[0].GetHostname()
+	agentID := suite.setAgentID(hostname, "agent-1")
+
+	r := &hpb.Reservation{
+		ReservationId: "reservation-1",
+		Hostname:      hostname,
+		Role:          "peloton",
+		Spec: &hpb.ReservationSpec{
+			Cpus:                1.0,
+			DiskMb:              1024.0,
+			VolumeContainerPath: "/data",
+		},
+		VolumeId: "volume-1",
+	}
+	resources, volume := buildReservedResources(r)
+	request := &svc.ReleaseReservationRequest{
+		Hostname:      hostname,
+		ReservationId: r.GetReservationId(),
+	}
+
+	gomock.InOrder(
+		suite.mockReservationOps.EXPECT().
+			Get(gomock.Any(), hostname, r.GetReservationId()).
+			Return(r, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			DestroyVolumes(agentID, []*mesos.Resource{volume}).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UnreserveResources(agentID, resources).
+			Return(nil),
+		suite.mockReservationOps.EXPECT().
+			Delete(gomock.Any(), hostname, r.GetReservationId()).
+			Return(nil),
+	)
+	_, err := suite.handler.ReleaseReservation(suite.ctx, request)
+	suite.NoError(err)
+
+	// Reservation not found
+	suite.mockReservationOps.EXPECT().
+		Get(gomock.Any(), hostname, r.GetReservationId()).
+		Return(nil, errors.New("not found"))
+	_, err = suite.handler.ReleaseReservation(suite.ctx, request)
+	suite.EqualError(err, "not found")
+
+	// Failure to unreserve keeps the reservation
+	gomock.InOrder(
+		suite.mockReservationOps.EXPECT().
+			Get(gomock.Any(), hostname, r.GetReservationId()).
+			Return(r, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			DestroyVolumes(agentID, gomock.Any()).
+			Return(nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UnreserveResources(agentID, gomock.Any()).
+			Return(errors.New("unreserve failed")),
+	)
+	_, err = suite.handler.ReleaseReservation(suite.ctx, request)
+	suite.EqualError(err, "unreserve failed")
+}
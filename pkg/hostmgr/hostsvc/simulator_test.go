@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+	"github.com/uber/peloton/pkg/hostmgr/simulator"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _simulatedHosts = 10000
+
+// SimulatedClusterTestSuite tests the host service against a simulated
+// Mesos cluster.
+type SimulatedClusterTestSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	ctrl    *gomock.Controller
+	cluster *simulator.Cluster
+	loader  *host.Loader
+	handler *serviceHandler
+}
+
+func (suite *SimulatedClusterTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.cluster = simulator.NewCluster(simulator.Config{
+		NumHosts: _simulatedHosts,
+		CPU:      32,
+		MemMb:    128 * 1024,
+		DiskMb:   1024 * 1024,
+		Seed:     1,
+	})
+	maintenanceHostInfoMap := host.NewMaintenanceHostInfoMap(tally.NoopScope)
+	suite.loader = &host.Loader{
+		OperatorClient:         suite.cluster,
+		Scope:                  tally.NoopScope,
+		MaintenanceHostInfoMap: maintenanceHostInfoMap,
+	}
+	candidate := leadermocks.NewMockCandidate(suite.ctrl)
+	candidate.EXPECT().IsLeader().Return(true).AnyTimes()
+	suite.handler = &serviceHandler{
+		maintenanceQueue:       queue.NewMaintenanceQueue(0),
+		metrics:                NewMetrics(tally.NoopScope),
+		operatorMasterClient:   suite.cluster,
+		maintenanceHostInfoMap: maintenanceHostInfoMap,
+		eventBus:               eventbus.NewBus(tally.NoopScope),
+		pidCache:               util.NewAgentPIDCache(tally.NoopScope),
+		candidate:              candidate,
+		maintenanceFreeze:      &maintenanceFreeze{},
+	}
+	suite.loader.Load(nil)
+}
+
+func (suite *SimulatedClusterTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestSimulatedCluster(t *testing.T) {
+	suite.Run(t, new(SimulatedClusterTestSuite))
+}
+
+func (suite *SimulatedClusterTestSuite) queryHosts(state hpb.HostState) []*hpb.HostInfo {
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{state},
+	})
+	suite.NoError(err)
+	return resp.GetHostInfos()
+}
+
+// TestMaintenanceLifecycle tests moving hosts of a simulated cluster
+// through the maintenance states.
+func (suite *SimulatedClusterTestSuite) TestMaintenanceLifecycle() {
+	hostnames := suite.cluster.Hostnames()[:500]
+
+	_, err := suite.handler.StartMaintenance(
+		suite.ctx,
+		&svcpb.StartMaintenanceRequest{Hostnames: hostnames})
+	suite.NoError(err)
+	suite.Len(suite.queryHosts(hpb.HostState_HOST_STATE_UP), _simulatedHosts-len(hostnames))
+	suite.Len(suite.queryHosts(hpb.HostState_HOST_STATE_DRAINING), len(hostnames))
+	suite.Equal(len(hostnames), suite.handler.maintenanceQueue.Length())
+	suite.ElementsMatch(hostnames, suite.handler.maintenanceQueue.Hosts())
+
+	status, err := suite.cluster.GetMaintenanceStatus()
+	suite.NoError(err)
+	suite.Len(status.GetStatus().GetDrainingMachines(), len(hostnames))
+
+	// Simulate the hosts being drained and put into maintenance.
+	for _, drainingMachine := range status.GetStatus().GetDrainingMachines() {
+		machineID := drainingMachine.GetId()
+		suite.NoError(suite.cluster.StartMaintenance(
+			suite.ctx,
+			[]*mesos.MachineID{machineID}))
+		suite.NoError(suite.handler.maintenanceHostInfoMap.UpdateHostState(
+			machineID.GetHostname(),
+			hpb.HostState_HOST_STATE_DRAINING,
+			hpb.HostState_HOST_STATE_DOWN))
+	}
+	suite.loader.Load(nil)
+	suite.Len(host.GetAgentMap().RegisteredAgents, _simulatedHosts-len(hostnames))
+	suite.Len(suite.queryHosts(hpb.HostState_HOST_STATE_DOWN), len(hostnames))
+
+	_, err = suite.handler.CompleteMaintenance(
+		suite.ctx,
+		&svcpb.CompleteMaintenanceRequest{Hostnames: hostnames})
+	suite.NoError(err)
+	suite.loader.Load(nil)
+	suite.Len(suite.queryHosts(hpb.HostState_HOST_STATE_UP), _simulatedHosts)
+	suite.Len(suite.queryHosts(hpb.HostState_HOST_STATE_DOWN), 0)
+}
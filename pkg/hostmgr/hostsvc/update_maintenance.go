@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"context"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/logging"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// UpdateMaintenance changes the start and duration of the maintenance
+// window of DRAINING hosts in the maintenance schedule of Mesos Master.
+// Hosts whose window starts in the future are not drained until it
+// starts, and hosts whose window has started are drained right away.
+func (m *serviceHandler) UpdateMaintenance(
+	ctx context.Context,
+	request *host_svc.UpdateMaintenanceRequest,
+) (*host_svc.UpdateMaintenanceResponse, error) {
+	m.metrics.UpdateMaintenanceAPI.Inc(1)
+
+	if err := m.checkLeader(); err != nil {
+		m.metrics.UpdateMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	var start time.Time
+	if request.GetStartTime() != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, request.GetStartTime())
+		if err != nil {
+			m.metrics.UpdateMaintenanceFail.Inc(1)
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid start time %q: %v", request.GetStartTime(), err)
+		}
+	}
+
+	drainingHostInfos := m.maintenanceHostInfoMap.GetDrainingHostInfos(
+		[]string{})
+	hostnames, mappings, err := m.resolveHostnames(
+		request.GetHostnames(),
+		drainingHostInfos)
+	if err != nil {
+		m.metrics.UpdateMaintenanceFail.Inc(1)
+		return nil, err
+	}
+	if len(hostnames) == 0 {
+		m.metrics.UpdateMaintenanceFail.Inc(1)
+		return nil, yarpcerrors.InvalidArgumentErrorf("no hosts specified")
+	}
+	ctx = logging.WithHosts(ctx, hostnames)
+
+	draining := make(map[string]bool)
+	for _, hostInfo := range drainingHostInfos {
+		// The maintenance schedule does not apply to hosts whose
+		// agents are drained by Mesos Master
+		if !isAgentDrain(hostInfo) {
+			draining[hostInfo.GetHostname()] = true
+		}
+	}
+	for _, hostname := range hostnames {
+		if !draining[hostname] {
+			m.metrics.UpdateMaintenanceFail.Inc(1)
+			return nil, newHostStateError(hostname,
+				"is not draining with a maintenance window")
+		}
+	}
+
+	duration := time.Duration(request.GetDurationSeconds()) * time.Second
+	if err := m.updateMaintenanceWindows(
+		ctx,
+		hostnames,
+		start,
+		duration); err != nil {
+		m.metrics.UpdateMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	if !start.IsZero() {
+		// Keep the hosts from being drained before their window starts,
+		// or drain them right away if it has started
+		if err := m.maintenanceQueue.Defer(hostnames, start); err != nil {
+			m.metrics.UpdateMaintenanceFail.Inc(1)
+			return nil, newInternalError(err, "failed to enqueue hosts")
+		}
+	}
+
+	logging.FromContext(ctx).
+		WithField("start_time", request.GetStartTime()).
+		WithField("duration", duration).
+		Info("Maintenance window updated")
+	m.metrics.UpdateMaintenanceSuccess.Inc(1)
+	return &host_svc.UpdateMaintenanceResponse{
+		HostnameMappings: mappings,
+	}, nil
+}
+
+// updateMaintenanceWindows moves the hosts out of their maintenance
+// windows into windows with the new unavailability, and posts the
+// schedule to Mesos Master. Hosts keep the start of their current window
+// if start is zero. Windows left without hosts are removed.
+func (m *serviceHandler) updateMaintenanceWindows(
+	ctx context.Context,
+	hostnames []string,
+	start time.Time,
+	duration time.Duration) error {
+	updated := make(map[string]bool)
+	for _, hostname := range hostnames {
+		updated[hostname] = true
+	}
+
+	m.scheduleLock.Lock()
+	defer m.scheduleLock.Unlock()
+
+	response, err := m.getMaintenanceSchedule(ctx)
+	if err != nil {
+		return newMasterError(err, "failed to get maintenance schedule")
+	}
+
+	// Windows of the updated hosts by the start of their unavailability
+	var starts []int64
+	windows := make(map[int64]*mesos_maintenance.Window)
+	found := make(map[string]bool)
+	schedule := &mesos_maintenance.Schedule{}
+	for _, window := range response.GetSchedule().GetWindows() {
+		var kept []*mesos.MachineID
+		for _, machineID := range window.GetMachineIds() {
+			if !updated[machineID.GetHostname()] {
+				kept = append(kept, machineID)
+				continue
+			}
+			found[machineID.GetHostname()] = true
+
+			nanos := window.GetUnavailability().GetStart().GetNanoseconds()
+			if !start.IsZero() {
+				nanos = start.UnixNano()
+			}
+			w, ok := windows[nanos]
+			if !ok {
+				w = newMaintenanceWindow(nanos, duration)
+				windows[nanos] = w
+				starts = append(starts, nanos)
+			}
+			w.MachineIds = append(w.MachineIds, machineID)
+		}
+		if len(kept) > 0 {
+			schedule.Windows = append(schedule.Windows,
+				&mesos_maintenance.Window{
+					MachineIds:     kept,
+					Unavailability: window.GetUnavailability(),
+				})
+		}
+	}
+	for _, hostname := range hostnames {
+		if !found[hostname] {
+			return newHostStateError(hostname,
+				"is not in the maintenance schedule")
+		}
+	}
+	for _, nanos := range starts {
+		schedule.Windows = append(schedule.Windows, windows[nanos])
+	}
+
+	if err := m.operatorMasterClient.UpdateMaintenanceSchedule(
+		ctx,
+		schedule); err != nil {
+		return newMasterError(err, "failed to update maintenance schedule")
+	}
+	audit.Logger(ctx).WithField("maintenance_schedule", schedule).
+		Info("Maintenance Schedule posted to Mesos Master")
+	return nil
+}
+
+// newMaintenanceWindow returns a maintenance window without machines,
+// whose unavailability starts at nanos and lasts for duration, or
+// forever if duration is 0.
+func newMaintenanceWindow(
+	nanos int64,
+	duration time.Duration) *mesos_maintenance.Window {
+	unavailability := &mesos.Unavailability{
+		Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+	}
+	if duration > 0 {
+		durationNanos := duration.Nanoseconds()
+		unavailability.Duration = &mesos.DurationInfo{
+			Nanoseconds: &durationNanos,
+		}
+	}
+	return &mesos_maintenance.Window{Unavailability: unavailability}
+}
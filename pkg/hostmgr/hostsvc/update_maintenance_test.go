@@ -0,0 +1,273 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostsvc
+
+import (
+	"fmt"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	svcpb "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// expectMaintenanceWindows sets the expectations of a draining host in
+// a maintenance window, shared with another host, which started at
+// scheduled.
+func (suite *HostSvcHandlerTestSuite) expectMaintenanceWindows(
+	machine *mesos.MachineID,
+	other *mesos.MachineID,
+	scheduled int64) {
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				Ip:       machine.GetIp(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+			},
+		})
+	suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+		Return(&mesosmaster.Response_GetMaintenanceSchedule{
+			Schedule: &mesosmaintenance.Schedule{
+				Windows: []*mesosmaintenance.Window{
+					{
+						MachineIds: []*mesos.MachineID{machine, other},
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &scheduled},
+						},
+					},
+				},
+			},
+		}, nil)
+}
+
+// TestUpdateMaintenancePostpone tests postponing the maintenance window
+// of a draining host
+func (suite *HostSvcHandlerTestSuite) TestUpdateMaintenancePostpone() {
+	machine := suite.drainingMachines[0]
+	other := suite.upMachines[0]
+	scheduled := time.Now().Add(-time.Hour).UnixNano()
+	start := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	startNanos := start.UnixNano()
+	durationNanos := time.Hour.Nanoseconds()
+
+	suite.expectMaintenanceWindows(machine, other, scheduled)
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), &mesosmaintenance.Schedule{
+				Windows: []*mesosmaintenance.Window{
+					{
+						MachineIds: []*mesos.MachineID{other},
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &scheduled},
+						},
+					},
+					{
+						MachineIds: []*mesos.MachineID{machine},
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &startNanos},
+							Duration: &mesos.DurationInfo{
+								Nanoseconds: &durationNanos,
+							},
+						},
+					},
+				},
+			}).
+			Return(nil),
+		suite.mockMaintenanceQueue.EXPECT().
+			Defer([]string{machine.GetHostname()}, start).
+			Return(nil),
+	)
+
+	_, err := suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{
+			Hostnames:       []string{machine.GetHostname()},
+			StartTime:       start.Format(time.RFC3339),
+			DurationSeconds: 3600,
+		})
+	suite.NoError(err)
+}
+
+// TestUpdateMaintenanceStarted tests that hosts whose updated window has
+// started are drained right away
+func (suite *HostSvcHandlerTestSuite) TestUpdateMaintenanceStarted() {
+	machine := suite.drainingMachines[0]
+	other := suite.upMachines[0]
+	scheduled := time.Now().Add(time.Hour).UnixNano()
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+	hosts := []string{machine.GetHostname()}
+
+	suite.expectMaintenanceWindows(machine, other, scheduled)
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).
+			Return(nil),
+		suite.mockMaintenanceQueue.EXPECT().Defer(hosts, start).Return(nil),
+	)
+
+	_, err := suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{
+			Hostnames: hosts,
+			StartTime: start.Format(time.RFC3339),
+		})
+	suite.NoError(err)
+}
+
+// TestUpdateMaintenanceDeferError tests that a failure to enqueue the
+// hosts of the updated window is returned
+func (suite *HostSvcHandlerTestSuite) TestUpdateMaintenanceDeferError() {
+	machine := suite.drainingMachines[0]
+	other := suite.upMachines[0]
+	scheduled := time.Now().Add(time.Hour).UnixNano()
+	start := time.Now().Add(time.Minute).Truncate(time.Second)
+	hosts := []string{machine.GetHostname()}
+
+	suite.expectMaintenanceWindows(machine, other, scheduled)
+	gomock.InOrder(
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).
+			Return(nil),
+		suite.mockMaintenanceQueue.EXPECT().
+			Defer(hosts, start).
+			Return(fmt.Errorf("fake defer error")),
+	)
+
+	_, err := suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{
+			Hostnames: hosts,
+			StartTime: start.Format(time.RFC3339),
+		})
+	suite.True(yarpcerrors.IsInternal(err))
+}
+
+// TestUpdateMaintenanceDuration tests that hosts keep the start of their
+// window if no start time is requested
+func (suite *HostSvcHandlerTestSuite) TestUpdateMaintenanceDuration() {
+	machine := suite.drainingMachines[0]
+	other := suite.upMachines[0]
+	scheduled := time.Now().Add(-time.Hour).UnixNano()
+	durationNanos := (2 * time.Hour).Nanoseconds()
+
+	suite.expectMaintenanceWindows(machine, other, scheduled)
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(gomock.Any(), &mesosmaintenance.Schedule{
+			Windows: []*mesosmaintenance.Window{
+				{
+					MachineIds: []*mesos.MachineID{other},
+					Unavailability: &mesos.Unavailability{
+						Start: &mesos.TimeInfo{Nanoseconds: &scheduled},
+					},
+				},
+				{
+					MachineIds: []*mesos.MachineID{machine},
+					Unavailability: &mesos.Unavailability{
+						Start: &mesos.TimeInfo{Nanoseconds: &scheduled},
+						Duration: &mesos.DurationInfo{
+							Nanoseconds: &durationNanos,
+						},
+					},
+				},
+			},
+		}).
+		Return(nil)
+
+	_, err := suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{
+			Hostnames:       []string{machine.GetHostname()},
+			DurationSeconds: 7200,
+		})
+	suite.NoError(err)
+}
+
+// TestUpdateMaintenanceErrors tests the failures of updating the
+// maintenance window of hosts
+func (suite *HostSvcHandlerTestSuite) TestUpdateMaintenanceErrors() {
+	machine := suite.drainingMachines[0]
+	other := suite.upMachines[0]
+	hosts := []string{machine.GetHostname()}
+	scheduled := time.Now().UnixNano()
+
+	// Invalid start time
+	_, err := suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{
+			Hostnames: hosts,
+			StartTime: "in two hours",
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err), err.Error())
+
+	// No hosts
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+	_, err = suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{})
+	suite.True(yarpcerrors.IsInvalidArgument(err), err.Error())
+
+	// Host not draining
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+	_, err = suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{Hostnames: hosts})
+	suite.True(yarpcerrors.IsFailedPrecondition(err), err.Error())
+
+	// Host drained by Mesos Master
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+				DrainOptions: &hpb.DrainOptions{
+					Method: hpb.DrainMethod_DRAIN_METHOD_AGENT_DRAIN,
+				},
+			},
+		})
+	_, err = suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{Hostnames: hosts})
+	suite.True(yarpcerrors.IsFailedPrecondition(err), err.Error())
+
+	// Host not in the maintenance schedule
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{
+			{
+				Hostname: machine.GetHostname(),
+				State:    hpb.HostState_HOST_STATE_DRAINING,
+			},
+		})
+	suite.mockMasterOperatorClient.EXPECT().GetMaintenanceSchedule().
+		Return(&mesosmaster.Response_GetMaintenanceSchedule{
+			Schedule: &mesosmaintenance.Schedule{},
+		}, nil)
+	_, err = suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{Hostnames: hosts})
+	suite.True(yarpcerrors.IsFailedPrecondition(err), err.Error())
+
+	// Failure to update the schedule
+	suite.expectMaintenanceWindows(machine, other, scheduled)
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("fake UpdateMaintenanceSchedule error"))
+	_, err = suite.handler.UpdateMaintenance(suite.ctx,
+		&svcpb.UpdateMaintenanceRequest{Hostnames: hosts})
+	suite.True(yarpcerrors.IsUnavailable(err), err.Error())
+}
@@ -14,11 +14,18 @@
 
 package mesos
 
+import (
+	"github.com/uber/peloton/pkg/common/tlsconfig"
+)
+
 // Config for Mesos specific configuration
 type Config struct {
 	Framework *FrameworkConfig `yaml:"framework"`
 	ZkPath    string           `yaml:"zk_path"`
 	Encoding  string           `yaml:"encoding"`
+
+	// TLS of the calls to the Mesos master operator API
+	OperatorTLS tlsconfig.Config `yaml:"operator_tls"`
 }
 
 // FrameworkConfig for framework specific configuration
@@ -20,21 +20,26 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/yarpc"
 
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 
+	"github.com/uber/peloton/pkg/hostmgr/host"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/storage"
 )
 
-// InitManager initializes the mesosManager
+// InitManager initializes the mesosManager. The agentEventHandler is
+// notified of agents removed by Mesos master and is optional.
 func InitManager(
 	d *yarpc.Dispatcher,
 	mesosConfig *Config,
-	store storage.FrameworkInfoStore) {
+	store storage.FrameworkInfoStore,
+	agentEventHandler host.AgentEventHandler) {
 
 	m := mesosManager{
-		store:         store,
-		frameworkName: mesosConfig.Framework.Name,
+		store:             store,
+		frameworkName:     mesosConfig.Framework.Name,
+		agentEventHandler: agentEventHandler,
 	}
 
 	for name, hdl := range getCallbacks(&m) {
@@ -44,8 +49,9 @@ func InitManager(
 
 // mesosManager is a handler for Mesos scheduler API events.
 type mesosManager struct {
-	store         storage.FrameworkInfoStore
-	frameworkName string
+	store             storage.FrameworkInfoStore
+	frameworkName     string
+	agentEventHandler host.AgentEventHandler
 }
 
 type schedulerEventCallback func(context.Context, *sched.Event) error
@@ -93,6 +99,20 @@ func (m *mesosManager) Message(ctx context.Context, body *sched.Event) error {
 func (m *mesosManager) Failure(ctx context.Context, body *sched.Event) error {
 	failure := body.GetFailure()
 	log.WithField("failure", failure).Debug("mesosManager: failure called")
+
+	// A failure without an executor id means the agent was removed
+	// from the cluster by Mesos master.
+	if m.agentEventHandler != nil &&
+		failure.GetAgentId() != nil &&
+		failure.GetExecutorId() == nil {
+		eventType := mesos_master.Event_AGENT_REMOVED
+		m.agentEventHandler.HandleMasterEvent(&mesos_master.Event{
+			Type: &eventType,
+			AgentRemoved: &mesos_master.Event_AgentRemoved{
+				AgentId: failure.GetAgentId(),
+			},
+		})
+	}
 	return nil
 }
 
@@ -22,25 +22,30 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 
+	hostmocks "github.com/uber/peloton/pkg/hostmgr/host/mocks"
 	storage_mocks "github.com/uber/peloton/pkg/storage/mocks"
 )
 
 type managerTestSuite struct {
 	suite.Suite
 
-	ctrl    *gomock.Controller
-	store   *storage_mocks.MockFrameworkInfoStore
-	manager *mesosManager
+	ctrl              *gomock.Controller
+	store             *storage_mocks.MockFrameworkInfoStore
+	agentEventHandler *hostmocks.MockAgentEventHandler
+	manager           *mesosManager
 }
 
 func (suite *managerTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.store = storage_mocks.NewMockFrameworkInfoStore(suite.ctrl)
+	suite.agentEventHandler = hostmocks.NewMockAgentEventHandler(suite.ctrl)
 	suite.manager = &mesosManager{
 		suite.store,
 		_frameworkName,
+		suite.agentEventHandler,
 	}
 }
 
@@ -124,6 +129,32 @@ func (suite *managerTestSuite) TestCallbacks() {
 	}
 }
 
+// TestFailureAgentRemoved tests that an agent failure is forwarded
+// as an agent removed event while an executor failure is not.
+func (suite *managerTestSuite) TestFailureAgentRemoved() {
+	agentID := "agent-1"
+	executorID := "executor-1"
+
+	suite.agentEventHandler.EXPECT().
+		HandleMasterEvent(gomock.Any()).
+		Do(func(event *mesos_master.Event) {
+			suite.Equal(mesos_master.Event_AGENT_REMOVED, event.GetType())
+			suite.Equal(agentID, event.GetAgentRemoved().GetAgentId().GetValue())
+		})
+	suite.NoError(suite.manager.Failure(context.Background(), &sched.Event{
+		Failure: &sched.Event_Failure{
+			AgentId: &mesos.AgentID{Value: &agentID},
+		},
+	}))
+
+	suite.NoError(suite.manager.Failure(context.Background(), &sched.Event{
+		Failure: &sched.Event_Failure{
+			AgentId:    &mesos.AgentID{Value: &agentID},
+			ExecutorId: &mesos.ExecutorID{Value: &executorID},
+		},
+	}))
+}
+
 func TestManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(managerTestSuite))
 }
@@ -23,6 +23,7 @@ import (
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	"github.com/uber/peloton/pkg/common/audit"
 
 	"go.uber.org/yarpc/api/transport"
 
@@ -44,10 +45,18 @@ type MasterOperatorClient interface {
 	AllocatedResources(ID string) ([]*mesos.Resource, error)
 	GetMaintenanceSchedule() (*mesos_master.Response_GetMaintenanceSchedule, error)
 	GetMaintenanceStatus() (*mesos_master.Response_GetMaintenanceStatus, error)
-	StartMaintenance([]*mesos.MachineID) error
-	StopMaintenance([]*mesos.MachineID) error
+	StartMaintenance(context.Context, []*mesos.MachineID) error
+	StopMaintenance(context.Context, []*mesos.MachineID) error
 	GetQuota(role string) ([]*mesos.Resource, error)
-	UpdateMaintenanceSchedule(*mesos_v1_maintenance.Schedule) error
+	UpdateMaintenanceSchedule(context.Context, *mesos_v1_maintenance.Schedule) error
+	ReserveResources(agentID *mesos.AgentID, resources []*mesos.Resource) error
+	UnreserveResources(agentID *mesos.AgentID, resources []*mesos.Resource) error
+	CreateVolumes(agentID *mesos.AgentID, volumes []*mesos.Resource) error
+	DestroyVolumes(agentID *mesos.AgentID, volumes []*mesos.Resource) error
+	GetMaster() (*mesos_master.Response_GetMaster, error)
+	DrainAgent(ctx context.Context, agentID *mesos.AgentID, maxGracePeriod time.Duration) error
+	DeactivateAgent(ctx context.Context, agentID *mesos.AgentID) error
+	ReactivateAgent(ctx context.Context, agentID *mesos.AgentID) error
 }
 
 type masterOperatorClient struct {
@@ -73,6 +82,11 @@ func (mo *masterOperatorClient) call(ctx context.Context, msg *mesos_master.Call
 		With("Content-Type", fmt.Sprintf("application/%s", mo.contentType)).
 		With("Accept", fmt.Sprintf("application/%s", mo.contentType))
 
+	// Propagate the correlation ID of the request being handled, if any
+	if requestID := audit.RequestID(ctx); requestID != "" {
+		headers = headers.With(audit.RequestIDHeader, requestID)
+	}
+
 	// Create pb Request
 	reqBody, err := MarshalPbMessage(msg, mo.contentType)
 	if err != nil {
@@ -299,7 +313,9 @@ func (mo *masterOperatorClient) GetMaintenanceSchedule() (*mesos_master.Response
 }
 
 // UpdateMaintenanceSchedule updates the Mesos Maintenance Schedule
-func (mo *masterOperatorClient) UpdateMaintenanceSchedule(schedule *mesos_v1_maintenance.Schedule) error {
+func (mo *masterOperatorClient) UpdateMaintenanceSchedule(
+	ctx context.Context,
+	schedule *mesos_v1_maintenance.Schedule) error {
 	// Set the CALL TYPE
 	callType := mesos_master.Call_UPDATE_MAINTENANCE_SCHEDULE
 
@@ -312,9 +328,7 @@ func (mo *masterOperatorClient) UpdateMaintenanceSchedule(schedule *mesos_v1_mai
 	}
 
 	// Create context to cancel automatically when Timeout expires
-	ctx, cancel := context.WithTimeout(
-		context.Background(), _timeout,
-	)
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
 
 	defer cancel()
 
@@ -355,7 +369,9 @@ func (mo *masterOperatorClient) GetMaintenanceStatus() (*mesos_master.Response_G
 // Master, the agents running on these machines. Any agents on machines in maintenance
 // are also prevented from reregistering with the master in the future until
 // maintenance is completed and the machine is brought back up (by StopMaintenance).
-func (mo *masterOperatorClient) StartMaintenance(machines []*mesos.MachineID) error {
+func (mo *masterOperatorClient) StartMaintenance(
+	ctx context.Context,
+	machines []*mesos.MachineID) error {
 	// Set the CALL TYPE
 	callType := mesos_master.Call_START_MAINTENANCE
 
@@ -368,9 +384,7 @@ func (mo *masterOperatorClient) StartMaintenance(machines []*mesos.MachineID) er
 	}
 
 	// Create context to cancel automatically when Timeout expires
-	ctx, cancel := context.WithTimeout(
-		context.Background(), _timeout,
-	)
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
 
 	defer cancel()
 
@@ -383,7 +397,9 @@ func (mo *masterOperatorClient) StartMaintenance(machines []*mesos.MachineID) er
 }
 
 // StopMaintenance brings the specified machines back 'UP'
-func (mo *masterOperatorClient) StopMaintenance(machines []*mesos.MachineID) error {
+func (mo *masterOperatorClient) StopMaintenance(
+	ctx context.Context,
+	machines []*mesos.MachineID) error {
 	// Set the CALL TYPE
 	callType := mesos_master.Call_STOP_MAINTENANCE
 
@@ -396,9 +412,7 @@ func (mo *masterOperatorClient) StopMaintenance(machines []*mesos.MachineID) err
 	}
 
 	// Create context to cancel automatically when Timeout expires
-	ctx, cancel := context.WithTimeout(
-		context.Background(), _timeout,
-	)
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
 
 	defer cancel()
 
@@ -447,3 +461,219 @@ func (mo *masterOperatorClient) GetQuota(role string) (
 	}
 	return nil, nil
 }
+
+// ReserveResources dynamically reserves the specified resources on an agent.
+// The resources must carry the reservation info (role, principal and labels)
+// to be applied.
+func (mo *masterOperatorClient) ReserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_RESERVE_RESOURCES
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		ReserveResources: &mesos_master.Call_ReserveResources{
+			AgentId:   agentID,
+			Resources: resources,
+		},
+	}
+
+	return mo.callWithTimeout(masterMsg)
+}
+
+// UnreserveResources releases dynamically reserved resources on an agent.
+// The resources must match the ones which were reserved, including
+// their reservation info.
+func (mo *masterOperatorClient) UnreserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_UNRESERVE_RESOURCES
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		UnreserveResources: &mesos_master.Call_UnreserveResources{
+			AgentId:   agentID,
+			Resources: resources,
+		},
+	}
+
+	return mo.callWithTimeout(masterMsg)
+}
+
+// CreateVolumes creates persistent volumes on reserved resources of an agent.
+func (mo *masterOperatorClient) CreateVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_CREATE_VOLUMES
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		CreateVolumes: &mesos_master.Call_CreateVolumes{
+			AgentId: agentID,
+			Volumes: volumes,
+		},
+	}
+
+	return mo.callWithTimeout(masterMsg)
+}
+
+// DestroyVolumes destroys persistent volumes on an agent. The reserved
+// resources backing the volumes are not released.
+func (mo *masterOperatorClient) DestroyVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_DESTROY_VOLUMES
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		DestroyVolumes: &mesos_master.Call_DestroyVolumes{
+			AgentId: agentID,
+			Volumes: volumes,
+		},
+	}
+
+	return mo.callWithTimeout(masterMsg)
+}
+
+// GetMaster returns the information of the current Mesos master, including
+// the capabilities it supports.
+func (mo *masterOperatorClient) GetMaster() (
+	*mesos_master.Response_GetMaster, error) {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_GET_MASTER
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+	}
+
+	// Create context to cancel automatically when Timeout expires
+	ctx, cancel := context.WithTimeout(
+		context.Background(), _timeout,
+	)
+
+	defer cancel()
+
+	// Make Call
+	response, err := mo.call(ctx, masterMsg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	getMaster := response.GetGetMaster()
+	if getMaster == nil {
+		return nil, errors.New("no master info returned from get master call")
+	}
+
+	return getMaster, nil
+}
+
+// DrainAgent deactivates the agent and kills all the tasks running on it.
+// The master reports the agent as DRAINED once all the tasks are terminal.
+// A zero maxGracePeriod leaves the kill grace period of the tasks as is.
+func (mo *masterOperatorClient) DrainAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID,
+	maxGracePeriod time.Duration) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_DRAIN_AGENT
+
+	drainAgent := &mesos_master.Call_DrainAgent{
+		AgentId: agentID,
+	}
+	if maxGracePeriod > 0 {
+		drainAgent.MaxGracePeriod = &mesos.DurationInfo{
+			Nanoseconds: proto.Int64(maxGracePeriod.Nanoseconds()),
+		}
+	}
+	masterMsg := &mesos_master.Call{
+		Type:       &callType,
+		DrainAgent: drainAgent,
+	}
+
+	// Create context to cancel automatically when Timeout expires
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
+
+	defer cancel()
+
+	// Make Call
+	_, err := mo.call(ctx, masterMsg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeactivateAgent stops the master from offering the resources of the
+// agent, without affecting the tasks running on it.
+func (mo *masterOperatorClient) DeactivateAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_DEACTIVATE_AGENT
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		DeactivateAgent: &mesos_master.Call_DeactivateAgent{
+			AgentId: agentID,
+		},
+	}
+
+	// Create context to cancel automatically when Timeout expires
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
+
+	defer cancel()
+
+	// Make Call
+	_, err := mo.call(ctx, masterMsg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ReactivateAgent brings a deactivated or drained agent back, so that its
+// resources are offered again.
+func (mo *masterOperatorClient) ReactivateAgent(
+	ctx context.Context,
+	agentID *mesos.AgentID) error {
+	// Set the CALL TYPE
+	callType := mesos_master.Call_REACTIVATE_AGENT
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+		ReactivateAgent: &mesos_master.Call_ReactivateAgent{
+			AgentId: agentID,
+		},
+	}
+
+	// Create context to cancel automatically when Timeout expires
+	ctx, cancel := context.WithTimeout(ctx, _timeout)
+
+	defer cancel()
+
+	// Make Call
+	_, err := mo.call(ctx, masterMsg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// callWithTimeout makes a call which returns no response body other than
+// the error, cancelling it automatically once the timeout expires.
+func (mo *masterOperatorClient) callWithTimeout(
+	masterMsg *mesos_master.Call) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), _timeout,
+	)
+	defer cancel()
+
+	if _, err := mo.call(ctx, masterMsg); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ package mpb
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -25,6 +26,7 @@ import (
 	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
 	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
 	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/util"
 
 	"go.uber.org/yarpc/api/transport"
@@ -567,12 +569,20 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_StartMainte
 		suite.mockUnaryOutbound.EXPECT().Call(
 			gomock.Any(),
 			gomock.Any(),
-		).Return(
+		).Do(func(_ context.Context, req *transport.Request) {
+			// The correlation ID of the request is propagated
+			requestID, _ := req.Headers.Get(audit.RequestIDHeader)
+			suite.Equal("request-1", requestID)
+		}).Return(
 			response,
 			nil,
 		),
 	)
-	err := suite.masterOperatorClient.StartMaintenance(testMachineIDs)
+	ctx := audit.WithInfo(
+		context.Background(),
+		audit.Info{RequestID: "request-1"},
+	)
+	err := suite.masterOperatorClient.StartMaintenance(ctx, testMachineIDs)
 	suite.NoError(err)
 
 	// Test error
@@ -591,7 +601,7 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_StartMainte
 			fmt.Errorf("fake Call error"),
 		),
 	)
-	err = suite.masterOperatorClient.StartMaintenance(testMachineIDs)
+	err = suite.masterOperatorClient.StartMaintenance(context.Background(), testMachineIDs)
 	suite.Error(err)
 }
 
@@ -637,7 +647,7 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_StopMainten
 			nil,
 		),
 	)
-	err := suite.masterOperatorClient.StopMaintenance(testMachineIDs)
+	err := suite.masterOperatorClient.StopMaintenance(context.Background(), testMachineIDs)
 	suite.NoError(err)
 
 	// Test error
@@ -656,7 +666,7 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_StopMainten
 			fmt.Errorf("fake Call error"),
 		),
 	)
-	err = suite.masterOperatorClient.StopMaintenance(testMachineIDs)
+	err = suite.masterOperatorClient.StopMaintenance(context.Background(), testMachineIDs)
 	suite.Error(err)
 }
 
@@ -715,7 +725,7 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_UpdateMaint
 			nil,
 		),
 	)
-	err := suite.masterOperatorClient.UpdateMaintenanceSchedule(schedule)
+	err := suite.masterOperatorClient.UpdateMaintenanceSchedule(context.Background(), schedule)
 	suite.NoError(err)
 
 	// Test error
@@ -734,7 +744,7 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_UpdateMaint
 			fmt.Errorf("fake Call error"),
 		),
 	)
-	err = suite.masterOperatorClient.UpdateMaintenanceSchedule(schedule)
+	err = suite.masterOperatorClient.UpdateMaintenanceSchedule(context.Background(), schedule)
 	suite.Error(err)
 }
 
@@ -781,6 +791,194 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_GetQuota()
 	suite.Nil(resources)
 }
 
+func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_ReservationCalls() {
+	agentID := &mesos.AgentID{Value: util.PtrPrintf("agent-1")}
+	resources := []*mesos.Resource{
+		mesosResource("cpus", 1.0),
+		mesosResource("mem", 1024.0),
+	}
+
+	calls := map[string]func() error{
+		"reserve": func() error {
+			return suite.masterOperatorClient.ReserveResources(agentID, resources)
+		},
+		"unreserve": func() error {
+			return suite.masterOperatorClient.UnreserveResources(agentID, resources)
+		},
+		"create_volumes": func() error {
+			return suite.masterOperatorClient.CreateVolumes(agentID, resources)
+		},
+		"destroy_volumes": func() error {
+			return suite.masterOperatorClient.DestroyVolumes(agentID, resources)
+		},
+	}
+
+	for name, call := range calls {
+		response := &transport.Response{
+			Body: ioutil.NopCloser(
+				bytes.NewReader([]byte{}),
+			),
+			Headers: transport.NewHeaders().With("a", "b"),
+		}
+		gomock.InOrder(
+			suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+			suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+			suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+				suite.mockUnaryOutbound,
+			),
+
+			suite.mockUnaryOutbound.EXPECT().Call(
+				gomock.Any(),
+				gomock.Any(),
+			).Return(
+				response,
+				nil,
+			),
+		)
+		suite.NoError(call(), name)
+
+		// Test error
+		gomock.InOrder(
+			suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+			suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+			suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+				suite.mockUnaryOutbound,
+			),
+
+			suite.mockUnaryOutbound.EXPECT().Call(
+				gomock.Any(),
+				gomock.Any(),
+			).Return(
+				nil,
+				fmt.Errorf("fake Call error"),
+			),
+		)
+		suite.Error(call(), name)
+	}
+}
+
+func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_AgentDrainingCalls() {
+	agentID := &mesos.AgentID{Value: util.PtrPrintf("agent-1")}
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"drain": func() error {
+			return suite.masterOperatorClient.DrainAgent(ctx, agentID, time.Minute)
+		},
+		"deactivate": func() error {
+			return suite.masterOperatorClient.DeactivateAgent(ctx, agentID)
+		},
+		"reactivate": func() error {
+			return suite.masterOperatorClient.ReactivateAgent(ctx, agentID)
+		},
+	}
+
+	for name, call := range calls {
+		response := &transport.Response{
+			Body: ioutil.NopCloser(
+				bytes.NewReader([]byte{}),
+			),
+			Headers: transport.NewHeaders().With("a", "b"),
+		}
+		gomock.InOrder(
+			suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+			suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+			suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+				suite.mockUnaryOutbound,
+			),
+
+			suite.mockUnaryOutbound.EXPECT().Call(
+				gomock.Any(),
+				gomock.Any(),
+			).Return(
+				response,
+				nil,
+			),
+		)
+		suite.NoError(call(), name)
+
+		// Test error
+		gomock.InOrder(
+			suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+			suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+			suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+				suite.mockUnaryOutbound,
+			),
+
+			suite.mockUnaryOutbound.EXPECT().Call(
+				gomock.Any(),
+				gomock.Any(),
+			).Return(
+				nil,
+				fmt.Errorf("fake Call error"),
+			),
+		)
+		suite.Error(call(), name)
+	}
+}
+
+func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_GetMaster() {
+	capability := mesos.MasterInfo_Capability_AGENT_DRAINING
+	callResp := &mesos_master.Response{
+		GetMaster: &mesos_master.Response_GetMaster{
+			MasterInfo: &mesos.MasterInfo{
+				Capabilities: []*mesos.MasterInfo_Capability{
+					{Type: &capability},
+				},
+			},
+		},
+	}
+	wireData, err := proto.Marshal(callResp)
+	suite.NoError(err)
+
+	response := &transport.Response{
+		Body: ioutil.NopCloser(
+			bytes.NewReader(wireData),
+		),
+		Headers: transport.NewHeaders().With("a", "b"),
+	}
+	gomock.InOrder(
+		suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+		suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+		suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+			suite.mockUnaryOutbound,
+		),
+
+		suite.mockUnaryOutbound.EXPECT().Call(
+			gomock.Any(),
+			gomock.Any(),
+		).Return(
+			response,
+			nil,
+		),
+	)
+	getMaster, err := suite.masterOperatorClient.GetMaster()
+	suite.NoError(err)
+	suite.Equal(
+		capability,
+		getMaster.GetMasterInfo().GetCapabilities()[0].GetType())
+
+	// Test error
+	gomock.InOrder(
+		suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+		suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+		suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+			suite.mockUnaryOutbound,
+		),
+
+		suite.mockUnaryOutbound.EXPECT().Call(
+			gomock.Any(),
+			gomock.Any(),
+		).Return(
+			nil,
+			fmt.Errorf("fake Call error"),
+		),
+	)
+	getMaster, err = suite.masterOperatorClient.GetMaster()
+	suite.Error(err)
+	suite.Nil(getMaster)
+}
+
 func TestMasterOperatorClientTestSuite(t *testing.T) {
 	suite.Run(t, new(masterOperatorClientTestSuite))
 }
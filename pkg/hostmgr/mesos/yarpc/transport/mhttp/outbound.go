@@ -22,6 +22,7 @@ package mhttp
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -58,6 +59,7 @@ var (
 type outboundConfig struct {
 	keepAlive       time.Duration
 	MaxConnsPerHost int
+	tlsConfig       func() *tls.Config
 }
 
 var defaultConfig = outboundConfig{
@@ -86,6 +88,16 @@ func MaxConnectionsPerHost(conns int) OutboundOption {
 	}
 }
 
+// TLSConfig makes the outbound connect to the leader over TLS. The config
+// is fetched for every new connection, so that rotated certificates are
+// picked up. The url template passed to NewOutbound should use the https
+// scheme.
+func TLSConfig(config func() *tls.Config) OutboundOption {
+	return func(c *outboundConfig) {
+		c.tlsConfig = config
+	}
+}
+
 // LeaderDetector provides current leader's hostport.
 type LeaderDetector interface {
 	// Current leader's hostport, or empty string if no leader.
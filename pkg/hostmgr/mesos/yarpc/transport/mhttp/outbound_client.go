@@ -15,23 +15,47 @@
 package mhttp
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 )
 
 func buildClient(cfg *outboundConfig) *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			// options lifted from https://golang.org/src/net/http/transport.go
-			Proxy: http.ProxyFromEnvironment,
-			Dial: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: cfg.keepAlive,
-			}).Dial,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxConnsPerHost:       cfg.MaxConnsPerHost,
-		},
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: cfg.keepAlive,
 	}
+	transport := &http.Transport{
+		// options lifted from https://golang.org/src/net/http/transport.go
+		Proxy:                 http.ProxyFromEnvironment,
+		Dial:                  dialer.Dial,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+	}
+	if cfg.tlsConfig != nil {
+		transport.DialTLS = func(network, addr string) (net.Conn, error) {
+			return dialTLS(dialer, cfg.tlsConfig(), network, addr)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// dialTLS dials addr and verifies the certificate of the peer against the
+// host of addr, unless the config overrides the server name.
+func dialTLS(
+	dialer *net.Dialer,
+	config *tls.Config,
+	network string,
+	addr string) (net.Conn, error) {
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+	return tls.DialWithDialer(dialer, network, addr, config)
 }
@@ -39,6 +39,10 @@ type Metrics struct {
 	KillTasks     tally.Counter
 	KillTasksFail tally.Counter
 
+	DrainNotifications        tally.Counter
+	DrainNotificationsFail    tally.Counter
+	DrainNotificationsSkipped tally.Counter
+
 	ShutdownExecutors        tally.Counter
 	ShutdownExecutorsInvalid tally.Counter
 	ShutdownExecutorsFail    tally.Counter
@@ -54,6 +58,9 @@ type Metrics struct {
 	ClusterCapacity     tally.Counter
 	ClusterCapacityFail tally.Counter
 
+	GetClusterCapacity     tally.Counter
+	GetClusterCapacityFail tally.Counter
+
 	OfferOperations              tally.Counter
 	OfferOperationsFail          tally.Counter
 	OfferOperationsInvalid       tally.Counter
@@ -68,6 +75,14 @@ type Metrics struct {
 	MarkHostsDrained     tally.Counter
 	MarkHostsDrainedFail tally.Counter
 
+	CordonHosts          tally.Counter
+	CordonHostsFail      tally.Counter
+	CordonHostsInvalid   tally.Counter
+	UncordonHosts        tally.Counter
+	UncordonHostsFail    tally.Counter
+	UncordonHostsInvalid tally.Counter
+	GetCordonedHosts     tally.Counter
+
 	scope tally.Scope
 }
 
@@ -96,6 +111,10 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		KillTasks:     scope.Counter("kill_tasks"),
 		KillTasksFail: scope.Counter("kill_tasks_fail"),
 
+		DrainNotifications:        scope.Counter("drain_notifications"),
+		DrainNotificationsFail:    scope.Counter("drain_notifications_fail"),
+		DrainNotificationsSkipped: scope.Counter("drain_notifications_skipped"),
+
 		ShutdownExecutors:        scope.Counter("shutdown_executors"),
 		ShutdownExecutorsInvalid: scope.Counter("shutdown_executors_invalid"),
 		ShutdownExecutorsFail:    scope.Counter("shutdown_executors_fail"),
@@ -111,6 +130,9 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		ClusterCapacity:     scope.Counter("cluster_capacity"),
 		ClusterCapacityFail: scope.Counter("cluster_capacity_fail"),
 
+		GetClusterCapacity:     scope.Counter("get_cluster_capacity"),
+		GetClusterCapacityFail: scope.Counter("get_cluster_capacity_fail"),
+
 		RecoverySuccess: scope.Counter("recovery_success"),
 		RecoveryFail:    scope.Counter("recovery_fail"),
 
@@ -120,6 +142,14 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		MarkHostsDrained:     scope.Counter("mark_hosts_drained"),
 		MarkHostsDrainedFail: scope.Counter("mark_hosts_drained_fail"),
 
+		CordonHosts:          scope.Counter("cordon_hosts"),
+		CordonHostsFail:      scope.Counter("cordon_hosts_fail"),
+		CordonHostsInvalid:   scope.Counter("cordon_hosts_invalid"),
+		UncordonHosts:        scope.Counter("uncordon_hosts"),
+		UncordonHostsFail:    scope.Counter("uncordon_hosts_fail"),
+		UncordonHostsInvalid: scope.Counter("uncordon_hosts_invalid"),
+		GetCordonedHosts:     scope.Counter("get_cordoned_hosts"),
+
 		scope: scope,
 	}
 }
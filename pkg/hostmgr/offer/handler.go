@@ -26,6 +26,9 @@ import (
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	"github.com/uber/peloton/pkg/common/background"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+	"github.com/uber/peloton/pkg/hostmgr/host"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
@@ -67,6 +70,13 @@ type eventHandler struct {
 	offerPool   offerpool.Pool
 	offerPruner Pruner
 	metrics     *offerpool.Metrics
+
+	// attributeWatcher detects attribute changes of re-registered agents
+	// from the attributes carried by their offers.
+	attributeWatcher host.AttributeWatcher
+
+	// eventBus is where the received and rescinded offers are published.
+	eventBus eventbus.Bus
 }
 
 // Singleton event handler for offers
@@ -87,7 +97,10 @@ func InitEventHandler(
 	slackResourceTypes []string,
 	ranker binpacking.Ranker,
 	binPackingRefreshIntervalSec time.Duration,
-	hostPlacingOfferStatusTimeout time.Duration) {
+	hostPlacingOfferStatusTimeout time.Duration,
+	declinePolicy declinepolicy.Policy,
+	attributeWatcher host.AttributeWatcher,
+	eventBus eventbus.Bus) {
 
 	if handler != nil {
 		log.Warning("Offer event handler has already been initialized")
@@ -104,6 +117,7 @@ func InitEventHandler(
 		slackResourceTypes,
 		ranker,
 		hostPlacingOfferStatusTimeout,
+		declinePolicy,
 	)
 
 	placingHostPruner := prune.NewPlacingHostPruner(
@@ -166,9 +180,11 @@ func InitEventHandler(
 	)
 	//TODO: refactor OfferPruner as a background worker
 	handler = &eventHandler{
-		offerPool:   pool,
-		offerPruner: NewOfferPruner(pool, offerPruningPeriod, metrics),
-		metrics:     metrics,
+		offerPool:        pool,
+		offerPruner:      NewOfferPruner(pool, offerPruningPeriod, metrics),
+		metrics:          metrics,
+		attributeWatcher: attributeWatcher,
+		eventBus:         eventBus,
 	}
 	procedures := map[sched.Event_Type]interface{}{
 		sched.Event_OFFERS:                handler.Offers,
@@ -199,7 +215,11 @@ func GetEventHandler() EventHandler {
 func (h *eventHandler) Offers(ctx context.Context, body *sched.Event) error {
 	event := body.GetOffers()
 	log.WithField("event", event).Debug("OfferManager: processing Offers event")
+	if h.attributeWatcher != nil {
+		h.attributeWatcher.ObserveOffers(event.Offers)
+	}
 	h.offerPool.AddOffers(ctx, event.Offers)
+	h.eventBus.Publish(&eventbus.OffersReceivedEvent{Offers: event.Offers})
 
 	return nil
 }
@@ -221,6 +241,7 @@ func (h *eventHandler) Rescind(ctx context.Context, body *sched.Event) error {
 	event := body.GetRescind()
 	log.WithField("event", event).Debug("OfferManager: processing Rescind event")
 	h.offerPool.RescindOffer(event.OfferId)
+	h.eventBus.Publish(&eventbus.OfferRescindedEvent{OfferID: event.OfferId})
 
 	return nil
 }
@@ -44,6 +44,9 @@ type Matcher struct {
 	hostOffers map[string]*summary.Offer
 
 	filterResultCounts map[string]uint32
+
+	// onResult is called with the result of matching each host, if set
+	onResult func(hostname string, result hostsvc.HostFilterResult)
 }
 
 // tryMatch tries to match ready unreserved offers in summary with particular
@@ -54,6 +57,9 @@ func (m *Matcher) tryMatch(
 	hostname string,
 	s summary.HostSummary) {
 	result := m.tryMatchImpl(hostname, s)
+	if m.onResult != nil {
+		m.onResult(hostname, result)
+	}
 	if name, ok := hostsvc.HostFilterResult_name[int32(result)]; !ok {
 		log.WithField("value", result).
 			Error("Unknown enum value for HostFilterResult_name")
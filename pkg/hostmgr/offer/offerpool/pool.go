@@ -17,6 +17,7 @@ package offerpool
 import (
 	"context"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -33,6 +34,8 @@ import (
 
 	"github.com/uber/peloton/pkg/common/constraints"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/host"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
@@ -62,6 +65,12 @@ type Pool interface {
 	// pool.
 	DeclineOffers(ctx context.Context, offerIds []*mesos.OfferID) error
 
+	// DeclineExpiredOffers sends Mesos Master decline call for the offers
+	// removed from the pool by RemoveExpiredOffers.
+	DeclineExpiredOffers(
+		ctx context.Context,
+		expiredOffers map[string]*TimedOffer) error
+
 	// ClaimForPlace obtains offers from pool conforming to given HostFilter
 	// for placement purposes.
 	// First return value is returned offers, grouped by hostname as key,
@@ -133,6 +142,13 @@ type Pool interface {
 
 	// ReleaseHoldForTasks release the hold of host for the tasks specified
 	ReleaseHoldForTasks(hostname string, taskIDs []*peloton.TaskID) error
+
+	// ScoreHosts matches the hosts with the given constraints without
+	// claiming their offers, and returns the candidate hosts in the order
+	// ClaimForPlace matches them, and the rejected hosts.
+	ScoreHosts(hostFilter *hostsvc.HostFilter) (
+		candidates []*hostsvc.HostScore,
+		rejected []*hostsvc.HostScore)
 }
 
 const (
@@ -163,7 +179,8 @@ func NewOfferPool(
 	scarceResourceTypes []string,
 	slackResourceTypes []string,
 	binPackingRanker binpacking.Ranker,
-	hostPlacingOfferStatusTimeout time.Duration) Pool {
+	hostPlacingOfferStatusTimeout time.Duration,
+	declinePolicy declinepolicy.Policy) Pool {
 
 	// GPU is only supported scarce resource type.
 	if !reflect.DeepEqual(supportedScarceResourceTypes, scarceResourceTypes) {
@@ -197,6 +214,7 @@ func NewOfferPool(
 
 		volumeStore:      volumeStore,
 		binPackingRanker: binPackingRanker,
+		declinePolicy:    declinePolicy,
 	}
 
 	return p
@@ -239,6 +257,8 @@ type offerPool struct {
 	volumeStore storage.PersistentVolumeStore
 	// indicate if bin packing is enabled/disabled
 	binPackingRanker binpacking.Ranker
+	// decides the refuse seconds to decline the offers of a host with
+	declinePolicy declinepolicy.Policy
 
 	// taskHeldIndex --- key: task id,
 	// value: host held for the task
@@ -259,6 +279,7 @@ func (p *offerPool) ClaimForPlace(hostFilter *hostsvc.HostFilter) (
 	matcher := NewMatcher(
 		hostFilter,
 		constraints.NewEvaluator(task.LabelConstraint_HOST))
+	matcher.onResult = p.declinePolicy.RecordMatchResult
 
 	// if host hint is provided, try to return the hosts in hints first
 	for _, filterHints := range hostFilter.GetHint().GetHostHint() {
@@ -276,7 +297,9 @@ func (p *offerPool) ClaimForPlace(hostFilter *hostsvc.HostFilter) (
 	// on the results we will optimize this.
 	var sortedSummaryList []interface{}
 	if !matcher.HasEnoughHosts() {
-		sortedSummaryList = p.getRankedHostSummaryList(p.hostOfferIndex)
+		sortedSummaryList = orderByHostPreference(
+			p.getRankedHostSummaryList(p.hostOfferIndex),
+			hostFilter)
 	}
 	for _, s := range sortedSummaryList {
 		matcher.tryMatch(s.(summary.HostSummary).GetHostname(), s.(summary.HostSummary))
@@ -302,11 +325,99 @@ func (p *offerPool) ClaimForPlace(hostFilter *hostsvc.HostFilter) (
 	return hostOffers, resultCount, nil
 }
 
+// ScoreHosts matches the hosts with the given constraints without claiming
+// their offers. The candidate hosts are returned in the order ClaimForPlace
+// matches them, i.e. by host preference and bin packing rank, the score of
+// a host decreasing with its position in that order from 1. The rejected
+// hosts are returned sorted by hostname, with the reason of their rejection.
+// Host hints and the host limit of the filter are ignored.
+func (p *offerPool) ScoreHosts(hostFilter *hostsvc.HostFilter) (
+	[]*hostsvc.HostScore,
+	[]*hostsvc.HostScore) {
+	p.RLock()
+	defer p.RUnlock()
+
+	evaluator := constraints.NewEvaluator(task.LabelConstraint_HOST)
+	ordered := orderByHostPreference(
+		p.getRankedHostSummaryList(p.hostOfferIndex),
+		hostFilter)
+
+	var candidates, rejected []*hostsvc.HostScore
+	for i, s := range ordered {
+		hs := s.(summary.HostSummary)
+		result, reason := hs.EvaluateFilter(hostFilter, evaluator)
+		if result != hostsvc.HostFilterResult_MATCH {
+			rejected = append(rejected, &hostsvc.HostScore{
+				Hostname:       hs.GetHostname(),
+				Result:         result,
+				Reason:         reason,
+				Unavailability: hs.GetUnavailability(),
+			})
+			continue
+		}
+		candidates = append(candidates, &hostsvc.HostScore{
+			Hostname:       hs.GetHostname(),
+			Score:          1 - float64(i)/float64(len(ordered)),
+			Result:         result,
+			Unavailability: hs.GetUnavailability(),
+		})
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].GetHostname() < rejected[j].GetHostname()
+	})
+	return candidates, rejected
+}
+
 func (p *offerPool) getRankedHostSummaryList(
 	offerIndex map[string]summary.HostSummary) []interface{} {
 	return p.binPackingRanker.GetRankedHostList(offerIndex)
 }
 
+// orderByHostPreference stable sorts the ranked hosts by the order of their
+// host pool in the filter, and moves cordoned hosts last, so that the
+// ranking is only kept among hosts of the same preference.
+func orderByHostPreference(
+	ranked []interface{},
+	hostFilter *hostsvc.HostFilter) []interface{} {
+	hostPools := hostFilter.GetHostPools()
+	excludeCordoned := hostFilter.GetExcludeCordoned()
+	if len(hostPools) <= 1 && excludeCordoned {
+		// Hosts outside the pool or cordoned are filtered out anyway.
+		return ranked
+	}
+
+	poolIndex := make(map[string]int, len(hostPools))
+	for i, pool := range hostPools {
+		if _, ok := poolIndex[pool]; !ok {
+			poolIndex[pool] = i
+		}
+	}
+
+	preferences := make(map[string]int, len(ranked))
+	for _, s := range ranked {
+		hostname := s.(summary.HostSummary).GetHostname()
+		preference := 0
+		if len(hostPools) > 0 {
+			preference = len(hostPools)
+			if i, ok := poolIndex[host.GetHostPoolByHostname(hostname)]; ok {
+				preference = i
+			}
+		}
+		if !excludeCordoned && host.IsCordoned(hostname) {
+			preference += len(hostPools) + 1
+		}
+		preferences[hostname] = preference
+	}
+
+	ordered := make([]interface{}, len(ranked))
+	copy(ordered, ranked)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return preferences[ordered[i].(summary.HostSummary).GetHostname()] <
+			preferences[ordered[j].(summary.HostSummary).GetHostname()]
+	})
+	return ordered
+}
+
 // ClaimForLaunch takes offers from pool (removes from hostsummary) for launch.
 func (p *offerPool) ClaimForLaunch(
 	hostname string,
@@ -533,6 +644,66 @@ func (p *offerPool) DeclineOffers(
 	p.RLock()
 	defer p.RUnlock()
 
+	// Group the offers by the refuse seconds of their host, offers not
+	// found in the pool are declined with the Mesos default
+	offersByRefuseSeconds := make(map[float64][]*mesos.OfferID)
+	for _, offerID := range offerIDs {
+		var refuseSeconds float64
+		if offer, ok := p.timedOffers.Load(offerID.GetValue()); ok {
+			refuseSeconds = p.declinePolicy.RefuseSeconds(
+				offer.(*TimedOffer).Hostname)
+		}
+		offersByRefuseSeconds[refuseSeconds] = append(
+			offersByRefuseSeconds[refuseSeconds], offerID)
+	}
+
+	var errs error
+	for refuseSeconds, ids := range offersByRefuseSeconds {
+		if err := p.declineOffers(ctx, ids, refuseSeconds); err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		for _, offerID := range ids {
+			p.removeOffer(*offerID.Value, "offer is declined")
+		}
+	}
+
+	return errs
+}
+
+// DeclineExpiredOffers calls mesos master to decline the offers removed
+// from the pool by RemoveExpiredOffers
+func (p *offerPool) DeclineExpiredOffers(
+	ctx context.Context,
+	expiredOffers map[string]*TimedOffer) error {
+	p.RLock()
+	defer p.RUnlock()
+
+	// Group the offers by the refuse seconds of their host
+	offersByRefuseSeconds := make(map[float64][]*mesos.OfferID)
+	for id, timedOffer := range expiredOffers {
+		offerID := id
+		refuseSeconds := p.declinePolicy.RefuseSeconds(timedOffer.Hostname)
+		offersByRefuseSeconds[refuseSeconds] = append(
+			offersByRefuseSeconds[refuseSeconds],
+			&mesos.OfferID{Value: &offerID})
+	}
+
+	var errs error
+	for refuseSeconds, ids := range offersByRefuseSeconds {
+		errs = multierr.Append(errs,
+			p.declineOffers(ctx, ids, refuseSeconds))
+	}
+	return errs
+}
+
+// declineOffers sends a decline call for the offers to mesos master, with a
+// filter to refuse the resources of the offers for refuseSeconds. The Mesos
+// default is used if refuseSeconds is 0.
+func (p *offerPool) declineOffers(
+	ctx context.Context,
+	offerIDs []*mesos.OfferID,
+	refuseSeconds float64) error {
 	callType := sched.Call_DECLINE
 	msg := &sched.Call{
 		FrameworkId: p.mesosFrameworkInfoProvider.GetFrameworkID(ctx),
@@ -541,6 +712,11 @@ func (p *offerPool) DeclineOffers(
 			OfferIds: offerIDs,
 		},
 	}
+	if refuseSeconds > 0 {
+		msg.Decline.Filters = &mesos.Filters{
+			RefuseSeconds: &refuseSeconds,
+		}
+	}
 	msid := p.mesosFrameworkInfoProvider.GetMesosStreamID(ctx)
 	err := p.mSchedulerClient.Call(msid, msg)
 	if err != nil {
@@ -555,10 +731,6 @@ func (p *offerPool) DeclineOffers(
 	}
 
 	p.metrics.Decline.Inc(int64(len(offerIDs)))
-	for _, offerID := range offerIDs {
-		p.removeOffer(*offerID.Value, "offer is declined")
-	}
-
 	return nil
 }
 
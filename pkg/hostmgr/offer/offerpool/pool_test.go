@@ -30,17 +30,21 @@ import (
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/host"
 	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
 	hostmgr_summary_mocks "github.com/uber/peloton/pkg/hostmgr/summary/mocks"
 	hmutil "github.com/uber/peloton/pkg/hostmgr/util"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
 )
 
 const (
@@ -203,6 +207,7 @@ func (suite *OfferPoolTestSuite) SetupTest() {
 		mSchedulerClient:           suite.schedulerClient,
 		mesosFrameworkInfoProvider: suite.provider,
 		binPackingRanker:           binpacking.CreateRanker(binpacking.DeFrag),
+		declinePolicy:              declinepolicy.NewDefaultPolicy(declinepolicy.Config{}, tally.NoopScope),
 	}
 
 	suite.pool.timedOffers.Range(func(key interface{}, value interface{}) bool {
@@ -226,6 +231,7 @@ func (suite *OfferPoolTestSuite) TestSlackResourceTypes() {
 		[]string{common.MesosCPU, "DUMMY"},
 		binpacking.CreateRanker("DEFRAG"),
 		time.Duration(30*time.Second),
+		declinepolicy.NewDefaultPolicy(declinepolicy.Config{}, tally.NoopScope),
 	)
 	suite.True(hmutil.IsSlackResourceType(
 		common.MesosCPU,
@@ -904,6 +910,94 @@ func (suite *OfferPoolTestSuite) TestDeclineOffers() {
 	suite.Equal(suite.GetTimedOfferLen(), 2)
 }
 
+func (suite *OfferPoolTestSuite) TestDeclineOffersWithBackoff() {
+	policy := declinepolicy.NewBackoffPolicy(declinepolicy.Config{
+		MinRefuseSeconds:  10,
+		MaxRefuseSeconds:  100,
+		MismatchThreshold: 1,
+		BackoffMultiplier: 2,
+	}, tally.NoopScope)
+	suite.pool.declinePolicy = policy
+
+	offer1 := suite.agent1Offers[0]
+	offer2 := suite.agent2Offers[0]
+	suite.pool.AddOffers(context.Background(), []*mesos.Offer{offer1, offer2})
+
+	// Neither host has the label required by the constraint, so matching
+	// records a constraint mismatch for both of them
+	filter := &hostsvc.HostFilter{
+		SchedulingConstraint: &task.Constraint{
+			Type: task.Constraint_LABEL_CONSTRAINT,
+			LabelConstraint: &task.LabelConstraint{
+				Kind:      task.LabelConstraint_HOST,
+				Condition: task.LabelConstraint_CONDITION_EQUAL,
+				Label: &peloton.Label{
+					Key:   "rack",
+					Value: "rack1",
+				},
+				Requirement: 1,
+			},
+		},
+	}
+	hostOffers, _, err := suite.pool.ClaimForPlace(filter)
+	suite.NoError(err)
+	suite.Empty(hostOffers)
+	suite.Equal(10.0, policy.RefuseSeconds(_testAgent1))
+	suite.Equal(10.0, policy.RefuseSeconds(_testAgent2))
+
+	// A match resets the backoff of the host
+	policy.RecordMatchResult(_testAgent2, hostsvc.HostFilterResult_MATCH)
+
+	_frameworkID := "frameworkID"
+	frameworkID := &mesos.FrameworkID{
+		Value: &_frameworkID,
+	}
+	callType := sched.Call_DECLINE
+	refuseSeconds := 10.0
+	backedOffMsg := &sched.Call{
+		FrameworkId: frameworkID,
+		Type:        &callType,
+		Decline: &sched.Call_Decline{
+			OfferIds: []*mesos.OfferID{offer1.Id},
+			Filters: &mesos.Filters{
+				RefuseSeconds: &refuseSeconds,
+			},
+		},
+	}
+	defaultMsg := &sched.Call{
+		FrameworkId: frameworkID,
+		Type:        &callType,
+		Decline: &sched.Call_Decline{
+			OfferIds: []*mesos.OfferID{offer2.Id},
+		},
+	}
+
+	suite.provider.EXPECT().GetFrameworkID(context.Background()).
+		Return(frameworkID).Times(2)
+	suite.provider.EXPECT().GetMesosStreamID(context.Background()).
+		Return(_streamID).Times(2)
+	suite.schedulerClient.EXPECT().Call(_streamID, backedOffMsg).Return(nil)
+	suite.schedulerClient.EXPECT().Call(_streamID, defaultMsg).Return(nil)
+
+	suite.NoError(suite.pool.DeclineOffers(
+		context.Background(),
+		[]*mesos.OfferID{offer1.Id, offer2.Id}))
+	suite.Equal(0, suite.GetTimedOfferLen())
+
+	// Expired offers are declined with the refuse seconds of their host
+	suite.provider.EXPECT().GetFrameworkID(context.Background()).
+		Return(frameworkID)
+	suite.provider.EXPECT().GetMesosStreamID(context.Background()).
+		Return(_streamID)
+	suite.schedulerClient.EXPECT().Call(_streamID, backedOffMsg).Return(nil)
+
+	suite.NoError(suite.pool.DeclineExpiredOffers(
+		context.Background(),
+		map[string]*TimedOffer{
+			offer1.GetId().GetValue(): {Hostname: _testAgent1},
+		}))
+}
+
 func (suite *OfferPoolTestSuite) TestOfferSorting() {
 	// Verify offer pool is empty
 	suite.Equal(suite.GetTimedOfferLen(), 0)
@@ -1127,6 +1221,42 @@ func (suite *OfferPoolTestSuite) TestClaimForPlaceWithFilterHint() {
 	suite.NotNil(result[hostname2])
 }
 
+// TestOrderByHostPreference tests that cordoned hosts are ranked last
+// unless they are excluded by the filter
+func (suite *OfferPoolTestSuite) TestOrderByHostPreference() {
+	hostCordonOps := objectmocks.NewMockHostCordonOps(suite.ctrl)
+	hostCordonOps.EXPECT().
+		Create(gomock.Any(), "hostname0", gomock.Any()).
+		Return(nil)
+	cordonMap := host.NewCordonMap(hostCordonOps, tally.NoopScope)
+	hostCordonOps.EXPECT().Delete(gomock.Any(), "hostname0").Return(nil)
+	suite.NoError(cordonMap.Cordon(
+		context.Background(), []string{"hostname0"}, ""))
+	defer cordonMap.Uncordon(context.Background(), []string{"hostname0"})
+
+	var ranked []interface{}
+	for i := 0; i < 3; i++ {
+		ranked = append(ranked, summary.New(
+			nil,
+			nil,
+			fmt.Sprintf("hostname%d", i),
+			nil,
+			time.Minute))
+	}
+
+	var hostnames []string
+	for _, s := range orderByHostPreference(ranked, &hostsvc.HostFilter{}) {
+		hostnames = append(hostnames, s.(summary.HostSummary).GetHostname())
+	}
+	suite.Equal([]string{"hostname1", "hostname2", "hostname0"}, hostnames)
+	suite.Equal("hostname0", ranked[0].(summary.HostSummary).GetHostname())
+
+	ordered := orderByHostPreference(
+		ranked,
+		&hostsvc.HostFilter{ExcludeCordoned: true})
+	suite.Equal(ranked, ordered)
+}
+
 func TestOfferPoolTestSuite(t *testing.T) {
 	suite.Run(t, new(OfferPoolTestSuite))
 }
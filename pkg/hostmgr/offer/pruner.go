@@ -18,8 +18,6 @@ import (
 	"context"
 	"time"
 
-	mesos "github.com/uber/peloton/.gen/mesos/v1"
-
 	"github.com/uber/peloton/pkg/common/lifecycle"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
 
@@ -84,15 +82,8 @@ func (p *offerPruner) Start() {
 				expiredOffers, _ := p.pool.RemoveExpiredOffers()
 
 				if len(expiredOffers) != 0 {
-					var offerIDs []*mesos.OfferID
-					for id := range expiredOffers {
-						tmp := id
-						offerIDs = append(offerIDs, &mesos.OfferID{
-							Value: &tmp,
-						})
-					}
-					log.WithField("offers", offerIDs).Debug("Offers to decline")
-					if err := p.pool.DeclineOffers(context.Background(), offerIDs); err != nil {
+					log.WithField("offers", expiredOffers).Debug("Offers to decline")
+					if err := p.pool.DeclineExpiredOffers(context.Background(), expiredOffers); err != nil {
 						log.WithError(err).Error("Failed to decline offers")
 					}
 				}
@@ -15,102 +15,206 @@
 package queue
 
 import (
-	"reflect"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/uber/peloton/pkg/common/delayqueue"
 	"github.com/uber/peloton/pkg/common/queue"
 	"github.com/uber/peloton/pkg/common/stringset"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
 	"go.uber.org/multierr"
 )
 
 // maxMaintenanceQueueSize is the max size of the maintenance queue.
 const (
-	maintenanceQueueName    = "maintenance-queue"
 	maxMaintenanceQueueSize = 10000
-	defaultDequeueTimeout   = 100 * time.Millisecond
 )
 
 type maintenanceQueue struct {
-	lock    sync.RWMutex
-	queue   queue.Queue
+	lock sync.RWMutex
+	// Delay queue of the hosts keyed by hostname, deferred hosts are
+	// queued with the time their deferral ends as not-before time
+	queue   delayqueue.DelayQueue
 	hostSet stringset.StringSet // Set containing hosts currently in maintenance queue
+	maxSize int                 // Max number of hosts in the queue
+
+	// Max number of times a host is dequeued without being marked processed,
+	// before it is moved to the dead-letter queue. 0 disables the DLQ.
+	maxAttempts int
+	// Map from hostname to the number of times it has been dequeued
+	// since it was last marked processed
+	attempts map[string]int
+	// Set containing hosts in the dead-letter queue
+	deadLetters stringset.StringSet
 }
 
 // MaintenanceQueue is the interface for maintenance queue.
 type MaintenanceQueue interface {
-	// Enqueue enqueues a list of hostnames into the maintenance queue
-	Enqueue(hostnames []string) error
+	// Enqueue enqueues a batch of hostnames into the maintenance queue.
+	// Either all the hosts of the batch are enqueued, or none of them if
+	// an error is returned. Hosts already in the maintenance or dead-letter
+	// queue, or dead-lettered by the call are skipped and returned.
+	Enqueue(hostnames []string) (skipped []string, err error)
+	// Hosts returns the hosts in the maintenance queue, deferred or not,
+	// sorted by hostname
+	Hosts() []string
 	// Dequeue dequeues a hostname from the maintenance queue
 	Dequeue(maxWaitTime time.Duration) (string, error)
 	// Length returns the length of maintenance queue at any time
 	Length() int
 	// Clear contents of maintenance queue
 	Clear()
+	// MarkProcessed resets the processing attempts of the given hosts,
+	// once they have been successfully processed
+	MarkProcessed(hostnames []string)
+	// DeadLetters returns the hosts in the dead-letter queue
+	DeadLetters() []string
+	// Redrive moves the given hosts from the dead-letter queue back into
+	// the maintenance queue. All dead-lettered hosts are re-driven if
+	// hostnames is empty.
+	Redrive(hostnames []string) error
+	// SetMaxAttempts changes the max number of times a host is dequeued
+	// without being marked processed, before it is dead-lettered. 0
+	// disables the dead-letter queue.
+	SetMaxAttempts(maxAttempts int)
+	// Defer enqueues the given hosts to be dequeued no earlier than the
+	// given time, moving them back if they are already queued. A zero or
+	// past time makes the hosts ready right away. Dead-lettered hosts are
+	// skipped. Either all the hosts are deferred, or none of them if an
+	// error is returned.
+	Defer(hostnames []string, until time.Time) error
 }
 
-// NewMaintenanceQueue returns an instance of the maintenance queue
-func NewMaintenanceQueue() MaintenanceQueue {
+// NewMaintenanceQueue returns an instance of the maintenance queue. Hosts
+// dequeued maxAttempts times without being marked processed are moved to
+// the dead-letter queue instead of being enqueued again. A maxAttempts of 0
+// disables the dead-letter queue.
+func NewMaintenanceQueue(maxAttempts int) MaintenanceQueue {
 	return &maintenanceQueue{
-		queue: queue.NewQueue(
-			maintenanceQueueName,
-			reflect.TypeOf(""),
-			maxMaintenanceQueueSize),
-		hostSet: stringset.New(),
+		queue:       delayqueue.New(delayqueue.NewMetrics(tally.NoopScope)),
+		hostSet:     stringset.New(),
+		maxSize:     maxMaintenanceQueueSize,
+		maxAttempts: maxAttempts,
+		attempts:    make(map[string]int),
+		deadLetters: stringset.New(),
 	}
 }
 
-// Enqueue enqueues a list of hostnames into the maintenance queue
-func (mq *maintenanceQueue) Enqueue(hostnames []string) error {
+// Enqueue enqueues a batch of hostnames into the maintenance queue, or
+// none of them if the queue has no room for the batch
+func (mq *maintenanceQueue) Enqueue(hostnames []string) ([]string, error) {
 	mq.lock.Lock()
 	defer mq.lock.Unlock()
 
-	var errs error
+	var skipped, deadLettered []string
+	batch := stringset.New()
 	for _, host := range hostnames {
-		if mq.hostSet.Contains(host) {
+		if mq.hostSet.Contains(host) || batch.Contains(host) {
 			log.
 				WithField("host", host).
 				Debug("Skipping enqueue. Host already present in maintenance queue.")
+			skipped = append(skipped, host)
 			continue
 		}
-		err := mq.queue.Enqueue(host)
-		if err != nil {
-			errs = multierr.Append(errs, err)
+		if mq.deadLetters.Contains(host) {
+			log.
+				WithField("host", host).
+				Debug("Skipping enqueue. Host present in dead-letter queue.")
+			skipped = append(skipped, host)
 			continue
 		}
-		mq.hostSet.Add(host)
+		if mq.maxAttempts > 0 && mq.attempts[host] >= mq.maxAttempts {
+			deadLettered = append(deadLettered, host)
+			skipped = append(skipped, host)
+			continue
+		}
+		batch.Add(host)
 	}
-	return errs
+
+	if err := mq.checkRoom(batch.Len()); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, host := range hostnames {
+		if batch.Contains(host) && !mq.hostSet.Contains(host) {
+			mq.push(host, now)
+		}
+	}
+	for _, host := range deadLettered {
+		log.WithFields(log.Fields{
+			"host":     host,
+			"attempts": mq.attempts[host],
+		}).Warn("Moving host to maintenance dead-letter queue")
+		delete(mq.attempts, host)
+		mq.deadLetters.Add(host)
+	}
+	return skipped, nil
 }
 
-// Dequeue dequeues a hostname from the maintenance queue
-func (mq *maintenanceQueue) Dequeue(maxWaitTime time.Duration) (string, error) {
-	mq.lock.Lock()
-	defer mq.lock.Unlock()
+// checkRoom returns an error if n more hosts do not fit in the queue. It
+// must be called with the lock held.
+func (mq *maintenanceQueue) checkRoom(n int) error {
+	if mq.hostSet.Len()+n > mq.maxSize {
+		return fmt.Errorf(
+			"maintenance queue full: %d hosts queued, %d to enqueue",
+			mq.hostSet.Len(), n)
+	}
+	return nil
+}
+
+// push adds a host to the queue, to be dequeued no earlier than
+// notBefore. It must be called with the lock held.
+func (mq *maintenanceQueue) push(host string, notBefore time.Time) {
+	mq.queue.Enqueue(delayqueue.Item{
+		Key:       host,
+		Value:     host,
+		NotBefore: notBefore,
+	})
+	mq.hostSet.Add(host)
+}
 
+// Dequeue dequeues a hostname from the maintenance queue. Deferred hosts
+// are only dequeued once their deferral ends.
+func (mq *maintenanceQueue) Dequeue(maxWaitTime time.Duration) (string, error) {
+	// The wait happens without the lock, so that hosts can be enqueued
+	// or deferred meanwhile
 	item, err := mq.queue.Dequeue(maxWaitTime)
 	if err != nil {
-		if _, isTimeout := err.(queue.DequeueTimeOutError); !isTimeout {
-			// error is not due to timeout so we log the error
-			log.WithError(err).
-				Error("unable to dequeue task from maintenance queue")
+		if err == delayqueue.ErrDequeueTimeout {
+			return "", queue.DequeueTimeOutError{}
 		}
+		log.WithError(err).
+			Error("unable to dequeue task from maintenance queue")
 		return "", err
 	}
 
-	host := item.(string)
-	mq.hostSet.Remove(host)
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	host := item.Value.(string)
+	if !mq.queue.Contains(host) {
+		mq.hostSet.Remove(host)
+	}
+	if mq.maxAttempts > 0 {
+		mq.attempts[host]++
+	}
 	return host, nil
 }
 
 // Length returns the length of maintenance queue at any time
 func (mq *maintenanceQueue) Length() int {
+	return mq.queue.Len()
+}
+
+// Hosts returns the hosts in the maintenance queue
+func (mq *maintenanceQueue) Hosts() []string {
 	mq.lock.RLock()
 	defer mq.lock.RUnlock()
 
-	return mq.queue.Length()
+	return mq.hostSet.ToSortedSlice()
 }
 
 // Clear clears the contents of the maintenance queue
@@ -118,12 +222,105 @@ func (mq *maintenanceQueue) Clear() {
 	mq.lock.Lock()
 	defer mq.lock.Unlock()
 
-	for len := mq.queue.Length(); len > 0; len-- {
-		_, err := mq.queue.Dequeue(defaultDequeueTimeout)
-		if err != nil {
-			log.WithError(err).
-				Error("unable to dequeue task from maintenance queue")
-		}
+	for _, host := range mq.hostSet.ToSlice() {
+		mq.queue.Remove(host)
 	}
+	mq.hostSet.Clear()
+	mq.attempts = make(map[string]int)
+	mq.deadLetters.Clear()
 	log.Info("Maintenance queue cleared")
 }
+
+// MarkProcessed resets the processing attempts of the given hosts
+func (mq *maintenanceQueue) MarkProcessed(hostnames []string) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	for _, host := range hostnames {
+		delete(mq.attempts, host)
+	}
+}
+
+// DeadLetters returns the hosts in the dead-letter queue
+func (mq *maintenanceQueue) DeadLetters() []string {
+	mq.lock.RLock()
+	defer mq.lock.RUnlock()
+
+	return mq.deadLetters.ToSortedSlice()
+}
+
+// Redrive moves hosts from the dead-letter queue back into the
+// maintenance queue
+func (mq *maintenanceQueue) Redrive(hostnames []string) error {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	if len(hostnames) == 0 {
+		hostnames = mq.deadLetters.ToSortedSlice()
+	}
+
+	var errs error
+	for _, host := range hostnames {
+		if !mq.deadLetters.Contains(host) {
+			errs = multierr.Append(errs,
+				fmt.Errorf("host %s not in dead-letter queue", host))
+			continue
+		}
+		if err := mq.checkRoom(1); err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		mq.deadLetters.Remove(host)
+		mq.push(host, time.Now())
+	}
+	return errs
+}
+
+// SetMaxAttempts changes the max processing attempts of hosts before they
+// are dead-lettered
+func (mq *maintenanceQueue) SetMaxAttempts(maxAttempts int) {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	mq.maxAttempts = maxAttempts
+	if maxAttempts == 0 {
+		mq.attempts = make(map[string]int)
+	}
+}
+
+// Defer enqueues hosts to be handed out for draining no earlier than the
+// given time
+func (mq *maintenanceQueue) Defer(hostnames []string, until time.Time) error {
+	mq.lock.Lock()
+	defer mq.lock.Unlock()
+
+	batch := stringset.New()
+	for _, host := range hostnames {
+		if mq.deadLetters.Contains(host) {
+			log.
+				WithField("host", host).
+				Debug("Skipping defer. Host present in dead-letter queue.")
+			continue
+		}
+		if !mq.hostSet.Contains(host) {
+			batch.Add(host)
+		}
+	}
+	if err := mq.checkRoom(batch.Len()); err != nil {
+		return err
+	}
+
+	if now := time.Now(); until.Before(now) {
+		until = now
+	}
+	for _, host := range hostnames {
+		if mq.deadLetters.Contains(host) {
+			continue
+		}
+		// The delay queue keeps the earlier not-before time of a queued
+		// host, so it is removed first to move its deferral back
+		mq.queue.Remove(host)
+		mq.push(host, until)
+	}
+	return nil
+}
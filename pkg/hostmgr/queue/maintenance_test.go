@@ -15,26 +15,21 @@
 package queue
 
 import (
-	"fmt"
 	"testing"
 	"time"
 
-	"github.com/uber/peloton/pkg/common/queue/mocks"
-	"github.com/uber/peloton/pkg/common/stringset"
+	"github.com/uber/peloton/pkg/common/queue"
 
-	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
 )
 
 type MaintenanceQueueTestSuite struct {
 	suite.Suite
-	mockCtrl      *gomock.Controller
 	maxWaitTime   time.Duration
 	testHostnames []string
 }
 
 func (suite *MaintenanceQueueTestSuite) SetupSuite() {
-	suite.mockCtrl = gomock.NewController(suite.T())
 	suite.maxWaitTime = 1
 	suite.testHostnames = []string{"testHost1", "testHost2"}
 }
@@ -44,16 +39,18 @@ func TestMaintenanceQueue(t *testing.T) {
 }
 
 func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueEnqueueDequeue() {
-	maintenanceQueue := NewMaintenanceQueue()
-	err := maintenanceQueue.Enqueue(suite.testHostnames)
-	suite.NoError(err)
+	maintenanceQueue := NewMaintenanceQueue(0)
+	suite.enqueue(maintenanceQueue, suite.testHostnames)
 
-	// Check length
+	// Check length and contents
 	suite.Equal(len(suite.testHostnames), maintenanceQueue.Length())
+	suite.Equal(suite.testHostnames, maintenanceQueue.Hosts())
 
 	// Test enqueuing duplicates
-	err = maintenanceQueue.Enqueue(suite.testHostnames)
-	suite.NoError(err)
+	suite.enqueue(
+		maintenanceQueue,
+		suite.testHostnames,
+		suite.testHostnames...)
 
 	// Length should remain the same
 	suite.Equal(len(suite.testHostnames), maintenanceQueue.Length())
@@ -63,49 +60,190 @@ func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueEnqueueDequeue() {
 		suite.NoError(err)
 		suite.Equal(hostname, h)
 	}
+	suite.Empty(maintenanceQueue.Hosts())
+}
 
+// enqueue enqueues the hosts and checks the hosts skipped by the queue
+func (suite *MaintenanceQueueTestSuite) enqueue(
+	maintenanceQueue MaintenanceQueue,
+	hostnames []string,
+	skipped ...string) {
+	s, err := maintenanceQueue.Enqueue(hostnames)
+	suite.NoError(err)
+	suite.Equal(skipped, s)
 }
 
-func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueErrors() {
-	queue := mocks.NewMockQueue(suite.mockCtrl)
-	maintenanceQueue := &maintenanceQueue{
-		queue:   queue,
-		hostSet: stringset.New(),
-	}
-	// Test Enqueue error
-	queue.EXPECT().Enqueue(gomock.Any()).
-		Return(fmt.Errorf("fake enqueue error")).
-		Times(len(suite.testHostnames))
-	err := maintenanceQueue.Enqueue(suite.testHostnames)
-	suite.Error(err)
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueEnqueueBatch() {
+	maintenanceQueue := NewMaintenanceQueue(0).(*maintenanceQueue)
+	maintenanceQueue.maxSize = 2
+
+	// Duplicates within a batch are skipped
+	suite.enqueue(maintenanceQueue, []string{"host1", "host1"}, "host1")
 
-	// Test Dequeue error
-	queue.EXPECT().Dequeue(gomock.Any()).
-		Return(nil, fmt.Errorf("fake dequeue error"))
-	hostname, err := maintenanceQueue.
-		Dequeue(suite.maxWaitTime * time.Second)
+	// A batch which does not fit is not enqueued at all
+	skipped, err := maintenanceQueue.Enqueue([]string{"host2", "host3"})
 	suite.Error(err)
+	suite.Empty(skipped)
+	suite.Equal([]string{"host1"}, maintenanceQueue.Hosts())
+	suite.Equal(1, maintenanceQueue.Length())
+
+	suite.enqueue(maintenanceQueue, []string{"host1", "host2"}, "host1")
+	suite.Equal([]string{"host1", "host2"}, maintenanceQueue.Hosts())
+	for _, hostname := range []string{"host1", "host2"} {
+		h, err := maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+		suite.NoError(err)
+		suite.Equal(hostname, h)
+	}
+}
+
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueDequeueTimeout() {
+	maintenanceQueue := NewMaintenanceQueue(0)
+
+	hostname, err := maintenanceQueue.Dequeue(time.Millisecond)
+	suite.IsType(queue.DequeueTimeOutError{}, err)
 	suite.Equal("", hostname)
 }
 
 func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueClear() {
-	queue := mocks.NewMockQueue(suite.mockCtrl)
-	maintenanceQueue := &maintenanceQueue{
-		queue: queue,
-	}
+	maintenanceQueue := NewMaintenanceQueue(1)
+	suite.enqueue(maintenanceQueue, suite.testHostnames)
+	suite.NoError(maintenanceQueue.Defer(
+		[]string{"testHost3"},
+		time.Now().Add(time.Hour)))
 
-	queue.EXPECT().Length().Return(len(suite.testHostnames))
-	queue.EXPECT().
-		Dequeue(gomock.Any()).
-		Return(gomock.Any(), nil).
-		Times(len(suite.testHostnames))
 	maintenanceQueue.Clear()
+	suite.Empty(maintenanceQueue.Hosts())
+	suite.Zero(maintenanceQueue.Length())
+	_, err := maintenanceQueue.Dequeue(time.Millisecond)
+	suite.IsType(queue.DequeueTimeOutError{}, err)
+}
+
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueDeadLetters() {
+	maintenanceQueue := NewMaintenanceQueue(2)
+
+	// Dequeue the hosts twice without marking them processed
+	for i := 0; i < 2; i++ {
+		suite.enqueue(maintenanceQueue, suite.testHostnames)
+		for range suite.testHostnames {
+			_, err := maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+			suite.NoError(err)
+		}
+	}
+	maintenanceQueue.MarkProcessed(suite.testHostnames[:1])
+
+	// The unprocessed host is moved to the dead-letter queue
+	suite.enqueue(
+		maintenanceQueue,
+		suite.testHostnames,
+		suite.testHostnames[1:]...)
+	suite.Equal(1, maintenanceQueue.Length())
+	suite.Equal(suite.testHostnames[1:], maintenanceQueue.DeadLetters())
+
+	// Hosts in the dead-letter queue are not enqueued again
+	suite.enqueue(
+		maintenanceQueue,
+		suite.testHostnames[1:],
+		suite.testHostnames[1:]...)
+	suite.Equal(1, maintenanceQueue.Length())
+
+	// Re-driving a host not in the dead-letter queue fails
+	suite.Error(maintenanceQueue.Redrive(suite.testHostnames[:1]))
+
+	// Re-drive all dead-lettered hosts
+	suite.NoError(maintenanceQueue.Redrive(nil))
+	suite.Empty(maintenanceQueue.DeadLetters())
+	suite.Equal(2, maintenanceQueue.Length())
+
+	// A re-driven host gets a fresh set of attempts
+	for range suite.testHostnames {
+		_, err := maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+		suite.NoError(err)
+	}
+	suite.enqueue(maintenanceQueue, suite.testHostnames[1:])
+	suite.Equal(1, maintenanceQueue.Length())
+	suite.Empty(maintenanceQueue.DeadLetters())
 
-	// Test Dequeue error
-	queue.EXPECT().Length().Return(len(suite.testHostnames))
-	queue.EXPECT().
-		Dequeue(gomock.Any()).
-		Return(gomock.Any(), fmt.Errorf("fake dequeue error")).
-		Times(len(suite.testHostnames))
 	maintenanceQueue.Clear()
+	suite.Empty(maintenanceQueue.DeadLetters())
+}
+
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueSetMaxAttempts() {
+	maintenanceQueue := NewMaintenanceQueue(0)
+
+	// Attempts are not counted while the dead-letter queue is disabled
+	suite.enqueue(maintenanceQueue, suite.testHostnames[:1])
+	_, err := maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+	suite.NoError(err)
+
+	maintenanceQueue.SetMaxAttempts(1)
+	suite.enqueue(maintenanceQueue, suite.testHostnames[:1])
+	suite.Empty(maintenanceQueue.DeadLetters())
+	_, err = maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+	suite.NoError(err)
+
+	// The host is dead-lettered once it reaches the new max attempts
+	suite.enqueue(
+		maintenanceQueue,
+		suite.testHostnames[:1],
+		suite.testHostnames[:1]...)
+	suite.Equal(suite.testHostnames[:1], maintenanceQueue.DeadLetters())
+	suite.Zero(maintenanceQueue.Length())
+}
+
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueDefer() {
+	maintenanceQueue := NewMaintenanceQueue(0)
+	suite.enqueue(maintenanceQueue, suite.testHostnames)
+
+	// Hosts deferred after they were enqueued stay queued, but are not
+	// dequeued before their deferral ends
+	suite.NoError(maintenanceQueue.Defer(
+		suite.testHostnames[:1],
+		time.Now().Add(time.Hour)))
+	h, err := maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+	suite.NoError(err)
+	suite.Equal(suite.testHostnames[1], h)
+	suite.Equal(suite.testHostnames[:1], maintenanceQueue.Hosts())
+	_, err = maintenanceQueue.Dequeue(time.Millisecond)
+	suite.IsType(queue.DequeueTimeOutError{}, err)
+
+	// Deferred hosts are not enqueued again
+	suite.enqueue(
+		maintenanceQueue,
+		suite.testHostnames[:1],
+		suite.testHostnames[:1]...)
+	suite.Equal(1, maintenanceQueue.Length())
+
+	// Hosts are dequeued by themselves once their deferral ends
+	suite.NoError(maintenanceQueue.Defer(
+		suite.testHostnames,
+		time.Now().Add(50*time.Millisecond)))
+	suite.Equal(2, maintenanceQueue.Length())
+	for range suite.testHostnames {
+		_, err = maintenanceQueue.Dequeue(suite.maxWaitTime * time.Second)
+		suite.NoError(err)
+	}
+	suite.Empty(maintenanceQueue.Hosts())
+
+	// A past time makes the hosts ready right away
+	suite.NoError(maintenanceQueue.Defer(suite.testHostnames[:1], time.Time{}))
+	h, err = maintenanceQueue.Dequeue(time.Millisecond)
+	suite.NoError(err)
+	suite.Equal(suite.testHostnames[0], h)
+}
+
+func (suite *MaintenanceQueueTestSuite) TestMaintenanceQueueDeferFull() {
+	maintenanceQueue := NewMaintenanceQueue(0).(*maintenanceQueue)
+	maintenanceQueue.maxSize = 1
+
+	// Hosts already queued are only moved back, and need no room
+	suite.enqueue(maintenanceQueue, suite.testHostnames[:1])
+	suite.NoError(maintenanceQueue.Defer(
+		suite.testHostnames[:1],
+		time.Now().Add(time.Hour)))
+
+	// A batch which does not fit is not deferred at all
+	suite.Error(maintenanceQueue.Defer(
+		suite.testHostnames,
+		time.Now().Add(time.Hour)))
+	suite.Equal(suite.testHostnames[:1], maintenanceQueue.Hosts())
 }
@@ -15,12 +15,13 @@
 package hostmgr
 
 import (
+	"context"
+
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/metrics"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
-
-	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	taskStateManager "github.com/uber/peloton/pkg/hostmgr/task"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
@@ -33,25 +34,41 @@ type RecoveryHandler interface {
 	Stop() error
 }
 
-// recoveryHandler restores the contents of MaintenanceQueue
-// from Mesos Maintenance Status
+// recoveryHandler restores the contents of MaintenanceQueue and
+// MaintenanceHostInfoMap from Mesos Maintenance Status and Schedule, and the task-to-host index,
+// host cordons and host assignments from storage
 type recoveryHandler struct {
-	metrics                *metrics.Metrics
-	maintenanceQueue       queue.MaintenanceQueue
-	masterOperatorClient   mpb.MasterOperatorClient
-	maintenanceHostInfoMap host.MaintenanceHostInfoMap
+	metrics               *metrics.Metrics
+	maintenanceQueue      queue.MaintenanceQueue
+	masterOperatorClient  mpb.MasterOperatorClient
+	maintenanceReconciler *host.MaintenanceReconciler
+	hostTaskIndex         taskStateManager.HostTaskIndex
+	cordonMap             host.CordonMap
+	assignmentMap         host.AssignmentMap
+	approvalMap           host.ApprovalMap
 }
 
 // NewRecoveryHandler creates a recoveryHandler
 func NewRecoveryHandler(parent tally.Scope,
 	maintenanceQueue queue.MaintenanceQueue,
 	masterOperatorClient mpb.MasterOperatorClient,
-	maintenanceHostInfoMap host.MaintenanceHostInfoMap) RecoveryHandler {
+	maintenanceHostInfoMap host.MaintenanceHostInfoMap,
+	hostTaskIndex taskStateManager.HostTaskIndex,
+	cordonMap host.CordonMap,
+	assignmentMap host.AssignmentMap,
+	approvalMap host.ApprovalMap) RecoveryHandler {
 	recovery := &recoveryHandler{
-		metrics:                metrics.NewMetrics(parent),
-		maintenanceQueue:       maintenanceQueue,
-		masterOperatorClient:   masterOperatorClient,
-		maintenanceHostInfoMap: maintenanceHostInfoMap,
+		metrics:              metrics.NewMetrics(parent),
+		maintenanceQueue:     maintenanceQueue,
+		masterOperatorClient: masterOperatorClient,
+		maintenanceReconciler: host.NewMaintenanceReconciler(
+			parent,
+			masterOperatorClient,
+			maintenanceHostInfoMap),
+		hostTaskIndex: hostTaskIndex,
+		cordonMap:     cordonMap,
+		assignmentMap: assignmentMap,
+		approvalMap:   approvalMap,
 	}
 	return recovery
 }
@@ -62,8 +79,17 @@ func (r *recoveryHandler) Stop() error {
 	return nil
 }
 
-// Start requeues all 'DRAINING' hosts into maintenance queue
+// Start requeues all 'DRAINING' hosts into maintenance queue, and loads
+// the persisted task-to-host index, host cordons and host assignments
 func (r *recoveryHandler) Start() error {
+	// The index is rebuilt from task status updates upon reconciliation
+	// anyway, so failing to recover it does not fail the recovery.
+	// Cordons and assignments only steer placement, so hosts are not
+	// made unavailable by failing to recover them either.
+	if err := r.recoverHostState(); err != nil {
+		log.WithError(err).Warn("Failed to recover task-to-host index and host cordons")
+	}
+
 	err := r.recoverMaintenanceState()
 	if err != nil {
 		r.metrics.RecoveryFail.Inc(1)
@@ -74,43 +100,43 @@ func (r *recoveryHandler) Start() error {
 	return nil
 }
 
+func (r *recoveryHandler) recoverHostState() error {
+	agents, err := r.masterOperatorClient.Agents()
+	if err != nil {
+		return err
+	}
+
+	var hostnames []string
+	for _, agent := range agents.GetAgents() {
+		hostnames = append(hostnames, agent.GetAgentInfo().GetHostname())
+	}
+	if err := r.cordonMap.Recover(context.Background(), hostnames); err != nil {
+		log.WithError(err).Warn("Failed to recover host cordons")
+	}
+	if err := r.assignmentMap.Recover(context.Background(), hostnames); err != nil {
+		log.WithError(err).Warn("Failed to recover host assignments")
+	}
+	return r.hostTaskIndex.Recover(context.Background(), hostnames)
+}
+
+// recoverMaintenanceState repairs the maintenance host info map from
+// Mesos Master, loads the maintenance approvals of the DRAINING hosts, and
+// requeues them. Failing to load the approvals fails the recovery, since
+// hosts waiting for approval would otherwise be put down.
 func (r *recoveryHandler) recoverMaintenanceState() error {
 	// Clear contents of maintenance queue before
 	// enqueuing, to ensure removal of stale data
 	r.maintenanceQueue.Clear()
 
-	response, err := r.masterOperatorClient.GetMaintenanceStatus()
+	drainingHosts, err := r.maintenanceReconciler.Reconcile()
 	if err != nil {
 		return err
 	}
-
-	clusterStatus := response.GetStatus()
-	if clusterStatus == nil {
-		log.Info("Empty maintenance status received")
-		return nil
-	}
-
-	var drainingHosts []string
-	var hostInfos []*hpb.HostInfo
-	for _, drainingMachine := range clusterStatus.GetDrainingMachines() {
-		machineID := drainingMachine.GetId()
-		hostInfos = append(hostInfos,
-			&hpb.HostInfo{
-				Hostname: machineID.GetHostname(),
-				Ip:       machineID.GetIp(),
-				State:    hpb.HostState_HOST_STATE_DRAINING,
-			})
-		drainingHosts = append(drainingHosts, machineID.GetHostname())
-	}
-
-	for _, downMachine := range clusterStatus.GetDownMachines() {
-		hostInfos = append(hostInfos,
-			&hpb.HostInfo{
-				Hostname: downMachine.GetHostname(),
-				Ip:       downMachine.GetIp(),
-				State:    hpb.HostState_HOST_STATE_DOWN,
-			})
+	if err := r.approvalMap.Recover(
+		context.Background(),
+		drainingHosts); err != nil {
+		return err
 	}
-	r.maintenanceHostInfoMap.ClearAndFillMap(hostInfos)
-	return r.maintenanceQueue.Enqueue(drainingHosts)
+	_, err = r.maintenanceQueue.Enqueue(drainingHosts)
+	return err
 }
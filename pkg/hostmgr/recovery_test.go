@@ -25,6 +25,7 @@ import (
 	host_mocks "github.com/uber/peloton/pkg/hostmgr/host/mocks"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
+	task_state_mocks "github.com/uber/peloton/pkg/hostmgr/task/mocks"
 
 	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
@@ -41,6 +42,10 @@ type RecoveryTestSuite struct {
 	drainingMachines         []*mesos.MachineID
 	downMachines             []*mesos.MachineID
 	maintenanceHostInfoMap   *host_mocks.MockMaintenanceHostInfoMap
+	hostTaskIndex            *task_state_mocks.MockHostTaskIndex
+	cordonMap                *host_mocks.MockCordonMap
+	assignmentMap            *host_mocks.MockAssignmentMap
+	approvalMap              *host_mocks.MockApprovalMap
 }
 
 func (suite *RecoveryTestSuite) SetupSuite() {
@@ -69,10 +74,18 @@ func (suite *RecoveryTestSuite) SetupTest() {
 	suite.mockMasterOperatorClient = mpb_mocks.NewMockMasterOperatorClient(suite.mockCtrl)
 
 	suite.maintenanceHostInfoMap = host_mocks.NewMockMaintenanceHostInfoMap(suite.mockCtrl)
+	suite.hostTaskIndex = task_state_mocks.NewMockHostTaskIndex(suite.mockCtrl)
+	suite.cordonMap = host_mocks.NewMockCordonMap(suite.mockCtrl)
+	suite.assignmentMap = host_mocks.NewMockAssignmentMap(suite.mockCtrl)
+	suite.approvalMap = host_mocks.NewMockApprovalMap(suite.mockCtrl)
 	suite.recoveryHandler = NewRecoveryHandler(tally.NoopScope,
 		suite.mockMaintenanceQueue,
 		suite.mockMasterOperatorClient,
-		suite.maintenanceHostInfoMap)
+		suite.maintenanceHostInfoMap,
+		suite.hostTaskIndex,
+		suite.cordonMap,
+		suite.assignmentMap,
+		suite.approvalMap)
 }
 
 func (suite *RecoveryTestSuite) TearDownTest() {
@@ -84,7 +97,50 @@ func TestHostmgrRecovery(t *testing.T) {
 	suite.Run(t, new(RecoveryTestSuite))
 }
 
+// expectHostTaskIndexRecovery sets the expectations to recover the
+// task-to-host index, cordons and assignments of the given hosts
+func (suite *RecoveryTestSuite) expectHostTaskIndexRecovery(
+	hostnames []string) {
+	var agents []*mesos_master.Response_GetAgents_Agent
+	for i := range hostnames {
+		agents = append(agents, &mesos_master.Response_GetAgents_Agent{
+			AgentInfo: &mesos.AgentInfo{
+				Hostname: &hostnames[i],
+			},
+		})
+	}
+
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(&mesos_master.Response_GetAgents{Agents: agents}, nil)
+	suite.cordonMap.EXPECT().
+		Recover(gomock.Any(), hostnames).
+		Return(nil)
+	suite.assignmentMap.EXPECT().
+		Recover(gomock.Any(), hostnames).
+		Return(nil)
+	suite.hostTaskIndex.EXPECT().
+		Recover(gomock.Any(), hostnames).
+		Return(nil)
+}
+
+// expectMaintenanceReconcile sets the expectations to reconcile an
+// empty maintenance host info map with an empty maintenance schedule
+func (suite *RecoveryTestSuite) expectMaintenanceReconcile() {
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{}, nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(nil)
+	suite.maintenanceHostInfoMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil)
+}
+
 func (suite *RecoveryTestSuite) TestStart() {
+	suite.expectHostTaskIndexRecovery([]string{"host1", "host2"})
+
 	var clusterDrainingMachines []*mesos_maintenance.ClusterStatus_DrainingMachine
 	for _, drainingMachine := range suite.drainingMachines {
 		clusterDrainingMachines = append(clusterDrainingMachines,
@@ -104,6 +160,7 @@ func (suite *RecoveryTestSuite) TestStart() {
 		Return(&mesos_master.Response_GetMaintenanceStatus{
 			Status: clusterStatus,
 		}, nil)
+	suite.expectMaintenanceReconcile()
 
 	var drainingHostnames []string
 	for _, machine := range suite.drainingMachines {
@@ -114,9 +171,13 @@ func (suite *RecoveryTestSuite) TestStart() {
 		suite.maintenanceHostInfoMap.EXPECT().
 			ClearAndFillMap(gomock.Any()),
 
+		suite.approvalMap.EXPECT().
+			Recover(gomock.Any(), drainingHostnames).
+			Return(nil),
+
 		suite.mockMaintenanceQueue.EXPECT().
 			Enqueue(gomock.Any()).
-			Return(nil).Do(func(hostnames []string) {
+			Return(nil, nil).Do(func(hostnames []string) {
 			suite.EqualValues(drainingHostnames, hostnames)
 		}),
 	)
@@ -125,6 +186,7 @@ func (suite *RecoveryTestSuite) TestStart() {
 }
 
 func (suite *RecoveryTestSuite) TestStart_Error() {
+	suite.expectHostTaskIndexRecovery([]string{"host1"})
 	suite.mockMaintenanceQueue.EXPECT().Clear()
 	suite.mockMasterOperatorClient.EXPECT().
 		GetMaintenanceStatus().
@@ -134,6 +196,76 @@ func (suite *RecoveryTestSuite) TestStart_Error() {
 	suite.Error(err)
 }
 
+// TestStart_ApprovalError tests that failing to recover the maintenance
+// approvals fails the recovery
+func (suite *RecoveryTestSuite) TestStart_ApprovalError() {
+	suite.expectHostTaskIndexRecovery([]string{"host1"})
+	suite.mockMaintenanceQueue.EXPECT().Clear()
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.expectMaintenanceReconcile()
+	suite.maintenanceHostInfoMap.EXPECT().ClearAndFillMap(nil)
+	suite.approvalMap.EXPECT().
+		Recover(gomock.Any(), nil).
+		Return(fmt.Errorf("Fake Recover error"))
+
+	err := suite.recoveryHandler.Start()
+	suite.Error(err)
+}
+
+// TestStart_HostTaskIndexError tests that failing to recover the
+// task-to-host index does not fail the recovery
+func (suite *RecoveryTestSuite) TestStart_HostTaskIndexError() {
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(nil, fmt.Errorf("Fake Agents error"))
+	suite.mockMaintenanceQueue.EXPECT().Clear()
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.expectMaintenanceReconcile()
+	suite.maintenanceHostInfoMap.EXPECT().ClearAndFillMap(nil)
+	suite.approvalMap.EXPECT().Recover(gomock.Any(), nil).Return(nil)
+	suite.mockMaintenanceQueue.EXPECT().Enqueue(nil).Return(nil, nil)
+
+	err := suite.recoveryHandler.Start()
+	suite.NoError(err)
+}
+
+// TestStart_CordonError tests that failing to recover the host cordons
+// and assignments does not fail the recovery, nor the recovery of the task-to-host index
+func (suite *RecoveryTestSuite) TestStart_CordonError() {
+	hostname := "host1"
+	suite.mockMasterOperatorClient.EXPECT().
+		Agents().
+		Return(&mesos_master.Response_GetAgents{
+			Agents: []*mesos_master.Response_GetAgents_Agent{{
+				AgentInfo: &mesos.AgentInfo{Hostname: &hostname},
+			}},
+		}, nil)
+	suite.cordonMap.EXPECT().
+		Recover(gomock.Any(), []string{hostname}).
+		Return(fmt.Errorf("Fake Recover error"))
+	suite.assignmentMap.EXPECT().
+		Recover(gomock.Any(), []string{hostname}).
+		Return(fmt.Errorf("Fake Recover error"))
+	suite.hostTaskIndex.EXPECT().
+		Recover(gomock.Any(), []string{hostname}).
+		Return(nil)
+	suite.mockMaintenanceQueue.EXPECT().Clear()
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceStatus().
+		Return(&mesos_master.Response_GetMaintenanceStatus{}, nil)
+	suite.expectMaintenanceReconcile()
+	suite.maintenanceHostInfoMap.EXPECT().ClearAndFillMap(nil)
+	suite.approvalMap.EXPECT().Recover(gomock.Any(), nil).Return(nil)
+	suite.mockMaintenanceQueue.EXPECT().Enqueue(nil).Return(nil, nil)
+
+	err := suite.recoveryHandler.Start()
+	suite.NoError(err)
+}
+
 func (suite *RecoveryTestSuite) TestStop() {
 	err := suite.recoveryHandler.Stop()
 	suite.NoError(err)
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reload
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber/peloton/pkg/hostmgr/config"
+)
+
+// Settings are the host manager settings which are reloaded without
+// restart.
+type Settings struct {
+	// Period for fully reloading the agent map from Mesos master
+	HostmapRefreshInterval time.Duration
+	// Period of the host drainer
+	HostDrainerPeriod time.Duration
+	// Max number of times a host is handed out for draining before it is
+	// dead-lettered
+	MaintenanceQueueMaxAttempts int
+}
+
+// SettingsFromConfig returns the reloadable settings of a host manager
+// config.
+func SettingsFromConfig(cfg config.Config) Settings {
+	return Settings{
+		HostmapRefreshInterval:      cfg.HostmapRefreshInterval,
+		HostDrainerPeriod:           cfg.HostDrainerPeriod,
+		MaintenanceQueueMaxAttempts: cfg.MaintenanceQueueMaxAttempts,
+	}
+}
+
+// Validate returns an error if any of the settings is invalid.
+func (s Settings) Validate() error {
+	if s.HostmapRefreshInterval <= 0 {
+		return fmt.Errorf(
+			"hostmap_refresh_interval %s must be positive",
+			s.HostmapRefreshInterval)
+	}
+	if s.HostDrainerPeriod <= 0 {
+		return fmt.Errorf(
+			"host_drainer_period %s must be positive",
+			s.HostDrainerPeriod)
+	}
+	if s.MaintenanceQueueMaxAttempts < 0 {
+		return fmt.Errorf(
+			"maintenance_queue_max_attempts %d must not be negative",
+			s.MaintenanceQueueMaxAttempts)
+	}
+	return nil
+}
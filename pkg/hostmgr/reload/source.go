@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	commonconfig "github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/hostmgr/config"
+
+	"github.com/pkg/errors"
+	"gopkg.in/validator.v2"
+	"gopkg.in/yaml.v2"
+)
+
+const _defaultFetchTimeout = 10 * time.Second
+
+// Source loads the current host manager config.
+type Source interface {
+	// Name describes the source in audit records
+	Name() string
+	// Load returns the current host manager config
+	Load() (config.Config, error)
+}
+
+// document is the part of the config document read by sources.
+type document struct {
+	HostManager config.Config `yaml:"host_manager"`
+}
+
+// fileSource loads the config from YAML files, merged in order the same way
+// as on start.
+type fileSource struct {
+	files []string
+}
+
+// NewFileSource returns a Source which loads the host manager config from
+// the given YAML config files.
+func NewFileSource(files ...string) Source {
+	return &fileSource{files: files}
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + strings.Join(s.files, ",")
+}
+
+func (s *fileSource) Load() (config.Config, error) {
+	var doc document
+	if err := commonconfig.Parse(&doc, s.files...); err != nil {
+		return config.Config{}, err
+	}
+	return doc.HostManager, nil
+}
+
+// urlSource loads the config from a config service, which serves the config
+// as a YAML document.
+type urlSource struct {
+	url    string
+	client *http.Client
+}
+
+// NewURLSource returns a Source which fetches the host manager config from
+// a config service serving it as a YAML document at url.
+func NewURLSource(url string) Source {
+	return &urlSource{
+		url:    url,
+		client: &http.Client{Timeout: _defaultFetchTimeout},
+	}
+}
+
+func (s *urlSource) Name() string {
+	return s.url
+}
+
+func (s *urlSource) Load() (config.Config, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return config.Config{}, errors.Wrap(err, "failed to fetch config")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return config.Config{}, fmt.Errorf(
+			"failed to fetch config: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return config.Config{}, errors.Wrap(err, "failed to read config")
+	}
+
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return config.Config{}, errors.Wrap(err, "failed to parse config")
+	}
+	if err := validator.Validate(doc); err != nil {
+		return config.Config{}, errors.Wrap(err, "invalid config")
+	}
+	return doc.HostManager, nil
+}
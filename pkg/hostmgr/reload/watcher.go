@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reload
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/background"
+	"github.com/uber/peloton/pkg/common/lifecycle"
+	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
+)
+
+const (
+	// HostmapWork is the name of the background work reloading the agent
+	// map, whose period is reloaded.
+	HostmapWork = "hostmap"
+
+	_defaultInterval = time.Minute
+)
+
+// Watcher periodically loads the host manager config from a Source, and
+// applies the changed settings to the running components. Every applied
+// change is recorded in an audit log entry.
+type Watcher struct {
+	sync.Mutex
+
+	source   Source
+	interval time.Duration
+	current  Settings
+
+	backgroundManager background.Manager
+	drainer           host.Drainer
+	maintenanceQueue  queue.MaintenanceQueue
+
+	lifeCycle lifecycle.LifeCycle
+	metrics   *Metrics
+}
+
+// NewWatcher returns a Watcher of source, whose settings on start are
+// those of the given config.
+func NewWatcher(
+	cfg config.ReloadConfig,
+	source Source,
+	initial config.Config,
+	parent tally.Scope,
+	backgroundManager background.Manager,
+	drainer host.Drainer,
+	maintenanceQueue queue.MaintenanceQueue) *Watcher {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = _defaultInterval
+	}
+	return &Watcher{
+		source:            source,
+		interval:          interval,
+		current:           SettingsFromConfig(initial),
+		backgroundManager: backgroundManager,
+		drainer:           drainer,
+		maintenanceQueue:  maintenanceQueue,
+		lifeCycle:         lifecycle.NewLifeCycle(),
+		metrics:           NewMetrics(parent.SubScope("config_reload")),
+	}
+}
+
+// Start starts watching the source for changes.
+func (w *Watcher) Start() {
+	if !w.lifeCycle.Start() {
+		return
+	}
+	go w.run()
+}
+
+// Stop stops watching the source, and blocks until the watcher exits.
+func (w *Watcher) Stop() {
+	if !w.lifeCycle.Stop() {
+		return
+	}
+	w.lifeCycle.Wait()
+}
+
+// Settings returns the currently applied settings.
+func (w *Watcher) Settings() Settings {
+	w.Lock()
+	defer w.Unlock()
+	return w.current
+}
+
+func (w *Watcher) run() {
+	defer w.lifeCycle.StopComplete()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.lifeCycle.StopCh():
+			return
+		case <-ticker.C:
+			if err := w.Reload(); err != nil {
+				log.WithError(err).
+					WithField("source", w.source.Name()).
+					Warn("Failed to reload host manager config")
+			}
+		}
+	}
+}
+
+// Reload loads the config from the source, and applies the settings which
+// changed. Invalid settings are rejected as a whole, keeping the current
+// settings.
+func (w *Watcher) Reload() error {
+	w.Lock()
+	defer w.Unlock()
+
+	cfg, err := w.source.Load()
+	if err != nil {
+		w.metrics.ReloadFail.Inc(1)
+		return errors.Wrap(err, "failed to load config")
+	}
+	settings := SettingsFromConfig(cfg)
+	if err := settings.Validate(); err != nil {
+		w.metrics.Invalid.Inc(1)
+		return errors.Wrap(err, "invalid config")
+	}
+	w.metrics.Reload.Inc(1)
+
+	var errs error
+	if settings.HostmapRefreshInterval != w.current.HostmapRefreshInterval {
+		err := w.backgroundManager.UpdatePeriod(
+			HostmapWork, settings.HostmapRefreshInterval)
+		if w.record(
+			"hostmap_refresh_interval",
+			w.current.HostmapRefreshInterval,
+			settings.HostmapRefreshInterval,
+			err) {
+			w.current.HostmapRefreshInterval = settings.HostmapRefreshInterval
+		}
+		errs = multierr.Append(errs, err)
+	}
+	if settings.HostDrainerPeriod != w.current.HostDrainerPeriod {
+		w.drainer.SetPeriod(settings.HostDrainerPeriod)
+		w.record(
+			"host_drainer_period",
+			w.current.HostDrainerPeriod,
+			settings.HostDrainerPeriod,
+			nil)
+		w.current.HostDrainerPeriod = settings.HostDrainerPeriod
+	}
+	if settings.MaintenanceQueueMaxAttempts !=
+		w.current.MaintenanceQueueMaxAttempts {
+		w.maintenanceQueue.SetMaxAttempts(settings.MaintenanceQueueMaxAttempts)
+		w.record(
+			"maintenance_queue_max_attempts",
+			w.current.MaintenanceQueueMaxAttempts,
+			settings.MaintenanceQueueMaxAttempts,
+			nil)
+		w.current.MaintenanceQueueMaxAttempts =
+			settings.MaintenanceQueueMaxAttempts
+	}
+	return errs
+}
+
+// record writes the audit log entry of a setting change, and returns
+// whether it was applied.
+func (w *Watcher) record(
+	setting string,
+	old interface{},
+	new interface{},
+	err error) bool {
+	entry := log.WithFields(log.Fields{
+		"audit":   true,
+		"setting": setting,
+		"old":     old,
+		"new":     new,
+		"source":  w.source.Name(),
+	})
+	if err != nil {
+		w.metrics.ApplyFail.Inc(1)
+		entry.WithError(err).Warn("Failed to apply host manager config change")
+		return false
+	}
+	w.metrics.Applied.Inc(1)
+	entry.Info("Applied host manager config change")
+	return true
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reload
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	background_mocks "github.com/uber/peloton/pkg/common/background/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/config"
+	host_mocks "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+// staticSource is a Source returning a fixed config
+type staticSource struct {
+	cfg config.Config
+	err error
+}
+
+func (s *staticSource) Name() string {
+	return "static"
+}
+
+func (s *staticSource) Load() (config.Config, error) {
+	return s.cfg, s.err
+}
+
+type WatcherTestSuite struct {
+	suite.Suite
+
+	ctrl              *gomock.Controller
+	backgroundManager *background_mocks.MockManager
+	drainer           *host_mocks.MockDrainer
+	maintenanceQueue  *qm.MockMaintenanceQueue
+
+	initial config.Config
+	source  *staticSource
+	watcher *Watcher
+}
+
+func (suite *WatcherTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.backgroundManager = background_mocks.NewMockManager(suite.ctrl)
+	suite.drainer = host_mocks.NewMockDrainer(suite.ctrl)
+	suite.maintenanceQueue = qm.NewMockMaintenanceQueue(suite.ctrl)
+
+	suite.initial = config.Config{
+		HostmapRefreshInterval:      10 * time.Second,
+		HostDrainerPeriod:           15 * time.Minute,
+		MaintenanceQueueMaxAttempts: 10,
+	}
+	suite.source = &staticSource{cfg: suite.initial}
+	suite.watcher = NewWatcher(
+		config.ReloadConfig{Interval: time.Millisecond},
+		suite.source,
+		suite.initial,
+		tally.NoopScope,
+		suite.backgroundManager,
+		suite.drainer,
+		suite.maintenanceQueue,
+	)
+}
+
+func (suite *WatcherTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestWatcher(t *testing.T) {
+	suite.Run(t, new(WatcherTestSuite))
+}
+
+// TestReloadUnchanged tests that nothing is applied if the settings did
+// not change
+func (suite *WatcherTestSuite) TestReloadUnchanged() {
+	suite.NoError(suite.watcher.Reload())
+	suite.Equal(SettingsFromConfig(suite.initial), suite.watcher.Settings())
+}
+
+// TestReloadApplyChanges tests that changed settings are applied
+func (suite *WatcherTestSuite) TestReloadApplyChanges() {
+	suite.source.cfg.HostmapRefreshInterval = 30 * time.Second
+	suite.source.cfg.HostDrainerPeriod = time.Minute
+	suite.source.cfg.MaintenanceQueueMaxAttempts = 0
+
+	suite.backgroundManager.EXPECT().
+		UpdatePeriod(HostmapWork, 30*time.Second).
+		Return(nil)
+	suite.drainer.EXPECT().SetPeriod(time.Minute)
+	suite.maintenanceQueue.EXPECT().SetMaxAttempts(0)
+
+	suite.NoError(suite.watcher.Reload())
+	suite.Equal(
+		SettingsFromConfig(suite.source.cfg),
+		suite.watcher.Settings())
+
+	// Reloading again does not apply the same settings twice
+	suite.NoError(suite.watcher.Reload())
+}
+
+// TestReloadApplyFailure tests that a setting failed to be applied is
+// retried on the next reload
+func (suite *WatcherTestSuite) TestReloadApplyFailure() {
+	suite.source.cfg.HostmapRefreshInterval = 30 * time.Second
+
+	suite.backgroundManager.EXPECT().
+		UpdatePeriod(HostmapWork, 30*time.Second).
+		Return(errors.New("unknown background work name"))
+	suite.Error(suite.watcher.Reload())
+	suite.Equal(
+		10*time.Second,
+		suite.watcher.Settings().HostmapRefreshInterval)
+
+	suite.backgroundManager.EXPECT().
+		UpdatePeriod(HostmapWork, 30*time.Second).
+		Return(nil)
+	suite.NoError(suite.watcher.Reload())
+	suite.Equal(
+		30*time.Second,
+		suite.watcher.Settings().HostmapRefreshInterval)
+}
+
+// TestReloadInvalid tests that invalid settings are rejected as a whole
+func (suite *WatcherTestSuite) TestReloadInvalid() {
+	suite.source.cfg.HostDrainerPeriod = time.Minute
+	suite.source.cfg.HostmapRefreshInterval = 0
+	suite.Error(suite.watcher.Reload())
+
+	suite.source.cfg.HostmapRefreshInterval = 10 * time.Second
+	suite.source.cfg.MaintenanceQueueMaxAttempts = -1
+	suite.Error(suite.watcher.Reload())
+
+	suite.Equal(SettingsFromConfig(suite.initial), suite.watcher.Settings())
+}
+
+// TestReloadSourceFailure tests that the current settings are kept if
+// the source fails to load
+func (suite *WatcherTestSuite) TestReloadSourceFailure() {
+	suite.source.err = errors.New("unavailable")
+	suite.Error(suite.watcher.Reload())
+	suite.Equal(SettingsFromConfig(suite.initial), suite.watcher.Settings())
+}
+
+// TestStartStop tests that a started watcher reloads periodically
+func (suite *WatcherTestSuite) TestStartStop() {
+	suite.source.cfg.HostDrainerPeriod = time.Minute
+	suite.drainer.EXPECT().SetPeriod(time.Minute)
+
+	suite.watcher.Start()
+	for i := 0; i < 100; i++ {
+		if suite.watcher.Settings().HostDrainerPeriod == time.Minute {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.watcher.Stop()
+	suite.Equal(time.Minute, suite.watcher.Settings().HostDrainerPeriod)
+}
+
+// TestFileSource tests loading the host manager config from merged files
+func (suite *WatcherTestSuite) TestFileSource() {
+	dir, err := ioutil.TempDir("", "reload")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.yaml")
+	override := filepath.Join(dir, "override.yaml")
+	suite.NoError(ioutil.WriteFile(base, []byte(`
+host_manager:
+  hostmap_refresh_interval: 10s
+  host_drainer_period: 900s
+`), 0644))
+	suite.NoError(ioutil.WriteFile(override, []byte(`
+host_manager:
+  host_drainer_period: 60s
+`), 0644))
+
+	cfg, err := NewFileSource(base, override).Load()
+	suite.NoError(err)
+	suite.Equal(10*time.Second, cfg.HostmapRefreshInterval)
+	suite.Equal(time.Minute, cfg.HostDrainerPeriod)
+
+	_, err = NewFileSource(filepath.Join(dir, "missing.yaml")).Load()
+	suite.Error(err)
+}
+
+// TestURLSource tests fetching the host manager config from a config
+// service
+func (suite *WatcherTestSuite) TestURLSource() {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/hostmgr" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`
+host_manager:
+  maintenance_queue_max_attempts: 5
+`))
+		}))
+	defer server.Close()
+
+	source := NewURLSource(server.URL + "/hostmgr")
+	suite.Equal(server.URL+"/hostmgr", source.Name())
+	cfg, err := source.Load()
+	suite.NoError(err)
+	suite.Equal(5, cfg.MaintenanceQueueMaxAttempts)
+
+	_, err = NewURLSource(server.URL + "/missing").Load()
+	suite.Error(err)
+}
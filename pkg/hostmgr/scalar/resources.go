@@ -246,6 +246,24 @@ func FromMesosResources(resources []*mesos.Resource) (r Resources) {
 	return r
 }
 
+// CustomScalarsFromMesosResources returns the total value of each scalar
+// Mesos resource other than cpus, mem, disk and gpus, keyed by name.
+func CustomScalarsFromMesosResources(
+	resources []*mesos.Resource) map[string]float64 {
+	custom := make(map[string]float64)
+	for _, resource := range resources {
+		if resource.GetType() != mesos.Value_SCALAR {
+			continue
+		}
+		switch name := resource.GetName(); name {
+		case "cpus", "mem", "disk", "gpus":
+		default:
+			custom[name] += resource.GetScalar().GetValue()
+		}
+	}
+	return custom
+}
+
 // FromOfferToMesosResources returns list of resources from a single offer
 func FromOfferToMesosResources(offer *mesos.Offer) []*mesos.Resource {
 	var resources []*mesos.Resource
@@ -381,6 +381,24 @@ func TestFromOfferMap(t *testing.T) {
 	assert.InDelta(t, 8.0, result.GPU, _zeroDelta)
 }
 
+func TestCustomScalarsFromMesosResources(t *testing.T) {
+	rs := []*mesos.Resource{
+		util.NewMesosResourceBuilder().WithName("cpus").WithValue(1.0).Build(),
+		util.NewMesosResourceBuilder().WithName("gpus").WithValue(4.0).Build(),
+		util.NewMesosResourceBuilder().WithName("custom").WithValue(5.0).Build(),
+		util.NewMesosResourceBuilder().WithName("custom").WithValue(1.0).Build(),
+		util.NewMesosResourceBuilder().
+			WithName("ports").
+			WithType(mesos.Value_RANGES).
+			Build(),
+	}
+
+	result := CustomScalarsFromMesosResources(rs)
+	assert.Len(t, result, 1)
+	assert.InDelta(t, 6.0, result["custom"], _zeroDelta)
+	assert.Empty(t, CustomScalarsFromMesosResources(nil))
+}
+
 func TestFromOffers(t *testing.T) {
 	rs := []*mesos.Resource{
 		util.NewMesosResourceBuilder().WithName("cpus").WithValue(1.0).Build(),
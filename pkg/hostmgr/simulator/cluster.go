@@ -0,0 +1,679 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulator provides a simulated Mesos master with a configurable
+// population of agents behind mpb.MasterOperatorClient, so that Host
+// Manager components can be integration tested at scale without a real
+// Mesos cluster.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	_defaultHostnamePrefix = "host"
+	_agentPort             = 5051
+)
+
+// Config is the configuration of a simulated cluster.
+type Config struct {
+	// Number of agents registered at start.
+	NumHosts int
+
+	// Prefix of the generated hostnames. Defaults to "host".
+	HostnamePrefix string
+
+	// Total resources of each agent.
+	CPU    float64
+	MemMb  float64
+	DiskMb float64
+	GPU    float64
+
+	// Every GPUHostInterval-th agent has GPUs. All agents have GPUs
+	// if it is 0 or 1.
+	GPUHostInterval int
+
+	// Text attributes of every agent.
+	Attributes map[string]string
+
+	// Seed of the random source used to pick agents for churn.
+	Seed int64
+
+	// Whether the master advertises the AGENT_DRAINING capability.
+	AgentDraining bool
+}
+
+// agent is a simulated Mesos agent.
+type agent struct {
+	index      int
+	id         string
+	hostname   string
+	ip         string
+	attributes map[string]string
+	reserved   []*mesos.Resource
+	volumes    []*mesos.Resource
+
+	// Agent draining state set by the DRAIN_AGENT, DEACTIVATE_AGENT
+	// and REACTIVATE_AGENT calls.
+	deactivated bool
+	drainInfo   *mesos.DrainInfo
+}
+
+// Cluster is a simulated Mesos master which implements
+// mpb.MasterOperatorClient. All methods are safe for concurrent use.
+type Cluster struct {
+	sync.RWMutex
+
+	config    Config
+	random    *rand.Rand
+	nextIndex int
+
+	// Registered agents by hostname.
+	agents map[string]*agent
+
+	// Maintenance state of machines by hostname.
+	draining map[string]*mesos.MachineID
+	down     map[string]*mesos.MachineID
+	schedule *mesos_maintenance.Schedule
+
+	// Quota guarantees by role.
+	quota map[string][]*mesos.Resource
+}
+
+var _ mpb.MasterOperatorClient = (*Cluster)(nil)
+
+// NewCluster returns a simulated cluster with config.NumHosts agents.
+func NewCluster(config Config) *Cluster {
+	if config.HostnamePrefix == "" {
+		config.HostnamePrefix = _defaultHostnamePrefix
+	}
+
+	c := &Cluster{
+		config:   config,
+		random:   rand.New(rand.NewSource(config.Seed)),
+		agents:   make(map[string]*agent),
+		draining: make(map[string]*mesos.MachineID),
+		down:     make(map[string]*mesos.MachineID),
+		schedule: &mesos_maintenance.Schedule{},
+		quota:    make(map[string][]*mesos.Resource),
+	}
+	c.AddHosts(config.NumHosts)
+	return c
+}
+
+// AddHosts registers n new agents and returns their hostnames.
+func (c *Cluster) AddHosts(n int) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	var hostnames []string
+	for i := 0; i < n; i++ {
+		a := c.newAgent(c.nextIndex)
+		c.nextIndex++
+		c.agents[a.hostname] = a
+		hostnames = append(hostnames, a.hostname)
+	}
+	return hostnames
+}
+
+// RemoveHosts unregisters n randomly picked agents which are not in
+// maintenance, and returns their hostnames.
+func (c *Cluster) RemoveHosts(n int) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	var candidates []string
+	for hostname := range c.agents {
+		if _, ok := c.draining[hostname]; ok {
+			continue
+		}
+		candidates = append(candidates, hostname)
+	}
+	// Sort before shuffling so that the picked hosts only depend
+	// on the seed.
+	sort.Strings(candidates)
+	c.random.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	removed := candidates[:n]
+	for _, hostname := range removed {
+		delete(c.agents, hostname)
+	}
+	return removed
+}
+
+// Churn removes and then adds agents, and returns the hostnames of the
+// added and removed agents.
+func (c *Cluster) Churn(add int, remove int) ([]string, []string) {
+	removed := c.RemoveHosts(remove)
+	added := c.AddHosts(add)
+	return added, removed
+}
+
+// SetAttribute sets a text attribute of a registered agent.
+func (c *Cluster) SetAttribute(hostname string, name string, value string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, ok := c.agents[hostname]
+	if !ok {
+		return errors.Errorf("unknown agent %s", hostname)
+	}
+	a.attributes[name] = value
+	return nil
+}
+
+// SetQuota sets the quota guarantee of a role.
+func (c *Cluster) SetQuota(role string, guarantee []*mesos.Resource) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.quota[role] = guarantee
+}
+
+// Hostnames returns the sorted hostnames of the registered agents.
+func (c *Cluster) Hostnames() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	hostnames := make([]string, 0, len(c.agents))
+	for hostname := range c.agents {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// MachineID returns the machine id of a registered agent.
+func (c *Cluster) MachineID(hostname string) *mesos.MachineID {
+	c.RLock()
+	defer c.RUnlock()
+
+	a, ok := c.agents[hostname]
+	if !ok {
+		return nil
+	}
+	return a.machineID()
+}
+
+// Agents implements MasterOperatorClient.Agents.
+func (c *Cluster) Agents() (*mesos_master.Response_GetAgents, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	response := &mesos_master.Response_GetAgents{}
+	for _, hostname := range c.sortedHostnames() {
+		response.Agents = append(response.Agents, c.agents[hostname].toAgent(c.config))
+	}
+	return response, nil
+}
+
+// GetTasksAllocation implements MasterOperatorClient.GetTasksAllocation.
+// The simulated cluster runs no tasks.
+func (c *Cluster) GetTasksAllocation(
+	ID string) ([]*mesos.Resource, []*mesos.Resource, error) {
+	return nil, nil, nil
+}
+
+// AllocatedResources implements MasterOperatorClient.AllocatedResources.
+// The simulated cluster runs no tasks.
+func (c *Cluster) AllocatedResources(ID string) ([]*mesos.Resource, error) {
+	if len(ID) == 0 {
+		return nil, errors.New("frameworkID cannot be empty")
+	}
+	return nil, nil
+}
+
+// GetMaintenanceSchedule implements MasterOperatorClient.GetMaintenanceSchedule.
+func (c *Cluster) GetMaintenanceSchedule() (
+	*mesos_master.Response_GetMaintenanceSchedule, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	// Return a copy since callers append windows to the schedule
+	// before posting it back.
+	return &mesos_master.Response_GetMaintenanceSchedule{
+		Schedule: proto.Clone(c.schedule).(*mesos_maintenance.Schedule),
+	}, nil
+}
+
+// UpdateMaintenanceSchedule implements
+// MasterOperatorClient.UpdateMaintenanceSchedule. Machines added to the
+// schedule start DRAINING and machines removed from it are UP again.
+// As in Mesos, DOWN machines cannot be removed from the schedule.
+func (c *Cluster) UpdateMaintenanceSchedule(
+	_ context.Context,
+	schedule *mesos_maintenance.Schedule) error {
+	c.Lock()
+	defer c.Unlock()
+
+	scheduled := make(map[string]*mesos.MachineID)
+	for _, window := range schedule.GetWindows() {
+		for _, machineID := range window.GetMachineIds() {
+			if _, ok := scheduled[machineID.GetHostname()]; ok {
+				return errors.Errorf(
+					"machine %s is in more than one window",
+					machineID.GetHostname())
+			}
+			scheduled[machineID.GetHostname()] = machineID
+		}
+	}
+	for hostname := range c.down {
+		if _, ok := scheduled[hostname]; !ok {
+			return errors.Errorf(
+				"down machine %s cannot be removed from the schedule",
+				hostname)
+		}
+	}
+
+	draining := make(map[string]*mesos.MachineID)
+	for hostname, machineID := range scheduled {
+		if _, ok := c.down[hostname]; !ok {
+			draining[hostname] = machineID
+		}
+	}
+	c.draining = draining
+	c.schedule = schedule
+	return nil
+}
+
+// GetMaintenanceStatus implements MasterOperatorClient.GetMaintenanceStatus.
+func (c *Cluster) GetMaintenanceStatus() (
+	*mesos_master.Response_GetMaintenanceStatus, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	status := &mesos_maintenance.ClusterStatus{}
+	for _, hostname := range sortedMachines(c.draining) {
+		status.DrainingMachines = append(status.DrainingMachines,
+			&mesos_maintenance.ClusterStatus_DrainingMachine{
+				Id: c.draining[hostname],
+			})
+	}
+	for _, hostname := range sortedMachines(c.down) {
+		status.DownMachines = append(status.DownMachines, c.down[hostname])
+	}
+	return &mesos_master.Response_GetMaintenanceStatus{
+		Status: status,
+	}, nil
+}
+
+// StartMaintenance implements MasterOperatorClient.StartMaintenance.
+// The machines must be DRAINING. They are moved to DOWN and their
+// agents are unregistered.
+func (c *Cluster) StartMaintenance(
+	_ context.Context,
+	machines []*mesos.MachineID) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, machineID := range machines {
+		if _, ok := c.draining[machineID.GetHostname()]; !ok {
+			return errors.Errorf(
+				"machine %s is not scheduled for maintenance",
+				machineID.GetHostname())
+		}
+	}
+	for _, machineID := range machines {
+		hostname := machineID.GetHostname()
+		c.down[hostname] = c.draining[hostname]
+		delete(c.draining, hostname)
+		delete(c.agents, hostname)
+	}
+	return nil
+}
+
+// StopMaintenance implements MasterOperatorClient.StopMaintenance.
+// The machines must be DOWN. They are removed from the schedule and
+// their agents register again.
+func (c *Cluster) StopMaintenance(
+	_ context.Context,
+	machines []*mesos.MachineID) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, machineID := range machines {
+		if _, ok := c.down[machineID.GetHostname()]; !ok {
+			return errors.Errorf(
+				"machine %s is not down",
+				machineID.GetHostname())
+		}
+	}
+
+	stopped := make(map[string]bool)
+	for _, machineID := range machines {
+		hostname := machineID.GetHostname()
+		delete(c.down, hostname)
+		stopped[hostname] = true
+
+		a := c.newAgent(c.nextIndex)
+		c.nextIndex++
+		a.hostname = hostname
+		a.ip = machineID.GetIp()
+		c.agents[hostname] = a
+	}
+
+	schedule := &mesos_maintenance.Schedule{}
+	for _, window := range c.schedule.GetWindows() {
+		var machineIDs []*mesos.MachineID
+		for _, machineID := range window.GetMachineIds() {
+			if !stopped[machineID.GetHostname()] {
+				machineIDs = append(machineIDs, machineID)
+			}
+		}
+		if len(machineIDs) == 0 {
+			continue
+		}
+		schedule.Windows = append(schedule.Windows, &mesos_maintenance.Window{
+			MachineIds:     machineIDs,
+			Unavailability: window.GetUnavailability(),
+		})
+	}
+	c.schedule = schedule
+	return nil
+}
+
+// GetQuota implements MasterOperatorClient.GetQuota.
+func (c *Cluster) GetQuota(role string) ([]*mesos.Resource, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.quota[role], nil
+}
+
+// ReserveResources implements MasterOperatorClient.ReserveResources.
+func (c *Cluster) ReserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	a.reserved = append(a.reserved, resources...)
+	return nil
+}
+
+// UnreserveResources implements MasterOperatorClient.UnreserveResources.
+func (c *Cluster) UnreserveResources(
+	agentID *mesos.AgentID,
+	resources []*mesos.Resource) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	a.reserved = removeResources(a.reserved, resources)
+	return nil
+}
+
+// CreateVolumes implements MasterOperatorClient.CreateVolumes.
+func (c *Cluster) CreateVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	a.volumes = append(a.volumes, volumes...)
+	return nil
+}
+
+// DestroyVolumes implements MasterOperatorClient.DestroyVolumes.
+func (c *Cluster) DestroyVolumes(
+	agentID *mesos.AgentID,
+	volumes []*mesos.Resource) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	a.volumes = removeResources(a.volumes, volumes)
+	return nil
+}
+
+// GetMaster implements MasterOperatorClient.GetMaster.
+func (c *Cluster) GetMaster() (*mesos_master.Response_GetMaster, error) {
+	masterInfo := &mesos.MasterInfo{}
+	if c.config.AgentDraining {
+		capability := mesos.MasterInfo_Capability_AGENT_DRAINING
+		masterInfo.Capabilities = append(masterInfo.Capabilities,
+			&mesos.MasterInfo_Capability{Type: &capability})
+	}
+	return &mesos_master.Response_GetMaster{MasterInfo: masterInfo}, nil
+}
+
+// DrainAgent implements MasterOperatorClient.DrainAgent. Since the
+// simulated cluster runs no tasks, the agent is DRAINED right away.
+func (c *Cluster) DrainAgent(
+	_ context.Context,
+	agentID *mesos.AgentID,
+	maxGracePeriod time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.config.AgentDraining {
+		return errors.New("master does not support agent draining")
+	}
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	state := mesos.DrainState_DRAINED
+	config := &mesos.DrainConfig{}
+	if maxGracePeriod > 0 {
+		config.MaxGracePeriod = &mesos.DurationInfo{
+			Nanoseconds: proto.Int64(maxGracePeriod.Nanoseconds()),
+		}
+	}
+	a.deactivated = true
+	a.drainInfo = &mesos.DrainInfo{State: &state, Config: config}
+	return nil
+}
+
+// DeactivateAgent implements MasterOperatorClient.DeactivateAgent.
+func (c *Cluster) DeactivateAgent(
+	_ context.Context,
+	agentID *mesos.AgentID) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.config.AgentDraining {
+		return errors.New("master does not support agent draining")
+	}
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	a.deactivated = true
+	return nil
+}
+
+// ReactivateAgent implements MasterOperatorClient.ReactivateAgent.
+// The agent must be deactivated.
+func (c *Cluster) ReactivateAgent(
+	_ context.Context,
+	agentID *mesos.AgentID) error {
+	c.Lock()
+	defer c.Unlock()
+
+	a, err := c.getAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	if !a.deactivated {
+		return errors.Errorf("agent %s is not deactivated", agentID.GetValue())
+	}
+	a.deactivated = false
+	a.drainInfo = nil
+	return nil
+}
+
+// newAgent returns a new simulated agent. It must be called with the
+// lock held.
+func (c *Cluster) newAgent(index int) *agent {
+	attributes := make(map[string]string, len(c.config.Attributes))
+	for name, value := range c.config.Attributes {
+		attributes[name] = value
+	}
+	return &agent{
+		index:      index,
+		id:         fmt.Sprintf("agent-%d", index),
+		hostname:   fmt.Sprintf("%s-%d", c.config.HostnamePrefix, index),
+		ip:         fmt.Sprintf("10.%d.%d.%d", (index>>16)&0xff, (index>>8)&0xff, index&0xff),
+		attributes: attributes,
+	}
+}
+
+// getAgentByID returns the registered agent with the given id. It must
+// be called with the lock held.
+func (c *Cluster) getAgentByID(agentID *mesos.AgentID) (*agent, error) {
+	for _, a := range c.agents {
+		if a.id == agentID.GetValue() {
+			return a, nil
+		}
+	}
+	return nil, errors.Errorf("unknown agent id %s", agentID.GetValue())
+}
+
+// sortedHostnames returns the sorted hostnames of the registered agents.
+// It must be called with the lock held.
+func (c *Cluster) sortedHostnames() []string {
+	hostnames := make([]string, 0, len(c.agents))
+	for hostname := range c.agents {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+func (a *agent) machineID() *mesos.MachineID {
+	hostname := a.hostname
+	ip := a.ip
+	return &mesos.MachineID{
+		Hostname: &hostname,
+		Ip:       &ip,
+	}
+}
+
+// toAgent returns the agent as reported by the GET_AGENTS call.
+func (a *agent) toAgent(config Config) *mesos_master.Response_GetAgents_Agent {
+	gpu := 0.0
+	if config.GPUHostInterval <= 1 || a.index%config.GPUHostInterval == 0 {
+		gpu = config.GPU
+	}
+	resources := util.CreateMesosScalarResources(map[string]float64{
+		common.MesosCPU:  config.CPU,
+		common.MesosMem:  config.MemMb,
+		common.MesosDisk: config.DiskMb,
+		common.MesosGPU:  gpu,
+	}, "*")
+	resources = append(resources, a.reserved...)
+	resources = append(resources, a.volumes...)
+
+	names := make([]string, 0, len(a.attributes))
+	for name := range a.attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	textType := mesos.Value_TEXT
+	var attributes []*mesos.Attribute
+	for _, name := range names {
+		name, value := name, a.attributes[name]
+		attributes = append(attributes, &mesos.Attribute{
+			Name: &name,
+			Type: &textType,
+			Text: &mesos.Value_Text{Value: &value},
+		})
+	}
+
+	id := a.id
+	hostname := a.hostname
+	pid := fmt.Sprintf("slave(1)@%s:%d", a.ip, _agentPort)
+	active := true
+	deactivated := a.deactivated
+	return &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{
+			Id:         &mesos.AgentID{Value: &id},
+			Hostname:   &hostname,
+			Resources:  resources,
+			Attributes: attributes,
+		},
+		Pid:            &pid,
+		Active:         &active,
+		TotalResources: resources,
+		Deactivated:    &deactivated,
+		DrainInfo:      a.drainInfo,
+	}
+}
+
+// sortedMachines returns the sorted hostnames of machines.
+func sortedMachines(machines map[string]*mesos.MachineID) []string {
+	hostnames := make([]string, 0, len(machines))
+	for hostname := range machines {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// removeResources returns resources without the ones in removed.
+func removeResources(
+	resources []*mesos.Resource,
+	removed []*mesos.Resource) []*mesos.Resource {
+	var result []*mesos.Resource
+	for _, r := range resources {
+		found := false
+		for _, rr := range removed {
+			if proto.Equal(r, rr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, r)
+		}
+	}
+	return result
+}
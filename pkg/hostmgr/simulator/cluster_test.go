@@ -0,0 +1,279 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/queue"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _numHosts = 10000
+
+type ClusterTestSuite struct {
+	suite.Suite
+
+	cluster *Cluster
+}
+
+func (suite *ClusterTestSuite) SetupTest() {
+	suite.cluster = NewCluster(Config{
+		NumHosts:        _numHosts,
+		CPU:             32,
+		MemMb:           128 * 1024,
+		DiskMb:          1024 * 1024,
+		GPU:             4,
+		GPUHostInterval: 10,
+		Attributes:      map[string]string{"zone": "z1"},
+		Seed:            1,
+	})
+}
+
+func TestClusterTestSuite(t *testing.T) {
+	suite.Run(t, new(ClusterTestSuite))
+}
+
+func (suite *ClusterTestSuite) schedule(hostnames ...string) *mesos_maintenance.Schedule {
+	var machineIDs []*mesos.MachineID
+	for _, hostname := range hostnames {
+		machineIDs = append(machineIDs, suite.cluster.MachineID(hostname))
+	}
+	return &mesos_maintenance.Schedule{
+		Windows: []*mesos_maintenance.Window{{MachineIds: machineIDs}},
+	}
+}
+
+// TestAgents tests the agents reported by the simulated master.
+func (suite *ClusterTestSuite) TestAgents() {
+	resp, err := suite.cluster.Agents()
+	suite.NoError(err)
+	suite.Len(resp.GetAgents(), _numHosts)
+
+	agent := resp.GetAgents()[0]
+	suite.Equal("host-0", agent.GetAgentInfo().GetHostname())
+	suite.Equal("agent-0", agent.GetAgentInfo().GetId().GetValue())
+	suite.Equal("slave(1)@10.0.0.0:5051", agent.GetPid())
+	suite.Equal("zone", agent.GetAgentInfo().GetAttributes()[0].GetName())
+
+	gpuHosts := 0
+	for _, agent := range resp.GetAgents() {
+		for _, r := range agent.GetTotalResources() {
+			if r.GetName() == common.MesosGPU {
+				gpuHosts++
+			}
+		}
+	}
+	suite.Equal(_numHosts/10, gpuHosts)
+
+	suite.NoError(suite.cluster.SetAttribute("host-0", "zone", "z2"))
+	suite.Error(suite.cluster.SetAttribute("unknown", "zone", "z2"))
+	resp, _ = suite.cluster.Agents()
+	suite.Equal(
+		"z2",
+		resp.GetAgents()[0].GetAgentInfo().GetAttributes()[0].GetText().GetValue())
+}
+
+// TestChurn tests that churn is deterministic for a seed and never
+// removes agents in maintenance.
+func (suite *ClusterTestSuite) TestChurn() {
+	suite.NoError(suite.cluster.UpdateMaintenanceSchedule(
+		context.Background(),
+		suite.schedule("host-1")))
+
+	added, removed := suite.cluster.Churn(100, _numHosts)
+	suite.Len(added, 100)
+	suite.Len(removed, _numHosts-1)
+	suite.Equal([]string{"host-1"}, difference(
+		suite.cluster.Hostnames(), added))
+
+	other := NewCluster(Config{NumHosts: 100, Seed: 1})
+	another := NewCluster(Config{NumHosts: 100, Seed: 1})
+	suite.Equal(other.RemoveHosts(10), another.RemoveHosts(10))
+}
+
+// TestMaintenance tests moving machines through the maintenance states.
+func (suite *ClusterTestSuite) TestMaintenance() {
+	machineID := suite.cluster.MachineID("host-1")
+
+	// Only DRAINING machines can be put into maintenance.
+	suite.Error(suite.cluster.StartMaintenance(
+		context.Background(),
+		[]*mesos.MachineID{machineID}))
+
+	suite.NoError(suite.cluster.UpdateMaintenanceSchedule(
+		context.Background(),
+		suite.schedule("host-1", "host-2")))
+	status, err := suite.cluster.GetMaintenanceStatus()
+	suite.NoError(err)
+	suite.Len(status.GetStatus().GetDrainingMachines(), 2)
+
+	suite.NoError(suite.cluster.StartMaintenance(
+		context.Background(),
+		[]*mesos.MachineID{machineID}))
+	status, _ = suite.cluster.GetMaintenanceStatus()
+	suite.Len(status.GetStatus().GetDrainingMachines(), 1)
+	suite.Len(status.GetStatus().GetDownMachines(), 1)
+	agents, _ := suite.cluster.Agents()
+	suite.Len(agents.GetAgents(), _numHosts-1)
+
+	// DOWN machines cannot be removed from the schedule.
+	suite.Error(suite.cluster.UpdateMaintenanceSchedule(
+		context.Background(),
+		suite.schedule("host-2")))
+
+	suite.NoError(suite.cluster.StopMaintenance(
+		context.Background(),
+		[]*mesos.MachineID{machineID}))
+	suite.Error(suite.cluster.StopMaintenance(
+		context.Background(),
+		[]*mesos.MachineID{machineID}))
+	status, _ = suite.cluster.GetMaintenanceStatus()
+	suite.Len(status.GetStatus().GetDownMachines(), 0)
+	agents, _ = suite.cluster.Agents()
+	suite.Len(agents.GetAgents(), _numHosts)
+
+	schedule, err := suite.cluster.GetMaintenanceSchedule()
+	suite.NoError(err)
+	suite.Len(schedule.GetSchedule().GetWindows(), 1)
+	suite.Len(schedule.GetSchedule().GetWindows()[0].GetMachineIds(), 1)
+}
+
+// TestAgentDraining tests draining and reactivating agents.
+func (suite *ClusterTestSuite) TestAgentDraining() {
+	agents, _ := suite.cluster.Agents()
+	agentID := agents.GetAgents()[0].GetAgentInfo().GetId()
+
+	// The master does not support agent draining by default.
+	master, err := suite.cluster.GetMaster()
+	suite.NoError(err)
+	suite.Empty(master.GetMasterInfo().GetCapabilities())
+	suite.Error(suite.cluster.DrainAgent(
+		context.Background(), agentID, time.Minute))
+
+	cluster := NewCluster(Config{NumHosts: 2, AgentDraining: true})
+	master, _ = cluster.GetMaster()
+	suite.Equal(
+		mesos.MasterInfo_Capability_AGENT_DRAINING,
+		master.GetMasterInfo().GetCapabilities()[0].GetType())
+
+	agents, _ = cluster.Agents()
+	agentID = agents.GetAgents()[0].GetAgentInfo().GetId()
+	suite.Error(cluster.ReactivateAgent(context.Background(), agentID))
+	suite.NoError(cluster.DrainAgent(
+		context.Background(), agentID, time.Minute))
+	agents, _ = cluster.Agents()
+	suite.True(agents.GetAgents()[0].GetDeactivated())
+	suite.Equal(
+		mesos.DrainState_DRAINED,
+		agents.GetAgents()[0].GetDrainInfo().GetState())
+	suite.Nil(agents.GetAgents()[1].GetDrainInfo())
+
+	suite.NoError(cluster.ReactivateAgent(context.Background(), agentID))
+	agents, _ = cluster.Agents()
+	suite.False(agents.GetAgents()[0].GetDeactivated())
+	suite.Nil(agents.GetAgents()[0].GetDrainInfo())
+}
+
+// TestReservations tests reserving resources and creating volumes.
+func (suite *ClusterTestSuite) TestReservations() {
+	agents, _ := suite.cluster.Agents()
+	agentID := agents.GetAgents()[0].GetAgentInfo().GetId()
+	numResources := len(agents.GetAgents()[0].GetTotalResources())
+
+	reserved := []*mesos.Resource{agents.GetAgents()[0].GetTotalResources()[0]}
+	suite.NoError(suite.cluster.ReserveResources(agentID, reserved))
+	suite.NoError(suite.cluster.CreateVolumes(agentID, reserved))
+	agents, _ = suite.cluster.Agents()
+	suite.Len(agents.GetAgents()[0].GetTotalResources(), numResources+2)
+
+	suite.NoError(suite.cluster.UnreserveResources(agentID, reserved))
+	suite.NoError(suite.cluster.DestroyVolumes(agentID, reserved))
+	agents, _ = suite.cluster.Agents()
+	suite.Len(agents.GetAgents()[0].GetTotalResources(), numResources)
+
+	unknown := "unknown"
+	suite.Error(suite.cluster.ReserveResources(
+		&mesos.AgentID{Value: &unknown}, reserved))
+}
+
+// TestLoaderAndDrainer tests loading the agent map and reconciling the
+// maintenance state of a simulated cluster at scale.
+func (suite *ClusterTestSuite) TestLoaderAndDrainer() {
+	drainingHosts := suite.cluster.Hostnames()[:100]
+	suite.NoError(suite.cluster.UpdateMaintenanceSchedule(
+		context.Background(),
+		suite.schedule(drainingHosts...)))
+
+	maintenanceHostInfoMap := host.NewMaintenanceHostInfoMap(tally.NoopScope)
+	maintenanceQueue := queue.NewMaintenanceQueue(0)
+	drainer := host.NewDrainer(
+		tally.NoopScope,
+		10*time.Millisecond,
+		suite.cluster,
+		maintenanceQueue,
+		maintenanceHostInfoMap,
+		nil)
+	drainer.Start()
+	defer drainer.Stop()
+
+	for i := 0; i < 100 && maintenanceQueue.Length() < len(drainingHosts); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	suite.Equal(len(drainingHosts), maintenanceQueue.Length())
+	suite.Len(
+		maintenanceHostInfoMap.GetDrainingHostInfos([]string{}),
+		len(drainingHosts))
+
+	loader := &host.Loader{
+		OperatorClient:         suite.cluster,
+		Scope:                  tally.NoopScope,
+		MaintenanceHostInfoMap: maintenanceHostInfoMap,
+	}
+	loader.Load(nil)
+	agentMap := host.GetAgentMap()
+	suite.Len(agentMap.RegisteredAgents, _numHosts-len(drainingHosts))
+	suite.Equal(
+		float64(32*(_numHosts-len(drainingHosts))),
+		agentMap.Capacity.GetCPU())
+
+	suite.cluster.Churn(10, 10)
+	loader.Load(nil)
+	suite.Len(host.GetAgentMap().RegisteredAgents, _numHosts-len(drainingHosts))
+}
+
+// difference returns the elements of a which are not in b.
+func difference(a []string, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !in[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"time"
+)
+
+const (
+	_defaultThreshold       = 30 * time.Minute
+	_defaultMinDeclines     = 10
+	_defaultMinDeclineRatio = 0.9
+)
+
+// Config is the configuration of the offer starvation detector.
+type Config struct {
+	// Period of the refresh of the metrics of the starved hosts. The
+	// detector is disabled if zero.
+	Period time.Duration `yaml:"period"`
+
+	// Time without its offers being used after which a host is starved,
+	// 30m if zero.
+	Threshold time.Duration `yaml:"threshold"`
+
+	// Number of declines of the offers of a host since they were last used
+	// under which the host is not starved, 10 if zero.
+	MinDeclines int `yaml:"min_declines"`
+
+	// Ratio of the declines to the matches of the offers of a host since
+	// they were last used under which the host is not starved, 0.9 if zero.
+	MinDeclineRatio float64 `yaml:"min_decline_ratio"`
+}
+
+// withDefaults returns the config with the defaults of the unset fields.
+func (c Config) withDefaults() Config {
+	if c.Threshold <= 0 {
+		c.Threshold = _defaultThreshold
+	}
+	if c.MinDeclines <= 0 {
+		c.MinDeclines = _defaultMinDeclines
+	}
+	if c.MinDeclineRatio <= 0 {
+		c.MinDeclineRatio = _defaultMinDeclineRatio
+	}
+	return c
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package starvation detects the hosts whose offers are consistently
+// declined by placement, e.g. because of task constraints, exclusivity or
+// host attributes nobody asks for, so that their stranded capacity becomes
+// visible.
+package starvation
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+// _declineResults are the results of matching a host which decline its
+// offers because of the host itself, i.e. of its attributes, resources or
+// host pool. The other results, such as a host without offers or already
+// held by another placement, do not tell whether the host is useful.
+var _declineResults = []hostsvc.HostFilterResult{
+	hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+	hostsvc.HostFilterResult_MISMATCH_GPU,
+	hostsvc.HostFilterResult_SCARCE_RESOURCES,
+	hostsvc.HostFilterResult_MISMATCH_HOST_POOL,
+}
+
+// HostReport is the offer starvation report of a host.
+type HostReport struct {
+	Hostname string `json:"hostname"`
+	// Starved is whether the offers of the host are consistently declined
+	Starved bool `json:"starved"`
+	// LastOffered is when offers of the host were last received
+	LastOffered time.Time `json:"last_offered"`
+	// LastUsed is when the offers of the host were last used to launch
+	// tasks, zero if they were not since the host is tracked
+	LastUsed time.Time `json:"last_used"`
+	// SecondsSinceUsed is the time since the offers of the host were
+	// last used, or since the host is tracked
+	SecondsSinceUsed float64 `json:"seconds_since_used"`
+	// Matches is the number of matches of the host since its offers were
+	// last used
+	Matches uint64 `json:"matches"`
+	// Declines are the numbers of declines of the host since its offers
+	// were last used, by reason
+	Declines map[string]uint64 `json:"declines"`
+	// Reason is the reason of most declines
+	Reason string `json:"reason,omitempty"`
+}
+
+// hostState is the offer usage of a host.
+type hostState struct {
+	// when the offers of the host were last used, or the host got tracked
+	since time.Time
+	// when the offers of the host were last used
+	lastUsed time.Time
+	// when offers of the host were last received
+	lastOffered time.Time
+	matches     uint64
+	// declines by result
+	declines map[hostsvc.HostFilterResult]uint64
+	// whether the host was starved at the last refresh
+	starved bool
+}
+
+// Detector tracks the time since the offers of each host were last used,
+// and the results of matching the host with the host filters of the
+// placements in the meantime. A host is starved once its offers have not
+// been used for the threshold, while they were declined at least the min
+// declines, and for at least the min decline ratio of the matches.
+// Hosts are forgotten once their agent is removed, or once they have not
+// been offered for the threshold, as a host without offers has no
+// stranded capacity.
+type Detector struct {
+	sync.Mutex
+
+	config  Config
+	metrics *Metrics
+	now     func() time.Time
+
+	hosts map[string]*hostState
+}
+
+// NewDetector returns a new offer starvation detector.
+func NewDetector(config Config, scope tally.Scope) *Detector {
+	return &Detector{
+		config:  config.withDefaults(),
+		metrics: NewMetrics(scope),
+		now:     time.Now,
+		hosts:   make(map[string]*hostState),
+	}
+}
+
+// Subscribe subscribes the detector to the offers received, the tasks
+// launched and the agents removed published on the event bus. Events are
+// dropped rather than slowing down the offer handling.
+func (d *Detector) Subscribe(
+	eventBus eventbus.Bus) (eventbus.Subscription, error) {
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name: "offer_starvation",
+		Topics: []eventbus.Topic{
+			eventbus.OffersReceived,
+			eventbus.TasksLaunched,
+			eventbus.AgentRemoved,
+		},
+		Policy: eventbus.Drop,
+		Handler: func(event eventbus.Event) {
+			switch e := event.(type) {
+			case *eventbus.OffersReceivedEvent:
+				for _, offer := range e.Offers {
+					d.RecordOffered(offer.GetHostname())
+				}
+			case *eventbus.TasksLaunchedEvent:
+				d.RecordUsed(e.Hostname)
+			case *eventbus.AgentRemovedEvent:
+				d.Forget(e.Hostname)
+			}
+		},
+	})
+}
+
+// RecordOffered records that offers of the host were received.
+func (d *Detector) RecordOffered(hostname string) {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	s, ok := d.hosts[hostname]
+	if !ok {
+		s = &hostState{
+			since:    now,
+			declines: make(map[hostsvc.HostFilterResult]uint64),
+		}
+		d.hosts[hostname] = s
+	}
+	s.lastOffered = now
+}
+
+// RecordMatchResult records the result of matching the offers of the host
+// against a host filter.
+func (d *Detector) RecordMatchResult(
+	hostname string,
+	result hostsvc.HostFilterResult) {
+	d.Lock()
+	defer d.Unlock()
+
+	s, ok := d.hosts[hostname]
+	if !ok {
+		return
+	}
+	if result == hostsvc.HostFilterResult_MATCH {
+		s.matches++
+		return
+	}
+	for _, r := range _declineResults {
+		if r == result {
+			s.declines[result]++
+			return
+		}
+	}
+}
+
+// RecordUsed records that the offers of the host were used to launch
+// tasks, which resets its matches and declines.
+func (d *Detector) RecordUsed(hostname string) {
+	d.Lock()
+	defer d.Unlock()
+
+	s, ok := d.hosts[hostname]
+	if !ok {
+		return
+	}
+	if s.starved {
+		s.starved = false
+		d.metrics.Recovered.Inc(1)
+		log.WithField("hostname", hostname).
+			Info("Offers of starved host got used")
+	}
+	now := d.now()
+	s.since = now
+	s.lastUsed = now
+	s.matches = 0
+	s.declines = make(map[hostsvc.HostFilterResult]uint64)
+}
+
+// Forget stops tracking the host.
+func (d *Detector) Forget(hostname string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.hosts, hostname)
+}
+
+// Report returns the reports of the tracked hosts, the starved hosts
+// only unless all is set. The reports are sorted by decreasing time since
+// the offers of the host were used, then by hostname.
+func (d *Detector) Report(all bool) []*HostReport {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	var reports []*HostReport
+	for hostname, s := range d.hosts {
+		r := d.report(hostname, s, now)
+		if r.Starved || all {
+			reports = append(reports, r)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].SecondsSinceUsed != reports[j].SecondsSinceUsed {
+			return reports[i].SecondsSinceUsed > reports[j].SecondsSinceUsed
+		}
+		return reports[i].Hostname < reports[j].Hostname
+	})
+	return reports
+}
+
+// Refresh forgets the hosts which were not offered for the threshold, and
+// updates the metrics of the starved hosts.
+func (d *Detector) Refresh(_ *atomic.Bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	starved := make(map[string]int)
+	for hostname, s := range d.hosts {
+		if now.Sub(s.lastOffered) >= d.config.Threshold {
+			delete(d.hosts, hostname)
+			continue
+		}
+		r := d.report(hostname, s, now)
+		if !r.Starved {
+			s.starved = false
+			continue
+		}
+		starved[r.Reason]++
+		if !s.starved {
+			s.starved = true
+			d.metrics.Starved.Inc(1)
+			log.WithFields(log.Fields{
+				"hostname":           hostname,
+				"seconds_since_used": r.SecondsSinceUsed,
+				"matches":            r.Matches,
+				"declines":           r.Declines,
+			}).Warn("Offers of host are consistently declined")
+		}
+	}
+
+	d.metrics.TrackedHosts.Update(float64(len(d.hosts)))
+	for _, result := range _declineResults {
+		reason := resultName(result)
+		d.metrics.StarvedHosts(reason).Update(float64(starved[reason]))
+	}
+}
+
+// report returns the report of the host at the given time.
+func (d *Detector) report(
+	hostname string,
+	s *hostState,
+	now time.Time) *HostReport {
+	r := &HostReport{
+		Hostname:         hostname,
+		LastOffered:      s.lastOffered,
+		LastUsed:         s.lastUsed,
+		SecondsSinceUsed: now.Sub(s.since).Seconds(),
+		Matches:          s.matches,
+		Declines:         make(map[string]uint64, len(s.declines)),
+	}
+
+	var declines, most uint64
+	for _, result := range _declineResults {
+		count := s.declines[result]
+		if count == 0 {
+			continue
+		}
+		r.Declines[resultName(result)] = count
+		declines += count
+		if count > most {
+			most = count
+			r.Reason = resultName(result)
+		}
+	}
+
+	total := declines + s.matches
+	r.Starved = now.Sub(s.since) >= d.config.Threshold &&
+		declines >= uint64(d.config.MinDeclines) &&
+		float64(declines) >= d.config.MinDeclineRatio*float64(total)
+	return r
+}
+
+// resultName returns the name of the result as in the match result
+// counts of the offer pool.
+func resultName(result hostsvc.HostFilterResult) string {
+	return strings.ToLower(result.String())
+}
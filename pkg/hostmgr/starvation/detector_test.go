@@ -0,0 +1,224 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type DetectorTestSuite struct {
+	suite.Suite
+
+	scope    tally.TestScope
+	detector *Detector
+	now      time.Time
+}
+
+func (suite *DetectorTestSuite) SetupTest() {
+	suite.scope = tally.NewTestScope("", map[string]string{})
+	suite.detector = NewDetector(Config{
+		Period:          time.Minute,
+		Threshold:       time.Hour,
+		MinDeclines:     3,
+		MinDeclineRatio: 0.75,
+	}, suite.scope)
+	suite.now = time.Unix(1000, 0)
+	suite.detector.now = func() time.Time { return suite.now }
+}
+
+func TestDetectorTestSuite(t *testing.T) {
+	suite.Run(t, new(DetectorTestSuite))
+}
+
+// gauge returns the value of a gauge of the detector
+func (suite *DetectorTestSuite) gauge(name string, reason string) float64 {
+	for _, g := range suite.scope.Snapshot().Gauges() {
+		if g.Name() == name && g.Tags()["reason"] == reason {
+			return g.Value()
+		}
+	}
+	return 0
+}
+
+// counter returns the value of a counter of the detector
+func (suite *DetectorTestSuite) counter(name string) int64 {
+	for _, c := range suite.scope.Snapshot().Counters() {
+		if c.Name() == name {
+			return c.Value()
+		}
+	}
+	return 0
+}
+
+// recordResults records the result of matching the host n times
+func (suite *DetectorTestSuite) recordResults(
+	hostname string,
+	result hostsvc.HostFilterResult,
+	n int) {
+	for i := 0; i < n; i++ {
+		suite.detector.RecordMatchResult(hostname, result)
+	}
+}
+
+// TestStarvedHost tests that a host whose offers are consistently declined
+// for the threshold is starved until its offers get used
+func (suite *DetectorTestSuite) TestStarvedHost() {
+	suite.detector.RecordOffered("host1")
+	suite.recordResults(
+		"host1", hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS, 3)
+	suite.recordResults("host1", hostsvc.HostFilterResult_MISMATCH_GPU, 1)
+	// results which do not tell whether the host is useful are ignored
+	suite.recordResults("host1", hostsvc.HostFilterResult_NO_OFFER, 5)
+	suite.recordResults("host1", hostsvc.HostFilterResult_MATCH, 1)
+
+	// not starved before the threshold
+	suite.now = suite.now.Add(30 * time.Minute)
+	suite.detector.RecordOffered("host1")
+	suite.Empty(suite.detector.Report(false))
+
+	suite.now = suite.now.Add(30 * time.Minute)
+	suite.detector.Refresh(nil)
+	reports := suite.detector.Report(false)
+	suite.Len(reports, 1)
+	suite.Equal("host1", reports[0].Hostname)
+	suite.True(reports[0].Starved)
+	suite.Equal(time.Hour.Seconds(), reports[0].SecondsSinceUsed)
+	suite.True(reports[0].LastUsed.IsZero())
+	suite.Equal(uint64(1), reports[0].Matches)
+	suite.Equal(map[string]uint64{
+		"mismatch_constraints": 3,
+		"mismatch_gpu":         1,
+	}, reports[0].Declines)
+	suite.Equal("mismatch_constraints", reports[0].Reason)
+	suite.Equal(float64(1), suite.gauge("starved_hosts", "mismatch_constraints"))
+	suite.Equal(float64(1), suite.gauge("tracked_hosts", ""))
+	suite.Equal(int64(1), suite.counter("starved"))
+
+	// a host is only counted once as it becomes starved
+	suite.detector.Refresh(nil)
+	suite.Equal(int64(1), suite.counter("starved"))
+
+	suite.detector.RecordUsed("host1")
+	suite.Empty(suite.detector.Report(false))
+	suite.Equal(int64(1), suite.counter("recovered"))
+	suite.detector.Refresh(nil)
+	suite.Zero(suite.gauge("starved_hosts", "mismatch_constraints"))
+
+	reports = suite.detector.Report(true)
+	suite.Len(reports, 1)
+	suite.False(reports[0].Starved)
+	suite.Equal(suite.now, reports[0].LastUsed)
+	suite.Empty(reports[0].Declines)
+}
+
+// TestNotStarvedHost tests that hosts with too few declines, or matched
+// too often, are not starved
+func (suite *DetectorTestSuite) TestNotStarvedHost() {
+	suite.detector.RecordOffered("host1")
+	suite.recordResults(
+		"host1", hostsvc.HostFilterResult_MISMATCH_HOST_POOL, 2)
+	suite.detector.RecordOffered("host2")
+	suite.recordResults(
+		"host2", hostsvc.HostFilterResult_SCARCE_RESOURCES, 6)
+	suite.recordResults("host2", hostsvc.HostFilterResult_MATCH, 3)
+	// hosts never offered are not tracked
+	suite.recordResults(
+		"host3", hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS, 10)
+
+	suite.now = suite.now.Add(2 * time.Hour)
+	suite.Empty(suite.detector.Report(false))
+
+	reports := suite.detector.Report(true)
+	suite.Len(reports, 2)
+	suite.Equal("host1", reports[0].Hostname)
+	suite.Equal("host2", reports[1].Hostname)
+}
+
+// TestForgetHosts tests that the hosts which are not offered for the
+// threshold, or whose agent is removed, are forgotten
+func (suite *DetectorTestSuite) TestForgetHosts() {
+	suite.detector.RecordOffered("host1")
+	suite.detector.RecordOffered("host2")
+	suite.now = suite.now.Add(45 * time.Minute)
+	suite.detector.RecordOffered("host2")
+	suite.detector.RecordOffered("host3")
+
+	suite.now = suite.now.Add(15 * time.Minute)
+	suite.detector.Refresh(nil)
+	suite.Len(suite.detector.Report(true), 2)
+	suite.Equal(float64(2), suite.gauge("tracked_hosts", ""))
+
+	suite.detector.Forget("host2")
+	reports := suite.detector.Report(true)
+	suite.Len(reports, 1)
+	suite.Equal("host3", reports[0].Hostname)
+}
+
+// TestSubscribe tests that the detector tracks the offers received, the
+// tasks launched and the agents removed published on the event bus
+func (suite *DetectorTestSuite) TestSubscribe() {
+	eventBus := eventbus.NewBus(tally.NoopScope)
+	defer eventBus.Close()
+
+	_, err := suite.detector.Subscribe(eventBus)
+	suite.NoError(err)
+
+	hostname := "host1"
+	eventBus.Publish(&eventbus.OffersReceivedEvent{
+		Offers: []*mesos.Offer{{Hostname: &hostname}},
+	})
+	suite.Eventually(func() bool {
+		return len(suite.detector.Report(true)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	suite.now = suite.now.Add(time.Minute)
+	eventBus.Publish(&eventbus.TasksLaunchedEvent{Hostname: "host1"})
+	suite.Eventually(func() bool {
+		return suite.detector.Report(true)[0].LastUsed.Equal(suite.now)
+	}, time.Second, 10*time.Millisecond)
+
+	eventBus.Publish(&eventbus.AgentRemovedEvent{Hostname: "host1"})
+	suite.Eventually(func() bool {
+		return len(suite.detector.Report(true)) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestDeclinePolicy tests that the decline policy records the match
+// results in the detector, and in the policy it wraps
+func (suite *DetectorTestSuite) TestDeclinePolicy() {
+	policy := NewDeclinePolicy(
+		declinepolicy.NewBackoffPolicy(declinepolicy.Config{
+			MismatchThreshold: 1,
+		}, tally.NoopScope),
+		suite.detector)
+	suite.Equal(declinepolicy.Backoff, policy.Name())
+
+	suite.detector.RecordOffered("host1")
+	policy.RecordMatchResult(
+		"host1", hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS)
+	suite.NotZero(policy.RefuseSeconds("host1"))
+	suite.Equal(map[string]uint64{"mismatch_constraints": 1},
+		suite.detector.Report(true)[0].Declines)
+}
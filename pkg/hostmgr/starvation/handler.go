@@ -0,0 +1,60 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Path is the debug endpoint of the offer starvation report. It lists
+	// the starved hosts, or all the tracked hosts with the all parameter.
+	Path = "/debug/hostmgr/starvation"
+
+	_allParam = "all"
+)
+
+// Handler returns a handler dumping the offer starvation report as JSON.
+func (d *Detector) Handler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var all bool
+		err := r.ParseForm()
+		if err == nil && r.Form.Get(_allParam) != "" {
+			all, err = strconv.ParseBool(r.Form.Get(_allParam))
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+
+		reports := d.Report(all)
+		if reports == nil {
+			reports = []*HostReport{}
+		}
+		body, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+func TestHandler(t *testing.T) {
+	detector := NewDetector(Config{MinDeclines: 1}, tally.NoopScope)
+	now := time.Unix(1000, 0)
+	detector.now = func() time.Time { return now }
+	detector.RecordOffered("host1")
+	detector.RecordOffered("host2")
+	detector.RecordMatchResult(
+		"host1", hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS)
+	now = now.Add(time.Hour)
+
+	for _, tc := range []struct {
+		query     string
+		code      int
+		hostnames []string
+	}{
+		{"", http.StatusOK, []string{"host1"}},
+		{"?all=true", http.StatusOK, []string{"host1", "host2"}},
+		{"?all=bogus", http.StatusBadRequest, nil},
+	} {
+		w := httptest.NewRecorder()
+		detector.Handler()(w, httptest.NewRequest("GET", Path+tc.query, nil))
+		assert.Equal(t, tc.code, w.Code, tc.query)
+		if tc.code != http.StatusOK {
+			continue
+		}
+
+		var reports []*HostReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reports))
+		var hostnames []string
+		for _, r := range reports {
+			hostnames = append(hostnames, r.Hostname)
+		}
+		assert.Equal(t, tc.hostnames, hostnames, tc.query)
+	}
+}
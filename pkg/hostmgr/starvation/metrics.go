@@ -0,0 +1,48 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics of the offer starvation detector.
+type Metrics struct {
+	scope tally.Scope
+
+	// Number of hosts whose offers are tracked
+	TrackedHosts tally.Gauge
+	// Number of hosts which became starved
+	Starved tally.Counter
+	// Number of starved hosts whose offers got used
+	Recovered tally.Counter
+}
+
+// NewMetrics returns a new instance of Metrics.
+func NewMetrics(scope tally.Scope) *Metrics {
+	return &Metrics{
+		scope:        scope,
+		TrackedHosts: scope.Gauge("tracked_hosts"),
+		Starved:      scope.Counter("starved"),
+		Recovered:    scope.Counter("recovered"),
+	}
+}
+
+// StarvedHosts is the number of starved hosts whose offers are mostly
+// declined for the given reason.
+func (m *Metrics) StarvedHosts(reason string) tally.Gauge {
+	return m.scope.Tagged(map[string]string{"reason": reason}).
+		Gauge("starved_hosts")
+}
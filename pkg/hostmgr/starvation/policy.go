@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starvation
+
+import (
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/hostmgr/declinepolicy"
+)
+
+// declinePolicy records the match results of the hosts in the detector
+// before passing them to the decline policy it wraps.
+type declinePolicy struct {
+	declinepolicy.Policy
+
+	detector *Detector
+}
+
+// NewDeclinePolicy returns a decline policy which is the same as policy,
+// but also records the results of matching the offers of the hosts with
+// host filters in the detector.
+func NewDeclinePolicy(
+	policy declinepolicy.Policy,
+	detector *Detector) declinepolicy.Policy {
+	return &declinePolicy{Policy: policy, detector: detector}
+}
+
+// RecordMatchResult is implementation of Policy.RecordMatchResult
+func (p *declinePolicy) RecordMatchResult(
+	hostname string,
+	result hostsvc.HostFilterResult) {
+	p.detector.RecordMatchResult(hostname, result)
+	p.Policy.RecordMatchResult(hostname, result)
+}
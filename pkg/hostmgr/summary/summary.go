@@ -22,6 +22,7 @@ import (
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/common/constraints"
@@ -100,6 +101,10 @@ const (
 	emptyOfferID = ""
 )
 
+// _taskEvaluator evaluates the constraints of kind TASK against the labels
+// of the tasks running on a host.
+var _taskEvaluator = constraints.NewEvaluator(task.LabelConstraint_TASK)
+
 // HostSummary is the core component of host manager's internal
 // data structure. It keeps track of offers in various state,
 // launching cycles and reservation information for a host.
@@ -118,6 +123,12 @@ type HostSummary interface {
 		hostFilter *hostsvc.HostFilter,
 		evaluator constraints.Evaluator) Match
 
+	// EvaluateFilter returns whether offers from the current host match the
+	// given constraint, and why they do not otherwise, without claiming them.
+	EvaluateFilter(
+		hostFilter *hostsvc.HostFilter,
+		evaluator constraints.Evaluator) (hostsvc.HostFilterResult, string)
+
 	// AddMesosOffer adds a Mesos offers to the current HostSummary.
 	AddMesosOffers(ctx context.Context, offer []*mesos.Offer) HostStatus
 
@@ -160,9 +171,22 @@ type HostSummary interface {
 	// GetHostname returns the hostname of the host
 	GetHostname() string
 
+	// GetUnavailability returns the unavailability window of the offers
+	// of the host which starts first, and nil if the host is not
+	// scheduled for maintenance
+	GetUnavailability() *mesos.Unavailability
+
 	// GetHostStatus returns the HostStatus of the host
 	GetHostStatus() HostStatus
 
+	// GetPlacingOfferExpiration returns the time at which the PLACING
+	// status of the host expires, and zero time if the host is not PLACING
+	GetPlacingOfferExpiration() time.Time
+
+	// GetHeldTasks returns a map from id of the tasks the host is held
+	// for to the expiration time of the hold
+	GetHeldTasks() map[string]time.Time
+
 	// HoldForTasks holds the host for the task specified.
 	// If an error is returned, hostsummary would guarantee that
 	// the host is not on held for the task
@@ -286,9 +310,57 @@ func matchHostFilter(
 	evaluator constraints.Evaluator,
 	scalarAgentRes scalar.Resources,
 	scarceResourceTypes []string) hostsvc.HostFilterResult {
+	result, _ := evaluateHostFilter(
+		offerMap,
+		c,
+		evaluator,
+		scalarAgentRes,
+		scarceResourceTypes)
+	return result
+}
+
+// evaluateHostFilter determines whether given HostFilter matches the given
+// map of offers, and explains why it does not match otherwise.
+func evaluateHostFilter(
+	offerMap map[string]*mesos.Offer,
+	c *hostsvc.HostFilter,
+	evaluator constraints.Evaluator,
+	scalarAgentRes scalar.Resources,
+	scarceResourceTypes []string) (hostsvc.HostFilterResult, string) {
 
 	if len(offerMap) == 0 {
-		return hostsvc.HostFilterResult_NO_OFFER
+		return hostsvc.HostFilterResult_NO_OFFER, "host has no offer"
+	}
+
+	// Only try to get first offer in this host because all the offers have
+	// the same host attributes.
+	var firstOffer *mesos.Offer
+	for _, offer := range offerMap {
+		firstOffer = offer
+		break
+	}
+
+	hostname := firstOffer.GetHostname()
+	if !matchHostPool(
+		hostname,
+		firstOffer.GetAttributes(),
+		c.GetHostPools()) {
+		return hostsvc.HostFilterResult_MISMATCH_HOST_POOL,
+			fmt.Sprintf("host pool %q is not one of %v",
+				host.GetAssignedHostPool(hostname, firstOffer.GetAttributes()),
+				c.GetHostPools())
+	}
+	if c.GetExcludeCordoned() && host.IsCordoned(hostname) {
+		return hostsvc.HostFilterResult_MISMATCH_CORDONED, "host is cordoned"
+	}
+	if lookahead := time.Duration(
+		c.GetUnavailabilityLookaheadSeconds()) * time.Second; lookahead > 0 {
+		if start, ok := getUnavailabilityWithin(
+			offerMap, time.Now(), lookahead); ok {
+			return hostsvc.HostFilterResult_MISMATCH_UNAVAILABILITY,
+				fmt.Sprintf("host is unavailable from %s, within %s",
+					start.UTC().Format(time.RFC3339), lookahead)
+		}
 	}
 
 	min := c.GetResourceConstraint().GetMinimum()
@@ -309,7 +381,9 @@ func matchHostFilter(
 
 		scalarMin := scalar.FromResourceConfig(min)
 		if !scalarRes.Contains(scalarMin) {
-			return hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES
+			return hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES,
+				fmt.Sprintf("offered resources %s do not contain %s",
+					scalarRes.String(), scalarMin.String())
 		}
 
 		// Validates iff requested resource types are present on current host.
@@ -317,42 +391,46 @@ func matchHostFilter(
 		// As of now, supported scarce resource type is GPU.
 		for _, resourceType := range scarceResourceTypes {
 			if scalar.HasResourceType(scalarAgentRes, scalarMin, resourceType) {
-				return hostsvc.HostFilterResult_SCARCE_RESOURCES
+				return hostsvc.HostFilterResult_SCARCE_RESOURCES,
+					fmt.Sprintf("host has %s resources, which are kept "+
+						"for tasks requesting them", resourceType)
 			}
 		}
 	}
 
 	// Match ports resources.
 	numPorts := c.GetResourceConstraint().GetNumPorts()
-	if numPorts > util.GetPortsNumFromOfferMap(offerMap) {
-		return hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES
+	if offeredPorts := util.GetPortsNumFromOfferMap(offerMap); numPorts > offeredPorts {
+		return hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES,
+			fmt.Sprintf("%d ports offered, %d requested", offeredPorts, numPorts)
 	}
 
-	// Only try to get first offer in this host because all the offers have
-	// the same host attributes.
-	var firstOffer *mesos.Offer
-	for _, offer := range offerMap {
-		firstOffer = offer
-		break
-	}
-
-	hostname := firstOffer.GetHostname()
 	hc := c.GetSchedulingConstraint()
 
-	// If constraints don't specify an exclusive host, then reject
-	// hosts that are designated as exclusive
-	if constraints.IsNonExclusiveConstraint(hc) &&
-		hmutil.HasExclusiveAttribute(firstOffer.GetAttributes()) {
+	// Reject hosts designated as exclusive, unless the constraints
+	// require one of their exclusive values
+	if !hmutil.MatchExclusiveAttribute(hc, firstOffer.GetAttributes()) {
 		log.WithField("hostname", hostname).Debug("Skipped exclusive host")
-		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS
+		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			fmt.Sprintf("host is exclusive to %v, and the constraint "+
+				"does not require it",
+				hmutil.GetExclusiveAttributeValues(firstOffer.GetAttributes()))
 	}
 
 	if hc == nil {
 		// No scheduling constraint, we have a match
-		return hostsvc.HostFilterResult_MATCH
+		return hostsvc.HostFilterResult_MATCH, ""
+	}
+
+	// The percentage requirements of the constraint are evaluated against
+	// the denominator of the filter, the instance count of the job.
+	taskEvaluator := _taskEvaluator
+	if d := c.GetConstraintDenominator(); d > 0 {
+		evaluator = constraints.WithDenominator(evaluator, d)
+		taskEvaluator = constraints.WithDenominator(taskEvaluator, d)
 	}
 
-	lv := constraints.GetHostLabelValues(
+	lv := host.GetHostLabelValues(
 		hostname,
 		firstOffer.GetAttributes(),
 	)
@@ -360,7 +438,8 @@ func matchHostFilter(
 	if err != nil {
 		log.WithError(err).
 			Error("Error when evaluating input constraint")
-		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS
+		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			fmt.Sprintf("failed to evaluate constraint: %v", err)
 	}
 
 	switch result {
@@ -378,10 +457,101 @@ func matchHostFilter(
 			"hostname":   hostname,
 			"constraint": hc,
 		}).Debug("Attributes do not match constraint")
-		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS
+		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			describeMismatchedConstraint(evaluator, hc, lv)
+	}
+
+	return evaluateTaskConstraint(hostname, hc, taskEvaluator)
+}
+
+// evaluateTaskConstraint evaluates the parts of kind TASK of a constraint,
+// e.g. job anti-affinity, against the labels of the tasks running on the
+// host, if they are tracked.
+func evaluateTaskConstraint(
+	hostname string,
+	hc *task.Constraint,
+	evaluator constraints.Evaluator) (hostsvc.HostFilterResult, string) {
+	lv, ok := host.GetTaskLabelValues(hostname)
+	if !ok {
+		return hostsvc.HostFilterResult_MATCH, ""
+	}
+	result, err := evaluator.Evaluate(hc, lv)
+	if err != nil {
+		log.WithError(err).
+			Error("Error when evaluating input constraint")
+		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			fmt.Sprintf("failed to evaluate constraint: %v", err)
+	}
+	if result == constraints.EvaluateResultMismatch {
+		return hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			describeMismatchedConstraint(evaluator, hc, lv)
+	}
+	return hostsvc.HostFilterResult_MATCH, ""
+}
+
+// describeMismatchedConstraint describes the part of a constraint which
+// the label values of a host do not satisfy.
+func describeMismatchedConstraint(
+	evaluator constraints.Evaluator,
+	constraint *task.Constraint,
+	lv constraints.LabelValues) string {
+	mismatched, err := constraints.GetMismatchedConstraint(
+		evaluator, constraint, lv)
+	if err != nil || mismatched == nil {
+		mismatched = constraint
+	}
+	description, err := constraints.MarshalJSON(mismatched)
+	if err != nil {
+		return fmt.Sprintf("constraint not satisfied: %v", mismatched)
+	}
+	return fmt.Sprintf("constraint not satisfied: %s", description)
+}
+
+// getUnavailabilityWithin returns the start of the unavailability window of
+// the offers, if it overlaps the lookahead from now. Unavailability windows
+// without a duration last forever.
+func getUnavailabilityWithin(
+	offerMap map[string]*mesos.Offer,
+	now time.Time,
+	lookahead time.Duration) (time.Time, bool) {
+	for _, offer := range offerMap {
+		unavailability := offer.GetUnavailability()
+		if unavailability.GetStart() == nil {
+			continue
+		}
+		start := time.Unix(0, unavailability.GetStart().GetNanoseconds())
+		if !start.Before(now.Add(lookahead)) {
+			continue
+		}
+		if unavailability.GetDuration() != nil {
+			end := start.Add(
+				time.Duration(unavailability.GetDuration().GetNanoseconds()))
+			if !end.After(now) {
+				continue
+			}
+		}
+		return start, true
 	}
+	return time.Time{}, false
+}
 
-	return hostsvc.HostFilterResult_MATCH
+// matchHostPool returns true if the host pool of a host, assigned or of
+// its agent attributes, is one of the requested host pools, or if no
+// host pool is requested.
+func matchHostPool(
+	hostname string,
+	attributes []*mesos.Attribute,
+	hostPools []string) bool {
+	if len(hostPools) == 0 {
+		return true
+	}
+	pool := host.GetAssignedHostPool(hostname, attributes)
+	for _, hostPool := range hostPools {
+		if hostPool == pool {
+			return true
+		}
+	}
+	return false
 }
 
 // TryMatch atomically tries to match offers from the current host with given
@@ -397,43 +567,7 @@ func (a *hostSummary) TryMatch(
 	a.Lock()
 	defer a.Unlock()
 
-	if a.status != ReadyHost && a.status != HeldHost {
-		return Match{
-			Result: hostsvc.HostFilterResult_MISMATCH_STATUS,
-		}
-	}
-
-	if !a.HasOffer() {
-		return Match{
-			Result: hostsvc.HostFilterResult_NO_OFFER,
-		}
-	}
-
-	// for host in Held state, it is only a match if the filter
-	// hint contains the host
-	if a.status == HeldHost {
-		var hintFound bool
-		for _, hostHint := range filter.GetHint().GetHostHint() {
-			if hostHint.GetHostname() == a.hostname {
-				hintFound = true
-				break
-			}
-		}
-
-		if !hintFound {
-			return Match{
-				Result: hostsvc.HostFilterResult_MISMATCH_STATUS,
-			}
-		}
-	}
-
-	result := matchHostFilter(
-		a.unreservedOffers,
-		filter,
-		evaluator,
-		scalar.FromMesosResources(host.GetAgentInfo(a.GetHostname()).GetResources()),
-		a.scarceResourceTypes)
-
+	result, _ := a.evaluateFilterLocked(filter, evaluator)
 	if result != hostsvc.HostFilterResult_MATCH {
 		return Match{Result: result}
 	}
@@ -476,6 +610,69 @@ func (a *hostSummary) TryMatch(
 	}
 }
 
+// EvaluateFilter returns whether offers from the current host match the
+// given HostFilter, and why they do not otherwise. Unlike TryMatch, the
+// status of the host is left unchanged.
+func (a *hostSummary) EvaluateFilter(
+	filter *hostsvc.HostFilter,
+	evaluator constraints.Evaluator) (hostsvc.HostFilterResult, string) {
+	a.Lock()
+	defer a.Unlock()
+
+	return a.evaluateFilterLocked(filter, evaluator)
+}
+
+// evaluateFilterLocked matches the status and offers of the current host
+// with given HostFilter. It must be called with the lock held.
+func (a *hostSummary) evaluateFilterLocked(
+	filter *hostsvc.HostFilter,
+	evaluator constraints.Evaluator) (hostsvc.HostFilterResult, string) {
+	if a.status != ReadyHost && a.status != HeldHost {
+		return hostsvc.HostFilterResult_MISMATCH_STATUS,
+			mismatchedStatusReason(a.status)
+	}
+
+	if !a.HasOffer() {
+		return hostsvc.HostFilterResult_NO_OFFER, "host has no unreserved offer"
+	}
+
+	// for host in Held state, it is only a match if the filter
+	// hint contains the host
+	if a.status == HeldHost {
+		var hintFound bool
+		for _, hostHint := range filter.GetHint().GetHostHint() {
+			if hostHint.GetHostname() == a.hostname {
+				hintFound = true
+				break
+			}
+		}
+
+		if !hintFound {
+			return hostsvc.HostFilterResult_MISMATCH_STATUS,
+				"host is held for other tasks"
+		}
+	}
+
+	return evaluateHostFilter(
+		a.unreservedOffers,
+		filter,
+		evaluator,
+		scalar.FromMesosResources(host.GetAgentInfo(a.GetHostname()).GetResources()),
+		a.scarceResourceTypes)
+}
+
+// mismatchedStatusReason explains why hosts with a status other than
+// ready or held are not matched.
+func mismatchedStatusReason(status HostStatus) string {
+	switch status {
+	case PlacingHost:
+		return "host is being used by a placement engine"
+	case ReservedHost:
+		return "host is reserved for tasks"
+	}
+	return fmt.Sprintf("host status %d is not ready", status)
+}
+
 // AddMesosOffers adds a Mesos offers to the current hostSummary and returns
 // its status for tracking purpose.
 func (a *hostSummary) AddMesosOffers(
@@ -790,6 +987,27 @@ func (a *hostSummary) GetHostname() string {
 	return a.hostname
 }
 
+// GetUnavailability returns the unavailability window of the offers of
+// the host which starts first, and nil if the host is not scheduled for
+// maintenance
+func (a *hostSummary) GetUnavailability() *mesos.Unavailability {
+	a.Lock()
+	defer a.Unlock()
+	offers := make(
+		[]*mesos.Offer, 0, len(a.unreservedOffers)+len(a.reservedOffers))
+	for _, offer := range a.unreservedOffers {
+		offers = append(offers, offer)
+	}
+	for _, offer := range a.reservedOffers {
+		offers = append(offers, offer)
+	}
+	unavailability := hmutil.GetUnavailability(offers)
+	if unavailability == nil {
+		return nil
+	}
+	return proto.Clone(unavailability).(*mesos.Unavailability)
+}
+
 // GetHostStatus returns the HostStatus of the host
 func (a *hostSummary) GetHostStatus() HostStatus {
 	a.Lock()
@@ -797,6 +1015,30 @@ func (a *hostSummary) GetHostStatus() HostStatus {
 	return a.status
 }
 
+// GetPlacingOfferExpiration returns the time at which the PLACING status
+// of the host expires
+func (a *hostSummary) GetPlacingOfferExpiration() time.Time {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.status != PlacingHost {
+		return time.Time{}
+	}
+	return a.statusPlacingOfferExpiration
+}
+
+// GetHeldTasks returns a copy of the tasks the host is held for
+func (a *hostSummary) GetHeldTasks() map[string]time.Time {
+	a.Lock()
+	defer a.Unlock()
+
+	heldTasks := make(map[string]time.Time, len(a.heldTasks))
+	for taskID, expiration := range a.heldTasks {
+		heldTasks[taskID] = expiration
+	}
+	return heldTasks
+}
+
 // HoldForTasks holds the host for the task specified
 func (a *hostSummary) HoldForTask(id *peloton.TaskID) error {
 	a.Lock()
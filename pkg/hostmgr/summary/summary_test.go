@@ -33,12 +33,15 @@ import (
 	"github.com/uber/peloton/pkg/common/constraints"
 	constraint_mocks "github.com/uber/peloton/pkg/common/constraints/mocks"
 	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/host"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 	store_mocks "github.com/uber/peloton/pkg/storage/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
 
 	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
 )
 
 var (
@@ -543,6 +546,117 @@ func (suite *HostOfferSummaryTestSuite) TestScarceResourcesConstraint() {
 	}
 }
 
+// TestHostPoolAndCordonFilter tests filtering hosts by host pool and cordon
+func (suite *HostOfferSummaryTestSuite) TestHostPoolAndCordonFilter() {
+	defer suite.ctrl.Finish()
+
+	poolAttribute := "pool"
+	batchPool := "batch"
+	host.SetHostPoolAttribute(poolAttribute)
+	defer host.SetHostPoolAttribute("")
+
+	hostCordonOps := objectmocks.NewMockHostCordonOps(suite.ctrl)
+	hostCordonOps.EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	cordonMap := host.NewCordonMap(hostCordonOps, tally.NoopScope)
+
+	batchHost := "batch-host"
+	defaultHost := "default-host"
+	offers := map[string]*mesos.Offer{
+		batchHost: {
+			Hostname:  &batchHost,
+			Resources: []*mesos.Resource{_cpuRes, _memRes, _diskRes},
+			Attributes: []*mesos.Attribute{{
+				Name: &poolAttribute,
+				Text: &mesos.Value_Text{Value: &batchPool},
+			}},
+		},
+		defaultHost: {
+			Hostname:  &defaultHost,
+			Resources: []*mesos.Resource{_cpuRes, _memRes, _diskRes},
+		},
+	}
+	hostCordonOps.EXPECT().Delete(gomock.Any(), defaultHost).Return(nil)
+	suite.NoError(cordonMap.Cordon(
+		context.Background(), []string{defaultHost}, "bad disk"))
+	defer cordonMap.Uncordon(context.Background(), []string{defaultHost})
+
+	testTable := []struct {
+		msg      string
+		hostname string
+		filter   *hostsvc.HostFilter
+		expected hostsvc.HostFilterResult
+	}{
+		{
+			msg:      "Any host pool",
+			hostname: batchHost,
+			filter:   &hostsvc.HostFilter{},
+			expected: hostsvc.HostFilterResult_MATCH,
+		},
+		{
+			msg:      "Matching host pool",
+			hostname: batchHost,
+			filter: &hostsvc.HostFilter{
+				HostPools: []string{"shared", batchPool},
+			},
+			expected: hostsvc.HostFilterResult_MATCH,
+		},
+		{
+			msg:      "Mismatching host pool",
+			hostname: defaultHost,
+			filter: &hostsvc.HostFilter{
+				HostPools: []string{batchPool},
+			},
+			expected: hostsvc.HostFilterResult_MISMATCH_HOST_POOL,
+		},
+		{
+			msg:      "Default host pool",
+			hostname: defaultHost,
+			filter: &hostsvc.HostFilter{
+				HostPools: []string{host.DefaultHostPool},
+			},
+			expected: hostsvc.HostFilterResult_MATCH,
+		},
+		{
+			msg:      "Cordoned host not excluded",
+			hostname: defaultHost,
+			filter:   &hostsvc.HostFilter{},
+			expected: hostsvc.HostFilterResult_MATCH,
+		},
+		{
+			msg:      "Cordoned host excluded",
+			hostname: defaultHost,
+			filter: &hostsvc.HostFilter{
+				ExcludeCordoned: true,
+			},
+			expected: hostsvc.HostFilterResult_MISMATCH_CORDONED,
+		},
+		{
+			msg:      "Uncordoned host not excluded",
+			hostname: batchHost,
+			filter: &hostsvc.HostFilter{
+				ExcludeCordoned: true,
+			},
+			expected: hostsvc.HostFilterResult_MATCH,
+		},
+	}
+
+	for _, tt := range testTable {
+		suite.Equal(
+			tt.expected,
+			matchHostFilter(
+				map[string]*mesos.Offer{"o1": offers[tt.hostname]},
+				tt.filter,
+				nil,
+				scalar.Resources{},
+				nil),
+			tt.msg,
+		)
+	}
+}
+
 func (suite *HostOfferSummaryTestSuite) TestSlackResourcesConstraint() {
 	defer suite.ctrl.Finish()
 
@@ -649,13 +763,21 @@ func (suite *HostOfferSummaryTestSuite) TestTryMatchSchedulingConstraint() {
 	offer := suite.createUnreservedMesosOffer("offer-id")
 	offers := suite.createUnreservedMesosOffers(5)
 
-	exclHostOffer := suite.createUnreservedMesosOffer("excl-offer-id")
 	exclAttrName := "peloton/exclusive"
-	exclHostOffer.Attributes = []*mesos.Attribute{
-		&mesos.Attribute{
-			Name: &exclAttrName,
-		},
+	textType := mesos.Value_TEXT
+	exclAttribute := func(value string) []*mesos.Attribute {
+		return []*mesos.Attribute{
+			&mesos.Attribute{
+				Name: &exclAttrName,
+				Type: &textType,
+				Text: &mesos.Value_Text{Value: &value},
+			},
+		}
 	}
+	exclHostOffer := suite.createUnreservedMesosOffer("excl-offer-id")
+	exclHostOffer.Attributes = exclAttribute("web-tier")
+	otherExclHostOffer := suite.createUnreservedMesosOffer("other-excl-offer-id")
+	otherExclHostOffer.Attributes = exclAttribute("gpu")
 
 	seqIDGenerator := func(i string) func() string {
 		return func() string {
@@ -756,6 +878,16 @@ func (suite *HostOfferSummaryTestSuite) TestTryMatchSchedulingConstraint() {
 			initialOffers:  []*mesos.Offer{exclHostOffer},
 			offerID:        emptyOfferID,
 		},
+		"other-exclusive-host-exclusive-constraint-mismatch": {
+			wantResult:         hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+			expectedOffers:     []*mesos.Offer{otherExclHostOffer},
+			noMock:             true, // mockEvaluator should not be called
+			initialStatus:      ReadyHost,
+			afterStatus:        ReadyHost,
+			initialOffers:      []*mesos.Offer{otherExclHostOffer},
+			offerID:            emptyOfferID,
+			exclHostConstraint: true,
+		},
 		"non-exclusive-host-exclusive-constraint-mismatch": {
 			wantResult:         hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
 			expectedOffers:     offers,
@@ -824,7 +956,7 @@ func (suite *HostOfferSummaryTestSuite) TestTryMatchSchedulingConstraint() {
 			}
 		}
 
-		lv := constraints.GetHostLabelValues(
+		lv := host.GetHostLabelValues(
 			offer0.GetHostname(),
 			offer0.Attributes)
 
@@ -1415,6 +1547,28 @@ func (suite *HostOfferSummaryTestSuite) TestHoldAndReleaseTask() {
 
 	suite.Equal(hs0.GetHostStatus(), ReadyHost)
 	suite.Equal(hs1.GetHostStatus(), HeldHost)
+
+	suite.Empty(hs0.GetHeldTasks())
+	heldTasks := hs1.GetHeldTasks()
+	suite.Len(heldTasks, 1)
+	suite.Contains(heldTasks, t3.GetValue())
+	suite.True(heldTasks[t3.GetValue()].After(time.Now()))
+}
+
+func (suite *HostOfferSummaryTestSuite) TestGetPlacingOfferExpiration() {
+	defer suite.ctrl.Finish()
+
+	hs := New(suite.mockVolumeStore, nil, _testAgent, supportedSlackResourceTypes, time.Duration(30*time.Second)).(*hostSummary)
+	suite.True(hs.GetPlacingOfferExpiration().IsZero())
+
+	expiration := time.Now().Add(30 * time.Second)
+	hs.status = PlacingHost
+	hs.statusPlacingOfferExpiration = expiration
+	suite.Equal(expiration, hs.GetPlacingOfferExpiration())
+
+	// expiration is only reported while the host is PLACING
+	hs.status = ReadyHost
+	suite.True(hs.GetPlacingOfferExpiration().IsZero())
 }
 
 func (suite *HostOfferSummaryTestSuite) TestReturnPlacingHost() {
@@ -1437,3 +1591,195 @@ func (suite *HostOfferSummaryTestSuite) TestReturnPlacingHost() {
 	suite.Equal(hs.GetHostStatus(), HeldHost)
 
 }
+
+// TestEvaluateFilter tests evaluating a host filter, which explains why
+// a host does not match without changing its status.
+func (suite *HostOfferSummaryTestSuite) TestEvaluateFilter() {
+	defer suite.ctrl.Finish()
+
+	hs := New(suite.mockVolumeStore, nil, _testAgent, supportedSlackResourceTypes, time.Duration(30*time.Second)).(*hostSummary)
+	result, reason := hs.EvaluateFilter(&hostsvc.HostFilter{}, nil)
+	suite.Equal(hostsvc.HostFilterResult_NO_OFFER, result)
+	suite.NotEmpty(reason)
+
+	suite.Equal(ReadyHost, hs.AddMesosOffers(
+		context.Background(),
+		suite.createUnreservedMesosOffers(1)))
+	filter := func(multiplier float64) *hostsvc.HostFilter {
+		return &hostsvc.HostFilter{
+			ResourceConstraint: &hostsvc.ResourceConstraint{
+				Minimum: &task.ResourceConfig{
+					CpuLimit:    _defaultResValue * multiplier,
+					MemLimitMb:  _defaultResValue,
+					DiskLimitMb: _defaultResValue,
+				},
+			},
+		}
+	}
+
+	result, reason = hs.EvaluateFilter(filter(1), nil)
+	suite.Equal(hostsvc.HostFilterResult_MATCH, result)
+	suite.Empty(reason)
+	suite.Equal(ReadyHost, hs.GetHostStatus())
+
+	result, reason = hs.EvaluateFilter(filter(7), nil)
+	suite.Equal(hostsvc.HostFilterResult_INSUFFICIENT_OFFER_RESOURCES, result)
+	suite.Contains(reason, "do not contain")
+
+	hs.status = PlacingHost
+	result, reason = hs.EvaluateFilter(filter(1), nil)
+	suite.Equal(hostsvc.HostFilterResult_MISMATCH_STATUS, result)
+	suite.Equal(mismatchedStatusReason(PlacingHost), reason)
+}
+
+// TestUnavailabilityFilter tests filtering out hosts with an unavailability
+// window overlapping the lookahead of the host filter.
+func (suite *HostOfferSummaryTestSuite) TestUnavailabilityFilter() {
+	defer suite.ctrl.Finish()
+
+	now := time.Now()
+	unavailability := func(start time.Duration, duration time.Duration) *mesos.Unavailability {
+		startNanos := now.Add(start).UnixNano()
+		u := &mesos.Unavailability{
+			Start: &mesos.TimeInfo{Nanoseconds: &startNanos},
+		}
+		if duration > 0 {
+			durationNanos := duration.Nanoseconds()
+			u.Duration = &mesos.DurationInfo{Nanoseconds: &durationNanos}
+		}
+		return u
+	}
+
+	testTable := map[string]struct {
+		unavailability *mesos.Unavailability
+		lookahead      uint32
+		wantResult     hostsvc.HostFilterResult
+	}{
+		"no-unavailability": {
+			lookahead:  3600,
+			wantResult: hostsvc.HostFilterResult_MATCH,
+		},
+		"unavailability-not-considered": {
+			unavailability: unavailability(time.Minute, 0),
+			wantResult:     hostsvc.HostFilterResult_MATCH,
+		},
+		"unavailability-within-lookahead": {
+			unavailability: unavailability(30*time.Minute, time.Hour),
+			lookahead:      3600,
+			wantResult:     hostsvc.HostFilterResult_MISMATCH_UNAVAILABILITY,
+		},
+		"unavailability-after-lookahead": {
+			unavailability: unavailability(2*time.Hour, 0),
+			lookahead:      3600,
+			wantResult:     hostsvc.HostFilterResult_MATCH,
+		},
+		"unavailability-in-progress": {
+			unavailability: unavailability(-time.Hour, 0),
+			lookahead:      3600,
+			wantResult:     hostsvc.HostFilterResult_MISMATCH_UNAVAILABILITY,
+		},
+		"unavailability-over": {
+			unavailability: unavailability(-2*time.Hour, time.Hour),
+			lookahead:      3600,
+			wantResult:     hostsvc.HostFilterResult_MATCH,
+		},
+	}
+
+	for ttName, tt := range testTable {
+		offer := suite.createUnreservedMesosOffer("offer-id")
+		offer.Unavailability = tt.unavailability
+
+		result, reason := evaluateHostFilter(
+			map[string]*mesos.Offer{"offer-id": offer},
+			&hostsvc.HostFilter{
+				UnavailabilityLookaheadSeconds: tt.lookahead,
+			},
+			nil,
+			scalar.Resources{},
+			nil)
+		suite.Equal(tt.wantResult, result, "test case is %s", ttName)
+		if result != hostsvc.HostFilterResult_MATCH {
+			suite.Contains(reason, "unavailable", "test case is %s", ttName)
+		}
+	}
+}
+
+// TestConstraintDenominator tests that the percentage requirements of the
+// scheduling constraint are evaluated against the denominator of the host
+// filter, through the metrics decorator of the evaluator.
+func (suite *HostOfferSummaryTestSuite) TestConstraintDenominator() {
+	defer suite.ctrl.Finish()
+
+	evaluator := constraints.WithMetrics(
+		constraints.NewEvaluator(task.LabelConstraint_HOST),
+		task.LabelConstraint_HOST,
+		tally.NoopScope)
+	// less than 20% of the instances on the host
+	constraint := &task.Constraint{
+		Type: task.Constraint_LABEL_CONSTRAINT,
+		LabelConstraint: &task.LabelConstraint{
+			Kind: task.LabelConstraint_HOST,
+			Label: &peloton.Label{
+				Key:   constraints.HostNameKey,
+				Value: _testAgent,
+			},
+			Condition:          task.LabelConstraint_CONDITION_LESS_THAN,
+			RequirementPercent: 20,
+		},
+	}
+
+	testTable := map[string]struct {
+		denominator uint32
+		wantResult  hostsvc.HostFilterResult
+	}{
+		"no-denominator": {
+			wantResult: hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+		},
+		"requirement-met": {
+			denominator: 10,
+			wantResult:  hostsvc.HostFilterResult_MATCH,
+		},
+		"requirement-not-met": {
+			denominator: 2,
+			wantResult:  hostsvc.HostFilterResult_MISMATCH_CONSTRAINTS,
+		},
+	}
+
+	for ttName, tt := range testTable {
+		offer := suite.createUnreservedMesosOffer("offer-id")
+		result, _ := evaluateHostFilter(
+			map[string]*mesos.Offer{"offer-id": offer},
+			&hostsvc.HostFilter{
+				SchedulingConstraint:  constraint,
+				ConstraintDenominator: tt.denominator,
+			},
+			evaluator,
+			scalar.Resources{},
+			nil)
+		suite.Equal(tt.wantResult, result, "test case is %s", ttName)
+	}
+}
+
+// TestGetUnavailability tests getting the unavailability window of the
+// offers of a host which starts first.
+func (suite *HostOfferSummaryTestSuite) TestGetUnavailability() {
+	defer suite.ctrl.Finish()
+
+	s := New(suite.mockVolumeStore, nil, "host1", supportedSlackResourceTypes, time.Duration(30*time.Second))
+	suite.Nil(s.GetUnavailability())
+
+	offer1 := suite.createUnreservedMesosOffer("offer-id-1")
+	offer2 := suite.createUnreservedMesosOffer("offer-id-2")
+	start1, start2 := int64(200), int64(100)
+	offer1.Unavailability = &mesos.Unavailability{
+		Start: &mesos.TimeInfo{Nanoseconds: &start1},
+	}
+	offer2.Unavailability = &mesos.Unavailability{
+		Start: &mesos.TimeInfo{Nanoseconds: &start2},
+	}
+	s.AddMesosOffers(context.Background(), []*mesos.Offer{offer1, offer2})
+	suite.Equal(start2, s.GetUnavailability().GetStart().GetNanoseconds())
+
+	s.RemoveMesosOffer("offer-id-2", "test")
+	suite.Equal(start1, s.GetUnavailability().GetStart().GetNanoseconds())
+}
@@ -0,0 +1,399 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/concurrency"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// _indexStorageTimeout is the timeout of each storage call made to
+	// persist or recover the index.
+	_indexStorageTimeout = 10 * time.Second
+
+	// _indexRecoverWorkers is the number of hosts whose tasks are
+	// recovered concurrently.
+	_indexRecoverWorkers = 16
+)
+
+// HostTaskIndex is an in-memory inverse index of the Mesos tasks running on
+// each host, maintained from the tasks launched by host manager and from the
+// task status updates received from Mesos. It lets host manager answer
+// which tasks run on a host, and where a task runs, without calling out to
+// resource manager or job manager.
+type HostTaskIndex interface {
+	// AddTasks records that the tasks were launched on the host.
+	AddTasks(hostname string, agentID *mesos.AgentID, taskIDs []string)
+
+	// UpdateTaskStatus updates the index from a Mesos task status update.
+	// Tasks in terminal states are removed from the index.
+	UpdateTaskStatus(status *mesos.TaskStatus)
+
+	// GetTasksByHosts returns the sorted Mesos task ids running on each of
+	// the given hosts, or on all the indexed hosts if no host is given.
+	GetTasksByHosts(hostnames []string) map[string][]string
+
+	// GetHostsByTasks returns the host each of the given Mesos tasks is
+	// running on. Tasks which are not indexed are not returned.
+	GetHostsByTasks(taskIDs []string) map[string]string
+
+	// GetExecutor returns the agent and executor of a Mesos task as last
+	// reported by its status updates, or nils if they are not known.
+	GetExecutor(taskID string) (*mesos.AgentID, *mesos.ExecutorID)
+
+	// Persist writes the hosts whose tasks changed since the last call to
+	// storage. It is run periodically as a background work.
+	Persist(_ *uatomic.Bool)
+
+	// Recover loads the persisted tasks of the given hosts into the index.
+	Recover(ctx context.Context, hostnames []string) error
+}
+
+// hostTaskIndex implements HostTaskIndex interface
+type hostTaskIndex struct {
+	sync.RWMutex
+
+	// host to ids of the tasks running on the host
+	hostTasks map[string]map[string]struct{}
+	// task id to the host it is running on
+	taskHosts map[string]string
+	// agent id to hostname, learnt from the launched tasks
+	agentHosts map[string]string
+	// task id to the agent and executor running it, learnt from the
+	// task status updates and not persisted
+	taskExecutors map[string]*taskExecutor
+	// hosts which changed since the index was last persisted
+	dirtyHosts map[string]struct{}
+
+	// hostTasksOps persists the index, persistence is disabled if nil
+	hostTasksOps   ormobjects.HostTasksOps
+	metrics        *Metrics
+	recoverMetrics *concurrency.PoolMetrics
+}
+
+// taskExecutor is the agent and executor running a task.
+type taskExecutor struct {
+	agentID    *mesos.AgentID
+	executorID *mesos.ExecutorID
+}
+
+// NewHostTaskIndex returns a new HostTaskIndex, which is persisted using
+// hostTasksOps. The index is kept in memory only if hostTasksOps is nil.
+func NewHostTaskIndex(
+	hostTasksOps ormobjects.HostTasksOps,
+	scope tally.Scope) HostTaskIndex {
+	return &hostTaskIndex{
+		hostTasks:     make(map[string]map[string]struct{}),
+		taskHosts:     make(map[string]string),
+		agentHosts:    make(map[string]string),
+		taskExecutors: make(map[string]*taskExecutor),
+		dirtyHosts:    make(map[string]struct{}),
+		hostTasksOps:  hostTasksOps,
+		metrics:       NewMetrics(scope.SubScope("host_task_index")),
+		recoverMetrics: concurrency.NewPoolMetrics(
+			scope.SubScope("host_task_index_recover")),
+	}
+}
+
+// AddTasks records that the tasks were launched on the host.
+func (i *hostTaskIndex) AddTasks(
+	hostname string,
+	agentID *mesos.AgentID,
+	taskIDs []string) {
+	i.Lock()
+	defer i.Unlock()
+
+	if agentID.GetValue() != "" {
+		i.agentHosts[agentID.GetValue()] = hostname
+	}
+	for _, taskID := range taskIDs {
+		i.addTask(hostname, taskID)
+	}
+	i.updateGauges()
+}
+
+// UpdateTaskStatus updates the index from a Mesos task status update.
+func (i *hostTaskIndex) UpdateTaskStatus(status *mesos.TaskStatus) {
+	taskID := status.GetTaskId().GetValue()
+	if taskID == "" {
+		return
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	state := util.MesosStateToPelotonState(status.GetState())
+	if util.IsPelotonStateTerminal(state) {
+		i.removeTask(taskID)
+		i.updateGauges()
+		return
+	}
+
+	if status.GetExecutorId().GetValue() != "" &&
+		status.GetAgentId().GetValue() != "" {
+		i.taskExecutors[taskID] = &taskExecutor{
+			agentID:    status.GetAgentId(),
+			executorID: status.GetExecutorId(),
+		}
+	}
+
+	// Tasks launched before a restart, and not recovered from storage,
+	// are indexed from their status updates.
+	if _, ok := i.taskHosts[taskID]; ok {
+		return
+	}
+	hostname := i.getHostname(status.GetAgentId())
+	if hostname == "" {
+		i.metrics.indexUnknownAgents.Inc(1)
+		return
+	}
+	i.addTask(hostname, taskID)
+	i.updateGauges()
+}
+
+// GetTasksByHosts returns the sorted Mesos task ids
+// running on each of the given hosts.
+func (i *hostTaskIndex) GetTasksByHosts(hostnames []string) map[string][]string {
+	i.RLock()
+	defer i.RUnlock()
+
+	if len(hostnames) == 0 {
+		for hostname := range i.hostTasks {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	result := make(map[string][]string)
+	for _, hostname := range hostnames {
+		tasks, ok := i.hostTasks[hostname]
+		if !ok {
+			continue
+		}
+		result[hostname] = sortedTaskIDs(tasks)
+	}
+	return result
+}
+
+// GetHostsByTasks returns the host each of the given Mesos tasks is
+// running on.
+func (i *hostTaskIndex) GetHostsByTasks(taskIDs []string) map[string]string {
+	i.RLock()
+	defer i.RUnlock()
+
+	result := make(map[string]string)
+	for _, taskID := range taskIDs {
+		if hostname, ok := i.taskHosts[taskID]; ok {
+			result[taskID] = hostname
+		}
+	}
+	return result
+}
+
+// GetExecutor returns the agent and executor of a Mesos task.
+func (i *hostTaskIndex) GetExecutor(
+	taskID string) (*mesos.AgentID, *mesos.ExecutorID) {
+	i.RLock()
+	defer i.RUnlock()
+
+	executor, ok := i.taskExecutors[taskID]
+	if !ok {
+		return nil, nil
+	}
+	return executor.agentID, executor.executorID
+}
+
+// Persist writes the hosts whose tasks changed since the last call
+// to storage.
+func (i *hostTaskIndex) Persist(_ *uatomic.Bool) {
+	if i.hostTasksOps == nil {
+		return
+	}
+
+	i.Lock()
+	dirtyHosts := make(map[string][]string, len(i.dirtyHosts))
+	for hostname := range i.dirtyHosts {
+		dirtyHosts[hostname] = sortedTaskIDs(i.hostTasks[hostname])
+	}
+	i.dirtyHosts = make(map[string]struct{})
+	i.Unlock()
+
+	var failedHosts []string
+	for hostname, taskIDs := range dirtyHosts {
+		if err := i.persistHost(hostname, taskIDs); err != nil {
+			log.WithError(err).
+				WithField("hostname", hostname).
+				Warn("Failed to persist tasks of host")
+			i.metrics.indexPersistFail.Inc(1)
+			failedHosts = append(failedHosts, hostname)
+			continue
+		}
+		i.metrics.indexPersist.Inc(1)
+	}
+
+	if len(failedHosts) == 0 {
+		return
+	}
+
+	// Retry the failed hosts on the next run.
+	i.Lock()
+	defer i.Unlock()
+	for _, hostname := range failedHosts {
+		i.dirtyHosts[hostname] = struct{}{}
+	}
+}
+
+// Recover loads the persisted tasks of the given hosts into the index.
+func (i *hostTaskIndex) Recover(ctx context.Context, hostnames []string) error {
+	if i.hostTasksOps == nil {
+		return nil
+	}
+
+	return concurrency.ForEach(
+		ctx,
+		len(hostnames),
+		concurrency.WorkerPoolOptions{
+			MaxWorkers:  _indexRecoverWorkers,
+			StopOnError: true,
+		},
+		i.recoverMetrics,
+		func(ctx context.Context, n int) error {
+			hostname := hostnames[n]
+			storageCtx, cancel := context.WithTimeout(ctx, _indexStorageTimeout)
+			taskIDs, err := i.hostTasksOps.Get(storageCtx, hostname)
+			cancel()
+			if err != nil {
+				i.metrics.indexRecoverFail.Inc(1)
+				return err
+			}
+
+			i.Lock()
+			defer i.Unlock()
+			for _, taskID := range taskIDs {
+				// Tasks indexed since the start are more recent
+				// than the persisted index.
+				if _, ok := i.taskHosts[taskID]; ok {
+					continue
+				}
+				i.addTask(hostname, taskID)
+				i.metrics.indexRecovered.Inc(1)
+			}
+			i.updateGauges()
+			return nil
+		})
+}
+
+// persistHost writes the tasks of a host to storage, and removes
+// the host from storage if no task is running on it.
+func (i *hostTaskIndex) persistHost(hostname string, taskIDs []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), _indexStorageTimeout)
+	defer cancel()
+
+	if len(taskIDs) == 0 {
+		return i.hostTasksOps.Delete(ctx, hostname)
+	}
+	return i.hostTasksOps.Update(ctx, hostname, taskIDs)
+}
+
+// addTask adds a task to the index, moving it from the host it was
+// previously indexed on. It must be called with the lock held.
+func (i *hostTaskIndex) addTask(hostname string, taskID string) {
+	if previous, ok := i.taskHosts[taskID]; ok {
+		if previous == hostname {
+			return
+		}
+		i.removeTask(taskID)
+	}
+
+	tasks, ok := i.hostTasks[hostname]
+	if !ok {
+		tasks = make(map[string]struct{})
+		i.hostTasks[hostname] = tasks
+	}
+	tasks[taskID] = struct{}{}
+	i.taskHosts[taskID] = hostname
+	i.dirtyHosts[hostname] = struct{}{}
+}
+
+// removeTask removes a task from the index. It must be called with
+// the lock held.
+func (i *hostTaskIndex) removeTask(taskID string) {
+	delete(i.taskExecutors, taskID)
+	hostname, ok := i.taskHosts[taskID]
+	if !ok {
+		return
+	}
+
+	delete(i.taskHosts, taskID)
+	delete(i.hostTasks[hostname], taskID)
+	if len(i.hostTasks[hostname]) == 0 {
+		delete(i.hostTasks, hostname)
+	}
+	i.dirtyHosts[hostname] = struct{}{}
+}
+
+// getHostname returns the hostname of an agent, looking it up in the
+// agent map if no task was launched on the agent since the start.
+// It must be called with the lock held.
+func (i *hostTaskIndex) getHostname(agentID *mesos.AgentID) string {
+	if agentID.GetValue() == "" {
+		return ""
+	}
+	if hostname, ok := i.agentHosts[agentID.GetValue()]; ok {
+		return hostname
+	}
+
+	agentMap := host.GetAgentMap()
+	if agentMap == nil {
+		return ""
+	}
+	for hostname, agent := range agentMap.RegisteredAgents {
+		if agent.GetAgentInfo().GetId().GetValue() == agentID.GetValue() {
+			i.agentHosts[agentID.GetValue()] = hostname
+			return hostname
+		}
+	}
+	return ""
+}
+
+// updateGauges reports the size of the index. It must be called with
+// the lock held.
+func (i *hostTaskIndex) updateGauges() {
+	i.metrics.indexedHosts.Update(float64(len(i.hostTasks)))
+	i.metrics.indexedTasks.Update(float64(len(i.taskHosts)))
+}
+
+// sortedTaskIDs returns the task ids of a set in sorted order.
+func sortedTaskIDs(tasks map[string]struct{}) []string {
+	taskIDs := make([]string, 0, len(tasks))
+	for taskID := range tasks {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+	return taskIDs
+}
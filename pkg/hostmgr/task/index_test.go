@@ -0,0 +1,298 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_indexHostname  = "hostname"
+	_indexHostname2 = "hostname2"
+	_indexAgentID   = "agent-id"
+	_indexAgentID2  = "agent-id2"
+	_indexTaskID    = "job-0-run"
+	_indexTaskID2   = "job-1-run"
+)
+
+type hostTaskIndexTestSuite struct {
+	suite.Suite
+
+	ctrl         *gomock.Controller
+	testScope    tally.TestScope
+	hostTasksOps *objectmocks.MockHostTasksOps
+	index        HostTaskIndex
+}
+
+func (s *hostTaskIndexTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.testScope = tally.NewTestScope("", map[string]string{})
+	s.hostTasksOps = objectmocks.NewMockHostTasksOps(s.ctrl)
+	s.index = NewHostTaskIndex(s.hostTasksOps, s.testScope)
+}
+
+func (s *hostTaskIndexTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func TestHostTaskIndex(t *testing.T) {
+	suite.Run(t, new(hostTaskIndexTestSuite))
+}
+
+func newIndexTaskStatus(
+	taskID string,
+	agentID string,
+	state mesos.TaskState) *mesos.TaskStatus {
+	return &mesos.TaskStatus{
+		TaskId:  &mesos.TaskID{Value: &taskID},
+		AgentId: &mesos.AgentID{Value: &agentID},
+		State:   &state,
+	}
+}
+
+func newIndexAgentID(agentID string) *mesos.AgentID {
+	return &mesos.AgentID{Value: &agentID}
+}
+
+// TestAddAndRemoveTasks tests that launched tasks are indexed until
+// a terminal status update is received.
+func (s *hostTaskIndexTestSuite) TestAddAndRemoveTasks() {
+	s.index.AddTasks(
+		_indexHostname,
+		newIndexAgentID(_indexAgentID),
+		[]string{_indexTaskID2, _indexTaskID})
+
+	s.Equal(
+		map[string][]string{_indexHostname: {_indexTaskID, _indexTaskID2}},
+		s.index.GetTasksByHosts(nil))
+	s.Equal(
+		map[string]string{
+			_indexTaskID:  _indexHostname,
+			_indexTaskID2: _indexHostname,
+		},
+		s.index.GetHostsByTasks([]string{_indexTaskID, _indexTaskID2}))
+
+	// Non-terminal status updates keep the task indexed.
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID, _indexAgentID, mesos.TaskState_TASK_RUNNING))
+	s.Equal(
+		map[string]string{_indexTaskID: _indexHostname},
+		s.index.GetHostsByTasks([]string{_indexTaskID}))
+
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID, _indexAgentID, mesos.TaskState_TASK_FINISHED))
+	s.Equal(
+		map[string][]string{_indexHostname: {_indexTaskID2}},
+		s.index.GetTasksByHosts([]string{_indexHostname, _indexHostname2}))
+	s.Empty(s.index.GetHostsByTasks([]string{_indexTaskID}))
+
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID2, _indexAgentID, mesos.TaskState_TASK_KILLED))
+	s.Empty(s.index.GetTasksByHosts(nil))
+	s.Equal(
+		float64(0),
+		s.testScope.Snapshot().Gauges()["host_task_index.indexed_tasks+"].Value())
+}
+
+// loadAgentMap loads an agent map with a single agent into host package.
+func (s *hostTaskIndexTestSuite) loadAgentMap(hostname string, agentID string) {
+	operatorClient := mpb_mocks.NewMockMasterOperatorClient(s.ctrl)
+	maintenanceMap := hm.NewMockMaintenanceHostInfoMap(s.ctrl)
+	loader := &host.Loader{
+		OperatorClient:         operatorClient,
+		Scope:                  s.testScope,
+		MaintenanceHostInfoMap: maintenanceMap,
+	}
+
+	operatorClient.EXPECT().Agents().Return(
+		&mesos_master.Response_GetAgents{
+			Agents: []*mesos_master.Response_GetAgents_Agent{
+				{
+					AgentInfo: &mesos.AgentInfo{
+						Hostname: &hostname,
+						Id:       &mesos.AgentID{Value: &agentID},
+					},
+				},
+			},
+		}, nil)
+	maintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{hostname}).
+		Return([]*hpb.HostInfo{})
+	loader.Load(nil)
+}
+
+// TestUpdateTaskStatusUnknownTask tests that running tasks which were not
+// launched since the start are indexed from their status updates.
+func (s *hostTaskIndexTestSuite) TestUpdateTaskStatusUnknownTask() {
+	s.loadAgentMap(_indexHostname2, _indexAgentID2)
+
+	// Agent learnt from launched tasks.
+	s.index.AddTasks(
+		_indexHostname,
+		newIndexAgentID(_indexAgentID),
+		[]string{_indexTaskID})
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID2, _indexAgentID, mesos.TaskState_TASK_RUNNING))
+
+	// Agent looked up in the agent map.
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		"job-2-run", _indexAgentID2, mesos.TaskState_TASK_RUNNING))
+
+	// Unknown agent.
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		"job-3-run", "unknown-agent", mesos.TaskState_TASK_RUNNING))
+
+	s.Equal(
+		map[string][]string{
+			_indexHostname:  {_indexTaskID, _indexTaskID2},
+			_indexHostname2: {"job-2-run"},
+		},
+		s.index.GetTasksByHosts(nil))
+	s.Equal(
+		int64(1),
+		s.testScope.Snapshot().Counters()["host_task_index.index_unknown_agents+"].Value())
+}
+
+// TestGetExecutor tests that the executor of a task is learnt from its
+// status updates and forgotten once the task terminates.
+func (s *hostTaskIndexTestSuite) TestGetExecutor() {
+	s.index.AddTasks(
+		_indexHostname,
+		newIndexAgentID(_indexAgentID),
+		[]string{_indexTaskID})
+
+	agentID, executorID := s.index.GetExecutor(_indexTaskID)
+	s.Nil(agentID)
+	s.Nil(executorID)
+
+	executorIDValue := "thermos-" + _indexTaskID
+	status := newIndexTaskStatus(
+		_indexTaskID, _indexAgentID, mesos.TaskState_TASK_RUNNING)
+	status.ExecutorId = &mesos.ExecutorID{Value: &executorIDValue}
+	s.index.UpdateTaskStatus(status)
+
+	agentID, executorID = s.index.GetExecutor(_indexTaskID)
+	s.Equal(_indexAgentID, agentID.GetValue())
+	s.Equal(executorIDValue, executorID.GetValue())
+
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID, _indexAgentID, mesos.TaskState_TASK_KILLED))
+	agentID, executorID = s.index.GetExecutor(_indexTaskID)
+	s.Nil(agentID)
+	s.Nil(executorID)
+}
+
+// TestPersist tests that only the changed hosts are persisted, and that
+// hosts failed to be persisted are retried.
+func (s *hostTaskIndexTestSuite) TestPersist() {
+	s.index.AddTasks(
+		_indexHostname,
+		newIndexAgentID(_indexAgentID),
+		[]string{_indexTaskID})
+	s.index.AddTasks(
+		_indexHostname2,
+		newIndexAgentID(_indexAgentID2),
+		[]string{_indexTaskID2})
+
+	s.hostTasksOps.EXPECT().
+		Update(gomock.Any(), _indexHostname, []string{_indexTaskID}).
+		Return(nil)
+	s.hostTasksOps.EXPECT().
+		Update(gomock.Any(), _indexHostname2, []string{_indexTaskID2}).
+		Return(errors.New("update failed"))
+	s.index.Persist(nil)
+
+	// Hosts without tasks are removed from storage.
+	s.index.UpdateTaskStatus(newIndexTaskStatus(
+		_indexTaskID, _indexAgentID, mesos.TaskState_TASK_FAILED))
+
+	s.hostTasksOps.EXPECT().
+		Delete(gomock.Any(), _indexHostname).
+		Return(nil)
+	s.hostTasksOps.EXPECT().
+		Update(gomock.Any(), _indexHostname2, []string{_indexTaskID2}).
+		Return(nil)
+	s.index.Persist(nil)
+
+	// Nothing changed since the last run.
+	s.index.Persist(nil)
+
+	s.Equal(
+		int64(3),
+		s.testScope.Snapshot().Counters()["host_task_index.index_persist+"].Value())
+	s.Equal(
+		int64(1),
+		s.testScope.Snapshot().Counters()["host_task_index.index_persist_fail+"].Value())
+}
+
+// TestRecover tests that persisted tasks are loaded into the index, without
+// overriding the tasks indexed since the start.
+func (s *hostTaskIndexTestSuite) TestRecover() {
+	s.index.AddTasks(
+		_indexHostname2,
+		newIndexAgentID(_indexAgentID2),
+		[]string{_indexTaskID2})
+
+	s.hostTasksOps.EXPECT().
+		Get(gomock.Any(), _indexHostname).
+		Return([]string{_indexTaskID, _indexTaskID2}, nil)
+	s.hostTasksOps.EXPECT().
+		Get(gomock.Any(), _indexHostname2).
+		Return(nil, nil)
+	s.NoError(s.index.Recover(
+		context.Background(),
+		[]string{_indexHostname, _indexHostname2}))
+
+	s.Equal(
+		map[string]string{
+			_indexTaskID:  _indexHostname,
+			_indexTaskID2: _indexHostname2,
+		},
+		s.index.GetHostsByTasks([]string{_indexTaskID, _indexTaskID2}))
+
+	s.hostTasksOps.EXPECT().
+		Get(gomock.Any(), _indexHostname).
+		Return(nil, errors.New("get failed"))
+	s.Error(s.index.Recover(context.Background(), []string{_indexHostname}))
+}
+
+// TestInMemoryIndex tests that the index is not persisted without storage.
+func (s *hostTaskIndexTestSuite) TestInMemoryIndex() {
+	index := NewHostTaskIndex(nil, s.testScope)
+	index.AddTasks(
+		_indexHostname,
+		newIndexAgentID(_indexAgentID),
+		[]string{_indexTaskID})
+	index.Persist(nil)
+	s.NoError(index.Recover(context.Background(), []string{_indexHostname}))
+	s.Equal(
+		map[string][]string{_indexHostname: {_indexTaskID}},
+		index.GetTasksByHosts(nil))
+}
@@ -26,6 +26,14 @@ type Metrics struct {
 	taskAckMapSize      tally.Gauge
 	taskUpdateAckDeDupe tally.Counter
 
+	indexedHosts       tally.Gauge
+	indexedTasks       tally.Gauge
+	indexPersist       tally.Counter
+	indexPersistFail   tally.Counter
+	indexRecovered     tally.Counter
+	indexRecoverFail   tally.Counter
+	indexUnknownAgents tally.Counter
+
 	scope tally.Scope
 }
 
@@ -39,6 +47,14 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		taskAckMapSize:      scope.Gauge("task_ack_map_size"),
 		taskUpdateAckDeDupe: scope.Counter("task_update_ack_dedupe"),
 
+		indexedHosts:       scope.Gauge("indexed_hosts"),
+		indexedTasks:       scope.Gauge("indexed_tasks"),
+		indexPersist:       scope.Counter("index_persist"),
+		indexPersistFail:   scope.Counter("index_persist_fail"),
+		indexRecovered:     scope.Counter("index_recovered_tasks"),
+		indexRecoverFail:   scope.Counter("index_recover_fail"),
+		indexUnknownAgents: scope.Counter("index_unknown_agents"),
+
 		scope: scope,
 	}
 }
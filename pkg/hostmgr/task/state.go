@@ -36,6 +36,7 @@ import (
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/cirbuf"
 	"github.com/uber/peloton/pkg/common/eventstream"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 )
@@ -58,6 +59,10 @@ type StateManager interface {
 	// GetStatusUpdateEvents returns all the outstanding status update events
 	// from the event stream
 	GetStatusUpdateEvents() ([]*pb_eventstream.Event, error)
+
+	// OnAttributesChanged adds a host event to the event stream when
+	// the attributes of an agent change.
+	OnAttributesChanged(previous *mesos.AgentInfo, current *mesos.AgentInfo)
 }
 
 type stateManager struct {
@@ -68,6 +73,8 @@ type stateManager struct {
 	ackStatusMap         sync.Map
 
 	eventStreamHandler *eventstream.Handler
+	hostTaskIndex      HostTaskIndex
+	eventBus           eventbus.Bus
 	metrics            *Metrics
 }
 
@@ -133,6 +140,8 @@ func NewStateManager(
 	updateBufferSize int,
 	updateAckConcurrency int,
 	resmgrClient resmgrsvc.ResourceManagerServiceYARPCClient,
+	hostTaskIndex HostTaskIndex,
+	eventBus eventbus.Bus,
 	parentScope tally.Scope) StateManager {
 
 	stateManagerScope := parentScope.SubScope("taskStateManager")
@@ -140,6 +149,8 @@ func NewStateManager(
 		schedulerclient:      schedulerClient,
 		updateAckConcurrency: updateAckConcurrency,
 		ackChannel:           make(chan *mesos.TaskStatus, updateBufferSize),
+		hostTaskIndex:        hostTaskIndex,
+		eventBus:             eventBus,
 		metrics:              NewMetrics(stateManagerScope),
 	}
 	mpb.Register(
@@ -217,6 +228,11 @@ func (m *stateManager) Update(ctx context.Context, body *sched.Event) error {
 		"task_state_" + taskUpdate.GetStatus().GetState().String())
 	taskStateCounter.Inc(1)
 
+	m.hostTaskIndex.UpdateTaskStatus(taskUpdate.GetStatus())
+	m.eventBus.Publish(&eventbus.TaskStatusUpdatedEvent{
+		Status: taskUpdate.GetStatus(),
+	})
+
 	event := &pb_eventstream.Event{
 		MesosTaskStatus: taskUpdate.GetStatus(),
 		Type:            pb_eventstream.Event_MESOS_TASK_STATUS,
@@ -234,6 +250,44 @@ func (m *stateManager) Update(ctx context.Context, body *sched.Event) error {
 	return nil
 }
 
+// OnAttributesChanged adds a host event to the event stream when
+// the attributes of an agent change.
+func (m *stateManager) OnAttributesChanged(
+	previous *mesos.AgentInfo,
+	current *mesos.AgentInfo) {
+	event := &pb_eventstream.Event{
+		Type: pb_eventstream.Event_HOST_EVENT,
+		HostEvent: &pb_eventstream.HostEvent{
+			Type:               pb_eventstream.HostEvent_ATTRIBUTES_CHANGED,
+			Hostname:           current.GetHostname(),
+			AgentId:            current.GetId(),
+			PreviousAttributes: previous.GetAttributes(),
+			Attributes:         current.GetAttributes(),
+		},
+	}
+	if err := m.eventStreamHandler.AddEvent(event); err != nil {
+		log.WithError(err).
+			WithField("hostname", current.GetHostname()).
+			Error("Cannot add host event")
+	}
+}
+
+// SubscribeHostEvents adds the attribute changes published on the event
+// bus to the host event stream of the state manager.
+func SubscribeHostEvents(
+	eventBus eventbus.Bus,
+	stateManager StateManager) (eventbus.Subscription, error) {
+	return eventBus.Subscribe(eventbus.Subscriber{
+		Name:   "host_event_stream",
+		Topics: []eventbus.Topic{eventbus.AttributesChanged},
+		Policy: eventbus.Block,
+		Handler: func(event eventbus.Event) {
+			change := event.(*eventbus.AttributesChangedEvent)
+			stateManager.OnAttributesChanged(change.Previous, change.Current)
+		},
+	})
+}
+
 // UpdateCounters tracks the count for task status update & ack count.
 func (m *stateManager) UpdateCounters(_ *uatomic.Bool) {
 	m.metrics.taskAckChannelSize.Update(float64(len(m.ackChannel)))
@@ -30,6 +30,7 @@ import (
 	res_mocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/rpc"
+	"github.com/uber/peloton/pkg/hostmgr/eventbus"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
@@ -149,6 +150,8 @@ func (s *stateManagerTestSuite) createNewStateManager(ackConcurrency int) StateM
 		10,
 		ackConcurrency,
 		s.resMgrClient,
+		NewHostTaskIndex(nil, s.testScope),
+		eventbus.NewBus(s.testScope),
 		s.testScope)
 }
 
@@ -275,6 +278,51 @@ func (s *stateManagerTestSuite) TestAckTaskStatusUpdate() {
 	s.Equal(s.testScope.Snapshot().Gauges()["taskStateManager.task_ack_map_size+"].Value(), float64(0))
 }
 
+func (s *stateManagerTestSuite) TestAttributesChangedAddsHostEvent() {
+	s.stateManager = s.createNewStateManager(10)
+	s.resMgrClient.EXPECT().
+		NotifyTaskUpdates(gomock.Any(), gomock.Any()).
+		Return(&resmgrsvc.NotifyTaskUpdatesResponse{}, nil).
+		AnyTimes()
+
+	hostname := "hostname"
+	agentID := "agent-id"
+	attrName := "rack"
+	previousValue := "rack1"
+	currentValue := "rack2"
+	textType := mesos.Value_TEXT
+	makeAgentInfo := func(value string) *mesos.AgentInfo {
+		return &mesos.AgentInfo{
+			Hostname: &hostname,
+			Id:       &mesos.AgentID{Value: &agentID},
+			Attributes: []*mesos.Attribute{
+				{
+					Name: &attrName,
+					Type: &textType,
+					Text: &mesos.Value_Text{Value: &value},
+				},
+			},
+		}
+	}
+	previous := makeAgentInfo(previousValue)
+	current := makeAgentInfo(currentValue)
+
+	s.stateManager.OnAttributesChanged(previous, current)
+
+	events, err := s.stateManager.GetStatusUpdateEvents()
+	s.NoError(err)
+	s.Equal(1, len(events))
+	s.Equal(pb_eventstream.Event_HOST_EVENT, events[0].GetType())
+	s.Equal(
+		pb_eventstream.HostEvent_ATTRIBUTES_CHANGED,
+		events[0].GetHostEvent().GetType())
+	s.Equal(hostname, events[0].GetHostEvent().GetHostname())
+	s.Equal(agentID, events[0].GetHostEvent().GetAgentId().GetValue())
+	s.Equal(previous.GetAttributes(),
+		events[0].GetHostEvent().GetPreviousAttributes())
+	s.Equal(current.GetAttributes(), events[0].GetHostEvent().GetAttributes())
+}
+
 func TestStateManager(t *testing.T) {
 	suite.Run(t, new(stateManagerTestSuite))
 }
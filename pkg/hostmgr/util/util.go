@@ -18,9 +18,11 @@ import (
 	"strings"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/constraints"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 
 	log "github.com/sirupsen/logrus"
@@ -50,10 +52,11 @@ func MesosOffersToHostOffers(hostoffers map[string][]*mesos.Offer) []*hostsvc.Ho
 		}
 
 		hostOffer := hostsvc.HostOffer{
-			Hostname:   hostname,
-			AgentId:    offers[0].GetAgentId(),
-			Attributes: attributes,
-			Resources:  resources,
+			Hostname:       hostname,
+			AgentId:        offers[0].GetAgentId(),
+			Attributes:     attributes,
+			Resources:      resources,
+			Unavailability: GetUnavailability(offers),
 		}
 
 		hostOffers = append(hostOffers, &hostOffer)
@@ -91,3 +94,55 @@ func HasExclusiveAttribute(attributes []*mesos.Attribute) bool {
 	}
 	return false
 }
+
+// GetExclusiveAttributeValues returns the values of the "peloton/exclusive"
+// text attributes in the provided attributes.
+func GetExclusiveAttributeValues(attributes []*mesos.Attribute) []string {
+	var values []string
+	for _, attr := range attributes {
+		if common.PelotonExclusiveAttributeName == attr.GetName() &&
+			attr.GetType() == mesos.Value_TEXT {
+			values = append(values, attr.GetText().GetValue())
+		}
+	}
+	return values
+}
+
+// MatchExclusiveAttribute returns true if a task with the given
+// scheduling constraint may be placed on a host with the given
+// attributes. Hosts designated as exclusive only take the tasks which
+// require one of the values of their exclusive attribute.
+func MatchExclusiveAttribute(
+	constraint *task.Constraint,
+	attributes []*mesos.Attribute) bool {
+	if !HasExclusiveAttribute(attributes) {
+		return true
+	}
+	values := GetExclusiveAttributeValues(attributes)
+	for _, required := range constraints.GetRequiredExclusiveValues(constraint) {
+		for _, value := range values {
+			if value == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetUnavailability returns the unavailability window of the given offers
+// of a host which starts first, and nil if none of them has one.
+func GetUnavailability(offers []*mesos.Offer) *mesos.Unavailability {
+	var earliest *mesos.Unavailability
+	for _, offer := range offers {
+		unavailability := offer.GetUnavailability()
+		if unavailability.GetStart() == nil {
+			continue
+		}
+		if earliest == nil ||
+			unavailability.GetStart().GetNanoseconds() <
+				earliest.GetStart().GetNanoseconds() {
+			earliest = unavailability
+		}
+	}
+	return earliest
+}
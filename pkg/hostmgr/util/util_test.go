@@ -186,4 +186,39 @@ func TestHasExclusiveAttribute(t *testing.T) {
 			HasExclusiveAttribute(tc.attributes),
 			tc.msg)
 	}
+
+	assert.Equal(
+		t,
+		[]string{tv1, tv2},
+		GetExclusiveAttributeValues([]*mesos.Attribute{excl1, other1, excl2}))
+	assert.Empty(
+		t,
+		GetExclusiveAttributeValues([]*mesos.Attribute{other1, other2}))
+}
+
+// TestGetUnavailability tests getting the unavailability window of the
+// offers of a host which starts first.
+func TestGetUnavailability(t *testing.T) {
+	unavailability := func(start int64) *mesos.Unavailability {
+		return &mesos.Unavailability{
+			Start: &mesos.TimeInfo{Nanoseconds: &start},
+		}
+	}
+
+	var offers []*mesos.Offer
+	for _, offer := range createUnreservedMesosOffers(3) {
+		offers = append(offers, offer)
+	}
+	assert.Nil(t, GetUnavailability(offers))
+
+	offers[1].Unavailability = unavailability(200)
+	offers[2].Unavailability = unavailability(100)
+	assert.Equal(t, int64(100),
+		GetUnavailability(offers).GetStart().GetNanoseconds())
+
+	hostOffers := MesosOffersToHostOffers(
+		map[string][]*mesos.Offer{_testAgent: offers})
+	assert.Len(t, hostOffers, 1)
+	assert.Equal(t, int64(100),
+		hostOffers[0].GetUnavailability().GetStart().GetNanoseconds())
 }
@@ -174,6 +174,9 @@ func (t *asyncEventProcessor) addEvent(event *pbeventstream.Event) error {
 		taskID = event.PelotonTaskEvent.TaskId.Value
 		log.WithField("Task ID", taskID).Debug("Received Event " +
 			"from resmgr")
+	} else if event.Type == pbeventstream.Event_HOST_EVENT {
+		// Host events do not carry any task update
+		return nil
 	}
 
 	_, instanceID, err := util.ParseTaskID(taskID)
@@ -284,6 +284,25 @@ func (suite *BucketEventProcessorTestSuite) TestBucketEventProcessor_AddEventFai
 	suite.Error(err)
 }
 
+// TestBucketEventProcessor_AddHostEvent tests that host events are skipped
+// since they carry no task update
+func (suite *BucketEventProcessorTestSuite) TestBucketEventProcessor_AddHostEvent() {
+	hostname := "hostname"
+	applier := newBucketEventProcessor(suite.statusProcessor, 15, 100)
+	applier.start()
+	err := applier.addEvent(&pbeventstream.Event{
+		Offset: 1,
+		Type:   pbeventstream.Event_HOST_EVENT,
+		HostEvent: &pbeventstream.HostEvent{
+			Type:     pbeventstream.HostEvent_ATTRIBUTES_CHANGED,
+			Hostname: hostname,
+		},
+	})
+	suite.NoError(err)
+	applier.drainAndShutdown()
+	suite.Equal(uint64(0), applier.GetEventProgress())
+}
+
 // TestBucketEventProcessor_StartStop tests that events can be processed
 // after a start-stop-start sequence
 func (suite *BucketEventProcessorTestSuite) TestBucketEventProcessor_StartStop() {
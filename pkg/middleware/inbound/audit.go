@@ -0,0 +1,73 @@
+package inbound
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/audit"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type auditInboundMiddleware struct {
+	// audited returns whether the calls of a procedure are recorded
+	audited func(procedure string) bool
+}
+
+func (m *auditInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	info := audit.NewInfo(req.Caller, req.Headers)
+	resw.AddHeaders(transport.NewHeaders().With(audit.RequestIDHeader, info.RequestID))
+
+	start := time.Now()
+	err := h.Handle(audit.WithInfo(ctx, info), req, resw)
+	m.record(info, req.Procedure, start, err)
+	return err
+}
+
+func (m *auditInboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	info := audit.NewInfo(req.Caller, req.Headers)
+
+	start := time.Now()
+	err := h.HandleOneway(audit.WithInfo(ctx, info), req)
+	m.record(info, req.Procedure, start, err)
+	return err
+}
+
+func (m *auditInboundMiddleware) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	meta := s.Request().Meta
+	info := audit.NewInfo(meta.Caller, meta.Headers)
+
+	start := time.Now()
+	err := h.HandleStream(s)
+	m.record(info, meta.Procedure, start, err)
+	return err
+}
+
+// record writes the audit record of a call if its procedure is audited
+func (m *auditInboundMiddleware) record(info audit.Info, procedure string, start time.Time, err error) {
+	if !m.audited(procedure) {
+		return
+	}
+
+	entry := log.WithFields(info.Fields()).WithFields(log.Fields{
+		"audit":     true,
+		"procedure": procedure,
+		"latency":   time.Since(start),
+		"code":      yarpcerrors.FromError(err).Code().String(),
+	})
+	if err != nil {
+		entry.WithError(err).Info("API call failed")
+		return
+	}
+	entry.Info("API call succeeded")
+}
+
+// NewAuditInboundMiddleware returns DispatcherInboundMiddleWare which
+// attaches the caller identity, request ID and user agent of requests to
+// their context, and writes an audit record for the calls of the audited
+// procedures.
+func NewAuditInboundMiddleware(audited func(procedure string) bool) DispatcherInboundMiddleWare {
+	return &auditInboundMiddleware{audited: audited}
+}
@@ -0,0 +1,149 @@
+package inbound
+
+import (
+	"context"
+	"testing"
+
+	auth_mocks "github.com/uber/peloton/pkg/auth/mocks"
+	"github.com/uber/peloton/pkg/common/audit"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type AuditInboundMiddlewareSuite struct {
+	suite.Suite
+
+	ctrl    *gomock.Controller
+	m       DispatcherInboundMiddleWare
+	audited []string
+}
+
+func (suite *AuditInboundMiddlewareSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.audited = nil
+	suite.m = NewAuditInboundMiddleware(func(procedure string) bool {
+		suite.audited = append(suite.audited, procedure)
+		return true
+	})
+}
+
+func (suite *AuditInboundMiddlewareSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *AuditInboundMiddlewareSuite) newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "peloton-cli",
+		Procedure: "HostService::StartMaintenance",
+		Headers: transport.NewHeaders().
+			With(audit.UserHeader, "operator").
+			With(audit.RequestIDHeader, "request-1"),
+	}
+}
+
+// TestHandle tests that the request info is attached to the context of
+// the handler and returned to the caller.
+func (suite *AuditInboundMiddlewareSuite) TestHandle() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) {
+			info, ok := audit.FromContext(ctx)
+			suite.True(ok)
+			suite.Equal("peloton-cli", info.Caller)
+			suite.Equal("operator", info.User)
+			suite.Equal("request-1", info.RequestID)
+		}).
+		Return(nil)
+
+	resw := &transporttest.FakeResponseWriter{}
+	suite.NoError(suite.m.Handle(context.Background(), suite.newRequest(), resw, h))
+	requestID, _ := resw.Headers.Get(audit.RequestIDHeader)
+	suite.Equal("request-1", requestID)
+	suite.Equal([]string{"HostService::StartMaintenance"}, suite.audited)
+}
+
+// TestHandleGeneratesRequestID tests that a request ID is generated for
+// requests without one.
+func (suite *AuditInboundMiddlewareSuite) TestHandleGeneratesRequestID() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) {
+			suite.NotEmpty(audit.RequestID(ctx))
+		}).
+		Return(nil)
+
+	req := &transport.Request{Procedure: "HostService::StartMaintenance"}
+	resw := &transporttest.FakeResponseWriter{}
+	suite.NoError(suite.m.Handle(context.Background(), req, resw, h))
+	requestID, _ := resw.Headers.Get(audit.RequestIDHeader)
+	suite.NotEmpty(requestID)
+}
+
+// TestHandleFail tests that failed calls are audited and their error
+// returned.
+func (suite *AuditInboundMiddlewareSuite) TestHandleFail() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("test error"))
+
+	resw := &transporttest.FakeResponseWriter{}
+	suite.Error(suite.m.Handle(context.Background(), suite.newRequest(), resw, h))
+	suite.Len(suite.audited, 1)
+}
+
+// TestHandleNotAudited tests that the request info is attached to the
+// context of calls which are not audited.
+func (suite *AuditInboundMiddlewareSuite) TestHandleNotAudited() {
+	m := NewAuditInboundMiddleware(func(string) bool { return false })
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) {
+			suite.Equal("request-1", audit.RequestID(ctx))
+		}).
+		Return(nil)
+
+	resw := &transporttest.FakeResponseWriter{}
+	suite.NoError(m.Handle(context.Background(), suite.newRequest(), resw, h))
+}
+
+func (suite *AuditInboundMiddlewareSuite) TestHandleOneway() {
+	h := transporttest.NewMockOnewayHandler(suite.ctrl)
+	h.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request) {
+			suite.Equal("request-1", audit.RequestID(ctx))
+		}).
+		Return(nil)
+	suite.NoError(suite.m.HandleOneway(context.Background(), suite.newRequest(), h))
+	suite.Len(suite.audited, 1)
+}
+
+// TestChain tests that the audit middleware records calls denied by the
+// auth middleware chained after it.
+func (suite *AuditInboundMiddlewareSuite) TestChain() {
+	s := auth_mocks.NewMockSecurityManager(suite.ctrl)
+	u := auth_mocks.NewMockUser(suite.ctrl)
+	m := Chain(suite.m, NewAuthInboundMiddleware(s))
+
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	s.EXPECT().Authenticate(gomock.Any()).Return(u, nil).Times(2)
+	u.EXPECT().IsPermitted(gomock.Any()).Return(false)
+	u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) {
+			suite.Equal("request-1", audit.RequestID(ctx))
+		}).
+		Return(nil)
+
+	resw := &transporttest.FakeResponseWriter{}
+	suite.Error(m.Handle(context.Background(), suite.newRequest(), resw, h))
+	suite.NoError(m.Handle(context.Background(), suite.newRequest(), resw, h))
+	suite.Len(suite.audited, 2)
+}
+
+func TestAuditInboundMiddleware(t *testing.T) {
+	suite.Run(t, &AuditInboundMiddlewareSuite{})
+}
@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"context"
+	"strings"
 
 	"github.com/uber/peloton/pkg/auth"
 	"go.uber.org/yarpc/api/transport"
@@ -10,8 +11,15 @@ import (
 
 var permissionDeniedErrorStr = "not permitted to call %s in %s"
 
+// separator between the service and the method of a procedure
+const _procedureSeparator = "::"
+
 type authInboundMiddleware struct {
 	auth.SecurityManager
+
+	// services whose procedures are checked, all procedures
+	// are checked if empty
+	services map[string]struct{}
 }
 
 func (m *authInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
@@ -57,6 +65,10 @@ func (m *authInboundMiddleware) HandleStream(s *transport.ServerStream, h transp
 }
 
 func (m *authInboundMiddleware) isPermitted(headers transport.Headers, procedure string) (bool, error) {
+	if !m.isChecked(procedure) {
+		return true, nil
+	}
+
 	user, err := m.Authenticate(headers)
 	if err != nil {
 		return false, err
@@ -65,9 +77,28 @@ func (m *authInboundMiddleware) isPermitted(headers transport.Headers, procedure
 	return user.IsPermitted(procedure), nil
 }
 
-// NewAuthInboundMiddleware returns DispatcherInboundMiddleWare with auth check
-func NewAuthInboundMiddleware(security auth.SecurityManager) DispatcherInboundMiddleWare {
-	return &authInboundMiddleware{
+// isChecked returns whether the procedure belongs to a service
+// whose procedures are checked
+func (m *authInboundMiddleware) isChecked(procedure string) bool {
+	if len(m.services) == 0 {
+		return true
+	}
+
+	service := strings.Split(procedure, _procedureSeparator)[0]
+	_, ok := m.services[service]
+	return ok
+}
+
+// NewAuthInboundMiddleware returns DispatcherInboundMiddleWare with auth check.
+// If services are provided, only the procedures of these services are checked,
+// and the other procedures are let through.
+func NewAuthInboundMiddleware(security auth.SecurityManager, services ...string) DispatcherInboundMiddleWare {
+	m := &authInboundMiddleware{
 		SecurityManager: security,
+		services:        make(map[string]struct{}),
+	}
+	for _, service := range services {
+		m.services[service] = struct{}{}
 	}
+	return m
 }
@@ -148,6 +148,29 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleStreamHandlerFail() {
 	suite.Error(suite.m.HandleStream(ss, h))
 }
 
+func (suite *AuthInboundMiddlewareSuite) TestHandleServiceScoped() {
+	m := NewAuthInboundMiddleware(suite.s, "peloton.api.v0.host.svc.HostService")
+
+	// procedures of other services are not checked
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	suite.NoError(m.Handle(context.Background(), &transport.Request{
+		Procedure: "peloton.private.hostmgr.InternalHostService::AcquireHostOffers",
+	}, nil, h))
+	suite.NoError(m.Handle(context.Background(), &transport.Request{
+		Procedure: "UPDATE",
+	}, nil, h))
+
+	// procedures of the service are checked
+	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
+	suite.u.EXPECT().
+		IsPermitted("peloton.api.v0.host.svc.HostService::StartMaintenance").
+		Return(false)
+	suite.Error(m.Handle(context.Background(), &transport.Request{
+		Procedure: "peloton.api.v0.host.svc.HostService::StartMaintenance",
+	}, nil, h))
+}
+
 func TestAuthInboundMiddlewareSuite(t *testing.T) {
 	suite.Run(t, &AuthInboundMiddlewareSuite{})
 }
@@ -0,0 +1,68 @@
+package inbound
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// chain applies inbound middleware in order, the first one
+// being the outermost
+type chain []DispatcherInboundMiddleWare
+
+func (c chain) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	return unaryChainHandler{chain: c, final: h}.Handle(ctx, req, resw)
+}
+
+func (c chain) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	return onewayChainHandler{chain: c, final: h}.HandleOneway(ctx, req)
+}
+
+func (c chain) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	return streamChainHandler{chain: c, final: h}.HandleStream(s)
+}
+
+type unaryChainHandler struct {
+	chain chain
+	final transport.UnaryHandler
+}
+
+func (h unaryChainHandler) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	if len(h.chain) == 0 {
+		return h.final.Handle(ctx, req, resw)
+	}
+	next := unaryChainHandler{chain: h.chain[1:], final: h.final}
+	return h.chain[0].Handle(ctx, req, resw, next)
+}
+
+type onewayChainHandler struct {
+	chain chain
+	final transport.OnewayHandler
+}
+
+func (h onewayChainHandler) HandleOneway(ctx context.Context, req *transport.Request) error {
+	if len(h.chain) == 0 {
+		return h.final.HandleOneway(ctx, req)
+	}
+	next := onewayChainHandler{chain: h.chain[1:], final: h.final}
+	return h.chain[0].HandleOneway(ctx, req, next)
+}
+
+type streamChainHandler struct {
+	chain chain
+	final transport.StreamHandler
+}
+
+func (h streamChainHandler) HandleStream(s *transport.ServerStream) error {
+	if len(h.chain) == 0 {
+		return h.final.HandleStream(s)
+	}
+	next := streamChainHandler{chain: h.chain[1:], final: h.final}
+	return h.chain[0].HandleStream(s, next)
+}
+
+// Chain returns DispatcherInboundMiddleWare applying the given middleware
+// in order, the first one being the outermost.
+func Chain(middleware ...DispatcherInboundMiddleWare) DispatcherInboundMiddleWare {
+	return chain(middleware)
+}
@@ -98,6 +98,11 @@ type PlacementConfig struct {
 	// MaxDesiredHostPlacementDuration is the max time duration to try to
 	// place a task on the desired host.
 	MaxDesiredHostPlacementDuration time.Duration `yaml:"max_desired_host_placement_duration"`
+
+	// UnavailabilityLookaheads is how long tasks of a type are expected to
+	// run, so that they are not placed on hosts with an unavailability
+	// window starting within that time.
+	UnavailabilityLookaheads UnavailabilityLookaheadsConfig `yaml:"unavailability_lookaheads"`
 }
 
 // MaxRoundsConfig is the config of the maximal number of successful rounds
@@ -154,6 +159,34 @@ func (c MaxDurationsConfig) Value(t resmgr.TaskType) time.Duration {
 	return 0
 }
 
+// UnavailabilityLookaheadsConfig is the config of how far ahead the
+// unavailability windows of hosts are considered when placing a task. The
+// unavailability of hosts is not considered if 0.
+type UnavailabilityLookaheadsConfig struct {
+	Unknown   time.Duration `yaml:"unknown"`
+	Batch     time.Duration `yaml:"batch"`
+	Stateless time.Duration `yaml:"stateless"`
+	Daemon    time.Duration `yaml:"daemon"`
+	Stateful  time.Duration `yaml:"stateful"`
+}
+
+// Value returns the value of the config for the given task type.
+func (c UnavailabilityLookaheadsConfig) Value(t resmgr.TaskType) time.Duration {
+	switch t {
+	case resmgr.TaskType_UNKNOWN:
+		return c.Unknown
+	case resmgr.TaskType_BATCH:
+		return c.Batch
+	case resmgr.TaskType_STATELESS:
+		return c.Stateless
+	case resmgr.TaskType_DAEMON:
+		return c.Daemon
+	case resmgr.TaskType_STATEFUL:
+		return c.Stateful
+	}
+	return 0
+}
+
 // Copy returns a deep copy of the config.
 func (config *PlacementConfig) Copy() *PlacementConfig {
 	copy := *config
@@ -195,8 +195,12 @@ func (e *engine) processAssignments(
 	}
 
 	filters := e.strategy.Filters(result)
+	lookahead := e.config.UnavailabilityLookaheads.Value(e.config.TaskType)
 	for f, b := range filters {
 		filter, batch := f, b
+		if filter != nil && lookahead > 0 {
+			filter.UnavailabilityLookaheadSeconds = uint32(lookahead.Seconds())
+		}
 		// Run the placement of each batch in parallel
 		e.pool.Enqueue(async.JobFunc(func(context.Context) {
 			e.placeAssignmentGroup(ctx, filter, batch)
@@ -284,6 +288,9 @@ func (e *engine) placeAssignmentGroup(
 
 		e.metrics.OfferGet.Inc(1)
 
+		// Prefer the hosts which are not entering maintenance soon.
+		models.SortByUnavailability(hosts, now)
+
 		// PlaceOnce the tasks on the hosts by delegating to the placement strategy.
 		e.strategy.PlaceOnce(assignments, hosts)
 
@@ -441,6 +441,53 @@ func TestEnginePlaceCallToStrategy(t *testing.T) {
 	assert.Equal(t, time.Duration(0), delay)
 }
 
+func TestEngineProcessAssignmentsUnavailabilityLookahead(t *testing.T) {
+	ctrl, engine, mockOfferService, mockTaskService, mockStrategy := setupEngine(t)
+	defer ctrl.Finish()
+	engine.config.MaxPlacementDuration = 100 * time.Millisecond
+	engine.config.UnavailabilityLookaheads = config.UnavailabilityLookaheadsConfig{
+		Batch:     time.Hour,
+		Stateless: 24 * time.Hour,
+	}
+
+	host := testutil.SetupHostOffers()
+	assignment := testutil.SetupAssignment(time.Now(), 1)
+	assignment.SetHost(host)
+	filter := &hostsvc.HostFilter{}
+
+	mockStrategy.EXPECT().
+		Filters(gomock.Any()).
+		Return(map[*hostsvc.HostFilter][]*models.Assignment{
+			filter: {assignment},
+		})
+	mockStrategy.EXPECT().
+		ConcurrencySafe().
+		AnyTimes().
+		Return(false)
+	mockStrategy.EXPECT().
+		PlaceOnce(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return()
+	mockOfferService.EXPECT().
+		Acquire(gomock.Any(), gomock.Any(), gomock.Any(), filter).
+		MinTimes(1).
+		Return([]*models.HostOffers{host}, _testReason)
+	mockTaskService.EXPECT().
+		SetPlacements(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return()
+	mockOfferService.EXPECT().
+		Release(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return()
+
+	engine.processAssignments(
+		context.Background(),
+		[]*models.Assignment{assignment},
+		func(*models.Assignment) bool { return true })
+	assert.Equal(t, uint32(3600), filter.GetUnavailabilityLookaheadSeconds())
+}
+
 func TestEngineFindUsedOffers(t *testing.T) {
 	ctrl, engine, _, _, _ := setupEngine(t)
 	defer ctrl.Finish()
@@ -15,6 +15,7 @@
 package models
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -73,3 +74,37 @@ func (host *HostOffers) Data() interface{} {
 func (host *HostOffers) Age(now time.Time) time.Duration {
 	return now.Sub(host.Claimed)
 }
+
+// UnavailableFrom returns when the host becomes unavailable for maintenance,
+// and false if the host is not scheduled for maintenance or if its
+// unavailability window is over at the given time.
+func (host *HostOffers) UnavailableFrom(now time.Time) (time.Time, bool) {
+	unavailability := host.GetOffer().GetUnavailability()
+	if unavailability.GetStart() == nil {
+		return time.Time{}, false
+	}
+	start := time.Unix(0, unavailability.GetStart().GetNanoseconds())
+	if unavailability.GetDuration() != nil {
+		end := start.Add(
+			time.Duration(unavailability.GetDuration().GetNanoseconds()))
+		if !end.After(now) {
+			return time.Time{}, false
+		}
+	}
+	return start, true
+}
+
+// SortByUnavailability stable sorts the hosts so that the hosts scheduled
+// for maintenance come after the other hosts, the ones becoming unavailable
+// sooner last, so that strategies placing tasks on the hosts in order
+// prefer the hosts which stay available longer.
+func SortByUnavailability(hosts []*HostOffers, now time.Time) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		si, oki := hosts[i].UnavailableFrom(now)
+		sj, okj := hosts[j].UnavailableFrom(now)
+		if !oki || !okj {
+			return !oki && okj
+		}
+		return si.After(sj)
+	})
+}
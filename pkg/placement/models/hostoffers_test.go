@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/.gen/peloton/private/resmgr"
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
@@ -65,3 +66,45 @@ func TestHost_Age(t *testing.T) {
 	assert.Equal(t, time.Duration(0), host.Age(now))
 	assert.Equal(t, 2*time.Second, host.Age(now.Add(2*time.Second)))
 }
+
+func TestHost_SortByUnavailability(t *testing.T) {
+	now := time.Now()
+	host := func(hostname string, start time.Duration, duration time.Duration) *HostOffers {
+		hostOffer := &hostsvc.HostOffer{Hostname: hostname}
+		if start != 0 {
+			startNanos := now.Add(start).UnixNano()
+			hostOffer.Unavailability = &mesos_v1.Unavailability{
+				Start: &mesos_v1.TimeInfo{Nanoseconds: &startNanos},
+			}
+			if duration > 0 {
+				durationNanos := duration.Nanoseconds()
+				hostOffer.Unavailability.Duration = &mesos_v1.DurationInfo{
+					Nanoseconds: &durationNanos,
+				}
+			}
+		}
+		return NewHostOffers(hostOffer, nil, now)
+	}
+
+	hosts := []*HostOffers{
+		host("in-progress", -time.Hour, 0),
+		host("soon", time.Hour, time.Hour),
+		host("available", 0, 0),
+		host("later", 24*time.Hour, 0),
+		host("over", -2*time.Hour, time.Hour),
+	}
+	start, ok := hosts[1].UnavailableFrom(now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Hour).UnixNano(), start.UnixNano())
+	_, ok = hosts[4].UnavailableFrom(now)
+	assert.False(t, ok)
+
+	SortByUnavailability(hosts, now)
+	var hostnames []string
+	for _, h := range hosts {
+		hostnames = append(hostnames, h.GetOffer().GetHostname())
+	}
+	assert.Equal(t,
+		[]string{"available", "over", "later", "soon", "in-progress"},
+		hostnames)
+}
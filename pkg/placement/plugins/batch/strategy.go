@@ -108,6 +108,9 @@ func (batch *batch) getHostFilter(assignment *models.Assignment) *hostsvc.HostFi
 	}
 	if constraint := assignment.GetTask().GetTask().Constraint; constraint != nil {
 		result.SchedulingConstraint = constraint
+		// The percentage requirements of the constraint are relative to
+		// the instance count of the job.
+		result.ConstraintDenominator = assignment.GetTask().GetTask().GetInstanceCount()
 	}
 	return result
 }
@@ -132,8 +135,9 @@ func (batch *batch) Filters(assignments []*models.Assignment) map[*hostsvc.HostF
 	result := map[*hostsvc.HostFilter][]*models.Assignment{}
 	for filter, assignments := range filters {
 		filterWithQuantity := &hostsvc.HostFilter{
-			ResourceConstraint:   filter.GetResourceConstraint(),
-			SchedulingConstraint: filter.GetSchedulingConstraint(),
+			ResourceConstraint:    filter.GetResourceConstraint(),
+			SchedulingConstraint:  filter.GetSchedulingConstraint(),
+			ConstraintDenominator: filter.GetConstraintDenominator(),
 			Quantity: &hostsvc.QuantityControl{
 				MaxHosts: uint32(len(assignments)),
 			},
@@ -106,3 +106,30 @@ func TestBatchFiltersWithPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestBatchFiltersWithConstraintDenominator(t *testing.T) {
+	assignments := []*models.Assignment{
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+	}
+	assignments[0].GetTask().GetTask().InstanceCount = 10
+	assignments[1].GetTask().GetTask().InstanceCount = 10
+	assignments[2].GetTask().GetTask().InstanceCount = 20
+	strategy := New()
+
+	filters := strategy.Filters(assignments)
+
+	assert.Equal(t, 2, len(filters))
+	for filter, batch := range filters {
+		assert.Equal(t, uint32(len(batch)), filter.GetQuantity().GetMaxHosts())
+		switch filter.GetConstraintDenominator() {
+		case 10:
+			assert.Equal(t, 2, len(batch))
+		case 20:
+			assert.Equal(t, 1, len(batch))
+		default:
+			assert.Fail(t, "unexpected constraint denominator")
+		}
+	}
+}
@@ -47,6 +47,15 @@ type Config struct {
 	// Period to run host drainer
 	HostDrainerPeriod time.Duration `yaml:"host_drainer_period"`
 
+	// Order of rescheduling the tasks on a draining host, either
+	// least_critical_first (default) or most_critical_first
+	HostDrainOrder string `yaml:"host_drain_order"`
+
+	// Time the capacity pre-admitted for the tasks displaced by a DRAINING
+	// host is kept after the host was last seen DRAINING. Pre-admission is
+	// disabled if 0.
+	HostDrainHintTTL time.Duration `yaml:"host_drain_hint_ttl"`
+
 	// RecoveryConfig to recover jobs on resmgr restart
 	RecoveryConfig *common.RecoveryConfig `yaml:"recovery"`
 }
@@ -762,6 +762,10 @@ func (h *ServiceHandler) NotifyTaskUpdates(
 func (h *ServiceHandler) handleEvent(event *pb_eventstream.Event) {
 	defer h.acknowledgeEvent(event.Offset)
 
+	if event.GetType() == pb_eventstream.Event_HOST_EVENT {
+		return
+	}
+
 	taskState := util.MesosStateToPelotonState(
 		event.MesosTaskStatus.GetState())
 	if taskState != t.TaskState_RUNNING &&
@@ -18,12 +18,17 @@ import (
 	"context"
 	"time"
 
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/.gen/peloton/private/resmgr"
 
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/backoff"
 	"github.com/uber/peloton/pkg/common/lifecycle"
+	"github.com/uber/peloton/pkg/common/logging"
 	"github.com/uber/peloton/pkg/common/stringset"
+	taskutil "github.com/uber/peloton/pkg/common/util/task"
 	"github.com/uber/peloton/pkg/resmgr/preemption"
 	rmtask "github.com/uber/peloton/pkg/resmgr/task"
 
@@ -51,6 +56,8 @@ type Drainer struct {
 	preemptionQueue preemption.Queue    // Preemption Queue
 	lifecycle       lifecycle.LifeCycle // Lifecycle manager
 	drainingHosts   stringset.StringSet // Set of hosts currently being drained
+	drainOrder      DrainOrder          // Order of rescheduling the tasks on a host
+	hints           *drainHints         // Capacity pre-admitted for displaced tasks
 }
 
 // NewDrainer creates a new Drainer
@@ -59,16 +66,30 @@ func NewDrainer(
 	hostMgrClient hostsvc.InternalHostServiceYARPCClient,
 	drainerPeriod time.Duration,
 	rmTracker rmtask.Tracker,
-	preemptionQueue preemption.Queue) *Drainer {
+	preemptionQueue preemption.Queue,
+	drainOrder DrainOrder,
+	drainHintTTL time.Duration) *Drainer {
 
+	if drainOrder == "" {
+		drainOrder = LeastCriticalFirst
+	}
+	if !drainOrder.isValid() {
+		log.WithField("drain_order", drainOrder).
+			Warn("Unknown host drain order, using least critical first")
+		drainOrder = LeastCriticalFirst
+	}
+
+	metrics := NewMetrics(parent.SubScope("drainer"))
 	return &Drainer{
 		hostMgrClient:   hostMgrClient,
-		metrics:         NewMetrics(parent.SubScope("drainer")),
+		metrics:         metrics,
 		rmTracker:       rmTracker,
 		preemptionQueue: preemptionQueue,
 		drainerPeriod:   drainerPeriod,
 		lifecycle:       lifecycle.NewLifeCycle(),
 		drainingHosts:   stringset.New(),
+		drainOrder:      drainOrder,
+		hints:           newDrainHints(drainHintTTL, rmTracker, metrics),
 	}
 }
 
@@ -117,6 +138,11 @@ func (d *Drainer) Stop() error {
 	d.lifecycle.Wait()
 	// Clear the set
 	d.drainingHosts.Clear()
+	// Release the capacity pre-admitted for the displaced tasks, it is
+	// pre-admitted again by the next leader
+	if d.hints != nil {
+		d.hints.releaseAll()
+	}
 	log.Info("Host Drainer Stopped")
 	return nil
 }
@@ -131,31 +157,74 @@ func (d *Drainer) performDrainCycle() error {
 		Limit:   drainingHostsLimit,
 		Timeout: drainingHostsTimeout,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	// The logs of a drain cycle are correlated by the request ID of the
+	// cycle
+	ctx := audit.NewOperation(
+		context.Background(),
+		common.PelotonResourceManager)
+	callCtx, cancel := context.WithTimeout(ctx, contextTimeout)
 	defer cancel()
 
-	response, err := d.hostMgrClient.GetDrainingHosts(ctx, request)
+	response, err := d.hostMgrClient.GetDrainingHosts(callCtx, request)
 	if err != nil {
 		return err
 	}
 
-	for _, host := range response.GetHostnames() {
-		d.drainingHosts.Add(host)
+	d.drainingHosts.AddAll(response.GetHostnames())
+	err = d.drainHosts(
+		ctx,
+		response.GetHostTasks(),
+		response.GetHostExemptions())
+	if d.hints != nil {
+		d.hints.prune(time.Now())
 	}
-	return d.drainHosts()
+	return err
 }
 
-func (d *Drainer) drainHosts() error {
+// drainHosts enqueues the tasks on the DRAINING hosts for preemption. The
+// tasks hinted by host manager for each host are used to pre-admit the
+// capacity of the displaced tasks. The tasks exempt from eviction by the
+// drain options of a host are left running on the host.
+func (d *Drainer) drainHosts(
+	ctx context.Context,
+	hostTasks map[string]*hostsvc.TaskIDList,
+	hostExemptions map[string]*hpb.EvictionExemption) error {
 	var errs error
 
 	drainingHosts := d.drainingHosts.ToSlice()
-	log.WithField("hosts", drainingHosts).Info("Draining hosts")
+	ctx = logging.WithHosts(ctx, drainingHosts)
+	logging.FromContext(ctx).Info("Draining hosts")
 	// No-op if there are no hosts to drain
 	if len(drainingHosts) == 0 {
 		return nil
 	}
 	// Get all tasks on the DRAINING hosts
 	tasksByHost := d.rmTracker.TasksByHosts(drainingHosts, resmgr.TaskType_UNKNOWN)
+	// Mesos task ids of the tasks exempt from eviction
+	exemptTaskIDs := make(map[string]struct{})
+	for host, exemption := range hostExemptions {
+		tasks := d.removeExemptTasks(
+			tasksByHost[host],
+			exemption,
+			exemptTaskIDs)
+		if len(tasks) == 0 {
+			delete(tasksByHost, host)
+		} else {
+			tasksByHost[host] = tasks
+		}
+	}
+	if d.hints != nil {
+		now := time.Now()
+		for _, host := range drainingHosts {
+			var hinted []string
+			for _, taskID := range hostTasks[host].GetTaskIds() {
+				if _, ok := exemptTaskIDs[taskID]; !ok {
+					hinted = append(hinted, taskID)
+				}
+			}
+			d.hints.add(host, tasksByHost[host], hinted, now)
+		}
+	}
 	var drainedHosts []string
 	for _, host := range drainingHosts {
 		if _, ok := tasksByHost[host]; !ok {
@@ -163,42 +232,64 @@ func (d *Drainer) drainHosts() error {
 			continue
 		}
 
+		// The preemption queue is FIFO, so the tasks are rescheduled
+		// in the order they are enqueued.
 		err := d.preemptionQueue.EnqueueTasks(
-			tasksByHost[host],
+			d.drainOrder.sort(tasksByHost[host]),
 			resmgr.PreemptionReason_PREEMPTION_REASON_HOST_MAINTENANCE)
 		if err != nil {
-			log.WithField("host", host).
+			logging.FromContext(ctx).WithField("host", host).
 				WithError(err).
 				Error("Failed to enqueue some tasks")
 			errs = multierror.Append(errs, err)
 		}
 	}
 	if len(drainedHosts) != 0 {
-		err := d.markHostsDrained(drainedHosts)
+		err := d.markHostsDrained(ctx, drainedHosts)
 		if err != nil {
 			errs = multierror.Append(err, errs)
 			return errs
 		}
-		log.WithField("hosts", drainedHosts).Info("Marked hosts as drained")
+		logging.FromContext(ctx).WithField("drained_hosts", drainedHosts).
+			Info("Marked hosts as drained")
 	}
 	return errs
 }
 
-func (d *Drainer) markHostsDrained(hosts []string) error {
+// removeExemptTasks returns the tasks which are not exempt from eviction
+// by the exemption, and adds the Mesos task ids of the exempt tasks to
+// exemptTaskIDs.
+func (d *Drainer) removeExemptTasks(
+	tasks []*rmtask.RMTask,
+	exemption *hpb.EvictionExemption,
+	exemptTaskIDs map[string]struct{}) []*rmtask.RMTask {
+	var evicted []*rmtask.RMTask
+	for _, rmTask := range tasks {
+		if taskutil.EvictionExemptReason(exemption, rmTask.Task()) != "" {
+			exemptTaskIDs[rmTask.Task().GetTaskId().GetValue()] = struct{}{}
+			d.metrics.DrainExemptTasks.Inc(1)
+			continue
+		}
+		evicted = append(evicted, rmTask)
+	}
+	return evicted
+}
+
+func (d *Drainer) markHostsDrained(ctx context.Context, hosts []string) error {
 	err := backoff.Retry(
 		func() error {
-			log.WithField("hosts", hosts).
+			logging.FromContext(ctx).WithField("drained_hosts", hosts).
 				Info("Attempting to mark hosts as drained")
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			callCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 			defer cancel()
 			response, err := d.hostMgrClient.MarkHostsDrained(
-				ctx,
+				callCtx,
 				&hostsvc.MarkHostsDrainedRequest{
 					Hostnames: hosts,
 				})
 			for _, host := range response.GetMarkedHosts() {
 				d.drainingHosts.Remove(host)
-				log.WithField("hostname", host).
+				logging.FromContext(ctx).WithField("hostname", host).
 					Info("successfully marked host as drained, removing from queue")
 			}
 			return err
@@ -19,7 +19,11 @@ import (
 	"testing"
 	"time"
 
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	host_mocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
 	"github.com/uber/peloton/.gen/peloton/private/resmgr"
@@ -30,6 +34,7 @@ import (
 	"github.com/uber/peloton/pkg/common/lifecycle"
 	"github.com/uber/peloton/pkg/common/stringset"
 	preemption_mocks "github.com/uber/peloton/pkg/resmgr/preemption/mocks"
+	"github.com/uber/peloton/pkg/resmgr/scalar"
 	rm_task "github.com/uber/peloton/pkg/resmgr/task"
 
 	"github.com/golang/mock/gomock"
@@ -77,6 +82,7 @@ func (suite *DrainerTestSuite) SetupTest() {
 	suite.drainer = Drainer{
 		drainerPeriod:   drainerPeriod,
 		hostMgrClient:   suite.mockHostmgr,
+		metrics:         NewMetrics(tally.NoopScope),
 		preemptionQueue: suite.preemptor,
 		rmTracker:       suite.tracker,
 		lifecycle:       lifecycle.NewLifeCycle(),
@@ -117,8 +123,33 @@ func (suite *DrainerTestSuite) TestDrainer_Init() {
 		suite.mockHostmgr,
 		drainerPeriod,
 		suite.tracker,
-		suite.preemptor)
+		suite.preemptor,
+		"",
+		0)
 	suite.NotNil(r)
+	suite.Equal(LeastCriticalFirst, r.drainOrder)
+	suite.Nil(r.hints)
+
+	r = NewDrainer(
+		tally.NoopScope,
+		suite.mockHostmgr,
+		drainerPeriod,
+		suite.tracker,
+		suite.preemptor,
+		MostCriticalFirst,
+		time.Minute)
+	suite.Equal(MostCriticalFirst, r.drainOrder)
+	suite.NotNil(r.hints)
+
+	r = NewDrainer(
+		tally.NoopScope,
+		suite.mockHostmgr,
+		drainerPeriod,
+		suite.tracker,
+		suite.preemptor,
+		"random",
+		0)
+	suite.Equal(LeastCriticalFirst, r.drainOrder)
 }
 
 func (suite *DrainerTestSuite) TestDrainer_StartStop() {
@@ -189,6 +220,265 @@ func (suite *DrainerTestSuite) TestDrainCycle() {
 		}
 	}
 }
+
+// TestDrainCycle_Order tests that the tasks on a draining host are enqueued
+// in the order of the drain policy
+func (suite *DrainerTestSuite) TestDrainCycle_Order() {
+	suite.tracker.Clear()
+
+	tasks := []*resmgr.Task{
+		{
+			Id:       &peloton.TaskID{Value: "stateful"},
+			Type:     resmgr.TaskType_STATEFUL,
+			Priority: 0,
+		},
+		{
+			Id:       &peloton.TaskID{Value: "stateless-high"},
+			Type:     resmgr.TaskType_STATELESS,
+			Priority: 10,
+		},
+		{
+			Id:          &peloton.TaskID{Value: "batch-preemptible"},
+			Type:        resmgr.TaskType_BATCH,
+			Preemptible: true,
+		},
+		{
+			Id:       &peloton.TaskID{Value: "stateless-low"},
+			Type:     resmgr.TaskType_STATELESS,
+			Priority: 1,
+		},
+		{
+			Id:        &peloton.TaskID{Value: "revocable"},
+			Type:      resmgr.TaskType_STATEFUL,
+			Revocable: true,
+		},
+	}
+	for _, t := range tasks {
+		t.Name = t.GetId().GetValue()
+		t.JobId = &peloton.JobID{Value: "job1"}
+		t.Hostname = hostname
+		suite.addTaskToTracker(t)
+	}
+
+	expected := []string{
+		"revocable",
+		"batch-preemptible",
+		"stateless-low",
+		"stateless-high",
+		"stateful",
+	}
+
+	for _, order := range []DrainOrder{LeastCriticalFirst, MostCriticalFirst} {
+		suite.drainer.drainOrder = order
+		suite.mockHostmgr.EXPECT().
+			GetDrainingHosts(gomock.Any(), gomock.Any()).
+			Return(&hostsvc.GetDrainingHostsResponse{
+				Hostnames: suite.hostnames,
+			}, nil)
+
+		var enqueued []string
+		suite.preemptor.EXPECT().
+			EnqueueTasks(gomock.Any(), gomock.Any()).
+			Do(func(tasks []*rm_task.RMTask, _ resmgr.PreemptionReason) {
+				for _, t := range tasks {
+					enqueued = append(enqueued, t.Task().GetId().GetValue())
+				}
+			}).
+			Return(nil)
+
+		err := suite.drainer.performDrainCycle()
+		suite.NoError(err)
+
+		if order == MostCriticalFirst {
+			suite.Equal([]string{
+				"stateful",
+				"stateless-high",
+				"stateless-low",
+				"batch-preemptible",
+				"revocable",
+			}, enqueued)
+		} else {
+			suite.Equal(expected, enqueued)
+		}
+		suite.drainer.drainingHosts.Clear()
+	}
+}
+
+// TestDrainCycle_Exemptions tests that the tasks exempt from eviction on
+// a draining host are not enqueued, and that a host with only exempt
+// tasks is marked drained
+func (suite *DrainerTestSuite) TestDrainCycle_Exemptions() {
+	suite.tracker.Clear()
+
+	tasks := []*resmgr.Task{
+		{
+			Id:      &peloton.TaskID{Value: "daemon"},
+			Type:    resmgr.TaskType_BATCH,
+			JobType: job.JobType_DAEMON,
+		},
+		{
+			Id:         &peloton.TaskID{Value: "controller"},
+			Type:       resmgr.TaskType_BATCH,
+			Controller: true,
+		},
+		{
+			Id:   &peloton.TaskID{Value: "stateless"},
+			Type: resmgr.TaskType_STATELESS,
+		},
+	}
+	for _, t := range tasks {
+		t.Name = t.GetId().GetValue()
+		t.JobId = &peloton.JobID{Value: "job1"}
+		t.Hostname = hostname
+		suite.addTaskToTracker(t)
+	}
+	exemption := &hpb.EvictionExemption{
+		JobTypes:        []job.JobType{job.JobType_DAEMON},
+		ControllerTasks: true,
+	}
+
+	suite.mockHostmgr.EXPECT().
+		GetDrainingHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetDrainingHostsResponse{
+			Hostnames: suite.hostnames,
+			HostExemptions: map[string]*hpb.EvictionExemption{
+				hostname: exemption,
+			},
+		}, nil)
+	var enqueued []string
+	suite.preemptor.EXPECT().
+		EnqueueTasks(gomock.Any(), gomock.Any()).
+		Do(func(tasks []*rm_task.RMTask, _ resmgr.PreemptionReason) {
+			for _, t := range tasks {
+				enqueued = append(enqueued, t.Task().GetId().GetValue())
+			}
+		}).
+		Return(nil)
+	suite.NoError(suite.drainer.performDrainCycle())
+	suite.Equal([]string{"stateless"}, enqueued)
+
+	// The host is drained once only exempt tasks are left
+	suite.tracker.DeleteTask(&peloton.TaskID{Value: "stateless"})
+	suite.mockHostmgr.EXPECT().
+		GetDrainingHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetDrainingHostsResponse{
+			Hostnames: suite.hostnames,
+			HostExemptions: map[string]*hpb.EvictionExemption{
+				hostname: exemption,
+			},
+		}, nil)
+	suite.mockHostmgr.EXPECT().
+		MarkHostsDrained(gomock.Any(), &hostsvc.MarkHostsDrainedRequest{
+			Hostnames: suite.hostnames,
+		}).
+		Return(&hostsvc.MarkHostsDrainedResponse{
+			MarkedHosts: suite.hostnames,
+		}, nil)
+	suite.NoError(suite.drainer.performDrainCycle())
+}
+
+// TestDrainCycle_Hints tests that the capacity of the tasks displaced by
+// a DRAINING host is pre-admitted until the tasks leave the host
+func (suite *DrainerTestSuite) TestDrainCycle_Hints() {
+	suite.tracker.Clear()
+	suite.drainer.hints = newDrainHints(
+		time.Minute,
+		suite.tracker,
+		NewMetrics(tally.NoopScope))
+
+	jobID := "bca875f5-322a-4439-b0c9-63e3cf9f982e"
+	taskID := &peloton.TaskID{Value: jobID + "-0"}
+	mesosTaskID := jobID + "-0-1"
+	resources := &task.ResourceConfig{CpuLimit: 2, MemLimitMb: 100}
+
+	mockRespool := res_mocks.NewMockResPool(suite.mockCtrl)
+	mockRespool.EXPECT().GetPath().Return("mockRespoolPath")
+	suite.tracker.AddTask(
+		&resmgr.Task{
+			Name:     taskID.GetValue(),
+			JobId:    &peloton.JobID{Value: jobID},
+			Id:       taskID,
+			TaskId:   &mesos.TaskID{Value: &mesosTaskID},
+			Hostname: hostname,
+			Resource: resources,
+		},
+		suite.eventStreamHandler,
+		mockRespool,
+		&rm_task.Config{})
+
+	// The demand of the displaced task is pre-admitted once, even though
+	// it is both placed on the host and hinted by host manager
+	mockRespool.EXPECT().
+		AddToDemand(scalar.ConvertToResmgrResource(resources)).
+		Return(nil)
+	suite.preemptor.EXPECT().
+		EnqueueTasks(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+	for i := 0; i < 2; i++ {
+		suite.mockHostmgr.EXPECT().
+			GetDrainingHosts(gomock.Any(), gomock.Any()).
+			Return(&hostsvc.GetDrainingHostsResponse{
+				Hostnames: suite.hostnames,
+				HostTasks: map[string]*hostsvc.TaskIDList{
+					hostname: {TaskIds: []string{mesosTaskID}},
+				},
+			}, nil)
+		suite.NoError(suite.drainer.performDrainCycle())
+	}
+	suite.Len(suite.drainer.hints.hosts[hostname].tasks, 1)
+
+	// The demand is released once the task has left the host
+	suite.tracker.DeleteTask(taskID)
+	mockRespool.EXPECT().
+		SubtractFromDemand(scalar.ConvertToResmgrResource(resources)).
+		Return(nil)
+	suite.mockHostmgr.EXPECT().
+		GetDrainingHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.GetDrainingHostsResponse{}, nil)
+	suite.NoError(suite.drainer.performDrainCycle())
+	suite.Empty(suite.drainer.hints.hosts)
+}
+
+// TestDrainHintsExpire tests that the hints of a host not seen DRAINING
+// for the ttl are released
+func (suite *DrainerTestSuite) TestDrainHintsExpire() {
+	hints := newDrainHints(
+		time.Minute,
+		suite.tracker,
+		NewMetrics(tally.NoopScope))
+	rmTask := suite.tracker.GetTask(&peloton.TaskID{Value: taskName})
+	suite.NotNil(rmTask)
+
+	mockRespool := res_mocks.NewMockResPool(suite.mockCtrl)
+	now := time.Now()
+	hints.hosts[hostname] = &hostHints{
+		refreshed: now,
+		tasks: map[string]*taskHint{
+			"mesos-task": {
+				taskID:    rmTask.Task().GetId(),
+				respool:   mockRespool,
+				resources: &scalar.Resources{CPU: 1},
+			},
+		},
+	}
+
+	// Not expired yet, the task is not running on the host anymore
+	// though, so its hint is released
+	mockRespool.EXPECT().
+		SubtractFromDemand(&scalar.Resources{CPU: 1}).
+		Return(nil)
+	hints.prune(now.Add(30 * time.Second))
+	suite.Empty(hints.hosts)
+
+	hints.hosts[hostname] = &hostHints{
+		refreshed: now,
+		tasks:     map[string]*taskHint{},
+	}
+	hints.prune(now.Add(2 * time.Minute))
+	suite.Empty(hints.hosts)
+}
+
 func (suite *DrainerTestSuite) TestDrainCycle_NoHostsToDrain() {
 	suite.mockHostmgr.EXPECT().
 		GetDrainingHosts(gomock.Any(), gomock.Any()).
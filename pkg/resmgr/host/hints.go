@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/resmgr/respool"
+	"github.com/uber/peloton/pkg/resmgr/scalar"
+	rmtask "github.com/uber/peloton/pkg/resmgr/task"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// drainHints pre-admits replacement capacity for the tasks displaced by
+// DRAINING hosts. The resources of each displaced task are added to the
+// demand of its resource pool when the host is first seen DRAINING, so
+// that the entitlement of the pool grows before the task is killed. The
+// demand is removed once the task has left the host, or once the host
+// was not seen DRAINING for the ttl.
+// drainHints is only used from the drain cycle and is not thread-safe.
+type drainHints struct {
+	ttl       time.Duration
+	rmTracker rmtask.Tracker
+	metrics   *Metrics
+
+	// hostname to the hints of the tasks displaced from the host
+	hosts map[string]*hostHints
+}
+
+// hostHints are the hints of the tasks displaced from a host.
+type hostHints struct {
+	// last time the host was seen DRAINING
+	refreshed time.Time
+	// Mesos task id to the hint of the task
+	tasks map[string]*taskHint
+}
+
+// taskHint is the demand pre-admitted for a displaced task.
+type taskHint struct {
+	taskID    *peloton.TaskID
+	respool   respool.ResPool
+	resources *scalar.Resources
+}
+
+// newDrainHints returns a new drainHints. Hints are disabled if the ttl
+// is 0.
+func newDrainHints(
+	ttl time.Duration,
+	rmTracker rmtask.Tracker,
+	metrics *Metrics) *drainHints {
+	if ttl == 0 {
+		return nil
+	}
+	return &drainHints{
+		ttl:       ttl,
+		rmTracker: rmTracker,
+		metrics:   metrics,
+		hosts:     make(map[string]*hostHints),
+	}
+}
+
+// add pre-admits the demand of the tasks displaced by a DRAINING host.
+// The tasks are the ones the tracker places on the host, and the ones
+// host manager hinted to run on the host by their Mesos task ids.
+func (h *drainHints) add(
+	hostname string,
+	tasks []*rmtask.RMTask,
+	hintedTaskIDs []string,
+	now time.Time) {
+	hints, ok := h.hosts[hostname]
+	if !ok {
+		hints = &hostHints{tasks: make(map[string]*taskHint)}
+		h.hosts[hostname] = hints
+	}
+	hints.refreshed = now
+
+	displaced := append([]*rmtask.RMTask{}, tasks...)
+	for _, mesosTaskID := range hintedTaskIDs {
+		if rmTask := h.getDisplacedTask(hostname, mesosTaskID); rmTask != nil {
+			displaced = append(displaced, rmTask)
+		}
+	}
+
+	for _, rmTask := range displaced {
+		mesosTaskID := rmTask.Task().GetTaskId().GetValue()
+		if _, ok := hints.tasks[mesosTaskID]; ok {
+			continue
+		}
+		// Revocable tasks run on slack resources and are not pre-admitted
+		if rmTask.Task().GetRevocable() || rmTask.Respool() == nil {
+			continue
+		}
+
+		resources := scalar.ConvertToResmgrResource(
+			rmTask.Task().GetResource())
+		if err := rmTask.Respool().AddToDemand(resources); err != nil {
+			log.WithField("task_id", mesosTaskID).
+				WithError(err).
+				Warn("Failed to pre-admit demand of displaced task")
+			continue
+		}
+		hints.tasks[mesosTaskID] = &taskHint{
+			taskID:    rmTask.Task().GetId(),
+			respool:   rmTask.Respool(),
+			resources: resources,
+		}
+		h.metrics.DrainHintsAdded.Inc(1)
+	}
+	h.updateGauges()
+}
+
+// prune releases the demand of the displaced tasks which have left their
+// host, and of the hosts which were not seen DRAINING for the ttl.
+func (h *drainHints) prune(now time.Time) {
+	for hostname, hints := range h.hosts {
+		if now.Sub(hints.refreshed) > h.ttl {
+			h.release(hostname)
+			h.metrics.DrainHintsExpired.Inc(1)
+			continue
+		}
+		for mesosTaskID, hint := range hints.tasks {
+			if h.getDisplacedTask(hostname, mesosTaskID) != nil {
+				continue
+			}
+			h.releaseTask(hints, mesosTaskID, hint)
+		}
+		if len(hints.tasks) == 0 {
+			delete(h.hosts, hostname)
+		}
+	}
+	h.updateGauges()
+}
+
+// release releases the demand pre-admitted for the tasks displaced
+// from a host.
+func (h *drainHints) release(hostname string) {
+	hints, ok := h.hosts[hostname]
+	if !ok {
+		return
+	}
+	for mesosTaskID, hint := range hints.tasks {
+		h.releaseTask(hints, mesosTaskID, hint)
+	}
+	delete(h.hosts, hostname)
+	h.updateGauges()
+}
+
+// releaseAll releases the demand pre-admitted for all displaced tasks.
+func (h *drainHints) releaseAll() {
+	for hostname := range h.hosts {
+		h.release(hostname)
+	}
+}
+
+func (h *drainHints) releaseTask(
+	hints *hostHints,
+	mesosTaskID string,
+	hint *taskHint) {
+	if err := hint.respool.SubtractFromDemand(hint.resources); err != nil {
+		log.WithField("task_id", mesosTaskID).
+			WithError(err).
+			Warn("Failed to release pre-admitted demand of displaced task")
+	}
+	delete(hints.tasks, mesosTaskID)
+	h.metrics.DrainHintsReleased.Inc(1)
+}
+
+// getDisplacedTask returns the task of the Mesos task id if the task is
+// still running on the host, or nil otherwise.
+func (h *drainHints) getDisplacedTask(
+	hostname string,
+	mesosTaskID string) *rmtask.RMTask {
+	taskID, err := util.ParseTaskIDFromMesosTaskID(mesosTaskID)
+	if err != nil {
+		return nil
+	}
+	rmTask := h.rmTracker.GetTask(&peloton.TaskID{Value: taskID})
+	if rmTask == nil ||
+		rmTask.Task().GetTaskId().GetValue() != mesosTaskID ||
+		rmTask.Task().GetHostname() != hostname {
+		return nil
+	}
+	return rmTask
+}
+
+func (h *drainHints) updateGauges() {
+	var tasks int
+	for _, hints := range h.hosts {
+		tasks += len(hints.tasks)
+	}
+	h.metrics.DrainHintHosts.Update(float64(len(h.hosts)))
+	h.metrics.DrainHintTasks.Update(float64(tasks))
+}
@@ -20,6 +20,13 @@ import "github.com/uber-go/tally"
 type Metrics struct {
 	HostDrainSuccess tally.Counter
 	HostDrainFail    tally.Counter
+	DrainExemptTasks tally.Counter
+
+	DrainHintsAdded    tally.Counter
+	DrainHintsReleased tally.Counter
+	DrainHintsExpired  tally.Counter
+	DrainHintHosts     tally.Gauge
+	DrainHintTasks     tally.Gauge
 }
 
 // NewMetrics returns a new instance of host.Metrics.
@@ -29,5 +36,12 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	return &Metrics{
 		HostDrainSuccess: hostSuccessScope.Counter("host_drain"),
 		HostDrainFail:    hostFailScope.Counter("host_drain"),
+		DrainExemptTasks: scope.Counter("drain_exempt_tasks"),
+
+		DrainHintsAdded:    scope.Counter("drain_hints_added"),
+		DrainHintsReleased: scope.Counter("drain_hints_released"),
+		DrainHintsExpired:  scope.Counter("drain_hints_expired"),
+		DrainHintHosts:     scope.Gauge("drain_hint_hosts"),
+		DrainHintTasks:     scope.Gauge("drain_hint_tasks"),
 	}
 }
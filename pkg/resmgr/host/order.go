@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"sort"
+
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+
+	rmtask "github.com/uber/peloton/pkg/resmgr/task"
+)
+
+// DrainOrder is the policy for ordering the rescheduling of the tasks
+// running on a draining host.
+type DrainOrder string
+
+const (
+	// LeastCriticalFirst reschedules revocable, preemptible, batch and low
+	// priority tasks first, and non-preemptible, stateful and high priority
+	// tasks last, so that SLA-critical tasks keep running for as long as
+	// possible. This is the default.
+	LeastCriticalFirst DrainOrder = "least_critical_first"
+
+	// MostCriticalFirst reschedules the tasks in the reverse order, so that
+	// SLA-critical tasks are the first to get capacity elsewhere.
+	MostCriticalFirst DrainOrder = "most_critical_first"
+)
+
+// taskTypeCriticality ranks the task types from the least to the most
+// critical to reschedule.
+var taskTypeCriticality = map[resmgr.TaskType]int{
+	resmgr.TaskType_BATCH:     0,
+	resmgr.TaskType_UNKNOWN:   1,
+	resmgr.TaskType_STATELESS: 1,
+	resmgr.TaskType_STATEFUL:  2,
+	resmgr.TaskType_DAEMON:    3,
+}
+
+// isValid returns true if the drain order is a known policy.
+func (o DrainOrder) isValid() bool {
+	switch o {
+	case LeastCriticalFirst, MostCriticalFirst:
+		return true
+	}
+	return false
+}
+
+// sort returns the tasks in the order in which they should be rescheduled.
+func (o DrainOrder) sort(tasks []*rmtask.RMTask) []*rmtask.RMTask {
+	sorted := make([]*rmtask.RMTask, len(tasks))
+	copy(sorted, tasks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cmp := compareCriticality(sorted[i], sorted[j])
+		if o == MostCriticalFirst {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	return sorted
+}
+
+// compareCriticality returns a negative value if t1 is less critical to
+// reschedule than t2, a positive value if it is more critical and 0 if
+// they are equally critical.
+func compareCriticality(t1, t2 *rmtask.RMTask) int {
+	task1, task2 := t1.Task(), t2.Task()
+	if task1.GetRevocable() != task2.GetRevocable() {
+		return boolCmp(!task1.GetRevocable(), !task2.GetRevocable())
+	}
+	if task1.GetPreemptible() != task2.GetPreemptible() {
+		return boolCmp(!task1.GetPreemptible(), !task2.GetPreemptible())
+	}
+	if cmp := taskTypeCriticality[task1.GetType()] -
+		taskTypeCriticality[task2.GetType()]; cmp != 0 {
+		return cmp
+	}
+	return int(task1.GetPriority()) - int(task2.GetPriority())
+}
+
+func boolCmp(b1, b2 bool) int {
+	switch {
+	case b1 == b2:
+		return 0
+	case b1:
+		return 1
+	default:
+		return -1
+	}
+}
@@ -59,11 +59,20 @@ func CreateStore(storeConfig *CassandraConn, keySpace string, scope tally.Scope)
 	return &cb, nil
 }
 
+// SessionOption customizes the cluster config of a session, after it is
+// populated from the connection config.
+type SessionOption func(cluster *gocql.ClusterConfig)
+
 // CreateStoreSession is to create clusters and connections
 func CreateStoreSession(
-	storeConfig *CassandraConn, keySpace string) (*gocql.Session, error) {
+	storeConfig *CassandraConn,
+	keySpace string,
+	opts ...SessionOption) (*gocql.Session, error) {
 	cluster := newCluster(storeConfig)
 	cluster.Keyspace = keySpace
+	for _, opt := range opts {
+		opt(cluster)
+	}
 
 	if len(storeConfig.Username) != 0 {
 		cluster.Authenticator = gocql.PasswordAuthenticator{
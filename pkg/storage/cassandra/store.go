@@ -106,6 +106,14 @@ const (
 	// _defaultPodEventsLimit is default number of pod events
 	// to read if not provided for jobID + instanceID
 	_defaultPodEventsLimit = 100
+
+	// _defaultVerificationConsistency is the consistency level of the
+	// ORM verification reads if not configured
+	_defaultVerificationConsistency = "ALL"
+
+	// _defaultSessionReadConsistency is the consistency level of the ORM
+	// session reads if not configured
+	_defaultSessionReadConsistency = "LOCAL_QUORUM"
 )
 
 // Config is the config for cassandra Store
@@ -122,6 +130,178 @@ type Config struct {
 	// MaxUpdatesPerJob controls the maximum number of
 	// updates per job kept in the database
 	MaxUpdatesPerJob int `yaml:"max_updates_job"`
+	// ORMVerification enables the dual-read verification mode of the ORM
+	ORMVerification ORMVerificationConfig `yaml:"orm_verification"`
+	// ORMQueries configures the timeout and slow query logging of the ORM
+	ORMQueries ORMQueryConfig `yaml:"orm_queries"`
+	// ORMPool configures the connection pool of the ORM
+	ORMPool ORMPoolConfig `yaml:"orm_pool"`
+	// ORMCircuitBreaker configures the circuit breaker of the ORM
+	ORMCircuitBreaker ORMCircuitBreakerConfig `yaml:"orm_circuit_breaker"`
+	// ORMSessions configures the reads of the ORM sessions
+	ORMSessions ORMSessionConfig `yaml:"orm_sessions"`
+}
+
+// ORMSessionConfig is the config of the reads of the partitions written
+// earlier in their ORM session, which must observe the writes.
+type ORMSessionConfig struct {
+	// ReadConsistency is the consistency level of the session reads,
+	// LOCAL_QUORUM if not set. It must be at least the consistency of
+	// the connection, and observe the writes at that consistency.
+	ReadConsistency string `yaml:"read_consistency"`
+	// Retries is the number of times a session read which misses a write
+	// of its session is retried, 3 if not set.
+	Retries int `yaml:"retries"`
+	// RetryInterval is the interval between the retries of a session
+	// read, 20ms if not set.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+// ORMCircuitBreakerConfig is the config of the circuit breaker of the ORM,
+// which fails the ORM operations fast while Cassandra is unavailable
+// instead of letting them pile up.
+type ORMCircuitBreakerConfig struct {
+	// Enabled turns on the circuit breaker
+	Enabled bool `yaml:"enabled"`
+	// Window is the duration of the windows over which the error rate of
+	// the ORM operations is measured, 10s if not set.
+	Window time.Duration `yaml:"window"`
+	// MinRequests is the min number of ORM operations in a window for the
+	// circuit breaker to open, 20 if not set.
+	MinRequests int `yaml:"min_requests"`
+	// ErrorRate is the rate of ORM operations of a window failed by
+	// Cassandra at which the circuit breaker opens, 0.5 if not set.
+	ErrorRate float64 `yaml:"error_rate"`
+	// OpenTimeout is how long the circuit breaker stays open before it
+	// probes Cassandra, 5s if not set.
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// Probes is the number of ORM operations let through to probe
+	// Cassandra, all of which must succeed to close the circuit breaker,
+	// 1 if not set.
+	Probes int `yaml:"probes"`
+}
+
+// ORMPoolConfig is the config of the connection pool of the ORM, which can
+// also be resized at runtime.
+type ORMPoolConfig struct {
+	// MaxConnsPerHost is the number of connections to every Cassandra
+	// node, connectionsPerHost of the connection if not set.
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+	// MaxQueueDepth is the max number of queries in flight to every
+	// Cassandra node. A node whose queue is full is skipped when routing
+	// a query, and the query fails if every node is full. No limit if
+	// not set.
+	MaxQueueDepth int `yaml:"max_queue_depth"`
+}
+
+// ORMQueryConfig is the config of the queries of the ORM.
+type ORMQueryConfig struct {
+	// Timeout bounds the duration of every ORM operation, on top of the
+	// deadline of the request. No timeout if not set.
+	Timeout time.Duration `yaml:"timeout"`
+	// SlowQueryThreshold is the latency above which an ORM operation is
+	// logged as slow. Slow queries are not logged if not set.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// SlowQueryBufferSize is the number of recent slow queries served on
+	// the debug endpoint, 100 if not set.
+	SlowQueryBufferSize int `yaml:"slow_query_buffer_size"`
+	// HedgedReads enables hedging the reads of the storage objects tagged
+	// with a hedge threshold: a read which takes longer than the threshold
+	// is issued a second time, and the first response is used.
+	HedgedReads bool `yaml:"hedged_reads"`
+	// AsyncWrites configures the batches of the storage objects written
+	// asynchronously, e.g. events.
+	AsyncWrites ORMAsyncWriteConfig `yaml:"async_writes"`
+}
+
+// ORMAsyncWriteConfig is the config of the storage objects written
+// asynchronously by the ORM, which are buffered by table and written in
+// batches.
+type ORMAsyncWriteConfig struct {
+	// FlushInterval is the interval between the writes of the buffered
+	// objects, 1s if not set.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// BatchSize is the number of objects of a table written in a single
+	// batch, 100 if not set.
+	BatchSize int `yaml:"batch_size"`
+	// BufferSize is the max number of objects of a table buffered, 10000
+	// if not set.
+	BufferSize int `yaml:"buffer_size"`
+	// OverflowPolicy is what happens to the objects written when the
+	// buffer of their table is full: they are dropped with "drop", the
+	// default, and the writes wait for the buffer to be flushed with
+	// "block".
+	OverflowPolicy string `yaml:"overflow_policy"`
+	// MaxAttempts is the number of batches an object is written in before
+	// it is lost, 3 if not set.
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// ORMVerificationConfig is the config of the dual-read verification mode
+// of the ORM, in which the rows read are verified against a second read
+// at a different consistency level or from a different cluster.
+type ORMVerificationConfig struct {
+	// Enabled turns on the verification of the rows read
+	Enabled bool `yaml:"enabled"`
+	// Consistency is the consistency level of the verification reads,
+	// ALL if not set
+	Consistency string `yaml:"consistency"`
+	// CassandraConn is the connection of the verification reads, the
+	// connection of the store if not set, e.g. to verify a data migration
+	// to another cluster
+	CassandraConn *impl.CassandraConn `yaml:"connection"`
+	// StoreName is the keyspace of the verification reads, the keyspace
+	// of the store if not set
+	StoreName string `yaml:"store_name"`
+	// Repair writes the rows read from the store over the divergent rows
+	// of the verification store. It only applies if the verification store
+	// is another cluster or keyspace, see VerifiesOtherStore.
+	Repair bool `yaml:"repair"`
+}
+
+// VerificationConfig returns the config of the connection of the
+// verification reads of the ORM.
+func (c *Config) VerificationConfig() *Config {
+	verification := c.ORMVerification
+
+	conn := *c.CassandraConn
+	if verification.CassandraConn != nil {
+		conn = *verification.CassandraConn
+	}
+	conn.Consistency = verification.Consistency
+	if conn.Consistency == "" {
+		conn.Consistency = _defaultVerificationConsistency
+	}
+
+	storeName := c.StoreName
+	if verification.StoreName != "" {
+		storeName = verification.StoreName
+	}
+	return &Config{
+		CassandraConn: &conn,
+		StoreName:     storeName,
+	}
+}
+
+// VerifiesOtherStore tells whether the verification reads of the ORM are
+// served by another cluster or keyspace than the store, rather than by the
+// store read at another consistency level.
+func (c *Config) VerifiesOtherStore() bool {
+	verification := c.VerificationConfig()
+	return verification.StoreName != c.StoreName ||
+		verification.CassandraConn.Port != c.CassandraConn.Port ||
+		!reflect.DeepEqual(
+			verification.CassandraConn.ContactPoints,
+			c.CassandraConn.ContactPoints)
+}
+
+// SessionReadConsistency returns the consistency level of the ORM
+// session reads.
+func (c *Config) SessionReadConsistency() string {
+	if c.ORMSessions.ReadConsistency == "" {
+		return _defaultSessionReadConsistency
+	}
+	return c.ORMSessions.ReadConsistency
 }
 
 type luceneClauses []string
@@ -170,21 +350,57 @@ type Store struct {
 	metrics     *storage.Metrics
 	Conf        *Config
 	retryPolicy backoff.RetryPolicy
+	// partitionDeleter deletes the job configs and pod events of deleted
+	// jobs, they are deleted with the statements of the store if nil
+	partitionDeleter PartitionDeleter
+}
+
+// PartitionDeleter deletes whole partitions of the job tables with the ORM
+// bulk delete, which bounds the number of rows deleted from a partition.
+type PartitionDeleter interface {
+	// DeleteJobConfigs deletes all the config versions of a job.
+	DeleteJobConfigs(ctx context.Context, jobID string) error
+	// DeletePodEvents deletes all the pod events of an instance of a job
+	// and returns the number of events deleted.
+	DeletePodEvents(
+		ctx context.Context,
+		jobID string,
+		instanceID uint32,
+	) (int, error)
+}
+
+// StoreOption is an optional setting of a Store.
+type StoreOption func(*Store)
+
+// WithPartitionDeleter makes the Store delete the job configs and pod events
+// of a job with the provided PartitionDeleter.
+func WithPartitionDeleter(d PartitionDeleter) StoreOption {
+	return func(s *Store) {
+		s.partitionDeleter = d
+	}
 }
 
 // NewStore creates a Store
-func NewStore(config *Config, scope tally.Scope) (*Store, error) {
+func NewStore(
+	config *Config,
+	scope tally.Scope,
+	opts ...StoreOption,
+) (*Store, error) {
 	dataStore, err := impl.CreateStore(config.CassandraConn, config.StoreName, scope)
 	if err != nil {
 		log.Errorf("Failed to NewStore, err=%v", err)
 		return nil, err
 	}
-	return &Store{
+	s := &Store{
 		DataStore:   dataStore,
 		metrics:     storage.NewMetrics(scope.SubScope("storage")),
 		Conf:        config,
 		retryPolicy: backoff.NewRetryPolicy(5, 50*time.Millisecond),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 func (s *Store) handleDataStoreError(err error, p backoff.Retrier) error {
@@ -2005,6 +2221,8 @@ func (s *Store) DeleteTaskRuntime(
 // read pod events for every - instance_id % 100 = 0
 // If pod event exist then continue to delete pod events for next 100 instances
 // If pod event not exist means pod events are deleted for all shrunk instances
+// 5) With a partition deleter, the partition of each instance is deleted
+// in bulk, and the number of events it deleted replaces the read of 4).
 func (s *Store) deletePodEventsOnDeleteJob(
 	ctx context.Context,
 	jobID string) error {
@@ -2015,6 +2233,10 @@ func (s *Store) deletePodEventsOnDeleteJob(
 		return err
 	}
 
+	if s.partitionDeleter != nil {
+		return s.deletePodEventPartitions(ctx, jobID, jobConfig.InstanceCount)
+	}
+
 	for {
 		// 1) read pod events to identify shrunk instances
 		// 2) read pod events if instance_id (shrunk instances) % 100 = 0
@@ -2045,6 +2267,40 @@ func (s *Store) deletePodEventsOnDeleteJob(
 	return nil
 }
 
+// deletePodEventPartitions deletes the pod events of a job with the
+// partition deleter, one instance partition at a time.
+func (s *Store) deletePodEventPartitions(
+	ctx context.Context,
+	jobID string,
+	instanceCount uint32) error {
+	for instanceID := uint32(0); ; instanceID++ {
+		deleted, err := s.partitionDeleter.DeletePodEvents(
+			ctx,
+			jobID,
+			instanceID)
+		if err != nil {
+			s.metrics.JobMetrics.JobDeleteFail.Inc(1)
+			return err
+		}
+		// no events left for the shrunk instances
+		if instanceID > instanceCount &&
+			instanceID%_defaultPodEventsLimit == 0 &&
+			deleted == 0 {
+			return nil
+		}
+	}
+}
+
+// deleteJobConfigs deletes all the config versions of a job.
+func (s *Store) deleteJobConfigs(ctx context.Context, jobID string) error {
+	if s.partitionDeleter != nil {
+		return s.partitionDeleter.DeleteJobConfigs(ctx, jobID)
+	}
+	stmt := s.DataStore.NewQuery().Delete(jobConfigTable).
+		Where(qb.Eq{"job_id": jobID})
+	return s.applyStatement(ctx, stmt, jobID)
+}
+
 // DeleteJob deletes a job and associated tasks, by job id.
 // TODO: This implementation is not perfect, as if it's getting an transient
 // error, the job or some tasks may not be fully deleted.
@@ -2079,8 +2335,7 @@ func (s *Store) DeleteJob(ctx context.Context, jobID string) error {
 		}
 	}
 
-	stmt = queryBuilder.Delete(jobConfigTable).Where(qb.Eq{"job_id": jobID})
-	if err := s.applyStatement(ctx, stmt, jobID); err != nil {
+	if err := s.deleteJobConfigs(ctx, jobID); err != nil {
 		s.metrics.JobMetrics.JobDeleteFail.Inc(1)
 		return err
 	}
@@ -3370,6 +3370,73 @@ func (suite *CassandraStoreTestSuite) TestPodEvents() {
 	}
 }
 
+// fakePartitionDeleter records the partitions deleted through it, and
+// reports podEvents events in the pod events partition of each instance
+// below instances.
+type fakePartitionDeleter struct {
+	jobConfigs []string
+	podEvents  []uint32
+	instances  uint32
+}
+
+func (d *fakePartitionDeleter) DeleteJobConfigs(
+	ctx context.Context,
+	jobID string,
+) error {
+	d.jobConfigs = append(d.jobConfigs, jobID)
+	return nil
+}
+
+func (d *fakePartitionDeleter) DeletePodEvents(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+) (int, error) {
+	d.podEvents = append(d.podEvents, instanceID)
+	if instanceID < d.instances {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// TestDeleteJobWithPartitionDeleter tests that DeleteJob deletes the job
+// configs and the pod events of every instance, including the shrunk ones,
+// with the partition deleter.
+func (suite *CassandraStoreTestSuite) TestDeleteJobWithPartitionDeleter() {
+	jobID := peloton.JobID{Value: uuid.New()}
+	jobConfig := createJobConfig()
+	suite.NoError(suite.createJob(
+		context.Background(),
+		&jobID,
+		jobConfig,
+		&models.ConfigAddOn{},
+		"user1"))
+
+	// the job had 105 instances before it shrunk to 6
+	deleter := &fakePartitionDeleter{instances: 105}
+	s := *store
+	WithPartitionDeleter(deleter)(&s)
+	suite.NoError(s.DeleteJob(context.Background(), jobID.GetValue()))
+
+	suite.Equal([]string{jobID.GetValue()}, deleter.jobConfigs)
+	suite.Len(deleter.podEvents, 201)
+	for i, instanceID := range deleter.podEvents {
+		suite.Equal(uint32(i), instanceID)
+	}
+
+	// the job configs are left to the partition deleter
+	_, _, err := store.GetJobConfigWithVersion(
+		context.Background(),
+		jobID.GetValue(),
+		jobConfig.GetChangeLog().GetVersion())
+	suite.NoError(err)
+
+	suite.NoError(store.deleteJobConfigs(
+		context.Background(),
+		jobID.GetValue()))
+	suite.NoError(deleteJobIndex(context.Background(), &jobID))
+}
+
 func (suite *CassandraStoreTestSuite) TestGetPodEvent() {
 	dummyJobID := &peloton.JobID{Value: "dummy id"}
 	_, err := store.GetPodEvents(
@@ -16,7 +16,10 @@ package cassandra
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/uber/peloton/pkg/common/backoff"
@@ -34,15 +37,43 @@ import (
 const (
 	_defaultRetryTimeout  = 50 * time.Millisecond
 	_defaultRetryAttempts = 5
+	// _maxRetryTimeout caps the exponential backoff between retries
+	_maxRetryTimeout = time.Second
+	// _retryJitter is the fraction of the backoff randomly shaved off
+	_retryJitter = 0.2
+	// _retryBudgetRatio is the number of retries allowed per read, and
+	// _retryBudgetBurst the number of retries allowed in a burst
+	_retryBudgetRatio = 0.1
+	_retryBudgetBurst = 100
 
 	useCasWrite = true
+
+	// _uniqueConstraintsTable keeps a row for every unique key value claimed
+	// by the rows of objects which have a unique constraint
+	_uniqueConstraintsTable = "unique_constraints"
+
+	// _closeSessionDelay is how long the session replaced by a resize of
+	// the pool is kept open for the queries in flight on it
+	_closeSessionDelay = time.Minute
+
+	// _maxBatchBytes bounds the size of the values written by a batch,
+	// which keeps it under the default batch_size_warn_threshold_in_kb of
+	// Cassandra
+	_maxBatchBytes = 5 * 1024
 )
 
 type cassandraConnector struct {
 	// implements orm.Connector interface
 	orm.Connector
-	// Session is the gocql session created for this connector
-	Session *gocql.Session
+
+	// mutex protects session and poolSize, which change on resize
+	mutex sync.RWMutex
+	// session is the gocql session created for this connector
+	session *gocql.Session
+	// poolSize is the size of the connection pool of the session
+	poolSize orm.PoolSize
+	// pool tracks the queries sent to every backend host
+	pool *hostPool
 	// metrics are the storage specific metrics
 	metrics impl.Metrics
 	// scope is the storage scope for metrics
@@ -51,8 +82,23 @@ type cassandraConnector struct {
 	Conf *pelotoncassandra.Config
 	// retryPolicy defines a DB query retry policy for this connector
 	retryPolicy backoff.RetryPolicy
+	// retryBudget limits the retries of the connector
+	retryBudget *backoff.Budget
+	// sessionConsistency is the consistency level of the session reads
+	sessionConsistency gocql.Consistency
 }
 
+// ensure that the connector can be resized at runtime
+var _ orm.Pool = (*cassandraConnector)(nil)
+
+var _ orm.Scanner = (*cassandraConnector)(nil)
+
+var _ orm.UnindexedQuerier = (*cassandraConnector)(nil)
+
+var _ orm.Pager = (*cassandraConnector)(nil)
+
+var _ orm.Batcher = (*cassandraConnector)(nil)
+
 // Config is the config for cassandra Store
 type Config struct {
 	// CassandraConn is the cassandra specific configuration
@@ -61,27 +107,130 @@ type Config struct {
 	StoreName string `yaml:"store_name"`
 }
 
+// IsBackendFailure tells whether the error of an ORM operation is a
+// failure of Cassandra, as opposed to e.g. a row which does not exist.
+func IsBackendFailure(err error) bool {
+	return err != gocql.ErrNotFound && orm.IsBackendFailure(err)
+}
+
+// IsNotFound tells whether the error of an ORM operation means that the
+// row does not exist.
+func IsNotFound(err error) bool {
+	return err == gocql.ErrNotFound || yarpcerrors.IsNotFound(err)
+}
+
 // NewCassandraConnector initializes a Cassandra Connector
 func NewCassandraConnector(
 	config *pelotoncassandra.Config, scope tally.Scope) (
 	orm.Connector, error) {
-	session, err := impl.CreateStoreSession(
-		config.CassandraConn, config.StoreName)
+	// create a storeScope for the keyspace StoreName
+	storeScope := scope.Tagged(map[string]string{"store": config.StoreName})
+
+	sessionConsistency, err := gocql.ParseConsistencyWrapper(
+		config.SessionReadConsistency())
 	if err != nil {
 		return nil, err
 	}
 
-	// create a storeScope for the keyspace StoreName
-	storeScope := scope.Tagged(map[string]string{"store": config.StoreName})
-
-	return &cassandraConnector{
-		Session: session,
+	c := &cassandraConnector{
+		poolSize: orm.PoolSize{
+			MaxConnsPerHost: config.ORMPool.MaxConnsPerHost,
+			MaxQueueDepth:   config.ORMPool.MaxQueueDepth,
+		},
+		pool:    newHostPool(storeScope, config.ORMPool.MaxQueueDepth),
 		metrics: impl.NewMetrics(storeScope),
 		scope:   storeScope,
 		Conf:    config,
-		retryPolicy: backoff.NewRetryPolicy(
-			_defaultRetryAttempts, _defaultRetryTimeout),
-	}, nil
+		retryPolicy: backoff.NewExponentialRetryPolicy(
+			_defaultRetryAttempts,
+			_defaultRetryTimeout,
+			_maxRetryTimeout,
+			_retryJitter),
+		retryBudget: backoff.NewBudget(
+			_retryBudgetRatio, _retryBudgetBurst),
+		sessionConsistency: sessionConsistency,
+	}
+	session, err := c.createSession(&c.poolSize.MaxConnsPerHost)
+	if err != nil {
+		return nil, err
+	}
+	c.session = session
+	return c, nil
+}
+
+// createSession creates a gocql session whose queries are tracked by the
+// host pool of the connector. maxConnsPerHost is the number of connections
+// to every host, and is set to the default of the connection if zero.
+func (c *cassandraConnector) createSession(
+	maxConnsPerHost *int) (*gocql.Session, error) {
+	return impl.CreateStoreSession(
+		c.Conf.CassandraConn,
+		c.Conf.StoreName,
+		func(cluster *gocql.ClusterConfig) {
+			if *maxConnsPerHost > 0 {
+				cluster.NumConns = *maxConnsPerHost
+			} else {
+				*maxConnsPerHost = cluster.NumConns
+			}
+			cluster.PoolConfig.HostSelectionPolicy = &queueingPolicy{
+				HostSelectionPolicy: cluster.PoolConfig.HostSelectionPolicy,
+				pool:                c.pool,
+			}
+			cluster.QueryObserver = c.pool
+		})
+}
+
+// getSession returns the current gocql session of the connector.
+func (c *cassandraConnector) getSession() *gocql.Session {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.session
+}
+
+// PoolStatus implements orm.Pool.
+func (c *cassandraConnector) PoolStatus() *orm.PoolStatus {
+	c.mutex.RLock()
+	size := c.poolSize
+	c.mutex.RUnlock()
+	size.MaxQueueDepth = c.pool.getMaxQueueDepth()
+
+	return &orm.PoolStatus{
+		PoolSize: size,
+		Hosts:    c.pool.stats(),
+	}
+}
+
+// ResizePool implements orm.Pool. The max queue depth applies to the next
+// queries. A change of the number of connections per host replaces the
+// session by a new one, the old session being closed once the queries in
+// flight on it are done.
+func (c *cassandraConnector) ResizePool(size orm.PoolSize) error {
+	if err := orm.ValidatePoolSize(size); err != nil {
+		return yarpcerrors.InvalidArgumentErrorf("%v", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pool.setMaxQueueDepth(size.MaxQueueDepth)
+	c.poolSize.MaxQueueDepth = size.MaxQueueDepth
+	if size.MaxConnsPerHost != c.poolSize.MaxConnsPerHost {
+		session, err := c.createSession(&size.MaxConnsPerHost)
+		if err != nil {
+			return err
+		}
+		old := c.session
+		c.session = session
+		c.poolSize.MaxConnsPerHost = size.MaxConnsPerHost
+		time.AfterFunc(_closeSessionDelay, old.Close)
+	}
+
+	log.WithFields(log.Fields{
+		"store":              c.Conf.StoreName,
+		"max_conns_per_host": c.poolSize.MaxConnsPerHost,
+		"max_queue_depth":    c.poolSize.MaxQueueDepth,
+	}).Info("Resized ORM connection pool")
+	return nil
 }
 
 // buildResultRow is used to allocate memory for the row to be populated by
@@ -191,7 +340,31 @@ func splitColumnNameValue(row []base.Column) (
 	return colNames, colValues
 }
 
-// TODO add retry and conversion of gocql errors to yarpcerrors
+// TODO add conversion of gocql errors to yarpcerrors
+
+// isTransientError returns true for the gocql errors of queries which
+// could not be served by Cassandra, and can be retried.
+func isTransientError(err error) bool {
+	switch err {
+	case gocql.ErrUnavailable,
+		gocql.ErrNoConnections,
+		gocql.ErrConnectionClosed,
+		gocql.ErrNoStreams:
+		return true
+	}
+	_, ok := err.(*gocql.RequestErrUnavailable)
+	return ok
+}
+
+// retryRead retries a read on transient errors. Writes are not retried
+// since a failed write may have been applied.
+func (c *cassandraConnector) retryRead(ctx context.Context, read func() error) error {
+	if c.retryPolicy == nil {
+		return read()
+	}
+	return backoff.RetryContext(
+		ctx, read, c.retryPolicy, isTransientError, c.retryBudget)
+}
 
 func (c *cassandraConnector) sendLatency(
 	ctx context.Context, name string, d time.Duration) {
@@ -227,6 +400,144 @@ func (c *cassandraConnector) create(
 	// maintained.
 	colNames, colValues := splitColumnNameValue(row)
 
+	// Claim the unique key of the row before writing it, so that concurrent
+	// creates of rows with the same unique key cannot both succeed.
+	var uniqueKey string
+	if len(e.UniqueKeys) > 0 {
+		var err error
+		if uniqueKey, err = getUniqueKey(e, row); err != nil {
+			return err
+		}
+		if err = c.claimUniqueKey(ctx, e, uniqueKey); err != nil {
+			return err
+		}
+	}
+
+	err := c.insert(ctx, e, colNames, colValues, casWrite)
+	if err != nil && len(e.UniqueKeys) > 0 {
+		c.releaseUniqueKey(ctx, e, uniqueKey)
+	}
+	return err
+}
+
+// CreateBatch creates the rows in DB with unlogged batches of the rows of
+// the same partition, each of them bounded to _maxBatchBytes of values, so
+// that a batch is applied by the replicas of a single partition without
+// going through the batch log. It returns the error of the first batch
+// which fails, after which some of the rows may have been created. The
+// rows are expected to be of a table without unique constraint.
+func (c *cassandraConnector) CreateBatch(
+	ctx context.Context,
+	e *base.Definition,
+	rows [][]base.Column,
+) error {
+	for _, batchRows := range splitBatch(e, rows) {
+		if err := c.createBatch(ctx, e, batchRows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createBatch creates the rows in DB with a single unlogged batch
+func (c *cassandraConnector) createBatch(
+	ctx context.Context,
+	e *base.Definition,
+	rows [][]base.Column,
+) error {
+	batch := c.getSession().NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+	for _, row := range rows {
+		colNames, colValues := splitColumnNameValue(row)
+		stmt, err := InsertStmt(
+			Table(e.Name),
+			Columns(colNames),
+			Values(colValues),
+		)
+		if err != nil {
+			return err
+		}
+		batch.Query(stmt, colValues...)
+	}
+	defer c.sendLatency(ctx, "execute_latency", time.Duration(batch.Latency()))
+
+	if err := c.getSession().ExecuteBatch(batch); err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return err
+	}
+
+	c.metrics.ExecuteSuccess.Inc(1)
+	return nil
+}
+
+// splitBatch splits rows into batches of the rows of the same partition,
+// in the order of their first row, with at most _maxBatchBytes of values
+// in a batch unless it has a single row.
+func splitBatch(e *base.Definition, rows [][]base.Column) [][][]base.Column {
+	var partitions []string
+	byPartition := make(map[string][][][]base.Column)
+	sizes := make(map[string]int)
+	for _, row := range rows {
+		partition := partitionKeyString(e, row)
+		batches, ok := byPartition[partition]
+		if !ok {
+			partitions = append(partitions, partition)
+		}
+
+		size := rowSize(row)
+		if len(batches) == 0 || sizes[partition]+size > _maxBatchBytes {
+			batches = append(batches, nil)
+			sizes[partition] = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], row)
+		sizes[partition] += size
+		byPartition[partition] = batches
+	}
+
+	var result [][][]base.Column
+	for _, partition := range partitions {
+		result = append(result, byPartition[partition]...)
+	}
+	return result
+}
+
+// partitionKeyString returns the values of the partition key of a row as
+// a string, which is the same for the rows of the same partition
+func partitionKeyString(e *base.Definition, row []base.Column) string {
+	values := make([]interface{}, len(e.Key.PartitionKeys))
+	for _, col := range row {
+		for i, pk := range e.Key.PartitionKeys {
+			if col.Name == pk {
+				values[i] = col.Value
+			}
+		}
+	}
+	return fmt.Sprintf("%v", values)
+}
+
+// rowSize returns the approximate size of the values of a row
+func rowSize(row []base.Column) int {
+	size := 0
+	for _, col := range row {
+		switch v := col.Value.(type) {
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		default:
+			size += 8
+		}
+	}
+	return size
+}
+
+// insert writes a row with the given columns to the DB
+func (c *cassandraConnector) insert(
+	ctx context.Context,
+	e *base.Definition,
+	colNames []string,
+	colValues []interface{},
+	casWrite bool,
+) error {
 	// Prepare insert statement
 	stmt, err := InsertStmt(
 		Table(e.Name),
@@ -238,7 +549,7 @@ func (c *cassandraConnector) create(
 		return err
 	}
 
-	q := c.Session.Query(stmt, colValues...).WithContext(ctx)
+	q := c.getSession().Query(stmt, colValues...).WithContext(ctx)
 	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
 
 	if casWrite {
@@ -261,7 +572,9 @@ func (c *cassandraConnector) create(
 	return nil
 }
 
-// buildSelectQuery builds a select query using base object and key columns
+// buildSelectQuery builds a select query using base object and key columns.
+// Session reads are escalated to the session consistency of the connector
+// to observe the writes of their session.
 func (c *cassandraConnector) buildSelectQuery(
 	ctx context.Context,
 	e *base.Definition,
@@ -284,7 +597,11 @@ func (c *cassandraConnector) buildSelectQuery(
 		return nil, err
 	}
 
-	return c.Session.Query(stmt, keyColValues...).WithContext(ctx), nil
+	q := c.getSession().Query(stmt, keyColValues...).WithContext(ctx)
+	if orm.IsSessionRead(ctx) {
+		q = q.Consistency(c.sessionConsistency)
+	}
+	return q, nil
 }
 
 // Get fetches a record from DB using primary keys
@@ -305,7 +622,9 @@ func (c *cassandraConnector) Get(
 	// build a result row
 	result := buildResultRow(e, colNamesToRead)
 
-	if err := q.Scan(result...); err != nil {
+	if err := c.retryRead(ctx, func() error {
+		return q.Scan(result...)
+	}); err != nil {
 		c.metrics.ExecuteFail.Inc(1)
 		return nil, err
 	}
@@ -329,17 +648,140 @@ func (c *cassandraConnector) GetAll(
 	}
 	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
 
-	// execute query and get Iterator
-	iter := q.Iter()
-	defer func() {
-		errors = iter.Close()
-	}()
+	// execute query and iterate its result, the rows read by a failed
+	// attempt are dropped
+	if err := c.retryRead(ctx, func() error {
+		rows = nil
+		iter := q.Iter()
+		for result := buildResultRow(e, colNamesToRead); iter.Scan(result...); {
+			rows = append(rows, getRowFromResult(e, colNamesToRead, result))
+		}
+		return iter.Close()
+	}); err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return nil, err
+	}
+
+	// translate the read result into a row ([]base.Column)
+	c.metrics.ExecuteSuccess.Inc(1)
+	return rows, nil
+}
+
+// GetAllPage fetches at most pageSize rows from DB using partition keys,
+// starting at the paging state of Cassandra given as page token. It
+// returns the rows and the paging state of the next page, empty if there
+// are no more rows.
+func (c *cassandraConnector) GetAllPage(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+	pageSize int,
+	pageToken []byte,
+) (rows [][]base.Column, next []byte, err error) {
+	colNamesToRead := e.GetColumnsToRead()
+
+	q, err := c.buildSelectQuery(ctx, e, keyCols, colNamesToRead)
+	if err != nil {
+		return nil, nil, err
+	}
+	q = q.PageSize(pageSize).PageState(pageToken)
+	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
+
+	// execute query and read its first page only, the rows read by a
+	// failed attempt are dropped
+	if err := c.retryRead(ctx, func() error {
+		rows = nil
+		iter := q.Iter()
+		next = iter.PageState()
+		for result := buildResultRow(e, colNamesToRead); len(rows) < pageSize &&
+			iter.Scan(result...); {
+			rows = append(rows, getRowFromResult(e, colNamesToRead, result))
+		}
+		return iter.Close()
+	}); err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return nil, nil, err
+	}
 
+	c.metrics.ExecuteSuccess.Inc(1)
+	return rows, next, nil
+}
+
+// Scan reads every row of the table, page by page, and calls fn with each
+// of them. It stops at the first error returned by fn.
+func (c *cassandraConnector) Scan(
+	ctx context.Context,
+	e *base.Definition,
+	fn func(row []base.Column) error,
+) error {
+	colNamesToRead := e.GetColumnsToRead()
+
+	stmt, err := SelectStmt(
+		Table(e.Name),
+		Columns(colNamesToRead),
+	)
+	if err != nil {
+		return err
+	}
+	q := c.getSession().Query(stmt).WithContext(ctx)
+	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
+
+	iter := q.Iter()
 	for result := buildResultRow(e, colNamesToRead); iter.Scan(result...); {
-		rows = append(rows, getRowFromResult(e, colNamesToRead, result))
+		if err := fn(getRowFromResult(e, colNamesToRead, result)); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return err
+	}
+
+	c.metrics.ExecuteSuccess.Inc(1)
+	return nil
+}
+
+// QueryUnindexed reads the rows of the table whose columns are equal to
+// the values of the conditions with ALLOW FILTERING, so that the columns
+// need not be part of the primary key. Cassandra reads the whole table to
+// answer it. At most limit rows are read, all of them if zero.
+func (c *cassandraConnector) QueryUnindexed(
+	ctx context.Context,
+	e *base.Definition,
+	conditions []base.Column,
+	limit int,
+) (rows [][]base.Column, err error) {
+	colNamesToRead := e.GetColumnsToRead()
+	condColNames, condColValues := splitColumnNameValue(conditions)
+
+	stmt, err := SelectStmt(
+		Table(e.Name),
+		Columns(colNamesToRead),
+		Conditions(condColNames),
+		Limit(limit),
+		AllowFiltering(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	q := c.getSession().Query(stmt, condColValues...).WithContext(ctx)
+	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
+
+	// execute query and iterate its result, the rows read by a failed
+	// attempt are dropped
+	if err := c.retryRead(ctx, func() error {
+		rows = nil
+		iter := q.Iter()
+		for result := buildResultRow(e, colNamesToRead); iter.Scan(result...); {
+			rows = append(rows, getRowFromResult(e, colNamesToRead, result))
+		}
+		return iter.Close()
+	}); err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return nil, err
 	}
 
-	// translate the read result into a row ([]base.Column)
 	c.metrics.ExecuteSuccess.Inc(1)
 	return rows, nil
 }
@@ -349,7 +791,28 @@ func (c *cassandraConnector) Delete(
 	ctx context.Context,
 	e *base.Definition,
 	keyCols []base.Column,
-) error {
+) (errors error) {
+
+	// Read the unique key of the row so that it is released with the row
+	var uniqueKey string
+	if len(e.UniqueKeys) > 0 {
+		row, err := c.Get(ctx, e, keyCols)
+		if err == gocql.ErrNotFound {
+			// nothing to delete, and no unique key to release
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if uniqueKey, err = getUniqueKey(e, row); err != nil {
+			return err
+		}
+		defer func() {
+			if errors == nil {
+				c.releaseUniqueKey(ctx, e, uniqueKey)
+			}
+		}()
+	}
 
 	// split keyCols into a list of names and values to compose query stmt using
 	// names and use values in the session query call, so the order needs to be
@@ -365,7 +828,7 @@ func (c *cassandraConnector) Delete(
 		return err
 	}
 
-	q := c.Session.Query(stmt, keyColValues...).WithContext(ctx)
+	q := c.getSession().Query(stmt, keyColValues...).WithContext(ctx)
 	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
 
 	if err := q.Exec(); err != nil {
@@ -383,7 +846,27 @@ func (c *cassandraConnector) Update(
 	e *base.Definition,
 	row []base.Column,
 	keyCols []base.Column,
-) error {
+) (errors error) {
+
+	// Move the claim on the unique key of the row if the update changes it
+	if len(e.UniqueKeys) > 0 && updatesUniqueKey(e, row) {
+		oldKey, newKey, err := c.getUpdatedUniqueKeys(ctx, e, row, keyCols)
+		if err != nil {
+			return err
+		}
+		if newKey != oldKey {
+			if err := c.claimUniqueKey(ctx, e, newKey); err != nil {
+				return err
+			}
+			defer func() {
+				if errors != nil {
+					c.releaseUniqueKey(ctx, e, newKey)
+				} else if oldKey != "" {
+					c.releaseUniqueKey(ctx, e, oldKey)
+				}
+			}()
+		}
+	}
 
 	// split keyCols into a list of names and values to compose query stmt using
 	// names and use values in the session query call, so the order needs to be
@@ -409,7 +892,7 @@ func (c *cassandraConnector) Update(
 	// list of values to be supplied in the query
 	updateVals := append(colValues, keyColValues...)
 
-	q := c.Session.Query(
+	q := c.getSession().Query(
 		stmt, updateVals...).WithContext(ctx)
 	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
 
@@ -421,3 +904,161 @@ func (c *cassandraConnector) Update(
 	c.metrics.ExecuteSuccess.Inc(1)
 	return nil
 }
+
+// getUniqueKey encodes the values of the unique columns of a row into the
+// key which is claimed in the unique constraints table.
+func getUniqueKey(e *base.Definition, row []base.Column) (string, error) {
+	values := make(map[string]interface{}, len(row))
+	for _, column := range row {
+		values[column.Name] = column.Value
+	}
+
+	uniqueValues := make([]interface{}, 0, len(e.UniqueKeys))
+	for _, col := range e.UniqueKeys {
+		value, ok := values[col]
+		if !ok {
+			return "", yarpcerrors.InternalErrorf(
+				"row of %s is missing unique key %s", e.Name, col)
+		}
+		uniqueValues = append(uniqueValues, normalizeUniqueValue(value))
+	}
+
+	buffer, err := json.Marshal(uniqueValues)
+	if err != nil {
+		return "", yarpcerrors.InternalErrorf(
+			"cannot encode unique key of %s: %v", e.Name, err)
+	}
+	return string(buffer), nil
+}
+
+// normalizeUniqueValue makes a column value written to the DB and the same
+// value read back from the DB encode to the same unique key. Values are read
+// back as pointers, and timestamps are stored with millisecond precision.
+func normalizeUniqueValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+	return v.Interface()
+}
+
+// updatesUniqueKey returns true if the row updates one of the unique columns
+func updatesUniqueKey(e *base.Definition, row []base.Column) bool {
+	for _, column := range row {
+		for _, col := range e.UniqueKeys {
+			if column.Name == col {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getUpdatedUniqueKeys returns the unique key of the row currently stored in
+// the DB, empty if there is none, and the unique key of the row once updated.
+func (c *cassandraConnector) getUpdatedUniqueKeys(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+	keyCols []base.Column,
+) (string, string, error) {
+	var oldKey string
+	current, err := c.Get(ctx, e, keyCols)
+	switch {
+	case err == gocql.ErrNotFound:
+		// update creates the row
+		current = keyCols
+	case err != nil:
+		return "", "", err
+	default:
+		if oldKey, err = getUniqueKey(e, current); err != nil {
+			return "", "", err
+		}
+	}
+
+	// apply the update on top of the current row
+	updated := append([]base.Column{}, current...)
+	updated = append(updated, row...)
+	newKey, err := getUniqueKey(e, updated)
+	if err != nil {
+		return "", "", err
+	}
+	return oldKey, newKey, nil
+}
+
+// claimUniqueKey records the unique key of a row in the unique constraints
+// table using CAS write. It fails with AlreadyExists if another row of the
+// object already holds the same unique key.
+func (c *cassandraConnector) claimUniqueKey(
+	ctx context.Context,
+	e *base.Definition,
+	uniqueKey string,
+) error {
+	stmt, err := InsertStmt(
+		Table(_uniqueConstraintsTable),
+		Columns([]string{"table_name", "unique_key"}),
+		Values([]interface{}{e.Name, uniqueKey}),
+		IfNotExist(useCasWrite),
+	)
+	if err != nil {
+		return err
+	}
+
+	q := c.getSession().Query(stmt, e.Name, uniqueKey).WithContext(ctx)
+	defer c.sendLatency(ctx, "execute_latency", time.Duration(q.Latency()))
+
+	applied, err := q.MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		return err
+	}
+	if !applied {
+		return yarpcerrors.AlreadyExistsErrorf(
+			"unique constraint %v of %s violated by %s",
+			e.UniqueKeys, e.Name, uniqueKey)
+	}
+
+	c.metrics.ExecuteSuccess.Inc(1)
+	return nil
+}
+
+// releaseUniqueKey removes the unique key of a row which was deleted, or
+// could not be written, from the unique constraints table using CAS write,
+// which is serialized with the CAS writes claiming the key. Failures are only
+// logged since the row itself has already been taken care of.
+func (c *cassandraConnector) releaseUniqueKey(
+	ctx context.Context,
+	e *base.Definition,
+	uniqueKey string,
+) {
+	stmt, err := DeleteStmt(
+		Table(_uniqueConstraintsTable),
+		Conditions([]string{"table_name", "unique_key"}),
+		IfExist(useCasWrite),
+	)
+	if err == nil {
+		q := c.getSession().Query(stmt, e.Name, uniqueKey).WithContext(ctx)
+		defer c.sendLatency(
+			ctx, "execute_latency", time.Duration(q.Latency()))
+		err = q.Exec()
+	}
+
+	if err != nil {
+		c.metrics.ExecuteFail.Inc(1)
+		log.WithError(err).
+			WithField("table", e.Name).
+			WithField("unique_key", uniqueKey).
+			Warn("Failed to release unique key")
+		return
+	}
+	c.metrics.ExecuteSuccess.Inc(1)
+}
@@ -16,9 +16,11 @@ package cassandra
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"reflect"
+	"strings"
 	"time"
 
 	pelotoncassandra "github.com/uber/peloton/pkg/storage/cassandra"
@@ -40,6 +42,7 @@ var connector *cassandraConnector
 // test table name to be created for this test
 var testTableName1 string
 var testTableName2 string
+var testTableName3 string
 
 // testRow in DB representation looks like this:
 //
@@ -133,6 +136,7 @@ func init() {
 
 	testTableName1 = fmt.Sprintf("test_table_%d", rand.Intn(1000))
 	testTableName2 = fmt.Sprintf("test_table_%d", rand.Intn(1000))
+	testTableName3 = fmt.Sprintf("test_table_%d", rand.Intn(1000))
 
 	// create a test table
 	table1 := fmt.Sprintf("CREATE TABLE peloton_test.%s"+
@@ -151,6 +155,24 @@ func init() {
 		log.Fatal(err)
 	}
 
+	// create a test table with a unique constraint on "id" and "name"
+	table3 := fmt.Sprintf("CREATE TABLE peloton_test.%s"+
+		" (id int, ck int, name text, data text, PRIMARY KEY ((id), ck))",
+		testTableName3)
+
+	if err := session.Query(table3).Exec(); err != nil {
+		log.Fatal(err)
+	}
+
+	// make sure the unique constraints table exists even if the test
+	// keyspace has not been migrated
+	if err := session.Query("CREATE TABLE IF NOT EXISTS " +
+		"peloton_test.unique_constraints (table_name text, " +
+		"unique_key text, PRIMARY KEY ((table_name, unique_key)))",
+	).Exec(); err != nil {
+		log.Fatal(err)
+	}
+
 	testScope := tally.NewTestScope("", map[string]string{})
 	conn, err := NewCassandraConnector(config, testScope)
 	if err != nil {
@@ -312,6 +334,56 @@ func (suite *CassandraConnSuite) TestCreateGetAll() {
 	}
 }
 
+// TestScan tests reading every row of a table
+func (suite *CassandraConnSuite) TestScan() {
+	obj := &base.Definition{
+		Name: testTableName2,
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+			ClusteringKeys: []*base.ClusteringKey{
+				{
+					Name:       "ck",
+					Descending: true,
+				},
+			},
+		},
+		ColumnToType: map[string]reflect.Type{
+			"id":   reflect.TypeOf(1),
+			"ck":   reflect.TypeOf(1),
+			"data": reflect.TypeOf("data"),
+			"name": reflect.TypeOf("name"),
+		},
+	}
+
+	for _, row := range testRowsWithCK {
+		err := connector.Create(context.Background(), obj, row)
+		suite.NoError(err)
+	}
+
+	var cks []int
+	err := connector.Scan(context.Background(), obj,
+		func(row []base.Column) error {
+			for _, col := range row {
+				if col.Name == "ck" {
+					cks = append(cks, *col.Value.(*int))
+				}
+			}
+			return nil
+		})
+	suite.NoError(err)
+	suite.ElementsMatch([]int{10, 20}, cks)
+
+	// the scan stops at the first error
+	count := 0
+	err = connector.Scan(context.Background(), obj,
+		func(row []base.Column) error {
+			count++
+			return errors.New("export failed")
+		})
+	suite.Error(err)
+	suite.Equal(1, count)
+}
+
 // TestCreateIfNotExists tests the CreateIfNotExists operation
 func (suite *CassandraConnSuite) TestCreateIfNotExists() {
 	// Definition stores schema information about an Object
@@ -370,3 +442,150 @@ func (suite *CassandraConnSuite) TestDBFailures() {
 	err = connector.Delete(ctx, obj, keyRow)
 	suite.Error(err)
 }
+
+// TestUniqueConstraint tests that rows with the same unique key cannot be
+// created twice, and that the unique key is released on update and delete
+func (suite *CassandraConnSuite) TestUniqueConstraint() {
+	obj := &base.Definition{
+		Name: testTableName3,
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+			ClusteringKeys: []*base.ClusteringKey{
+				{
+					Name:       "ck",
+					Descending: true,
+				},
+			},
+		},
+		ColumnToType: map[string]reflect.Type{
+			"id":   reflect.TypeOf(1),
+			"ck":   reflect.TypeOf(1),
+			"data": reflect.TypeOf("data"),
+			"name": reflect.TypeOf("name"),
+		},
+		UniqueKeys: []string{"id", "name"},
+	}
+	row := func(ck int, name string) []base.Column {
+		return []base.Column{
+			{Name: "id", Value: 1},
+			{Name: "ck", Value: ck},
+			{Name: "name", Value: name},
+			{Name: "data", Value: "testdata"},
+		}
+	}
+	key := func(ck int) []base.Column {
+		return []base.Column{
+			{Name: "id", Value: 1},
+			{Name: "ck", Value: ck},
+		}
+	}
+	ctx := context.Background()
+
+	suite.NoError(connector.Create(ctx, obj, row(10, "test")))
+
+	// same id and name with another clustering key violates the constraint
+	err := connector.Create(ctx, obj, row(20, "test"))
+	suite.True(yarpcerrors.IsAlreadyExists(err))
+	_, err = connector.Get(ctx, obj, key(20))
+	suite.Equal(gocql.ErrNotFound, err)
+
+	// updating the name moves the unique key of the row
+	suite.NoError(connector.Update(ctx, obj,
+		[]base.Column{{Name: "name", Value: "test2"}}, key(10)))
+	suite.NoError(connector.Create(ctx, obj, row(20, "test")))
+	err = connector.Update(ctx, obj,
+		[]base.Column{{Name: "name", Value: "test"}}, key(10))
+	suite.True(yarpcerrors.IsAlreadyExists(err))
+
+	// updating other columns does not touch the unique key
+	suite.NoError(connector.Update(ctx, obj,
+		[]base.Column{{Name: "data", Value: "newdata"}}, key(10)))
+
+	// deleting a row releases its unique key
+	suite.NoError(connector.Delete(ctx, obj, key(10)))
+	suite.NoError(connector.Create(ctx, obj, row(30, "test2")))
+}
+
+// TestGetUniqueKey tests that values written and read back from the DB
+// encode to the same unique key
+func (suite *CassandraConnSuite) TestGetUniqueKey() {
+	obj := &base.Definition{
+		Name:       "test",
+		UniqueKeys: []string{"id", "name", "time"},
+	}
+	now := time.Now()
+	id := int64(1)
+	name := "test"
+	readTime := now.Truncate(time.Millisecond)
+
+	written, err := getUniqueKey(obj, []base.Column{
+		{Name: "id", Value: uint64(1)},
+		{Name: "name", Value: name},
+		{Name: "time", Value: now},
+		{Name: "data", Value: "data"},
+	})
+	suite.NoError(err)
+
+	read, err := getUniqueKey(obj, []base.Column{
+		{Name: "data", Value: "data"},
+		{Name: "time", Value: &readTime},
+		{Name: "name", Value: &name},
+		{Name: "id", Value: &id},
+	})
+	suite.NoError(err)
+	suite.Equal(written, read)
+
+	_, err = getUniqueKey(obj, []base.Column{
+		{Name: "id", Value: uint64(1)},
+	})
+	suite.Error(err)
+}
+
+// TestSplitBatch tests that the rows of a batch are split by partition,
+// and by size within a partition
+func (suite *CassandraConnSuite) TestSplitBatch() {
+	obj := &base.Definition{
+		Name: "test",
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+			ClusteringKeys: []*base.ClusteringKey{
+				{Name: "seq"},
+			},
+		},
+	}
+	row := func(id uint64, seq uint64, data string) []base.Column {
+		return []base.Column{
+			{Name: "id", Value: id},
+			{Name: "seq", Value: seq},
+			{Name: "data", Value: data},
+		}
+	}
+	large := strings.Repeat("x", _maxBatchBytes/2)
+
+	rows := [][]base.Column{
+		row(1, 1, "a"),
+		row(2, 1, "b"),
+		row(1, 2, "c"),
+		row(3, 1, large),
+		row(3, 2, large),
+		row(3, 3, large+large),
+	}
+	suite.Equal([][][]base.Column{
+		{rows[0], rows[2]},
+		{rows[1]},
+		{rows[3]},
+		{rows[4]},
+		{rows[5]},
+	}, splitBatch(obj, rows))
+	suite.Empty(splitBatch(obj, nil))
+}
+
+// TestIsBackendFailure tests that rows which do not exist are not
+// failures of Cassandra
+func (suite *CassandraConnSuite) TestIsBackendFailure() {
+	suite.False(IsBackendFailure(nil))
+	suite.False(IsBackendFailure(gocql.ErrNotFound))
+	suite.False(IsBackendFailure(yarpcerrors.AlreadyExistsErrorf("exists")))
+	suite.True(IsBackendFailure(gocql.ErrNoConnections))
+	suite.True(IsBackendFailure(gocql.ErrTimeoutNoResponse))
+}
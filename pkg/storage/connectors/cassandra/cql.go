@@ -35,6 +35,13 @@ const (
 	updates = "Updates"
 	// ifNotExist is used to indicate CAS write in the insert query
 	ifNotExist = "IfNotExist"
+	// ifExist is used to indicate CAS write in the delete query
+	ifExist = "IfExist"
+	// limit is used to limit the number of rows read by the select query
+	limit = "Limit"
+	// allowFiltering is used to allow conditions on columns which are not
+	// part of the primary key in the select query
+	allowFiltering = "AllowFiltering"
 
 	// insertTemplate is used to construct an insert query
 	insertTemplate = `INSERT INTO {{.Table}} ({{ColumnFunc .Columns ", "}})` +
@@ -42,11 +49,12 @@ const (
 
 	// selectTemplate is used to construct a select query
 	selectTemplate = `SELECT {{ColumnFunc .Columns ", "}} FROM {{.Table}}` +
-		`{{WhereFunc .Conditions}}{{ConditionsFunc .Conditions " AND "}};`
+		`{{WhereFunc .Conditions}}{{ConditionsFunc .Conditions " AND "}}` +
+		`{{LimitFunc .Limit}}{{AllowFilteringFunc .AllowFiltering}};`
 
 	// deleteTemplate is used to construct a delete query
 	deleteTemplate = `DELETE FROM {{.Table}} WHERE ` +
-		`{{ConditionsFunc .Conditions " AND "}};`
+		`{{ConditionsFunc .Conditions " AND "}}{{IfExistFunc .IfExist}};`
 
 	// updateTemplate is used to construct update query
 	updateTemplate = `UPDATE {{.Table}} SET {{ConditionsFunc .Updates ", "}}` +
@@ -56,11 +64,14 @@ const (
 var (
 	// function map for populating CQL templates
 	funcMap = template.FuncMap{
-		"ColumnFunc":     strings.Join,
-		"QuestionMark":   questionMarkFunc,
-		"ConditionsFunc": conditionsFunc,
-		"WhereFunc":      whereFunc,
-		"ExistsFunc":     existsFunc,
+		"ColumnFunc":         strings.Join,
+		"QuestionMark":       questionMarkFunc,
+		"ConditionsFunc":     conditionsFunc,
+		"WhereFunc":          whereFunc,
+		"ExistsFunc":         existsFunc,
+		"LimitFunc":          limitFunc,
+		"AllowFilteringFunc": allowFilteringFunc,
+		"IfExistFunc":        ifExistFunc,
 	}
 
 	// insert CQL query template implementation
@@ -111,6 +122,32 @@ func existsFunc(exists bool) string {
 	return ""
 }
 
+// limitFunc adds a limit clause to the select query, if the limit is set
+// and positive
+func limitFunc(v interface{}) string {
+	if n, ok := v.(int); ok && n > 0 {
+		return fmt.Sprintf(" LIMIT %d", n)
+	}
+	return ""
+}
+
+// allowFilteringFunc adds an allow filtering clause to the select query,
+// if it is set
+func allowFilteringFunc(v interface{}) string {
+	if allow, ok := v.(bool); ok && allow {
+		return " ALLOW FILTERING"
+	}
+	return ""
+}
+
+// ifExistFunc adds an if exists clause to the delete query, if it is set
+func ifExistFunc(v interface{}) string {
+	if exists, ok := v.(bool); ok && exists {
+		return " IF EXISTS"
+	}
+	return ""
+}
+
 // Option to compose a cql statement
 type Option map[string]interface{}
 
@@ -156,6 +193,20 @@ func Updates(v interface{}) OptFunc {
 	}
 }
 
+// Limit sets the `limit` clause to the cql statement
+func Limit(v int) OptFunc {
+	return func(opt Option) {
+		opt[limit] = v
+	}
+}
+
+// AllowFiltering sets the `allow filtering` clause to the cql statement
+func AllowFiltering(v bool) OptFunc {
+	return func(opt Option) {
+		opt[allowFiltering] = v
+	}
+}
+
 // IfNotExist sets the `if not exist` clause to the cql statement
 func IfNotExist(v interface{}) OptFunc {
 	return func(opt Option) {
@@ -163,6 +214,13 @@ func IfNotExist(v interface{}) OptFunc {
 	}
 }
 
+// IfExist sets the `if exists` clause to the cql statement
+func IfExist(v bool) OptFunc {
+	return func(opt Option) {
+		opt[ifExist] = v
+	}
+}
+
 // InsertStmt creates insert statement
 func InsertStmt(opts ...OptFunc) (string, error) {
 	var bb bytes.Buffer
@@ -108,6 +108,32 @@ func (suite *CassandraConnSuite) TestSelectStmt() {
 	}
 }
 
+// TestSelectStmtAllowFiltering tests constructing select CQL query on
+// columns which are not part of the primary key
+func (suite *CassandraConnSuite) TestSelectStmtAllowFiltering() {
+	stmt, err := SelectStmt(
+		Table("table1"),
+		Columns([]string{"c1", "c2"}),
+		Conditions([]string{"c3"}),
+		Limit(10),
+		AllowFiltering(true),
+	)
+	suite.NoError(err)
+	suite.Equal(
+		"SELECT \"c1\", \"c2\" FROM \"table1\" WHERE c3=?"+
+			" LIMIT 10 ALLOW FILTERING;", stmt)
+
+	stmt, err = SelectStmt(
+		Table("table1"),
+		Columns([]string{"c1"}),
+		Conditions([]string{"c3"}),
+		Limit(0),
+		AllowFiltering(false),
+	)
+	suite.NoError(err)
+	suite.Equal("SELECT \"c1\" FROM \"table1\" WHERE c3=?;", stmt)
+}
+
 // TestDeleteStmt tests constructing delete CQL query
 func (suite *CassandraConnSuite) TestDeleteStmt() {
 
@@ -150,6 +176,15 @@ func (suite *CassandraConnSuite) TestDeleteStmt() {
 		suite.NoError(err)
 		suite.Equal(stmt, d.stmt)
 	}
+
+	// CAS delete
+	stmt, err := DeleteStmt(
+		Table("table1"),
+		Conditions([]string{"c3", "c4"}),
+		IfExist(true),
+	)
+	suite.NoError(err)
+	suite.Equal("DELETE FROM \"table1\" WHERE c3=? AND c4=? IF EXISTS;", stmt)
 }
 
 // TestUpdateStmt tests constructing the update statement
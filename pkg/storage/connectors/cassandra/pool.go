@@ -0,0 +1,234 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandra
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	"github.com/gocql/gocql"
+	"github.com/uber-go/tally"
+)
+
+// backendHostTag is the tag of the metrics of a backend host
+const backendHostTag = "backend_host"
+
+// backendHost is the queue and the statistics of the queries sent to one
+// Cassandra node.
+type backendHost struct {
+	inFlight     int64
+	queries      uint64
+	errors       uint64
+	rejected     uint64
+	totalLatency int64
+
+	latency       tally.Timer
+	queryCount    tally.Counter
+	errorCount    tally.Counter
+	rejectedCount tally.Counter
+	inFlightGauge tally.Gauge
+}
+
+// hostPool tracks the queries sent to every Cassandra node, and bounds
+// the number of queries in flight to a node with the max queue depth,
+// so that an overloaded node is skipped instead of slowing down every
+// query routed to it.
+type hostPool struct {
+	sync.RWMutex
+
+	scope         tally.Scope
+	maxQueueDepth int64
+	hosts         map[string]*backendHost
+}
+
+func newHostPool(scope tally.Scope, maxQueueDepth int) *hostPool {
+	return &hostPool{
+		scope:         scope,
+		maxQueueDepth: int64(maxQueueDepth),
+		hosts:         make(map[string]*backendHost),
+	}
+}
+
+// host returns the backend host of an address, creating it if needed.
+func (p *hostPool) host(addr string) *backendHost {
+	p.RLock()
+	h, ok := p.hosts[addr]
+	p.RUnlock()
+	if ok {
+		return h
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if h, ok := p.hosts[addr]; ok {
+		return h
+	}
+	scope := p.scope.Tagged(map[string]string{backendHostTag: addr})
+	h = &backendHost{
+		latency:       scope.Timer("backend_latency"),
+		queryCount:    scope.Counter("backend_queries"),
+		errorCount:    scope.Counter("backend_errors"),
+		rejectedCount: scope.Counter("backend_rejected"),
+		inFlightGauge: scope.Gauge("backend_in_flight"),
+	}
+	p.hosts[addr] = h
+	return h
+}
+
+// setMaxQueueDepth changes the max number of queries in flight to a
+// host, no limit if zero.
+func (p *hostPool) setMaxQueueDepth(maxQueueDepth int) {
+	atomic.StoreInt64(&p.maxQueueDepth, int64(maxQueueDepth))
+}
+
+// getMaxQueueDepth returns the max number of queries in flight to a host.
+func (p *hostPool) getMaxQueueDepth() int {
+	return int(atomic.LoadInt64(&p.maxQueueDepth))
+}
+
+// acquire adds a query in flight to a host, and returns false if the
+// queue of the host is full.
+func (p *hostPool) acquire(addr string) bool {
+	h := p.host(addr)
+	n := atomic.AddInt64(&h.inFlight, 1)
+	if max := atomic.LoadInt64(&p.maxQueueDepth); max > 0 && n > max {
+		atomic.AddInt64(&h.inFlight, -1)
+		atomic.AddUint64(&h.rejected, 1)
+		h.rejectedCount.Inc(1)
+		return false
+	}
+	h.inFlightGauge.Update(float64(n))
+	return true
+}
+
+// release removes a query in flight to a host.
+func (p *hostPool) release(addr string) {
+	h := p.host(addr)
+	h.inFlightGauge.Update(float64(atomic.AddInt64(&h.inFlight, -1)))
+}
+
+// observe records the latency and the result of a query sent to a host.
+func (p *hostPool) observe(addr string, latency time.Duration, err error) {
+	h := p.host(addr)
+	atomic.AddUint64(&h.queries, 1)
+	atomic.AddInt64(&h.totalLatency, int64(latency))
+	h.queryCount.Inc(1)
+	h.latency.Record(latency)
+	if err != nil {
+		atomic.AddUint64(&h.errors, 1)
+		h.errorCount.Inc(1)
+	}
+}
+
+// stats returns the statistics of the hosts, sorted by address.
+func (p *hostPool) stats() []*orm.BackendHostStats {
+	p.RLock()
+	defer p.RUnlock()
+
+	result := make([]*orm.BackendHostStats, 0, len(p.hosts))
+	for addr, h := range p.hosts {
+		s := &orm.BackendHostStats{
+			Host:     addr,
+			InFlight: atomic.LoadInt64(&h.inFlight),
+			Queries:  atomic.LoadUint64(&h.queries),
+			Errors:   atomic.LoadUint64(&h.errors),
+			Rejected: atomic.LoadUint64(&h.rejected),
+		}
+		if s.Queries > 0 {
+			s.AverageLatency = time.Duration(
+				atomic.LoadInt64(&h.totalLatency) / int64(s.Queries))
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Host < result[j].Host
+	})
+	return result
+}
+
+// ObserveQuery implements gocql.QueryObserver, recording the latency and
+// the result of every query by host.
+func (p *hostPool) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	if q.Host == nil {
+		return
+	}
+	p.observe(hostAddress(q.Host), q.End.Sub(q.Start), q.Err)
+}
+
+// hostAddress returns the address identifying a host in the metrics.
+func hostAddress(host *gocql.HostInfo) string {
+	return host.ConnectAddress().String()
+}
+
+// queueingPolicy is a host selection policy skipping the hosts whose
+// queue is full.
+type queueingPolicy struct {
+	gocql.HostSelectionPolicy
+
+	pool *hostPool
+}
+
+// Pick returns the hosts of the wrapped policy whose queue is not full.
+// A host is in the queue from the time it is picked until the query is
+// done, or until the next host is picked for a retry.
+func (q *queueingPolicy) Pick(qry gocql.ExecutableQuery) gocql.NextHost {
+	next := q.HostSelectionPolicy.Pick(qry)
+	var picked *queuedHost
+	return func() gocql.SelectedHost {
+		if picked != nil {
+			picked.release()
+			picked = nil
+		}
+		for {
+			h := next()
+			if h == nil || h.Info() == nil {
+				return h
+			}
+			addr := hostAddress(h.Info())
+			if !q.pool.acquire(addr) {
+				continue
+			}
+			picked = &queuedHost{SelectedHost: h, pool: q.pool, addr: addr}
+			return picked
+		}
+	}
+}
+
+// queuedHost is a host picked for a query, which leaves the queue of the
+// host once the query is done.
+type queuedHost struct {
+	gocql.SelectedHost
+
+	pool *hostPool
+	addr string
+	once sync.Once
+}
+
+// Mark implements gocql.SelectedHost.
+func (h *queuedHost) Mark(err error) {
+	h.release()
+	h.SelectedHost.Mark(err)
+}
+
+func (h *queuedHost) release() {
+	h.once.Do(func() {
+		h.pool.release(h.addr)
+	})
+}
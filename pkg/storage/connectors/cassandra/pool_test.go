@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	"github.com/uber-go/tally"
+)
+
+// TestHostPool tests tracking the queries of the backend hosts
+func (suite *CassandraConnSuite) TestHostPool() {
+	scope := tally.NewTestScope("", nil)
+	p := newHostPool(scope, 2)
+
+	suite.True(p.acquire("10.0.0.1"))
+	suite.True(p.acquire("10.0.0.1"))
+	// the queue of the host is full
+	suite.False(p.acquire("10.0.0.1"))
+	suite.True(p.acquire("10.0.0.2"))
+
+	p.observe("10.0.0.1", 10*time.Millisecond, nil)
+	p.observe("10.0.0.1", 30*time.Millisecond, errors.New("timeout"))
+	p.release("10.0.0.1")
+
+	stats := p.stats()
+	suite.Len(stats, 2)
+	suite.Equal(orm.BackendHostStats{
+		Host:           "10.0.0.1",
+		InFlight:       1,
+		Queries:        2,
+		Errors:         1,
+		Rejected:       1,
+		AverageLatency: 20 * time.Millisecond,
+	}, *stats[0])
+	suite.Equal("10.0.0.2", stats[1].Host)
+	suite.Equal(int64(1), stats[1].InFlight)
+	suite.Zero(stats[1].AverageLatency)
+
+	snapshot := scope.Snapshot()
+	tags := "+backend_host=10.0.0.1"
+	suite.Equal(int64(2), snapshot.Counters()["backend_queries"+tags].Value())
+	suite.Equal(int64(1), snapshot.Counters()["backend_errors"+tags].Value())
+	suite.Equal(int64(1), snapshot.Counters()["backend_rejected"+tags].Value())
+	suite.Equal(float64(1), snapshot.Gauges()["backend_in_flight"+tags].Value())
+
+	// no limit once the max queue depth is zero
+	p.setMaxQueueDepth(0)
+	suite.Equal(0, p.getMaxQueueDepth())
+	for i := 0; i < 10; i++ {
+		suite.True(p.acquire("10.0.0.1"))
+	}
+}
+
+// TestResizePool tests resizing the connection pool of the connector
+func (suite *CassandraConnSuite) TestResizePool() {
+	status := connector.PoolStatus()
+	suite.True(status.MaxConnsPerHost > 0)
+	size := status.PoolSize
+
+	obj := &base.Definition{
+		Name: testTableName1,
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+		},
+		ColumnToType: map[string]reflect.Type{
+			"id":   reflect.TypeOf(1),
+			"data": reflect.TypeOf("data"),
+			"name": reflect.TypeOf("name"),
+		},
+	}
+	suite.NoError(connector.Create(context.Background(), obj, testRow))
+
+	suite.NoError(connector.ResizePool(orm.PoolSize{
+		MaxConnsPerHost: size.MaxConnsPerHost + 1,
+		MaxQueueDepth:   100,
+	}))
+	status = connector.PoolStatus()
+	suite.Equal(size.MaxConnsPerHost+1, status.MaxConnsPerHost)
+	suite.Equal(100, status.MaxQueueDepth)
+
+	// the queries go through the new session
+	_, err := connector.Get(context.Background(), obj, keyRow)
+	suite.NoError(err)
+	status = connector.PoolStatus()
+	suite.NotEmpty(status.Hosts)
+	for _, h := range status.Hosts {
+		suite.True(h.Queries > 0, fmt.Sprintf("host %s", h.Host))
+		suite.Zero(h.InFlight)
+	}
+
+	suite.Error(connector.ResizePool(orm.PoolSize{}))
+	suite.NoError(connector.ResizePool(size))
+	suite.NoError(connector.Delete(context.Background(), obj, keyRow))
+}
@@ -102,12 +102,14 @@ type OrmJobMetrics struct {
 	JobNameToIDGetAllFail tally.Counter
 
 	// job_config
-	JobConfigCreate     tally.Counter
-	JobConfigCreateFail tally.Counter
-	JobConfigGet        tally.Counter
-	JobConfigGetFail    tally.Counter
-	JobConfigDelete     tally.Counter
-	JobConfigDeleteFail tally.Counter
+	JobConfigCreate        tally.Counter
+	JobConfigCreateFail    tally.Counter
+	JobConfigGet           tally.Counter
+	JobConfigGetFail       tally.Counter
+	JobConfigDelete        tally.Counter
+	JobConfigDeleteFail    tally.Counter
+	JobConfigDeleteAll     tally.Counter
+	JobConfigDeleteAllFail tally.Counter
 
 	// secret_info
 	SecretInfoCreate     tally.Counter
@@ -307,6 +309,90 @@ type OrmTaskMetrics struct {
 	PodEventsGetFail tally.Counter
 }
 
+// OrmHostMetrics tracks counters for host related tables accessed through
+// ORM layer
+type OrmHostMetrics struct {
+	// host_reservations
+	HostReservationCreate     tally.Counter
+	HostReservationCreateFail tally.Counter
+	HostReservationGet        tally.Counter
+	HostReservationGetFail    tally.Counter
+	HostReservationGetAll     tally.Counter
+	HostReservationGetAllFail tally.Counter
+	HostReservationDelete     tally.Counter
+	HostReservationDeleteFail tally.Counter
+
+	// host_tasks
+	HostTasksUpdate     tally.Counter
+	HostTasksUpdateFail tally.Counter
+	HostTasksGet        tally.Counter
+	HostTasksGetFail    tally.Counter
+	HostTasksDelete     tally.Counter
+	HostTasksDeleteFail tally.Counter
+
+	// host_cordons
+	HostCordonCreate     tally.Counter
+	HostCordonCreateFail tally.Counter
+	HostCordonGet        tally.Counter
+	HostCordonGetFail    tally.Counter
+	HostCordonDelete     tally.Counter
+	HostCordonDeleteFail tally.Counter
+
+	// maintenance_approvals
+	MaintenanceApprovalCreate     tally.Counter
+	MaintenanceApprovalCreateFail tally.Counter
+	MaintenanceApprovalGet        tally.Counter
+	MaintenanceApprovalGetFail    tally.Counter
+	MaintenanceApprovalDelete     tally.Counter
+	MaintenanceApprovalDeleteFail tally.Counter
+
+	// host_assignments
+	HostAssignmentCreate     tally.Counter
+	HostAssignmentCreateFail tally.Counter
+	HostAssignmentGet        tally.Counter
+	HostAssignmentGetFail    tally.Counter
+	HostAssignmentDelete     tally.Counter
+	HostAssignmentDeleteFail tally.Counter
+
+	// host_maintenance_events
+	HostMaintenanceEventCreate     tally.Counter
+	HostMaintenanceEventCreateFail tally.Counter
+	HostMaintenanceEventGetAll     tally.Counter
+	HostMaintenanceEventGetAllFail tally.Counter
+	HostMaintenanceEventDelete     tally.Counter
+	HostMaintenanceEventDeleteFail tally.Counter
+
+	// host_maintenance_history
+	HostMaintenanceHistoryCreate     tally.Counter
+	HostMaintenanceHistoryCreateFail tally.Counter
+	HostMaintenanceHistoryGetAll     tally.Counter
+	HostMaintenanceHistoryGetAllFail tally.Counter
+	HostMaintenanceHistoryDelete     tally.Counter
+	HostMaintenanceHistoryDeleteFail tally.Counter
+
+	// host_events
+	HostEventCreate     tally.Counter
+	HostEventCreateFail tally.Counter
+	HostEventGetAll     tally.Counter
+	HostEventGetAllFail tally.Counter
+
+	// idempotency_keys
+	IdempotencyKeyCreate     tally.Counter
+	IdempotencyKeyCreateFail tally.Counter
+	IdempotencyKeyGet        tally.Counter
+	IdempotencyKeyGetFail    tally.Counter
+
+	// maintenance_policies
+	MaintenancePolicyCreate     tally.Counter
+	MaintenancePolicyCreateFail tally.Counter
+	MaintenancePolicyGet        tally.Counter
+	MaintenancePolicyGetFail    tally.Counter
+	MaintenancePolicyGetAll     tally.Counter
+	MaintenancePolicyGetAllFail tally.Counter
+	MaintenancePolicyDelete     tally.Counter
+	MaintenancePolicyDeleteFail tally.Counter
+}
+
 // Metrics is a struct for tracking all the general purpose counters that have relevance to the storage
 // layer, i.e. how many jobs and tasks were created/deleted in the storage layer
 type Metrics struct {
@@ -321,6 +407,7 @@ type Metrics struct {
 	WorkflowMetrics       *WorkflowMetrics
 	OrmJobMetrics         *OrmJobMetrics
 	OrmTaskMetrics        *OrmTaskMetrics
+	OrmHostMetrics        *OrmHostMetrics
 }
 
 // NewMetrics returns a new Metrics struct, with all metrics initialized and rooted at the given tally.Scope
@@ -603,6 +690,66 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	secretInfoFailScope := secretInfoScope.Tagged(
 		map[string]string{"result": "fail"})
 
+	hostReservationScope := ormScope.SubScope("host_reservations")
+	hostReservationSuccessScope := hostReservationScope.Tagged(
+		map[string]string{"result": "success"})
+	hostReservationFailScope := hostReservationScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostTasksScope := ormScope.SubScope("host_tasks")
+	hostTasksSuccessScope := hostTasksScope.Tagged(
+		map[string]string{"result": "success"})
+	hostTasksFailScope := hostTasksScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostCordonScope := ormScope.SubScope("host_cordons")
+	hostCordonSuccessScope := hostCordonScope.Tagged(
+		map[string]string{"result": "success"})
+	hostCordonFailScope := hostCordonScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	maintenanceApprovalScope := ormScope.SubScope("maintenance_approvals")
+	maintenanceApprovalSuccessScope := maintenanceApprovalScope.Tagged(
+		map[string]string{"result": "success"})
+	maintenanceApprovalFailScope := maintenanceApprovalScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostAssignmentScope := ormScope.SubScope("host_assignments")
+	hostAssignmentSuccessScope := hostAssignmentScope.Tagged(
+		map[string]string{"result": "success"})
+	hostAssignmentFailScope := hostAssignmentScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostMaintenanceEventScope := ormScope.SubScope("host_maintenance_events")
+	hostMaintenanceEventSuccessScope := hostMaintenanceEventScope.Tagged(
+		map[string]string{"result": "success"})
+	hostMaintenanceEventFailScope := hostMaintenanceEventScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostMaintenanceHistoryScope := ormScope.SubScope("host_maintenance_history")
+	hostMaintenanceHistorySuccessScope := hostMaintenanceHistoryScope.Tagged(
+		map[string]string{"result": "success"})
+	hostMaintenanceHistoryFailScope := hostMaintenanceHistoryScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	hostEventScope := ormScope.SubScope("host_events")
+	hostEventSuccessScope := hostEventScope.Tagged(
+		map[string]string{"result": "success"})
+	hostEventFailScope := hostEventScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	idempotencyKeyScope := ormScope.SubScope("idempotency_keys")
+	idempotencyKeySuccessScope := idempotencyKeyScope.Tagged(
+		map[string]string{"result": "success"})
+	idempotencyKeyFailScope := idempotencyKeyScope.Tagged(
+		map[string]string{"result": "fail"})
+
+	maintenancePolicyScope := ormScope.SubScope("maintenance_policies")
+	maintenancePolicySuccessScope := maintenancePolicyScope.Tagged(
+		map[string]string{"result": "success"})
+	maintenancePolicyFailScope := maintenancePolicyScope.Tagged(
+		map[string]string{"result": "fail"})
+
 	ormJobMetrics := &OrmJobMetrics{
 		JobIndexCreate:     jobIndexSuccessScope.Counter("create"),
 		JobIndexCreateFail: jobIndexFailScope.Counter("create"),
@@ -618,12 +765,14 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		JobNameToIDGetAll:     jobNameToIDSuccessScope.Counter("get_all"),
 		JobNameToIDGetAllFail: jobNameToIDFailScope.Counter("get_all"),
 
-		JobConfigCreate:     jobConfigSuccessScope.Counter("create"),
-		JobConfigCreateFail: jobConfigFailScope.Counter("create"),
-		JobConfigGet:        jobConfigSuccessScope.Counter("get"),
-		JobConfigGetFail:    jobConfigFailScope.Counter("get"),
-		JobConfigDelete:     jobConfigSuccessScope.Counter("delete"),
-		JobConfigDeleteFail: jobConfigFailScope.Counter("delete"),
+		JobConfigCreate:        jobConfigSuccessScope.Counter("create"),
+		JobConfigCreateFail:    jobConfigFailScope.Counter("create"),
+		JobConfigGet:           jobConfigSuccessScope.Counter("get"),
+		JobConfigGetFail:       jobConfigFailScope.Counter("get"),
+		JobConfigDelete:        jobConfigSuccessScope.Counter("delete"),
+		JobConfigDeleteFail:    jobConfigFailScope.Counter("delete"),
+		JobConfigDeleteAll:     jobConfigSuccessScope.Counter("delete_all"),
+		JobConfigDeleteAllFail: jobConfigFailScope.Counter("delete_all"),
 
 		SecretInfoCreate:     secretInfoSuccessScope.Counter("create"),
 		SecretInfoCreateFail: secretInfoFailScope.Counter("create"),
@@ -642,6 +791,78 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		PodEventsGetFail: podEventsFailScope.Counter("get"),
 	}
 
+	ormHostMetrics := &OrmHostMetrics{
+		HostReservationCreate:     hostReservationSuccessScope.Counter("create"),
+		HostReservationCreateFail: hostReservationFailScope.Counter("create"),
+		HostReservationGet:        hostReservationSuccessScope.Counter("get"),
+		HostReservationGetFail:    hostReservationFailScope.Counter("get"),
+		HostReservationGetAll:     hostReservationSuccessScope.Counter("get_all"),
+		HostReservationGetAllFail: hostReservationFailScope.Counter("get_all"),
+		HostReservationDelete:     hostReservationSuccessScope.Counter("delete"),
+		HostReservationDeleteFail: hostReservationFailScope.Counter("delete"),
+
+		HostTasksUpdate:     hostTasksSuccessScope.Counter("update"),
+		HostTasksUpdateFail: hostTasksFailScope.Counter("update"),
+		HostTasksGet:        hostTasksSuccessScope.Counter("get"),
+		HostTasksGetFail:    hostTasksFailScope.Counter("get"),
+		HostTasksDelete:     hostTasksSuccessScope.Counter("delete"),
+		HostTasksDeleteFail: hostTasksFailScope.Counter("delete"),
+
+		HostCordonCreate:     hostCordonSuccessScope.Counter("create"),
+		HostCordonCreateFail: hostCordonFailScope.Counter("create"),
+		HostCordonGet:        hostCordonSuccessScope.Counter("get"),
+		HostCordonGetFail:    hostCordonFailScope.Counter("get"),
+		HostCordonDelete:     hostCordonSuccessScope.Counter("delete"),
+		HostCordonDeleteFail: hostCordonFailScope.Counter("delete"),
+
+		MaintenanceApprovalCreate:     maintenanceApprovalSuccessScope.Counter("create"),
+		MaintenanceApprovalCreateFail: maintenanceApprovalFailScope.Counter("create"),
+		MaintenanceApprovalGet:        maintenanceApprovalSuccessScope.Counter("get"),
+		MaintenanceApprovalGetFail:    maintenanceApprovalFailScope.Counter("get"),
+		MaintenanceApprovalDelete:     maintenanceApprovalSuccessScope.Counter("delete"),
+		MaintenanceApprovalDeleteFail: maintenanceApprovalFailScope.Counter("delete"),
+
+		HostAssignmentCreate:     hostAssignmentSuccessScope.Counter("create"),
+		HostAssignmentCreateFail: hostAssignmentFailScope.Counter("create"),
+		HostAssignmentGet:        hostAssignmentSuccessScope.Counter("get"),
+		HostAssignmentGetFail:    hostAssignmentFailScope.Counter("get"),
+		HostAssignmentDelete:     hostAssignmentSuccessScope.Counter("delete"),
+		HostAssignmentDeleteFail: hostAssignmentFailScope.Counter("delete"),
+
+		HostMaintenanceEventCreate:     hostMaintenanceEventSuccessScope.Counter("create"),
+		HostMaintenanceEventCreateFail: hostMaintenanceEventFailScope.Counter("create"),
+		HostMaintenanceEventGetAll:     hostMaintenanceEventSuccessScope.Counter("get_all"),
+		HostMaintenanceEventGetAllFail: hostMaintenanceEventFailScope.Counter("get_all"),
+		HostMaintenanceEventDelete:     hostMaintenanceEventSuccessScope.Counter("delete"),
+		HostMaintenanceEventDeleteFail: hostMaintenanceEventFailScope.Counter("delete"),
+
+		HostMaintenanceHistoryCreate:     hostMaintenanceHistorySuccessScope.Counter("create"),
+		HostMaintenanceHistoryCreateFail: hostMaintenanceHistoryFailScope.Counter("create"),
+		HostMaintenanceHistoryGetAll:     hostMaintenanceHistorySuccessScope.Counter("get_all"),
+		HostMaintenanceHistoryGetAllFail: hostMaintenanceHistoryFailScope.Counter("get_all"),
+		HostMaintenanceHistoryDelete:     hostMaintenanceHistorySuccessScope.Counter("delete"),
+		HostMaintenanceHistoryDeleteFail: hostMaintenanceHistoryFailScope.Counter("delete"),
+
+		HostEventCreate:     hostEventSuccessScope.Counter("create"),
+		HostEventCreateFail: hostEventFailScope.Counter("create"),
+		HostEventGetAll:     hostEventSuccessScope.Counter("get_all"),
+		HostEventGetAllFail: hostEventFailScope.Counter("get_all"),
+
+		IdempotencyKeyCreate:     idempotencyKeySuccessScope.Counter("create"),
+		IdempotencyKeyCreateFail: idempotencyKeyFailScope.Counter("create"),
+		IdempotencyKeyGet:        idempotencyKeySuccessScope.Counter("get"),
+		IdempotencyKeyGetFail:    idempotencyKeyFailScope.Counter("get"),
+
+		MaintenancePolicyCreate:     maintenancePolicySuccessScope.Counter("create"),
+		MaintenancePolicyCreateFail: maintenancePolicyFailScope.Counter("create"),
+		MaintenancePolicyGet:        maintenancePolicySuccessScope.Counter("get"),
+		MaintenancePolicyGetFail:    maintenancePolicyFailScope.Counter("get"),
+		MaintenancePolicyGetAll:     maintenancePolicySuccessScope.Counter("get_all"),
+		MaintenancePolicyGetAllFail: maintenancePolicyFailScope.Counter("get_all"),
+		MaintenancePolicyDelete:     maintenancePolicySuccessScope.Counter("delete"),
+		MaintenancePolicyDeleteFail: maintenancePolicyFailScope.Counter("delete"),
+	}
+
 	metrics := &Metrics{
 		JobMetrics:            jobMetrics,
 		TaskMetrics:           taskMetrics,
@@ -654,6 +875,7 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		WorkflowMetrics:       workflowMetrics,
 		OrmJobMetrics:         ormJobMetrics,
 		OrmTaskMetrics:        ormTaskMetrics,
+		OrmHostMetrics:        ormHostMetrics,
 	}
 
 	return metrics
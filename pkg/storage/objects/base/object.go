@@ -15,6 +15,7 @@
 package base
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -26,6 +27,11 @@ type Definition struct {
 	Key *PrimaryKey
 	// Column name to data type mapping of the object
 	ColumnToType map[string]reflect.Type
+	// Columns whose combined values must be unique across all rows of the
+	// object. Connectors enforce the constraint on create and update, the
+	// Cassandra connector with lightweight transactions claiming the values,
+	// SQL connectors with a unique index on the columns.
+	UniqueKeys []string
 }
 
 // Column holds a column name and value for one row.
@@ -52,6 +58,52 @@ type PrimaryKey struct {
 	ClusteringKeys []*ClusteringKey
 }
 
+// Columns returns the names of all primary key columns in key order, i.e.
+// partition keys followed by clustering keys.
+func (k *PrimaryKey) Columns() []string {
+	columns := make(
+		[]string, 0, len(k.PartitionKeys)+len(k.ClusteringKeys))
+	columns = append(columns, k.PartitionKeys...)
+	for _, ck := range k.ClusteringKeys {
+		columns = append(columns, ck.Name)
+	}
+	return columns
+}
+
+// Validate checks that the object has a primary key with at least one
+// partition key, and that every partition and clustering key refers to a
+// distinct column of the object. The same is checked for unique keys.
+func (o *Definition) Validate() error {
+	if o.Key == nil || len(o.Key.PartitionKeys) == 0 {
+		return fmt.Errorf("object %s has no partition key", o.Name)
+	}
+	seen := make(map[string]struct{})
+	for _, col := range o.Key.Columns() {
+		if _, ok := o.ColumnToType[col]; !ok {
+			return fmt.Errorf(
+				"key %s of object %s is not a column", col, o.Name)
+		}
+		if _, ok := seen[col]; ok {
+			return fmt.Errorf(
+				"key %s of object %s is repeated", col, o.Name)
+		}
+		seen[col] = struct{}{}
+	}
+	seen = make(map[string]struct{})
+	for _, col := range o.UniqueKeys {
+		if _, ok := o.ColumnToType[col]; !ok {
+			return fmt.Errorf(
+				"unique key %s of object %s is not a column", col, o.Name)
+		}
+		if _, ok := seen[col]; ok {
+			return fmt.Errorf(
+				"unique key %s of object %s is repeated", col, o.Name)
+		}
+		seen[col] = struct{}{}
+	}
+	return nil
+}
+
 // GetColumnsToRead returns a list of column names to be read for this object
 // in a select operation
 func (o *Definition) GetColumnsToRead() []string {
@@ -79,8 +131,28 @@ func (o *Definition) GetColumnsToRead() []string {
 // table name of that object. The partition key is `id` and clustering key is
 // `name` while table name is `valid_object`.
 //
+// Objects which need to spread rows over more than one column use a composite
+// partition key, e.g. `primaryKey=((job_id, day), event_time)`. All partition
+// key columns must be set to read a row or a partition; use
+// orm.SetPrimaryKey and orm.SetPartitionKey to set them in key order.
+//
+// Timestamp columns of type time.Time can be maintained by the ORM by adding
+// an `autotime` annotation to the column tag:
+//	CreationTime time.Time `column:"name=creation_time, autotime=create"`
+//	UpdateTime   time.Time `column:"name=update_time, autotime=update"`
+// A create time is set when the object is created, unless the caller already
+// set it. An update time is set every time the object is created or updated.
+//
+// Objects can declare that the combined values of some columns must be unique
+// across all rows, which connectors enforce on create and update by failing
+// with AlreadyExists. For example
+// `primaryKey=((id), version), unique=(id, name)` allows one row per id and
+// name, whatever the version. A SQL connector maps the constraint to
+// `CREATE UNIQUE INDEX ... ON <table> (id, name)`, which unlike the primary
+// key can span the clustering columns.
+//
 // The `cassandra` keyword denotes that this annotation is for Cassandra
 // connector. The only primary key format supported right now is:
-// ((PK1,PK2..), CK1, CK2..)
+// ((PK1,PK2..), CK1, CK2..) and the unique key format is (C1, C2..)
 type Object interface {
 }
@@ -0,0 +1,154 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// init adds a HostAssignmentObject instance to the global list of storage
+// objects
+func init() {
+	Objs = append(Objs, &HostAssignmentObject{})
+}
+
+// HostAssignmentObject corresponds to a row in host_assignments table.
+type HostAssignmentObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_assignments, primaryKey=((hostname))"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Marshaled HostAssignment of the host
+	Assignment []byte `column:"name=assignment"`
+	// Time at which the assignment was last updated
+	UpdateTime time.Time `column:"name=update_time"`
+}
+
+// HostAssignmentOps provides methods for manipulating host_assignments
+// table.
+type HostAssignmentOps interface {
+	// Create upserts the assignment of a host.
+	Create(
+		ctx context.Context,
+		assignment *hpb.HostAssignment,
+	) error
+
+	// Get retrieves the assignment of a host, nil if the host has none.
+	Get(
+		ctx context.Context,
+		hostname string,
+	) (*hpb.HostAssignment, error)
+
+	// Delete removes the assignment of a host.
+	Delete(
+		ctx context.Context,
+		hostname string,
+	) error
+}
+
+// ensure that default implementation (hostAssignmentOps) satisfies the
+// interface
+var _ HostAssignmentOps = (*hostAssignmentOps)(nil)
+
+// hostAssignmentOps implements HostAssignmentOps using a particular Store
+type hostAssignmentOps struct {
+	store *Store
+	// typed store of the table
+	table *HostAssignmentStore
+}
+
+// NewHostAssignmentOps constructs a HostAssignmentOps object for provided
+// Store.
+func NewHostAssignmentOps(s *Store) HostAssignmentOps {
+	return &hostAssignmentOps{
+		store: s,
+		table: NewHostAssignmentStore(s.oClient),
+	}
+}
+
+// Create upserts a HostAssignmentObject in db
+func (d *hostAssignmentOps) Create(
+	ctx context.Context,
+	assignment *hpb.HostAssignment,
+) error {
+	buffer, err := proto.Marshal(assignment)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostAssignmentCreateFail.Inc(1)
+		return errors.Wrap(err, "Failed to marshal host assignment")
+	}
+
+	obj := &HostAssignmentObject{
+		Hostname:   assignment.GetHostname(),
+		Assignment: buffer,
+		UpdateTime: time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostAssignmentCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostAssignmentCreate.Inc(1)
+	return nil
+}
+
+// Get gets the HostAssignment of a HostAssignmentObject from db
+func (d *hostAssignmentOps) Get(
+	ctx context.Context,
+	hostname string,
+) (*hpb.HostAssignment, error) {
+	// Read the partition of the host, so that a host
+	// without assignment is not reported as an error.
+	objs, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostAssignmentGetFail.Inc(1)
+		return nil, err
+	}
+
+	for _, obj := range objs {
+		assignment := &hpb.HostAssignment{}
+		if err := proto.Unmarshal(obj.Assignment, assignment); err != nil {
+			d.store.metrics.OrmHostMetrics.HostAssignmentGetFail.Inc(1)
+			return nil, errors.Wrap(err, "Failed to unmarshal host assignment")
+		}
+		d.store.metrics.OrmHostMetrics.HostAssignmentGet.Inc(1)
+		return assignment, nil
+	}
+
+	d.store.metrics.OrmHostMetrics.HostAssignmentGet.Inc(1)
+	return nil, nil
+}
+
+// Delete deletes a HostAssignmentObject from db
+func (d *hostAssignmentOps) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	if err := d.table.Delete(ctx, hostname); err != nil {
+		d.store.metrics.OrmHostMetrics.HostAssignmentDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostAssignmentDelete.Inc(1)
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostAssignmentObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostAssignmentObjectTestSuite) SetupTest() {
+}
+
+func TestHostAssignmentObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostAssignmentObjectTestSuite))
+}
+
+// TestHostAssignmentOps tests HostAssignmentObject CRUD operations.
+func (s *HostAssignmentObjectTestSuite) TestHostAssignmentOps() {
+	db := NewHostAssignmentOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+
+	assignment, err := db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Nil(assignment)
+
+	expected := &hpb.HostAssignment{
+		Hostname: hostname,
+		Pool:     "batch",
+		Labels: []*peloton.Label{
+			{Key: "rack", Value: "r1"},
+		},
+	}
+	s.NoError(db.Create(ctx, expected))
+	assignment, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(expected, assignment)
+
+	expected = &hpb.HostAssignment{
+		Hostname: hostname,
+		Pool:     "stateless",
+	}
+	s.NoError(db.Create(ctx, expected))
+	assignment, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(expected, assignment)
+
+	s.NoError(db.Delete(ctx, hostname))
+	assignment, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Nil(assignment)
+}
+
+// TestHostAssignmentOpsClientFail tests failure cases due to ORM Client
+// errors
+func (s *HostAssignmentObjectTestSuite) TestHostAssignmentOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostAssignmentOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, &hpb.HostAssignment{Hostname: "hostname"})
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname")
+	s.EqualError(err, "delete failed")
+}
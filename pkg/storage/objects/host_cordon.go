@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// init adds a HostCordonObject instance to the global list of storage objects
+func init() {
+	Objs = append(Objs, &HostCordonObject{})
+}
+
+// HostCordonObject corresponds to a row in host_cordons table.
+type HostCordonObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_cordons, primaryKey=((hostname))"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Reason given by the operator for cordoning the host
+	Reason string `column:"name=reason"`
+	// Time at which the host was cordoned
+	CordonTime time.Time `column:"name=cordon_time"`
+}
+
+// HostCordonOps provides methods for manipulating host_cordons table.
+type HostCordonOps interface {
+	// Create upserts the cordon of a host.
+	Create(
+		ctx context.Context,
+		hostname string,
+		reason string,
+	) error
+
+	// Get retrieves the cordon reason of a host. The returned bool is
+	// false if the host is not cordoned.
+	Get(
+		ctx context.Context,
+		hostname string,
+	) (string, bool, error)
+
+	// Delete removes the cordon of a host.
+	Delete(
+		ctx context.Context,
+		hostname string,
+	) error
+}
+
+// ensure that default implementation (hostCordonOps) satisfies the interface
+var _ HostCordonOps = (*hostCordonOps)(nil)
+
+// hostCordonOps implements HostCordonOps using a particular Store
+type hostCordonOps struct {
+	store *Store
+	// typed store of the table
+	table *HostCordonStore
+}
+
+// NewHostCordonOps constructs a HostCordonOps object for provided Store.
+func NewHostCordonOps(s *Store) HostCordonOps {
+	return &hostCordonOps{
+		store: s,
+		table: NewHostCordonStore(s.oClient),
+	}
+}
+
+// Create upserts a HostCordonObject in db
+func (d *hostCordonOps) Create(
+	ctx context.Context,
+	hostname string,
+	reason string,
+) error {
+	obj := &HostCordonObject{
+		Hostname:   hostname,
+		Reason:     reason,
+		CordonTime: time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostCordonCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostCordonCreate.Inc(1)
+	return nil
+}
+
+// Get gets the reason of a HostCordonObject from db
+func (d *hostCordonOps) Get(
+	ctx context.Context,
+	hostname string,
+) (string, bool, error) {
+	// Read the partition of the host, so that a host
+	// which is not cordoned is not reported as an error.
+	objs, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostCordonGetFail.Inc(1)
+		return "", false, err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostCordonGet.Inc(1)
+	for _, obj := range objs {
+		return obj.Reason, true, nil
+	}
+	return "", false, nil
+}
+
+// Delete deletes a HostCordonObject from db
+func (d *hostCordonOps) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	if err := d.table.Delete(ctx, hostname); err != nil {
+		d.store.metrics.OrmHostMetrics.HostCordonDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostCordonDelete.Inc(1)
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostCordonObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostCordonObjectTestSuite) SetupTest() {
+}
+
+func TestHostCordonObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostCordonObjectTestSuite))
+}
+
+// TestHostCordonOps tests HostCordonObject CRUD operations.
+func (s *HostCordonObjectTestSuite) TestHostCordonOps() {
+	db := NewHostCordonOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+
+	_, found, err := db.Get(ctx, hostname)
+	s.NoError(err)
+	s.False(found)
+
+	s.NoError(db.Create(ctx, hostname, "bad disk"))
+	reason, found, err := db.Get(ctx, hostname)
+	s.NoError(err)
+	s.True(found)
+	s.Equal("bad disk", reason)
+
+	s.NoError(db.Create(ctx, hostname, "kernel upgrade"))
+	reason, found, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.True(found)
+	s.Equal("kernel upgrade", reason)
+
+	s.NoError(db.Delete(ctx, hostname))
+	_, found, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.False(found)
+}
+
+// TestHostCordonOpsClientFail tests failure cases due to ORM Client errors
+func (s *HostCordonObjectTestSuite) TestHostCordonOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostCordonOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, "hostname", "reason")
+	s.EqualError(err, "create failed")
+
+	_, _, err = db.Get(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname")
+	s.EqualError(err, "delete failed")
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// init adds a HostEventObject instance to the global list of storage objects
+func init() {
+	Objs = append(Objs, &HostEventObject{})
+}
+
+// HostEventObject corresponds to a row in host_events table.
+type HostEventObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_events, primaryKey=((hostname), event_time, event_type)"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Time of the event
+	EventTime time.Time `column:"name=event_time"`
+	// Kind of the event
+	EventType string `column:"name=event_type"`
+	// Details of the event
+	Message string `column:"name=message"`
+}
+
+// HostEventOps provides methods for manipulating host_events table.
+type HostEventOps interface {
+	// Create buffers an event of a host to be inserted in a batch with
+	// other events, since events are written at a high rate. It fails if
+	// too many events are buffered, see orm.Client.CreateAsync.
+	Create(
+		ctx context.Context,
+		hostname string,
+		eventTime time.Time,
+		eventType string,
+		message string,
+	) error
+
+	// GetAll retrieves the events of a host, oldest first.
+	GetAll(
+		ctx context.Context,
+		hostname string,
+	) ([]*HostEventObject, error)
+}
+
+// ensure that default implementation (hostEventOps) satisfies the interface
+var _ HostEventOps = (*hostEventOps)(nil)
+
+// hostEventOps implements HostEventOps using a particular Store
+type hostEventOps struct {
+	store *Store
+	// typed store of the table
+	table *HostEventStore
+}
+
+// NewHostEventOps constructs a HostEventOps object for provided Store.
+func NewHostEventOps(s *Store) HostEventOps {
+	return &hostEventOps{
+		store: s,
+		table: NewHostEventStore(s.oClient),
+	}
+}
+
+// Create buffers a HostEventObject to be created in db
+func (d *hostEventOps) Create(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+	eventType string,
+	message string,
+) error {
+	obj := &HostEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+		EventType: eventType,
+		Message:   message,
+	}
+	if err := d.table.CreateAsync(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostEventCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostEventCreate.Inc(1)
+	return nil
+}
+
+// GetAll gets all the HostEventObjects of a host from db
+func (d *hostEventOps) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostEventObject, error) {
+	events, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostEventGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostEventGetAll.Inc(1)
+	return events, nil
+}
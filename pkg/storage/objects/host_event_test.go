@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostEventObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostEventObjectTestSuite) SetupTest() {
+}
+
+func TestHostEventObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostEventObjectTestSuite))
+}
+
+// TestHostEventOps tests HostEventObject CRUD operations.
+func (s *HostEventObjectTestSuite) TestHostEventOps() {
+	db := NewHostEventOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+	drainTime := time.Now().UTC().Truncate(time.Millisecond)
+	downTime := drainTime.Add(time.Minute)
+
+	events, err := db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Empty(events)
+
+	s.NoError(db.Create(
+		ctx, hostname, downTime, "HOST_EVENT_TYPE_DOWNED", ""))
+	s.NoError(db.Create(
+		ctx, hostname, drainTime, "HOST_EVENT_TYPE_DRAIN_STARTED", ""))
+	// Events of the same time are kept apart by their type
+	s.NoError(db.Create(
+		ctx, hostname, drainTime, "HOST_EVENT_TYPE_OFFERS_WITHHELD", "3 offers"))
+
+	// Events are buffered until they are flushed
+	s.NoError(testStore.oClient.Flush(ctx))
+
+	events, err = db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Len(events, 3)
+	s.True(drainTime.Equal(events[0].EventTime))
+	s.Equal("HOST_EVENT_TYPE_DRAIN_STARTED", events[0].EventType)
+	s.True(drainTime.Equal(events[1].EventTime))
+	s.Equal("HOST_EVENT_TYPE_OFFERS_WITHHELD", events[1].EventType)
+	s.Equal("3 offers", events[1].Message)
+	s.True(downTime.Equal(events[2].EventTime))
+	s.Equal("HOST_EVENT_TYPE_DOWNED", events[2].EventType)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// init adds a HostMaintenanceEventObject instance to the global list of
+// storage objects
+func init() {
+	Objs = append(Objs, &HostMaintenanceEventObject{})
+}
+
+// HostMaintenanceEventObject corresponds to a row in
+// host_maintenance_events table.
+type HostMaintenanceEventObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_maintenance_events, primaryKey=((hostname), event_time)"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Time at which the host transitioned
+	EventTime time.Time `column:"name=event_time"`
+	// State of the host before the transition
+	FromState string `column:"name=from_state"`
+	// State of the host after the transition
+	ToState string `column:"name=to_state"`
+}
+
+// HostMaintenanceEventOps provides methods for manipulating
+// host_maintenance_events table.
+type HostMaintenanceEventOps interface {
+	// Create inserts a maintenance state transition of a host.
+	Create(
+		ctx context.Context,
+		hostname string,
+		eventTime time.Time,
+		fromState string,
+		toState string,
+	) error
+
+	// GetAll retrieves the maintenance state transitions of a host,
+	// oldest first.
+	GetAll(
+		ctx context.Context,
+		hostname string,
+	) ([]*HostMaintenanceEventObject, error)
+
+	// Delete removes a maintenance state transition of a host.
+	Delete(
+		ctx context.Context,
+		hostname string,
+		eventTime time.Time,
+	) error
+}
+
+// ensure that default implementation (hostMaintenanceEventOps) satisfies
+// the interface
+var _ HostMaintenanceEventOps = (*hostMaintenanceEventOps)(nil)
+
+// hostMaintenanceEventOps implements HostMaintenanceEventOps using a
+// particular Store
+type hostMaintenanceEventOps struct {
+	store *Store
+	// typed store of the table
+	table *HostMaintenanceEventStore
+}
+
+// NewHostMaintenanceEventOps constructs a HostMaintenanceEventOps object
+// for provided Store.
+func NewHostMaintenanceEventOps(s *Store) HostMaintenanceEventOps {
+	return &hostMaintenanceEventOps{
+		store: s,
+		table: NewHostMaintenanceEventStore(s.oClient),
+	}
+}
+
+// Create creates a HostMaintenanceEventObject in db
+func (d *hostMaintenanceEventOps) Create(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+	fromState string,
+	toState string,
+) error {
+	obj := &HostMaintenanceEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+		FromState: fromState,
+		ToState:   toState,
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceEventCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceEventCreate.Inc(1)
+	return nil
+}
+
+// GetAll gets all the HostMaintenanceEventObjects of a host from db
+func (d *hostMaintenanceEventOps) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostMaintenanceEventObject, error) {
+	events, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceEventGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceEventGetAll.Inc(1)
+	return events, nil
+}
+
+// Delete deletes a HostMaintenanceEventObject from db
+func (d *hostMaintenanceEventOps) Delete(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) error {
+	if err := d.table.Delete(ctx, hostname, eventTime); err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceEventDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceEventDelete.Inc(1)
+	return nil
+}
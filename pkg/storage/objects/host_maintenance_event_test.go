@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostMaintenanceEventObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostMaintenanceEventObjectTestSuite) SetupTest() {
+}
+
+func TestHostMaintenanceEventObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostMaintenanceEventObjectTestSuite))
+}
+
+// TestHostMaintenanceEventOps tests HostMaintenanceEventObject
+// CRUD operations.
+func (s *HostMaintenanceEventObjectTestSuite) TestHostMaintenanceEventOps() {
+	db := NewHostMaintenanceEventOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+	drainTime := time.Now().UTC().Truncate(time.Millisecond)
+	downTime := drainTime.Add(time.Minute)
+
+	events, err := db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Empty(events)
+
+	s.NoError(db.Create(
+		ctx, hostname, downTime, "HOST_STATE_DRAINING", "HOST_STATE_DOWN"))
+	s.NoError(db.Create(
+		ctx, hostname, drainTime, "HOST_STATE_UP", "HOST_STATE_DRAINING"))
+
+	events, err = db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Len(events, 2)
+	s.True(drainTime.Equal(events[0].EventTime))
+	s.Equal("HOST_STATE_UP", events[0].FromState)
+	s.Equal("HOST_STATE_DRAINING", events[0].ToState)
+	s.True(downTime.Equal(events[1].EventTime))
+	s.Equal("HOST_STATE_DOWN", events[1].ToState)
+
+	s.NoError(db.Delete(ctx, hostname, drainTime))
+	events, err = db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Len(events, 1)
+	s.True(downTime.Equal(events[0].EventTime))
+
+	s.NoError(db.Delete(ctx, hostname, downTime))
+	events, err = db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Empty(events)
+}
+
+// TestHostMaintenanceEventOpsClientFail tests failure cases due to ORM
+// Client errors
+func (s *HostMaintenanceEventObjectTestSuite) TestHostMaintenanceEventOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostMaintenanceEventOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+	now := time.Now()
+
+	err := db.Create(ctx, "hostname", now, "HOST_STATE_UP", "HOST_STATE_DRAINING")
+	s.EqualError(err, "create failed")
+
+	_, err = db.GetAll(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname", now)
+	s.EqualError(err, "delete failed")
+}
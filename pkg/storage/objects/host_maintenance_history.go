@@ -0,0 +1,142 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// init adds a HostMaintenanceHistoryObject instance to the global list of
+// storage objects
+func init() {
+	Objs = append(Objs, &HostMaintenanceHistoryObject{})
+}
+
+// HostMaintenanceHistoryObject corresponds to a row in
+// host_maintenance_history table.
+type HostMaintenanceHistoryObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_maintenance_history, primaryKey=((hostname), event_time)"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Time at which the host transitioned
+	EventTime time.Time `column:"name=event_time"`
+	// State of the host before the transition
+	FromState string `column:"name=from_state"`
+	// State of the host after the transition
+	ToState string `column:"name=to_state"`
+	// Time at which the transition was archived
+	ArchiveTime time.Time `column:"name=archive_time"`
+}
+
+// HostMaintenanceHistoryOps provides methods for manipulating
+// host_maintenance_history table.
+type HostMaintenanceHistoryOps interface {
+	// Create archives a maintenance state transition of a host.
+	Create(
+		ctx context.Context,
+		event *HostMaintenanceEventObject,
+	) error
+
+	// GetAll retrieves the archived maintenance state transitions of
+	// a host, oldest first.
+	GetAll(
+		ctx context.Context,
+		hostname string,
+	) ([]*HostMaintenanceHistoryObject, error)
+
+	// Delete removes an archived maintenance state transition of a host.
+	Delete(
+		ctx context.Context,
+		hostname string,
+		eventTime time.Time,
+	) error
+}
+
+// ensure that default implementation (hostMaintenanceHistoryOps) satisfies
+// the interface
+var _ HostMaintenanceHistoryOps = (*hostMaintenanceHistoryOps)(nil)
+
+// hostMaintenanceHistoryOps implements HostMaintenanceHistoryOps using a
+// particular Store
+type hostMaintenanceHistoryOps struct {
+	store *Store
+	// typed store of the table
+	table *HostMaintenanceHistoryStore
+}
+
+// NewHostMaintenanceHistoryOps constructs a HostMaintenanceHistoryOps
+// object for provided Store.
+func NewHostMaintenanceHistoryOps(s *Store) HostMaintenanceHistoryOps {
+	return &hostMaintenanceHistoryOps{
+		store: s,
+		table: NewHostMaintenanceHistoryStore(s.oClient),
+	}
+}
+
+// Create creates a HostMaintenanceHistoryObject in db
+func (d *hostMaintenanceHistoryOps) Create(
+	ctx context.Context,
+	event *HostMaintenanceEventObject,
+) error {
+	obj := &HostMaintenanceHistoryObject{
+		Hostname:    event.Hostname,
+		EventTime:   event.EventTime,
+		FromState:   event.FromState,
+		ToState:     event.ToState,
+		ArchiveTime: time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryCreate.Inc(1)
+	return nil
+}
+
+// GetAll gets all the HostMaintenanceHistoryObjects of a host from db
+func (d *hostMaintenanceHistoryOps) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostMaintenanceHistoryObject, error) {
+	history, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryGetAll.Inc(1)
+	return history, nil
+}
+
+// Delete deletes a HostMaintenanceHistoryObject from db
+func (d *hostMaintenanceHistoryOps) Delete(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) error {
+	if err := d.table.Delete(ctx, hostname, eventTime); err != nil {
+		d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostMaintenanceHistoryDelete.Inc(1)
+	return nil
+}
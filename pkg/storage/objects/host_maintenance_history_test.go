@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostMaintenanceHistoryObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostMaintenanceHistoryObjectTestSuite) SetupTest() {
+}
+
+func TestHostMaintenanceHistoryObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostMaintenanceHistoryObjectTestSuite))
+}
+
+// TestHostMaintenanceHistoryOps tests HostMaintenanceHistoryObject
+// CRUD operations.
+func (s *HostMaintenanceHistoryObjectTestSuite) TestHostMaintenanceHistoryOps() {
+	db := NewHostMaintenanceHistoryOps(testStore)
+	ctx := context.Background()
+
+	event := &HostMaintenanceEventObject{
+		Hostname:  "hostname-" + uuid.New(),
+		EventTime: time.Now().UTC().Truncate(time.Millisecond),
+		FromState: "HOST_STATE_DOWN",
+		ToState:   "HOST_STATE_UP",
+	}
+
+	history, err := db.GetAll(ctx, event.Hostname)
+	s.NoError(err)
+	s.Empty(history)
+
+	s.NoError(db.Create(ctx, event))
+	history, err = db.GetAll(ctx, event.Hostname)
+	s.NoError(err)
+	s.Len(history, 1)
+	s.True(event.EventTime.Equal(history[0].EventTime))
+	s.Equal(event.FromState, history[0].FromState)
+	s.Equal(event.ToState, history[0].ToState)
+	s.False(history[0].ArchiveTime.IsZero())
+
+	s.NoError(db.Delete(ctx, event.Hostname, event.EventTime))
+	history, err = db.GetAll(ctx, event.Hostname)
+	s.NoError(err)
+	s.Empty(history)
+}
+
+// TestHostMaintenanceHistoryOpsClientFail tests failure cases due to ORM
+// Client errors
+func (s *HostMaintenanceHistoryObjectTestSuite) TestHostMaintenanceHistoryOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostMaintenanceHistoryOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+	event := &HostMaintenanceEventObject{
+		Hostname:  "hostname",
+		EventTime: time.Now(),
+	}
+
+	err := db.Create(ctx, event)
+	s.EqualError(err, "create failed")
+
+	_, err = db.GetAll(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname", event.EventTime)
+	s.EqualError(err, "delete failed")
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// init adds a HostReservationObject instance to the global list of storage
+// objects
+func init() {
+	Objs = append(Objs, &HostReservationObject{})
+}
+
+// HostReservationObject corresponds to a row in host_reservations table.
+type HostReservationObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_reservations, primaryKey=((hostname), reservation_id)"`
+
+	// Hostname of the host the resources are reserved on
+	Hostname string `column:"name=hostname"`
+	// ID of the reservation
+	ReservationID string `column:"name=reservation_id"`
+	// Role the resources are reserved for
+	Role string `column:"name=role"`
+	// Marshaled ReservationSpec of the reserved resources
+	Spec []byte `column:"name=spec"`
+	// ID of the persistent volume created on the reservation
+	VolumeID string `column:"name=volume_id"`
+	// ID of the stateful job allowed to claim the reservation
+	JobID string `column:"name=job_id"`
+	// Instance of the stateful job allowed to claim the reservation
+	InstanceID uint32 `column:"name=instance_id"`
+	// Creation time of the reservation
+	CreationTime time.Time `column:"name=creation_time"`
+}
+
+// HostReservationOps provides methods for manipulating host_reservations
+// table.
+type HostReservationOps interface {
+	// Create inserts a row in the table.
+	Create(
+		ctx context.Context,
+		reservation *hpb.Reservation,
+	) error
+
+	// Get retrieves a row from the table.
+	Get(
+		ctx context.Context,
+		hostname string,
+		reservationID string,
+	) (*hpb.Reservation, error)
+
+	// GetAll retrieves all the rows of a host from the table.
+	GetAll(
+		ctx context.Context,
+		hostname string,
+	) ([]*hpb.Reservation, error)
+
+	// Delete removes a row from the table.
+	Delete(
+		ctx context.Context,
+		hostname string,
+		reservationID string,
+	) error
+}
+
+// ensure that default implementation (hostReservationOps) satisfies the
+// interface
+var _ HostReservationOps = (*hostReservationOps)(nil)
+
+// hostReservationOps implements HostReservationOps using a particular Store
+type hostReservationOps struct {
+	store *Store
+	// typed store of the table
+	table *HostReservationStore
+}
+
+// NewHostReservationOps constructs a HostReservationOps object for provided
+// Store.
+func NewHostReservationOps(s *Store) HostReservationOps {
+	return &hostReservationOps{
+		store: s,
+		table: NewHostReservationStore(s.oClient),
+	}
+}
+
+// newHostReservationObject creates a HostReservationObject from reservation
+func newHostReservationObject(
+	reservation *hpb.Reservation,
+) (*HostReservationObject, error) {
+	specBuffer, err := proto.Marshal(reservation.GetSpec())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal reservation spec")
+	}
+
+	creationTime := time.Now().UTC()
+	if reservation.GetCreationTime() != "" {
+		creationTime, err = time.Parse(
+			time.RFC3339Nano, reservation.GetCreationTime())
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse creation time")
+		}
+	}
+
+	return &HostReservationObject{
+		Hostname:      reservation.GetHostname(),
+		ReservationID: reservation.GetReservationId(),
+		Role:          reservation.GetRole(),
+		Spec:          specBuffer,
+		VolumeID:      reservation.GetVolumeId(),
+		JobID:         reservation.GetJobId(),
+		InstanceID:    reservation.GetInstanceId(),
+		CreationTime:  creationTime,
+	}, nil
+}
+
+// toReservation converts the HostReservationObject to a reservation
+func (r *HostReservationObject) toReservation() (*hpb.Reservation, error) {
+	spec := &hpb.ReservationSpec{}
+	if err := proto.Unmarshal(r.Spec, spec); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal reservation spec")
+	}
+
+	return &hpb.Reservation{
+		ReservationId: r.ReservationID,
+		Hostname:      r.Hostname,
+		Role:          r.Role,
+		Spec:          spec,
+		VolumeId:      r.VolumeID,
+		JobId:         r.JobID,
+		InstanceId:    r.InstanceID,
+		CreationTime:  r.CreationTime.UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// Create creates a HostReservationObject in db
+func (d *hostReservationOps) Create(
+	ctx context.Context,
+	reservation *hpb.Reservation,
+) error {
+	obj, err := newHostReservationObject(reservation)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationCreateFail.Inc(1)
+		return errors.Wrap(err, "Failed to construct HostReservationObject")
+	}
+
+	if err = d.table.CreateIfNotExists(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostReservationCreate.Inc(1)
+	return nil
+}
+
+// Get gets a HostReservationObject from db
+func (d *hostReservationOps) Get(
+	ctx context.Context,
+	hostname string,
+	reservationID string,
+) (*hpb.Reservation, error) {
+	obj, err := d.table.Get(ctx, hostname, reservationID)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationGetFail.Inc(1)
+		return nil, err
+	}
+
+	reservation, err := obj.toReservation()
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationGetFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostReservationGet.Inc(1)
+	return reservation, nil
+}
+
+// GetAll gets all the HostReservationObjects of a host from db
+func (d *hostReservationOps) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*hpb.Reservation, error) {
+	objs, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	reservations := make([]*hpb.Reservation, 0, len(objs))
+	for _, obj := range objs {
+		reservation, err := obj.toReservation()
+		if err != nil {
+			d.store.metrics.OrmHostMetrics.HostReservationGetAllFail.Inc(1)
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	d.store.metrics.OrmHostMetrics.HostReservationGetAll.Inc(1)
+	return reservations, nil
+}
+
+// Delete deletes a HostReservationObject from db
+func (d *hostReservationOps) Delete(
+	ctx context.Context,
+	hostname string,
+	reservationID string,
+) error {
+	if err := d.table.Delete(ctx, hostname, reservationID); err != nil {
+		d.store.metrics.OrmHostMetrics.HostReservationDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostReservationDelete.Inc(1)
+	return nil
+}
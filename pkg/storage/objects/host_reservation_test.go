@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/gocql/gocql"
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostReservationObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostReservationObjectTestSuite) SetupTest() {
+}
+
+func TestHostReservationObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostReservationObjectTestSuite))
+}
+
+// TestHostReservationOps tests HostReservationObject CRUD operations.
+func (s *HostReservationObjectTestSuite) TestHostReservationOps() {
+	db := NewHostReservationOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+	reservations := []*hpb.Reservation{
+		{
+			ReservationId: uuid.New(),
+			Hostname:      hostname,
+			Role:          "peloton",
+			Spec: &hpb.ReservationSpec{
+				Cpus:   1.0,
+				MemMb:  1024.0,
+				DiskMb: 2048.0,
+			},
+			CreationTime: "2019-01-02T15:04:05Z",
+		},
+		{
+			ReservationId: uuid.New(),
+			Hostname:      hostname,
+			Role:          "peloton",
+			Spec: &hpb.ReservationSpec{
+				Cpus:                1.0,
+				DiskMb:              1024.0,
+				VolumeContainerPath: "/data",
+			},
+			VolumeId:     uuid.New(),
+			JobId:        uuid.New(),
+			InstanceId:   3,
+			CreationTime: "2019-01-02T15:04:05Z",
+		},
+	}
+
+	for _, reservation := range reservations {
+		s.NoError(db.Create(ctx, reservation))
+
+		result, err := db.Get(
+			ctx, reservation.GetHostname(), reservation.GetReservationId())
+		s.NoError(err)
+		s.Equal(reservation, result)
+	}
+
+	results, err := db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Len(results, len(reservations))
+
+	for _, reservation := range reservations {
+		s.NoError(db.Delete(
+			ctx, reservation.GetHostname(), reservation.GetReservationId()))
+
+		_, err = db.Get(
+			ctx, reservation.GetHostname(), reservation.GetReservationId())
+		s.Equal(gocql.ErrNotFound, err)
+	}
+
+	results, err = db.GetAll(ctx, hostname)
+	s.NoError(err)
+	s.Empty(results)
+}
+
+// TestHostReservationOpsClientFail tests failure cases due to ORM Client
+// errors
+func (s *HostReservationObjectTestSuite) TestHostReservationOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostReservationOps(mockStore)
+
+	mockClient.EXPECT().CreateIfNotExists(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().Get(gomock.Any(), gomock.Any()).
+		Return(errors.New("get failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, &hpb.Reservation{
+		ReservationId: uuid.New(),
+		Hostname:      "hostname",
+	})
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "hostname", uuid.New())
+	s.EqualError(err, "get failed")
+
+	_, err = db.GetAll(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname", uuid.New())
+	s.EqualError(err, "delete failed")
+
+	// creation time which cannot be parsed
+	err = db.Create(ctx, &hpb.Reservation{
+		ReservationId: uuid.New(),
+		Hostname:      "hostname",
+		CreationTime:  "invalid",
+	})
+	s.Error(err)
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/pkg/errors"
+)
+
+// init adds a HostTasksObject instance to the global list of storage objects
+func init() {
+	Objs = append(Objs, &HostTasksObject{})
+}
+
+// HostTasksObject corresponds to a row in host_tasks table.
+type HostTasksObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=host_tasks, primaryKey=((hostname))"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// JSON encoded list of the Mesos task ids running on the host
+	TaskIDs string `column:"name=task_ids"`
+	// Last time the row was updated
+	UpdateTime time.Time `column:"name=update_time, autotime=update"`
+}
+
+// HostTasksOps provides methods for manipulating host_tasks table.
+type HostTasksOps interface {
+	// Update upserts the task ids running on a host.
+	Update(
+		ctx context.Context,
+		hostname string,
+		taskIDs []string,
+	) error
+
+	// Get retrieves the task ids running on a host. No task ids are
+	// returned if the host has no row in the table.
+	Get(
+		ctx context.Context,
+		hostname string,
+	) ([]string, error)
+
+	// Delete removes the row of a host from the table.
+	Delete(
+		ctx context.Context,
+		hostname string,
+	) error
+}
+
+// ensure that default implementation (hostTasksOps) satisfies the interface
+var _ HostTasksOps = (*hostTasksOps)(nil)
+
+// hostTasksOps implements HostTasksOps using a particular Store
+type hostTasksOps struct {
+	store *Store
+	// typed store of the table
+	table *HostTasksStore
+}
+
+// NewHostTasksOps constructs a HostTasksOps object for provided Store.
+func NewHostTasksOps(s *Store) HostTasksOps {
+	return &hostTasksOps{
+		store: s,
+		table: NewHostTasksStore(s.oClient),
+	}
+}
+
+// Update upserts a HostTasksObject in db
+func (d *hostTasksOps) Update(
+	ctx context.Context,
+	hostname string,
+	taskIDs []string,
+) error {
+	taskIDsBuffer, err := json.Marshal(taskIDs)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostTasksUpdateFail.Inc(1)
+		return errors.Wrap(err, "Failed to marshal task ids")
+	}
+
+	obj := &HostTasksObject{
+		Hostname: hostname,
+		TaskIDs:  string(taskIDsBuffer),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.HostTasksUpdateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostTasksUpdate.Inc(1)
+	return nil
+}
+
+// Get gets the task ids of a HostTasksObject from db
+func (d *hostTasksOps) Get(
+	ctx context.Context,
+	hostname string,
+) ([]string, error) {
+	// Read the partition of the host, so that a host
+	// without any row is not reported as an error.
+	objs, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.HostTasksGetFail.Inc(1)
+		return nil, err
+	}
+
+	var taskIDs []string
+	for _, obj := range objs {
+		var ids []string
+		if err := json.Unmarshal([]byte(obj.TaskIDs), &ids); err != nil {
+			d.store.metrics.OrmHostMetrics.HostTasksGetFail.Inc(1)
+			return nil, errors.Wrap(err, "Failed to unmarshal task ids")
+		}
+		taskIDs = append(taskIDs, ids...)
+	}
+
+	d.store.metrics.OrmHostMetrics.HostTasksGet.Inc(1)
+	return taskIDs, nil
+}
+
+// Delete deletes a HostTasksObject from db
+func (d *hostTasksOps) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	if err := d.table.Delete(ctx, hostname); err != nil {
+		d.store.metrics.OrmHostMetrics.HostTasksDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.HostTasksDelete.Inc(1)
+	return nil
+}
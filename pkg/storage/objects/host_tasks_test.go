@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostTasksObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *HostTasksObjectTestSuite) SetupTest() {
+}
+
+func TestHostTasksObjectSuite(t *testing.T) {
+	suite.Run(t, new(HostTasksObjectTestSuite))
+}
+
+// TestHostTasksOps tests HostTasksObject CRUD operations.
+func (s *HostTasksObjectTestSuite) TestHostTasksOps() {
+	db := NewHostTasksOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+	taskIDs := []string{
+		uuid.New() + "-0-" + uuid.New(),
+		uuid.New() + "-1-" + uuid.New(),
+	}
+
+	result, err := db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Empty(result)
+
+	s.NoError(db.Update(ctx, hostname, taskIDs))
+	result, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(taskIDs, result)
+
+	s.NoError(db.Update(ctx, hostname, taskIDs[:1]))
+	result, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(taskIDs[:1], result)
+
+	s.NoError(db.Delete(ctx, hostname))
+	result, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Empty(result)
+}
+
+// TestHostTasksOpsClientFail tests failure cases due to ORM Client errors
+func (s *HostTasksObjectTestSuite) TestHostTasksOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewHostTasksOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Update(ctx, "hostname", []string{uuid.New()})
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname")
+	s.EqualError(err, "delete failed")
+}
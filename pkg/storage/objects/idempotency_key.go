@@ -0,0 +1,133 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// init adds an IdempotencyKeyObject instance to the global list of storage
+// objects
+func init() {
+	Objs = append(Objs, &IdempotencyKeyObject{})
+}
+
+// IdempotencyKeyObject corresponds to a row in idempotency_keys table.
+type IdempotencyKeyObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=idempotency_keys, primaryKey=((idempotency_key), procedure)"`
+
+	// Idempotency key given by the caller
+	Key string `column:"name=idempotency_key"`
+	// Procedure called with the key
+	Procedure string `column:"name=procedure"`
+	// Fingerprint of the request
+	RequestHash string `column:"name=request_hash"`
+	// Marshaled response of the request
+	Response []byte `column:"name=response"`
+	// Time at which the response was recorded
+	CreateTime time.Time `column:"name=create_time"`
+}
+
+// IdempotencyKeyOps provides methods for manipulating idempotency_keys
+// table.
+type IdempotencyKeyOps interface {
+	// Create records the response of a request made with a key.
+	Create(
+		ctx context.Context,
+		key string,
+		procedure string,
+		requestHash string,
+		response []byte,
+	) error
+
+	// Get retrieves the recorded request made with a key, nil if there is
+	// none or it expired.
+	Get(
+		ctx context.Context,
+		key string,
+		procedure string,
+	) (*IdempotencyKeyObject, error)
+}
+
+// ensure that default implementation (idempotencyKeyOps) satisfies the
+// interface
+var _ IdempotencyKeyOps = (*idempotencyKeyOps)(nil)
+
+// idempotencyKeyOps implements IdempotencyKeyOps using a particular Store
+type idempotencyKeyOps struct {
+	store *Store
+	// typed store of the table
+	table *IdempotencyKeyStore
+}
+
+// NewIdempotencyKeyOps constructs an IdempotencyKeyOps object for provided
+// Store.
+func NewIdempotencyKeyOps(s *Store) IdempotencyKeyOps {
+	return &idempotencyKeyOps{
+		store: s,
+		table: NewIdempotencyKeyStore(s.oClient),
+	}
+}
+
+// Create creates an IdempotencyKeyObject in db
+func (d *idempotencyKeyOps) Create(
+	ctx context.Context,
+	key string,
+	procedure string,
+	requestHash string,
+	response []byte,
+) error {
+	obj := &IdempotencyKeyObject{
+		Key:         key,
+		Procedure:   procedure,
+		RequestHash: requestHash,
+		Response:    response,
+		CreateTime:  time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.IdempotencyKeyCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.IdempotencyKeyCreate.Inc(1)
+	return nil
+}
+
+// Get gets the IdempotencyKeyObject of a key and procedure from db
+func (d *idempotencyKeyOps) Get(
+	ctx context.Context,
+	key string,
+	procedure string,
+) (*IdempotencyKeyObject, error) {
+	// Read the partition of the key, so that a key
+	// without recorded request is not reported as an error.
+	objs, err := d.table.GetAll(ctx, key)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.IdempotencyKeyGetFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.IdempotencyKeyGet.Inc(1)
+	for _, obj := range objs {
+		if obj.Procedure == procedure {
+			return obj, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyKeyObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *IdempotencyKeyObjectTestSuite) SetupTest() {
+}
+
+func TestIdempotencyKeyObjectSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyKeyObjectTestSuite))
+}
+
+// TestIdempotencyKeyOps tests IdempotencyKeyObject CRUD operations.
+func (s *IdempotencyKeyObjectTestSuite) TestIdempotencyKeyOps() {
+	db := NewIdempotencyKeyOps(testStore)
+	ctx := context.Background()
+
+	key := "key-" + uuid.New()
+
+	obj, err := db.Get(ctx, key, "StartMaintenance")
+	s.NoError(err)
+	s.Nil(obj)
+
+	s.NoError(db.Create(ctx, key, "StartMaintenance", "hash1", []byte("response1")))
+	obj, err = db.Get(ctx, key, "StartMaintenance")
+	s.NoError(err)
+	s.Equal(key, obj.Key)
+	s.Equal("hash1", obj.RequestHash)
+	s.Equal([]byte("response1"), obj.Response)
+	s.False(obj.CreateTime.IsZero())
+
+	// The requests of the key are recorded by procedure
+	obj, err = db.Get(ctx, key, "CompleteMaintenance")
+	s.NoError(err)
+	s.Nil(obj)
+}
+
+// TestIdempotencyKeyOpsClientFail tests failure cases due to ORM Client
+// errors
+func (s *IdempotencyKeyObjectTestSuite) TestIdempotencyKeyOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewIdempotencyKeyOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, "key", "StartMaintenance", "hash", nil)
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "key", "StartMaintenance")
+	s.EqualError(err, "getall failed")
+}
@@ -26,9 +26,11 @@ import (
 	"github.com/uber/peloton/.gen/peloton/private/models"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 // init adds a JobConfigObject instance to the global list of storage objects
@@ -39,7 +41,7 @@ func init() {
 // JobConfigObject corresponds to a row in job_config table.
 type JobConfigObject struct {
 	// DB specific annotations
-	base.Object `cassandra:"name=job_config, primaryKey=((job_id), version)"`
+	base.Object `cassandra:"name=job_config, primaryKey=((job_id), version), hedge=50ms, parent=job_index, onDelete=cascade"`
 
 	// JobID of the job
 	JobID string `column:"name=job_id"`
@@ -50,7 +52,7 @@ type JobConfigObject struct {
 	// Config AddOn field for the job
 	ConfigAddOn []byte `column:"name=config_addon"`
 	// Creation time of the job
-	CreationTime time.Time `column:"name=creation_time"`
+	CreationTime time.Time `column:"name=creation_time, autotime=create"`
 }
 
 // JobConfigOps provides methods for manipulating job_config table.
@@ -73,6 +75,10 @@ type JobConfigOps interface {
 
 	// Delete removes an object from the table.
 	Delete(ctx context.Context, id *peloton.JobID, version uint64) error
+
+	// DeleteAll removes all the versions of the config of a job from
+	// the table.
+	DeleteAll(ctx context.Context, id *peloton.JobID) error
 }
 
 // ensure that default implementation (jobConfigOps) satisfies the interface
@@ -156,18 +162,22 @@ func newJobConfigObject(
 	obj.Version = version
 	obj.Config = configBuffer
 	obj.ConfigAddOn = addOnBuffer
-	obj.CreationTime = time.Now().UTC()
 	return obj, nil
 }
 
 // jobConfigOps implements jobConfigOps using a particular Store
 type jobConfigOps struct {
 	store *Store
+	// typed store of the table
+	table *JobConfigStore
 }
 
 // NewJobConfigOps constructs a jobConfigOps object for provided Store.
 func NewJobConfigOps(s *Store) JobConfigOps {
-	return &jobConfigOps{store: s}
+	return &jobConfigOps{
+		store: s,
+		table: NewJobConfigStore(s.oClient),
+	}
 }
 
 // Create creates a JobConfigObject in db
@@ -185,7 +195,7 @@ func (d *jobConfigOps) Create(
 		return errors.Wrap(err, "Failed to construct JobConfigObject")
 	}
 
-	if err = d.store.oClient.CreateIfNotExists(ctx, obj); err != nil {
+	if err = d.table.CreateIfNotExists(ctx, obj); err != nil {
 		d.store.metrics.OrmJobMetrics.JobConfigCreateFail.Inc(1)
 		return err
 	}
@@ -201,12 +211,8 @@ func (d *jobConfigOps) Get(
 	version uint64,
 ) (*job.JobConfig, *models.ConfigAddOn, error) {
 
-	obj := &JobConfigObject{
-		JobID:   id.GetValue(),
-		Version: version,
-	}
-
-	if err := d.store.oClient.Get(ctx, obj); err != nil {
+	obj, err := d.table.Get(ctx, id.GetValue(), version)
+	if err != nil {
 		d.store.metrics.OrmJobMetrics.JobConfigGetFail.Inc(1)
 		return nil, nil, err
 	}
@@ -233,15 +239,36 @@ func (d *jobConfigOps) Delete(
 	id *peloton.JobID,
 	version uint64,
 ) error {
-	obj := &JobConfigObject{
-		JobID:   id.GetValue(),
-		Version: version,
-	}
-
-	if err := d.store.oClient.Delete(ctx, obj); err != nil {
+	if err := d.table.Delete(ctx, id.GetValue(), version); err != nil {
 		d.store.metrics.OrmJobMetrics.JobConfigDeleteFail.Inc(1)
 		return err
 	}
 	d.store.metrics.OrmJobMetrics.JobConfigDelete.Inc(1)
 	return nil
 }
+
+// DeleteAll deletes all the JobConfigObjects of a job from db
+func (d *jobConfigOps) DeleteAll(
+	ctx context.Context,
+	id *peloton.JobID,
+) error {
+	n, err := d.table.DeleteAllInPartition(ctx, id.GetValue(),
+		orm.WithDeleteProgress(func(deleted, total int) {
+			log.WithFields(log.Fields{
+				"job_id":  id.GetValue(),
+				"deleted": deleted,
+				"total":   total,
+			}).Debug("Deleting job configs")
+		}))
+	if err != nil {
+		d.store.metrics.OrmJobMetrics.JobConfigDeleteAllFail.Inc(1)
+		return errors.Wrap(err, "Failed to delete job configs")
+	}
+
+	log.WithFields(log.Fields{
+		"job_id":   id.GetValue(),
+		"versions": n,
+	}).Info("Deleted job configs")
+	d.store.metrics.OrmJobMetrics.JobConfigDeleteAll.Inc(1)
+	return nil
+}
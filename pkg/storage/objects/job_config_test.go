@@ -75,6 +75,27 @@ func (s *JobConfigObjectTestSuite) TestCreateGetDeleteJobConfig() {
 
 }
 
+// TestDeleteAllJobConfigs tests deleting all the versions of a job config
+func (s *JobConfigObjectTestSuite) TestDeleteAllJobConfigs() {
+	jobConfigOps := NewJobConfigOps(testStore)
+	ctx := context.Background()
+
+	for version := uint64(1); version <= 3; version++ {
+		s.NoError(jobConfigOps.Create(
+			ctx, s.jobID, s.config, s.configAddOn, version))
+	}
+
+	s.NoError(jobConfigOps.DeleteAll(ctx, s.jobID))
+
+	for version := uint64(1); version <= 3; version++ {
+		_, _, err := jobConfigOps.Get(ctx, s.jobID, version)
+		s.Equal(gocql.ErrNotFound, err)
+	}
+
+	// deleting the configs of a job without configs is a no-op
+	s.NoError(jobConfigOps.DeleteAll(ctx, s.jobID))
+}
+
 // TestCreateGetDeleteJobConfigFail tests failure cases due to ORM Client errors
 func (s *JobConfigObjectTestSuite) TestCreateGetDeleteJobConfigFail() {
 	ctrl := gomock.NewController(s.T())
@@ -90,6 +111,9 @@ func (s *JobConfigObjectTestSuite) TestCreateGetDeleteJobConfigFail() {
 		Return(errors.New("get failed"))
 	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
 		Return(errors.New("delete failed"))
+	mockClient.EXPECT().DeleteAllInPartition(
+		gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(0, errors.New("delete all failed"))
 
 	ctx := context.Background()
 	version := uint64(1)
@@ -105,6 +129,10 @@ func (s *JobConfigObjectTestSuite) TestCreateGetDeleteJobConfigFail() {
 	err = configOps.Delete(ctx, s.jobID, version)
 	s.Error(err)
 	s.Equal("delete failed", err.Error())
+
+	err = configOps.DeleteAll(ctx, s.jobID)
+	s.Error(err)
+	s.Contains(err.Error(), "delete all failed")
 }
 
 func (s *JobConfigObjectTestSuite) buildConfig() {
@@ -28,22 +28,22 @@ import (
 )
 
 var (
-	_configFields = []string{
-		"Name",
-		"Owner",
-		"RespoolID",
-		"JobType",
-		"Config",
-		"InstanceCount",
-		"Labels",
+	_configFields = []JobIndexField{
+		JobIndexFieldName,
+		JobIndexFieldOwner,
+		JobIndexFieldRespoolID,
+		JobIndexFieldJobType,
+		JobIndexFieldConfig,
+		JobIndexFieldInstanceCount,
+		JobIndexFieldLabels,
 	}
-	_runtimeFields = []string{
-		"RuntimeInfo",
-		"State",
-		"UpdateTime",
-		"CreationTime",
-		"StartTime",
-		"CompletionTime",
+	_runtimeFields = []JobIndexField{
+		JobIndexFieldRuntimeInfo,
+		JobIndexFieldState,
+		JobIndexFieldUpdateTime,
+		JobIndexFieldCreationTime,
+		JobIndexFieldStartTime,
+		JobIndexFieldCompletionTime,
 	}
 )
 
@@ -55,7 +55,7 @@ func init() {
 // JobIndexObject corresponds to a row in job_index table.
 type JobIndexObject struct {
 	// DB specific annotations
-	base.Object `cassandra:"name=job_index, primaryKey=((job_id))"`
+	base.Object `cassandra:"name=job_index, primaryKey=((job_id)), hedge=50ms"`
 
 	// JobID of the job
 	JobID string `column:"name=job_id"`
@@ -88,7 +88,7 @@ type JobIndexObject struct {
 	// Completion time of the job
 	CompletionTime time.Time `column:"name=completion_time"`
 	// Time when job was updated
-	UpdateTime time.Time `column:"name=update_time"`
+	UpdateTime time.Time `column:"name=update_time, autotime=update"`
 	// Sla of the job
 	SLA string `column:"name=sla"`
 }
@@ -180,7 +180,6 @@ func newJobIndexObject(
 
 		obj.RuntimeInfo = string(runtimeBuffer)
 		obj.State = runtime.GetState().String()
-		obj.UpdateTime = time.Now()
 
 		if runtime.GetCreationTime() != "" {
 			t, err := time.Parse(time.RFC3339Nano, runtime.GetCreationTime())
@@ -258,11 +257,16 @@ func (j *JobIndexObject) ToJobSummary() (*job.JobSummary, error) {
 // jobIndexOps implements JobIndexOps using a particular Store
 type jobIndexOps struct {
 	store *Store
+	// typed store of the table
+	table *JobIndexStore
 }
 
 // NewJobIndexOps constructs a JobIndexOps object for provided Store.
 func NewJobIndexOps(s *Store) JobIndexOps {
-	return &jobIndexOps{store: s}
+	return &jobIndexOps{
+		store: s,
+		table: NewJobIndexStore(s.oClient),
+	}
 }
 
 // Create creates a JobIndexObject in db
@@ -280,7 +284,7 @@ func (d *jobIndexOps) Create(
 		return errors.Wrap(err, "Failed to construct JobIndexObject")
 	}
 
-	if err = d.store.oClient.Create(ctx, obj); err != nil {
+	if err = d.table.Create(ctx, obj); err != nil {
 		d.store.metrics.OrmJobMetrics.JobIndexCreateFail.Inc(1)
 		return err
 	}
@@ -295,11 +299,8 @@ func (d *jobIndexOps) Get(
 	id *peloton.JobID,
 ) (*JobIndexObject, error) {
 
-	jobIndexObject := &JobIndexObject{
-		JobID: id.GetValue(),
-	}
-
-	if err := d.store.oClient.Get(ctx, jobIndexObject); err != nil {
+	jobIndexObject, err := d.table.Get(ctx, id.GetValue())
+	if err != nil {
 		d.store.metrics.OrmJobMetrics.JobIndexGetFail.Inc(1)
 		return nil, err
 	}
@@ -340,7 +341,7 @@ func (d *jobIndexOps) Update(
 		return errors.Wrap(err, "Failed to construct JobIndexObject")
 	}
 
-	fields := []string{}
+	fields := []JobIndexField{}
 	if config != nil {
 		fields = append(fields, _configFields...)
 	}
@@ -349,7 +350,7 @@ func (d *jobIndexOps) Update(
 		fields = append(fields, _runtimeFields...)
 	}
 
-	err = d.store.oClient.Update(ctx, obj, fields...)
+	err = d.table.Update(ctx, obj, fields...)
 	if err != nil {
 		log.WithField("job_id", id.GetValue()).
 			WithField("config", config).
@@ -368,10 +369,7 @@ func (d *jobIndexOps) Delete(
 	ctx context.Context,
 	id *peloton.JobID,
 ) error {
-	jobIndexObject := &JobIndexObject{
-		JobID: id.GetValue(),
-	}
-	if err := d.store.oClient.Delete(ctx, jobIndexObject); err != nil {
+	if err := d.table.Delete(ctx, id.GetValue()); err != nil {
 		d.store.metrics.OrmJobMetrics.JobIndexDeleteFail.Inc(1)
 		return err
 	}
@@ -31,6 +31,10 @@ func init() {
 }
 
 // JobNameToIDObject corresponds to a row in job_name_to_id table.
+// Job names can be reused, so a job name maps to every job created with it,
+// latest first, including the deleted jobs whose mappings are kept. The
+// table has no unique constraint: concurrent submissions of jobs with the
+// same name both map the name to their job.
 type JobNameToIDObject struct {
 	// DB specific annotations
 	base.Object `cassandra:"name=job_name_to_id, primaryKey=((job_name), update_time)"`
@@ -65,11 +69,16 @@ var _ JobNameToIDOps = (*jobNameToIDOps)(nil)
 // jobNameToIDOps implements JobNameToIDOps using a particular Store
 type jobNameToIDOps struct {
 	store *Store
+	// typed store of the table
+	table *JobNameToIDStore
 }
 
 // NewJobNameToIDOps constructs a JobNameToIDOps object for provided Store.
 func NewJobNameToIDOps(s *Store) JobNameToIDOps {
-	return &jobNameToIDOps{store: s}
+	return &jobNameToIDOps{
+		store: s,
+		table: NewJobNameToIDStore(s.oClient),
+	}
 }
 
 // Create creates a JobNameToIDObject in db
@@ -85,7 +94,7 @@ func (d *jobNameToIDOps) Create(
 		UpdateTime: gocql.UUIDFromTime(time.Now()),
 	}
 
-	if err := d.store.oClient.Create(ctx, obj); err != nil {
+	if err := d.table.Create(ctx, obj); err != nil {
 		d.store.metrics.OrmJobMetrics.JobNameToIDCreateFail.Inc(1)
 		return err
 	}
@@ -99,24 +108,12 @@ func (d *jobNameToIDOps) GetAll(
 	ctx context.Context,
 	jobName string,
 ) ([]*JobNameToIDObject, error) {
-
-	resultObjs := []*JobNameToIDObject{}
-
-	jobNameToIDObject := &JobNameToIDObject{
-		JobName: jobName,
-	}
-
-	objs, err := d.store.oClient.GetAll(ctx, jobNameToIDObject)
+	resultObjs, err := d.table.GetAll(ctx, jobName)
 	if err != nil {
 		d.store.metrics.OrmJobMetrics.JobNameToIDGetAllFail.Inc(1)
 		return nil, err
 	}
 
-	for _, obj := range objs {
-		jobNameToIDObj := obj.(*JobNameToIDObject)
-		resultObjs = append(resultObjs, jobNameToIDObj)
-	}
-
 	d.store.metrics.OrmJobMetrics.JobNameToIDGetAll.Inc(1)
 	return resultObjs, nil
 }
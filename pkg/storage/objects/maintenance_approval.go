@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// init adds a MaintenanceApprovalObject instance to the global list of
+// storage objects
+func init() {
+	Objs = append(Objs, &MaintenanceApprovalObject{})
+}
+
+// MaintenanceApprovalObject corresponds to a row in maintenance_approvals
+// table.
+type MaintenanceApprovalObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=maintenance_approvals, primaryKey=((hostname))"`
+
+	// Hostname of the host
+	Hostname string `column:"name=hostname"`
+	// Marshaled MaintenanceApproval of the host
+	Approval []byte `column:"name=approval"`
+	// Time at which the approval was last updated
+	UpdateTime time.Time `column:"name=update_time"`
+}
+
+// MaintenanceApprovalOps provides methods for manipulating
+// maintenance_approvals table.
+type MaintenanceApprovalOps interface {
+	// Create upserts the approval of a host.
+	Create(
+		ctx context.Context,
+		approval *hpb.MaintenanceApproval,
+	) error
+
+	// Get retrieves the approval of a host, nil if the host has none.
+	Get(
+		ctx context.Context,
+		hostname string,
+	) (*hpb.MaintenanceApproval, error)
+
+	// Delete removes the approval of a host.
+	Delete(
+		ctx context.Context,
+		hostname string,
+	) error
+}
+
+// ensure that default implementation (maintenanceApprovalOps) satisfies the
+// interface
+var _ MaintenanceApprovalOps = (*maintenanceApprovalOps)(nil)
+
+// maintenanceApprovalOps implements MaintenanceApprovalOps using a
+// particular Store
+type maintenanceApprovalOps struct {
+	store *Store
+	// typed store of the table
+	table *MaintenanceApprovalStore
+}
+
+// NewMaintenanceApprovalOps constructs a MaintenanceApprovalOps object for
+// provided Store.
+func NewMaintenanceApprovalOps(s *Store) MaintenanceApprovalOps {
+	return &maintenanceApprovalOps{
+		store: s,
+		table: NewMaintenanceApprovalStore(s.oClient),
+	}
+}
+
+// Create upserts a MaintenanceApprovalObject in db
+func (d *maintenanceApprovalOps) Create(
+	ctx context.Context,
+	approval *hpb.MaintenanceApproval,
+) error {
+	buffer, err := proto.Marshal(approval)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenanceApprovalCreateFail.Inc(1)
+		return errors.Wrap(err, "Failed to marshal maintenance approval")
+	}
+
+	obj := &MaintenanceApprovalObject{
+		Hostname:   approval.GetHostname(),
+		Approval:   buffer,
+		UpdateTime: time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenanceApprovalCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenanceApprovalCreate.Inc(1)
+	return nil
+}
+
+// Get gets the MaintenanceApproval of a MaintenanceApprovalObject from db
+func (d *maintenanceApprovalOps) Get(
+	ctx context.Context,
+	hostname string,
+) (*hpb.MaintenanceApproval, error) {
+	// Read the partition of the host, so that a host
+	// without approval is not reported as an error.
+	objs, err := d.table.GetAll(ctx, hostname)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenanceApprovalGetFail.Inc(1)
+		return nil, err
+	}
+
+	for _, obj := range objs {
+		approval := &hpb.MaintenanceApproval{}
+		if err := proto.Unmarshal(obj.Approval, approval); err != nil {
+			d.store.metrics.OrmHostMetrics.MaintenanceApprovalGetFail.Inc(1)
+			return nil, errors.Wrap(err, "Failed to unmarshal maintenance approval")
+		}
+		d.store.metrics.OrmHostMetrics.MaintenanceApprovalGet.Inc(1)
+		return approval, nil
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenanceApprovalGet.Inc(1)
+	return nil, nil
+}
+
+// Delete deletes a MaintenanceApprovalObject from db
+func (d *maintenanceApprovalOps) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	if err := d.table.Delete(ctx, hostname); err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenanceApprovalDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenanceApprovalDelete.Inc(1)
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaintenanceApprovalObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *MaintenanceApprovalObjectTestSuite) SetupTest() {
+}
+
+func TestMaintenanceApprovalObjectSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceApprovalObjectTestSuite))
+}
+
+// TestMaintenanceApprovalOps tests MaintenanceApprovalObject CRUD
+// operations.
+func (s *MaintenanceApprovalObjectTestSuite) TestMaintenanceApprovalOps() {
+	db := NewMaintenanceApprovalOps(testStore)
+	ctx := context.Background()
+
+	hostname := "hostname-" + uuid.New()
+
+	approval, err := db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Nil(approval)
+
+	expected := &hpb.MaintenanceApproval{
+		Hostname:    hostname,
+		Requester:   "alice",
+		RequestTime: "2019-05-01T10:00:00Z",
+	}
+	s.NoError(db.Create(ctx, expected))
+	approval, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(expected, approval)
+
+	expected.Approver = "bob"
+	expected.ApproveTime = "2019-05-01T11:00:00Z"
+	s.NoError(db.Create(ctx, expected))
+	approval, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Equal(expected, approval)
+
+	s.NoError(db.Delete(ctx, hostname))
+	approval, err = db.Get(ctx, hostname)
+	s.NoError(err)
+	s.Nil(approval)
+}
+
+// TestMaintenanceApprovalOpsClientFail tests failure cases due to ORM
+// Client errors
+func (s *MaintenanceApprovalObjectTestSuite) TestMaintenanceApprovalOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewMaintenanceApprovalOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, &hpb.MaintenanceApproval{Hostname: "hostname"})
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "hostname")
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "hostname")
+	s.EqualError(err, "delete failed")
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// _maintenancePolicySet is the partition of maintenance_policies which
+// holds all the policies.
+const _maintenancePolicySet = "default"
+
+// init adds a MaintenancePolicyObject instance to the global list of
+// storage objects
+func init() {
+	Objs = append(Objs, &MaintenancePolicyObject{})
+}
+
+// MaintenancePolicyObject corresponds to a row in maintenance_policies
+// table.
+type MaintenancePolicyObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=maintenance_policies, primaryKey=((policy_set), name)"`
+
+	// Partition of the policy, the same for all policies
+	PolicySet string `column:"name=policy_set"`
+	// Name of the policy
+	Name string `column:"name=name"`
+	// Marshaled MaintenancePolicy
+	Policy []byte `column:"name=policy"`
+	// Time at which the policy was last updated
+	UpdateTime time.Time `column:"name=update_time"`
+}
+
+// MaintenancePolicyOps provides methods for manipulating
+// maintenance_policies table.
+type MaintenancePolicyOps interface {
+	// Create upserts a policy.
+	Create(
+		ctx context.Context,
+		policy *hpb.MaintenancePolicy,
+	) error
+
+	// Get retrieves a policy by name, nil if there is none.
+	Get(
+		ctx context.Context,
+		name string,
+	) (*hpb.MaintenancePolicy, error)
+
+	// GetAll retrieves all the policies, sorted by name.
+	GetAll(ctx context.Context) ([]*hpb.MaintenancePolicy, error)
+
+	// Delete removes a policy.
+	Delete(
+		ctx context.Context,
+		name string,
+	) error
+}
+
+// ensure that default implementation (maintenancePolicyOps) satisfies the
+// interface
+var _ MaintenancePolicyOps = (*maintenancePolicyOps)(nil)
+
+// maintenancePolicyOps implements MaintenancePolicyOps using a particular
+// Store
+type maintenancePolicyOps struct {
+	store *Store
+	// typed store of the table
+	table *MaintenancePolicyStore
+}
+
+// NewMaintenancePolicyOps constructs a MaintenancePolicyOps object for
+// provided Store.
+func NewMaintenancePolicyOps(s *Store) MaintenancePolicyOps {
+	return &maintenancePolicyOps{
+		store: s,
+		table: NewMaintenancePolicyStore(s.oClient),
+	}
+}
+
+// Create upserts a MaintenancePolicyObject in db
+func (d *maintenancePolicyOps) Create(
+	ctx context.Context,
+	policy *hpb.MaintenancePolicy,
+) error {
+	buffer, err := proto.Marshal(policy)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenancePolicyCreateFail.Inc(1)
+		return errors.Wrap(err, "Failed to marshal maintenance policy")
+	}
+
+	obj := &MaintenancePolicyObject{
+		PolicySet:  _maintenancePolicySet,
+		Name:       policy.GetName(),
+		Policy:     buffer,
+		UpdateTime: time.Now().UTC(),
+	}
+	if err := d.table.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenancePolicyCreateFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenancePolicyCreate.Inc(1)
+	return nil
+}
+
+// Get gets the MaintenancePolicy of a MaintenancePolicyObject from db
+func (d *maintenancePolicyOps) Get(
+	ctx context.Context,
+	name string,
+) (*hpb.MaintenancePolicy, error) {
+	policies, err := d.getAll(ctx)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenancePolicyGetFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenancePolicyGet.Inc(1)
+	for _, policy := range policies {
+		if policy.GetName() == name {
+			return policy, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAll gets the MaintenancePolicies of all MaintenancePolicyObjects
+// from db
+func (d *maintenancePolicyOps) GetAll(
+	ctx context.Context,
+) ([]*hpb.MaintenancePolicy, error) {
+	policies, err := d.getAll(ctx)
+	if err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenancePolicyGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenancePolicyGetAll.Inc(1)
+	return policies, nil
+}
+
+// getAll reads the partition of the policies, which is sorted by name.
+func (d *maintenancePolicyOps) getAll(
+	ctx context.Context,
+) ([]*hpb.MaintenancePolicy, error) {
+	objs, err := d.table.GetAll(ctx, _maintenancePolicySet)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*hpb.MaintenancePolicy
+	for _, obj := range objs {
+		policy := &hpb.MaintenancePolicy{}
+		if err := proto.Unmarshal(obj.Policy, policy); err != nil {
+			return nil, errors.Wrap(err, "Failed to unmarshal maintenance policy")
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// Delete deletes a MaintenancePolicyObject from db
+func (d *maintenancePolicyOps) Delete(
+	ctx context.Context,
+	name string,
+) error {
+	if err := d.table.Delete(ctx, _maintenancePolicySet, name); err != nil {
+		d.store.metrics.OrmHostMetrics.MaintenancePolicyDeleteFail.Inc(1)
+		return err
+	}
+
+	d.store.metrics.OrmHostMetrics.MaintenancePolicyDelete.Inc(1)
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaintenancePolicyObjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *MaintenancePolicyObjectTestSuite) SetupTest() {
+}
+
+func TestMaintenancePolicyObjectSuite(t *testing.T) {
+	suite.Run(t, new(MaintenancePolicyObjectTestSuite))
+}
+
+// TestMaintenancePolicyOps tests MaintenancePolicyObject CRUD operations.
+func (s *MaintenancePolicyObjectTestSuite) TestMaintenancePolicyOps() {
+	db := NewMaintenancePolicyOps(testStore)
+	ctx := context.Background()
+
+	name := "kernel-upgrade-" + uuid.New()
+
+	policy, err := db.Get(ctx, name)
+	s.NoError(err)
+	s.Nil(policy)
+
+	expected := &hpb.MaintenancePolicy{
+		Name: name,
+		DrainOptions: &hpb.DrainOptions{
+			KillGracePeriodSeconds: 30,
+			DrainTimeoutSeconds:    3600,
+		},
+		MaxDrainingHosts: 10,
+	}
+	s.NoError(db.Create(ctx, expected))
+	policy, err = db.Get(ctx, name)
+	s.NoError(err)
+	s.Equal(expected, policy)
+
+	expected.MaxDrainingHosts = 5
+	s.NoError(db.Create(ctx, expected))
+	policies, err := db.GetAll(ctx)
+	s.NoError(err)
+	s.Contains(policies, expected)
+
+	s.NoError(db.Delete(ctx, name))
+	policy, err = db.Get(ctx, name)
+	s.NoError(err)
+	s.Nil(policy)
+}
+
+// TestMaintenancePolicyOpsClientFail tests failure cases due to ORM
+// Client errors
+func (s *MaintenancePolicyObjectTestSuite) TestMaintenancePolicyOpsClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	db := NewMaintenancePolicyOps(mockStore)
+
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getall failed")).Times(2)
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := db.Create(ctx, &hpb.MaintenancePolicy{Name: "kernel-upgrade"})
+	s.EqualError(err, "create failed")
+
+	_, err = db.Get(ctx, "kernel-upgrade")
+	s.EqualError(err, "getall failed")
+
+	_, err = db.GetAll(ctx)
+	s.EqualError(err, "getall failed")
+
+	err = db.Delete(ctx, "kernel-upgrade")
+	s.EqualError(err, "delete failed")
+}
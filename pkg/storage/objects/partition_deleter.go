@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/storage/cassandra"
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// partitionDeleter implements cassandra.PartitionDeleter with the ORM bulk
+// partition delete.
+type partitionDeleter struct {
+	jobConfigOps JobConfigOps
+	// typed store of the pod_events table
+	podEvents *PodEventsStore
+}
+
+// NewPartitionDeleter returns a cassandra.PartitionDeleter which deletes
+// the job configs and pod events of a job through the provided Store.
+func NewPartitionDeleter(s *Store) cassandra.PartitionDeleter {
+	return &partitionDeleter{
+		jobConfigOps: NewJobConfigOps(s),
+		podEvents:    NewPodEventsStore(s.oClient),
+	}
+}
+
+// DeleteJobConfigs deletes all the config versions of a job.
+func (d *partitionDeleter) DeleteJobConfigs(
+	ctx context.Context,
+	jobID string,
+) error {
+	return d.jobConfigOps.DeleteAll(ctx, &peloton.JobID{Value: jobID})
+}
+
+// DeletePodEvents deletes all the pod events of an instance of a job.
+func (d *partitionDeleter) DeletePodEvents(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+) (int, error) {
+	return d.podEvents.DeleteAllInPartition(ctx, jobID, instanceID,
+		orm.WithDeleteProgress(func(deleted, total int) {
+			log.WithFields(log.Fields{
+				"job_id":      jobID,
+				"instance_id": instanceID,
+				"deleted":     deleted,
+				"total":       total,
+			}).Debug("Deleting pod events")
+		}))
+}
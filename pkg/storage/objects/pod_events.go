@@ -105,11 +105,16 @@ var _ PodEventsOps = (*podEventsOps)(nil)
 // podEventsOps implements PodEventsOps using a particular Store
 type podEventsOps struct {
 	store *Store
+	// typed store of the table
+	table *PodEventsStore
 }
 
 // NewPodEventsOps constructs a PodEventsOps object for provided Store.
 func NewPodEventsOps(s *Store) PodEventsOps {
-	return &podEventsOps{store: s}
+	return &podEventsOps{
+		store: s,
+		table: NewPodEventsStore(s.oClient),
+	}
 }
 
 // Create upserts single pod state change for a Job -> Instance -> Run.
@@ -172,7 +177,7 @@ func (d *podEventsOps) Create(
 		PodStatus:            podStatus,
 	}
 
-	if err = d.store.oClient.Create(ctx, podEventsObject); err != nil {
+	if err = d.table.Create(ctx, podEventsObject); err != nil {
 		d.store.metrics.OrmTaskMetrics.PodEventsAddFail.Inc(1)
 		return err
 	}
@@ -186,35 +191,22 @@ func (d *podEventsOps) GetAll(
 	instanceID uint32,
 	podID ...string) ([]*pod.PodEvent, error) {
 	var PodEventsObjects []*pod.PodEvent
-	podEventsObject := &PodEventsObject{
-		JobID:      jobID,
-		InstanceID: instanceID,
-	}
-
 	if len(podID) > 0 && len(podID[0]) > 0 {
-		runID, err := util.ParseRunID(podID[0])
-		if err != nil {
+		if _, err := util.ParseRunID(podID[0]); err != nil {
 			return nil, errors.Wrap(err, "Failed to parse runID")
 		}
-		// Events are sorted in descending order by run_id and then update_time.
-		podEventsObject = &PodEventsObject{
-			JobID:      jobID,
-			InstanceID: instanceID,
-			RunID:      runID,
-		}
 	}
-	result, err := d.store.oClient.GetAll(ctx,
-		podEventsObject)
+	// Events are sorted in descending order by run_id and then update_time.
+	result, err := d.table.GetAll(ctx, jobID, instanceID)
 	if err != nil {
 		d.store.metrics.OrmTaskMetrics.PodEventsGetFail.Inc(1)
 		return nil, err
 	}
 
 	var podEvents []*pod.PodEvent
-	for _, value := range result {
+	for _, podEventsObjectValue := range result {
 		podEvent := &pod.PodEvent{}
 
-		podEventsObjectValue := value.(*PodEventsObject)
 		podID := fmt.Sprintf("%s-%d-%d",
 			podEventsObjectValue.JobID,
 			podEventsObjectValue.InstanceID,
@@ -91,11 +91,16 @@ type SecretInfoOps interface {
 // secretInfoOps implements SecretInfoOps interface using a particular Store.
 type secretInfoOps struct {
 	store *Store
+	// typed store of the table
+	table *SecretInfoStore
 }
 
 // NewSecretInfoOps constructs a SecretInfoOps object for provided Store.
 func NewSecretInfoOps(s *Store) SecretInfoOps {
-	return &secretInfoOps{store: s}
+	return &secretInfoOps{
+		store: s,
+		table: NewSecretInfoStore(s.oClient),
+	}
 }
 
 // ensure that default implementation (secretInfoOps) satisfies the interface
@@ -142,7 +147,7 @@ func (s *secretInfoOps) CreateSecret(
 		s.store.metrics.OrmJobMetrics.SecretInfoCreateFail.Inc(1)
 		return errors.Wrap(err, "Failed to construct SecretInfoObject")
 	}
-	if err = s.store.oClient.Create(ctx, obj); err != nil {
+	if err = s.table.Create(ctx, obj); err != nil {
 		s.store.metrics.OrmJobMetrics.SecretInfoCreateFail.Inc(1)
 		return err
 	}
@@ -155,11 +160,8 @@ func (s *secretInfoOps) GetSecret(
 	ctx context.Context,
 	secretID string,
 ) (*SecretInfoObject, error) {
-	secretInfoObject := &SecretInfoObject{
-		SecretID: secretID,
-		Valid:    true,
-	}
-	if err := s.store.oClient.Get(ctx, secretInfoObject); err != nil {
+	secretInfoObject, err := s.table.Get(ctx, secretID, true)
+	if err != nil {
 		s.store.metrics.OrmJobMetrics.SecretInfoGetFail.Inc(1)
 		return nil, err
 	}
@@ -177,8 +179,8 @@ func (s *secretInfoOps) UpdateSecretData(
 		Valid:    true,
 		Data:     secretString,
 	}
-	fieldToUpdate := []string{"Data"}
-	if err := s.store.oClient.Update(ctx, secretInfoObject, fieldToUpdate...); err != nil {
+	if err := s.table.Update(
+		ctx, secretInfoObject, SecretInfoFieldData); err != nil {
 		s.store.metrics.OrmJobMetrics.SecretInfoUpdateFail.Inc(1)
 		return err
 	}
@@ -191,11 +193,7 @@ func (s *secretInfoOps) DeleteSecret(
 	ctx context.Context,
 	secretID string,
 ) error {
-	secretInfoObject := &SecretInfoObject{
-		SecretID: secretID,
-		Valid:    true,
-	}
-	if err := s.store.oClient.Delete(ctx, secretInfoObject); err != nil {
+	if err := s.table.Delete(ctx, secretID, true); err != nil {
 		s.store.metrics.OrmJobMetrics.SecretInfoDeleteFail.Inc(1)
 		return err
 	}
@@ -15,15 +15,26 @@
 package objects
 
 import (
+	"context"
+	"net/http"
+	"time"
+
 	pelotonstore "github.com/uber/peloton/pkg/storage"
 	"github.com/uber/peloton/pkg/storage/cassandra"
 	escassandra "github.com/uber/peloton/pkg/storage/connectors/cassandra"
 	"github.com/uber/peloton/pkg/storage/objects/base"
 	"github.com/uber/peloton/pkg/storage/orm"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
 )
 
+//go:generate go run github.com/uber/peloton/cmd/ormgen --output stores_generated.go
+
+// _closeTimeout is the max duration of the last flush of the storage
+// objects written asynchronously when the store is closed.
+const _closeTimeout = 10 * time.Second
+
 // Objs is a global list of storage objects. Every storage object will be added
 // using an init method to this list. This list will be used when creating the
 // ORM client.
@@ -33,6 +44,9 @@ var Objs []base.Object
 type Store struct {
 	oClient orm.Client
 	metrics *pelotonstore.Metrics
+	// pool is the connection pool of the connector, nil if the connector
+	// can not be resized
+	pool orm.Pool
 }
 
 // NewCassandraStore creates a new Cassandra storage client
@@ -44,14 +58,103 @@ func NewCassandraStore(
 	if err != nil {
 		return nil, err
 	}
+	pool, _ := connector.(orm.Pool)
+	if config.ORMVerification.Enabled {
+		verificationConnector, err := escassandra.NewCassandraConnector(
+			config.VerificationConfig(),
+			scope.SubScope("orm_verification"))
+		if err != nil {
+			return nil, err
+		}
+		// The rows of the store are only the source of truth for the rows
+		// of another cluster or keyspace. Otherwise the verification reads
+		// return the fresher rows, and repair the replicas themselves.
+		repair := config.ORMVerification.Repair
+		if repair && !config.VerifiesOtherStore() {
+			log.Warn("ORM verification repair disabled, " +
+				"the verification store is the store")
+			repair = false
+		}
+		connector = orm.NewVerifyingConnector(
+			connector,
+			verificationConnector,
+			orm.VerificationConfig{
+				Repair:     repair,
+				IsNotFound: escassandra.IsNotFound,
+			},
+			scope)
+	}
+	if config.ORMCircuitBreaker.Enabled {
+		connector = orm.NewCircuitBreakerConnector(
+			connector,
+			orm.CircuitBreakerConfig{
+				Window:      config.ORMCircuitBreaker.Window,
+				MinRequests: config.ORMCircuitBreaker.MinRequests,
+				ErrorRate:   config.ORMCircuitBreaker.ErrorRate,
+				OpenTimeout: config.ORMCircuitBreaker.OpenTimeout,
+				Probes:      config.ORMCircuitBreaker.Probes,
+				IsFailure:   escassandra.IsBackendFailure,
+			},
+			scope)
+	}
 	// TODO: Load up all objects automatically instead of explicitly adding
 	// them here. Might need to add some Go init() magic to do this.
-	oclient, err := orm.NewClient(connector, Objs...)
+	oclient, err := orm.NewClientWithConfig(connector, &orm.ClientConfig{
+		QueryTimeout:        config.ORMQueries.Timeout,
+		SlowQueryThreshold:  config.ORMQueries.SlowQueryThreshold,
+		SlowQueryBufferSize: config.ORMQueries.SlowQueryBufferSize,
+		HedgedReads:         config.ORMQueries.HedgedReads,
+		AsyncWrites: orm.AsyncWriteConfig{
+			FlushInterval:  config.ORMQueries.AsyncWrites.FlushInterval,
+			BatchSize:      config.ORMQueries.AsyncWrites.BatchSize,
+			BufferSize:     config.ORMQueries.AsyncWrites.BufferSize,
+			OverflowPolicy: config.ORMQueries.AsyncWrites.OverflowPolicy,
+			MaxAttempts:    config.ORMQueries.AsyncWrites.MaxAttempts,
+		},
+		SessionReads: orm.SessionReadConfig{
+			Retries:       config.ORMSessions.Retries,
+			RetryInterval: config.ORMSessions.RetryInterval,
+		},
+		Scope: scope.SubScope("orm_hedging"),
+	}, Objs...)
 	if err != nil {
 		return nil, err
 	}
 	return &Store{
 		oClient: oclient,
 		metrics: pelotonstore.NewMetrics(scope),
+		pool:    pool,
 	}, nil
 }
+
+// Close writes the storage objects buffered for asynchronous writes, and
+// stops their periodic flushes. The store must not be used once closed.
+func (s *Store) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), _closeTimeout)
+	defer cancel()
+	return s.oClient.Close(ctx)
+}
+
+// StatsHandler returns a handler dumping the access statistics
+// of the storage objects.
+func (s *Store) StatsHandler() func(http.ResponseWriter, *http.Request) {
+	return orm.StatsHandler(s.oClient)
+}
+
+// SlowQueriesHandler returns a handler dumping the recent slow queries
+// of the storage objects.
+func (s *Store) SlowQueriesHandler() func(http.ResponseWriter, *http.Request) {
+	return orm.SlowQueriesHandler(s.oClient)
+}
+
+// PoolHandler returns a handler dumping the connection pool of the store
+// and the statistics of its backend hosts, and resizing the pool on POST.
+func (s *Store) PoolHandler() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.pool == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		orm.PoolHandler(s.pool)(w, r)
+	}
+}
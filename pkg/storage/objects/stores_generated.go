@@ -0,0 +1,1793 @@
+// Code generated by ormgen. DO NOT EDIT.
+
+package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/uber/peloton/pkg/storage/orm"
+)
+
+// HostAssignmentField is an updatable field of HostAssignmentObject.
+type HostAssignmentField string
+
+// Fields of HostAssignmentObject which can be updated.
+const (
+	HostAssignmentFieldAssignment HostAssignmentField = "Assignment"
+	HostAssignmentFieldUpdateTime HostAssignmentField = "UpdateTime"
+)
+
+// HostAssignmentStore provides typed access to the host_assignments table.
+type HostAssignmentStore struct {
+	client orm.Client
+}
+
+// NewHostAssignmentStore returns a store using the given ORM client.
+func NewHostAssignmentStore(client orm.Client) *HostAssignmentStore {
+	return &HostAssignmentStore{client: client}
+}
+
+// Create creates the HostAssignmentObject in the database.
+func (s *HostAssignmentStore) Create(
+	ctx context.Context,
+	obj *HostAssignmentObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostAssignmentObject in the database
+// if it does not exist yet.
+func (s *HostAssignmentStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostAssignmentObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostAssignmentObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostAssignmentStore) CreateAsync(
+	ctx context.Context,
+	obj *HostAssignmentObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostAssignmentObject with the given primary key.
+func (s *HostAssignmentStore) Get(
+	ctx context.Context,
+	hostname string,
+) (*HostAssignmentObject, error) {
+	obj := &HostAssignmentObject{
+		Hostname: hostname,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostAssignmentObjects of the given partition.
+func (s *HostAssignmentStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostAssignmentObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostAssignmentObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostAssignmentObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostAssignmentObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostAssignmentObject in the
+// database, or all its fields if none are given.
+func (s *HostAssignmentStore) Update(
+	ctx context.Context,
+	obj *HostAssignmentObject,
+	fields ...HostAssignmentField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostAssignmentObject with the given primary key.
+func (s *HostAssignmentStore) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	return s.client.Delete(ctx, &HostAssignmentObject{
+		Hostname: hostname,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostAssignmentObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostAssignmentStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostAssignmentObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostCordonField is an updatable field of HostCordonObject.
+type HostCordonField string
+
+// Fields of HostCordonObject which can be updated.
+const (
+	HostCordonFieldReason     HostCordonField = "Reason"
+	HostCordonFieldCordonTime HostCordonField = "CordonTime"
+)
+
+// HostCordonStore provides typed access to the host_cordons table.
+type HostCordonStore struct {
+	client orm.Client
+}
+
+// NewHostCordonStore returns a store using the given ORM client.
+func NewHostCordonStore(client orm.Client) *HostCordonStore {
+	return &HostCordonStore{client: client}
+}
+
+// Create creates the HostCordonObject in the database.
+func (s *HostCordonStore) Create(
+	ctx context.Context,
+	obj *HostCordonObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostCordonObject in the database
+// if it does not exist yet.
+func (s *HostCordonStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostCordonObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostCordonObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostCordonStore) CreateAsync(
+	ctx context.Context,
+	obj *HostCordonObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostCordonObject with the given primary key.
+func (s *HostCordonStore) Get(
+	ctx context.Context,
+	hostname string,
+) (*HostCordonObject, error) {
+	obj := &HostCordonObject{
+		Hostname: hostname,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostCordonObjects of the given partition.
+func (s *HostCordonStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostCordonObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostCordonObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostCordonObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostCordonObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostCordonObject in the
+// database, or all its fields if none are given.
+func (s *HostCordonStore) Update(
+	ctx context.Context,
+	obj *HostCordonObject,
+	fields ...HostCordonField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostCordonObject with the given primary key.
+func (s *HostCordonStore) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	return s.client.Delete(ctx, &HostCordonObject{
+		Hostname: hostname,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostCordonObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostCordonStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostCordonObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostEventField is an updatable field of HostEventObject.
+type HostEventField string
+
+// Fields of HostEventObject which can be updated.
+const (
+	HostEventFieldMessage HostEventField = "Message"
+)
+
+// HostEventStore provides typed access to the host_events table.
+type HostEventStore struct {
+	client orm.Client
+}
+
+// NewHostEventStore returns a store using the given ORM client.
+func NewHostEventStore(client orm.Client) *HostEventStore {
+	return &HostEventStore{client: client}
+}
+
+// Create creates the HostEventObject in the database.
+func (s *HostEventStore) Create(
+	ctx context.Context,
+	obj *HostEventObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostEventObject in the database
+// if it does not exist yet.
+func (s *HostEventStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostEventObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostEventObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostEventStore) CreateAsync(
+	ctx context.Context,
+	obj *HostEventObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostEventObject with the given primary key.
+func (s *HostEventStore) Get(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+	eventType string,
+) (*HostEventObject, error) {
+	obj := &HostEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+		EventType: eventType,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostEventObjects of the given partition.
+func (s *HostEventStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostEventObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostEventObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostEventObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostEventObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostEventObject in the
+// database, or all its fields if none are given.
+func (s *HostEventStore) Update(
+	ctx context.Context,
+	obj *HostEventObject,
+	fields ...HostEventField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostEventObject with the given primary key.
+func (s *HostEventStore) Delete(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+	eventType string,
+) error {
+	return s.client.Delete(ctx, &HostEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+		EventType: eventType,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostEventObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostEventStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostEventObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostMaintenanceEventField is an updatable field of HostMaintenanceEventObject.
+type HostMaintenanceEventField string
+
+// Fields of HostMaintenanceEventObject which can be updated.
+const (
+	HostMaintenanceEventFieldFromState HostMaintenanceEventField = "FromState"
+	HostMaintenanceEventFieldToState   HostMaintenanceEventField = "ToState"
+)
+
+// HostMaintenanceEventStore provides typed access to the host_maintenance_events table.
+type HostMaintenanceEventStore struct {
+	client orm.Client
+}
+
+// NewHostMaintenanceEventStore returns a store using the given ORM client.
+func NewHostMaintenanceEventStore(client orm.Client) *HostMaintenanceEventStore {
+	return &HostMaintenanceEventStore{client: client}
+}
+
+// Create creates the HostMaintenanceEventObject in the database.
+func (s *HostMaintenanceEventStore) Create(
+	ctx context.Context,
+	obj *HostMaintenanceEventObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostMaintenanceEventObject in the database
+// if it does not exist yet.
+func (s *HostMaintenanceEventStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostMaintenanceEventObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostMaintenanceEventObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostMaintenanceEventStore) CreateAsync(
+	ctx context.Context,
+	obj *HostMaintenanceEventObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostMaintenanceEventObject with the given primary key.
+func (s *HostMaintenanceEventStore) Get(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) (*HostMaintenanceEventObject, error) {
+	obj := &HostMaintenanceEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostMaintenanceEventObjects of the given partition.
+func (s *HostMaintenanceEventStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostMaintenanceEventObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostMaintenanceEventObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostMaintenanceEventObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostMaintenanceEventObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostMaintenanceEventObject in the
+// database, or all its fields if none are given.
+func (s *HostMaintenanceEventStore) Update(
+	ctx context.Context,
+	obj *HostMaintenanceEventObject,
+	fields ...HostMaintenanceEventField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostMaintenanceEventObject with the given primary key.
+func (s *HostMaintenanceEventStore) Delete(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) error {
+	return s.client.Delete(ctx, &HostMaintenanceEventObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostMaintenanceEventObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostMaintenanceEventStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostMaintenanceEventObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostMaintenanceHistoryField is an updatable field of HostMaintenanceHistoryObject.
+type HostMaintenanceHistoryField string
+
+// Fields of HostMaintenanceHistoryObject which can be updated.
+const (
+	HostMaintenanceHistoryFieldFromState   HostMaintenanceHistoryField = "FromState"
+	HostMaintenanceHistoryFieldToState     HostMaintenanceHistoryField = "ToState"
+	HostMaintenanceHistoryFieldArchiveTime HostMaintenanceHistoryField = "ArchiveTime"
+)
+
+// HostMaintenanceHistoryStore provides typed access to the host_maintenance_history table.
+type HostMaintenanceHistoryStore struct {
+	client orm.Client
+}
+
+// NewHostMaintenanceHistoryStore returns a store using the given ORM client.
+func NewHostMaintenanceHistoryStore(client orm.Client) *HostMaintenanceHistoryStore {
+	return &HostMaintenanceHistoryStore{client: client}
+}
+
+// Create creates the HostMaintenanceHistoryObject in the database.
+func (s *HostMaintenanceHistoryStore) Create(
+	ctx context.Context,
+	obj *HostMaintenanceHistoryObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostMaintenanceHistoryObject in the database
+// if it does not exist yet.
+func (s *HostMaintenanceHistoryStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostMaintenanceHistoryObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostMaintenanceHistoryObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostMaintenanceHistoryStore) CreateAsync(
+	ctx context.Context,
+	obj *HostMaintenanceHistoryObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostMaintenanceHistoryObject with the given primary key.
+func (s *HostMaintenanceHistoryStore) Get(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) (*HostMaintenanceHistoryObject, error) {
+	obj := &HostMaintenanceHistoryObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostMaintenanceHistoryObjects of the given partition.
+func (s *HostMaintenanceHistoryStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostMaintenanceHistoryObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostMaintenanceHistoryObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostMaintenanceHistoryObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostMaintenanceHistoryObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostMaintenanceHistoryObject in the
+// database, or all its fields if none are given.
+func (s *HostMaintenanceHistoryStore) Update(
+	ctx context.Context,
+	obj *HostMaintenanceHistoryObject,
+	fields ...HostMaintenanceHistoryField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostMaintenanceHistoryObject with the given primary key.
+func (s *HostMaintenanceHistoryStore) Delete(
+	ctx context.Context,
+	hostname string,
+	eventTime time.Time,
+) error {
+	return s.client.Delete(ctx, &HostMaintenanceHistoryObject{
+		Hostname:  hostname,
+		EventTime: eventTime,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostMaintenanceHistoryObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostMaintenanceHistoryStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostMaintenanceHistoryObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostReservationField is an updatable field of HostReservationObject.
+type HostReservationField string
+
+// Fields of HostReservationObject which can be updated.
+const (
+	HostReservationFieldRole         HostReservationField = "Role"
+	HostReservationFieldSpec         HostReservationField = "Spec"
+	HostReservationFieldVolumeID     HostReservationField = "VolumeID"
+	HostReservationFieldJobID        HostReservationField = "JobID"
+	HostReservationFieldInstanceID   HostReservationField = "InstanceID"
+	HostReservationFieldCreationTime HostReservationField = "CreationTime"
+)
+
+// HostReservationStore provides typed access to the host_reservations table.
+type HostReservationStore struct {
+	client orm.Client
+}
+
+// NewHostReservationStore returns a store using the given ORM client.
+func NewHostReservationStore(client orm.Client) *HostReservationStore {
+	return &HostReservationStore{client: client}
+}
+
+// Create creates the HostReservationObject in the database.
+func (s *HostReservationStore) Create(
+	ctx context.Context,
+	obj *HostReservationObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostReservationObject in the database
+// if it does not exist yet.
+func (s *HostReservationStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostReservationObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostReservationObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostReservationStore) CreateAsync(
+	ctx context.Context,
+	obj *HostReservationObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostReservationObject with the given primary key.
+func (s *HostReservationStore) Get(
+	ctx context.Context,
+	hostname string,
+	reservationID string,
+) (*HostReservationObject, error) {
+	obj := &HostReservationObject{
+		Hostname:      hostname,
+		ReservationID: reservationID,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostReservationObjects of the given partition.
+func (s *HostReservationStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostReservationObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostReservationObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostReservationObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostReservationObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostReservationObject in the
+// database, or all its fields if none are given.
+func (s *HostReservationStore) Update(
+	ctx context.Context,
+	obj *HostReservationObject,
+	fields ...HostReservationField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostReservationObject with the given primary key.
+func (s *HostReservationStore) Delete(
+	ctx context.Context,
+	hostname string,
+	reservationID string,
+) error {
+	return s.client.Delete(ctx, &HostReservationObject{
+		Hostname:      hostname,
+		ReservationID: reservationID,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostReservationObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostReservationStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostReservationObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// HostTasksField is an updatable field of HostTasksObject.
+type HostTasksField string
+
+// Fields of HostTasksObject which can be updated.
+const (
+	HostTasksFieldTaskIDs    HostTasksField = "TaskIDs"
+	HostTasksFieldUpdateTime HostTasksField = "UpdateTime"
+)
+
+// HostTasksStore provides typed access to the host_tasks table.
+type HostTasksStore struct {
+	client orm.Client
+}
+
+// NewHostTasksStore returns a store using the given ORM client.
+func NewHostTasksStore(client orm.Client) *HostTasksStore {
+	return &HostTasksStore{client: client}
+}
+
+// Create creates the HostTasksObject in the database.
+func (s *HostTasksStore) Create(
+	ctx context.Context,
+	obj *HostTasksObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the HostTasksObject in the database
+// if it does not exist yet.
+func (s *HostTasksStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *HostTasksObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the HostTasksObject to be created in the
+// database in a batch with other objects of its table.
+func (s *HostTasksStore) CreateAsync(
+	ctx context.Context,
+	obj *HostTasksObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the HostTasksObject with the given primary key.
+func (s *HostTasksStore) Get(
+	ctx context.Context,
+	hostname string,
+) (*HostTasksObject, error) {
+	obj := &HostTasksObject{
+		Hostname: hostname,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the HostTasksObjects of the given partition.
+func (s *HostTasksStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*HostTasksObject, error) {
+	objs, err := s.client.GetAll(ctx, &HostTasksObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HostTasksObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*HostTasksObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the HostTasksObject in the
+// database, or all its fields if none are given.
+func (s *HostTasksStore) Update(
+	ctx context.Context,
+	obj *HostTasksObject,
+	fields ...HostTasksField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the HostTasksObject with the given primary key.
+func (s *HostTasksStore) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	return s.client.Delete(ctx, &HostTasksObject{
+		Hostname: hostname,
+	})
+}
+
+// DeleteAllInPartition deletes all the HostTasksObjects of the
+// given partition and returns the number of deleted objects.
+func (s *HostTasksStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &HostTasksObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// IdempotencyKeyField is an updatable field of IdempotencyKeyObject.
+type IdempotencyKeyField string
+
+// Fields of IdempotencyKeyObject which can be updated.
+const (
+	IdempotencyKeyFieldRequestHash IdempotencyKeyField = "RequestHash"
+	IdempotencyKeyFieldResponse    IdempotencyKeyField = "Response"
+	IdempotencyKeyFieldCreateTime  IdempotencyKeyField = "CreateTime"
+)
+
+// IdempotencyKeyStore provides typed access to the idempotency_keys table.
+type IdempotencyKeyStore struct {
+	client orm.Client
+}
+
+// NewIdempotencyKeyStore returns a store using the given ORM client.
+func NewIdempotencyKeyStore(client orm.Client) *IdempotencyKeyStore {
+	return &IdempotencyKeyStore{client: client}
+}
+
+// Create creates the IdempotencyKeyObject in the database.
+func (s *IdempotencyKeyStore) Create(
+	ctx context.Context,
+	obj *IdempotencyKeyObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the IdempotencyKeyObject in the database
+// if it does not exist yet.
+func (s *IdempotencyKeyStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *IdempotencyKeyObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the IdempotencyKeyObject to be created in the
+// database in a batch with other objects of its table.
+func (s *IdempotencyKeyStore) CreateAsync(
+	ctx context.Context,
+	obj *IdempotencyKeyObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the IdempotencyKeyObject with the given primary key.
+func (s *IdempotencyKeyStore) Get(
+	ctx context.Context,
+	key string,
+	procedure string,
+) (*IdempotencyKeyObject, error) {
+	obj := &IdempotencyKeyObject{
+		Key:       key,
+		Procedure: procedure,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the IdempotencyKeyObjects of the given partition.
+func (s *IdempotencyKeyStore) GetAll(
+	ctx context.Context,
+	key string,
+) ([]*IdempotencyKeyObject, error) {
+	objs, err := s.client.GetAll(ctx, &IdempotencyKeyObject{
+		Key: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*IdempotencyKeyObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*IdempotencyKeyObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the IdempotencyKeyObject in the
+// database, or all its fields if none are given.
+func (s *IdempotencyKeyStore) Update(
+	ctx context.Context,
+	obj *IdempotencyKeyObject,
+	fields ...IdempotencyKeyField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the IdempotencyKeyObject with the given primary key.
+func (s *IdempotencyKeyStore) Delete(
+	ctx context.Context,
+	key string,
+	procedure string,
+) error {
+	return s.client.Delete(ctx, &IdempotencyKeyObject{
+		Key:       key,
+		Procedure: procedure,
+	})
+}
+
+// DeleteAllInPartition deletes all the IdempotencyKeyObjects of the
+// given partition and returns the number of deleted objects.
+func (s *IdempotencyKeyStore) DeleteAllInPartition(
+	ctx context.Context,
+	key string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &IdempotencyKeyObject{
+		Key: key,
+	}, opts...)
+}
+
+// JobConfigField is an updatable field of JobConfigObject.
+type JobConfigField string
+
+// Fields of JobConfigObject which can be updated.
+const (
+	JobConfigFieldConfig       JobConfigField = "Config"
+	JobConfigFieldConfigAddOn  JobConfigField = "ConfigAddOn"
+	JobConfigFieldCreationTime JobConfigField = "CreationTime"
+)
+
+// JobConfigStore provides typed access to the job_config table.
+type JobConfigStore struct {
+	client orm.Client
+}
+
+// NewJobConfigStore returns a store using the given ORM client.
+func NewJobConfigStore(client orm.Client) *JobConfigStore {
+	return &JobConfigStore{client: client}
+}
+
+// Create creates the JobConfigObject in the database.
+func (s *JobConfigStore) Create(
+	ctx context.Context,
+	obj *JobConfigObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the JobConfigObject in the database
+// if it does not exist yet.
+func (s *JobConfigStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *JobConfigObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the JobConfigObject to be created in the
+// database in a batch with other objects of its table.
+func (s *JobConfigStore) CreateAsync(
+	ctx context.Context,
+	obj *JobConfigObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the JobConfigObject with the given primary key.
+func (s *JobConfigStore) Get(
+	ctx context.Context,
+	jobID string,
+	version uint64,
+) (*JobConfigObject, error) {
+	obj := &JobConfigObject{
+		JobID:   jobID,
+		Version: version,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the JobConfigObjects of the given partition.
+func (s *JobConfigStore) GetAll(
+	ctx context.Context,
+	jobID string,
+) ([]*JobConfigObject, error) {
+	objs, err := s.client.GetAll(ctx, &JobConfigObject{
+		JobID: jobID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*JobConfigObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*JobConfigObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the JobConfigObject in the
+// database, or all its fields if none are given.
+func (s *JobConfigStore) Update(
+	ctx context.Context,
+	obj *JobConfigObject,
+	fields ...JobConfigField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the JobConfigObject with the given primary key.
+func (s *JobConfigStore) Delete(
+	ctx context.Context,
+	jobID string,
+	version uint64,
+) error {
+	return s.client.Delete(ctx, &JobConfigObject{
+		JobID:   jobID,
+		Version: version,
+	})
+}
+
+// DeleteAllInPartition deletes all the JobConfigObjects of the
+// given partition and returns the number of deleted objects.
+func (s *JobConfigStore) DeleteAllInPartition(
+	ctx context.Context,
+	jobID string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &JobConfigObject{
+		JobID: jobID,
+	}, opts...)
+}
+
+// JobIndexField is an updatable field of JobIndexObject.
+type JobIndexField string
+
+// Fields of JobIndexObject which can be updated.
+const (
+	JobIndexFieldJobType        JobIndexField = "JobType"
+	JobIndexFieldName           JobIndexField = "Name"
+	JobIndexFieldOwner          JobIndexField = "Owner"
+	JobIndexFieldRespoolID      JobIndexField = "RespoolID"
+	JobIndexFieldConfig         JobIndexField = "Config"
+	JobIndexFieldInstanceCount  JobIndexField = "InstanceCount"
+	JobIndexFieldLabels         JobIndexField = "Labels"
+	JobIndexFieldRuntimeInfo    JobIndexField = "RuntimeInfo"
+	JobIndexFieldState          JobIndexField = "State"
+	JobIndexFieldCreationTime   JobIndexField = "CreationTime"
+	JobIndexFieldStartTime      JobIndexField = "StartTime"
+	JobIndexFieldCompletionTime JobIndexField = "CompletionTime"
+	JobIndexFieldUpdateTime     JobIndexField = "UpdateTime"
+	JobIndexFieldSLA            JobIndexField = "SLA"
+)
+
+// JobIndexStore provides typed access to the job_index table.
+type JobIndexStore struct {
+	client orm.Client
+}
+
+// NewJobIndexStore returns a store using the given ORM client.
+func NewJobIndexStore(client orm.Client) *JobIndexStore {
+	return &JobIndexStore{client: client}
+}
+
+// Create creates the JobIndexObject in the database.
+func (s *JobIndexStore) Create(
+	ctx context.Context,
+	obj *JobIndexObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the JobIndexObject in the database
+// if it does not exist yet.
+func (s *JobIndexStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *JobIndexObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the JobIndexObject to be created in the
+// database in a batch with other objects of its table.
+func (s *JobIndexStore) CreateAsync(
+	ctx context.Context,
+	obj *JobIndexObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the JobIndexObject with the given primary key.
+func (s *JobIndexStore) Get(
+	ctx context.Context,
+	jobID string,
+) (*JobIndexObject, error) {
+	obj := &JobIndexObject{
+		JobID: jobID,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the JobIndexObjects of the given partition.
+func (s *JobIndexStore) GetAll(
+	ctx context.Context,
+	jobID string,
+) ([]*JobIndexObject, error) {
+	objs, err := s.client.GetAll(ctx, &JobIndexObject{
+		JobID: jobID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*JobIndexObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*JobIndexObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the JobIndexObject in the
+// database, or all its fields if none are given.
+func (s *JobIndexStore) Update(
+	ctx context.Context,
+	obj *JobIndexObject,
+	fields ...JobIndexField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the JobIndexObject with the given primary key.
+func (s *JobIndexStore) Delete(
+	ctx context.Context,
+	jobID string,
+) error {
+	return s.client.Delete(ctx, &JobIndexObject{
+		JobID: jobID,
+	})
+}
+
+// DeleteAllInPartition deletes all the JobIndexObjects of the
+// given partition and returns the number of deleted objects.
+func (s *JobIndexStore) DeleteAllInPartition(
+	ctx context.Context,
+	jobID string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &JobIndexObject{
+		JobID: jobID,
+	}, opts...)
+}
+
+// JobNameToIDField is an updatable field of JobNameToIDObject.
+type JobNameToIDField string
+
+// Fields of JobNameToIDObject which can be updated.
+const (
+	JobNameToIDFieldJobID JobNameToIDField = "JobID"
+)
+
+// JobNameToIDStore provides typed access to the job_name_to_id table.
+type JobNameToIDStore struct {
+	client orm.Client
+}
+
+// NewJobNameToIDStore returns a store using the given ORM client.
+func NewJobNameToIDStore(client orm.Client) *JobNameToIDStore {
+	return &JobNameToIDStore{client: client}
+}
+
+// Create creates the JobNameToIDObject in the database.
+func (s *JobNameToIDStore) Create(
+	ctx context.Context,
+	obj *JobNameToIDObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the JobNameToIDObject in the database
+// if it does not exist yet.
+func (s *JobNameToIDStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *JobNameToIDObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the JobNameToIDObject to be created in the
+// database in a batch with other objects of its table.
+func (s *JobNameToIDStore) CreateAsync(
+	ctx context.Context,
+	obj *JobNameToIDObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the JobNameToIDObject with the given primary key.
+func (s *JobNameToIDStore) Get(
+	ctx context.Context,
+	jobName string,
+	updateTime gocql.UUID,
+) (*JobNameToIDObject, error) {
+	obj := &JobNameToIDObject{
+		JobName:    jobName,
+		UpdateTime: updateTime,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the JobNameToIDObjects of the given partition.
+func (s *JobNameToIDStore) GetAll(
+	ctx context.Context,
+	jobName string,
+) ([]*JobNameToIDObject, error) {
+	objs, err := s.client.GetAll(ctx, &JobNameToIDObject{
+		JobName: jobName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*JobNameToIDObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*JobNameToIDObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the JobNameToIDObject in the
+// database, or all its fields if none are given.
+func (s *JobNameToIDStore) Update(
+	ctx context.Context,
+	obj *JobNameToIDObject,
+	fields ...JobNameToIDField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the JobNameToIDObject with the given primary key.
+func (s *JobNameToIDStore) Delete(
+	ctx context.Context,
+	jobName string,
+	updateTime gocql.UUID,
+) error {
+	return s.client.Delete(ctx, &JobNameToIDObject{
+		JobName:    jobName,
+		UpdateTime: updateTime,
+	})
+}
+
+// DeleteAllInPartition deletes all the JobNameToIDObjects of the
+// given partition and returns the number of deleted objects.
+func (s *JobNameToIDStore) DeleteAllInPartition(
+	ctx context.Context,
+	jobName string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &JobNameToIDObject{
+		JobName: jobName,
+	}, opts...)
+}
+
+// MaintenanceApprovalField is an updatable field of MaintenanceApprovalObject.
+type MaintenanceApprovalField string
+
+// Fields of MaintenanceApprovalObject which can be updated.
+const (
+	MaintenanceApprovalFieldApproval   MaintenanceApprovalField = "Approval"
+	MaintenanceApprovalFieldUpdateTime MaintenanceApprovalField = "UpdateTime"
+)
+
+// MaintenanceApprovalStore provides typed access to the maintenance_approvals table.
+type MaintenanceApprovalStore struct {
+	client orm.Client
+}
+
+// NewMaintenanceApprovalStore returns a store using the given ORM client.
+func NewMaintenanceApprovalStore(client orm.Client) *MaintenanceApprovalStore {
+	return &MaintenanceApprovalStore{client: client}
+}
+
+// Create creates the MaintenanceApprovalObject in the database.
+func (s *MaintenanceApprovalStore) Create(
+	ctx context.Context,
+	obj *MaintenanceApprovalObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the MaintenanceApprovalObject in the database
+// if it does not exist yet.
+func (s *MaintenanceApprovalStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *MaintenanceApprovalObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the MaintenanceApprovalObject to be created in the
+// database in a batch with other objects of its table.
+func (s *MaintenanceApprovalStore) CreateAsync(
+	ctx context.Context,
+	obj *MaintenanceApprovalObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the MaintenanceApprovalObject with the given primary key.
+func (s *MaintenanceApprovalStore) Get(
+	ctx context.Context,
+	hostname string,
+) (*MaintenanceApprovalObject, error) {
+	obj := &MaintenanceApprovalObject{
+		Hostname: hostname,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the MaintenanceApprovalObjects of the given partition.
+func (s *MaintenanceApprovalStore) GetAll(
+	ctx context.Context,
+	hostname string,
+) ([]*MaintenanceApprovalObject, error) {
+	objs, err := s.client.GetAll(ctx, &MaintenanceApprovalObject{
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*MaintenanceApprovalObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*MaintenanceApprovalObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the MaintenanceApprovalObject in the
+// database, or all its fields if none are given.
+func (s *MaintenanceApprovalStore) Update(
+	ctx context.Context,
+	obj *MaintenanceApprovalObject,
+	fields ...MaintenanceApprovalField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the MaintenanceApprovalObject with the given primary key.
+func (s *MaintenanceApprovalStore) Delete(
+	ctx context.Context,
+	hostname string,
+) error {
+	return s.client.Delete(ctx, &MaintenanceApprovalObject{
+		Hostname: hostname,
+	})
+}
+
+// DeleteAllInPartition deletes all the MaintenanceApprovalObjects of the
+// given partition and returns the number of deleted objects.
+func (s *MaintenanceApprovalStore) DeleteAllInPartition(
+	ctx context.Context,
+	hostname string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &MaintenanceApprovalObject{
+		Hostname: hostname,
+	}, opts...)
+}
+
+// MaintenancePolicyField is an updatable field of MaintenancePolicyObject.
+type MaintenancePolicyField string
+
+// Fields of MaintenancePolicyObject which can be updated.
+const (
+	MaintenancePolicyFieldPolicy     MaintenancePolicyField = "Policy"
+	MaintenancePolicyFieldUpdateTime MaintenancePolicyField = "UpdateTime"
+)
+
+// MaintenancePolicyStore provides typed access to the maintenance_policies table.
+type MaintenancePolicyStore struct {
+	client orm.Client
+}
+
+// NewMaintenancePolicyStore returns a store using the given ORM client.
+func NewMaintenancePolicyStore(client orm.Client) *MaintenancePolicyStore {
+	return &MaintenancePolicyStore{client: client}
+}
+
+// Create creates the MaintenancePolicyObject in the database.
+func (s *MaintenancePolicyStore) Create(
+	ctx context.Context,
+	obj *MaintenancePolicyObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the MaintenancePolicyObject in the database
+// if it does not exist yet.
+func (s *MaintenancePolicyStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *MaintenancePolicyObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the MaintenancePolicyObject to be created in the
+// database in a batch with other objects of its table.
+func (s *MaintenancePolicyStore) CreateAsync(
+	ctx context.Context,
+	obj *MaintenancePolicyObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the MaintenancePolicyObject with the given primary key.
+func (s *MaintenancePolicyStore) Get(
+	ctx context.Context,
+	policySet string,
+	name string,
+) (*MaintenancePolicyObject, error) {
+	obj := &MaintenancePolicyObject{
+		PolicySet: policySet,
+		Name:      name,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the MaintenancePolicyObjects of the given partition.
+func (s *MaintenancePolicyStore) GetAll(
+	ctx context.Context,
+	policySet string,
+) ([]*MaintenancePolicyObject, error) {
+	objs, err := s.client.GetAll(ctx, &MaintenancePolicyObject{
+		PolicySet: policySet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*MaintenancePolicyObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*MaintenancePolicyObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the MaintenancePolicyObject in the
+// database, or all its fields if none are given.
+func (s *MaintenancePolicyStore) Update(
+	ctx context.Context,
+	obj *MaintenancePolicyObject,
+	fields ...MaintenancePolicyField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the MaintenancePolicyObject with the given primary key.
+func (s *MaintenancePolicyStore) Delete(
+	ctx context.Context,
+	policySet string,
+	name string,
+) error {
+	return s.client.Delete(ctx, &MaintenancePolicyObject{
+		PolicySet: policySet,
+		Name:      name,
+	})
+}
+
+// DeleteAllInPartition deletes all the MaintenancePolicyObjects of the
+// given partition and returns the number of deleted objects.
+func (s *MaintenancePolicyStore) DeleteAllInPartition(
+	ctx context.Context,
+	policySet string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &MaintenancePolicyObject{
+		PolicySet: policySet,
+	}, opts...)
+}
+
+// PodEventsField is an updatable field of PodEventsObject.
+type PodEventsField string
+
+// Fields of PodEventsObject which can be updated.
+const (
+	PodEventsFieldActualState          PodEventsField = "ActualState"
+	PodEventsFieldAgentID              PodEventsField = "AgentID"
+	PodEventsFieldConfigVersion        PodEventsField = "ConfigVersion"
+	PodEventsFieldDesiredConfigVersion PodEventsField = "DesiredConfigVersion"
+	PodEventsFieldDesiredRunID         PodEventsField = "DesiredRunID"
+	PodEventsFieldGoalState            PodEventsField = "GoalState"
+	PodEventsFieldHealthy              PodEventsField = "Healthy"
+	PodEventsFieldHostname             PodEventsField = "Hostname"
+	PodEventsFieldMessage              PodEventsField = "Message"
+	PodEventsFieldPodStatus            PodEventsField = "PodStatus"
+	PodEventsFieldPreviousRunID        PodEventsField = "PreviousRunID"
+	PodEventsFieldReason               PodEventsField = "Reason"
+	PodEventsFieldVolumeID             PodEventsField = "VolumeID"
+)
+
+// PodEventsStore provides typed access to the pod_events table.
+type PodEventsStore struct {
+	client orm.Client
+}
+
+// NewPodEventsStore returns a store using the given ORM client.
+func NewPodEventsStore(client orm.Client) *PodEventsStore {
+	return &PodEventsStore{client: client}
+}
+
+// Create creates the PodEventsObject in the database.
+func (s *PodEventsStore) Create(
+	ctx context.Context,
+	obj *PodEventsObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the PodEventsObject in the database
+// if it does not exist yet.
+func (s *PodEventsStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *PodEventsObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the PodEventsObject to be created in the
+// database in a batch with other objects of its table.
+func (s *PodEventsStore) CreateAsync(
+	ctx context.Context,
+	obj *PodEventsObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the PodEventsObject with the given primary key.
+func (s *PodEventsStore) Get(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	runID uint64,
+	updateTime gocql.UUID,
+) (*PodEventsObject, error) {
+	obj := &PodEventsObject{
+		JobID:      jobID,
+		InstanceID: instanceID,
+		RunID:      runID,
+		UpdateTime: updateTime,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the PodEventsObjects of the given partition.
+func (s *PodEventsStore) GetAll(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+) ([]*PodEventsObject, error) {
+	objs, err := s.client.GetAll(ctx, &PodEventsObject{
+		JobID:      jobID,
+		InstanceID: instanceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*PodEventsObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*PodEventsObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the PodEventsObject in the
+// database, or all its fields if none are given.
+func (s *PodEventsStore) Update(
+	ctx context.Context,
+	obj *PodEventsObject,
+	fields ...PodEventsField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the PodEventsObject with the given primary key.
+func (s *PodEventsStore) Delete(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	runID uint64,
+	updateTime gocql.UUID,
+) error {
+	return s.client.Delete(ctx, &PodEventsObject{
+		JobID:      jobID,
+		InstanceID: instanceID,
+		RunID:      runID,
+		UpdateTime: updateTime,
+	})
+}
+
+// DeleteAllInPartition deletes all the PodEventsObjects of the
+// given partition and returns the number of deleted objects.
+func (s *PodEventsStore) DeleteAllInPartition(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &PodEventsObject{
+		JobID:      jobID,
+		InstanceID: instanceID,
+	}, opts...)
+}
+
+// SecretInfoField is an updatable field of SecretInfoObject.
+type SecretInfoField string
+
+// Fields of SecretInfoObject which can be updated.
+const (
+	SecretInfoFieldJobID        SecretInfoField = "JobID"
+	SecretInfoFieldPath         SecretInfoField = "Path"
+	SecretInfoFieldData         SecretInfoField = "Data"
+	SecretInfoFieldCreationTime SecretInfoField = "CreationTime"
+	SecretInfoFieldVersion      SecretInfoField = "Version"
+)
+
+// SecretInfoStore provides typed access to the secret_info table.
+type SecretInfoStore struct {
+	client orm.Client
+}
+
+// NewSecretInfoStore returns a store using the given ORM client.
+func NewSecretInfoStore(client orm.Client) *SecretInfoStore {
+	return &SecretInfoStore{client: client}
+}
+
+// Create creates the SecretInfoObject in the database.
+func (s *SecretInfoStore) Create(
+	ctx context.Context,
+	obj *SecretInfoObject,
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the SecretInfoObject in the database
+// if it does not exist yet.
+func (s *SecretInfoStore) CreateIfNotExists(
+	ctx context.Context,
+	obj *SecretInfoObject,
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the SecretInfoObject to be created in the
+// database in a batch with other objects of its table.
+func (s *SecretInfoStore) CreateAsync(
+	ctx context.Context,
+	obj *SecretInfoObject,
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the SecretInfoObject with the given primary key.
+func (s *SecretInfoStore) Get(
+	ctx context.Context,
+	secretID string,
+	valid bool,
+) (*SecretInfoObject, error) {
+	obj := &SecretInfoObject{
+		SecretID: secretID,
+		Valid:    valid,
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the SecretInfoObjects of the given partition.
+func (s *SecretInfoStore) GetAll(
+	ctx context.Context,
+	secretID string,
+) ([]*SecretInfoObject, error) {
+	objs, err := s.client.GetAll(ctx, &SecretInfoObject{
+		SecretID: secretID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*SecretInfoObject, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*SecretInfoObject))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the SecretInfoObject in the
+// database, or all its fields if none are given.
+func (s *SecretInfoStore) Update(
+	ctx context.Context,
+	obj *SecretInfoObject,
+	fields ...SecretInfoField,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the SecretInfoObject with the given primary key.
+func (s *SecretInfoStore) Delete(
+	ctx context.Context,
+	secretID string,
+	valid bool,
+) error {
+	return s.client.Delete(ctx, &SecretInfoObject{
+		SecretID: secretID,
+		Valid:    valid,
+	})
+}
+
+// DeleteAllInPartition deletes all the SecretInfoObjects of the
+// given partition and returns the number of deleted objects.
+func (s *SecretInfoStore) DeleteAllInPartition(
+	ctx context.Context,
+	secretID string,
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &SecretInfoObject{
+		SecretID: secretID,
+	}, opts...)
+}
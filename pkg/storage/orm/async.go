@@ -0,0 +1,464 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Overflow policies of the objects written with CreateAsync when the
+// buffer of their table is full.
+const (
+	// AsyncDrop drops the object, CreateAsync fails right away
+	AsyncDrop = "drop"
+	// AsyncBlock blocks CreateAsync until the buffer is flushed or the
+	// context of the write is done
+	AsyncBlock = "block"
+)
+
+const (
+	// DefaultAsyncFlushInterval is the default interval between the
+	// periodic flushes of the objects written with CreateAsync.
+	DefaultAsyncFlushInterval = time.Second
+	// DefaultAsyncBatchSize is the default number of rows of a table
+	// written in a single batch.
+	DefaultAsyncBatchSize = 100
+	// DefaultAsyncBufferSize is the default max number of rows of a
+	// table buffered by CreateAsync.
+	DefaultAsyncBufferSize = 10000
+	// DefaultAsyncMaxAttempts is the default number of batches a row
+	// written with CreateAsync is written in before it is lost.
+	DefaultAsyncMaxAttempts = 3
+)
+
+// Batcher is implemented by the connectors which can create several rows
+// of a table in a few round trips. The objects written with CreateAsync
+// are created one row at a time with connectors which are not a Batcher.
+type Batcher interface {
+	// CreateBatch creates the rows in the DB for the base object. If it
+	// fails, some of the rows may have been created, which are overwritten
+	// by the same rows when the batch is retried.
+	CreateBatch(
+		ctx context.Context,
+		e *base.Definition,
+		rows [][]base.Column,
+	) error
+}
+
+// AsyncWriteConfig is the config of the objects written with CreateAsync.
+type AsyncWriteConfig struct {
+	// FlushInterval is the interval between the periodic flushes of the
+	// buffered objects, DefaultAsyncFlushInterval if zero.
+	FlushInterval time.Duration
+	// BatchSize is the number of rows of a table written in a single
+	// batch, DefaultAsyncBatchSize if zero. A table is flushed ahead of
+	// the next periodic flush once a batch worth of rows is buffered.
+	BatchSize int
+	// BufferSize is the max number of rows of a table buffered until they
+	// are flushed, DefaultAsyncBufferSize if zero.
+	BufferSize int
+	// OverflowPolicy is what happens to the objects written when the
+	// buffer of their table is full, AsyncDrop or AsyncBlock, AsyncDrop if
+	// empty.
+	OverflowPolicy string
+	// MaxAttempts is the number of batches a row is written in before it
+	// is lost, DefaultAsyncMaxAttempts if zero. The rows of a failed batch
+	// are buffered again to be written by the next flush.
+	MaxAttempts int
+}
+
+// asyncMetrics are the metrics of the objects of a table written with
+// CreateAsync.
+type asyncMetrics struct {
+	// number of rows buffered
+	writes tally.Counter
+	// number of rows dropped because the buffer was full
+	dropped tally.Counter
+	// number of rows written to the DB
+	flushed tally.Counter
+	// number of rows buffered again because their batch failed
+	retried tally.Counter
+	// number of rows lost because their batches kept failing
+	failed tally.Counter
+}
+
+func newAsyncMetrics(scope tally.Scope, table string) *asyncMetrics {
+	s := scope.Tagged(map[string]string{"table": table})
+	return &asyncMetrics{
+		writes:  s.Counter("async_writes"),
+		dropped: s.Counter("async_writes_dropped"),
+		flushed: s.Counter("async_rows_flushed"),
+		retried: s.Counter("async_rows_retried"),
+		failed:  s.Counter("async_rows_failed"),
+	}
+}
+
+// asyncRow is a row written with CreateAsync
+type asyncRow struct {
+	columns []base.Column
+	// number of batches of the row which failed
+	attempts int
+}
+
+// asyncBuffer is the buffer of the rows of a table written with
+// CreateAsync.
+type asyncBuffer struct {
+	table   *Table
+	metrics *asyncMetrics
+	rows    []asyncRow
+	// closed when the rows are taken out of the buffer to be flushed
+	flushed chan struct{}
+}
+
+// asyncWriter buffers the rows written with CreateAsync by table, and
+// writes them in batches periodically, once a batch is full, or when the
+// buffers are flushed explicitly.
+type asyncWriter struct {
+	sync.Mutex
+
+	client *client
+	config AsyncWriteConfig
+	scope  tally.Scope
+	// buffers of the rows by table name
+	buffers map[string]*asyncBuffer
+	// whether the flush loop is running
+	started bool
+	// whether the writer is closed, no row is buffered once it is
+	closed bool
+	// wakes up the flush loop once a batch is full
+	kick chan struct{}
+	// stops the flush loop, which closes stopped once it returns
+	stop    chan struct{}
+	stopped chan struct{}
+
+	// serializes the flushes so that the rows being written are bounded
+	// by the size of the buffers
+	flushLock sync.Mutex
+}
+
+func newAsyncWriter(
+	c *client,
+	config AsyncWriteConfig,
+	scope tally.Scope,
+) (*asyncWriter, error) {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultAsyncFlushInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultAsyncBatchSize
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultAsyncBufferSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultAsyncMaxAttempts
+	}
+	switch config.OverflowPolicy {
+	case "":
+		config.OverflowPolicy = AsyncDrop
+	case AsyncDrop, AsyncBlock:
+	default:
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid async write overflow policy %q", config.OverflowPolicy)
+	}
+	return &asyncWriter{
+		client:  c,
+		config:  config,
+		scope:   scope,
+		buffers: make(map[string]*asyncBuffer),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// enqueue buffers a row of the table, starting the flush loop on the first
+// row. It fails if the buffer of the table is full and the overflow policy
+// is AsyncDrop, and otherwise waits for the buffer to be flushed.
+func (w *asyncWriter) enqueue(
+	ctx context.Context,
+	table *Table,
+	row []base.Column,
+) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.started && !w.closed {
+		w.started = true
+		go w.run()
+	}
+	buf, ok := w.buffers[table.Name]
+	if !ok {
+		buf = &asyncBuffer{
+			table:   table,
+			metrics: newAsyncMetrics(w.scope, table.Name),
+			flushed: make(chan struct{}),
+		}
+		w.buffers[table.Name] = buf
+	}
+
+	for {
+		if w.closed {
+			return yarpcerrors.FailedPreconditionErrorf(
+				"async writes of %s after the client is closed", table.Name)
+		}
+		if len(buf.rows) < w.config.BufferSize {
+			break
+		}
+		if w.config.OverflowPolicy != AsyncBlock {
+			buf.metrics.dropped.Inc(1)
+			return yarpcerrors.ResourceExhaustedErrorf(
+				"async write buffer of %s is full", table.Name)
+		}
+
+		flushed := buf.flushed
+		w.wakeUp()
+		w.Unlock()
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			w.Lock()
+			return ctx.Err()
+		}
+		w.Lock()
+	}
+
+	buf.rows = append(buf.rows, asyncRow{columns: row})
+	buf.metrics.writes.Inc(1)
+	if len(buf.rows) >= w.config.BatchSize {
+		w.wakeUp()
+	}
+	return nil
+}
+
+// wakeUp makes the flush loop flush the buffers without waiting for the
+// next periodic flush.
+func (w *asyncWriter) wakeUp() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// run flushes the buffers periodically, or once woken up, until stopped.
+func (w *asyncWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		if err := w.flush(context.Background()); err != nil {
+			log.WithError(err).Warn("Failed to flush async ORM writes")
+		}
+	}
+}
+
+// flush writes the rows of every buffer in batches. The rows of a failed
+// batch are buffered again, unless they ran out of attempts. It returns the
+// first error.
+func (w *asyncWriter) flush(ctx context.Context) error {
+	w.flushLock.Lock()
+	defer w.flushLock.Unlock()
+
+	// take the rows out of the buffers, which unblocks the writers
+	w.Lock()
+	var pending []*asyncBuffer
+	for _, buf := range w.buffers {
+		if len(buf.rows) == 0 {
+			continue
+		}
+		pending = append(pending, &asyncBuffer{
+			table:   buf.table,
+			metrics: buf.metrics,
+			rows:    buf.rows,
+		})
+		buf.rows = nil
+		close(buf.flushed)
+		buf.flushed = make(chan struct{})
+	}
+	w.Unlock()
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].table.Name < pending[j].table.Name
+	})
+
+	var firstErr error
+	for _, buf := range pending {
+		// rows which cannot be batched are written, and retried, one by one
+		batchSize := w.config.BatchSize
+		if !w.client.canBatch(buf.table) {
+			batchSize = 1
+		}
+
+		var retries []asyncRow
+		for start := 0; start < len(buf.rows); start += batchSize {
+			end := start + batchSize
+			if end > len(buf.rows) {
+				end = len(buf.rows)
+			}
+			batch := buf.rows[start:end]
+			rows := make([][]base.Column, 0, len(batch))
+			for _, row := range batch {
+				rows = append(rows, row.columns)
+			}
+			if err := w.client.createBatch(ctx, buf.table, rows); err != nil {
+				log.WithError(err).
+					WithField("table", buf.table.Name).
+					WithField("rows", len(batch)).
+					Warn("Failed to write batch of async ORM writes")
+				if firstErr == nil {
+					firstErr = err
+				}
+				for _, row := range batch {
+					row.attempts++
+					if row.attempts < w.config.MaxAttempts {
+						retries = append(retries, row)
+					} else {
+						buf.metrics.failed.Inc(1)
+					}
+				}
+				continue
+			}
+			buf.metrics.flushed.Inc(int64(len(batch)))
+		}
+		w.rebuffer(buf, retries)
+	}
+	return firstErr
+}
+
+// rebuffer puts the rows of failed batches back in front of the buffer of
+// their table, to be written by the next flush. The oldest rows are lost if
+// the buffer was filled up in the meantime.
+func (w *asyncWriter) rebuffer(pending *asyncBuffer, rows []asyncRow) {
+	if len(rows) == 0 {
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	buf := w.buffers[pending.table.Name]
+	room := w.config.BufferSize - len(buf.rows)
+	if room < 0 {
+		room = 0
+	}
+	if len(rows) > room {
+		pending.metrics.failed.Inc(int64(len(rows) - room))
+		rows = rows[len(rows)-room:]
+	}
+	pending.metrics.retried.Inc(int64(len(rows)))
+	buf.rows = append(rows, buf.rows...)
+}
+
+// close stops the flush loop and flushes the buffers until they are
+// written or run out of attempts.
+func (w *asyncWriter) close(ctx context.Context) error {
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return nil
+	}
+	w.closed = true
+	started := w.started
+	w.Unlock()
+
+	if started {
+		close(w.stop)
+		<-w.stopped
+	}
+
+	// the rows of failed batches are written again until they run out of
+	// attempts
+	var err error
+	for i := 0; i < w.config.MaxAttempts; i++ {
+		if err = w.flush(ctx); err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// canBatch returns true if the rows of the table are created in batches,
+// which requires the connector to be a Batcher. Rows of objects with a
+// unique constraint are always created one at a time so that the connector
+// claims their unique keys.
+func (c *client) canBatch(table *Table) bool {
+	_, ok := c.connector.(Batcher)
+	return ok && len(table.UniqueKeys) == 0
+}
+
+// createBatch creates the rows of the table in a single batch if they can
+// be batched, and otherwise one row at a time.
+func (c *client) createBatch(
+	ctx context.Context,
+	table *Table,
+	rows [][]base.Column,
+) error {
+	opCtx, done := c.begin(ctx, table, OpCreateBatch, nil)
+	var err error
+	if c.canBatch(table) {
+		err = c.connector.(Batcher).CreateBatch(opCtx, &table.Definition, rows)
+	} else {
+		for _, row := range rows {
+			if err = c.connector.Create(
+				opCtx, &table.Definition, row); err != nil {
+				break
+			}
+		}
+	}
+	done(err, rows...)
+	return err
+}
+
+// CreateAsync buffers the storage object to be created in the database
+// along with other objects of its table. See ClientConfig.AsyncWrites.
+func (c *client) CreateAsync(ctx context.Context, e base.Object) error {
+	// lookup if a table exists for this object, return error if not found
+	table, err := c.getTable(e)
+	if err != nil {
+		return err
+	}
+
+	// populate the timestamps maintained by the ORM when the object is
+	// written rather than when it is flushed
+	table.SetCreateTimes(e, time.Now().UTC())
+
+	return c.async.enqueue(ctx, table, table.GetRowFromObject(e))
+}
+
+// Flush writes the storage objects buffered by CreateAsync.
+func (c *client) Flush(ctx context.Context) error {
+	return c.async.flush(ctx)
+}
+
+// Close stops the periodic flushes of the storage objects written with
+// CreateAsync and flushes them until they are written or run out of
+// attempts.
+func (c *client) Close(ctx context.Context) error {
+	return c.async.close(ctx)
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// batcherConnector is a connector which creates rows in batches
+type batcherConnector struct {
+	*connectormocks.MockConnector
+	*connectormocks.MockBatcher
+}
+
+// newAsyncClient returns a client writing ValidObject asynchronously,
+// flushed only explicitly unless the flush interval is set, and the scope
+// of its metrics
+func (suite *ORMTestSuite) newAsyncClient(
+	conn Connector,
+	config AsyncWriteConfig,
+) (Client, tally.TestScope) {
+	if config.FlushInterval == 0 {
+		config.FlushInterval = time.Hour
+	}
+	scope := tally.NewTestScope("", map[string]string{})
+	client, err := NewClientWithConfig(conn, &ClientConfig{
+		AsyncWrites: config,
+		Scope:       scope,
+	}, &ValidObject{})
+	suite.NoError(err)
+	return client, scope
+}
+
+// asyncCounter returns the value of an async write counter of a table
+func asyncCounter(scope tally.TestScope, name string, table string) int64 {
+	for _, c := range scope.Snapshot().Counters() {
+		if c.Name() == name && c.Tags()["table"] == table {
+			return c.Value()
+		}
+	}
+	return 0
+}
+
+// TestCreateAsyncFlush tests that the objects written asynchronously are
+// created one at a time on flush with a connector which cannot batch them
+func (suite *ORMTestSuite) TestCreateAsyncFlush() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newAsyncClient(conn, AsyncWriteConfig{})
+	defer client.Close(suite.ctx)
+
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "a"}))
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "b"}))
+
+	var names []interface{}
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, e *base.Definition, row []base.Column) {
+			suite.Equal("valid_object", e.Name)
+			for _, col := range row {
+				if col.Name == "name" {
+					names = append(names, col.Value)
+				}
+			}
+		}).Return(nil).Times(2)
+	suite.NoError(client.Flush(suite.ctx))
+	suite.Equal([]interface{}{"a", "b"}, names)
+	suite.Equal(int64(2), asyncCounter(scope, "async_rows_flushed", "valid_object"))
+
+	// nothing is left to flush
+	suite.NoError(client.Flush(suite.ctx))
+}
+
+// TestCreateAsyncBatches tests that the objects written asynchronously are
+// created in batches of the configured size
+func (suite *ORMTestSuite) TestCreateAsyncBatches() {
+	defer suite.ctrl.Finish()
+	conn := &batcherConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockBatcher:   connectormocks.NewMockBatcher(suite.ctrl),
+	}
+	client, scope := suite.newAsyncClient(conn, AsyncWriteConfig{
+		BatchSize: 2,
+	})
+	defer client.Close(suite.ctx)
+
+	for i := 0; i < 3; i++ {
+		suite.NoError(client.CreateAsync(
+			suite.ctx, &ValidObject{ID: uint64(i), Name: "test"}))
+	}
+	gomock.InOrder(
+		conn.MockBatcher.EXPECT().
+			CreateBatch(gomock.Any(), gomock.Any(), gomock.Len(2)).
+			Return(nil),
+		conn.MockBatcher.EXPECT().
+			CreateBatch(gomock.Any(), gomock.Any(), gomock.Len(1)).
+			Return(errors.New("batch failed")),
+	)
+	suite.Error(client.Flush(suite.ctx))
+	suite.Equal(int64(2), asyncCounter(scope, "async_rows_flushed", "valid_object"))
+	suite.Equal(int64(1), asyncCounter(scope, "async_rows_retried", "valid_object"))
+
+	// the row of the failed batch is written by the next flush
+	conn.MockBatcher.EXPECT().
+		CreateBatch(gomock.Any(), gomock.Any(), gomock.Len(1)).
+		Return(nil)
+	suite.NoError(client.Flush(suite.ctx))
+	suite.Equal(int64(3), asyncCounter(scope, "async_rows_flushed", "valid_object"))
+	suite.Equal(int64(0), asyncCounter(scope, "async_rows_failed", "valid_object"))
+}
+
+// TestCreateAsyncRetry tests that the objects of failed batches are
+// written again until they run out of attempts, including when the client
+// is closed, and that the objects which cannot be batched are retried one
+// by one
+func (suite *ORMTestSuite) TestCreateAsyncRetry() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newAsyncClient(conn, AsyncWriteConfig{
+		MaxAttempts: 2,
+	})
+
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "a"}))
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "b"}))
+
+	// only the object which failed is written again, and it is lost after
+	// its second attempt
+	var names []interface{}
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context, _ *base.Definition, row []base.Column) error {
+			for _, col := range row {
+				if col.Name == "name" {
+					names = append(names, col.Value)
+					if col.Value == "a" {
+						return errors.New("create failed")
+					}
+				}
+			}
+			return nil
+		}).Times(3)
+	suite.Error(client.Close(suite.ctx))
+	suite.Equal([]interface{}{"a", "b", "a"}, names)
+	suite.Equal(int64(1), asyncCounter(scope, "async_rows_flushed", "valid_object"))
+	suite.Equal(int64(1), asyncCounter(scope, "async_rows_retried", "valid_object"))
+	suite.Equal(int64(1), asyncCounter(scope, "async_rows_failed", "valid_object"))
+}
+
+// TestCreateAsyncPeriodicFlush tests that the objects written
+// asynchronously are flushed periodically
+func (suite *ORMTestSuite) TestCreateAsyncPeriodicFlush() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, _ := suite.newAsyncClient(conn, AsyncWriteConfig{
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer client.Close(suite.ctx)
+
+	created := make(chan struct{})
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *base.Definition, []base.Column) {
+			close(created)
+		}).Return(nil)
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "test"}))
+	<-created
+}
+
+// TestCreateAsyncDrop tests that the objects written asynchronously are
+// dropped once the buffer of their table is full with the drop policy
+func (suite *ORMTestSuite) TestCreateAsyncDrop() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newAsyncClient(conn, AsyncWriteConfig{
+		BufferSize:     1,
+		OverflowPolicy: AsyncDrop,
+	})
+
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "a"}))
+	err := client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "b"})
+	suite.True(yarpcerrors.IsResourceExhausted(err))
+	suite.Equal(int64(1), asyncCounter(scope, "async_writes", "valid_object"))
+	suite.Equal(
+		int64(1), asyncCounter(scope, "async_writes_dropped", "valid_object"))
+
+	// closing the client flushes the buffer, and no more object is taken
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(client.Close(suite.ctx))
+	err = client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "c"})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestCreateAsyncBlock tests that the objects written asynchronously wait
+// for the buffer of their table to be flushed with the block policy
+func (suite *ORMTestSuite) TestCreateAsyncBlock() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, _ := suite.newAsyncClient(conn, AsyncWriteConfig{
+		BatchSize:      10,
+		BufferSize:     1,
+		OverflowPolicy: AsyncBlock,
+	})
+	defer client.Close(suite.ctx)
+
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).Times(2)
+
+	// the second write waits for the flush it wakes up to take the first
+	// object out of the buffer
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "a"}))
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "b"}))
+	suite.NoError(client.Flush(suite.ctx))
+}
+
+// TestCreateAsyncBlockTimeout tests that a write blocked on a full buffer
+// fails once its context is done
+func (suite *ORMTestSuite) TestCreateAsyncBlockTimeout() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, _ := suite.newAsyncClient(conn, AsyncWriteConfig{
+		BufferSize:     1,
+		OverflowPolicy: AsyncBlock,
+	})
+
+	// the flush woken up by the second write is held until the third
+	// write times out
+	release := make(chan struct{})
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *base.Definition, []base.Column) {
+			<-release
+		}).Return(nil).AnyTimes()
+
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "a"}))
+	suite.NoError(client.CreateAsync(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "b"}))
+	ctx, cancel := context.WithTimeout(suite.ctx, 10*time.Millisecond)
+	defer cancel()
+	err := client.CreateAsync(ctx, &ValidObject{ID: uint64(1), Name: "c"})
+	suite.Equal(context.DeadlineExceeded, err)
+	close(release)
+	suite.NoError(client.Close(suite.ctx))
+}
+
+// TestCreateAsyncInvalidPolicy tests that a client is not created with an
+// invalid overflow policy
+func (suite *ORMTestSuite) TestCreateAsyncInvalidPolicy() {
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	_, err := NewClientWithConfig(conn, &ClientConfig{
+		AsyncWrites: AsyncWriteConfig{OverflowPolicy: "retry"},
+	}, &ValidObject{})
+	suite.Error(err)
+}
@@ -0,0 +1,458 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// DefaultCircuitBreakerWindow is the default duration of the windows
+	// over which the error rate of the operations is measured.
+	DefaultCircuitBreakerWindow = 10 * time.Second
+	// DefaultCircuitBreakerMinRequests is the default min number of
+	// operations in a window for the circuit breaker to open.
+	DefaultCircuitBreakerMinRequests = 20
+	// DefaultCircuitBreakerErrorRate is the default error rate of the
+	// operations of a window above which the circuit breaker opens.
+	DefaultCircuitBreakerErrorRate = 0.5
+	// DefaultCircuitBreakerOpenTimeout is the default duration the
+	// circuit breaker stays open before probing the backend.
+	DefaultCircuitBreakerOpenTimeout = 5 * time.Second
+	// DefaultCircuitBreakerProbes is the default number of successful
+	// probes closing the circuit breaker.
+	DefaultCircuitBreakerProbes = 1
+)
+
+// ErrBackendUnavailable is the error of the operations failed fast while
+// the circuit breaker of the connector is open.
+var ErrBackendUnavailable = yarpcerrors.UnavailableErrorf(
+	"storage backend unavailable, circuit breaker is open")
+
+// Circuit breaker states, reported by the state gauge.
+const (
+	// operations go through, and their error rate is measured
+	circuitClosed = iota
+	// operations fail fast with ErrBackendUnavailable
+	circuitOpen
+	// only probes go through, which close the circuit breaker once they
+	// succeed, or open it again if one fails
+	circuitHalfOpen
+)
+
+// CircuitBreakerConfig is the config of the circuit breaker of a
+// connector.
+type CircuitBreakerConfig struct {
+	// Window is the duration of the consecutive windows over which the
+	// error rate of the operations is measured,
+	// DefaultCircuitBreakerWindow if zero.
+	Window time.Duration
+	// MinRequests is the min number of operations in a window for the
+	// circuit breaker to open, DefaultCircuitBreakerMinRequests if zero.
+	MinRequests int
+	// ErrorRate is the rate of failed operations in a window at which
+	// the circuit breaker opens, DefaultCircuitBreakerErrorRate if zero.
+	ErrorRate float64
+	// OpenTimeout is how long the circuit breaker stays open before it
+	// half-opens to probe the backend, DefaultCircuitBreakerOpenTimeout
+	// if zero.
+	OpenTimeout time.Duration
+	// Probes is the number of operations let through as probes while the
+	// circuit breaker is half-open, all of which must succeed for it to
+	// close, DefaultCircuitBreakerProbes if zero.
+	Probes int
+	// IsFailure tells whether the error of an operation is a failure of
+	// the backend, IsBackendFailure if nil.
+	IsFailure func(err error) bool
+}
+
+// IsBackendFailure tells whether the error of an operation is a failure
+// of the backend, as opposed to an error caused by the operation itself,
+// e.g. a row which already exists, or the cancellation of the operation
+// by its caller.
+func IsBackendFailure(err error) bool {
+	switch {
+	case err == nil, err == context.Canceled:
+		return false
+	case yarpcerrors.IsNotFound(err),
+		yarpcerrors.IsAlreadyExists(err),
+		yarpcerrors.IsInvalidArgument(err),
+		yarpcerrors.IsFailedPrecondition(err):
+		return false
+	}
+	return true
+}
+
+// circuitBreakerMetrics are the metrics of the circuit breaker of a
+// connector.
+type circuitBreakerMetrics struct {
+	// state of the circuit breaker
+	state tally.Gauge
+	// number of operations failed fast
+	rejected tally.Counter
+	// number of times the circuit breaker opened
+	opened tally.Counter
+	// number of times the circuit breaker closed
+	closed tally.Counter
+}
+
+// circuitBreaker tracks the error rate of the operations of a connector,
+// and opens once it is too high so that the operations fail fast instead
+// of piling up on a backend which is down. Once open for the open timeout,
+// it half-opens to let a few operations through to probe the backend.
+type circuitBreaker struct {
+	sync.Mutex
+
+	config  CircuitBreakerConfig
+	metrics *circuitBreakerMetrics
+	// returns the current time, overridden by tests
+	now func() time.Time
+
+	state int
+	// start of the current window, and the number of operations and
+	// failures in it
+	windowStart time.Time
+	requests    int
+	failures    int
+	// when the circuit breaker last opened
+	openedAt time.Time
+	// number of probes let through and succeeded since the circuit
+	// breaker half-opened
+	probes    int
+	succeeded int
+}
+
+func newCircuitBreaker(
+	config CircuitBreakerConfig,
+	scope tally.Scope) *circuitBreaker {
+	if config.Window <= 0 {
+		config.Window = DefaultCircuitBreakerWindow
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = DefaultCircuitBreakerMinRequests
+	}
+	if config.ErrorRate <= 0 {
+		config.ErrorRate = DefaultCircuitBreakerErrorRate
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultCircuitBreakerOpenTimeout
+	}
+	if config.Probes <= 0 {
+		config.Probes = DefaultCircuitBreakerProbes
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = IsBackendFailure
+	}
+	return &circuitBreaker{
+		config: config,
+		metrics: &circuitBreakerMetrics{
+			state:    scope.Gauge("state"),
+			rejected: scope.Counter("rejected"),
+			opened:   scope.Counter("opened"),
+			closed:   scope.Counter("closed"),
+		},
+		now: time.Now,
+	}
+}
+
+// call calls op unless the circuit breaker is open, and records its
+// outcome. It fails with ErrBackendUnavailable if the circuit breaker is
+// open.
+func (b *circuitBreaker) call(op func() error) error {
+	probe, ok := b.allow()
+	if !ok {
+		b.metrics.rejected.Inc(1)
+		return ErrBackendUnavailable
+	}
+	err := op()
+	b.done(probe, b.config.IsFailure(err))
+	return err
+}
+
+// allow returns whether an operation can go through, and whether it is a
+// probe of the half-open circuit breaker.
+func (b *circuitBreaker) allow() (probe bool, ok bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false, false
+		}
+		b.setState(circuitHalfOpen)
+		b.probes = 0
+		b.succeeded = 0
+		fallthrough
+	case circuitHalfOpen:
+		if b.probes >= b.config.Probes {
+			return false, false
+		}
+		b.probes++
+		return true, true
+	}
+	return false, true
+}
+
+// done records the outcome of an operation let through.
+func (b *circuitBreaker) done(probe bool, failed bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case circuitClosed:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.config.MinRequests &&
+			float64(b.failures) >= b.config.ErrorRate*float64(b.requests) {
+			b.open(now)
+		}
+	case circuitHalfOpen:
+		if !probe {
+			// let through before the circuit breaker opened
+			return
+		}
+		if failed {
+			b.open(now)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.config.Probes {
+			b.setState(circuitClosed)
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+			b.metrics.closed.Inc(1)
+			log.Info("ORM circuit breaker closed, storage backend recovered")
+		}
+	}
+}
+
+// open opens the circuit breaker. It must be called with the lock held.
+func (b *circuitBreaker) open(now time.Time) {
+	log.WithFields(log.Fields{
+		"requests":     b.requests,
+		"failures":     b.failures,
+		"open_timeout": b.config.OpenTimeout,
+	}).Warn("ORM circuit breaker opened, storage backend unavailable")
+	b.setState(circuitOpen)
+	b.openedAt = now
+	b.metrics.opened.Inc(1)
+}
+
+// setState sets the state of the circuit breaker. It must be called with
+// the lock held.
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	b.metrics.state.Update(float64(state))
+}
+
+// circuitBreakerConnector is a Connector whose operations fail fast with
+// ErrBackendUnavailable while its circuit breaker is open.
+type circuitBreakerConnector struct {
+	connector Connector
+	breaker   *circuitBreaker
+}
+
+// circuitBreakerBatcher is the Batcher of a circuitBreakerConnector
+// whose connector is a Batcher.
+type circuitBreakerBatcher struct {
+	batcher Batcher
+	breaker *circuitBreaker
+}
+
+// circuitBreakerQuerier is the UnindexedQuerier of a
+// circuitBreakerConnector whose connector is an UnindexedQuerier.
+type circuitBreakerQuerier struct {
+	querier UnindexedQuerier
+	breaker *circuitBreaker
+}
+
+// circuitBreakerPager is the Pager of a circuitBreakerConnector whose
+// connector is a Pager.
+type circuitBreakerPager struct {
+	pager   Pager
+	breaker *circuitBreaker
+}
+
+// NewCircuitBreakerConnector returns a Connector serving all operations
+// from connector, which fail fast with ErrBackendUnavailable once the
+// error rate of the operations is too high, until probes find the
+// backend available again. The returned Connector is a Batcher, an
+// UnindexedQuerier or a Pager if connector is.
+func NewCircuitBreakerConnector(
+	connector Connector,
+	config CircuitBreakerConfig,
+	scope tally.Scope) Connector {
+	breaker := newCircuitBreaker(
+		config, scope.SubScope("orm_circuit_breaker"))
+
+	var (
+		batcher Batcher
+		querier UnindexedQuerier
+		pager   Pager
+	)
+	if b, ok := connector.(Batcher); ok {
+		batcher = &circuitBreakerBatcher{batcher: b, breaker: breaker}
+	}
+	if q, ok := connector.(UnindexedQuerier); ok {
+		querier = &circuitBreakerQuerier{querier: q, breaker: breaker}
+	}
+	if p, ok := connector.(Pager); ok {
+		pager = &circuitBreakerPager{pager: p, breaker: breaker}
+	}
+	return withExtensions(
+		&circuitBreakerConnector{connector: connector, breaker: breaker},
+		batcher,
+		querier,
+		pager)
+}
+
+// CreateIfNotExists creates a row in the DB for the base object if it
+// doesn't already exist
+func (c *circuitBreakerConnector) CreateIfNotExists(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	return c.breaker.call(func() error {
+		return c.connector.CreateIfNotExists(ctx, e, values)
+	})
+}
+
+// Create creates a row in the DB for the base object
+func (c *circuitBreakerConnector) Create(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	return c.breaker.call(func() error {
+		return c.connector.Create(ctx, e, values)
+	})
+}
+
+// Get fetches a row by primary key of base object
+func (c *circuitBreakerConnector) Get(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) ([]base.Column, error) {
+	var row []base.Column
+	err := c.breaker.call(func() (err error) {
+		row, err = c.connector.Get(ctx, e, keys)
+		return err
+	})
+	return row, err
+}
+
+// GetAll fetches all rows by partition key of base object
+func (c *circuitBreakerConnector) GetAll(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) ([][]base.Column, error) {
+	var rows [][]base.Column
+	err := c.breaker.call(func() (err error) {
+		rows, err = c.connector.GetAll(ctx, e, keys)
+		return err
+	})
+	return rows, err
+}
+
+// Update updates a row in the DB for the base object
+func (c *circuitBreakerConnector) Update(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+	keys []base.Column,
+) error {
+	return c.breaker.call(func() error {
+		return c.connector.Update(ctx, e, values, keys)
+	})
+}
+
+// Delete deletes a row from the DB for the base object
+func (c *circuitBreakerConnector) Delete(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) error {
+	return c.breaker.call(func() error {
+		return c.connector.Delete(ctx, e, keys)
+	})
+}
+
+// CreateBatch creates the rows in the DB for the base object
+func (b *circuitBreakerBatcher) CreateBatch(
+	ctx context.Context,
+	e *base.Definition,
+	rows [][]base.Column,
+) error {
+	return b.breaker.call(func() error {
+		return b.batcher.CreateBatch(ctx, e, rows)
+	})
+}
+
+// QueryUnindexed returns the rows of the table of the object whose
+// columns are equal to the values of the conditions
+func (q *circuitBreakerQuerier) QueryUnindexed(
+	ctx context.Context,
+	e *base.Definition,
+	conditions []base.Column,
+	limit int,
+) ([][]base.Column, error) {
+	var rows [][]base.Column
+	err := q.breaker.call(func() (err error) {
+		rows, err = q.querier.QueryUnindexed(ctx, e, conditions, limit)
+		return err
+	})
+	return rows, err
+}
+
+// GetAllPage fetches a page of the rows by partition key of base object
+func (p *circuitBreakerPager) GetAllPage(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+	pageSize int,
+	pageToken []byte,
+) ([][]base.Column, []byte, error) {
+	var (
+		rows      [][]base.Column
+		nextToken []byte
+	)
+	err := p.breaker.call(func() (err error) {
+		rows, nextToken, err = p.pager.GetAllPage(
+			ctx, e, keys, pageSize, pageToken)
+		return err
+	})
+	return rows, nextToken, err
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// unindexedBatcherConnector is a connector which creates rows in batches
+// and queries rows by unindexed columns
+type unindexedBatcherConnector struct {
+	*connectormocks.MockConnector
+	*connectormocks.MockBatcher
+	*connectormocks.MockUnindexedQuerier
+}
+
+// setupCircuitBreakerConnector returns a circuit breaker connector over a
+// mock connector, with a clock set by the returned function, the scope of
+// its metrics and the definition of ValidObject
+func (suite *ORMTestSuite) setupCircuitBreakerConnector() (
+	Connector,
+	*connectormocks.MockConnector,
+	func(time.Time),
+	tally.TestScope,
+	*base.Definition,
+) {
+	mockConn := connectormocks.NewMockConnector(suite.ctrl)
+	scope := tally.NewTestScope("", map[string]string{})
+	conn := NewCircuitBreakerConnector(mockConn, CircuitBreakerConfig{
+		Window:      time.Minute,
+		MinRequests: 4,
+		ErrorRate:   0.5,
+		OpenTimeout: 10 * time.Second,
+		Probes:      2,
+	}, scope)
+
+	now := time.Now()
+	breaker := conn.(*circuitBreakerConnector).breaker
+	breaker.now = func() time.Time { return now }
+
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	return conn, mockConn, func(t time.Time) { now = t }, scope, &table.Definition
+}
+
+// breakerCounter returns the value of a circuit breaker counter
+func breakerCounter(scope tally.TestScope, name string) int64 {
+	c, ok := scope.Snapshot().Counters()["orm_circuit_breaker."+name+"+"]
+	if !ok {
+		return 0
+	}
+	return c.Value()
+}
+
+// breakerState returns the state reported by the circuit breaker
+func breakerState(scope tally.TestScope) int {
+	g, ok := scope.Snapshot().Gauges()["orm_circuit_breaker.state+"]
+	if !ok {
+		return circuitClosed
+	}
+	return int(g.Value())
+}
+
+// TestCircuitBreakerOpens tests that the circuit breaker opens once the
+// error rate of a window is too high, and fails operations fast
+func (suite *ORMTestSuite) TestCircuitBreakerOpens() {
+	defer suite.ctrl.Finish()
+	conn, mockConn, _, scope, e := suite.setupCircuitBreakerConnector()
+
+	backendErr := errors.New("no hosts available")
+	mockConn.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil).Times(2)
+	mockConn.EXPECT().Get(suite.ctx, e, keyRow).Return(nil, backendErr).Times(2)
+
+	for i := 0; i < 4; i++ {
+		conn.Get(suite.ctx, e, keyRow)
+	}
+	suite.Equal(circuitOpen, breakerState(scope))
+	suite.Equal(int64(1), breakerCounter(scope, "opened"))
+
+	// Fails fast without calling the connector
+	_, err := conn.Get(suite.ctx, e, keyRow)
+	suite.Equal(ErrBackendUnavailable, err)
+	suite.True(yarpcerrors.IsUnavailable(err))
+	suite.Equal(ErrBackendUnavailable, conn.Create(suite.ctx, e, testRow))
+	suite.Equal(int64(2), breakerCounter(scope, "rejected"))
+}
+
+// TestCircuitBreakerIgnoresClientErrors tests that errors caused by the
+// operations themselves do not open the circuit breaker
+func (suite *ORMTestSuite) TestCircuitBreakerIgnoresClientErrors() {
+	defer suite.ctrl.Finish()
+	conn, mockConn, _, scope, e := suite.setupCircuitBreakerConnector()
+
+	mockConn.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(nil, yarpcerrors.NotFoundErrorf("not found")).Times(5)
+	mockConn.EXPECT().Create(suite.ctx, e, testRow).
+		Return(yarpcerrors.AlreadyExistsErrorf("already exists")).Times(5)
+
+	for i := 0; i < 5; i++ {
+		_, err := conn.Get(suite.ctx, e, keyRow)
+		suite.True(yarpcerrors.IsNotFound(err))
+		suite.True(yarpcerrors.IsAlreadyExists(
+			conn.Create(suite.ctx, e, testRow)))
+	}
+	suite.Equal(circuitClosed, breakerState(scope))
+}
+
+// TestCircuitBreakerWindow tests that the failures of a past window are
+// not counted in the error rate
+func (suite *ORMTestSuite) TestCircuitBreakerWindow() {
+	defer suite.ctrl.Finish()
+	conn, mockConn, setNow, scope, e := suite.setupCircuitBreakerConnector()
+
+	backendErr := errors.New("timeout")
+	mockConn.EXPECT().Delete(suite.ctx, e, keyRow).Return(backendErr).Times(3)
+	mockConn.EXPECT().Delete(suite.ctx, e, keyRow).Return(nil).Times(3)
+
+	for i := 0; i < 3; i++ {
+		conn.Delete(suite.ctx, e, keyRow)
+	}
+	setNow(time.Now().Add(2 * time.Minute))
+	for i := 0; i < 3; i++ {
+		suite.NoError(conn.Delete(suite.ctx, e, keyRow))
+	}
+	suite.Equal(circuitClosed, breakerState(scope))
+}
+
+// TestCircuitBreakerHalfOpen tests that the open circuit breaker lets
+// probes through once the open timeout expired, and closes once they
+// succeed or opens again if one fails
+func (suite *ORMTestSuite) TestCircuitBreakerHalfOpen() {
+	defer suite.ctrl.Finish()
+	conn, mockConn, setNow, scope, e := suite.setupCircuitBreakerConnector()
+
+	backendErr := errors.New("no hosts available")
+	mockConn.EXPECT().Update(suite.ctx, e, testRow, keyRow).
+		Return(backendErr).Times(4)
+	for i := 0; i < 4; i++ {
+		conn.Update(suite.ctx, e, testRow, keyRow)
+	}
+	suite.Equal(circuitOpen, breakerState(scope))
+
+	// A failed probe opens the circuit breaker again
+	start := time.Now()
+	setNow(start.Add(11 * time.Second))
+	mockConn.EXPECT().Update(suite.ctx, e, testRow, keyRow).
+		Return(backendErr)
+	suite.Equal(backendErr, conn.Update(suite.ctx, e, testRow, keyRow))
+	suite.Equal(circuitOpen, breakerState(scope))
+	suite.Equal(int64(2), breakerCounter(scope, "opened"))
+	suite.Equal(
+		ErrBackendUnavailable,
+		conn.Update(suite.ctx, e, testRow, keyRow))
+
+	// Probes close the circuit breaker once they all succeed
+	setNow(start.Add(22 * time.Second))
+	mockConn.EXPECT().Update(suite.ctx, e, testRow, keyRow).
+		Return(nil).Times(3)
+	suite.NoError(conn.Update(suite.ctx, e, testRow, keyRow))
+	suite.Equal(circuitHalfOpen, breakerState(scope))
+	suite.NoError(conn.Update(suite.ctx, e, testRow, keyRow))
+	suite.Equal(circuitClosed, breakerState(scope))
+	suite.Equal(int64(1), breakerCounter(scope, "closed"))
+	suite.NoError(conn.Update(suite.ctx, e, testRow, keyRow))
+}
+
+// TestCircuitBreakerHalfOpenLimitsProbes tests that no more operations
+// than the probes go through while the circuit breaker is half-open
+func (suite *ORMTestSuite) TestCircuitBreakerHalfOpenLimitsProbes() {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		MinRequests: 1,
+		Probes:      2,
+	}, tally.NoopScope)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.done(false, true)
+	suite.Equal(circuitOpen, b.state)
+
+	now = now.Add(DefaultCircuitBreakerOpenTimeout)
+	for i := 0; i < 2; i++ {
+		probe, ok := b.allow()
+		suite.True(probe)
+		suite.True(ok)
+	}
+	_, ok := b.allow()
+	suite.False(ok)
+}
+
+// TestCircuitBreakerConnectorInterfaces tests that the circuit breaker
+// connector is a Batcher and an UnindexedQuerier only if its connector is
+func (suite *ORMTestSuite) TestCircuitBreakerConnectorInterfaces() {
+	defer suite.ctrl.Finish()
+	scope := tally.NoopScope
+
+	conn := NewCircuitBreakerConnector(
+		connectormocks.NewMockConnector(suite.ctrl),
+		CircuitBreakerConfig{}, scope)
+	_, ok := conn.(Batcher)
+	suite.False(ok)
+	_, ok = conn.(UnindexedQuerier)
+	suite.False(ok)
+
+	conn = NewCircuitBreakerConnector(&batcherConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockBatcher:   connectormocks.NewMockBatcher(suite.ctrl),
+	}, CircuitBreakerConfig{}, scope)
+	_, ok = conn.(Batcher)
+	suite.True(ok)
+	_, ok = conn.(UnindexedQuerier)
+	suite.False(ok)
+
+	conn = NewCircuitBreakerConnector(&unindexedConnector{
+		MockConnector:        connectormocks.NewMockConnector(suite.ctrl),
+		MockUnindexedQuerier: connectormocks.NewMockUnindexedQuerier(suite.ctrl),
+	}, CircuitBreakerConfig{}, scope)
+	_, ok = conn.(Batcher)
+	suite.False(ok)
+	_, ok = conn.(UnindexedQuerier)
+	suite.True(ok)
+
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	e := &table.Definition
+	both := &unindexedBatcherConnector{
+		MockConnector:        connectormocks.NewMockConnector(suite.ctrl),
+		MockBatcher:          connectormocks.NewMockBatcher(suite.ctrl),
+		MockUnindexedQuerier: connectormocks.NewMockUnindexedQuerier(suite.ctrl),
+	}
+	both.MockBatcher.EXPECT().
+		CreateBatch(suite.ctx, e, testRows).Return(nil)
+	both.MockUnindexedQuerier.EXPECT().
+		QueryUnindexed(suite.ctx, e, keyRow, 10).Return(testRows, nil)
+
+	conn = NewCircuitBreakerConnector(both, CircuitBreakerConfig{}, scope)
+	suite.NoError(conn.(Batcher).CreateBatch(suite.ctx, e, testRows))
+	rows, err := conn.(UnindexedQuerier).
+		QueryUnindexed(suite.ctx, e, keyRow, 10)
+	suite.NoError(err)
+	suite.Equal(testRows, rows)
+}
+
+// TestCircuitBreakerConnectorPager tests that the circuit breaker
+// connector is a Pager if its connector is, whose failures open the
+// circuit breaker
+func (suite *ORMTestSuite) TestCircuitBreakerConnectorPager() {
+	defer suite.ctrl.Finish()
+
+	conn := NewCircuitBreakerConnector(
+		connectormocks.NewMockConnector(suite.ctrl),
+		CircuitBreakerConfig{}, tally.NoopScope)
+	_, ok := conn.(Pager)
+	suite.False(ok)
+
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	e := &table.Definition
+	pager := &pagerConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockPager:     connectormocks.NewMockPager(suite.ctrl),
+	}
+	conn = NewCircuitBreakerConnector(pager, CircuitBreakerConfig{
+		Window:      time.Minute,
+		MinRequests: 2,
+		ErrorRate:   0.5,
+		OpenTimeout: time.Minute,
+	}, tally.NoopScope)
+	_, ok = conn.(Batcher)
+	suite.False(ok)
+
+	pager.MockPager.EXPECT().
+		GetAllPage(suite.ctx, e, keyRow, 10, []byte("token")).
+		Return(testRows, []byte("next"), nil)
+	rows, token, err := conn.(Pager).
+		GetAllPage(suite.ctx, e, keyRow, 10, []byte("token"))
+	suite.NoError(err)
+	suite.Equal(testRows, rows)
+	suite.Equal([]byte("next"), token)
+
+	pager.MockPager.EXPECT().
+		GetAllPage(suite.ctx, e, keyRow, 10, nil).
+		Return(nil, nil, errors.New("timeout"))
+	_, _, err = conn.(Pager).GetAllPage(suite.ctx, e, keyRow, 10, nil)
+	suite.Error(err)
+	_, _, err = conn.(Pager).GetAllPage(suite.ctx, e, keyRow, 10, nil)
+	suite.Equal(ErrBackendUnavailable, err)
+}
+
+// TestIsBackendFailure tests telling apart the failures of the backend
+// from the errors caused by the operations
+func (suite *ORMTestSuite) TestIsBackendFailure() {
+	suite.False(IsBackendFailure(nil))
+	suite.False(IsBackendFailure(context.Canceled))
+	suite.False(IsBackendFailure(yarpcerrors.NotFoundErrorf("not found")))
+	suite.False(IsBackendFailure(
+		yarpcerrors.AlreadyExistsErrorf("already exists")))
+	suite.False(IsBackendFailure(
+		yarpcerrors.InvalidArgumentErrorf("invalid argument")))
+	suite.False(IsBackendFailure(
+		yarpcerrors.FailedPreconditionErrorf("failed precondition")))
+	suite.True(IsBackendFailure(context.DeadlineExceeded))
+	suite.True(IsBackendFailure(errors.New("no hosts available")))
+	suite.True(IsBackendFailure(yarpcerrors.UnavailableErrorf("unavailable")))
+}
@@ -17,9 +17,11 @@ package orm
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
 
+	"github.com/uber-go/tally"
 	"go.uber.org/yarpc/yarpcerrors"
 )
 
@@ -28,9 +30,26 @@ type Client interface {
 	// CreateIfNotExists creates the storage object in the database if it
 	// doesn't already exist
 	CreateIfNotExists(ctx context.Context, e base.Object) error
-	// Create creates the storage object in the database
+	// Create creates the storage object in the database. Fields tagged with
+	// autotime=create are set to the current time unless already set, and
+	// fields tagged with autotime=update are set to the current time
 	Create(ctx context.Context, e base.Object) error
-	// Get gets the storage object from the database
+	// CreateAsync buffers the storage object to be created in the database
+	// in a batch with other objects of the same table, which is flushed
+	// periodically. Fields tagged with autotime are set as in Create. It
+	// fails, or blocks, if too many objects of the table are buffered,
+	// see ClientConfig.AsyncWrites. The object is written again by the
+	// next flushes if its batch fails, see AsyncWriteConfig.MaxAttempts
+	CreateAsync(ctx context.Context, e base.Object) error
+	// Flush writes the storage objects buffered by CreateAsync, and
+	// returns the first error of their batches
+	Flush(ctx context.Context) error
+	// Close stops the periodic flushes of the storage objects buffered by
+	// CreateAsync and flushes them until they are written or run out of
+	// attempts. CreateAsync fails once the client is closed
+	Close(ctx context.Context) error
+	// Get gets the storage object from the database. With a session in
+	// the context, it observes the writes of the session, see Session
 	Get(ctx context.Context, e base.Object) error
 	// Get gets all the storage objects for the partition key from the database
 	GetAll(ctx context.Context, e base.Object) ([]base.Object, error)
@@ -38,28 +57,186 @@ type Client interface {
 	// The fields to be updated can be specified as fieldsToUpdate which is
 	// a variable list of field names and is to be optionally specified by
 	// the caller. If not specified, all fields in the object will be updated
-	// to the DB
+	// to the DB. Fields tagged with autotime=update are set to the current
+	// time and always updated
 	Update(ctx context.Context, e base.Object, fieldsToUpdate ...string) error
 	// Delete deletes the storage object from the database
 	Delete(ctx context.Context, e base.Object) error
+	// DeleteAllInPartition deletes all the storage objects of the partition
+	// of the given object, which must contain the value of its partition key.
+	// Nothing is deleted if the partition has more objects than the limit.
+	// It returns the number of objects deleted.
+	DeleteAllInPartition(
+		ctx context.Context,
+		e base.Object,
+		opts ...DeleteAllOption,
+	) (int, error)
+	// QueryUnindexed gets the storage objects whose given fields are equal
+	// to the fields of the given object, which need not be part of its
+	// primary key. It reads the whole table, so it is meant for rare admin
+	// queries, and only allowed for objects tagged with unindexed=<limit>.
+	// It fails if more objects than the limit match.
+	QueryUnindexed(
+		ctx context.Context,
+		e base.Object,
+		fields ...string,
+	) ([]base.Object, error)
+	// GetChildren gets a page of the children of the given parent, which
+	// must contain the values of the partition key of the children. The
+	// children are of the type of the given child object, which must be
+	// tagged with parent=<table of the parent>. It returns the children
+	// and the token of the next page, empty after the last page.
+	GetChildren(
+		ctx context.Context,
+		parent base.Object,
+		child base.Object,
+		opts ...ChildrenOption,
+	) ([]base.Object, []byte, error)
+	// DeleteWithChildren deletes the storage object and its children
+	// tagged with onDelete=cascade, unless it has children tagged with
+	// onDelete=restrict. The deletes are not atomic: the children are
+	// deleted first, so that a failed delete can be retried. It returns
+	// the number of children deleted.
+	DeleteWithChildren(
+		ctx context.Context,
+		e base.Object,
+		opts ...DeleteAllOption,
+	) (int, error)
+	// Stats returns the access statistics of every storage object
+	// of the client, sorted by object name
+	Stats() []*ObjectStats
+	// SlowQueries returns the recent operations slower than the slow
+	// query threshold of the client, most recent first
+	SlowQueries() []*SlowQuery
 }
 
 type client struct {
 	objectIndex map[reflect.Type]*Table
 	connector   Connector
+	// access statistics by table name
+	stats map[string]*objectStats
+	// timeout of every operation, none if zero
+	queryTimeout time.Duration
+	// log of the recent slow queries
+	slowQueries *slowQueryLog
+	// whether the reads of the objects tagged with hedge are hedged
+	hedgedReads bool
+	// metrics of the hedged reads by table name and operation
+	hedges map[string]map[string]*hedgeMetrics
+	// metrics of the unindexed queries by table name
+	unindexed map[string]*unindexedMetrics
+	// children of the storage objects by table name of the parent
+	relations map[string][]*relation
+	// buffers of the storage objects written with CreateAsync
+	async *asyncWriter
+	// retries of the reads of the partitions written in their session
+	sessionReads SessionReadConfig
+	// metrics of the session reads by table name
+	sessions map[string]*sessionMetrics
 }
 
 // NewClient returns a new ORM client for the base instance and
 // connector provided.
 func NewClient(conn Connector, objects ...base.Object) (Client, error) {
+	return NewClientWithConfig(conn, &ClientConfig{}, objects...)
+}
+
+// NewClientWithConfig returns a new ORM client for the base instance and
+// connector provided, with the query timeout, slow query logging, read
+// hedging, async writes and session reads of the config.
+func NewClientWithConfig(
+	conn Connector,
+	config *ClientConfig,
+	objects ...base.Object,
+) (Client, error) {
 	oi, err := BuildObjectIndex(objects)
 	if err != nil {
 		return nil, err
 	}
-	return &client{
-		objectIndex: oi,
-		connector:   conn,
-	}, nil
+	relations, err := buildRelations(oi)
+	if err != nil {
+		return nil, err
+	}
+	scope := config.Scope
+	if scope == nil {
+		scope = tally.NoopScope
+	}
+	stats := make(map[string]*objectStats, len(oi))
+	hedges := make(map[string]map[string]*hedgeMetrics, len(oi))
+	unindexed := make(map[string]*unindexedMetrics, len(oi))
+	sessions := make(map[string]*sessionMetrics, len(oi))
+	for _, table := range oi {
+		stats[table.Name] = newObjectStats(table.Name)
+		hedges[table.Name] = newHedgeMetrics(scope, table.Name)
+		unindexed[table.Name] = newUnindexedMetrics(scope, table.Name)
+		sessions[table.Name] = newSessionMetrics(scope, table.Name)
+	}
+	sessionReads := config.SessionReads
+	if sessionReads.Retries <= 0 {
+		sessionReads.Retries = DefaultSessionReadRetries
+	}
+	if sessionReads.RetryInterval <= 0 {
+		sessionReads.RetryInterval = DefaultSessionReadRetryInterval
+	}
+	c := &client{
+		objectIndex:  oi,
+		connector:    conn,
+		stats:        stats,
+		queryTimeout: config.QueryTimeout,
+		slowQueries: newSlowQueryLog(
+			config.SlowQueryThreshold, config.SlowQueryBufferSize),
+		hedgedReads:  config.HedgedReads,
+		hedges:       hedges,
+		unindexed:    unindexed,
+		relations:    relations,
+		sessionReads: sessionReads,
+		sessions:     sessions,
+	}
+	if c.async, err = newAsyncWriter(c, config.AsyncWrites, scope); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SlowQueries returns the recent operations slower than the slow query
+// threshold of the client, most recent first
+func (c *client) SlowQueries() []*SlowQuery {
+	return c.slowQueries.snapshot()
+}
+
+// begin marks the start of an operation on the key of a storage object. It
+// returns the context of the operation, bounded by the query timeout of the
+// client, and the function recording its outcome in the statistics and the
+// slow query log.
+func (c *client) begin(
+	ctx context.Context,
+	table *Table,
+	op string,
+	keyRow []base.Column,
+) (context.Context, func(err error, rows ...[]base.Column)) {
+	cancel := func() {}
+	if c.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.queryTimeout)
+	}
+	start := time.Now()
+	done := c.stats[table.Name].begin(op)
+	return ctx, func(err error, rows ...[]base.Column) {
+		cancel()
+		done(err, rows...)
+		c.slowQueries.record(
+			table.Name, op, keyRow, start, time.Since(start), err)
+	}
+}
+
+// Stats returns the access statistics of every storage object
+// of the client, sorted by object name
+func (c *client) Stats() []*ObjectStats {
+	var stats []*ObjectStats
+	for _, s := range c.stats {
+		stats = append(stats, s.snapshot())
+	}
+	sortObjectStats(stats)
+	return stats
 }
 
 // getTable gets the base Table structure that matches the base instance
@@ -83,13 +260,21 @@ func (c *client) CreateIfNotExists(ctx context.Context, e base.Object) error {
 		return err
 	}
 
+	// populate the timestamps maintained by the ORM
+	now := time.Now().UTC()
+	table.SetCreateTimes(e, now)
+
 	// Tell the connector to create a row in the DB using this row if it
 	// doesn't already exist
-	return c.connector.CreateIfNotExists(
-		ctx,
-		&table.Definition,
-		table.GetRowFromObject(e),
-	)
+	row := table.GetRowFromObject(e)
+	opCtx, done := c.begin(
+		ctx, table, OpCreateIfNotExists, table.GetKeyRowFromObject(e))
+	err = c.connector.CreateIfNotExists(opCtx, &table.Definition, row)
+	done(err, row)
+	if err == nil {
+		c.recordSessionWrite(ctx, table, e, now, false)
+	}
+	return err
 }
 
 // Create creates the storage object in the database
@@ -100,8 +285,20 @@ func (c *client) Create(ctx context.Context, e base.Object) error {
 		return err
 	}
 
+	// populate the timestamps maintained by the ORM
+	now := time.Now().UTC()
+	table.SetCreateTimes(e, now)
+
 	// Tell the connector to create a row in the DB using this row
-	return c.connector.Create(ctx, &table.Definition, table.GetRowFromObject(e))
+	row := table.GetRowFromObject(e)
+	opCtx, done := c.begin(
+		ctx, table, OpCreate, table.GetKeyRowFromObject(e))
+	err = c.connector.Create(opCtx, &table.Definition, row)
+	done(err, row)
+	if err == nil {
+		c.recordSessionWrite(ctx, table, e, now, false)
+	}
+	return err
 }
 
 // Get fetches an base by primary key, The base provided must contain
@@ -117,7 +314,20 @@ func (c *client) Get(ctx context.Context, e base.Object) error {
 	// build a primary key row from storage object
 	keyRow := table.GetKeyRowFromObject(e)
 
-	row, err := c.connector.Get(ctx, &table.Definition, keyRow)
+	opCtx, done := c.begin(ctx, table, OpGet, keyRow)
+	rows, err := c.sessionRead(opCtx, table, e,
+		func(ctx context.Context) ([][]base.Column, error) {
+			return c.hedgedRead(ctx, table, OpGet,
+				func(ctx context.Context) ([][]base.Column, error) {
+					row, err := c.connector.Get(ctx, &table.Definition, keyRow)
+					return [][]base.Column{row}, err
+				})
+		})
+	var row []base.Column
+	if err == nil {
+		row = rows[0]
+	}
+	done(err, row)
 	if err != nil {
 		return err
 	}
@@ -144,7 +354,15 @@ func (c *client) GetAll(
 	// build a partition key row from storage object
 	keyRow := table.GetPartitionKeyRowFromObject(e)
 
-	rows, err := c.connector.GetAll(ctx, &table.Definition, keyRow)
+	opCtx, done := c.begin(ctx, table, OpGetAll, keyRow)
+	rows, err := c.sessionRead(opCtx, table, e,
+		func(ctx context.Context) ([][]base.Column, error) {
+			return c.hedgedRead(ctx, table, OpGetAll,
+				func(ctx context.Context) ([][]base.Column, error) {
+					return c.connector.GetAll(ctx, &table.Definition, keyRow)
+				})
+		})
+	done(err, rows...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,14 +382,25 @@ func (c *client) Update(
 		return err
 	}
 
+	// record the modification time maintained by the ORM
+	now := time.Now().UTC()
+	table.SetUpdateTimes(e, now)
+
 	// translate the storage object into a row (list of column)
-	row := table.GetRowFromObject(e, fieldsToUpdate...)
+	row := table.GetRowFromObject(
+		e, table.withUpdateTimeFields(fieldsToUpdate)...)
 
 	// build a primary key row from storage object
 	keyRow := table.GetKeyRowFromObject(e)
 
 	// Tell the connector to update a row in the DB using this row
-	return c.connector.Update(ctx, &table.Definition, row, keyRow)
+	opCtx, done := c.begin(ctx, table, OpUpdate, keyRow)
+	err = c.connector.Update(opCtx, &table.Definition, row, keyRow)
+	done(err, row)
+	if err == nil {
+		c.recordSessionWrite(ctx, table, e, now, false)
+	}
+	return err
 }
 
 // Delete deletes the storage object in the database
@@ -186,5 +415,79 @@ func (c *client) Delete(ctx context.Context, e base.Object) error {
 	keyRow := table.GetKeyRowFromObject(e)
 
 	// Tell the connector to delete the row in the DB using this keyRow
-	return c.connector.Delete(ctx, &table.Definition, keyRow)
+	opCtx, done := c.begin(ctx, table, OpDelete, keyRow)
+	err = c.connector.Delete(opCtx, &table.Definition, keyRow)
+	done(err)
+	if err == nil {
+		c.recordSessionWrite(ctx, table, e, time.Now().UTC(), true)
+	}
+	return err
+}
+
+// DeleteAllInPartition deletes all the storage objects in the partition of
+// the given object, after checking that there are no more objects than the
+// limit so that a wrong partition key cannot wipe out a huge partition.
+func (c *client) DeleteAllInPartition(
+	ctx context.Context,
+	e base.Object,
+	opts ...DeleteAllOption,
+) (int, error) {
+	// lookup if a table exists for this object, return error if not found
+	table, err := c.getTable(e)
+	if err != nil {
+		return 0, err
+	}
+
+	options := &deleteAllOptions{limit: DefaultDeleteAllLimit}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// build a partition key row from storage object
+	keyRow := table.GetPartitionKeyRowFromObject(e)
+
+	// read the partition to enforce the limit before deleting anything
+	opCtx, done := c.begin(ctx, table, OpGetAll, keyRow)
+	rows, err := c.connector.GetAll(opCtx, &table.Definition, keyRow)
+	done(err, rows...)
+	if err != nil {
+		return 0, err
+	}
+	total := len(rows)
+	if options.limit > 0 && total > options.limit {
+		return 0, yarpcerrors.FailedPreconditionErrorf(
+			"partition %v of %s has %d rows, more than the limit of %d",
+			keyRow, table.Name, total, options.limit)
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	opCtx, done = c.begin(ctx, table, OpDeleteAllInPartition, keyRow)
+
+	// Rows of objects with a unique constraint are deleted one at a time so
+	// that the connector releases their unique keys, all other partitions
+	// are deleted with a single statement.
+	if len(table.UniqueKeys) > 0 {
+		for i, row := range rows {
+			if err := c.connector.Delete(
+				opCtx, &table.Definition, table.getKeyRowFromRow(row),
+			); err != nil {
+				done(err)
+				return i, err
+			}
+			options.reportProgress(i+1, total)
+		}
+	} else {
+		if err := c.connector.Delete(
+			opCtx, &table.Definition, keyRow); err != nil {
+			done(err)
+			return 0, err
+		}
+		options.reportProgress(total, total)
+	}
+
+	done(nil, rows...)
+	c.recordSessionWrite(ctx, table, e, time.Now().UTC(), true)
+	return total, nil
 }
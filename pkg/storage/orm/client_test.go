@@ -16,10 +16,12 @@ package orm
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
-	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
@@ -136,7 +138,7 @@ func TestORMTestSuite(t *testing.T) {
 // TestNewClientc tests creating new base client with base objects
 func (suite *ORMTestSuite) TestNewClient() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 	_, err := NewClient(conn, &ValidObject{})
 	suite.NoError(err)
 
@@ -147,7 +149,7 @@ func (suite *ORMTestSuite) TestNewClient() {
 // TestClientCreate tests client create operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientCreate() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 
 	conn.EXPECT().Create(suite.ctx, gomock.Any(), gomock.Any()).
 		Do(func(_ context.Context, _ *base.Definition, row []base.Column) {
@@ -167,7 +169,7 @@ func (suite *ORMTestSuite) TestClientCreate() {
 // TestClientGet tests client get operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientGet() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 
 	// ValidObject instance with only primary key set
 	e := &ValidObject{
@@ -200,7 +202,7 @@ func (suite *ORMTestSuite) TestClientGet() {
 // TestClientGetAll tests client GetAll operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientGetAll() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 
 	// ValidObject instance with only primary key set
 	e := &ValidObject{
@@ -237,7 +239,7 @@ func (suite *ORMTestSuite) TestClientGetAll() {
 // TestClientUpdate tests client update operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientUpdate() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 
 	conn.EXPECT().Update(suite.ctx, gomock.Any(), gomock.Any(), gomock.Any()).
 		Do(func(_ context.Context, _ *base.Definition,
@@ -279,7 +281,7 @@ func (suite *ORMTestSuite) TestClientUpdate() {
 // TestClientDelete tests client delete operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientDelete() {
 	defer suite.ctrl.Finish()
-	conn := ormmocks.NewMockConnector(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
 
 	conn.EXPECT().Delete(suite.ctx, gomock.Any(), gomock.Any()).
 		Do(func(_ context.Context, _ *base.Definition, row []base.Column) {
@@ -295,3 +297,159 @@ func (suite *ORMTestSuite) TestClientDelete() {
 	err = client.Delete(suite.ctx, &InvalidObject1{})
 	suite.Error(err)
 }
+
+// TestClientAutoTime tests that client create and update operations populate
+// the timestamps maintained by the ORM
+func (suite *ORMTestSuite) TestClientAutoTime() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &AutoTimeObject{})
+	suite.NoError(err)
+
+	before := time.Now().UTC()
+	conn.EXPECT().Create(suite.ctx, gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, _ *base.Definition, row []base.Column) {
+			suite.Len(row, 4)
+			for _, col := range row {
+				if col.Name == "creation_time" || col.Name == "update_time" {
+					suite.False(col.Value.(time.Time).Before(before))
+				}
+			}
+		}).Return(nil)
+
+	e := &AutoTimeObject{ID: 1, Data: "testdata"}
+	suite.NoError(client.Create(suite.ctx, e))
+	suite.False(e.CreationTime.IsZero())
+	suite.Equal(e.CreationTime, e.UpdateTime)
+	creationTime := e.CreationTime
+
+	conn.EXPECT().Update(suite.ctx, gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, _ *base.Definition,
+			row []base.Column, keyRow []base.Column) {
+			suite.Len(row, 2)
+			for _, col := range row {
+				suite.NotEqual("creation_time", col.Name)
+			}
+		}).Return(nil)
+
+	// only the data field is selected, the update time is added by the ORM
+	suite.NoError(client.Update(suite.ctx, e, "Data"))
+	suite.Equal(creationTime, e.CreationTime)
+	suite.False(e.UpdateTime.Before(creationTime))
+}
+
+// TestClientDeleteAllInPartition tests deleting all the objects of a partition
+func (suite *ORMTestSuite) TestClientDeleteAllInPartition() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+
+	e := &ValidObject{ID: uint64(1)}
+	partitionKeyRow := []base.Column{{Name: "id", Value: uint64(1)}}
+
+	// the partition is deleted with a single statement
+	var progress [][]int
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(testRows, nil)
+	conn.EXPECT().Delete(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(nil)
+	n, err := client.DeleteAllInPartition(suite.ctx, e,
+		WithDeleteProgress(func(deleted, total int) {
+			progress = append(progress, []int{deleted, total})
+		}))
+	suite.NoError(err)
+	suite.Equal(2, n)
+	suite.Equal([][]int{{2, 2}}, progress)
+
+	// nothing is deleted if the partition is over the limit
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(testRows, nil)
+	n, err = client.DeleteAllInPartition(suite.ctx, e, WithDeleteLimit(1))
+	suite.Error(err)
+	suite.Zero(n)
+
+	// nothing to delete in an empty partition
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(nil, nil)
+	n, err = client.DeleteAllInPartition(suite.ctx, e)
+	suite.NoError(err)
+	suite.Zero(n)
+
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(nil, errors.New("getall failed"))
+	_, err = client.DeleteAllInPartition(suite.ctx, e)
+	suite.Error(err)
+
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(testRows, nil)
+	conn.EXPECT().Delete(suite.ctx, gomock.Any(), partitionKeyRow).
+		Return(errors.New("delete failed"))
+	n, err = client.DeleteAllInPartition(suite.ctx, e)
+	suite.Error(err)
+	suite.Zero(n)
+
+	_, err = client.DeleteAllInPartition(suite.ctx, &InvalidObject1{})
+	suite.Error(err)
+}
+
+// TestClientDeleteAllInPartitionUnique tests that the objects of a partition
+// with a unique constraint are deleted one at a time
+func (suite *ORMTestSuite) TestClientDeleteAllInPartitionUnique() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &UniqueObject{})
+	suite.NoError(err)
+
+	rows := [][]base.Column{
+		{
+			{Name: "name", Value: "a"},
+			{Name: "version", Value: uint64(1)},
+			{Name: "id", Value: uint64(1)},
+		},
+		{
+			{Name: "name", Value: "b"},
+			{Name: "version", Value: uint64(2)},
+			{Name: "id", Value: uint64(1)},
+		},
+	}
+	keyRow := func(version uint64) []base.Column {
+		return []base.Column{
+			{Name: "id", Value: uint64(1)},
+			{Name: "version", Value: version},
+		}
+	}
+
+	var progress [][]int
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(rows, nil)
+	gomock.InOrder(
+		conn.EXPECT().Delete(suite.ctx, gomock.Any(), keyRow(1)).
+			Return(nil),
+		conn.EXPECT().Delete(suite.ctx, gomock.Any(), keyRow(2)).
+			Return(nil),
+	)
+	n, err := client.DeleteAllInPartition(suite.ctx, &UniqueObject{ID: 1},
+		WithDeleteProgress(func(deleted, total int) {
+			progress = append(progress, []int{deleted, total})
+		}))
+	suite.NoError(err)
+	suite.Equal(2, n)
+	suite.Equal([][]int{{1, 2}, {2, 2}}, progress)
+
+	// a failure stops the deletion and reports the objects deleted so far
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(rows, nil)
+	gomock.InOrder(
+		conn.EXPECT().Delete(suite.ctx, gomock.Any(), keyRow(1)).
+			Return(nil),
+		conn.EXPECT().Delete(suite.ctx, gomock.Any(), keyRow(2)).
+			Return(errors.New("delete failed")),
+	)
+	n, err = client.DeleteAllInPartition(suite.ctx, &UniqueObject{ID: 1})
+	suite.Error(err)
+	suite.Equal(1, n)
+}
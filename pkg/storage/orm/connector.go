@@ -30,7 +30,8 @@ type Connector interface {
 		values []base.Column,
 	) error
 
-	// Create creates a row in the DB for the base object
+	// Create creates a row in the DB for the base object. It fails with
+	// AlreadyExists if the row violates the unique constraint of the object.
 	Create(ctx context.Context, e *base.Definition, values []base.Column) error
 
 	// Get fetches a row by primary key of base object
@@ -47,7 +48,8 @@ type Connector interface {
 		keys []base.Column,
 	) ([][]base.Column, error)
 
-	// Update updates a row in the DB for the base object
+	// Update updates a row in the DB for the base object. It fails with
+	// AlreadyExists if the row violates the unique constraint of the object.
 	Update(
 		ctx context.Context,
 		e *base.Definition,
@@ -58,3 +60,56 @@ type Connector interface {
 	// Delete deletes a row from the DB for the base object
 	Delete(ctx context.Context, e *base.Definition, keys []base.Column) error
 }
+
+// withExtensions returns c extended with the optional interfaces of the
+// connector it wraps, which are nil if the wrapped connector does not
+// implement them, so that wrapping a connector keeps its extensions.
+func withExtensions(
+	c Connector,
+	batcher Batcher,
+	querier UnindexedQuerier,
+	pager Pager) Connector {
+	switch {
+	case batcher != nil && querier != nil && pager != nil:
+		return &struct {
+			Connector
+			Batcher
+			UnindexedQuerier
+			Pager
+		}{c, batcher, querier, pager}
+	case batcher != nil && querier != nil:
+		return &struct {
+			Connector
+			Batcher
+			UnindexedQuerier
+		}{c, batcher, querier}
+	case batcher != nil && pager != nil:
+		return &struct {
+			Connector
+			Batcher
+			Pager
+		}{c, batcher, pager}
+	case querier != nil && pager != nil:
+		return &struct {
+			Connector
+			UnindexedQuerier
+			Pager
+		}{c, querier, pager}
+	case batcher != nil:
+		return &struct {
+			Connector
+			Batcher
+		}{c, batcher}
+	case querier != nil:
+		return &struct {
+			Connector
+			UnindexedQuerier
+		}{c, querier}
+	case pager != nil:
+		return &struct {
+			Connector
+			Pager
+		}{c, pager}
+	}
+	return c
+}
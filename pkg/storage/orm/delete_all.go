@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+// DefaultDeleteAllLimit is the default maximum number of rows of a partition
+// which DeleteAllInPartition deletes.
+const DefaultDeleteAllLimit = 10000
+
+// DeleteAllOption is an option of DeleteAllInPartition.
+type DeleteAllOption func(*deleteAllOptions)
+
+// deleteAllOptions are the options of DeleteAllInPartition.
+type deleteAllOptions struct {
+	// maximum number of rows to delete, no limit if zero
+	limit int
+	// called with the number of rows deleted so far and the total
+	progress func(deleted, total int)
+}
+
+// reportProgress calls the progress callback, if any.
+func (o *deleteAllOptions) reportProgress(deleted, total int) {
+	if o.progress != nil {
+		o.progress(deleted, total)
+	}
+}
+
+// WithDeleteLimit sets the maximum number of rows of the partition. The
+// partition is not deleted if it has more rows. A limit of zero disables
+// the check.
+func WithDeleteLimit(limit int) DeleteAllOption {
+	return func(o *deleteAllOptions) {
+		o.limit = limit
+	}
+}
+
+// WithDeleteProgress sets a callback which is called as rows get deleted
+// with the number of rows deleted so far and the number of rows to delete.
+func WithDeleteProgress(progress func(deleted, total int)) DeleteAllOption {
+	return func(o *deleteAllOptions) {
+		o.progress = progress
+	}
+}
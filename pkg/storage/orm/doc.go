@@ -38,4 +38,23 @@ peloton. There are three major components of this layer:
              client and should be implemented by different storage connectors.
              Peloton currently has a cassandra implementation of the connector
              and we can extend this to other DBs.
+
+Typed stores wrapping the Client for each storage object are generated by
+cmd/ormgen (see package ormgen). They take the primary key of the object as
+typed arguments, return the concrete object type and only accept the
+non-key fields of the object in Update, so that a typo in a field name is
+caught at compile time. Run go generate in the package of the storage
+objects after changing any of them.
+
+A storage object may declare a parent with parent=<table> in its cassandra
+tag, e.g. the config versions of a job are children of its job index. The
+partition key of the child must be made of primary key columns of the
+parent, so that GetChildren reads the children of a parent from a single
+partition, page by page. The onDelete=cascade option deletes the children
+along with their parent in DeleteWithChildren, and onDelete=restrict
+prevents deleting a parent which still has children; other children are
+kept. Cassandra has no transactions across tables, so the children are
+deleted first and then the parent: a failed delete leaves the parent in
+place and can be retried, but a child created concurrently with the delete
+of its parent may outlive it. Delete never touches the children.
 */
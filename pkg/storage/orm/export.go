@@ -0,0 +1,244 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// ExportFileSuffix is the suffix of the files the storage objects are
+// exported to, after their table name.
+const ExportFileSuffix = ".ndjson"
+
+// Scanner is implemented by the connectors which can read every row of a
+// table, which is needed to export storage objects.
+type Scanner interface {
+	// Scan calls fn with every row of the table of the object, and stops
+	// at the first error returned by fn.
+	Scan(
+		ctx context.Context,
+		e *base.Definition,
+		fn func(row []base.Column) error,
+	) error
+}
+
+// ExportHeader is the first line of an export of a storage object. Every
+// following line is a row of the object, as a JSON object of its column
+// values by column name.
+type ExportHeader struct {
+	// Object is the table name of the exported storage object.
+	Object string `json:"object"`
+	// Time is when the export started.
+	Time time.Time `json:"time"`
+	// Columns is the sorted names of the columns of the object.
+	Columns []string `json:"columns"`
+}
+
+// Export streams every row of the storage object to w as newline-delimited
+// JSON, after an ExportHeader. The rows are read with the scanner, and are
+// not a consistent snapshot of the table if it is written meanwhile. It
+// returns the number of rows exported.
+func Export(
+	ctx context.Context,
+	scanner Scanner,
+	e base.Object,
+	w io.Writer,
+) (int, error) {
+	table, err := TableFromObject(e)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	header := &ExportHeader{
+		Object:  table.Name,
+		Time:    time.Now().UTC(),
+		Columns: table.sortedColumns(),
+	}
+	if err := enc.Encode(header); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	objectType := reflect.TypeOf(e).Elem()
+	err = scanner.Scan(ctx, &table.Definition, func(row []base.Column) error {
+		// go through the storage object so that the values are exported
+		// with the types of its fields rather than the types of the backend
+		obj := reflect.New(objectType).Interface().(base.Object)
+		table.SetObjectFromRow(obj, row)
+
+		values := make(map[string]interface{}, len(row))
+		for _, col := range table.GetRowFromObject(obj) {
+			values[col.Name] = col.Value
+		}
+		if err := enc.Encode(values); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// Restore writes every row of an export of the storage object read from r
+// with the connector, overwriting the rows with the same primary key. The
+// rows which were created after the export are kept. A row of an object
+// with a unique constraint whose unique key is already held by another row
+// is skipped, and that row is kept. It returns the number of rows restored.
+func Restore(
+	ctx context.Context,
+	conn Connector,
+	e base.Object,
+	r io.Reader,
+) (int, error) {
+	table, err := TableFromObject(e)
+	if err != nil {
+		return 0, err
+	}
+
+	dec := json.NewDecoder(r)
+	var header ExportHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, yarpcerrors.InvalidArgumentErrorf(
+			"invalid export header: %v", err)
+	}
+	if header.Object != table.Name {
+		return 0, yarpcerrors.InvalidArgumentErrorf(
+			"export of %s can not be restored to %s",
+			header.Object, table.Name)
+	}
+
+	count := 0
+	for {
+		var values map[string]json.RawMessage
+		if err := dec.Decode(&values); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, yarpcerrors.InvalidArgumentErrorf(
+				"invalid row %d of export of %s: %v", count+1, table.Name, err)
+		}
+
+		row, err := table.rowFromJSON(values)
+		if err != nil {
+			return count, err
+		}
+		restored, err := table.restoreRow(ctx, conn, row)
+		if err != nil {
+			return count, err
+		}
+		if restored {
+			count++
+		}
+	}
+}
+
+// restoreRow writes a row of an export. A row whose unique key is already
+// claimed overwrites the row with the same primary key if that row holds
+// the claim, and is not restored if another row holds it.
+func (t *Table) restoreRow(
+	ctx context.Context,
+	conn Connector,
+	row []base.Column,
+) (bool, error) {
+	err := conn.Create(ctx, &t.Definition, row)
+	if err == nil {
+		return true, nil
+	}
+	if len(t.UniqueKeys) == 0 || !yarpcerrors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	keyRow := t.getKeyRowFromRow(row)
+	keyColumns := make(map[string]bool, len(keyRow))
+	for _, column := range keyRow {
+		keyColumns[column.Name] = true
+	}
+	values := make([]base.Column, 0, len(row))
+	for _, column := range row {
+		if !keyColumns[column.Name] {
+			values = append(values, column)
+		}
+	}
+
+	if len(values) == 0 {
+		// nothing to write besides the key, the row holding the claim
+		// is kept
+		return false, nil
+	}
+
+	// the update moves the claim to the row if it changes its unique key,
+	// which fails if another row holds the key
+	err = conn.Update(ctx, &t.Definition, values, keyRow)
+	if yarpcerrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// sortedColumns returns the sorted names of the columns of the table.
+func (t *Table) sortedColumns() []string {
+	columns := make([]string, 0, len(t.ColumnToType))
+	for name := range t.ColumnToType {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// rowFromJSON decodes the exported column values of a row, by column name,
+// into a row sorted by column name. The row must have a value for every
+// column of its primary key.
+func (t *Table) rowFromJSON(
+	values map[string]json.RawMessage,
+) ([]base.Column, error) {
+	for _, name := range t.Key.Columns() {
+		if _, ok := values[name]; !ok {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"missing key column %s in export of %s", name, t.Name)
+		}
+	}
+
+	row := make([]base.Column, 0, len(values))
+	for name, raw := range values {
+		columnType, ok := t.ColumnToType[name]
+		if !ok {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"unknown column %s in export of %s", name, t.Name)
+		}
+		value := reflect.New(columnType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid value of column %s in export of %s: %v",
+				name, t.Name, err)
+		}
+		row = append(row, base.Column{
+			Name:  name,
+			Value: value.Elem().Interface(),
+		})
+	}
+	sort.Slice(row, func(i, j int) bool {
+		return row[i].Name < row[j].Name
+	})
+	return row, nil
+}
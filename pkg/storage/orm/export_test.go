@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// autoTimeRow is a row of AutoTimeObject as read from the backend
+var autoTimeRow = []base.Column{
+	{Name: "id", Value: uint64(1)},
+	{Name: "data", Value: "testdata"},
+	{Name: "creation_time", Value: time.Unix(1000, 0).UTC()},
+	{Name: "update_time", Value: time.Unix(2000, 0).UTC()},
+}
+
+// TestExportRestore tests that an exported object is restored as is
+func (suite *ORMTestSuite) TestExportRestore() {
+	defer suite.ctrl.Finish()
+	scanner := connectormocks.NewMockScanner(suite.ctrl)
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	scanner.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			e *base.Definition,
+			fn func(row []base.Column) error,
+		) error {
+			suite.Equal("autotime_object", e.Name)
+			for i := 0; i < 2; i++ {
+				if err := fn(autoTimeRow); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	var buf bytes.Buffer
+	count, err := Export(suite.ctx, scanner, &AutoTimeObject{}, &buf)
+	suite.NoError(err)
+	suite.Equal(2, count)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	suite.Len(lines, 3)
+	var header ExportHeader
+	suite.NoError(json.Unmarshal([]byte(lines[0]), &header))
+	suite.Equal("autotime_object", header.Object)
+	suite.Equal(
+		[]string{"creation_time", "data", "id", "update_time"},
+		header.Columns)
+
+	var restored [][]base.Column
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(
+			_ context.Context,
+			e *base.Definition,
+			row []base.Column,
+		) {
+			suite.Equal("autotime_object", e.Name)
+			restored = append(restored, row)
+		}).Return(nil).Times(2)
+	count, err = Restore(suite.ctx, conn, &AutoTimeObject{}, &buf)
+	suite.NoError(err)
+	suite.Equal(2, count)
+	for _, row := range restored {
+		suite.ensureRowsEqual(autoTimeRow, row)
+	}
+}
+
+// TestExportScanError tests that an export fails with the error of the scan
+func (suite *ORMTestSuite) TestExportScanError() {
+	defer suite.ctrl.Finish()
+	scanner := connectormocks.NewMockScanner(suite.ctrl)
+
+	scanner.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("scan failed"))
+	_, err := Export(suite.ctx, scanner, &ValidObject{}, &bytes.Buffer{})
+	suite.Error(err)
+}
+
+// TestRestoreInvalidExport tests that an invalid export is not restored
+func (suite *ORMTestSuite) TestRestoreInvalidExport() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	header := `{"object":"valid_object","columns":["data","id","name"]}` + "\n"
+	tt := []string{
+		"",
+		`{"object":"autotime_object"}`,
+		header + `{"id":1,"name":"test","unknown":"x"}`,
+		header + `{"id":1,"data":"testdata"}`,
+		header + `{"id":"one","name":"test"}`,
+		header + `{"id":1,"name":`,
+	}
+	for _, t := range tt {
+		_, err := Restore(
+			suite.ctx, conn, &ValidObject{}, strings.NewReader(t))
+		suite.Error(err, t)
+	}
+
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	count, err := Restore(suite.ctx, conn, &ValidObject{}, strings.NewReader(
+		header+`{"id":1,"name":"test","data":"testdata"}`))
+	suite.Error(err)
+	suite.Zero(count)
+}
+
+// TestRestoreUniqueConstraint tests that the rows of an object with a
+// unique constraint overwrite the rows holding their unique key, and are
+// skipped if other rows hold it
+func (suite *ORMTestSuite) TestRestoreUniqueConstraint() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	header := `{"object":"unique_object","columns":["id","name","version"]}`
+	export := strings.Join([]string{
+		header,
+		`{"id":1,"version":1,"name":"new"}`,
+		`{"id":2,"version":1,"name":"restored"}`,
+		`{"id":3,"version":2,"name":"claimed"}`,
+	}, "\n")
+	claimed := yarpcerrors.AlreadyExistsErrorf("unique key claimed")
+
+	gomock.InOrder(
+		conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil),
+		// the row with the same primary key holds the claim
+		conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(claimed),
+		conn.EXPECT().Update(
+			gomock.Any(),
+			gomock.Any(),
+			[]base.Column{{Name: "name", Value: "restored"}},
+			[]base.Column{
+				{Name: "id", Value: uint64(2)},
+				{Name: "version", Value: uint64(1)},
+			}).Return(nil),
+		// another row holds the claim
+		conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(claimed),
+		conn.EXPECT().Update(
+			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(claimed),
+	)
+	count, err := Restore(
+		suite.ctx, conn, &UniqueObject{}, strings.NewReader(export))
+	suite.NoError(err)
+	suite.Equal(2, count)
+
+	// other errors fail the restore
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(claimed)
+	conn.EXPECT().Update(
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("update failed"))
+	count, err = Restore(suite.ctx, conn, &UniqueObject{}, strings.NewReader(
+		header+"\n"+`{"id":1,"version":1,"name":"new"}`))
+	suite.Error(err)
+	suite.Zero(count)
+}
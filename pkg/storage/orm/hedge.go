@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/uber-go/tally"
+)
+
+// hedgeMetrics are the metrics of the hedged reads of a storage object.
+type hedgeMetrics struct {
+	// number of reads which were hedged
+	hedged tally.Counter
+	// number of hedged reads answered first by the hedge
+	wins tally.Counter
+	// number of hedged reads for which both calls failed
+	failed tally.Counter
+}
+
+func newHedgeMetrics(scope tally.Scope, table string) map[string]*hedgeMetrics {
+	metrics := make(map[string]*hedgeMetrics, 2)
+	for _, op := range []string{OpGet, OpGetAll} {
+		s := scope.Tagged(map[string]string{"table": table, "op": op})
+		metrics[op] = &hedgeMetrics{
+			hedged: s.Counter("hedged"),
+			wins:   s.Counter("hedge_wins"),
+			failed: s.Counter("hedge_failed"),
+		}
+	}
+	return metrics
+}
+
+// hedgedRead calls read, and calls it a second time if the first call
+// takes longer than the hedging threshold of the table. The result of the
+// first successful call is returned, and the other call is canceled. Reads
+// are not hedged unless the client enables hedging and the object opts in
+// with the hedge tag.
+func (c *client) hedgedRead(
+	ctx context.Context,
+	table *Table,
+	op string,
+	read func(ctx context.Context) ([][]base.Column, error),
+) ([][]base.Column, error) {
+	if !c.hedgedReads || table.HedgeAfter <= 0 {
+		return read(ctx)
+	}
+
+	// cancel the slower call once a result is returned
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		rows  [][]base.Column
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	call := func(hedge bool) {
+		rows, err := read(ctx)
+		results <- result{rows: rows, err: err, hedge: hedge}
+	}
+	go call(false)
+
+	timer := time.NewTimer(table.HedgeAfter)
+	defer timer.Stop()
+	select {
+	case r := <-results:
+		return r.rows, r.err
+	case <-timer.C:
+	}
+
+	metrics := c.hedges[table.Name][op]
+	metrics.hedged.Inc(1)
+	go call(true)
+
+	r := <-results
+	if r.err != nil {
+		// wait for the other call, it may still succeed
+		r = <-results
+		if r.err != nil {
+			metrics.failed.Inc(1)
+			return nil, r.err
+		}
+	}
+	if r.hedge {
+		metrics.wins.Inc(1)
+	}
+	return r.rows, nil
+}
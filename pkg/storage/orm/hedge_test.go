@@ -0,0 +1,188 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/uber-go/tally"
+)
+
+// hedgedRow is a row of HedgedObject
+var hedgedRow = []base.Column{
+	{Name: "id", Value: uint64(1)},
+	{Name: "name", Value: "test"},
+	{Name: "data", Value: "testdata"},
+}
+
+// newHedgingClient returns a client hedging the reads of HedgedObject,
+// and the scope of its hedging metrics
+func (suite *ORMTestSuite) newHedgingClient(
+	conn Connector) (Client, tally.TestScope) {
+	scope := tally.NewTestScope("", map[string]string{})
+	client, err := NewClientWithConfig(conn, &ClientConfig{
+		HedgedReads: true,
+		Scope:       scope,
+	}, &HedgedObject{}, &ValidObject{})
+	suite.NoError(err)
+	return client, scope
+}
+
+// hedgeCounter returns the value of a hedging counter of a table and
+// operation
+func hedgeCounter(
+	scope tally.TestScope, name string, table string, op string) int64 {
+	for _, c := range scope.Snapshot().Counters() {
+		if c.Name() == name && c.Tags()["table"] == table &&
+			c.Tags()["op"] == op {
+			return c.Value()
+		}
+	}
+	return 0
+}
+
+// TestHedgedGetFast tests that a read faster than the threshold is not
+// hedged
+func (suite *ORMTestSuite) TestHedgedGetFast() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newHedgingClient(conn)
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(hedgedRow, nil)
+	e := &HedgedObject{ID: uint64(1), Name: "test"}
+	suite.NoError(client.Get(suite.ctx, e))
+	suite.Equal("testdata", e.Data)
+	suite.Zero(hedgeCounter(scope, "hedged", "hedged_object", OpGet))
+}
+
+// TestHedgedGetSlow tests that a slow read is hedged and answered by the
+// hedge, the slow read being canceled
+func (suite *ORMTestSuite) TestHedgedGetSlow() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newHedgingClient(conn)
+
+	canceled := make(chan struct{})
+	gomock.InOrder(
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(
+				ctx context.Context,
+				_ *base.Definition,
+				_ []base.Column,
+			) ([]base.Column, error) {
+				<-ctx.Done()
+				close(canceled)
+				return nil, ctx.Err()
+			}),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(hedgedRow, nil),
+	)
+	e := &HedgedObject{ID: uint64(1), Name: "test"}
+	suite.NoError(client.Get(suite.ctx, e))
+	suite.Equal("testdata", e.Data)
+	<-canceled
+	suite.Equal(int64(1), hedgeCounter(scope, "hedged", "hedged_object", OpGet))
+	suite.Equal(
+		int64(1), hedgeCounter(scope, "hedge_wins", "hedged_object", OpGet))
+}
+
+// TestHedgedGetAllFailure tests that a hedged read fails only if both
+// reads fail, and otherwise returns the successful one
+func (suite *ORMTestSuite) TestHedgedGetAllFailure() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newHedgingClient(conn)
+
+	slowError := func(
+		ctx context.Context,
+		_ *base.Definition,
+		_ []base.Column,
+	) ([][]base.Column, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, errors.New("read failed")
+	}
+
+	// the first read fails after the hedge, which succeeds
+	gomock.InOrder(
+		conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(slowError),
+		conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(
+				ctx context.Context,
+				_ *base.Definition,
+				_ []base.Column,
+			) ([][]base.Column, error) {
+				time.Sleep(20 * time.Millisecond)
+				return [][]base.Column{hedgedRow}, nil
+			}),
+	)
+	objs, err := client.GetAll(suite.ctx, &HedgedObject{ID: uint64(1)})
+	suite.NoError(err)
+	suite.Len(objs, 1)
+
+	// both reads fail
+	conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(slowError).Times(2)
+	_, err = client.GetAll(suite.ctx, &HedgedObject{ID: uint64(1)})
+	suite.Error(err)
+
+	suite.Equal(
+		int64(2), hedgeCounter(scope, "hedged", "hedged_object", OpGetAll))
+	suite.Equal(
+		int64(1), hedgeCounter(scope, "hedge_wins", "hedged_object", OpGetAll))
+	suite.Equal(
+		int64(1),
+		hedgeCounter(scope, "hedge_failed", "hedged_object", OpGetAll))
+}
+
+// TestHedgedReadsDisabled tests that the reads are not hedged unless
+// both the client and the object enable hedging
+func (suite *ORMTestSuite) TestHedgedReadsDisabled() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClientWithConfig(
+		conn, &ClientConfig{}, &HedgedObject{})
+	suite.NoError(err)
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ *base.Definition,
+			_ []base.Column,
+		) ([]base.Column, error) {
+			time.Sleep(20 * time.Millisecond)
+			return hedgedRow, nil
+		})
+	suite.NoError(client.Get(suite.ctx, &HedgedObject{ID: uint64(1)}))
+
+	hedgingClient, _ := suite.newHedgingClient(conn)
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ *base.Definition,
+			_ []base.Column,
+		) ([]base.Column, error) {
+			time.Sleep(20 * time.Millisecond)
+			return testRow, nil
+		})
+	suite.NoError(hedgingClient.Get(suite.ctx, &ValidObject{ID: uint64(1)}))
+}
@@ -16,7 +16,9 @@ package orm
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
 
@@ -33,6 +35,13 @@ const (
 	// "Object" is the reflection name of the marker interface used to embed DB
 	// annotations in storage objects
 	objectName = "Object"
+
+	// autoTimeCreate is the autotime tag value of a column which is set to
+	// the current time when the object is created
+	autoTimeCreate = "create"
+	// autoTimeUpdate is the autotime tag value of a column which is set to
+	// the current time whenever the object is created or updated
+	autoTimeUpdate = "update"
 )
 
 var (
@@ -40,7 +49,18 @@ var (
 		`primaryKey\s*=\s*([^=]*)((\s+.*=)|$)`)
 	// primaryKeyPattern is regex for the format((PK1,PK2..), CK1, CK2..)
 	primaryKeyPattern = regexp.MustCompile(`\(\s*\((.*)\)(.*)\)`)
-	namePattern       = regexp.MustCompile(`name\s*=\s*(\S*)`)
+	namePattern       = regexp.MustCompile(`name\s*=\s*([^\s,]*)`)
+	autoTimePattern   = regexp.MustCompile(`autotime\s*=\s*([^\s,]*)`)
+	// uniquePattern is regex for the format unique=(C1, C2..)
+	uniquePattern = regexp.MustCompile(`,?\s*unique\s*=\s*\(([^)]*)\)`)
+	// hedgePattern is regex for the format hedge=<duration>
+	hedgePattern = regexp.MustCompile(`,?\s*hedge\s*=\s*([^\s,]*)`)
+	// unindexedPattern is regex for the format unindexed=<limit>
+	unindexedPattern = regexp.MustCompile(`,?\s*unindexed\s*=\s*([^\s,]*)`)
+	// parentPattern is regex for the format parent=<table>
+	parentPattern = regexp.MustCompile(`,?\s*parent\s*=\s*([^\s,]*)`)
+	// onDeletePattern is regex for the format onDelete=<action>
+	onDeletePattern = regexp.MustCompile(`,?\s*onDelete\s*=\s*([^\s,]*)`)
 )
 
 // parseClusteringKeys func parses the clustering key of storage object
@@ -74,7 +94,7 @@ func parsePartitionKey(pkStr string) []string {
 	partitionKeys := strings.Split(pkStr, ",")
 	for _, pk := range partitionKeys {
 		npk := strings.TrimSpace(pk)
-		if len(pk) > 0 {
+		if len(npk) > 0 {
 			pks = append(pks, npk)
 		}
 	}
@@ -130,23 +150,132 @@ func parseNameTag(tag string) (string, error) {
 	return name, nil
 }
 
+// parseAutoTimeTag function parses the optional "autotime" column tag,
+// which is either empty, "create" or "update"
+func parseAutoTimeTag(tag string) (string, error) {
+	matches := autoTimePattern.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		return "", nil
+	}
+	switch matches[1] {
+	case autoTimeCreate, autoTimeUpdate:
+		return matches[1], nil
+	}
+	return "", yarpcerrors.InternalErrorf(
+		"invalid autotime %q in tag %v", matches[1], tag)
+}
+
+// parseUniqueKeys func parses the optional unique constraint of storage
+// object, which should be of the format unique=(C1, C2..). It returns the
+// unique columns and the tag without the unique constraint.
+func parseUniqueKeys(tag string) ([]string, string, error) {
+	matches := uniquePattern.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		return nil, tag, nil
+	}
+	var uniqueKeys []string
+	for _, col := range strings.Split(matches[1], ",") {
+		if col = strings.TrimSpace(col); len(col) > 0 {
+			uniqueKeys = append(uniqueKeys, col)
+		}
+	}
+	if len(uniqueKeys) == 0 {
+		return nil, "", yarpcerrors.InternalErrorf(
+			"empty unique constraint in tag %v", tag)
+	}
+	return uniqueKeys, strings.Replace(tag, matches[0], "", 1), nil
+}
+
+// parseHedgeTag func parses the optional hedging threshold of the reads of
+// a storage object, which should be of the format hedge=<duration>. It
+// returns the threshold, zero if reads are not hedged, and the tag without
+// the hedging option.
+func parseHedgeTag(tag string) (time.Duration, string, error) {
+	matches := hedgePattern.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		return 0, tag, nil
+	}
+	threshold, err := time.ParseDuration(matches[1])
+	if err != nil || threshold <= 0 {
+		return 0, "", yarpcerrors.InternalErrorf(
+			"invalid hedge threshold %q in tag %v", matches[1], tag)
+	}
+	return threshold, strings.Replace(tag, matches[0], "", 1), nil
+}
+
+// parseUnindexedTag func parses the optional guard of the unindexed
+// queries of a storage object, which should be of the format
+// unindexed=<limit>. It returns the max number of objects returned by an
+// unindexed query, zero if they are not allowed, and the tag without the
+// guard.
+func parseUnindexedTag(tag string) (int, string, error) {
+	matches := unindexedPattern.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		return 0, tag, nil
+	}
+	limit, err := strconv.Atoi(matches[1])
+	if err != nil || limit <= 0 {
+		return 0, "", yarpcerrors.InternalErrorf(
+			"invalid unindexed limit %q in tag %v", matches[1], tag)
+	}
+	return limit, strings.Replace(tag, matches[0], "", 1), nil
+}
+
+// parseParentTag func parses the optional parent of a storage object, which
+// should be of the format parent=<table>, and what happens to the object
+// when its parent is deleted, of the format onDelete=cascade|restrict. It
+// returns the table name of the parent, empty if the object has none, the
+// delete action and the tag without both options.
+func parseParentTag(tag string) (string, string, string, error) {
+	onDelete := ""
+	if matches := onDeletePattern.FindStringSubmatch(tag); len(matches) == 2 {
+		switch matches[1] {
+		case OnDeleteCascade, OnDeleteRestrict:
+			onDelete = matches[1]
+		default:
+			return "", "", "", yarpcerrors.InternalErrorf(
+				"invalid onDelete %q in tag %v", matches[1], tag)
+		}
+		tag = strings.Replace(tag, matches[0], "", 1)
+	}
+
+	matches := parentPattern.FindStringSubmatch(tag)
+	if len(matches) != 2 {
+		if onDelete != "" {
+			return "", "", "", yarpcerrors.InternalErrorf(
+				"onDelete without parent in tag %v", tag)
+		}
+		return "", "", tag, nil
+	}
+	if matches[1] == "" {
+		return "", "", "", yarpcerrors.InternalErrorf(
+			"empty parent in tag %v", tag)
+	}
+	return matches[1], onDelete, strings.Replace(tag, matches[0], "", 1), nil
+}
+
 // parseCassandraObjectTag function parses Cassandra specifc ORM annotation on
 // the "Object" field of the storage object
 func parseCassandraObjectTag(ormAnnotation string) (
-	string, *base.PrimaryKey, error) {
-	tag := ormAnnotation
+	string, *base.PrimaryKey, []string, error) {
+	// extract the unique constraint first so that it does not get in the
+	// way of parsing the primary key
+	uniqueKeys, tag, err := parseUniqueKeys(ormAnnotation)
+	if err != nil {
+		return "", nil, nil, err
+	}
 
 	// find the primaryKey
 	matchs := primaryKeyTagPattern.FindStringSubmatch(tag)
 	if len(matchs) != primaryKeyTagPattern.NumSubexp()+1 {
-		return "", nil, yarpcerrors.InternalErrorf(
+		return "", nil, nil, yarpcerrors.InternalErrorf(
 			"primary key pattern mismatch for tag %v", tag)
 	}
 	pkString := matchs[1]
 
 	key, err := parsePrimaryKey(pkString)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 
 	// Remove the partition and clustering key strings from the base tag
@@ -156,8 +285,8 @@ func parseCassandraObjectTag(ormAnnotation string) (
 	tag = strings.Replace(tag, toRemove, "", 1)
 	name, err := parseNameTag(tag)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 
-	return name, key, nil
+	return name, key, uniqueKeys, nil
 }
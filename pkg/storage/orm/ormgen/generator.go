@@ -0,0 +1,380 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ormgen generates typed stores for the storage objects of a
+// package. For every struct embedding base.Object, it emits a store which
+// wraps orm.Client with methods taking the primary key of the object and
+// returning the concrete object type, and a field type which restricts the
+// fields which can be passed to Update to the existing non-key fields.
+package ormgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// objectSuffix is trimmed from the storage object type names to
+	// derive the names of the generated types
+	objectSuffix = "Object"
+
+	ormImportPath     = "github.com/uber/peloton/pkg/storage/orm"
+	contextImportPath = "context"
+)
+
+var (
+	// tableNamePattern extracts the table name from the cassandra tag
+	tableNamePattern = regexp.MustCompile(`name\s*=\s*([a-zA-Z_0-9]+)`)
+	// primaryKeyPattern extracts the partition and clustering keys from the
+	// cassandra tag, e.g. primaryKey=((PK1, PK2), CK1, CK2)
+	primaryKeyPattern = regexp.MustCompile(
+		`primaryKey\s*=\s*\(\s*\(([^)]+)\)\s*,?([^)]*)\)`)
+	// columnNamePattern extracts the column name from the column tag
+	columnNamePattern = regexp.MustCompile(`^\s*name\s*=\s*([a-zA-Z_0-9]+)`)
+)
+
+// reservedNames are the identifiers used by the generated methods, which
+// can not be used as parameter names
+var reservedNames = map[string]bool{
+	"ctx":    true,
+	"s":      true,
+	"obj":    true,
+	"objs":   true,
+	"err":    true,
+	"fields": true,
+	"names":  true,
+	"result": true,
+	"opts":   true,
+}
+
+// field is a column of a storage object.
+type field struct {
+	// Name of the struct field
+	Name string
+	// Column is the name of the DB column
+	Column string
+	// Type is the Go type of the field as written in the source
+	Type string
+	// Param is the name of the parameter used for the field in the
+	// generated methods
+	Param string
+}
+
+// object is a storage object for which a store is generated.
+type object struct {
+	// TypeName is the name of the storage object type
+	TypeName string
+	// Name is the type name without the Object suffix
+	Name string
+	// Table is the name of the DB table
+	Table string
+	// PartitionKeys are the fields making up the partition key
+	PartitionKeys []*field
+	// PrimaryKeys are the partition key fields followed by the
+	// clustering key fields
+	PrimaryKeys []*field
+	// Fields are the fields which are not part of the primary key
+	Fields []*field
+	// imports are the import paths needed by the key field types
+	imports []string
+}
+
+// GenerateDir parses the non-test Go files in dir and returns the source of
+// the typed stores of the storage objects declared in them. The output
+// file itself is skipped, so that a stale version does not get in the way.
+func GenerateDir(dir string, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") &&
+			fi.Name() != filepath.Base(output)
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d",
+			dir, len(pkgs))
+	}
+	for name, pkg := range pkgs {
+		var files []*ast.File
+		var fileNames []string
+		for fileName := range pkg.Files {
+			fileNames = append(fileNames, fileName)
+		}
+		sort.Strings(fileNames)
+		for _, fileName := range fileNames {
+			files = append(files, pkg.Files[fileName])
+		}
+		return Generate(fset, name, files)
+	}
+	return nil, nil
+}
+
+// Generate returns the source of the typed stores of the storage objects
+// declared in the given files of package pkgName.
+func Generate(
+	fset *token.FileSet,
+	pkgName string,
+	files []*ast.File,
+) ([]byte, error) {
+	var objs []*object
+	for _, f := range files {
+		fileObjs, err := parseFile(fset, f)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, fileObjs...)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].TypeName < objs[j].TypeName
+	})
+
+	var buf bytes.Buffer
+	if err := write(&buf, pkgName, objs); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %v", err)
+	}
+	return src, nil
+}
+
+// parseFile returns the storage objects declared in a file.
+func parseFile(fset *token.FileSet, f *ast.File) ([]*object, error) {
+	// map from the name of an import to its path
+	imports := make(map[string]string)
+	for _, imp := range f.Imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		name := filepath.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+
+	var objs []*object
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			obj, err := parseObject(fset, ts.Name.Name, st, imports)
+			if err != nil {
+				return nil, err
+			}
+			if obj != nil {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs, nil
+}
+
+// parseObject returns the storage object of a struct, or nil if the struct
+// does not embed base.Object.
+func parseObject(
+	fset *token.FileSet,
+	typeName string,
+	st *ast.StructType,
+	imports map[string]string,
+) (*object, error) {
+	var objectTag string
+	var fields []*field
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			tag = strings.Trim(f.Tag.Value, "`")
+		}
+		if len(f.Names) == 0 {
+			if isBaseObject(f.Type) {
+				objectTag = reflect.StructTag(tag).Get("cassandra")
+				if objectTag == "" {
+					return nil, fmt.Errorf(
+						"%s: missing cassandra tag", typeName)
+				}
+			}
+			continue
+		}
+		column := reflect.StructTag(tag).Get("column")
+		if column == "" {
+			continue
+		}
+		matches := columnNamePattern.FindStringSubmatch(column)
+		if len(matches) != 2 {
+			return nil, fmt.Errorf("%s.%s: invalid column tag %q",
+				typeName, f.Names[0].Name, column)
+		}
+		var typ bytes.Buffer
+		if err := printer.Fprint(&typ, fset, f.Type); err != nil {
+			return nil, err
+		}
+		for _, name := range f.Names {
+			fields = append(fields, &field{
+				Name:   name.Name,
+				Column: matches[1],
+				Type:   typ.String(),
+				Param:  paramName(name.Name),
+			})
+		}
+	}
+	if objectTag == "" {
+		return nil, nil
+	}
+
+	obj := &object{
+		TypeName: typeName,
+		Name:     strings.TrimSuffix(typeName, objectSuffix),
+	}
+	if obj.Name == "" {
+		obj.Name = typeName
+	}
+	partitionKeys, clusteringKeys, err := parseObjectTag(obj, objectTag)
+	if err != nil {
+		return nil, err
+	}
+
+	byColumn := make(map[string]*field)
+	for _, f := range fields {
+		byColumn[f.Column] = f
+	}
+	keys := make(map[string]bool)
+	for i, column := range append(partitionKeys, clusteringKeys...) {
+		f, ok := byColumn[column]
+		if !ok {
+			return nil, fmt.Errorf("%s: no field for key column %s",
+				typeName, column)
+		}
+		if i < len(partitionKeys) {
+			obj.PartitionKeys = append(obj.PartitionKeys, f)
+		}
+		obj.PrimaryKeys = append(obj.PrimaryKeys, f)
+		keys[column] = true
+	}
+	for _, f := range fields {
+		if !keys[f.Column] {
+			obj.Fields = append(obj.Fields, f)
+		}
+	}
+
+	// the key fields are parameters of the generated methods, so their
+	// types may need additional imports
+	needed := make(map[string]bool)
+	for _, f := range obj.PrimaryKeys {
+		if i := strings.Index(f.Type, "."); i >= 0 {
+			name := strings.TrimLeft(f.Type[:i], "[]*")
+			path, ok := imports[name]
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: unknown package %s",
+					typeName, f.Name, name)
+			}
+			needed[path] = true
+		}
+	}
+	for path := range needed {
+		obj.imports = append(obj.imports, path)
+	}
+	return obj, nil
+}
+
+// parseObjectTag parses the cassandra tag of the base.Object field and
+// returns the partition and clustering key columns.
+func parseObjectTag(obj *object, tag string) ([]string, []string, error) {
+	matches := primaryKeyPattern.FindStringSubmatch(tag)
+	if len(matches) != 3 {
+		return nil, nil, fmt.Errorf("%s: invalid primary key in tag %q",
+			obj.TypeName, tag)
+	}
+	partitionKeys := splitColumns(matches[1])
+	if len(partitionKeys) == 0 {
+		return nil, nil, fmt.Errorf("%s: empty partition key in tag %q",
+			obj.TypeName, tag)
+	}
+	var clusteringKeys []string
+	for _, ck := range splitColumns(matches[2]) {
+		// clustering keys may carry an ordering, e.g. "event_time DESC"
+		clusteringKeys = append(clusteringKeys, strings.Fields(ck)[0])
+	}
+
+	name := tableNamePattern.FindStringSubmatch(
+		strings.Replace(tag, matches[0], "", 1))
+	if len(name) != 2 {
+		return nil, nil, fmt.Errorf("%s: missing table name in tag %q",
+			obj.TypeName, tag)
+	}
+	obj.Table = name[1]
+	return partitionKeys, clusteringKeys, nil
+}
+
+// splitColumns splits a comma separated list of columns.
+func splitColumns(s string) []string {
+	var columns []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); len(c) > 0 {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// isBaseObject returns true if the type expression is base.Object.
+func isBaseObject(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == "base" && sel.Sel.Name == "Object"
+}
+
+// paramName returns the name of the method parameter for a field, which
+// is the field name with its leading initialism in lower case,
+// e.g. JobID -> jobID and SLA -> sla.
+func paramName(fieldName string) string {
+	runes := []rune(fieldName)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		// the last upper case letter starts the next word
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	name := string(runes)
+	if token.IsKeyword(name) || reservedNames[name] {
+		name += "Value"
+	}
+	return name
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ormgen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testObjects = `package objects
+
+import (
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// EventObject is a test storage object
+type EventObject struct {
+	base.Object ` + "`cassandra:\"name=events, primaryKey=((job_id, type), event_time), unique=(job_id, message)\"`" + `
+
+	JobID     string    ` + "`column:\"name=job_id\"`" + `
+	Type      uint32    ` + "`column:\"name=type\"`" + `
+	EventTime time.Time ` + "`column:\"name=event_time\"`" + `
+	Message   string    ` + "`column:\"name=message\"`" + `
+	Ignored   string
+}
+
+// helper is not a storage object
+type helper struct {
+	Name string
+}
+`
+
+type generatorTestSuite struct {
+	suite.Suite
+}
+
+func TestGenerator(t *testing.T) {
+	suite.Run(t, new(generatorTestSuite))
+}
+
+// parse parses source code as a single file
+func (suite *generatorTestSuite) parse(
+	src string) (*token.FileSet, []*ast.File) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "objects.go", src, 0)
+	suite.NoError(err)
+	return fset, []*ast.File{f}
+}
+
+// TestGenerate tests generating the typed store of a storage object
+func (suite *generatorTestSuite) TestGenerate() {
+	fset, files := suite.parse(testObjects)
+	src, err := Generate(fset, "objects", files)
+	suite.NoError(err)
+
+	// the generated code must be valid Go
+	genFset, genFiles := suite.parse(string(src))
+	suite.NotNil(genFset)
+
+	var funcs []string
+	var consts []string
+	for _, decl := range genFiles[0].Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil {
+				name = "EventStore." + name
+			}
+			funcs = append(funcs, name)
+		case *ast.GenDecl:
+			if d.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range d.Specs {
+				consts = append(consts, spec.(*ast.ValueSpec).Names[0].Name)
+			}
+		}
+	}
+	suite.Equal([]string{
+		"NewEventStore",
+		"EventStore.Create",
+		"EventStore.CreateIfNotExists",
+		"EventStore.CreateAsync",
+		"EventStore.Get",
+		"EventStore.GetAll",
+		"EventStore.Update",
+		"EventStore.Delete",
+		"EventStore.DeleteAllInPartition",
+	}, funcs)
+	// key fields can not be updated
+	suite.Equal([]string{"EventFieldMessage"}, consts)
+
+	var imports []string
+	for _, imp := range genFiles[0].Imports {
+		imports = append(imports, imp.Path.Value)
+	}
+	suite.Equal([]string{
+		`"context"`,
+		`"time"`,
+		`"github.com/uber/peloton/pkg/storage/orm"`,
+	}, imports)
+
+	suite.Contains(string(src),
+		"ctx context.Context,\n\tjobID string,\n\ttypeValue uint32,\n"+
+			"\teventTime time.Time,\n) (*EventObject, error) {")
+	suite.Contains(string(src),
+		"objs, err := s.client.GetAll(ctx, &EventObject{\n"+
+			"\t\tJobID: jobID,\n\t\tType:  typeValue,\n\t})")
+	suite.Contains(string(src), "access to the events table")
+}
+
+// TestGenerateErrors tests failures to generate typed stores
+func (suite *generatorTestSuite) TestGenerateErrors() {
+	tt := []struct {
+		msg string
+		src string
+	}{
+		{
+			msg: "missing cassandra tag",
+			src: "package objects\ntype AObject struct {\n" +
+				"base.Object\nID string `column:\"name=id\"`\n}",
+		},
+		{
+			msg: "invalid primary key",
+			src: "package objects\ntype AObject struct {\n" +
+				"base.Object `cassandra:\"name=a, primaryKey=()\"`\n" +
+				"ID string `column:\"name=id\"`\n}",
+		},
+		{
+			msg: "key which is not a column",
+			src: "package objects\ntype AObject struct {\n" +
+				"base.Object `cassandra:\"name=a, primaryKey=((id), day)\"`\n" +
+				"ID string `column:\"name=id\"`\n}",
+		},
+		{
+			msg: "invalid column tag",
+			src: "package objects\ntype AObject struct {\n" +
+				"base.Object `cassandra:\"name=a, primaryKey=((id))\"`\n" +
+				"ID string `column:\"id\"`\n}",
+		},
+		{
+			msg: "key type from unknown package",
+			src: "package objects\ntype AObject struct {\n" +
+				"base.Object `cassandra:\"name=a, primaryKey=((id))\"`\n" +
+				"ID gocql.UUID `column:\"name=id\"`\n}",
+		},
+	}
+	for _, t := range tt {
+		fset, files := suite.parse(t.src)
+		_, err := Generate(fset, "objects", files)
+		suite.Error(err, t.msg)
+	}
+}
+
+// TestGenerateDir tests that the output file and the test files of the
+// package are not parsed
+func (suite *generatorTestSuite) TestGenerateDir() {
+	dir, err := ioutil.TempDir("", "ormgen")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	for name, src := range map[string]string{
+		"objects.go":           testObjects,
+		"objects_test.go":      "package objects_test\n",
+		"stores_generated.go":  "not go",
+		"stores_generated2.go": "package objects\n",
+	} {
+		suite.NoError(ioutil.WriteFile(
+			filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	src, err := GenerateDir(dir, "stores_generated.go")
+	suite.NoError(err)
+	suite.Contains(string(src), "type EventStore struct")
+}
+
+// TestParamName tests deriving parameter names from field names
+func (suite *generatorTestSuite) TestParamName() {
+	for fieldName, param := range map[string]string{
+		"Hostname":  "hostname",
+		"JobID":     "jobID",
+		"ID":        "id",
+		"SLA":       "sla",
+		"UUIDValue": "uuidValue",
+		"Type":      "typeValue",
+		"Err":       "errValue",
+	} {
+		suite.Equal(param, paramName(fieldName))
+	}
+}
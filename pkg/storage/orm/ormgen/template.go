@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ormgen
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// header is written at the top of the generated file, followed by the
+// imports and the store of each storage object.
+const header = `// Code generated by ormgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+`
+
+const storeTemplate = `
+// {{.Name}}Field is an updatable field of {{.TypeName}}.
+type {{.Name}}Field string
+
+{{if .Fields -}}
+// Fields of {{.TypeName}} which can be updated.
+const (
+{{- range .Fields}}
+	{{$.Name}}Field{{.Name}} {{$.Name}}Field = "{{.Name}}"
+{{- end}}
+)
+{{- end}}
+
+// {{.Name}}Store provides typed access to the {{.Table}} table.
+type {{.Name}}Store struct {
+	client orm.Client
+}
+
+// New{{.Name}}Store returns a store using the given ORM client.
+func New{{.Name}}Store(client orm.Client) *{{.Name}}Store {
+	return &{{.Name}}Store{client: client}
+}
+
+// Create creates the {{.TypeName}} in the database.
+func (s *{{.Name}}Store) Create(
+	ctx context.Context,
+	obj *{{.TypeName}},
+) error {
+	return s.client.Create(ctx, obj)
+}
+
+// CreateIfNotExists creates the {{.TypeName}} in the database
+// if it does not exist yet.
+func (s *{{.Name}}Store) CreateIfNotExists(
+	ctx context.Context,
+	obj *{{.TypeName}},
+) error {
+	return s.client.CreateIfNotExists(ctx, obj)
+}
+
+// CreateAsync buffers the {{.TypeName}} to be created in the
+// database in a batch with other objects of its table.
+func (s *{{.Name}}Store) CreateAsync(
+	ctx context.Context,
+	obj *{{.TypeName}},
+) error {
+	return s.client.CreateAsync(ctx, obj)
+}
+
+// Get reads the {{.TypeName}} with the given primary key.
+func (s *{{.Name}}Store) Get(
+	ctx context.Context,
+{{- range .PrimaryKeys}}
+	{{.Param}} {{.Type}},
+{{- end}}
+) (*{{.TypeName}}, error) {
+	obj := &{{.TypeName}}{
+{{- range .PrimaryKeys}}
+		{{.Name}}: {{.Param}},
+{{- end}}
+	}
+	if err := s.client.Get(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetAll reads all the {{.TypeName}}s of the given partition.
+func (s *{{.Name}}Store) GetAll(
+	ctx context.Context,
+{{- range .PartitionKeys}}
+	{{.Param}} {{.Type}},
+{{- end}}
+) ([]*{{.TypeName}}, error) {
+	objs, err := s.client.GetAll(ctx, &{{.TypeName}}{
+{{- range .PartitionKeys}}
+		{{.Name}}: {{.Param}},
+{{- end}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*{{.TypeName}}, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*{{.TypeName}}))
+	}
+	return result, nil
+}
+
+// Update updates the given fields of the {{.TypeName}} in the
+// database, or all its fields if none are given.
+func (s *{{.Name}}Store) Update(
+	ctx context.Context,
+	obj *{{.TypeName}},
+	fields ...{{.Name}}Field,
+) error {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, string(f))
+	}
+	return s.client.Update(ctx, obj, names...)
+}
+
+// Delete deletes the {{.TypeName}} with the given primary key.
+func (s *{{.Name}}Store) Delete(
+	ctx context.Context,
+{{- range .PrimaryKeys}}
+	{{.Param}} {{.Type}},
+{{- end}}
+) error {
+	return s.client.Delete(ctx, &{{.TypeName}}{
+{{- range .PrimaryKeys}}
+		{{.Name}}: {{.Param}},
+{{- end}}
+	})
+}
+
+// DeleteAllInPartition deletes all the {{.TypeName}}s of the
+// given partition and returns the number of deleted objects.
+func (s *{{.Name}}Store) DeleteAllInPartition(
+	ctx context.Context,
+{{- range .PartitionKeys}}
+	{{.Param}} {{.Type}},
+{{- end}}
+	opts ...orm.DeleteAllOption,
+) (int, error) {
+	return s.client.DeleteAllInPartition(ctx, &{{.TypeName}}{
+{{- range .PartitionKeys}}
+		{{.Name}}: {{.Param}},
+{{- end}}
+	}, opts...)
+}
+`
+
+var (
+	headerTmpl = template.Must(template.New("header").Parse(header))
+	storeTmpl  = template.Must(template.New("store").Parse(storeTemplate))
+)
+
+// write writes the unformatted source of the typed stores of the objects.
+func write(w io.Writer, pkgName string, objs []*object) error {
+	paths := map[string]bool{
+		contextImportPath: true,
+		ormImportPath:     true,
+	}
+	for _, obj := range objs {
+		for _, path := range obj.imports {
+			paths[path] = true
+		}
+	}
+	// standard library imports are grouped separately
+	var stdImports, imports []string
+	for path := range paths {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			imports = append(imports, path)
+		} else {
+			stdImports = append(stdImports, path)
+		}
+	}
+	sort.Strings(stdImports)
+	sort.Strings(imports)
+
+	if err := headerTmpl.Execute(w, struct {
+		Package    string
+		StdImports []string
+		Imports    []string
+	}{pkgName, stdImports, imports}); err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := storeTmpl.Execute(w, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// PoolPath is the default endpoint dumping the connection pool of the
+	// connector and its backend hosts. A POST to it resizes the pool.
+	PoolPath = "/debug/orm/pool"
+
+	_poolMaxConnsPerHostParam = "max_conns_per_host"
+	_poolMaxQueueDepthParam   = "max_queue_depth"
+)
+
+// PoolSize is the size of the connection pool of a connector.
+type PoolSize struct {
+	// MaxConnsPerHost is the number of connections to every backend host.
+	MaxConnsPerHost int `json:"max_conns_per_host"`
+	// MaxQueueDepth is the maximum number of queries in flight to every
+	// backend host, no limit if zero.
+	MaxQueueDepth int `json:"max_queue_depth"`
+}
+
+// BackendHostStats is the statistics of the queries sent to one backend
+// host since the start of the process.
+type BackendHostStats struct {
+	// Host is the address of the backend host.
+	Host string `json:"host"`
+	// InFlight is the number of queries in flight to the host.
+	InFlight int64 `json:"in_flight"`
+	// Queries is the number of queries sent to the host.
+	Queries uint64 `json:"queries"`
+	// Errors is the number of these queries which failed.
+	Errors uint64 `json:"errors"`
+	// Rejected is the number of times the host was skipped because its
+	// queue was full.
+	Rejected uint64 `json:"rejected"`
+	// AverageLatency is the average latency of the queries.
+	AverageLatency time.Duration `json:"average_latency_ns"`
+}
+
+// PoolStatus is the size of the connection pool of a connector and the
+// statistics of its backend hosts.
+type PoolStatus struct {
+	PoolSize
+	// Hosts is the statistics of every backend host, by address.
+	Hosts []*BackendHostStats `json:"hosts"`
+}
+
+// Pool is implemented by the connectors which can report the statistics
+// of their backend hosts and resize their connection pool at runtime.
+type Pool interface {
+	// PoolStatus returns the current size of the pool and the statistics
+	// of the backend hosts.
+	PoolStatus() *PoolStatus
+	// ResizePool changes the size of the pool.
+	ResizePool(size PoolSize) error
+}
+
+// ValidatePoolSize returns an error if the pool size is invalid.
+func ValidatePoolSize(size PoolSize) error {
+	if size.MaxConnsPerHost <= 0 {
+		return fmt.Errorf("%s must be positive", _poolMaxConnsPerHostParam)
+	}
+	if size.MaxQueueDepth < 0 {
+		return fmt.Errorf("%s must not be negative", _poolMaxQueueDepthParam)
+	}
+	return nil
+}
+
+// PoolHandler returns a handler dumping the status of the connection pool
+// as JSON. On POST, the pool is first resized using the max_conns_per_host
+// and max_queue_depth parameters, the sizes which are not given being kept.
+func PoolHandler(p Pool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if err := resizePool(p, r); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, err.Error())
+				return
+			}
+		}
+
+		body, err := json.MarshalIndent(p.PoolStatus(), "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// resizePool resizes the pool using the parameters of the request.
+func resizePool(p Pool, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	size := p.PoolStatus().PoolSize
+	for param, value := range map[string]*int{
+		_poolMaxConnsPerHostParam: &size.MaxConnsPerHost,
+		_poolMaxQueueDepthParam:   &size.MaxQueueDepth,
+	} {
+		s := r.Form.Get(param)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", param, s)
+		}
+		*value = v
+	}
+	if err := ValidatePoolSize(size); err != nil {
+		return err
+	}
+	return p.ResizePool(size)
+}
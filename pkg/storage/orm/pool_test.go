@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// fakePool is a connection pool which records its resizes
+type fakePool struct {
+	status    *PoolStatus
+	resizeErr error
+	resized   []PoolSize
+}
+
+func (p *fakePool) PoolStatus() *PoolStatus {
+	return p.status
+}
+
+func (p *fakePool) ResizePool(size PoolSize) error {
+	if p.resizeErr != nil {
+		return p.resizeErr
+	}
+	p.resized = append(p.resized, size)
+	return nil
+}
+
+// TestPoolHandler tests dumping the status of the connection pool
+func (suite *ORMTestSuite) TestPoolHandler() {
+	status := &PoolStatus{
+		PoolSize: PoolSize{MaxConnsPerHost: 3, MaxQueueDepth: 100},
+		Hosts: []*BackendHostStats{{
+			Host:           "10.0.0.1",
+			InFlight:       2,
+			Queries:        10,
+			Errors:         1,
+			AverageLatency: time.Millisecond,
+		}},
+	}
+	pool := &fakePool{status: status}
+
+	w := httptest.NewRecorder()
+	PoolHandler(pool)(w, httptest.NewRequest("GET", PoolPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	var dumped PoolStatus
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &dumped))
+	suite.Equal(*status.Hosts[0], *dumped.Hosts[0])
+	suite.Equal(status.PoolSize, dumped.PoolSize)
+	suite.Empty(pool.resized)
+}
+
+// TestPoolHandlerResize tests resizing the connection pool, keeping the
+// sizes which are not given
+func (suite *ORMTestSuite) TestPoolHandlerResize() {
+	pool := &fakePool{status: &PoolStatus{
+		PoolSize: PoolSize{MaxConnsPerHost: 3, MaxQueueDepth: 100},
+	}}
+
+	w := httptest.NewRecorder()
+	PoolHandler(pool)(w, httptest.NewRequest(
+		"POST", PoolPath+"?max_queue_depth=10", nil))
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal(
+		[]PoolSize{{MaxConnsPerHost: 3, MaxQueueDepth: 10}}, pool.resized)
+}
+
+// TestPoolHandlerResizeErrors tests the failures to resize the pool
+func (suite *ORMTestSuite) TestPoolHandlerResizeErrors() {
+	pool := &fakePool{
+		status: &PoolStatus{
+			PoolSize: PoolSize{MaxConnsPerHost: 3, MaxQueueDepth: 100},
+		},
+		resizeErr: errors.New("failed to create session"),
+	}
+
+	for _, query := range []string{
+		"max_conns_per_host=0",
+		"max_queue_depth=-1",
+		"max_queue_depth=many",
+		"max_conns_per_host=5",
+	} {
+		w := httptest.NewRecorder()
+		PoolHandler(pool)(w, httptest.NewRequest(
+			"POST", PoolPath+"?"+query, nil))
+		suite.Equal(http.StatusBadRequest, w.Code, query)
+		suite.True(strings.TrimSpace(w.Body.String()) != "", query)
+	}
+}
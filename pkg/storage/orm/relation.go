@@ -0,0 +1,304 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Actions on the children of a storage object deleted with
+// DeleteWithChildren, set with the onDelete option of the children.
+const (
+	// OnDeleteCascade deletes the children with their parent
+	OnDeleteCascade = "cascade"
+	// OnDeleteRestrict prevents deleting a parent which has children
+	OnDeleteRestrict = "restrict"
+)
+
+// DefaultChildrenPageSize is the default number of children returned by a
+// page of GetChildren.
+const DefaultChildrenPageSize = 1000
+
+// Pager is implemented by the connectors which can read the rows of a
+// partition page by page. The children of the objects of a client whose
+// connector is not a Pager are paged in memory, the whole partition being
+// read for every page.
+type Pager interface {
+	// GetAllPage fetches at most pageSize rows by partition key of base
+	// object, starting at the page token, the first page if empty. It
+	// returns the rows and the token of the next page, empty if the rows
+	// are the last ones.
+	GetAllPage(
+		ctx context.Context,
+		e *base.Definition,
+		keys []base.Column,
+		pageSize int,
+		pageToken []byte,
+	) ([][]base.Column, []byte, error)
+}
+
+// ChildrenOption is an option of GetChildren.
+type ChildrenOption func(*childrenOptions)
+
+// childrenOptions are the options of GetChildren.
+type childrenOptions struct {
+	// max number of children returned, all of them if zero
+	pageSize int
+	// token of the page to return, the first one if empty
+	pageToken []byte
+}
+
+// WithPageSize sets the max number of children returned by GetChildren. A
+// size of zero returns all the children at once.
+func WithPageSize(size int) ChildrenOption {
+	return func(o *childrenOptions) {
+		o.pageSize = size
+	}
+}
+
+// WithPageToken sets the token of the page of children returned by
+// GetChildren, as returned by the previous page.
+func WithPageToken(token []byte) ChildrenOption {
+	return func(o *childrenOptions) {
+		o.pageToken = token
+	}
+}
+
+// relation is a storage object which has a parent.
+type relation struct {
+	// table of the child object
+	child *Table
+	// type of the child object
+	typ reflect.Type
+}
+
+// newObject returns a new child object in the partition of the parent.
+func (r *relation) newObject(parent *Table, e base.Object) base.Object {
+	obj := reflect.New(r.typ).Interface().(base.Object)
+	v := reflect.ValueOf(e).Elem()
+	o := reflect.ValueOf(obj).Elem()
+	for _, pk := range r.child.Key.PartitionKeys {
+		o.FieldByName(r.child.ColToField[pk]).Set(
+			v.FieldByName(parent.ColToField[pk]))
+	}
+	return obj
+}
+
+// buildRelations checks the relationships between the storage objects of
+// the index and returns the children of the objects by table name, sorted
+// by table name. The partition key of a child must be made of columns of
+// the primary key of its parent, of the same type. Relationships with a
+// parent which is not in the index are ignored.
+func buildRelations(
+	objectIndex map[reflect.Type]*Table,
+) (map[string][]*relation, error) {
+	byName := make(map[string]*Table, len(objectIndex))
+	for _, table := range objectIndex {
+		byName[table.Name] = table
+	}
+
+	relations := make(map[string][]*relation)
+	for typ, table := range objectIndex {
+		if table.Parent == "" {
+			continue
+		}
+		parent, ok := byName[table.Parent]
+		if !ok {
+			continue
+		}
+		if parent == table {
+			return nil, yarpcerrors.InternalErrorf(
+				"object %s is its own parent", table.Name)
+		}
+		for _, pk := range table.Key.PartitionKeys {
+			if !parent.isKeyColumn(pk) ||
+				parent.ColumnToType[pk] != table.ColumnToType[pk] {
+				return nil, yarpcerrors.InternalErrorf(
+					"partition key %s of %s is not a key of its parent %s",
+					pk, table.Name, parent.Name)
+			}
+		}
+		relations[parent.Name] = append(
+			relations[parent.Name], &relation{child: table, typ: typ})
+	}
+	for _, children := range relations {
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].child.Name < children[j].child.Name
+		})
+	}
+	return relations, nil
+}
+
+// isKeyColumn returns true if the column is part of the primary key.
+func (t *Table) isKeyColumn(column string) bool {
+	for _, col := range t.Key.Columns() {
+		if col == column {
+			return true
+		}
+	}
+	return false
+}
+
+// getRelation returns the relationship between the storage objects, an
+// error if the child object is not a child of the parent object.
+func (c *client) getRelation(
+	parent base.Object,
+	child base.Object,
+) (*Table, *relation, error) {
+	parentTable, err := c.getTable(parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	childTable, err := c.getTable(child)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range c.relations[parentTable.Name] {
+		if r.child == childTable {
+			return parentTable, r, nil
+		}
+	}
+	return nil, nil, yarpcerrors.InvalidArgumentErrorf(
+		"%s is not a child of %s", childTable.Name, parentTable.Name)
+}
+
+// GetChildren fetches a page of the child objects of the given parent,
+// which must contain the values of the primary key columns making the
+// partition key of the children. The type of the children is the type of
+// the given child object. It returns the children and the token of the
+// next page, empty if they are the last ones.
+func (c *client) GetChildren(
+	ctx context.Context,
+	parent base.Object,
+	child base.Object,
+	opts ...ChildrenOption,
+) ([]base.Object, []byte, error) {
+	parentTable, r, err := c.getRelation(parent, child)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := &childrenOptions{pageSize: DefaultChildrenPageSize}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.pageSize < 0 {
+		return nil, nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid page size %d", options.pageSize)
+	}
+
+	table := r.child
+	keyRow := table.GetPartitionKeyRowFromObject(
+		r.newObject(parentTable, parent))
+
+	opCtx, done := c.begin(ctx, table, OpGetChildren, keyRow)
+	rows, next, err := c.getAllPage(opCtx, table, keyRow, options)
+	done(err, rows...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return table.BuildObjectsFromRows(child, rows), next, nil
+}
+
+// getAllPage reads a page of the rows of the partition with the connector
+// if it is a Pager, and otherwise reads the whole partition and returns
+// the page, the token being the offset of the page.
+func (c *client) getAllPage(
+	ctx context.Context,
+	table *Table,
+	keyRow []base.Column,
+	options *childrenOptions,
+) ([][]base.Column, []byte, error) {
+	if pager, ok := c.connector.(Pager); ok && options.pageSize > 0 {
+		return pager.GetAllPage(ctx, &table.Definition, keyRow,
+			options.pageSize, options.pageToken)
+	}
+
+	offset := 0
+	if len(options.pageToken) > 0 {
+		var err error
+		offset, err = strconv.Atoi(string(options.pageToken))
+		if err != nil || offset < 0 {
+			return nil, nil, yarpcerrors.InvalidArgumentErrorf(
+				"invalid page token %q", options.pageToken)
+		}
+	}
+	rows, err := c.connector.GetAll(ctx, &table.Definition, keyRow)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	if options.pageSize == 0 || len(rows) <= options.pageSize {
+		return rows, nil, nil
+	}
+	next := []byte(strconv.Itoa(offset + options.pageSize))
+	return rows[:options.pageSize], next, nil
+}
+
+// DeleteWithChildren deletes the storage object along with its children
+// tagged with onDelete=cascade. It fails without deleting anything if it
+// has children tagged with onDelete=restrict. The children are deleted
+// before the parent, partition by partition with DeleteAllInPartition and
+// the given options. It returns the number of children deleted.
+func (c *client) DeleteWithChildren(
+	ctx context.Context,
+	e base.Object,
+	opts ...DeleteAllOption,
+) (int, error) {
+	table, err := c.getTable(e)
+	if err != nil {
+		return 0, err
+	}
+	relations := c.relations[table.Name]
+
+	// check every restricting child before deleting anything
+	for _, r := range relations {
+		if r.child.OnParentDelete != OnDeleteRestrict {
+			continue
+		}
+		children, _, err := c.GetChildren(
+			ctx, e, r.newObject(table, e), WithPageSize(1))
+		if err != nil {
+			return 0, err
+		}
+		if len(children) > 0 {
+			return 0, yarpcerrors.FailedPreconditionErrorf(
+				"%s has children in %s", table.Name, r.child.Name)
+		}
+	}
+
+	deleted := 0
+	for _, r := range relations {
+		if r.child.OnParentDelete != OnDeleteCascade {
+			continue
+		}
+		count, err := c.DeleteAllInPartition(ctx, r.newObject(table, e), opts...)
+		deleted += count
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, c.Delete(ctx, e)
+}
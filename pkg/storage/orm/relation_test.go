@@ -0,0 +1,216 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// ChildObject is a child of ValidObject deleted along with it
+type ChildObject struct {
+	base.Object `cassandra:"name=child_object, primaryKey=((id), seq), parent=valid_object, onDelete=cascade"`
+	ID          uint64 `column:"name=id"`
+	Seq         uint64 `column:"name=seq"`
+	Data        string `column:"name=data"`
+}
+
+// RestrictedChildObject is a child of ValidObject preventing its delete
+type RestrictedChildObject struct {
+	base.Object `cassandra:"name=restricted_child_object, primaryKey=((id), seq), onDelete=restrict, parent=valid_object"`
+	ID          uint64 `column:"name=id"`
+	Seq         uint64 `column:"name=seq"`
+}
+
+// KeptChildObject is a child of ValidObject kept when it is deleted
+type KeptChildObject struct {
+	base.Object `cassandra:"name=kept_child_object, primaryKey=((id), seq), parent=valid_object"`
+	ID          uint64 `column:"name=id"`
+	Seq         uint64 `column:"name=seq"`
+}
+
+// InvalidChildObject has a partition key which is not a key of its parent
+type InvalidChildObject struct {
+	base.Object `cassandra:"name=invalid_child_object, primaryKey=((data), seq), parent=valid_object"`
+	Data        string `column:"name=data"`
+	Seq         uint64 `column:"name=seq"`
+}
+
+// pagerConnector is a connector which reads partitions page by page
+type pagerConnector struct {
+	*connectormocks.MockConnector
+	*connectormocks.MockPager
+}
+
+// childRow returns a row of ChildObject
+func childRow(seq uint64) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: uint64(1)},
+		{Name: "seq", Value: seq},
+		{Name: "data", Value: "testdata"},
+	}
+}
+
+// childPartition is the partition of the children of the parent with id 1
+var childPartition = []base.Column{{Name: "id", Value: uint64(1)}}
+
+// TestGetChildren tests paging the children of an object with a connector
+// which reads whole partitions
+func (suite *ORMTestSuite) TestGetChildren() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, err := NewClient(conn, &ValidObject{}, &ChildObject{})
+	suite.NoError(err)
+
+	conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), childPartition).
+		Return([][]base.Column{childRow(1), childRow(2), childRow(3)}, nil).
+		Times(2)
+	parent := &ValidObject{ID: uint64(1), Name: "test"}
+	children, token, err := client.GetChildren(
+		suite.ctx, parent, &ChildObject{}, WithPageSize(2))
+	suite.NoError(err)
+	suite.Len(children, 2)
+	suite.Equal(uint64(1), children[0].(*ChildObject).Seq)
+	suite.Equal(uint64(2), children[1].(*ChildObject).Seq)
+	suite.NotEmpty(token)
+
+	children, token, err = client.GetChildren(
+		suite.ctx, parent, &ChildObject{},
+		WithPageSize(2), WithPageToken(token))
+	suite.NoError(err)
+	suite.Len(children, 1)
+	suite.Equal(uint64(3), children[0].(*ChildObject).Seq)
+	suite.Equal("testdata", children[0].(*ChildObject).Data)
+	suite.Empty(token)
+}
+
+// TestGetChildrenPager tests paging the children of an object with a
+// connector which reads partitions page by page
+func (suite *ORMTestSuite) TestGetChildrenPager() {
+	defer suite.ctrl.Finish()
+	conn := &pagerConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockPager:     connectormocks.NewMockPager(suite.ctrl),
+	}
+	client, err := NewClient(conn, &ValidObject{}, &ChildObject{})
+	suite.NoError(err)
+
+	conn.MockPager.EXPECT().GetAllPage(
+		gomock.Any(), gomock.Any(), childPartition, 2, []byte("state")).
+		Return([][]base.Column{childRow(3), childRow(4)}, []byte("next"), nil)
+	children, token, err := client.GetChildren(
+		suite.ctx, &ValidObject{ID: uint64(1)}, &ChildObject{},
+		WithPageSize(2), WithPageToken([]byte("state")))
+	suite.NoError(err)
+	suite.Len(children, 2)
+	suite.Equal(uint64(4), children[1].(*ChildObject).Seq)
+	suite.Equal([]byte("next"), token)
+}
+
+// TestGetChildrenInvalid tests that the children of an object are not
+// read for objects which are not its children, nor with an invalid page
+func (suite *ORMTestSuite) TestGetChildrenInvalid() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, err := NewClient(
+		conn, &ValidObject{}, &ChildObject{}, &HedgedObject{})
+	suite.NoError(err)
+
+	parent := &ValidObject{ID: uint64(1)}
+	_, _, err = client.GetChildren(suite.ctx, parent, &HedgedObject{})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	_, _, err = client.GetChildren(
+		suite.ctx, parent, &ChildObject{}, WithPageSize(-1))
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	_, _, err = client.GetChildren(
+		suite.ctx, parent, &ChildObject{}, WithPageToken([]byte("first")))
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestDeleteWithChildren tests that the children of an object tagged with
+// onDelete=cascade are deleted before it, and the other children are kept
+func (suite *ORMTestSuite) TestDeleteWithChildren() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, err := NewClient(
+		conn, &ValidObject{}, &ChildObject{}, &KeptChildObject{})
+	suite.NoError(err)
+
+	gomock.InOrder(
+		conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), childPartition).
+			DoAndReturn(func(
+				_ context.Context,
+				e *base.Definition,
+				_ []base.Column,
+			) ([][]base.Column, error) {
+				suite.Equal("child_object", e.Name)
+				return [][]base.Column{childRow(1), childRow(2)}, nil
+			}),
+		conn.EXPECT().Delete(gomock.Any(), gomock.Any(), childPartition).
+			Do(func(_ context.Context, e *base.Definition, _ []base.Column) {
+				suite.Equal("child_object", e.Name)
+			}).Return(nil),
+		conn.EXPECT().Delete(gomock.Any(), gomock.Any(), []base.Column{
+			{Name: "id", Value: uint64(1)},
+			{Name: "name", Value: "test"},
+		}).Return(nil),
+	)
+	count, err := client.DeleteWithChildren(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "test"})
+	suite.NoError(err)
+	suite.Equal(2, count)
+}
+
+// TestDeleteWithChildrenRestricted tests that an object is not deleted if
+// it has children tagged with onDelete=restrict
+func (suite *ORMTestSuite) TestDeleteWithChildrenRestricted() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, err := NewClient(
+		conn, &ValidObject{}, &ChildObject{}, &RestrictedChildObject{})
+	suite.NoError(err)
+
+	conn.EXPECT().GetAll(gomock.Any(), gomock.Any(), childPartition).
+		DoAndReturn(func(
+			_ context.Context,
+			e *base.Definition,
+			_ []base.Column,
+		) ([][]base.Column, error) {
+			suite.Equal("restricted_child_object", e.Name)
+			return [][]base.Column{childRow(1)}, nil
+		})
+	_, err = client.DeleteWithChildren(
+		suite.ctx, &ValidObject{ID: uint64(1), Name: "test"})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestInvalidRelation tests that a client is not created with a child
+// whose partition key is not a key of its parent, and that the children
+// of objects missing from the client are ignored
+func (suite *ORMTestSuite) TestInvalidRelation() {
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	_, err := NewClient(conn, &ValidObject{}, &InvalidChildObject{})
+	suite.Error(err)
+
+	_, err = NewClient(conn, &InvalidChildObject{})
+	suite.NoError(err)
+}
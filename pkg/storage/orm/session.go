@@ -0,0 +1,253 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	// DefaultSessionReadRetries is the default number of retries of a
+	// session read which misses a write of its session.
+	DefaultSessionReadRetries = 3
+	// DefaultSessionReadRetryInterval is the default interval between the
+	// retries of a session read.
+	DefaultSessionReadRetryInterval = 20 * time.Millisecond
+)
+
+// SessionReadConfig is the config of the reads of the partitions written
+// earlier in their session.
+type SessionReadConfig struct {
+	// Retries is the number of times a session read which misses a write
+	// of its session is retried, DefaultSessionReadRetries if zero.
+	Retries int
+	// RetryInterval is the interval between the retries of a session
+	// read, DefaultSessionReadRetryInterval if zero.
+	RetryInterval time.Duration
+}
+
+// Session is a logical session of ORM operations, e.g. the handling of a
+// request which creates a job and then reads it, whose reads observe the
+// writes made earlier in the session on an eventually consistent backend.
+// The client records a write token for every partition written in the
+// session, and reads a written partition as a session read: connectors
+// escalate the consistency of the session reads (see IsSessionRead), and
+// a session read which misses the write is retried, as told by the
+// autotime=update fields of the storage object. A Session is safe for
+// concurrent use.
+type Session struct {
+	sync.Mutex
+
+	// write tokens of the partitions written in the session, by table
+	// name and hash of the partition key
+	tokens map[string]writeToken
+}
+
+// writeToken records the last write of a partition in a session.
+type writeToken struct {
+	// time of the write, as set in the autotime=update fields
+	time time.Time
+	// whether the write deleted the rows
+	deleted bool
+}
+
+type sessionKey struct{}
+
+type sessionReadKey struct{}
+
+// NewSession returns a new session without writes.
+func NewSession() *Session {
+	return &Session{tokens: make(map[string]writeToken)}
+}
+
+// WithSession returns a copy of the context carrying the session, so that
+// the ORM operations made with the context are part of the session.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext returns the session carried by the context.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// IsSessionRead tells whether a read is the read of a partition written
+// earlier in its session. Connectors should serve it at a consistency
+// level which observes the writes, e.g. a quorum of replicas.
+func IsSessionRead(ctx context.Context) bool {
+	read, _ := ctx.Value(sessionReadKey{}).(bool)
+	return read
+}
+
+// tokenKey returns the key of the write token of a partition of a table.
+func tokenKey(table string, partitionKeyRow []base.Column) string {
+	return table + "/" + keyHash(partitionKeyRow)
+}
+
+// recordWrite records a write of the partition of the table at t.
+func (s *Session) recordWrite(
+	table string,
+	partitionKeyRow []base.Column,
+	t time.Time,
+	deleted bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.tokens[tokenKey(table, partitionKeyRow)] = writeToken{
+		time:    t,
+		deleted: deleted,
+	}
+}
+
+// token returns the write token of the partition of the table, false if
+// the partition was not written in the session.
+func (s *Session) token(
+	table string,
+	partitionKeyRow []base.Column) (writeToken, bool) {
+	s.Lock()
+	defer s.Unlock()
+	token, ok := s.tokens[tokenKey(table, partitionKeyRow)]
+	return token, ok
+}
+
+// sessionMetrics are the metrics of the session reads of a storage object.
+type sessionMetrics struct {
+	// number of session reads
+	reads tally.Counter
+	// number of retries of session reads missing a write
+	retries tally.Counter
+	// number of session reads which missed a write after all retries
+	stale tally.Counter
+}
+
+func newSessionMetrics(scope tally.Scope, table string) *sessionMetrics {
+	s := scope.Tagged(map[string]string{"table": table})
+	return &sessionMetrics{
+		reads:   s.Counter("session_reads"),
+		retries: s.Counter("session_read_retries"),
+		stale:   s.Counter("session_reads_stale"),
+	}
+}
+
+// recordSessionWrite records the write of the partition of e in the
+// session of the context, if any, at the time set in its autotime fields.
+func (c *client) recordSessionWrite(
+	ctx context.Context,
+	table *Table,
+	e base.Object,
+	t time.Time,
+	deleted bool) {
+	if s, ok := SessionFromContext(ctx); ok {
+		s.recordWrite(
+			table.Name, table.GetPartitionKeyRowFromObject(e), t, deleted)
+	}
+}
+
+// sessionRead calls read, as a session read if the partition of e was
+// written earlier in the session of the context. A session read which
+// misses the write is retried, unless the write deleted the partition.
+// The write is missed if no row read has an autotime=update field at or
+// after the time of the write, at millisecond precision like Cassandra
+// timestamps. Reads of objects without autotime=update fields are not
+// retried.
+func (c *client) sessionRead(
+	ctx context.Context,
+	table *Table,
+	e base.Object,
+	read func(ctx context.Context) ([][]base.Column, error),
+) ([][]base.Column, error) {
+	s, ok := SessionFromContext(ctx)
+	if !ok {
+		return read(ctx)
+	}
+	token, ok := s.token(table.Name, table.GetPartitionKeyRowFromObject(e))
+	if !ok {
+		return read(ctx)
+	}
+
+	ctx = context.WithValue(ctx, sessionReadKey{}, true)
+	metrics := c.sessions[table.Name]
+	metrics.reads.Inc(1)
+	for attempt := 0; ; attempt++ {
+		rows, err := read(ctx)
+		if token.deleted ||
+			len(table.UpdateTimeFields) == 0 ||
+			table.observes(rows, token.time) {
+			return rows, err
+		}
+		if attempt >= c.sessionReads.Retries {
+			metrics.stale.Inc(1)
+			return rows, err
+		}
+		metrics.retries.Inc(1)
+		select {
+		case <-ctx.Done():
+			return rows, err
+		case <-time.After(c.sessionReads.RetryInterval):
+		}
+	}
+}
+
+// observes tells whether one of the rows has an autotime=update field at
+// or after t, at millisecond precision.
+func (t *Table) observes(rows [][]base.Column, at time.Time) bool {
+	at = at.Truncate(time.Millisecond)
+	for _, row := range rows {
+		for _, col := range row {
+			field, ok := t.ColToField[col.Name]
+			if !ok || !t.isUpdateTimeField(field) {
+				continue
+			}
+			if updated, ok := columnTime(col.Value); ok && !updated.Before(at) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnTime returns the time of a column value, which connectors return
+// as a time.Time or as a pointer to it, e.g. *time.Time for Cassandra.
+func columnTime(value interface{}) (time.Time, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return time.Time{}, false
+	}
+	t, ok := v.Interface().(time.Time)
+	return t, ok
+}
+
+// isUpdateTimeField tells whether the field is tagged autotime=update.
+func (t *Table) isUpdateTimeField(field string) bool {
+	for _, f := range t.UpdateTimeFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// newSessionClient returns a client of AutoTimeObject and ValidObject
+// retrying the session reads twice, and the scope of its metrics
+func (suite *ORMTestSuite) newSessionClient(
+	conn Connector) (Client, tally.TestScope) {
+	scope := tally.NewTestScope("", map[string]string{})
+	client, err := NewClientWithConfig(conn, &ClientConfig{
+		SessionReads: SessionReadConfig{
+			Retries:       2,
+			RetryInterval: time.Millisecond,
+		},
+		Scope: scope,
+	}, &AutoTimeObject{}, &ValidObject{})
+	suite.NoError(err)
+	return client, scope
+}
+
+// autoTimeRowAt returns a row of AutoTimeObject last updated at t
+func autoTimeRowAt(t time.Time) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: uint64(1)},
+		{Name: "data", Value: "testdata"},
+		{Name: "creation_time", Value: time.Unix(1000, 0).UTC()},
+		{Name: "update_time", Value: t},
+	}
+}
+
+// TestSessionTokens tests that a session records the last write of a
+// partition
+func (suite *ORMTestSuite) TestSessionTokens() {
+	s := NewSession()
+	key := []base.Column{{Name: "id", Value: uint64(1)}}
+	other := []base.Column{{Name: "id", Value: uint64(2)}}
+	now := time.Now().UTC()
+
+	_, ok := s.token("autotime_object", key)
+	suite.False(ok)
+
+	s.recordWrite("autotime_object", key, now, false)
+	token, ok := s.token("autotime_object", key)
+	suite.True(ok)
+	suite.Equal(now, token.time)
+	suite.False(token.deleted)
+
+	_, ok = s.token("autotime_object", other)
+	suite.False(ok)
+	_, ok = s.token("valid_object", key)
+	suite.False(ok)
+
+	s.recordWrite("autotime_object", key, now.Add(time.Second), true)
+	token, ok = s.token("autotime_object", key)
+	suite.True(ok)
+	suite.True(token.deleted)
+
+	ctx := WithSession(suite.ctx, s)
+	fromCtx, ok := SessionFromContext(ctx)
+	suite.True(ok)
+	suite.Equal(s, fromCtx)
+	_, ok = SessionFromContext(suite.ctx)
+	suite.False(ok)
+}
+
+// TestSessionReadRetriesUntilWriteObserved tests that the read of an
+// object created earlier in the session is a session read retried until
+// the write is observed
+func (suite *ORMTestSuite) TestSessionReadRetriesUntilWriteObserved() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	e := &AutoTimeObject{ID: 1, Data: "testdata"}
+	suite.NoError(client.Create(ctx, e))
+	written := e.UpdateTime
+
+	gomock.InOrder(
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, _ *base.Definition, _ []base.Column) {
+				suite.True(IsSessionRead(ctx))
+			}).Return(nil, nil),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(autoTimeRowAt(written.Add(-time.Hour)), nil),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(autoTimeRowAt(written), nil),
+	)
+
+	read := &AutoTimeObject{ID: 1}
+	suite.NoError(client.Get(ctx, read))
+	suite.Equal(written.Truncate(time.Millisecond),
+		read.UpdateTime.Truncate(time.Millisecond))
+	suite.Equal(int64(1), asyncCounter(scope, "session_reads", "autotime_object"))
+	suite.Equal(int64(2),
+		asyncCounter(scope, "session_read_retries", "autotime_object"))
+	suite.Zero(asyncCounter(scope, "session_reads_stale", "autotime_object"))
+}
+
+// TestSessionReadPointerTime tests that a session read observes the write
+// in rows whose update time is a *time.Time, as returned by Cassandra
+func (suite *ORMTestSuite) TestSessionReadPointerTime() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	e := &AutoTimeObject{ID: 1, Data: "testdata"}
+	suite.NoError(client.Create(ctx, e))
+	written := e.UpdateTime
+
+	// pointerRowAt returns autoTimeRowAt(t) with pointers to its times
+	pointerRowAt := func(t time.Time) []base.Column {
+		row := autoTimeRowAt(t)
+		for i, col := range row {
+			if v, ok := col.Value.(time.Time); ok {
+				row[i].Value = &v
+			}
+		}
+		return row
+	}
+	gomock.InOrder(
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(pointerRowAt(written.Add(-time.Hour)), nil),
+		conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(pointerRowAt(written), nil),
+	)
+
+	read := &AutoTimeObject{ID: 1}
+	suite.NoError(client.Get(ctx, read))
+	suite.Equal(written.Truncate(time.Millisecond),
+		read.UpdateTime.Truncate(time.Millisecond))
+	suite.Equal(int64(1),
+		asyncCounter(scope, "session_read_retries", "autotime_object"))
+	suite.Zero(asyncCounter(scope, "session_reads_stale", "autotime_object"))
+}
+
+// TestSessionReadStale tests that a session read missing the write after
+// all retries returns the stale row
+func (suite *ORMTestSuite) TestSessionReadStale() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	conn.EXPECT().Update(
+		gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	e := &AutoTimeObject{ID: 1, Data: "testdata"}
+	suite.NoError(client.Update(ctx, e, "Data"))
+
+	stale := time.Unix(2000, 0).UTC()
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(autoTimeRowAt(stale), nil).Times(3)
+
+	read := &AutoTimeObject{ID: 1}
+	suite.NoError(client.Get(ctx, read))
+	suite.Equal(stale, read.UpdateTime)
+	suite.Equal(int64(1),
+		asyncCounter(scope, "session_reads_stale", "autotime_object"))
+}
+
+// TestSessionReadOtherPartition tests that reads outside a session, or of
+// a partition not written in the session, are not session reads
+func (suite *ORMTestSuite) TestSessionReadOtherPartition() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, scope := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(client.Create(ctx, &AutoTimeObject{ID: 2, Data: "data"}))
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *base.Definition, _ []base.Column) {
+			suite.False(IsSessionRead(ctx))
+		}).Return(autoTimeRowAt(time.Unix(2000, 0).UTC()), nil).Times(2)
+
+	suite.NoError(client.Get(ctx, &AutoTimeObject{ID: 1}))
+	suite.NoError(client.Get(suite.ctx, &AutoTimeObject{ID: 2}))
+	suite.Zero(asyncCounter(scope, "session_reads", "autotime_object"))
+}
+
+// TestSessionReadAfterDelete tests that the read of a partition deleted
+// earlier in the session is a session read which is not retried
+func (suite *ORMTestSuite) TestSessionReadAfterDelete() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, _ := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	conn.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(client.Delete(ctx, &AutoTimeObject{ID: 1}))
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *base.Definition, _ []base.Column) {
+			suite.True(IsSessionRead(ctx))
+		}).Return(nil, yarpcerrors.NotFoundErrorf("not found"))
+
+	suite.Error(client.Get(ctx, &AutoTimeObject{ID: 1}))
+}
+
+// TestSessionReadWithoutAutoTime tests that the session reads of objects
+// without autotime=update fields are not retried
+func (suite *ORMTestSuite) TestSessionReadWithoutAutoTime() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, _ := suite.newSessionClient(conn)
+	ctx := WithSession(suite.ctx, NewSession())
+
+	e := &ValidObject{ID: 1, Name: "test"}
+	conn.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.NoError(client.Create(ctx, e))
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *base.Definition, _ []base.Column) {
+			suite.True(IsSessionRead(ctx))
+		}).Return(testRow, nil)
+
+	suite.NoError(client.Get(ctx, &ValidObject{ID: 1, Name: "test"}))
+}
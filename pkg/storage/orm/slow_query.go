@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	// SlowQueriesPath is the default endpoint dumping the recent slow
+	// queries of the ORM.
+	SlowQueriesPath = "/debug/orm/slow_queries"
+
+	// DefaultSlowQueryBufferSize is the default number of recent slow
+	// queries kept by the client.
+	DefaultSlowQueryBufferSize = 100
+)
+
+// ClientConfig is the config of the queries of the ORM client.
+type ClientConfig struct {
+	// QueryTimeout bounds the duration of every operation, on top of the
+	// deadline of the context of the caller. No timeout if zero.
+	QueryTimeout time.Duration
+	// SlowQueryThreshold is the latency above which an operation is logged
+	// as slow. Slow queries are not tracked if zero.
+	SlowQueryThreshold time.Duration
+	// SlowQueryBufferSize is the number of recent slow queries kept for the
+	// debug endpoint, DefaultSlowQueryBufferSize if zero.
+	SlowQueryBufferSize int
+	// HedgedReads enables hedging the reads of the storage objects whose
+	// tag has a hedge threshold, see Table.HedgeAfter.
+	HedgedReads bool
+	// AsyncWrites configures the batches of the storage objects written
+	// with CreateAsync.
+	AsyncWrites AsyncWriteConfig
+	// SessionReads configures the retries of the reads of the partitions
+	// written earlier in their session, see Session.
+	SessionReads SessionReadConfig
+	// Scope is the scope of the metrics of the hedged reads, of the
+	// unindexed queries, of the async writes and of the session reads,
+	// they are not reported if nil.
+	Scope tally.Scope
+}
+
+// SlowQuery is an operation which took longer than the slow query
+// threshold of the client.
+type SlowQuery struct {
+	// Time is when the operation started.
+	Time time.Time `json:"time"`
+	// Object is the table name of the storage object.
+	Object string `json:"object"`
+	// Op is the name of the operation.
+	Op string `json:"op"`
+	// KeyHash is a hash of the key of the operation, which tells apart
+	// operations on the same key without logging the key values.
+	KeyHash string `json:"key_hash"`
+	// Latency is the duration of the operation.
+	Latency time.Duration `json:"latency_ns"`
+	// Error is the error of the operation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// slowQueryLog logs the slow queries and keeps the most recent ones in a
+// ring buffer.
+type slowQueryLog struct {
+	sync.Mutex
+
+	threshold time.Duration
+	queries   []*SlowQuery
+	// index of the next query to write in queries
+	next int
+	// whether the buffer wrapped around
+	full bool
+}
+
+func newSlowQueryLog(threshold time.Duration, size int) *slowQueryLog {
+	if size <= 0 {
+		size = DefaultSlowQueryBufferSize
+	}
+	return &slowQueryLog{
+		threshold: threshold,
+		queries:   make([]*SlowQuery, size),
+	}
+}
+
+// record logs the operation if it is slower than the threshold.
+func (l *slowQueryLog) record(
+	object string,
+	op string,
+	keyRow []base.Column,
+	start time.Time,
+	latency time.Duration,
+	err error,
+) {
+	if l.threshold <= 0 || latency < l.threshold {
+		return
+	}
+
+	q := &SlowQuery{
+		Time:    start,
+		Object:  object,
+		Op:      op,
+		KeyHash: keyHash(keyRow),
+		Latency: latency,
+	}
+	if err != nil {
+		q.Error = err.Error()
+	}
+
+	log.WithFields(log.Fields{
+		"table":    object,
+		"op":       op,
+		"key_hash": q.KeyHash,
+		"latency":  latency,
+		"error":    q.Error,
+	}).Warn("Slow ORM query")
+
+	l.Lock()
+	defer l.Unlock()
+	l.queries[l.next] = q
+	l.next = (l.next + 1) % len(l.queries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the recent slow queries, most recent first.
+func (l *slowQueryLog) snapshot() []*SlowQuery {
+	l.Lock()
+	defer l.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.queries)
+	}
+	result := make([]*SlowQuery, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (l.next - i + len(l.queries)) % len(l.queries)
+		result = append(result, l.queries[idx])
+	}
+	return result
+}
+
+// keyHash returns a short hash of the values of the key columns.
+func keyHash(keyRow []base.Column) string {
+	h := fnv.New64a()
+	for _, col := range keyRow {
+		fmt.Fprintf(h, "%s=%v;", col.Name, col.Value)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// SlowQueriesHandler returns a handler dumping the recent slow queries of
+// the client as JSON, most recent first.
+func SlowQueriesHandler(c Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.MarshalIndent(c.SlowQueries(), "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
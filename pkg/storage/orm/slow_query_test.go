@@ -0,0 +1,110 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+)
+
+// TestSlowQueryLog tests that only queries over the threshold are kept,
+// and that the most recent ones are kept once the buffer is full
+func (suite *ORMTestSuite) TestSlowQueryLog() {
+	l := newSlowQueryLog(10*time.Millisecond, 2)
+	suite.Empty(l.snapshot())
+
+	now := time.Now()
+	key := []base.Column{{Name: "id", Value: uint64(1)}}
+	l.record("valid_object", OpGet, key, now, time.Millisecond, nil)
+	suite.Empty(l.snapshot())
+
+	l.record("valid_object", OpGet, key, now, 10*time.Millisecond, nil)
+	l.record("valid_object", OpUpdate, key, now, 20*time.Millisecond,
+		errors.New("update failed"))
+	l.record("valid_object", OpDelete, key, now, 30*time.Millisecond, nil)
+
+	queries := l.snapshot()
+	suite.Len(queries, 2)
+	suite.Equal(OpDelete, queries[0].Op)
+	suite.Equal(30*time.Millisecond, queries[0].Latency)
+	suite.Empty(queries[0].Error)
+	suite.Equal(OpUpdate, queries[1].Op)
+	suite.Equal("update failed", queries[1].Error)
+	suite.Equal("valid_object", queries[1].Object)
+	suite.Equal(now, queries[1].Time)
+	suite.Equal(queries[0].KeyHash, queries[1].KeyHash)
+
+	// a zero threshold disables the slow query log
+	l = newSlowQueryLog(0, 0)
+	l.record("valid_object", OpGet, key, now, time.Hour, nil)
+	suite.Empty(l.snapshot())
+	suite.Len(l.queries, DefaultSlowQueryBufferSize)
+}
+
+// TestKeyHash tests that the key hash depends on the key values
+func (suite *ORMTestSuite) TestKeyHash() {
+	key1 := []base.Column{{Name: "id", Value: uint64(1)}}
+	key2 := []base.Column{{Name: "id", Value: uint64(2)}}
+	suite.Len(keyHash(key1), 16)
+	suite.Equal(keyHash(key1), keyHash(key1))
+	suite.NotEqual(keyHash(key1), keyHash(key2))
+}
+
+// TestClientSlowQueries tests that the client records its slow queries and
+// bounds them with the query timeout
+func (suite *ORMTestSuite) TestClientSlowQueries() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClientWithConfig(conn, &ClientConfig{
+		QueryTimeout:       time.Minute,
+		SlowQueryThreshold: time.Millisecond,
+	}, &ValidObject{})
+	suite.NoError(err)
+
+	conn.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *base.Definition, _ []base.Column) {
+			deadline, ok := ctx.Deadline()
+			suite.True(ok)
+			suite.WithinDuration(time.Now().Add(time.Minute), deadline,
+				time.Second)
+			time.Sleep(2 * time.Millisecond)
+		}).Return(testRow, nil)
+	suite.NoError(client.Get(suite.ctx, &ValidObject{ID: uint64(1)}))
+
+	queries := client.SlowQueries()
+	suite.Len(queries, 1)
+	suite.Equal("valid_object", queries[0].Object)
+	suite.Equal(OpGet, queries[0].Op)
+	suite.True(queries[0].Latency >= 2*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	SlowQueriesHandler(client)(
+		w, httptest.NewRequest("GET", SlowQueriesPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	var dumped []*SlowQuery
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &dumped))
+	suite.Len(dumped, 1)
+	suite.Equal(queries[0].KeyHash, dumped[0].KeyHash)
+}
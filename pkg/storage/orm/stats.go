@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+const (
+	// StatsPath is the default endpoint dumping the ORM statistics.
+	StatsPath = "/debug/orm/stats"
+
+	_statsObjectParam = "object"
+)
+
+// Names of the operations tracked by the ORM statistics.
+const (
+	OpCreateIfNotExists = "create_if_not_exists"
+	OpCreate            = "create"
+	OpGet               = "get"
+	OpGetAll            = "get_all"
+	OpUpdate            = "update"
+	OpDelete            = "delete"
+	// OpDeleteAllInPartition records the rows deleted from a partition
+	OpDeleteAllInPartition = "delete_all_in_partition"
+	// OpQueryUnindexed records the rows read by unindexed queries
+	OpQueryUnindexed = "query_unindexed"
+	// OpGetChildren records the rows read by the pages of children
+	OpGetChildren = "get_children"
+	// OpCreateBatch records the rows written by CreateAsync, batch by batch
+	OpCreateBatch = "create_batch"
+)
+
+var _ops = []string{
+	OpCreateIfNotExists,
+	OpCreate,
+	OpGet,
+	OpGetAll,
+	OpUpdate,
+	OpDelete,
+	OpDeleteAllInPartition,
+	OpQueryUnindexed,
+	OpGetChildren,
+	OpCreateBatch,
+}
+
+// OpStats is the statistics of one operation on a storage object.
+type OpStats struct {
+	// Count is the number of calls of the operation.
+	Count uint64 `json:"count"`
+	// Errors is the number of calls which failed.
+	Errors uint64 `json:"errors"`
+	// ErrorRate is Errors over Count.
+	ErrorRate float64 `json:"error_rate"`
+	// Rows is the number of rows written or read by successful calls.
+	Rows uint64 `json:"rows"`
+	// AverageRowSize is the estimated average size of these rows in bytes.
+	AverageRowSize float64 `json:"average_row_size_bytes"`
+}
+
+// ObjectStats is the statistics of a storage object since the start
+// of the process.
+type ObjectStats struct {
+	// Object is the table name of the storage object.
+	Object string `json:"object"`
+	// ActiveQueries is the number of operations in flight.
+	ActiveQueries int64 `json:"active_queries"`
+	// Ops is the statistics of each operation, by operation name.
+	Ops map[string]*OpStats `json:"ops"`
+}
+
+// opCounters are the counters of an operation, updated atomically.
+type opCounters struct {
+	count    uint64
+	errors   uint64
+	rows     uint64
+	rowBytes uint64
+}
+
+// objectStats tracks the operations on a storage object.
+type objectStats struct {
+	name   string
+	active int64
+	ops    map[string]*opCounters
+}
+
+func newObjectStats(name string) *objectStats {
+	s := &objectStats{
+		name: name,
+		ops:  make(map[string]*opCounters, len(_ops)),
+	}
+	for _, op := range _ops {
+		s.ops[op] = &opCounters{}
+	}
+	return s
+}
+
+// begin marks the start of an operation and returns the function
+// recording its outcome along with the rows written or read.
+func (s *objectStats) begin(op string) func(err error, rows ...[]base.Column) {
+	atomic.AddInt64(&s.active, 1)
+	return func(err error, rows ...[]base.Column) {
+		atomic.AddInt64(&s.active, -1)
+
+		counters := s.ops[op]
+		atomic.AddUint64(&counters.count, 1)
+		if err != nil {
+			atomic.AddUint64(&counters.errors, 1)
+			return
+		}
+		var size uint64
+		for _, row := range rows {
+			size += rowSize(row)
+		}
+		atomic.AddUint64(&counters.rows, uint64(len(rows)))
+		atomic.AddUint64(&counters.rowBytes, size)
+	}
+}
+
+// snapshot returns the current statistics of the storage object.
+func (s *objectStats) snapshot() *ObjectStats {
+	result := &ObjectStats{
+		Object:        s.name,
+		ActiveQueries: atomic.LoadInt64(&s.active),
+		Ops:           make(map[string]*OpStats, len(s.ops)),
+	}
+	for op, counters := range s.ops {
+		stats := &OpStats{
+			Count:  atomic.LoadUint64(&counters.count),
+			Errors: atomic.LoadUint64(&counters.errors),
+			Rows:   atomic.LoadUint64(&counters.rows),
+		}
+		if stats.Count > 0 {
+			stats.ErrorRate = float64(stats.Errors) / float64(stats.Count)
+		}
+		if stats.Rows > 0 {
+			stats.AverageRowSize = float64(
+				atomic.LoadUint64(&counters.rowBytes)) / float64(stats.Rows)
+		}
+		result.Ops[op] = stats
+	}
+	return result
+}
+
+// rowSize estimates the size in bytes of the values of a row.
+func rowSize(row []base.Column) uint64 {
+	var size uint64
+	for _, col := range row {
+		size += valueSize(reflect.ValueOf(col.Value))
+	}
+	return size
+}
+
+// valueSize estimates the size in bytes of a column value.
+func valueSize(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return valueSize(v.Elem())
+	case reflect.String:
+		return uint64(v.Len())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return uint64(v.Len())
+		}
+		var size uint64
+		for i := 0; i < v.Len(); i++ {
+			size += valueSize(v.Index(i))
+		}
+		return size
+	case reflect.Map:
+		var size uint64
+		for _, key := range v.MapKeys() {
+			size += valueSize(key) + valueSize(v.MapIndex(key))
+		}
+		return size
+	case reflect.Struct:
+		// Timestamps are stored on 8 bytes.
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return 8
+		}
+		return uint64(len(fmt.Sprint(v.Interface())))
+	default:
+		return uint64(v.Type().Size())
+	}
+}
+
+// StatsHandler returns a handler dumping the statistics of the storage
+// objects of the client as JSON. The `object` query parameter restricts
+// the dump to the storage object with that table name.
+func StatsHandler(c Client) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := c.Stats()
+		if object := r.URL.Query().Get(_statsObjectParam); object != "" {
+			var filtered []*ObjectStats
+			for _, s := range stats {
+				if s.Object == object {
+					filtered = append(filtered, s)
+				}
+			}
+			if len(filtered) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, "Storage object %s not found\n", object)
+				return
+			}
+			stats = filtered
+		}
+
+		body, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// sortObjectStats sorts the statistics by storage object name.
+func sortObjectStats(stats []*ObjectStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Object < stats[j].Object
+	})
+}
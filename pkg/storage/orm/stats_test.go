@@ -0,0 +1,147 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+)
+
+// TestClientStats tests that the client tracks the operation counts,
+// errors and row sizes of its storage objects
+func (suite *ORMTestSuite) TestClientStats() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+
+	conn.EXPECT().Create(suite.ctx, gomock.Any(), gomock.Any()).Return(nil)
+	suite.NoError(client.Create(suite.ctx, testValidObject))
+	conn.EXPECT().Create(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	suite.Error(client.Create(suite.ctx, testValidObject))
+	conn.EXPECT().GetAll(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(testRows, nil)
+	_, err = client.GetAll(suite.ctx, &ValidObject{ID: uint64(1)})
+	suite.NoError(err)
+
+	// Operations on unknown objects are not tracked.
+	suite.Error(client.Create(suite.ctx, &InvalidObject1{}))
+
+	stats := client.Stats()
+	suite.Len(stats, 1)
+	suite.Equal("valid_object", stats[0].Object)
+	suite.Equal(int64(0), stats[0].ActiveQueries)
+	suite.Len(stats[0].Ops, len(_ops))
+
+	create := stats[0].Ops[OpCreate]
+	suite.Equal(uint64(2), create.Count)
+	suite.Equal(uint64(1), create.Errors)
+	suite.Equal(0.5, create.ErrorRate)
+	suite.Equal(uint64(1), create.Rows)
+	// id (8 bytes) + "test" + "testdata"
+	suite.Equal(float64(20), create.AverageRowSize)
+
+	getAll := stats[0].Ops[OpGetAll]
+	suite.Equal(uint64(1), getAll.Count)
+	suite.Equal(uint64(0), getAll.Errors)
+	suite.Equal(uint64(2), getAll.Rows)
+	// id (8 bytes) + "testN" + "testdataN"
+	suite.Equal(float64(22), getAll.AverageRowSize)
+
+	suite.Equal(&OpStats{}, stats[0].Ops[OpDelete])
+}
+
+// TestClientStatsActiveQueries tests that the operations in flight
+// are reported as active queries
+func (suite *ORMTestSuite) TestClientStatsActiveQueries() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+
+	conn.EXPECT().Get(suite.ctx, gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, _ *base.Definition, _ []base.Column) {
+			suite.Equal(int64(1), client.Stats()[0].ActiveQueries)
+		}).Return(testRow, nil)
+	suite.NoError(client.Get(suite.ctx, &ValidObject{ID: uint64(1)}))
+	suite.Equal(int64(0), client.Stats()[0].ActiveQueries)
+}
+
+// TestValueSize tests the size estimation of column values
+func (suite *ORMTestSuite) TestValueSize() {
+	name := "name"
+	tests := []struct {
+		value interface{}
+		size  uint64
+	}{
+		{nil, 0},
+		{"abc", 3},
+		{[]byte{1, 2}, 2},
+		{int32(1), 4},
+		{true, 1},
+		{&name, 4},
+		{(*string)(nil), 0},
+		{[]string{"a", "bc"}, 3},
+		{map[string]string{"k": "value"}, 6},
+		{time.Now(), 8},
+	}
+	for _, test := range tests {
+		suite.Equal(test.size, rowSize([]base.Column{{Value: test.value}}),
+			"%v", test.value)
+	}
+}
+
+// TestStatsHandler tests dumping the statistics of all storage objects
+// and of a single storage object
+func (suite *ORMTestSuite) TestStatsHandler() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+
+	client, err := NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+	conn.EXPECT().Delete(suite.ctx, gomock.Any(), gomock.Any()).Return(nil)
+	suite.NoError(client.Delete(suite.ctx, testValidObject))
+
+	handler := StatsHandler(client)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", StatsPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	var stats []*ObjectStats
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &stats))
+	suite.Len(stats, 1)
+	suite.Equal(uint64(1), stats[0].Ops[OpDelete].Count)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(
+		"GET", StatsPath+"?object=valid_object", nil))
+	suite.Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", StatsPath+"?object=unknown", nil))
+	suite.Equal(http.StatusNotFound, w.Code)
+}
@@ -17,12 +17,18 @@ package orm
 import (
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
 
 	"go.uber.org/yarpc/yarpcerrors"
 )
 
+// tableCache caches the Table of storage object types used with the
+// package level key builders.
+var tableCache sync.Map
+
 // Table is an ORM internal representation of storage object. Storage
 // object is translated into Definition that contains the primary key
 // information as well as column to datatype map
@@ -36,6 +42,79 @@ type Table struct {
 
 	// map of base field name to DB column name
 	FieldToCol map[string]string
+
+	// names of the fields tagged autotime=create, which are set to the
+	// current time on create if they are not already set
+	CreateTimeFields []string
+
+	// names of the fields tagged autotime=update, which are set to the
+	// current time on every create and update
+	UpdateTimeFields []string
+
+	// latency after which a read of the object is hedged, the reads are
+	// not hedged if zero
+	HedgeAfter time.Duration
+
+	// max number of objects returned by an unindexed query of the object,
+	// unindexed queries are not allowed if zero
+	UnindexedLimit int
+
+	// table name of the parent of the object, whose primary key contains
+	// the partition key of the object, empty if the object has no parent
+	Parent string
+
+	// what happens to the object when its parent is deleted with
+	// DeleteWithChildren, OnDeleteCascade, OnDeleteRestrict or empty if
+	// the object is kept
+	OnParentDelete string
+}
+
+// timeType is the only field type which may be tagged with autotime
+var timeType = reflect.TypeOf(time.Time{})
+
+// SetCreateTimes populates the autotime fields of a storage object which is
+// about to be created. Creation times already set by the caller are kept.
+func (t *Table) SetCreateTimes(e base.Object, now time.Time) {
+	v := reflect.ValueOf(e).Elem()
+	for _, fieldName := range t.CreateTimeFields {
+		field := v.FieldByName(fieldName)
+		if field.Interface().(time.Time).IsZero() {
+			field.Set(reflect.ValueOf(now))
+		}
+	}
+	t.SetUpdateTimes(e, now)
+}
+
+// SetUpdateTimes sets the autotime=update fields of a storage object which
+// is about to be written to the current time.
+func (t *Table) SetUpdateTimes(e base.Object, now time.Time) {
+	v := reflect.ValueOf(e).Elem()
+	for _, fieldName := range t.UpdateTimeFields {
+		v.FieldByName(fieldName).Set(reflect.ValueOf(now))
+	}
+}
+
+// withUpdateTimeFields adds the autotime=update fields to the list of fields
+// selected for an update, so that a partial update also records its time.
+// An empty list selects all fields and is returned as is.
+func (t *Table) withUpdateTimeFields(selectedFields []string) []string {
+	if len(selectedFields) == 0 {
+		return selectedFields
+	}
+	fields := append([]string{}, selectedFields...)
+	for _, fieldName := range t.UpdateTimeFields {
+		found := false
+		for _, f := range selectedFields {
+			if f == fieldName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, fieldName)
+		}
+	}
+	return fields
 }
 
 // GetKeyRowFromObject is a helper for generating a row of partition and
@@ -68,6 +147,24 @@ func (t *Table) GetKeyRowFromObject(
 	return row
 }
 
+// getKeyRowFromRow is a helper for extracting the primary key columns, in
+// key order, from a row read from the DB.
+func (t *Table) getKeyRowFromRow(row []base.Column) []base.Column {
+	values := make(map[string]interface{}, len(row))
+	for _, column := range row {
+		values[column.Name] = column.Value
+	}
+
+	keyRow := []base.Column{}
+	for _, col := range t.Key.Columns() {
+		keyRow = append(keyRow, base.Column{
+			Name:  col,
+			Value: values[col],
+		})
+	}
+	return keyRow
+}
+
 // GetPartitionKeyRowFromObject is a helper for generating a row of partition
 // key column values to be used in a GetAll query.
 func (t *Table) GetPartitionKeyRowFromObject(
@@ -184,9 +281,27 @@ func TableFromObject(e base.Object) (*Table, error) {
 			// Extract Object tags which have all the connector key information
 			tag := strings.TrimSpace(structField.Tag.Get(connectorTag))
 
-			// Parse cassandra specific tag to extract table name and primary
-			// key information
-			if t.Definition.Name, t.Key, err =
+			// Extract the hedging threshold of the reads, which is not
+			// part of the definition of the table
+			if t.HedgeAfter, tag, err = parseHedgeTag(tag); err != nil {
+				return nil, err
+			}
+
+			// Extract the guard of the unindexed queries, which is not
+			// part of the definition of the table either
+			if t.UnindexedLimit, tag, err = parseUnindexedTag(tag); err != nil {
+				return nil, err
+			}
+
+			// Extract the relationship of the object with its parent
+			if t.Parent, t.OnParentDelete, tag, err =
+				parseParentTag(tag); err != nil {
+				return nil, err
+			}
+
+			// Parse cassandra specific tag to extract table name, primary
+			// key and unique constraint information
+			if t.Definition.Name, t.Key, t.UniqueKeys, err =
 				parseCassandraObjectTag(tag); err != nil {
 				return nil, err
 			}
@@ -205,6 +320,22 @@ func TableFromObject(e base.Object) (*Table, error) {
 			// it is easy to convert table to object and viceversa
 			t.ColToField[columnName] = name
 			t.FieldToCol[name] = columnName
+
+			// Keep track of the timestamp fields maintained by the ORM
+			autoTime, err := parseAutoTimeTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			if autoTime != "" && structField.Type != timeType {
+				return nil, yarpcerrors.InternalErrorf(
+					"autotime field %s must be a time.Time", name)
+			}
+			switch autoTime {
+			case autoTimeCreate:
+				t.CreateTimeFields = append(t.CreateTimeFields, name)
+			case autoTimeUpdate:
+				t.UpdateTimeFields = append(t.UpdateTimeFields, name)
+			}
 		}
 	}
 
@@ -213,9 +344,114 @@ func TableFromObject(e base.Object) (*Table, error) {
 			"cannot find orm.Object in object %v", e)
 	}
 
+	if err := t.Validate(); err != nil {
+		return nil, yarpcerrors.InternalErrorf("invalid primary key: %v", err)
+	}
+
+	return t, nil
+}
+
+// SetPrimaryKey sets the primary key fields of the storage object to the
+// given values. Values are given in key order, i.e. partition keys followed
+// by clustering keys, and must be assignable to the key fields.
+func (t *Table) SetPrimaryKey(e base.Object, values ...interface{}) error {
+	return t.setKeyFields(e, t.Key.Columns(), values)
+}
+
+// SetPartitionKey sets the partition key fields of the storage object to the
+// given values, in partition key order. This is used to build the object
+// passed to a GetAll query.
+func (t *Table) SetPartitionKey(e base.Object, values ...interface{}) error {
+	return t.setKeyFields(e, t.Key.PartitionKeys, values)
+}
+
+// setKeyFields sets the fields backing the given key columns of the storage
+// object, one value per column.
+func (t *Table) setKeyFields(
+	e base.Object,
+	columns []string,
+	values []interface{},
+) error {
+	if len(values) != len(columns) {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"object %s expects %d key values %v, got %d",
+			t.Name, len(columns), columns, len(values))
+	}
+
+	v := reflect.ValueOf(e).Elem()
+	for i, col := range columns {
+		field := v.FieldByName(t.ColToField[col])
+		value := reflect.ValueOf(values[i])
+		if !value.IsValid() {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"nil value for key %s of object %s", col, t.Name)
+		}
+
+		switch {
+		case value.Type().AssignableTo(field.Type()):
+			field.Set(value)
+		case isNumeric(value.Kind()) && isNumeric(field.Kind()):
+			field.Set(value.Convert(field.Type()))
+		default:
+			return yarpcerrors.InvalidArgumentErrorf(
+				"invalid type %s for key %s of object %s, expected %s",
+				value.Type(), col, t.Name, field.Type())
+		}
+	}
+	return nil
+}
+
+// isNumeric returns true if values of the given kind are integer or floating
+// point numbers, which can be converted to one another.
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// tableOf returns the cached Table of the storage object, building it on
+// first use.
+func tableOf(e base.Object) (*Table, error) {
+	typ := reflect.TypeOf(e)
+	if t, ok := tableCache.Load(typ); ok {
+		return t.(*Table), nil
+	}
+	t, err := TableFromObject(e)
+	if err != nil {
+		return nil, err
+	}
+	tableCache.Store(typ, t)
 	return t, nil
 }
 
+// SetPrimaryKey sets the primary key fields of the storage object to the
+// given values, given in key order. It saves callers from spelling out every
+// column of a composite key, for example:
+//
+//	obj := &PodEventsBucketObject{}
+//	err := orm.SetPrimaryKey(obj, jobID, day, eventTime)
+func SetPrimaryKey(e base.Object, values ...interface{}) error {
+	t, err := tableOf(e)
+	if err != nil {
+		return err
+	}
+	return t.SetPrimaryKey(e, values...)
+}
+
+// SetPartitionKey sets the partition key fields of the storage object to the
+// given values, given in partition key order.
+func SetPartitionKey(e base.Object, values ...interface{}) error {
+	t, err := tableOf(e)
+	if err != nil {
+		return err
+	}
+	return t.SetPartitionKey(e, values...)
+}
+
 // BuildObjectIndex builds an index to map storage object type to its
 // Table representation
 func BuildObjectIndex(objects []base.Object) (
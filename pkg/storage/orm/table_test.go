@@ -15,6 +15,8 @@
 package orm
 
 import (
+	"time"
+
 	"github.com/uber/peloton/pkg/storage/objects/base"
 )
 
@@ -47,6 +49,133 @@ type InvalidObject3 struct {
 	Name        string `column:"name=name"`
 }
 
+// InvalidObject4 has a primary key which is not a column
+type InvalidObject4 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id, day), name)"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject5 repeats a primary key column
+type InvalidObject5 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id, name), name)"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject6 has an empty partition key
+type InvalidObject6 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((), name)"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// CompositeKeyObject has a composite partition key
+type CompositeKeyObject struct {
+	base.Object `cassandra:"name=composite_object, primaryKey=((id, day), name)"`
+	ID          uint64 `column:"name=id"`
+	Day         string `column:"name=day"`
+	Name        string `column:"name=name"`
+	Data        string `column:"name=data"`
+}
+
+// InvalidObject7 has an autotime tag on a field which is not a time
+type InvalidObject7 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id))"`
+	ID          uint64 `column:"name=id"`
+	Version     uint64 `column:"name=version, autotime=update"`
+}
+
+// InvalidObject8 has an unknown autotime tag
+type InvalidObject8 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id))"`
+	ID          uint64    `column:"name=id"`
+	UpdateTime  time.Time `column:"name=update_time, autotime=delete"`
+}
+
+// InvalidObject9 has a unique key which is not a column
+type InvalidObject9 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), unique=(id, day)"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject10 has an empty unique constraint
+type InvalidObject10 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), unique=()"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject11 has an invalid hedge threshold
+type InvalidObject11 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), hedge=fast"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject12 has an invalid limit of unindexed queries
+type InvalidObject12 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), unindexed=0"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject13 has an invalid delete action
+type InvalidObject13 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), parent=parent_object, onDelete=nullify"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// InvalidObject14 has a delete action without parent
+type InvalidObject14 struct {
+	base.Object `cassandra:"name=valid_object, primaryKey=((id)), onDelete=cascade"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// UnindexedObject can be queried by columns outside of its primary key,
+// matching at most 2 rows
+type UnindexedObject struct {
+	base.Object `cassandra:"name=unindexed_object, unindexed=2, primaryKey=((id))"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+	Data        string `column:"name=data"`
+}
+
+// HedgedObject has its reads hedged after 10ms
+type HedgedObject struct {
+	base.Object `cassandra:"name=hedged_object, hedge=10ms, primaryKey=((id), name)"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+	Data        string `column:"name=data"`
+}
+
+// UniqueObject has a unique constraint across a partition key and a column
+type UniqueObject struct {
+	base.Object `cassandra:"name=unique_object, primaryKey=((id), version), unique=(id, name)"`
+	ID          uint64 `column:"name=id"`
+	Version     uint64 `column:"name=version"`
+	Name        string `column:"name=name"`
+}
+
+// UniqueFirstObject declares the unique constraint before the primary key
+type UniqueFirstObject struct {
+	base.Object `cassandra:"name=unique_first_object, unique=(name), primaryKey=((id))"`
+	ID          uint64 `column:"name=id"`
+	Name        string `column:"name=name"`
+}
+
+// AutoTimeObject has timestamps maintained by the ORM
+type AutoTimeObject struct {
+	base.Object  `cassandra:"name=autotime_object, primaryKey=((id))"`
+	ID           uint64    `column:"name=id"`
+	Data         string    `column:"name=data"`
+	CreationTime time.Time `column:"name=creation_time, autotime=create"`
+	UpdateTime   time.Time `column:"name=update_time,autotime=update"`
+}
+
 // TestTableFromObject tests creating orm.Table from given base object
 // This is meant to test that only entities annotated in a certain format will
 // be successfully converted to orm tables
@@ -55,7 +184,11 @@ func (suite *ORMTestSuite) TestTableFromObject() {
 	suite.NoError(err)
 
 	tt := []base.Object{
-		&InvalidObject1{}, &InvalidObject2{}, &InvalidObject3{}}
+		&InvalidObject1{}, &InvalidObject2{}, &InvalidObject3{},
+		&InvalidObject4{}, &InvalidObject5{}, &InvalidObject6{},
+		&InvalidObject7{}, &InvalidObject8{}, &InvalidObject9{},
+		&InvalidObject10{}, &InvalidObject11{}, &InvalidObject12{},
+		&InvalidObject13{}, &InvalidObject14{}}
 	for _, t := range tt {
 		_, err := TableFromObject(t)
 		suite.Error(err)
@@ -125,3 +258,161 @@ func (suite *ORMTestSuite) TestGetPartitionKeyRowFromObject() {
 	suite.Equal(e.ID, keyRow[0].Value)
 	suite.Equal(len(keyRow), 1)
 }
+
+// TestCompositePartitionKey tests building key rows of an object with a
+// composite partition key
+func (suite *ORMTestSuite) TestCompositePartitionKey() {
+	e := &CompositeKeyObject{
+		ID:   uint64(1),
+		Day:  "2019-01-01",
+		Name: "test",
+		Data: "junk",
+	}
+	table, err := TableFromObject(e)
+	suite.NoError(err)
+	suite.Equal([]string{"id", "day"}, table.Key.PartitionKeys)
+	suite.Equal([]string{"id", "day", "name"}, table.Key.Columns())
+
+	keyRow := table.GetKeyRowFromObject(e)
+	suite.Equal(3, len(keyRow))
+	suite.Equal(e.ID, keyRow[0].Value)
+	suite.Equal(e.Day, keyRow[1].Value)
+	suite.Equal(e.Name, keyRow[2].Value)
+
+	partitionKeyRow := table.GetPartitionKeyRowFromObject(e)
+	suite.Equal(2, len(partitionKeyRow))
+	suite.Equal(e.ID, partitionKeyRow[0].Value)
+	suite.Equal(e.Day, partitionKeyRow[1].Value)
+}
+
+// TestSetPrimaryKey tests setting the key fields of an object from values
+// given in key order
+func (suite *ORMTestSuite) TestSetPrimaryKey() {
+	e := &CompositeKeyObject{}
+	suite.NoError(SetPrimaryKey(e, 1, "2019-01-01", "test"))
+	suite.Equal(uint64(1), e.ID)
+	suite.Equal("2019-01-01", e.Day)
+	suite.Equal("test", e.Name)
+	suite.Empty(e.Data)
+
+	e = &CompositeKeyObject{}
+	suite.NoError(SetPartitionKey(e, uint64(2), "2019-01-02"))
+	suite.Equal(uint64(2), e.ID)
+	suite.Equal("2019-01-02", e.Day)
+	suite.Empty(e.Name)
+
+	tt := []struct {
+		name   string
+		values []interface{}
+	}{
+		{"too few values", []interface{}{1, "2019-01-01"}},
+		{"too many values", []interface{}{1, "2019-01-01", "a", "b"}},
+		{"nil value", []interface{}{nil, "2019-01-01", "test"}},
+		{"wrong type", []interface{}{1, 20190101, "test"}},
+	}
+	for _, t := range tt {
+		suite.Error(SetPrimaryKey(&CompositeKeyObject{}, t.values...), t.name)
+	}
+
+	suite.Error(SetPrimaryKey(&InvalidObject4{}, 1, "2019-01-01", "test"))
+}
+
+// TestAutoTimeFields tests parsing autotime tags and populating the
+// timestamps of an object
+func (suite *ORMTestSuite) TestAutoTimeFields() {
+	table, err := TableFromObject(&AutoTimeObject{})
+	suite.NoError(err)
+	suite.Equal([]string{"CreationTime"}, table.CreateTimeFields)
+	suite.Equal([]string{"UpdateTime"}, table.UpdateTimeFields)
+	suite.Equal("CreationTime", table.ColToField["creation_time"])
+	suite.Equal("UpdateTime", table.ColToField["update_time"])
+
+	now := time.Now().UTC()
+	e := &AutoTimeObject{ID: 1}
+	table.SetCreateTimes(e, now)
+	suite.Equal(now, e.CreationTime)
+	suite.Equal(now, e.UpdateTime)
+
+	// an existing creation time is kept
+	later := now.Add(time.Minute)
+	table.SetCreateTimes(e, later)
+	suite.Equal(now, e.CreationTime)
+	suite.Equal(later, e.UpdateTime)
+
+	table.SetUpdateTimes(e, later.Add(time.Minute))
+	suite.Equal(now, e.CreationTime)
+	suite.Equal(later.Add(time.Minute), e.UpdateTime)
+
+	suite.Empty(table.withUpdateTimeFields(nil))
+	suite.Equal(
+		[]string{"Data", "UpdateTime"},
+		table.withUpdateTimeFields([]string{"Data"}))
+	suite.Equal(
+		[]string{"UpdateTime", "Data"},
+		table.withUpdateTimeFields([]string{"UpdateTime", "Data"}))
+}
+
+// TestUniqueKeys tests parsing the unique constraint of an object
+func (suite *ORMTestSuite) TestUniqueKeys() {
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	suite.Empty(table.UniqueKeys)
+
+	table, err = TableFromObject(&UniqueObject{})
+	suite.NoError(err)
+	suite.Equal("unique_object", table.Name)
+	suite.Equal([]string{"id", "name"}, table.UniqueKeys)
+	suite.Equal([]string{"id", "version"}, table.Key.Columns())
+
+	table, err = TableFromObject(&UniqueFirstObject{})
+	suite.NoError(err)
+	suite.Equal("unique_first_object", table.Name)
+	suite.Equal([]string{"name"}, table.UniqueKeys)
+	suite.Equal([]string{"id"}, table.Key.Columns())
+}
+
+// TestHedgeTag tests parsing the hedge threshold of an object
+func (suite *ORMTestSuite) TestHedgeTag() {
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	suite.Zero(table.HedgeAfter)
+
+	table, err = TableFromObject(&HedgedObject{})
+	suite.NoError(err)
+	suite.Equal("hedged_object", table.Name)
+	suite.Equal(10*time.Millisecond, table.HedgeAfter)
+	suite.Equal([]string{"id", "name"}, table.Key.Columns())
+}
+
+// TestUnindexedTag tests parsing the limit of unindexed queries of an object
+func (suite *ORMTestSuite) TestUnindexedTag() {
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	suite.Zero(table.UnindexedLimit)
+
+	table, err = TableFromObject(&UnindexedObject{})
+	suite.NoError(err)
+	suite.Equal("unindexed_object", table.Name)
+	suite.Equal(2, table.UnindexedLimit)
+	suite.Equal([]string{"id"}, table.Key.Columns())
+}
+
+// TestParentTag tests parsing the parent of an object
+func (suite *ORMTestSuite) TestParentTag() {
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	suite.Empty(table.Parent)
+	suite.Empty(table.OnParentDelete)
+
+	table, err = TableFromObject(&ChildObject{})
+	suite.NoError(err)
+	suite.Equal("child_object", table.Name)
+	suite.Equal("valid_object", table.Parent)
+	suite.Equal(OnDeleteCascade, table.OnParentDelete)
+	suite.Equal([]string{"id", "seq"}, table.Key.Columns())
+
+	table, err = TableFromObject(&KeptChildObject{})
+	suite.NoError(err)
+	suite.Equal("valid_object", table.Parent)
+	suite.Empty(table.OnParentDelete)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// UnindexedQuerier is implemented by the connectors which can query the
+// rows of a table by columns which are not part of its primary key, e.g.
+// with ALLOW FILTERING on Cassandra. Such queries read the whole table.
+type UnindexedQuerier interface {
+	// QueryUnindexed returns the rows of the table of the object whose
+	// columns are equal to the values of the conditions, at most limit
+	// rows, all of them if zero.
+	QueryUnindexed(
+		ctx context.Context,
+		e *base.Definition,
+		conditions []base.Column,
+		limit int,
+	) ([][]base.Column, error)
+}
+
+// unindexedMetrics are the metrics of the unindexed queries of a storage
+// object.
+type unindexedMetrics struct {
+	// number of unindexed queries
+	queries tally.Counter
+	// number of unindexed queries which were not allowed
+	denied tally.Counter
+	// number of unindexed queries matching more rows than the limit
+	limitExceeded tally.Counter
+}
+
+func newUnindexedMetrics(scope tally.Scope, table string) *unindexedMetrics {
+	s := scope.Tagged(map[string]string{"table": table})
+	return &unindexedMetrics{
+		queries:       s.Counter("unindexed_queries"),
+		denied:        s.Counter("unindexed_queries_denied"),
+		limitExceeded: s.Counter("unindexed_queries_limit_exceeded"),
+	}
+}
+
+// QueryUnindexed gets the storage objects whose given fields are equal to
+// the fields of e. The fields need not be part of the primary key, so the
+// whole table is read. Only the objects tagged with unindexed=<limit> can
+// be queried, and the query fails if more objects than the limit match.
+func (c *client) QueryUnindexed(
+	ctx context.Context,
+	e base.Object,
+	fields ...string,
+) ([]base.Object, error) {
+	// lookup if a table exists for this object, return error if not found
+	table, err := c.getTable(e)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := c.unindexed[table.Name]
+	if table.UnindexedLimit <= 0 {
+		metrics.denied.Inc(1)
+		return nil, yarpcerrors.PermissionDeniedErrorf(
+			"unindexed queries of %s are not allowed", table.Name)
+	}
+	querier, ok := c.connector.(UnindexedQuerier)
+	if !ok {
+		metrics.denied.Inc(1)
+		return nil, yarpcerrors.UnimplementedErrorf(
+			"connector does not support unindexed queries of %s", table.Name)
+	}
+	if len(fields) == 0 {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"unindexed query of %s without fields", table.Name)
+	}
+	for _, field := range fields {
+		if _, ok := table.FieldToCol[field]; !ok {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"unknown field %s of %s", field, table.Name)
+		}
+	}
+
+	conditions := table.GetRowFromObject(e, fields...)
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Name < conditions[j].Name
+	})
+	columns := make([]string, 0, len(conditions))
+	for _, col := range conditions {
+		columns = append(columns, col.Name)
+	}
+	log.WithFields(log.Fields{
+		"object":  table.Name,
+		"columns": columns,
+		"limit":   table.UnindexedLimit,
+	}).Warn("Unindexed query reading the whole table")
+	metrics.queries.Inc(1)
+
+	// read one row more than the limit to tell whether it is exceeded
+	opCtx, done := c.begin(ctx, table, OpQueryUnindexed, conditions)
+	rows, err := querier.QueryUnindexed(
+		opCtx, &table.Definition, conditions, table.UnindexedLimit+1)
+	done(err, rows...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > table.UnindexedLimit {
+		metrics.limitExceeded.Inc(1)
+		return nil, yarpcerrors.ResourceExhaustedErrorf(
+			"unindexed query of %s by %v matches more than %d rows",
+			table.Name, columns, table.UnindexedLimit)
+	}
+
+	return table.BuildObjectsFromRows(e, rows), nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// unindexedConnector is a connector which supports unindexed queries
+type unindexedConnector struct {
+	*connectormocks.MockConnector
+	*connectormocks.MockUnindexedQuerier
+}
+
+// unindexedRow returns a row of UnindexedObject
+func unindexedRow(id uint64) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: id},
+		{Name: "name", Value: "test"},
+		{Name: "data", Value: "testdata"},
+	}
+}
+
+// newUnindexedClient returns a client of a connector supporting unindexed
+// queries, and the mock of those queries
+func (suite *ORMTestSuite) newUnindexedClient() (
+	Client, *connectormocks.MockUnindexedQuerier) {
+	conn := &unindexedConnector{
+		MockConnector:        connectormocks.NewMockConnector(suite.ctrl),
+		MockUnindexedQuerier: connectormocks.NewMockUnindexedQuerier(suite.ctrl),
+	}
+	client, err := NewClient(conn, &UnindexedObject{}, &ValidObject{})
+	suite.NoError(err)
+	return client, conn.MockUnindexedQuerier
+}
+
+// TestQueryUnindexed tests querying objects by a column outside of their
+// primary key
+func (suite *ORMTestSuite) TestQueryUnindexed() {
+	defer suite.ctrl.Finish()
+	client, querier := suite.newUnindexedClient()
+
+	querier.EXPECT().QueryUnindexed(gomock.Any(), gomock.Any(), gomock.Any(), 3).
+		DoAndReturn(func(
+			_ context.Context,
+			e *base.Definition,
+			conditions []base.Column,
+			_ int,
+		) ([][]base.Column, error) {
+			suite.Equal("unindexed_object", e.Name)
+			suite.Equal([]base.Column{{Name: "name", Value: "test"}}, conditions)
+			return [][]base.Column{unindexedRow(1), unindexedRow(2)}, nil
+		})
+	objs, err := client.QueryUnindexed(
+		suite.ctx, &UnindexedObject{Name: "test"}, "Name")
+	suite.NoError(err)
+	suite.Len(objs, 2)
+	suite.Equal(uint64(1), objs[0].(*UnindexedObject).ID)
+	suite.Equal(uint64(2), objs[1].(*UnindexedObject).ID)
+	suite.Equal("testdata", objs[1].(*UnindexedObject).Data)
+}
+
+// TestQueryUnindexedLimitExceeded tests that an unindexed query matching
+// more rows than the limit of the object fails
+func (suite *ORMTestSuite) TestQueryUnindexedLimitExceeded() {
+	defer suite.ctrl.Finish()
+	client, querier := suite.newUnindexedClient()
+
+	querier.EXPECT().QueryUnindexed(gomock.Any(), gomock.Any(), gomock.Any(), 3).
+		Return([][]base.Column{
+			unindexedRow(1), unindexedRow(2), unindexedRow(3)}, nil)
+	_, err := client.QueryUnindexed(
+		suite.ctx, &UnindexedObject{Name: "test"}, "Name")
+	suite.True(yarpcerrors.IsResourceExhausted(err))
+}
+
+// TestQueryUnindexedInvalid tests that the unindexed queries of objects
+// not tagged for them, or by unknown fields, are rejected
+func (suite *ORMTestSuite) TestQueryUnindexedInvalid() {
+	defer suite.ctrl.Finish()
+	client, _ := suite.newUnindexedClient()
+
+	_, err := client.QueryUnindexed(
+		suite.ctx, &ValidObject{Name: "test"}, "Name")
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	_, err = client.QueryUnindexed(suite.ctx, &UnindexedObject{})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	_, err = client.QueryUnindexed(
+		suite.ctx, &UnindexedObject{Name: "test"}, "Unknown")
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestQueryUnindexedUnsupported tests that unindexed queries fail if the
+// connector does not support them
+func (suite *ORMTestSuite) TestQueryUnindexedUnsupported() {
+	defer suite.ctrl.Finish()
+	conn := connectormocks.NewMockConnector(suite.ctrl)
+	client, err := NewClient(conn, &UnindexedObject{})
+	suite.NoError(err)
+
+	_, err = client.QueryUnindexed(
+		suite.ctx, &UnindexedObject{Name: "test"}, "Name")
+	suite.True(yarpcerrors.IsUnimplemented(err))
+}
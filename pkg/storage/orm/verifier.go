@@ -0,0 +1,400 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// verifyingConnector is a Connector which serves all operations from a
+// primary connector, and verifies the rows read against a secondary
+// connector. The secondary is typically the same cluster read at a higher
+// consistency level, to confirm that replicas converged after an incident,
+// or the target cluster of a data migration. Divergences are logged and
+// counted, and repaired on the secondary if repair is enabled, the primary
+// being the source of truth.
+type verifyingConnector struct {
+	primary   Connector
+	secondary Connector
+	config    VerificationConfig
+	scope     tally.Scope
+}
+
+// VerificationConfig is the config of a verifying connector.
+type VerificationConfig struct {
+	// Repair writes the primary rows over the divergent rows of the
+	// secondary, and deletes the rows which only exist in the secondary.
+	// It must only be set if the secondary is another cluster or keyspace,
+	// e.g. the target of a data migration. When the secondary is the same
+	// cluster read at a higher consistency level, the secondary rows are
+	// the fresher ones, and the read itself repairs the replicas.
+	Repair bool
+	// IsNotFound tells whether the error of a read of the secondary means
+	// that the row does not exist, yarpcerrors.IsNotFound if nil. Other
+	// errors, e.g. timeouts, fail the verification of the row instead of
+	// being counted as divergences.
+	IsNotFound func(err error) bool
+}
+
+// verifyingBatcher is the Batcher of a verifyingConnector whose primary
+// is a Batcher.
+type verifyingBatcher struct {
+	batcher Batcher
+}
+
+// verifyingQuerier is the UnindexedQuerier of a verifyingConnector whose
+// primary is an UnindexedQuerier.
+type verifyingQuerier struct {
+	*verifyingConnector
+	querier UnindexedQuerier
+}
+
+// verifyingPager is the Pager of a verifyingConnector whose primary is a
+// Pager.
+type verifyingPager struct {
+	*verifyingConnector
+	pager Pager
+}
+
+// NewVerifyingConnector returns a Connector serving all operations from
+// primary, which verifies the rows read against secondary and repairs the
+// divergent rows on secondary if config.Repair is set. The returned
+// Connector is a Batcher, an UnindexedQuerier or a Pager if primary is.
+func NewVerifyingConnector(
+	primary Connector,
+	secondary Connector,
+	config VerificationConfig,
+	scope tally.Scope) Connector {
+	if config.IsNotFound == nil {
+		config.IsNotFound = yarpcerrors.IsNotFound
+	}
+	c := &verifyingConnector{
+		primary:   primary,
+		secondary: secondary,
+		config:    config,
+		scope:     scope.SubScope("orm_verification"),
+	}
+
+	var (
+		batcher Batcher
+		querier UnindexedQuerier
+		pager   Pager
+	)
+	if b, ok := primary.(Batcher); ok {
+		batcher = &verifyingBatcher{batcher: b}
+	}
+	if q, ok := primary.(UnindexedQuerier); ok {
+		querier = &verifyingQuerier{verifyingConnector: c, querier: q}
+	}
+	if p, ok := primary.(Pager); ok {
+		pager = &verifyingPager{verifyingConnector: c, pager: p}
+	}
+	return withExtensions(c, batcher, querier, pager)
+}
+
+// CreateIfNotExists creates a row in the primary connector if it
+// doesn't already exist
+func (c *verifyingConnector) CreateIfNotExists(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	return c.primary.CreateIfNotExists(ctx, e, values)
+}
+
+// Create creates a row in the primary connector
+func (c *verifyingConnector) Create(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	return c.primary.Create(ctx, e, values)
+}
+
+// Update updates a row in the primary connector
+func (c *verifyingConnector) Update(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+	keys []base.Column,
+) error {
+	return c.primary.Update(ctx, e, values, keys)
+}
+
+// Delete deletes a row from the primary connector
+func (c *verifyingConnector) Delete(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) error {
+	return c.primary.Delete(ctx, e, keys)
+}
+
+// Get fetches a row from the primary connector and verifies it against
+// the secondary connector. A row which does not exist in the secondary is
+// a divergence, while the other errors fail the verification.
+func (c *verifyingConnector) Get(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) ([]base.Column, error) {
+	row, err := c.primary.Get(ctx, e, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	c.verifyRow(ctx, e, keys, row)
+	return row, nil
+}
+
+// verifyRow verifies a row read from the primary connector against the
+// row with the same keys in the secondary connector.
+func (c *verifyingConnector) verifyRow(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+	row []base.Column,
+) {
+	scope := c.tableScope(e)
+	scope.Counter("verified").Inc(1)
+
+	secondaryRow, err := c.secondary.Get(ctx, e, keys)
+	if err != nil {
+		if c.config.IsNotFound(err) {
+			c.diverged(ctx, e, scope, keys, row, nil, err)
+		} else {
+			c.verificationFailed(e, scope, keys, err)
+		}
+		return
+	}
+	if diff := diffColumns(row, secondaryRow); len(diff) > 0 {
+		c.diverged(ctx, e, scope, keys, row, diff, nil)
+	}
+}
+
+// GetAll fetches the rows of a partition from the primary connector and
+// verifies them against the rows of the partition in the secondary
+// connector.
+func (c *verifyingConnector) GetAll(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) ([][]base.Column, error) {
+	rows, err := c.primary.GetAll(ctx, e, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := c.tableScope(e)
+	scope.Counter("verified").Inc(1)
+
+	secondaryRows, err := c.secondary.GetAll(ctx, e, keys)
+	if err != nil {
+		c.verificationFailed(e, scope, keys, err)
+		return rows, nil
+	}
+
+	secondaryByKey := make(map[string][]base.Column, len(secondaryRows))
+	for _, row := range secondaryRows {
+		secondaryByKey[primaryKeyString(e, row)] = row
+	}
+	for _, row := range rows {
+		key := primaryKeyString(e, row)
+		secondaryRow, ok := secondaryByKey[key]
+		delete(secondaryByKey, key)
+		if !ok {
+			c.diverged(ctx, e, scope, primaryKeyColumns(e, row), row, nil,
+				fmt.Errorf("row missing from verification store"))
+			continue
+		}
+		if diff := diffColumns(row, secondaryRow); len(diff) > 0 {
+			c.diverged(ctx, e, scope, primaryKeyColumns(e, row), row, diff, nil)
+		}
+	}
+	// The rows left only exist in the secondary.
+	for _, row := range secondaryByKey {
+		c.diverged(ctx, e, scope, primaryKeyColumns(e, row), nil, nil,
+			fmt.Errorf("row missing from primary store"))
+	}
+	return rows, nil
+}
+
+// CreateBatch creates the rows in the primary connector
+func (b *verifyingBatcher) CreateBatch(
+	ctx context.Context,
+	e *base.Definition,
+	rows [][]base.Column,
+) error {
+	return b.batcher.CreateBatch(ctx, e, rows)
+}
+
+// QueryUnindexed queries the rows from the primary connector, and
+// verifies each of them against the secondary connector. Rows which
+// only exist in the secondary are not found.
+func (q *verifyingQuerier) QueryUnindexed(
+	ctx context.Context,
+	e *base.Definition,
+	conditions []base.Column,
+	limit int,
+) ([][]base.Column, error) {
+	rows, err := q.querier.QueryUnindexed(ctx, e, conditions, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		q.verifyRow(ctx, e, primaryKeyColumns(e, row), row)
+	}
+	return rows, nil
+}
+
+// GetAllPage fetches a page of the rows of a partition from the primary
+// connector, and verifies each of them against the secondary connector.
+// Rows which only exist in the secondary are not found, as the pages of
+// the two connectors do not line up.
+func (p *verifyingPager) GetAllPage(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+	pageSize int,
+	pageToken []byte,
+) ([][]base.Column, []byte, error) {
+	rows, nextToken, err := p.pager.GetAllPage(
+		ctx, e, keys, pageSize, pageToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		p.verifyRow(ctx, e, primaryKeyColumns(e, row), row)
+	}
+	return rows, nextToken, nil
+}
+
+// diverged logs and counts a divergence between the primary row and the
+// secondary row, and repairs the secondary if repair is enabled. A nil
+// primary row means the row only exists in the secondary.
+func (c *verifyingConnector) diverged(
+	ctx context.Context,
+	e *base.Definition,
+	scope tally.Scope,
+	keys []base.Column,
+	row []base.Column,
+	diff []string,
+	readErr error,
+) {
+	scope.Counter("diverged").Inc(1)
+	logger := log.WithFields(log.Fields{
+		"table":           e.Name,
+		"keys":            keys,
+		"diverged_fields": diff,
+	})
+	if readErr != nil {
+		logger = logger.WithError(readErr)
+	}
+	logger.Warn("Row diverged between primary and verification store")
+
+	if !c.config.Repair {
+		return
+	}
+
+	var err error
+	if row == nil {
+		err = c.secondary.Delete(ctx, e, keys)
+	} else {
+		err = c.secondary.Create(ctx, e, row)
+	}
+	if err != nil {
+		scope.Counter("repair_fail").Inc(1)
+		logger.WithError(err).Error("Cannot repair row in verification store")
+		return
+	}
+	scope.Counter("repaired").Inc(1)
+}
+
+// verificationFailed logs and counts a read of the secondary which failed,
+// so that the rows could not be verified. They are not repaired, as the
+// read does not tell whether they diverged.
+func (c *verifyingConnector) verificationFailed(
+	e *base.Definition,
+	scope tally.Scope,
+	keys []base.Column,
+	err error,
+) {
+	scope.Counter("verification_fail").Inc(1)
+	log.WithFields(log.Fields{
+		"table": e.Name,
+		"keys":  keys,
+	}).WithError(err).Warn("Cannot read from verification store")
+}
+
+// tableScope returns the metrics scope of the table of the object.
+func (c *verifyingConnector) tableScope(e *base.Definition) tally.Scope {
+	return c.scope.Tagged(map[string]string{"table": e.Name})
+}
+
+// diffColumns returns the sorted names of the columns whose values
+// differ between two rows.
+func diffColumns(a []base.Column, b []base.Column) []string {
+	values := make(map[string]interface{}, len(a))
+	for _, col := range a {
+		values[col.Name] = col.Value
+	}
+
+	var diff []string
+	for _, col := range b {
+		value, ok := values[col.Name]
+		delete(values, col.Name)
+		if !ok || !reflect.DeepEqual(value, col.Value) {
+			diff = append(diff, col.Name)
+		}
+	}
+	for name := range values {
+		diff = append(diff, name)
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// primaryKeyColumns returns the partition and clustering key
+// columns of a row.
+func primaryKeyColumns(e *base.Definition, row []base.Column) []base.Column {
+	values := make(map[string]base.Column, len(row))
+	for _, col := range row {
+		values[col.Name] = col
+	}
+
+	var keys []base.Column
+	for _, pk := range e.Key.PartitionKeys {
+		keys = append(keys, values[pk])
+	}
+	for _, ck := range e.Key.ClusteringKeys {
+		keys = append(keys, values[ck.Name])
+	}
+	return keys
+}
+
+// primaryKeyString returns the primary key of a row as a string,
+// to index rows by primary key.
+func primaryKeyString(e *base.Definition, row []base.Column) string {
+	return fmt.Sprint(primaryKeyColumns(e, row))
+}
@@ -0,0 +1,321 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"errors"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	connectormocks "github.com/uber/peloton/pkg/storage/orm/connectormocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// setupVerifyingConnector returns a verifying connector over mock primary
+// and secondary connectors, and the definition of ValidObject
+func (suite *ORMTestSuite) setupVerifyingConnector(repair bool) (
+	Connector,
+	*connectormocks.MockConnector,
+	*connectormocks.MockConnector,
+	tally.TestScope,
+	*base.Definition,
+) {
+	primary := connectormocks.NewMockConnector(suite.ctrl)
+	secondary := connectormocks.NewMockConnector(suite.ctrl)
+	scope := tally.NewTestScope("", map[string]string{})
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	return NewVerifyingConnector(
+			primary, secondary, VerificationConfig{Repair: repair}, scope),
+		primary, secondary, scope, &table.Definition
+}
+
+// counter returns the value of a verification counter of valid_object
+func counter(scope tally.TestScope, name string) int64 {
+	c, ok := scope.Snapshot().Counters()["orm_verification."+name+"+table=valid_object"]
+	if !ok {
+		return 0
+	}
+	return c.Value()
+}
+
+// withData returns a copy of the row with the data column replaced
+func withData(row []base.Column, data string) []base.Column {
+	var result []base.Column
+	for _, col := range row {
+		if col.Name == "data" {
+			col.Value = data
+		}
+		result = append(result, col)
+	}
+	return result
+}
+
+// TestVerifyingConnectorWrites tests that writes only go to the primary
+func (suite *ORMTestSuite) TestVerifyingConnectorWrites() {
+	defer suite.ctrl.Finish()
+	conn, primary, _, _, e := suite.setupVerifyingConnector(true)
+
+	primary.EXPECT().CreateIfNotExists(suite.ctx, e, testRow).Return(nil)
+	primary.EXPECT().Create(suite.ctx, e, testRow).Return(nil)
+	primary.EXPECT().Update(suite.ctx, e, testRow, keyRow).Return(nil)
+	primary.EXPECT().Delete(suite.ctx, e, keyRow).Return(nil)
+
+	suite.NoError(conn.CreateIfNotExists(suite.ctx, e, testRow))
+	suite.NoError(conn.Create(suite.ctx, e, testRow))
+	suite.NoError(conn.Update(suite.ctx, e, testRow, keyRow))
+	suite.NoError(conn.Delete(suite.ctx, e, keyRow))
+}
+
+// TestVerifyingConnectorGet tests verifying a row read against
+// the secondary
+func (suite *ORMTestSuite) TestVerifyingConnectorGet() {
+	defer suite.ctrl.Finish()
+	conn, primary, secondary, scope, e := suite.setupVerifyingConnector(true)
+
+	// Converged rows are not repaired, regardless of column order.
+	reversed := []base.Column{testRow[2], testRow[1], testRow[0]}
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keyRow).Return(reversed, nil)
+	row, err := conn.Get(suite.ctx, e, keyRow)
+	suite.NoError(err)
+	suite.Equal(testRow, row)
+	suite.Equal(int64(1), counter(scope, "verified"))
+	suite.Equal(int64(0), counter(scope, "diverged"))
+
+	// A divergent row is repaired with the primary row.
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(withData(testRow, "stale"), nil)
+	secondary.EXPECT().Create(suite.ctx, e, testRow).Return(nil)
+	row, err = conn.Get(suite.ctx, e, keyRow)
+	suite.NoError(err)
+	suite.Equal(testRow, row)
+
+	// A row missing from the secondary is repaired.
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(nil, yarpcerrors.NotFoundErrorf("not found"))
+	secondary.EXPECT().Create(suite.ctx, e, testRow).
+		Return(errors.New("create failed"))
+	row, err = conn.Get(suite.ctx, e, keyRow)
+	suite.NoError(err)
+	suite.Equal(testRow, row)
+
+	// A row which cannot be read from the secondary is not repaired.
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(nil, yarpcerrors.DeadlineExceededErrorf("timeout"))
+	row, err = conn.Get(suite.ctx, e, keyRow)
+	suite.NoError(err)
+	suite.Equal(testRow, row)
+
+	suite.Equal(int64(4), counter(scope, "verified"))
+	suite.Equal(int64(2), counter(scope, "diverged"))
+	suite.Equal(int64(1), counter(scope, "repaired"))
+	suite.Equal(int64(1), counter(scope, "repair_fail"))
+	suite.Equal(int64(1), counter(scope, "verification_fail"))
+
+	// Primary errors are returned without verification.
+	primary.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(nil, errors.New("get failed"))
+	_, err = conn.Get(suite.ctx, e, keyRow)
+	suite.Error(err)
+	suite.Equal(int64(4), counter(scope, "verified"))
+}
+
+// TestVerifyingConnectorIsNotFound tests that the errors of the secondary
+// which mean that the row does not exist are configurable
+func (suite *ORMTestSuite) TestVerifyingConnectorIsNotFound() {
+	defer suite.ctrl.Finish()
+	primary := connectormocks.NewMockConnector(suite.ctrl)
+	secondary := connectormocks.NewMockConnector(suite.ctrl)
+	scope := tally.NewTestScope("", map[string]string{})
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	e := &table.Definition
+	errNotFound := errors.New("not found")
+	conn := NewVerifyingConnector(primary, secondary, VerificationConfig{
+		Repair:     true,
+		IsNotFound: func(err error) bool { return err == errNotFound },
+	}, scope)
+
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil).Times(2)
+	gomock.InOrder(
+		secondary.EXPECT().Get(suite.ctx, e, keyRow).
+			Return(nil, errNotFound),
+		secondary.EXPECT().Create(suite.ctx, e, testRow).Return(nil),
+		secondary.EXPECT().Get(suite.ctx, e, keyRow).
+			Return(nil, errors.New("unavailable")),
+	)
+	for i := 0; i < 2; i++ {
+		row, err := conn.Get(suite.ctx, e, keyRow)
+		suite.NoError(err)
+		suite.Equal(testRow, row)
+	}
+	suite.Equal(int64(1), counter(scope, "diverged"))
+	suite.Equal(int64(1), counter(scope, "repaired"))
+	suite.Equal(int64(1), counter(scope, "verification_fail"))
+}
+
+// TestVerifyingConnectorGetNoRepair tests that divergent rows are only
+// reported if repair is disabled
+func (suite *ORMTestSuite) TestVerifyingConnectorGetNoRepair() {
+	defer suite.ctrl.Finish()
+	conn, primary, secondary, scope, e := suite.setupVerifyingConnector(false)
+
+	primary.EXPECT().Get(suite.ctx, e, keyRow).Return(testRow, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keyRow).
+		Return(withData(testRow, "stale"), nil)
+	row, err := conn.Get(suite.ctx, e, keyRow)
+	suite.NoError(err)
+	suite.Equal(testRow, row)
+	suite.Equal(int64(1), counter(scope, "diverged"))
+	suite.Equal(int64(0), counter(scope, "repaired"))
+}
+
+// TestVerifyingConnectorGetAll tests verifying the rows of a partition
+// against the secondary
+func (suite *ORMTestSuite) TestVerifyingConnectorGetAll() {
+	defer suite.ctrl.Finish()
+	conn, primary, secondary, scope, e := suite.setupVerifyingConnector(true)
+
+	partitionKey := keyRow[:1]
+	extraRow := withData(testRow, "extra")
+	extraRow[1] = base.Column{Name: "name", Value: "extra"}
+
+	// testRows[0] converged, testRows[1] is stale and missing from the
+	// secondary, which has an extra row.
+	primary.EXPECT().GetAll(suite.ctx, e, partitionKey).Return(testRows, nil)
+	secondary.EXPECT().GetAll(suite.ctx, e, partitionKey).
+		Return([][]base.Column{testRows[0], extraRow}, nil)
+	secondary.EXPECT().Create(suite.ctx, e, testRows[1]).Return(nil)
+	secondary.EXPECT().Delete(suite.ctx, e, []base.Column{
+		extraRow[0], extraRow[1],
+	}).Return(nil)
+
+	rows, err := conn.GetAll(suite.ctx, e, partitionKey)
+	suite.NoError(err)
+	suite.Equal(testRows, rows)
+	suite.Equal(int64(2), counter(scope, "diverged"))
+	suite.Equal(int64(2), counter(scope, "repaired"))
+
+	// Secondary errors are counted without repair.
+	primary.EXPECT().GetAll(suite.ctx, e, partitionKey).Return(testRows, nil)
+	secondary.EXPECT().GetAll(suite.ctx, e, partitionKey).
+		Return(nil, errors.New("getall failed"))
+	rows, err = conn.GetAll(suite.ctx, e, partitionKey)
+	suite.NoError(err)
+	suite.Equal(testRows, rows)
+	suite.Equal(int64(1), counter(scope, "verification_fail"))
+	suite.Equal(int64(2), counter(scope, "diverged"))
+}
+
+// TestDiffColumns tests comparing the columns of two rows
+func (suite *ORMTestSuite) TestDiffColumns() {
+	suite.Empty(diffColumns(testRow, testRow))
+	suite.Equal([]string{"data"},
+		diffColumns(testRow, withData(testRow, "other")))
+	suite.Equal([]string{"data"}, diffColumns(testRow, testRow[:2]))
+	suite.Equal([]string{"data"}, diffColumns(testRow[:2], testRow))
+}
+
+// TestVerifyingConnectorExtensions tests that the verifying connector is a
+// Batcher, an UnindexedQuerier or a Pager only if the primary is, and
+// verifies the rows read through them
+func (suite *ORMTestSuite) TestVerifyingConnectorExtensions() {
+	defer suite.ctrl.Finish()
+	scope := tally.NewTestScope("", map[string]string{})
+	secondary := connectormocks.NewMockConnector(suite.ctrl)
+
+	conn := NewVerifyingConnector(connectormocks.NewMockConnector(suite.ctrl),
+		secondary, VerificationConfig{}, scope)
+	_, ok := conn.(Batcher)
+	suite.False(ok)
+	_, ok = conn.(UnindexedQuerier)
+	suite.False(ok)
+	_, ok = conn.(Pager)
+	suite.False(ok)
+
+	table, err := TableFromObject(&ValidObject{})
+	suite.NoError(err)
+	e := &table.Definition
+	keys := func(row []base.Column) []base.Column {
+		return []base.Column{row[0], row[1]}
+	}
+
+	batcher := &batcherConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockBatcher:   connectormocks.NewMockBatcher(suite.ctrl),
+	}
+	conn = NewVerifyingConnector(
+		batcher, secondary, VerificationConfig{}, scope)
+	_, ok = conn.(UnindexedQuerier)
+	suite.False(ok)
+	batcher.MockBatcher.EXPECT().
+		CreateBatch(suite.ctx, e, testRows).Return(nil)
+	suite.NoError(conn.(Batcher).CreateBatch(suite.ctx, e, testRows))
+
+	querier := &unindexedConnector{
+		MockConnector:        connectormocks.NewMockConnector(suite.ctrl),
+		MockUnindexedQuerier: connectormocks.NewMockUnindexedQuerier(suite.ctrl),
+	}
+	conn = NewVerifyingConnector(
+		querier, secondary, VerificationConfig{}, scope)
+	_, ok = conn.(Batcher)
+	suite.False(ok)
+	querier.MockUnindexedQuerier.EXPECT().
+		QueryUnindexed(suite.ctx, e, keyRow, 10).Return(testRows, nil)
+	secondary.EXPECT().Get(suite.ctx, e, keys(testRows[0])).
+		Return(testRows[0], nil)
+	secondary.EXPECT().Get(suite.ctx, e, keys(testRows[1])).
+		Return(withData(testRows[1], "stale"), nil)
+	rows, err := conn.(UnindexedQuerier).
+		QueryUnindexed(suite.ctx, e, keyRow, 10)
+	suite.NoError(err)
+	suite.Equal(testRows, rows)
+	suite.Equal(int64(2), counter(scope, "verified"))
+	suite.Equal(int64(1), counter(scope, "diverged"))
+
+	pager := &pagerConnector{
+		MockConnector: connectormocks.NewMockConnector(suite.ctrl),
+		MockPager:     connectormocks.NewMockPager(suite.ctrl),
+	}
+	conn = NewVerifyingConnector(pager, secondary, VerificationConfig{}, scope)
+	_, ok = conn.(UnindexedQuerier)
+	suite.False(ok)
+	pager.MockPager.EXPECT().
+		GetAllPage(suite.ctx, e, keyRow, 10, []byte("token")).
+		Return(testRows[:1], []byte("next"), nil)
+	secondary.EXPECT().Get(suite.ctx, e, keys(testRows[0])).
+		Return(nil, errors.New("get failed"))
+	rows, token, err := conn.(Pager).
+		GetAllPage(suite.ctx, e, keyRow, 10, []byte("token"))
+	suite.NoError(err)
+	suite.Equal(testRows[:1], rows)
+	suite.Equal([]byte("next"), token)
+	suite.Equal(int64(3), counter(scope, "verified"))
+	suite.Equal(int64(1), counter(scope, "verification_fail"))
+
+	// Primary errors are returned without verification.
+	pager.MockPager.EXPECT().
+		GetAllPage(suite.ctx, e, keyRow, 10, nil).
+		Return(nil, nil, errors.New("page failed"))
+	_, _, err = conn.(Pager).GetAllPage(suite.ctx, e, keyRow, 10, nil)
+	suite.Error(err)
+	suite.Equal(int64(3), counter(scope, "verified"))
+}
@@ -25,7 +25,10 @@ import (
 // MustCreateStore creates a generic store that is needed by peloton
 // and exits if store can't be created
 func MustCreateStore(
-	cfg *storage_config.Config, rootScope tally.Scope) storage.Store {
+	cfg *storage_config.Config,
+	rootScope tally.Scope,
+	opts ...cassandra.StoreOption,
+) storage.Store {
 	log.WithFields(log.Fields{
 		"cassandra_connection": cfg.Cassandra.CassandraConn,
 		"cassandra_config":     cfg.Cassandra,
@@ -35,7 +38,7 @@ func MustCreateStore(
 			log.Fatalf("Could not migrate database: %+v", errs)
 		}
 	}
-	store, err := cassandra.NewStore(&cfg.Cassandra, rootScope)
+	store, err := cassandra.NewStore(&cfg.Cassandra, rootScope, opts...)
 	if err != nil {
 		log.Fatalf("Could not create cassandra store: %+v", err)
 	}